@@ -0,0 +1,109 @@
+// Command omp-relay is the public side of relay remote access. Run it on a
+// host with a public address and point one or more instances' RELAY_URL at
+// it; each instance is reachable under its own host name without port
+// forwarding. See docs/REMOTE_ACCESS.md.
+//
+// Environment:
+//
+//	RELAY_ADDR      listen address (default :8090)
+//	RELAY_TUNNELS   comma-separated host=token pairs, one per exposed host
+//	RELAY_TLS_CERT  certificate file; with RELAY_TLS_KEY serves HTTPS
+//	RELAY_TLS_KEY   key file for RELAY_TLS_CERT
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/relay"
+)
+
+const defaultAddr = ":8090"
+
+func main() {
+	tunnels, err := parseTunnels(os.Getenv("RELAY_TUNNELS"))
+	if err != nil {
+		log.Fatalf("omp-relay: RELAY_TUNNELS: %v", err)
+	}
+	addr := os.Getenv("RELAY_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+	certFile, keyFile := os.Getenv("RELAY_TLS_CERT"), os.Getenv("RELAY_TLS_KEY")
+	if (certFile == "") != (keyFile == "") {
+		log.Fatal("omp-relay: set both RELAY_TLS_CERT and RELAY_TLS_KEY, or neither")
+	}
+
+	relayServer := relay.NewServer(tunnels)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           relayServer,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	shutdownComplete := make(chan struct{})
+	go func() {
+		defer close(shutdownComplete)
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		relayServer.Close()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("omp-relay: shutdown: %v", err)
+		}
+	}()
+
+	hosts := make([]string, 0, len(tunnels))
+	for host := range tunnels {
+		hosts = append(hosts, host)
+	}
+	log.Printf("omp-relay listening on %s for %s", addr, strings.Join(hosts, ", "))
+	if certFile != "" {
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("omp-relay: %v", err)
+	}
+	<-shutdownComplete
+}
+
+// parseTunnels reads "host=token,host=token" into a host-to-token map.
+func parseTunnels(value string) (map[string]string, error) {
+	tunnels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		host, token, ok := strings.Cut(pair, "=")
+		host, token = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(token)
+		if !ok || host == "" || token == "" {
+			return nil, fmt.Errorf("%q is not host=token", pair)
+		}
+		if _, dup := tunnels[host]; dup {
+			return nil, fmt.Errorf("host %s is listed twice", host)
+		}
+		for other, otherToken := range tunnels {
+			if otherToken == token {
+				return nil, fmt.Errorf("hosts %s and %s share a token", other, host)
+			}
+		}
+		tunnels[host] = token
+	}
+	if len(tunnels) == 0 {
+		return nil, errors.New("at least one host=token pair is required")
+	}
+	return tunnels, nil
+}
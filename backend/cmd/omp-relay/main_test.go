@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestParseTunnels(t *testing.T) {
+	tunnels, err := parseTunnels(" Music.Example.Test = one, media.example.test=two ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tunnels) != 2 || tunnels["music.example.test"] != "one" || tunnels["media.example.test"] != "two" {
+		t.Fatalf("tunnels = %v", tunnels)
+	}
+
+	for _, value := range []string{
+		"",
+		"music.example.test",
+		"music.example.test=",
+		"a.test=one,A.test=two",
+		"a.test=same,b.test=same",
+	} {
+		if _, err := parseTunnels(value); err == nil {
+			t.Errorf("parseTunnels(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Command rebuild-playlist-totals recomputes the track count and duration
+// stored on every playlist. Database triggers keep them current; run this
+// after restoring a backup or editing playlist_tracks with triggers disabled.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/openmusicplayer/backend/internal/config"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func main() {
+	log.SetFlags(0)
+	cfg := config.Load()
+
+	database, err := db.New(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+	if err != nil {
+		log.Fatalf("rebuild-playlist-totals: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("rebuild-playlist-totals: %v", err)
+	}
+
+	if err := run(context.Background(), db.NewPlaylistRepository(database), os.Stdout); err != nil {
+		log.Fatalf("rebuild-playlist-totals: %v", err)
+	}
+}
+
+type totalsStore interface {
+	RebuildTotals(ctx context.Context) (int, error)
+}
+
+func run(ctx context.Context, store totalsStore, out io.Writer) error {
+	changed, err := store.RebuildTotals(ctx)
+	if err != nil {
+		return fmt.Errorf("rebuild totals: %w", err)
+	}
+	if changed == 0 {
+		fmt.Fprintln(out, "All playlist totals were already correct.")
+		return nil
+	}
+	fmt.Fprintf(out, "Corrected totals on %d playlists.\n", changed)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeTotalsStore struct {
+	changed int
+	err     error
+}
+
+func (f fakeTotalsStore) RebuildTotals(context.Context) (int, error) {
+	return f.changed, f.err
+}
+
+func TestRunReportsCorrectedPlaylists(t *testing.T) {
+	for _, tc := range []struct {
+		changed int
+		want    string
+	}{
+		{0, "already correct"},
+		{3, "Corrected totals on 3 playlists."},
+	} {
+		var out strings.Builder
+		if err := run(context.Background(), fakeTotalsStore{changed: tc.changed}, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), tc.want) {
+			t.Fatalf("output = %q, want %q", out.String(), tc.want)
+		}
+	}
+}
+
+func TestRunReturnsStoreError(t *testing.T) {
+	err := run(context.Background(), fakeTotalsStore{err: errors.New("connection refused")}, &strings.Builder{})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Command rehash-identities recomputes every track's identity hash under a new
+// deduplication strategy. Tracks the new strategy no longer tells apart
+// collide; each collision is merged into one track, kept as separate tracks,
+// or skipped, either interactively or by a -on-collision default.
+//
+// Without -apply it only reports what would change. Run it with the same
+// IDENTITY_* settings the server will use, then restart the server so new
+// downloads hash the same way.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/openmusicplayer/backend/internal/config"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	onCollisionAsk = "ask"
+	// collisionListLimit bounds how many collisions a dry run prints.
+	collisionListLimit = 20
+)
+
+type options struct {
+	strategy    db.IdentityStrategy
+	apply       bool
+	onCollision string
+}
+
+func main() {
+	log.SetFlags(0)
+	cfg := config.Load()
+
+	opts := options{}
+	flag.BoolVar(&opts.strategy.IncludeAlbum, "include-album", cfg.IdentityIncludeAlbum, "keep the same recording on different albums apart")
+	flag.IntVar(&opts.strategy.DurationBucketMs, "duration-bucket-ms", cfg.IdentityDurationBucketMs, "duration bucket width in milliseconds; 0 ignores duration")
+	flag.BoolVar(&opts.strategy.VersionSensitive, "version-sensitive", cfg.IdentityVersionSensitive, "keep versions such as live or remix apart")
+	flag.BoolVar(&opts.apply, "apply", false, "write the new hashes; without it, only report")
+	flag.StringVar(&opts.onCollision, "on-collision", onCollisionAsk, "how to settle collisions: ask, merge, separate, or skip")
+	flag.Parse()
+
+	switch opts.onCollision {
+	case onCollisionAsk, db.CollisionMerge, db.CollisionSeparate, db.CollisionSkip:
+	default:
+		log.Fatalf("rehash-identities: unknown -on-collision %q", opts.onCollision)
+	}
+	if opts.strategy.DurationBucketMs < 0 {
+		log.Fatal("rehash-identities: -duration-bucket-ms must not be negative")
+	}
+
+	database, err := db.New(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+	if err != nil {
+		log.Fatalf("rehash-identities: %v", err)
+	}
+	defer database.Close()
+
+	if err := run(context.Background(), db.NewTrackRepository(database), opts, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("rehash-identities: %v", err)
+	}
+}
+
+type rehashStore interface {
+	ListIdentityRehashTracks(ctx context.Context) ([]db.IdentityRehashTrack, error)
+	ApplyIdentityRehash(ctx context.Context, plan *db.IdentityRehashPlan, resolutions map[string]db.CollisionResolution) (*db.IdentityRehashResult, error)
+}
+
+func run(ctx context.Context, store rehashStore, opts options, in io.Reader, out io.Writer) error {
+	tracks, err := store.ListIdentityRehashTracks(ctx)
+	if err != nil {
+		return fmt.Errorf("load tracks: %w", err)
+	}
+	plan := db.PlanIdentityRehash(tracks, opts.strategy)
+	collided := 0
+	for _, c := range plan.Collisions {
+		collided += len(c.Tracks)
+	}
+	fmt.Fprintf(out, "Strategy: %s\n", opts.strategy)
+	fmt.Fprintf(out, "%d tracks: %d unchanged, %d rehashed, %d in %d collisions\n",
+		len(tracks), plan.Unchanged, len(plan.Changes), collided, len(plan.Collisions))
+
+	if !opts.apply {
+		for i, c := range plan.Collisions {
+			if i == collisionListLimit {
+				fmt.Fprintf(out, "... and %d more collisions\n", len(plan.Collisions)-i)
+				break
+			}
+			printCollision(out, c)
+		}
+		fmt.Fprintln(out, "Dry run; rerun with -apply to write the new hashes.")
+		return nil
+	}
+	if len(plan.Changes) == 0 && len(plan.Collisions) == 0 {
+		return nil
+	}
+
+	resolutions := make(map[string]db.CollisionResolution, len(plan.Collisions))
+	prompt := bufio.NewScanner(in)
+	for _, c := range plan.Collisions {
+		if opts.onCollision != onCollisionAsk {
+			resolutions[c.Hash] = db.CollisionResolution{Action: opts.onCollision, KeeperID: c.Tracks[0].ID}
+			continue
+		}
+		printCollision(out, c)
+		resolution, err := askResolution(prompt, out, c)
+		if err != nil {
+			return err
+		}
+		resolutions[c.Hash] = resolution
+	}
+
+	result, err := store.ApplyIdentityRehash(ctx, plan, resolutions)
+	if err != nil {
+		return fmt.Errorf("apply: %w", err)
+	}
+	fmt.Fprintf(out, "Rehashed %d tracks; merged %d, separated %d, skipped %d collisions; moved %d takedowns\n",
+		result.Rehashed, result.Merged, result.Separated, result.Skipped, result.TakedownsUpdated)
+	if len(result.OrphanedStorageKeys) > 0 {
+		fmt.Fprintln(out, "Merged-away tracks left these objects unreferenced in storage:")
+		for _, key := range result.OrphanedStorageKeys {
+			fmt.Fprintf(out, "  %s\n", key)
+		}
+	}
+	return nil
+}
+
+func printCollision(out io.Writer, c db.IdentityCollision) {
+	fmt.Fprintf(out, "\nCollision %s:\n", c.Hash)
+	for i, t := range c.Tracks {
+		fmt.Fprintf(out, "  [%d] track %d: %s - %s", i+1, t.ID, t.Identity.Artist, t.Identity.Title)
+		if t.Identity.Version != "" {
+			fmt.Fprintf(out, " (%s)", t.Identity.Version)
+		}
+		if t.Identity.Album != "" {
+			fmt.Fprintf(out, " on %s", t.Identity.Album)
+		}
+		if t.Identity.DurationMs > 0 {
+			fmt.Fprintf(out, ", %d:%02d", t.Identity.DurationMs/60000, t.Identity.DurationMs/1000%60)
+		}
+		fmt.Fprintf(out, ", in %d libraries", t.LibraryCount)
+		if t.SourceURL != "" {
+			fmt.Fprintf(out, ", %s", t.SourceURL)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// askResolution prompts until it reads a valid answer: "m N" merges into
+// track [N], "s N" keeps all apart with [N] holding the hash, "k" skips. A
+// bare "m" or "s" keeps [1].
+func askResolution(prompt *bufio.Scanner, out io.Writer, c db.IdentityCollision) (db.CollisionResolution, error) {
+	for {
+		fmt.Fprint(out, "[m]erge into, [s]eparate keeping, or s[k]ip? (e.g. \"m 1\"): ")
+		if !prompt.Scan() {
+			if err := prompt.Err(); err != nil {
+				return db.CollisionResolution{}, err
+			}
+			return db.CollisionResolution{}, errors.New("input ended before every collision was resolved")
+		}
+		resolution, err := parseResolution(prompt.Text(), c)
+		if err == nil {
+			return resolution, nil
+		}
+		fmt.Fprintln(out, err)
+	}
+}
+
+func parseResolution(answer string, c db.IdentityCollision) (db.CollisionResolution, error) {
+	fields := strings.Fields(strings.ToLower(answer))
+	if len(fields) == 0 || len(fields) > 2 {
+		return db.CollisionResolution{}, errors.New("answer with m, s, or k, optionally followed by a track number")
+	}
+	var action string
+	switch fields[0] {
+	case "m", "merge":
+		action = db.CollisionMerge
+	case "s", "separate":
+		action = db.CollisionSeparate
+	case "k", "skip":
+		if len(fields) > 1 {
+			return db.CollisionResolution{}, errors.New("skip takes no track number")
+		}
+		return db.CollisionResolution{Action: db.CollisionSkip}, nil
+	default:
+		return db.CollisionResolution{}, fmt.Errorf("unknown answer %q", fields[0])
+	}
+	index := 1
+	if len(fields) == 2 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > len(c.Tracks) {
+			return db.CollisionResolution{}, fmt.Errorf("track number must be 1-%d", len(c.Tracks))
+		}
+		index = n
+	}
+	return db.CollisionResolution{Action: action, KeeperID: c.Tracks[index-1].ID}, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeRehashStore struct {
+	tracks      []db.IdentityRehashTrack
+	applied     bool
+	resolutions map[string]db.CollisionResolution
+}
+
+func (f *fakeRehashStore) ListIdentityRehashTracks(context.Context) ([]db.IdentityRehashTrack, error) {
+	return f.tracks, nil
+}
+
+func (f *fakeRehashStore) ApplyIdentityRehash(_ context.Context, plan *db.IdentityRehashPlan, resolutions map[string]db.CollisionResolution) (*db.IdentityRehashResult, error) {
+	f.applied, f.resolutions = true, resolutions
+	return &db.IdentityRehashResult{Rehashed: len(plan.Changes), Merged: 1}, nil
+}
+
+// collidingStore holds two tracks that differ only by album, plus another
+// pair that differs only by version.
+func collidingStore() *fakeRehashStore {
+	identities := []db.TrackIdentity{
+		{Artist: "Artist", Title: "Song", Album: "Album", DurationMs: 200000},
+		{Artist: "Artist", Title: "Song", Album: "Compilation", DurationMs: 200000},
+		{Artist: "Band", Title: "Tune", Album: "LP", DurationMs: 180000},
+		{Artist: "Band", Title: "Tune", Album: "Live LP", DurationMs: 180000},
+	}
+	store := &fakeRehashStore{}
+	for i, identity := range identities {
+		store.tracks = append(store.tracks, db.IdentityRehashTrack{
+			ID: int64(i + 1), IdentityHash: db.CalculateIdentityHashFromTrack(identity), Identity: identity,
+		})
+	}
+	return store
+}
+
+var withoutAlbum = db.IdentityStrategy{DurationBucketMs: 5000, VersionSensitive: true}
+
+func TestRunDryRunReportsCollisionsWithoutApplying(t *testing.T) {
+	store := collidingStore()
+	var out strings.Builder
+	err := run(context.Background(), store, options{strategy: withoutAlbum, onCollision: onCollisionAsk}, strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.applied {
+		t.Fatal("dry run applied the rehash")
+	}
+	if !strings.Contains(out.String(), "4 in 2 collisions") || !strings.Contains(out.String(), "track 2: Artist - Song on Compilation") {
+		t.Fatalf("output:\n%s", out.String())
+	}
+}
+
+func TestRunAsksForEachCollisionAndRepromptsOnBadAnswers(t *testing.T) {
+	store := collidingStore()
+	var out strings.Builder
+	answers := "m 3\nx\nm 2\nk\n"
+	err := run(context.Background(), store, options{strategy: withoutAlbum, apply: true, onCollision: onCollisionAsk}, strings.NewReader(answers), &out)
+	if err != nil {
+		t.Fatalf("run: %v\n%s", err, out.String())
+	}
+	if !store.applied || len(store.resolutions) != 2 {
+		t.Fatalf("applied=%v resolutions=%+v", store.applied, store.resolutions)
+	}
+	plan := db.PlanIdentityRehash(store.tracks, withoutAlbum)
+	first, second := store.resolutions[plan.Collisions[0].Hash], store.resolutions[plan.Collisions[1].Hash]
+	if first != (db.CollisionResolution{Action: db.CollisionMerge, KeeperID: 2}) || second.Action != db.CollisionSkip {
+		t.Fatalf("resolutions = %+v, %+v", first, second)
+	}
+	if !strings.Contains(out.String(), "track number must be 1-2") || !strings.Contains(out.String(), `unknown answer "x"`) {
+		t.Fatalf("bad answers were not explained:\n%s", out.String())
+	}
+}
+
+func TestRunFailsWhenInputEndsBeforeEveryCollisionIsResolved(t *testing.T) {
+	store := collidingStore()
+	var out strings.Builder
+	err := run(context.Background(), store, options{strategy: withoutAlbum, apply: true, onCollision: onCollisionAsk}, strings.NewReader("s\n"), &out)
+	if err == nil || store.applied {
+		t.Fatalf("err = %v, applied = %v; want an error and nothing applied", err, store.applied)
+	}
+}
+
+func TestRunSettlesCollisionsWithDefaultWithoutPrompting(t *testing.T) {
+	store := collidingStore()
+	var out strings.Builder
+	err := run(context.Background(), store, options{strategy: withoutAlbum, apply: true, onCollision: db.CollisionSeparate}, strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hash, resolution := range store.resolutions {
+		if resolution.Action != db.CollisionSeparate || resolution.KeeperID == 0 {
+			t.Fatalf("collision %s resolved as %+v", hash, resolution)
+		}
+	}
+}
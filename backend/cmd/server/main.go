@@ -10,19 +10,28 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/openmusicplayer/backend/internal/aiassist"
 	"github.com/openmusicplayer/backend/internal/analyzer"
 	"github.com/openmusicplayer/backend/internal/api"
+	"github.com/openmusicplayer/backend/internal/artwork"
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/cache"
+	"github.com/openmusicplayer/backend/internal/capability"
+	"github.com/openmusicplayer/backend/internal/chapters"
 	"github.com/openmusicplayer/backend/internal/config"
+	"github.com/openmusicplayer/backend/internal/dailymix"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/discovery"
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/fingerprint"
+	"github.com/openmusicplayer/backend/internal/goals"
 	"github.com/openmusicplayer/backend/internal/health"
+	"github.com/openmusicplayer/backend/internal/libraryimport"
 	"github.com/openmusicplayer/backend/internal/logger"
+	"github.com/openmusicplayer/backend/internal/lyrics"
 	"github.com/openmusicplayer/backend/internal/matcher"
 	"github.com/openmusicplayer/backend/internal/metrics"
 	"github.com/openmusicplayer/backend/internal/middleware"
@@ -30,9 +39,16 @@ import (
 	"github.com/openmusicplayer/backend/internal/playlistimport"
 	"github.com/openmusicplayer/backend/internal/processor"
 	"github.com/openmusicplayer/backend/internal/queue"
+	"github.com/openmusicplayer/backend/internal/recommender"
+	"github.com/openmusicplayer/backend/internal/relay"
 	"github.com/openmusicplayer/backend/internal/research"
+	"github.com/openmusicplayer/backend/internal/retention"
+	"github.com/openmusicplayer/backend/internal/scrobbler"
 	"github.com/openmusicplayer/backend/internal/search"
 	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/telemetry"
+	"github.com/openmusicplayer/backend/internal/transcode"
+	"github.com/openmusicplayer/backend/internal/validators"
 	"github.com/openmusicplayer/backend/internal/websocket"
 )
 
@@ -45,6 +61,11 @@ const (
 	startupAnalyzerRepairWorkers = 4
 	startupAnalyzerRepairTimeout = 15 * time.Second
 	startupAnalyzerRetryInterval = 30 * time.Second
+	unverifiedTrackGaugeInterval = time.Minute
+	downloadTempSweepInterval    = 15 * time.Minute
+	// downloadQueuePositionInterval is how often waiting download jobs are
+	// told their new queue position.
+	downloadQueuePositionInterval = 2 * time.Second
 )
 
 type analyzerInfoClient interface {
@@ -80,6 +101,56 @@ func (r sourceSelectionPlaybackRecovery) EnsureSourceCandidateWithID(ctx context
 	return err
 }
 
+// processorPlaybackQueue queues finished downloads next for the "queue next"
+// download setting. A job that already backs a queue item is left alone; that
+// item becomes playable on its own.
+type processorPlaybackQueue struct {
+	service *queue.Service
+}
+
+func (q processorPlaybackQueue) QueueNext(ctx context.Context, userID, jobID string, trackID int64) error {
+	state, err := q.service.GetQueue(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, item := range state.Items {
+		if item.DownloadJobID == jobID {
+			return nil
+		}
+	}
+	_, err = q.service.AddToQueue(ctx, userID, trackID, "next")
+	return err
+}
+
+// scrobblingPlayEvents records plays and then queues completed ones for the
+// listener's connected scrobbling services. Skips are never scrobbled. A
+// scrobbling failure is logged; the play itself is already recorded.
+type scrobblingPlayEvents struct {
+	*db.PlayEventRepository
+	tracks    *db.TrackRepository
+	scrobbler *scrobbler.Service
+}
+
+func (p scrobblingPlayEvents) RecordPlayEvent(ctx context.Context, play db.PlayRecord) error {
+	if err := p.PlayEventRepository.RecordPlayEvent(ctx, play); err != nil {
+		return err
+	}
+	if p.scrobbler == nil || play.Skipped {
+		return nil
+	}
+	track, err := p.tracks.GetByID(ctx, play.TrackID)
+	if err == nil {
+		err = p.scrobbler.Scrobble(ctx, play.UserID, scrobbler.ListenFromPlay(track, play, time.Now()))
+	}
+	if err != nil {
+		logger.Default().Warn(ctx, "Failed to queue scrobble", map[string]interface{}{
+			"track_id": play.TrackID,
+			"error":    err.Error(),
+		})
+	}
+	return nil
+}
+
 type analyzerMaintenanceReport struct {
 	Analyzer        string
 	AnalyzerVersion string
@@ -141,9 +212,221 @@ func shouldStartResearchWorker(cfg *config.Config) bool {
 	return cfg != nil && cfg.ResearchEnabled && cfg.ResearchWorkerEnabled
 }
 
+// telemetryFeatures names the optional features cfg switches on. Only the
+// names are reported; URLs, paths, and credentials never are.
+func telemetryFeatures(cfg *config.Config) []string {
+	enabled := map[string]bool{
+		"downloads":          cfg.RedisEnabled && cfg.WorkerCount > 0,
+		"analysis":           cfg.AnalyzerEnabled,
+		"transcode":          cfg.TranscodeWorkers > 0,
+		"tag_stored_audio":   cfg.TagStoredAudio,
+		"download_webhook":   cfg.DownloadWebhookURL != "",
+		"export_dir":         cfg.ExportDir != "",
+		"daily_mix":          cfg.DailyMixEnabled,
+		"goal_nudges":        cfg.GoalNudgesEnabled,
+		"oidc":               cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "",
+		"playlist_mix":       cfg.EnablePlaylistMix,
+		"ai_assist":          cfg.AIAssistEnabled,
+		"metadata_llm":       cfg.MetadataLLMEnabled,
+		"source_quality_llm": cfg.SourceQualityLLMEnabled,
+		"research":           cfg.ResearchEnabled,
+		"relay":              cfg.RelayURL != "",
+		"relay_media":        cfg.RelayURL != "" && cfg.RelayMediaToken != "",
+	}
+	features := make([]string, 0, len(enabled))
+	for name, on := range enabled {
+		if on {
+			features = append(features, name)
+		}
+	}
+	return features
+}
+
 // newResearchRuntime keeps durable research independent from Redis, download,
 // playback, and the private agent-tools gateway. The HTTP baseline uses the
 // existing discovery service; model enhancement remains a bounded child process.
+// downloadOutcomeRecorder feeds terminal download outcomes to both the
+// per-provider metrics and the admin report table.
+type downloadOutcomeRecorder struct {
+	metrics *metrics.Metrics
+	store   *db.DownloadOutcomeRepository
+}
+
+func (r downloadOutcomeRecorder) ObserveDownloadOutcome(ctx context.Context, job *download.DownloadJob, outcome string) {
+	r.metrics.ObserveDownloadOutcome(job.SourceType, outcome)
+	if err := r.store.Record(ctx, job.SourceType, outcome, job.ID); err != nil {
+		logger.Default().Warn(ctx, "Failed to record download outcome", map[string]interface{}{
+			"job_id": job.ID,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// downloadQueuePositionNotifier pushes queue position changes to the job
+// owner's WebSocket connections.
+type downloadQueuePositionNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n downloadQueuePositionNotifier) QueuePositionChanged(userID string, position download.QueuePosition) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+	if !n.tracker.HasConnectedClients(id) {
+		return
+	}
+	n.tracker.UpdateQueuePosition(id, position.JobID, position.Position, position.EstimatedStartAt)
+}
+
+// downloadProgressNotifier pushes download job updates, including each item
+// of a playlist download, to the job owner's WebSocket connections.
+type downloadProgressNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n downloadProgressNotifier) DownloadJobChanged(job *download.DownloadJob) {
+	id, err := uuid.Parse(job.UserID)
+	if err != nil {
+		return
+	}
+	if !n.tracker.HasConnectedClients(id) {
+		return
+	}
+	n.tracker.UpdateDownloadJob(id, job.ID, job.ParentJobID, job.Status, job.Progress, job.Error, job.Title, job.RequestID)
+}
+
+// batchMatchProgressNotifier pushes batch match progress to the admin who
+// started the run.
+type batchMatchProgressNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n batchMatchProgressNotifier) report(userID uuid.UUID, status processor.BatchMatchStatus) {
+	if !n.tracker.HasConnectedClients(userID) {
+		return
+	}
+	progress := 100
+	if status.Total > 0 {
+		progress = status.Processed * 100 / status.Total
+	}
+	n.tracker.UpdateBatchMatch(userID, status.State, progress, status)
+}
+
+// artworkBackfillProgressNotifier pushes artwork backfill progress to the
+// admin who started the run.
+type artworkBackfillProgressNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n artworkBackfillProgressNotifier) report(userID uuid.UUID, status processor.ArtworkBackfillStatus) {
+	if !n.tracker.HasConnectedClients(userID) {
+		return
+	}
+	progress := 100
+	if status.Total > 0 {
+		progress = status.Processed * 100 / status.Total
+	}
+	n.tracker.UpdateArtworkBackfill(userID, status.State, progress, status)
+}
+
+// queueChangeNotifier pushes shared queue edits to the user's other devices.
+type queueChangeNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n queueChangeNotifier) QueueChanged(userID uuid.UUID, fromDeviceID string, change queue.QueueChange) {
+	if !n.tracker.HasConnectedClients(userID) {
+		return
+	}
+	n.tracker.QueueChanged(userID, fromDeviceID, change)
+}
+
+// playlistChangeNotifier pushes playlist edits to the user's devices.
+type playlistChangeNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n playlistChangeNotifier) PlaylistChanged(userID uuid.UUID, change api.PlaylistChange) {
+	if !n.tracker.HasConnectedClients(userID) {
+		return
+	}
+	n.tracker.PlaylistChanged(userID, change)
+}
+
+// libraryScanProgressNotifier pushes library scan progress to the admin who
+// started the run.
+type libraryScanProgressNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n libraryScanProgressNotifier) report(adminID uuid.UUID, status processor.LibraryScanStatus) {
+	if !n.tracker.HasConnectedClients(adminID) {
+		return
+	}
+	progress := 100
+	if status.Total > 0 {
+		progress = status.Processed * 100 / status.Total
+	}
+	n.tracker.UpdateLibraryScan(adminID, status.State, progress, status)
+}
+
+// libraryExportProgressNotifier pushes library export progress to the user
+// who requested the archive.
+type libraryExportProgressNotifier struct {
+	tracker *websocket.ProgressTracker
+}
+
+func (n libraryExportProgressNotifier) report(userID uuid.UUID, status processor.LibraryExportStatus) {
+	if !n.tracker.HasConnectedClients(userID) {
+		return
+	}
+	progress := 100
+	if status.Total > 0 {
+		progress = status.Processed * 100 / status.Total
+	}
+	n.tracker.UpdateLibraryExport(userID, status.State, progress, status)
+}
+
+// refreshUnverifiedTrackGauge keeps the unverified-track gauge current. The
+// count is a table scan, so it runs on a slow interval rather than per scrape.
+func refreshUnverifiedTrackGauge(ctx context.Context, tracks *db.TrackRepository, m *metrics.Metrics) {
+	ticker := time.NewTicker(unverifiedTrackGaugeInterval)
+	defer ticker.Stop()
+	for {
+		if count, err := tracks.CountUnverifiedTracks(ctx); err == nil {
+			m.SetUnverifiedTracks(count)
+		} else if ctx.Err() == nil {
+			logger.Default().Warn(ctx, "Failed to count unverified tracks", map[string]interface{}{"error": err.Error()})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepDownloadTempFiles removes download scratch entries that outlived any
+// job, which happens when a worker is killed before its deferred cleanup.
+func sweepDownloadTempFiles(ctx context.Context, dir string, maxAge time.Duration) {
+	ticker := time.NewTicker(downloadTempSweepInterval)
+	defer ticker.Stop()
+	for {
+		removed, err := processor.SweepStaleTempFiles(dir, maxAge, time.Now())
+		if err != nil {
+			logger.Default().Warn(ctx, "Failed to sweep download temp files", map[string]interface{}{"error": err.Error()})
+		} else if removed > 0 {
+			logger.Default().Info(ctx, "Swept stale download temp files", map[string]interface{}{"removed": removed})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func newResearchRuntime(cfg *config.Config, database *db.DB, search *discovery.Service, observer api.ResearchObserver) (*researchRuntime, error) {
 	if cfg == nil || database == nil || search == nil {
 		return nil, fmt.Errorf("research runtime requires config, database, and discovery")
@@ -409,7 +692,13 @@ func main() {
 	// Initialize repositories
 	userRepo := db.NewUserRepository(database)
 	tokenRepo := db.NewTokenRepository(database)
+	apiKeyRepo := db.NewAPIKeyRepository(database)
 	trackRepo := db.NewTrackRepository(database)
+	trackRepo.SetIdentityStrategy(db.IdentityStrategy{
+		IncludeAlbum:     cfg.IdentityIncludeAlbum,
+		DurationBucketMs: cfg.IdentityDurationBucketMs,
+		VersionSensitive: cfg.IdentityVersionSensitive,
+	})
 	libraryRepo := db.NewLibraryRepository(database)
 	analysisRepo := db.NewAnalysisRepository(database)
 	playlistRepo := db.NewPlaylistRepository(database)
@@ -418,13 +707,41 @@ func main() {
 	trackSourceRepo := playlistimport.NewTrackSourceRepository(database)
 	mixPlanRepo := db.NewMixPlanRepository(database)
 	playEventRepo := db.NewPlayEventRepository(database)
+	profileRepo := db.NewProfileRepository(database)
+	notificationRepo := db.NewNotificationRepository(database)
+	dailyMixRepo := db.NewDailyMixRepository(database)
+	wrappedRepo := db.NewWrappedRepository(database)
+	listeningGoalRepo := db.NewListeningGoalRepository(database)
+	libraryImportRepo := libraryimport.NewRepository(database)
 	sourceSelectionRepo := db.NewSourceSelectionRepository(database)
+	takedownRepo := db.NewTakedownRepository(database)
+	downloadOutcomeRepo := db.NewDownloadOutcomeRepository(database)
+	retentionRepo := db.NewRetentionRepository(database)
 
 	// Initialize services
 	authService := auth.NewService(userRepo, tokenRepo, cfg.JWTSecret)
+	authService.SetAPIKeys(apiKeyRepo)
+	authService.SetAdminEmails(cfg.AdminEmails)
+	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
+		authService.EnableOIDC(auth.OIDCConfig{
+			IssuerURL:      cfg.OIDCIssuerURL,
+			ClientID:       cfg.OIDCClientID,
+			ClientSecret:   cfg.OIDCClientSecret,
+			RedirectURL:    cfg.OIDCRedirectURL,
+			AppRedirectURL: cfg.OIDCAppRedirectURL,
+			Scopes:         cfg.OIDCScopes,
+			AutoRegister:   cfg.OIDCAutoRegister,
+		}, db.NewOIDCRepository(database))
+		log.Info(ctx, "Enabled OIDC single sign-on", map[string]interface{}{
+			"issuer":        cfg.OIDCIssuerURL,
+			"auto_register": cfg.OIDCAutoRegister,
+		})
+	}
 	authHandlers := auth.NewHandlers(authService)
-	searchHandlers := search.NewHandlers(trackRepo)
+	searchHandlers := search.NewHandlersWithPlaylists(trackRepo, playlistRepo)
 	mbClient := musicbrainz.NewClient(redisCache)
+	mbClient.SetObserver(appMetrics)
+	mbClient.SetRateLimit(float64(cfg.MusicBrainzRequestsPerSecond), cfg.MusicBrainzRequestsPerSecond)
 	mbHandlers := musicbrainz.NewHandlers(mbClient)
 	sourceQualityJudge := newSourceQualityJudge(cfg)
 	discoveryService := discovery.NewDefaultServiceWithCatalogAndSourceQualityJudge(mbClient, sourceQualityJudge)
@@ -433,6 +750,44 @@ func main() {
 		log.Error(ctx, "Failed to initialize durable research", nil, err)
 		os.Exit(1)
 	}
+	// Daily Mix playlists are rebuilt at startup and then nightly.
+	var dailyMixGenerator *dailymix.Generator
+	if cfg.DailyMixEnabled {
+		opts := dailymix.DefaultOptions()
+		opts.Mixes = cfg.DailyMixCount
+		dailyMixGenerator = dailymix.NewGenerator(dailyMixRepo, opts, cfg.DailyMixHourUTC)
+		dailyMixGenerator.Start()
+		log.Info(ctx, "Started daily mix generator", map[string]interface{}{
+			"mixes":    opts.Mixes,
+			"hour_utc": cfg.DailyMixHourUTC,
+		})
+	}
+	// Listening goals are always served; nudges run at startup and then daily.
+	goalService := goals.NewService(listeningGoalRepo)
+	goalService.SetTimeZones(userRepo)
+	var goalNudger *goals.Nudger
+	if cfg.GoalNudgesEnabled {
+		goalNudger = goals.NewNudger(goalService, cfg.GoalNudgeHourUTC)
+		goalNudger.Start()
+		log.Info(ctx, "Started listening goal nudges", map[string]interface{}{
+			"hour_utc": cfg.GoalNudgeHourUTC,
+		})
+	}
+	// Retention purges run at startup and then every RetentionPurgeInterval.
+	// The service always exists so admins can edit policies and purge by hand.
+	retentionService := retention.NewService(retentionRepo, map[string]int{
+		retention.PlayHistory:    cfg.RetentionPlayHistoryDays,
+		retention.AuditLogs:      cfg.RetentionAuditLogDays,
+		retention.Notifications:  cfg.RetentionNotificationDays,
+		retention.DeadLetterJobs: cfg.RetentionDeadLetterJobDays,
+	}, cfg.RetentionPurgeInterval)
+	if cfg.RetentionEnabled {
+		retentionService.Start()
+		log.Info(ctx, "Started retention purges", map[string]interface{}{
+			"interval": cfg.RetentionPurgeInterval.String(),
+		})
+	}
+	retentionHandlers := api.NewRetentionHandlers(retentionService, cfg.RetentionEnabled)
 	// Start research worker only when both RESEARCH_ENABLED and RESEARCH_WORKER_ENABLED are true.
 	// This ensures the worker respects the production configuration boundary.
 	if shouldStartResearchWorker(cfg) {
@@ -463,6 +818,10 @@ func main() {
 		Timeout: cfg.AIAssistTimeout,
 	})
 	discoveryHandlers := discovery.NewHandlersWithAssistAndSelectionStore(discoveryService, assistService, sourceSelectionRepo)
+	// One cache of expanded SoundCloud short links serves resolve-url, assist,
+	// and direct downloads.
+	soundCloudShortLinks := validators.NewShortLinkResolver(nil, validators.DefaultShortLinkTTL)
+	discoveryHandlers.SetShortLinks(soundCloudShortLinks)
 	sourceSelectionHandlers := api.NewSourceSelectionHandlers(sourceSelectionRepo)
 	log.Info(ctx, "Initialized discovery assist", map[string]interface{}{
 		"ai_assist_enabled": assistClient != nil,
@@ -473,11 +832,49 @@ func main() {
 		"firecrawl_enabled":   agentToolsHandler != nil && cfg.FirecrawlAPIKey != "",
 	})
 	libraryHandlers := api.NewLibraryHandlers(trackRepo, libraryRepo)
+	beetsExportHandlers := api.NewBeetsExportHandlers(libraryRepo, cfg.BeetsPathPrefix)
 	analysisHandlers := api.NewAnalysisHandlers(analysisRepo, libraryRepo)
 	playlistHandlers := api.NewPlaylistHandlers(playlistRepo, trackRepo)
 	mixPlanHandlers := api.NewMixPlanHandlers(mixPlanRepo)
 	playlistMixHandlers := api.NewPlaylistMixHandlers(playlistRepo, mixPlanRepo, cfg.EnablePlaylistMix)
-	playEventHandlers := api.NewPlayEventHandlers(playEventRepo, trackRepo)
+	// Completed plays are forwarded to connected scrobbling services through a
+	// Redis retry queue, so scrobbling is only available with Redis.
+	var scrobbleService *scrobbler.Service
+	var scrobbleHandlers *api.ScrobbleHandlers
+	if redisCache != nil {
+		scrobbleService = scrobbler.NewService(userRepo, scrobbler.NewRedisQueue(redisCache.Client()), scrobbler.NewListenBrainz(cfg.ListenBrainzAPIURL, nil))
+		scrobbleService.Start()
+		scrobbleHandlers = api.NewScrobbleHandlers(userRepo, scrobbleService)
+	}
+	playEvents := scrobblingPlayEvents{PlayEventRepository: playEventRepo, tracks: trackRepo, scrobbler: scrobbleService}
+	playEventHandlers := api.NewPlayEventHandlers(playEvents, trackRepo)
+	playEventHandlers.SetTimeZones(userRepo)
+	profileHandlers := api.NewProfileHandlers(profileRepo)
+	collaborationHandlers := api.NewPlaylistCollaborationHandlers(playlistRepo)
+	notificationHandlers := api.NewNotificationHandlers(notificationRepo)
+	wrappedHandlers := api.NewWrappedHandlers(wrappedRepo)
+	wrappedHandlers.SetTimeZones(userRepo)
+	goalHandlers := api.NewListeningGoalHandlers(goalService)
+	statsHandlers := api.NewStatsHandlers(db.NewListeningStatsRepository(database))
+	statsHandlers.SetTimeZones(userRepo)
+	recommenderService := recommender.NewService(db.NewRecommendationRepository(database), trackRepo, recommender.DefaultOptions())
+	recommenderService.SetArtistRelations(mbClient)
+	recommendationHandlers := api.NewRecommendationHandlers(recommenderService)
+	libraryImportService := libraryimport.NewService(libraryImportRepo, playlistRepo)
+	libraryImportHandlers := api.NewLibraryImportHandlers(libraryImportService)
+	libraryImportHandlers.SetPlaylistProviders(libraryimport.NewSpotifySource(cfg.SpotifyClientID, cfg.SpotifyClientSecret, nil))
+	playlistFileHandlers := api.NewPlaylistFileImportHandlers(libraryImportService)
+	trackSourceHandlers := api.NewTrackSourceHandlers(trackRepo, libraryRepo, mbClient)
+	trackChapterHandlers := api.NewTrackChapterHandlers(trackRepo, libraryRepo)
+	var lyricsSource lyrics.Source
+	if cfg.LyricsLookupEnabled {
+		lyricsSource = lyrics.NewLRCLIB(cfg.LRCLIBURL, nil)
+	}
+	lyricsHandlers := api.NewLyricsHandlers(trackRepo, libraryRepo, lyrics.NewService(db.NewLyricsRepository(database), lyricsSource))
+	calendarHandlers := api.NewCalendarHandlers(userRepo, mbClient)
+	if redisCache != nil {
+		calendarHandlers.SetCache(redisCache)
+	}
 
 	// Initialize storage client
 	storageClient, err := storage.New(&storage.Config{
@@ -499,15 +896,67 @@ func main() {
 		"bucket":          cfg.MinioBucket,
 	})
 
+	// Uploaded playlist covers, generated track mosaics, and user avatars live
+	// alongside the audio objects and are served through signed URLs the same way.
+	playlistArtwork := artwork.NewService(storageClient, playlistRepo, nil)
+	playlistHandlers.SetArtwork(playlistArtwork)
+	avatars := artwork.NewAvatars(storageClient, userRepo)
+	accountProfileHandlers := api.NewAccountProfileHandlers(userRepo, avatars)
+	downloadSettingsHandlers := api.NewDownloadSettingsHandlers(userRepo, playlistRepo)
+	profileHandlers.SetAvatars(avatars)
+	collaborationHandlers.SetAvatars(avatars)
+	// Release covers are fetched from Cover Art Archive once and served as
+	// cached thumbnails; tracks without a release fall back to the cover
+	// extracted from their audio.
+	releaseCovers := artwork.NewReleaseCovers(storageClient, nil)
+	artworkHandlers := api.NewArtworkHandlers(releaseCovers)
+	artworkHandlers.SetTrackArtwork(trackRepo, libraryRepo, storageClient)
+	trackWaveformHandlers := api.NewTrackWaveformHandlers(trackRepo, libraryRepo, storageClient)
+	// Admins rename artists and albums and upload artwork for them once; the
+	// entity tables carry both into search results.
+	entityRepo := db.NewEntityRepository(database)
+	entityHandlers := api.NewEntityHandlers(entityRepo, artwork.NewEntities(storageClient, entityRepo))
+
 	// Initialize playback URL handlers. Normal audio bytes are served by object
 	// storage/CDN through short-lived signed URLs; the backend does not register a
 	// byte-proxy streaming route in the normal playback path.
 	playbackHandlers := api.NewPlaybackHandlers(trackRepo, libraryRepo, storageClient)
+	playlistExportHandlers := api.NewPlaylistExportHandlers(playlistRepo, trackRepo, storageClient)
+
+	downloadTempDir := cfg.DownloadTempDir
+	if downloadTempDir == "" {
+		downloadTempDir = os.TempDir()
+	} else if err := os.MkdirAll(downloadTempDir, 0o700); err != nil {
+		log.Error(ctx, "Failed to create download temp dir", map[string]interface{}{
+			"path": downloadTempDir,
+		}, err)
+		os.Exit(1)
+	}
+	downloadDiskGuard := download.NewDiskGuard(downloadTempDir, cfg.DownloadMinFreeBytes, appMetrics)
+
+	// Clients asking for another format get a variant transcoded once and
+	// cached in object storage, still served through a signed URL. Scratch
+	// files share the download temp dir, its disk guard, and its sweep.
+	playbackHandlers.SetTranscoder(transcode.NewService(storageClient, nil, cfg.TranscodeWorkers, cfg.TranscodeTimeout,
+		transcode.WithTempDir(downloadTempDir), transcode.WithDiskGuard(downloadDiskGuard)))
+	// Shared playlists hand out signed per-track capabilities backed by
+	// revocable grants; playback checks both before signing a URL.
+	trackGrantRepo := db.NewTrackGrantRepository(database)
+	capabilitySigner := capability.NewSigner(cfg.JWTSecret)
+	playbackHandlers.SetCapabilities(capability.NewChecker(capabilitySigner, trackGrantRepo))
+	playlistHandlers.SetTrackGrants(trackGrantRepo)
+	trackGrantHandlers := api.NewTrackGrantHandlers(trackGrantRepo, playlistRepo, userRepo, capabilitySigner)
+	playlistLinkHandlers := api.NewPlaylistLinkHandlers(trackGrantRepo, playlistRepo, capabilitySigner)
+	playlistLinkHandlers.SetArtwork(playlistArtwork)
+	trackDeletionHandlers := api.NewTrackDeletionHandlers(trackRepo, storageClient)
+	takedownHandlers := api.NewTakedownHandlers(takedownRepo)
+	downloadOutcomeHandlers := api.NewDownloadOutcomeHandlers(downloadOutcomeRepo)
 
 	// Initialize WebSocket hub and handler
 	wsHub := websocket.NewHub()
 	go wsHub.Run()
 	wsHandler := websocket.NewHandler(wsHub, authService)
+	playlistHandlers.SetNotifier(playlistChangeNotifier{tracker: websocket.NewProgressTracker(wsHub)})
 
 	// Initialize matcher service. The Ollama disambiguator is optional and only
 	// selects among MusicBrainz candidates; unavailable local providers fall back
@@ -519,6 +968,9 @@ func main() {
 		Timeout: cfg.MetadataLLMTimeout,
 	})
 	matcherService := matcher.NewMatcherWithDisambiguator(mbClient, metadataDisambiguator)
+	matcherService.SetObserver(appMetrics)
+	matcherService.SetExplanationStore(db.NewMatchExplanationRepository(database))
+	matcherService.SetGenreStore(trackRepo)
 	log.Info(ctx, "Initialized metadata disambiguator", map[string]interface{}{
 		"metadata_llm_enabled": metadataDisambiguator != nil,
 		"metadata_llm_model":   cfg.MetadataLLMModel,
@@ -546,6 +998,26 @@ func main() {
 	})
 
 	// Initialize job processor with matching integration
+	var downloadWebhook *processor.Webhook
+	if cfg.DownloadWebhookURL != "" {
+		downloadWebhook = processor.NewWebhook(cfg.DownloadWebhookURL, cfg.DownloadWebhookSecret, nil)
+	}
+	log.Info(ctx, "Configured library manager hand-off", map[string]interface{}{
+		"webhook_enabled": downloadWebhook != nil,
+		"export_dir":      cfg.ExportDir,
+		"tag_stored":      cfg.TagStoredAudio,
+	})
+	var audioFingerprinter processor.Fingerprinter
+	if cfg.AcoustIDAPIKey != "" {
+		audioFingerprinter = fingerprint.NewIdentifier(fingerprint.NewFpcalc(), fingerprint.NewAcoustID(cfg.AcoustIDAPIKey, nil))
+	}
+	log.Info(ctx, "Configured audio fingerprinting", map[string]interface{}{
+		"enabled": audioFingerprinter != nil,
+	})
+	var chapterSource processor.ChapterSource
+	if cfg.SponsorBlockEnabled {
+		chapterSource = chapters.NewSponsorBlock(cfg.SponsorBlockURL, nil)
+	}
 	jobProcessor := processor.New(&processor.ProcessorConfig{
 		Matcher:                 matcherService,
 		TrackRepo:               trackRepo,
@@ -559,7 +1031,47 @@ func main() {
 		AnalysisConcurrency:     cfg.AnalyzerConcurrency,
 		RequireAnalyzerIdentity: serviceAnalyzerClient != nil,
 		Storage:                 storageClient,
+		Takedowns:               takedownRepo,
+		DownloadSettings:        userRepo,
+		Webhook:                 downloadWebhook,
+		ExportDir:               cfg.ExportDir,
+		Fingerprinter:           audioFingerprinter,
+		ChapterSource:           chapterSource,
+		TrackArtwork:            trackRepo,
+		TrackWaveforms:          trackRepo,
+		TagAudio:                cfg.TagStoredAudio,
+		ReleaseCovers:           releaseCovers,
+		TempDir:                 downloadTempDir,
+	})
+	batchMatcher := processor.NewBatchMatcher(trackRepo, jobProcessor, processor.DefaultBatchMatchInterval)
+	batchMatcher.SetReporter(batchMatchProgressNotifier{tracker: websocket.NewProgressTracker(wsHub)}.report)
+	batchMatchHandlers := api.NewBatchMatchHandlers(batchMatcher)
+	// Tracks stored before artwork was cached get covers from an admin-started
+	// backfill instead of waiting for a re-download.
+	artworkBackfill := processor.NewArtworkBackfill(trackRepo, releaseCovers, nil, storageClient)
+	artworkBackfill.SetReporter(artworkBackfillProgressNotifier{tracker: websocket.NewProgressTracker(wsHub)}.report)
+	artworkBackfillHandlers := api.NewArtworkBackfillHandlers(artworkBackfill)
+	// Library ZIP exports stream into object storage and are downloaded
+	// through signed URLs like the audio they contain.
+	libraryExporter := processor.NewLibraryExporter(libraryRepo, trackRepo, storageClient, nil, downloadTempDir)
+	libraryExporter.SetReporter(libraryExportProgressNotifier{tracker: websocket.NewProgressTracker(wsHub)}.report)
+	libraryExportHandlers := api.NewLibraryExportHandlers(libraryExporter, storageClient)
+	// Telemetry is opt-in; the reporter always exists so admins can preview
+	// what enabling it would send.
+	telemetryReporter := telemetry.NewReporter(telemetry.Config{
+		Enabled:  cfg.TelemetryEnabled,
+		Endpoint: cfg.TelemetryURL,
+		Interval: cfg.TelemetryInterval,
+		Version:  version,
+		Features: telemetryFeatures(cfg),
+		Users:    userRepo,
+		Tracks:   trackRepo,
 	})
+	if cfg.TelemetryEnabled && cfg.TelemetryURL == "" {
+		log.Warn(ctx, "TELEMETRY_ENABLED is set but TELEMETRY_URL is empty; no reports will be sent", nil)
+	}
+	telemetryReporter.Start()
+	telemetryHandlers := api.NewTelemetryHandlers(telemetryReporter)
 	stopAnalyzerMaintenance := func() {}
 	if serviceAnalyzerClient != nil {
 		maintenanceCtx, maintenanceCancel := context.WithCancel(context.Background())
@@ -596,19 +1108,41 @@ func main() {
 		}()
 	}
 	maintenanceHandlers := api.NewMaintenanceHandlers(trackRepo, jobProcessor)
+	tempSweepCtx, stopTempSweep := context.WithCancel(context.Background())
+	go sweepDownloadTempFiles(tempSweepCtx, downloadTempDir, cfg.DownloadTempMaxAge)
+	metadataMetricsCtx, stopMetadataMetrics := context.WithCancel(context.Background())
+	go refreshUnverifiedTrackGauge(metadataMetricsCtx, trackRepo, appMetrics)
+	queuePositionCtx, stopQueuePositions := context.WithCancel(context.Background())
 
 	// Initialize Redis-backed download and playback queue services only when enabled.
 	var downloadService *download.Service
 	var downloadHandlers *api.DownloadHandlers
 	var queueHandlers *queue.Handlers
 	var playlistImportHandlers *api.PlaylistImportHandlers
+	var downloadLimitHandlers *api.DownloadLimitHandlers
+	var uploadHandlers *api.UploadHandlers
+	var libraryScan *processor.LibraryScan
+	var libraryScanHandlers *api.LibraryScanHandlers
+	var playbackTransferHandlers *api.PlaybackTransferHandlers
 
 	if cfg.RedisEnabled {
 		sourceSelectionLifecycle := db.NewSourceSelectionDownloadLifecycle(database)
 		sourceSelectionIngestion := db.NewSourceSelectionIngestion(database, sourceSelectionRepo)
+		downloadSchedule := download.ScheduleSettings{MaxBytesPerSecond: cfg.DownloadMaxBytesPerSecond}
+		if cfg.DownloadWindow != "" {
+			if window, err := download.ParseDownloadWindow(cfg.DownloadWindow); err != nil {
+				log.Warn(ctx, "Ignoring DOWNLOAD_WINDOW; downloads may start at any time", map[string]interface{}{"window": cfg.DownloadWindow, "error": err.Error()})
+			} else {
+				downloadSchedule.Window = &window
+			}
+		}
 		downloadService, err = download.NewService(&download.ServiceConfig{
-			RedisURL:    cfg.RedisURL,
-			WorkerCount: cfg.WorkerCount,
+			RedisURL:       cfg.RedisURL,
+			WorkerCount:    cfg.WorkerCount,
+			Outcomes:       downloadOutcomeRecorder{metrics: appMetrics, store: downloadOutcomeRepo},
+			DiskGuard:      downloadDiskGuard,
+			ProviderLimits: cfg.DownloadProviderLimits,
+			Schedule:       downloadSchedule,
 		}, jobProcessor.Process, sourceSelectionLifecycle)
 		if err != nil {
 			log.Error(ctx, "Failed to initialize download service", nil, err)
@@ -620,6 +1154,10 @@ func main() {
 			os.Exit(1)
 		}
 		defer queueService.Close()
+		queueService.SetSnapshots(db.NewQueueSnapshotRepository(database))
+		searchHandlers.SetQueue(queueService)
+		trackDeletionHandlers.SetQueue(queueService)
+		jobProcessor.SetPlaybackQueue(processorPlaybackQueue{service: queueService})
 		// Recovery happens before workers start. It restores only durable,
 		// nonterminal source-decision jobs from their persisted snapshots and is
 		// idempotent when Redis already contains the same job ID.
@@ -633,7 +1171,24 @@ func main() {
 			"workers": cfg.WorkerCount,
 		})
 		downloadHandlers = api.NewDownloadHandlers(downloadService, sourceSelectionIngestion)
+		downloadHandlers.SetTakedowns(takedownRepo)
+		downloadHandlers.SetQueuePositions(downloadService)
+		downloadHandlers.SetShortLinks(validators.DefaultRegistryWithShortLinks(soundCloudShortLinks))
+		downloadHandlers.SetDestinationPlaylists(playlistRepo)
 		ytdlpEnumerator := playlistimport.NewYTDLPEnumerator()
+		downloadHandlers.SetPlaylistEnumerator(ytdlpEnumerator)
+		downloadHandlers.SetReleaseSources(mbClient, discoveryService)
+		uploadHandlers = api.NewUploadHandlers(db.NewUploadRepository(database), storageClient, downloadService, cfg.UploadMaxBytes, cfg.UploadURLTTL)
+		if cfg.LibraryScanDir != "" {
+			libraryScan = processor.NewLibraryScan(cfg.LibraryScanDir, trackRepo, libraryRepo, storageClient, downloadService, nil)
+			libraryScan.SetReporter(libraryScanProgressNotifier{tracker: websocket.NewProgressTracker(wsHub)}.report)
+			libraryScanHandlers = api.NewLibraryScanHandlers(libraryScan, userRepo)
+		}
+		queuePositionNotifier := downloadQueuePositionNotifier{tracker: websocket.NewProgressTracker(wsHub)}
+		go download.NewPositionWatcher(downloadService, queuePositionNotifier, downloadQueuePositionInterval).Run(queuePositionCtx)
+		go downloadService.RelayProgress(queuePositionCtx, downloadProgressNotifier{tracker: websocket.NewProgressTracker(wsHub)})
+		downloadLimitHandlers = api.NewDownloadLimitHandlers(downloadService.ProviderLimits())
+		downloadLimitHandlers.SetSchedule(downloadService.Schedule())
 		playlistImportService := playlistimport.NewService(playlistimport.Config{
 			Store:          playlistImportRepo,
 			Playlists:      playlistRepo,
@@ -649,6 +1204,17 @@ func main() {
 		playlistImportHandlers = api.NewPlaylistImportHandlers(playlistImportService)
 
 		queueHandlers = queue.NewHandlersWithSourceSelections(queueService, downloadService, analysisRepo, sourceSelectionRepo, database)
+		queueHandlers.SetPlays(playEvents)
+		queueHandlers.SetShuffleSource(playEvents)
+		queueHandlers.SetEntitySources(libraryRepo, mbClient)
+		queueHandlers.SetRadio(recommenderService, libraryRepo)
+		queueHandlers.SetPlaybackStates(queueService)
+		queueHandlers.SetNamedQueues(queueService)
+		queueHandlers.SetTrackLookup(trackRepo)
+		queueHandlers.SetQueueNotifier(queueChangeNotifier{tracker: websocket.NewProgressTracker(wsHub)})
+
+		playbackTransferHandlers = api.NewPlaybackTransferHandlers(queueService, wsHub)
+		wsHub.SetMessageHandler(playbackTransferHandlers.HandleDeviceMessage)
 	}
 
 	var redisClient *redis.Client
@@ -665,36 +1231,74 @@ func main() {
 		},
 		Version: version,
 		Timeout: 5 * time.Second,
+		DiskCheck: func(ctx context.Context) error {
+			return downloadDiskGuard.Check()
+		},
 	})
 	healthHandler := health.NewHandler(healthChecker)
 
 	// Create router with all handlers
 	router := api.NewRouterWithConfig(&api.RouterConfig{
-		AuthHandlers:            authHandlers,
-		AuthService:             authService,
-		SearchHandlers:          searchHandlers,
-		MBClient:                mbClient,
-		MBHandlers:              mbHandlers,
-		WSHandler:               wsHandler,
-		MatcherHandlers:         matcherHandlers,
-		LibraryHandlers:         libraryHandlers,
-		AnalysisHandlers:        analysisHandlers,
-		PlaybackHandlers:        playbackHandlers,
-		QueueHandlers:           queueHandlers,
-		DiscoveryHandlers:       discoveryHandlers,
-		AgentToolsHandler:       agentToolsHandler,
-		PlaylistHandlers:        playlistHandlers,
-		PlaylistImportHandlers:  playlistImportHandlers,
-		PlaylistMixHandlers:     playlistMixHandlers,
-		MixPlanHandlers:         mixPlanHandlers,
-		DownloadHandlers:        downloadHandlers,
-		SourceSelectionHandlers: sourceSelectionHandlers,
-		MaintenanceHandlers:     maintenanceHandlers,
-		PlayEventHandlers:       playEventHandlers,
-		ResearchHandlers:        researchRuntime.handlers,
-		HealthHandler:           healthHandler,
-		Metrics:                 appMetrics,
-		CORSAllowedOrigins:      cfg.CORSAllowedOrigins,
+		AuthHandlers:             authHandlers,
+		AuthService:              authService,
+		SearchHandlers:           searchHandlers,
+		MBClient:                 mbClient,
+		MBHandlers:               mbHandlers,
+		WSHandler:                wsHandler,
+		MatcherHandlers:          matcherHandlers,
+		LibraryHandlers:          libraryHandlers,
+		AnalysisHandlers:         analysisHandlers,
+		PlaybackHandlers:         playbackHandlers,
+		QueueHandlers:            queueHandlers,
+		DiscoveryHandlers:        discoveryHandlers,
+		AgentToolsHandler:        agentToolsHandler,
+		PlaylistHandlers:         playlistHandlers,
+		PlaylistImportHandlers:   playlistImportHandlers,
+		PlaylistMixHandlers:      playlistMixHandlers,
+		PlaylistExportHandlers:   playlistExportHandlers,
+		MixPlanHandlers:          mixPlanHandlers,
+		DownloadHandlers:         downloadHandlers,
+		SourceSelectionHandlers:  sourceSelectionHandlers,
+		MaintenanceHandlers:      maintenanceHandlers,
+		PlayEventHandlers:        playEventHandlers,
+		ResearchHandlers:         researchRuntime.handlers,
+		ProfileHandlers:          profileHandlers,
+		AccountProfileHandlers:   accountProfileHandlers,
+		CollaborationHandlers:    collaborationHandlers,
+		NotificationHandlers:     notificationHandlers,
+		WrappedHandlers:          wrappedHandlers,
+		GoalHandlers:             goalHandlers,
+		StatsHandlers:            statsHandlers,
+		RecommendationHandlers:   recommendationHandlers,
+		LibraryImportHandlers:    libraryImportHandlers,
+		PlaylistFileHandlers:     playlistFileHandlers,
+		TrackSourceHandlers:      trackSourceHandlers,
+		TrackChapterHandlers:     trackChapterHandlers,
+		TrackWaveformHandlers:    trackWaveformHandlers,
+		LyricsHandlers:           lyricsHandlers,
+		EntityHandlers:           entityHandlers,
+		TrackDeletionHandlers:    trackDeletionHandlers,
+		TakedownHandlers:         takedownHandlers,
+		DownloadOutcomeHandlers:  downloadOutcomeHandlers,
+		DownloadLimitHandlers:    downloadLimitHandlers,
+		DownloadSettingsHandlers: downloadSettingsHandlers,
+		ScrobbleHandlers:         scrobbleHandlers,
+		UploadHandlers:           uploadHandlers,
+		LibraryScanHandlers:      libraryScanHandlers,
+		TrackGrantHandlers:       trackGrantHandlers,
+		CalendarHandlers:         calendarHandlers,
+		PlaylistLinkHandlers:     playlistLinkHandlers,
+		PlaybackTransferHandlers: playbackTransferHandlers,
+		BatchMatchHandlers:       batchMatchHandlers,
+		ArtworkBackfillHandlers:  artworkBackfillHandlers,
+		BeetsExportHandlers:      beetsExportHandlers,
+		LibraryExportHandlers:    libraryExportHandlers,
+		TelemetryHandlers:        telemetryHandlers,
+		RetentionHandlers:        retentionHandlers,
+		ArtworkHandlers:          artworkHandlers,
+		HealthHandler:            healthHandler,
+		Metrics:                  appMetrics,
+		CORSAllowedOrigins:       cfg.CORSAllowedOrigins,
 	})
 
 	// Apply middleware chain
@@ -710,9 +1314,50 @@ func main() {
 	// Note: ETag is after gzip so it calculates hash on compressed content
 	handler = middleware.Timing(middleware.Gzip(middleware.ETag(handler)))
 
+	// Requests through the relay take the client address the relay forwards.
+	handler = relay.ClientAddr(handler)
+
 	server := &http.Server{
-		Addr:    cfg.ServerAddr,
-		Handler: handler,
+		Addr:        cfg.ServerAddr,
+		Handler:     handler,
+		ConnContext: relay.ConnContext,
+	}
+
+	// Relay remote access: serve the same handler through tunnels to the
+	// relay. server.Shutdown closes the API listener; the media one is
+	// closed below.
+	var relayMediaListener *relay.Listener
+	if cfg.RelayURL != "" {
+		relayListener, err := relay.Listen(cfg.RelayURL, cfg.RelayToken, cfg.RelayConnections)
+		if err != nil {
+			log.Error(ctx, "Failed to start relay tunnels", nil, err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := server.Serve(relayListener); err != nil && err != http.ErrServerClosed {
+				log.Error(ctx, "Relay listener stopped", nil, err)
+			}
+		}()
+		if cfg.RelayMediaToken != "" {
+			if cfg.MinioUseSSL {
+				log.Warn(ctx, "Relay media tunnels forward plain HTTP; MINIO_USE_SSL endpoints will not work through the relay", nil)
+			}
+			relayMediaListener, err = relay.Listen(cfg.RelayURL, cfg.RelayMediaToken, cfg.RelayConnections)
+			if err != nil {
+				log.Error(ctx, "Failed to start relay media tunnels", nil, err)
+				os.Exit(1)
+			}
+			go func() {
+				if err := relay.Forward(relayMediaListener, cfg.MinioEndpoint); err != nil {
+					log.Error(ctx, "Relay media forwarding stopped", nil, err)
+				}
+			}()
+		}
+		log.Info(ctx, "Relay remote access enabled", map[string]interface{}{
+			"relay":       cfg.RelayURL,
+			"connections": cfg.RelayConnections,
+			"media":       relayMediaListener != nil,
+		})
 	}
 
 	// Graceful shutdown handling
@@ -727,6 +1372,9 @@ func main() {
 			"signal": sig.String(),
 		})
 		stopAnalyzerMaintenance()
+		stopMetadataMetrics()
+		stopTempSweep()
+		stopQueuePositions()
 
 		// Stop accepting new requests
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -736,6 +1384,44 @@ func main() {
 			log.Error(ctx, "HTTP server shutdown error", nil, err)
 			_ = server.Close()
 		}
+		if relayMediaListener != nil {
+			_ = relayMediaListener.Close()
+		}
+		if err := batchMatcher.Stop(shutdownCtx); err != nil {
+			log.Error(ctx, "Batch matcher shutdown error", nil, err)
+		}
+		if err := artworkBackfill.Stop(shutdownCtx); err != nil {
+			log.Error(ctx, "Artwork backfill shutdown error", nil, err)
+		}
+		if libraryScan != nil {
+			if err := libraryScan.Stop(shutdownCtx); err != nil {
+				log.Error(ctx, "Library scan shutdown error", nil, err)
+			}
+		}
+		if err := libraryExporter.Stop(shutdownCtx); err != nil {
+			log.Error(ctx, "Library exporter shutdown error", nil, err)
+		}
+		if err := telemetryReporter.Stop(shutdownCtx); err != nil {
+			log.Error(ctx, "Telemetry reporter shutdown error", nil, err)
+		}
+		if err := retentionService.Stop(shutdownCtx); err != nil {
+			log.Error(ctx, "Retention service shutdown error", nil, err)
+		}
+		if dailyMixGenerator != nil {
+			if err := dailyMixGenerator.Stop(shutdownCtx); err != nil {
+				log.Error(ctx, "Daily mix generator shutdown error", nil, err)
+			}
+		}
+		if goalNudger != nil {
+			if err := goalNudger.Stop(shutdownCtx); err != nil {
+				log.Error(ctx, "Goal nudger shutdown error", nil, err)
+			}
+		}
+		if scrobbleService != nil {
+			if err := scrobbleService.Stop(shutdownCtx); err != nil {
+				log.Error(ctx, "Scrobbler shutdown error", nil, err)
+			}
+		}
 		if researchRuntime.worker != nil {
 			researchShutdownCtx, researchShutdownCancel := context.WithTimeout(shutdownCtx, cfg.ResearchShutdownTimeout)
 			if err := researchRuntime.worker.Stop(researchShutdownCtx); err != nil {
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -35,6 +37,19 @@ func TestNewSourceQualityJudgeEnabledConfigConstructsOllamaJudge(t *testing.T) {
 	}
 }
 
+func TestTelemetryFeaturesNamesOnlyEnabledFeatures(t *testing.T) {
+	features := telemetryFeatures(&config.Config{
+		RedisEnabled:       true,
+		WorkerCount:        0,
+		TranscodeWorkers:   2,
+		DownloadWebhookURL: "https://hooks.example.test/secret",
+	})
+	sort.Strings(features)
+	if strings.Join(features, ",") != "download_webhook,transcode" {
+		t.Fatalf("features = %v", features)
+	}
+}
+
 func TestNewAgentToolsHandlerRequiresServiceToken(t *testing.T) {
 	search := discovery.NewService(discovery.ServiceConfig{})
 	if handler := newAgentToolsHandler(&config.Config{FirecrawlAPIKey: "configured"}, search); handler != nil {
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxAvatarUploadBytes = 5 << 20
+)
+
+type accountProfileStore interface {
+	GetAccountProfile(ctx context.Context, userID uuid.UUID) (*db.AccountProfile, error)
+	UpdateAccountProfile(ctx context.Context, profile *db.AccountProfile) error
+}
+
+// AvatarURLs presigns avatar object keys for responses that show a user.
+type AvatarURLs interface {
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// AvatarStorage stores resized user avatars.
+type AvatarStorage interface {
+	AvatarURLs
+	Upload(ctx context.Context, userID uuid.UUID, r io.Reader) error
+	Remove(ctx context.Context, userID uuid.UUID) error
+}
+
+// AccountProfileHandlers serves the caller's display name, bio, and avatar.
+// These are shown wherever the user is attributed, whether or not they have a
+// public profile.
+type AccountProfileHandlers struct {
+	users   accountProfileStore
+	avatars AvatarStorage
+}
+
+// NewAccountProfileHandlers creates the handlers. A nil avatars disables avatar
+// uploads; the text fields still work.
+func NewAccountProfileHandlers(users accountProfileStore, avatars AvatarStorage) *AccountProfileHandlers {
+	return &AccountProfileHandlers{users: users, avatars: avatars}
+}
+
+// PatchAccountProfileRequest updates only the fields present; an empty string
+// clears a field.
+type PatchAccountProfileRequest struct {
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+	TimeZone    *string `json:"timeZone"`
+}
+
+type AccountProfileResponse struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	TimeZone    string    `json:"timeZone"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// GetProfile handles GET /api/v1/profile.
+func (h *AccountProfileHandlers) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	h.writeProfile(w, r, userCtx.UserID)
+}
+
+// PatchProfile handles PATCH /api/v1/profile.
+func (h *AccountProfileHandlers) PatchProfile(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	var req PatchAccountProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	profile, err := h.users.GetAccountProfile(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeAccountProfileLoadError(w, err)
+		return
+	}
+
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "displayName must be at most 64 characters without control characters")
+			return
+		}
+		profile.DisplayName = sql.NullString{String: name, Valid: name != ""}
+	}
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "bio must be at most 500 characters")
+			return
+		}
+		profile.Bio = sql.NullString{String: bio, Valid: bio != ""}
+	}
+	if req.TimeZone != nil {
+		zone := strings.TrimSpace(*req.TimeZone)
+		if zone != "" && !validTimeZone(zone) {
+			writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "timeZone must be an IANA time zone name such as Europe/Berlin")
+			return
+		}
+		profile.TimeZone = sql.NullString{String: zone, Valid: zone != ""}
+	}
+
+	if err := h.users.UpdateAccountProfile(r.Context(), profile); err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+		writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to save profile")
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, h.newAccountProfileResponse(r.Context(), profile))
+}
+
+// UploadAvatar handles PUT /api/v1/profile/avatar. The body is the raw JPEG or
+// PNG image.
+func (h *AccountProfileHandlers) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	if h.avatars == nil {
+		writeProfileError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "avatar uploads are not configured")
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "image/jpeg" && mediaType != "image/png" {
+		writeProfileError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "avatar must be image/jpeg or image/png")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxAvatarUploadBytes)
+	if err := h.avatars.Upload(r.Context(), userCtx.UserID, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeProfileError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "avatar must be at most 5 MB")
+		case errors.Is(err, artwork.ErrUnsupportedImage):
+			writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "avatar is not a valid JPEG or PNG image")
+		case errors.Is(err, artwork.ErrImageTooLarge):
+			writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "avatar dimensions are too large")
+		case errors.Is(err, db.ErrUserNotFound):
+			writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		default:
+			log.Printf("Error: failed to store avatar for user %s: %v", userCtx.UserID, err)
+			writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to store avatar")
+		}
+		return
+	}
+	h.writeProfile(w, r, userCtx.UserID)
+}
+
+// DeleteAvatar handles DELETE /api/v1/profile/avatar.
+func (h *AccountProfileHandlers) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	if h.avatars == nil {
+		writeProfileError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "avatar uploads are not configured")
+		return
+	}
+	if err := h.avatars.Remove(r.Context(), userCtx.UserID); err != nil {
+		writeAccountProfileLoadError(w, err)
+		return
+	}
+	h.writeProfile(w, r, userCtx.UserID)
+}
+
+func (h *AccountProfileHandlers) writeProfile(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	profile, err := h.users.GetAccountProfile(r.Context(), userID)
+	if err != nil {
+		writeAccountProfileLoadError(w, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, h.newAccountProfileResponse(r.Context(), profile))
+}
+
+func (h *AccountProfileHandlers) newAccountProfileResponse(ctx context.Context, p *db.AccountProfile) AccountProfileResponse {
+	resp := AccountProfileResponse{
+		Username:  p.Username,
+		AvatarURL: avatarURL(ctx, h.avatars, p.AvatarKey),
+		TimeZone:  "UTC",
+		UpdatedAt: p.UpdatedAt,
+	}
+	if p.DisplayName.Valid {
+		resp.DisplayName = p.DisplayName.String
+	}
+	if p.Bio.Valid {
+		resp.Bio = p.Bio.String
+	}
+	if p.TimeZone.Valid {
+		resp.TimeZone = p.TimeZone.String
+	}
+	return resp
+}
+
+func writeAccountProfileLoadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrUserNotFound) {
+		writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		return
+	}
+	writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load profile")
+}
+
+// avatarURL presigns an avatar key, returning "" when there is no avatar or it
+// cannot be signed; a missing picture should never fail the response.
+func avatarURL(ctx context.Context, urls AvatarURLs, key sql.NullString) string {
+	if urls == nil || !key.Valid {
+		return ""
+	}
+	url, err := urls.URL(ctx, key.String)
+	if err != nil {
+		return ""
+	}
+	return url
+}
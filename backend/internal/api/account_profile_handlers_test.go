@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeAccountProfileStore struct {
+	profiles map[uuid.UUID]*db.AccountProfile
+}
+
+func (f *fakeAccountProfileStore) GetAccountProfile(ctx context.Context, userID uuid.UUID) (*db.AccountProfile, error) {
+	p, ok := f.profiles[userID]
+	if !ok {
+		return nil, db.ErrUserNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (f *fakeAccountProfileStore) UpdateAccountProfile(ctx context.Context, profile *db.AccountProfile) error {
+	copied := *profile
+	f.profiles[profile.UserID] = &copied
+	return nil
+}
+
+type fakeAvatarStorage struct {
+	store    *fakeAccountProfileStore
+	uploaded []byte
+	err      error
+}
+
+func (f *fakeAvatarStorage) URL(ctx context.Context, key string) (string, error) {
+	return "https://cdn.test/" + key, nil
+}
+
+func (f *fakeAvatarStorage) Upload(ctx context.Context, userID uuid.UUID, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.uploaded = data
+	f.store.profiles[userID].AvatarKey = sql.NullString{String: "avatars/" + userID.String() + "/avatar.jpg", Valid: true}
+	return nil
+}
+
+func (f *fakeAvatarStorage) Remove(ctx context.Context, userID uuid.UUID) error {
+	f.store.profiles[userID].AvatarKey = sql.NullString{}
+	return nil
+}
+
+func newAccountProfileFixture() (*AccountProfileHandlers, *fakeAccountProfileStore, *fakeAvatarStorage, uuid.UUID) {
+	userID := uuid.New()
+	store := &fakeAccountProfileStore{profiles: map[uuid.UUID]*db.AccountProfile{
+		userID: {UserID: userID, Username: "dj", Bio: sql.NullString{String: "old bio", Valid: true}},
+	}}
+	avatars := &fakeAvatarStorage{store: store}
+	return NewAccountProfileHandlers(store, avatars), store, avatars, userID
+}
+
+func TestPatchProfileUpdatesOnlyProvidedFields(t *testing.T) {
+	h, store, _, userID := newAccountProfileFixture()
+
+	req := withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/profile", strings.NewReader(`{"displayName":"  DJ Shadow "}`)), userID)
+	rr := httptest.NewRecorder()
+	h.PatchProfile(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp AccountProfileResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.DisplayName != "DJ Shadow" || resp.Bio != "old bio" {
+		t.Fatalf("response = %+v, want trimmed name and untouched bio", resp)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/profile", strings.NewReader(`{"bio":""}`)), userID)
+	rr = httptest.NewRecorder()
+	h.PatchProfile(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	saved := store.profiles[userID]
+	if saved.Bio.Valid || saved.DisplayName.String != "DJ Shadow" {
+		t.Fatalf("saved = %+v, want bio cleared and display name kept", saved)
+	}
+}
+
+func TestPatchProfileSetsAndClearsTimeZone(t *testing.T) {
+	h, store, _, userID := newAccountProfileFixture()
+
+	req := withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/profile", strings.NewReader(`{"timeZone":"Europe/Berlin"}`)), userID)
+	rr := httptest.NewRecorder()
+	h.PatchProfile(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	if saved := store.profiles[userID].TimeZone; saved.String != "Europe/Berlin" {
+		t.Fatalf("saved time zone = %+v, want Europe/Berlin", saved)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/profile", strings.NewReader(`{"timeZone":""}`)), userID)
+	rr = httptest.NewRecorder()
+	h.PatchProfile(rr, req)
+	var resp AccountProfileResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TimeZone != "UTC" || store.profiles[userID].TimeZone.Valid {
+		t.Fatalf("timeZone = %q, want cleared back to UTC", resp.TimeZone)
+	}
+}
+
+func TestPatchProfileValidatesLengths(t *testing.T) {
+	h, _, _, userID := newAccountProfileFixture()
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"invalid body", `{`},
+		{"long name", `{"displayName":"` + strings.Repeat("a", 65) + `"}`},
+		{"control characters", `{"displayName":"dj\u0007"}`},
+		{"long bio", `{"bio":"` + strings.Repeat("é", 501) + `"}`},
+		{"unknown time zone", `{"timeZone":"Mars/Olympus_Mons"}`},
+		{"server local time zone", `{"timeZone":"Local"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/profile", strings.NewReader(tc.body)), userID)
+			rr := httptest.NewRecorder()
+			h.PatchProfile(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body=%s)", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUploadAvatarChecksContentTypeAndReturnsURL(t *testing.T) {
+	h, _, avatars, userID := newAccountProfileFixture()
+
+	req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/profile/avatar", strings.NewReader("GIF89a")), userID)
+	req.Header.Set("Content-Type", "image/gif")
+	rr := httptest.NewRecorder()
+	h.UploadAvatar(rr, req)
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status = %d, want 415", rr.Code)
+	}
+
+	avatars.err = artwork.ErrUnsupportedImage
+	req = withUser(httptest.NewRequest(http.MethodPut, "/api/v1/profile/avatar", strings.NewReader("not a png")), userID)
+	req.Header.Set("Content-Type", "image/png")
+	rr = httptest.NewRecorder()
+	h.UploadAvatar(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 for an undecodable image", rr.Code)
+	}
+
+	avatars.err = nil
+	req = withUser(httptest.NewRequest(http.MethodPut, "/api/v1/profile/avatar", strings.NewReader("png bytes")), userID)
+	req.Header.Set("Content-Type", "image/png")
+	rr = httptest.NewRecorder()
+	h.UploadAvatar(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp AccountProfileResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(resp.AvatarURL, "https://cdn.test/avatars/") || string(avatars.uploaded) != "png bytes" {
+		t.Fatalf("avatarUrl = %q after uploading %q", resp.AvatarURL, avatars.uploaded)
+	}
+}
+
+func TestUploadAvatarRejectsOversizedBody(t *testing.T) {
+	h, _, _, userID := newAccountProfileFixture()
+	body := strings.NewReader(strings.Repeat("x", maxAvatarUploadBytes+1))
+	req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/profile/avatar", body), userID)
+	req.Header.Set("Content-Type", "image/jpeg")
+	rr := httptest.NewRecorder()
+	h.UploadAvatar(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rr.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+const maxArtworkBackfillLimit = 1000000
+
+type artworkBackfillRunner interface {
+	Start(userID uuid.UUID, limit int) (processor.ArtworkBackfillStatus, error)
+	Status() (processor.ArtworkBackfillStatus, bool)
+	Cancel() bool
+}
+
+// ArtworkBackfillHandlers lets admins give tracks stored before artwork was
+// cached a cover in the background and follow its progress.
+type ArtworkBackfillHandlers struct {
+	runner artworkBackfillRunner
+}
+
+func NewArtworkBackfillHandlers(runner artworkBackfillRunner) *ArtworkBackfillHandlers {
+	return &ArtworkBackfillHandlers{runner: runner}
+}
+
+type StartArtworkBackfillRequest struct {
+	// Limit caps how many tracks without a cover the run visits; 0 visits all.
+	Limit int `json:"limit"`
+}
+
+// StartBackfill handles POST /api/v1/admin/artwork/backfill. Progress is
+// pushed to the caller's WebSocket connections as artwork_backfill_progress
+// messages.
+func (h *ArtworkBackfillHandlers) StartBackfill(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req StartArtworkBackfillRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeArtworkError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Limit < 0 || req.Limit > maxArtworkBackfillLimit {
+		writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 0 and 1000000")
+		return
+	}
+
+	status, err := h.runner.Start(userCtx.UserID, req.Limit)
+	if errors.Is(err, processor.ErrArtworkBackfillRunning) {
+		writeArtworkBackfillJSON(w, http.StatusConflict, map[string]interface{}{
+			"code":    "ARTWORK_BACKFILL_RUNNING",
+			"message": "an artwork backfill is already running",
+			"run":     status,
+		})
+		return
+	}
+	if err != nil {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start artwork backfill")
+		return
+	}
+	writeArtworkBackfillJSON(w, http.StatusAccepted, status)
+}
+
+// GetBackfill handles GET /api/v1/admin/artwork/backfill, returning the
+// current or most recent run.
+func (h *ArtworkBackfillHandlers) GetBackfill(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	status, ok := h.runner.Status()
+	if !ok {
+		writeArtworkError(w, http.StatusNotFound, "ARTWORK_BACKFILL_NOT_FOUND", "no artwork backfill has run")
+		return
+	}
+	writeArtworkBackfillJSON(w, http.StatusOK, status)
+}
+
+// CancelBackfill handles DELETE /api/v1/admin/artwork/backfill. The run
+// stops after the track it is resolving.
+func (h *ArtworkBackfillHandlers) CancelBackfill(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	if !h.runner.Cancel() {
+		writeArtworkError(w, http.StatusConflict, "ARTWORK_BACKFILL_NOT_RUNNING", "no artwork backfill is running")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ArtworkBackfillHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) (*auth.UserContext, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeArtworkError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	if !userCtx.IsAdmin() {
+		writeArtworkError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return nil, false
+	}
+	return userCtx, true
+}
+
+func writeArtworkBackfillJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+type fakeArtworkBackfillRunner struct {
+	status    *processor.ArtworkBackfillStatus
+	lastLimit int
+}
+
+func (f *fakeArtworkBackfillRunner) Start(_ uuid.UUID, limit int) (processor.ArtworkBackfillStatus, error) {
+	if f.status != nil && f.status.State == processor.ArtworkBackfillRunning {
+		return *f.status, processor.ErrArtworkBackfillRunning
+	}
+	f.lastLimit = limit
+	f.status = &processor.ArtworkBackfillStatus{ID: "run-1", State: processor.ArtworkBackfillRunning}
+	return *f.status, nil
+}
+
+func (f *fakeArtworkBackfillRunner) Status() (processor.ArtworkBackfillStatus, bool) {
+	if f.status == nil {
+		return processor.ArtworkBackfillStatus{}, false
+	}
+	return *f.status, true
+}
+
+func (f *fakeArtworkBackfillRunner) Cancel() bool {
+	if f.status == nil || f.status.State != processor.ArtworkBackfillRunning {
+		return false
+	}
+	f.status.State = processor.ArtworkBackfillCanceled
+	return true
+}
+
+func artworkBackfillRequest(method, body, email string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/admin/artwork/backfill", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestArtworkBackfillStartStatusAndCancel(t *testing.T) {
+	runner := &fakeArtworkBackfillRunner{}
+	h := NewArtworkBackfillHandlers(runner)
+
+	rec := httptest.NewRecorder()
+	h.StartBackfill(rec, artworkBackfillRequest(http.MethodPost, `{"limit":500}`, "listener@example.test"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin start = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartBackfill(rec, artworkBackfillRequest(http.MethodPost, `{"limit":500}`, "ops@example.test"))
+	var started processor.ArtworkBackfillStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || rec.Code != http.StatusAccepted || runner.lastLimit != 500 {
+		t.Fatalf("start = %d %s, limit %d", rec.Code, rec.Body.String(), runner.lastLimit)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartBackfill(rec, artworkBackfillRequest(http.MethodPost, "", "ops@example.test"))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"ARTWORK_BACKFILL_RUNNING"`) {
+		t.Fatalf("second start = %d %s, want 409", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelBackfill(rec, artworkBackfillRequest(http.MethodDelete, "", "ops@example.test"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("cancel = %d, want 204", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.GetBackfill(rec, artworkBackfillRequest(http.MethodGet, "", "ops@example.test"))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"state":"canceled"`) {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	// defaultReleaseCoverSize is served when a request names no size.
+	defaultReleaseCoverSize = 500
+	// releaseCoverCacheControl lets clients and proxies keep a cover for a
+	// year; a release's front cover rarely changes.
+	releaseCoverCacheControl = "public, max-age=31536000, immutable"
+	// missingCoverCacheControl matches how long the server remembers a
+	// release without a cover before asking Cover Art Archive again.
+	missingCoverCacheControl = "public, max-age=3600"
+)
+
+// ReleaseCovers serves cached Cover Art Archive front covers.
+type ReleaseCovers interface {
+	Cover(ctx context.Context, releaseID uuid.UUID, size int) ([]byte, error)
+}
+
+// TrackArtworkStore finds a track's release and extracted cover.
+type TrackArtworkStore interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+	GetArtworkKey(ctx context.Context, trackID int64) (string, error)
+}
+
+// ArtworkURLSigner presigns reads of stored artwork.
+type ArtworkURLSigner interface {
+	PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// ArtworkHandlers proxies release cover art so clients do not each fetch it
+// from Cover Art Archive, and resolves a track's cover to either its release
+// cover or the art embedded in its audio.
+type ArtworkHandlers struct {
+	covers  ReleaseCovers
+	tracks  TrackArtworkStore
+	library trackLibraryChecker
+	signer  ArtworkURLSigner
+}
+
+// NewArtworkHandlers creates handlers backed by a release cover cache.
+func NewArtworkHandlers(covers ReleaseCovers) *ArtworkHandlers {
+	return &ArtworkHandlers{covers: covers}
+}
+
+// SetTrackArtwork enables GET /api/v1/tracks/{track_id}/artwork.
+func (h *ArtworkHandlers) SetTrackArtwork(tracks TrackArtworkStore, library trackLibraryChecker, signer ArtworkURLSigner) {
+	h.tracks, h.library, h.signer = tracks, library, signer
+}
+
+// GetReleaseCover handles GET /api/v1/artwork/{release_mbid}?size=250|500|1200,
+// serving the release's front cover as a square JPEG. Covers are public
+// catalog data, so the route needs no auth and responses may be cached by
+// shared proxies.
+func (h *ArtworkHandlers) GetReleaseCover(w http.ResponseWriter, r *http.Request) {
+	releaseID, err := uuid.Parse(r.PathValue("release_mbid"))
+	if err != nil {
+		writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "release_mbid must be a MusicBrainz release ID")
+		return
+	}
+	size, ok := releaseCoverSize(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.covers.Cover(r.Context(), releaseID, size)
+	if err != nil {
+		switch {
+		case errors.Is(err, artwork.ErrCoverNotFound):
+			w.Header().Set("Cache-Control", missingCoverCacheControl)
+			writeArtworkError(w, http.StatusNotFound, "NOT_FOUND", "release has no cover art")
+		case errors.Is(err, context.Canceled):
+		default:
+			log.Printf("Error: failed to load release %s cover: %v", releaseID, err)
+			writeArtworkError(w, http.StatusBadGateway, "REMOTE_UNAVAILABLE", "cover art is unavailable")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", releaseCoverCacheControl)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func writeArtworkError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
+// GetTrackArtwork handles GET /api/v1/tracks/{track_id}/artwork?size=250|500|1200
+// for a track in the caller's library. Tracks on a MusicBrainz release
+// redirect to the release cover; others, and those whose release has no
+// cover, redirect to a signed URL for the cover embedded in their audio or
+// backfilled from their source thumbnail, which is stored at one size.
+func (h *ArtworkHandlers) GetTrackArtwork(w http.ResponseWriter, r *http.Request) {
+	if h.tracks == nil {
+		writeArtworkError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "track artwork is not configured")
+		return
+	}
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeArtworkError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeArtworkError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return
+	}
+	size, ok := releaseCoverSize(w, r)
+	if !ok {
+		return
+	}
+
+	inLibrary, err := h.library.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library membership")
+		return
+	}
+	if !inLibrary {
+		writeArtworkError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	track, err := h.tracks.GetByID(r.Context(), trackID)
+	if errors.Is(err, db.ErrTrackNotFound) {
+		writeArtworkError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	if err != nil {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return
+	}
+
+	key, err := h.tracks.GetArtworkKey(r.Context(), trackID)
+	if err != nil && !errors.Is(err, db.ErrTrackNotFound) {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track artwork")
+		return
+	}
+	if track.MBReleaseID != nil {
+		// A release Cover Art Archive has no cover for falls back to the
+		// track's own cover when it has one.
+		releaseCover := key == ""
+		if !releaseCover {
+			_, err := h.covers.Cover(r.Context(), *track.MBReleaseID, size)
+			releaseCover = !errors.Is(err, artwork.ErrCoverNotFound)
+		}
+		if releaseCover {
+			http.Redirect(w, r, fmt.Sprintf("/api/v1/artwork/%s?size=%d", track.MBReleaseID, size), http.StatusFound)
+			return
+		}
+	}
+	if key == "" {
+		writeArtworkError(w, http.StatusNotFound, "NOT_FOUND", "track has no artwork")
+		return
+	}
+	url, err := h.signer.PresignGetObject(r.Context(), key, artwork.URLTTL)
+	if err != nil {
+		log.Printf("Error: failed to sign track %d artwork URL: %v", trackID, err)
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to sign artwork URL")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// releaseCoverSize reads the optional size query parameter, writing a 400
+// when it is not one of artwork.ReleaseCoverSizes.
+func releaseCoverSize(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("size")
+	if raw == "" {
+		return defaultReleaseCoverSize, true
+	}
+	size, err := strconv.Atoi(raw)
+	if err != nil || !artwork.IsReleaseCoverSize(size) {
+		writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "size must be 250, 500, or 1200")
+		return 0, false
+	}
+	return size, true
+}
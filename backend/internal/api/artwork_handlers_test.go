@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeReleaseCovers struct {
+	releaseID uuid.UUID
+	size      int
+	err       error
+}
+
+func (f *fakeReleaseCovers) Cover(_ context.Context, releaseID uuid.UUID, size int) ([]byte, error) {
+	f.releaseID, f.size = releaseID, size
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte("jpeg"), nil
+}
+
+func releaseCoverRequest(id, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/artwork/"+id+query, nil)
+	req.SetPathValue("release_mbid", id)
+	return req
+}
+
+func TestGetReleaseCoverServesCachedJPEG(t *testing.T) {
+	covers := &fakeReleaseCovers{}
+	h := NewArtworkHandlers(covers)
+	releaseID := uuid.New()
+
+	rec := httptest.NewRecorder()
+	h.GetReleaseCover(rec, releaseCoverRequest(releaseID.String(), "?size=1200"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "jpeg" {
+		t.Fatalf("status = %d body = %q", rec.Code, rec.Body.String())
+	}
+	if covers.releaseID != releaseID || covers.size != 1200 {
+		t.Fatalf("requested %s at %d", covers.releaseID, covers.size)
+	}
+	if rec.Header().Get("Content-Type") != "image/jpeg" || rec.Header().Get("Cache-Control") != releaseCoverCacheControl {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetReleaseCover(rec, releaseCoverRequest(releaseID.String(), ""))
+	if rec.Code != http.StatusOK || covers.size != defaultReleaseCoverSize {
+		t.Fatalf("default size: status = %d size = %d", rec.Code, covers.size)
+	}
+}
+
+func TestGetReleaseCoverRejectsBadRequests(t *testing.T) {
+	h := NewArtworkHandlers(&fakeReleaseCovers{})
+	for _, tc := range []struct{ id, query string }{
+		{"not-a-uuid", ""},
+		{uuid.NewString(), "?size=300"},
+		{uuid.NewString(), "?size=big"},
+	} {
+		rec := httptest.NewRecorder()
+		h.GetReleaseCover(rec, releaseCoverRequest(tc.id, tc.query))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s%s: status = %d, want 400", tc.id, tc.query, rec.Code)
+		}
+	}
+}
+
+func TestGetReleaseCoverMapsCacheErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err          error
+		status       int
+		cacheControl string
+	}{
+		{artwork.ErrCoverNotFound, http.StatusNotFound, missingCoverCacheControl},
+		{errors.New("coverartarchive.org: status 503"), http.StatusBadGateway, ""},
+	} {
+		rec := httptest.NewRecorder()
+		NewArtworkHandlers(&fakeReleaseCovers{err: tc.err}).GetReleaseCover(rec, releaseCoverRequest(uuid.NewString(), ""))
+		if rec.Code != tc.status || rec.Header().Get("Cache-Control") != tc.cacheControl {
+			t.Fatalf("%v: status = %d cache = %q", tc.err, rec.Code, rec.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+type fakeTrackArtworkStore struct {
+	tracks map[int64]*db.Track
+	keys   map[int64]string
+}
+
+func (f fakeTrackArtworkStore) GetByID(_ context.Context, id int64) (*db.Track, error) {
+	if t, ok := f.tracks[id]; ok {
+		return t, nil
+	}
+	return nil, db.ErrTrackNotFound
+}
+
+func (f fakeTrackArtworkStore) GetArtworkKey(_ context.Context, trackID int64) (string, error) {
+	return f.keys[trackID], nil
+}
+
+type fakeArtworkSigner struct{}
+
+func (fakeArtworkSigner) PresignGetObject(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://cdn.test/" + key, nil
+}
+
+func trackArtworkRequest(id, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/"+id+"/artwork"+query, nil)
+	req.SetPathValue("track_id", id)
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()})
+	return req.WithContext(ctx)
+}
+
+func TestGetTrackArtworkPrefersReleaseCoverAndFallsBackToEmbeddedArt(t *testing.T) {
+	releaseID := uuid.New()
+	h := NewArtworkHandlers(&fakeReleaseCovers{})
+	h.SetTrackArtwork(fakeTrackArtworkStore{
+		tracks: map[int64]*db.Track{1: {ID: 1, MBReleaseID: &releaseID}, 2: {ID: 2}, 3: {ID: 3}},
+		keys:   map[int64]string{1: "artwork/tracks/a.jpg", 2: "artwork/tracks/b.jpg"},
+	}, fakeTrackLibrary{trackIDs: map[int64]bool{1: true, 2: true, 3: true}}, fakeArtworkSigner{})
+
+	for _, tc := range []struct {
+		id, query string
+		status    int
+		location  string
+	}{
+		{"1", "?size=250", http.StatusFound, "/api/v1/artwork/" + releaseID.String() + "?size=250"},
+		{"2", "", http.StatusFound, "https://cdn.test/artwork/tracks/b.jpg"},
+		{"3", "", http.StatusNotFound, ""},
+		{"4", "", http.StatusNotFound, ""},
+		{"2", "?size=9", http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		h.GetTrackArtwork(rec, trackArtworkRequest(tc.id, tc.query))
+		if rec.Code != tc.status || rec.Header().Get("Location") != tc.location {
+			t.Fatalf("track %s%s: status = %d location = %q", tc.id, tc.query, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestGetTrackArtworkFallsBackWhenReleaseHasNoCover(t *testing.T) {
+	releaseID := uuid.New()
+	h := NewArtworkHandlers(&fakeReleaseCovers{err: artwork.ErrCoverNotFound})
+	h.SetTrackArtwork(fakeTrackArtworkStore{
+		tracks: map[int64]*db.Track{1: {ID: 1, MBReleaseID: &releaseID}, 2: {ID: 2, MBReleaseID: &releaseID}},
+		keys:   map[int64]string{1: "artwork/tracks/a.jpg"},
+	}, fakeTrackLibrary{trackIDs: map[int64]bool{1: true, 2: true}}, fakeArtworkSigner{})
+
+	for id, location := range map[string]string{
+		"1": "https://cdn.test/artwork/tracks/a.jpg",
+		"2": "/api/v1/artwork/" + releaseID.String() + "?size=500",
+	} {
+		rec := httptest.NewRecorder()
+		h.GetTrackArtwork(rec, trackArtworkRequest(id, ""))
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != location {
+			t.Fatalf("track %s: status = %d location = %q, want %q", id, rec.Code, rec.Header().Get("Location"), location)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+const maxBatchMatchLimit = 100000
+
+type batchMatchRunner interface {
+	Start(userID uuid.UUID, limit int) (processor.BatchMatchStatus, error)
+	Status() (processor.BatchMatchStatus, bool)
+	Cancel() bool
+}
+
+// BatchMatchHandlers lets admins run MusicBrainz matching over every
+// unverified track in the background and follow its progress.
+type BatchMatchHandlers struct {
+	runner batchMatchRunner
+}
+
+func NewBatchMatchHandlers(runner batchMatchRunner) *BatchMatchHandlers {
+	return &BatchMatchHandlers{runner: runner}
+}
+
+type StartBatchMatchRequest struct {
+	// Limit caps how many unverified tracks the run visits; 0 visits all.
+	Limit int `json:"limit"`
+}
+
+// StartBatch handles POST /api/v1/admin/match/batch. Progress is pushed to
+// the caller's WebSocket connections as batch_match_progress messages.
+func (h *BatchMatchHandlers) StartBatch(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req StartBatchMatchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeBatchMatchError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Limit < 0 || req.Limit > maxBatchMatchLimit {
+		writeBatchMatchError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 0 and 100000")
+		return
+	}
+
+	status, err := h.runner.Start(userCtx.UserID, req.Limit)
+	if errors.Is(err, processor.ErrBatchMatchRunning) {
+		writeBatchMatchJSON(w, http.StatusConflict, map[string]interface{}{
+			"code":    "BATCH_MATCH_RUNNING",
+			"message": "a batch match is already running",
+			"run":     status,
+		})
+		return
+	}
+	if err != nil {
+		writeBatchMatchError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start batch match")
+		return
+	}
+	writeBatchMatchJSON(w, http.StatusAccepted, status)
+}
+
+// GetBatch handles GET /api/v1/admin/match/batch, returning the current or
+// most recent run.
+func (h *BatchMatchHandlers) GetBatch(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	status, ok := h.runner.Status()
+	if !ok {
+		writeBatchMatchError(w, http.StatusNotFound, "BATCH_MATCH_NOT_FOUND", "no batch match has run")
+		return
+	}
+	writeBatchMatchJSON(w, http.StatusOK, status)
+}
+
+// CancelBatch handles DELETE /api/v1/admin/match/batch. The run stops after
+// the track it is matching.
+func (h *BatchMatchHandlers) CancelBatch(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	if !h.runner.Cancel() {
+		writeBatchMatchError(w, http.StatusConflict, "BATCH_MATCH_NOT_RUNNING", "no batch match is running")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BatchMatchHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) (*auth.UserContext, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeBatchMatchError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	if !userCtx.IsAdmin() {
+		writeBatchMatchError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return nil, false
+	}
+	return userCtx, true
+}
+
+func writeBatchMatchJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeBatchMatchError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
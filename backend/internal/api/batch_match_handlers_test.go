@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+type fakeBatchMatchRunner struct {
+	status    *processor.BatchMatchStatus
+	lastLimit int
+	startedBy uuid.UUID
+}
+
+func (f *fakeBatchMatchRunner) Start(userID uuid.UUID, limit int) (processor.BatchMatchStatus, error) {
+	if f.status != nil && f.status.State == processor.BatchMatchRunning {
+		return *f.status, processor.ErrBatchMatchRunning
+	}
+	f.lastLimit, f.startedBy = limit, userID
+	f.status = &processor.BatchMatchStatus{ID: "run-1", State: processor.BatchMatchRunning}
+	return *f.status, nil
+}
+
+func (f *fakeBatchMatchRunner) Status() (processor.BatchMatchStatus, bool) {
+	if f.status == nil {
+		return processor.BatchMatchStatus{}, false
+	}
+	return *f.status, true
+}
+
+func (f *fakeBatchMatchRunner) Cancel() bool {
+	if f.status == nil || f.status.State != processor.BatchMatchRunning {
+		return false
+	}
+	f.status.State = processor.BatchMatchCanceled
+	return true
+}
+
+func batchMatchRequest(method, body, email string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/admin/match/batch", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestBatchMatchStartStatusAndCancel(t *testing.T) {
+	runner := &fakeBatchMatchRunner{}
+	h := NewBatchMatchHandlers(runner)
+
+	rec := httptest.NewRecorder()
+	h.GetBatch(rec, batchMatchRequest(http.MethodGet, "", "ops@example.test"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status before any run = %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartBatch(rec, batchMatchRequest(http.MethodPost, `{"limit":500}`, "OPS@example.test"))
+	if rec.Code != http.StatusAccepted || runner.lastLimit != 500 {
+		t.Fatalf("start = %d with limit %d; body=%s", rec.Code, runner.lastLimit, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.StartBatch(rec, batchMatchRequest(http.MethodPost, "", "ops@example.test"))
+	var conflict struct {
+		Code string                     `json:"code"`
+		Run  processor.BatchMatchStatus `json:"run"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&conflict); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusConflict || conflict.Code != "BATCH_MATCH_RUNNING" || conflict.Run.ID != "run-1" {
+		t.Fatalf("second start = %d %+v, want 409 with the running run", rec.Code, conflict)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelBatch(rec, batchMatchRequest(http.MethodDelete, "", "ops@example.test"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("cancel = %d, want 204", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.CancelBatch(rec, batchMatchRequest(http.MethodDelete, "", "ops@example.test"))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second cancel = %d, want 409", rec.Code)
+	}
+}
+
+func TestBatchMatchRejectsNonAdminsAndBadLimits(t *testing.T) {
+	runner := &fakeBatchMatchRunner{}
+	h := NewBatchMatchHandlers(runner)
+
+	rec := httptest.NewRecorder()
+	h.StartBatch(rec, batchMatchRequest(http.MethodPost, "{}", "listener@example.test"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin start = %d, want 403", rec.Code)
+	}
+	for _, body := range []string{`{"limit":-1}`, `{"limit":"all"}`, `{"force":true}`} {
+		rec := httptest.NewRecorder()
+		h.StartBatch(rec, batchMatchRequest(http.MethodPost, body, "ops@example.test"))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("start %s = %d, want 400", body, rec.Code)
+		}
+	}
+	if runner.status != nil {
+		t.Fatal("a rejected request started a run")
+	}
+}
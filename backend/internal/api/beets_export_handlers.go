@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const beetsExportPageSize = 100
+
+type beetsLibraryStore interface {
+	GetUserLibrary(ctx context.Context, userID uuid.UUID, opts db.LibraryQueryOptions) ([]db.LibraryTrack, int, error)
+}
+
+// BeetsExportHandlers exports a user's library as beets items, so beets and
+// its plugins can run against the library without copying the audio. Item
+// paths point into a mount of the object storage bucket at pathPrefix;
+// without one, items carry no path and only the omp_* flexible attributes
+// locate the audio.
+type BeetsExportHandlers struct {
+	library    beetsLibraryStore
+	pathPrefix string
+}
+
+func NewBeetsExportHandlers(library beetsLibraryStore, pathPrefix string) *BeetsExportHandlers {
+	return &BeetsExportHandlers{library: library, pathPrefix: pathPrefix}
+}
+
+// BeetsItem is one library track in beets' field names. Fields beets does
+// not define are flexible attributes prefixed omp_.
+type BeetsItem struct {
+	Path        string  `json:"path,omitempty"`
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist,omitempty"`
+	AlbumArtist string  `json:"albumartist,omitempty"`
+	Album       string  `json:"album,omitempty"`
+	Genre       string  `json:"genre,omitempty"`
+	Length      float64 `json:"length,omitempty"`
+	Format      string  `json:"format,omitempty"`
+	Bitrate     int     `json:"bitrate,omitempty"`
+	Samplerate  int     `json:"samplerate,omitempty"`
+	Channels    int     `json:"channels,omitempty"`
+	BPM         int     `json:"bpm,omitempty"`
+	InitialKey  string  `json:"initial_key,omitempty"`
+	MBTrackID   string  `json:"mb_trackid,omitempty"`
+	MBAlbumID   string  `json:"mb_albumid,omitempty"`
+	MBArtistID  string  `json:"mb_artistid,omitempty"`
+
+	OMPTrackID    int64  `json:"omp_track_id"`
+	OMPStorageKey string `json:"omp_storage_key,omitempty"`
+	OMPSourceURL  string `json:"omp_source_url,omitempty"`
+	OMPSourceType string `json:"omp_source_type,omitempty"`
+	OMPVerified   bool   `json:"omp_mb_verified"`
+	OMPLiked      bool   `json:"omp_liked"`
+	OMPPlayCount  int    `json:"omp_play_count"`
+	OMPAdded      int64  `json:"omp_added"`
+}
+
+// ExportBeets handles GET /api/v1/library/export/beets. The response is
+// newline-delimited JSON, one BeetsItem per line, oldest library addition
+// first. ?since=<RFC 3339> limits it to tracks added at or after that time
+// for incremental syncs.
+func (h *BeetsExportHandlers) ExportBeets(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeBeetsExportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	opts := db.LibraryQueryOptions{Limit: beetsExportPageSize, SortBy: "added_at", SortOrder: "asc"}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeBeetsExportError(w, http.StatusBadRequest, "INVALID_SINCE", "since must be an RFC 3339 timestamp")
+			return
+		}
+		opts.AddedAfter = &t
+	}
+
+	// Fetch the first page before committing to a 200 so a failing
+	// database still gets a proper error response.
+	page, total, err := h.library.GetUserLibrary(r.Context(), userCtx.UserID, opts)
+	if err != nil {
+		writeBeetsExportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to read library")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="openmusicplayer-beets.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for {
+		for i := range page {
+			if err := encoder.Encode(h.beetsItem(&page[i])); err != nil {
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		opts.Offset += len(page)
+		if len(page) < opts.Limit || opts.Offset >= total {
+			return
+		}
+		page, _, err = h.library.GetUserLibrary(r.Context(), userCtx.UserID, opts)
+		if err != nil {
+			// The status is already sent; a truncated stream is the signal.
+			log.Printf("Warning: beets export for user %s stopped after %d tracks: %v", userCtx.UserID, opts.Offset, err)
+			return
+		}
+	}
+}
+
+func (h *BeetsExportHandlers) beetsItem(track *db.LibraryTrack) BeetsItem {
+	item := BeetsItem{
+		Title:         track.Title,
+		Artist:        track.Artist.String,
+		AlbumArtist:   track.Artist.String,
+		Album:         track.Album.String,
+		Genre:         track.Genre.String,
+		Format:        beetsFormat(track.Codec.String),
+		Bitrate:       int(track.BitrateKbps.Int32) * 1000,
+		Samplerate:    int(track.SampleRateHz.Int32),
+		Channels:      int(track.Channels.Int32),
+		OMPTrackID:    track.ID,
+		OMPStorageKey: track.StorageKey.String,
+		OMPSourceURL:  track.SourceURL.String,
+		OMPSourceType: track.SourceType.String,
+		OMPVerified:   track.MBVerified,
+		OMPLiked:      track.IsLiked,
+		OMPPlayCount:  track.PlayCount,
+		OMPAdded:      track.AddedAt.Unix(),
+	}
+	if track.DurationMs.Valid {
+		item.Length = float64(track.DurationMs.Int32) / 1000
+	}
+	if h.pathPrefix != "" && track.StorageKey.String != "" {
+		item.Path = path.Join(h.pathPrefix, track.StorageKey.String)
+	}
+	if track.MBRecordingID != nil {
+		item.MBTrackID = track.MBRecordingID.String()
+	}
+	if track.MBReleaseID != nil {
+		item.MBAlbumID = track.MBReleaseID.String()
+	}
+	if track.MBArtistID != nil {
+		item.MBArtistID = track.MBArtistID.String()
+	}
+
+	var analysis struct {
+		BPM *struct {
+			Value *float64 `json:"value"`
+		} `json:"bpm"`
+		Key *struct {
+			Value *string `json:"value"`
+		} `json:"key"`
+	}
+	if len(track.AnalysisSummary) > 0 && json.Unmarshal(track.AnalysisSummary, &analysis) == nil {
+		if analysis.BPM != nil && analysis.BPM.Value != nil {
+			item.BPM = int(math.Round(*analysis.BPM.Value))
+		}
+		if analysis.Key != nil && analysis.Key.Value != nil {
+			item.InitialKey = beetsKey(*analysis.Key.Value)
+		}
+	}
+	return item
+}
+
+// beetsFormat names a codec the way beets' format field does.
+func beetsFormat(codec string) string {
+	codec = strings.ToLower(codec)
+	switch {
+	case codec == "":
+		return ""
+	case codec == "vorbis":
+		return "OGG"
+	case codec == "opus":
+		return "Opus"
+	case strings.HasPrefix(codec, "pcm_"):
+		return "WAVE"
+	default:
+		return strings.ToUpper(codec)
+	}
+}
+
+// beetsKey converts an analyzer key such as "G minor" to beets' initial_key
+// notation, "Gm". Values it does not recognize pass through unchanged.
+func beetsKey(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) != 2 {
+		return key
+	}
+	switch strings.ToLower(fields[1]) {
+	case "major":
+		return fields[0]
+	case "minor":
+		return fields[0] + "m"
+	}
+	return key
+}
+
+func writeBeetsExportError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeBeetsLibrary struct {
+	tracks []db.LibraryTrack
+	calls  []db.LibraryQueryOptions
+	err    error
+}
+
+func (f *fakeBeetsLibrary) GetUserLibrary(_ context.Context, _ uuid.UUID, opts db.LibraryQueryOptions) ([]db.LibraryTrack, int, error) {
+	f.calls = append(f.calls, opts)
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	end := min(opts.Offset+opts.Limit, len(f.tracks))
+	return f.tracks[opts.Offset:end], len(f.tracks), nil
+}
+
+func TestExportBeetsStreamsEveryTrackAsBeetsItems(t *testing.T) {
+	recordingID := uuid.New()
+	library := &fakeBeetsLibrary{}
+	for id := int64(1); id <= 150; id++ {
+		library.tracks = append(library.tracks, db.LibraryTrack{Track: db.Track{ID: id, Title: "Track"}})
+	}
+	library.tracks[0] = db.LibraryTrack{
+		Track: db.Track{
+			ID:            1,
+			Title:         "Roygbiv",
+			Artist:        sql.NullString{String: "Boards of Canada", Valid: true},
+			Album:         sql.NullString{String: "Music Has the Right to Children", Valid: true},
+			DurationMs:    sql.NullInt32{Int32: 151500, Valid: true},
+			MBRecordingID: &recordingID,
+			StorageKey:    sql.NullString{String: "tracks/upload/job-1.flac", Valid: true},
+			Codec:         sql.NullString{String: "flac", Valid: true},
+			BitrateKbps:   sql.NullInt32{Int32: 900, Valid: true},
+		},
+		AnalysisSummary: json.RawMessage(`{"bpm":{"value":92.6},"key":{"value":"F# minor"}}`),
+		IsLiked:         true,
+		PlayCount:       3,
+		AddedAt:         time.Unix(1700000000, 0),
+	}
+	h := NewBeetsExportHandlers(library, "/mnt/omp")
+
+	rec := httptest.NewRecorder()
+	h.ExportBeets(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/library/export/beets", nil), uuid.New()))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var items []BeetsItem
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var item BeetsItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		items = append(items, item)
+	}
+	if len(items) != 150 || len(library.calls) != 2 || library.calls[0].SortOrder != "asc" {
+		t.Fatalf("exported %d items in %d pages, want 150 in 2 ascending pages", len(items), len(library.calls))
+	}
+	want := BeetsItem{
+		Path: "/mnt/omp/tracks/upload/job-1.flac", Title: "Roygbiv", Artist: "Boards of Canada", AlbumArtist: "Boards of Canada",
+		Album: "Music Has the Right to Children", Length: 151.5, Format: "FLAC", Bitrate: 900000, BPM: 93, InitialKey: "F#m",
+		MBTrackID: recordingID.String(), OMPTrackID: 1, OMPStorageKey: "tracks/upload/job-1.flac", OMPLiked: true,
+		OMPPlayCount: 3, OMPAdded: 1700000000,
+	}
+	if items[0] != want {
+		t.Fatalf("item = %+v\nwant %+v", items[0], want)
+	}
+	if items[1].Path != "" {
+		t.Fatalf("track without storage got path %q", items[1].Path)
+	}
+}
+
+func TestExportBeetsValidatesSinceAndReportsFirstPageErrors(t *testing.T) {
+	library := &fakeBeetsLibrary{}
+	h := NewBeetsExportHandlers(library, "")
+
+	rec := httptest.NewRecorder()
+	h.ExportBeets(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/library/export/beets?since=yesterday", nil), uuid.New()))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad since = %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ExportBeets(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/library/export/beets?since=2026-10-01T00:00:00Z", nil), uuid.New()))
+	if rec.Code != http.StatusOK || library.calls[0].AddedAfter == nil || !library.calls[0].AddedAfter.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("since export = %d with options %+v", rec.Code, library.calls)
+	}
+
+	library.err = errors.New("database down")
+	rec = httptest.NewRecorder()
+	h.ExportBeets(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/library/export/beets", nil), uuid.New()))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failing library = %d, want 500", rec.Code)
+	}
+}
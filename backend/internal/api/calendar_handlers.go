@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+const (
+	calendarDateLayout      = "2006-01-02"
+	defaultCalendarLookback = 30 * 24 * time.Hour
+	defaultCalendarLookhead = 60 * 24 * time.Hour
+	maxCalendarSpanDays     = 180
+	calendarCacheTTL        = time.Hour
+	maxFollowedArtists      = 200
+)
+
+type artistFollowStore interface {
+	ListFollowedArtists(ctx context.Context, userID uuid.UUID) ([]db.ArtistFollow, error)
+	FollowArtist(ctx context.Context, follow *db.ArtistFollow) error
+	UnfollowArtist(ctx context.Context, userID, mbArtistID uuid.UUID) error
+}
+
+type calendarReleases interface {
+	GetArtist(ctx context.Context, mbID string) (*musicbrainz.Artist, error)
+	ReleaseGroupsByArtists(ctx context.Context, artistIDs []string, from, to string) ([]musicbrainz.AlbumResult, error)
+	GetReleaseGroupCoverArtURL(releaseGroupID string) string
+}
+
+type calendarCache interface {
+	Get(ctx context.Context, key string) (string, bool)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+}
+
+// CalendarHandlers serves the artists the caller follows and the release
+// calendar built from them.
+type CalendarHandlers struct {
+	follows  artistFollowStore
+	releases calendarReleases
+	cache    calendarCache
+	now      func() time.Time
+}
+
+func NewCalendarHandlers(follows artistFollowStore, releases calendarReleases) *CalendarHandlers {
+	return &CalendarHandlers{follows: follows, releases: releases, now: time.Now}
+}
+
+// SetCache caches each user's calendar for an hour. Following or unfollowing
+// an artist changes the cache key, so the next request sees the change.
+func (h *CalendarHandlers) SetCache(cache calendarCache) {
+	h.cache = cache
+}
+
+type FollowedArtistResponse struct {
+	MBID       string    `json:"mbid"`
+	Name       string    `json:"name"`
+	FollowedAt time.Time `json:"followedAt"`
+}
+
+type FollowedArtistsResponse struct {
+	Artists []FollowedArtistResponse `json:"artists"`
+}
+
+type CalendarRelease struct {
+	MBID           string   `json:"mbid"`
+	Title          string   `json:"title"`
+	Artist         string   `json:"artist,omitempty"`
+	ArtistMBID     string   `json:"artistMbid,omitempty"`
+	PrimaryType    string   `json:"primaryType,omitempty"`
+	SecondaryTypes []string `json:"secondaryTypes,omitempty"`
+	CoverArtURL    string   `json:"coverArtUrl"`
+}
+
+// CalendarDay lists the releases first released on Date. Upcoming days are
+// after today (UTC).
+type CalendarDay struct {
+	Date     string            `json:"date"`
+	Upcoming bool              `json:"upcoming"`
+	Releases []CalendarRelease `json:"releases"`
+}
+
+type CalendarResponse struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Today string        `json:"today"`
+	Days  []CalendarDay `json:"days"`
+}
+
+// ListFollowedArtists handles GET /api/v1/me/followed-artists
+func (h *CalendarHandlers) ListFollowedArtists(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeCalendarError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	follows, err := h.follows.ListFollowedArtists(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeCalendarError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load followed artists")
+		return
+	}
+	resp := FollowedArtistsResponse{Artists: make([]FollowedArtistResponse, 0, len(follows))}
+	for _, f := range follows {
+		resp.Artists = append(resp.Artists, followedArtistResponse(f))
+	}
+	writeCalendarJSON(w, http.StatusOK, resp)
+}
+
+// FollowArtist handles PUT /api/v1/me/followed-artists/{mb_id}. Following an
+// artist again is a no-op.
+func (h *CalendarHandlers) FollowArtist(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeCalendarError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	mbID, err := uuid.Parse(r.PathValue("mb_id"))
+	if err != nil {
+		writeCalendarError(w, http.StatusBadRequest, "INVALID_ID", "invalid MusicBrainz ID format")
+		return
+	}
+
+	follows, err := h.follows.ListFollowedArtists(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeCalendarError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load followed artists")
+		return
+	}
+	for _, f := range follows {
+		if f.MBArtistID == mbID {
+			writeCalendarJSON(w, http.StatusOK, followedArtistResponse(f))
+			return
+		}
+	}
+	if len(follows) >= maxFollowedArtists {
+		writeCalendarError(w, http.StatusConflict, "FOLLOW_LIMIT_REACHED", "at most 200 artists can be followed")
+		return
+	}
+
+	artist, err := h.releases.GetArtist(r.Context(), mbID.String())
+	if err != nil {
+		if errors.Is(err, musicbrainz.ErrNotFound) {
+			writeCalendarError(w, http.StatusNotFound, "ARTIST_NOT_FOUND", "artist not found")
+			return
+		}
+		writeCalendarError(w, http.StatusServiceUnavailable, "MUSICBRAINZ_UNAVAILABLE", "failed to look up artist")
+		return
+	}
+	follow := &db.ArtistFollow{UserID: userCtx.UserID, MBArtistID: mbID, Name: artist.Name}
+	if err := h.follows.FollowArtist(r.Context(), follow); err != nil {
+		writeCalendarError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to follow artist")
+		return
+	}
+	writeCalendarJSON(w, http.StatusCreated, followedArtistResponse(*follow))
+}
+
+// UnfollowArtist handles DELETE /api/v1/me/followed-artists/{mb_id}
+func (h *CalendarHandlers) UnfollowArtist(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeCalendarError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	mbID, err := uuid.Parse(r.PathValue("mb_id"))
+	if err != nil {
+		writeCalendarError(w, http.StatusBadRequest, "INVALID_ID", "invalid MusicBrainz ID format")
+		return
+	}
+	if err := h.follows.UnfollowArtist(r.Context(), userCtx.UserID, mbID); err != nil {
+		if errors.Is(err, db.ErrArtistFollowNotFound) {
+			writeCalendarError(w, http.StatusNotFound, "NOT_FOLLOWING", "artist is not followed")
+			return
+		}
+		writeCalendarError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to unfollow artist")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCalendar handles GET /api/v1/calendar?from=&to=. Dates are YYYY-MM-DD
+// and inclusive; by default the calendar covers the last 30 days and the
+// next 60.
+func (h *CalendarHandlers) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeCalendarError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	today := h.now().UTC().Truncate(24 * time.Hour)
+	from, ok := parseCalendarDate(r.URL.Query().Get("from"), today.Add(-defaultCalendarLookback))
+	if !ok {
+		writeCalendarError(w, http.StatusBadRequest, "INVALID_DATE", "from must be a date in YYYY-MM-DD format")
+		return
+	}
+	to, ok := parseCalendarDate(r.URL.Query().Get("to"), today.Add(defaultCalendarLookhead))
+	if !ok {
+		writeCalendarError(w, http.StatusBadRequest, "INVALID_DATE", "to must be a date in YYYY-MM-DD format")
+		return
+	}
+	if to.Before(from) || to.Sub(from) > maxCalendarSpanDays*24*time.Hour {
+		writeCalendarError(w, http.StatusBadRequest, "INVALID_RANGE", "to must be on or after from and at most 180 days later")
+		return
+	}
+
+	follows, err := h.follows.ListFollowedArtists(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeCalendarError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load followed artists")
+		return
+	}
+	artistIDs := make([]string, 0, len(follows))
+	for _, f := range follows {
+		artistIDs = append(artistIDs, f.MBArtistID.String())
+	}
+	sort.Strings(artistIDs)
+
+	resp := CalendarResponse{
+		From:  from.Format(calendarDateLayout),
+		To:    to.Format(calendarDateLayout),
+		Today: today.Format(calendarDateLayout),
+		Days:  []CalendarDay{},
+	}
+	if len(artistIDs) == 0 {
+		writeCalendarJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	cacheKey := calendarCacheKey(userCtx.UserID, resp.From, resp.To, resp.Today, artistIDs)
+	if h.cache != nil {
+		if cached, ok := h.cache.Get(r.Context(), cacheKey); ok {
+			var days []CalendarDay
+			if err := json.Unmarshal([]byte(cached), &days); err == nil {
+				resp.Days = days
+				writeCalendarJSON(w, http.StatusOK, resp)
+				return
+			}
+		}
+	}
+
+	albums, err := h.releases.ReleaseGroupsByArtists(r.Context(), artistIDs, resp.From, resp.To)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		writeCalendarError(w, http.StatusServiceUnavailable, "MUSICBRAINZ_UNAVAILABLE", "failed to load releases")
+		return
+	}
+	resp.Days = h.groupCalendarDays(albums, from, to, today)
+
+	if h.cache != nil {
+		if data, err := json.Marshal(resp.Days); err == nil {
+			_ = h.cache.Set(r.Context(), cacheKey, string(data), calendarCacheTTL)
+		}
+	}
+	writeCalendarJSON(w, http.StatusOK, resp)
+}
+
+// groupCalendarDays buckets releases by first release date, newest day
+// last. Releases dated only to the month or year cannot be placed and are
+// left out.
+func (h *CalendarHandlers) groupCalendarDays(albums []musicbrainz.AlbumResult, from, to, today time.Time) []CalendarDay {
+	byDate := make(map[string][]CalendarRelease)
+	for _, album := range albums {
+		date, err := time.Parse(calendarDateLayout, album.ReleaseDate)
+		if err != nil || date.Before(from) || date.After(to) {
+			continue
+		}
+		byDate[album.ReleaseDate] = append(byDate[album.ReleaseDate], CalendarRelease{
+			MBID:           album.MBID,
+			Title:          album.Title,
+			Artist:         album.Artist,
+			ArtistMBID:     album.ArtistMBID,
+			PrimaryType:    album.PrimaryType,
+			SecondaryTypes: album.SecondaryTypes,
+			CoverArtURL:    h.releases.GetReleaseGroupCoverArtURL(album.MBID),
+		})
+	}
+
+	days := make([]CalendarDay, 0, len(byDate))
+	for date, releases := range byDate {
+		sort.Slice(releases, func(i, j int) bool {
+			if a, b := strings.ToLower(releases[i].Artist), strings.ToLower(releases[j].Artist); a != b {
+				return a < b
+			}
+			return strings.ToLower(releases[i].Title) < strings.ToLower(releases[j].Title)
+		})
+		days = append(days, CalendarDay{
+			Date:     date,
+			Upcoming: date > today.Format(calendarDateLayout),
+			Releases: releases,
+		})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+func parseCalendarDate(value string, fallback time.Time) (time.Time, bool) {
+	if value == "" {
+		return fallback, true
+	}
+	date, err := time.Parse(calendarDateLayout, value)
+	return date, err == nil
+}
+
+// calendarCacheKey covers everything the calendar depends on, including the
+// set of followed artists and today's date, which decides what is upcoming.
+func calendarCacheKey(userID uuid.UUID, from, to, today string, artistIDs []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(artistIDs, ",")))
+	return "calendar:" + userID.String() + ":" + from + ":" + to + ":" + today + ":" + hex.EncodeToString(sum[:8])
+}
+
+func followedArtistResponse(f db.ArtistFollow) FollowedArtistResponse {
+	return FollowedArtistResponse{MBID: f.MBArtistID.String(), Name: f.Name, FollowedAt: f.CreatedAt}
+}
+
+func writeCalendarJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeCalendarError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
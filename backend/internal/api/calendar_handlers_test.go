@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+type fakeArtistFollows struct {
+	follows []db.ArtistFollow
+}
+
+func (f *fakeArtistFollows) ListFollowedArtists(_ context.Context, userID uuid.UUID) ([]db.ArtistFollow, error) {
+	var out []db.ArtistFollow
+	for _, follow := range f.follows {
+		if follow.UserID == userID {
+			out = append(out, follow)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeArtistFollows) FollowArtist(_ context.Context, follow *db.ArtistFollow) error {
+	follow.CreatedAt = time.Now()
+	f.follows = append(f.follows, *follow)
+	return nil
+}
+
+func (f *fakeArtistFollows) UnfollowArtist(_ context.Context, userID, mbArtistID uuid.UUID) error {
+	for i, follow := range f.follows {
+		if follow.UserID == userID && follow.MBArtistID == mbArtistID {
+			f.follows = append(f.follows[:i], f.follows[i+1:]...)
+			return nil
+		}
+	}
+	return db.ErrArtistFollowNotFound
+}
+
+type fakeCalendarReleases struct {
+	artists  map[string]string
+	albums   []musicbrainz.AlbumResult
+	queries  int
+	lastFrom string
+	lastTo   string
+}
+
+func (f *fakeCalendarReleases) GetArtist(_ context.Context, mbID string) (*musicbrainz.Artist, error) {
+	name, ok := f.artists[mbID]
+	if !ok {
+		return nil, musicbrainz.ErrNotFound
+	}
+	return &musicbrainz.Artist{ID: mbID, Name: name}, nil
+}
+
+func (f *fakeCalendarReleases) ReleaseGroupsByArtists(_ context.Context, artistIDs []string, from, to string) ([]musicbrainz.AlbumResult, error) {
+	f.queries++
+	f.lastFrom, f.lastTo = from, to
+	return f.albums, nil
+}
+
+func (f *fakeCalendarReleases) GetReleaseGroupCoverArtURL(id string) string {
+	return "https://covers.example.test/" + id
+}
+
+type fakeCalendarCache map[string]string
+
+func (c fakeCalendarCache) Get(_ context.Context, key string) (string, bool) {
+	v, ok := c[key]
+	return v, ok
+}
+
+func (c fakeCalendarCache) Set(_ context.Context, key, value string, _ time.Duration) error {
+	c[key] = value
+	return nil
+}
+
+func getCalendar(h *CalendarHandlers, userID uuid.UUID, query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.GetCalendar(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/calendar"+query, nil), userID))
+	return rec
+}
+
+func TestCalendarGroupsFollowedArtistReleasesByDate(t *testing.T) {
+	userID := uuid.New()
+	artistID := uuid.New()
+	follows := &fakeArtistFollows{follows: []db.ArtistFollow{{UserID: userID, MBArtistID: artistID, Name: "Boards of Canada"}}}
+	releases := &fakeCalendarReleases{albums: []musicbrainz.AlbumResult{
+		{MBID: "rg-upcoming", Title: "Later", Artist: "Boards of Canada", ReleaseDate: "2026-10-23"},
+		{MBID: "rg-b", Title: "Second", Artist: "Boards of Canada", ReleaseDate: "2026-10-09"},
+		{MBID: "rg-a", Title: "First", Artist: "Aphex Twin", ReleaseDate: "2026-10-09"},
+		{MBID: "rg-year-only", Title: "Someday", ReleaseDate: "2026"},
+	}}
+	cache := fakeCalendarCache{}
+	h := NewCalendarHandlers(follows, releases)
+	h.SetCache(cache)
+	h.now = func() time.Time { return time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC) }
+
+	rec := getCalendar(h, userID, "?from=2026-10-01&to=2026-10-31")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp CalendarResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Days) != 2 || resp.Days[0].Date != "2026-10-09" || resp.Days[0].Upcoming || !resp.Days[1].Upcoming {
+		t.Fatalf("days = %+v, want a recent 10-09 and an upcoming 10-23", resp.Days)
+	}
+	if got := resp.Days[0].Releases; len(got) != 2 || got[0].MBID != "rg-a" || got[0].CoverArtURL != "https://covers.example.test/rg-a" {
+		t.Fatalf("10-09 releases = %+v, want sorted by artist with cover art", got)
+	}
+
+	if rec := getCalendar(h, userID, "?from=2026-10-01&to=2026-10-31"); rec.Code != http.StatusOK || releases.queries != 1 {
+		t.Fatalf("second request status %d made %d MusicBrainz queries, want a cache hit", rec.Code, releases.queries)
+	}
+	follows.follows = append(follows.follows, db.ArtistFollow{UserID: userID, MBArtistID: uuid.New(), Name: "Autechre"})
+	if getCalendar(h, userID, "?from=2026-10-01&to=2026-10-31"); releases.queries != 2 {
+		t.Fatalf("after following another artist made %d queries, want the cache bypassed", releases.queries)
+	}
+}
+
+func TestCalendarValidatesRangeAndSkipsLookupWithoutFollows(t *testing.T) {
+	userID := uuid.New()
+	releases := &fakeCalendarReleases{}
+	h := NewCalendarHandlers(&fakeArtistFollows{}, releases)
+	h.now = func() time.Time { return time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC) }
+
+	for _, query := range []string{"?from=10/01/2026", "?from=2026-10-31&to=2026-10-01", "?from=2026-01-01&to=2026-12-31"} {
+		if rec := getCalendar(h, userID, query); rec.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want 400", query, rec.Code)
+		}
+	}
+	rec := getCalendar(h, userID, "")
+	var resp CalendarResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || resp.From != "2026-09-16" || resp.To != "2026-12-15" || len(resp.Days) != 0 || releases.queries != 0 {
+		t.Fatalf("default calendar = %d %+v after %d queries", rec.Code, resp, releases.queries)
+	}
+}
+
+func TestFollowArtistLooksUpNameAndUnfollows(t *testing.T) {
+	userID := uuid.New()
+	artistID := uuid.New()
+	follows := &fakeArtistFollows{}
+	h := NewCalendarHandlers(follows, &fakeCalendarReleases{artists: map[string]string{artistID.String(): "Burial"}})
+
+	follow := func(id string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/followed-artists/"+id, nil), userID)
+		req.SetPathValue("mb_id", id)
+		rec := httptest.NewRecorder()
+		h.FollowArtist(rec, req)
+		return rec
+	}
+	if rec := follow(artistID.String()); rec.Code != http.StatusCreated || len(follows.follows) != 1 || follows.follows[0].Name != "Burial" {
+		t.Fatalf("follow = %d, follows %+v", rec.Code, follows.follows)
+	}
+	if rec := follow(artistID.String()); rec.Code != http.StatusOK || len(follows.follows) != 1 {
+		t.Fatalf("refollow = %d with %d follows, want 200 and no duplicate", rec.Code, len(follows.follows))
+	}
+	if rec := follow(uuid.NewString()); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown artist = %d, want 404", rec.Code)
+	}
+
+	unfollow := func() int {
+		req := withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/me/followed-artists/"+artistID.String(), nil), userID)
+		req.SetPathValue("mb_id", artistID.String())
+		rec := httptest.NewRecorder()
+		h.UnfollowArtist(rec, req)
+		return rec.Code
+	}
+	if code := unfollow(); code != http.StatusNoContent {
+		t.Fatalf("unfollow = %d, want 204", code)
+	}
+	if code := unfollow(); code != http.StatusNotFound {
+		t.Fatalf("second unfollow = %d, want 404", code)
+	}
+}
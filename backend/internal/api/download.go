@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,24 +16,63 @@ import (
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/pagination"
+	"github.com/openmusicplayer/backend/internal/playlistimport"
+	"github.com/openmusicplayer/backend/internal/validators"
 )
 
 const maxCreateDownloadBodyBytes = 16 * 1024
 
+// maxPlaylistDownloadItems caps how many entries of a playlist URL become
+// child download jobs.
+const maxPlaylistDownloadItems = playlistimport.DefaultMaxItems
+
 type trustedDownloadIngestion interface {
 	CreateTrustedDownload(context.Context, uuid.UUID, string, download.SourceCandidate, string) (*db.SourceSelectionDownload, error)
 	EnqueueTrustedDownload(context.Context, *db.SourceSelectionDownload, db.SourceSelectionDownloadEnqueuer) (*download.DownloadJob, error)
+	CreateSearchedDownload(context.Context, uuid.UUID, string, download.SourceCandidate, db.TrustedSourceSelectionQuality, string) (*db.SourceSelectionDownload, error)
+	EnqueueTrustedRecordingDownload(context.Context, *db.SourceSelectionDownload, db.SourceSelectionDownloadEnqueuer, *string) (*download.DownloadJob, error)
 }
 
 type downloadService interface {
 	db.SourceSelectionDownloadEnqueuer
 	GetJob(context.Context, string) (*download.DownloadJob, error)
 	GetUserJobs(context.Context, string) ([]*download.DownloadJob, error)
+	RetryJob(context.Context, string) error
+	CancelJob(context.Context, string) error
+	CreateParentJob(ctx context.Context, userID, url, sourceType, title string) (*download.DownloadJob, error)
+	AttachChildJobs(ctx context.Context, parentID string, childIDs []string) (*download.DownloadJob, error)
+}
+
+type downloadTakedownChecker interface {
+	FindActive(ctx context.Context, sourceURL, identityHash string) (*db.ContentTakedown, error)
+}
+
+type downloadQueuePositions interface {
+	QueuePositions(ctx context.Context) (map[string]download.QueuePosition, error)
+}
+
+type downloadURLExpander interface {
+	Expand(ctx context.Context, url string) (string, error)
+}
+
+// downloadPlaylistEnumerator lists a playlist's entries without downloading
+// them. playlistimport.YTDLPEnumerator satisfies it.
+type downloadPlaylistEnumerator interface {
+	Enumerate(ctx context.Context, sourceURL string, maxItems int) (playlistimport.PlaylistMetadata, []playlistimport.Entry, error)
 }
 
 type DownloadHandlers struct {
 	downloadService downloadService
 	ingestion       trustedDownloadIngestion
+	takedowns       downloadTakedownChecker
+	positions       downloadQueuePositions
+	shortLinks      downloadURLExpander
+	enumerator      downloadPlaylistEnumerator
+	releases        releaseCatalog
+	releaseSources  releaseSourceSearcher
+	playlists       destinationPlaylists
+	submissions     *downloadSubmissions
 }
 
 func NewDownloadHandlers(downloadService downloadService, ingestion ...trustedDownloadIngestion) *DownloadHandlers {
@@ -43,14 +83,52 @@ func NewDownloadHandlers(downloadService downloadService, ingestion ...trustedDo
 	return &DownloadHandlers{
 		downloadService: downloadService,
 		ingestion:       trustedIngestion,
+		submissions:     newDownloadSubmissions(),
 	}
 }
 
-// CreateDownloadRequest represents the request body for creating a download
+// SetTakedowns rejects direct downloads whose URL matches an active content
+// takedown before any job is queued.
+func (h *DownloadHandlers) SetTakedowns(takedowns downloadTakedownChecker) {
+	h.takedowns = takedowns
+}
+
+// SetQueuePositions adds queue position and estimated start time to queued
+// jobs in job responses.
+func (h *DownloadHandlers) SetQueuePositions(positions downloadQueuePositions) {
+	h.positions = positions
+}
+
+// SetShortLinks expands provider short links to their canonical URL before
+// the job is created, so the same track pasted as a short and a full link is
+// one source.
+func (h *DownloadHandlers) SetShortLinks(shortLinks downloadURLExpander) {
+	h.shortLinks = shortLinks
+}
+
+// SetPlaylistEnumerator makes YouTube playlist and SoundCloud set URLs queue
+// one child job per entry under a parent job, rather than a single job.
+func (h *DownloadHandlers) SetPlaylistEnumerator(enumerator downloadPlaylistEnumerator) {
+	h.enumerator = enumerator
+}
+
+// SetDestinationPlaylists checks that a playlist_id given on a download is a
+// playlist the caller can add to before the job is created.
+func (h *DownloadHandlers) SetDestinationPlaylists(playlists destinationPlaylists) {
+	h.playlists = playlists
+}
+
+// CreateDownloadRequest represents the request body for creating a download.
+// The embedded destination fields override the user's download settings for
+// this download only. Priority defaults to interactive, or batch for a
+// playlist URL; clients queueing many downloads at once send batch so they do
+// not hold up the user's others.
 type CreateDownloadRequest struct {
 	URL          string       `json:"url"`
 	SourceType   string       `json:"source_type"`
+	Priority     string       `json:"priority,omitempty"`
 	PageMetadata PageMetadata `json:"page_metadata,omitempty"`
+	download.Destination
 }
 
 // PageMetadata contains metadata extracted from the source page
@@ -59,11 +137,14 @@ type PageMetadata struct {
 	Thumbnail string `json:"thumbnail,omitempty"`
 }
 
-// CreateDownloadResponse represents the response for a created download job
+// CreateDownloadResponse represents the response for a created download job.
+// A playlist download has no source decision of its own; ChildJobIDs lists
+// its items instead.
 type CreateDownloadResponse struct {
-	JobID            string `json:"job_id"`
-	Status           string `json:"status"`
-	SourceDecisionID string `json:"sourceDecisionId"`
+	JobID            string   `json:"job_id"`
+	Status           string   `json:"status"`
+	SourceDecisionID string   `json:"sourceDecisionId,omitempty"`
+	ChildJobIDs      []string `json:"child_job_ids,omitempty"`
 }
 
 // DownloadErrorResponse represents an error response
@@ -80,13 +161,28 @@ type GetJobResponse struct {
 	Error       string  `json:"error,omitempty"`
 	URL         string  `json:"url"`
 	SourceType  string  `json:"source_type"`
+	Priority    string  `json:"priority,omitempty"`
+	RetryCount  int     `json:"retry_count"`
 	TrackID     *int64  `json:"track_id,omitempty"`
 	CreatedAt   string  `json:"created_at"`
 	StartedAt   *string `json:"started_at,omitempty"`
 	CompletedAt *string `json:"completed_at,omitempty"`
+	RequestID   string  `json:"request_id,omitempty"`
+	// ParentJobID is set on a playlist download's items; ChildJobIDs and
+	// the item counts on the playlist download itself.
+	ParentJobID    string   `json:"parent_job_id,omitempty"`
+	ChildJobIDs    []string `json:"child_job_ids,omitempty"`
+	CompletedItems int      `json:"completed_items,omitempty"`
+	FailedItems    int      `json:"failed_items,omitempty"`
+	// QueuePosition is 1 for the next job a worker picks up; it and
+	// EstimatedStartAt are only set while the job is queued.
+	QueuePosition    int     `json:"queue_position,omitempty"`
+	EstimatedStartAt *string `json:"estimated_start_at,omitempty"`
 }
 
-// CreateDownload handles POST /api/v1/downloads
+// CreateDownload handles POST /api/v1/downloads. A repeat submission of the
+// same source by the same user, while the first is in flight or within a few
+// seconds of it, returns the first job with 200 instead of creating another.
 func (h *DownloadHandlers) CreateDownload(w http.ResponseWriter, r *http.Request) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	if userCtx == nil {
@@ -99,29 +195,164 @@ func (h *DownloadHandlers) CreateDownload(w http.ResponseWriter, r *http.Request
 		writeDownloadError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
 		return
 	}
+	if h.shortLinks != nil {
+		expanded, err := h.shortLinks.Expand(r.Context(), strings.TrimSpace(req.URL))
+		if err != nil {
+			writeDownloadError(w, http.StatusUnprocessableEntity, "SHORT_LINK_UNRESOLVED", "could not resolve the short link to a track or playlist")
+			return
+		}
+		req.URL = expanded
+	}
 	candidate, err := normalizedDirectCandidate(req)
 	if err != nil {
 		writeDownloadError(w, http.StatusBadRequest, "INVALID_URL", err.Error())
 		return
 	}
+	isPlaylist := h.enumerator != nil && candidate.Metadata["mediaType"] == "playlist"
+	priority := download.PriorityInteractive
+	if isPlaylist {
+		priority = download.PriorityBatch
+	}
+	if req.Priority != "" {
+		if !download.ValidPriority(req.Priority) {
+			writeDownloadError(w, http.StatusBadRequest, "INVALID_PRIORITY", "priority must be interactive or batch")
+			return
+		}
+		priority = req.Priority
+	}
+	if req.PlaylistID != nil {
+		if *req.PlaylistID <= 0 {
+			writeDownloadError(w, http.StatusBadRequest, "INVALID_PLAYLIST", "playlist_id must be a positive playlist ID")
+			return
+		}
+		if h.playlists != nil {
+			if err := checkDestinationPlaylist(r.Context(), h.playlists, userCtx.UserID, *req.PlaylistID); err != nil {
+				writeDestinationPlaylistError(writeDownloadError, w, err)
+				return
+			}
+		}
+	}
+	candidate.Metadata = download.WithDestination(candidate.Metadata, req.Destination)
+	if h.takedowns != nil {
+		takedown, err := h.takedowns.FindActive(r.Context(), candidate.SourceURL, "")
+		if err != nil {
+			writeDownloadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check content takedowns")
+			return
+		}
+		if takedown != nil {
+			writeDownloadError(w, http.StatusUnavailableForLegalReasons, "CONTENT_TAKEN_DOWN", "this source has been taken down: "+takedown.Reason)
+			return
+		}
+	}
 	if h.ingestion == nil || h.downloadService == nil {
 		writeDownloadError(w, http.StatusServiceUnavailable, "DOWNLOAD_UNAVAILABLE", "download processing is unavailable")
 		return
 	}
+	submissionKey := userCtx.UserID.String() + " " + candidate.SourceURL
+	existing, err := h.submissions.acquire(r.Context(), submissionKey)
+	if err != nil {
+		writeDownloadError(w, http.StatusServiceUnavailable, "DOWNLOAD_UNAVAILABLE", "request canceled while waiting for a matching download")
+		return
+	}
+	if existing != nil {
+		writeDownloadJSON(w, http.StatusOK, existing)
+		return
+	}
+	var created *CreateDownloadResponse
+	defer func() { h.submissions.release(submissionKey, created) }()
+
+	if isPlaylist {
+		created = h.createPlaylistDownload(w, download.WithPriority(r.Context(), priority), userCtx.UserID, candidate, req)
+		return
+	}
+
 	persisted, err := h.ingestion.CreateTrustedDownload(r.Context(), userCtx.UserID, db.SourceSelectionOriginDirectURL, candidate, "server-normalized authenticated direct/share URL")
 	if err != nil {
 		writeDownloadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to persist trusted download")
 		return
 	}
-	job, err := h.ingestion.EnqueueTrustedDownload(r.Context(), persisted, h.downloadService)
+	job, err := h.ingestion.EnqueueTrustedDownload(download.WithPriority(r.Context(), priority), persisted, h.downloadService)
 	if err != nil {
 		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to enqueue trusted download")
 		return
 	}
 
-	writeDownloadJSON(w, http.StatusCreated, CreateDownloadResponse{
+	created = &CreateDownloadResponse{
 		JobID: job.ID, Status: job.Status, SourceDecisionID: persisted.Decision.ID.String(),
-	})
+	}
+	writeDownloadJSON(w, http.StatusCreated, created)
+}
+
+// createPlaylistDownload enumerates a playlist URL and queues each entry as a
+// child of one parent job. Entries that cannot be queued are skipped; the
+// request fails only when none can be. It returns the response it wrote on
+// success, or nil.
+func (h *DownloadHandlers) createPlaylistDownload(w http.ResponseWriter, ctx context.Context, userID uuid.UUID, playlist download.SourceCandidate, req CreateDownloadRequest) *CreateDownloadResponse {
+	metadata, entries, err := h.enumerator.Enumerate(ctx, playlist.SourceURL, maxPlaylistDownloadItems)
+	if err != nil {
+		writeDownloadError(w, http.StatusBadGateway, "PLAYLIST_UNRESOLVED", "could not list the playlist's entries")
+		return nil
+	}
+	candidates := make([]download.SourceCandidate, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Unavailable || entry.SourceURL == "" {
+			continue
+		}
+		candidate, err := normalizedDirectCandidate(CreateDownloadRequest{
+			URL:          entry.SourceURL,
+			PageMetadata: PageMetadata{Title: entry.Title, Thumbnail: entry.ThumbnailURL},
+		})
+		if err != nil {
+			continue
+		}
+		candidate.Artist = entry.Artist
+		candidate.Album = entry.Album
+		candidate.Uploader = entry.Uploader
+		candidate.DurationMs = entry.DurationMs
+		candidate.Metadata["playlistURL"] = playlist.SourceURL
+		candidate.Metadata = download.WithDestination(candidate.Metadata, req.Destination)
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		writeDownloadError(w, http.StatusUnprocessableEntity, "PLAYLIST_EMPTY", "the playlist has no downloadable entries")
+		return nil
+	}
+
+	title := metadata.Title
+	if title == "" {
+		title = playlist.Title
+	}
+	parent, err := h.downloadService.CreateParentJob(ctx, userID.String(), playlist.SourceURL, playlist.Provider, title)
+	if err != nil {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to create playlist download")
+		return nil
+	}
+	childCtx := download.WithParentJob(ctx, parent.ID)
+	childIDs := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		persisted, err := h.ingestion.CreateTrustedDownload(childCtx, userID, db.SourceSelectionOriginDirectURL, candidate, "server-normalized entry of authenticated direct playlist URL")
+		if err != nil {
+			continue
+		}
+		job, err := h.ingestion.EnqueueTrustedDownload(childCtx, persisted, h.downloadService)
+		if err != nil {
+			continue
+		}
+		childIDs = append(childIDs, job.ID)
+	}
+	if len(childIDs) == 0 {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to enqueue any playlist entry")
+		return nil
+	}
+	parent, err = h.downloadService.AttachChildJobs(ctx, parent.ID, childIDs)
+	if err != nil {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to record playlist entries")
+		return nil
+	}
+
+	created := &CreateDownloadResponse{JobID: parent.ID, Status: parent.Status, ChildJobIDs: parent.ChildJobIDs}
+	writeDownloadJSON(w, http.StatusCreated, created)
+	return created
 }
 
 func decodeCreateDownloadRequest(w http.ResponseWriter, r *http.Request, req *CreateDownloadRequest) error {
@@ -168,11 +399,22 @@ func normalizedDirectCandidate(req CreateDownloadRequest) (download.SourceCandid
 	normalized := parsed.String()
 	digest := sha256.Sum256([]byte(normalized))
 	sourceID := fmt.Sprintf("%x", digest[:16])
+	metadata := map[string]interface{}{"trustedIngestion": true, "origin": db.SourceSelectionOriginDirectURL}
+	// A URL the validators recognize is stored in canonical form under its
+	// media ID, so share links, tracking parameters, and music./m./youtu.be
+	// variants of one video dedup to a single source. Anything else keeps the
+	// hashed normalized URL.
+	if result := validators.DefaultRegistry().Validate(normalized); result.Valid && result.Canonical != "" && result.MediaID != "" {
+		normalized = result.Canonical
+		sourceID = result.MediaID
+		metadata["mediaType"] = result.MediaType
+		metadata["canonicalizedFrom"] = parsed.Host
+	}
 	title := strings.TrimSpace(req.PageMetadata.Title)
 	if title == "" {
 		title = "Shared " + provider + " source"
 	}
-	return download.SourceCandidate{CandidateID: provider + ":" + sourceID, Provider: provider, SourceID: sourceID, SourceURL: normalized, Title: title, ThumbnailURL: strings.TrimSpace(req.PageMetadata.Thumbnail), Metadata: map[string]interface{}{"trustedIngestion": true, "origin": db.SourceSelectionOriginDirectURL}}, nil
+	return download.SourceCandidate{CandidateID: provider + ":" + sourceID, Provider: provider, SourceID: sourceID, SourceURL: normalized, Title: title, ThumbnailURL: strings.TrimSpace(req.PageMetadata.Thumbnail), Metadata: metadata}, nil
 }
 
 // GetJob handles GET /api/v1/downloads/{job_id}
@@ -201,69 +443,196 @@ func (h *DownloadHandlers) GetJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := GetJobResponse{
-		JobID:      job.ID,
-		Status:     job.Status,
-		Progress:   job.Progress,
-		Error:      job.Error,
-		URL:        job.URL,
-		SourceType: job.SourceType,
-		TrackID:    job.TrackID,
-		CreatedAt:  job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	var positions map[string]download.QueuePosition
+	if job.Status == download.StatusQueued {
+		positions = h.queuePositions(r.Context())
 	}
+	writeDownloadJSON(w, http.StatusOK, newGetJobResponse(job, positions))
+}
 
-	if job.StartedAt != nil {
-		startedAt := job.StartedAt.Format("2006-01-02T15:04:05Z")
-		resp.StartedAt = &startedAt
+// ChangeJob handles POST /api/v1/downloads/{job_id}/{action} for the retry
+// and cancel actions. The actions share one route so that it does not clash
+// with POST /api/v1/downloads/release/{mb_release_id}.
+func (h *DownloadHandlers) ChangeJob(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("action") {
+	case "retry":
+		h.RetryJob(w, r)
+	case "cancel":
+		h.CancelJob(w, r)
+	default:
+		http.NotFound(w, r)
 	}
-	if job.CompletedAt != nil {
-		completedAt := job.CompletedAt.Format("2006-01-02T15:04:05Z")
-		resp.CompletedAt = &completedAt
+}
+
+// RetryJob handles POST /api/v1/downloads/{job_id}/retry, putting a failed
+// or cancelled job back on the queue.
+func (h *DownloadHandlers) RetryJob(w http.ResponseWriter, r *http.Request) {
+	h.changeJob(w, r, h.downloadService.RetryJob, "JOB_NOT_RETRYABLE", "job is not failed or cancelled, or has used all its retries")
+}
+
+// CancelJob handles POST /api/v1/downloads/{job_id}/cancel. A queued job is
+// taken off the queue; a running one has its download process killed.
+func (h *DownloadHandlers) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.changeJob(w, r, h.downloadService.CancelJob, "JOB_NOT_CANCELLABLE", "job has already finished")
+}
+
+// changeJob applies change to the caller's job and responds with the job as
+// it stands afterwards. change failing with ErrJobNotRetryable or
+// ErrJobNotCancellable is a conflict reported with conflictCode.
+func (h *DownloadHandlers) changeJob(w http.ResponseWriter, r *http.Request, change func(context.Context, string) error, conflictCode, conflictMessage string) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
 	}
 
-	writeDownloadJSON(w, http.StatusOK, resp)
+	jobID := r.PathValue("job_id")
+	if jobID == "" {
+		writeDownloadError(w, http.StatusBadRequest, "INVALID_REQUEST", "job_id is required")
+		return
+	}
+
+	job, err := h.downloadService.GetJob(r.Context(), jobID)
+	if err != nil || job.UserID != userCtx.UserID.String() {
+		writeDownloadError(w, http.StatusNotFound, "JOB_NOT_FOUND", "job not found")
+		return
+	}
+
+	if err := change(r.Context(), jobID); err != nil {
+		switch {
+		case errors.Is(err, download.ErrJobNotRetryable), errors.Is(err, download.ErrJobNotCancellable):
+			writeDownloadError(w, http.StatusConflict, conflictCode, conflictMessage)
+		case errors.Is(err, download.ErrJobNotFound):
+			writeDownloadError(w, http.StatusNotFound, "JOB_NOT_FOUND", "job not found")
+		default:
+			writeDownloadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update job")
+		}
+		return
+	}
+
+	job, err = h.downloadService.GetJob(r.Context(), jobID)
+	if err != nil {
+		writeDownloadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to retrieve job")
+		return
+	}
+	var positions map[string]download.QueuePosition
+	if job.Status == download.StatusQueued {
+		positions = h.queuePositions(r.Context())
+	}
+	writeDownloadJSON(w, http.StatusOK, newGetJobResponse(job, positions))
 }
 
 // GetUserJobs handles GET /api/v1/downloads
 func (h *DownloadHandlers) GetUserJobs(w http.ResponseWriter, r *http.Request) {
+	responses, ok := h.userJobs(w, r)
+	if !ok {
+		return
+	}
+
+	writeDownloadJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs": responses,
+	})
+}
+
+// GetUserJobsPage handles GET /api/v2/downloads. The job list is not
+// paginated in storage, so the page is cut from the full list.
+func (h *DownloadHandlers) GetUserJobsPage(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 50)
+	if limit == 0 {
+		limit = 50
+	}
+	offset, err := pagination.Offset(r, parseIntParam(r, "offset", 0))
+	if err != nil {
+		writeDownloadError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid or expired")
+		return
+	}
+
+	responses, ok := h.userJobs(w, r)
+	if !ok {
+		return
+	}
+
+	total := len(responses)
+	page, offset := pagination.Slice(responses, limit, offset)
+	pagination.Write(w, page, limit, offset, &total)
+}
+
+func (h *DownloadHandlers) userJobs(w http.ResponseWriter, r *http.Request) ([]GetJobResponse, bool) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	if userCtx == nil {
 		writeDownloadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
-		return
+		return nil, false
 	}
 
 	jobs, err := h.downloadService.GetUserJobs(r.Context(), userCtx.UserID.String())
 	if err != nil {
 		writeDownloadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to retrieve jobs")
-		return
+		return nil, false
 	}
 
-	responses := make([]GetJobResponse, 0, len(jobs))
+	var positions map[string]download.QueuePosition
 	for _, job := range jobs {
-		resp := GetJobResponse{
-			JobID:      job.ID,
-			Status:     job.Status,
-			Progress:   job.Progress,
-			Error:      job.Error,
-			URL:        job.URL,
-			SourceType: job.SourceType,
-			TrackID:    job.TrackID,
-			CreatedAt:  job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		if job.Status == download.StatusQueued {
+			positions = h.queuePositions(r.Context())
+			break
 		}
-		if job.StartedAt != nil {
-			startedAt := job.StartedAt.Format("2006-01-02T15:04:05Z")
-			resp.StartedAt = &startedAt
-		}
-		if job.CompletedAt != nil {
-			completedAt := job.CompletedAt.Format("2006-01-02T15:04:05Z")
-			resp.CompletedAt = &completedAt
-		}
-		responses = append(responses, resp)
 	}
+	responses := make([]GetJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, newGetJobResponse(job, positions))
+	}
+	return responses, true
+}
 
-	writeDownloadJSON(w, http.StatusOK, map[string]interface{}{
-		"jobs": responses,
-	})
+// queuePositions returns the current queue positions, or nil when they are
+// not configured or cannot be read; positions are a hint, not worth failing
+// the response over.
+func (h *DownloadHandlers) queuePositions(ctx context.Context) map[string]download.QueuePosition {
+	if h.positions == nil {
+		return nil
+	}
+	positions, err := h.positions.QueuePositions(ctx)
+	if err != nil {
+		return nil
+	}
+	return positions
+}
+
+func newGetJobResponse(job *download.DownloadJob, positions map[string]download.QueuePosition) GetJobResponse {
+	resp := GetJobResponse{
+		JobID:      job.ID,
+		Status:     job.Status,
+		Progress:   job.Progress,
+		Error:      job.Error,
+		URL:        job.URL,
+		SourceType: job.SourceType,
+		Priority:   job.Priority,
+		RetryCount: job.RetryCount,
+		TrackID:    job.TrackID,
+		CreatedAt:  job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		RequestID:  job.RequestID,
+
+		ParentJobID:    job.ParentJobID,
+		ChildJobIDs:    job.ChildJobIDs,
+		CompletedItems: job.CompletedItems,
+		FailedItems:    job.FailedItems,
+	}
+	if job.StartedAt != nil {
+		startedAt := job.StartedAt.Format("2006-01-02T15:04:05Z")
+		resp.StartedAt = &startedAt
+	}
+	if job.CompletedAt != nil {
+		completedAt := job.CompletedAt.Format("2006-01-02T15:04:05Z")
+		resp.CompletedAt = &completedAt
+	}
+	if position, ok := positions[job.ID]; ok && job.Status == download.StatusQueued {
+		resp.QueuePosition = position.Position
+		if position.EstimatedStartAt != nil {
+			estimated := position.EstimatedStartAt.UTC().Format("2006-01-02T15:04:05Z")
+			resp.EstimatedStartAt = &estimated
+		}
+	}
+	return resp
 }
 
 func writeDownloadJSON(w http.ResponseWriter, status int, data interface{}) {
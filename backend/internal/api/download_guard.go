@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// downloadSubmissionWindow is how long a created download answers repeat
+// submissions of the same source by the same user.
+const downloadSubmissionWindow = 10 * time.Second
+
+// downloadSubmissions holds a short-lived per-user lock on each canonical
+// source URL being submitted, so a double-clicked download button or two
+// devices submitting at once create one job. The lock lives in memory: it
+// covers one API instance, which is how the server is deployed.
+type downloadSubmissions struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]*downloadSubmission
+}
+
+type downloadSubmission struct {
+	done    chan struct{}
+	resp    *CreateDownloadResponse
+	expires time.Time
+}
+
+func newDownloadSubmissions() *downloadSubmissions {
+	return &downloadSubmissions{now: time.Now, entries: make(map[string]*downloadSubmission)}
+}
+
+// acquire takes the lock for key. When another request already holds it,
+// acquire waits for that request and returns its response; a nil response
+// with a nil error means the caller now holds the lock and must call release.
+func (s *downloadSubmissions) acquire(ctx context.Context, key string) (*CreateDownloadResponse, error) {
+	for {
+		s.mu.Lock()
+		now := s.now()
+		for k, entry := range s.entries {
+			if entry.resp != nil && !now.Before(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		entry, held := s.entries[key]
+		if !held {
+			s.entries[key] = &downloadSubmission{done: make(chan struct{})}
+			s.mu.Unlock()
+			return nil, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-entry.done:
+			if entry.resp != nil {
+				return entry.resp, nil
+			}
+			// The first submission failed; try again rather than report its
+			// error for it.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release ends the caller's hold on key. A non-nil resp is handed to waiting
+// and later repeat submissions until the window closes; a nil resp frees the
+// key at once so a retry can create the job.
+func (s *downloadSubmissions) release(key string, resp *CreateDownloadResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[key]
+	if entry == nil {
+		return
+	}
+	if resp == nil {
+		delete(s.entries, key)
+	} else {
+		entry.resp = resp
+		entry.expires = s.now().Add(downloadSubmissionWindow)
+	}
+	close(entry.done)
+}
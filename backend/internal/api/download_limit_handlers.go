@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+const maxProviderConcurrency = 32
+
+// maxDownloadBytesPerSecond bounds the bandwidth cap an admin can set, at
+// 1 GiB/s.
+const maxDownloadBytesPerSecond = 1 << 30
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type providerLimitStore interface {
+	Snapshot() []download.ProviderLimitStatus
+	SetLimit(provider string, limit int)
+}
+
+type downloadScheduleStore interface {
+	Settings() download.ScheduleSettings
+	Update(download.ScheduleSettings)
+	Allow(now time.Time) bool
+}
+
+// DownloadLimitHandlers lets admins view and adjust per-provider download
+// concurrency, the download window, and the bandwidth cap while workers are
+// running.
+type DownloadLimitHandlers struct {
+	limits   providerLimitStore
+	schedule downloadScheduleStore
+}
+
+func NewDownloadLimitHandlers(limits providerLimitStore) *DownloadLimitHandlers {
+	return &DownloadLimitHandlers{limits: limits}
+}
+
+// SetSchedule enables the download window and bandwidth cap settings.
+func (h *DownloadLimitHandlers) SetSchedule(schedule downloadScheduleStore) {
+	h.schedule = schedule
+}
+
+type ProviderLimitResponse struct {
+	Provider string `json:"provider"`
+	Limit    *int   `json:"limit"`
+	InFlight int    `json:"inFlight"`
+}
+
+type UpdateProviderLimitRequest struct {
+	Limit *int `json:"limit"`
+}
+
+// ListLimits handles GET /api/v1/admin/download-limits
+func (h *DownloadLimitHandlers) ListLimits(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	writeDownloadLimitJSON(w, http.StatusOK, map[string]interface{}{"providers": h.providerResponses()})
+}
+
+// UpdateLimit handles PUT /api/v1/admin/download-limits/{provider}. A null
+// limit removes the provider's cap.
+func (h *DownloadLimitHandlers) UpdateLimit(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	provider := r.PathValue("provider")
+	if !providerNamePattern.MatchString(provider) {
+		writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_PROVIDER", "provider must be 1-32 lowercase letters, digits, '_' or '-'")
+		return
+	}
+	var req UpdateProviderLimitRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Limit != nil && (*req.Limit < 1 || *req.Limit > maxProviderConcurrency) {
+		writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be between 1 and 32, or null to remove the cap")
+		return
+	}
+
+	limit := 0
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	h.limits.SetLimit(provider, limit)
+
+	resp := ProviderLimitResponse{Provider: provider, Limit: req.Limit}
+	for _, status := range h.limits.Snapshot() {
+		if status.Provider == provider {
+			resp.InFlight = status.InFlight
+		}
+	}
+	writeDownloadLimitJSON(w, http.StatusOK, resp)
+}
+
+// DownloadScheduleSettings is the download window and bandwidth cap. A null
+// window lets jobs start at any time; a null or zero rate is uncapped.
+type DownloadScheduleSettings struct {
+	Window            *string `json:"window"`
+	MaxBytesPerSecond *int64  `json:"maxBytesPerSecond"`
+}
+
+type DownloadScheduleResponse struct {
+	DownloadScheduleSettings
+	// DownloadingAllowed is whether workers may start jobs right now.
+	DownloadingAllowed bool `json:"downloadingAllowed"`
+}
+
+// GetSchedule handles GET /api/v1/admin/downloads/settings
+func (h *DownloadLimitHandlers) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.requireSchedule(w) {
+		return
+	}
+	writeDownloadLimitJSON(w, http.StatusOK, h.scheduleResponse())
+}
+
+// UpdateSchedule handles PUT /api/v1/admin/downloads/settings, replacing
+// both settings. Jobs already running keep their rate; outside a new window
+// workers stop taking jobs but let running ones finish.
+func (h *DownloadLimitHandlers) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.requireSchedule(w) {
+		return
+	}
+	var req DownloadScheduleSettings
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+
+	var settings download.ScheduleSettings
+	if req.Window != nil {
+		window, err := download.ParseDownloadWindow(*req.Window)
+		if err != nil {
+			writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_WINDOW", "window must be two different HH:MM times like 01:00-07:00, or null for any time")
+			return
+		}
+		settings.Window = &window
+	}
+	if req.MaxBytesPerSecond != nil {
+		if *req.MaxBytesPerSecond < 0 || *req.MaxBytesPerSecond > maxDownloadBytesPerSecond {
+			writeDownloadLimitError(w, http.StatusBadRequest, "INVALID_RATE", "maxBytesPerSecond must be between 0 and 1073741824, or null to remove the cap")
+			return
+		}
+		settings.MaxBytesPerSecond = *req.MaxBytesPerSecond
+	}
+	h.schedule.Update(settings)
+	writeDownloadLimitJSON(w, http.StatusOK, h.scheduleResponse())
+}
+
+func (h *DownloadLimitHandlers) scheduleResponse() DownloadScheduleResponse {
+	settings := h.schedule.Settings()
+	resp := DownloadScheduleResponse{DownloadingAllowed: h.schedule.Allow(time.Now())}
+	if settings.Window != nil {
+		window := settings.Window.String()
+		resp.Window = &window
+	}
+	if settings.MaxBytesPerSecond > 0 {
+		rate := settings.MaxBytesPerSecond
+		resp.MaxBytesPerSecond = &rate
+	}
+	return resp
+}
+
+func (h *DownloadLimitHandlers) requireSchedule(w http.ResponseWriter) bool {
+	if h.schedule == nil {
+		writeDownloadLimitError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "download scheduling is unavailable")
+		return false
+	}
+	return true
+}
+
+func (h *DownloadLimitHandlers) providerResponses() []ProviderLimitResponse {
+	statuses := h.limits.Snapshot()
+	providers := make([]ProviderLimitResponse, 0, len(statuses))
+	for _, status := range statuses {
+		item := ProviderLimitResponse{Provider: status.Provider, InFlight: status.InFlight}
+		if status.Limit > 0 {
+			limit := status.Limit
+			item.Limit = &limit
+		}
+		providers = append(providers, item)
+	}
+	return providers
+}
+
+func (h *DownloadLimitHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadLimitError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return false
+	}
+	if !userCtx.IsAdmin() {
+		writeDownloadLimitError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return false
+	}
+	return true
+}
+
+func writeDownloadLimitJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeDownloadLimitError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+func downloadLimitRequest(method, provider, body, email string) *http.Request {
+	target := "/api/v1/admin/download-limits"
+	if provider != "" {
+		target += "/" + provider
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetPathValue("provider", provider)
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestDownloadLimitsUpdateAndListForAdmins(t *testing.T) {
+	limits := download.NewProviderLimits(map[string]int{"youtube": 2})
+	limits.TryAcquire("youtube")
+	h := NewDownloadLimitHandlers(limits)
+
+	rec := httptest.NewRecorder()
+	h.UpdateLimit(rec, downloadLimitRequest(http.MethodPut, "soundcloud", `{"limit":1}`, "ops@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.UpdateLimit(rec, downloadLimitRequest(http.MethodPut, "youtube", `{"limit":null}`, "ops@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("remove status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ListLimits(rec, downloadLimitRequest(http.MethodGet, "", "", "ops@example.test"))
+	var resp struct {
+		Providers []ProviderLimitResponse `json:"providers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Providers) != 2 ||
+		resp.Providers[0].Provider != "soundcloud" || resp.Providers[0].Limit == nil || *resp.Providers[0].Limit != 1 ||
+		resp.Providers[1].Provider != "youtube" || resp.Providers[1].Limit != nil || resp.Providers[1].InFlight != 1 {
+		t.Fatalf("providers = %+v", resp.Providers)
+	}
+}
+
+func TestDownloadLimitsRejectInvalidInputAndNonAdmins(t *testing.T) {
+	h := NewDownloadLimitHandlers(download.NewProviderLimits(nil))
+	cases := []struct {
+		name     string
+		provider string
+		body     string
+		email    string
+		want     int
+	}{
+		{"non-admin", "youtube", `{"limit":1}`, "user@example.test", http.StatusForbidden},
+		{"bad provider", "YouTube!", `{"limit":1}`, "ops@example.test", http.StatusBadRequest},
+		{"zero limit", "youtube", `{"limit":0}`, "ops@example.test", http.StatusBadRequest},
+		{"too large", "youtube", `{"limit":33}`, "ops@example.test", http.StatusBadRequest},
+		{"unknown field", "youtube", `{"max":1}`, "ops@example.test", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		h.UpdateLimit(rec, downloadLimitRequest(http.MethodPut, tc.provider, tc.body, tc.email))
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestDownloadScheduleUpdateAndGet(t *testing.T) {
+	schedule := download.NewDownloadSchedule(download.ScheduleSettings{})
+	h := NewDownloadLimitHandlers(download.NewProviderLimits(nil))
+	h.SetSchedule(schedule)
+
+	rec := httptest.NewRecorder()
+	h.UpdateSchedule(rec, downloadLimitRequest(http.MethodPut, "", `{"window":"01:00-07:00","maxBytesPerSecond":1048576}`, "ops@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	settings := schedule.Settings()
+	if settings.Window == nil || settings.Window.String() != "01:00-07:00" || settings.MaxBytesPerSecond != 1<<20 {
+		t.Fatalf("schedule = %+v, want the window and cap applied", settings)
+	}
+
+	rec = httptest.NewRecorder()
+	h.UpdateSchedule(rec, downloadLimitRequest(http.MethodPut, "", `{"window":null,"maxBytesPerSecond":null}`, "ops@example.test"))
+	if rec.Code != http.StatusOK || schedule.Settings().Window != nil || schedule.Settings().MaxBytesPerSecond != 0 {
+		t.Fatalf("clear status = %d, schedule = %+v", rec.Code, schedule.Settings())
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetSchedule(rec, downloadLimitRequest(http.MethodGet, "", "", "ops@example.test"))
+	var resp DownloadScheduleResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Window != nil || resp.MaxBytesPerSecond != nil || !resp.DownloadingAllowed {
+		t.Fatalf("settings = %+v, want no window, no cap, and downloading allowed", resp)
+	}
+
+	for name, body := range map[string]string{
+		"bad window":    `{"window":"7am-1am"}`,
+		"negative rate": `{"maxBytesPerSecond":-1}`,
+		"unknown field": `{"rate":10}`,
+	} {
+		rec = httptest.NewRecorder()
+		h.UpdateSchedule(rec, downloadLimitRequest(http.MethodPut, "", body, "ops@example.test"))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.UpdateSchedule(rec, downloadLimitRequest(http.MethodPut, "", `{"window":null}`, "user@example.test"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	defaultDownloadReportDays = 7
+	maxDownloadReportDays     = 90
+)
+
+type downloadOutcomeStore interface {
+	ProviderReport(ctx context.Context, since time.Time) ([]db.ProviderDownloadReport, error)
+}
+
+// DownloadOutcomeHandlers serves the admin per-provider download report.
+type DownloadOutcomeHandlers struct {
+	store downloadOutcomeStore
+	now   func() time.Time
+}
+
+func NewDownloadOutcomeHandlers(store downloadOutcomeStore) *DownloadOutcomeHandlers {
+	return &DownloadOutcomeHandlers{store: store, now: time.Now}
+}
+
+type ProviderDownloadReportResponse struct {
+	Provider      string         `json:"provider"`
+	Total         int            `json:"total"`
+	SuccessRate   float64        `json:"successRate"`
+	Outcomes      map[string]int `json:"outcomes"`
+	LastSuccessAt *string        `json:"lastSuccessAt,omitempty"`
+	LastFailureAt *string        `json:"lastFailureAt,omitempty"`
+}
+
+type DownloadOutcomeReportResponse struct {
+	Since     string                           `json:"since"`
+	Days      int                              `json:"days"`
+	Providers []ProviderDownloadReportResponse `json:"providers"`
+}
+
+// GetProviderReport handles GET /api/v1/admin/download-outcomes?days=7
+func (h *DownloadOutcomeHandlers) GetProviderReport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadOutcomeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if !userCtx.IsAdmin() {
+		writeDownloadOutcomeError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return
+	}
+
+	days := defaultDownloadReportDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDownloadReportDays {
+			writeDownloadOutcomeError(w, http.StatusBadRequest, "INVALID_REQUEST", "days must be between 1 and 90")
+			return
+		}
+		days = parsed
+	}
+	since := h.now().UTC().AddDate(0, 0, -days)
+
+	reports, err := h.store.ProviderReport(r.Context(), since)
+	if err != nil {
+		writeDownloadOutcomeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load download outcomes")
+		return
+	}
+
+	resp := DownloadOutcomeReportResponse{
+		Since:     since.Format(time.RFC3339),
+		Days:      days,
+		Providers: make([]ProviderDownloadReportResponse, 0, len(reports)),
+	}
+	for _, report := range reports {
+		item := ProviderDownloadReportResponse{
+			Provider: report.Provider,
+			Total:    report.Total,
+			Outcomes: report.Outcomes,
+		}
+		if report.Total > 0 {
+			item.SuccessRate = float64(report.Outcomes["success"]) / float64(report.Total)
+		}
+		if report.LastSuccessAt.Valid {
+			lastSuccess := report.LastSuccessAt.Time.UTC().Format(time.RFC3339)
+			item.LastSuccessAt = &lastSuccess
+		}
+		if report.LastFailureAt.Valid {
+			lastFailure := report.LastFailureAt.Time.UTC().Format(time.RFC3339)
+			item.LastFailureAt = &lastFailure
+		}
+		resp.Providers = append(resp.Providers, item)
+	}
+
+	writeDownloadOutcomeJSON(w, http.StatusOK, resp)
+}
+
+func writeDownloadOutcomeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeDownloadOutcomeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeDownloadOutcomeStore struct {
+	since time.Time
+}
+
+func (f *fakeDownloadOutcomeStore) ProviderReport(ctx context.Context, since time.Time) ([]db.ProviderDownloadReport, error) {
+	f.since = since
+	return []db.ProviderDownloadReport{{
+		Provider:      "soundcloud",
+		Total:         4,
+		Outcomes:      map[string]int{"success": 1, "network": 3},
+		LastSuccessAt: sql.NullTime{Time: since.Add(time.Hour), Valid: true},
+	}}, nil
+}
+
+func downloadOutcomeRequest(rawQuery, email string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/download-outcomes?"+rawQuery, nil)
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestProviderReportComputesSuccessRateForAdmins(t *testing.T) {
+	store := &fakeDownloadOutcomeStore{}
+	h := NewDownloadOutcomeHandlers(store)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	rec := httptest.NewRecorder()
+	h.GetProviderReport(rec, downloadOutcomeRequest("days=2", "ops@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !store.since.Equal(now.AddDate(0, 0, -2)) {
+		t.Fatalf("since = %v, want two days back", store.since)
+	}
+	var resp DownloadOutcomeReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Providers) != 1 || resp.Providers[0].SuccessRate != 0.25 || resp.Providers[0].LastSuccessAt == nil || resp.Providers[0].LastFailureAt != nil {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestProviderReportRejectsNonAdminsAndBadWindows(t *testing.T) {
+	h := NewDownloadOutcomeHandlers(&fakeDownloadOutcomeStore{})
+
+	rec := httptest.NewRecorder()
+	h.GetProviderReport(rec, downloadOutcomeRequest("", "listener@example.test"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetProviderReport(rec, downloadOutcomeRequest("days=365", "ops@example.test"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("oversized window status = %d, want 400", rec.Code)
+	}
+}
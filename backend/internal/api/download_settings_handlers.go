@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type downloadSettingsStore interface {
+	GetDownloadSettings(ctx context.Context, userID uuid.UUID) (*db.DownloadSettings, error)
+	UpdateDownloadSettings(ctx context.Context, settings *db.DownloadSettings) error
+}
+
+// destinationPlaylists looks up playlists a download may be added to.
+type destinationPlaylists interface {
+	GetByID(ctx context.Context, id int64) (*db.Playlist, error)
+}
+
+var errReadOnlyDestination = errors.New("generated playlists cannot be edited")
+
+// checkDestinationPlaylist returns nil when downloads for userID may be
+// appended to the playlist: it exists, the user owns it, and it is not a
+// generated playlist.
+func checkDestinationPlaylist(ctx context.Context, playlists destinationPlaylists, userID uuid.UUID, playlistID int64) error {
+	playlist, err := playlists.GetByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+	if playlist.UserID != userID {
+		return db.ErrPlaylistNotFound
+	}
+	if playlist.SystemKind.Valid {
+		return errReadOnlyDestination
+	}
+	return nil
+}
+
+// writeDestinationPlaylistError reports a checkDestinationPlaylist failure
+// with the calling handler's error writer.
+func writeDestinationPlaylistError(write func(http.ResponseWriter, int, string, string), w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrPlaylistNotFound):
+		write(w, http.StatusNotFound, "PLAYLIST_NOT_FOUND", "playlist not found")
+	case errors.Is(err, errReadOnlyDestination):
+		write(w, http.StatusForbidden, "READ_ONLY_PLAYLIST", errReadOnlyDestination.Error())
+	default:
+		write(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check playlist")
+	}
+}
+
+// DownloadSettingsHandlers serves where the caller's finished downloads go
+// by default: the library, a playlist, and the front of the play queue.
+type DownloadSettingsHandlers struct {
+	settings  downloadSettingsStore
+	playlists destinationPlaylists
+}
+
+func NewDownloadSettingsHandlers(settings downloadSettingsStore, playlists destinationPlaylists) *DownloadSettingsHandlers {
+	return &DownloadSettingsHandlers{settings: settings, playlists: playlists}
+}
+
+// UpdateDownloadSettingsRequest replaces the settings. Omitted fields take
+// their defaults: added to the library, no playlist, not queued.
+type UpdateDownloadSettingsRequest struct {
+	AddToLibrary *bool  `json:"addToLibrary"`
+	PlaylistID   *int64 `json:"playlistId"`
+	QueueNext    *bool  `json:"queueNext"`
+}
+
+type DownloadSettingsResponse struct {
+	AddToLibrary bool       `json:"addToLibrary"`
+	PlaylistID   *int64     `json:"playlistId"`
+	QueueNext    bool       `json:"queueNext"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+}
+
+// GetSettings handles GET /api/v1/me/download-settings
+func (h *DownloadSettingsHandlers) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadSettingsError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	settings, err := h.settings.GetDownloadSettings(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeDownloadSettingsError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load download settings")
+		return
+	}
+	writeDownloadSettingsJSON(w, http.StatusOK, downloadSettingsResponse(settings))
+}
+
+// UpdateSettings handles PUT /api/v1/me/download-settings
+func (h *DownloadSettingsHandlers) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadSettingsError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req UpdateDownloadSettingsRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeDownloadSettingsError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+
+	settings := db.DefaultDownloadSettings(userCtx.UserID)
+	if req.AddToLibrary != nil {
+		settings.AddToLibrary = *req.AddToLibrary
+	}
+	if req.QueueNext != nil {
+		settings.QueueNext = *req.QueueNext
+	}
+	if req.PlaylistID != nil {
+		if *req.PlaylistID <= 0 {
+			writeDownloadSettingsError(w, http.StatusBadRequest, "INVALID_PLAYLIST", "playlistId must be a positive playlist ID or null")
+			return
+		}
+		if err := checkDestinationPlaylist(r.Context(), h.playlists, userCtx.UserID, *req.PlaylistID); err != nil {
+			writeDestinationPlaylistError(writeDownloadSettingsError, w, err)
+			return
+		}
+		settings.PlaylistID = sql.NullInt64{Int64: *req.PlaylistID, Valid: true}
+	}
+
+	if err := h.settings.UpdateDownloadSettings(r.Context(), settings); err != nil {
+		writeDownloadSettingsError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to save download settings")
+		return
+	}
+	writeDownloadSettingsJSON(w, http.StatusOK, downloadSettingsResponse(settings))
+}
+
+func downloadSettingsResponse(settings *db.DownloadSettings) DownloadSettingsResponse {
+	resp := DownloadSettingsResponse{AddToLibrary: settings.AddToLibrary, QueueNext: settings.QueueNext}
+	if settings.PlaylistID.Valid {
+		id := settings.PlaylistID.Int64
+		resp.PlaylistID = &id
+	}
+	if !settings.UpdatedAt.IsZero() {
+		updatedAt := settings.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp
+}
+
+func writeDownloadSettingsJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeDownloadSettingsError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
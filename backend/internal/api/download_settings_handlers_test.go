@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeDownloadSettingsStore struct {
+	saved map[uuid.UUID]db.DownloadSettings
+}
+
+func (f *fakeDownloadSettingsStore) GetDownloadSettings(_ context.Context, userID uuid.UUID) (*db.DownloadSettings, error) {
+	if s, ok := f.saved[userID]; ok {
+		return &s, nil
+	}
+	return db.DefaultDownloadSettings(userID), nil
+}
+
+func (f *fakeDownloadSettingsStore) UpdateDownloadSettings(_ context.Context, settings *db.DownloadSettings) error {
+	f.saved[settings.UserID] = *settings
+	return nil
+}
+
+func TestDownloadSettingsDefaultsAndReplace(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeDownloadSettingsStore{saved: map[uuid.UUID]db.DownloadSettings{}}
+	handlers := NewDownloadSettingsHandlers(store, fakeDestinationPlaylists{
+		3: {ID: 3, UserID: userID},
+		4: {ID: 4, UserID: userID, SystemKind: sql.NullString{String: "daily_mix", Valid: true}},
+		5: {ID: 5, UserID: uuid.New()},
+	})
+
+	rec := httptest.NewRecorder()
+	handlers.GetSettings(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/download-settings", nil), userID))
+	var got DownloadSettingsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if !got.AddToLibrary || got.PlaylistID != nil || got.QueueNext {
+		t.Fatalf("defaults = %+v, want library only", got)
+	}
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handlers.UpdateSettings(rec, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/download-settings", strings.NewReader(body)), userID))
+		return rec
+	}
+	rec = put(`{"addToLibrary":false,"playlistId":3,"queueNext":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	saved := store.saved[userID]
+	if saved.AddToLibrary || saved.PlaylistID.Int64 != 3 || !saved.QueueNext {
+		t.Fatalf("saved = %+v, want library off, playlist 3, queue next", saved)
+	}
+
+	// Omitted fields go back to their defaults.
+	if rec = put(`{"queueNext":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if saved := store.saved[userID]; !saved.AddToLibrary || saved.PlaylistID.Valid || !saved.QueueNext {
+		t.Fatalf("saved = %+v, want defaults except queue next", saved)
+	}
+
+	for body, want := range map[string]int{
+		`{"playlistId":4}`:  http.StatusForbidden,
+		`{"playlistId":5}`:  http.StatusNotFound,
+		`{"playlistId":0}`:  http.StatusBadRequest,
+		`{"autoPlay":true}`: http.StatusBadRequest,
+	} {
+		if rec := put(body); rec.Code != want {
+			t.Fatalf("PUT %s status = %d, want %d; body=%s", body, rec.Code, want, rec.Body.String())
+		}
+	}
+}
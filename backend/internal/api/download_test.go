@@ -3,17 +3,22 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/playlistimport"
 )
 
 func TestCreateDownloadRejectsNonHTTPUserFacingURLBeforeEnqueue(t *testing.T) {
@@ -88,6 +93,142 @@ func TestCreateDownloadEnqueueFailureKeepsTrustedAudit(t *testing.T) {
 	}
 }
 
+func TestCreateDownloadRejectsTakenDownSourceBeforeTrustedIngestion(t *testing.T) {
+	ingestion := &fakeDirectIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	takedowns := &fakeDownloadTakedowns{takedown: &db.ContentTakedown{ID: 3, Reason: "DMCA notice 42"}}
+	handler.SetTakedowns(takedowns)
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://soundcloud.com/artist/track#t=10"}`))
+	if rec.Code != http.StatusUnavailableForLegalReasons || !strings.Contains(rec.Body.String(), "CONTENT_TAKEN_DOWN") || !strings.Contains(rec.Body.String(), "DMCA notice 42") {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if takedowns.sourceURL != "https://soundcloud.com/artist/track" {
+		t.Fatalf("takedown check used %q, want the normalized URL", takedowns.sourceURL)
+	}
+	if ingestion.created != nil {
+		t.Fatalf("taken-down source reached ingestion: %+v", ingestion.created)
+	}
+}
+
+func TestCreateDownloadExpandsShortLinksBeforeIngestion(t *testing.T) {
+	ingestion := &fakeDirectIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	handler.SetShortLinks(fakeShortLinks{"https://on.soundcloud.com/AbCdE12345": "https://soundcloud.com/artist/track"})
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://on.soundcloud.com/AbCdE12345"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	full, err := normalizedDirectCandidate(CreateDownloadRequest{URL: "https://soundcloud.com/artist/track"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if ingestion.created.Candidate.SourceURL != full.SourceURL || ingestion.created.Candidate.CandidateID != full.CandidateID {
+		t.Fatalf("candidate = %+v, want the same source as the full link", ingestion.created.Candidate)
+	}
+
+	ingestion.created = nil
+	rec = httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://on.soundcloud.com/Unknown123"}`))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "SHORT_LINK_UNRESOLVED") || ingestion.created != nil {
+		t.Fatalf("unresolved short link status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNormalizedDirectCandidateDedupsShareLinkVariants(t *testing.T) {
+	var first download.SourceCandidate
+	for i, raw := range []string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=trackingToken",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share",
+		"https://m.youtube.com/shorts/dQw4w9WgXcQ",
+	} {
+		candidate, err := normalizedDirectCandidate(CreateDownloadRequest{URL: raw})
+		if err != nil {
+			t.Fatalf("normalize %s: %v", raw, err)
+		}
+		if i == 0 {
+			first = candidate
+			continue
+		}
+		if candidate.CandidateID != first.CandidateID || candidate.SourceURL != first.SourceURL {
+			t.Fatalf("%s normalized to %s (%s), want %s (%s)", raw, candidate.CandidateID, candidate.SourceURL, first.CandidateID, first.SourceURL)
+		}
+	}
+	if first.CandidateID != "youtube:dQw4w9WgXcQ" || first.SourceURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || first.Metadata["mediaType"] != "video" {
+		t.Fatalf("candidate = %+v, want the canonical video", first)
+	}
+}
+
+func TestCreateDownloadRecordsDestinationOverrides(t *testing.T) {
+	ingestion := &fakeDirectIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	owner := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	handler.SetDestinationPlaylists(fakeDestinationPlaylists{
+		7: {ID: 7, UserID: owner},
+		8: {ID: 8, UserID: uuid.New()},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://soundcloud.com/artist/track","add_to_library":false,"playlist_id":7,"queue_next":true}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	// Metadata is persisted as JSON, so read the override back the same way
+	// the processor does.
+	raw, err := json.Marshal(ingestion.created.Candidate.Metadata)
+	if err != nil {
+		t.Fatalf("marshal metadata: %v", err)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		t.Fatalf("unmarshal metadata: %v", err)
+	}
+	got := download.DestinationFromMetadata(metadata)
+	if got.AddToLibrary == nil || *got.AddToLibrary || got.PlaylistID == nil || *got.PlaylistID != 7 || got.QueueNext == nil || !*got.QueueNext {
+		t.Fatalf("destination = %+v, want library off, playlist 7, queue next", got)
+	}
+
+	ingestion.created = nil
+	rec = httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://soundcloud.com/artist/track","playlist_id":8}`))
+	if rec.Code != http.StatusNotFound || ingestion.created != nil {
+		t.Fatalf("someone else's playlist status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+type fakeDestinationPlaylists map[int64]*db.Playlist
+
+func (f fakeDestinationPlaylists) GetByID(_ context.Context, id int64) (*db.Playlist, error) {
+	if p, ok := f[id]; ok {
+		return p, nil
+	}
+	return nil, db.ErrPlaylistNotFound
+}
+
+type fakeShortLinks map[string]string
+
+func (f fakeShortLinks) Expand(_ context.Context, raw string) (string, error) {
+	if !strings.Contains(raw, "on.soundcloud.com") {
+		return raw, nil
+	}
+	if expanded, ok := f[raw]; ok {
+		return expanded, nil
+	}
+	return "", errors.New("unresolved")
+}
+
+type fakeDownloadTakedowns struct {
+	takedown  *db.ContentTakedown
+	sourceURL string
+}
+
+func (f *fakeDownloadTakedowns) FindActive(_ context.Context, sourceURL, _ string) (*db.ContentTakedown, error) {
+	f.sourceURL = sourceURL
+	return f.takedown, nil
+}
+
 func authenticatedDownloadRequest(body string) *http.Request {
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/downloads", bytes.NewBufferString(body))
 	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.MustParse("11111111-1111-1111-1111-111111111111")}))
@@ -104,6 +245,18 @@ func (fakeDirectDownloadService) GetJob(context.Context, string) (*download.Down
 func (fakeDirectDownloadService) GetUserJobs(context.Context, string) ([]*download.DownloadJob, error) {
 	return nil, nil
 }
+func (fakeDirectDownloadService) RetryJob(context.Context, string) error {
+	return nil
+}
+func (fakeDirectDownloadService) CancelJob(context.Context, string) error {
+	return nil
+}
+func (fakeDirectDownloadService) CreateParentJob(_ context.Context, userID, url, sourceType, title string) (*download.DownloadJob, error) {
+	return &download.DownloadJob{ID: "parent-1", UserID: userID, URL: url, SourceType: sourceType, Title: title, Status: download.StatusQueued}, nil
+}
+func (fakeDirectDownloadService) AttachChildJobs(_ context.Context, parentID string, childIDs []string) (*download.DownloadJob, error) {
+	return &download.DownloadJob{ID: parentID, Status: download.StatusQueued, ChildJobIDs: childIDs}, nil
+}
 
 type fakeDirectIngestion struct {
 	created       *db.SourceSelectionDownload
@@ -122,3 +275,312 @@ func (f *fakeDirectIngestion) EnqueueTrustedDownload(_ context.Context, persiste
 	}
 	return persisted.Job, nil
 }
+func (f *fakeDirectIngestion) CreateSearchedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, _ db.TrustedSourceSelectionQuality, reason string) (*db.SourceSelectionDownload, error) {
+	return f.CreateTrustedDownload(ctx, userID, origin, candidate, reason)
+}
+func (f *fakeDirectIngestion) EnqueueTrustedRecordingDownload(ctx context.Context, persisted *db.SourceSelectionDownload, enqueuer db.SourceSelectionDownloadEnqueuer, _ *string) (*download.DownloadJob, error) {
+	return f.EnqueueTrustedDownload(ctx, persisted, enqueuer)
+}
+
+type fakeUserJobsService struct {
+	fakeDirectDownloadService
+	jobs []*download.DownloadJob
+}
+
+func (f fakeUserJobsService) GetUserJobs(context.Context, string) ([]*download.DownloadJob, error) {
+	return f.jobs, nil
+}
+
+type fakeQueuePositions map[string]download.QueuePosition
+
+func (f fakeQueuePositions) QueuePositions(context.Context) (map[string]download.QueuePosition, error) {
+	return f, nil
+}
+
+func TestGetUserJobsIncludesQueuePositionForQueuedJobs(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 2, 0, 0, time.UTC)
+	handler := NewDownloadHandlers(fakeUserJobsService{jobs: []*download.DownloadJob{
+		{ID: "waiting", Status: download.StatusQueued},
+		{ID: "running", Status: download.StatusDownloading},
+	}})
+	handler.SetQueuePositions(fakeQueuePositions{
+		"waiting": {JobID: "waiting", Position: 3, EstimatedStartAt: &start},
+		"running": {JobID: "running", Position: 1},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/downloads", nil)
+	req = withUser(req, uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+	rec := httptest.NewRecorder()
+	handler.GetUserJobs(rec, req)
+
+	var resp struct {
+		Jobs []GetJobResponse `json:"jobs"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v (body=%s)", err, rec.Body.String())
+	}
+	if len(resp.Jobs) != 2 {
+		t.Fatalf("jobs = %+v, want 2", resp.Jobs)
+	}
+	waiting, running := resp.Jobs[0], resp.Jobs[1]
+	if waiting.QueuePosition != 3 || waiting.EstimatedStartAt == nil || *waiting.EstimatedStartAt != "2026-05-01T12:02:00Z" {
+		t.Fatalf("waiting job = %+v, want position 3 starting 12:02", waiting)
+	}
+	if running.QueuePosition != 0 || running.EstimatedStartAt != nil {
+		t.Fatalf("running job = %+v, want no queue position once started", running)
+	}
+}
+
+type blockingDirectIngestion struct {
+	fakeDirectIngestion
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingDirectIngestion) CreateTrustedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, reason string) (*db.SourceSelectionDownload, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	close(f.started)
+	<-f.release
+	return f.fakeDirectIngestion.CreateTrustedDownload(ctx, userID, origin, candidate, reason)
+}
+
+func TestCreateDownloadReturnsExistingJobForDuplicateSubmission(t *testing.T) {
+	ingestion := &blockingDirectIngestion{started: make(chan struct{}), release: make(chan struct{})}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.CreateDownload(first, authenticatedDownloadRequest(`{"url":"https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`))
+	}()
+	<-ingestion.started
+
+	// The same video as a share link, submitted while the first is in flight.
+	second := httptest.NewRecorder()
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		handler.CreateDownload(second, authenticatedDownloadRequest(`{"url":"https://youtu.be/dQw4w9WgXcQ?si=share"}`))
+	}()
+	close(ingestion.release)
+	<-done
+	<-secondDone
+
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d body=%s", first.Code, first.Body.String())
+	}
+	if second.Code != http.StatusOK {
+		t.Fatalf("duplicate status = %d body=%s; want 200", second.Code, second.Body.String())
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Fatalf("duplicate body = %s; want the first job %s", second.Body.String(), first.Body.String())
+	}
+
+	third := httptest.NewRecorder()
+	handler.CreateDownload(third, authenticatedDownloadRequest(`{"url":"https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`))
+	if third.Code != http.StatusOK || ingestion.calls != 1 {
+		t.Fatalf("repeat within window status = %d, jobs created = %d; want 200 and 1", third.Code, ingestion.calls)
+	}
+}
+
+func TestCreateDownloadRetriesAfterFailedSubmission(t *testing.T) {
+	ingestion := &fakeDirectIngestion{enqueueErr: errors.New("queue down")}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	body := `{"url":"https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`
+
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(body))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failing status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	ingestion.enqueueErr = nil
+	rec = httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d body=%s; want 201", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDownloadSubmissionsExpireAfterWindow(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	subs := newDownloadSubmissions()
+	subs.now = func() time.Time { return now }
+
+	if resp, err := subs.acquire(context.Background(), "k"); resp != nil || err != nil {
+		t.Fatalf("first acquire = %v, %v", resp, err)
+	}
+	subs.release("k", &CreateDownloadResponse{JobID: "job-1"})
+	if resp, _ := subs.acquire(context.Background(), "k"); resp == nil || resp.JobID != "job-1" {
+		t.Fatalf("acquire within window = %+v; want job-1", resp)
+	}
+	now = now.Add(downloadSubmissionWindow)
+	if resp, _ := subs.acquire(context.Background(), "k"); resp != nil {
+		t.Fatalf("acquire after window = %+v; want the lock", resp)
+	}
+}
+
+type fakeJobChangeService struct {
+	fakeDirectDownloadService
+	job       *download.DownloadJob
+	cancelErr error
+	cancelled string
+	retried   string
+}
+
+func (f *fakeJobChangeService) GetJob(_ context.Context, id string) (*download.DownloadJob, error) {
+	if f.job == nil || f.job.ID != id {
+		return nil, download.ErrJobNotFound
+	}
+	copied := *f.job
+	return &copied, nil
+}
+
+func (f *fakeJobChangeService) CancelJob(_ context.Context, id string) error {
+	if f.cancelErr != nil {
+		return f.cancelErr
+	}
+	f.cancelled = id
+	f.job.Status = download.StatusCancelled
+	return nil
+}
+
+func (f *fakeJobChangeService) RetryJob(_ context.Context, id string) error {
+	f.retried = id
+	f.job.Status = download.StatusQueued
+	f.job.RetryCount++
+	return nil
+}
+
+func jobChangeRequest(jobID, action string, userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/downloads/"+jobID+"/"+action, nil)
+	req.SetPathValue("job_id", jobID)
+	return withUser(req, userID)
+}
+
+func TestCancelAndRetryDownloadJob(t *testing.T) {
+	owner := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	service := &fakeJobChangeService{job: &download.DownloadJob{ID: "job-1", UserID: owner.String(), Status: download.StatusDownloading, Priority: download.PriorityInteractive}}
+	handler := NewDownloadHandlers(service)
+
+	rec := httptest.NewRecorder()
+	handler.CancelJob(rec, jobChangeRequest("job-1", "cancel", owner))
+	if rec.Code != http.StatusOK || service.cancelled != "job-1" || !strings.Contains(rec.Body.String(), `"status":"cancelled"`) {
+		t.Fatalf("cancel status = %d body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.RetryJob(rec, jobChangeRequest("job-1", "retry", owner))
+	var resp GetJobResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("retry status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if service.retried != "job-1" || resp.Status != download.StatusQueued || resp.RetryCount != 1 || resp.Priority != download.PriorityInteractive {
+		t.Fatalf("retry response = %+v", resp)
+	}
+
+	service.cancelErr = download.ErrJobNotCancellable
+	rec = httptest.NewRecorder()
+	handler.CancelJob(rec, jobChangeRequest("job-1", "cancel", owner))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "JOB_NOT_CANCELLABLE") {
+		t.Fatalf("finished job cancel status = %d body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCancelDownloadJobHidesOtherUsersJobs(t *testing.T) {
+	service := &fakeJobChangeService{job: &download.DownloadJob{ID: "job-1", UserID: uuid.NewString(), Status: download.StatusQueued}}
+	handler := NewDownloadHandlers(service)
+
+	rec := httptest.NewRecorder()
+	handler.CancelJob(rec, jobChangeRequest("job-1", "cancel", uuid.MustParse("11111111-1111-1111-1111-111111111111")))
+	if rec.Code != http.StatusNotFound || service.cancelled != "" {
+		t.Fatalf("status = %d, cancelled %q; want 404 without cancelling", rec.Code, service.cancelled)
+	}
+}
+
+func TestCreateDownloadRejectsUnknownPriority(t *testing.T) {
+	ingestion := &fakeDirectIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://www.youtube.com/watch?v=trusted","priority":"urgent"}`))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_PRIORITY") || ingestion.created != nil {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+type fakePlaylistEnumerator struct {
+	entries []playlistimport.Entry
+	err     error
+}
+
+func (f fakePlaylistEnumerator) Enumerate(context.Context, string, int) (playlistimport.PlaylistMetadata, []playlistimport.Entry, error) {
+	return playlistimport.PlaylistMetadata{Title: "Road Trip"}, f.entries, f.err
+}
+
+type fakePlaylistIngestion struct {
+	fakeDirectIngestion
+	candidates []download.SourceCandidate
+	priorities []string
+}
+
+func (f *fakePlaylistIngestion) CreateTrustedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, _ string) (*db.SourceSelectionDownload, error) {
+	f.candidates = append(f.candidates, candidate)
+	f.priorities = append(f.priorities, download.PriorityFromContext(ctx))
+	jobID := fmt.Sprintf("child-%d", len(f.candidates))
+	return &db.SourceSelectionDownload{Decision: &db.SourceSelectionDecision{ID: uuid.New(), UserID: userID, Origin: origin}, Job: &download.DownloadJob{ID: jobID, Status: download.StatusQueued}, Candidate: candidate}, nil
+}
+
+func TestCreateDownloadQueuesPlaylistEntriesUnderParentJob(t *testing.T) {
+	ingestion := &fakePlaylistIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	handler.SetPlaylistEnumerator(fakePlaylistEnumerator{entries: []playlistimport.Entry{
+		{Index: 1, SourceURL: "https://www.youtube.com/watch?v=first", Title: "First", Artist: "Band", DurationMs: 180000},
+		{Index: 2, SourceURL: "https://www.youtube.com/watch?v=gone", Unavailable: true},
+		{Index: 3, SourceURL: "https://www.youtube.com/watch?v=third", Title: "Third"},
+	}})
+
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://www.youtube.com/playlist?list=PLroadtrip"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	var resp CreateDownloadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.JobID != "parent-1" || resp.SourceDecisionID != "" || len(resp.ChildJobIDs) != 2 || resp.ChildJobIDs[1] != "child-2" {
+		t.Fatalf("response = %+v, want the parent job with both available entries", resp)
+	}
+	first := ingestion.candidates[0]
+	if first.Title != "First" || first.Artist != "Band" || first.DurationMs != 180000 || first.Metadata["playlistURL"] != "https://www.youtube.com/playlist?list=PLroadtrip" {
+		t.Fatalf("first candidate = %+v", first)
+	}
+	for i, priority := range ingestion.priorities {
+		if priority != download.PriorityBatch {
+			t.Fatalf("entry %d priority = %q, want batch by default", i, priority)
+		}
+	}
+}
+
+func TestCreateDownloadRejectsPlaylistWithoutDownloadableEntries(t *testing.T) {
+	ingestion := &fakePlaylistIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	handler.SetPlaylistEnumerator(fakePlaylistEnumerator{entries: []playlistimport.Entry{{Index: 1, Unavailable: true}}})
+	rec := httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://soundcloud.com/artist/sets/demo"}`))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "PLAYLIST_EMPTY") || len(ingestion.candidates) != 0 {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+
+	handler = NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	handler.SetPlaylistEnumerator(fakePlaylistEnumerator{err: errors.New("yt-dlp exited 1")})
+	rec = httptest.NewRecorder()
+	handler.CreateDownload(rec, authenticatedDownloadRequest(`{"url":"https://soundcloud.com/artist/sets/demo"}`))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "PLAYLIST_UNRESOLVED") {
+		t.Fatalf("enumeration failure status = %d body = %s", rec.Code, rec.Body.String())
+	}
+}
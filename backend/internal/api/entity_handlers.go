@@ -0,0 +1,337 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// maxEntityNameLength bounds an admin rename of an artist or release.
+const maxEntityNameLength = 500
+
+// EntityStore reads and renames artists and releases keyed by MusicBrainz
+// ID. *db.EntityRepository satisfies it.
+type EntityStore interface {
+	GetArtist(ctx context.Context, id uuid.UUID) (*db.ArtistEntity, error)
+	GetRelease(ctx context.Context, id uuid.UUID) (*db.ReleaseEntity, error)
+	RenameArtist(ctx context.Context, id uuid.UUID, name string) error
+	RenameRelease(ctx context.Context, id uuid.UUID, title string) error
+}
+
+// EntityArtwork stores artwork admins upload for artists and releases.
+type EntityArtwork interface {
+	Upload(ctx context.Context, kind db.EntityKind, id uuid.UUID, r io.Reader) error
+	Remove(ctx context.Context, kind db.EntityKind, id uuid.UUID) error
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// EntityHandlers serve artist and release artwork and let admins rename
+// artists and releases and replace their artwork. Renames show in search
+// results.
+type EntityHandlers struct {
+	entities EntityStore
+	artwork  EntityArtwork
+}
+
+// NewEntityHandlers creates the handlers. A nil artwork disables artwork
+// uploads; renames still work.
+func NewEntityHandlers(entities EntityStore, artwork EntityArtwork) *EntityHandlers {
+	return &EntityHandlers{entities: entities, artwork: artwork}
+}
+
+type ArtistEntityResponse struct {
+	MBArtistID uuid.UUID `json:"mbArtistId"`
+	Name       string    `json:"name"`
+	NameEdited bool      `json:"nameEdited"`
+	ArtworkURL string    `json:"artworkUrl,omitempty"`
+	TrackCount int       `json:"trackCount"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type ReleaseEntityResponse struct {
+	MBReleaseID uuid.UUID  `json:"mbReleaseId"`
+	Title       string     `json:"title"`
+	MBArtistID  *uuid.UUID `json:"mbArtistId,omitempty"`
+	Artist      string     `json:"artist,omitempty"`
+	TitleEdited bool       `json:"titleEdited"`
+	ArtworkURL  string     `json:"artworkUrl"`
+	TrackCount  int        `json:"trackCount"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type RenameArtistRequest struct {
+	Name string `json:"name"`
+}
+
+type RenameReleaseRequest struct {
+	Title string `json:"title"`
+}
+
+// GetArtistArtwork handles GET /api/v1/artists/{mb_id}/artwork, redirecting
+// to a signed URL for the artist's uploaded artwork.
+func (h *EntityHandlers) GetArtistArtwork(w http.ResponseWriter, r *http.Request) {
+	id, ok := entityID(w, r)
+	if !ok {
+		return
+	}
+	artist, err := h.entities.GetArtist(r.Context(), id)
+	if err != nil && !errors.Is(err, db.ErrArtistNotFound) {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load artist")
+		return
+	}
+	if artist == nil || !artist.ArtworkKey.Valid {
+		writeArtworkError(w, http.StatusNotFound, "NOT_FOUND", "artist has no artwork")
+		return
+	}
+	h.redirectToArtwork(w, r, artist.ArtworkKey.String)
+}
+
+// GetAlbumArtwork handles GET /api/v1/albums/{mb_id}/artwork. Albums an
+// admin uploaded artwork for redirect to it; the rest redirect to their
+// Cover Art Archive cover.
+func (h *EntityHandlers) GetAlbumArtwork(w http.ResponseWriter, r *http.Request) {
+	id, ok := entityID(w, r)
+	if !ok {
+		return
+	}
+	release, err := h.entities.GetRelease(r.Context(), id)
+	if err != nil && !errors.Is(err, db.ErrReleaseNotFound) {
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load album")
+		return
+	}
+	if release == nil || !release.ArtworkKey.Valid {
+		target := "/api/v1/artwork/" + id.String()
+		if size := r.URL.Query().Get("size"); size != "" {
+			target += "?size=" + size
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	h.redirectToArtwork(w, r, release.ArtworkKey.String)
+}
+
+// RenameArtist handles PATCH /api/v1/admin/artists/{mb_id}.
+func (h *EntityHandlers) RenameArtist(w http.ResponseWriter, r *http.Request) {
+	id, ok := entityID(w, r)
+	if !ok {
+		return
+	}
+	var req RenameArtistRequest
+	if !decodeEntityName(w, r, &req, &req.Name, "name") {
+		return
+	}
+	if err := h.entities.RenameArtist(r.Context(), id, req.Name); err != nil {
+		writeEntityError(w, err, "failed to rename artist")
+		return
+	}
+	h.writeArtist(w, r, id)
+}
+
+// RenameAlbum handles PATCH /api/v1/admin/albums/{mb_id}.
+func (h *EntityHandlers) RenameAlbum(w http.ResponseWriter, r *http.Request) {
+	id, ok := entityID(w, r)
+	if !ok {
+		return
+	}
+	var req RenameReleaseRequest
+	if !decodeEntityName(w, r, &req, &req.Title, "title") {
+		return
+	}
+	if err := h.entities.RenameRelease(r.Context(), id, req.Title); err != nil {
+		writeEntityError(w, err, "failed to rename album")
+		return
+	}
+	h.writeRelease(w, r, id)
+}
+
+// UploadArtistArtwork handles PUT /api/v1/admin/artists/{mb_id}/artwork.
+// The body is the raw JPEG or PNG image.
+func (h *EntityHandlers) UploadArtistArtwork(w http.ResponseWriter, r *http.Request) {
+	if id, ok := h.uploadArtwork(w, r, db.EntityArtist); ok {
+		h.writeArtist(w, r, id)
+	}
+}
+
+// DeleteArtistArtwork handles DELETE /api/v1/admin/artists/{mb_id}/artwork.
+func (h *EntityHandlers) DeleteArtistArtwork(w http.ResponseWriter, r *http.Request) {
+	if id, ok := h.removeArtwork(w, r, db.EntityArtist); ok {
+		h.writeArtist(w, r, id)
+	}
+}
+
+// UploadAlbumArtwork handles PUT /api/v1/admin/albums/{mb_id}/artwork. The
+// upload replaces the album's Cover Art Archive cover wherever the album's
+// artwork is shown.
+func (h *EntityHandlers) UploadAlbumArtwork(w http.ResponseWriter, r *http.Request) {
+	if id, ok := h.uploadArtwork(w, r, db.EntityRelease); ok {
+		h.writeRelease(w, r, id)
+	}
+}
+
+// DeleteAlbumArtwork handles DELETE /api/v1/admin/albums/{mb_id}/artwork,
+// going back to the album's Cover Art Archive cover.
+func (h *EntityHandlers) DeleteAlbumArtwork(w http.ResponseWriter, r *http.Request) {
+	if id, ok := h.removeArtwork(w, r, db.EntityRelease); ok {
+		h.writeRelease(w, r, id)
+	}
+}
+
+func (h *EntityHandlers) uploadArtwork(w http.ResponseWriter, r *http.Request, kind db.EntityKind) (uuid.UUID, bool) {
+	if h.artwork == nil {
+		writeArtworkError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "artwork uploads are not configured")
+		return uuid.Nil, false
+	}
+	id, ok := entityID(w, r)
+	if !ok {
+		return uuid.Nil, false
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "image/jpeg" && mediaType != "image/png" {
+		writeArtworkError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "artwork must be image/jpeg or image/png")
+		return uuid.Nil, false
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxArtworkUploadBytes)
+	if err := h.artwork.Upload(r.Context(), kind, id, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeArtworkError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "artwork must be at most 10 MB")
+		case errors.Is(err, artwork.ErrUnsupportedImage):
+			writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "artwork is not a valid JPEG or PNG image")
+		case errors.Is(err, artwork.ErrImageTooLarge):
+			writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "artwork dimensions are too large")
+		default:
+			if !errors.Is(err, db.ErrArtistNotFound) && !errors.Is(err, db.ErrReleaseNotFound) {
+				log.Printf("Error: failed to store %s %s artwork: %v", kind, id, err)
+			}
+			writeEntityError(w, err, "failed to store artwork")
+		}
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *EntityHandlers) removeArtwork(w http.ResponseWriter, r *http.Request, kind db.EntityKind) (uuid.UUID, bool) {
+	if h.artwork == nil {
+		writeArtworkError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "artwork uploads are not configured")
+		return uuid.Nil, false
+	}
+	id, ok := entityID(w, r)
+	if !ok {
+		return uuid.Nil, false
+	}
+	if err := h.artwork.Remove(r.Context(), kind, id); err != nil {
+		writeEntityError(w, err, "failed to remove artwork")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *EntityHandlers) redirectToArtwork(w http.ResponseWriter, r *http.Request, key string) {
+	if h.artwork == nil {
+		writeArtworkError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "artwork is not configured")
+		return
+	}
+	url, err := h.artwork.URL(r.Context(), key)
+	if err != nil {
+		log.Printf("Error: failed to sign artwork URL %s: %v", key, err)
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to sign artwork URL")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (h *EntityHandlers) writeArtist(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	artist, err := h.entities.GetArtist(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, err, "failed to load artist")
+		return
+	}
+	resp := ArtistEntityResponse{
+		MBArtistID: artist.MBArtistID,
+		Name:       artist.Name,
+		NameEdited: artist.NameEdited,
+		TrackCount: artist.TrackCount,
+		UpdatedAt:  artist.UpdatedAt,
+	}
+	if artist.ArtworkKey.Valid {
+		resp.ArtworkURL = fmt.Sprintf("/api/v1/artists/%s/artwork", artist.MBArtistID)
+	}
+	writeEntityJSON(w, http.StatusOK, resp)
+}
+
+func (h *EntityHandlers) writeRelease(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	release, err := h.entities.GetRelease(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, err, "failed to load album")
+		return
+	}
+	writeEntityJSON(w, http.StatusOK, ReleaseEntityResponse{
+		MBReleaseID: release.MBReleaseID,
+		Title:       release.Title,
+		MBArtistID:  release.MBArtistID,
+		Artist:      release.ArtistName.String,
+		TitleEdited: release.TitleEdited,
+		ArtworkURL:  fmt.Sprintf("/api/v1/albums/%s/artwork", release.MBReleaseID),
+		TrackCount:  release.TrackCount,
+		UpdatedAt:   release.UpdatedAt,
+	})
+}
+
+// entityID reads the MusicBrainz ID in the path, writing a 400 when it is
+// not a UUID.
+func entityID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("mb_id"))
+	if err != nil {
+		writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "mb_id must be a MusicBrainz ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// decodeEntityName decodes a rename request into req and checks the name it
+// carries in field.
+func decodeEntityName(w http.ResponseWriter, r *http.Request, req any, name *string, field string) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeArtworkError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return false
+	}
+	*name = strings.TrimSpace(*name)
+	if *name == "" || utf8.RuneCountInString(*name) > maxEntityNameLength {
+		writeArtworkError(w, http.StatusBadRequest, "VALIDATION_ERROR", fmt.Sprintf("%s must be 1 to %d characters", field, maxEntityNameLength))
+		return false
+	}
+	return true
+}
+
+func writeEntityError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrArtistNotFound):
+		writeArtworkError(w, http.StatusNotFound, "NOT_FOUND", "artist not found")
+	case errors.Is(err, db.ErrReleaseNotFound):
+		writeArtworkError(w, http.StatusNotFound, "NOT_FOUND", "album not found")
+	default:
+		writeArtworkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
+	}
+}
+
+func writeEntityJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
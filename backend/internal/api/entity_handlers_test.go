@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeEntityStore struct {
+	artists  map[uuid.UUID]*db.ArtistEntity
+	releases map[uuid.UUID]*db.ReleaseEntity
+}
+
+func (f *fakeEntityStore) GetArtist(ctx context.Context, id uuid.UUID) (*db.ArtistEntity, error) {
+	a, ok := f.artists[id]
+	if !ok {
+		return nil, db.ErrArtistNotFound
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (f *fakeEntityStore) GetRelease(ctx context.Context, id uuid.UUID) (*db.ReleaseEntity, error) {
+	rel, ok := f.releases[id]
+	if !ok {
+		return nil, db.ErrReleaseNotFound
+	}
+	copied := *rel
+	return &copied, nil
+}
+
+func (f *fakeEntityStore) RenameArtist(ctx context.Context, id uuid.UUID, name string) error {
+	a, ok := f.artists[id]
+	if !ok {
+		return db.ErrArtistNotFound
+	}
+	a.Name, a.NameEdited = name, true
+	return nil
+}
+
+func (f *fakeEntityStore) RenameRelease(ctx context.Context, id uuid.UUID, title string) error {
+	rel, ok := f.releases[id]
+	if !ok {
+		return db.ErrReleaseNotFound
+	}
+	rel.Title, rel.TitleEdited = title, true
+	return nil
+}
+
+type fakeEntityArtwork struct {
+	store *fakeEntityStore
+}
+
+func (f *fakeEntityArtwork) Upload(ctx context.Context, kind db.EntityKind, id uuid.UUID, r io.Reader) error {
+	if _, err := io.ReadAll(r); err != nil {
+		return err
+	}
+	key := sql.NullString{String: "artwork/" + string(kind) + "s/" + id.String() + "/cover.jpg", Valid: true}
+	if kind == db.EntityRelease {
+		rel, ok := f.store.releases[id]
+		if !ok {
+			return db.ErrReleaseNotFound
+		}
+		rel.ArtworkKey = key
+		return nil
+	}
+	a, ok := f.store.artists[id]
+	if !ok {
+		return db.ErrArtistNotFound
+	}
+	a.ArtworkKey = key
+	return nil
+}
+
+func (f *fakeEntityArtwork) Remove(ctx context.Context, kind db.EntityKind, id uuid.UUID) error {
+	if kind == db.EntityRelease {
+		f.store.releases[id].ArtworkKey = sql.NullString{}
+	} else {
+		f.store.artists[id].ArtworkKey = sql.NullString{}
+	}
+	return nil
+}
+
+func (f *fakeEntityArtwork) URL(ctx context.Context, key string) (string, error) {
+	return "https://cdn.test/" + key, nil
+}
+
+func newEntityTestHandlers() (*EntityHandlers, *fakeEntityStore, uuid.UUID, uuid.UUID) {
+	artistID, releaseID := uuid.New(), uuid.New()
+	store := &fakeEntityStore{
+		artists:  map[uuid.UUID]*db.ArtistEntity{artistID: {MBArtistID: artistID, Name: "Boards of Canada", TrackCount: 3}},
+		releases: map[uuid.UUID]*db.ReleaseEntity{releaseID: {MBReleaseID: releaseID, Title: "Geogaddi", MBArtistID: &artistID, TrackCount: 2}},
+	}
+	return NewEntityHandlers(store, &fakeEntityArtwork{store: store}), store, artistID, releaseID
+}
+
+func TestRenameArtistTrimsAndMarksEdited(t *testing.T) {
+	h, _, artistID, _ := newEntityTestHandlers()
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/admin/artists/"+artistID.String(), strings.NewReader(`{"name":"  BoC  "}`))
+	req.SetPathValue("mb_id", artistID.String())
+	rr := httptest.NewRecorder()
+	h.RenameArtist(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp ArtistEntityResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "BoC" || !resp.NameEdited || resp.ArtworkURL != "" {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	for _, body := range []string{`{"name":"   "}`, `{"name":"` + strings.Repeat("x", maxEntityNameLength+1) + `"}`} {
+		req = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		req.SetPathValue("mb_id", artistID.String())
+		rr = httptest.NewRecorder()
+		h.RenameArtist(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("rename with %d-byte body = %d, want 400", len(body), rr.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"name":"Someone"}`))
+	req.SetPathValue("mb_id", uuid.NewString())
+	rr = httptest.NewRecorder()
+	h.RenameArtist(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown artist = %d, want 404", rr.Code)
+	}
+}
+
+func TestAlbumArtworkPrefersUploadOverReleaseCover(t *testing.T) {
+	h, _, _, releaseID := newEntityTestHandlers()
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/albums/"+releaseID.String()+"/artwork?size=250", nil)
+		req.SetPathValue("mb_id", releaseID.String())
+		rr := httptest.NewRecorder()
+		h.GetAlbumArtwork(rr, req)
+		return rr
+	}
+	if rr := get(); rr.Code != http.StatusFound || rr.Header().Get("Location") != "/api/v1/artwork/"+releaseID.String()+"?size=250" {
+		t.Fatalf("without upload = %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("image"))
+	req.Header.Set("Content-Type", "image/png")
+	req.SetPathValue("mb_id", releaseID.String())
+	rr := httptest.NewRecorder()
+	h.UploadAlbumArtwork(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if rr := get(); rr.Code != http.StatusFound || !strings.HasPrefix(rr.Header().Get("Location"), "https://cdn.test/artwork/releases/") {
+		t.Fatalf("with upload = %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/", strings.NewReader("image"))
+	req.Header.Set("Content-Type", "image/gif")
+	req.SetPathValue("mb_id", releaseID.String())
+	rr = httptest.NewRecorder()
+	h.UploadAlbumArtwork(rr, req)
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("gif upload = %d, want 415", rr.Code)
+	}
+}
+
+func TestArtistArtworkNotFoundWithoutUpload(t *testing.T) {
+	h, _, artistID, _ := newEntityTestHandlers()
+
+	for _, id := range []string{artistID.String(), uuid.NewString()} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetPathValue("mb_id", id)
+		rr := httptest.NewRecorder()
+		h.GetArtistArtwork(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("artist %s artwork = %d, want 404", id, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/openmusicplayer/backend/internal/middleware"
+)
+
+// fieldRegistry lists the fields each endpoint accepts in ?fields=. A request
+// naming a field outside its entry gets 400 INVALID_FIELDS. Fields use the
+// endpoint's own JSON names, so the library keeps its snake_case names.
+var fieldRegistry = map[string]middleware.FieldSet{
+	// GET /api/v1/library: fields of each entry in "tracks". The handler
+	// builds only the selected fields instead of trimming afterwards.
+	"library": {
+		Items: []string{"tracks"},
+		Fields: []string{
+			"id", "title", "artist", "album", "duration_ms", "mb_verified", "genre",
+			"added_at", "play_count", "skip_count", "last_played_at", "cover_art_url", "source_url",
+			"file_size_bytes", "codec", "bitrate_kbps", "sample_rate_hz", "channels",
+			"content_type", "metadata_status", "metadata_confidence", "metadata_provenance",
+			"mb_recording_id", "mb_suggestions", "is_liked", "analysis_status",
+			"analysis_summary", "analysis_updated_at", "quarantined", "links", "tags", "genres",
+			"loudness_lufs", "track_gain_db", "track_peak_dbtp", "album_gain_db", "album_peak_dbtp",
+		},
+		Always: []string{"id"},
+	},
+	// GET /api/v1/playlists and /api/v2/playlists: fields of each playlist in
+	// "data" (v1) or "items" (v2).
+	"playlists": {
+		Items: []string{"data", "items"},
+		Fields: []string{
+			"id", "name", "description", "coverUrl", "artworkUrl", "isPublic",
+			"systemKind", "trackCount", "durationMs", "createdAt", "updatedAt",
+		},
+		Always: []string{"id"},
+	},
+	// GET /api/v1/playlists/{id}: fields of each entry in "tracks". The
+	// playlist's own fields are always returned.
+	"playlist": {
+		Items: []string{"tracks"},
+		Fields: []string{
+			"id", "title", "artist", "album", "durationMs", "fileSizeBytes", "codec",
+			"bitrateKbps", "sampleRateHz", "channels", "contentType", "mbRecordingId",
+			"mbReleaseId", "mbArtistId", "analysisStatus", "analysisSummary",
+			"analysisUpdatedAt", "links",
+		},
+		Always: []string{"id"},
+	},
+	// GET /api/v1/search: fields of each entry in the "tracks", "artists", and
+	// "albums" sections. A field applies to every section that has it, so
+	// ?fields=title,name keeps track titles and artist and album names.
+	// type=playlists and type=queue responses are returned in full.
+	"search": {
+		Items: []string{"tracks", "artists", "albums"},
+		Fields: []string{
+			"id", "title", "name", "artist", "album", "durationMs", "coverArtUrl",
+			"mbRecordingId", "mbReleaseId", "mbArtistId", "trackCount",
+			"analysisStatus", "analysisSummary", "analysisUpdatedAt",
+		},
+		Always: []string{"id"},
+	},
+	// GET /api/v1/queue: fields of each entry in "items". Position and
+	// playback state stay on the queue itself.
+	"queue": {
+		Items: []string{"items"},
+		Fields: []string{
+			"id", "queueItemId", "position", "kind", "trackId", "playbackState",
+			"downloadJobId", "sourceCandidate", "title", "artist", "album", "uploader",
+			"durationMs", "thumbnailUrl", "progress", "error", "analysisStatus",
+			"analysisSummary", "analysisOverrides", "analysisUpdatedAt", "canPlay",
+			"canRetry", "canRemove", "addedAt", "updatedAt",
+		},
+		Always: []string{"id", "queueItemId"},
+	},
+	// GET /api/v1/tracks/{mb_id}: fields of the track itself.
+	"track": {
+		Fields: []string{
+			"id", "title", "artist", "artistId", "album", "albumId", "duration",
+			"position", "inLibrary", "downloadable",
+		},
+		Always: []string{"id"},
+	},
+}
+
+// withFields applies the registered ?fields= selection for endpoint to next.
+func withFields(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	set, ok := fieldRegistry[endpoint]
+	if !ok {
+		panic("api: no field registry entry for " + endpoint)
+	}
+	return middleware.Fields(set)(next).ServeHTTP
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+	"github.com/openmusicplayer/backend/internal/queue"
+	"github.com/openmusicplayer/backend/internal/search"
+)
+
+func servePlaylistPage(w http.ResponseWriter, r *http.Request) {
+	page := PaginatedPlaylistResponse{Total: 2, Limit: 20}
+	for i, name := range []string{"Morning", "Evening"} {
+		page.Data = append(page.Data, PlaylistResponse{
+			ID:          int64(i + 1),
+			Name:        name,
+			Description: "A long description that clients rendering a picker never show",
+			CoverURL:    "https://covers.test/" + name + ".jpg",
+			TrackCount:  12,
+			DurationMs:  3_600_000,
+			CreatedAt:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:   time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	writePlaylistJSON(w, http.StatusOK, page)
+}
+
+func TestWithFieldsTrimsListItemsAndShrinksPayload(t *testing.T) {
+	handler := withFields("playlists", servePlaylistPage)
+
+	full := httptest.NewRecorder()
+	handler(full, httptest.NewRequest(http.MethodGet, "/api/v1/playlists", nil))
+
+	partial := httptest.NewRecorder()
+	handler(partial, httptest.NewRequest(http.MethodGet, "/api/v1/playlists?fields=name,trackCount", nil))
+	if partial.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", partial.Code, partial.Body.String())
+	}
+	if partial.Body.Len() >= full.Body.Len()/2 {
+		t.Fatalf("partial body = %d bytes, want well under the full %d bytes", partial.Body.Len(), full.Body.Len())
+	}
+
+	var resp struct {
+		Data  []map[string]interface{} `json:"data"`
+		Total int                      `json:"total"`
+	}
+	if err := json.Unmarshal(partial.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Data) != 2 {
+		t.Fatalf("response = %+v, want pagination kept", resp)
+	}
+	for _, item := range resp.Data {
+		if len(item) != 3 || item["id"] == nil || item["name"] == nil || item["trackCount"] == nil {
+			t.Fatalf("item = %v, want only id, name, and trackCount", item)
+		}
+	}
+}
+
+func TestWithFieldsTrimsSingleObject(t *testing.T) {
+	handler := withFields("track", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(musicbrainz.Track{ID: "abc", Title: "Roygbiv", Artist: "Boards of Canada", Duration: 151})
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tracks/abc?fields=title", nil))
+	var track map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &track); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(track) != 2 || track["id"] != "abc" || track["title"] != "Roygbiv" {
+		t.Fatalf("track = %v, want id and title only", track)
+	}
+}
+
+func TestWithFieldsRejectsUnknownFields(t *testing.T) {
+	called := false
+	handler := withFields("playlists", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/playlists?fields=name,nmae", nil))
+	if rec.Code != http.StatusBadRequest || called {
+		t.Fatalf("status = %d, called = %v; want 400 before the handler runs", rec.Code, called)
+	}
+	if !strings.Contains(rec.Body.String(), "nmae") {
+		t.Fatalf("body = %s, want the unknown field named", rec.Body.String())
+	}
+}
+
+func TestLibraryPageValidatesFieldsLikeV1(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+	for _, serve := range []http.HandlerFunc{h.GetLibrary, h.GetLibraryPage} {
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/v2/library?fields=title,ttile", nil), uuid.New())
+		rec := httptest.NewRecorder()
+		serve(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_FIELDS") {
+			t.Fatalf("status = %d, body = %s; want 400 INVALID_FIELDS", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWithFieldsLeavesErrorsUntouched(t *testing.T) {
+	handler := withFields("playlist", func(w http.ResponseWriter, r *http.Request) {
+		writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/playlists/9?fields=title", nil))
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusNotFound || resp.Code != "NOT_FOUND" || resp.Message == "" {
+		t.Fatalf("status = %d, body = %+v; want the error passed through", rec.Code, resp)
+	}
+}
+
+// TestFieldRegistryMatchesResponseTypes keeps the registry in step with the
+// JSON each endpoint actually returns.
+func TestFieldRegistryMatchesResponseTypes(t *testing.T) {
+	types := map[string][]interface{}{
+		"playlists": {PlaylistResponse{}},
+		"playlist":  {TrackResponse{}},
+		"search":    {search.RecordingResponse{}, search.ArtistResponse{}, search.ReleaseResponse{}},
+		"queue":     {queue.QueueItemResponse{}},
+		"track":     {musicbrainz.Track{}},
+	}
+	for endpoint, values := range types {
+		names := map[string]bool{}
+		for _, v := range values {
+			rt := reflect.TypeOf(v)
+			for i := 0; i < rt.NumField(); i++ {
+				name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+				names[name] = true
+			}
+		}
+		for _, field := range fieldRegistry[endpoint].Fields {
+			if !names[field] {
+				t.Errorf("%s: registry field %q is not in the response", endpoint, field)
+			}
+		}
+	}
+}
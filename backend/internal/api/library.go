@@ -5,7 +5,6 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +12,8 @@ import (
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/matcher"
+	"github.com/openmusicplayer/backend/internal/middleware"
+	"github.com/openmusicplayer/backend/internal/pagination"
 )
 
 type LibraryHandlers struct {
@@ -63,67 +64,83 @@ type LibraryErrorResponse struct {
 	Message string `json:"message"`
 }
 
-// FieldSelector tracks which fields to include in the response
-type FieldSelector struct {
-	fields map[string]bool
-	all    bool
-}
-
-// NewFieldSelector creates a selector from a comma-separated list of fields
-// If fields is empty, all fields are included
-func NewFieldSelector(fieldsParam string) *FieldSelector {
-	if fieldsParam == "" {
-		return &FieldSelector{all: true}
-	}
-	fields := make(map[string]bool)
-	for _, f := range strings.Split(fieldsParam, ",") {
-		fields[strings.TrimSpace(f)] = true
-	}
-	return &FieldSelector{fields: fields}
-}
-
-func (s *FieldSelector) Include(field string) bool {
-	if s.all {
-		return true
-	}
-	return s.fields[field]
-}
-
 // GetLibrary handles GET /api/v1/library
-// Query params: limit, offset, sort (added_at|title|artist|duration), order (asc|desc),
+// Query params: limit, offset,
+// sort (added_at|title|artist|duration|play_count|most_played|recently_played), order (asc|desc;
+// most_played and recently_played always list the most played or most recent first),
 // q (full-text search), mb_verified (bool), liked (true -> only liked tracks),
 // genre (exact match; "Unknown" matches tracks with no genre),
 // artist (exact match, local artist listing), album (exact match, local album listing),
-// fields (comma-separated field selection).
-// Available fields: id, title, artist, album, duration_ms, mb_verified, genre, added_at, cover_art_url, source_url, file_size_bytes, codec, bitrate_kbps, sample_rate_hz, channels, content_type, metadata_status, metadata_confidence, metadata_provenance, mb_recording_id, mb_suggestions, is_liked, analysis_status, analysis_summary, analysis_updated_at
+// source_type (youtube|soundcloud|upload), added_after (inclusive) and added_before
+// (exclusive) as RFC 3339 timestamps or YYYY-MM-DD dates (UTC midnight),
+// tag (repeatable; keeps tracks carrying every listed user tag),
+// fields (comma-separated field selection; see fieldRegistry["library"]).
 //
 // Note: liked/is_liked here are scoped to the caller's library — this endpoint
 // lists the library, optionally filtered to liked tracks. A standalone "Liked
 // Songs" collection returning every favorite regardless of library membership is
 // a separate future endpoint (roadmap C11b); see docs/UX_GAP_ANALYSIS.md.
 func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
+	offset := parseIntParam(r, "offset", 0)
+	tracks, total, limit, ok := h.listLibrary(w, r, offset)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{
+		"tracks": tracks,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	writeLibraryJSON(w, http.StatusOK, response)
+}
+
+// GetLibraryPage handles GET /api/v2/library. It takes GetLibrary's query
+// params, fields included, plus ?cursor= in place of offset.
+func (h *LibraryHandlers) GetLibraryPage(w http.ResponseWriter, r *http.Request) {
+	offset, err := pagination.Offset(r, parseIntParam(r, "offset", 0))
+	if err != nil {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid or expired")
+		return
+	}
+	tracks, total, limit, ok := h.listLibrary(w, r, offset)
+	if !ok {
+		return
+	}
+	pagination.Write(w, tracks, limit, offset, &total)
+}
+
+// listLibrary loads the page of the caller's library at offset with the
+// selected fields, writing the error itself when it cannot.
+func (h *LibraryHandlers) listLibrary(w http.ResponseWriter, r *http.Request, offset int) ([]map[string]interface{}, int, int, bool) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	if userCtx == nil {
 		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
-		return
+		return nil, 0, 0, false
 	}
 
 	opts := db.LibraryQueryOptions{
 		Limit:  parseIntParam(r, "limit", 50),
-		Offset: parseIntParam(r, "offset", 0),
+		Offset: offset,
 	}
 
 	// Parse field selection
-	fields := NewFieldSelector(r.URL.Query().Get("fields"))
+	fields, err := fieldRegistry["library"].Parse(r.URL.Query().Get(middleware.FieldsParam))
+	if err != nil {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_FIELDS", err.Error())
+		return nil, 0, 0, false
+	}
 
 	// Parse sort parameters
 	if sortBy := r.URL.Query().Get("sort"); sortBy != "" {
 		switch sortBy {
-		case "added_at", "title", "artist", "duration":
+		case "added_at", "title", "artist", "duration", "play_count", "most_played", "recently_played":
 			opts.SortBy = sortBy
 		default:
-			writeLibraryError(w, http.StatusBadRequest, "INVALID_SORT", "sort must be one of: added_at, title, artist, duration")
-			return
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_SORT", "sort must be one of: added_at, title, artist, duration, play_count, most_played, recently_played")
+			return nil, 0, 0, false
 		}
 	}
 
@@ -133,7 +150,7 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 			opts.SortOrder = sortOrder
 		default:
 			writeLibraryError(w, http.StatusBadRequest, "INVALID_ORDER", "order must be one of: asc, desc")
-			return
+			return nil, 0, 0, false
 		}
 	}
 
@@ -164,10 +181,54 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		opts.Album = album
 	}
 
+	// Parse ingest audit filters: platform and library-added date range.
+	if sourceType := r.URL.Query().Get("source_type"); sourceType != "" {
+		switch sourceType {
+		case "youtube", "soundcloud", "upload":
+			opts.SourceType = sourceType
+		default:
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_SOURCE_TYPE", "source_type must be one of: youtube, soundcloud, upload")
+			return nil, 0, 0, false
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"added_after", &opts.AddedAfter}, {"added_before", &opts.AddedBefore}} {
+		raw := r.URL.Query().Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := parseLibraryDateParam(raw)
+		if err != nil {
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_DATE", bound.name+" must be an RFC 3339 timestamp or YYYY-MM-DD date")
+			return nil, 0, 0, false
+		}
+		*bound.dst = &parsed
+	}
+	if opts.AddedAfter != nil && opts.AddedBefore != nil && !opts.AddedAfter.Before(*opts.AddedBefore) {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_DATE", "added_after must be earlier than added_before")
+		return nil, 0, 0, false
+	}
+	if rawTags := r.URL.Query()["tag"]; len(rawTags) > 0 {
+		if len(rawTags) > maxTagFilters {
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_TAG", "at most 10 tag filters are allowed")
+			return nil, 0, 0, false
+		}
+		for _, raw := range rawTags {
+			tag, ok := normalizeTag(raw)
+			if !ok {
+				writeLibraryError(w, http.StatusBadRequest, "INVALID_TAG", "invalid tag: "+raw)
+				return nil, 0, 0, false
+			}
+			opts.Tags = append(opts.Tags, tag)
+		}
+	}
+
 	tracks, total, err := h.libraryRepo.GetUserLibrary(r.Context(), userCtx.UserID, opts)
 	if err != nil {
 		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to retrieve library")
-		return
+		return nil, 0, 0, false
 	}
 
 	// Build response with field selection for reduced payload size
@@ -203,6 +264,15 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		if fields.Include("added_at") {
 			track["added_at"] = t.AddedAt.Format("2006-01-02T15:04:05Z")
 		}
+		if fields.Include("play_count") {
+			track["play_count"] = t.PlayCount
+		}
+		if fields.Include("skip_count") {
+			track["skip_count"] = t.SkipCount
+		}
+		if fields.Include("last_played_at") && t.LastPlayedAt.Valid {
+			track["last_played_at"] = t.LastPlayedAt.Time.UTC().Format(time.RFC3339)
+		}
 		if fields.Include("cover_art_url") {
 			if t.CoverArtURL.Valid {
 				track["cover_art_url"] = t.CoverArtURL.String
@@ -231,6 +301,21 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		if fields.Include("content_type") && t.ContentType.Valid {
 			track["content_type"] = t.ContentType.String
 		}
+		if fields.Include("loudness_lufs") && t.ReplayGain.LoudnessLUFS.Valid {
+			track["loudness_lufs"] = t.ReplayGain.LoudnessLUFS.Float64
+		}
+		if fields.Include("track_gain_db") && t.ReplayGain.TrackGainDB.Valid {
+			track["track_gain_db"] = t.ReplayGain.TrackGainDB.Float64
+		}
+		if fields.Include("track_peak_dbtp") && t.ReplayGain.TruePeakDBTP.Valid {
+			track["track_peak_dbtp"] = t.ReplayGain.TruePeakDBTP.Float64
+		}
+		if fields.Include("album_gain_db") && t.ReplayGain.AlbumGainDB.Valid {
+			track["album_gain_db"] = t.ReplayGain.AlbumGainDB.Float64
+		}
+		if fields.Include("album_peak_dbtp") && t.ReplayGain.AlbumPeakDBTP.Valid {
+			track["album_peak_dbtp"] = t.ReplayGain.AlbumPeakDBTP.Float64
+		}
 		if fields.Include("metadata_status") && t.MetadataStatus.Valid {
 			track["metadata_status"] = t.MetadataStatus.String
 		}
@@ -261,6 +346,28 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		if fields.Include("analysis_updated_at") && t.AnalysisUpdatedAt.Valid {
 			track["analysis_updated_at"] = t.AnalysisUpdatedAt.Time.UTC().Format(time.RFC3339Nano)
 		}
+		if fields.Include("quarantined") {
+			track["quarantined"] = t.QuarantinedAt.Valid
+		}
+		if fields.Include("tags") {
+			if t.Tags != nil {
+				track["tags"] = t.Tags
+			} else {
+				track["tags"] = []string{}
+			}
+		}
+		if fields.Include("genres") {
+			if t.Genres != nil {
+				track["genres"] = t.Genres
+			} else {
+				track["genres"] = []string{}
+			}
+		}
+		if fields.Include("links") {
+			if links := trackLinks(&t.Track); len(links) > 0 {
+				track["links"] = links
+			}
+		}
 		// Include suggestions for unverified tracks
 		if fields.Include("mb_suggestions") && !t.MBVerified && len(t.MetadataJSON) > 0 {
 			if suggestions := parseMBSuggestions(t.MetadataJSON); len(suggestions) > 0 {
@@ -271,14 +378,7 @@ func (h *LibraryHandlers) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		trackResponses = append(trackResponses, track)
 	}
 
-	response := map[string]interface{}{
-		"tracks": trackResponses,
-		"total":  total,
-		"limit":  opts.Limit,
-		"offset": opts.Offset,
-	}
-
-	writeLibraryJSON(w, http.StatusOK, response)
+	return trackResponses, total, opts.Limit, true
 }
 
 // AddTrackToLibrary handles POST /api/v1/library/tracks/{track_id}
@@ -436,6 +536,15 @@ func (h *LibraryHandlers) UnlikeTrack(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// parseLibraryDateParam accepts an RFC 3339 timestamp or a bare YYYY-MM-DD
+// date, which is read as midnight UTC.
+func parseLibraryDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
 func parseIntParam(r *http.Request, name string, defaultVal int) int {
 	if val := r.URL.Query().Get(name); val != "" {
 		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
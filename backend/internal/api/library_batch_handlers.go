@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	// maxBatchTrackIDs caps how many tracks one batch request may name.
+	maxBatchTrackIDs = 500
+	// maxBatchRequestBytes fits maxBatchTrackIDs large IDs with room to spare.
+	maxBatchRequestBytes = 64 * 1024
+)
+
+// BatchTracksRequest names the tracks to look up.
+type BatchTracksRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// BatchTracksResponse holds the tracks found, in request order, and the
+// IDs of any that do not exist.
+type BatchTracksResponse struct {
+	Tracks  []TrackResponse `json:"tracks"`
+	Missing []int64         `json:"missing"`
+}
+
+// LibraryBatchRequest names the tracks to add to or remove from the library.
+type LibraryBatchRequest struct {
+	TrackIDs []int64 `json:"track_ids"`
+}
+
+// BatchAddTracksResponse reports what a batch library add did with each
+// track.
+type BatchAddTracksResponse struct {
+	Added            []int64 `json:"added"`
+	AlreadyInLibrary []int64 `json:"already_in_library"`
+	NotFound         []int64 `json:"not_found"`
+}
+
+// BatchRemoveTracksResponse reports what a batch library remove did with
+// each track.
+type BatchRemoveTracksResponse struct {
+	Removed      []int64 `json:"removed"`
+	NotInLibrary []int64 `json:"not_in_library"`
+}
+
+// BatchGetTracks handles POST /api/v1/tracks/batch, returning up to 500
+// tracks in one query.
+func (h *LibraryHandlers) BatchGetTracks(w http.ResponseWriter, r *http.Request) {
+	if auth.GetUserFromContext(r.Context()) == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	var req BatchTracksRequest
+	if !decodeBatchRequest(w, r, &req) {
+		return
+	}
+	ids, ok := batchTrackIDs(w, req.IDs, "ids")
+	if !ok {
+		return
+	}
+
+	found, err := h.trackRepo.GetByIDs(r.Context(), ids)
+	if err != nil {
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get tracks")
+		return
+	}
+	tracks := make([]db.Track, 0, len(found))
+	missing := []int64{}
+	for _, id := range ids {
+		if track := found[id]; track != nil {
+			tracks = append(tracks, *track)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	writeLibraryJSON(w, http.StatusOK, BatchTracksResponse{Tracks: mapTrackResponses(tracks), Missing: missing})
+}
+
+// BatchAddTracksToLibrary handles POST /api/v1/library/tracks/batch, adding
+// up to 500 tracks in one statement. Tracks already in the library or that
+// do not exist are reported rather than failing the batch.
+func (h *LibraryHandlers) BatchAddTracksToLibrary(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	var req LibraryBatchRequest
+	if !decodeBatchRequest(w, r, &req) {
+		return
+	}
+	ids, ok := batchTrackIDs(w, req.TrackIDs, "track_ids")
+	if !ok {
+		return
+	}
+
+	found, err := h.trackRepo.GetByIDs(r.Context(), ids)
+	if err != nil {
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify tracks")
+		return
+	}
+	resp := BatchAddTracksResponse{Added: []int64{}, AlreadyInLibrary: []int64{}, NotFound: []int64{}}
+	existing := make([]int64, 0, len(found))
+	for _, id := range ids {
+		if found[id] == nil {
+			resp.NotFound = append(resp.NotFound, id)
+		} else {
+			existing = append(existing, id)
+		}
+	}
+	if len(existing) > 0 {
+		added, err := h.libraryRepo.AddTracksToLibrary(r.Context(), userCtx.UserID, existing)
+		if err != nil {
+			writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add tracks to library")
+			return
+		}
+		resp.Added, resp.AlreadyInLibrary = splitByMembership(existing, added)
+	}
+	writeLibraryJSON(w, http.StatusOK, resp)
+}
+
+// BatchRemoveTracksFromLibrary handles POST
+// /api/v1/library/tracks/batch-remove, removing up to 500 tracks in one
+// statement.
+func (h *LibraryHandlers) BatchRemoveTracksFromLibrary(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	var req LibraryBatchRequest
+	if !decodeBatchRequest(w, r, &req) {
+		return
+	}
+	ids, ok := batchTrackIDs(w, req.TrackIDs, "track_ids")
+	if !ok {
+		return
+	}
+
+	removed, err := h.libraryRepo.RemoveTracksFromLibrary(r.Context(), userCtx.UserID, ids)
+	if err != nil {
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove tracks from library")
+		return
+	}
+	var resp BatchRemoveTracksResponse
+	resp.Removed, resp.NotInLibrary = splitByMembership(ids, removed)
+	writeLibraryJSON(w, http.StatusOK, resp)
+}
+
+func decodeBatchRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchRequestBytes)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeLibraryError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "request body is too large")
+			return false
+		}
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return false
+	}
+	return true
+}
+
+// batchTrackIDs validates a batch's IDs and drops repeats, keeping the
+// first occurrence's place.
+func batchTrackIDs(w http.ResponseWriter, ids []int64, field string) ([]int64, bool) {
+	if len(ids) == 0 {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_REQUEST", field+" is required")
+		return nil, false
+	}
+	if len(ids) > maxBatchTrackIDs {
+		writeLibraryError(w, http.StatusBadRequest, "TOO_MANY_TRACKS", field+" may name at most "+strconv.Itoa(maxBatchTrackIDs)+" tracks")
+		return nil, false
+	}
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track ID: "+strconv.FormatInt(id, 10))
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, true
+}
+
+// splitByMembership splits ids, in order, into those in subset and the rest.
+func splitByMembership(ids, subset []int64) (in, out []int64) {
+	member := make(map[int64]bool, len(subset))
+	for _, id := range subset {
+		member[id] = true
+	}
+	in, out = []int64{}, []int64{}
+	for _, id := range ids {
+		if member[id] {
+			in = append(in, id)
+		} else {
+			out = append(out, id)
+		}
+	}
+	return in, out
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+func batchRequest(path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+}
+
+// TestBatchHandlersRejectInvalidBatches confirms bad batches are refused
+// before any repository access (nil repos are never touched).
+func TestBatchHandlersRejectInvalidBatches(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+	ids := make([]string, maxBatchTrackIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+	tooMany := "[" + strings.Join(ids, ",") + "]"
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		body    string
+		code    string
+	}{
+		{"no ids", h.BatchGetTracks, "/api/v1/tracks/batch", `{"ids":[]}`, "INVALID_REQUEST"},
+		{"too many ids", h.BatchGetTracks, "/api/v1/tracks/batch", `{"ids":` + tooMany + `}`, "TOO_MANY_TRACKS"},
+		{"bad id", h.BatchAddTracksToLibrary, "/api/v1/library/tracks/batch", `{"track_ids":[3,0]}`, "INVALID_REQUEST"},
+		{"bad body", h.BatchRemoveTracksFromLibrary, "/api/v1/library/tracks/batch-remove", `{"track_ids":`, "INVALID_REQUEST"},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, batchRequest(tc.path, tc.body))
+		var body LibraryErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode error body: %v", tc.name, err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Code != tc.code {
+			t.Errorf("%s: status = %d, code = %q; want 400 %s", tc.name, rec.Code, body.Code, tc.code)
+		}
+	}
+}
+
+func TestBatchTrackIDsDropsRepeats(t *testing.T) {
+	ids, ok := batchTrackIDs(httptest.NewRecorder(), []int64{5, 3, 5, 9, 3}, "ids")
+	if !ok || !slices.Equal(ids, []int64{5, 3, 9}) {
+		t.Fatalf("ids = %v, %v; want [5 3 9]", ids, ok)
+	}
+	in, out := splitByMembership([]int64{5, 3, 9}, []int64{9, 5})
+	if !slices.Equal(in, []int64{5, 9}) || !slices.Equal(out, []int64{3}) {
+		t.Fatalf("split = %v / %v; want [5 9] / [3]", in, out)
+	}
+}
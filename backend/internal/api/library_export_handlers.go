@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+const (
+	maxLibraryExportTracks = 10000
+	// libraryExportURLTTL is how long a download URL for a finished archive
+	// stays valid; clients fetch a new one by polling the export again.
+	libraryExportURLTTL = 15 * time.Minute
+)
+
+type libraryExportRunner interface {
+	Start(userID uuid.UUID, trackIDs []int64) (processor.LibraryExportStatus, error)
+	Status(userID uuid.UUID, id string) (processor.LibraryExportStatus, bool)
+	Cancel(userID uuid.UUID, id string) bool
+}
+
+type libraryExportSigner interface {
+	PresignDownloadObject(ctx context.Context, key string, expires time.Duration, filename string) (string, error)
+}
+
+// LibraryExportHandlers builds ZIP archives of a user's tracks in the
+// background and hands out signed URLs for finished ones.
+type LibraryExportHandlers struct {
+	runner libraryExportRunner
+	signer libraryExportSigner
+}
+
+func NewLibraryExportHandlers(runner libraryExportRunner, signer libraryExportSigner) *LibraryExportHandlers {
+	return &LibraryExportHandlers{runner: runner, signer: signer}
+}
+
+type StartLibraryExportRequest struct {
+	// TrackIDs selects tracks to export; empty exports the whole library.
+	TrackIDs []int64 `json:"trackIds"`
+}
+
+// LibraryExportResponse is an export's status, with a download URL once
+// the archive is complete.
+type LibraryExportResponse struct {
+	processor.LibraryExportStatus
+	DownloadURL       string     `json:"downloadUrl,omitempty"`
+	DownloadExpiresAt *time.Time `json:"downloadExpiresAt,omitempty"`
+}
+
+// StartExport handles POST /api/v1/library/export. Progress is pushed to the
+// caller's WebSocket connections as library_export_progress messages.
+func (h *LibraryExportHandlers) StartExport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryExportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	var req StartLibraryExportRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 256*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeLibraryExportError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	trackIDs, err := validateAndDedupeTrackIDs(req.TrackIDs)
+	if err != nil {
+		writeLibraryExportError(w, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+		return
+	}
+	if len(trackIDs) > maxLibraryExportTracks {
+		writeLibraryExportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "trackIds must contain at most 10000 track IDs")
+		return
+	}
+
+	status, err := h.runner.Start(userCtx.UserID, trackIDs)
+	if errors.Is(err, processor.ErrLibraryExportRunning) {
+		writeLibraryExportJSON(w, http.StatusConflict, map[string]interface{}{
+			"code":    "LIBRARY_EXPORT_RUNNING",
+			"message": "a library export is already running",
+			"export":  status,
+		})
+		return
+	}
+	if err != nil {
+		writeLibraryExportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start library export")
+		return
+	}
+	writeLibraryExportJSON(w, http.StatusAccepted, LibraryExportResponse{LibraryExportStatus: status})
+}
+
+// GetExport handles GET /api/v1/library/export/{export_id}. A completed
+// export carries a short-lived signed URL for its archive.
+func (h *LibraryExportHandlers) GetExport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryExportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	status, ok := h.runner.Status(userCtx.UserID, r.PathValue("export_id"))
+	if !ok {
+		writeLibraryExportError(w, http.StatusNotFound, "LIBRARY_EXPORT_NOT_FOUND", "library export not found")
+		return
+	}
+	resp := LibraryExportResponse{LibraryExportStatus: status}
+	if status.State == processor.LibraryExportCompleted && status.ArchiveKey != "" {
+		ttl := libraryExportURLTTL
+		if status.ExpiresAt != nil && time.Until(*status.ExpiresAt) < ttl {
+			ttl = time.Until(*status.ExpiresAt)
+		}
+		if ttl > 0 {
+			filename := "openmusicplayer-library-" + status.CreatedAt.UTC().Format("2006-01-02") + ".zip"
+			url, err := h.signer.PresignDownloadObject(r.Context(), status.ArchiveKey, ttl, filename)
+			if err != nil {
+				log.Printf("Error: failed to sign library export %s URL: %v", status.ID, err)
+				writeLibraryExportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to sign download URL")
+				return
+			}
+			expiresAt := time.Now().Add(ttl).UTC()
+			resp.DownloadURL, resp.DownloadExpiresAt = url, &expiresAt
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeLibraryExportJSON(w, http.StatusOK, resp)
+}
+
+// CancelExport handles DELETE /api/v1/library/export/{export_id}. A running
+// export stops without leaving an archive; a finished one's is deleted.
+func (h *LibraryExportHandlers) CancelExport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryExportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if !h.runner.Cancel(userCtx.UserID, r.PathValue("export_id")) {
+		writeLibraryExportError(w, http.StatusNotFound, "LIBRARY_EXPORT_NOT_FOUND", "library export not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeLibraryExportJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeLibraryExportError(w http.ResponseWriter, status int, code, message string) {
+	writeLibraryExportJSON(w, status, ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+type fakeLibraryExportRunner struct {
+	started  []int64
+	status   processor.LibraryExportStatus
+	startErr error
+	canceled string
+}
+
+func (f *fakeLibraryExportRunner) Start(_ uuid.UUID, trackIDs []int64) (processor.LibraryExportStatus, error) {
+	f.started = trackIDs
+	return f.status, f.startErr
+}
+
+func (f *fakeLibraryExportRunner) Status(_ uuid.UUID, id string) (processor.LibraryExportStatus, bool) {
+	return f.status, id == f.status.ID
+}
+
+func (f *fakeLibraryExportRunner) Cancel(_ uuid.UUID, id string) bool {
+	f.canceled = id
+	return id == f.status.ID
+}
+
+type fakeLibraryExportSigner struct {
+	filename string
+	ttl      time.Duration
+}
+
+func (f *fakeLibraryExportSigner) PresignDownloadObject(_ context.Context, key string, expires time.Duration, filename string) (string, error) {
+	f.filename, f.ttl = filename, expires
+	return "https://objects.example.test/" + key, nil
+}
+
+func libraryExportRequest(method, target, body, exportID string) *http.Request {
+	req := withUser(httptest.NewRequest(method, target, strings.NewReader(body)), uuid.New())
+	if exportID != "" {
+		req.SetPathValue("export_id", exportID)
+	}
+	return req
+}
+
+func TestStartLibraryExportValidatesSelection(t *testing.T) {
+	runner := &fakeLibraryExportRunner{status: processor.LibraryExportStatus{ID: "exp", State: processor.LibraryExportQueued}}
+	h := NewLibraryExportHandlers(runner, &fakeLibraryExportSigner{})
+
+	rec := httptest.NewRecorder()
+	h.StartExport(rec, libraryExportRequest(http.MethodPost, "/api/v1/library/export", `{"trackIds":[3,1,3]}`, ""))
+	if rec.Code != http.StatusAccepted || len(runner.started) != 2 || runner.started[0] != 3 {
+		t.Fatalf("status = %d started = %v body = %s", rec.Code, runner.started, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartExport(rec, libraryExportRequest(http.MethodPost, "/api/v1/library/export", `{"trackIds":[0]}`, ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("non-positive ID: status = %d", rec.Code)
+	}
+
+	runner.startErr = processor.ErrLibraryExportRunning
+	rec = httptest.NewRecorder()
+	h.StartExport(rec, libraryExportRequest(http.MethodPost, "/api/v1/library/export", "", ""))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "LIBRARY_EXPORT_RUNNING") {
+		t.Fatalf("running export: status = %d body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetLibraryExportSignsCompletedArchive(t *testing.T) {
+	expiresAt := time.Now().Add(5 * time.Minute)
+	runner := &fakeLibraryExportRunner{status: processor.LibraryExportStatus{
+		ID:         "exp",
+		State:      processor.LibraryExportCompleted,
+		CreatedAt:  time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC),
+		ExpiresAt:  &expiresAt,
+		ArchiveKey: "exports/library/u/exp.zip",
+	}}
+	signer := &fakeLibraryExportSigner{}
+	h := NewLibraryExportHandlers(runner, signer)
+
+	rec := httptest.NewRecorder()
+	h.GetExport(rec, libraryExportRequest(http.MethodGet, "/api/v1/library/export/exp", "", "exp"))
+	var got LibraryExportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if got.DownloadURL != "https://objects.example.test/exports/library/u/exp.zip" || got.DownloadExpiresAt == nil {
+		t.Fatalf("response = %+v", got)
+	}
+	if signer.filename != "openmusicplayer-library-2026-10-16.zip" || signer.ttl > 5*time.Minute {
+		t.Fatalf("signed %q for %s; want the URL to end with the archive", signer.filename, signer.ttl)
+	}
+	if strings.Contains(rec.Body.String(), "ArchiveKey") {
+		t.Fatalf("response exposes the archive key: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetExport(rec, libraryExportRequest(http.MethodGet, "/api/v1/library/export/other", "", "other"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown export: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelExport(rec, libraryExportRequest(http.MethodDelete, "/api/v1/library/export/exp", "", "exp"))
+	if rec.Code != http.StatusNoContent || runner.canceled != "exp" {
+		t.Fatalf("cancel: status = %d canceled = %q", rec.Code, runner.canceled)
+	}
+}
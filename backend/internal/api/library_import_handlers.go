@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/libraryimport"
+)
+
+// libraryImportMaxBodyBytes bounds uploaded library exports. iTunes XML for a
+// large library runs to tens of megabytes.
+const libraryImportMaxBodyBytes = 64 << 20
+
+const remoteLibraryImportMaxBodyBytes = 16 << 10
+
+type libraryImporter interface {
+	Import(ctx context.Context, userID uuid.UUID, source string, lib *libraryimport.SourceLibrary) (*libraryimport.Report, error)
+}
+
+type LibraryImportHandlers struct {
+	importer  libraryImporter
+	newRemote func(libraryimport.RemoteCredentials) (libraryimport.RemoteSource, error)
+	providers []libraryimport.PlaylistProvider
+}
+
+func NewLibraryImportHandlers(importer libraryImporter) *LibraryImportHandlers {
+	return &LibraryImportHandlers{
+		importer: importer,
+		newRemote: func(creds libraryimport.RemoteCredentials) (libraryimport.RemoteSource, error) {
+			return libraryimport.NewRemoteSource(creds, nil)
+		},
+	}
+}
+
+// SetPlaylistProviders enables playlist URL imports from the given services.
+func (h *LibraryImportHandlers) SetPlaylistProviders(providers ...libraryimport.PlaylistProvider) {
+	h.providers = providers
+}
+
+// PlaylistURLImportRequest names a public playlist on a streaming service.
+type PlaylistURLImportRequest struct {
+	URL string `json:"url"`
+}
+
+// RemoteLibraryImportRequest names another music server account to migrate
+// from. The password is used for this request only and is never stored.
+type RemoteLibraryImportRequest struct {
+	Kind     string `json:"kind"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LibraryImportTrackResponse struct {
+	SourceID         string  `json:"sourceId"`
+	Title            string  `json:"title"`
+	Artist           string  `json:"artist,omitempty"`
+	Status           string  `json:"status"`
+	TrackID          *int64  `json:"trackId,omitempty"`
+	Score            float64 `json:"score"`
+	RatingApplied    bool    `json:"ratingApplied"`
+	PlayCountApplied bool    `json:"playCountApplied"`
+	Favorited        bool    `json:"favorited"`
+}
+
+type LibraryImportPlaylistResponse struct {
+	SourceID      string `json:"sourceId"`
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	PlaylistID    *int64 `json:"playlistId,omitempty"`
+	MatchedTracks int    `json:"matchedTracks"`
+	MissingTracks int    `json:"missingTracks"`
+}
+
+type LibraryImportResponse struct {
+	Source             string                          `json:"source"`
+	ExactMatches       int                             `json:"exactMatches"`
+	FuzzyMatches       int                             `json:"fuzzyMatches"`
+	Unmatched          int                             `json:"unmatched"`
+	RatingsImported    int                             `json:"ratingsImported"`
+	PlayCountsImported int                             `json:"playCountsImported"`
+	FavoritesImported  int                             `json:"favoritesImported"`
+	PlaylistsCreated   int                             `json:"playlistsCreated"`
+	Tracks             []LibraryImportTrackResponse    `json:"tracks"`
+	Playlists          []LibraryImportPlaylistResponse `json:"playlists"`
+}
+
+// ImportITunes handles POST /api/v1/library/import/itunes. The body is the raw
+// iTunes / Music.app Library.xml export. The import runs synchronously and
+// responds with the per-item match report.
+func (h *LibraryImportHandlers) ImportITunes(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryImportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, libraryImportMaxBodyBytes)
+	lib, err := libraryimport.ParseITunesLibrary(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeLibraryImportError(w, http.StatusRequestEntityTooLarge, "LIBRARY_TOO_LARGE", "library export is too large")
+			return
+		}
+		writeLibraryImportError(w, http.StatusBadRequest, "INVALID_LIBRARY", "body must be an iTunes Library XML export")
+		return
+	}
+
+	report, err := h.importer.Import(r.Context(), userCtx.UserID, libraryimport.SourceITunes, lib)
+	if err != nil {
+		log.Printf("Warning: iTunes library import failed for user %s: %v", userCtx.UserID, err)
+		writeLibraryImportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to import library")
+		return
+	}
+	writeLibraryImportJSON(w, http.StatusOK, newLibraryImportResponse(report))
+}
+
+// ImportRemote handles POST /api/v1/library/import/remote. It signs in to a
+// Navidrome or Jellyfin server, pulls the account's playlists, favorites, play
+// counts, and (Navidrome only) ratings, and applies them like a file import.
+func (h *LibraryImportHandlers) ImportRemote(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryImportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	var req RemoteLibraryImportRequest
+	r.Body = http.MaxBytesReader(w, r.Body, remoteLibraryImportMaxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	if strings.TrimSpace(req.Username) == "" {
+		writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "username is required")
+		return
+	}
+
+	source, err := h.newRemote(libraryimport.RemoteCredentials{
+		Kind:     kind,
+		BaseURL:  req.URL,
+		Username: strings.TrimSpace(req.Username),
+		Password: req.Password,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, libraryimport.ErrUnsupportedRemote):
+			writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "kind must be navidrome or jellyfin")
+		default:
+			writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "url must be an absolute http(s) URL")
+		}
+		return
+	}
+
+	lib, err := source.Fetch(r.Context())
+	if err != nil {
+		if errors.Is(err, libraryimport.ErrRemoteAuth) {
+			writeLibraryImportError(w, http.StatusBadRequest, "REMOTE_AUTH_FAILED", "the remote server rejected the credentials")
+			return
+		}
+		log.Printf("Warning: %s library fetch failed for user %s: %v", kind, userCtx.UserID, err)
+		writeLibraryImportError(w, http.StatusBadGateway, "REMOTE_UNAVAILABLE", "failed to read the remote library")
+		return
+	}
+
+	report, err := h.importer.Import(r.Context(), userCtx.UserID, kind, lib)
+	if err != nil {
+		log.Printf("Warning: %s library import failed for user %s: %v", kind, userCtx.UserID, err)
+		writeLibraryImportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to import library")
+		return
+	}
+	writeLibraryImportJSON(w, http.StatusOK, newLibraryImportResponse(report))
+}
+
+// ImportPlaylistURL handles POST /api/v1/library/import/playlist. It reads a
+// public playlist from the service its URL belongs to, matches each track
+// against the caller's library, and creates a local playlist of the matches.
+// Unmatched tracks in the report are the ones that would need downloading.
+func (h *LibraryImportHandlers) ImportPlaylistURL(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryImportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	var req PlaylistURLImportRequest
+	r.Body = http.MaxBytesReader(w, r.Body, remoteLibraryImportMaxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	provider, playlistID, err := libraryimport.PlaylistProviderFor(h.providers, req.URL)
+	if err != nil {
+		writeLibraryImportError(w, http.StatusBadRequest, "UNSUPPORTED_PLAYLIST_URL", "url must be a public Spotify playlist link")
+		return
+	}
+
+	lib, err := provider.FetchPlaylist(r.Context(), playlistID)
+	if err != nil {
+		switch {
+		case errors.Is(err, libraryimport.ErrProviderNotConfigured):
+			writeLibraryImportError(w, http.StatusServiceUnavailable, "PROVIDER_NOT_CONFIGURED", "importing from this service is not configured on this server")
+		case errors.Is(err, libraryimport.ErrRemotePlaylistNotFound):
+			writeLibraryImportError(w, http.StatusNotFound, "PLAYLIST_NOT_FOUND", "the playlist does not exist or is not public")
+		default:
+			log.Printf("Warning: %s playlist fetch failed for user %s: %v", provider.Source(), userCtx.UserID, err)
+			writeLibraryImportError(w, http.StatusBadGateway, "REMOTE_UNAVAILABLE", "failed to read the playlist")
+		}
+		return
+	}
+
+	report, err := h.importer.Import(r.Context(), userCtx.UserID, provider.Source(), lib)
+	if err != nil {
+		log.Printf("Warning: %s playlist import failed for user %s: %v", provider.Source(), userCtx.UserID, err)
+		writeLibraryImportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to import playlist")
+		return
+	}
+	writeLibraryImportJSON(w, http.StatusOK, newLibraryImportResponse(report))
+}
+
+func newLibraryImportResponse(report *libraryimport.Report) LibraryImportResponse {
+	resp := LibraryImportResponse{
+		Source:             report.Source,
+		ExactMatches:       report.ExactMatches,
+		FuzzyMatches:       report.FuzzyMatches,
+		Unmatched:          report.Unmatched,
+		RatingsImported:    report.RatingsImported,
+		PlayCountsImported: report.PlayCountsImported,
+		FavoritesImported:  report.FavoritesImported,
+		PlaylistsCreated:   report.PlaylistsCreated,
+		Tracks:             make([]LibraryImportTrackResponse, 0, len(report.Tracks)),
+		Playlists:          make([]LibraryImportPlaylistResponse, 0, len(report.Playlists)),
+	}
+	for _, t := range report.Tracks {
+		item := LibraryImportTrackResponse{
+			SourceID:         t.SourceID,
+			Title:            t.Title,
+			Artist:           t.Artist,
+			Status:           t.Status,
+			Score:            t.Score,
+			RatingApplied:    t.RatingApplied,
+			PlayCountApplied: t.PlayCountApplied,
+			Favorited:        t.Favorited,
+		}
+		if t.TrackID != 0 {
+			trackID := t.TrackID
+			item.TrackID = &trackID
+		}
+		resp.Tracks = append(resp.Tracks, item)
+	}
+	for _, p := range report.Playlists {
+		item := LibraryImportPlaylistResponse{
+			SourceID:      p.SourceID,
+			Name:          p.Name,
+			Status:        p.Status,
+			MatchedTracks: p.MatchedTracks,
+			MissingTracks: p.MissingTracks,
+		}
+		if p.PlaylistID != 0 {
+			playlistID := p.PlaylistID
+			item.PlaylistID = &playlistID
+		}
+		resp.Playlists = append(resp.Playlists, item)
+	}
+	return resp
+}
+
+func writeLibraryImportJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeLibraryImportError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/libraryimport"
+)
+
+type fakeLibraryImporter struct {
+	lib *libraryimport.SourceLibrary
+}
+
+func (f *fakeLibraryImporter) Import(_ context.Context, userID uuid.UUID, source string, lib *libraryimport.SourceLibrary) (*libraryimport.Report, error) {
+	f.lib = lib
+	return &libraryimport.Report{
+		UserID:       userID,
+		Source:       source,
+		ExactMatches: 1,
+		Tracks: []libraryimport.TrackReport{
+			{SourceID: "1", Title: "Song", Status: libraryimport.MatchExact, TrackID: 7, Score: 100},
+		},
+		Playlists: []libraryimport.PlaylistReport{
+			{SourceID: "P1", Name: "Empty", Status: libraryimport.PlaylistStatusEmpty, MissingTracks: 2},
+		},
+	}, nil
+}
+
+const minimalITunesLibrary = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+	<key>Tracks</key><dict>
+		<key>1</key><dict><key>Track ID</key><integer>1</integer><key>Name</key><string>Song</string></dict>
+	</dict>
+</dict></plist>`
+
+func TestImportITunesReturnsMatchReport(t *testing.T) {
+	importer := &fakeLibraryImporter{}
+	h := NewLibraryImportHandlers(importer)
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/itunes", strings.NewReader(minimalITunesLibrary)), uuid.New())
+	rec := httptest.NewRecorder()
+	h.ImportITunes(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if importer.lib == nil || len(importer.lib.Tracks) != 1 {
+		t.Fatalf("parsed library = %#v", importer.lib)
+	}
+	var resp LibraryImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Source != libraryimport.SourceITunes || resp.ExactMatches != 1 || len(resp.Tracks) != 1 || resp.Tracks[0].TrackID == nil || *resp.Tracks[0].TrackID != 7 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resp.Playlists[0].PlaylistID != nil {
+		t.Fatalf("skipped playlist should not report an id: %+v", resp.Playlists[0])
+	}
+}
+
+func TestImportITunesRejectsInvalidExport(t *testing.T) {
+	h := NewLibraryImportHandlers(&fakeLibraryImporter{})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/itunes", strings.NewReader(`{"tracks": []}`)), uuid.New())
+	rec := httptest.NewRecorder()
+	h.ImportITunes(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+type fakeRemoteSource struct {
+	lib *libraryimport.SourceLibrary
+	err error
+}
+
+func (f fakeRemoteSource) Fetch(context.Context) (*libraryimport.SourceLibrary, error) {
+	return f.lib, f.err
+}
+
+func TestImportRemoteMapsRemoteFailures(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		source fakeRemoteSource
+		status int
+		code   string
+	}{
+		{"unsupported kind", `{"kind":"plex","url":"http://x","username":"a"}`, fakeRemoteSource{}, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"bad credentials", `{"kind":"navidrome","url":"http://x","username":"a"}`, fakeRemoteSource{err: libraryimport.ErrRemoteAuth}, http.StatusBadRequest, "REMOTE_AUTH_FAILED"},
+		{"server down", `{"kind":"jellyfin","url":"http://x","username":"a"}`, fakeRemoteSource{err: libraryimport.ErrRemoteUnavailable}, http.StatusBadGateway, "REMOTE_UNAVAILABLE"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewLibraryImportHandlers(&fakeLibraryImporter{})
+			h.newRemote = func(creds libraryimport.RemoteCredentials) (libraryimport.RemoteSource, error) {
+				if creds.Kind != libraryimport.SourceNavidrome && creds.Kind != libraryimport.SourceJellyfin {
+					return nil, libraryimport.ErrUnsupportedRemote
+				}
+				return tc.source, nil
+			}
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/remote", strings.NewReader(tc.body)), uuid.New())
+			rec := httptest.NewRecorder()
+			h.ImportRemote(rec, req)
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if rec.Code != tc.status || resp.Code != tc.code {
+				t.Fatalf("status = %d code = %q, want %d %q", rec.Code, resp.Code, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestImportRemoteImportsFetchedLibrary(t *testing.T) {
+	importer := &fakeLibraryImporter{}
+	h := NewLibraryImportHandlers(importer)
+	h.newRemote = func(libraryimport.RemoteCredentials) (libraryimport.RemoteSource, error) {
+		return fakeRemoteSource{lib: &libraryimport.SourceLibrary{Tracks: []libraryimport.SourceTrack{{SourceID: "x", Title: "Song"}}}}, nil
+	}
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/remote",
+		strings.NewReader(`{"kind":"Navidrome","url":"http://music.local","username":"a","password":"p"}`)), uuid.New())
+	rec := httptest.NewRecorder()
+	h.ImportRemote(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if importer.lib == nil || len(importer.lib.Tracks) != 1 {
+		t.Fatalf("imported library = %#v", importer.lib)
+	}
+}
+
+type fakePlaylistProvider struct {
+	lib *libraryimport.SourceLibrary
+	err error
+}
+
+func (f fakePlaylistProvider) Source() string { return libraryimport.SourceSpotify }
+
+func (f fakePlaylistProvider) PlaylistID(rawURL string) (string, bool) {
+	return "p1", strings.HasPrefix(rawURL, "https://open.spotify.com/playlist/")
+}
+
+func (f fakePlaylistProvider) FetchPlaylist(context.Context, string) (*libraryimport.SourceLibrary, error) {
+	return f.lib, f.err
+}
+
+func TestImportPlaylistURLMapsFailures(t *testing.T) {
+	cases := []struct {
+		name     string
+		url      string
+		provider fakePlaylistProvider
+		status   int
+		code     string
+	}{
+		{"unsupported url", "https://music.apple.com/playlist/pl.1", fakePlaylistProvider{}, http.StatusBadRequest, "UNSUPPORTED_PLAYLIST_URL"},
+		{"not configured", "https://open.spotify.com/playlist/p1", fakePlaylistProvider{err: libraryimport.ErrProviderNotConfigured}, http.StatusServiceUnavailable, "PROVIDER_NOT_CONFIGURED"},
+		{"private playlist", "https://open.spotify.com/playlist/p1", fakePlaylistProvider{err: libraryimport.ErrRemotePlaylistNotFound}, http.StatusNotFound, "PLAYLIST_NOT_FOUND"},
+		{"service down", "https://open.spotify.com/playlist/p1", fakePlaylistProvider{err: libraryimport.ErrRemoteUnavailable}, http.StatusBadGateway, "REMOTE_UNAVAILABLE"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewLibraryImportHandlers(&fakeLibraryImporter{})
+			h.SetPlaylistProviders(tc.provider)
+			body, _ := json.Marshal(PlaylistURLImportRequest{URL: tc.url})
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/playlist", strings.NewReader(string(body))), uuid.New())
+			rec := httptest.NewRecorder()
+			h.ImportPlaylistURL(rec, req)
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if rec.Code != tc.status || resp.Code != tc.code {
+				t.Fatalf("status = %d code = %q, want %d %q", rec.Code, resp.Code, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestImportPlaylistURLImportsFetchedPlaylist(t *testing.T) {
+	importer := &fakeLibraryImporter{}
+	h := NewLibraryImportHandlers(importer)
+	h.SetPlaylistProviders(fakePlaylistProvider{lib: &libraryimport.SourceLibrary{
+		Tracks:    []libraryimport.SourceTrack{{SourceID: "t1", Title: "Song"}},
+		Playlists: []libraryimport.SourcePlaylist{{SourceID: "p1", Name: "Mix", TrackSourceIDs: []string{"t1"}}},
+	}})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/library/import/playlist",
+		strings.NewReader(`{"url":"https://open.spotify.com/playlist/p1"}`)), uuid.New())
+	rec := httptest.NewRecorder()
+	h.ImportPlaylistURL(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp LibraryImportResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if importer.lib == nil || len(importer.lib.Playlists) != 1 || resp.Source != libraryimport.SourceSpotify {
+		t.Fatalf("imported library = %#v, response = %+v", importer.lib, resp)
+	}
+}
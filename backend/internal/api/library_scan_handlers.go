@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+type libraryScanRunner interface {
+	Start(adminID, userID uuid.UUID, dir string) (processor.LibraryScanStatus, error)
+	Status() (processor.LibraryScanStatus, bool)
+	Cancel() bool
+}
+
+type libraryScanUsers interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*db.User, error)
+}
+
+// LibraryScanHandlers lets admins import a mounted directory of existing
+// music into a user's library in the background and follow its progress.
+type LibraryScanHandlers struct {
+	runner libraryScanRunner
+	users  libraryScanUsers
+}
+
+func NewLibraryScanHandlers(runner libraryScanRunner, users libraryScanUsers) *LibraryScanHandlers {
+	return &LibraryScanHandlers{runner: runner, users: users}
+}
+
+type StartLibraryScanRequest struct {
+	// UserID is whose library the files are imported into.
+	UserID string `json:"userId"`
+	// Path is a directory under LIBRARY_SCAN_DIR; empty scans all of it.
+	Path string `json:"path"`
+}
+
+// StartScan handles POST /api/v1/admin/import/scan. Progress is pushed to
+// the caller's WebSocket connections as library_scan_progress messages,
+// and the queued files are items of the run's parent download job.
+func (h *LibraryScanHandlers) StartScan(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req StartLibraryScanRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeLibraryScanError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		writeLibraryScanError(w, http.StatusBadRequest, "VALIDATION_ERROR", "userId must be a user ID")
+		return
+	}
+	if _, err := h.users.GetByID(r.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			writeLibraryScanError(w, http.StatusNotFound, "USER_NOT_FOUND", "user not found")
+			return
+		}
+		writeLibraryScanError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load user")
+		return
+	}
+
+	status, err := h.runner.Start(userCtx.UserID, userID, req.Path)
+	switch {
+	case errors.Is(err, processor.ErrLibraryScanPath):
+		writeLibraryScanError(w, http.StatusBadRequest, "INVALID_PATH", "path must be a directory under the import directory")
+		return
+	case errors.Is(err, processor.ErrLibraryScanRunning):
+		writeLibraryScanJSON(w, http.StatusConflict, map[string]interface{}{
+			"code":    "LIBRARY_SCAN_RUNNING",
+			"message": "a library scan is already running",
+			"run":     status,
+		})
+		return
+	case err != nil:
+		writeLibraryScanError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start library scan")
+		return
+	}
+	writeLibraryScanJSON(w, http.StatusAccepted, status)
+}
+
+// GetScan handles GET /api/v1/admin/import/scan, returning the current or
+// most recent run.
+func (h *LibraryScanHandlers) GetScan(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	status, ok := h.runner.Status()
+	if !ok {
+		writeLibraryScanError(w, http.StatusNotFound, "LIBRARY_SCAN_NOT_FOUND", "no library scan has run")
+		return
+	}
+	writeLibraryScanJSON(w, http.StatusOK, status)
+}
+
+// CancelScan handles DELETE /api/v1/admin/import/scan. The run stops after
+// the file it is importing; files already queued still finish processing.
+func (h *LibraryScanHandlers) CancelScan(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	if !h.runner.Cancel() {
+		writeLibraryScanError(w, http.StatusConflict, "LIBRARY_SCAN_NOT_RUNNING", "no library scan is running")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *LibraryScanHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) (*auth.UserContext, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryScanError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	if !userCtx.IsAdmin() {
+		writeLibraryScanError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return nil, false
+	}
+	return userCtx, true
+}
+
+func writeLibraryScanJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeLibraryScanError(w http.ResponseWriter, status int, code, message string) {
+	writeLibraryScanJSON(w, status, ErrorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+type fakeLibraryScanRunner struct {
+	status   *processor.LibraryScanStatus
+	lastUser uuid.UUID
+	lastPath string
+}
+
+func (f *fakeLibraryScanRunner) Start(_, userID uuid.UUID, dir string) (processor.LibraryScanStatus, error) {
+	if dir == "missing" {
+		return processor.LibraryScanStatus{}, processor.ErrLibraryScanPath
+	}
+	if f.status != nil && f.status.State == processor.LibraryScanRunning {
+		return *f.status, processor.ErrLibraryScanRunning
+	}
+	f.lastUser, f.lastPath = userID, dir
+	f.status = &processor.LibraryScanStatus{ID: "run-1", State: processor.LibraryScanRunning, UserID: userID.String(), Path: dir}
+	return *f.status, nil
+}
+
+func (f *fakeLibraryScanRunner) Status() (processor.LibraryScanStatus, bool) {
+	if f.status == nil {
+		return processor.LibraryScanStatus{}, false
+	}
+	return *f.status, true
+}
+
+func (f *fakeLibraryScanRunner) Cancel() bool {
+	if f.status == nil || f.status.State != processor.LibraryScanRunning {
+		return false
+	}
+	f.status.State = processor.LibraryScanCanceled
+	return true
+}
+
+type fakeLibraryScanUsers map[uuid.UUID]bool
+
+func (f fakeLibraryScanUsers) GetByID(_ context.Context, id uuid.UUID) (*db.User, error) {
+	if !f[id] {
+		return nil, db.ErrUserNotFound
+	}
+	return &db.User{ID: id}, nil
+}
+
+func libraryScanRequest(method, body, email string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/admin/import/scan", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestLibraryScanStartStatusAndCancel(t *testing.T) {
+	listener := uuid.New()
+	runner := &fakeLibraryScanRunner{}
+	h := NewLibraryScanHandlers(runner, fakeLibraryScanUsers{listener: true})
+	start := func(body, email string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.StartScan(rec, libraryScanRequest(http.MethodPost, body, email))
+		return rec
+	}
+
+	valid := `{"userId":"` + listener.String() + `","path":"Band"}`
+	if rec := start(valid, "listener@example.test"); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin start = %d, want 403", rec.Code)
+	}
+	for body, want := range map[string]string{
+		`{"userId":"nope"}`:                                       "VALIDATION_ERROR",
+		`{"userId":"` + uuid.NewString() + `"}`:                   "USER_NOT_FOUND",
+		`{"userId":"` + listener.String() + `","path":"missing"}`: "INVALID_PATH",
+	} {
+		if rec := start(body, "ops@example.test"); !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("start %s = %d %s, want %s", body, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	if rec := start(valid, "ops@example.test"); rec.Code != http.StatusAccepted || runner.lastUser != listener || runner.lastPath != "Band" {
+		t.Fatalf("start = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := start(valid, "ops@example.test"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"LIBRARY_SCAN_RUNNING"`) {
+		t.Fatalf("second start = %d %s, want 409", rec.Code, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	h.CancelScan(rec, libraryScanRequest(http.MethodDelete, "", "ops@example.test"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("cancel = %d, want 204", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.GetScan(rec, libraryScanRequest(http.MethodGet, "", "ops@example.test"))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"state":"canceled"`) {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -60,3 +61,77 @@ func TestGetLibraryAcceptsDurationSort(t *testing.T) {
 	h.GetLibrary(rec, authedLibraryRequest("sort=duration&order=asc"))
 	t.Fatalf("expected nil-repo panic after validation, but handler returned cleanly")
 }
+
+// TestGetLibraryAcceptsPlayCountSort confirms sort=play_count passes validation,
+// using the same nil-repo panic probe as the duration case.
+func TestGetLibraryAcceptsPlayCountSort(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+	rec := httptest.NewRecorder()
+
+	defer func() {
+		_ = recover() // expected: nil libraryRepo dereference after validation passes
+		if rec.Code == http.StatusBadRequest {
+			t.Fatalf("sort=play_count was rejected with 400; want accepted")
+		}
+	}()
+
+	h.GetLibrary(rec, authedLibraryRequest("sort=play_count&order=asc"))
+	t.Fatalf("expected nil-repo panic after validation, but handler returned cleanly")
+}
+
+// TestGetLibraryAcceptsPlayStatSorts confirms the most_played and
+// recently_played presets pass validation.
+func TestGetLibraryAcceptsPlayStatSorts(t *testing.T) {
+	for _, sort := range []string{"most_played", "recently_played"} {
+		t.Run(sort, func(t *testing.T) {
+			h := NewLibraryHandlers(nil, nil)
+			rec := httptest.NewRecorder()
+
+			defer func() {
+				_ = recover() // expected: nil libraryRepo dereference after validation passes
+				if rec.Code == http.StatusBadRequest {
+					t.Fatalf("sort=%s was rejected with 400; want accepted", sort)
+				}
+			}()
+
+			h.GetLibrary(rec, authedLibraryRequest("sort="+sort))
+			t.Fatalf("expected nil-repo panic after validation, but handler returned cleanly")
+		})
+	}
+}
+
+// TestGetLibraryValidatesAuditFilters confirms source_type and the added_at
+// range are validated before any repository access.
+func TestGetLibraryValidatesAuditFilters(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+	for query, want := range map[string]string{
+		"source_type=bandcamp":                                     "INVALID_SOURCE_TYPE",
+		"added_after=yesterday":                                    "INVALID_DATE",
+		"added_after=2024-02-01&added_before=2024-01-01":           "INVALID_DATE",
+		"added_after=2024-01-01T00:00:00Z&added_before=2024-01-01": "INVALID_DATE",
+	} {
+		rec := httptest.NewRecorder()
+		h.GetLibrary(rec, authedLibraryRequest(query))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d; want 400", query, rec.Code)
+		}
+		var body LibraryErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode error body: %v", query, err)
+		}
+		if body.Code != want {
+			t.Fatalf("%s: code = %q; want %s", query, body.Code, want)
+		}
+	}
+}
+
+func TestParseLibraryDateParamAcceptsDatesAndTimestamps(t *testing.T) {
+	day, err := parseLibraryDateParam("2024-03-05")
+	if err != nil || !day.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("date = %v, %v", day, err)
+	}
+	ts, err := parseLibraryDateParam("2024-03-05T10:00:00+02:00")
+	if err != nil || !ts.Equal(time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("timestamp = %v, %v", ts, err)
+	}
+}
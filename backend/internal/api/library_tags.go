@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	maxTagLength = db.MaxTagLength
+	// maxTagFilters bounds how many ?tag= filters one library request may
+	// combine.
+	maxTagFilters = 10
+)
+
+type TrackTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TrackTagsResponse lists the caller's tags on a track. Genres, the
+// track's MusicBrainz genres, are only listed by GET.
+type TrackTagsResponse struct {
+	TrackID int64    `json:"track_id"`
+	Tags    []string `json:"tags"`
+	Genres  []string `json:"genres,omitempty"`
+}
+
+type TagListResponse struct {
+	Tags []db.TagCount `json:"tags"`
+}
+
+// normalizeTag lowercases a tag and checks it is 1-64 letters, digits, or
+// ':', '-', '_', '.' characters (see db.NormalizeTag).
+func normalizeTag(raw string) (string, bool) {
+	return db.NormalizeTag(raw)
+}
+
+// ListTags handles GET /api/v1/library/tags, returning each of the caller's
+// tags with how many library tracks carry it.
+func (h *LibraryHandlers) ListTags(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	tags, err := h.libraryRepo.ListTags(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list tags")
+		return
+	}
+	writeLibraryJSON(w, http.StatusOK, TagListResponse{Tags: tags})
+}
+
+// AddTrackTags handles POST /api/v1/tracks/{track_id}/tags (and the
+// deprecated POST /api/v1/library/tracks/{track_id}/tags). Tags the track
+// already has are ignored; the response lists all of its tags.
+func (h *LibraryHandlers) AddTrackTags(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, ok := parseTrackIDPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req TrackTagsRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 16*1024)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if len(req.Tags) == 0 || len(req.Tags) > db.MaxTrackTags {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_TAG", "tags must list 1-50 tags")
+		return
+	}
+	tags := make([]string, 0, len(req.Tags))
+	for _, raw := range req.Tags {
+		tag, ok := normalizeTag(raw)
+		if !ok {
+			writeLibraryError(w, http.StatusBadRequest, "INVALID_TAG", "tags must be 1-64 letters, digits, or : - _ . characters")
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	current, err := h.libraryRepo.AddTrackTags(r.Context(), userCtx.UserID, trackID, tags)
+	switch {
+	case errors.Is(err, db.ErrTrackNotInLibrary):
+		writeLibraryError(w, http.StatusNotFound, "TRACK_NOT_IN_LIBRARY", "track not in library")
+	case errors.Is(err, db.ErrTooManyTrackTags):
+		writeLibraryError(w, http.StatusConflict, "TOO_MANY_TAGS", "a track can have at most 50 tags")
+	case err != nil:
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to tag track")
+	default:
+		writeLibraryJSON(w, http.StatusOK, TrackTagsResponse{TrackID: trackID, Tags: current})
+	}
+}
+
+// GetTrackTags handles GET /api/v1/tracks/{track_id}/tags, listing the
+// caller's tags on a library track alongside its MusicBrainz genres. Both
+// work as ?tag= filters on the library and search.
+func (h *LibraryHandlers) GetTrackTags(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, ok := parseTrackIDPath(w, r)
+	if !ok {
+		return
+	}
+
+	tags, genres, err := h.libraryRepo.GetTrackTags(r.Context(), userCtx.UserID, trackID)
+	switch {
+	case errors.Is(err, db.ErrTrackNotInLibrary):
+		writeLibraryError(w, http.StatusNotFound, "TRACK_NOT_IN_LIBRARY", "track not in library")
+	case err != nil:
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load tags")
+	default:
+		writeLibraryJSON(w, http.StatusOK, TrackTagsResponse{TrackID: trackID, Tags: tags, Genres: genres})
+	}
+}
+
+// RemoveTrackTag handles DELETE /api/v1/tracks/{track_id}/tags/{tag} (and the
+// deprecated DELETE /api/v1/library/tracks/{track_id}/tags/{tag}).
+func (h *LibraryHandlers) RemoveTrackTag(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, ok := parseTrackIDPath(w, r)
+	if !ok {
+		return
+	}
+	tag, ok := normalizeTag(r.PathValue("tag"))
+	if !ok {
+		writeLibraryError(w, http.StatusBadRequest, "INVALID_TAG", "invalid tag")
+		return
+	}
+
+	current, err := h.libraryRepo.RemoveTrackTag(r.Context(), userCtx.UserID, trackID, tag)
+	switch {
+	case errors.Is(err, db.ErrTrackNotInLibrary):
+		writeLibraryError(w, http.StatusNotFound, "TRACK_NOT_IN_LIBRARY", "track not in library")
+	case err != nil:
+		writeLibraryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove tag")
+	default:
+		writeLibraryJSON(w, http.StatusOK, TrackTagsResponse{TrackID: trackID, Tags: current})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for raw, want := range map[string]string{
+		"  Mood:Focus ": "mood:focus",
+		"gym":           "gym",
+		"lo-fi_90s.v2":  "lo-fi_90s.v2",
+		"café":          "café",
+	} {
+		got, ok := normalizeTag(raw)
+		if !ok || got != want {
+			t.Errorf("normalizeTag(%q) = %q, %v; want %q, true", raw, got, ok, want)
+		}
+	}
+	for _, raw := range []string{"", "   ", "two words", "a/b", "bad!", strings.Repeat("x", maxTagLength+1)} {
+		if _, ok := normalizeTag(raw); ok {
+			t.Errorf("normalizeTag(%q) accepted; want rejected", raw)
+		}
+	}
+}
+
+// TestGetLibraryRejectsInvalidTag confirms a malformed tag filter is a 400
+// before any repository access (nil repo is never touched).
+func TestGetLibraryRejectsInvalidTag(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.GetLibrary(rec, authedLibraryRequest("tag=gym&tag=bad%20tag"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body LibraryErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	if body.Code != "INVALID_TAG" {
+		t.Fatalf("code = %q; want INVALID_TAG", body.Code)
+	}
+}
+
+func TestAddTrackTagsRejectsInvalidTag(t *testing.T) {
+	h := NewLibraryHandlers(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tracks/7/tags", strings.NewReader(`{"tags":["gym","no/slashes"]}`))
+	req.SetPathValue("track_id", "7")
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{
+		UserID: uuid.New(),
+		Email:  "tags@test.local",
+	}))
+	rec := httptest.NewRecorder()
+	h.AddTrackTags(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body LibraryErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	if body.Code != "INVALID_TAG" {
+		t.Fatalf("code = %q; want INVALID_TAG", body.Code)
+	}
+}
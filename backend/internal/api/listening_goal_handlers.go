@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/goals"
+)
+
+type listeningGoalService interface {
+	Statuses(ctx context.Context, userID uuid.UUID) ([]goals.Status, *time.Location, error)
+	Create(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error)
+	UpdateTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error)
+	Delete(ctx context.Context, userID uuid.UUID, kind string) error
+}
+
+// ListeningGoalHandlers serves a user's optional listening goals and their
+// progress.
+type ListeningGoalHandlers struct {
+	service listeningGoalService
+}
+
+func NewListeningGoalHandlers(service listeningGoalService) *ListeningGoalHandlers {
+	return &ListeningGoalHandlers{service: service}
+}
+
+type CreateListeningGoalRequest struct {
+	Kind   string `json:"kind"`
+	Target int    `json:"target"`
+}
+
+type UpdateListeningGoalRequest struct {
+	Target int `json:"target"`
+}
+
+type ListeningGoalResponse struct {
+	Kind          string    `json:"kind"`
+	Target        int       `json:"target"`
+	PeriodStart   string    `json:"periodStart,omitempty"`
+	PeriodEnd     string    `json:"periodEnd,omitempty"`
+	Progress      int       `json:"progress"`
+	Percent       int       `json:"percent"`
+	Met           bool      `json:"met"`
+	CurrentStreak int       `json:"currentStreak"`
+	BestStreak    int       `json:"bestStreak"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type ListeningGoalsResponse struct {
+	TimeZone string                  `json:"timeZone"`
+	Goals    []ListeningGoalResponse `json:"goals"`
+}
+
+// ListGoals handles GET /api/v1/stats/goals. Periods end at local midnight
+// (exclusive), so periodEnd is the first day of the next period.
+func (h *ListeningGoalHandlers) ListGoals(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeGoalError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	statuses, loc, err := h.service.Statuses(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeGoalError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load goals")
+		return
+	}
+	resp := ListeningGoalsResponse{TimeZone: loc.String(), Goals: make([]ListeningGoalResponse, 0, len(statuses))}
+	for _, status := range statuses {
+		resp.Goals = append(resp.Goals, ListeningGoalResponse{
+			Kind:          status.Goal.Kind,
+			Target:        status.Goal.Target,
+			PeriodStart:   status.PeriodStart.Format("2006-01-02"),
+			PeriodEnd:     status.PeriodEnd.Format("2006-01-02"),
+			Progress:      status.Progress,
+			Percent:       status.Percent(),
+			Met:           status.Met,
+			CurrentStreak: status.CurrentStreak,
+			BestStreak:    status.BestStreak,
+			CreatedAt:     status.Goal.CreatedAt,
+		})
+	}
+	writeGoalJSON(w, http.StatusOK, resp)
+}
+
+// CreateGoal handles POST /api/v1/stats/goals
+func (h *ListeningGoalHandlers) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeGoalError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	var req CreateListeningGoalRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGoalError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if !goals.ValidKind(req.Kind) {
+		writeInvalidGoalKind(w)
+		return
+	}
+
+	goal, err := h.service.Create(r.Context(), userCtx.UserID, req.Kind, req.Target)
+	switch {
+	case errors.Is(err, goals.ErrInvalidTarget):
+		writeInvalidGoalTarget(w, req.Kind)
+	case errors.Is(err, db.ErrListeningGoalExists):
+		writeGoalError(w, http.StatusConflict, "GOAL_EXISTS", "a goal of this kind already exists")
+	case err != nil:
+		writeGoalError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create goal")
+	default:
+		writeGoalJSON(w, http.StatusCreated, newListeningGoalResponse(goal))
+	}
+}
+
+// UpdateGoal handles PUT /api/v1/stats/goals/{kind}
+func (h *ListeningGoalHandlers) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeGoalError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	kind := r.PathValue("kind")
+	if !goals.ValidKind(kind) {
+		writeInvalidGoalKind(w)
+		return
+	}
+
+	var req UpdateListeningGoalRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGoalError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+
+	goal, err := h.service.UpdateTarget(r.Context(), userCtx.UserID, kind, req.Target)
+	switch {
+	case errors.Is(err, goals.ErrInvalidTarget):
+		writeInvalidGoalTarget(w, kind)
+	case errors.Is(err, db.ErrListeningGoalNotFound):
+		writeGoalError(w, http.StatusNotFound, "GOAL_NOT_FOUND", "goal not found")
+	case err != nil:
+		writeGoalError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update goal")
+	default:
+		writeGoalJSON(w, http.StatusOK, newListeningGoalResponse(goal))
+	}
+}
+
+// DeleteGoal handles DELETE /api/v1/stats/goals/{kind}
+func (h *ListeningGoalHandlers) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeGoalError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	kind := r.PathValue("kind")
+	if !goals.ValidKind(kind) {
+		writeInvalidGoalKind(w)
+		return
+	}
+
+	err := h.service.Delete(r.Context(), userCtx.UserID, kind)
+	switch {
+	case errors.Is(err, db.ErrListeningGoalNotFound):
+		writeGoalError(w, http.StatusNotFound, "GOAL_NOT_FOUND", "goal not found")
+	case err != nil:
+		writeGoalError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete goal")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newListeningGoalResponse describes a goal just created or updated; progress
+// is left to GET /api/v1/stats/goals.
+func newListeningGoalResponse(goal *db.ListeningGoal) ListeningGoalResponse {
+	return ListeningGoalResponse{Kind: goal.Kind, Target: goal.Target, CreatedAt: goal.CreatedAt}
+}
+
+func writeInvalidGoalKind(w http.ResponseWriter) {
+	writeGoalError(w, http.StatusBadRequest, "INVALID_GOAL_KIND", "kind must be minutes_per_week or new_artists_per_month")
+}
+
+func writeInvalidGoalTarget(w http.ResponseWriter, kind string) {
+	writeGoalError(w, http.StatusBadRequest, "INVALID_GOAL_TARGET", "target must be between 1 and "+strconv.Itoa(goals.MaxTarget(kind)))
+}
+
+func writeGoalJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeGoalError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/goals"
+)
+
+type fakeGoalService struct {
+	statuses []goals.Status
+	loc      *time.Location
+	created  []string
+	exists   bool
+}
+
+func (f *fakeGoalService) Statuses(ctx context.Context, userID uuid.UUID) ([]goals.Status, *time.Location, error) {
+	return f.statuses, f.loc, nil
+}
+
+func (f *fakeGoalService) Create(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	if target < 1 || target > goals.MaxTarget(kind) {
+		return nil, goals.ErrInvalidTarget
+	}
+	if f.exists {
+		return nil, db.ErrListeningGoalExists
+	}
+	f.created = append(f.created, kind)
+	return &db.ListeningGoal{UserID: userID, Kind: kind, Target: target}, nil
+}
+
+func (f *fakeGoalService) UpdateTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	return nil, db.ErrListeningGoalNotFound
+}
+
+func (f *fakeGoalService) Delete(ctx context.Context, userID uuid.UUID, kind string) error {
+	return nil
+}
+
+func TestListGoalsReportsProgress(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	svc := &fakeGoalService{loc: tokyo, statuses: []goals.Status{{
+		Goal:          db.ListeningGoal{Kind: goals.KindMinutesPerWeek, Target: 300},
+		PeriodStart:   time.Date(2026, 10, 12, 0, 0, 0, 0, tokyo),
+		PeriodEnd:     time.Date(2026, 10, 19, 0, 0, 0, 0, tokyo),
+		Progress:      150,
+		CurrentStreak: 2,
+		BestStreak:    4,
+	}}}
+	h := NewListeningGoalHandlers(svc)
+
+	rec := httptest.NewRecorder()
+	h.ListGoals(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/stats/goals", nil), uuid.New()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp ListeningGoalsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TimeZone != "Asia/Tokyo" || len(resp.Goals) != 1 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	goal := resp.Goals[0]
+	if goal.PeriodStart != "2026-10-12" || goal.PeriodEnd != "2026-10-19" || goal.Percent != 50 || goal.Met {
+		t.Fatalf("goal = %+v", goal)
+	}
+	if goal.CurrentStreak != 2 || goal.BestStreak != 4 {
+		t.Fatalf("streaks = %d/%d", goal.CurrentStreak, goal.BestStreak)
+	}
+}
+
+func TestListGoalsEncodesEmptyList(t *testing.T) {
+	h := NewListeningGoalHandlers(&fakeGoalService{loc: time.UTC})
+
+	rec := httptest.NewRecorder()
+	h.ListGoals(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/stats/goals", nil), uuid.New()))
+	if !strings.Contains(rec.Body.String(), `"goals":[]`) {
+		t.Fatalf("body = %s, want an empty goals list", rec.Body.String())
+	}
+}
+
+func TestCreateGoalValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		exists bool
+		status int
+		code   string
+	}{
+		{"created", `{"kind":"minutes_per_week","target":300}`, false, http.StatusCreated, ""},
+		{"unknown kind", `{"kind":"hours_per_day","target":3}`, false, http.StatusBadRequest, "INVALID_GOAL_KIND"},
+		{"target too high", `{"kind":"new_artists_per_month","target":5000}`, false, http.StatusBadRequest, "INVALID_GOAL_TARGET"},
+		{"zero target", `{"kind":"minutes_per_week"}`, false, http.StatusBadRequest, "INVALID_GOAL_TARGET"},
+		{"duplicate", `{"kind":"minutes_per_week","target":300}`, true, http.StatusConflict, "GOAL_EXISTS"},
+		{"bad json", `{`, false, http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewListeningGoalHandlers(&fakeGoalService{exists: tc.exists})
+			rec := httptest.NewRecorder()
+			h.CreateGoal(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/v1/stats/goals", strings.NewReader(tc.body)), uuid.New()))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.code != "" && !strings.Contains(rec.Body.String(), tc.code) {
+				t.Fatalf("body = %s, want code %s", rec.Body.String(), tc.code)
+			}
+		})
+	}
+}
+
+func TestUpdateAndDeleteGoalRejectUnknownKind(t *testing.T) {
+	h := NewListeningGoalHandlers(&fakeGoalService{})
+
+	req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/stats/goals/nope", strings.NewReader(`{"target":1}`)), uuid.New())
+	req.SetPathValue("kind", "nope")
+	rec := httptest.NewRecorder()
+	h.UpdateGoal(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("update status = %d", rec.Code)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/stats/goals/nope", nil), uuid.New())
+	req.SetPathValue("kind", "nope")
+	rec = httptest.NewRecorder()
+	h.DeleteGoal(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodPut, "/api/v1/stats/goals/minutes_per_week", strings.NewReader(`{"target":60}`)), uuid.New())
+	req.SetPathValue("kind", goals.KindMinutesPerWeek)
+	rec = httptest.NewRecorder()
+	h.UpdateGoal(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("update missing status = %d", rec.Code)
+	}
+}
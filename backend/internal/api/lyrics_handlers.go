@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/lyrics"
+)
+
+// maxLyricsRequestBytes bounds a lyrics edit; the longest songs run to a
+// few tens of kilobytes of LRC.
+const maxLyricsRequestBytes = 256 << 10
+
+type lyricsTrackStore interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+}
+
+// LyricsService finds, caches, and edits track lyrics. *lyrics.Service
+// satisfies it.
+type LyricsService interface {
+	ForTrack(ctx context.Context, track *db.Track) (*db.TrackLyrics, error)
+	Save(ctx context.Context, trackID int64, userID uuid.UUID, edit lyrics.Edit) (*db.TrackLyrics, error)
+	Reset(ctx context.Context, trackID int64) error
+}
+
+// LyricsHandlers serve and edit the lyrics of tracks in the caller's
+// library. Lyrics are shared by every listener of a track.
+type LyricsHandlers struct {
+	trackRepo   lyricsTrackStore
+	libraryRepo trackLibraryChecker
+	lyrics      LyricsService
+}
+
+func NewLyricsHandlers(trackRepo lyricsTrackStore, libraryRepo trackLibraryChecker, service LyricsService) *LyricsHandlers {
+	return &LyricsHandlers{trackRepo: trackRepo, libraryRepo: libraryRepo, lyrics: service}
+}
+
+// TrackLyricsResponse carries both lyric forms. Lines are the parsed
+// timings of SyncedLyrics and are empty for unsynced lyrics.
+type TrackLyricsResponse struct {
+	TrackID      int64         `json:"trackId"`
+	Source       string        `json:"source"`
+	Instrumental bool          `json:"instrumental"`
+	PlainLyrics  string        `json:"plainLyrics"`
+	SyncedLyrics string        `json:"syncedLyrics,omitempty"`
+	Lines        []lyrics.Line `json:"lines"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
+
+// UpdateLyricsRequest replaces a track's lyrics. SyncedLyrics must be LRC
+// text with [mm:ss.xx] line timings.
+type UpdateLyricsRequest struct {
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
+	Instrumental bool   `json:"instrumental"`
+}
+
+// GetTrackLyrics handles GET /api/v1/tracks/{track_id}/lyrics, looking the
+// lyrics up on LRCLIB the first time they are asked for.
+func (h *LyricsHandlers) GetTrackLyrics(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.libraryTrack(w, r)
+	if !ok {
+		return
+	}
+	found, err := h.lyrics.ForTrack(r.Context(), track)
+	if errors.Is(err, lyrics.ErrNotFound) {
+		writeTrackSourcesError(w, http.StatusNotFound, "LYRICS_NOT_FOUND", "no lyrics found for this track")
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: lyrics lookup failed for track %d: %v", track.ID, err)
+		writeTrackSourcesError(w, http.StatusBadGateway, "REMOTE_UNAVAILABLE", "lyrics are unavailable")
+		return
+	}
+	writeTrackSourcesJSON(w, http.StatusOK, newTrackLyricsResponse(found))
+}
+
+// UpdateTrackLyrics handles PUT /api/v1/tracks/{track_id}/lyrics. The edit
+// replaces the track's lyrics for every listener and is never overwritten
+// by a lookup.
+func (h *LyricsHandlers) UpdateTrackLyrics(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.libraryTrack(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateLyricsRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxLyricsRequestBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeTrackSourcesError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	userCtx := auth.GetUserFromContext(r.Context())
+	saved, err := h.lyrics.Save(r.Context(), track.ID, userCtx.UserID, lyrics.Edit{
+		PlainLyrics:  req.PlainLyrics,
+		SyncedLyrics: req.SyncedLyrics,
+		Instrumental: req.Instrumental,
+	})
+	switch {
+	case errors.Is(err, lyrics.ErrNoTimedLines):
+		writeTrackSourcesError(w, http.StatusBadRequest, "VALIDATION_ERROR", "syncedLyrics must be LRC text with [mm:ss.xx] timed lines")
+		return
+	case errors.Is(err, lyrics.ErrEmptyEdit):
+		writeTrackSourcesError(w, http.StatusBadRequest, "VALIDATION_ERROR", "plainLyrics or syncedLyrics is required unless the track is instrumental")
+		return
+	case err != nil:
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to save lyrics")
+		return
+	}
+	writeTrackSourcesJSON(w, http.StatusOK, newTrackLyricsResponse(saved))
+}
+
+// DeleteTrackLyrics handles DELETE /api/v1/tracks/{track_id}/lyrics,
+// dropping an edit or a bad match so the next request looks the lyrics up
+// again.
+func (h *LyricsHandlers) DeleteTrackLyrics(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.libraryTrack(w, r)
+	if !ok {
+		return
+	}
+	if err := h.lyrics.Reset(r.Context(), track.ID); err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to reset lyrics")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// libraryTrack loads the track named by the path when it is in the caller's
+// library, writing the error response otherwise.
+func (h *LyricsHandlers) libraryTrack(w http.ResponseWriter, r *http.Request) (*db.Track, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackSourcesError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeTrackSourcesError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return nil, false
+	}
+	inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library membership")
+		return nil, false
+	}
+	if !inLibrary {
+		writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return nil, false
+	}
+	track, err := h.trackRepo.GetByID(r.Context(), trackID)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotFound) {
+			writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+			return nil, false
+		}
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return nil, false
+	}
+	return track, true
+}
+
+func newTrackLyricsResponse(l *db.TrackLyrics) TrackLyricsResponse {
+	resp := TrackLyricsResponse{
+		TrackID:      l.TrackID,
+		Source:       l.Source,
+		Instrumental: l.Instrumental,
+		PlainLyrics:  l.PlainLyrics,
+		SyncedLyrics: l.SyncedLyrics,
+		Lines:        []lyrics.Line{},
+		UpdatedAt:    l.UpdatedAt,
+	}
+	if l.SyncedLyrics != "" {
+		resp.Lines = lyrics.ParseLRC(l.SyncedLyrics)
+	}
+	if resp.PlainLyrics == "" && len(resp.Lines) > 0 {
+		// Synced-only lyrics still read as text without their timings.
+		texts := make([]string, len(resp.Lines))
+		for i, line := range resp.Lines {
+			texts[i] = line.Text
+		}
+		resp.PlainLyrics = strings.Join(texts, "\n")
+	}
+	return resp
+}
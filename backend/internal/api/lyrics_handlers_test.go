@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/lyrics"
+)
+
+type fakeLyricsService struct {
+	stored  *db.TrackLyrics
+	err     error
+	edits   []lyrics.Edit
+	resets  []int64
+	editors []uuid.UUID
+}
+
+func (f *fakeLyricsService) ForTrack(_ context.Context, track *db.Track) (*db.TrackLyrics, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.stored, nil
+}
+
+func (f *fakeLyricsService) Save(_ context.Context, trackID int64, userID uuid.UUID, edit lyrics.Edit) (*db.TrackLyrics, error) {
+	if edit.SyncedLyrics != "" {
+		if err := lyrics.ValidateLRC(edit.SyncedLyrics); err != nil {
+			return nil, err
+		}
+	}
+	f.edits = append(f.edits, edit)
+	f.editors = append(f.editors, userID)
+	return &db.TrackLyrics{TrackID: trackID, Source: db.LyricsSourceManual, PlainLyrics: edit.PlainLyrics, SyncedLyrics: edit.SyncedLyrics}, nil
+}
+
+func (f *fakeLyricsService) Reset(_ context.Context, trackID int64) error {
+	f.resets = append(f.resets, trackID)
+	return nil
+}
+
+func serveLyrics(t *testing.T, handler http.HandlerFunc, method, trackID, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/tracks/"+trackID+"/lyrics", strings.NewReader(body))
+	req.SetPathValue("track_id", trackID)
+	rec := httptest.NewRecorder()
+	handler(rec, withUser(req, userID))
+	return rec
+}
+
+func TestGetTrackLyricsParsesSyncedLines(t *testing.T) {
+	service := &fakeLyricsService{stored: &db.TrackLyrics{TrackID: 42, Source: db.LyricsSourceLRCLIB, SyncedLyrics: "[00:01.00]Hello\n[00:03.50]World"}}
+	h := NewLyricsHandlers(&fakeTrackSourcesStore{track: sourcesTestTrack()}, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, service)
+
+	rec := serveLyrics(t, h.GetTrackLyrics, http.MethodGet, "42", "", uuid.New())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	var resp TrackLyricsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Source != "lrclib" || resp.PlainLyrics != "Hello\nWorld" || len(resp.Lines) != 2 || resp.Lines[1] != (lyrics.Line{TimeMs: 3500, Text: "World"}) {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	service.err = lyrics.ErrNotFound
+	if rec := serveLyrics(t, h.GetTrackLyrics, http.MethodGet, "42", "", uuid.New()); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "LYRICS_NOT_FOUND") {
+		t.Fatalf("status = %d body = %s, want LYRICS_NOT_FOUND", rec.Code, rec.Body.String())
+	}
+	service.err = errors.New("lrclib down")
+	if rec := serveLyrics(t, h.GetTrackLyrics, http.MethodGet, "42", "", uuid.New()); rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502 when the lookup fails", rec.Code)
+	}
+}
+
+func TestUpdateAndResetTrackLyrics(t *testing.T) {
+	service := &fakeLyricsService{}
+	h := NewLyricsHandlers(&fakeTrackSourcesStore{track: sourcesTestTrack()}, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, service)
+	userID := uuid.New()
+
+	rec := serveLyrics(t, h.UpdateTrackLyrics, http.MethodPut, "42", `{"syncedLyrics":"no timings here"}`, userID)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 for untimed LRC", rec.Code)
+	}
+	rec = serveLyrics(t, h.UpdateTrackLyrics, http.MethodPut, "42", `{"plainLyrics":"Mine","syncedLyrics":"[00:02.00]Mine"}`, userID)
+	if rec.Code != http.StatusOK || len(service.edits) != 1 || service.editors[0] != userID || !strings.Contains(rec.Body.String(), `"source":"manual"`) {
+		t.Fatalf("status = %d body = %s edits = %+v", rec.Code, rec.Body.String(), service.edits)
+	}
+
+	if rec := serveLyrics(t, h.DeleteTrackLyrics, http.MethodDelete, "42", "", userID); rec.Code != http.StatusNoContent || len(service.resets) != 1 {
+		t.Fatalf("status = %d resets = %v", rec.Code, service.resets)
+	}
+
+	h = NewLyricsHandlers(&fakeTrackSourcesStore{track: sourcesTestTrack()}, fakeTrackLibrary{}, service)
+	if rec := serveLyrics(t, h.UpdateTrackLyrics, http.MethodPut, "42", `{"plainLyrics":"x"}`, userID); rec.Code != http.StatusNotFound || len(service.edits) != 1 {
+		t.Fatalf("status = %d, want 404 outside the library", rec.Code)
+	}
+}
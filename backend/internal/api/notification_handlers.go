@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type notificationStore interface {
+	List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]db.Notification, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, id int64) error
+}
+
+// NotificationHandlers serves the caller's notification inbox.
+type NotificationHandlers struct {
+	notificationRepo notificationStore
+}
+
+func NewNotificationHandlers(notificationRepo notificationStore) *NotificationHandlers {
+	return &NotificationHandlers{notificationRepo: notificationRepo}
+}
+
+type NotificationResponse struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	Read      bool            `json:"read"`
+	ReadAt    *time.Time      `json:"readAt,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type NotificationsResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	Limit         int                    `json:"limit"`
+	Offset        int                    `json:"offset"`
+}
+
+// ListNotifications handles GET /api/v1/me/notifications.
+func (h *NotificationHandlers) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeNotificationError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	limit := parseIntParam(r, "limit", 50)
+	offset := parseIntParam(r, "offset", 0)
+
+	notifications, err := h.notificationRepo.List(r.Context(), userCtx.UserID, unreadOnly, limit, offset)
+	if err != nil {
+		writeNotificationError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list notifications")
+		return
+	}
+
+	resp := NotificationsResponse{
+		Notifications: make([]NotificationResponse, 0, len(notifications)),
+		Limit:         limit,
+		Offset:        offset,
+	}
+	for _, n := range notifications {
+		item := NotificationResponse{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Payload:   n.Payload,
+			Read:      n.ReadAt.Valid,
+			CreatedAt: n.CreatedAt,
+		}
+		if n.ReadAt.Valid {
+			readAt := n.ReadAt.Time
+			item.ReadAt = &readAt
+		}
+		resp.Notifications = append(resp.Notifications, item)
+	}
+	writeNotificationJSON(w, http.StatusOK, resp)
+}
+
+// MarkNotificationRead handles POST /api/v1/me/notifications/{id}/read.
+func (h *NotificationHandlers) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeNotificationError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeNotificationError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid notification ID")
+		return
+	}
+
+	if err := h.notificationRepo.MarkRead(r.Context(), userCtx.UserID, id); err != nil {
+		if errors.Is(err, db.ErrNotificationNotFound) {
+			writeNotificationError(w, http.StatusNotFound, "NOT_FOUND", "notification not found")
+			return
+		}
+		writeNotificationError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update notification")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeNotificationJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeNotificationError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/pagination"
 )
 
 // validPlayContextTypes is the exact allowed set for a play event's context_type.
@@ -23,33 +25,59 @@ var validPlayContextTypes = map[string]bool{
 	"search":   true,
 }
 
+const (
+	// maxPlayClockSkew is how far in the future a client-supplied playedAt
+	// may be before it is rejected.
+	maxPlayClockSkew = 5 * time.Minute
+	// maxPlayBackfillAge bounds how old a play submitted after listening
+	// offline may be.
+	maxPlayBackfillAge    = 30 * 24 * time.Hour
+	maxDurationListenedMs = 24 * 60 * 60 * 1000
+)
+
 type playEventTrackRepository interface {
 	GetByID(ctx context.Context, id int64) (*db.Track, error)
 }
 
 type playEventStore interface {
-	RecordPlay(ctx context.Context, userID uuid.UUID, trackID int64, contextType, contextID string) error
+	RecordPlayEvent(ctx context.Context, play db.PlayRecord) error
 	RecentlyPlayed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]db.RecentlyPlayedTrack, error)
 	PlayHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]db.PlayHistoryEvent, error)
-	TopTracks(ctx context.Context, userID uuid.UUID, days, limit int) ([]db.TopTrack, error)
+	TopTracks(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]db.TopTrack, error)
 }
 
 type PlayEventHandlers struct {
 	playEventRepo playEventStore
 	trackRepo     playEventTrackRepository
+	timeZones     timeZoneStore
+	now           func() time.Time
 }
 
 func NewPlayEventHandlers(playEventRepo playEventStore, trackRepo playEventTrackRepository) *PlayEventHandlers {
 	return &PlayEventHandlers{
 		playEventRepo: playEventRepo,
 		trackRepo:     trackRepo,
+		now:           time.Now,
 	}
 }
 
+// SetTimeZones makes top-track windows start at local midnight in each
+// listener's time zone instead of UTC.
+func (h *PlayEventHandlers) SetTimeZones(zones timeZoneStore) {
+	h.timeZones = zones
+}
+
+// RecordPlayRequest records one listen. PlayedAt defaults to now; clients
+// that queued plays while offline send when each play started. Skipped
+// reports a track the listener skipped away from, which counts as a skip
+// rather than a play in library statistics.
 type RecordPlayRequest struct {
-	TrackID     int64  `json:"trackId"`
-	ContextType string `json:"contextType,omitempty"`
-	ContextID   string `json:"contextId,omitempty"`
+	TrackID            int64      `json:"trackId"`
+	ContextType        string     `json:"contextType,omitempty"`
+	ContextID          string     `json:"contextId,omitempty"`
+	PlayedAt           *time.Time `json:"playedAt,omitempty"`
+	DurationListenedMs *int       `json:"durationListenedMs,omitempty"`
+	Skipped            bool       `json:"skipped,omitempty"`
 }
 
 type PlayEventTrackResponse struct {
@@ -80,11 +108,12 @@ type RecentlyPlayedResponse struct {
 }
 
 type PlayHistoryEntryResponse struct {
-	ID          int64                  `json:"id"`
-	Track       PlayEventTrackResponse `json:"track"`
-	PlayedAt    time.Time              `json:"playedAt"`
-	ContextType string                 `json:"contextType,omitempty"`
-	ContextID   string                 `json:"contextId,omitempty"`
+	ID                 int64                  `json:"id"`
+	Track              PlayEventTrackResponse `json:"track"`
+	PlayedAt           time.Time              `json:"playedAt"`
+	ContextType        string                 `json:"contextType,omitempty"`
+	ContextID          string                 `json:"contextId,omitempty"`
+	DurationListenedMs *int                   `json:"durationListenedMs,omitempty"`
 }
 
 type PlayHistoryResponse struct {
@@ -94,12 +123,14 @@ type PlayHistoryResponse struct {
 }
 
 type TopTracksResponse struct {
-	Tracks []PlayEventTrackResponse `json:"tracks"`
-	Days   int                      `json:"days"`
-	Limit  int                      `json:"limit"`
+	Tracks   []PlayEventTrackResponse `json:"tracks"`
+	Days     int                      `json:"days"`
+	Since    time.Time                `json:"since"`
+	TimeZone string                   `json:"timeZone"`
+	Limit    int                      `json:"limit"`
 }
 
-// RecordPlay handles POST /api/v1/me/plays.
+// RecordPlay handles POST /api/v1/me/plays and POST /api/v1/plays.
 func (h *PlayEventHandlers) RecordPlay(w http.ResponseWriter, r *http.Request) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	if userCtx == nil {
@@ -124,6 +155,29 @@ func (h *PlayEventHandlers) RecordPlay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	play := db.PlayRecord{
+		UserID:      userCtx.UserID,
+		TrackID:     req.TrackID,
+		ContextType: req.ContextType,
+		ContextID:   req.ContextID,
+		Skipped:     req.Skipped,
+	}
+	if req.PlayedAt != nil {
+		now := h.now()
+		if req.PlayedAt.After(now.Add(maxPlayClockSkew)) || req.PlayedAt.Before(now.Add(-maxPlayBackfillAge)) {
+			writePlayEventError(w, http.StatusBadRequest, "VALIDATION_ERROR", "playedAt must be within the last 30 days")
+			return
+		}
+		play.PlayedAt = *req.PlayedAt
+	}
+	if req.DurationListenedMs != nil {
+		if *req.DurationListenedMs < 0 || *req.DurationListenedMs > maxDurationListenedMs {
+			writePlayEventError(w, http.StatusBadRequest, "VALIDATION_ERROR", "durationListenedMs must be between 0 and 86400000")
+			return
+		}
+		play.DurationListenedMs = sql.NullInt64{Int64: int64(*req.DurationListenedMs), Valid: true}
+	}
+
 	// Verify the track exists so an unknown/foreign track is a clean 404 and no row
 	// is inserted.
 	if _, err := h.trackRepo.GetByID(r.Context(), req.TrackID); err != nil {
@@ -135,7 +189,7 @@ func (h *PlayEventHandlers) RecordPlay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.playEventRepo.RecordPlay(r.Context(), userCtx.UserID, req.TrackID, req.ContextType, req.ContextID); err != nil {
+	if err := h.playEventRepo.RecordPlayEvent(r.Context(), play); err != nil {
 		writePlayEventError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to record play")
 		return
 	}
@@ -146,21 +200,52 @@ func (h *PlayEventHandlers) RecordPlay(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// PlayHistory handles GET /api/v1/me/plays/history.
+// PlayHistory handles GET /api/v1/me/plays/history and GET /api/v1/history.
 func (h *PlayEventHandlers) PlayHistory(w http.ResponseWriter, r *http.Request) {
-	userCtx := auth.GetUserFromContext(r.Context())
-	if userCtx == nil {
-		writePlayEventError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+	limit := parseIntParam(r, "limit", 50)
+	offset := parseIntParam(r, "offset", 0)
+
+	responses, ok := h.playHistory(w, r, limit, offset)
+	if !ok {
 		return
 	}
 
+	writePlayEventJSON(w, http.StatusOK, PlayHistoryResponse{
+		Plays:  responses,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// PlayHistoryPage handles GET /api/v2/me/plays/history. History is not
+// counted, so the page carries no total.
+func (h *PlayEventHandlers) PlayHistoryPage(w http.ResponseWriter, r *http.Request) {
 	limit := parseIntParam(r, "limit", 50)
-	offset := parseIntParam(r, "offset", 0)
+	offset, err := pagination.Offset(r, parseIntParam(r, "offset", 0))
+	if err != nil {
+		writePlayEventError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid or expired")
+		return
+	}
+
+	responses, ok := h.playHistory(w, r, limit, offset)
+	if !ok {
+		return
+	}
+
+	pagination.Write(w, responses, limit, offset, nil)
+}
+
+func (h *PlayEventHandlers) playHistory(w http.ResponseWriter, r *http.Request, limit, offset int) ([]PlayHistoryEntryResponse, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlayEventError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return nil, false
+	}
 
 	events, err := h.playEventRepo.PlayHistory(r.Context(), userCtx.UserID, limit, offset)
 	if err != nil {
 		writePlayEventError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load play history")
-		return
+		return nil, false
 	}
 
 	responses := make([]PlayHistoryEntryResponse, 0, len(events))
@@ -178,14 +263,14 @@ func (h *PlayEventHandlers) PlayHistory(w http.ResponseWriter, r *http.Request)
 		if event.ContextID.Valid {
 			response.ContextID = event.ContextID.String
 		}
+		if event.DurationListenedMs.Valid {
+			listened := int(event.DurationListenedMs.Int64)
+			response.DurationListenedMs = &listened
+		}
 		responses = append(responses, response)
 	}
 
-	writePlayEventJSON(w, http.StatusOK, PlayHistoryResponse{
-		Plays:  responses,
-		Limit:  limit,
-		Offset: offset,
-	})
+	return responses, true
 }
 
 // RecentlyPlayed handles GET /api/v1/me/plays/recent.
@@ -228,9 +313,17 @@ func (h *PlayEventHandlers) TopTracks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	days := parseIntParam(r, "days", 30)
+	if days <= 0 {
+		days = 30
+	}
 	limit := parseIntParam(r, "limit", 20)
 
-	tracks, err := h.playEventRepo.TopTracks(r.Context(), userCtx.UserID, days, limit)
+	// The window covers today plus the days-1 calendar days before it, so
+	// "last 7 days" lines up with the listener's own midnights.
+	loc := userLocation(r.Context(), h.timeZones, userCtx.UserID)
+	since := startOfDay(h.now(), loc).AddDate(0, 0, -(days - 1))
+
+	tracks, err := h.playEventRepo.TopTracks(r.Context(), userCtx.UserID, since, limit)
 	if err != nil {
 		writePlayEventError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load top tracks")
 		return
@@ -245,9 +338,11 @@ func (h *PlayEventHandlers) TopTracks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writePlayEventJSON(w, http.StatusOK, TopTracksResponse{
-		Tracks: responses,
-		Days:   days,
-		Limit:  limit,
+		Tracks:   responses,
+		Days:     days,
+		Since:    since,
+		TimeZone: loc.String(),
+		Limit:    limit,
 	})
 }
 
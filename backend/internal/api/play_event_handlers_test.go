@@ -14,6 +14,7 @@ import (
 
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/pagination"
 )
 
 func withUser(req *http.Request, userID uuid.UUID) *http.Request {
@@ -37,6 +38,9 @@ type recordedPlay struct {
 	trackID     int64
 	contextType string
 	contextID   string
+	playedAt    time.Time
+	listenedMs  sql.NullInt64
+	skipped     bool
 }
 
 type fakePlayStore struct {
@@ -44,10 +48,13 @@ type fakePlayStore struct {
 	recent  []db.RecentlyPlayedTrack
 	history []db.PlayHistoryEvent
 	top     []db.TopTrack
+	since   time.Time
+
+	historyOffset int
 }
 
-func (f *fakePlayStore) RecordPlay(ctx context.Context, userID uuid.UUID, trackID int64, contextType, contextID string) error {
-	f.records = append(f.records, recordedPlay{userID, trackID, contextType, contextID})
+func (f *fakePlayStore) RecordPlayEvent(ctx context.Context, play db.PlayRecord) error {
+	f.records = append(f.records, recordedPlay{play.UserID, play.TrackID, play.ContextType, play.ContextID, play.PlayedAt, play.DurationListenedMs, play.Skipped})
 	return nil
 }
 
@@ -56,10 +63,12 @@ func (f *fakePlayStore) RecentlyPlayed(ctx context.Context, userID uuid.UUID, li
 }
 
 func (f *fakePlayStore) PlayHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]db.PlayHistoryEvent, error) {
+	f.historyOffset = offset
 	return f.history, nil
 }
 
-func (f *fakePlayStore) TopTracks(ctx context.Context, userID uuid.UUID, days, limit int) ([]db.TopTrack, error) {
+func (f *fakePlayStore) TopTracks(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]db.TopTrack, error) {
+	f.since = since
 	return f.top, nil
 }
 
@@ -83,6 +92,9 @@ func TestRecordPlayValidation(t *testing.T) {
 		{"missing trackId -> 400", true, `{"contextType":"library"}`, http.StatusBadRequest},
 		{"invalid contextType -> 400", true, `{"trackId":1,"contextType":"radio"}`, http.StatusBadRequest},
 		{"unknown track -> 404", true, `{"trackId":999,"contextType":"library"}`, http.StatusNotFound},
+		{"future playedAt -> 400", true, `{"trackId":1,"playedAt":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`, http.StatusBadRequest},
+		{"stale playedAt -> 400", true, `{"trackId":1,"playedAt":"` + time.Now().AddDate(0, 0, -31).Format(time.RFC3339) + `"}`, http.StatusBadRequest},
+		{"negative durationListenedMs -> 400", true, `{"trackId":1,"durationListenedMs":-1}`, http.StatusBadRequest},
 	}
 
 	for _, tc := range cases {
@@ -138,6 +150,47 @@ func TestRecordPlaySuccessInsertsOne(t *testing.T) {
 	if got.userID != userID || got.trackID != 7 || got.contextType != "playlist" || got.contextID != "pl-9" {
 		t.Fatalf("recorded play = %#v, want user %v track 7 playlist pl-9", got, userID)
 	}
+	if !got.playedAt.IsZero() || got.listenedMs.Valid {
+		t.Fatalf("recorded play = %#v, want server-set time and no duration", got)
+	}
+}
+
+func TestRecordPlayKeepsClientTimestampAndDuration(t *testing.T) {
+	store := &fakePlayStore{}
+	tracks := &fakePlayTrackRepo{tracks: map[int64]*db.Track{7: newTrack(7, "Alpha")}}
+	h := NewPlayEventHandlers(store, tracks)
+
+	playedAt := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	body := `{"trackId":7,"playedAt":"` + playedAt.Format(time.RFC3339) + `","durationListenedMs":183000}`
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/plays", strings.NewReader(body)), uuid.New())
+	rr := httptest.NewRecorder()
+	h.RecordPlay(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body=%s)", rr.Code, rr.Body.String())
+	}
+	got := store.records[0]
+	if !got.playedAt.Equal(playedAt) || got.listenedMs != (sql.NullInt64{Int64: 183000, Valid: true}) {
+		t.Fatalf("recorded play = %#v, want playedAt %v and 183000ms listened", got, playedAt)
+	}
+}
+
+func TestRecordPlayPassesSkip(t *testing.T) {
+	store := &fakePlayStore{}
+	tracks := &fakePlayTrackRepo{tracks: map[int64]*db.Track{7: newTrack(7, "Alpha")}}
+	h := NewPlayEventHandlers(store, tracks)
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/plays",
+		strings.NewReader(`{"trackId":7,"durationListenedMs":4000,"skipped":true}`)), uuid.New())
+	rr := httptest.NewRecorder()
+	h.RecordPlay(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body=%s)", rr.Code, rr.Body.String())
+	}
+	if got := store.records[0]; !got.skipped {
+		t.Fatalf("recorded play = %#v, want a skip", got)
+	}
 }
 
 func TestRecentlyPlayedHTTP(t *testing.T) {
@@ -167,11 +220,12 @@ func TestPlayHistoryHTTP(t *testing.T) {
 	now := time.Now()
 	store := &fakePlayStore{history: []db.PlayHistoryEvent{
 		{
-			ID:          10,
-			Track:       *newTrack(2, "Bravo"),
-			PlayedAt:    now,
-			ContextType: sqlNullString("playlist"),
-			ContextID:   sqlNullString("pl-1"),
+			ID:                 10,
+			Track:              *newTrack(2, "Bravo"),
+			PlayedAt:           now,
+			ContextType:        sqlNullString("playlist"),
+			ContextID:          sqlNullString("pl-1"),
+			DurationListenedMs: sql.NullInt64{Int64: 95000, Valid: true},
 		},
 		{
 			ID:       9,
@@ -197,9 +251,50 @@ func TestPlayHistoryHTTP(t *testing.T) {
 	if resp.Plays[0].ID != 10 || resp.Plays[0].Track.ID != 2 || resp.Plays[0].ContextType != "playlist" || resp.Plays[0].ContextID != "pl-1" {
 		t.Fatalf("first play = %#v, want event 10 track 2 playlist pl-1", resp.Plays[0])
 	}
+	if resp.Plays[0].DurationListenedMs == nil || *resp.Plays[0].DurationListenedMs != 95000 {
+		t.Fatalf("first play listened = %v, want 95000", resp.Plays[0].DurationListenedMs)
+	}
 	if resp.Plays[1].ID != 9 || resp.Plays[1].Track.ID != 2 {
 		t.Fatalf("second play = %#v, want repeated track event 9", resp.Plays[1])
 	}
+	if resp.Plays[1].DurationListenedMs != nil {
+		t.Fatalf("second play listened = %v, want omitted", *resp.Plays[1].DurationListenedMs)
+	}
+}
+
+func TestPlayHistoryPageHTTP(t *testing.T) {
+	store := &fakePlayStore{history: []db.PlayHistoryEvent{
+		{ID: 10, Track: *newTrack(2, "Bravo"), PlayedAt: time.Now()},
+		{ID: 9, Track: *newTrack(1, "Alpha"), PlayedAt: time.Now()},
+	}}
+	h := NewPlayEventHandlers(store, &fakePlayTrackRepo{})
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/v2/me/plays/history?limit=2&cursor="+pagination.EncodeCursor(4), nil), uuid.New())
+	rr := httptest.NewRecorder()
+	h.PlayHistoryPage(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	var page pagination.Page[PlayHistoryEntryResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if store.historyOffset != 4 || page.PageInfo.Offset != 4 {
+		t.Fatalf("offset = %d (pageInfo %d), want the cursor's 4", store.historyOffset, page.PageInfo.Offset)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != 10 {
+		t.Fatalf("items = %#v, want events 10 and 9", page.Items)
+	}
+	if page.PageInfo.Total != nil || !page.PageInfo.HasMore || page.PageInfo.NextCursor != pagination.EncodeCursor(6) {
+		t.Fatalf("pageInfo = %+v, want no total and a cursor to offset 6 after a full page", page.PageInfo)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodGet, "/api/v2/me/plays/history?cursor=not-a-cursor", nil), uuid.New())
+	rr = httptest.NewRecorder()
+	h.PlayHistoryPage(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "INVALID_CURSOR") {
+		t.Fatalf("bad cursor: status = %d, body = %s; want 400 INVALID_CURSOR", rr.Code, rr.Body.String())
+	}
 }
 
 func TestTopTracksHTTP(t *testing.T) {
@@ -228,6 +323,48 @@ func TestTopTracksHTTP(t *testing.T) {
 	}
 }
 
+type fakeTimeZones map[uuid.UUID]string
+
+func (f fakeTimeZones) TimeZone(ctx context.Context, userID uuid.UUID) (string, error) {
+	return f[userID], nil
+}
+
+func TestTopTracksWindowStartsAtLocalMidnight(t *testing.T) {
+	userID := uuid.New()
+	store := &fakePlayStore{}
+	h := NewPlayEventHandlers(store, &fakePlayTrackRepo{})
+	h.SetTimeZones(fakeTimeZones{userID: "America/Los_Angeles"})
+	// 03:00 UTC on Mar 10 is still the evening of Mar 9 in Los Angeles.
+	h.now = func() time.Time { return time.Date(2026, time.March, 10, 3, 0, 0, 0, time.UTC) }
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/plays/top?days=2", nil), userID)
+	rr := httptest.NewRecorder()
+	h.TopTracks(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	want := time.Date(2026, time.March, 8, 0, 0, 0, 0, loc)
+	if !store.since.Equal(want) {
+		t.Fatalf("since = %s, want %s", store.since, want)
+	}
+	var resp TopTracksResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TimeZone != "America/Los_Angeles" {
+		t.Fatalf("timeZone = %q, want America/Los_Angeles", resp.TimeZone)
+	}
+
+	// Without a stored zone the window falls back to UTC days.
+	h.SetTimeZones(fakeTimeZones{})
+	rr = httptest.NewRecorder()
+	h.TopTracks(rr, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/plays/top?days=2", nil), userID))
+	if want := time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC); !store.since.Equal(want) {
+		t.Fatalf("since = %s, want %s", store.since, want)
+	}
+}
+
 func sqlNullString(value string) sql.NullString {
 	return sql.NullString{String: value, Valid: value != ""}
 }
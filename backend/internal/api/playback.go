@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -12,8 +14,10 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/capability"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/transcode"
 )
 
 const (
@@ -24,6 +28,8 @@ const (
 
 	playbackUnavailableCodeAudioUnavailable = "audio_unavailable"
 	playbackUnavailableCodeArtifactMissing  = "artifact_missing"
+	playbackUnavailableCodeTakenDown        = "taken_down"
+	playbackUnavailableCodeTranscodeFailed  = "transcode_failed"
 )
 
 type playbackTrackRepository interface {
@@ -36,15 +42,28 @@ type playbackLibraryRepository interface {
 
 type playbackURLStorage interface {
 	StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
-	PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+	PresignPrivateGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+	PresignDownloadObject(ctx context.Context, key string, expires time.Duration, filename string) (string, error)
+}
+
+// playbackCapabilities checks track capabilities; *capability.Checker
+// satisfies it.
+type playbackCapabilities interface {
+	Authorize(ctx context.Context, token string, listener uuid.NullUUID) (capability.Claims, error)
+}
+
+type playbackTranscoder interface {
+	Variant(ctx context.Context, sourceKey string, source *storage.ObjectInfo, format transcode.Format) (string, *storage.ObjectInfo, error)
 }
 
 // PlaybackHandlers issues short-lived direct object URLs for authorized playback/download.
 type PlaybackHandlers struct {
-	trackRepo   playbackTrackRepository
-	libraryRepo playbackLibraryRepository
-	storage     playbackURLStorage
-	now         func() time.Time
+	trackRepo    playbackTrackRepository
+	libraryRepo  playbackLibraryRepository
+	storage      playbackURLStorage
+	transcoder   playbackTranscoder
+	capabilities playbackCapabilities
+	now          func() time.Time
 }
 
 func NewPlaybackHandlers(trackRepo playbackTrackRepository, libraryRepo playbackLibraryRepository, storageClient playbackURLStorage) *PlaybackHandlers {
@@ -56,13 +75,36 @@ func NewPlaybackHandlers(trackRepo playbackTrackRepository, libraryRepo playback
 	}
 }
 
+// SetTranscoder lets clients ask for a format other than the stored one.
+// Without it, format requests are ignored and the original is signed.
+func (h *PlaybackHandlers) SetTranscoder(transcoder playbackTranscoder) {
+	h.transcoder = transcoder
+}
+
+// SetCapabilities lets listeners play tracks outside their library with
+// track capabilities from a shared context, and enables anonymous playback
+// of tracks shared without a grantee.
+func (h *PlaybackHandlers) SetCapabilities(capabilities playbackCapabilities) {
+	h.capabilities = capabilities
+}
+
+// PlaybackURLRequest asks for signed URLs. Format ("opus", "mp3", "flac"),
+// or ?format=, picks the encoding; without either, audio/* types in the
+// Accept header are used. Capabilities authorize tracks that are not in the
+// caller's library. Cached maps track IDs to the validator of audio the
+// client already holds; tracks whose audio is unchanged come back in
+// NotModified instead of with a new URL.
 type PlaybackURLRequest struct {
-	TrackIDs   []int64 `json:"trackIds"`
-	TTLSeconds int     `json:"ttlSeconds,omitempty"`
+	TrackIDs     []int64          `json:"trackIds"`
+	TTLSeconds   int              `json:"ttlSeconds,omitempty"`
+	Format       string           `json:"format,omitempty"`
+	Capabilities []string         `json:"capabilities,omitempty"`
+	Cached       map[int64]string `json:"cached,omitempty"`
 }
 
 type PlaybackURLResponse struct {
 	URLs        []PlaybackURLItem         `json:"urls"`
+	NotModified []int64                   `json:"notModified,omitempty"`
 	Unavailable []PlaybackUnavailableItem `json:"unavailable,omitempty"`
 }
 
@@ -78,6 +120,20 @@ type PlaybackURLItem struct {
 	Channels          int       `json:"channels,omitempty"`
 	ETag              string    `json:"etag,omitempty"`
 	StorageKeyVersion string    `json:"storageKeyVersion,omitempty"`
+	// Validator is a strong entity tag for the signed audio, derived from
+	// the object's key and ETag and the track version. Clients send it back
+	// in PlaybackURLRequest.Cached to skip unchanged audio.
+	Validator string `json:"validator,omitempty"`
+	// Transcoded is set when the URL points at a variant in the requested
+	// format rather than the stored original.
+	Transcoded bool `json:"transcoded,omitempty"`
+	// ReplayGain values for volume normalization, set once the track's
+	// loudness has been measured. Peaks are true peaks in dBTP.
+	LoudnessLUFS  *float64 `json:"loudnessLufs,omitempty"`
+	TrackGainDB   *float64 `json:"trackGainDb,omitempty"`
+	TrackPeakDBTP *float64 `json:"trackPeakDbtp,omitempty"`
+	AlbumGainDB   *float64 `json:"albumGainDb,omitempty"`
+	AlbumPeakDBTP *float64 `json:"albumPeakDbtp,omitempty"`
 }
 
 type PlaybackUnavailableItem struct {
@@ -103,7 +159,26 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 		writePlaybackError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
 		return
 	}
+	h.issuePlaybackURLs(w, r, uuid.NullUUID{UUID: userCtx.UserID, Valid: true})
+}
 
+// CreatePublicPlaybackURLs handles POST /api/v1/public/playback/urls. Without
+// a signed-in listener, every track needs a capability from a grant that
+// names no grantee.
+func (h *PlaybackHandlers) CreatePublicPlaybackURLs(w http.ResponseWriter, r *http.Request) {
+	if h == nil || h.trackRepo == nil || h.storage == nil || h.capabilities == nil {
+		writePlaybackError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "playback URL issuance is unavailable")
+		return
+	}
+	h.issuePlaybackURLs(w, r, uuid.NullUUID{})
+}
+
+// issuePlaybackURLs signs URLs for tracks listener may play: tracks in their
+// library, or tracks covered by a capability in the request. Capabilities
+// are checked only here, so a URL signed for one never outlives its grant's
+// expiry, but one already issued stays valid until its own expiresAt after
+// the grant is revoked.
+func (h *PlaybackHandlers) issuePlaybackURLs(w http.ResponseWriter, r *http.Request, listener uuid.NullUUID) {
 	var req PlaybackURLRequest
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -129,20 +204,53 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 		writePlaybackError(w, http.StatusBadRequest, "INVALID_REQUEST", "too many track IDs requested")
 		return
 	}
+	if len(req.Capabilities) > maxPlaybackURLBatch {
+		writePlaybackError(w, http.StatusBadRequest, "INVALID_REQUEST", "too many capabilities")
+		return
+	}
+	if len(req.Cached) > maxPlaybackURLBatch {
+		writePlaybackError(w, http.StatusBadRequest, "INVALID_REQUEST", "too many cached validators")
+		return
+	}
+	granted, err := h.authorizeCapabilities(r.Context(), req.Capabilities, listener)
+	if err != nil {
+		if errors.Is(err, capability.ErrInvalid) || errors.Is(err, capability.ErrExpired) {
+			writePlaybackError(w, http.StatusForbidden, "CAPABILITY_DENIED", "track capability is invalid, expired, or revoked")
+			return
+		}
+		writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify track capability")
+		return
+	}
+
+	formatName := req.Format
+	if formatName == "" {
+		formatName = r.URL.Query().Get("format")
+	}
+	format, wantFormat, err := transcode.Negotiate(formatName, r.Header.Get("Accept"))
+	if err != nil {
+		writePlaybackError(w, http.StatusBadRequest, "UNSUPPORTED_FORMAT", "format must be one of opus, mp3, or flac")
+		return
+	}
 
 	ttl := clampPlaybackTTL(req.TTLSeconds)
-	expiresAt := h.now().Add(ttl).UTC()
 	resp := PlaybackURLResponse{
 		URLs: make([]PlaybackURLItem, 0, len(trackIDs)),
 	}
 
 	for _, trackID := range trackIDs {
-		inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
-		if err != nil {
-			writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library ownership")
-			return
+		itemTTL := ttl
+		grantExpiry, allowed := granted[trackID]
+		if allowed {
+			itemTTL = capabilityTTL(ttl, grantExpiry.Sub(h.now()))
+		}
+		if !allowed && listener.Valid {
+			allowed, err = h.libraryRepo.IsTrackInLibrary(r.Context(), listener.UUID, trackID)
+			if err != nil {
+				writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library ownership")
+				return
+			}
 		}
-		if !inLibrary {
+		if !allowed {
 			writePlaybackError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
 			return
 		}
@@ -157,6 +265,15 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 			return
 		}
 
+		if track.QuarantinedAt.Valid {
+			resp.Unavailable = append(resp.Unavailable, PlaybackUnavailableItem{
+				TrackID: trackID,
+				Code:    playbackUnavailableCodeTakenDown,
+				Message: "track is blocked by a content takedown",
+			})
+			continue
+		}
+
 		storageKey := strings.TrimSpace(track.StorageKey.String)
 		if !track.StorageKey.Valid || storageKey == "" {
 			resp.Unavailable = append(resp.Unavailable, PlaybackUnavailableItem{
@@ -180,7 +297,33 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 			continue
 		}
 
-		url, err := h.storage.PresignGetObject(r.Context(), storageKey, ttl)
+		var variant *transcode.Format
+		if wantFormat && h.transcoder != nil && !storedInFormat(track, storageKey, objInfo, format) {
+			variantKey, variantInfo, err := h.transcoder.Variant(r.Context(), storageKey, objInfo, format)
+			switch {
+			case err == nil:
+				storageKey, objInfo, variant = variantKey, variantInfo, &format
+			case r.Context().Err() != nil:
+				return
+			case errors.Is(err, transcode.ErrDisabled):
+				// Serve the original; the item's codec tells the client.
+			default:
+				resp.Unavailable = append(resp.Unavailable, PlaybackUnavailableItem{
+					TrackID: trackID,
+					Code:    playbackUnavailableCodeTranscodeFailed,
+					Message: "audio could not be transcoded to " + format.Name,
+				})
+				continue
+			}
+		}
+
+		validator := playbackValidator(storageKey, objInfo, track)
+		if cached := req.Cached[trackID]; validator != "" && cached == validator {
+			resp.NotModified = append(resp.NotModified, trackID)
+			continue
+		}
+
+		url, err := h.storage.PresignPrivateGetObject(r.Context(), storageKey, itemTTL)
 		if err != nil {
 			if r.Context().Err() != nil {
 				return
@@ -192,10 +335,11 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 		item := PlaybackURLItem{
 			TrackID:     trackID,
 			URL:         url,
-			ExpiresAt:   expiresAt,
+			ExpiresAt:   h.now().Add(itemTTL).UTC(),
 			ContentType: playbackContentType(storageKey, objInfo.ContentType),
 			SizeBytes:   objInfo.Size,
 			ETag:        objInfo.ETag,
+			Validator:   validator,
 		}
 		if track.Version.Valid {
 			item.StorageKeyVersion = track.Version.String
@@ -215,12 +359,87 @@ func (h *PlaybackHandlers) CreatePlaybackURLs(w http.ResponseWriter, r *http.Req
 		if track.ContentType.Valid {
 			item.ContentType = track.ContentType.String
 		}
+		item.LoudnessLUFS = replayGainValue(track.ReplayGain.LoudnessLUFS)
+		item.TrackGainDB = replayGainValue(track.ReplayGain.TrackGainDB)
+		item.TrackPeakDBTP = replayGainValue(track.ReplayGain.TruePeakDBTP)
+		item.AlbumGainDB = replayGainValue(track.ReplayGain.AlbumGainDB)
+		item.AlbumPeakDBTP = replayGainValue(track.ReplayGain.AlbumPeakDBTP)
+		if variant != nil {
+			item.Transcoded = true
+			item.ContentType = variant.ContentType
+			item.Codec = variant.Codec
+			item.BitrateKbps = variant.BitrateKbps
+			switch {
+			case variant.Codec == "opus":
+				item.SampleRateHz = 48000
+			case variant.Codec == "mp3" && item.SampleRateHz > 48000:
+				// ffmpeg resamples to a rate MP3 supports; which one is
+				// not known here.
+				item.SampleRateHz = 0
+			}
+		}
 		resp.URLs = append(resp.URLs, item)
 	}
 
 	writePlaybackJSON(w, http.StatusOK, resp)
 }
 
+// authorizeCapabilities returns the tracks the capabilities let listener
+// play, each with when its grant expires. Capabilities are ignored when none
+// can be checked.
+func (h *PlaybackHandlers) authorizeCapabilities(ctx context.Context, tokens []string, listener uuid.NullUUID) (map[int64]time.Time, error) {
+	granted := make(map[int64]time.Time, len(tokens))
+	if h.capabilities == nil {
+		return granted, nil
+	}
+	for _, token := range tokens {
+		claims, err := h.capabilities.Authorize(ctx, token, listener)
+		if err != nil {
+			return nil, err
+		}
+		if claims.ExpiresAt.After(granted[claims.TrackID]) {
+			granted[claims.TrackID] = claims.ExpiresAt
+		}
+	}
+	return granted, nil
+}
+
+// capabilityTTL shortens ttl so a URL authorized by a capability expires
+// with its grant.
+func capabilityTTL(ttl, remaining time.Duration) time.Duration {
+	remaining = remaining.Truncate(time.Second)
+	if remaining < time.Second {
+		return time.Second
+	}
+	return min(ttl, remaining)
+}
+
+// storedInFormat reports whether the stored original already is in format, by
+// its probed codec or, for tracks probed before codecs were recorded, by its
+// content type.
+func storedInFormat(track *db.Track, storageKey string, objInfo *storage.ObjectInfo, format transcode.Format) bool {
+	if track.Codec.Valid && track.Codec.String != "" {
+		return format.Matches(track.Codec.String)
+	}
+	contentType := playbackContentType(storageKey, objInfo.ContentType)
+	if track.ContentType.Valid && track.ContentType.String != "" {
+		contentType = track.ContentType.String
+	}
+	return strings.EqualFold(contentType, format.ContentType)
+}
+
+// playbackValidator returns a strong entity tag for the object a descriptor
+// signs, or "" when storage reports no ETag to derive one from. Replacing the
+// audio, switching the track to another stored object, or changing the
+// track version all change it.
+func playbackValidator(storageKey string, objInfo *storage.ObjectInfo, track *db.Track) string {
+	if objInfo.ETag == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(storageKey + "\x00" + objInfo.ETag + "\x00" + track.Version.String))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
 func validateAndDedupeTrackIDs(ids []int64) ([]int64, error) {
 	seen := make(map[int64]struct{}, len(ids))
 	out := make([]int64, 0, len(ids))
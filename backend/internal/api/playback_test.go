@@ -17,8 +17,10 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/capability"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/transcode"
 )
 
 type fakePlaybackTrackRepo struct {
@@ -58,6 +60,7 @@ type fakePlaybackStorage struct {
 	lastTTL     time.Duration
 	statKeys    []string
 	presignKeys []string
+	filenames   []string
 }
 
 func (f *fakePlaybackStorage) StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error) {
@@ -72,7 +75,7 @@ func (f *fakePlaybackStorage) StatObject(ctx context.Context, key string) (*stor
 	return info, nil
 }
 
-func (f *fakePlaybackStorage) PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error) {
+func (f *fakePlaybackStorage) PresignPrivateGetObject(ctx context.Context, key string, expires time.Duration) (string, error) {
 	f.presignKeys = append(f.presignKeys, key)
 	f.lastTTL = expires
 	if f.presignErr != nil {
@@ -81,6 +84,11 @@ func (f *fakePlaybackStorage) PresignGetObject(ctx context.Context, key string,
 	return "https://objects.example.test/" + key + "?X-Amz-Signature=secret", nil
 }
 
+func (f *fakePlaybackStorage) PresignDownloadObject(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	f.filenames = append(f.filenames, filename)
+	return f.PresignPrivateGetObject(ctx, key, expires)
+}
+
 func playbackRequest(t *testing.T, handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
 	t.Helper()
 	userID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
@@ -219,6 +227,49 @@ func TestPlaybackURLIssuanceReturnsSignedObjectMetadataAndContractNames(t *testi
 	}
 }
 
+func TestPlaybackURLIssuanceSkipsAudioTheClientHolds(t *testing.T) {
+	track := &db.Track{
+		ID:         42,
+		StorageKey: sql.NullString{String: "audio/track-42.mp3", Valid: true},
+		Version:    sql.NullString{String: "v7", Valid: true},
+	}
+	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.mp3": {Size: 100, ContentType: "audio/mpeg", ETag: "abc123"},
+	}}
+	handler, _ := newPlaybackHandlerForTrack(track, true, fakeStorage)
+
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42]}`)
+	var first PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil || len(first.URLs) != 1 {
+		t.Fatalf("first response = %s, %v", rec.Body.String(), err)
+	}
+	validator := first.URLs[0].Validator
+	if !strings.HasPrefix(validator, `"`) || !strings.HasSuffix(validator, `"`) || len(validator) != 34 {
+		t.Fatalf("validator = %q, want a quoted strong entity tag", validator)
+	}
+
+	cached, _ := json.Marshal(map[int64]string{42: validator})
+	rec = playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"cached":`+string(cached)+`}`)
+	var revalidated PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &revalidated); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(revalidated.URLs) != 0 || len(revalidated.NotModified) != 1 || revalidated.NotModified[0] != 42 {
+		t.Fatalf("unchanged audio = %s, want only notModified", rec.Body.String())
+	}
+	if len(fakeStorage.presignKeys) != 1 {
+		t.Fatalf("presigned %d times, want no URL for unchanged audio", len(fakeStorage.presignKeys))
+	}
+
+	// A new track version is different audio even when the object is not.
+	track.Version = sql.NullString{String: "v8", Valid: true}
+	rec = playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"cached":`+string(cached)+`}`)
+	var changed PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &changed); err != nil || len(changed.URLs) != 1 || changed.URLs[0].Validator == validator {
+		t.Fatalf("changed version = %s, %v; want a new URL and validator", rec.Body.String(), err)
+	}
+}
+
 func TestPlaybackURLIssuanceClampsTTL(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -295,6 +346,29 @@ func TestPlaybackURLIssuanceReportsArtifactMissing(t *testing.T) {
 	}
 }
 
+func TestPlaybackURLIssuanceWithholdsQuarantinedTracks(t *testing.T) {
+	handler, _ := newPlaybackHandlerForTrack(&db.Track{
+		ID:            42,
+		StorageKey:    sql.NullString{String: "audio/track-42.mp3", Valid: true},
+		QuarantinedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}, true, &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.mp3": {Size: 123456, ContentType: "audio/mpeg"},
+	}})
+
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42]}`)
+
+	var got PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got.Unavailable) != 1 || got.Unavailable[0].Code != playbackUnavailableCodeTakenDown {
+		t.Fatalf("unavailable response = %+v, want %s", got.Unavailable, playbackUnavailableCodeTakenDown)
+	}
+	if len(got.URLs) != 0 {
+		t.Fatalf("urls = %+v, want no signed URL for quarantined track", got.URLs)
+	}
+}
+
 func TestPlaybackURLIssuanceUsesTrimmedStorageKey(t *testing.T) {
 	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
 		"audio/track-42.mp3": {Size: 123456, ContentType: "audio/mpeg", ETag: "abc123"},
@@ -337,3 +411,219 @@ func TestPlaybackURLIssuanceReportsPresignFailureAsInternalError(t *testing.T) {
 		t.Fatalf("error response leaked signed URL: %s", rec.Body.String())
 	}
 }
+
+type fakePlaybackTranscoder struct {
+	err     error
+	formats []string
+}
+
+func (f *fakePlaybackTranscoder) Variant(_ context.Context, sourceKey string, _ *storage.ObjectInfo, format transcode.Format) (string, *storage.ObjectInfo, error) {
+	f.formats = append(f.formats, format.Name)
+	if f.err != nil {
+		return "", nil, f.err
+	}
+	return "transcodes/" + format.Name + "/" + sourceKey, &storage.ObjectInfo{Size: 4000, ContentType: format.ContentType, ETag: "variant"}, nil
+}
+
+func flacPlaybackHandler(transcoder *fakePlaybackTranscoder) (*PlaybackHandlers, *fakePlaybackStorage) {
+	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.flac": {Size: 40000, ContentType: "audio/flac", ETag: "original"},
+	}}
+	handler, _ := newPlaybackHandlerForTrack(&db.Track{
+		ID:           42,
+		StorageKey:   sql.NullString{String: "audio/track-42.flac", Valid: true},
+		Codec:        sql.NullString{String: "flac", Valid: true},
+		SampleRateHz: sql.NullInt32{Int32: 96000, Valid: true},
+		ContentType:  sql.NullString{String: "audio/flac", Valid: true},
+	}, true, fakeStorage)
+	handler.SetTranscoder(transcoder)
+	return handler, fakeStorage
+}
+
+func TestPlaybackURLIssuanceSignsTranscodedVariantForRequestedFormat(t *testing.T) {
+	transcoder := &fakePlaybackTranscoder{}
+	handler, fakeStorage := flacPlaybackHandler(transcoder)
+
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"format":"opus"}`)
+	var got PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.URLs) != 1 {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	item := got.URLs[0]
+	if !item.Transcoded || item.Codec != "opus" || item.ContentType != "audio/ogg" || item.SampleRateHz != 48000 || item.SizeBytes != 4000 {
+		t.Fatalf("item = %+v, want the opus variant", item)
+	}
+	if len(fakeStorage.presignKeys) != 1 || fakeStorage.presignKeys[0] != "transcodes/opus/audio/track-42.flac" {
+		t.Fatalf("presigned %v, want the variant key", fakeStorage.presignKeys)
+	}
+
+	// Asking for the stored format signs the original without transcoding.
+	transcoder.formats = nil
+	rec = playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"format":"flac"}`)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"transcoded"`) || len(transcoder.formats) != 0 {
+		t.Fatalf("status = %d body = %s transcodes = %v; want the original", rec.Code, rec.Body.String(), transcoder.formats)
+	}
+}
+
+func TestPlaybackURLIssuanceNegotiatesFormatFromAcceptHeader(t *testing.T) {
+	transcoder := &fakePlaybackTranscoder{}
+	handler, _ := flacPlaybackHandler(transcoder)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/playback/urls", strings.NewReader(`{"trackIds":[42]}`))
+	req.Header.Set("Accept", "application/json, audio/mpeg;q=0.5, audio/webm;codecs=opus;q=0.9")
+	req = withUser(req, uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+	rec := httptest.NewRecorder()
+	handler.CreatePlaybackURLs(rec, req)
+	if rec.Code != http.StatusOK || len(transcoder.formats) != 1 || transcoder.formats[0] != "opus" {
+		t.Fatalf("status = %d transcodes = %v; want the preferred opus", rec.Code, transcoder.formats)
+	}
+
+	rec = playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"format":"wma"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "UNSUPPORTED_FORMAT") {
+		t.Fatalf("status = %d body = %s; want 400 UNSUPPORTED_FORMAT", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPlaybackURLIssuanceReportsTranscodeFailure(t *testing.T) {
+	handler, _ := flacPlaybackHandler(&fakePlaybackTranscoder{err: errors.New("ffmpeg failed")})
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"format":"mp3"}`)
+	var got PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.URLs) != 0 || len(got.Unavailable) != 1 || got.Unavailable[0].Code != playbackUnavailableCodeTranscodeFailed {
+		t.Fatalf("response = %+v, want the track reported unavailable", got)
+	}
+
+	handler, _ = flacPlaybackHandler(&fakePlaybackTranscoder{err: transcode.ErrDisabled})
+	rec = playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42],"format":"mp3"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.URLs) != 1 || got.URLs[0].Codec != "flac" {
+		t.Fatalf("body = %s, want the original when transcoding is disabled", rec.Body.String())
+	}
+}
+
+type fakePlaybackGrants struct {
+	grants map[uuid.UUID]*db.TrackGrant
+}
+
+func (f *fakePlaybackGrants) GrantCovers(ctx context.Context, grantID uuid.UUID, trackID int64, listener uuid.NullUUID) (bool, error) {
+	grant, ok := f.grants[grantID]
+	if !ok || !grant.Active(time.Now()) {
+		return false, nil
+	}
+	if grant.GranteeID.Valid && grant.GranteeID != listener {
+		return false, nil
+	}
+	for _, id := range grant.TrackIDs {
+		if id == trackID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestPlaybackURLIssuanceHonorsTrackCapabilities(t *testing.T) {
+	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.mp3": {Size: 100, ContentType: "audio/mpeg"},
+	}}
+	handler, _ := newPlaybackHandlerForTrack(&db.Track{ID: 42, StorageKey: sql.NullString{String: "audio/track-42.mp3", Valid: true}}, false, fakeStorage)
+	signer := capability.NewSigner("test-secret")
+	grant := &db.TrackGrant{ID: uuid.New(), TrackIDs: []int64{42}, ExpiresAt: time.Now().Add(time.Hour)}
+	grants := &fakePlaybackGrants{grants: map[uuid.UUID]*db.TrackGrant{grant.ID: grant}}
+	handler.SetCapabilities(capability.NewChecker(signer, grants))
+	token := signer.Sign(capability.Claims{GrantID: grant.ID, TrackID: 42, ExpiresAt: grant.ExpiresAt})
+	body := `{"trackIds":[42],"capabilities":["` + token + `"]}`
+
+	if rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42]}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("without capability status = %d, want 404", rec.Code)
+	}
+	if rec := playbackRequest(t, handler.CreatePlaybackURLs, body); rec.Code != http.StatusOK {
+		t.Fatalf("with capability status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	anonymous := httptest.NewRecorder()
+	handler.CreatePublicPlaybackURLs(anonymous, httptest.NewRequest(http.MethodPost, "/api/v1/public/playback/urls", strings.NewReader(body)))
+	if anonymous.Code != http.StatusOK {
+		t.Fatalf("anonymous with capability status = %d, want 200; body=%s", anonymous.Code, anonymous.Body.String())
+	}
+
+	grant.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, body)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "CAPABILITY_DENIED") {
+		t.Fatalf("revoked capability = %d %s, want 403 CAPABILITY_DENIED", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPlaybackURLsForCapabilitiesExpireWithTheGrant(t *testing.T) {
+	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.mp3": {Size: 100, ContentType: "audio/mpeg"},
+	}}
+	handler, _ := newPlaybackHandlerForTrack(&db.Track{ID: 42, StorageKey: sql.NullString{String: "audio/track-42.mp3", Valid: true}}, false, fakeStorage)
+	now := time.Now()
+	handler.now = func() time.Time { return now }
+	signer := capability.NewSigner("test-secret")
+	grant := &db.TrackGrant{ID: uuid.New(), TrackIDs: []int64{42}, ExpiresAt: now.Add(3 * time.Minute)}
+	handler.SetCapabilities(capability.NewChecker(signer, &fakePlaybackGrants{grants: map[uuid.UUID]*db.TrackGrant{grant.ID: grant}}))
+	body := `{"trackIds":[42],"ttlSeconds":1800,"capabilities":["` + signer.Sign(capability.Claims{GrantID: grant.ID, TrackID: 42, ExpiresAt: grant.ExpiresAt}) + `"]}`
+
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, body)
+	var got PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.URLs) != 1 {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if fakeStorage.lastTTL > 3*time.Minute || got.URLs[0].ExpiresAt.After(grant.ExpiresAt) {
+		t.Fatalf("ttl = %v, expiresAt = %v; want no later than the grant's %v", fakeStorage.lastTTL, got.URLs[0].ExpiresAt, grant.ExpiresAt)
+	}
+}
+
+func TestPublicPlaybackURLIssuanceRejectsGranteeCapabilities(t *testing.T) {
+	fakeStorage := &fakePlaybackStorage{info: map[string]*storage.ObjectInfo{
+		"audio/track-42.mp3": {Size: 100, ContentType: "audio/mpeg"},
+	}}
+	handler, _ := newPlaybackHandlerForTrack(&db.Track{ID: 42, StorageKey: sql.NullString{String: "audio/track-42.mp3", Valid: true}}, false, fakeStorage)
+	signer := capability.NewSigner("test-secret")
+	grant := &db.TrackGrant{
+		ID:        uuid.New(),
+		GranteeID: uuid.NullUUID{UUID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), Valid: true},
+		TrackIDs:  []int64{42},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	handler.SetCapabilities(capability.NewChecker(signer, &fakePlaybackGrants{grants: map[uuid.UUID]*db.TrackGrant{grant.ID: grant}}))
+	body := `{"trackIds":[42],"capabilities":["` + signer.Sign(capability.Claims{GrantID: grant.ID, TrackID: 42, ExpiresAt: grant.ExpiresAt}) + `"]}`
+
+	if rec := playbackRequest(t, handler.CreatePlaybackURLs, body); rec.Code != http.StatusOK {
+		t.Fatalf("grantee status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	anonymous := httptest.NewRecorder()
+	handler.CreatePublicPlaybackURLs(anonymous, httptest.NewRequest(http.MethodPost, "/api/v1/public/playback/urls", strings.NewReader(body)))
+	if anonymous.Code != http.StatusForbidden {
+		t.Fatalf("anonymous status = %d, want 403", anonymous.Code)
+	}
+	noCapability := httptest.NewRecorder()
+	handler.CreatePublicPlaybackURLs(noCapability, httptest.NewRequest(http.MethodPost, "/api/v1/public/playback/urls", strings.NewReader(`{"trackIds":[42]}`)))
+	if noCapability.Code != http.StatusNotFound {
+		t.Fatalf("anonymous without capability status = %d, want 404", noCapability.Code)
+	}
+}
+
+func TestPlaybackURLIssuanceIncludesReplayGain(t *testing.T) {
+	handler, _ := flacPlaybackHandler(&fakePlaybackTranscoder{})
+	track, _ := handler.trackRepo.GetByID(context.Background(), 42)
+	track.ReplayGain = db.ReplayGain{
+		LoudnessLUFS: sql.NullFloat64{Float64: -9.84, Valid: true},
+		TrackGainDB:  sql.NullFloat64{Float64: -8.16, Valid: true},
+		TruePeakDBTP: sql.NullFloat64{Float64: 0.31, Valid: true},
+	}
+
+	rec := playbackRequest(t, handler.CreatePlaybackURLs, `{"trackIds":[42]}`)
+	var got PlaybackURLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.URLs) != 1 {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	item := got.URLs[0]
+	if item.TrackGainDB == nil || *item.TrackGainDB != -8.16 || item.TrackPeakDBTP == nil || *item.TrackPeakDBTP != 0.31 || *item.LoudnessLUFS != -9.84 {
+		t.Fatalf("item = %+v, want the track's ReplayGain", item)
+	}
+	if item.AlbumGainDB != nil || strings.Contains(rec.Body.String(), "albumGainDb") {
+		t.Fatalf("unmeasured album gain was sent: %s", rec.Body.String())
+	}
+}
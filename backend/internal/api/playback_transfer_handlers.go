@@ -0,0 +1,389 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/queue"
+	"github.com/openmusicplayer/backend/internal/websocket"
+)
+
+const (
+	// playbackTransferTTL is how long the target device has to answer a
+	// transfer before it expires and the source keeps playing.
+	playbackTransferTTL = 30 * time.Second
+	// playbackTransferRetention is how long answered and expired transfers
+	// stay readable for the source device to poll their outcome.
+	playbackTransferRetention = 10 * time.Minute
+)
+
+// Playback transfer statuses.
+const (
+	PlaybackTransferPending  = "pending"
+	PlaybackTransferAccepted = "accepted"
+	PlaybackTransferDeclined = "declined"
+	PlaybackTransferExpired  = "expired"
+)
+
+var (
+	errPlaybackTransferNotFound = errors.New("playback transfer not found")
+	errPlaybackTransferAnswered = errors.New("playback transfer already answered")
+	errPlaybackTransferDevice   = errors.New("playback transfer is for another device")
+)
+
+// transferQueue is the user's shared play queue; *queue.Service satisfies it.
+type transferQueue interface {
+	GetQueue(ctx context.Context, userID string) (*queue.QueueState, error)
+	SetCurrentPosition(ctx context.Context, userID string, position int) (*queue.QueueState, *queue.QueueItem, error)
+	SetPlaybackPosition(ctx context.Context, userID, queueItemID string, positionMs int64, paused bool) (*queue.QueueState, error)
+}
+
+// transferDevices delivers messages to one of a user's devices;
+// *websocket.Hub satisfies it.
+type transferDevices interface {
+	SendToDevice(userID uuid.UUID, deviceID, msgType string, transfer any) bool
+}
+
+// PlaybackTransferHandlers hands playback from one of a user's devices to
+// another. The queue is already shared per user, so a transfer carries which
+// item is playing and how far in; the target answers over its WebSocket or,
+// when it has none, by polling for pending transfers.
+type PlaybackTransferHandlers struct {
+	queue   transferQueue
+	devices transferDevices
+	now     func() time.Time
+
+	mu        sync.Mutex
+	transfers map[uuid.UUID]*playbackTransfer
+}
+
+// playbackTransfer is a transfer and the user it belongs to.
+type playbackTransfer struct {
+	PlaybackTransferResponse
+	userID uuid.UUID
+}
+
+func NewPlaybackTransferHandlers(queue transferQueue, devices transferDevices) *PlaybackTransferHandlers {
+	return &PlaybackTransferHandlers{
+		queue:     queue,
+		devices:   devices,
+		now:       time.Now,
+		transfers: map[uuid.UUID]*playbackTransfer{},
+	}
+}
+
+type CreatePlaybackTransferRequest struct {
+	FromDeviceID string `json:"fromDeviceId"`
+	ToDeviceID   string `json:"toDeviceId"`
+	PositionMs   int64  `json:"positionMs"`
+	Paused       bool   `json:"paused"`
+}
+
+type AckPlaybackTransferRequest struct {
+	DeviceID string `json:"deviceId"`
+	Accepted bool   `json:"accepted"`
+}
+
+type PlaybackTransferResponse struct {
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`
+	FromDeviceID    string     `json:"fromDeviceId"`
+	ToDeviceID      string     `json:"toDeviceId"`
+	QueueItemID     string     `json:"queueItemId"`
+	TrackID         *int64     `json:"trackId"`
+	CurrentPosition int        `json:"currentPosition"`
+	PositionMs      int64      `json:"positionMs"`
+	Paused          bool       `json:"paused"`
+	Delivered       bool       `json:"delivered"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+	AnsweredAt      *time.Time `json:"answeredAt,omitempty"`
+}
+
+type PendingPlaybackTransfersResponse struct {
+	Transfers []PlaybackTransferResponse `json:"transfers"`
+}
+
+// CreateTransfer handles POST /api/v1/playback/transfer
+func (h *PlaybackTransferHandlers) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaybackTransferError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req CreatePlaybackTransferRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writePlaybackTransferError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if !websocket.ValidDeviceID(req.FromDeviceID) || !websocket.ValidDeviceID(req.ToDeviceID) {
+		writePlaybackTransferError(w, http.StatusBadRequest, "INVALID_DEVICE_ID", "fromDeviceId and toDeviceId must be 1-64 letters, digits, '-', '_' or '.'")
+		return
+	}
+	if req.FromDeviceID == req.ToDeviceID {
+		writePlaybackTransferError(w, http.StatusBadRequest, "VALIDATION_ERROR", "cannot transfer playback to the same device")
+		return
+	}
+	if req.PositionMs < 0 {
+		writePlaybackTransferError(w, http.StatusBadRequest, "VALIDATION_ERROR", "positionMs must not be negative")
+		return
+	}
+
+	state, err := h.queue.GetQueue(r.Context(), userCtx.UserID.String())
+	if err != nil {
+		writePlaybackTransferError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get queue")
+		return
+	}
+	if state.CurrentPosition < 0 || state.CurrentPosition >= len(state.Items) {
+		writePlaybackTransferError(w, http.StatusConflict, "QUEUE_EMPTY", "there is nothing playing to transfer")
+		return
+	}
+	item := state.Items[state.CurrentPosition]
+
+	now := h.now()
+	transfer := &playbackTransfer{userID: userCtx.UserID, PlaybackTransferResponse: PlaybackTransferResponse{
+		ID:              uuid.NewString(),
+		Status:          PlaybackTransferPending,
+		FromDeviceID:    req.FromDeviceID,
+		ToDeviceID:      req.ToDeviceID,
+		QueueItemID:     item.ID,
+		TrackID:         item.TrackID,
+		CurrentPosition: state.CurrentPosition,
+		PositionMs:      req.PositionMs,
+		Paused:          req.Paused,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(playbackTransferTTL),
+	}}
+	id := uuid.MustParse(transfer.ID)
+
+	h.mu.Lock()
+	h.pruneLocked(now)
+	// A newer transfer to the same device replaces one still pending.
+	for _, other := range h.transfers {
+		if other.userID == userCtx.UserID && other.ToDeviceID == req.ToDeviceID && other.Status == PlaybackTransferPending {
+			other.Status = PlaybackTransferExpired
+		}
+	}
+	h.transfers[id] = transfer
+	msg := transfer.PlaybackTransferResponse
+	h.mu.Unlock()
+
+	delivered := h.devices.SendToDevice(userCtx.UserID, req.ToDeviceID, websocket.MessagePlaybackTransfer, msg)
+	h.mu.Lock()
+	transfer.Delivered = delivered
+	resp := transfer.PlaybackTransferResponse
+	h.mu.Unlock()
+
+	writePlaybackTransferJSON(w, http.StatusAccepted, resp)
+}
+
+// GetTransfer handles GET /api/v1/playback/transfer/{id}. The source device
+// polls it when it has no WebSocket to hear the answer on.
+func (h *PlaybackTransferHandlers) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaybackTransferError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writePlaybackTransferError(w, http.StatusNotFound, "TRANSFER_NOT_FOUND", "playback transfer not found")
+		return
+	}
+
+	h.mu.Lock()
+	h.pruneLocked(h.now())
+	transfer, ok := h.transfers[id]
+	var resp PlaybackTransferResponse
+	if ok && transfer.userID == userCtx.UserID {
+		resp = transfer.PlaybackTransferResponse
+	} else {
+		ok = false
+	}
+	h.mu.Unlock()
+	if !ok {
+		writePlaybackTransferError(w, http.StatusNotFound, "TRANSFER_NOT_FOUND", "playback transfer not found")
+		return
+	}
+	writePlaybackTransferJSON(w, http.StatusOK, resp)
+}
+
+// ListPending handles GET /api/v1/playback/transfer/pending?deviceId=, the
+// fallback for target devices without a WebSocket connection.
+func (h *PlaybackTransferHandlers) ListPending(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaybackTransferError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	deviceID := r.URL.Query().Get("deviceId")
+	if !websocket.ValidDeviceID(deviceID) {
+		writePlaybackTransferError(w, http.StatusBadRequest, "INVALID_DEVICE_ID", "deviceId must be 1-64 letters, digits, '-', '_' or '.'")
+		return
+	}
+
+	resp := PendingPlaybackTransfersResponse{Transfers: []PlaybackTransferResponse{}}
+	h.mu.Lock()
+	h.pruneLocked(h.now())
+	for _, transfer := range h.transfers {
+		if transfer.userID == userCtx.UserID && transfer.ToDeviceID == deviceID && transfer.Status == PlaybackTransferPending {
+			resp.Transfers = append(resp.Transfers, transfer.PlaybackTransferResponse)
+		}
+	}
+	h.mu.Unlock()
+	writePlaybackTransferJSON(w, http.StatusOK, resp)
+}
+
+// AckTransfer handles POST /api/v1/playback/transfer/{id}/ack, the HTTP form
+// of the playback_transfer_ack WebSocket message.
+func (h *PlaybackTransferHandlers) AckTransfer(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaybackTransferError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writePlaybackTransferError(w, http.StatusNotFound, "TRANSFER_NOT_FOUND", "playback transfer not found")
+		return
+	}
+	var req AckPlaybackTransferRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writePlaybackTransferError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+
+	transfer, err := h.answer(r.Context(), userCtx.UserID, id, req.DeviceID, req.Accepted)
+	switch {
+	case errors.Is(err, errPlaybackTransferNotFound):
+		writePlaybackTransferError(w, http.StatusNotFound, "TRANSFER_NOT_FOUND", "playback transfer not found")
+	case errors.Is(err, errPlaybackTransferDevice):
+		writePlaybackTransferError(w, http.StatusForbidden, "WRONG_DEVICE", "playback transfer is for another device")
+	case errors.Is(err, errPlaybackTransferAnswered):
+		writePlaybackTransferError(w, http.StatusConflict, "TRANSFER_NOT_PENDING", "playback transfer was already answered or has expired")
+	case err != nil:
+		writePlaybackTransferError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to answer playback transfer")
+	default:
+		writePlaybackTransferJSON(w, http.StatusOK, transfer)
+	}
+}
+
+// HandleDeviceMessage answers transfers acknowledged over WebSocket. Register
+// it with (*websocket.Hub).SetMessageHandler.
+func (h *PlaybackTransferHandlers) HandleDeviceMessage(msg websocket.ClientMessage) {
+	if msg.Type != websocket.MessagePlaybackTransferAck {
+		return
+	}
+	id, err := uuid.Parse(msg.TransferID)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := h.answer(ctx, msg.UserID, id, msg.DeviceID, msg.Accepted); err != nil {
+		log.Printf("Warning: ignoring playback transfer ack %s from device %q: %v", id, msg.DeviceID, err)
+	}
+}
+
+// answer records the target device's answer and tells the source device.
+// Accepting moves the queue's current position back to the transferred item,
+// in case the source skipped ahead while the transfer was pending.
+func (h *PlaybackTransferHandlers) answer(ctx context.Context, userID, id uuid.UUID, deviceID string, accepted bool) (PlaybackTransferResponse, error) {
+	now := h.now()
+	h.mu.Lock()
+	h.pruneLocked(now)
+	transfer, ok := h.transfers[id]
+	if !ok || transfer.userID != userID {
+		h.mu.Unlock()
+		return PlaybackTransferResponse{}, errPlaybackTransferNotFound
+	}
+	if transfer.ToDeviceID != deviceID {
+		h.mu.Unlock()
+		return PlaybackTransferResponse{}, errPlaybackTransferDevice
+	}
+	if transfer.Status != PlaybackTransferPending {
+		h.mu.Unlock()
+		return PlaybackTransferResponse{}, errPlaybackTransferAnswered
+	}
+	transfer.Status = PlaybackTransferDeclined
+	if accepted {
+		transfer.Status = PlaybackTransferAccepted
+	}
+	transfer.AnsweredAt = &now
+	resp := transfer.PlaybackTransferResponse
+	h.mu.Unlock()
+
+	msgType := websocket.MessagePlaybackTransferDeclined
+	if accepted {
+		msgType = websocket.MessagePlaybackTransferAccepted
+		if err := h.restoreQueuePosition(ctx, userID, resp); err != nil {
+			log.Printf("Warning: failed to restore queue position for playback transfer %s: %v", resp.ID, err)
+		}
+	}
+	h.devices.SendToDevice(userID, resp.FromDeviceID, msgType, resp)
+	return resp, nil
+}
+
+// restoreQueuePosition makes the transferred item current again and saves
+// how far into it the source was, so the target resumes from the queue.
+func (h *PlaybackTransferHandlers) restoreQueuePosition(ctx context.Context, userID uuid.UUID, transfer PlaybackTransferResponse) error {
+	state, err := h.queue.GetQueue(ctx, userID.String())
+	if err != nil {
+		return err
+	}
+	for i, item := range state.Items {
+		if item.ID == transfer.QueueItemID {
+			if i != state.CurrentPosition {
+				if _, _, err := h.queue.SetCurrentPosition(ctx, userID.String(), i); err != nil {
+					return err
+				}
+			}
+			_, err := h.queue.SetPlaybackPosition(ctx, userID.String(), item.ID, transfer.PositionMs, transfer.Paused)
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneLocked expires unanswered transfers and forgets old ones. h.mu must
+// be held.
+func (h *PlaybackTransferHandlers) pruneLocked(now time.Time) {
+	for id, transfer := range h.transfers {
+		if transfer.Status == PlaybackTransferPending && !now.Before(transfer.ExpiresAt) {
+			transfer.Status = PlaybackTransferExpired
+		}
+		if now.Sub(transfer.CreatedAt) > playbackTransferRetention {
+			delete(h.transfers, id)
+		}
+	}
+}
+
+func writePlaybackTransferJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writePlaybackTransferError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/queue"
+	"github.com/openmusicplayer/backend/internal/websocket"
+)
+
+type fakeTransferQueue struct {
+	state queue.QueueState
+}
+
+func (f *fakeTransferQueue) GetQueue(context.Context, string) (*queue.QueueState, error) {
+	state := f.state
+	return &state, nil
+}
+
+func (f *fakeTransferQueue) SetCurrentPosition(_ context.Context, _ string, position int) (*queue.QueueState, *queue.QueueItem, error) {
+	f.state.CurrentPosition = position
+	state := f.state
+	return &state, &state.Items[position], nil
+}
+
+func (f *fakeTransferQueue) SetPlaybackPosition(_ context.Context, _ string, _ string, positionMs int64, paused bool) (*queue.QueueState, error) {
+	f.state.PositionMs, f.state.Paused = positionMs, paused
+	state := f.state
+	return &state, nil
+}
+
+type sentDeviceMessage struct {
+	deviceID string
+	msgType  string
+}
+
+type fakeTransferDevices struct {
+	connected map[string]bool
+	sent      []sentDeviceMessage
+}
+
+func (f *fakeTransferDevices) SendToDevice(_ uuid.UUID, deviceID, msgType string, _ any) bool {
+	if !f.connected[deviceID] {
+		return false
+	}
+	f.sent = append(f.sent, sentDeviceMessage{deviceID: deviceID, msgType: msgType})
+	return true
+}
+
+func newTransferFixture() (*PlaybackTransferHandlers, *fakeTransferQueue, *fakeTransferDevices) {
+	trackID := int64(7)
+	q := &fakeTransferQueue{state: queue.QueueState{
+		Items:           []queue.QueueItem{{ID: "item-a", TrackID: &trackID}, {ID: "item-b"}},
+		CurrentPosition: 0,
+	}}
+	devices := &fakeTransferDevices{connected: map[string]bool{"phone": true, "desktop": true}}
+	return NewPlaybackTransferHandlers(q, devices), q, devices
+}
+
+func createTransfer(h *PlaybackTransferHandlers, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playback/transfer", strings.NewReader(body)), userID)
+	h.CreateTransfer(rec, req)
+	return rec
+}
+
+func ackTransfer(h *PlaybackTransferHandlers, userID uuid.UUID, id, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playback/transfer/"+id+"/ack", strings.NewReader(body)), userID)
+	req.SetPathValue("id", id)
+	h.AckTransfer(rec, req)
+	return rec
+}
+
+func TestPlaybackTransferAcceptedOverWebSocketRestoresQueuePosition(t *testing.T) {
+	userID := uuid.New()
+	h, q, devices := newTransferFixture()
+
+	rec := createTransfer(h, userID, `{"fromDeviceId":"phone","toDeviceId":"desktop","positionMs":61500}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var transfer PlaybackTransferResponse
+	if err := json.NewDecoder(rec.Body).Decode(&transfer); err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Status != PlaybackTransferPending || !transfer.Delivered || transfer.QueueItemID != "item-a" || transfer.PositionMs != 61500 {
+		t.Fatalf("transfer = %+v, want a delivered pending handoff of item-a", transfer)
+	}
+	if len(devices.sent) != 1 || devices.sent[0] != (sentDeviceMessage{"desktop", websocket.MessagePlaybackTransfer}) {
+		t.Fatalf("sent = %+v, want the transfer pushed to desktop", devices.sent)
+	}
+
+	// The source skipped ahead while the transfer was pending.
+	q.state.CurrentPosition = 1
+	h.HandleDeviceMessage(websocket.ClientMessage{Type: websocket.MessagePlaybackTransferAck, TransferID: transfer.ID, Accepted: true, UserID: userID, DeviceID: "desktop"})
+
+	if q.state.CurrentPosition != 0 || q.state.PositionMs != 61500 {
+		t.Fatalf("current position = %d at %dms, want the transferred item restored at 61500ms", q.state.CurrentPosition, q.state.PositionMs)
+	}
+	if last := devices.sent[len(devices.sent)-1]; last != (sentDeviceMessage{"phone", websocket.MessagePlaybackTransferAccepted}) {
+		t.Fatalf("last message = %+v, want acceptance sent to phone", last)
+	}
+	if rec := ackTransfer(h, userID, transfer.ID, `{"deviceId":"desktop","accepted":true}`); rec.Code != http.StatusConflict {
+		t.Fatalf("second ack = %d, want 409", rec.Code)
+	}
+}
+
+func TestPlaybackTransferFallsBackToPollingAndExpires(t *testing.T) {
+	userID := uuid.New()
+	h, _, devices := newTransferFixture()
+	devices.connected["desktop"] = false
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	rec := createTransfer(h, userID, `{"fromDeviceId":"phone","toDeviceId":"desktop","positionMs":0}`)
+	var transfer PlaybackTransferResponse
+	if err := json.NewDecoder(rec.Body).Decode(&transfer); err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Delivered {
+		t.Fatal("transfer to a disconnected device reported as delivered")
+	}
+
+	pending := func(deviceID string) PendingPlaybackTransfersResponse {
+		rec := httptest.NewRecorder()
+		h.ListPending(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/playback/transfer/pending?deviceId="+deviceID, nil), userID))
+		var resp PendingPlaybackTransfersResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if got := pending("desktop"); len(got.Transfers) != 1 || got.Transfers[0].ID != transfer.ID {
+		t.Fatalf("pending for desktop = %+v", got)
+	}
+	if got := pending("phone"); len(got.Transfers) != 0 {
+		t.Fatalf("pending for phone = %+v, want none", got)
+	}
+	if rec := ackTransfer(h, userID, transfer.ID, `{"deviceId":"phone","accepted":true}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("ack from source device = %d, want 403", rec.Code)
+	}
+	if rec := ackTransfer(h, uuid.New(), transfer.ID, `{"deviceId":"desktop","accepted":true}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("ack from another user = %d, want 404", rec.Code)
+	}
+
+	now = now.Add(playbackTransferTTL)
+	if got := pending("desktop"); len(got.Transfers) != 0 {
+		t.Fatalf("pending after TTL = %+v, want none", got)
+	}
+	get := httptest.NewRecorder()
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/v1/playback/transfer/"+transfer.ID, nil), userID)
+	req.SetPathValue("id", transfer.ID)
+	h.GetTransfer(get, req)
+	if err := json.NewDecoder(get.Body).Decode(&transfer); err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Status != PlaybackTransferExpired {
+		t.Fatalf("status after TTL = %q, want expired", transfer.Status)
+	}
+}
+
+func TestPlaybackTransferValidation(t *testing.T) {
+	userID := uuid.New()
+	h, q, _ := newTransferFixture()
+
+	for _, body := range []string{
+		`{"fromDeviceId":"phone","toDeviceId":"phone"}`,
+		`{"fromDeviceId":"phone","toDeviceId":"bad id"}`,
+		`{"fromDeviceId":"phone","toDeviceId":"desktop","positionMs":-1}`,
+	} {
+		if rec := createTransfer(h, userID, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("POST %s = %d, want 400", body, rec.Code)
+		}
+	}
+	q.state.Items = nil
+	if rec := createTransfer(h, userID, `{"fromDeviceId":"phone","toDeviceId":"desktop"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("empty queue = %d, want 409", rec.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// maxArtworkUploadBytes bounds an uploaded playlist cover before decoding.
+const maxArtworkUploadBytes = 10 << 20
+
+// PlaylistArtwork stores uploaded covers and generated track mosaics.
+type PlaylistArtwork interface {
+	Upload(ctx context.Context, playlistID int64, r io.Reader) error
+	Remove(ctx context.Context, playlistID int64) error
+	RefreshMosaicAsync(playlistID int64)
+	URL(ctx context.Context, key string) (string, error)
+	DeleteObjects(ctx context.Context, keys ...string)
+}
+
+// SetArtwork enables cover uploads and mosaics. Without it playlist responses
+// only carry the user-supplied coverUrl as artworkUrl.
+func (h *PlaylistHandlers) SetArtwork(a PlaylistArtwork) {
+	h.artwork = a
+}
+
+// UploadArtwork handles PUT /api/v1/playlists/{id}/artwork. The body is the raw
+// JPEG or PNG image; owners and collaborators may set the cover.
+func (h *PlaylistHandlers) UploadArtwork(w http.ResponseWriter, r *http.Request) {
+	if h.artwork == nil {
+		writePlaylistError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "playlist artwork is not configured")
+		return
+	}
+	playlist, _, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "image/jpeg" && mediaType != "image/png" {
+		writePlaylistError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "artwork must be image/jpeg or image/png")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxArtworkUploadBytes)
+	if err := h.artwork.Upload(r.Context(), playlist.ID, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writePlaylistError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "artwork must be at most 10 MB")
+		case errors.Is(err, artwork.ErrUnsupportedImage):
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "artwork is not a valid JPEG or PNG image")
+		case errors.Is(err, artwork.ErrImageTooLarge):
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "artwork dimensions are too large")
+		case errors.Is(err, db.ErrPlaylistNotFound):
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+		default:
+			log.Printf("Error: failed to store playlist %d artwork: %v", playlist.ID, err)
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to store artwork")
+		}
+		return
+	}
+
+	h.writeArtworkPlaylist(w, r, playlist.ID)
+}
+
+// DeleteArtwork handles DELETE /api/v1/playlists/{id}/artwork, reverting the
+// playlist to its coverUrl or generated mosaic.
+func (h *PlaylistHandlers) DeleteArtwork(w http.ResponseWriter, r *http.Request) {
+	if h.artwork == nil {
+		writePlaylistError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "playlist artwork is not configured")
+		return
+	}
+	playlist, _, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
+
+	if err := h.artwork.Remove(r.Context(), playlist.ID); err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove artwork")
+		return
+	}
+	h.refreshMosaic(playlist.ID)
+
+	h.writeArtworkPlaylist(w, r, playlist.ID)
+}
+
+func (h *PlaylistHandlers) writeArtworkPlaylist(w http.ResponseWriter, r *http.Request, playlistID int64) {
+	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlistID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get updated playlist")
+		return
+	}
+	writePlaylistJSON(w, http.StatusOK, h.newPlaylistResponse(r.Context(), updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs))
+}
+
+// artworkURL picks the image shown for a playlist: an uploaded cover first,
+// then the user-supplied coverUrl, then the generated mosaic.
+func (h *PlaylistHandlers) artworkURL(ctx context.Context, p db.Playlist) string {
+	return playlistArtworkURL(ctx, h.artwork, p)
+}
+
+func playlistArtworkURL(ctx context.Context, artwork PlaylistArtwork, p db.Playlist) string {
+	if artwork != nil && p.ArtworkKey.Valid {
+		if url, err := artwork.URL(ctx, p.ArtworkKey.String); err == nil {
+			return url
+		}
+	}
+	if p.CoverURL.Valid {
+		return p.CoverURL.String
+	}
+	if artwork != nil && p.MosaicKey.Valid {
+		if url, err := artwork.URL(ctx, p.MosaicKey.String); err == nil {
+			return url
+		}
+	}
+	return ""
+}
+
+// refreshMosaic schedules a background mosaic rebuild after the playlist's
+// tracks change.
+func (h *PlaylistHandlers) refreshMosaic(playlistID int64) {
+	if h.artwork != nil {
+		h.artwork.RefreshMosaicAsync(playlistID)
+	}
+}
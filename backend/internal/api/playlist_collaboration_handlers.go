@@ -0,0 +1,481 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// mentionPattern matches @handle tokens that are not part of a larger word, such
+// as an email address. Handles follow the profile handle rules.
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-z0-9_@.])@([a-z0-9][a-z0-9_-]{2,31})`)
+
+const maxPlaylistCommentLength = 2000
+
+type playlistCollaborationStore interface {
+	GetByID(ctx context.Context, id int64) (*db.Playlist, error)
+	IsCollaborator(ctx context.Context, playlistID int64, userID uuid.UUID) (bool, error)
+	AddCollaboratorByHandle(ctx context.Context, playlistID int64, handle string, addedBy uuid.UUID) (*db.PlaylistCollaborator, error)
+	RemoveCollaborator(ctx context.Context, playlistID int64, handle string, removedBy uuid.UUID) error
+	ListCollaborators(ctx context.Context, playlistID int64) ([]db.PlaylistCollaborator, error)
+	ListActivity(ctx context.Context, playlistID int64, limit, offset int) ([]db.PlaylistActivity, error)
+	ListComments(ctx context.Context, playlistID, trackID int64, limit, offset int) ([]db.PlaylistComment, error)
+	GetComment(ctx context.Context, playlistID, commentID int64) (*db.PlaylistComment, error)
+	CreateComment(ctx context.Context, comment *db.PlaylistComment, mentions []string) (int, error)
+	UpdateComment(ctx context.Context, comment *db.PlaylistComment) error
+	DeleteComment(ctx context.Context, playlistID, commentID int64, actorID uuid.UUID) error
+}
+
+// PlaylistCollaborationHandlers serves collaborator management, comments, and
+// the activity feed for shared playlists. Owners manage collaborators; owners
+// and collaborators can read and write comments.
+type PlaylistCollaborationHandlers struct {
+	playlistRepo playlistCollaborationStore
+	avatars      AvatarURLs
+}
+
+func NewPlaylistCollaborationHandlers(playlistRepo playlistCollaborationStore) *PlaylistCollaborationHandlers {
+	return &PlaylistCollaborationHandlers{playlistRepo: playlistRepo}
+}
+
+// SetAvatars lets collaborator lists show each member's avatar.
+func (h *PlaylistCollaborationHandlers) SetAvatars(avatars AvatarURLs) {
+	h.avatars = avatars
+}
+
+type AddCollaboratorRequest struct {
+	Handle string `json:"handle"`
+}
+
+type CollaboratorResponse struct {
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"displayName"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	AddedAt     time.Time `json:"addedAt"`
+}
+
+type CollaboratorsResponse struct {
+	Collaborators []CollaboratorResponse `json:"collaborators"`
+}
+
+type PlaylistCommentRequest struct {
+	Body    string `json:"body"`
+	TrackID int64  `json:"trackId,omitempty"`
+}
+
+type PlaylistCommentResponse struct {
+	ID         int64     `json:"id"`
+	PlaylistID int64     `json:"playlistId"`
+	TrackID    int64     `json:"trackId,omitempty"`
+	Author     string    `json:"author"`
+	IsMine     bool      `json:"isMine"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Notified   int       `json:"notified,omitempty"`
+}
+
+type PlaylistCommentsResponse struct {
+	Comments []PlaylistCommentResponse `json:"comments"`
+	Limit    int                       `json:"limit"`
+	Offset   int                       `json:"offset"`
+}
+
+type PlaylistActivityResponse struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	Actor     string          `json:"actor,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type PlaylistActivityFeedResponse struct {
+	Activity []PlaylistActivityResponse `json:"activity"`
+	Limit    int                        `json:"limit"`
+	Offset   int                        `json:"offset"`
+}
+
+// ListCollaborators handles GET /api/v1/playlists/{id}/collaborators.
+func (h *PlaylistCollaborationHandlers) ListCollaborators(w http.ResponseWriter, r *http.Request) {
+	playlist, _, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	collaborators, err := h.playlistRepo.ListCollaborators(r.Context(), playlist.ID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list collaborators")
+		return
+	}
+
+	resp := CollaboratorsResponse{Collaborators: make([]CollaboratorResponse, 0, len(collaborators))}
+	for _, c := range collaborators {
+		resp.Collaborators = append(resp.Collaborators, h.newCollaboratorResponse(r.Context(), c))
+	}
+	writePlaylistJSON(w, http.StatusOK, resp)
+}
+
+// AddCollaborator handles POST /api/v1/playlists/{id}/collaborators.
+func (h *PlaylistCollaborationHandlers) AddCollaborator(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, true)
+	if !ok {
+		return
+	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
+
+	var req AddCollaboratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	handle := strings.ToLower(strings.TrimSpace(req.Handle))
+	if !profileHandlePattern.MatchString(handle) {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "handle is required")
+		return
+	}
+
+	collaborator, err := h.playlistRepo.AddCollaboratorByHandle(r.Context(), playlist.ID, handle, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrProfileNotFound):
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		case errors.Is(err, db.ErrCollaboratorIsOwner):
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "playlist owner is already a member")
+		default:
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add collaborator")
+		}
+		return
+	}
+
+	writePlaylistJSON(w, http.StatusCreated, h.newCollaboratorResponse(r.Context(), *collaborator))
+}
+
+// RemoveCollaborator handles DELETE /api/v1/playlists/{id}/collaborators/{handle}.
+func (h *PlaylistCollaborationHandlers) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, true)
+	if !ok {
+		return
+	}
+
+	handle := strings.ToLower(strings.TrimSpace(r.PathValue("handle")))
+	if err := h.playlistRepo.RemoveCollaborator(r.Context(), playlist.ID, handle, userID); err != nil {
+		if errors.Is(err, db.ErrCollaboratorNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "collaborator not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove collaborator")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListActivity handles GET /api/v1/playlists/{id}/activity.
+func (h *PlaylistCollaborationHandlers) ListActivity(w http.ResponseWriter, r *http.Request) {
+	playlist, _, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 50)
+	offset := parseIntParam(r, "offset", 0)
+	activity, err := h.playlistRepo.ListActivity(r.Context(), playlist.ID, limit, offset)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load activity")
+		return
+	}
+
+	resp := PlaylistActivityFeedResponse{
+		Activity: make([]PlaylistActivityResponse, 0, len(activity)),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, a := range activity {
+		item := PlaylistActivityResponse{
+			ID:        a.ID,
+			Kind:      a.Kind,
+			Payload:   a.Payload,
+			CreatedAt: a.CreatedAt,
+		}
+		if a.ActorName.Valid {
+			item.Actor = a.ActorName.String
+		}
+		resp.Activity = append(resp.Activity, item)
+	}
+	writePlaylistJSON(w, http.StatusOK, resp)
+}
+
+// ListComments handles GET /api/v1/playlists/{id}/comments.
+func (h *PlaylistCollaborationHandlers) ListComments(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	var trackID int64
+	if raw := r.URL.Query().Get("trackId"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid trackId")
+			return
+		}
+		trackID = parsed
+	}
+
+	limit := parseIntParam(r, "limit", 50)
+	offset := parseIntParam(r, "offset", 0)
+	comments, err := h.playlistRepo.ListComments(r.Context(), playlist.ID, trackID, limit, offset)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list comments")
+		return
+	}
+
+	resp := PlaylistCommentsResponse{
+		Comments: make([]PlaylistCommentResponse, 0, len(comments)),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, c := range comments {
+		resp.Comments = append(resp.Comments, newPlaylistCommentResponse(c, userID))
+	}
+	writePlaylistJSON(w, http.StatusOK, resp)
+}
+
+// CreateComment handles POST /api/v1/playlists/{id}/comments.
+func (h *PlaylistCollaborationHandlers) CreateComment(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req PlaylistCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	body, ok := validateCommentBody(w, req.Body)
+	if !ok {
+		return
+	}
+	if req.TrackID < 0 {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid trackId")
+		return
+	}
+
+	comment := &db.PlaylistComment{
+		PlaylistID: playlist.ID,
+		TrackID:    sql.NullInt64{Int64: req.TrackID, Valid: req.TrackID > 0},
+		UserID:     userID,
+		Body:       body,
+	}
+	notified, err := h.playlistRepo.CreateComment(r.Context(), comment, extractMentions(body))
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotInPlaylist) {
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "track not in playlist")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create comment")
+		return
+	}
+
+	resp := newPlaylistCommentResponse(*comment, userID)
+	resp.Notified = notified
+	writePlaylistJSON(w, http.StatusCreated, resp)
+}
+
+// UpdateComment handles PUT /api/v1/playlists/{id}/comments/{commentId}.
+// Only the comment's author may edit it.
+func (h *PlaylistCollaborationHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+	comment, ok := h.loadComment(w, r, playlist.ID)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID {
+		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "only the author can edit this comment")
+		return
+	}
+
+	var req PlaylistCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	body, ok := validateCommentBody(w, req.Body)
+	if !ok {
+		return
+	}
+
+	comment.Body = body
+	if err := h.playlistRepo.UpdateComment(r.Context(), comment); err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "comment not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update comment")
+		return
+	}
+
+	writePlaylistJSON(w, http.StatusOK, newPlaylistCommentResponse(*comment, userID))
+}
+
+// DeleteComment handles DELETE /api/v1/playlists/{id}/comments/{commentId}.
+// Authors can delete their own comments; the playlist owner can delete any.
+func (h *PlaylistCollaborationHandlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorize(w, r, false)
+	if !ok {
+		return
+	}
+	comment, ok := h.loadComment(w, r, playlist.ID)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID && playlist.UserID != userID {
+		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to delete this comment")
+		return
+	}
+
+	if err := h.playlistRepo.DeleteComment(r.Context(), playlist.ID, comment.ID, userID); err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "comment not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize loads the playlist from the path and checks that the caller is its
+// owner, or a collaborator when ownerOnly is false. It writes the error response
+// itself and reports whether the handler should continue.
+func (h *PlaylistCollaborationHandlers) authorize(w http.ResponseWriter, r *http.Request, ownerOnly bool) (*db.Playlist, uuid.UUID, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return nil, uuid.Nil, false
+	}
+
+	playlistID, err := parsePlaylistID(r)
+	if err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid playlist ID")
+		return nil, uuid.Nil, false
+	}
+
+	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return nil, uuid.Nil, false
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return nil, uuid.Nil, false
+	}
+
+	if playlist.UserID == userCtx.UserID {
+		return playlist, userCtx.UserID, true
+	}
+	if !ownerOnly {
+		member, err := h.playlistRepo.IsCollaborator(r.Context(), playlist.ID, userCtx.UserID)
+		if err != nil {
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+			return nil, uuid.Nil, false
+		}
+		if member {
+			return playlist, userCtx.UserID, true
+		}
+	}
+
+	writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to access this playlist")
+	return nil, uuid.Nil, false
+}
+
+func (h *PlaylistCollaborationHandlers) loadComment(w http.ResponseWriter, r *http.Request, playlistID int64) (*db.PlaylistComment, bool) {
+	commentID, err := strconv.ParseInt(r.PathValue("commentId"), 10, 64)
+	if err != nil || commentID <= 0 {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid comment ID")
+		return nil, false
+	}
+	comment, err := h.playlistRepo.GetComment(r.Context(), playlistID, commentID)
+	if err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "comment not found")
+			return nil, false
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get comment")
+		return nil, false
+	}
+	return comment, true
+}
+
+func validateCommentBody(w http.ResponseWriter, raw string) (string, bool) {
+	body := strings.TrimSpace(raw)
+	if body == "" {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "body is required")
+		return "", false
+	}
+	if len([]rune(body)) > maxPlaylistCommentLength {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "body must be at most 2000 characters")
+		return "", false
+	}
+	return body, true
+}
+
+// extractMentions returns the distinct, lowercased handles @-mentioned in body.
+func extractMentions(body string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(strings.ToLower(body), -1)
+	seen := make(map[string]bool, len(matches))
+	handles := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			handles = append(handles, m[1])
+		}
+	}
+	return handles
+}
+
+func (h *PlaylistCollaborationHandlers) newCollaboratorResponse(ctx context.Context, c db.PlaylistCollaborator) CollaboratorResponse {
+	resp := CollaboratorResponse{
+		DisplayName: c.Username,
+		AvatarURL:   avatarURL(ctx, h.avatars, c.AvatarKey),
+		AddedAt:     c.AddedAt,
+	}
+	if c.Handle.Valid {
+		resp.Handle = c.Handle.String
+	}
+	return resp
+}
+
+func newPlaylistCommentResponse(c db.PlaylistComment, viewerID uuid.UUID) PlaylistCommentResponse {
+	resp := PlaylistCommentResponse{
+		ID:         c.ID,
+		PlaylistID: c.PlaylistID,
+		Author:     c.AuthorName,
+		IsMine:     c.UserID == viewerID,
+		Body:       c.Body,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+	}
+	if c.TrackID.Valid {
+		resp.TrackID = c.TrackID.Int64
+	}
+	return resp
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeCollaborationStore struct {
+	playlists     map[int64]*db.Playlist
+	collaborators map[int64]map[uuid.UUID]bool
+	comments      map[int64]*db.PlaylistComment
+	nextCommentID int64
+
+	lastMentions []string
+	deleted      []int64
+}
+
+func newFakeCollaborationStore() *fakeCollaborationStore {
+	return &fakeCollaborationStore{
+		playlists:     map[int64]*db.Playlist{},
+		collaborators: map[int64]map[uuid.UUID]bool{},
+		comments:      map[int64]*db.PlaylistComment{},
+	}
+}
+
+func (f *fakeCollaborationStore) GetByID(ctx context.Context, id int64) (*db.Playlist, error) {
+	if p, ok := f.playlists[id]; ok {
+		return p, nil
+	}
+	return nil, db.ErrPlaylistNotFound
+}
+
+func (f *fakeCollaborationStore) IsCollaborator(ctx context.Context, playlistID int64, userID uuid.UUID) (bool, error) {
+	return f.collaborators[playlistID][userID], nil
+}
+
+func (f *fakeCollaborationStore) AddCollaboratorByHandle(ctx context.Context, playlistID int64, handle string, addedBy uuid.UUID) (*db.PlaylistCollaborator, error) {
+	if handle == "nobody" {
+		return nil, db.ErrProfileNotFound
+	}
+	return &db.PlaylistCollaborator{PlaylistID: playlistID, UserID: uuid.New(), Username: handle, AddedAt: time.Now()}, nil
+}
+
+func (f *fakeCollaborationStore) RemoveCollaborator(ctx context.Context, playlistID int64, handle string, removedBy uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeCollaborationStore) ListCollaborators(ctx context.Context, playlistID int64) ([]db.PlaylistCollaborator, error) {
+	return nil, nil
+}
+
+func (f *fakeCollaborationStore) ListActivity(ctx context.Context, playlistID int64, limit, offset int) ([]db.PlaylistActivity, error) {
+	return nil, nil
+}
+
+func (f *fakeCollaborationStore) ListComments(ctx context.Context, playlistID, trackID int64, limit, offset int) ([]db.PlaylistComment, error) {
+	var comments []db.PlaylistComment
+	for _, c := range f.comments {
+		if c.PlaylistID == playlistID && (trackID == 0 || c.TrackID.Int64 == trackID) {
+			comments = append(comments, *c)
+		}
+	}
+	return comments, nil
+}
+
+func (f *fakeCollaborationStore) GetComment(ctx context.Context, playlistID, commentID int64) (*db.PlaylistComment, error) {
+	if c, ok := f.comments[commentID]; ok && c.PlaylistID == playlistID {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, db.ErrCommentNotFound
+}
+
+func (f *fakeCollaborationStore) CreateComment(ctx context.Context, comment *db.PlaylistComment, mentions []string) (int, error) {
+	f.nextCommentID++
+	comment.ID = f.nextCommentID
+	copied := *comment
+	f.comments[comment.ID] = &copied
+	f.lastMentions = mentions
+	return len(mentions), nil
+}
+
+func (f *fakeCollaborationStore) UpdateComment(ctx context.Context, comment *db.PlaylistComment) error {
+	copied := *comment
+	f.comments[comment.ID] = &copied
+	return nil
+}
+
+func (f *fakeCollaborationStore) DeleteComment(ctx context.Context, playlistID, commentID int64, actorID uuid.UUID) error {
+	delete(f.comments, commentID)
+	f.deleted = append(f.deleted, commentID)
+	return nil
+}
+
+func TestExtractMentions(t *testing.T) {
+	got := extractMentions("@Alice loved this, cc @bob and @alice again; mail me at dj@example.com, @x is too short")
+	want := []string{"alice", "bob"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("extractMentions = %v, want %v", got, want)
+	}
+}
+
+func TestPlaylistCommentsRequireMembership(t *testing.T) {
+	store := newFakeCollaborationStore()
+	owner, collaborator := uuid.New(), uuid.New()
+	store.playlists[1] = &db.Playlist{ID: 1, UserID: owner}
+	store.collaborators[1] = map[uuid.UUID]bool{collaborator: true}
+	h := NewPlaylistCollaborationHandlers(store)
+
+	cases := []struct {
+		name       string
+		userID     uuid.UUID
+		wantStatus int
+	}{
+		{"owner", owner, http.StatusCreated},
+		{"collaborator", collaborator, http.StatusCreated},
+		{"stranger", uuid.New(), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/1/comments", strings.NewReader(`{"body":"nice pick @Owner"}`)), tc.userID)
+			req.SetPathValue("id", "1")
+			rr := httptest.NewRecorder()
+			h.CreateComment(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(store.lastMentions, []string{"owner"}) {
+		t.Fatalf("mentions = %v, want [owner]", store.lastMentions)
+	}
+}
+
+func TestCreatePlaylistCommentValidatesBody(t *testing.T) {
+	store := newFakeCollaborationStore()
+	owner := uuid.New()
+	store.playlists[1] = &db.Playlist{ID: 1, UserID: owner}
+	h := NewPlaylistCollaborationHandlers(store)
+
+	for _, body := range []string{`{`, `{"body":"   "}`, `{"body":"` + strings.Repeat("a", maxPlaylistCommentLength+1) + `"}`} {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/1/comments", strings.NewReader(body)), owner)
+		req.SetPathValue("id", "1")
+		rr := httptest.NewRecorder()
+		h.CreateComment(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400 for body %.20q", rr.Code, body)
+		}
+	}
+	if len(store.comments) != 0 {
+		t.Fatalf("invalid comments were stored: %d", len(store.comments))
+	}
+}
+
+func TestPlaylistCommentEditAndDeletePermissions(t *testing.T) {
+	store := newFakeCollaborationStore()
+	owner, author, other := uuid.New(), uuid.New(), uuid.New()
+	store.playlists[1] = &db.Playlist{ID: 1, UserID: owner}
+	store.collaborators[1] = map[uuid.UUID]bool{author: true, other: true}
+	store.comments[7] = &db.PlaylistComment{ID: 7, PlaylistID: 1, UserID: author, Body: "first"}
+	h := NewPlaylistCollaborationHandlers(store)
+
+	call := func(method string, userID uuid.UUID, body string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/v1/playlists/1/comments/7", strings.NewReader(body)), userID)
+		req.SetPathValue("id", "1")
+		req.SetPathValue("commentId", "7")
+		rr := httptest.NewRecorder()
+		fn(rr, req)
+		return rr
+	}
+
+	if rr := call(http.MethodPut, owner, `{"body":"edited"}`, h.UpdateComment); rr.Code != http.StatusForbidden {
+		t.Fatalf("owner edit status = %d, want 403", rr.Code)
+	}
+	rr := call(http.MethodPut, author, `{"body":"edited"}`, h.UpdateComment)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("author edit status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp PlaylistCommentResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Body != "edited" || !resp.IsMine {
+		t.Fatalf("edited comment = %#v", resp)
+	}
+
+	if rr := call(http.MethodDelete, other, "", h.DeleteComment); rr.Code != http.StatusForbidden {
+		t.Fatalf("other collaborator delete status = %d, want 403", rr.Code)
+	}
+	if rr := call(http.MethodDelete, owner, "", h.DeleteComment); rr.Code != http.StatusNoContent {
+		t.Fatalf("owner delete status = %d, want 204", rr.Code)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != 7 {
+		t.Fatalf("deleted = %v, want [7]", store.deleted)
+	}
+}
+
+func TestAddCollaboratorIsOwnerOnly(t *testing.T) {
+	store := newFakeCollaborationStore()
+	owner, collaborator := uuid.New(), uuid.New()
+	store.playlists[1] = &db.Playlist{ID: 1, UserID: owner}
+	store.collaborators[1] = map[uuid.UUID]bool{collaborator: true}
+	h := NewPlaylistCollaborationHandlers(store)
+
+	cases := []struct {
+		name       string
+		userID     uuid.UUID
+		body       string
+		wantStatus int
+	}{
+		{"collaborator cannot invite", collaborator, `{"handle":"friend"}`, http.StatusForbidden},
+		{"unknown handle", owner, `{"handle":"nobody"}`, http.StatusNotFound},
+		{"invalid handle", owner, `{"handle":"a"}`, http.StatusBadRequest},
+		{"owner invites", owner, `{"handle":" Friend "}`, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/1/collaborators", strings.NewReader(tc.body)), tc.userID)
+			req.SetPathValue("id", "1")
+			rr := httptest.NewRecorder()
+			h.AddCollaborator(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type DuplicatePlaylistRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+type MergePlaylistRequest struct {
+	SourcePlaylistID int64  `json:"sourcePlaylistId"`
+	Strategy         string `json:"strategy,omitempty"`
+}
+
+// DuplicatePlaylist handles POST /api/v1/playlists/{id}/duplicate. Any member
+// of the source playlist may duplicate it; the copy is private and owned by the
+// caller. The request body is optional.
+func (h *PlaylistHandlers) DuplicatePlaylist(w http.ResponseWriter, r *http.Request) {
+	source, userID, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+
+	var req DuplicatePlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+
+	dup := &db.Playlist{
+		UserID:      userID,
+		Name:        name,
+		Description: source.Description,
+		CoverURL:    source.CoverURL,
+	}
+	if err := h.playlistRepo.Duplicate(r.Context(), source.ID, dup); err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to duplicate playlist")
+		return
+	}
+
+	h.refreshMosaic(dup.ID)
+
+	created, err := h.playlistRepo.GetByIDWithTracks(r.Context(), dup.ID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get duplicated playlist")
+		return
+	}
+
+	writePlaylistJSON(w, http.StatusCreated, h.newPlaylistWithTracksResponse(r.Context(), created, mapTrackResponses(created.Tracks)))
+}
+
+// MergePlaylist handles POST /api/v1/playlists/{id}/merge. It copies the
+// source playlist's tracks into this one, skipping tracks already present.
+// strategy is "append" (default), "prepend", or "interleave".
+func (h *PlaylistHandlers) MergePlaylist(w http.ResponseWriter, r *http.Request) {
+	target, userID, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+	if rejectSystemPlaylist(w, target) {
+		return
+	}
+
+	var req MergePlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	if req.SourcePlaylistID <= 0 {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "sourcePlaylistId is required")
+		return
+	}
+	if req.SourcePlaylistID == target.ID {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "cannot merge a playlist into itself")
+		return
+	}
+	switch req.Strategy {
+	case "", db.PlaylistMergeAppend, db.PlaylistMergePrepend, db.PlaylistMergeInterleave:
+	default:
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "strategy must be append, prepend, or interleave")
+		return
+	}
+
+	// The caller must also be able to read the source playlist.
+	source, err := h.playlistRepo.GetByID(r.Context(), req.SourcePlaylistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "source playlist not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get source playlist")
+		return
+	}
+	member, err := h.isPlaylistMember(r.Context(), source, userID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get source playlist")
+		return
+	}
+	if !member {
+		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to access the source playlist")
+		return
+	}
+
+	h.ensureHistoryBaseline(r.Context(), target.ID)
+	report, err := h.playlistRepo.MergeTracks(r.Context(), target.ID, source.ID, req.Strategy)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to merge playlists")
+		return
+	}
+	if len(report.Added) > 0 {
+		h.recordTrackChange(r.Context(), target.ID, userID, db.PlaylistActivityTracksAdded, map[string]interface{}{
+			"trackIds":         report.Added,
+			"sourcePlaylistId": source.ID,
+		})
+	}
+
+	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), target.ID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get updated playlist")
+		return
+	}
+
+	writePlaylistJSON(w, http.StatusOK, AddTracksResponse{
+		Added:    report.Added,
+		Skipped:  report.Skipped,
+		Playlist: h.newPlaylistResponse(r.Context(), updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs),
+	})
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/processor"
+)
+
+const (
+	defaultPlaylistExportURLTTL = 24 * time.Hour
+	// maxPlaylistExportURLTTL is the longest presigned URL S3-compatible
+	// storage accepts.
+	maxPlaylistExportURLTTL = 7 * 24 * time.Hour
+)
+
+type playlistExportStore interface {
+	GetByIDWithTracks(ctx context.Context, id int64) (*db.PlaylistWithTracks, error)
+	IsCollaborator(ctx context.Context, playlistID int64, userID uuid.UUID) (bool, error)
+}
+
+type playlistExportTracks interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+}
+
+type playlistExportStorage interface {
+	PresignPrivateGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// PlaylistExportHandlers writes playlists as M3U or JSON files for backups
+// and external players. Entries point at presigned stream URLs, or at the
+// paths tracks get in a library export so the file works next to an
+// unpacked ZIP or EXPORT_DIR.
+type PlaylistExportHandlers struct {
+	playlists playlistExportStore
+	tracks    playlistExportTracks
+	storage   playlistExportStorage
+}
+
+func NewPlaylistExportHandlers(playlists playlistExportStore, tracks playlistExportTracks, storage playlistExportStorage) *PlaylistExportHandlers {
+	return &PlaylistExportHandlers{playlists: playlists, tracks: tracks, storage: storage}
+}
+
+type PlaylistExportTrack struct {
+	Position      int    `json:"position"`
+	TrackID       int64  `json:"trackId"`
+	Title         string `json:"title"`
+	Artist        string `json:"artist,omitempty"`
+	Album         string `json:"album,omitempty"`
+	DurationMs    int    `json:"durationMs,omitempty"`
+	MBRecordingID string `json:"mbRecordingId,omitempty"`
+	// Location is the stream URL or relative path, empty when the track has
+	// no playable audio.
+	Location string `json:"location,omitempty"`
+}
+
+type PlaylistExportFile struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	ExportedAt  time.Time             `json:"exportedAt"`
+	Paths       string                `json:"paths"`
+	ExpiresAt   *time.Time            `json:"expiresAt,omitempty"`
+	Tracks      []PlaylistExportTrack `json:"tracks"`
+}
+
+// ExportPlaylist handles GET /api/v1/playlists/{id}/export. ?format=m3u (or
+// m3u8) writes an extended M3U playlist and ?format=json a
+// PlaylistExportFile. ?paths=stream, the default, points entries at
+// presigned stream URLs valid for ?ttl seconds (default one day, at most
+// seven); ?paths=relative uses Artist/Album/Title.ext paths instead. M3U
+// files leave out tracks without playable audio; JSON keeps them with no
+// location so the backup still lists them.
+func (h *PlaylistExportHandlers) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	playlistID, err := parsePlaylistID(r)
+	if err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid playlist ID")
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format != "m3u" && format != "m3u8" && format != "json" {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "format must be m3u, m3u8, or json")
+		return
+	}
+	paths := query.Get("paths")
+	if paths == "" {
+		paths = "stream"
+	}
+	if paths != "stream" && paths != "relative" {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "paths must be stream or relative")
+		return
+	}
+	ttl := defaultPlaylistExportURLTTL
+	if raw := query.Get("ttl"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 60 || time.Duration(seconds)*time.Second > maxPlaylistExportURLTTL {
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "ttl must be between 60 and 604800 seconds")
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+	if paths == "stream" && h.storage == nil {
+		writePlaylistError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "stream URLs are unavailable; use paths=relative")
+		return
+	}
+
+	playlist, err := h.playlists.GetByIDWithTracks(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	member := playlist.UserID == userCtx.UserID
+	if !member {
+		member, err = h.playlists.IsCollaborator(r.Context(), playlist.ID, userCtx.UserID)
+		if err != nil {
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+			return
+		}
+	}
+	if !member {
+		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to access this playlist")
+		return
+	}
+
+	now := time.Now().UTC()
+	file := PlaylistExportFile{
+		Name:        playlist.Name,
+		Description: playlist.Description.String,
+		ExportedAt:  now,
+		Paths:       paths,
+		Tracks:      make([]PlaylistExportTrack, 0, len(playlist.Tracks)),
+	}
+	if paths == "stream" {
+		expiresAt := now.Add(ttl)
+		file.ExpiresAt = &expiresAt
+	}
+	for i := range playlist.Tracks {
+		entry, err := h.exportTrack(r.Context(), &playlist.Tracks[i], paths, ttl)
+		if err != nil {
+			log.Printf("Warning: playlist %d export failed on track %d: %v", playlist.ID, playlist.Tracks[i].ID, err)
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export playlist")
+			return
+		}
+		entry.Position = i + 1
+		file.Tracks = append(file.Tracks, entry)
+	}
+
+	filename := exportFilename(playlist.Name, fmt.Sprintf("Playlist %d", playlist.ID))
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + ".json"}))
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(file)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + format}))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(playlistExportM3U(file)))
+}
+
+// exportTrack describes one playlist entry. Quarantined tracks and tracks
+// without stored audio get no location.
+func (h *PlaylistExportHandlers) exportTrack(ctx context.Context, listed *db.Track, paths string, ttl time.Duration) (PlaylistExportTrack, error) {
+	entry := PlaylistExportTrack{
+		TrackID: listed.ID,
+		Title:   listed.Title,
+		Artist:  listed.Artist.String,
+		Album:   listed.Album.String,
+	}
+	if listed.DurationMs.Valid {
+		entry.DurationMs = int(listed.DurationMs.Int32)
+	}
+	if listed.MBRecordingID != nil {
+		entry.MBRecordingID = listed.MBRecordingID.String()
+	}
+
+	// Playlist listings do not carry quarantine state; the single-track
+	// lookup does.
+	track, err := h.tracks.GetByID(ctx, listed.ID)
+	if errors.Is(err, db.ErrTrackNotFound) {
+		return entry, nil
+	}
+	if err != nil {
+		return entry, err
+	}
+	key := strings.TrimSpace(track.StorageKey.String)
+	if track.QuarantinedAt.Valid || key == "" {
+		return entry, nil
+	}
+	if paths == "relative" {
+		entry.Location = processor.ExportPath(track)
+		return entry, nil
+	}
+	entry.Location, err = h.storage.PresignPrivateGetObject(ctx, key, ttl)
+	return entry, err
+}
+
+// playlistExportM3U writes the entries that have a location as extended M3U.
+func playlistExportM3U(file PlaylistExportFile) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#PLAYLIST:" + m3uText(file.Name) + "\n")
+	for _, t := range file.Tracks {
+		if t.Location == "" {
+			continue
+		}
+		seconds := -1
+		if t.DurationMs > 0 {
+			seconds = (t.DurationMs + 500) / 1000
+		}
+		display := t.Title
+		if t.Artist != "" {
+			display = t.Artist + " - " + t.Title
+		}
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", seconds, m3uText(display))
+		if t.Album != "" {
+			b.WriteString("#EXTALB:" + m3uText(t.Album) + "\n")
+		}
+		b.WriteString(t.Location + "\n")
+	}
+	return b.String()
+}
+
+// m3uText keeps a value on one line.
+func m3uText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// exportFilename makes a playlist name safe as a download file name.
+func exportFilename(name, fallback string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 120 {
+		name = string(runes[:120])
+	}
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakePlaylistExportStore struct {
+	playlist *db.PlaylistWithTracks
+}
+
+func (f fakePlaylistExportStore) GetByIDWithTracks(_ context.Context, id int64) (*db.PlaylistWithTracks, error) {
+	if f.playlist == nil || f.playlist.ID != id {
+		return nil, db.ErrPlaylistNotFound
+	}
+	return f.playlist, nil
+}
+
+func (f fakePlaylistExportStore) IsCollaborator(context.Context, int64, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+type fakePlaylistExportTracks map[int64]*db.Track
+
+func (f fakePlaylistExportTracks) GetByID(_ context.Context, id int64) (*db.Track, error) {
+	if track, ok := f[id]; ok {
+		return track, nil
+	}
+	return nil, db.ErrTrackNotFound
+}
+
+type fakePlaylistExportStorage struct{}
+
+func (fakePlaylistExportStorage) PresignPrivateGetObject(_ context.Context, key string, expires time.Duration) (string, error) {
+	return "https://media.example.test/" + key + "?X-Amz-Expires=" + expires.String(), nil
+}
+
+func newPlaylistExportFixture(owner uuid.UUID) *PlaylistExportHandlers {
+	playable := &db.Track{ID: 1, Title: "Archangel", Artist: sql.NullString{String: "Burial", Valid: true}, Album: sql.NullString{String: "Untrue", Valid: true},
+		DurationMs: sql.NullInt32{Int32: 238400, Valid: true}, StorageKey: sql.NullString{String: "audio/1.mp3", Valid: true}}
+	quarantined := &db.Track{ID: 2, Title: "Blocked", StorageKey: sql.NullString{String: "audio/2.flac", Valid: true}, QuarantinedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	playlist := &db.PlaylistWithTracks{
+		Playlist: db.Playlist{ID: 9, UserID: owner, Name: "Night/Bus"},
+		Tracks:   []db.Track{*playable, {ID: 2, Title: "Blocked"}, {ID: 3, Title: "Gone"}},
+	}
+	return NewPlaylistExportHandlers(fakePlaylistExportStore{playlist: playlist},
+		fakePlaylistExportTracks{1: playable, 2: quarantined}, fakePlaylistExportStorage{})
+}
+
+func playlistExportRequest(h *PlaylistExportHandlers, user uuid.UUID, query string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/v1/playlists/9/export?"+query, nil), user)
+	req.SetPathValue("id", "9")
+	rec := httptest.NewRecorder()
+	h.ExportPlaylist(rec, req)
+	return rec
+}
+
+func TestExportPlaylistM3UWithStreamURLs(t *testing.T) {
+	owner := uuid.New()
+	rec := playlistExportRequest(newPlaylistExportFixture(owner), owner, "format=m3u&ttl=3600")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	want := "#EXTM3U\n#PLAYLIST:Night/Bus\n#EXTINF:238,Burial - Archangel\n#EXTALB:Untrue\nhttps://media.example.test/audio/1.mp3?X-Amz-Expires=1h0m0s\n"
+	if rec.Body.String() != want {
+		t.Fatalf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, `filename=Night_Bus.m3u`) {
+		t.Fatalf("Content-Disposition = %q", disposition)
+	}
+}
+
+func TestExportPlaylistJSONWithRelativePaths(t *testing.T) {
+	owner := uuid.New()
+	rec := playlistExportRequest(newPlaylistExportFixture(owner), owner, "format=json&paths=relative")
+	var file PlaylistExportFile
+	if err := json.Unmarshal(rec.Body.Bytes(), &file); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if file.ExpiresAt != nil || len(file.Tracks) != 3 {
+		t.Fatalf("file = %+v", file)
+	}
+	if got := file.Tracks[0].Location; got != "Burial/Untrue/Archangel.mp3" {
+		t.Fatalf("relative path = %q", got)
+	}
+	if file.Tracks[1].Location != "" || file.Tracks[2].Location != "" || file.Tracks[2].Position != 3 {
+		t.Fatalf("unavailable tracks = %+v", file.Tracks[1:])
+	}
+}
+
+func TestExportPlaylistRejectsBadRequests(t *testing.T) {
+	owner := uuid.New()
+	h := newPlaylistExportFixture(owner)
+	for _, tc := range []struct {
+		user  uuid.UUID
+		query string
+		want  int
+	}{
+		{owner, "format=pls", http.StatusBadRequest},
+		{owner, "format=m3u&paths=absolute", http.StatusBadRequest},
+		{owner, "format=m3u&ttl=999999", http.StatusBadRequest},
+		{uuid.New(), "format=m3u", http.StatusForbidden},
+	} {
+		if rec := playlistExportRequest(h, tc.user, tc.query); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.query, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/libraryimport"
+)
+
+// playlistFileMaxBodyBytes bounds uploaded playlist files; 10000 M3U entries
+// with long paths stay well under it.
+const playlistFileMaxBodyBytes = 8 << 20
+
+type playlistFileMatcher interface {
+	MatchPlaylistFile(ctx context.Context, userID uuid.UUID, entries []libraryimport.PlaylistFileEntry) ([]libraryimport.PlaylistFileMatch, error)
+}
+
+// PlaylistFileImportHandlers matches uploaded M3U/M3U8 and CSV playlists
+// against the caller's library.
+type PlaylistFileImportHandlers struct {
+	matcher playlistFileMatcher
+}
+
+func NewPlaylistFileImportHandlers(matcher playlistFileMatcher) *PlaylistFileImportHandlers {
+	return &PlaylistFileImportHandlers{matcher: matcher}
+}
+
+type PlaylistFileTrackResponse struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist,omitempty"`
+	DurationMs int    `json:"durationMs,omitempty"`
+}
+
+type PlaylistFileSuggestionResponse struct {
+	Track PlaylistFileTrackResponse `json:"track"`
+	Score float64                   `json:"score"`
+}
+
+type PlaylistFileEntryResponse struct {
+	Position    int                              `json:"position"`
+	Line        int                              `json:"line"`
+	Title       string                           `json:"title"`
+	Artist      string                           `json:"artist,omitempty"`
+	Album       string                           `json:"album,omitempty"`
+	DurationMs  int                              `json:"durationMs,omitempty"`
+	Location    string                           `json:"location,omitempty"`
+	Status      string                           `json:"status"`
+	Score       float64                          `json:"score"`
+	Track       *PlaylistFileTrackResponse       `json:"track"`
+	Suggestions []PlaylistFileSuggestionResponse `json:"suggestions"`
+}
+
+type PlaylistFileImportResponse struct {
+	Format       string                      `json:"format"`
+	Total        int                         `json:"total"`
+	ExactMatches int                         `json:"exactMatches"`
+	FuzzyMatches int                         `json:"fuzzyMatches"`
+	Unmatched    int                         `json:"unmatched"`
+	Entries      []PlaylistFileEntryResponse `json:"entries"`
+}
+
+// ImportPlaylistFile handles POST /api/v1/playlists/import. The body is the
+// playlist file itself, or a multipart form with it in a "file" field. The
+// format comes from ?format=m3u|m3u8|csv, then the Content-Type or file name,
+// then the file's first line. Nothing is created: the response lists each
+// entry with its match and suggestions, and the client builds the playlist
+// from the track IDs the user settles on.
+func (h *PlaylistFileImportHandlers) ImportPlaylistFile(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeLibraryImportError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, playlistFileMaxBodyBytes)
+	body, contentType, filename, err := playlistFileBody(r)
+	if err != nil {
+		writePlaylistFileReadError(w, err)
+		return
+	}
+	defer body.Close()
+
+	format := playlistFileFormat(r.URL.Query().Get("format"))
+	if format == "" && r.URL.Query().Get("format") != "" {
+		writeLibraryImportError(w, http.StatusBadRequest, "VALIDATION_ERROR", "format must be m3u, m3u8, or csv")
+		return
+	}
+	if format == "" {
+		format = playlistFileFormatFromType(contentType, filename)
+	}
+	buffered := bufio.NewReader(body)
+	if format == "" {
+		head, _ := buffered.Peek(512)
+		format = libraryimport.DetectPlaylistFormat(head)
+	}
+
+	entries, err := libraryimport.ParsePlaylistFile(buffered, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, libraryimport.ErrPlaylistFileTooLarge):
+			writeLibraryImportError(w, http.StatusRequestEntityTooLarge, "PLAYLIST_TOO_LARGE", err.Error())
+		case errors.Is(err, libraryimport.ErrInvalidPlaylistFile):
+			writeLibraryImportError(w, http.StatusBadRequest, "INVALID_PLAYLIST", err.Error())
+		default:
+			writePlaylistFileReadError(w, err)
+		}
+		return
+	}
+
+	matches, err := h.matcher.MatchPlaylistFile(r.Context(), userCtx.UserID, entries)
+	if err != nil {
+		log.Printf("Warning: playlist file match failed for user %s: %v", userCtx.UserID, err)
+		writeLibraryImportError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to match playlist")
+		return
+	}
+	writeLibraryImportJSON(w, http.StatusOK, newPlaylistFileImportResponse(format, matches))
+}
+
+// playlistFileBody returns the uploaded file with its declared type and name.
+func playlistFileBody(r *http.Request) (io.ReadCloser, string, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, mediaType, "", nil
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", "", err
+	}
+	fileType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	return file, fileType, header.Filename, nil
+}
+
+func writePlaylistFileReadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeLibraryImportError(w, http.StatusRequestEntityTooLarge, "PLAYLIST_TOO_LARGE", "playlist file is too large")
+		return
+	}
+	writeLibraryImportError(w, http.StatusBadRequest, "INVALID_PLAYLIST", "body must be an M3U/M3U8 or CSV playlist, or a multipart form with one in \"file\"")
+}
+
+func playlistFileFormat(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "m3u", "m3u8":
+		return libraryimport.PlaylistFormatM3U
+	case "csv", "tsv":
+		return libraryimport.PlaylistFormatCSV
+	}
+	return ""
+}
+
+func playlistFileFormatFromType(contentType, filename string) string {
+	switch contentType {
+	case "audio/x-mpegurl", "audio/mpegurl", "application/x-mpegurl", "application/vnd.apple.mpegurl":
+		return libraryimport.PlaylistFormatM3U
+	case "text/csv", "text/tab-separated-values":
+		return libraryimport.PlaylistFormatCSV
+	}
+	return playlistFileFormat(strings.TrimPrefix(path.Ext(filename), "."))
+}
+
+func newPlaylistFileImportResponse(format string, matches []libraryimport.PlaylistFileMatch) PlaylistFileImportResponse {
+	resp := PlaylistFileImportResponse{
+		Format:  format,
+		Total:   len(matches),
+		Entries: make([]PlaylistFileEntryResponse, 0, len(matches)),
+	}
+	for _, m := range matches {
+		position, _ := strconv.Atoi(m.Entry.SourceID)
+		item := PlaylistFileEntryResponse{
+			Position:    position,
+			Line:        m.Entry.Line,
+			Title:       m.Entry.Title,
+			Artist:      m.Entry.Artist,
+			Album:       m.Entry.Album,
+			DurationMs:  m.Entry.DurationMs,
+			Location:    m.Entry.Location,
+			Status:      m.Match.Status,
+			Score:       m.Match.Score,
+			Suggestions: make([]PlaylistFileSuggestionResponse, 0, len(m.Suggestions)),
+		}
+		switch m.Match.Status {
+		case libraryimport.MatchExact:
+			resp.ExactMatches++
+		case libraryimport.MatchFuzzy:
+			resp.FuzzyMatches++
+		default:
+			resp.Unmatched++
+		}
+		if m.Track != nil {
+			track := newPlaylistFileTrackResponse(*m.Track)
+			item.Track = &track
+		}
+		for _, s := range m.Suggestions {
+			item.Suggestions = append(item.Suggestions, PlaylistFileSuggestionResponse{Track: newPlaylistFileTrackResponse(s.Track), Score: s.Score})
+		}
+		resp.Entries = append(resp.Entries, item)
+	}
+	return resp
+}
+
+func newPlaylistFileTrackResponse(c libraryimport.Candidate) PlaylistFileTrackResponse {
+	return PlaylistFileTrackResponse{ID: c.TrackID, Title: c.Title, Artist: c.Artist, DurationMs: c.DurationMs}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/libraryimport"
+)
+
+// fakePlaylistFileMatcher matches entries titled "Windowlicker" exactly and
+// suggests it for everything else.
+type fakePlaylistFileMatcher struct {
+	entries []libraryimport.PlaylistFileEntry
+}
+
+func (f *fakePlaylistFileMatcher) MatchPlaylistFile(_ context.Context, _ uuid.UUID, entries []libraryimport.PlaylistFileEntry) ([]libraryimport.PlaylistFileMatch, error) {
+	f.entries = entries
+	track := libraryimport.Candidate{TrackID: 7, Title: "Windowlicker", Artist: "Aphex Twin"}
+	matches := make([]libraryimport.PlaylistFileMatch, 0, len(entries))
+	for _, e := range entries {
+		if e.Title == "Windowlicker" {
+			matches = append(matches, libraryimport.PlaylistFileMatch{Entry: e, Match: libraryimport.Match{TrackID: 7, Status: libraryimport.MatchExact, Score: 100}, Track: &track})
+			continue
+		}
+		matches = append(matches, libraryimport.PlaylistFileMatch{
+			Entry:       e,
+			Match:       libraryimport.Match{Status: libraryimport.MatchUnmatched, Score: 40},
+			Suggestions: []libraryimport.PlaylistFileSuggestion{{Track: track, Score: 55}},
+		})
+	}
+	return matches, nil
+}
+
+func TestImportPlaylistFileMatchesRawM3U(t *testing.T) {
+	matcher := &fakePlaylistFileMatcher{}
+	h := NewPlaylistFileImportHandlers(matcher)
+	body := "#EXTM3U\n#EXTINF:365,Aphex Twin - Windowlicker\nwindowlicker.flac\n#EXTINF:238,Burial - Archangel\narchangel.mp3\n"
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/import", strings.NewReader(body)), uuid.New())
+
+	rec := httptest.NewRecorder()
+	h.ImportPlaylistFile(rec, req)
+	var resp PlaylistFileImportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if resp.Format != libraryimport.PlaylistFormatM3U || resp.Total != 2 || resp.ExactMatches != 1 || resp.Unmatched != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	first, second := resp.Entries[0], resp.Entries[1]
+	if first.Position != 1 || first.Track == nil || first.Track.ID != 7 || first.Line != 2 {
+		t.Fatalf("first entry = %+v", first)
+	}
+	if second.Track != nil || len(second.Suggestions) != 1 || second.Suggestions[0].Track.Title != "Windowlicker" {
+		t.Fatalf("second entry = %+v", second)
+	}
+}
+
+func TestImportPlaylistFileReadsMultipartCSV(t *testing.T) {
+	matcher := &fakePlaylistFileMatcher{}
+	h := NewPlaylistFileImportHandlers(matcher)
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "road trip.csv")
+	part.Write([]byte("Track Name,Artist Name(s)\nWindowlicker,Aphex Twin\n"))
+	writer.Close()
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/import", &form), uuid.New())
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+	h.ImportPlaylistFile(rec, req)
+	if rec.Code != http.StatusOK || len(matcher.entries) != 1 || matcher.entries[0].Artist != "Aphex Twin" {
+		t.Fatalf("status = %d entries = %+v body = %s", rec.Code, matcher.entries, rec.Body.String())
+	}
+}
+
+func TestImportPlaylistFileRejectsBadInput(t *testing.T) {
+	h := NewPlaylistFileImportHandlers(&fakePlaylistFileMatcher{})
+	for _, tc := range []struct {
+		target, body string
+		want         int
+	}{
+		{"/api/v1/playlists/import?format=xspf", "x", http.StatusBadRequest},
+		{"/api/v1/playlists/import", "Artist,Album\nBurial,Untrue\n", http.StatusBadRequest},
+		{"/api/v1/playlists/import?format=m3u", strings.Repeat("a.mp3\n", libraryimport.MaxPlaylistFileEntries+1), http.StatusRequestEntityTooLarge},
+	} {
+		rec := httptest.NewRecorder()
+		h.ImportPlaylistFile(rec, withUser(httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body)), uuid.New()))
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tc.target, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,11 +14,15 @@ import (
 
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/pagination"
 )
 
 type PlaylistHandlers struct {
 	playlistRepo *db.PlaylistRepository
 	trackRepo    *db.TrackRepository
+	artwork      PlaylistArtwork
+	trackGrants  PlaylistTrackGrants
+	notifier     PlaylistNotifier
 }
 
 func NewPlaylistHandlers(playlistRepo *db.PlaylistRepository, trackRepo *db.TrackRepository) *PlaylistHandlers {
@@ -26,6 +32,32 @@ func NewPlaylistHandlers(playlistRepo *db.PlaylistRepository, trackRepo *db.Trac
 	}
 }
 
+// PlaylistTrackGrants revokes the track grants shared from a playlist.
+// db.TrackGrantRepository satisfies it.
+type PlaylistTrackGrants interface {
+	RevokeContext(ctx context.Context, contextType, contextID string, bearerOnly bool) (int64, error)
+}
+
+// SetTrackGrants revokes a playlist's shared track capabilities and public
+// links when it is made private (those without a grantee) or deleted (all of
+// them).
+func (h *PlaylistHandlers) SetTrackGrants(grants PlaylistTrackGrants) {
+	h.trackGrants = grants
+}
+
+// revokeTrackGrants logs rather than fails: the playlist change has already
+// been saved.
+func (h *PlaylistHandlers) revokeTrackGrants(ctx context.Context, playlistID int64, bearerOnly bool) {
+	if h.trackGrants == nil {
+		return
+	}
+	for _, contextType := range []string{db.TrackGrantContextPlaylist, db.TrackGrantContextPlaylistLink} {
+		if _, err := h.trackGrants.RevokeContext(ctx, contextType, strconv.FormatInt(playlistID, 10), bearerOnly); err != nil {
+			log.Printf("Warning: failed to revoke %s grants for playlist %d: %v", contextType, playlistID, err)
+		}
+	}
+}
+
 // Request/Response types
 
 type CreatePlaylistRequest struct {
@@ -66,7 +98,9 @@ type PlaylistResponse struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description,omitempty"`
 	CoverURL    string    `json:"coverUrl,omitempty"`
+	ArtworkURL  string    `json:"artworkUrl,omitempty"`
 	IsPublic    bool      `json:"isPublic"`
+	SystemKind  string    `json:"systemKind,omitempty"`
 	TrackCount  int       `json:"trackCount"`
 	DurationMs  int64     `json:"durationMs"`
 	CreatedAt   time.Time `json:"createdAt"`
@@ -78,7 +112,9 @@ type PlaylistWithTracksResponse struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description,omitempty"`
 	CoverURL    string          `json:"coverUrl,omitempty"`
+	ArtworkURL  string          `json:"artworkUrl,omitempty"`
 	IsPublic    bool            `json:"isPublic"`
+	SystemKind  string          `json:"systemKind,omitempty"`
 	TrackCount  int             `json:"trackCount"`
 	DurationMs  int64           `json:"durationMs"`
 	CreatedAt   time.Time       `json:"createdAt"`
@@ -87,23 +123,24 @@ type PlaylistWithTracksResponse struct {
 }
 
 type TrackResponse struct {
-	ID                int64           `json:"id"`
-	Title             string          `json:"title"`
-	Artist            string          `json:"artist,omitempty"`
-	Album             string          `json:"album,omitempty"`
-	DurationMs        int             `json:"durationMs,omitempty"`
-	FileSizeBytes     int64           `json:"fileSizeBytes,omitempty"`
-	Codec             string          `json:"codec,omitempty"`
-	BitrateKbps       int             `json:"bitrateKbps,omitempty"`
-	SampleRateHz      int             `json:"sampleRateHz,omitempty"`
-	Channels          int             `json:"channels,omitempty"`
-	ContentType       string          `json:"contentType,omitempty"`
-	MBRecordingID     *uuid.UUID      `json:"mbRecordingId,omitempty"`
-	MBReleaseID       *uuid.UUID      `json:"mbReleaseId,omitempty"`
-	MBArtistID        *uuid.UUID      `json:"mbArtistId,omitempty"`
-	AnalysisStatus    string          `json:"analysisStatus,omitempty"`
-	AnalysisSummary   json.RawMessage `json:"analysisSummary,omitempty"`
-	AnalysisUpdatedAt string          `json:"analysisUpdatedAt,omitempty"`
+	ID                int64               `json:"id"`
+	Title             string              `json:"title"`
+	Artist            string              `json:"artist,omitempty"`
+	Album             string              `json:"album,omitempty"`
+	DurationMs        int                 `json:"durationMs,omitempty"`
+	FileSizeBytes     int64               `json:"fileSizeBytes,omitempty"`
+	Codec             string              `json:"codec,omitempty"`
+	BitrateKbps       int                 `json:"bitrateKbps,omitempty"`
+	SampleRateHz      int                 `json:"sampleRateHz,omitempty"`
+	Channels          int                 `json:"channels,omitempty"`
+	ContentType       string              `json:"contentType,omitempty"`
+	MBRecordingID     *uuid.UUID          `json:"mbRecordingId,omitempty"`
+	MBReleaseID       *uuid.UUID          `json:"mbReleaseId,omitempty"`
+	MBArtistID        *uuid.UUID          `json:"mbArtistId,omitempty"`
+	AnalysisStatus    string              `json:"analysisStatus,omitempty"`
+	AnalysisSummary   json.RawMessage     `json:"analysisSummary,omitempty"`
+	AnalysisUpdatedAt string              `json:"analysisUpdatedAt,omitempty"`
+	Links             []TrackLinkResponse `json:"links,omitempty"`
 }
 
 type PaginatedPlaylistResponse struct {
@@ -115,13 +152,43 @@ type PaginatedPlaylistResponse struct {
 
 // ListPlaylists handles GET /api/v1/playlists
 func (h *PlaylistHandlers) ListPlaylists(w http.ResponseWriter, r *http.Request) {
-	userCtx := auth.GetUserFromContext(r.Context())
-	if userCtx == nil {
-		writePlaylistError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+	limit, offset := parsePlaylistPagination(r)
+	responses, total, ok := h.listPlaylists(w, r, limit, offset)
+	if !ok {
 		return
 	}
 
+	writePlaylistJSON(w, http.StatusOK, PaginatedPlaylistResponse{
+		Data:   responses,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// ListPlaylistsPage handles GET /api/v2/playlists.
+func (h *PlaylistHandlers) ListPlaylistsPage(w http.ResponseWriter, r *http.Request) {
 	limit, offset := parsePlaylistPagination(r)
+	offset, err := pagination.Offset(r, offset)
+	if err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid or expired")
+		return
+	}
+	responses, total, ok := h.listPlaylists(w, r, limit, offset)
+	if !ok {
+		return
+	}
+	pagination.Write(w, responses, limit, offset, &total)
+}
+
+// listPlaylists loads a page of the caller's playlists, writing the error
+// itself when it cannot.
+func (h *PlaylistHandlers) listPlaylists(w http.ResponseWriter, r *http.Request, limit, offset int) ([]PlaylistResponse, int, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return nil, 0, false
+	}
 
 	params := db.ListPlaylistsParams{
 		Query:  r.URL.Query().Get("q"),
@@ -134,20 +201,14 @@ func (h *PlaylistHandlers) ListPlaylists(w http.ResponseWriter, r *http.Request)
 	playlists, total, err := h.playlistRepo.GetByUserID(r.Context(), userCtx.UserID, params)
 	if err != nil {
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list playlists")
-		return
+		return nil, 0, false
 	}
 
 	responses := make([]PlaylistResponse, 0, len(playlists))
 	for _, p := range playlists {
-		responses = append(responses, newPlaylistResponse(p.Playlist, p.TrackCount, p.DurationMs))
+		responses = append(responses, h.newPlaylistResponse(r.Context(), p.Playlist, p.TrackCount, p.DurationMs))
 	}
-
-	writePlaylistJSON(w, http.StatusOK, PaginatedPlaylistResponse{
-		Data:   responses,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	})
+	return responses, total, true
 }
 
 // CreatePlaylist handles POST /api/v1/playlists
@@ -182,7 +243,7 @@ func (h *PlaylistHandlers) CreatePlaylist(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	writePlaylistJSON(w, http.StatusCreated, newPlaylistResponse(*playlist, 0, 0))
+	writePlaylistJSON(w, http.StatusCreated, h.newPlaylistResponse(r.Context(), *playlist, 0, 0))
 }
 
 // GetPlaylist handles GET /api/v1/playlists/{id}
@@ -209,13 +270,21 @@ func (h *PlaylistHandlers) GetPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership
-	if playlist.UserID != userCtx.UserID {
+	// Owners and invited collaborators may read the playlist.
+	member, err := h.isPlaylistMember(r.Context(), &playlist.Playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	if !member {
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to access this playlist")
 		return
 	}
+	if playlist.TrackCount > 0 && !playlist.MosaicKey.Valid {
+		h.refreshMosaic(playlistID)
+	}
 
-	writePlaylistJSON(w, http.StatusOK, newPlaylistWithTracksResponse(playlist, mapTrackResponses(playlist.Tracks)))
+	writePlaylistJSON(w, http.StatusOK, h.newPlaylistWithTracksResponse(r.Context(), playlist, mapTrackResponses(playlist.Tracks)))
 }
 
 // UpdatePlaylist handles PUT /api/v1/playlists/{id}
@@ -247,6 +316,9 @@ func (h *PlaylistHandlers) UpdatePlaylist(w http.ResponseWriter, r *http.Request
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to modify this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
 	var req UpdatePlaylistRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -259,6 +331,7 @@ func (h *PlaylistHandlers) UpdatePlaylist(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	wasPublic := playlist.IsPublic
 	playlist.Name = req.Name
 	playlist.Description = sql.NullString{String: req.Description, Valid: req.Description != ""}
 	playlist.CoverURL = sql.NullString{String: req.CoverURL, Valid: req.CoverURL != ""}
@@ -268,6 +341,9 @@ func (h *PlaylistHandlers) UpdatePlaylist(w http.ResponseWriter, r *http.Request
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update playlist")
 		return
 	}
+	if wasPublic && !playlist.IsPublic {
+		h.revokeTrackGrants(r.Context(), playlistID, true)
+	}
 
 	// Get updated playlist with track count
 	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlistID)
@@ -276,7 +352,7 @@ func (h *PlaylistHandlers) UpdatePlaylist(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	writePlaylistJSON(w, http.StatusOK, newPlaylistResponse(updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs))
+	writePlaylistJSON(w, http.StatusOK, h.newPlaylistResponse(r.Context(), updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs))
 }
 
 // DeletePlaylist handles DELETE /api/v1/playlists/{id}
@@ -308,11 +384,18 @@ func (h *PlaylistHandlers) DeletePlaylist(w http.ResponseWriter, r *http.Request
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to delete this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
 	if err := h.playlistRepo.Delete(r.Context(), playlistID); err != nil {
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete playlist")
 		return
 	}
+	h.revokeTrackGrants(r.Context(), playlistID, false)
+	if h.artwork != nil {
+		h.artwork.DeleteObjects(r.Context(), playlist.ArtworkKey.String, playlist.MosaicKey.String)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -331,7 +414,7 @@ func (h *PlaylistHandlers) AddTracks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership
+	// Owners and invited collaborators may edit tracks.
 	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
 	if err != nil {
 		if errors.Is(err, db.ErrPlaylistNotFound) {
@@ -342,10 +425,18 @@ func (h *PlaylistHandlers) AddTracks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if playlist.UserID != userCtx.UserID {
+	member, err := h.isPlaylistMember(r.Context(), playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	if !member {
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to modify this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
 	var req AddTracksRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -358,24 +449,30 @@ func (h *PlaylistHandlers) AddTracks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify tracks exist
+	// Verify tracks exist, in one query however many there are
+	found, err := h.trackRepo.GetByIDs(r.Context(), req.TrackIDs)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify track")
+		return
+	}
 	for _, trackID := range req.TrackIDs {
-		_, err := h.trackRepo.GetByID(r.Context(), trackID)
-		if err != nil {
-			if errors.Is(err, db.ErrTrackNotFound) {
-				writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "track not found: "+strconv.FormatInt(trackID, 10))
-				return
-			}
-			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify track")
+		if found[trackID] == nil {
+			writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "track not found: "+strconv.FormatInt(trackID, 10))
 			return
 		}
 	}
 
+	h.ensureHistoryBaseline(r.Context(), playlistID)
 	report, err := h.playlistRepo.AddTracks(r.Context(), playlistID, req.TrackIDs)
 	if err != nil {
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add tracks")
 		return
 	}
+	if len(report.Added) > 0 {
+		h.recordTrackChange(r.Context(), playlistID, userCtx.UserID, db.PlaylistActivityTracksAdded, map[string]interface{}{
+			"trackIds": report.Added,
+		})
+	}
 
 	// Return updated playlist alongside the added/skipped report
 	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlistID)
@@ -387,7 +484,7 @@ func (h *PlaylistHandlers) AddTracks(w http.ResponseWriter, r *http.Request) {
 	writePlaylistJSON(w, http.StatusOK, AddTracksResponse{
 		Added:    report.Added,
 		Skipped:  report.Skipped,
-		Playlist: newPlaylistResponse(updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs),
+		Playlist: h.newPlaylistResponse(r.Context(), updatedPlaylist.Playlist, updatedPlaylist.TrackCount, updatedPlaylist.DurationMs),
 	})
 }
 
@@ -405,7 +502,7 @@ func (h *PlaylistHandlers) BatchRemoveTracks(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Check ownership
+	// Owners and invited collaborators may edit tracks.
 	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
 	if err != nil {
 		if errors.Is(err, db.ErrPlaylistNotFound) {
@@ -416,10 +513,18 @@ func (h *PlaylistHandlers) BatchRemoveTracks(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if playlist.UserID != userCtx.UserID {
+	member, err := h.isPlaylistMember(r.Context(), playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	if !member {
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to modify this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
 	var req BatchRemoveTracksRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -432,10 +537,14 @@ func (h *PlaylistHandlers) BatchRemoveTracks(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.ensureHistoryBaseline(r.Context(), playlistID)
 	if err := h.playlistRepo.RemoveTracks(r.Context(), playlistID, req.TrackIDs); err != nil {
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove tracks")
 		return
 	}
+	h.recordTrackChange(r.Context(), playlistID, userCtx.UserID, db.PlaylistActivityTracksRemoved, map[string]interface{}{
+		"trackIds": req.TrackIDs,
+	})
 
 	// Return updated playlist with tracks
 	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlistID)
@@ -444,7 +553,7 @@ func (h *PlaylistHandlers) BatchRemoveTracks(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	writePlaylistJSON(w, http.StatusOK, newPlaylistWithTracksResponse(updatedPlaylist, mapTrackResponses(updatedPlaylist.Tracks)))
+	writePlaylistJSON(w, http.StatusOK, h.newPlaylistWithTracksResponse(r.Context(), updatedPlaylist, mapTrackResponses(updatedPlaylist.Tracks)))
 }
 
 // RemoveTrack handles DELETE /api/v1/playlists/{id}/tracks/{trackId}
@@ -467,7 +576,7 @@ func (h *PlaylistHandlers) RemoveTrack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership
+	// Owners and invited collaborators may edit tracks.
 	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
 	if err != nil {
 		if errors.Is(err, db.ErrPlaylistNotFound) {
@@ -478,11 +587,20 @@ func (h *PlaylistHandlers) RemoveTrack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if playlist.UserID != userCtx.UserID {
+	member, err := h.isPlaylistMember(r.Context(), playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	if !member {
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to modify this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
+	h.ensureHistoryBaseline(r.Context(), playlistID)
 	if err := h.playlistRepo.RemoveTrack(r.Context(), playlistID, trackID); err != nil {
 		if errors.Is(err, db.ErrTrackNotInPlaylist) {
 			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "track not in playlist")
@@ -491,6 +609,9 @@ func (h *PlaylistHandlers) RemoveTrack(w http.ResponseWriter, r *http.Request) {
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove track")
 		return
 	}
+	h.recordTrackChange(r.Context(), playlistID, userCtx.UserID, db.PlaylistActivityTracksRemoved, map[string]interface{}{
+		"trackIds": []int64{trackID},
+	})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -509,7 +630,7 @@ func (h *PlaylistHandlers) ReorderTracks(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check ownership
+	// Owners and invited collaborators may edit tracks.
 	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
 	if err != nil {
 		if errors.Is(err, db.ErrPlaylistNotFound) {
@@ -520,10 +641,18 @@ func (h *PlaylistHandlers) ReorderTracks(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if playlist.UserID != userCtx.UserID {
+	member, err := h.isPlaylistMember(r.Context(), playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return
+	}
+	if !member {
 		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to modify this playlist")
 		return
 	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
 
 	var req ReorderTrackRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -541,6 +670,7 @@ func (h *PlaylistHandlers) ReorderTracks(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	h.ensureHistoryBaseline(r.Context(), playlistID)
 	if err := h.playlistRepo.ReorderTrack(r.Context(), playlistID, req.TrackID, req.NewPosition); err != nil {
 		if errors.Is(err, db.ErrTrackNotInPlaylist) {
 			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "track not in playlist")
@@ -549,6 +679,10 @@ func (h *PlaylistHandlers) ReorderTracks(w http.ResponseWriter, r *http.Request)
 		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to reorder track")
 		return
 	}
+	h.recordTrackChange(r.Context(), playlistID, userCtx.UserID, db.PlaylistActivityTrackMoved, map[string]interface{}{
+		"trackId":     req.TrackID,
+		"newPosition": req.NewPosition,
+	})
 
 	// Return updated playlist with tracks
 	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlistID)
@@ -557,20 +691,62 @@ func (h *PlaylistHandlers) ReorderTracks(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	writePlaylistJSON(w, http.StatusOK, newPlaylistWithTracksResponse(updatedPlaylist, mapTrackResponses(updatedPlaylist.Tracks)))
+	writePlaylistJSON(w, http.StatusOK, h.newPlaylistWithTracksResponse(r.Context(), updatedPlaylist, mapTrackResponses(updatedPlaylist.Tracks)))
 }
 
 // Helper functions
 
+// isPlaylistMember reports whether userID owns the playlist or has been invited
+// to collaborate on it.
+func (h *PlaylistHandlers) isPlaylistMember(ctx context.Context, playlist *db.Playlist, userID uuid.UUID) (bool, error) {
+	if playlist.UserID == userID {
+		return true, nil
+	}
+	return h.playlistRepo.IsCollaborator(ctx, playlist.ID, userID)
+}
+
+// ensureHistoryBaseline snapshots a playlist that has no history yet, before a
+// track mutation, so the first change is revertible too.
+func (h *PlaylistHandlers) ensureHistoryBaseline(ctx context.Context, playlistID int64) {
+	if err := h.playlistRepo.EnsureHistoryBaseline(ctx, playlistID); err != nil {
+		log.Printf("Warning: failed to record playlist %d history baseline: %v", playlistID, err)
+	}
+}
+
+// recordTrackChange snapshots the playlist into its version history, appends
+// to the collaborative activity feed, and schedules a mosaic refresh. All are
+// best-effort, so a failed write never fails the mutation it describes.
+func (h *PlaylistHandlers) recordTrackChange(ctx context.Context, playlistID int64, actorID uuid.UUID, kind string, payload map[string]interface{}) {
+	if _, err := h.playlistRepo.RecordSnapshot(ctx, playlistID, actorID, kind); err != nil {
+		log.Printf("Warning: failed to record playlist %d version for %s: %v", playlistID, kind, err)
+	}
+	if err := h.playlistRepo.RecordActivity(ctx, playlistID, actorID, kind, payload); err != nil {
+		log.Printf("Warning: failed to record playlist %d activity %s: %v", playlistID, kind, err)
+	}
+	h.refreshMosaic(playlistID)
+}
+
+// rejectSystemPlaylist writes a 403 and returns true when the playlist was
+// generated by the server (e.g. a Daily Mix). Those can be played and
+// duplicated but not edited.
+func rejectSystemPlaylist(w http.ResponseWriter, playlist *db.Playlist) bool {
+	if !playlist.SystemKind.Valid {
+		return false
+	}
+	writePlaylistError(w, http.StatusForbidden, "READ_ONLY_PLAYLIST", "generated playlists cannot be edited")
+	return true
+}
+
 // newPlaylistResponse builds a PlaylistResponse from a base playlist plus its
 // aggregate track count and duration.
-func newPlaylistResponse(p db.Playlist, trackCount int, durationMs int64) PlaylistResponse {
+func (h *PlaylistHandlers) newPlaylistResponse(ctx context.Context, p db.Playlist, trackCount int, durationMs int64) PlaylistResponse {
 	resp := PlaylistResponse{
 		ID:         p.ID,
 		Name:       p.Name,
 		IsPublic:   p.IsPublic,
 		TrackCount: trackCount,
 		DurationMs: durationMs,
+		ArtworkURL: h.artworkURL(ctx, p),
 		CreatedAt:  p.CreatedAt,
 		UpdatedAt:  p.UpdatedAt,
 	}
@@ -580,18 +756,22 @@ func newPlaylistResponse(p db.Playlist, trackCount int, durationMs int64) Playli
 	if p.CoverURL.Valid {
 		resp.CoverURL = p.CoverURL.String
 	}
+	if p.SystemKind.Valid {
+		resp.SystemKind = p.SystemKind.String
+	}
 	return resp
 }
 
 // newPlaylistWithTracksResponse builds a PlaylistWithTracksResponse from a
 // playlist and its already-mapped track responses.
-func newPlaylistWithTracksResponse(p *db.PlaylistWithTracks, tracks []TrackResponse) PlaylistWithTracksResponse {
+func (h *PlaylistHandlers) newPlaylistWithTracksResponse(ctx context.Context, p *db.PlaylistWithTracks, tracks []TrackResponse) PlaylistWithTracksResponse {
 	resp := PlaylistWithTracksResponse{
 		ID:         p.ID,
 		Name:       p.Name,
 		IsPublic:   p.IsPublic,
 		TrackCount: p.TrackCount,
 		DurationMs: p.DurationMs,
+		ArtworkURL: h.artworkURL(ctx, p.Playlist),
 		CreatedAt:  p.CreatedAt,
 		UpdatedAt:  p.UpdatedAt,
 		Tracks:     tracks,
@@ -602,6 +782,9 @@ func newPlaylistWithTracksResponse(p *db.PlaylistWithTracks, tracks []TrackRespo
 	if p.CoverURL.Valid {
 		resp.CoverURL = p.CoverURL.String
 	}
+	if p.SystemKind.Valid {
+		resp.SystemKind = p.SystemKind.String
+	}
 	return resp
 }
 
@@ -615,6 +798,7 @@ func mapTrackResponses(in []db.Track) []TrackResponse {
 			MBRecordingID: t.MBRecordingID,
 			MBReleaseID:   t.MBReleaseID,
 			MBArtistID:    t.MBArtistID,
+			Links:         trackLinks(&t),
 		}
 		if t.Artist.Valid {
 			track.Artist = t.Artist.String
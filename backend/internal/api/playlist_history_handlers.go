@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type PlaylistVersionResponse struct {
+	Version      int       `json:"version"`
+	Reason       string    `json:"reason"`
+	Actor        string    `json:"actor,omitempty"`
+	TrackCount   int       `json:"trackCount"`
+	RevertedFrom *int      `json:"revertedFrom,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type PlaylistHistoryResponse struct {
+	Versions []PlaylistVersionResponse `json:"versions"`
+	Limit    int                       `json:"limit"`
+	Offset   int                       `json:"offset"`
+}
+
+type RevertPlaylistResponse struct {
+	Version       PlaylistVersionResponse    `json:"version"`
+	MissingTracks int                        `json:"missingTracks"`
+	Playlist      PlaylistWithTracksResponse `json:"playlist"`
+}
+
+// GetHistory handles GET /api/v1/playlists/{id}/history
+func (h *PlaylistHandlers) GetHistory(w http.ResponseWriter, r *http.Request) {
+	playlist, _, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 50)
+	offset := parseIntParam(r, "offset", 0)
+	versions, err := h.playlistRepo.ListVersions(r.Context(), playlist.ID, limit, offset)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load playlist history")
+		return
+	}
+
+	resp := PlaylistHistoryResponse{
+		Versions: make([]PlaylistVersionResponse, 0, len(versions)),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, newPlaylistVersionResponse(v))
+	}
+	writePlaylistJSON(w, http.StatusOK, resp)
+}
+
+// RevertPlaylist handles POST /api/v1/playlists/{id}/revert/{version}. The
+// revert is recorded as a new version, so it can be undone the same way.
+func (h *PlaylistHandlers) RevertPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.authorizePlaylistMember(w, r)
+	if !ok {
+		return
+	}
+	if rejectSystemPlaylist(w, playlist) {
+		return
+	}
+
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version <= 0 {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid version")
+		return
+	}
+
+	restored, missing, err := h.playlistRepo.RevertToVersion(r.Context(), playlist.ID, version, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrPlaylistVersionNotFound):
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist version not found")
+		case errors.Is(err, db.ErrPlaylistNotFound):
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+		default:
+			writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revert playlist")
+		}
+		return
+	}
+
+	h.refreshMosaic(playlist.ID)
+
+	updatedPlaylist, err := h.playlistRepo.GetByIDWithTracks(r.Context(), playlist.ID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get updated playlist")
+		return
+	}
+
+	writePlaylistJSON(w, http.StatusOK, RevertPlaylistResponse{
+		Version:       newPlaylistVersionResponse(*restored),
+		MissingTracks: missing,
+		Playlist:      h.newPlaylistWithTracksResponse(r.Context(), updatedPlaylist, mapTrackResponses(updatedPlaylist.Tracks)),
+	})
+}
+
+// authorizePlaylistMember loads the playlist named in the path and checks that
+// the caller owns it or collaborates on it, writing the error response itself.
+func (h *PlaylistHandlers) authorizePlaylistMember(w http.ResponseWriter, r *http.Request) (*db.Playlist, uuid.UUID, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return nil, uuid.Nil, false
+	}
+
+	playlistID, err := parsePlaylistID(r)
+	if err != nil {
+		writePlaylistError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid playlist ID")
+		return nil, uuid.Nil, false
+	}
+
+	playlist, err := h.playlistRepo.GetByID(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return nil, uuid.Nil, false
+		}
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return nil, uuid.Nil, false
+	}
+
+	member, err := h.isPlaylistMember(r.Context(), playlist, userCtx.UserID)
+	if err != nil {
+		writePlaylistError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return nil, uuid.Nil, false
+	}
+	if !member {
+		writePlaylistError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to access this playlist")
+		return nil, uuid.Nil, false
+	}
+
+	return playlist, userCtx.UserID, true
+}
+
+func newPlaylistVersionResponse(v db.PlaylistVersion) PlaylistVersionResponse {
+	resp := PlaylistVersionResponse{
+		Version:    v.Version,
+		Reason:     v.Reason,
+		TrackCount: v.TrackCount,
+		CreatedAt:  v.CreatedAt,
+	}
+	if v.ActorName.Valid {
+		resp.Actor = v.ActorName.String
+	}
+	if v.RevertedFrom.Valid {
+		from := int(v.RevertedFrom.Int32)
+		resp.RevertedFrom = &from
+	}
+	return resp
+}
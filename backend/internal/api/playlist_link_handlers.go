@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/capability"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const defaultPlaylistLinkTTL = 30 * 24 * time.Hour
+
+type playlistLinkStore interface {
+	Create(ctx context.Context, grant *db.TrackGrant) error
+	Get(ctx context.Context, id uuid.UUID) (*db.TrackGrant, error)
+	RevokeContext(ctx context.Context, contextType, contextID string, bearerOnly bool) (int64, error)
+}
+
+type playlistLinkPlaylists interface {
+	GetByIDWithTracks(ctx context.Context, id int64) (*db.PlaylistWithTracks, error)
+}
+
+// PlaylistLinkHandlers manages read-only public links to playlists. A link is
+// a signed token for a playlist link grant; anyone holding it can read the
+// playlist and stream its tracks until the link expires, is replaced, or the
+// playlist stops being public.
+type PlaylistLinkHandlers struct {
+	links     playlistLinkStore
+	playlists playlistLinkPlaylists
+	signer    *capability.Signer
+	artwork   PlaylistArtwork
+	now       func() time.Time
+}
+
+func NewPlaylistLinkHandlers(links playlistLinkStore, playlists playlistLinkPlaylists, signer *capability.Signer) *PlaylistLinkHandlers {
+	return &PlaylistLinkHandlers{links: links, playlists: playlists, signer: signer, now: time.Now}
+}
+
+// SetArtwork lets public playlist responses carry uploaded covers and
+// mosaics, not just the user-supplied coverUrl.
+func (h *PlaylistLinkHandlers) SetArtwork(a PlaylistArtwork) {
+	h.artwork = a
+}
+
+// CreatePlaylistLinkRequest is optional; without it the link lasts 30 days.
+type CreatePlaylistLinkRequest struct {
+	ExpiresInSeconds int `json:"expiresInSeconds,omitempty"`
+}
+
+type PlaylistLinkResponse struct {
+	// Token is a bearer credential for the playlist; Path is where it is read.
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	GrantID   string    `json:"grantId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type PublicPlaylistTrack struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist,omitempty"`
+	Album      string `json:"album,omitempty"`
+	DurationMs int    `json:"durationMs,omitempty"`
+	// Capability is passed to POST /api/v1/public/playback/urls to stream
+	// the track.
+	Capability string `json:"capability"`
+}
+
+type PublicPlaylistResponse struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	ArtworkURL  string                `json:"artworkUrl,omitempty"`
+	TrackCount  int                   `json:"trackCount"`
+	DurationMs  int64                 `json:"durationMs"`
+	UpdatedAt   time.Time             `json:"updatedAt"`
+	ExpiresAt   time.Time             `json:"expiresAt"`
+	Tracks      []PublicPlaylistTrack `json:"tracks"`
+}
+
+// CreateLink handles POST /api/v1/playlists/{id}/public-link. Only the owner
+// of a public playlist can create a link; creating one replaces the previous
+// link.
+func (h *PlaylistLinkHandlers) CreateLink(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistLinkError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	playlist, ok := h.ownedPlaylist(w, r, userCtx.UserID)
+	if !ok {
+		return
+	}
+	if !playlist.IsPublic {
+		writePlaylistLinkError(w, http.StatusForbidden, "PLAYLIST_NOT_PUBLIC", "make the playlist public before sharing a link")
+		return
+	}
+
+	var req CreatePlaylistLinkRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writePlaylistLinkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	ttl := defaultPlaylistLinkTTL
+	if req.ExpiresInSeconds != 0 {
+		ttl = time.Duration(req.ExpiresInSeconds) * time.Second
+		if ttl < minTrackGrantTTL || ttl > maxTrackGrantTTL {
+			writePlaylistLinkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "expiresInSeconds must be between 60 and 7776000")
+			return
+		}
+	}
+
+	contextID := strconv.FormatInt(playlist.ID, 10)
+	if _, err := h.links.RevokeContext(r.Context(), db.TrackGrantContextPlaylistLink, contextID, true); err != nil {
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to replace existing link")
+		return
+	}
+	grant := &db.TrackGrant{
+		ID:          uuid.New(),
+		OwnerID:     userCtx.UserID,
+		ContextType: db.TrackGrantContextPlaylistLink,
+		ContextID:   contextID,
+		ExpiresAt:   h.now().Add(ttl).UTC().Truncate(time.Second),
+	}
+	if err := h.links.Create(r.Context(), grant); err != nil {
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create link")
+		return
+	}
+
+	token := h.signer.SignLink(capability.LinkClaims{GrantID: grant.ID, ExpiresAt: grant.ExpiresAt})
+	writePlaylistLinkJSON(w, http.StatusCreated, PlaylistLinkResponse{
+		Token:     token,
+		Path:      "/api/v1/public/playlists/" + token,
+		GrantID:   grant.ID.String(),
+		ExpiresAt: grant.ExpiresAt,
+	})
+}
+
+// RevokeLink handles DELETE /api/v1/playlists/{id}/public-link
+func (h *PlaylistLinkHandlers) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaylistLinkError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	playlist, ok := h.ownedPlaylist(w, r, userCtx.UserID)
+	if !ok {
+		return
+	}
+	n, err := h.links.RevokeContext(r.Context(), db.TrackGrantContextPlaylistLink, strconv.FormatInt(playlist.ID, 10), true)
+	if err != nil {
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke link")
+		return
+	}
+	if n == 0 {
+		writePlaylistLinkError(w, http.StatusNotFound, "NOT_FOUND", "playlist has no public link")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPublicPlaylist handles GET /api/v1/public/playlists/{token}. Every way a
+// link can be unusable reads as not found.
+func (h *PlaylistLinkHandlers) GetPublicPlaylist(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.signer.ParseLink(r.PathValue("token"), h.now())
+	if err != nil {
+		writePlaylistLinkError(w, http.StatusNotFound, "LINK_NOT_FOUND", "link is invalid or has expired")
+		return
+	}
+	grant, err := h.links.Get(r.Context(), claims.GrantID)
+	if err != nil && !errors.Is(err, db.ErrTrackGrantNotFound) {
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load link")
+		return
+	}
+	if grant == nil || !grant.Active(h.now()) || grant.ContextType != db.TrackGrantContextPlaylistLink {
+		writePlaylistLinkError(w, http.StatusNotFound, "LINK_NOT_FOUND", "link is invalid or has expired")
+		return
+	}
+	playlistID, err := strconv.ParseInt(grant.ContextID, 10, 64)
+	if err != nil {
+		writePlaylistLinkError(w, http.StatusNotFound, "LINK_NOT_FOUND", "link is invalid or has expired")
+		return
+	}
+	playlist, err := h.playlists.GetByIDWithTracks(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistLinkError(w, http.StatusNotFound, "LINK_NOT_FOUND", "link is invalid or has expired")
+			return
+		}
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load playlist")
+		return
+	}
+	if !playlist.IsPublic {
+		writePlaylistLinkError(w, http.StatusNotFound, "LINK_NOT_FOUND", "link is invalid or has expired")
+		return
+	}
+
+	resp := PublicPlaylistResponse{
+		Name:       playlist.Name,
+		ArtworkURL: playlistArtworkURL(r.Context(), h.artwork, playlist.Playlist),
+		TrackCount: playlist.TrackCount,
+		DurationMs: playlist.DurationMs,
+		UpdatedAt:  playlist.UpdatedAt,
+		ExpiresAt:  grant.ExpiresAt,
+		Tracks:     make([]PublicPlaylistTrack, 0, len(playlist.Tracks)),
+	}
+	if playlist.Description.Valid {
+		resp.Description = playlist.Description.String
+	}
+	for _, t := range playlist.Tracks {
+		track := PublicPlaylistTrack{
+			ID:         t.ID,
+			Title:      t.Title,
+			Capability: h.signer.Sign(capability.Claims{GrantID: grant.ID, TrackID: t.ID, ExpiresAt: grant.ExpiresAt}),
+		}
+		if t.Artist.Valid {
+			track.Artist = t.Artist.String
+		}
+		if t.Album.Valid {
+			track.Album = t.Album.String
+		}
+		if t.DurationMs.Valid {
+			track.DurationMs = int(t.DurationMs.Int32)
+		}
+		resp.Tracks = append(resp.Tracks, track)
+	}
+	writePlaylistLinkJSON(w, http.StatusOK, resp)
+}
+
+// ownedPlaylist loads the {id} playlist for its owner, writing the error
+// response when it returns false.
+func (h *PlaylistLinkHandlers) ownedPlaylist(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*db.PlaylistWithTracks, bool) {
+	playlistID, err := parsePlaylistID(r)
+	if err != nil {
+		writePlaylistLinkError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid playlist ID")
+		return nil, false
+	}
+	playlist, err := h.playlists.GetByIDWithTracks(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writePlaylistLinkError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return nil, false
+		}
+		writePlaylistLinkError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get playlist")
+		return nil, false
+	}
+	if playlist.UserID != userID {
+		writePlaylistLinkError(w, http.StatusForbidden, "FORBIDDEN", "not authorized to share this playlist")
+		return nil, false
+	}
+	if playlist.SystemKind.Valid {
+		writePlaylistLinkError(w, http.StatusForbidden, "READ_ONLY_PLAYLIST", "generated playlists cannot be shared")
+		return nil, false
+	}
+	return playlist, true
+}
+
+func writePlaylistLinkJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writePlaylistLinkError(w http.ResponseWriter, status int, code, message string) {
+	writePlaylistLinkJSON(w, status, ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
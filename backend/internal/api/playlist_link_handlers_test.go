@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/capability"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakePlaylistLinks struct {
+	grants map[uuid.UUID]*db.TrackGrant
+}
+
+func (f *fakePlaylistLinks) Create(_ context.Context, grant *db.TrackGrant) error {
+	grant.CreatedAt = time.Now()
+	f.grants[grant.ID] = grant
+	return nil
+}
+
+func (f *fakePlaylistLinks) Get(_ context.Context, id uuid.UUID) (*db.TrackGrant, error) {
+	if g, ok := f.grants[id]; ok {
+		return g, nil
+	}
+	return nil, db.ErrTrackGrantNotFound
+}
+
+func (f *fakePlaylistLinks) RevokeContext(_ context.Context, contextType, contextID string, bearerOnly bool) (int64, error) {
+	var n int64
+	for _, g := range f.grants {
+		if g.ContextType == contextType && g.ContextID == contextID && !g.RevokedAt.Valid && (!bearerOnly || !g.GranteeID.Valid) {
+			g.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newPlaylistLinkTestHandlers(owner uuid.UUID) (*PlaylistLinkHandlers, fakeGrantPlaylists) {
+	playlists := fakeGrantPlaylists{
+		7: {Playlist: db.Playlist{ID: 7, UserID: owner, Name: "Road trip", IsPublic: true}, TrackCount: 2, Tracks: []db.Track{
+			{ID: 1, Title: "One", Artist: sql.NullString{String: "A", Valid: true}},
+			{ID: 2, Title: "Two"},
+		}},
+		8: {Playlist: db.Playlist{ID: 8, UserID: owner, Name: "Diary"}},
+	}
+	links := &fakePlaylistLinks{grants: map[uuid.UUID]*db.TrackGrant{}}
+	return NewPlaylistLinkHandlers(links, playlists, capability.NewSigner("test-secret")), playlists
+}
+
+func createPlaylistLink(h *PlaylistLinkHandlers, userID uuid.UUID, playlistID string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/playlists/"+playlistID+"/public-link", nil), userID)
+	req.SetPathValue("id", playlistID)
+	rec := httptest.NewRecorder()
+	h.CreateLink(rec, req)
+	return rec
+}
+
+func getPublicPlaylist(h *PlaylistLinkHandlers, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/public/playlists/"+token, nil)
+	req.SetPathValue("token", token)
+	rec := httptest.NewRecorder()
+	h.GetPublicPlaylist(rec, req)
+	return rec
+}
+
+func TestPublicPlaylistLinkServesTracksWithCapabilities(t *testing.T) {
+	owner := uuid.New()
+	h, playlists := newPlaylistLinkTestHandlers(owner)
+
+	rec := createPlaylistLink(h, owner, "7")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var link PlaylistLinkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = getPublicPlaylist(h, link.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public GET status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp PublicPlaylistResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "Road trip" || len(resp.Tracks) != 2 || resp.Tracks[0].Artist != "A" {
+		t.Fatalf("public playlist = %+v", resp)
+	}
+	claims, err := capability.NewSigner("test-secret").Parse(resp.Tracks[1].Capability, time.Now())
+	if err != nil || claims.TrackID != 2 || claims.GrantID.String() != link.GrantID {
+		t.Fatalf("track capability claims = %+v, %v", claims, err)
+	}
+
+	// Making the playlist private hides it even before grants are revoked.
+	playlists[7].IsPublic = false
+	if rec := getPublicPlaylist(h, link.Token); rec.Code != http.StatusNotFound {
+		t.Fatalf("private playlist via link = %d, want 404", rec.Code)
+	}
+	playlists[7].IsPublic = true
+
+	if rec := createPlaylistLink(h, owner, "7"); rec.Code != http.StatusCreated {
+		t.Fatalf("second create status = %d", rec.Code)
+	}
+	if rec := getPublicPlaylist(h, link.Token); rec.Code != http.StatusNotFound {
+		t.Fatalf("replaced link = %d, want 404", rec.Code)
+	}
+	if rec := getPublicPlaylist(h, "pl1.forged.token"); rec.Code != http.StatusNotFound {
+		t.Fatalf("forged link = %d, want 404", rec.Code)
+	}
+}
+
+func TestPublicPlaylistLinkRequiresPublicOwnedPlaylist(t *testing.T) {
+	owner := uuid.New()
+	h, _ := newPlaylistLinkTestHandlers(owner)
+
+	if rec := createPlaylistLink(h, owner, "8"); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "PLAYLIST_NOT_PUBLIC") {
+		t.Fatalf("private playlist = %d %s, want 403 PLAYLIST_NOT_PUBLIC", rec.Code, rec.Body.String())
+	}
+	if rec := createPlaylistLink(h, uuid.New(), "7"); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-owner = %d, want 403", rec.Code)
+	}
+	if rec := createPlaylistLink(h, owner, "99"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing playlist = %d, want 404", rec.Code)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/playlists/7/public-link", nil), owner)
+	req.SetPathValue("id", "7")
+	rec := httptest.NewRecorder()
+	h.RevokeLink(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("revoke without a link = %d, want 404", rec.Code)
+	}
+}
+
+func TestPublicPlaylistRouteIsRateLimited(t *testing.T) {
+	h, _ := newPlaylistLinkTestHandlers(uuid.New())
+	router := NewRouterWithConfig(&RouterConfig{
+		AuthHandlers:         auth.NewHandlers(nil),
+		PlaylistLinkHandlers: h,
+	})
+
+	var last int
+	for i := 0; i <= publicRequestsPerMinute; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/public/playlists/pl1.x.y", nil)
+		req.RemoteAddr = "203.0.113.9:5000"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		last = rec.Code
+		if i < publicRequestsPerMinute && rec.Code != http.StatusNotFound {
+			t.Fatalf("request %d status = %d, want 404 before the limit", i, rec.Code)
+		}
+	}
+	if last != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit status = %d, want 429", last)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/public/playlists/pl1.x.y", nil)
+	req.RemoteAddr = "198.51.100.4:5000"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another client status = %d, want 404", rec.Code)
+	}
+}
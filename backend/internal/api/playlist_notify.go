@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+// Playlist change actions.
+const (
+	PlaylistCreated = "created"
+	PlaylistUpdated = "updated"
+	PlaylistDeleted = "deleted"
+)
+
+// PlaylistChange tells a user's devices which playlist changed and how, so
+// they refetch it or drop it.
+type PlaylistChange struct {
+	PlaylistID int64  `json:"playlistId"`
+	Action     string `json:"action"`
+}
+
+// PlaylistNotifier tells a user's devices one of their playlists changed.
+type PlaylistNotifier interface {
+	PlaylistChanged(userID uuid.UUID, change PlaylistChange)
+}
+
+// SetNotifier makes edits made through Notified handlers notify the user's
+// devices.
+func (h *PlaylistHandlers) SetNotifier(notifier PlaylistNotifier) {
+	h.notifier = notifier
+}
+
+// Notified wraps a handler that creates, edits or deletes a playlist and,
+// when it succeeds, tells the caller's devices. Edited and deleted
+// playlists are the {id} in the path; a created one is read from the
+// response, which is the new playlist.
+func (h *PlaylistHandlers) Notified(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userCtx := auth.GetUserFromContext(r.Context())
+		if h.notifier == nil || userCtx == nil {
+			next(w, r)
+			return
+		}
+
+		recorder := &playlistRecorder{ResponseWriter: w, status: http.StatusOK, keepBody: action == PlaylistCreated}
+		next(recorder, r)
+		if recorder.status >= http.StatusMultipleChoices {
+			return
+		}
+
+		var playlistID int64
+		if action == PlaylistCreated {
+			var created struct {
+				ID int64 `json:"id"`
+			}
+			if err := json.Unmarshal(recorder.body.Bytes(), &created); err != nil || created.ID == 0 {
+				return
+			}
+			playlistID = created.ID
+		} else {
+			id, err := parsePlaylistID(r)
+			if err != nil {
+				return
+			}
+			playlistID = id
+		}
+		h.notifier.PlaylistChanged(userCtx.UserID, PlaylistChange{PlaylistID: playlistID, Action: action})
+	}
+}
+
+// playlistRecorder remembers the status a handler wrote and, when keepBody
+// is set, the body.
+type playlistRecorder struct {
+	http.ResponseWriter
+	status   int
+	keepBody bool
+	body     bytes.Buffer
+}
+
+func (r *playlistRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *playlistRecorder) Write(p []byte) (int, error) {
+	if r.keepBody {
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
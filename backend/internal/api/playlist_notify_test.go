@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+type fakePlaylistNotifier struct {
+	changes []PlaylistChange
+}
+
+func (f *fakePlaylistNotifier) PlaylistChanged(_ uuid.UUID, change PlaylistChange) {
+	f.changes = append(f.changes, change)
+}
+
+func notifyRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+}
+
+func TestPlaylistNotifiedReportsSuccessfulChanges(t *testing.T) {
+	notifier := &fakePlaylistNotifier{}
+	h := &PlaylistHandlers{}
+	h.SetNotifier(notifier)
+
+	created := h.Notified(PlaylistCreated, func(w http.ResponseWriter, r *http.Request) {
+		writePlaylistJSON(w, http.StatusCreated, PlaylistResponse{ID: 42, Name: "New"})
+	})
+	created(httptest.NewRecorder(), notifyRequest(http.MethodPost, "/api/v1/playlists"))
+
+	deleted := h.Notified(PlaylistDeleted, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req := notifyRequest(http.MethodDelete, "/api/v1/playlists/7")
+	req.SetPathValue("id", "7")
+	deleted(httptest.NewRecorder(), req)
+
+	failed := h.Notified(PlaylistUpdated, func(w http.ResponseWriter, r *http.Request) {
+		writePlaylistError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+	})
+	req = notifyRequest(http.MethodPut, "/api/v1/playlists/8")
+	req.SetPathValue("id", "8")
+	failed(httptest.NewRecorder(), req)
+
+	want := []PlaylistChange{{PlaylistID: 42, Action: PlaylistCreated}, {PlaylistID: 7, Action: PlaylistDeleted}}
+	if len(notifier.changes) != len(want) || notifier.changes[0] != want[0] || notifier.changes[1] != want[1] {
+		t.Fatalf("changes = %+v, want %+v", notifier.changes, want)
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// profileHandlePattern mirrors chk_user_profiles_handle so invalid handles are a
+// 400 rather than a constraint violation.
+var profileHandlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,31}$`)
+
+const (
+	publicProfilePlaylistLimit = 50
+	publicProfileArtistLimit   = 10
+)
+
+type profileStore interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*db.UserProfile, error)
+	GetPublicByHandle(ctx context.Context, handle string) (*db.UserProfile, error)
+	Upsert(ctx context.Context, profile *db.UserProfile) error
+	SetPlaylistVisibility(ctx context.Context, userID uuid.UUID, playlistID int64, visible bool) error
+	PublicPlaylists(ctx context.Context, userID uuid.UUID, limit int) ([]db.PlaylistWithTracks, error)
+	TopArtists(ctx context.Context, userID uuid.UUID, limit int) ([]db.ArtistPlayCount, error)
+}
+
+type ProfileHandlers struct {
+	profileRepo profileStore
+	avatars     AvatarURLs
+}
+
+func NewProfileHandlers(profileRepo profileStore) *ProfileHandlers {
+	return &ProfileHandlers{profileRepo: profileRepo}
+}
+
+// SetAvatars lets public profiles show the owner's avatar.
+func (h *ProfileHandlers) SetAvatars(avatars AvatarURLs) {
+	h.avatars = avatars
+}
+
+type UpdateProfileRequest struct {
+	Handle         string `json:"handle"`
+	IsPublic       bool   `json:"isPublic"`
+	ShowPlaylists  *bool  `json:"showPlaylists,omitempty"`
+	ShowTopArtists bool   `json:"showTopArtists"`
+}
+
+type ProfilePlaylistVisibilityRequest struct {
+	Visible bool `json:"visible"`
+}
+
+type ProfileResponse struct {
+	Handle         string    `json:"handle"`
+	IsPublic       bool      `json:"isPublic"`
+	ShowPlaylists  bool      `json:"showPlaylists"`
+	ShowTopArtists bool      `json:"showTopArtists"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type PublicProfilePlaylistResponse struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	CoverURL    string `json:"coverUrl,omitempty"`
+	TrackCount  int    `json:"trackCount"`
+	DurationMs  int64  `json:"durationMs"`
+}
+
+type PublicProfileArtistResponse struct {
+	Name      string `json:"name"`
+	PlayCount int    `json:"playCount"`
+}
+
+// PublicProfileResponse deliberately omits user IDs and emails; the handle is
+// the only identifier a public profile exposes.
+type PublicProfileResponse struct {
+	Handle      string                          `json:"handle"`
+	DisplayName string                          `json:"displayName"`
+	Bio         string                          `json:"bio,omitempty"`
+	AvatarURL   string                          `json:"avatarUrl,omitempty"`
+	Playlists   []PublicProfilePlaylistResponse `json:"playlists,omitempty"`
+	TopArtists  []PublicProfileArtistResponse   `json:"topArtists,omitempty"`
+}
+
+// GetMyProfile handles GET /api/v1/me/profile.
+func (h *ProfileHandlers) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	profile, err := h.profileRepo.GetByUserID(r.Context(), userCtx.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrProfileNotFound) {
+			writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "profile not found")
+			return
+		}
+		writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load profile")
+		return
+	}
+
+	writeProfileJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+// UpdateMyProfile handles PUT /api/v1/me/profile.
+func (h *ProfileHandlers) UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	handle := strings.ToLower(strings.TrimSpace(req.Handle))
+	if !profileHandlePattern.MatchString(handle) {
+		writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "handle must be 3-32 characters of a-z, 0-9, '_' or '-'")
+		return
+	}
+
+	profile := &db.UserProfile{
+		UserID:         userCtx.UserID,
+		Handle:         handle,
+		IsPublic:       req.IsPublic,
+		ShowPlaylists:  req.ShowPlaylists == nil || *req.ShowPlaylists,
+		ShowTopArtists: req.ShowTopArtists,
+	}
+	if err := h.profileRepo.Upsert(r.Context(), profile); err != nil {
+		if errors.Is(err, db.ErrProfileHandleTaken) {
+			writeProfileError(w, http.StatusConflict, "HANDLE_TAKEN", "handle is already taken")
+			return
+		}
+		writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to save profile")
+		return
+	}
+
+	writeProfileJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+// SetPlaylistVisibility handles PUT /api/v1/me/profile/playlists/{id}.
+func (h *ProfileHandlers) SetPlaylistVisibility(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeProfileError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	playlistID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || playlistID <= 0 {
+		writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid playlist ID")
+		return
+	}
+
+	var req ProfilePlaylistVisibilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProfileError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	// Ownership is enforced in the update itself, so a foreign playlist reads as
+	// missing rather than leaking its existence.
+	if err := h.profileRepo.SetPlaylistVisibility(r.Context(), userCtx.UserID, playlistID, req.Visible); err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "playlist not found")
+			return
+		}
+		writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update playlist visibility")
+		return
+	}
+
+	writeProfileJSON(w, http.StatusOK, map[string]interface{}{
+		"playlistId": playlistID,
+		"visible":    req.Visible,
+	})
+}
+
+// GetPublicProfile handles GET /api/v1/public/users/{handle}. It is served
+// without authentication, so only sections the owner opted into are loaded.
+func (h *ProfileHandlers) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
+	handle := strings.ToLower(strings.TrimSpace(r.PathValue("handle")))
+	if !profileHandlePattern.MatchString(handle) {
+		writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "profile not found")
+		return
+	}
+
+	profile, err := h.profileRepo.GetPublicByHandle(r.Context(), handle)
+	if err != nil {
+		if errors.Is(err, db.ErrProfileNotFound) {
+			writeProfileError(w, http.StatusNotFound, "NOT_FOUND", "profile not found")
+			return
+		}
+		writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load profile")
+		return
+	}
+
+	resp := PublicProfileResponse{
+		Handle:      profile.Handle,
+		DisplayName: profile.Username,
+		AvatarURL:   avatarURL(r.Context(), h.avatars, profile.AvatarKey),
+	}
+	if profile.Bio.Valid {
+		resp.Bio = profile.Bio.String
+	}
+
+	if profile.ShowPlaylists {
+		playlists, err := h.profileRepo.PublicPlaylists(r.Context(), profile.UserID, publicProfilePlaylistLimit)
+		if err != nil {
+			writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load profile playlists")
+			return
+		}
+		resp.Playlists = make([]PublicProfilePlaylistResponse, 0, len(playlists))
+		for _, p := range playlists {
+			item := PublicProfilePlaylistResponse{
+				ID:         p.ID,
+				Name:       p.Name,
+				TrackCount: p.TrackCount,
+				DurationMs: p.DurationMs,
+			}
+			if p.Description.Valid {
+				item.Description = p.Description.String
+			}
+			if p.CoverURL.Valid {
+				item.CoverURL = p.CoverURL.String
+			}
+			resp.Playlists = append(resp.Playlists, item)
+		}
+	}
+
+	if profile.ShowTopArtists {
+		artists, err := h.profileRepo.TopArtists(r.Context(), profile.UserID, publicProfileArtistLimit)
+		if err != nil {
+			writeProfileError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load profile artists")
+			return
+		}
+		resp.TopArtists = make([]PublicProfileArtistResponse, 0, len(artists))
+		for _, a := range artists {
+			resp.TopArtists = append(resp.TopArtists, PublicProfileArtistResponse{Name: a.Artist, PlayCount: a.PlayCount})
+		}
+	}
+
+	writeProfileJSON(w, http.StatusOK, resp)
+}
+
+func newProfileResponse(p *db.UserProfile) ProfileResponse {
+	return ProfileResponse{
+		Handle:         p.Handle,
+		IsPublic:       p.IsPublic,
+		ShowPlaylists:  p.ShowPlaylists,
+		ShowTopArtists: p.ShowTopArtists,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+func writeProfileJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeProfileError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
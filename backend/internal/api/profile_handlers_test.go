@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeProfileStore struct {
+	profiles   map[uuid.UUID]*db.UserProfile
+	playlists  []db.PlaylistWithTracks
+	artists    []db.ArtistPlayCount
+	visibility map[int64]bool
+	owned      map[int64]uuid.UUID
+
+	playlistCalls int
+	artistCalls   int
+}
+
+func newFakeProfileStore() *fakeProfileStore {
+	return &fakeProfileStore{
+		profiles:   map[uuid.UUID]*db.UserProfile{},
+		visibility: map[int64]bool{},
+		owned:      map[int64]uuid.UUID{},
+	}
+}
+
+func (f *fakeProfileStore) GetByUserID(ctx context.Context, userID uuid.UUID) (*db.UserProfile, error) {
+	if p, ok := f.profiles[userID]; ok {
+		return p, nil
+	}
+	return nil, db.ErrProfileNotFound
+}
+
+func (f *fakeProfileStore) GetPublicByHandle(ctx context.Context, handle string) (*db.UserProfile, error) {
+	for _, p := range f.profiles {
+		if p.Handle == handle && p.IsPublic {
+			return p, nil
+		}
+	}
+	return nil, db.ErrProfileNotFound
+}
+
+func (f *fakeProfileStore) Upsert(ctx context.Context, profile *db.UserProfile) error {
+	for userID, p := range f.profiles {
+		if p.Handle == profile.Handle && userID != profile.UserID {
+			return db.ErrProfileHandleTaken
+		}
+	}
+	copied := *profile
+	f.profiles[profile.UserID] = &copied
+	return nil
+}
+
+func (f *fakeProfileStore) SetPlaylistVisibility(ctx context.Context, userID uuid.UUID, playlistID int64, visible bool) error {
+	if owner, ok := f.owned[playlistID]; !ok || owner != userID {
+		return db.ErrPlaylistNotFound
+	}
+	f.visibility[playlistID] = visible
+	return nil
+}
+
+func (f *fakeProfileStore) PublicPlaylists(ctx context.Context, userID uuid.UUID, limit int) ([]db.PlaylistWithTracks, error) {
+	f.playlistCalls++
+	return f.playlists, nil
+}
+
+func (f *fakeProfileStore) TopArtists(ctx context.Context, userID uuid.UUID, limit int) ([]db.ArtistPlayCount, error) {
+	f.artistCalls++
+	return f.artists, nil
+}
+
+func TestUpdateMyProfileValidatesAndNormalizesHandle(t *testing.T) {
+	store := newFakeProfileStore()
+	h := NewProfileHandlers(store)
+	userID := uuid.New()
+
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"too short", `{"handle":"ab"}`, http.StatusBadRequest},
+		{"bad characters", `{"handle":"dj shadow!"}`, http.StatusBadRequest},
+		{"normalized", `{"handle":"  DJ_Shadow ","isPublic":true}`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/profile", strings.NewReader(tc.body)), userID)
+			rr := httptest.NewRecorder()
+			h.UpdateMyProfile(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+		})
+	}
+
+	saved := store.profiles[userID]
+	if saved == nil || saved.Handle != "dj_shadow" || !saved.IsPublic || !saved.ShowPlaylists {
+		t.Fatalf("saved profile = %#v, want normalized public handle with playlists shown by default", saved)
+	}
+}
+
+func TestUpdateMyProfileRejectsTakenHandle(t *testing.T) {
+	store := newFakeProfileStore()
+	store.profiles[uuid.New()] = &db.UserProfile{Handle: "taken"}
+	h := NewProfileHandlers(store)
+
+	req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/profile", strings.NewReader(`{"handle":"taken"}`)), uuid.New())
+	rr := httptest.NewRecorder()
+	h.UpdateMyProfile(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (body=%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSetPlaylistVisibilityScopesToOwner(t *testing.T) {
+	store := newFakeProfileStore()
+	owner := uuid.New()
+	store.owned[5] = owner
+	h := NewProfileHandlers(store)
+
+	req := withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/profile/playlists/5", strings.NewReader(`{"visible":true}`)), uuid.New())
+	req.SetPathValue("id", "5")
+	rr := httptest.NewRecorder()
+	h.SetPlaylistVisibility(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("foreign playlist status = %d, want 404", rr.Code)
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodPut, "/api/v1/me/profile/playlists/5", strings.NewReader(`{"visible":true}`)), owner)
+	req.SetPathValue("id", "5")
+	rr = httptest.NewRecorder()
+	h.SetPlaylistVisibility(rr, req)
+	if rr.Code != http.StatusOK || !store.visibility[5] {
+		t.Fatalf("owner status = %d visible = %v, want 200 and visible", rr.Code, store.visibility[5])
+	}
+}
+
+func TestGetPublicProfileHonorsSectionFlags(t *testing.T) {
+	store := newFakeProfileStore()
+	userID := uuid.New()
+	store.profiles[userID] = &db.UserProfile{
+		UserID:         userID,
+		Handle:         "listener",
+		Username:       "Listener",
+		IsPublic:       true,
+		ShowPlaylists:  true,
+		ShowTopArtists: false,
+	}
+	store.playlists = []db.PlaylistWithTracks{{
+		Playlist:   db.Playlist{ID: 3, Name: "Warmup", Description: sql.NullString{String: "openers", Valid: true}},
+		TrackCount: 12,
+		DurationMs: 3600000,
+	}}
+	store.artists = []db.ArtistPlayCount{{Artist: "Hidden", PlayCount: 9}}
+	h := NewProfileHandlers(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/public/users/Listener", nil)
+	req.SetPathValue("handle", "Listener")
+	rr := httptest.NewRecorder()
+	h.GetPublicProfile(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+
+	var resp PublicProfileResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Handle != "listener" || resp.DisplayName != "Listener" {
+		t.Fatalf("profile identity = %#v", resp)
+	}
+	if len(resp.Playlists) != 1 || resp.Playlists[0].ID != 3 || resp.Playlists[0].Description != "openers" {
+		t.Fatalf("playlists = %#v, want the one chosen playlist", resp.Playlists)
+	}
+	if resp.TopArtists != nil || store.artistCalls != 0 {
+		t.Fatalf("top artists exposed/loaded while hidden: %#v calls=%d", resp.TopArtists, store.artistCalls)
+	}
+	if strings.Contains(rr.Body.String(), userID.String()) {
+		t.Fatalf("public profile leaked the user ID: %s", rr.Body.String())
+	}
+}
+
+func TestGetPublicProfileHidesPrivateProfiles(t *testing.T) {
+	store := newFakeProfileStore()
+	userID := uuid.New()
+	store.profiles[userID] = &db.UserProfile{UserID: userID, Handle: "secret", IsPublic: false, ShowPlaylists: true}
+	h := NewProfileHandlers(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/public/users/secret", nil)
+	req.SetPathValue("handle", "secret")
+	rr := httptest.NewRecorder()
+	h.GetPublicProfile(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404 for a private profile", rr.Code)
+	}
+	if store.playlistCalls != 0 {
+		t.Fatalf("private profile playlists were loaded")
+	}
+}
+
+func TestGetPublicProfileIncludesBioAndAvatar(t *testing.T) {
+	store := newFakeProfileStore()
+	userID := uuid.New()
+	store.profiles[userID] = &db.UserProfile{
+		UserID:    userID,
+		Handle:    "shadow",
+		Username:  "DJ Shadow",
+		Bio:       sql.NullString{String: "Endtroducing.", Valid: true},
+		AvatarKey: sql.NullString{String: "avatars/shadow.jpg", Valid: true},
+		IsPublic:  true,
+	}
+	h := NewProfileHandlers(store)
+	h.SetAvatars(&fakeAvatarStorage{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/public/users/shadow", nil)
+	req.SetPathValue("handle", "shadow")
+	rr := httptest.NewRecorder()
+	h.GetPublicProfile(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body=%s)", rr.Code, rr.Body.String())
+	}
+	var resp PublicProfileResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.DisplayName != "DJ Shadow" || resp.Bio != "Endtroducing." || resp.AvatarURL != "https://cdn.test/avatars/shadow.jpg" {
+		t.Fatalf("response = %+v", resp)
+	}
+}
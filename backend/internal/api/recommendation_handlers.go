@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/recommender"
+)
+
+const maxRecommendationLimit = 50
+
+// Recommender builds recommendation lists; *recommender.Service satisfies
+// it.
+type Recommender interface {
+	Recommend(ctx context.Context, userID uuid.UUID, limit int) ([]recommender.List, error)
+}
+
+type RecommendationHandlers struct {
+	recommender Recommender
+}
+
+func NewRecommendationHandlers(rec Recommender) *RecommendationHandlers {
+	return &RecommendationHandlers{recommender: rec}
+}
+
+type RecommendedTrackResponse struct {
+	ID          int64   `json:"id"`
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist,omitempty"`
+	Album       string  `json:"album,omitempty"`
+	DurationMs  int     `json:"durationMs,omitempty"`
+	CoverArtURL string  `json:"coverArtUrl,omitempty"`
+	InLibrary   bool    `json:"inLibrary"`
+	Score       float64 `json:"score"`
+}
+
+type RecommendationSeedResponse struct {
+	TrackID   int64  `json:"trackId"`
+	Title     string `json:"title"`
+	Artist    string `json:"artist,omitempty"`
+	PlayCount int    `json:"playCount"`
+}
+
+type RecommendationListResponse struct {
+	Kind   string                      `json:"kind"`
+	Title  string                      `json:"title"`
+	Seed   *RecommendationSeedResponse `json:"seed,omitempty"`
+	Tracks []RecommendedTrackResponse  `json:"tracks"`
+}
+
+type RecommendationsResponse struct {
+	Lists []RecommendationListResponse `json:"lists"`
+}
+
+// GetRecommendations handles GET /api/v1/recommendations. Query params:
+// limit (tracks per list, 1-50). Lists are "because you listened to" lists
+// for recent favorites followed by a daily discovery mix; a user without
+// recent plays gets none.
+func (h *RecommendationHandlers) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeRecommendationError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxRecommendationLimit {
+			writeRecommendationError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 1 and 50")
+			return
+		}
+		limit = parsed
+	}
+
+	lists, err := h.recommender.Recommend(r.Context(), userCtx.UserID, limit)
+	if err != nil {
+		log.Printf("Warning: recommendations failed for user %s: %v", userCtx.UserID, err)
+		writeRecommendationError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to build recommendations")
+		return
+	}
+
+	resp := RecommendationsResponse{Lists: make([]RecommendationListResponse, 0, len(lists))}
+	for _, list := range lists {
+		item := RecommendationListResponse{
+			Kind:   list.Kind,
+			Title:  list.Title,
+			Tracks: make([]RecommendedTrackResponse, 0, len(list.Tracks)),
+		}
+		if list.Seed != nil {
+			item.Seed = &RecommendationSeedResponse{
+				TrackID:   list.Seed.TrackID,
+				Title:     list.Seed.Title,
+				Artist:    list.Seed.Artist,
+				PlayCount: list.Seed.PlayCount,
+			}
+		}
+		for _, rec := range list.Tracks {
+			item.Tracks = append(item.Tracks, newRecommendedTrackResponse(rec))
+		}
+		resp.Lists = append(resp.Lists, item)
+	}
+	writeRecommendationJSON(w, http.StatusOK, resp)
+}
+
+func newRecommendedTrackResponse(rec recommender.Recommendation) RecommendedTrackResponse {
+	t := rec.Track
+	resp := RecommendedTrackResponse{
+		ID:        t.ID,
+		Title:     t.Title,
+		InLibrary: rec.InLibrary,
+		Score:     rec.Score,
+	}
+	if t.Artist.Valid {
+		resp.Artist = t.Artist.String
+	}
+	if t.Album.Valid {
+		resp.Album = t.Album.String
+	}
+	if t.DurationMs.Valid {
+		resp.DurationMs = int(t.DurationMs.Int32)
+	}
+	if t.CoverArtURL.Valid {
+		resp.CoverArtURL = t.CoverArtURL.String
+	} else if t.MBReleaseID != nil {
+		resp.CoverArtURL = "https://coverartarchive.org/release/" + t.MBReleaseID.String() + "/front-250"
+	}
+	return resp
+}
+
+func writeRecommendationJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeRecommendationError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
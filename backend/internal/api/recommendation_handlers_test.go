@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/recommender"
+)
+
+type fakeRecommender struct {
+	lists []recommender.List
+	limit int
+}
+
+func (f *fakeRecommender) Recommend(ctx context.Context, userID uuid.UUID, limit int) ([]recommender.List, error) {
+	f.limit = limit
+	return f.lists, nil
+}
+
+func TestGetRecommendationsRendersLists(t *testing.T) {
+	rec := &fakeRecommender{lists: []recommender.List{{
+		Kind:  recommender.KindBecauseYouListened,
+		Title: "Because you listened to Alpha",
+		Seed:  &db.RecommendationSeed{TrackID: 1, Title: "Alpha", PlayCount: 9},
+		Tracks: []recommender.Recommendation{{
+			Track:     &db.Track{ID: 7, Title: "Beta", Artist: sql.NullString{String: "B", Valid: true}},
+			Score:     2.5,
+			InLibrary: true,
+		}},
+	}}}
+	h := NewRecommendationHandlers(rec)
+
+	rr := httptest.NewRecorder()
+	h.GetRecommendations(rr, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/recommendations?limit=5", nil), uuid.New()))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp RecommendationsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.limit != 5 || len(resp.Lists) != 1 {
+		t.Fatalf("limit = %d, lists = %+v", rec.limit, resp.Lists)
+	}
+	list := resp.Lists[0]
+	if list.Seed == nil || list.Seed.TrackID != 1 || len(list.Tracks) != 1 || list.Tracks[0].Artist != "B" || !list.Tracks[0].InLibrary {
+		t.Fatalf("list = %+v", list)
+	}
+}
+
+func TestGetRecommendationsValidatesLimit(t *testing.T) {
+	h := NewRecommendationHandlers(&fakeRecommender{})
+	for _, limit := range []string{"0", "51", "many"} {
+		rr := httptest.NewRecorder()
+		h.GetRecommendations(rr, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/recommendations?limit="+limit, nil), uuid.New()))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("limit=%s: status = %d, want 400", limit, rr.Code)
+		}
+	}
+}
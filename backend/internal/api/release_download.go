@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/discovery"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+const (
+	// releaseSourceSearchLimit is how many source results are weighed for
+	// each track of a release.
+	releaseSourceSearchLimit = 5
+	// releaseSearchConcurrency bounds the source searches run at once for
+	// one release, each of which is a yt-dlp process.
+	releaseSearchConcurrency = 4
+	// releaseDurationToleranceMs is the smallest length difference between a
+	// source and its track that rules the source out; longer tracks allow a
+	// tenth of their length.
+	releaseDurationToleranceMs = 15000
+)
+
+// Per-track outcomes of a release download.
+const (
+	ReleaseTrackQueued  = "queued"
+	ReleaseTrackNoMatch = "no_match"
+	ReleaseTrackFailed  = "failed"
+)
+
+type releaseCatalog interface {
+	GetRelease(ctx context.Context, mbID string) (*musicbrainz.Release, error)
+}
+
+type releaseSourceSearcher interface {
+	SearchSources(ctx context.Context, query string, providers []string, limit int) discovery.SourceSearchResponse
+}
+
+// SetReleaseSources enables downloading a whole MusicBrainz release: catalog
+// supplies its track list and sources is searched for each track.
+func (h *DownloadHandlers) SetReleaseSources(catalog releaseCatalog, sources releaseSourceSearcher) {
+	h.releases = catalog
+	h.releaseSources = sources
+}
+
+// ReleaseDownloadResponse is the parent job of a release download and what
+// became of each of the release's tracks.
+type ReleaseDownloadResponse struct {
+	JobID     string                 `json:"job_id"`
+	Status    string                 `json:"status"`
+	ReleaseID string                 `json:"release_id"`
+	Title     string                 `json:"title"`
+	Artist    string                 `json:"artist,omitempty"`
+	Tracks    []ReleaseTrackDownload `json:"tracks"`
+}
+
+// ReleaseTrackDownload reports one track of a release download. Position
+// counts across all of the release's media.
+type ReleaseTrackDownload struct {
+	Position    int    `json:"position"`
+	RecordingID string `json:"recording_id"`
+	Title       string `json:"title"`
+	Status      string `json:"status"`
+	JobID       string `json:"job_id,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	SourceTitle string `json:"source_title,omitempty"`
+}
+
+// releaseTrackSource is the source picked for a release track, if any.
+type releaseTrackSource struct {
+	candidate discovery.Candidate
+	quality   discovery.SourceQuality
+	found     bool
+}
+
+// DownloadRelease handles POST /api/v1/downloads/release/{mb_release_id}. It
+// searches the sources for each track of the release and queues the best
+// result for each as an item of one batch-priority parent job. Tracks without
+// an acceptable source are reported as no_match rather than failing the
+// request, which fails only when no track could be queued.
+func (h *DownloadHandlers) DownloadRelease(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeDownloadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	releaseID, err := uuid.Parse(r.PathValue("mb_release_id"))
+	if err != nil {
+		writeDownloadError(w, http.StatusBadRequest, "INVALID_REQUEST", "mb_release_id must be a MusicBrainz release ID")
+		return
+	}
+	if h.releases == nil || h.releaseSources == nil || h.ingestion == nil || h.downloadService == nil {
+		writeDownloadError(w, http.StatusServiceUnavailable, "DOWNLOAD_UNAVAILABLE", "release downloads are unavailable")
+		return
+	}
+
+	release, err := h.releases.GetRelease(r.Context(), releaseID.String())
+	if errors.Is(err, musicbrainz.ErrNotFound) {
+		writeDownloadError(w, http.StatusNotFound, "RELEASE_NOT_FOUND", "release not found")
+		return
+	}
+	if err != nil {
+		writeDownloadError(w, http.StatusBadGateway, "MUSICBRAINZ_UNAVAILABLE", "failed to fetch the release from MusicBrainz")
+		return
+	}
+	if len(release.Tracks) == 0 {
+		writeDownloadError(w, http.StatusUnprocessableEntity, "RELEASE_EMPTY", "the release has no tracks")
+		return
+	}
+
+	sources := h.findReleaseSources(r.Context(), release.Tracks)
+	resp := &ReleaseDownloadResponse{ReleaseID: release.ID, Title: release.Title, Artist: release.Artist, Tracks: make([]ReleaseTrackDownload, len(release.Tracks))}
+	matched := 0
+	for i, track := range release.Tracks {
+		resp.Tracks[i] = ReleaseTrackDownload{Position: i + 1, RecordingID: track.ID, Title: track.Title, Status: ReleaseTrackNoMatch}
+		if sources[i].found && !h.releaseSourceTakenDown(r.Context(), sources[i].candidate.SourceURL) {
+			resp.Tracks[i].Provider = sources[i].candidate.Provider
+			resp.Tracks[i].SourceTitle = sources[i].candidate.Title
+			matched++
+		} else {
+			sources[i].found = false
+		}
+	}
+	if matched == 0 {
+		writeDownloadError(w, http.StatusUnprocessableEntity, "NO_SOURCES_FOUND", "no downloadable source was found for any track of the release")
+		return
+	}
+
+	ctx := download.WithPriority(r.Context(), download.PriorityBatch)
+	title := release.Title
+	if release.Artist != "" {
+		title = release.Artist + " - " + release.Title
+	}
+	parent, err := h.downloadService.CreateParentJob(ctx, userCtx.UserID.String(), musicBrainzSiteURL+"/release/"+release.ID, "musicbrainz", title)
+	if err != nil {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to create release download")
+		return
+	}
+	childCtx := download.WithParentJob(ctx, parent.ID)
+	childIDs := make([]string, 0, matched)
+	for i, track := range release.Tracks {
+		if !sources[i].found {
+			continue
+		}
+		jobID, err := h.queueReleaseTrack(childCtx, userCtx.UserID, release, track, sources[i])
+		if err != nil {
+			resp.Tracks[i].Status = ReleaseTrackFailed
+			continue
+		}
+		resp.Tracks[i].Status = ReleaseTrackQueued
+		resp.Tracks[i].JobID = jobID
+		childIDs = append(childIDs, jobID)
+	}
+	if len(childIDs) == 0 {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to enqueue any track of the release")
+		return
+	}
+	parent, err = h.downloadService.AttachChildJobs(ctx, parent.ID, childIDs)
+	if err != nil {
+		writeDownloadError(w, http.StatusInternalServerError, "DOWNLOAD_ENQUEUE_FAILED", "failed to record release tracks")
+		return
+	}
+
+	resp.JobID = parent.ID
+	resp.Status = parent.Status
+	writeDownloadJSON(w, http.StatusCreated, resp)
+}
+
+// findReleaseSources searches the sources for every track, a few at a time,
+// and returns the pick for each in track order.
+func (h *DownloadHandlers) findReleaseSources(ctx context.Context, tracks []musicbrainz.Track) []releaseTrackSource {
+	sources := make([]releaseTrackSource, len(tracks))
+	sem := make(chan struct{}, releaseSearchConcurrency)
+	var wg sync.WaitGroup
+	for i, track := range tracks {
+		wg.Add(1)
+		go func(i int, track musicbrainz.Track) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			query := strings.TrimSpace(track.Artist + " " + track.Title)
+			results := h.releaseSources.SearchSources(ctx, query, nil, releaseSourceSearchLimit)
+			sources[i] = bestReleaseSource(query, track, results.Results)
+		}(i, track)
+	}
+	wg.Wait()
+	return sources
+}
+
+// bestReleaseSource picks the highest-scoring downloadable result for a
+// track. Results the quality check says to avoid, and results whose length
+// is too far from the track's, are never picked.
+func bestReleaseSource(query string, track musicbrainz.Track, results []discovery.Candidate) releaseTrackSource {
+	picks := make([]releaseTrackSource, 0, len(results))
+	for _, candidate := range results {
+		if !candidate.Downloadable || candidate.SourceURL == "" || candidate.SourceID == "" {
+			continue
+		}
+		if !releaseDurationMatches(track.Duration, candidate.DurationMs) {
+			continue
+		}
+		quality := discovery.EvaluateSourceQuality(query, candidate)
+		if quality.Recommendation == discovery.SourceQualityAvoid {
+			continue
+		}
+		picks = append(picks, releaseTrackSource{candidate: candidate, quality: quality, found: true})
+	}
+	if len(picks) == 0 {
+		return releaseTrackSource{}
+	}
+	sort.SliceStable(picks, func(i, j int) bool {
+		return picks[i].quality.Score > picks[j].quality.Score
+	})
+	return picks[0]
+}
+
+// releaseDurationMatches reports whether a source of sourceMs could be a
+// track of trackMs. An unknown length on either side is not held against
+// the source.
+func releaseDurationMatches(trackMs, sourceMs int) bool {
+	if trackMs <= 0 || sourceMs <= 0 {
+		return true
+	}
+	tolerance := max(releaseDurationToleranceMs, trackMs/10)
+	diff := trackMs - sourceMs
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+func (h *DownloadHandlers) releaseSourceTakenDown(ctx context.Context, sourceURL string) bool {
+	if h.takedowns == nil {
+		return false
+	}
+	takedown, err := h.takedowns.FindActive(ctx, sourceURL, "")
+	return err != nil || takedown != nil
+}
+
+// queueReleaseTrack records the decision for a release track's source and
+// queues it, tagged with the track's recording so the processor need not
+// match it again.
+func (h *DownloadHandlers) queueReleaseTrack(ctx context.Context, userID uuid.UUID, release *musicbrainz.Release, track musicbrainz.Track, source releaseTrackSource) (string, error) {
+	candidate := source.candidate
+	metadata := make(map[string]interface{}, len(candidate.Metadata)+5)
+	for key, value := range candidate.Metadata {
+		metadata[key] = value
+	}
+	metadata["trustedIngestion"] = true
+	metadata["origin"] = db.SourceSelectionOriginRelease
+	metadata["mbReleaseId"] = release.ID
+	metadata["sourceQuality"] = source.quality
+	selected := download.SourceCandidate{
+		CandidateID: candidate.CandidateID, Provider: candidate.Provider, SourceID: candidate.SourceID, SourceURL: candidate.SourceURL,
+		Title: track.Title, Artist: track.Artist, Album: release.Title, Uploader: candidate.Uploader,
+		DurationMs: candidate.DurationMs, ThumbnailURL: candidate.ThumbnailURL, Metadata: metadata,
+	}
+
+	persisted, err := h.ingestion.CreateSearchedDownload(ctx, userID, db.SourceSelectionOriginRelease, selected, trustedReleaseQuality(source.quality), "best source search result for a MusicBrainz release track")
+	if err != nil {
+		return "", err
+	}
+	var recordingID *string
+	if track.ID != "" {
+		recordingID = &track.ID
+	}
+	job, err := h.ingestion.EnqueueTrustedRecordingDownload(ctx, persisted, h.downloadService, recordingID)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// trustedReleaseQuality converts a source-quality judgment to the bounded form
+// kept with a source selection decision.
+func trustedReleaseQuality(quality discovery.SourceQuality) db.TrustedSourceSelectionQuality {
+	const maxEntries = 8
+	return db.TrustedSourceSelectionQuality{
+		Score: quality.Score, Classification: quality.Classification, Recommendation: quality.Recommendation,
+		Confidence: quality.Confidence, Reasons: quality.Reasons[:min(len(quality.Reasons), maxEntries)],
+		Warnings: quality.Warnings[:min(len(quality.Warnings), maxEntries)], Provenance: quality.Provenance,
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/discovery"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+const testReleaseID = "9f2c3a1e-1d5b-4c1a-8f7e-2b6d0c9a7e11"
+
+type fakeReleaseCatalog map[string]*musicbrainz.Release
+
+func (f fakeReleaseCatalog) GetRelease(_ context.Context, mbID string) (*musicbrainz.Release, error) {
+	release, ok := f[mbID]
+	if !ok {
+		return nil, musicbrainz.ErrNotFound
+	}
+	return release, nil
+}
+
+// fakeReleaseSources answers a search with the candidates listed for the
+// query's track title.
+type fakeReleaseSources map[string][]discovery.Candidate
+
+func (f fakeReleaseSources) SearchSources(_ context.Context, query string, _ []string, _ int) discovery.SourceSearchResponse {
+	for title, results := range f {
+		if strings.HasSuffix(query, title) {
+			return discovery.SourceSearchResponse{Query: query, Results: results}
+		}
+	}
+	return discovery.SourceSearchResponse{Query: query, Results: []discovery.Candidate{}}
+}
+
+type fakeReleaseIngestion struct {
+	fakeDirectIngestion
+	origins    []string
+	candidates []download.SourceCandidate
+	recordings []string
+	priorities []string
+}
+
+func (f *fakeReleaseIngestion) CreateSearchedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, _ db.TrustedSourceSelectionQuality, _ string) (*db.SourceSelectionDownload, error) {
+	f.origins = append(f.origins, origin)
+	f.candidates = append(f.candidates, candidate)
+	f.priorities = append(f.priorities, download.PriorityFromContext(ctx))
+	jobID := fmt.Sprintf("track-%d", len(f.candidates))
+	return &db.SourceSelectionDownload{Decision: &db.SourceSelectionDecision{ID: uuid.New(), UserID: userID, Origin: origin}, Job: &download.DownloadJob{ID: jobID, Status: download.StatusQueued}, Candidate: candidate}, nil
+}
+
+func (f *fakeReleaseIngestion) EnqueueTrustedRecordingDownload(_ context.Context, persisted *db.SourceSelectionDownload, _ db.SourceSelectionDownloadEnqueuer, mbRecordingID *string) (*download.DownloadJob, error) {
+	if mbRecordingID != nil {
+		f.recordings = append(f.recordings, *mbRecordingID)
+	}
+	return persisted.Job, nil
+}
+
+func releaseDownloadRequest(releaseID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/downloads/release/"+releaseID, nil)
+	req.SetPathValue("mb_release_id", releaseID)
+	return withUser(req, uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+}
+
+func TestDownloadReleaseQueuesBestSourcePerTrack(t *testing.T) {
+	catalog := fakeReleaseCatalog{testReleaseID: {
+		ID: testReleaseID, Title: "Night Drive", Artist: "Band",
+		Tracks: []musicbrainz.Track{
+			{ID: "rec-1", Title: "Opener", Artist: "Band", Duration: 200000},
+			{ID: "rec-2", Title: "Interlude", Artist: "Band", Duration: 60000},
+			{ID: "rec-3", Title: "Closer", Artist: "Band", Duration: 300000},
+		},
+	}}
+	sources := fakeReleaseSources{
+		"Opener": {
+			{CandidateID: "youtube:fan", Provider: "youtube", SourceID: "fan", SourceURL: "https://www.youtube.com/watch?v=fan", Title: "Band - Opener (live at the pub)", DurationMs: 201000, Downloadable: true},
+			{CandidateID: "youtube:official", Provider: "youtube", SourceID: "official", SourceURL: "https://www.youtube.com/watch?v=official", Title: "Band - Opener (Official Audio)", Uploader: "Band - Topic", DurationMs: 199000, Downloadable: true},
+		},
+		// The only result is far longer than the track, so it is no match.
+		"Interlude": {
+			{CandidateID: "youtube:mix", Provider: "youtube", SourceID: "mix", SourceURL: "https://www.youtube.com/watch?v=mix", Title: "Band - Interlude (Official Audio)", DurationMs: 3600000, Downloadable: true},
+		},
+		"Closer": {
+			{CandidateID: "soundcloud:band/closer", Provider: "soundcloud", SourceID: "band/closer", SourceURL: "https://soundcloud.com/band/closer", Title: "Closer", Artist: "Band", DurationMs: 300000, Downloadable: true},
+		},
+	}
+	ingestion := &fakeReleaseIngestion{}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, ingestion)
+	handler.SetReleaseSources(catalog, sources)
+
+	rec := httptest.NewRecorder()
+	handler.DownloadRelease(rec, releaseDownloadRequest(testReleaseID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	var resp ReleaseDownloadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.JobID != "parent-1" || resp.Title != "Night Drive" || len(resp.Tracks) != 3 {
+		t.Fatalf("response = %+v", resp)
+	}
+	want := []struct{ status, jobID string }{{ReleaseTrackQueued, "track-1"}, {ReleaseTrackNoMatch, ""}, {ReleaseTrackQueued, "track-2"}}
+	for i, w := range want {
+		if got := resp.Tracks[i]; got.Status != w.status || got.JobID != w.jobID || got.Position != i+1 {
+			t.Fatalf("track %d = %+v, want %s %q", i+1, got, w.status, w.jobID)
+		}
+	}
+
+	if len(ingestion.candidates) != 2 || ingestion.candidates[0].SourceID != "official" {
+		t.Fatalf("queued candidates = %+v, want the official audio for the opener", ingestion.candidates)
+	}
+	first := ingestion.candidates[0]
+	if first.Title != "Opener" || first.Album != "Night Drive" || first.Metadata["mbReleaseId"] != testReleaseID || ingestion.origins[0] != db.SourceSelectionOriginRelease {
+		t.Fatalf("first candidate = %+v, origin %q", first, ingestion.origins[0])
+	}
+	if strings.Join(ingestion.recordings, ",") != "rec-1,rec-3" {
+		t.Fatalf("recordings = %v, want each queued track's recording", ingestion.recordings)
+	}
+	if ingestion.priorities[0] != download.PriorityBatch {
+		t.Fatalf("priority = %q, want batch", ingestion.priorities[0])
+	}
+}
+
+func TestDownloadReleaseErrors(t *testing.T) {
+	catalog := fakeReleaseCatalog{testReleaseID: {ID: testReleaseID, Title: "Unknown", Tracks: []musicbrainz.Track{{ID: "rec-1", Title: "Nothing"}}}}
+	handler := NewDownloadHandlers(fakeDirectDownloadService{}, &fakeReleaseIngestion{})
+	handler.SetReleaseSources(catalog, fakeReleaseSources{})
+
+	for name, tc := range map[string]struct {
+		releaseID string
+		status    int
+		code      string
+	}{
+		"invalid id": {"not-a-release", http.StatusBadRequest, "INVALID_REQUEST"},
+		"unknown":    {uuid.NewString(), http.StatusNotFound, "RELEASE_NOT_FOUND"},
+		"no sources": {testReleaseID, http.StatusUnprocessableEntity, "NO_SOURCES_FOUND"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.DownloadRelease(rec, releaseDownloadRequest(tc.releaseID))
+			if rec.Code != tc.status || !strings.Contains(rec.Body.String(), tc.code) {
+				t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReleaseDurationMatches(t *testing.T) {
+	for _, tc := range []struct {
+		track, source int
+		want          bool
+	}{
+		{100000, 114000, true},
+		{100000, 116000, false},
+		{600000, 650000, true},
+		{600000, 670000, false},
+		{0, 900000, true},
+	} {
+		if got := releaseDurationMatches(tc.track, tc.source); got != tc.want {
+			t.Errorf("releaseDurationMatches(%d, %d) = %v, want %v", tc.track, tc.source, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// ReplayGain headers carry a track's normalization values on audio
+// responses, named after the REPLAYGAIN_* tags players already read. Gains
+// are "<dB> dB" and peaks are linear sample amplitudes, as in the tags.
+const (
+	headerReplayGainTrackGain = "X-ReplayGain-Track-Gain"
+	headerReplayGainTrackPeak = "X-ReplayGain-Track-Peak"
+	headerReplayGainAlbumGain = "X-ReplayGain-Album-Gain"
+	headerReplayGainAlbumPeak = "X-ReplayGain-Album-Peak"
+)
+
+// setReplayGainHeaders sets the ReplayGain headers for the values the track
+// has been measured for.
+func setReplayGainHeaders(h http.Header, rg db.ReplayGain) {
+	if rg.TrackGainDB.Valid {
+		h.Set(headerReplayGainTrackGain, formatReplayGainDB(rg.TrackGainDB.Float64))
+	}
+	if rg.TruePeakDBTP.Valid {
+		h.Set(headerReplayGainTrackPeak, formatReplayGainPeak(rg.TruePeakDBTP.Float64))
+	}
+	if rg.AlbumGainDB.Valid {
+		h.Set(headerReplayGainAlbumGain, formatReplayGainDB(rg.AlbumGainDB.Float64))
+	}
+	if rg.AlbumPeakDBTP.Valid {
+		h.Set(headerReplayGainAlbumPeak, formatReplayGainPeak(rg.AlbumPeakDBTP.Float64))
+	}
+}
+
+func formatReplayGainDB(gain float64) string {
+	return strconv.FormatFloat(gain, 'f', 2, 64) + " dB"
+}
+
+func formatReplayGainPeak(dbtp float64) string {
+	return strconv.FormatFloat(math.Pow(10, dbtp/20), 'f', 6, 64)
+}
+
+// replayGainValue returns a measured value, or nil for an omitted JSON field.
+func replayGainValue(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/retention"
+)
+
+type retentionService interface {
+	Policies(ctx context.Context) ([]retention.Policy, error)
+	SetPolicy(ctx context.Context, dataType string, maxAgeDays *int, updatedBy uuid.UUID) (retention.Policy, error)
+	Purge(ctx context.Context) (retention.Report, error)
+	Interval() time.Duration
+}
+
+// RetentionHandlers lets admins view and change how long each kind of
+// accumulating data is kept, and run a purge on demand.
+type RetentionHandlers struct {
+	service retentionService
+	// scheduled reports whether the purge loop is running; when it is not,
+	// policies are only applied by POST /api/v1/admin/retention/purge.
+	scheduled bool
+}
+
+func NewRetentionHandlers(service retentionService, scheduled bool) *RetentionHandlers {
+	return &RetentionHandlers{service: service, scheduled: scheduled}
+}
+
+type RetentionPolicyResponse struct {
+	DataType          string     `json:"dataType"`
+	MaxAgeDays        int        `json:"maxAgeDays"`
+	DefaultMaxAgeDays int        `json:"defaultMaxAgeDays"`
+	Overridden        bool       `json:"overridden"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	LastPurgedAt      *time.Time `json:"lastPurgedAt,omitempty"`
+	LastDeleted       int64      `json:"lastDeleted"`
+	LastError         string     `json:"lastError,omitempty"`
+}
+
+type RetentionPoliciesResponse struct {
+	Scheduled       bool                      `json:"scheduled"`
+	IntervalSeconds int64                     `json:"intervalSeconds"`
+	Policies        []RetentionPolicyResponse `json:"policies"`
+}
+
+type UpdateRetentionPolicyRequest struct {
+	MaxAgeDays *int `json:"maxAgeDays"`
+}
+
+type RetentionPurgeResponse struct {
+	Deleted  map[string]int64 `json:"deleted"`
+	Failures int              `json:"failures"`
+}
+
+// ListPolicies handles GET /api/v1/admin/retention
+func (h *RetentionHandlers) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	if h.requireAdmin(w, r) == nil {
+		return
+	}
+	policies, err := h.service.Policies(r.Context())
+	if err != nil {
+		log.Printf("Warning: failed to load retention policies: %v", err)
+		writeRetentionError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load retention policies")
+		return
+	}
+	resp := RetentionPoliciesResponse{
+		Scheduled:       h.scheduled,
+		IntervalSeconds: int64(h.service.Interval().Seconds()),
+		Policies:        make([]RetentionPolicyResponse, 0, len(policies)),
+	}
+	for _, p := range policies {
+		resp.Policies = append(resp.Policies, newRetentionPolicyResponse(p))
+	}
+	writeRetentionJSON(w, http.StatusOK, resp)
+}
+
+// UpdatePolicy handles PUT /api/v1/admin/retention/{data_type}. maxAgeDays 0
+// keeps the data forever; null restores the configured default.
+func (h *RetentionHandlers) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	userCtx := h.requireAdmin(w, r)
+	if userCtx == nil {
+		return
+	}
+	var req UpdateRetentionPolicyRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeRetentionError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+
+	policy, err := h.service.SetPolicy(r.Context(), r.PathValue("data_type"), req.MaxAgeDays, userCtx.UserID)
+	switch {
+	case errors.Is(err, retention.ErrUnknownDataType):
+		writeRetentionError(w, http.StatusNotFound, "UNKNOWN_DATA_TYPE", "data type has no retention policy")
+	case errors.Is(err, retention.ErrInvalidMaxAgeDays):
+		writeRetentionError(w, http.StatusBadRequest, "INVALID_MAX_AGE", "maxAgeDays must be between 0 and 36500, or null to restore the default")
+	case err != nil:
+		log.Printf("Warning: failed to update retention policy: %v", err)
+		writeRetentionError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update retention policy")
+	default:
+		writeRetentionJSON(w, http.StatusOK, newRetentionPolicyResponse(policy))
+	}
+}
+
+// Purge handles POST /api/v1/admin/retention/purge. It runs a full purge
+// before responding, waiting for a scheduled one in progress to finish first.
+func (h *RetentionHandlers) Purge(w http.ResponseWriter, r *http.Request) {
+	if h.requireAdmin(w, r) == nil {
+		return
+	}
+	report, err := h.service.Purge(r.Context())
+	if err != nil {
+		log.Printf("Warning: manual retention purge failed: %v", err)
+		writeRetentionError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "retention purge failed")
+		return
+	}
+	writeRetentionJSON(w, http.StatusOK, RetentionPurgeResponse{Deleted: report.Deleted, Failures: report.Failures})
+}
+
+func (h *RetentionHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) *auth.UserContext {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeRetentionError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil
+	}
+	if !userCtx.IsAdmin() {
+		writeRetentionError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return nil
+	}
+	return userCtx
+}
+
+func newRetentionPolicyResponse(p retention.Policy) RetentionPolicyResponse {
+	resp := RetentionPolicyResponse{
+		DataType:          p.DataType,
+		MaxAgeDays:        p.MaxAgeDays,
+		DefaultMaxAgeDays: p.DefaultMaxAgeDays,
+		Overridden:        p.Overridden,
+		LastDeleted:       p.LastDeleted,
+		LastError:         p.LastError,
+	}
+	if !p.UpdatedAt.IsZero() {
+		updatedAt := p.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	if !p.LastPurgedAt.IsZero() {
+		purgedAt := p.LastPurgedAt
+		resp.LastPurgedAt = &purgedAt
+	}
+	return resp
+}
+
+func writeRetentionJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeRetentionError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/retention"
+)
+
+type fakeRetentionService struct {
+	set *int
+}
+
+func (f *fakeRetentionService) Policies(context.Context) ([]retention.Policy, error) {
+	return []retention.Policy{{DataType: retention.Notifications, MaxAgeDays: 90, DefaultMaxAgeDays: 90}}, nil
+}
+
+func (f *fakeRetentionService) SetPolicy(_ context.Context, dataType string, maxAgeDays *int, _ uuid.UUID) (retention.Policy, error) {
+	if dataType != retention.Notifications {
+		return retention.Policy{}, retention.ErrUnknownDataType
+	}
+	if maxAgeDays != nil && *maxAgeDays < 0 {
+		return retention.Policy{}, retention.ErrInvalidMaxAgeDays
+	}
+	f.set = maxAgeDays
+	return retention.Policy{DataType: dataType, MaxAgeDays: *maxAgeDays, DefaultMaxAgeDays: 90, Overridden: true, UpdatedAt: time.Now()}, nil
+}
+
+func (f *fakeRetentionService) Purge(context.Context) (retention.Report, error) {
+	return retention.Report{Deleted: map[string]int64{retention.Notifications: 3}}, nil
+}
+
+func (f *fakeRetentionService) Interval() time.Duration {
+	return 6 * time.Hour
+}
+
+func retentionRequest(method, target, body, email string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestRetentionPoliciesAreAdminOnly(t *testing.T) {
+	h := NewRetentionHandlers(&fakeRetentionService{}, true)
+
+	rec := httptest.NewRecorder()
+	h.ListPolicies(rec, retentionRequest(http.MethodGet, "/api/v1/admin/retention", "", "ops@example.test"))
+	var resp RetentionPoliciesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if !resp.Scheduled || resp.IntervalSeconds != 21600 || len(resp.Policies) != 1 || resp.Policies[0].LastPurgedAt != nil {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Purge(rec, retentionRequest(http.MethodPost, "/api/v1/admin/retention/purge", "", "listener@example.test"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin purge: status = %d", rec.Code)
+	}
+}
+
+func TestUpdateRetentionPolicy(t *testing.T) {
+	service := &fakeRetentionService{}
+	h := NewRetentionHandlers(service, true)
+	for _, tc := range []struct {
+		dataType, body string
+		want           int
+	}{
+		{retention.Notifications, `{"maxAgeDays":14}`, http.StatusOK},
+		{retention.Notifications, `{"maxAgeDays":-1}`, http.StatusBadRequest},
+		{retention.Notifications, `{"days":14}`, http.StatusBadRequest},
+		{"crash_reports", `{"maxAgeDays":14}`, http.StatusNotFound},
+	} {
+		req := retentionRequest(http.MethodPut, "/api/v1/admin/retention/"+tc.dataType, tc.body, "ops@example.test")
+		req.SetPathValue("data_type", tc.dataType)
+		rec := httptest.NewRecorder()
+		h.UpdatePolicy(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d (%s)", tc.dataType, tc.body, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+	if service.set == nil || *service.set != 14 {
+		t.Fatalf("saved maxAgeDays = %v", service.set)
+	}
+}
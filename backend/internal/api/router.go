@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/discovery"
@@ -20,35 +21,75 @@ import (
 )
 
 type Router struct {
-	mux                     *http.ServeMux
-	authHandlers            *auth.Handlers
-	authService             *auth.Service
-	searchHandlers          *search.Handlers
-	browseHandlers          *BrowseHandlers
-	musicbrainzHandlers     *musicbrainz.Handlers
-	wsHandler               *websocket.Handler
-	validatorHandlers       *validators.Handlers
-	matcherHandlers         *matcher.Handler
-	libraryHandlers         *LibraryHandlers
-	analysisHandlers        *AnalysisHandlers
-	playbackHandlers        *PlaybackHandlers
-	queueHandlers           *queue.Handlers
-	discoveryHandlers       *discovery.Handlers
-	agentToolsHandler       http.Handler
-	playlistHandlers        *PlaylistHandlers
-	playlistImportHandlers  *PlaylistImportHandlers
-	playlistMixHandlers     *PlaylistMixHandlers
-	mixPlanHandlers         *MixPlanHandlers
-	downloadHandlers        *DownloadHandlers
-	sourceSelectionHandlers *SourceSelectionHandlers
-	maintenanceHandlers     *MaintenanceHandlers
-	playEventHandlers       *PlayEventHandlers
-	researchHandlers        *ResearchHandlers
-	healthHandler           *health.Handler
-	metricsHandler          http.HandlerFunc
-	corsAllowedOrigins      []string
+	mux                      *http.ServeMux
+	authHandlers             *auth.Handlers
+	authService              *auth.Service
+	searchHandlers           *search.Handlers
+	browseHandlers           *BrowseHandlers
+	musicbrainzHandlers      *musicbrainz.Handlers
+	wsHandler                *websocket.Handler
+	validatorHandlers        *validators.Handlers
+	matcherHandlers          *matcher.Handler
+	libraryHandlers          *LibraryHandlers
+	analysisHandlers         *AnalysisHandlers
+	playbackHandlers         *PlaybackHandlers
+	queueHandlers            *queue.Handlers
+	discoveryHandlers        *discovery.Handlers
+	agentToolsHandler        http.Handler
+	playlistHandlers         *PlaylistHandlers
+	playlistImportHandlers   *PlaylistImportHandlers
+	playlistMixHandlers      *PlaylistMixHandlers
+	playlistExportHandlers   *PlaylistExportHandlers
+	mixPlanHandlers          *MixPlanHandlers
+	downloadHandlers         *DownloadHandlers
+	sourceSelectionHandlers  *SourceSelectionHandlers
+	maintenanceHandlers      *MaintenanceHandlers
+	playEventHandlers        *PlayEventHandlers
+	researchHandlers         *ResearchHandlers
+	profileHandlers          *ProfileHandlers
+	accountProfileHandlers   *AccountProfileHandlers
+	collaborationHandlers    *PlaylistCollaborationHandlers
+	notificationHandlers     *NotificationHandlers
+	wrappedHandlers          *WrappedHandlers
+	goalHandlers             *ListeningGoalHandlers
+	statsHandlers            *StatsHandlers
+	recommendationHandlers   *RecommendationHandlers
+	libraryImportHandlers    *LibraryImportHandlers
+	playlistFileHandlers     *PlaylistFileImportHandlers
+	trackSourceHandlers      *TrackSourceHandlers
+	trackChapterHandlers     *TrackChapterHandlers
+	trackWaveformHandlers    *TrackWaveformHandlers
+	lyricsHandlers           *LyricsHandlers
+	entityHandlers           *EntityHandlers
+	trackDeletionHandlers    *TrackDeletionHandlers
+	takedownHandlers         *TakedownHandlers
+	downloadOutcomeHandlers  *DownloadOutcomeHandlers
+	downloadLimitHandlers    *DownloadLimitHandlers
+	downloadSettingsHandlers *DownloadSettingsHandlers
+	scrobbleHandlers         *ScrobbleHandlers
+	uploadHandlers           *UploadHandlers
+	trackGrantHandlers       *TrackGrantHandlers
+	calendarHandlers         *CalendarHandlers
+	playlistLinkHandlers     *PlaylistLinkHandlers
+	playbackTransferHandlers *PlaybackTransferHandlers
+	batchMatchHandlers       *BatchMatchHandlers
+	artworkBackfillHandlers  *ArtworkBackfillHandlers
+	libraryScanHandlers      *LibraryScanHandlers
+	beetsExportHandlers      *BeetsExportHandlers
+	libraryExportHandlers    *LibraryExportHandlers
+	telemetryHandlers        *TelemetryHandlers
+	retentionHandlers        *RetentionHandlers
+	artworkHandlers          *ArtworkHandlers
+	publicRateLimiter        *middleware.RateLimiter
+	healthHandler            *health.Handler
+	metricsHandler           http.HandlerFunc
+	corsAllowedOrigins       []string
 }
 
+// publicRequestsPerMinute bounds unauthenticated requests that read shared
+// content, per client address.
+const publicRequestsPerMinute = 120
+
 var defaultCORSAllowedOrigins = []string{
 	"http://localhost:18145",
 	"http://127.0.0.1:18145",
@@ -56,31 +97,66 @@ var defaultCORSAllowedOrigins = []string{
 
 // RouterConfig holds configuration for creating a new router
 type RouterConfig struct {
-	AuthHandlers            *auth.Handlers
-	AuthService             *auth.Service
-	SearchHandlers          *search.Handlers
-	MBClient                *musicbrainz.Client
-	MBHandlers              *musicbrainz.Handlers
-	WSHandler               *websocket.Handler
-	MatcherHandlers         *matcher.Handler
-	LibraryHandlers         *LibraryHandlers
-	AnalysisHandlers        *AnalysisHandlers
-	PlaybackHandlers        *PlaybackHandlers
-	QueueHandlers           *queue.Handlers
-	DiscoveryHandlers       *discovery.Handlers
-	AgentToolsHandler       http.Handler
-	PlaylistHandlers        *PlaylistHandlers
-	PlaylistImportHandlers  *PlaylistImportHandlers
-	PlaylistMixHandlers     *PlaylistMixHandlers
-	MixPlanHandlers         *MixPlanHandlers
-	DownloadHandlers        *DownloadHandlers
-	SourceSelectionHandlers *SourceSelectionHandlers
-	MaintenanceHandlers     *MaintenanceHandlers
-	PlayEventHandlers       *PlayEventHandlers
-	ResearchHandlers        *ResearchHandlers
-	HealthHandler           *health.Handler
-	Metrics                 *metrics.Metrics
-	CORSAllowedOrigins      []string
+	AuthHandlers             *auth.Handlers
+	AuthService              *auth.Service
+	SearchHandlers           *search.Handlers
+	MBClient                 *musicbrainz.Client
+	MBHandlers               *musicbrainz.Handlers
+	WSHandler                *websocket.Handler
+	MatcherHandlers          *matcher.Handler
+	LibraryHandlers          *LibraryHandlers
+	AnalysisHandlers         *AnalysisHandlers
+	PlaybackHandlers         *PlaybackHandlers
+	QueueHandlers            *queue.Handlers
+	DiscoveryHandlers        *discovery.Handlers
+	AgentToolsHandler        http.Handler
+	PlaylistHandlers         *PlaylistHandlers
+	PlaylistImportHandlers   *PlaylistImportHandlers
+	PlaylistMixHandlers      *PlaylistMixHandlers
+	PlaylistExportHandlers   *PlaylistExportHandlers
+	MixPlanHandlers          *MixPlanHandlers
+	DownloadHandlers         *DownloadHandlers
+	SourceSelectionHandlers  *SourceSelectionHandlers
+	MaintenanceHandlers      *MaintenanceHandlers
+	PlayEventHandlers        *PlayEventHandlers
+	ResearchHandlers         *ResearchHandlers
+	ProfileHandlers          *ProfileHandlers
+	AccountProfileHandlers   *AccountProfileHandlers
+	CollaborationHandlers    *PlaylistCollaborationHandlers
+	NotificationHandlers     *NotificationHandlers
+	WrappedHandlers          *WrappedHandlers
+	GoalHandlers             *ListeningGoalHandlers
+	StatsHandlers            *StatsHandlers
+	RecommendationHandlers   *RecommendationHandlers
+	LibraryImportHandlers    *LibraryImportHandlers
+	PlaylistFileHandlers     *PlaylistFileImportHandlers
+	TrackSourceHandlers      *TrackSourceHandlers
+	TrackChapterHandlers     *TrackChapterHandlers
+	TrackWaveformHandlers    *TrackWaveformHandlers
+	LyricsHandlers           *LyricsHandlers
+	EntityHandlers           *EntityHandlers
+	TrackDeletionHandlers    *TrackDeletionHandlers
+	TakedownHandlers         *TakedownHandlers
+	DownloadOutcomeHandlers  *DownloadOutcomeHandlers
+	DownloadLimitHandlers    *DownloadLimitHandlers
+	DownloadSettingsHandlers *DownloadSettingsHandlers
+	ScrobbleHandlers         *ScrobbleHandlers
+	UploadHandlers           *UploadHandlers
+	TrackGrantHandlers       *TrackGrantHandlers
+	CalendarHandlers         *CalendarHandlers
+	PlaylistLinkHandlers     *PlaylistLinkHandlers
+	PlaybackTransferHandlers *PlaybackTransferHandlers
+	BatchMatchHandlers       *BatchMatchHandlers
+	ArtworkBackfillHandlers  *ArtworkBackfillHandlers
+	LibraryScanHandlers      *LibraryScanHandlers
+	BeetsExportHandlers      *BeetsExportHandlers
+	LibraryExportHandlers    *LibraryExportHandlers
+	TelemetryHandlers        *TelemetryHandlers
+	RetentionHandlers        *RetentionHandlers
+	ArtworkHandlers          *ArtworkHandlers
+	HealthHandler            *health.Handler
+	Metrics                  *metrics.Metrics
+	CORSAllowedOrigins       []string
 }
 
 func NewRouter(authHandlers *auth.Handlers, authService *auth.Service, searchHandlers *search.Handlers, mbClient *musicbrainz.Client, mbHandlers *musicbrainz.Handlers, wsHandler *websocket.Handler, matcherHandlers *matcher.Handler, libraryHandlers *LibraryHandlers, queueHandlers *queue.Handlers, playlistHandlers *PlaylistHandlers, downloadHandlers *DownloadHandlers) *Router {
@@ -113,33 +189,69 @@ func NewRouterWithConfig(cfg *RouterConfig) *Router {
 	}
 
 	r := &Router{
-		mux:                     http.NewServeMux(),
-		authHandlers:            cfg.AuthHandlers,
-		authService:             cfg.AuthService,
-		searchHandlers:          cfg.SearchHandlers,
-		browseHandlers:          NewBrowseHandlers(cfg.MBClient),
-		musicbrainzHandlers:     cfg.MBHandlers,
-		wsHandler:               cfg.WSHandler,
-		validatorHandlers:       validators.NewHandlers(validatorRegistry),
-		matcherHandlers:         cfg.MatcherHandlers,
-		libraryHandlers:         cfg.LibraryHandlers,
-		analysisHandlers:        cfg.AnalysisHandlers,
-		playbackHandlers:        cfg.PlaybackHandlers,
-		queueHandlers:           cfg.QueueHandlers,
-		discoveryHandlers:       cfg.DiscoveryHandlers,
-		agentToolsHandler:       cfg.AgentToolsHandler,
-		playlistHandlers:        cfg.PlaylistHandlers,
-		playlistImportHandlers:  cfg.PlaylistImportHandlers,
-		playlistMixHandlers:     cfg.PlaylistMixHandlers,
-		mixPlanHandlers:         cfg.MixPlanHandlers,
-		downloadHandlers:        cfg.DownloadHandlers,
-		sourceSelectionHandlers: cfg.SourceSelectionHandlers,
-		maintenanceHandlers:     cfg.MaintenanceHandlers,
-		playEventHandlers:       cfg.PlayEventHandlers,
-		researchHandlers:        cfg.ResearchHandlers,
-		healthHandler:           cfg.HealthHandler,
-		metricsHandler:          metricsHandler,
-		corsAllowedOrigins:      corsAllowedOrigins,
+		mux:                      http.NewServeMux(),
+		authHandlers:             cfg.AuthHandlers,
+		authService:              cfg.AuthService,
+		searchHandlers:           cfg.SearchHandlers,
+		browseHandlers:           NewBrowseHandlers(cfg.MBClient),
+		musicbrainzHandlers:      cfg.MBHandlers,
+		wsHandler:                cfg.WSHandler,
+		validatorHandlers:        validators.NewHandlers(validatorRegistry),
+		matcherHandlers:          cfg.MatcherHandlers,
+		libraryHandlers:          cfg.LibraryHandlers,
+		analysisHandlers:         cfg.AnalysisHandlers,
+		playbackHandlers:         cfg.PlaybackHandlers,
+		queueHandlers:            cfg.QueueHandlers,
+		discoveryHandlers:        cfg.DiscoveryHandlers,
+		agentToolsHandler:        cfg.AgentToolsHandler,
+		playlistHandlers:         cfg.PlaylistHandlers,
+		playlistImportHandlers:   cfg.PlaylistImportHandlers,
+		playlistMixHandlers:      cfg.PlaylistMixHandlers,
+		playlistExportHandlers:   cfg.PlaylistExportHandlers,
+		mixPlanHandlers:          cfg.MixPlanHandlers,
+		downloadHandlers:         cfg.DownloadHandlers,
+		sourceSelectionHandlers:  cfg.SourceSelectionHandlers,
+		maintenanceHandlers:      cfg.MaintenanceHandlers,
+		playEventHandlers:        cfg.PlayEventHandlers,
+		researchHandlers:         cfg.ResearchHandlers,
+		profileHandlers:          cfg.ProfileHandlers,
+		accountProfileHandlers:   cfg.AccountProfileHandlers,
+		collaborationHandlers:    cfg.CollaborationHandlers,
+		notificationHandlers:     cfg.NotificationHandlers,
+		wrappedHandlers:          cfg.WrappedHandlers,
+		goalHandlers:             cfg.GoalHandlers,
+		statsHandlers:            cfg.StatsHandlers,
+		recommendationHandlers:   cfg.RecommendationHandlers,
+		libraryImportHandlers:    cfg.LibraryImportHandlers,
+		playlistFileHandlers:     cfg.PlaylistFileHandlers,
+		trackSourceHandlers:      cfg.TrackSourceHandlers,
+		trackChapterHandlers:     cfg.TrackChapterHandlers,
+		trackWaveformHandlers:    cfg.TrackWaveformHandlers,
+		lyricsHandlers:           cfg.LyricsHandlers,
+		entityHandlers:           cfg.EntityHandlers,
+		trackDeletionHandlers:    cfg.TrackDeletionHandlers,
+		takedownHandlers:         cfg.TakedownHandlers,
+		downloadOutcomeHandlers:  cfg.DownloadOutcomeHandlers,
+		downloadLimitHandlers:    cfg.DownloadLimitHandlers,
+		downloadSettingsHandlers: cfg.DownloadSettingsHandlers,
+		scrobbleHandlers:         cfg.ScrobbleHandlers,
+		uploadHandlers:           cfg.UploadHandlers,
+		trackGrantHandlers:       cfg.TrackGrantHandlers,
+		calendarHandlers:         cfg.CalendarHandlers,
+		playlistLinkHandlers:     cfg.PlaylistLinkHandlers,
+		playbackTransferHandlers: cfg.PlaybackTransferHandlers,
+		batchMatchHandlers:       cfg.BatchMatchHandlers,
+		artworkBackfillHandlers:  cfg.ArtworkBackfillHandlers,
+		libraryScanHandlers:      cfg.LibraryScanHandlers,
+		beetsExportHandlers:      cfg.BeetsExportHandlers,
+		libraryExportHandlers:    cfg.LibraryExportHandlers,
+		telemetryHandlers:        cfg.TelemetryHandlers,
+		retentionHandlers:        cfg.RetentionHandlers,
+		artworkHandlers:          cfg.ArtworkHandlers,
+		publicRateLimiter:        middleware.NewRateLimiter(publicRequestsPerMinute, time.Minute),
+		healthHandler:            cfg.HealthHandler,
+		metricsHandler:           metricsHandler,
+		corsAllowedOrigins:       corsAllowedOrigins,
 	}
 	r.setupRoutes()
 	return r
@@ -183,12 +295,26 @@ func (r *Router) setupRoutes() {
 	r.mux.HandleFunc("POST /api/v1/auth/register", r.authHandlers.Register)
 	r.mux.HandleFunc("POST /api/v1/auth/login", r.authHandlers.Login)
 	r.mux.HandleFunc("POST /api/v1/auth/refresh", r.authHandlers.Refresh)
+	r.mux.HandleFunc("GET /api/v1/auth/oidc", r.authHandlers.OIDCStatus)
+	r.mux.HandleFunc("GET /api/v1/auth/oidc/login", r.authHandlers.OIDCLogin)
+	r.mux.HandleFunc("GET /api/v1/auth/oidc/callback", r.authHandlers.OIDCCallback)
+	r.mux.HandleFunc("POST /api/v1/auth/oidc/exchange", r.authHandlers.OIDCExchange)
 
 	// Auth routes (auth required)
 	r.mux.HandleFunc("POST /api/v1/auth/logout", r.withAuth(r.authHandlers.Logout))
+	r.mux.HandleFunc("POST /api/v1/auth/api-keys", r.withAuth(r.authHandlers.CreateAPIKey))
+	r.mux.HandleFunc("GET /api/v1/auth/api-keys", r.withAuth(r.authHandlers.ListAPIKeys))
+	r.mux.HandleFunc("DELETE /api/v1/auth/api-keys/{id}", r.withAuth(r.authHandlers.RevokeAPIKey))
+	r.mux.HandleFunc("GET /api/v1/auth/sessions", r.withAuth(r.authHandlers.ListSessions))
+	r.mux.HandleFunc("DELETE /api/v1/auth/sessions", r.withAuth(r.authHandlers.RevokeOtherSessions))
+	r.mux.HandleFunc("DELETE /api/v1/auth/sessions/{id}", r.withAuth(r.authHandlers.RevokeSession))
+
+	// User management routes (admin role required)
+	r.mux.HandleFunc("GET /api/v1/admin/users", r.withAdmin(r.authHandlers.ListUsers))
+	r.mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", r.withAdmin(r.authHandlers.UpdateUserRole))
 
 	// Search routes - local database (auth required)
-	r.mux.HandleFunc("GET /api/v1/search", r.withAuth(r.searchHandlers.Search))
+	r.mux.HandleFunc("GET /api/v1/search", r.withAuth(withFields("search", r.searchHandlers.Search)))
 	r.mux.HandleFunc("GET /api/v1/search/recordings", r.withAuth(r.searchHandlers.SearchRecordings))
 	r.mux.HandleFunc("GET /api/v1/search/artists", r.withAuth(r.searchHandlers.SearchArtists))
 	r.mux.HandleFunc("GET /api/v1/search/releases", r.withAuth(r.searchHandlers.SearchReleases))
@@ -197,6 +323,7 @@ func (r *Router) setupRoutes() {
 	r.mux.HandleFunc("GET /api/v1/musicbrainz/search/tracks", r.withAuth(r.musicbrainzHandlers.SearchTracks))
 	r.mux.HandleFunc("GET /api/v1/musicbrainz/search/artists", r.withAuth(r.musicbrainzHandlers.SearchArtists))
 	r.mux.HandleFunc("GET /api/v1/musicbrainz/search/albums", r.withAuth(r.musicbrainzHandlers.SearchAlbums))
+	r.mux.HandleFunc("POST /api/v1/musicbrainz/lookup:batch", r.withAuth(r.musicbrainzHandlers.LookupBatch))
 
 	// Browse/discovery routes (auth required)
 	if r.discoveryHandlers != nil {
@@ -239,7 +366,30 @@ func (r *Router) setupRoutes() {
 	}
 	r.mux.HandleFunc("GET /api/v1/artists/{mb_id}", r.withAuth(r.browseHandlers.GetArtist))
 	r.mux.HandleFunc("GET /api/v1/albums/{mb_id}", r.withAuth(r.browseHandlers.GetAlbum))
-	r.mux.HandleFunc("GET /api/v1/tracks/{mb_id}", r.withAuth(r.browseHandlers.GetTrack))
+	r.mux.HandleFunc("GET /api/v1/tracks/{mb_id}", r.withAuth(withFields("track", r.browseHandlers.GetTrack)))
+
+	// Release cover art proxy (no auth required). Covers are public catalog
+	// images that clients load directly, e.g. in image tags.
+	if r.artworkHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/artwork/{release_mbid}", r.artworkHandlers.GetReleaseCover)
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/artwork", r.withAuth(r.artworkHandlers.GetTrackArtwork))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/artwork/{release_mbid}", unavailableHandler("Cover art is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/artwork", r.withAuth(unavailableHandler("Cover art is unavailable")))
+	}
+
+	// Artist and album artwork admins uploaded is public catalog data like
+	// release covers; renames and uploads are admin-only.
+	if r.entityHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/artists/{mb_id}/artwork", r.entityHandlers.GetArtistArtwork)
+		r.mux.HandleFunc("GET /api/v1/albums/{mb_id}/artwork", r.entityHandlers.GetAlbumArtwork)
+		r.mux.HandleFunc("PATCH /api/v1/admin/artists/{mb_id}", r.withAdmin(r.entityHandlers.RenameArtist))
+		r.mux.HandleFunc("PATCH /api/v1/admin/albums/{mb_id}", r.withAdmin(r.entityHandlers.RenameAlbum))
+		r.mux.HandleFunc("PUT /api/v1/admin/artists/{mb_id}/artwork", r.withAdmin(r.entityHandlers.UploadArtistArtwork))
+		r.mux.HandleFunc("DELETE /api/v1/admin/artists/{mb_id}/artwork", r.withAdmin(r.entityHandlers.DeleteArtistArtwork))
+		r.mux.HandleFunc("PUT /api/v1/admin/albums/{mb_id}/artwork", r.withAdmin(r.entityHandlers.UploadAlbumArtwork))
+		r.mux.HandleFunc("DELETE /api/v1/admin/albums/{mb_id}/artwork", r.withAdmin(r.entityHandlers.DeleteAlbumArtwork))
+	}
 
 	// WebSocket route (auth via query param)
 	r.mux.HandleFunc("GET /api/v1/ws/progress", r.wsHandler.ServeWS)
@@ -252,15 +402,161 @@ func (r *Router) setupRoutes() {
 	// Auto-matching routes (auth required)
 	r.mux.HandleFunc("POST /api/v1/match", r.withAuth(r.matcherHandlers.HandleMatch))
 	r.mux.HandleFunc("POST /api/v1/tracks/{id}/match", r.withAuth(r.matcherHandlers.HandleMatchTrack))
+	r.mux.HandleFunc("GET /api/v1/tracks/{id}/match-explanation", r.withAuth(r.matcherHandlers.HandleMatchExplanation))
 	r.mux.HandleFunc("POST /api/v1/tracks/{id}/confirm-match", r.withAuth(r.matcherHandlers.HandleConfirmMatch))
 	r.mux.HandleFunc("POST /api/v1/tracks/{id}/link-mb", r.withAuth(r.matcherHandlers.HandleLinkMB))
+	r.mux.HandleFunc("POST /api/v1/albums/match", r.withAuth(r.matcherHandlers.HandleMatchAlbum))
 
 	// Library routes (auth required)
 	r.mux.HandleFunc("GET /api/v1/library", r.withAuth(r.libraryHandlers.GetLibrary))
 	r.mux.HandleFunc("POST /api/v1/library/tracks/{track_id}", r.withAuth(r.libraryHandlers.AddTrackToLibrary))
 	r.mux.HandleFunc("DELETE /api/v1/library/tracks/{track_id}", r.withAuth(r.libraryHandlers.RemoveTrackFromLibrary))
+	r.mux.HandleFunc("POST /api/v1/library/tracks/batch", r.withAuth(r.libraryHandlers.BatchAddTracksToLibrary))
+	r.mux.HandleFunc("POST /api/v1/library/tracks/batch-remove", r.withAuth(r.libraryHandlers.BatchRemoveTracksFromLibrary))
+	r.mux.HandleFunc("POST /api/v1/tracks/batch", r.withAuth(r.libraryHandlers.BatchGetTracks))
 	r.mux.HandleFunc("POST /api/v1/library/tracks/{track_id}/like", r.withAuth(r.libraryHandlers.LikeTrack))
 	r.mux.HandleFunc("DELETE /api/v1/library/tracks/{track_id}/like", r.withAuth(r.libraryHandlers.UnlikeTrack))
+	r.mux.HandleFunc("GET /api/v1/library/tags", r.withAuth(r.libraryHandlers.ListTags))
+	r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/tags", r.withAuth(r.libraryHandlers.GetTrackTags))
+	r.mux.HandleFunc("POST /api/v1/tracks/{track_id}/tags", r.withAuth(r.libraryHandlers.AddTrackTags))
+	r.mux.HandleFunc("DELETE /api/v1/tracks/{track_id}/tags/{tag}", r.withAuth(r.libraryHandlers.RemoveTrackTag))
+	// Deprecated: the tag routes first shipped under /api/v1/library/tracks;
+	// these aliases stay for clients written against them. Use the
+	// /api/v1/tracks/{track_id}/tags routes above.
+	r.mux.HandleFunc("POST /api/v1/library/tracks/{track_id}/tags", r.withAuth(r.libraryHandlers.AddTrackTags))
+	r.mux.HandleFunc("DELETE /api/v1/library/tracks/{track_id}/tags/{tag}", r.withAuth(r.libraryHandlers.RemoveTrackTag))
+
+	// Library export import routes (auth required)
+	if r.beetsExportHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/library/export/beets", r.withAuth(r.beetsExportHandlers.ExportBeets))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/library/export/beets", r.withAuth(unavailableHandler("Beets export is unavailable")))
+	}
+	if r.libraryExportHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/library/export", r.withAuth(r.libraryExportHandlers.StartExport))
+		r.mux.HandleFunc("GET /api/v1/library/export/{export_id}", r.withAuth(r.libraryExportHandlers.GetExport))
+		r.mux.HandleFunc("DELETE /api/v1/library/export/{export_id}", r.withAuth(r.libraryExportHandlers.CancelExport))
+	} else {
+		libraryExportUnavailable := r.withAuth(unavailableHandler("Library export is unavailable"))
+		r.mux.HandleFunc("POST /api/v1/library/export", libraryExportUnavailable)
+		r.mux.HandleFunc("GET /api/v1/library/export/{export_id}", libraryExportUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/library/export/{export_id}", libraryExportUnavailable)
+	}
+	if r.libraryImportHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/library/import/itunes", r.withAuth(r.libraryImportHandlers.ImportITunes))
+		r.mux.HandleFunc("POST /api/v1/library/import/remote", r.withAuth(r.libraryImportHandlers.ImportRemote))
+		r.mux.HandleFunc("POST /api/v1/library/import/playlist", r.withAuth(r.libraryImportHandlers.ImportPlaylistURL))
+	} else {
+		libraryImportUnavailable := r.withAuth(unavailableHandler("Library import is unavailable"))
+		r.mux.HandleFunc("POST /api/v1/library/import/itunes", libraryImportUnavailable)
+		r.mux.HandleFunc("POST /api/v1/library/import/remote", libraryImportUnavailable)
+		r.mux.HandleFunc("POST /api/v1/library/import/playlist", libraryImportUnavailable)
+	}
+	if r.trackSourceHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/sources", r.withAuth(r.trackSourceHandlers.ListTrackSources))
+		r.mux.HandleFunc("POST /api/v1/tracks/{track_id}/sources/canonical", r.withAuth(r.trackSourceHandlers.SetCanonicalSource))
+	} else {
+		trackSourcesUnavailable := r.withAuth(unavailableHandler("Track sources are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/sources", trackSourcesUnavailable)
+		r.mux.HandleFunc("POST /api/v1/tracks/{track_id}/sources/canonical", trackSourcesUnavailable)
+	}
+	if r.trackChapterHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/chapters", r.withAuth(r.trackChapterHandlers.GetTrackChapters))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/chapters", r.withAuth(unavailableHandler("Track chapters are unavailable")))
+	}
+	if r.trackWaveformHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/waveform", r.withAuth(r.trackWaveformHandlers.GetTrackWaveform))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/waveform", r.withAuth(unavailableHandler("Track waveforms are unavailable")))
+	}
+	if r.lyricsHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/lyrics", r.withAuth(r.lyricsHandlers.GetTrackLyrics))
+		r.mux.HandleFunc("PUT /api/v1/tracks/{track_id}/lyrics", r.withAuth(r.lyricsHandlers.UpdateTrackLyrics))
+		r.mux.HandleFunc("DELETE /api/v1/tracks/{track_id}/lyrics", r.withAuth(r.lyricsHandlers.DeleteTrackLyrics))
+	} else {
+		lyricsUnavailable := r.withAuth(unavailableHandler("Lyrics are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/lyrics", lyricsUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/tracks/{track_id}/lyrics", lyricsUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/tracks/{track_id}/lyrics", lyricsUnavailable)
+	}
+	if r.trackDeletionHandlers != nil {
+		r.mux.HandleFunc("DELETE /api/v1/tracks/{track_id}", r.withAuth(r.trackDeletionHandlers.DeleteTrack))
+	} else {
+		r.mux.HandleFunc("DELETE /api/v1/tracks/{track_id}", r.withAuth(unavailableHandler("Track deletion is unavailable")))
+	}
+	if r.takedownHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/admin/takedowns", r.withAdmin(r.takedownHandlers.ListTakedowns))
+		r.mux.HandleFunc("POST /api/v1/admin/takedowns", r.withAdmin(r.takedownHandlers.CreateTakedown))
+		r.mux.HandleFunc("DELETE /api/v1/admin/takedowns/{id}", r.withAdmin(r.takedownHandlers.LiftTakedown))
+	} else {
+		takedownsUnavailable := r.withAuth(unavailableHandler("Content takedowns are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/admin/takedowns", takedownsUnavailable)
+		r.mux.HandleFunc("POST /api/v1/admin/takedowns", takedownsUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/admin/takedowns/{id}", takedownsUnavailable)
+	}
+	if r.downloadOutcomeHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/admin/download-outcomes", r.withAdmin(r.downloadOutcomeHandlers.GetProviderReport))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/admin/download-outcomes", r.withAuth(unavailableHandler("Download outcome reports are unavailable")))
+	}
+	if r.downloadLimitHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/admin/download-limits", r.withAdmin(r.downloadLimitHandlers.ListLimits))
+		r.mux.HandleFunc("PUT /api/v1/admin/download-limits/{provider}", r.withAdmin(r.downloadLimitHandlers.UpdateLimit))
+		r.mux.HandleFunc("GET /api/v1/admin/downloads/settings", r.withAdmin(r.downloadLimitHandlers.GetSchedule))
+		r.mux.HandleFunc("PUT /api/v1/admin/downloads/settings", r.withAdmin(r.downloadLimitHandlers.UpdateSchedule))
+	} else {
+		downloadLimitsUnavailable := r.withAuth(unavailableHandler("Download workers are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/admin/download-limits", downloadLimitsUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/admin/download-limits/{provider}", downloadLimitsUnavailable)
+		r.mux.HandleFunc("GET /api/v1/admin/downloads/settings", downloadLimitsUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/admin/downloads/settings", downloadLimitsUnavailable)
+	}
+	if r.telemetryHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/admin/telemetry", r.withAdmin(r.telemetryHandlers.GetPreview))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/admin/telemetry", r.withAuth(unavailableHandler("Telemetry preview is unavailable")))
+	}
+	if r.retentionHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/admin/retention", r.withAdmin(r.retentionHandlers.ListPolicies))
+		r.mux.HandleFunc("PUT /api/v1/admin/retention/{data_type}", r.withAdmin(r.retentionHandlers.UpdatePolicy))
+		r.mux.HandleFunc("POST /api/v1/admin/retention/purge", r.withAdmin(r.retentionHandlers.Purge))
+	} else {
+		retentionUnavailable := r.withAuth(unavailableHandler("Retention settings are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/admin/retention", retentionUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/admin/retention/{data_type}", retentionUnavailable)
+		r.mux.HandleFunc("POST /api/v1/admin/retention/purge", retentionUnavailable)
+	}
+	if r.batchMatchHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/admin/match/batch", r.withAdmin(r.batchMatchHandlers.StartBatch))
+		r.mux.HandleFunc("GET /api/v1/admin/match/batch", r.withAdmin(r.batchMatchHandlers.GetBatch))
+		r.mux.HandleFunc("DELETE /api/v1/admin/match/batch", r.withAdmin(r.batchMatchHandlers.CancelBatch))
+	} else {
+		batchMatchUnavailable := r.withAuth(unavailableHandler("Batch matching is unavailable"))
+		r.mux.HandleFunc("POST /api/v1/admin/match/batch", batchMatchUnavailable)
+		r.mux.HandleFunc("GET /api/v1/admin/match/batch", batchMatchUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/admin/match/batch", batchMatchUnavailable)
+	}
+	if r.artworkBackfillHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/admin/artwork/backfill", r.withAdmin(r.artworkBackfillHandlers.StartBackfill))
+		r.mux.HandleFunc("GET /api/v1/admin/artwork/backfill", r.withAdmin(r.artworkBackfillHandlers.GetBackfill))
+		r.mux.HandleFunc("DELETE /api/v1/admin/artwork/backfill", r.withAdmin(r.artworkBackfillHandlers.CancelBackfill))
+	} else {
+		artworkBackfillUnavailable := r.withAuth(unavailableHandler("Artwork backfill is unavailable"))
+		r.mux.HandleFunc("POST /api/v1/admin/artwork/backfill", artworkBackfillUnavailable)
+		r.mux.HandleFunc("GET /api/v1/admin/artwork/backfill", artworkBackfillUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/admin/artwork/backfill", artworkBackfillUnavailable)
+	}
+	if r.libraryScanHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/admin/import/scan", r.withAdmin(r.libraryScanHandlers.StartScan))
+		r.mux.HandleFunc("GET /api/v1/admin/import/scan", r.withAdmin(r.libraryScanHandlers.GetScan))
+		r.mux.HandleFunc("DELETE /api/v1/admin/import/scan", r.withAdmin(r.libraryScanHandlers.CancelScan))
+	} else {
+		libraryScanUnavailable := r.withAuth(unavailableHandler("Library scan is unavailable"))
+		r.mux.HandleFunc("POST /api/v1/admin/import/scan", libraryScanUnavailable)
+		r.mux.HandleFunc("GET /api/v1/admin/import/scan", libraryScanUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/admin/import/scan", libraryScanUnavailable)
+	}
 	if r.analysisHandlers != nil {
 		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/analysis", r.withAuth(r.analysisHandlers.GetTrackAnalysis))
 		r.mux.HandleFunc("PATCH /api/v1/tracks/{track_id}/analysis/overrides", r.withAuth(r.analysisHandlers.UpdateTrackAnalysisOverrides))
@@ -272,18 +568,81 @@ func (r *Router) setupRoutes() {
 	// Direct playback/download URL issuance (auth required)
 	if r.playbackHandlers != nil {
 		r.mux.HandleFunc("POST /api/v1/playback/urls", r.withAuth(r.playbackHandlers.CreatePlaybackURLs))
+		// Anonymous playback of tracks shared by capability.
+		r.mux.HandleFunc("POST /api/v1/public/playback/urls", r.withPublicRateLimit(r.playbackHandlers.CreatePublicPlaybackURLs))
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/download", r.withAuth(r.playbackHandlers.DownloadTrack))
 	} else {
 		r.mux.HandleFunc("POST /api/v1/playback/urls", r.withAuth(unavailableHandler("Playback URL issuance is unavailable")))
+		r.mux.HandleFunc("POST /api/v1/public/playback/urls", unavailableHandler("Playback URL issuance is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/tracks/{track_id}/download", r.withAuth(unavailableHandler("Track downloads are unavailable")))
+	}
+
+	// Cross-device playback handoff (auth required). It moves playback within
+	// the Redis-backed queue, so it is unavailable without Redis.
+	if r.playbackTransferHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/playback/transfer", r.withAuth(r.playbackTransferHandlers.CreateTransfer))
+		r.mux.HandleFunc("GET /api/v1/playback/transfer/pending", r.withAuth(r.playbackTransferHandlers.ListPending))
+		r.mux.HandleFunc("GET /api/v1/playback/transfer/{id}", r.withAuth(r.playbackTransferHandlers.GetTransfer))
+		r.mux.HandleFunc("POST /api/v1/playback/transfer/{id}/ack", r.withAuth(r.playbackTransferHandlers.AckTransfer))
+	} else {
+		transferUnavailable := r.withAuth(unavailableHandler("Redis queue support is disabled for this local mode"))
+		r.mux.HandleFunc("POST /api/v1/playback/transfer", transferUnavailable)
+		r.mux.HandleFunc("GET /api/v1/playback/transfer/pending", transferUnavailable)
+		r.mux.HandleFunc("GET /api/v1/playback/transfer/{id}", transferUnavailable)
+		r.mux.HandleFunc("POST /api/v1/playback/transfer/{id}/ack", transferUnavailable)
+	}
+
+	// Release calendar routes (auth required): followed MusicBrainz artists and
+	// their recent and upcoming releases.
+	if r.calendarHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/followed-artists", r.withAuth(r.calendarHandlers.ListFollowedArtists))
+		r.mux.HandleFunc("PUT /api/v1/me/followed-artists/{mb_id}", r.withAuth(r.calendarHandlers.FollowArtist))
+		r.mux.HandleFunc("DELETE /api/v1/me/followed-artists/{mb_id}", r.withAuth(r.calendarHandlers.UnfollowArtist))
+		r.mux.HandleFunc("GET /api/v1/calendar", r.withAuth(r.calendarHandlers.GetCalendar))
+	} else {
+		calendarUnavailable := r.withAuth(unavailableHandler("Release calendar is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/followed-artists", calendarUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/me/followed-artists/{mb_id}", calendarUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/me/followed-artists/{mb_id}", calendarUnavailable)
+		r.mux.HandleFunc("GET /api/v1/calendar", calendarUnavailable)
+	}
+
+	// Track grant routes (auth required): share tracks from a playlist the
+	// caller owns as revocable per-track capabilities.
+	if r.trackGrantHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/track-grants", r.withAuth(r.trackGrantHandlers.ListGrants))
+		r.mux.HandleFunc("POST /api/v1/track-grants", r.withAuth(r.trackGrantHandlers.CreateGrant))
+		r.mux.HandleFunc("DELETE /api/v1/track-grants/{id}", r.withAuth(r.trackGrantHandlers.RevokeGrant))
+	} else {
+		trackGrantsUnavailable := r.withAuth(unavailableHandler("Track sharing is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/track-grants", trackGrantsUnavailable)
+		r.mux.HandleFunc("POST /api/v1/track-grants", trackGrantsUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/track-grants/{id}", trackGrantsUnavailable)
 	}
 
 	// Queue routes (auth required, Redis-backed)
 	if r.queueHandlers != nil {
-		r.mux.HandleFunc("GET /api/v1/queue", r.withAuth(r.queueHandlers.GetQueue))
-		r.mux.HandleFunc("POST /api/v1/queue/items", r.withAuth(r.queueHandlers.AddQueueItem))
-		r.mux.HandleFunc("POST /api/v1/queue/items/{queueItemId}/retry", r.withAuth(r.queueHandlers.RetryQueueItem))
-		r.mux.HandleFunc("DELETE /api/v1/queue/items/{queueItemId}", r.withAuth(r.queueHandlers.RemoveQueueItem))
-		r.mux.HandleFunc("PUT /api/v1/queue/reorder", r.withAuth(r.queueHandlers.ReorderQueue))
-		r.mux.HandleFunc("DELETE /api/v1/queue", r.withAuth(r.queueHandlers.ClearQueue))
+		r.mux.HandleFunc("GET /api/v1/queue", r.withAuth(withFields("queue", r.queueHandlers.GetQueue)))
+		r.mux.HandleFunc("POST /api/v1/queue/items", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.AddQueueItem)))
+		r.mux.HandleFunc("POST /api/v1/queue/items/{queueItemId}/retry", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.RetryQueueItem)))
+		r.mux.HandleFunc("DELETE /api/v1/queue/items/{queueItemId}", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.RemoveQueueItem)))
+		r.mux.HandleFunc("PUT /api/v1/queue/reorder", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.ReorderQueue)))
+		r.mux.HandleFunc("PUT /api/v1/queue/current", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.SetCurrentPosition)))
+		r.mux.HandleFunc("PUT /api/v1/queue/position", r.withAuth(r.queueHandlers.UpdatePlaybackPosition))
+		r.mux.HandleFunc("POST /api/v1/queue/shuffle", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.ShuffleQueue)))
+		r.mux.HandleFunc("PUT /api/v1/queue/shuffle", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.UpdateShuffle)))
+		r.mux.HandleFunc("PUT /api/v1/queue/repeat", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.UpdateRepeat)))
+		r.mux.HandleFunc("POST /api/v1/queue/play-album/{mb_release_id}", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.PlayAlbum)))
+		r.mux.HandleFunc("POST /api/v1/queue/play-artist/{mb_artist_id}", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.PlayArtist)))
+		r.mux.HandleFunc("POST /api/v1/queue/radio", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.StartRadio)))
+		r.mux.HandleFunc("DELETE /api/v1/queue", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.ClearQueue)))
+		r.mux.HandleFunc("GET /api/v1/playback/state/export", r.withAuth(r.queueHandlers.ExportPlaybackState))
+		r.mux.HandleFunc("POST /api/v1/playback/state/import", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.ImportPlaybackState)))
+		r.mux.HandleFunc("GET /api/v1/queues", r.withAuth(r.queueHandlers.ListQueues))
+		r.mux.HandleFunc("POST /api/v1/queues", r.withAuth(r.queueHandlers.CreateQueue))
+		r.mux.HandleFunc("PUT /api/v1/queues/active", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.SwitchQueue)))
+		r.mux.HandleFunc("POST /api/v1/queues/{name}/merge", r.withAuth(r.queueHandlers.Synced(r.queueHandlers.MergeQueue)))
+		r.mux.HandleFunc("DELETE /api/v1/queues/{name}", r.withAuth(r.queueHandlers.DeleteQueue))
 	} else {
 		queueUnavailable := r.withAuth(unavailableHandler("Redis queue support is disabled for this local mode"))
 		r.mux.HandleFunc("GET /api/v1/queue", queueUnavailable)
@@ -291,25 +650,68 @@ func (r *Router) setupRoutes() {
 		r.mux.HandleFunc("POST /api/v1/queue/items/{queueItemId}/retry", queueUnavailable)
 		r.mux.HandleFunc("DELETE /api/v1/queue/items/{queueItemId}", queueUnavailable)
 		r.mux.HandleFunc("PUT /api/v1/queue/reorder", queueUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/queue/current", queueUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/queue/position", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queue/shuffle", queueUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/queue/shuffle", queueUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/queue/repeat", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queue/play-album/{mb_release_id}", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queue/play-artist/{mb_artist_id}", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queue/radio", queueUnavailable)
 		r.mux.HandleFunc("DELETE /api/v1/queue", queueUnavailable)
+		r.mux.HandleFunc("GET /api/v1/playback/state/export", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/playback/state/import", queueUnavailable)
+		r.mux.HandleFunc("GET /api/v1/queues", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queues", queueUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/queues/active", queueUnavailable)
+		r.mux.HandleFunc("POST /api/v1/queues/{name}/merge", queueUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/queues/{name}", queueUnavailable)
 	}
 
 	// Playlist routes (auth required)
-	r.mux.HandleFunc("GET /api/v1/playlists", r.withAuth(r.playlistHandlers.ListPlaylists))
-	r.mux.HandleFunc("POST /api/v1/playlists", r.withAuth(r.playlistHandlers.CreatePlaylist))
-	r.mux.HandleFunc("GET /api/v1/playlists/{id}", r.withAuth(r.playlistHandlers.GetPlaylist))
-	r.mux.HandleFunc("PUT /api/v1/playlists/{id}", r.withAuth(r.playlistHandlers.UpdatePlaylist))
-	r.mux.HandleFunc("DELETE /api/v1/playlists/{id}", r.withAuth(r.playlistHandlers.DeletePlaylist))
-	r.mux.HandleFunc("POST /api/v1/playlists/{id}/tracks", r.withAuth(r.playlistHandlers.AddTracks))
-	r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/tracks/{trackId}", r.withAuth(r.playlistHandlers.RemoveTrack))
-	r.mux.HandleFunc("POST /api/v1/playlists/{id}/tracks/batch-remove", r.withAuth(r.playlistHandlers.BatchRemoveTracks))
-	r.mux.HandleFunc("PUT /api/v1/playlists/{id}/tracks/reorder", r.withAuth(r.playlistHandlers.ReorderTracks))
+	r.mux.HandleFunc("GET /api/v1/playlists", r.withAuth(withFields("playlists", r.playlistHandlers.ListPlaylists)))
+	r.mux.HandleFunc("POST /api/v1/playlists", r.withAuth(r.playlistHandlers.Notified(PlaylistCreated, r.playlistHandlers.CreatePlaylist)))
+	if r.playlistFileHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/playlists/import", r.withAuth(r.playlistFileHandlers.ImportPlaylistFile))
+	} else {
+		r.mux.HandleFunc("POST /api/v1/playlists/import", r.withAuth(unavailableHandler("Playlist file import is unavailable")))
+	}
+	r.mux.HandleFunc("GET /api/v1/playlists/{id}", r.withAuth(withFields("playlist", r.playlistHandlers.GetPlaylist)))
+	r.mux.HandleFunc("PUT /api/v1/playlists/{id}", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.UpdatePlaylist)))
+	r.mux.HandleFunc("DELETE /api/v1/playlists/{id}", r.withAuth(r.playlistHandlers.Notified(PlaylistDeleted, r.playlistHandlers.DeletePlaylist)))
+	r.mux.HandleFunc("POST /api/v1/playlists/{id}/tracks", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.AddTracks)))
+	r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/tracks/{trackId}", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.RemoveTrack)))
+	r.mux.HandleFunc("POST /api/v1/playlists/{id}/tracks/batch-remove", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.BatchRemoveTracks)))
+	r.mux.HandleFunc("PUT /api/v1/playlists/{id}/tracks/reorder", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.ReorderTracks)))
+	r.mux.HandleFunc("GET /api/v1/playlists/{id}/history", r.withAuth(r.playlistHandlers.GetHistory))
+	r.mux.HandleFunc("POST /api/v1/playlists/{id}/revert/{version}", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.RevertPlaylist)))
+	r.mux.HandleFunc("POST /api/v1/playlists/{id}/duplicate", r.withAuth(r.playlistHandlers.Notified(PlaylistCreated, r.playlistHandlers.DuplicatePlaylist)))
+	r.mux.HandleFunc("POST /api/v1/playlists/{id}/merge", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.MergePlaylist)))
+	r.mux.HandleFunc("PUT /api/v1/playlists/{id}/artwork", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.UploadArtwork)))
+	r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/artwork", r.withAuth(r.playlistHandlers.Notified(PlaylistUpdated, r.playlistHandlers.DeleteArtwork)))
+	// Read-only public links: the owner mints a signed token; anyone holding it
+	// reads the playlist and streams its tracks through public playback URLs.
+	if r.playlistLinkHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/public-link", r.withAuth(r.playlistLinkHandlers.CreateLink))
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/public-link", r.withAuth(r.playlistLinkHandlers.RevokeLink))
+		r.mux.HandleFunc("GET /api/v1/public/playlists/{token}", r.withPublicRateLimit(r.playlistLinkHandlers.GetPublicPlaylist))
+	} else {
+		playlistLinksUnavailable := unavailableHandler("Public playlist links are unavailable")
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/public-link", r.withAuth(playlistLinksUnavailable))
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/public-link", r.withAuth(playlistLinksUnavailable))
+		r.mux.HandleFunc("GET /api/v1/public/playlists/{token}", playlistLinksUnavailable)
+	}
 	// Flag-gated save-playlist-as-mix seam. The handler itself returns 404 when
 	// the feature is disabled (ENABLE_PLAYLIST_MIX); when the handler is not wired
 	// at all (legacy router construction) the route stays unregistered.
 	if r.playlistMixHandlers != nil {
 		r.mux.HandleFunc("POST /api/v1/playlists/{id}/mix", r.withAuth(r.playlistMixHandlers.CreateMixFromPlaylist))
 	}
+	if r.playlistExportHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/export", r.withAuth(r.playlistExportHandlers.ExportPlaylist))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/export", r.withAuth(unavailableHandler("Playlist export is unavailable")))
+	}
 	if r.playlistImportHandlers != nil {
 		r.mux.HandleFunc("POST /api/v1/playlist-imports", r.withAuth(r.playlistImportHandlers.CreateImport))
 		r.mux.HandleFunc("GET /api/v1/playlist-imports/{importJobId}", r.withAuth(r.playlistImportHandlers.GetImport))
@@ -331,11 +733,29 @@ func (r *Router) setupRoutes() {
 		r.mux.HandleFunc("POST /api/v1/downloads", r.withAuth(r.downloadHandlers.CreateDownload))
 		r.mux.HandleFunc("GET /api/v1/downloads", r.withAuth(r.downloadHandlers.GetUserJobs))
 		r.mux.HandleFunc("GET /api/v1/downloads/{job_id}", r.withAuth(r.downloadHandlers.GetJob))
+		r.mux.HandleFunc("POST /api/v1/downloads/{job_id}/{action}", r.withAuth(r.downloadHandlers.ChangeJob))
+		r.mux.HandleFunc("POST /api/v1/downloads/release/{mb_release_id}", r.withAuth(r.downloadHandlers.DownloadRelease))
 	} else {
 		downloadUnavailable := r.withAuth(unavailableHandler("Download processing is disabled for this local mode"))
 		r.mux.HandleFunc("POST /api/v1/downloads", downloadUnavailable)
 		r.mux.HandleFunc("GET /api/v1/downloads", downloadUnavailable)
 		r.mux.HandleFunc("GET /api/v1/downloads/{job_id}", downloadUnavailable)
+		r.mux.HandleFunc("POST /api/v1/downloads/{job_id}/{action}", downloadUnavailable)
+		r.mux.HandleFunc("POST /api/v1/downloads/release/{mb_release_id}", downloadUnavailable)
+	}
+
+	// Direct upload routes (auth required). Clients PUT files to a presigned
+	// object storage URL, or resumable uploads part by part, then finalize to
+	// queue processing.
+	if r.uploadHandlers != nil {
+		r.mux.HandleFunc("POST /api/v1/uploads", r.withAuth(r.uploadHandlers.CreateUpload))
+		r.mux.HandleFunc("GET /api/v1/uploads/{id}/parts", r.withAuth(r.uploadHandlers.GetUploadParts))
+		r.mux.HandleFunc("POST /api/v1/uploads/{id}/finalize", r.withAuth(r.uploadHandlers.FinalizeUpload))
+	} else {
+		uploadUnavailable := r.withAuth(unavailableHandler("Uploads are unavailable"))
+		r.mux.HandleFunc("POST /api/v1/uploads", uploadUnavailable)
+		r.mux.HandleFunc("GET /api/v1/uploads/{id}/parts", uploadUnavailable)
+		r.mux.HandleFunc("POST /api/v1/uploads/{id}/finalize", uploadUnavailable)
 	}
 
 	// Play event routes (auth required): record a play and read personal history.
@@ -344,20 +764,184 @@ func (r *Router) setupRoutes() {
 		r.mux.HandleFunc("GET /api/v1/me/plays/history", r.withAuth(r.playEventHandlers.PlayHistory))
 		r.mux.HandleFunc("GET /api/v1/me/plays/recent", r.withAuth(r.playEventHandlers.RecentlyPlayed))
 		r.mux.HandleFunc("GET /api/v1/me/plays/top", r.withAuth(r.playEventHandlers.TopTracks))
+		r.mux.HandleFunc("POST /api/v1/plays", r.withAuth(r.playEventHandlers.RecordPlay))
+		r.mux.HandleFunc("GET /api/v1/history", r.withAuth(r.playEventHandlers.PlayHistory))
 	} else {
 		playEventUnavailable := r.withAuth(unavailableHandler("Play history is unavailable"))
 		r.mux.HandleFunc("POST /api/v1/me/plays", playEventUnavailable)
 		r.mux.HandleFunc("GET /api/v1/me/plays/history", playEventUnavailable)
 		r.mux.HandleFunc("GET /api/v1/me/plays/recent", playEventUnavailable)
 		r.mux.HandleFunc("GET /api/v1/me/plays/top", playEventUnavailable)
+		r.mux.HandleFunc("POST /api/v1/plays", playEventUnavailable)
+		r.mux.HandleFunc("GET /api/v1/history", playEventUnavailable)
+	}
+
+	// Public profile routes. Owners manage settings with auth; the public read is
+	// unauthenticated and only returns sections the owner opted into.
+	if r.profileHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/profile", r.withAuth(r.profileHandlers.GetMyProfile))
+		r.mux.HandleFunc("PUT /api/v1/me/profile", r.withAuth(r.profileHandlers.UpdateMyProfile))
+		r.mux.HandleFunc("PUT /api/v1/me/profile/playlists/{id}", r.withAuth(r.profileHandlers.SetPlaylistVisibility))
+		r.mux.HandleFunc("GET /api/v1/public/users/{handle}", r.profileHandlers.GetPublicProfile)
+	} else {
+		profileUnavailable := r.withAuth(unavailableHandler("Public profiles are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/profile", profileUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/me/profile", profileUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/me/profile/playlists/{id}", profileUnavailable)
+		r.mux.HandleFunc("GET /api/v1/public/users/{handle}", unavailableHandler("Public profiles are unavailable"))
+	}
+
+	// Account profile routes (auth required): display name, bio, and avatar
+	// shown wherever the user is attributed.
+	if r.accountProfileHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/profile", r.withAuth(r.accountProfileHandlers.GetProfile))
+		r.mux.HandleFunc("PATCH /api/v1/profile", r.withAuth(r.accountProfileHandlers.PatchProfile))
+		r.mux.HandleFunc("PUT /api/v1/profile/avatar", r.withAuth(r.accountProfileHandlers.UploadAvatar))
+		r.mux.HandleFunc("DELETE /api/v1/profile/avatar", r.withAuth(r.accountProfileHandlers.DeleteAvatar))
+	} else {
+		accountProfileUnavailable := r.withAuth(unavailableHandler("Profiles are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/profile", accountProfileUnavailable)
+		r.mux.HandleFunc("PATCH /api/v1/profile", accountProfileUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/profile/avatar", accountProfileUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/profile/avatar", accountProfileUnavailable)
+	}
+
+	// Download settings routes (auth required): where finished downloads go
+	// when the download request does not say.
+	if r.downloadSettingsHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/download-settings", r.withAuth(r.downloadSettingsHandlers.GetSettings))
+		r.mux.HandleFunc("PUT /api/v1/me/download-settings", r.withAuth(r.downloadSettingsHandlers.UpdateSettings))
+	} else {
+		downloadSettingsUnavailable := r.withAuth(unavailableHandler("Download settings are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/download-settings", downloadSettingsUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/me/download-settings", downloadSettingsUnavailable)
+	}
+
+	// Scrobbling routes (auth required, Redis-backed): connect accounts that
+	// completed plays are forwarded to.
+	if r.scrobbleHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/scrobbling", r.withAuth(r.scrobbleHandlers.ListConnections))
+		r.mux.HandleFunc("PUT /api/v1/me/scrobbling/{service}", r.withAuth(r.scrobbleHandlers.Connect))
+		r.mux.HandleFunc("DELETE /api/v1/me/scrobbling/{service}", r.withAuth(r.scrobbleHandlers.Disconnect))
+	} else {
+		scrobbleUnavailable := r.withAuth(unavailableHandler("Scrobbling is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/scrobbling", scrobbleUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/me/scrobbling/{service}", scrobbleUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/me/scrobbling/{service}", scrobbleUnavailable)
+	}
+
+	// Playlist collaboration routes (auth required). Owners manage collaborators;
+	// owners and collaborators share comments and the activity feed.
+	if r.collaborationHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/collaborators", r.withAuth(r.collaborationHandlers.ListCollaborators))
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/collaborators", r.withAuth(r.collaborationHandlers.AddCollaborator))
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/collaborators/{handle}", r.withAuth(r.collaborationHandlers.RemoveCollaborator))
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/activity", r.withAuth(r.collaborationHandlers.ListActivity))
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/comments", r.withAuth(r.collaborationHandlers.ListComments))
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/comments", r.withAuth(r.collaborationHandlers.CreateComment))
+		r.mux.HandleFunc("PUT /api/v1/playlists/{id}/comments/{commentId}", r.withAuth(r.collaborationHandlers.UpdateComment))
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/comments/{commentId}", r.withAuth(r.collaborationHandlers.DeleteComment))
+	} else {
+		collaborationUnavailable := r.withAuth(unavailableHandler("Playlist collaboration is unavailable"))
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/collaborators", collaborationUnavailable)
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/collaborators", collaborationUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/collaborators/{handle}", collaborationUnavailable)
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/activity", collaborationUnavailable)
+		r.mux.HandleFunc("GET /api/v1/playlists/{id}/comments", collaborationUnavailable)
+		r.mux.HandleFunc("POST /api/v1/playlists/{id}/comments", collaborationUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/playlists/{id}/comments/{commentId}", collaborationUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/playlists/{id}/comments/{commentId}", collaborationUnavailable)
+	}
+
+	// Notification inbox routes (auth required)
+	if r.notificationHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/notifications", r.withAuth(r.notificationHandlers.ListNotifications))
+		r.mux.HandleFunc("POST /api/v1/me/notifications/{id}/read", r.withAuth(r.notificationHandlers.MarkNotificationRead))
+	} else {
+		notificationUnavailable := r.withAuth(unavailableHandler("Notifications are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/notifications", notificationUnavailable)
+		r.mux.HandleFunc("POST /api/v1/me/notifications/{id}/read", notificationUnavailable)
+	}
+
+	// Year-in-review routes. Owners generate and share with auth; a shared report
+	// is readable without auth through its token.
+	if r.wrappedHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/me/wrapped/{year}", r.withAuth(r.wrappedHandlers.GetReport))
+		r.mux.HandleFunc("POST /api/v1/me/wrapped/{year}", r.withAuth(r.wrappedHandlers.RegenerateReport))
+		r.mux.HandleFunc("POST /api/v1/me/wrapped/{year}/share", r.withAuth(r.wrappedHandlers.ShareReport))
+		r.mux.HandleFunc("DELETE /api/v1/me/wrapped/{year}/share", r.withAuth(r.wrappedHandlers.UnshareReport))
+		r.mux.HandleFunc("GET /api/v1/public/wrapped/{token}", r.wrappedHandlers.GetSharedReport)
+	} else {
+		wrappedUnavailable := r.withAuth(unavailableHandler("Year-in-review reports are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/me/wrapped/{year}", wrappedUnavailable)
+		r.mux.HandleFunc("POST /api/v1/me/wrapped/{year}", wrappedUnavailable)
+		r.mux.HandleFunc("POST /api/v1/me/wrapped/{year}/share", wrappedUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/me/wrapped/{year}/share", wrappedUnavailable)
+		r.mux.HandleFunc("GET /api/v1/public/wrapped/{token}", unavailableHandler("Year-in-review reports are unavailable"))
+	}
+
+	// Listening goal routes (auth required)
+	if r.goalHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/stats/goals", r.withAuth(r.goalHandlers.ListGoals))
+		r.mux.HandleFunc("POST /api/v1/stats/goals", r.withAuth(r.goalHandlers.CreateGoal))
+		r.mux.HandleFunc("PUT /api/v1/stats/goals/{kind}", r.withAuth(r.goalHandlers.UpdateGoal))
+		r.mux.HandleFunc("DELETE /api/v1/stats/goals/{kind}", r.withAuth(r.goalHandlers.DeleteGoal))
+	} else {
+		goalsUnavailable := r.withAuth(unavailableHandler("Listening goals are unavailable"))
+		r.mux.HandleFunc("GET /api/v1/stats/goals", goalsUnavailable)
+		r.mux.HandleFunc("POST /api/v1/stats/goals", goalsUnavailable)
+		r.mux.HandleFunc("PUT /api/v1/stats/goals/{kind}", goalsUnavailable)
+		r.mux.HandleFunc("DELETE /api/v1/stats/goals/{kind}", goalsUnavailable)
+	}
+
+	// Listening stats routes (auth required)
+	if r.statsHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/stats/overview", r.withAuth(r.statsHandlers.Overview))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/stats/overview", r.withAuth(unavailableHandler("Listening stats are unavailable")))
+	}
+
+	// Recommendation routes (auth required)
+	if r.recommendationHandlers != nil {
+		r.mux.HandleFunc("GET /api/v1/recommendations", r.withAuth(r.recommendationHandlers.GetRecommendations))
+	} else {
+		r.mux.HandleFunc("GET /api/v1/recommendations", r.withAuth(unavailableHandler("Recommendations are unavailable")))
 	}
 
 	// Maintenance repair routes (auth required)
 	if r.maintenanceHandlers != nil {
-		r.mux.HandleFunc("POST /api/v1/maintenance/repair", r.withAuth(r.maintenanceHandlers.RepairTracks))
+		r.mux.HandleFunc("POST /api/v1/maintenance/repair", r.withAdmin(r.maintenanceHandlers.RepairTracks))
 	} else {
 		r.mux.HandleFunc("POST /api/v1/maintenance/repair", r.withAuth(unavailableHandler("Maintenance repair is unavailable")))
 	}
+
+	r.setupV2Routes()
+}
+
+// setupV2Routes registers the /api/v2 list endpoints, which return the
+// standard pagination.Page envelope (items, pageInfo, nextCursor). v1 keeps
+// its per-endpoint envelopes for existing clients.
+func (r *Router) setupV2Routes() {
+	// The library applies ?fields= itself, like GET /api/v1/library, so it
+	// only loads the selected columns; withFields would filter a second time.
+	r.mux.HandleFunc("GET /api/v2/library", r.withAuth(r.libraryHandlers.GetLibraryPage))
+	r.mux.HandleFunc("GET /api/v2/playlists", r.withAuth(withFields("playlists", r.playlistHandlers.ListPlaylistsPage)))
+
+	r.mux.HandleFunc("GET /api/v2/search/recordings", r.withAuth(r.searchHandlers.SearchRecordingsPage))
+	r.mux.HandleFunc("GET /api/v2/search/artists", r.withAuth(r.searchHandlers.SearchArtistsPage))
+	r.mux.HandleFunc("GET /api/v2/search/releases", r.withAuth(r.searchHandlers.SearchReleasesPage))
+
+	if r.downloadHandlers != nil {
+		r.mux.HandleFunc("GET /api/v2/downloads", r.withAuth(r.downloadHandlers.GetUserJobsPage))
+	} else {
+		r.mux.HandleFunc("GET /api/v2/downloads", r.withAuth(unavailableHandler("Download processing is disabled for this local mode")))
+	}
+
+	if r.playEventHandlers != nil {
+		r.mux.HandleFunc("GET /api/v2/me/plays/history", r.withAuth(r.playEventHandlers.PlayHistoryPage))
+	} else {
+		r.mux.HandleFunc("GET /api/v2/me/plays/history", r.withAuth(unavailableHandler("Play history is unavailable")))
+	}
 }
 
 func unavailableHandler(message string) http.HandlerFunc {
@@ -378,6 +962,20 @@ func (r *Router) withAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// withAdmin authenticates the request and lets only admins through. API keys
+// need the admin scope for the route wherever its path lives.
+func (r *Router) withAdmin(next http.HandlerFunc) http.HandlerFunc {
+	guarded := r.withAuth(auth.RequireAdmin(next).ServeHTTP)
+	return func(w http.ResponseWriter, req *http.Request) {
+		guarded(w, auth.AdminRoute(req))
+	}
+}
+
+// withPublicRateLimit limits an unauthenticated route per client address.
+func (r *Router) withPublicRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return middleware.RateLimit(r.publicRateLimiter, middleware.ClientAddr)(next).ServeHTTP
+}
+
 func defaultHealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+
 	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
 )
 
 func TestDisabledQueueRoutesRequireAuth(t *testing.T) {
@@ -194,3 +198,66 @@ func TestSavedMixPlanItemRoutesUseOpenAPIPathParam(t *testing.T) {
 		t.Fatal("saved mix-plan routes must use OpenAPI path parameter {mixPlanId}, not {id}")
 	}
 }
+
+// adminAPIKeys is an API key store whose keys all belong to one admin.
+type adminAPIKeys struct {
+	keys map[string]*db.APIKey
+}
+
+func (s *adminAPIKeys) Create(_ context.Context, key *db.APIKey) error {
+	s.keys[key.KeyHash] = key
+	return nil
+}
+
+func (s *adminAPIKeys) ListForUser(context.Context, uuid.UUID) ([]db.APIKey, error) {
+	return nil, nil
+}
+
+func (s *adminAPIKeys) CountActiveForUser(context.Context, uuid.UUID) (int, error) {
+	return len(s.keys), nil
+}
+
+func (s *adminAPIKeys) GetActiveByHash(_ context.Context, keyHash string) (*db.APIKeyOwner, error) {
+	key, ok := s.keys[keyHash]
+	if !ok {
+		return nil, db.ErrAPIKeyNotFound
+	}
+	return &db.APIKeyOwner{APIKey: *key, Email: "ops@example.test", Role: auth.RoleAdmin}, nil
+}
+
+func (s *adminAPIKeys) Revoke(context.Context, uuid.UUID, uuid.UUID) error { return nil }
+
+func (s *adminAPIKeys) TouchLastUsed(context.Context, uuid.UUID) error { return nil }
+
+func TestAdminRouteOutsideAdminPathTakesAdminScopedKeys(t *testing.T) {
+	authService := auth.NewService(nil, nil, "secret")
+	authService.SetAPIKeys(&adminAPIKeys{keys: map[string]*db.APIKey{}})
+	userID := uuid.New()
+	adminKey, _, err := authService.CreateAPIKey(context.Background(), userID, "ops", []string{auth.ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readKey, _, err := authService.CreateAPIKey(context.Background(), userID, "reader", []string{auth.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouterWithConfig(&RouterConfig{
+		AuthHandlers:        auth.NewHandlers(nil),
+		AuthService:         authService,
+		MaintenanceHandlers: NewMaintenanceHandlers(nil, nil),
+	})
+
+	for key, want := range map[string]int{
+		// The repair handler has no stores here, so reaching it is a 503.
+		adminKey: http.StatusServiceUnavailable,
+		readKey:  http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/maintenance/repair", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("POST /api/v1/maintenance/repair = %d, want %d; body=%s", rec.Code, want, rec.Body.String())
+		}
+	}
+}
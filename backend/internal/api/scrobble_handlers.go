@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/scrobbler"
+)
+
+const maxScrobbleTokenLength = 255
+
+type scrobbleConnectionStore interface {
+	ListScrobbleConnections(ctx context.Context, userID uuid.UUID) ([]db.ScrobbleConnection, error)
+	SaveScrobbleConnection(ctx context.Context, connection *db.ScrobbleConnection) error
+	DeleteScrobbleConnection(ctx context.Context, userID uuid.UUID, service string) error
+}
+
+type scrobbleTargets interface {
+	Target(name string) (scrobbler.Target, bool)
+}
+
+// ScrobbleHandlers connects the caller's accounts on scrobbling services.
+// Once connected, completed plays are forwarded in the background.
+type ScrobbleHandlers struct {
+	connections scrobbleConnectionStore
+	targets     scrobbleTargets
+}
+
+func NewScrobbleHandlers(connections scrobbleConnectionStore, targets scrobbleTargets) *ScrobbleHandlers {
+	return &ScrobbleHandlers{connections: connections, targets: targets}
+}
+
+type ConnectScrobbleServiceRequest struct {
+	Token string `json:"token"`
+}
+
+type ScrobbleConnectionResponse struct {
+	Service     string    `json:"service"`
+	Username    string    `json:"username"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+type ScrobbleConnectionsResponse struct {
+	Connections []ScrobbleConnectionResponse `json:"connections"`
+}
+
+// ListConnections handles GET /api/v1/me/scrobbling
+func (h *ScrobbleHandlers) ListConnections(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeScrobbleError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	connections, err := h.connections.ListScrobbleConnections(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeScrobbleError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load scrobbling connections")
+		return
+	}
+	resp := ScrobbleConnectionsResponse{Connections: make([]ScrobbleConnectionResponse, 0, len(connections))}
+	for i := range connections {
+		resp.Connections = append(resp.Connections, scrobbleConnectionResponse(&connections[i]))
+	}
+	writeScrobbleJSON(w, http.StatusOK, resp)
+}
+
+// Connect handles PUT /api/v1/me/scrobbling/{service}. The token is checked
+// with the service before it is saved.
+func (h *ScrobbleHandlers) Connect(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeScrobbleError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	target, ok := h.targets.Target(r.PathValue("service"))
+	if !ok {
+		writeScrobbleError(w, http.StatusNotFound, "SCROBBLE_SERVICE_NOT_FOUND", "unknown scrobbling service")
+		return
+	}
+
+	var req ConnectScrobbleServiceRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeScrobbleError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+	if token == "" || len(token) > maxScrobbleTokenLength {
+		writeScrobbleError(w, http.StatusBadRequest, "INVALID_TOKEN", "token is required")
+		return
+	}
+
+	username, err := target.ValidateToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, scrobbler.ErrInvalidToken) {
+			writeScrobbleError(w, http.StatusBadRequest, "INVALID_TOKEN", "the service did not accept this token")
+			return
+		}
+		writeScrobbleError(w, http.StatusBadGateway, "SCROBBLE_SERVICE_UNAVAILABLE", "could not reach the scrobbling service")
+		return
+	}
+
+	connection := &db.ScrobbleConnection{UserID: userCtx.UserID, Service: target.Name(), Token: token, Username: username}
+	if err := h.connections.SaveScrobbleConnection(r.Context(), connection); err != nil {
+		writeScrobbleError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to save scrobbling connection")
+		return
+	}
+	writeScrobbleJSON(w, http.StatusOK, scrobbleConnectionResponse(connection))
+}
+
+// Disconnect handles DELETE /api/v1/me/scrobbling/{service}. Listens still
+// queued for the service are dropped.
+func (h *ScrobbleHandlers) Disconnect(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeScrobbleError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if err := h.connections.DeleteScrobbleConnection(r.Context(), userCtx.UserID, r.PathValue("service")); err != nil {
+		if errors.Is(err, db.ErrScrobbleConnectionNotFound) {
+			writeScrobbleError(w, http.StatusNotFound, "SCROBBLE_CONNECTION_NOT_FOUND", "service is not connected")
+			return
+		}
+		writeScrobbleError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to disconnect scrobbling service")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scrobbleConnectionResponse(c *db.ScrobbleConnection) ScrobbleConnectionResponse {
+	return ScrobbleConnectionResponse{Service: c.Service, Username: c.Username, ConnectedAt: c.CreatedAt}
+}
+
+func writeScrobbleJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeScrobbleError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/scrobbler"
+)
+
+type fakeScrobbleConnections struct {
+	saved map[string]db.ScrobbleConnection
+}
+
+func (f *fakeScrobbleConnections) ListScrobbleConnections(_ context.Context, userID uuid.UUID) ([]db.ScrobbleConnection, error) {
+	var out []db.ScrobbleConnection
+	for _, c := range f.saved {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeScrobbleConnections) SaveScrobbleConnection(_ context.Context, c *db.ScrobbleConnection) error {
+	c.CreatedAt = time.Now()
+	f.saved[c.Service] = *c
+	return nil
+}
+
+func (f *fakeScrobbleConnections) DeleteScrobbleConnection(_ context.Context, userID uuid.UUID, service string) error {
+	if c, ok := f.saved[service]; !ok || c.UserID != userID {
+		return db.ErrScrobbleConnectionNotFound
+	}
+	delete(f.saved, service)
+	return nil
+}
+
+type fakeScrobbleTarget struct {
+	tokens map[string]string
+	down   bool
+}
+
+func (t fakeScrobbleTarget) Name() string { return scrobbler.ServiceListenBrainz }
+
+func (t fakeScrobbleTarget) ValidateToken(_ context.Context, token string) (string, error) {
+	if t.down {
+		return "", errors.New("listenbrainz returned status 503")
+	}
+	if name, ok := t.tokens[token]; ok {
+		return name, nil
+	}
+	return "", scrobbler.ErrInvalidToken
+}
+
+func (t fakeScrobbleTarget) Submit(context.Context, string, scrobbler.Listen) error { return nil }
+
+type fakeScrobbleTargets struct {
+	target fakeScrobbleTarget
+}
+
+func (f fakeScrobbleTargets) Target(name string) (scrobbler.Target, bool) {
+	if name != f.target.Name() {
+		return nil, false
+	}
+	return f.target, true
+}
+
+func TestScrobbleConnectValidatesTokenBeforeSaving(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeScrobbleConnections{saved: map[string]db.ScrobbleConnection{}}
+	connect := func(targets fakeScrobbleTargets, service, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/me/scrobbling/"+service, strings.NewReader(body))
+		req.SetPathValue("service", service)
+		rec := httptest.NewRecorder()
+		NewScrobbleHandlers(store, targets).Connect(rec, withUser(req, userID))
+		return rec
+	}
+	targets := fakeScrobbleTargets{fakeScrobbleTarget{tokens: map[string]string{"good-token": "listener"}}}
+
+	cases := []struct {
+		name       string
+		targets    fakeScrobbleTargets
+		service    string
+		body       string
+		wantStatus int
+	}{
+		{"unknown service", targets, "lastfm", `{"token":"good-token"}`, http.StatusNotFound},
+		{"missing token", targets, "listenbrainz", `{"token":"  "}`, http.StatusBadRequest},
+		{"rejected token", targets, "listenbrainz", `{"token":"bad-token"}`, http.StatusBadRequest},
+		{"service down", fakeScrobbleTargets{fakeScrobbleTarget{down: true}}, "listenbrainz", `{"token":"good-token"}`, http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec := connect(tc.targets, tc.service, tc.body); rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+		})
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("saved connections on failed requests: %#v", store.saved)
+	}
+
+	rec := connect(targets, "listenbrainz", `{"token":"good-token"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	saved := store.saved["listenbrainz"]
+	if saved.UserID != userID || saved.Token != "good-token" || saved.Username != "listener" {
+		t.Fatalf("saved = %#v, want the user's validated token for listener", saved)
+	}
+	if strings.Contains(rec.Body.String(), "good-token") {
+		t.Fatalf("response leaks the token: %s", rec.Body.String())
+	}
+}
+
+func TestScrobbleDisconnect(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeScrobbleConnections{saved: map[string]db.ScrobbleConnection{
+		"listenbrainz": {UserID: userID, Service: "listenbrainz", Token: "tok", Username: "listener"},
+	}}
+	handlers := NewScrobbleHandlers(store, fakeScrobbleTargets{})
+	disconnect := func() int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/scrobbling/listenbrainz", nil)
+		req.SetPathValue("service", "listenbrainz")
+		rec := httptest.NewRecorder()
+		handlers.Disconnect(rec, withUser(req, userID))
+		return rec.Code
+	}
+
+	if code := disconnect(); code != http.StatusNoContent {
+		t.Fatalf("first disconnect status = %d, want 204", code)
+	}
+	if code := disconnect(); code != http.StatusNotFound {
+		t.Fatalf("second disconnect status = %d, want 404", code)
+	}
+}
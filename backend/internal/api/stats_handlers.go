@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	statsDefaultTopLimit = 10
+	statsMaxTopLimit     = 50
+)
+
+type listeningStatsStore interface {
+	Overview(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int, loc *time.Location) (*db.ListeningOverview, error)
+}
+
+// StatsHandlers serves listening stats over calendar periods in each
+// listener's time zone.
+type StatsHandlers struct {
+	statsRepo listeningStatsStore
+	timeZones timeZoneStore
+	now       func() time.Time
+}
+
+func NewStatsHandlers(statsRepo listeningStatsStore) *StatsHandlers {
+	return &StatsHandlers{statsRepo: statsRepo, now: time.Now}
+}
+
+// SetTimeZones makes periods start at local midnight in each listener's time
+// zone instead of UTC.
+func (h *StatsHandlers) SetTimeZones(zones timeZoneStore) {
+	h.timeZones = zones
+}
+
+type StatsArtistResponse struct {
+	Name         string `json:"name"`
+	PlayCount    int    `json:"playCount"`
+	TotalMinutes int    `json:"totalMinutes"`
+}
+
+type StatsTrackResponse struct {
+	TrackID      int64  `json:"trackId"`
+	Title        string `json:"title"`
+	Artist       string `json:"artist,omitempty"`
+	PlayCount    int    `json:"playCount"`
+	TotalMinutes int    `json:"totalMinutes"`
+}
+
+// StatsOverviewResponse covers [Start, End). HourOfDay sums Heatmap's rows;
+// Heatmap rows are weekdays, Monday first, of 24 local hours each.
+type StatsOverviewResponse struct {
+	Period       string                `json:"period"`
+	TimeZone     string                `json:"timeZone"`
+	Start        time.Time             `json:"start"`
+	End          time.Time             `json:"end"`
+	TotalPlays   int                   `json:"totalPlays"`
+	TotalSkips   int                   `json:"totalSkips"`
+	TotalMinutes int                   `json:"totalMinutes"`
+	TopArtists   []StatsArtistResponse `json:"topArtists"`
+	TopTracks    []StatsTrackResponse  `json:"topTracks"`
+	HourOfDay    [24]int               `json:"hourOfDay"`
+	Heatmap      [7][24]int            `json:"heatmap"`
+}
+
+// Overview handles GET /api/v1/stats/overview.
+// Query params: period (week|month|year, default week), date (YYYY-MM-DD in
+// the period to report; default today), limit (top list size, 1-50).
+// Weeks start on Monday.
+func (h *StatsHandlers) Overview(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeStatsError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if period != "week" && period != "month" && period != "year" {
+		writeStatsError(w, http.StatusBadRequest, "VALIDATION_ERROR", "period must be one of: week, month, year")
+		return
+	}
+
+	limit := statsDefaultTopLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > statsMaxTopLimit {
+			writeStatsError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 1 and 50")
+			return
+		}
+		limit = parsed
+	}
+
+	loc := userLocation(r.Context(), h.timeZones, userCtx.UserID)
+	day := startOfDay(h.now(), loc)
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil || parsed.After(day) {
+			writeStatsError(w, http.StatusBadRequest, "VALIDATION_ERROR", "date must be a past YYYY-MM-DD date")
+			return
+		}
+		day = parsed
+	}
+	start, end := statsPeriodBounds(period, day)
+
+	overview, err := h.statsRepo.Overview(r.Context(), userCtx.UserID, start, end, limit, loc)
+	if err != nil {
+		writeStatsError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load stats")
+		return
+	}
+
+	resp := StatsOverviewResponse{
+		Period:       period,
+		TimeZone:     loc.String(),
+		Start:        start,
+		End:          end,
+		TotalPlays:   overview.TotalPlays,
+		TotalSkips:   overview.TotalSkips,
+		TotalMinutes: int(overview.ListenedMs / 60000),
+		TopArtists:   make([]StatsArtistResponse, 0, len(overview.TopArtists)),
+		TopTracks:    make([]StatsTrackResponse, 0, len(overview.TopTracks)),
+		Heatmap:      overview.Heatmap,
+	}
+	for _, a := range overview.TopArtists {
+		resp.TopArtists = append(resp.TopArtists, StatsArtistResponse{
+			Name:         a.Name,
+			PlayCount:    a.PlayCount,
+			TotalMinutes: int(a.ListenedMs / 60000),
+		})
+	}
+	for _, t := range overview.TopTracks {
+		resp.TopTracks = append(resp.TopTracks, StatsTrackResponse{
+			TrackID:      t.TrackID,
+			Title:        t.Title,
+			Artist:       t.Artist,
+			PlayCount:    t.PlayCount,
+			TotalMinutes: int(t.ListenedMs / 60000),
+		})
+	}
+	for _, hours := range overview.Heatmap {
+		for hour, plays := range hours {
+			resp.HourOfDay[hour] += plays
+		}
+	}
+	writeStatsJSON(w, http.StatusOK, resp)
+}
+
+// statsPeriodBounds returns the calendar week (from Monday), month, or year
+// containing day, which must be a local midnight.
+func statsPeriodBounds(period string, day time.Time) (time.Time, time.Time) {
+	y, m, d := day.Date()
+	loc := day.Location()
+	switch period {
+	case "year":
+		start := time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0)
+	case "month":
+		start := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		offset := (int(day.Weekday()) + 6) % 7
+		start := time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7)
+	}
+}
+
+func writeStatsJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeStatsError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
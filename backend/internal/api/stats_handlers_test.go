@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeStatsStore struct {
+	overview   *db.ListeningOverview
+	start, end time.Time
+	limit      int
+}
+
+func (f *fakeStatsStore) Overview(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int, loc *time.Location) (*db.ListeningOverview, error) {
+	f.start, f.end, f.limit = start, end, limit
+	return f.overview, nil
+}
+
+func newTestStatsHandlers(store *fakeStatsStore, now time.Time) *StatsHandlers {
+	h := NewStatsHandlers(store)
+	h.now = func() time.Time { return now }
+	return h
+}
+
+func TestStatsOverviewAggregatesHeatmapAndMinutes(t *testing.T) {
+	overview := &db.ListeningOverview{
+		TotalPlays: 3,
+		TotalSkips: 1,
+		ListenedMs: 9 * 60000,
+		TopArtists: []db.ListeningCount{{Name: "Alpha", PlayCount: 3, ListenedMs: 9 * 60000}},
+		TopTracks:  []db.ListeningTrackCount{{TrackID: 7, Title: "One", Artist: "Alpha", PlayCount: 2, ListenedMs: 6 * 60000}},
+	}
+	overview.Heatmap[0][21] = 2
+	overview.Heatmap[4][21] = 1
+	store := &fakeStatsStore{overview: overview}
+	h := newTestStatsHandlers(store, time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC))
+
+	rec := httptest.NewRecorder()
+	h.Overview(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/stats/overview?period=month&limit=5", nil), uuid.New()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp StatsOverviewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !store.start.Equal(time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)) || !store.end.Equal(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)) || store.limit != 5 {
+		t.Fatalf("store got [%v, %v) limit %d, want October 2026 limit 5", store.start, store.end, store.limit)
+	}
+	if resp.TotalMinutes != 9 || resp.TotalSkips != 1 || resp.HourOfDay[21] != 3 || resp.Heatmap[4][21] != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(resp.TopTracks) != 1 || resp.TopTracks[0].TotalMinutes != 6 || resp.TopArtists[0].Name != "Alpha" {
+		t.Fatalf("top lists = %+v %+v", resp.TopArtists, resp.TopTracks)
+	}
+}
+
+func TestStatsOverviewWeekStartsMondayInUserTimeZone(t *testing.T) {
+	store := &fakeStatsStore{overview: &db.ListeningOverview{}}
+	// Saturday 2026-10-17 20:00 UTC is already Sunday in Tokyo.
+	h := newTestStatsHandlers(store, time.Date(2026, time.October, 17, 20, 0, 0, 0, time.UTC))
+	userID := uuid.New()
+	h.SetTimeZones(fakeTimeZones{userID: "Asia/Tokyo"})
+
+	rec := httptest.NewRecorder()
+	h.Overview(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/stats/overview", nil), userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	if want := time.Date(2026, time.October, 12, 0, 0, 0, 0, tokyo); !store.start.Equal(want) || !store.end.Equal(want.AddDate(0, 0, 7)) {
+		t.Fatalf("week = [%v, %v), want from %v", store.start, store.end, want)
+	}
+}
+
+func TestStatsOverviewValidatesParams(t *testing.T) {
+	h := newTestStatsHandlers(&fakeStatsStore{overview: &db.ListeningOverview{}}, time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC))
+	for _, query := range []string{"period=decade", "limit=0", "limit=51", "date=2026-10-18", "date=yesterday"} {
+		rec := httptest.NewRecorder()
+		h.Overview(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/stats/overview?"+query, nil), uuid.New()))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
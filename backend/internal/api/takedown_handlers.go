@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	maxTakedownRequestBytes = 16 << 10
+	maxTakedownPatternLen   = 2048
+	maxTakedownReasonLen    = 1000
+)
+
+type takedownStore interface {
+	Create(ctx context.Context, t *db.ContentTakedown) (int, int, error)
+	List(ctx context.Context, includeLifted bool) ([]db.ContentTakedown, error)
+	Lift(ctx context.Context, id int64) (int, error)
+}
+
+// TakedownHandlers lets admins block content. Creating a takedown quarantines
+// matching tracks and notifies their listeners; the processor and download
+// endpoint reject new copies while it is active.
+type TakedownHandlers struct {
+	store takedownStore
+}
+
+func NewTakedownHandlers(store takedownStore) *TakedownHandlers {
+	return &TakedownHandlers{store: store}
+}
+
+// CreateTakedownRequest blocks either a source URL glob (* matches anything)
+// or a track identity hash.
+type CreateTakedownRequest struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+}
+
+type TakedownResponse struct {
+	ID                int64   `json:"id"`
+	Kind              string  `json:"kind"`
+	Pattern           string  `json:"pattern"`
+	Reason            string  `json:"reason"`
+	CreatedBy         *string `json:"createdBy,omitempty"`
+	CreatedAt         string  `json:"createdAt"`
+	LiftedAt          *string `json:"liftedAt,omitempty"`
+	QuarantinedTracks int     `json:"quarantinedTracks"`
+}
+
+type CreateTakedownResponse struct {
+	TakedownResponse
+	NotifiedUsers int `json:"notifiedUsers"`
+}
+
+type LiftTakedownResponse struct {
+	ID             int64 `json:"id"`
+	ReleasedTracks int   `json:"releasedTracks"`
+}
+
+// CreateTakedown handles POST /api/v1/admin/takedowns
+func (h *TakedownHandlers) CreateTakedown(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateTakedownRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxTakedownRequestBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeTakedownError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	takedown, err := validateTakedownRequest(req)
+	if err != nil {
+		writeTakedownError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	takedown.CreatedBy = uuid.NullUUID{UUID: userCtx.UserID, Valid: true}
+
+	_, notified, err := h.store.Create(r.Context(), takedown)
+	if err != nil {
+		writeTakedownError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create takedown")
+		return
+	}
+
+	writeTakedownJSON(w, http.StatusCreated, CreateTakedownResponse{
+		TakedownResponse: toTakedownResponse(*takedown),
+		NotifiedUsers:    notified,
+	})
+}
+
+// ListTakedowns handles GET /api/v1/admin/takedowns?include_lifted=true
+func (h *TakedownHandlers) ListTakedowns(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	takedowns, err := h.store.List(r.Context(), r.URL.Query().Get("include_lifted") == "true")
+	if err != nil {
+		writeTakedownError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list takedowns")
+		return
+	}
+	resp := make([]TakedownResponse, len(takedowns))
+	for i, t := range takedowns {
+		resp[i] = toTakedownResponse(t)
+	}
+	writeTakedownJSON(w, http.StatusOK, map[string]interface{}{"takedowns": resp})
+}
+
+// LiftTakedown handles DELETE /api/v1/admin/takedowns/{id}; tracks it
+// quarantined become streamable again.
+func (h *TakedownHandlers) LiftTakedown(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeTakedownError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid takedown id")
+		return
+	}
+	released, err := h.store.Lift(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrTakedownNotFound) {
+			writeTakedownError(w, http.StatusNotFound, "TAKEDOWN_NOT_FOUND", "takedown not found or already lifted")
+			return
+		}
+		writeTakedownError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to lift takedown")
+		return
+	}
+	writeTakedownJSON(w, http.StatusOK, LiftTakedownResponse{ID: id, ReleasedTracks: released})
+}
+
+func (h *TakedownHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) (*auth.UserContext, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTakedownError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	if !userCtx.IsAdmin() {
+		writeTakedownError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return nil, false
+	}
+	return userCtx, true
+}
+
+func validateTakedownRequest(req CreateTakedownRequest) (*db.ContentTakedown, error) {
+	pattern := strings.TrimSpace(req.Pattern)
+	reason := strings.TrimSpace(req.Reason)
+	if pattern == "" || len(pattern) > maxTakedownPatternLen {
+		return nil, errors.New("pattern is required and must be at most 2048 characters")
+	}
+	if reason == "" || len(reason) > maxTakedownReasonLen {
+		return nil, errors.New("reason is required and must be at most 1000 characters")
+	}
+	switch req.Kind {
+	case db.TakedownKindSourcePattern:
+		// A bare wildcard would quarantine the whole catalog.
+		if len(strings.Trim(pattern, "*")) < 4 {
+			return nil, errors.New("source_pattern must contain at least 4 literal characters")
+		}
+	case db.TakedownKindIdentityHash:
+		if strings.ContainsAny(pattern, " \t*") {
+			return nil, errors.New("identity_hash must be a single exact hash")
+		}
+	default:
+		return nil, errors.New("kind must be one of: source_pattern, identity_hash")
+	}
+	return &db.ContentTakedown{Kind: req.Kind, Pattern: pattern, Reason: reason}, nil
+}
+
+func toTakedownResponse(t db.ContentTakedown) TakedownResponse {
+	resp := TakedownResponse{
+		ID:                t.ID,
+		Kind:              t.Kind,
+		Pattern:           t.Pattern,
+		Reason:            t.Reason,
+		CreatedAt:         t.CreatedAt.UTC().Format(time.RFC3339),
+		QuarantinedTracks: t.QuarantinedTracks,
+	}
+	if t.CreatedBy.Valid {
+		createdBy := t.CreatedBy.UUID.String()
+		resp.CreatedBy = &createdBy
+	}
+	if t.LiftedAt.Valid {
+		liftedAt := t.LiftedAt.Time.UTC().Format(time.RFC3339)
+		resp.LiftedAt = &liftedAt
+	}
+	return resp
+}
+
+func writeTakedownJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTakedownError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeTakedownStore struct {
+	created  *db.ContentTakedown
+	liftedID int64
+	liftErr  error
+}
+
+func (f *fakeTakedownStore) Create(ctx context.Context, t *db.ContentTakedown) (int, int, error) {
+	t.ID = 7
+	t.CreatedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t.QuarantinedTracks = 2
+	f.created = t
+	return 2, 5, nil
+}
+
+func (f *fakeTakedownStore) List(ctx context.Context, includeLifted bool) ([]db.ContentTakedown, error) {
+	return []db.ContentTakedown{{ID: 7, Kind: db.TakedownKindIdentityHash, Pattern: "abc", Reason: "dmca"}}, nil
+}
+
+func (f *fakeTakedownStore) Lift(ctx context.Context, id int64) (int, error) {
+	f.liftedID = id
+	if f.liftErr != nil {
+		return 0, f.liftErr
+	}
+	return 2, nil
+}
+
+// testAdminEmail signs in as an admin in the admin handler tests; every other
+// email is a member.
+const testAdminEmail = "ops@example.test"
+
+func testUser(email string) *auth.UserContext {
+	role := auth.RoleMember
+	if strings.EqualFold(email, testAdminEmail) {
+		role = auth.RoleAdmin
+	}
+	return &auth.UserContext{UserID: uuid.New(), Email: email, Role: role}
+}
+
+func takedownRequest(method, body, email string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/admin/takedowns", bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestCreateTakedownRequiresAdmin(t *testing.T) {
+	store := &fakeTakedownStore{}
+	h := NewTakedownHandlers(store)
+
+	rec := httptest.NewRecorder()
+	h.CreateTakedown(rec, takedownRequest(http.MethodPost, `{"kind":"identity_hash","pattern":"abc","reason":"dmca"}`, "listener@example.test"))
+	if rec.Code != http.StatusForbidden || store.created != nil {
+		t.Fatalf("non-admin status = %d, created = %+v", rec.Code, store.created)
+	}
+}
+
+func TestCreateTakedownQuarantinesAndReportsCounts(t *testing.T) {
+	store := &fakeTakedownStore{}
+	h := NewTakedownHandlers(store)
+
+	rec := httptest.NewRecorder()
+	h.CreateTakedown(rec, takedownRequest(http.MethodPost, `{"kind":"source_pattern","pattern":" https://soundcloud.com/label/* ","reason":"rights holder request"}`, "ops@example.test"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp CreateTakedownResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ID != 7 || resp.QuarantinedTracks != 2 || resp.NotifiedUsers != 5 || resp.CreatedBy == nil {
+		t.Fatalf("response = %+v", resp)
+	}
+	if store.created.Pattern != "https://soundcloud.com/label/*" {
+		t.Fatalf("stored pattern = %q, want trimmed", store.created.Pattern)
+	}
+}
+
+func TestCreateTakedownValidatesInput(t *testing.T) {
+	h := NewTakedownHandlers(&fakeTakedownStore{})
+	for name, body := range map[string]string{
+		"unknown kind":      `{"kind":"artist","pattern":"abc","reason":"dmca"}`,
+		"bare wildcard":     `{"kind":"source_pattern","pattern":"**","reason":"dmca"}`,
+		"wildcard hash":     `{"kind":"identity_hash","pattern":"ab*","reason":"dmca"}`,
+		"missing reason":    `{"kind":"identity_hash","pattern":"abc","reason":"  "}`,
+		"oversized pattern": `{"kind":"identity_hash","pattern":"` + strings.Repeat("a", maxTakedownPatternLen+1) + `","reason":"dmca"}`,
+		"malformed":         `{`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.CreateTakedown(rec, takedownRequest(http.MethodPost, body, "ops@example.test"))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLiftTakedownReportsReleasedTracksAndMissingTakedowns(t *testing.T) {
+	store := &fakeTakedownStore{}
+	h := NewTakedownHandlers(store)
+
+	req := takedownRequest(http.MethodDelete, "", "ops@example.test")
+	req.SetPathValue("id", "7")
+	rec := httptest.NewRecorder()
+	h.LiftTakedown(rec, req)
+	if rec.Code != http.StatusOK || store.liftedID != 7 || !strings.Contains(rec.Body.String(), `"releasedTracks":2`) {
+		t.Fatalf("status = %d, lifted = %d, body = %s", rec.Code, store.liftedID, rec.Body.String())
+	}
+
+	store.liftErr = db.ErrTakedownNotFound
+	rec = httptest.NewRecorder()
+	h.LiftTakedown(rec, req)
+	var resp ErrorResponse
+	_ = json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusNotFound || resp.Code != "TAKEDOWN_NOT_FOUND" {
+		t.Fatalf("missing takedown status = %d code = %q", rec.Code, resp.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/telemetry"
+)
+
+type telemetryReporter interface {
+	Preview(ctx context.Context) (telemetry.Report, error)
+	Status() telemetry.Status
+}
+
+// TelemetryHandlers shows admins exactly what the opt-in telemetry reporter
+// sends, whether or not it is enabled.
+type TelemetryHandlers struct {
+	reporter telemetryReporter
+}
+
+func NewTelemetryHandlers(reporter telemetryReporter) *TelemetryHandlers {
+	return &TelemetryHandlers{reporter: reporter}
+}
+
+type TelemetryPreviewResponse struct {
+	telemetry.Status
+	IntervalSeconds int64            `json:"intervalSeconds"`
+	Payload         telemetry.Report `json:"payload"`
+}
+
+// GetPreview handles GET /api/v1/admin/telemetry. Payload is built the same
+// way as a sent report, from the instance as it is now.
+func (h *TelemetryHandlers) GetPreview(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTelemetryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if !userCtx.IsAdmin() {
+		writeTelemetryError(w, http.StatusForbidden, "FORBIDDEN", "admin access required")
+		return
+	}
+	payload, err := h.reporter.Preview(r.Context())
+	if err != nil {
+		writeTelemetryError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to build telemetry report")
+		return
+	}
+	status := h.reporter.Status()
+	writeTelemetryJSON(w, http.StatusOK, TelemetryPreviewResponse{
+		Status:          status,
+		IntervalSeconds: int64(status.Interval.Seconds()),
+		Payload:         payload,
+	})
+}
+
+func writeTelemetryJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTelemetryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/telemetry"
+)
+
+type fakeTelemetryReporter struct{}
+
+func (fakeTelemetryReporter) Preview(context.Context) (telemetry.Report, error) {
+	return telemetry.Report{SchemaVersion: 1, Version: "1.0.0", Users: "2-5", Tracks: "0", Features: []string{"analysis"}}, nil
+}
+
+func (fakeTelemetryReporter) Status() telemetry.Status {
+	return telemetry.Status{Interval: 24 * time.Hour}
+}
+
+func TestTelemetryPreviewIsAdminOnly(t *testing.T) {
+	h := NewTelemetryHandlers(fakeTelemetryReporter{})
+	request := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/telemetry", nil)
+		ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+		rec := httptest.NewRecorder()
+		h.GetPreview(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	rec := request("ops@example.test")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `"enabled":false`) || !strings.Contains(body, `"intervalSeconds":86400`) || !strings.Contains(body, `"users":"2-5"`) {
+		t.Fatalf("status = %d body = %s", rec.Code, body)
+	}
+	if rec := request("listener@example.test"); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+	_ "time/tzdata" // zone names must resolve even on hosts without zoneinfo
+
+	"github.com/google/uuid"
+)
+
+// timeZoneStore looks up the IANA zone a user's stats are bucketed in.
+type timeZoneStore interface {
+	TimeZone(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// validTimeZone reports whether name is a loadable IANA zone. "Local" is
+// rejected because it means the server's zone, not the listener's.
+func validTimeZone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// userLocation resolves the user's stored time zone, falling back to UTC when
+// none is set or it cannot be loaded; stats should still render in that case.
+func userLocation(ctx context.Context, zones timeZoneStore, userID uuid.UUID) *time.Location {
+	if zones == nil {
+		return time.UTC
+	}
+	name, err := zones.TimeZone(ctx, userID)
+	if err != nil {
+		log.Printf("Warning: failed to load time zone for user %s: %v", userID, err)
+		return time.UTC
+	}
+	if name == "" || !validTimeZone(name) {
+		return time.UTC
+	}
+	loc, _ := time.LoadLocation(name)
+	return loc
+}
+
+// startOfDay returns local midnight of the day containing t in loc.
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/chapters"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type trackChaptersStore interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+}
+
+// TrackChapterHandlers serve the chapter markers and skip segments the
+// processor found for long YouTube downloads.
+type TrackChapterHandlers struct {
+	trackRepo   trackChaptersStore
+	libraryRepo trackLibraryChecker
+}
+
+func NewTrackChapterHandlers(trackRepo trackChaptersStore, libraryRepo trackLibraryChecker) *TrackChapterHandlers {
+	return &TrackChapterHandlers{trackRepo: trackRepo, libraryRepo: libraryRepo}
+}
+
+type TrackChaptersResponse struct {
+	TrackID      int64              `json:"trackId"`
+	DurationMs   int                `json:"durationMs,omitempty"`
+	Chapters     []chapters.Chapter `json:"chapters"`
+	SkipSegments []chapters.Segment `json:"skipSegments"`
+}
+
+// GetTrackChapters handles GET /api/v1/tracks/{track_id}/chapters for a
+// track in the caller's library. Tracks nothing was found for have empty
+// lists.
+func (h *TrackChapterHandlers) GetTrackChapters(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackSourcesError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeTrackSourcesError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return
+	}
+	inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library membership")
+		return
+	}
+	if !inLibrary {
+		writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	track, err := h.trackRepo.GetByID(r.Context(), trackID)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotFound) {
+			writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+			return
+		}
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return
+	}
+
+	resp := TrackChaptersResponse{TrackID: track.ID, Chapters: []chapters.Chapter{}, SkipSegments: []chapters.Segment{}}
+	if track.DurationMs.Valid {
+		resp.DurationMs = int(track.DurationMs.Int32)
+	}
+	var metadata struct {
+		Chapters *chapters.Chapters `json:"chapters"`
+	}
+	if len(track.MetadataJSON) > 0 && json.Unmarshal(track.MetadataJSON, &metadata) == nil && metadata.Chapters != nil {
+		if metadata.Chapters.Chapters != nil {
+			resp.Chapters = metadata.Chapters.Chapters
+		}
+		if metadata.Chapters.SkipSegments != nil {
+			resp.SkipSegments = metadata.Chapters.SkipSegments
+		}
+	}
+	writeTrackSourcesJSON(w, http.StatusOK, resp)
+}
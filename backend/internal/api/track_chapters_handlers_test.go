@@ -0,0 +1,57 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func serveTrackChapters(t *testing.T, h *TrackChapterHandlers, trackID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/"+trackID+"/chapters", nil)
+	req.SetPathValue("track_id", trackID)
+	rec := httptest.NewRecorder()
+	h.GetTrackChapters(rec, withUser(req, uuid.New()))
+	return rec
+}
+
+func TestGetTrackChaptersReadsMetadata(t *testing.T) {
+	track := sourcesTestTrack()
+	track.DurationMs = sql.NullInt32{Int32: 1200000, Valid: true}
+	track.MetadataJSON = json.RawMessage(`{"provider":{"title":"Mix"},"chapters":{
+		"chapters":[{"startMs":0,"endMs":600000,"title":"Part 1","source":"silence"},{"startMs":600000,"endMs":1200000,"title":"Part 2","source":"silence"}],
+		"skipSegments":[{"startMs":0,"endMs":12000,"category":"music_offtopic","source":"sponsorblock"}]}}`)
+	h := NewTrackChapterHandlers(&fakeTrackSourcesStore{track: track}, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}})
+
+	rec := serveTrackChapters(t, h, "42")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	var resp TrackChaptersResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TrackID != 42 || resp.DurationMs != 1200000 || len(resp.Chapters) != 2 || resp.Chapters[1].Title != "Part 2" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(resp.SkipSegments) != 1 || resp.SkipSegments[0].Category != "music_offtopic" || resp.SkipSegments[0].EndMs != 12000 {
+		t.Fatalf("skip segments = %+v", resp.SkipSegments)
+	}
+}
+
+func TestGetTrackChaptersWithoutChapters(t *testing.T) {
+	h := NewTrackChapterHandlers(&fakeTrackSourcesStore{track: sourcesTestTrack()}, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}})
+	rec := serveTrackChapters(t, h, "42")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"trackId":42,"chapters":[],"skipSegments":[]}`+"\n" {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+
+	h = NewTrackChapterHandlers(&fakeTrackSourcesStore{track: sourcesTestTrack()}, fakeTrackLibrary{})
+	if rec := serveTrackChapters(t, h, "42"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404 outside the library", rec.Code)
+	}
+}
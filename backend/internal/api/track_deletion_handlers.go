@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/transcode"
+)
+
+type trackDeletionStore interface {
+	DeleteTrackReferences(ctx context.Context, trackID int64, userID uuid.UUID, allowShared bool) (*db.TrackDeletion, error)
+}
+
+type trackObjectDeleter interface {
+	StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
+	DeleteObject(ctx context.Context, key string) error
+}
+
+type trackQueueRemover interface {
+	RemoveTrack(ctx context.Context, userID string, trackID int64) (int, error)
+}
+
+// TrackDeletionHandlers fully deletes tracks. Listeners may delete a track only
+// while nobody else has it in their library; admins may always drop their own
+// references, and the audio is released once no library holds the track.
+type TrackDeletionHandlers struct {
+	store   trackDeletionStore
+	storage trackObjectDeleter
+	queue   trackQueueRemover
+}
+
+// TrackDeletionResponse reports which references were removed.
+type TrackDeletionResponse struct {
+	TrackID              int64 `json:"trackId"`
+	RemovedFromLibrary   bool  `json:"removedFromLibrary"`
+	RemovedFromPlaylists int   `json:"removedFromPlaylists"`
+	RemovedQueueItems    int   `json:"removedQueueItems"`
+	RemainingLibraryRefs int   `json:"remainingLibraryRefs"`
+	TrackDeleted         bool  `json:"trackDeleted"`
+	DeletedObjects       int   `json:"deletedObjects"`
+}
+
+func NewTrackDeletionHandlers(store trackDeletionStore, storage trackObjectDeleter) *TrackDeletionHandlers {
+	return &TrackDeletionHandlers{store: store, storage: storage}
+}
+
+// SetQueue lets deletions also drop the track from the caller's playback
+// queue. The queue only exists when Redis is enabled.
+func (h *TrackDeletionHandlers) SetQueue(queue trackQueueRemover) {
+	h.queue = queue
+}
+
+// DeleteTrack handles DELETE /api/v1/tracks/{track_id}
+func (h *TrackDeletionHandlers) DeleteTrack(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackDeletionError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeTrackDeletionError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return
+	}
+
+	deletion, err := h.store.DeleteTrackReferences(r.Context(), trackID, userCtx.UserID, userCtx.IsAdmin())
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTrackNotFound):
+			writeTrackDeletionError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		case errors.Is(err, db.ErrTrackNotInLibrary):
+			writeTrackDeletionError(w, http.StatusNotFound, "TRACK_NOT_IN_LIBRARY", "track not in library")
+		case errors.Is(err, db.ErrTrackStillReferenced):
+			writeTrackDeletionError(w, http.StatusConflict, "TRACK_SHARED", "track is in other listeners' libraries; remove it from your library instead")
+		default:
+			writeTrackDeletionError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete track")
+		}
+		return
+	}
+
+	resp := TrackDeletionResponse{
+		TrackID:              trackID,
+		RemovedFromLibrary:   deletion.RemovedFromLibrary,
+		RemovedFromPlaylists: deletion.RemovedFromPlaylists,
+		RemainingLibraryRefs: deletion.OtherLibraryRefs,
+		TrackDeleted:         deletion.TrackDeleted,
+	}
+
+	// The database is the source of truth; queue and storage cleanup are best
+	// effort after it commits. A leftover object is unreachable, not dangling.
+	if h.queue != nil {
+		removed, err := h.queue.RemoveTrack(r.Context(), userCtx.UserID.String(), trackID)
+		if err != nil {
+			log.Printf("Warning: failed to remove deleted track %d from queue: %v", trackID, err)
+		}
+		resp.RemovedQueueItems = removed
+	}
+	if h.storage != nil {
+		// Variants are named after the audio's ETag, so they go while the
+		// audio can still be read.
+		for _, key := range h.storedVariants(r.Context(), deletion.AudioKeys) {
+			if err := h.storage.DeleteObject(r.Context(), key); err != nil {
+				log.Printf("Warning: failed to delete transcoded variant %s for track %d: %v", key, trackID, err)
+				continue
+			}
+			resp.DeletedObjects++
+		}
+		for _, key := range deletion.StorageKeys {
+			if err := h.storage.DeleteObject(r.Context(), key); err != nil {
+				log.Printf("Warning: failed to delete storage object %s for track %d: %v", key, trackID, err)
+				continue
+			}
+			resp.DeletedObjects++
+		}
+	}
+
+	writeTrackDeletionJSON(w, http.StatusOK, resp)
+}
+
+// storedVariants returns the transcoded variants stored for the audio at
+// keys. Lookup failures only leave a variant behind.
+func (h *TrackDeletionHandlers) storedVariants(ctx context.Context, keys []string) []string {
+	var variants []string
+	for _, key := range keys {
+		info, err := h.storage.StatObject(ctx, key)
+		if err != nil {
+			log.Printf("Warning: failed to look up %s for its transcoded variants: %v", key, err)
+			continue
+		}
+		for _, variant := range transcode.VariantKeys(key, info.ETag) {
+			if _, err := h.storage.StatObject(ctx, variant); err == nil {
+				variants = append(variants, variant)
+			}
+		}
+	}
+	return variants
+}
+
+func writeTrackDeletionJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTrackDeletionError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/transcode"
+)
+
+type fakeTrackDeletionStore struct {
+	allowShared bool
+	result      *db.TrackDeletion
+	err         error
+}
+
+func (f *fakeTrackDeletionStore) DeleteTrackReferences(ctx context.Context, trackID int64, userID uuid.UUID, allowShared bool) (*db.TrackDeletion, error) {
+	f.allowShared = allowShared
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.result, nil
+}
+
+type fakeObjectDeleter struct {
+	deleted []string
+	failKey string
+	stored  map[string]string
+}
+
+func (f *fakeObjectDeleter) StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	etag, ok := f.stored[key]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return &storage.ObjectInfo{ETag: etag}, nil
+}
+
+func (f *fakeObjectDeleter) DeleteObject(ctx context.Context, key string) error {
+	if key == f.failKey {
+		return errors.New("storage down")
+	}
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+type fakeQueueRemover struct {
+	trackID int64
+}
+
+func (f *fakeQueueRemover) RemoveTrack(ctx context.Context, userID string, trackID int64) (int, error) {
+	f.trackID = trackID
+	return 2, nil
+}
+
+func deleteTrackRequest(id, email string) *http.Request {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/tracks/"+id, nil)
+	req.SetPathValue("track_id", id)
+	ctx := context.WithValue(req.Context(), auth.UserContextKey, testUser(email))
+	return req.WithContext(ctx)
+}
+
+func TestDeleteTrackReleasesUnreferencedStorageAndQueueItems(t *testing.T) {
+	store := &fakeTrackDeletionStore{result: &db.TrackDeletion{
+		TrackID:              9,
+		RemovedFromLibrary:   true,
+		RemovedFromPlaylists: 3,
+		TrackDeleted:         true,
+		StorageKeys:          []string{"audio/a.mp3", "audio/b.opus"},
+	}}
+	objects := &fakeObjectDeleter{failKey: "audio/b.opus"}
+	queue := &fakeQueueRemover{}
+	h := NewTrackDeletionHandlers(store, objects)
+	h.SetQueue(queue)
+
+	rec := httptest.NewRecorder()
+	h.DeleteTrack(rec, deleteTrackRequest("9", "listener@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if store.allowShared {
+		t.Fatal("non-admin deletion allowed shared tracks")
+	}
+	var resp TrackDeletionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.TrackDeleted || resp.RemovedFromPlaylists != 3 || resp.RemovedQueueItems != 2 || queue.trackID != 9 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resp.DeletedObjects != 1 || len(objects.deleted) != 1 || objects.deleted[0] != "audio/a.mp3" {
+		t.Fatalf("deleted objects = %v (count %d), want only the one storage accepted", objects.deleted, resp.DeletedObjects)
+	}
+}
+
+func TestDeleteTrackReleasesWaveformAndTranscodedVariants(t *testing.T) {
+	opus, _ := transcode.Lookup("opus")
+	variant := transcode.VariantKey("audio/a.flac", "etag-1", opus)
+	store := &fakeTrackDeletionStore{result: &db.TrackDeletion{
+		TrackID:      9,
+		TrackDeleted: true,
+		StorageKeys:  []string{"audio/a.flac", "waveforms/tracks/hash.json"},
+		AudioKeys:    []string{"audio/a.flac"},
+	}}
+	objects := &fakeObjectDeleter{stored: map[string]string{
+		"audio/a.flac":               "etag-1",
+		"waveforms/tracks/hash.json": "etag-2",
+		variant:                      "etag-3",
+	}}
+	h := NewTrackDeletionHandlers(store, objects)
+
+	rec := httptest.NewRecorder()
+	h.DeleteTrack(rec, deleteTrackRequest("9", "listener@example.test"))
+	var resp TrackDeletionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []string{variant, "audio/a.flac", "waveforms/tracks/hash.json"}
+	if resp.DeletedObjects != len(want) || len(objects.deleted) != len(want) {
+		t.Fatalf("deleted %v (count %d), want %v", objects.deleted, resp.DeletedObjects, want)
+	}
+	for i, key := range want {
+		if objects.deleted[i] != key {
+			t.Fatalf("deleted %v, want %v with the variant before its audio", objects.deleted, want)
+		}
+	}
+}
+
+func TestDeleteTrackLetsAdminsDropSharedTracks(t *testing.T) {
+	store := &fakeTrackDeletionStore{result: &db.TrackDeletion{TrackID: 9, RemovedFromLibrary: true, OtherLibraryRefs: 4}}
+	objects := &fakeObjectDeleter{}
+	h := NewTrackDeletionHandlers(store, objects)
+
+	rec := httptest.NewRecorder()
+	h.DeleteTrack(rec, deleteTrackRequest("9", "ops@example.test"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !store.allowShared {
+		t.Fatal("admin deletion did not allow shared tracks")
+	}
+	if len(objects.deleted) != 0 {
+		t.Fatalf("deleted %v while other libraries still hold the track", objects.deleted)
+	}
+}
+
+func TestDeleteTrackMapsOwnershipErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{db.ErrTrackStillReferenced, http.StatusConflict, "TRACK_SHARED"},
+		{db.ErrTrackNotInLibrary, http.StatusNotFound, "TRACK_NOT_IN_LIBRARY"},
+		{db.ErrTrackNotFound, http.StatusNotFound, "TRACK_NOT_FOUND"},
+	} {
+		h := NewTrackDeletionHandlers(&fakeTrackDeletionStore{err: tc.err}, &fakeObjectDeleter{})
+		rec := httptest.NewRecorder()
+		h.DeleteTrack(rec, deleteTrackRequest("9", "listener@example.test"))
+		var resp ErrorResponse
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != tc.status || resp.Code != tc.code {
+			t.Fatalf("%v: status = %d code = %q, want %d %s", tc.err, rec.Code, resp.Code, tc.status, tc.code)
+		}
+	}
+
+	h := NewTrackDeletionHandlers(&fakeTrackDeletionStore{}, &fakeObjectDeleter{})
+	rec := httptest.NewRecorder()
+	h.DeleteTrack(rec, deleteTrackRequest("abc", "listener@example.test"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid id status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/transcode"
+)
+
+// maxDownloadFilenameLength bounds the name before its extension, in runes.
+const maxDownloadFilenameLength = 150
+
+// DownloadTrack handles GET /api/v1/tracks/{track_id}/download?format=opus|mp3|flac
+// for a track in the caller's library. It redirects to a short-lived signed
+// URL that serves the audio as an attachment named "Artist - Title.ext", so
+// a plain link saves the file. Without a format the stored original is
+// served; otherwise the format's variant, transcoded on first request. The
+// redirect carries the track's X-ReplayGain-* headers once it has been
+// measured.
+func (h *PlaybackHandlers) DownloadTrack(w http.ResponseWriter, r *http.Request) {
+	if h == nil || h.trackRepo == nil || h.libraryRepo == nil || h.storage == nil {
+		writePlaybackError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "track downloads are unavailable")
+		return
+	}
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writePlaybackError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writePlaybackError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return
+	}
+	var format transcode.Format
+	wantFormat := false
+	if name := r.URL.Query().Get("format"); name != "" {
+		if format, wantFormat = transcode.Lookup(name); !wantFormat {
+			writePlaybackError(w, http.StatusBadRequest, "UNSUPPORTED_FORMAT", "format must be one of opus, mp3, or flac")
+			return
+		}
+	}
+
+	inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library ownership")
+		return
+	}
+	if !inLibrary {
+		writePlaybackError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	track, err := h.trackRepo.GetByID(r.Context(), trackID)
+	if errors.Is(err, db.ErrTrackNotFound) {
+		writePlaybackError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	if err != nil {
+		writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return
+	}
+	if track.QuarantinedAt.Valid {
+		writePlaybackError(w, http.StatusUnavailableForLegalReasons, "CONTENT_TAKEN_DOWN", "track is blocked by a content takedown")
+		return
+	}
+
+	storageKey := strings.TrimSpace(track.StorageKey.String)
+	if !track.StorageKey.Valid || storageKey == "" {
+		writePlaybackError(w, http.StatusNotFound, "AUDIO_UNAVAILABLE", "track has no stored audio object")
+		return
+	}
+	objInfo, err := h.storage.StatObject(r.Context(), storageKey)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		writePlaybackError(w, http.StatusNotFound, "ARTIFACT_MISSING", "stored audio object is unavailable")
+		return
+	}
+
+	ext := strings.ToLower(path.Ext(storageKey))
+	if wantFormat && !storedInFormat(track, storageKey, objInfo, format) {
+		if h.transcoder == nil {
+			writePlaybackError(w, http.StatusNotAcceptable, "FORMAT_UNAVAILABLE", "audio is not available as "+format.Name)
+			return
+		}
+		variantKey, _, err := h.transcoder.Variant(r.Context(), storageKey, objInfo, format)
+		switch {
+		case err == nil:
+			storageKey, ext = variantKey, "."+format.Extension
+		case r.Context().Err() != nil:
+			return
+		case errors.Is(err, transcode.ErrDisabled):
+			writePlaybackError(w, http.StatusNotAcceptable, "FORMAT_UNAVAILABLE", "audio is not available as "+format.Name)
+			return
+		case errors.Is(err, transcode.ErrLowDiskSpace):
+			writePlaybackError(w, http.StatusServiceUnavailable, "TRANSCODE_UNAVAILABLE", "audio cannot be transcoded right now; try again later")
+			return
+		default:
+			log.Printf("Error: failed to transcode track %d to %s for download: %v", trackID, format.Name, err)
+			writePlaybackError(w, http.StatusInternalServerError, "TRANSCODE_FAILED", "audio could not be transcoded to "+format.Name)
+			return
+		}
+	}
+
+	url, err := h.storage.PresignDownloadObject(r.Context(), storageKey, defaultPlaybackURLTTL, downloadFilename(track, ext))
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		writePlaybackError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to issue download URL")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	setReplayGainHeaders(w.Header(), track.ReplayGain)
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// downloadFilename names a downloaded file "Artist - Title.ext", dropping
+// characters that common filesystems reject.
+func downloadFilename(track *db.Track, ext string) string {
+	title := downloadNamePart(track.Title)
+	if title == "" {
+		title = fmt.Sprintf("Track %d", track.ID)
+	}
+	name := title
+	if artist := downloadNamePart(track.Artist.String); artist != "" {
+		name = artist + " - " + title
+	}
+	if runes := []rune(name); len(runes) > maxDownloadFilenameLength {
+		name = strings.TrimSpace(string(runes[:maxDownloadFilenameLength]))
+	}
+	return name + ext
+}
+
+func downloadNamePart(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, value)
+	return strings.Trim(strings.Join(strings.Fields(value), " "), ". ")
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/transcode"
+)
+
+func trackDownloadRequest(handler *PlaybackHandlers, id, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/"+id+"/download"+query, nil)
+	req.SetPathValue("track_id", id)
+	rec := httptest.NewRecorder()
+	handler.DownloadTrack(rec, withUser(req, uuid.New()))
+	return rec
+}
+
+func TestDownloadTrackRedirectsToNamedAttachment(t *testing.T) {
+	transcoder := &fakePlaybackTranscoder{}
+	handler, fakeStorage := flacPlaybackHandler(transcoder)
+	track, _ := handler.trackRepo.GetByID(context.Background(), 42)
+	track.Title = "Teardrop: Live?"
+	track.Artist = sql.NullString{String: "Massive Attack", Valid: true}
+
+	rec := trackDownloadRequest(handler, "42", "")
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), "https://objects.example.test/audio/track-42.flac") {
+		t.Fatalf("status = %d location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if got := fakeStorage.filenames[0]; got != "Massive Attack - Teardrop_ Live_.flac" {
+		t.Fatalf("filename = %q", got)
+	}
+	if len(transcoder.formats) != 0 {
+		t.Fatalf("transcoded %v without a requested format", transcoder.formats)
+	}
+
+	rec = trackDownloadRequest(handler, "42", "?format=mp3")
+	if rec.Code != http.StatusFound || fakeStorage.presignKeys[1] != "transcodes/mp3/audio/track-42.flac" || fakeStorage.filenames[1] != "Massive Attack - Teardrop_ Live_.mp3" {
+		t.Fatalf("status = %d signed %v as %v", rec.Code, fakeStorage.presignKeys, fakeStorage.filenames)
+	}
+}
+
+func TestDownloadTrackErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		id     string
+		query  string
+		err    error
+		status int
+	}{
+		{"bad id", "x", "", nil, http.StatusBadRequest},
+		{"unknown format", "42", "?format=wma", nil, http.StatusBadRequest},
+		{"not in library", "7", "", nil, http.StatusNotFound},
+		{"transcoding disabled", "42", "?format=opus", transcode.ErrDisabled, http.StatusNotAcceptable},
+		{"transcode failed", "42", "?format=opus", errors.New("ffmpeg failed"), http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, _ := flacPlaybackHandler(&fakePlaybackTranscoder{err: tc.err})
+			rec := trackDownloadRequest(handler, tc.id, tc.query)
+			if rec.Code != tc.status || rec.Header().Get("Location") != "" {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tc.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDownloadFilenameFallsBackToTrackID(t *testing.T) {
+	for _, tc := range []struct {
+		track *db.Track
+		want  string
+	}{
+		{&db.Track{ID: 9, Title: " .. "}, "Track 9.mp3"},
+		{&db.Track{ID: 9, Title: "Intro", Artist: sql.NullString{String: "AC/DC", Valid: true}}, "AC_DC - Intro.mp3"},
+		{&db.Track{ID: 9, Title: strings.Repeat("a", 200)}, strings.Repeat("a", maxDownloadFilenameLength) + ".mp3"},
+	} {
+		if got := downloadFilename(tc.track, ".mp3"); got != tc.want {
+			t.Fatalf("downloadFilename(%q) = %q, want %q", tc.track.Title, got, tc.want)
+		}
+	}
+}
+
+func TestDownloadTrackSendsReplayGainHeaders(t *testing.T) {
+	handler, _ := flacPlaybackHandler(&fakePlaybackTranscoder{})
+	rec := trackDownloadRequest(handler, "42", "")
+	if got := rec.Header().Get(headerReplayGainTrackGain); got != "" {
+		t.Fatalf("unmeasured track sent track gain %q", got)
+	}
+
+	track, _ := handler.trackRepo.GetByID(context.Background(), 42)
+	track.ReplayGain = db.ReplayGain{
+		TrackGainDB:   sql.NullFloat64{Float64: -8.16, Valid: true},
+		TruePeakDBTP:  sql.NullFloat64{Float64: 0, Valid: true},
+		AlbumGainDB:   sql.NullFloat64{Float64: -7.5, Valid: true},
+		AlbumPeakDBTP: sql.NullFloat64{Float64: -6.0206, Valid: true},
+	}
+	rec = trackDownloadRequest(handler, "42", "")
+	for header, want := range map[string]string{
+		headerReplayGainTrackGain: "-8.16 dB",
+		headerReplayGainTrackPeak: "1.000000",
+		headerReplayGainAlbumGain: "-7.50 dB",
+		headerReplayGainAlbumPeak: "0.500000",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/capability"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	defaultTrackGrantTTL = 7 * 24 * time.Hour
+	minTrackGrantTTL     = time.Minute
+	maxTrackGrantTTL     = 90 * 24 * time.Hour
+	maxTrackGrantTracks  = 1000
+)
+
+type trackGrantStore interface {
+	Create(ctx context.Context, grant *db.TrackGrant) error
+	ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]db.TrackGrant, error)
+	Revoke(ctx context.Context, id, ownerID uuid.UUID) error
+}
+
+type trackGrantPlaylists interface {
+	GetByIDWithTracks(ctx context.Context, id int64) (*db.PlaylistWithTracks, error)
+}
+
+type trackGrantUsers interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*db.User, error)
+}
+
+// TrackGrantHandlers lets owners share tracks from a context they control as
+// per-track capabilities, and revoke them.
+type TrackGrantHandlers struct {
+	grants    trackGrantStore
+	playlists trackGrantPlaylists
+	users     trackGrantUsers
+	signer    *capability.Signer
+	now       func() time.Time
+}
+
+func NewTrackGrantHandlers(grants trackGrantStore, playlists trackGrantPlaylists, users trackGrantUsers, signer *capability.Signer) *TrackGrantHandlers {
+	return &TrackGrantHandlers{grants: grants, playlists: playlists, users: users, signer: signer, now: time.Now}
+}
+
+// CreateTrackGrantRequest shares tracks of a context. For a playlist,
+// TrackIDs defaults to the whole playlist and must otherwise be a subset of
+// it. Without GranteeID anyone holding the capabilities may play the tracks,
+// which is only allowed for public playlists.
+type CreateTrackGrantRequest struct {
+	ContextType      string  `json:"contextType"`
+	ContextID        string  `json:"contextId"`
+	TrackIDs         []int64 `json:"trackIds,omitempty"`
+	GranteeID        *string `json:"granteeId,omitempty"`
+	ExpiresInSeconds int     `json:"expiresInSeconds,omitempty"`
+}
+
+type TrackCapabilityResponse struct {
+	TrackID int64 `json:"trackId"`
+	// Capability is omitted once the grant is revoked or expired.
+	Capability string `json:"capability,omitempty"`
+}
+
+type TrackGrantResponse struct {
+	ID          string                    `json:"id"`
+	ContextType string                    `json:"contextType"`
+	ContextID   string                    `json:"contextId"`
+	GranteeID   *string                   `json:"granteeId"`
+	Tracks      []TrackCapabilityResponse `json:"tracks"`
+	Active      bool                      `json:"active"`
+	ExpiresAt   time.Time                 `json:"expiresAt"`
+	RevokedAt   *time.Time                `json:"revokedAt,omitempty"`
+	CreatedAt   time.Time                 `json:"createdAt"`
+}
+
+type TrackGrantsResponse struct {
+	Grants []TrackGrantResponse `json:"grants"`
+}
+
+// CreateGrant handles POST /api/v1/track-grants
+func (h *TrackGrantHandlers) CreateGrant(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackGrantError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req CreateTrackGrantRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeTrackGrantError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.ContextType != db.TrackGrantContextPlaylist {
+		writeTrackGrantError(w, http.StatusBadRequest, "INVALID_CONTEXT", "contextType must be playlist")
+		return
+	}
+	ttl := defaultTrackGrantTTL
+	if req.ExpiresInSeconds != 0 {
+		ttl = time.Duration(req.ExpiresInSeconds) * time.Second
+		if ttl < minTrackGrantTTL || ttl > maxTrackGrantTTL {
+			writeTrackGrantError(w, http.StatusBadRequest, "INVALID_EXPIRY", "expiresInSeconds must be between 60 and 7776000")
+			return
+		}
+	}
+
+	grant := &db.TrackGrant{
+		ID:          uuid.New(),
+		OwnerID:     userCtx.UserID,
+		ContextType: req.ContextType,
+		ExpiresAt:   h.now().Add(ttl).UTC().Truncate(time.Second),
+	}
+	if req.GranteeID != nil {
+		granteeID, err := uuid.Parse(*req.GranteeID)
+		if err != nil || granteeID == userCtx.UserID {
+			writeTrackGrantError(w, http.StatusBadRequest, "INVALID_GRANTEE", "granteeId must be another user's ID")
+			return
+		}
+		if _, err := h.users.GetByID(r.Context(), granteeID); err != nil {
+			if errors.Is(err, db.ErrUserNotFound) {
+				writeTrackGrantError(w, http.StatusBadRequest, "INVALID_GRANTEE", "granteeId must be another user's ID")
+				return
+			}
+			writeTrackGrantError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to look up grantee")
+			return
+		}
+		grant.GranteeID = uuid.NullUUID{UUID: granteeID, Valid: true}
+	}
+
+	trackIDs, ok := h.playlistGrantTracks(w, r, userCtx.UserID, req, grant)
+	if !ok {
+		return
+	}
+	grant.TrackIDs = trackIDs
+
+	if err := h.grants.Create(r.Context(), grant); err != nil {
+		writeTrackGrantError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create grant")
+		return
+	}
+	writeTrackGrantJSON(w, http.StatusCreated, h.grantResponse(grant))
+}
+
+// playlistGrantTracks checks the caller may share the playlist and returns the
+// tracks to grant, writing the error response when it returns false.
+func (h *TrackGrantHandlers) playlistGrantTracks(w http.ResponseWriter, r *http.Request, userID uuid.UUID, req CreateTrackGrantRequest, grant *db.TrackGrant) ([]int64, bool) {
+	playlistID, err := strconv.ParseInt(req.ContextID, 10, 64)
+	if err != nil || playlistID <= 0 {
+		writeTrackGrantError(w, http.StatusBadRequest, "INVALID_CONTEXT", "contextId must be a playlist ID")
+		return nil, false
+	}
+	playlist, err := h.playlists.GetByIDWithTracks(r.Context(), playlistID)
+	if err != nil {
+		if errors.Is(err, db.ErrPlaylistNotFound) {
+			writeTrackGrantError(w, http.StatusNotFound, "PLAYLIST_NOT_FOUND", "playlist not found")
+			return nil, false
+		}
+		writeTrackGrantError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load playlist")
+		return nil, false
+	}
+	if playlist.UserID != userID {
+		writeTrackGrantError(w, http.StatusNotFound, "PLAYLIST_NOT_FOUND", "playlist not found")
+		return nil, false
+	}
+	if playlist.SystemKind.Valid {
+		writeTrackGrantError(w, http.StatusForbidden, "READ_ONLY_PLAYLIST", "generated playlists cannot be shared")
+		return nil, false
+	}
+	if !grant.GranteeID.Valid && !playlist.IsPublic {
+		writeTrackGrantError(w, http.StatusForbidden, "PLAYLIST_NOT_PUBLIC", "only public playlists can be shared without a grantee")
+		return nil, false
+	}
+	grant.ContextID = strconv.FormatInt(playlistID, 10)
+
+	inPlaylist := make(map[int64]bool, len(playlist.Tracks))
+	all := make([]int64, 0, len(playlist.Tracks))
+	for _, track := range playlist.Tracks {
+		if !inPlaylist[track.ID] {
+			inPlaylist[track.ID] = true
+			all = append(all, track.ID)
+		}
+	}
+	trackIDs := all
+	if len(req.TrackIDs) > 0 {
+		trackIDs, err = validateAndDedupeTrackIDs(req.TrackIDs)
+		if err != nil {
+			writeTrackGrantError(w, http.StatusBadRequest, "INVALID_TRACKS", err.Error())
+			return nil, false
+		}
+		for _, id := range trackIDs {
+			if !inPlaylist[id] {
+				writeTrackGrantError(w, http.StatusBadRequest, "INVALID_TRACKS", "trackIds must all be in the playlist")
+				return nil, false
+			}
+		}
+	}
+	if len(trackIDs) == 0 {
+		writeTrackGrantError(w, http.StatusBadRequest, "INVALID_TRACKS", "playlist has no tracks to share")
+		return nil, false
+	}
+	if len(trackIDs) > maxTrackGrantTracks {
+		writeTrackGrantError(w, http.StatusBadRequest, "INVALID_TRACKS", "a grant covers at most 1000 tracks")
+		return nil, false
+	}
+	return trackIDs, true
+}
+
+// ListGrants handles GET /api/v1/track-grants
+func (h *TrackGrantHandlers) ListGrants(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackGrantError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	grants, err := h.grants.ListByOwner(r.Context(), userCtx.UserID)
+	if err != nil {
+		writeTrackGrantError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load grants")
+		return
+	}
+	resp := TrackGrantsResponse{Grants: make([]TrackGrantResponse, 0, len(grants))}
+	for i := range grants {
+		resp.Grants = append(resp.Grants, h.grantResponse(&grants[i]))
+	}
+	writeTrackGrantJSON(w, http.StatusOK, resp)
+}
+
+// RevokeGrant handles DELETE /api/v1/track-grants/{id}. Every capability of
+// the grant stops working immediately.
+func (h *TrackGrantHandlers) RevokeGrant(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackGrantError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	grantID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeTrackGrantError(w, http.StatusNotFound, "GRANT_NOT_FOUND", "grant not found")
+		return
+	}
+	if err := h.grants.Revoke(r.Context(), grantID, userCtx.UserID); err != nil {
+		if errors.Is(err, db.ErrTrackGrantNotFound) {
+			writeTrackGrantError(w, http.StatusNotFound, "GRANT_NOT_FOUND", "grant not found")
+			return
+		}
+		writeTrackGrantError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke grant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TrackGrantHandlers) grantResponse(grant *db.TrackGrant) TrackGrantResponse {
+	active := grant.Active(h.now())
+	resp := TrackGrantResponse{
+		ID:          grant.ID.String(),
+		ContextType: grant.ContextType,
+		ContextID:   grant.ContextID,
+		Tracks:      make([]TrackCapabilityResponse, 0, len(grant.TrackIDs)),
+		Active:      active,
+		ExpiresAt:   grant.ExpiresAt,
+		CreatedAt:   grant.CreatedAt,
+	}
+	if grant.GranteeID.Valid {
+		granteeID := grant.GranteeID.UUID.String()
+		resp.GranteeID = &granteeID
+	}
+	if grant.RevokedAt.Valid {
+		revokedAt := grant.RevokedAt.Time
+		resp.RevokedAt = &revokedAt
+	}
+	for _, trackID := range grant.TrackIDs {
+		item := TrackCapabilityResponse{TrackID: trackID}
+		if active {
+			item.Capability = h.signer.Sign(capability.Claims{GrantID: grant.ID, TrackID: trackID, ExpiresAt: grant.ExpiresAt})
+		}
+		resp.Tracks = append(resp.Tracks, item)
+	}
+	return resp
+}
+
+func writeTrackGrantJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTrackGrantError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/capability"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeTrackGrants struct {
+	grants []*db.TrackGrant
+}
+
+func (f *fakeTrackGrants) Create(_ context.Context, grant *db.TrackGrant) error {
+	grant.CreatedAt = time.Now()
+	f.grants = append(f.grants, grant)
+	return nil
+}
+
+func (f *fakeTrackGrants) ListByOwner(_ context.Context, ownerID uuid.UUID) ([]db.TrackGrant, error) {
+	var out []db.TrackGrant
+	for _, g := range f.grants {
+		if g.OwnerID == ownerID {
+			out = append(out, *g)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTrackGrants) Revoke(_ context.Context, id, ownerID uuid.UUID) error {
+	for _, g := range f.grants {
+		if g.ID == id && g.OwnerID == ownerID {
+			g.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			return nil
+		}
+	}
+	return db.ErrTrackGrantNotFound
+}
+
+type fakeGrantPlaylists map[int64]*db.PlaylistWithTracks
+
+func (f fakeGrantPlaylists) GetByIDWithTracks(_ context.Context, id int64) (*db.PlaylistWithTracks, error) {
+	if p, ok := f[id]; ok {
+		return p, nil
+	}
+	return nil, db.ErrPlaylistNotFound
+}
+
+type fakeGrantUsers map[uuid.UUID]bool
+
+func (f fakeGrantUsers) GetByID(_ context.Context, id uuid.UUID) (*db.User, error) {
+	if !f[id] {
+		return nil, db.ErrUserNotFound
+	}
+	return &db.User{ID: id}, nil
+}
+
+func newTrackGrantTestHandlers(owner, friend uuid.UUID) (*TrackGrantHandlers, *fakeTrackGrants) {
+	grants := &fakeTrackGrants{}
+	playlists := fakeGrantPlaylists{
+		7: {Playlist: db.Playlist{ID: 7, UserID: owner, IsPublic: true}, Tracks: []db.Track{{ID: 1}, {ID: 2}}},
+		8: {Playlist: db.Playlist{ID: 8, UserID: owner}, Tracks: []db.Track{{ID: 3}}},
+	}
+	return NewTrackGrantHandlers(grants, playlists, fakeGrantUsers{owner: true, friend: true}, capability.NewSigner("test-secret")), grants
+}
+
+func createTrackGrant(h *TrackGrantHandlers, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/v1/track-grants", strings.NewReader(body)), userID)
+	rec := httptest.NewRecorder()
+	h.CreateGrant(rec, req)
+	return rec
+}
+
+func TestCreateTrackGrantSignsEveryPlaylistTrack(t *testing.T) {
+	owner := uuid.New()
+	h, grants := newTrackGrantTestHandlers(owner, uuid.New())
+
+	rec := createTrackGrant(h, owner, `{"contextType":"playlist","contextId":"7","expiresInSeconds":3600}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp TrackGrantResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Tracks) != 2 || !resp.Active || resp.GranteeID != nil {
+		t.Fatalf("response = %+v, want two tracks in an active bearer grant", resp)
+	}
+	signer := capability.NewSigner("test-secret")
+	for _, track := range resp.Tracks {
+		claims, err := signer.Parse(track.Capability, time.Now())
+		if err != nil || claims.TrackID != track.TrackID || claims.GrantID != grants.grants[0].ID {
+			t.Fatalf("capability for track %d = %+v, %v", track.TrackID, claims, err)
+		}
+	}
+}
+
+func TestCreateTrackGrantValidatesContext(t *testing.T) {
+	owner, friend := uuid.New(), uuid.New()
+	h, grants := newTrackGrantTestHandlers(owner, friend)
+
+	cases := []struct {
+		name   string
+		caller uuid.UUID
+		body   string
+		want   int
+	}{
+		{"unknown context", owner, `{"contextType":"party","contextId":"7"}`, http.StatusBadRequest},
+		{"not the owner", friend, `{"contextType":"playlist","contextId":"7"}`, http.StatusNotFound},
+		{"private without grantee", owner, `{"contextType":"playlist","contextId":"8"}`, http.StatusForbidden},
+		{"track outside playlist", owner, `{"contextType":"playlist","contextId":"7","trackIds":[3]}`, http.StatusBadRequest},
+		{"unknown grantee", owner, `{"contextType":"playlist","contextId":"8","granteeId":"` + uuid.NewString() + `"}`, http.StatusBadRequest},
+		{"expiry too long", owner, `{"contextType":"playlist","contextId":"7","expiresInSeconds":99999999}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec := createTrackGrant(h, tc.caller, tc.body); rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+	if len(grants.grants) != 0 {
+		t.Fatalf("created %d grants, want none", len(grants.grants))
+	}
+
+	if rec := createTrackGrant(h, owner, `{"contextType":"playlist","contextId":"8","granteeId":"`+friend.String()+`"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("private playlist with grantee status = %d, want 201; body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRevokeTrackGrantWithholdsCapabilities(t *testing.T) {
+	owner := uuid.New()
+	h, grants := newTrackGrantTestHandlers(owner, uuid.New())
+	if rec := createTrackGrant(h, owner, `{"contextType":"playlist","contextId":"7","trackIds":[2]}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d", rec.Code)
+	}
+	grantID := grants.grants[0].ID.String()
+
+	other := withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/track-grants/"+grantID, nil), uuid.New())
+	other.SetPathValue("id", grantID)
+	rec := httptest.NewRecorder()
+	h.RevokeGrant(rec, other)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("revoke by another user = %d, want 404", rec.Code)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/track-grants/"+grantID, nil), owner)
+	req.SetPathValue("id", grantID)
+	rec = httptest.NewRecorder()
+	h.RevokeGrant(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke status = %d, want 204", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ListGrants(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/track-grants", nil), owner))
+	var resp TrackGrantsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Grants) != 1 || resp.Grants[0].Active || resp.Grants[0].RevokedAt == nil || resp.Grants[0].Tracks[0].Capability != "" {
+		t.Fatalf("listed grants = %+v, want one revoked grant without capabilities", resp.Grants)
+	}
+}
@@ -0,0 +1,533 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	TrackLinkSource               = "source"
+	TrackLinkMusicBrainzRecording = "musicbrainz_recording"
+	TrackLinkMusicBrainzRelease   = "musicbrainz_release"
+	TrackLinkMusicBrainzArtist    = "musicbrainz_artist"
+	TrackLinkArtistSite           = "artist_site"
+
+	musicBrainzSiteURL = "https://musicbrainz.org"
+)
+
+// TrackLinkResponse is an outbound link for attribution: where the audio came
+// from and where the track is catalogued.
+type TrackLinkResponse struct {
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// trackLinks builds the link-outs derivable from the track row alone. The
+// artist's own site needs a MusicBrainz lookup and is only added by the
+// sources endpoint.
+func trackLinks(t *db.Track) []TrackLinkResponse {
+	var links []TrackLinkResponse
+	if t.SourceURL.Valid && isWebURL(t.SourceURL.String) {
+		provider := ""
+		if t.SourceType.Valid {
+			provider = t.SourceType.String
+		}
+		links = append(links, TrackLinkResponse{
+			Kind:  TrackLinkSource,
+			Label: "Listen on " + sourceProviderLabel(provider, t.SourceURL.String),
+			URL:   t.SourceURL.String,
+		})
+	}
+	if t.MBRecordingID != nil {
+		links = append(links, TrackLinkResponse{
+			Kind:  TrackLinkMusicBrainzRecording,
+			Label: "MusicBrainz recording",
+			URL:   musicBrainzSiteURL + "/recording/" + t.MBRecordingID.String(),
+		})
+	}
+	if t.MBReleaseID != nil {
+		links = append(links, TrackLinkResponse{
+			Kind:  TrackLinkMusicBrainzRelease,
+			Label: "MusicBrainz release",
+			URL:   musicBrainzSiteURL + "/release/" + t.MBReleaseID.String(),
+		})
+	}
+	if t.MBArtistID != nil {
+		links = append(links, TrackLinkResponse{
+			Kind:  TrackLinkMusicBrainzArtist,
+			Label: "MusicBrainz artist",
+			URL:   musicBrainzSiteURL + "/artist/" + t.MBArtistID.String(),
+		})
+	}
+	return links
+}
+
+// sourceProviderLabel names a provider for display, falling back to the URL's
+// host for providers without a known name.
+func sourceProviderLabel(provider, rawURL string) string {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "youtube":
+		return "YouTube"
+	case "soundcloud":
+		return "SoundCloud"
+	case "spotify":
+		return "Spotify"
+	case "bandcamp":
+		return "Bandcamp"
+	}
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" {
+		return strings.TrimPrefix(parsed.Hostname(), "www.")
+	}
+	if provider != "" {
+		return provider
+	}
+	return "source"
+}
+
+// isWebURL reports whether raw is an absolute http(s) URL, so internal schemes
+// such as fixture:// are never handed to clients as links.
+func isWebURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+type trackSourcesStore interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+	ListSources(ctx context.Context, trackID int64) ([]db.TrackSource, error)
+	SetCanonicalSource(ctx context.Context, trackID, sourceID int64) (*db.Track, error)
+}
+
+type trackLibraryChecker interface {
+	IsTrackInLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (bool, error)
+}
+
+// artistHomepageResolver looks up an artist's official site by MusicBrainz ID.
+type artistHomepageResolver interface {
+	GetArtistHomepage(ctx context.Context, mbID string) (string, error)
+}
+
+const (
+	maxCanonicalSourceRequestBytes = 4 << 10
+	// clippedSourceRatio is the share of full-scale samples above which a
+	// source counts as audibly clipped.
+	clippedSourceRatio = 0.0005
+)
+
+// TrackSourceHandlers lists every known source for a deduplicated track along
+// with attribution links and measured quality, and lets a listener switch the
+// track's stored audio to a better source.
+type TrackSourceHandlers struct {
+	trackRepo   trackSourcesStore
+	libraryRepo trackLibraryChecker
+	artists     artistHomepageResolver
+}
+
+// NewTrackSourceHandlers wires the handlers; artists may be nil, in which case
+// the artist-site link is omitted.
+func NewTrackSourceHandlers(trackRepo trackSourcesStore, libraryRepo trackLibraryChecker, artists artistHomepageResolver) *TrackSourceHandlers {
+	return &TrackSourceHandlers{trackRepo: trackRepo, libraryRepo: libraryRepo, artists: artists}
+}
+
+type TrackSourceResponse struct {
+	ID          int64                      `json:"id,omitempty"`
+	Provider    string                     `json:"provider"`
+	Label       string                     `json:"label"`
+	SourceID    string                     `json:"sourceId,omitempty"`
+	URL         string                     `json:"url,omitempty"`
+	Primary     bool                       `json:"primary"`
+	Canonical   bool                       `json:"canonical"`
+	Quality     *TrackAudioQualityResponse `json:"quality,omitempty"`
+	FirstSeenAt string                     `json:"firstSeenAt"`
+	LastSeenAt  string                     `json:"lastSeenAt"`
+}
+
+type TrackAudioQualityResponse struct {
+	Codec         string   `json:"codec,omitempty"`
+	BitrateKbps   int      `json:"bitrateKbps,omitempty"`
+	SampleRateHz  int      `json:"sampleRateHz,omitempty"`
+	Channels      int      `json:"channels,omitempty"`
+	FileSizeBytes int64    `json:"fileSizeBytes,omitempty"`
+	ContentType   string   `json:"contentType,omitempty"`
+	PeakDBFS      *float64 `json:"peakDbfs,omitempty"`
+	ClippingRatio *float64 `json:"clippingRatio,omitempty"`
+	Clipped       bool     `json:"clipped,omitempty"`
+}
+
+type TrackSourcesResponse struct {
+	TrackID      int64                     `json:"trackId"`
+	Quality      TrackAudioQualityResponse `json:"quality"`
+	BestSourceID int64                     `json:"bestSourceId,omitempty"`
+	Sources      []TrackSourceResponse     `json:"sources"`
+	Links        []TrackLinkResponse       `json:"links"`
+}
+
+type CanonicalSourceRequest struct {
+	SourceID int64 `json:"sourceId,omitempty"`
+}
+
+type CanonicalSourceResponse struct {
+	Switched bool `json:"switched"`
+	TrackSourcesResponse
+}
+
+// ListTrackSources handles GET /api/v1/tracks/{track_id}/sources
+func (h *TrackSourceHandlers) ListTrackSources(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.libraryTrack(w, r)
+	if !ok {
+		return
+	}
+	sources, err := h.trackRepo.ListSources(r.Context(), track.ID)
+	if err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track sources")
+		return
+	}
+	writeTrackSourcesJSON(w, http.StatusOK, h.sourcesResponse(r.Context(), track, sources))
+}
+
+// SetCanonicalSource handles POST /api/v1/tracks/{track_id}/sources/canonical.
+// The body may name a measured source by id; without one the best measured
+// source is chosen. Tracks are shared across users and this server has no
+// admin role, so any listener with the track in their library may switch it.
+func (h *TrackSourceHandlers) SetCanonicalSource(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.libraryTrack(w, r)
+	if !ok {
+		return
+	}
+
+	var req CanonicalSourceRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxCanonicalSourceRequestBytes)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeTrackSourcesError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.SourceID < 0 {
+		writeTrackSourcesError(w, http.StatusBadRequest, "VALIDATION_ERROR", "sourceId must be positive")
+		return
+	}
+
+	sources, err := h.trackRepo.ListSources(r.Context(), track.ID)
+	if err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track sources")
+		return
+	}
+	targetID := req.SourceID
+	if targetID == 0 {
+		targetID = bestTrackSource(sources)
+		if targetID == 0 {
+			writeTrackSourcesError(w, http.StatusConflict, "NO_MEASURED_SOURCE", "track has no measured source to switch to")
+			return
+		}
+	}
+
+	var target *db.TrackSource
+	for i := range sources {
+		if sources[i].ID == targetID {
+			target = &sources[i]
+			break
+		}
+	}
+	if target == nil || !target.StorageKey.Valid || strings.TrimSpace(target.StorageKey.String) == "" {
+		writeTrackSourcesError(w, http.StatusNotFound, "SOURCE_NOT_FOUND", "no measured source with that id for this track")
+		return
+	}
+	if isCanonicalSource(track, *target) {
+		writeTrackSourcesJSON(w, http.StatusOK, CanonicalSourceResponse{
+			Switched:             false,
+			TrackSourcesResponse: h.sourcesResponse(r.Context(), track, sources),
+		})
+		return
+	}
+
+	updated, err := h.trackRepo.SetCanonicalSource(r.Context(), track.ID, target.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackSourceNotFound) {
+			writeTrackSourcesError(w, http.StatusNotFound, "SOURCE_NOT_FOUND", "no measured source with that id for this track")
+			return
+		}
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to switch track source")
+		return
+	}
+	log.Printf("Track %d canonical audio switched to source %d (%s) by user %s", track.ID, target.ID, target.Provider, auth.GetUserFromContext(r.Context()).UserID)
+	writeTrackSourcesJSON(w, http.StatusOK, CanonicalSourceResponse{
+		Switched:             true,
+		TrackSourcesResponse: h.sourcesResponse(r.Context(), updated, sources),
+	})
+}
+
+// libraryTrack resolves the path track and checks it is in the caller's
+// library, writing the error response when it is not.
+func (h *TrackSourceHandlers) libraryTrack(w http.ResponseWriter, r *http.Request) (*db.Track, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackSourcesError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return nil, false
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeTrackSourcesError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return nil, false
+	}
+
+	inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library membership")
+		return nil, false
+	}
+	if !inLibrary {
+		writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return nil, false
+	}
+
+	track, err := h.trackRepo.GetByID(r.Context(), trackID)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotFound) {
+			writeTrackSourcesError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+			return nil, false
+		}
+		writeTrackSourcesError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return nil, false
+	}
+	return track, true
+}
+
+func (h *TrackSourceHandlers) sourcesResponse(ctx context.Context, track *db.Track, sources []db.TrackSource) TrackSourcesResponse {
+	links := trackLinks(track)
+	if link, ok := h.artistSiteLink(ctx, track); ok {
+		links = append(links, link)
+	}
+	if links == nil {
+		links = []TrackLinkResponse{}
+	}
+	return TrackSourcesResponse{
+		TrackID:      track.ID,
+		Quality:      trackAudioQuality(track),
+		BestSourceID: bestTrackSource(sources),
+		Sources:      mergeTrackSources(track, sources),
+		Links:        links,
+	}
+}
+
+// artistSiteLink is best effort: a MusicBrainz outage must not fail the
+// listing, so lookup errors are logged and the link is skipped.
+func (h *TrackSourceHandlers) artistSiteLink(ctx context.Context, track *db.Track) (TrackLinkResponse, bool) {
+	if h.artists == nil || track.MBArtistID == nil {
+		return TrackLinkResponse{}, false
+	}
+	homepage, err := h.artists.GetArtistHomepage(ctx, track.MBArtistID.String())
+	if err != nil {
+		log.Printf("Warning: failed to look up artist homepage for track %d: %v", track.ID, err)
+		return TrackLinkResponse{}, false
+	}
+	if !isWebURL(homepage) {
+		return TrackLinkResponse{}, false
+	}
+	return TrackLinkResponse{Kind: TrackLinkArtistSite, Label: "Artist website", URL: homepage}, true
+}
+
+// mergeTrackSources lists the track's own source first, marked primary, then
+// every other recorded source. A track_sources row matching the primary URL is
+// folded into the primary entry rather than listed twice.
+func mergeTrackSources(track *db.Track, sources []db.TrackSource) []TrackSourceResponse {
+	out := make([]TrackSourceResponse, 0, len(sources)+1)
+	primaryURL := ""
+	if track.SourceURL.Valid {
+		primaryURL = strings.TrimSpace(track.SourceURL.String)
+	}
+	if primaryURL != "" {
+		provider := ""
+		if track.SourceType.Valid {
+			provider = track.SourceType.String
+		}
+		primary := TrackSourceResponse{
+			Provider:    provider,
+			Label:       sourceProviderLabel(provider, primaryURL),
+			Primary:     true,
+			FirstSeenAt: track.CreatedAt.UTC().Format(time.RFC3339),
+			LastSeenAt:  track.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if isWebURL(primaryURL) {
+			primary.URL = primaryURL
+		}
+		for _, s := range sources {
+			if s.SourceURL == primaryURL {
+				primary.ID = s.ID
+				primary.SourceID = s.SourceID
+				primary.Canonical = isCanonicalSource(track, s)
+				primary.Quality = sourceAudioQuality(s)
+				if s.CreatedAt.Before(track.CreatedAt) {
+					primary.FirstSeenAt = s.CreatedAt.UTC().Format(time.RFC3339)
+				}
+				break
+			}
+		}
+		out = append(out, primary)
+	}
+
+	for _, s := range sources {
+		if primaryURL != "" && s.SourceURL == primaryURL {
+			continue
+		}
+		item := TrackSourceResponse{
+			ID:          s.ID,
+			Provider:    s.Provider,
+			Label:       sourceProviderLabel(s.Provider, s.SourceURL),
+			SourceID:    s.SourceID,
+			Canonical:   isCanonicalSource(track, s),
+			Quality:     sourceAudioQuality(s),
+			FirstSeenAt: s.CreatedAt.UTC().Format(time.RFC3339),
+			LastSeenAt:  s.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if isWebURL(s.SourceURL) {
+			item.URL = s.SourceURL
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// isCanonicalSource reports whether the track currently plays this source's
+// stored object.
+func isCanonicalSource(track *db.Track, s db.TrackSource) bool {
+	return track.StorageKey.Valid && s.StorageKey.Valid && s.StorageKey.String != "" &&
+		track.StorageKey.String == s.StorageKey.String
+}
+
+// bestTrackSource returns the id of the best measured source, or 0 when no
+// source has stored audio. Unclipped sources always beat clipped ones; ties
+// go to the higher codec-adjusted bitrate, then the older source.
+func bestTrackSource(sources []db.TrackSource) int64 {
+	var best *db.TrackSource
+	for i := range sources {
+		s := &sources[i]
+		if !s.StorageKey.Valid || strings.TrimSpace(s.StorageKey.String) == "" {
+			continue
+		}
+		if best == nil || betterTrackSource(*s, *best) {
+			best = s
+		}
+	}
+	if best == nil {
+		return 0
+	}
+	return best.ID
+}
+
+func betterTrackSource(a, b db.TrackSource) bool {
+	if clippedA, clippedB := sourceClipped(a), sourceClipped(b); clippedA != clippedB {
+		return !clippedA
+	}
+	scoreA, scoreB := sourceQualityScore(a), sourceQualityScore(b)
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+func sourceClipped(s db.TrackSource) bool {
+	return s.ClippingRatio.Valid && s.ClippingRatio.Float64 > clippedSourceRatio
+}
+
+// sourceQualityScore is the source bitrate scaled by how efficient its codec
+// is, so a 160 kbps Opus stream outranks a 192 kbps MP3. Lossless codecs
+// outrank every lossy bitrate.
+func sourceQualityScore(s db.TrackSource) float64 {
+	codec := ""
+	if s.Codec.Valid {
+		codec = strings.ToLower(s.Codec.String)
+	}
+	switch {
+	case codec == "flac" || codec == "alac" || strings.HasPrefix(codec, "pcm_"):
+		return 1e6 + float64(s.SampleRateHz.Int32)
+	case codec == "opus":
+		return float64(s.BitrateKbps.Int32) * 1.5
+	case codec == "aac" || codec == "vorbis":
+		return float64(s.BitrateKbps.Int32) * 1.3
+	}
+	return float64(s.BitrateKbps.Int32)
+}
+
+func sourceAudioQuality(s db.TrackSource) *TrackAudioQualityResponse {
+	if !s.QualityMeasuredAt.Valid {
+		return nil
+	}
+	q := &TrackAudioQualityResponse{Clipped: sourceClipped(s)}
+	if s.Codec.Valid {
+		q.Codec = s.Codec.String
+	}
+	if s.BitrateKbps.Valid {
+		q.BitrateKbps = int(s.BitrateKbps.Int32)
+	}
+	if s.SampleRateHz.Valid {
+		q.SampleRateHz = int(s.SampleRateHz.Int32)
+	}
+	if s.Channels.Valid {
+		q.Channels = int(s.Channels.Int32)
+	}
+	if s.FileSizeBytes.Valid {
+		q.FileSizeBytes = s.FileSizeBytes.Int64
+	}
+	if s.ContentType.Valid {
+		q.ContentType = s.ContentType.String
+	}
+	if s.PeakDBFS.Valid {
+		q.PeakDBFS = &s.PeakDBFS.Float64
+	}
+	if s.ClippingRatio.Valid {
+		q.ClippingRatio = &s.ClippingRatio.Float64
+	}
+	return q
+}
+
+func trackAudioQuality(t *db.Track) TrackAudioQualityResponse {
+	var q TrackAudioQualityResponse
+	if t.Codec.Valid {
+		q.Codec = t.Codec.String
+	}
+	if t.BitrateKbps.Valid {
+		q.BitrateKbps = int(t.BitrateKbps.Int32)
+	}
+	if t.SampleRateHz.Valid {
+		q.SampleRateHz = int(t.SampleRateHz.Int32)
+	}
+	if t.Channels.Valid {
+		q.Channels = int(t.Channels.Int32)
+	}
+	if t.FileSizeBytes.Valid {
+		q.FileSizeBytes = t.FileSizeBytes.Int64
+	}
+	if t.ContentType.Valid {
+		q.ContentType = t.ContentType.String
+	}
+	return q
+}
+
+func writeTrackSourcesJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeTrackSourcesError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeTrackSourcesStore struct {
+	track    *db.Track
+	sources  []db.TrackSource
+	switched int64
+}
+
+func (f *fakeTrackSourcesStore) GetByID(ctx context.Context, id int64) (*db.Track, error) {
+	if f.track == nil || f.track.ID != id {
+		return nil, db.ErrTrackNotFound
+	}
+	copied := *f.track
+	return &copied, nil
+}
+
+func (f *fakeTrackSourcesStore) ListSources(ctx context.Context, trackID int64) ([]db.TrackSource, error) {
+	return f.sources, nil
+}
+
+func (f *fakeTrackSourcesStore) SetCanonicalSource(ctx context.Context, trackID, sourceID int64) (*db.Track, error) {
+	for _, s := range f.sources {
+		if s.ID == sourceID && s.StorageKey.Valid {
+			f.switched = sourceID
+			f.track.StorageKey = s.StorageKey
+			f.track.Codec = s.Codec
+			f.track.BitrateKbps = s.BitrateKbps
+			copied := *f.track
+			return &copied, nil
+		}
+	}
+	return nil, db.ErrTrackSourceNotFound
+}
+
+type fakeTrackLibrary struct {
+	trackIDs map[int64]bool
+}
+
+func (f fakeTrackLibrary) IsTrackInLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (bool, error) {
+	return f.trackIDs[trackID], nil
+}
+
+type fakeArtistHomepages struct {
+	homepage string
+	err      error
+}
+
+func (f fakeArtistHomepages) GetArtistHomepage(ctx context.Context, mbID string) (string, error) {
+	return f.homepage, f.err
+}
+
+func sourcesTestTrack() *db.Track {
+	recordingID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	artistID := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &db.Track{
+		ID:            42,
+		Title:         "Song",
+		SourceURL:     sql.NullString{String: "https://www.youtube.com/watch?v=abc", Valid: true},
+		SourceType:    sql.NullString{String: "youtube", Valid: true},
+		MBRecordingID: &recordingID,
+		MBArtistID:    &artistID,
+		Codec:         sql.NullString{String: "opus", Valid: true},
+		BitrateKbps:   sql.NullInt32{Int32: 160, Valid: true},
+		CreatedAt:     created,
+		UpdatedAt:     created,
+	}
+}
+
+func serveTrackSources(t *testing.T, h *TrackSourceHandlers, trackID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/"+trackID+"/sources", nil)
+	req.SetPathValue("track_id", trackID)
+	req = withUser(req, uuid.New())
+	rec := httptest.NewRecorder()
+	h.ListTrackSources(rec, req)
+	return rec
+}
+
+func TestListTrackSourcesMergesPrimaryAndRecordedSources(t *testing.T) {
+	track := sourcesTestTrack()
+	seen := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	store := &fakeTrackSourcesStore{track: track, sources: []db.TrackSource{
+		{TrackID: 42, Provider: "youtube", SourceID: "abc", SourceURL: "https://www.youtube.com/watch?v=abc", CreatedAt: track.CreatedAt, UpdatedAt: seen},
+		{TrackID: 42, Provider: "soundcloud", SourceID: "sc-1", SourceURL: "https://soundcloud.com/artist/song", CreatedAt: seen, UpdatedAt: seen},
+		{TrackID: 42, Provider: "spotify", SourceID: "sp-1", CreatedAt: seen, UpdatedAt: seen},
+	}}
+	h := NewTrackSourceHandlers(store, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, fakeArtistHomepages{homepage: "https://artist.example"})
+
+	rec := serveTrackSources(t, h, "42")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp TrackSourcesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(resp.Sources) != 3 {
+		t.Fatalf("sources = %+v, want primary plus two others", resp.Sources)
+	}
+	primary := resp.Sources[0]
+	if !primary.Primary || primary.Provider != "youtube" || primary.SourceID != "abc" || primary.Label != "YouTube" {
+		t.Fatalf("primary source = %+v", primary)
+	}
+	if resp.Sources[1].Provider != "soundcloud" || resp.Sources[1].Primary || resp.Sources[1].URL == "" {
+		t.Fatalf("second source = %+v", resp.Sources[1])
+	}
+	if resp.Sources[2].URL != "" || resp.Sources[2].Label != "Spotify" {
+		t.Fatalf("url-less source = %+v", resp.Sources[2])
+	}
+	if resp.Quality.Codec != "opus" || resp.Quality.BitrateKbps != 160 {
+		t.Fatalf("quality = %+v", resp.Quality)
+	}
+
+	kinds := map[string]string{}
+	for _, link := range resp.Links {
+		kinds[link.Kind] = link.URL
+	}
+	want := map[string]string{
+		TrackLinkSource:               "https://www.youtube.com/watch?v=abc",
+		TrackLinkMusicBrainzRecording: "https://musicbrainz.org/recording/11111111-1111-1111-1111-111111111111",
+		TrackLinkMusicBrainzArtist:    "https://musicbrainz.org/artist/33333333-3333-3333-3333-333333333333",
+		TrackLinkArtistSite:           "https://artist.example",
+	}
+	for kind, url := range want {
+		if kinds[kind] != url {
+			t.Fatalf("link %s = %q, want %q (links %+v)", kind, kinds[kind], url, resp.Links)
+		}
+	}
+	if _, ok := kinds[TrackLinkMusicBrainzRelease]; ok {
+		t.Fatalf("unexpected release link without a release id: %+v", resp.Links)
+	}
+}
+
+func TestListTrackSourcesSkipsArtistSiteOnLookupFailure(t *testing.T) {
+	store := &fakeTrackSourcesStore{track: sourcesTestTrack()}
+	h := NewTrackSourceHandlers(store, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, fakeArtistHomepages{err: errors.New("musicbrainz down")})
+
+	rec := serveTrackSources(t, h, "42")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp TrackSourcesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, link := range resp.Links {
+		if link.Kind == TrackLinkArtistSite {
+			t.Fatalf("artist site link present despite lookup failure: %+v", link)
+		}
+	}
+	if len(resp.Sources) != 1 || !resp.Sources[0].Primary {
+		t.Fatalf("sources = %+v, want only the primary source", resp.Sources)
+	}
+}
+
+func TestListTrackSourcesRequiresLibraryMembership(t *testing.T) {
+	store := &fakeTrackSourcesStore{track: sourcesTestTrack()}
+	h := NewTrackSourceHandlers(store, fakeTrackLibrary{}, nil)
+
+	if rec := serveTrackSources(t, h, "42"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if rec := serveTrackSources(t, h, "abc"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestTrackLinksOmitNonWebSources(t *testing.T) {
+	track := &db.Track{
+		SourceURL:  sql.NullString{String: "fixture://sine", Valid: true},
+		SourceType: sql.NullString{String: "fixture", Valid: true},
+	}
+	if links := trackLinks(track); len(links) != 0 {
+		t.Fatalf("links = %+v, want none for a fixture source", links)
+	}
+}
+
+func measuredSource(id int64, provider, key, codec string, kbps int32, clipping float64, created time.Time) db.TrackSource {
+	return db.TrackSource{
+		ID:                id,
+		TrackID:           42,
+		Provider:          provider,
+		SourceID:          provider + "-id",
+		StorageKey:        sql.NullString{String: key, Valid: true},
+		Codec:             sql.NullString{String: codec, Valid: true},
+		BitrateKbps:       sql.NullInt32{Int32: kbps, Valid: true},
+		SampleRateHz:      sql.NullInt32{Int32: 48000, Valid: true},
+		Channels:          sql.NullInt32{Int32: 2, Valid: true},
+		ClippingRatio:     sql.NullFloat64{Float64: clipping, Valid: true},
+		QualityMeasuredAt: sql.NullTime{Time: created, Valid: true},
+		CreatedAt:         created,
+		UpdatedAt:         created,
+	}
+}
+
+func TestBestTrackSourcePrefersUnclippedThenCodecAdjustedBitrate(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sources := []db.TrackSource{
+		measuredSource(1, "youtube", "tracks/youtube/a.webm", "opus", 160, 0, created),
+		measuredSource(2, "soundcloud", "tracks/soundcloud/b.mp3", "mp3", 192, 0, created.Add(time.Hour)),
+		measuredSource(3, "bandcamp", "tracks/bandcamp/c.flac", "flac", 900, 0.01, created.Add(2*time.Hour)),
+		{ID: 4, Provider: "spotify", SourceID: "sp"},
+	}
+	if got := bestTrackSource(sources); got != 1 {
+		t.Fatalf("best source = %d, want opus source 1 (flac source is clipped)", got)
+	}
+
+	sources[2].ClippingRatio.Float64 = 0
+	if got := bestTrackSource(sources); got != 3 {
+		t.Fatalf("best source = %d, want unclipped lossless source 3", got)
+	}
+	if got := bestTrackSource(sources[3:]); got != 0 {
+		t.Fatalf("best source = %d, want 0 without measured sources", got)
+	}
+}
+
+func serveCanonicalSource(t *testing.T, h *TrackSourceHandlers, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tracks/42/sources/canonical", strings.NewReader(body))
+	req.SetPathValue("track_id", "42")
+	req = withUser(req, uuid.New())
+	rec := httptest.NewRecorder()
+	h.SetCanonicalSource(rec, req)
+	return rec
+}
+
+func TestSetCanonicalSourceSwitchesToBestSource(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	track := sourcesTestTrack()
+	track.MBArtistID = nil
+	track.StorageKey = sql.NullString{String: "tracks/soundcloud/b.mp3", Valid: true}
+	store := &fakeTrackSourcesStore{track: track, sources: []db.TrackSource{
+		measuredSource(1, "soundcloud", "tracks/soundcloud/b.mp3", "mp3", 128, 0, created),
+		measuredSource(2, "youtube", "tracks/youtube/a.webm", "opus", 160, 0, created.Add(time.Hour)),
+	}}
+	h := NewTrackSourceHandlers(store, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, nil)
+
+	rec := serveCanonicalSource(t, h, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp CanonicalSourceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Switched || store.switched != 2 || resp.Quality.Codec != "opus" {
+		t.Fatalf("response = %+v, switched = %d; want switch to opus source 2", resp, store.switched)
+	}
+	for _, s := range resp.Sources {
+		if s.Canonical != (s.ID == 2) {
+			t.Fatalf("source %d canonical = %v after switch", s.ID, s.Canonical)
+		}
+	}
+
+	rec = serveCanonicalSource(t, h, "")
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Switched {
+		t.Fatalf("second switch status = %d switched = %v, want a no-op", rec.Code, resp.Switched)
+	}
+}
+
+func TestSetCanonicalSourceRejectsUnmeasuredSource(t *testing.T) {
+	store := &fakeTrackSourcesStore{track: sourcesTestTrack(), sources: []db.TrackSource{{ID: 7, TrackID: 42, Provider: "spotify", SourceID: "sp"}}}
+	h := NewTrackSourceHandlers(store, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, nil)
+
+	if rec := serveCanonicalSource(t, h, `{"sourceId":7}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404 for a source without stored audio", rec.Code)
+	}
+	if rec := serveCanonicalSource(t, h, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 when nothing is measured", rec.Code)
+	}
+	if store.switched != 0 {
+		t.Fatalf("switched = %d, want no switch", store.switched)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/waveform"
+)
+
+// waveformCacheControl lets a client keep a track's waveform for a day; it
+// only changes if the track is processed again.
+const waveformCacheControl = "private, max-age=86400"
+
+type trackWaveformStore interface {
+	GetWaveformKey(ctx context.Context, trackID int64) (string, error)
+}
+
+// WaveformObjects reads stored waveforms. storage.Client satisfies it.
+type WaveformObjects interface {
+	ObjectExists(ctx context.Context, key string) (bool, error)
+	GetObject(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error)
+}
+
+// TrackWaveformHandlers serve the seek bar peaks the processor generated
+// for a track.
+type TrackWaveformHandlers struct {
+	trackRepo   trackWaveformStore
+	libraryRepo trackLibraryChecker
+	objects     WaveformObjects
+}
+
+func NewTrackWaveformHandlers(trackRepo trackWaveformStore, libraryRepo trackLibraryChecker, objects WaveformObjects) *TrackWaveformHandlers {
+	return &TrackWaveformHandlers{trackRepo: trackRepo, libraryRepo: libraryRepo, objects: objects}
+}
+
+// GetTrackWaveform handles GET /api/v1/tracks/{track_id}/waveform for a
+// track in the caller's library, serving the stored waveform.Waveform JSON.
+// Tracks processed before waveforms existed, or whose generation failed,
+// get a 404.
+func (h *TrackWaveformHandlers) GetTrackWaveform(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeTrackWaveformError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	trackID, err := strconv.ParseInt(r.PathValue("track_id"), 10, 64)
+	if err != nil || trackID <= 0 {
+		writeTrackWaveformError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid track id")
+		return
+	}
+	inLibrary, err := h.libraryRepo.IsTrackInLibrary(r.Context(), userCtx.UserID, trackID)
+	if err != nil {
+		writeTrackWaveformError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify library membership")
+		return
+	}
+	if !inLibrary {
+		writeTrackWaveformError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+		return
+	}
+	key, err := h.trackRepo.GetWaveformKey(r.Context(), trackID)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotFound) {
+			writeTrackWaveformError(w, http.StatusNotFound, "TRACK_NOT_FOUND", "track not found")
+			return
+		}
+		writeTrackWaveformError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load track")
+		return
+	}
+	if key == "" {
+		writeTrackWaveformError(w, http.StatusNotFound, "WAVEFORM_NOT_FOUND", "track has no waveform")
+		return
+	}
+	exists, err := h.objects.ObjectExists(r.Context(), key)
+	if err != nil {
+		writeTrackWaveformError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load waveform")
+		return
+	}
+	if !exists {
+		writeTrackWaveformError(w, http.StatusNotFound, "WAVEFORM_NOT_FOUND", "track has no waveform")
+		return
+	}
+	reader, info, err := h.objects.GetObject(r.Context(), key)
+	if err != nil {
+		writeTrackWaveformError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load waveform")
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", waveform.ContentType)
+	w.Header().Set("Cache-Control", waveformCacheControl)
+	if info != nil && info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("Warning: failed to send track %d waveform: %v", trackID, err)
+	}
+}
+
+func writeTrackWaveformError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
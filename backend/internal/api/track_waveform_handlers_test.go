@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+type fakeWaveformKeys map[int64]string
+
+func (f fakeWaveformKeys) GetWaveformKey(_ context.Context, trackID int64) (string, error) {
+	key, ok := f[trackID]
+	if !ok {
+		return "", db.ErrTrackNotFound
+	}
+	return key, nil
+}
+
+type fakeWaveformObjects map[string][]byte
+
+func (f fakeWaveformObjects) ObjectExists(_ context.Context, key string) (bool, error) {
+	_, ok := f[key]
+	return ok, nil
+}
+
+func (f fakeWaveformObjects) GetObject(_ context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	data, ok := f[key]
+	if !ok {
+		return nil, nil, errors.New("object not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), &storage.ObjectInfo{Size: int64(len(data))}, nil
+}
+
+func serveTrackWaveform(t *testing.T, h *TrackWaveformHandlers, trackID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/"+trackID+"/waveform", nil)
+	req.SetPathValue("track_id", trackID)
+	rec := httptest.NewRecorder()
+	h.GetTrackWaveform(rec, withUser(req, uuid.New()))
+	return rec
+}
+
+func TestGetTrackWaveformServesStoredPeaks(t *testing.T) {
+	// The recorded key is served even after the track's identity was
+	// rehashed away from the one it was stored under.
+	stored := `{"version":1,"durationMs":1000,"sampleRate":8000,"peaks":[0.1,0.5,1]}`
+	objects := fakeWaveformObjects{"waveforms/tracks/old-hash.json": []byte(stored)}
+	keys := fakeWaveformKeys{42: "waveforms/tracks/old-hash.json"}
+	h := NewTrackWaveformHandlers(keys, fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}, objects)
+
+	rec := serveTrackWaveform(t, h, "42")
+	if rec.Code != http.StatusOK || rec.Body.String() != stored {
+		t.Fatalf("status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("Cache-Control") != waveformCacheControl {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+}
+
+func TestGetTrackWaveformNotFound(t *testing.T) {
+	library := fakeTrackLibrary{trackIDs: map[int64]bool{42: true}}
+	h := NewTrackWaveformHandlers(fakeWaveformKeys{42: ""}, library, fakeWaveformObjects{})
+	if rec := serveTrackWaveform(t, h, "42"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "WAVEFORM_NOT_FOUND") {
+		t.Fatalf("status = %d body = %s, want 404 WAVEFORM_NOT_FOUND without a recorded waveform", rec.Code, rec.Body.String())
+	}
+	h = NewTrackWaveformHandlers(fakeWaveformKeys{42: "waveforms/tracks/hash.json"}, library, fakeWaveformObjects{})
+	if rec := serveTrackWaveform(t, h, "42"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404 when the recorded object is missing", rec.Code)
+	}
+
+	objects := fakeWaveformObjects{"waveforms/tracks/hash.json": []byte(`{}`)}
+	h = NewTrackWaveformHandlers(fakeWaveformKeys{42: "waveforms/tracks/hash.json"}, fakeTrackLibrary{}, objects)
+	if rec := serveTrackWaveform(t, h, "42"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404 outside the library", rec.Code)
+	}
+}
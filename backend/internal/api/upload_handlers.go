@@ -0,0 +1,479 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+const (
+	maxUploadFilenameLength = 255
+	// uploadPartSizeBytes is the size of each part of a resumable upload but
+	// the last. Storage needs parts of at least 5 MiB and at most 10,000
+	// parts, so this covers files up to about 160 GB.
+	uploadPartSizeBytes = 16 << 20
+	// uploadPartURLBatch bounds the part URLs handed out in one response.
+	uploadPartURLBatch = 100
+)
+
+// uploadExtensions maps the audio file extensions accepted for upload to the
+// content type used when the client does not send an audio/* type.
+var uploadExtensions = map[string]string{
+	"flac": "audio/flac",
+	"mp3":  "audio/mpeg",
+	"m4a":  "audio/mp4",
+	"aac":  "audio/aac",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg",
+	"wav":  "audio/wav",
+	"aiff": "audio/aiff",
+	"aif":  "audio/aiff",
+}
+
+type uploadStore interface {
+	Create(ctx context.Context, upload *db.Upload) error
+	Get(ctx context.Context, id, userID uuid.UUID) (*db.Upload, error)
+	Finalize(ctx context.Context, id, userID uuid.UUID, jobID string) error
+	Reopen(ctx context.Context, id uuid.UUID, jobID string) error
+}
+
+type uploadObjects interface {
+	PresignPutObject(ctx context.Context, key string, expires time.Duration) (string, error)
+	StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
+	DeleteObject(ctx context.Context, key string) error
+	NewMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int, expires time.Duration) (string, error)
+	ListUploadedParts(ctx context.Context, key, uploadID string) ([]storage.UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []storage.UploadedPart) error
+}
+
+type uploadEnqueuer interface {
+	EnqueueSourceCandidateWithID(ctx context.Context, jobID, userID string, candidate download.SourceCandidate, mbRecordingID *string) (*download.DownloadJob, error)
+}
+
+// UploadHandlers lets clients upload large files straight to object storage
+// instead of through the API server. The client asks for a presigned PUT URL,
+// uploads, then finalizes, which queues the file through the same processing
+// pipeline as a download. A resumable upload is PUT in fixed-size parts,
+// each to its own URL, so an interrupted upload only resends missing parts.
+type UploadHandlers struct {
+	uploads  uploadStore
+	objects  uploadObjects
+	jobs     uploadEnqueuer
+	maxBytes int64
+	urlTTL   time.Duration
+	partSize int64
+}
+
+func NewUploadHandlers(uploads uploadStore, objects uploadObjects, jobs uploadEnqueuer, maxBytes int64, urlTTL time.Duration) *UploadHandlers {
+	return &UploadHandlers{uploads: uploads, objects: objects, jobs: jobs, maxBytes: maxBytes, urlTTL: urlTTL, partSize: uploadPartSizeBytes}
+}
+
+type CreateUploadRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	SizeBytes   int64  `json:"sizeBytes"`
+	// Resumable asks for a multipart upload instead of a single PUT.
+	Resumable bool `json:"resumable"`
+}
+
+type CreateUploadResponse struct {
+	UploadID string `json:"uploadId"`
+	// UploadURL is a bearer credential; clients PUT the file to it with the
+	// given headers before ExpiresAt. It is empty for a resumable upload,
+	// whose parts are PUT to the URLs in Parts instead.
+	UploadURL     string            `json:"uploadUrl,omitempty"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	ExpiresAt     time.Time         `json:"expiresAt"`
+	MaxBytes      int64             `json:"maxBytes"`
+	PartSizeBytes int64             `json:"partSizeBytes,omitempty"`
+	PartCount     int               `json:"partCount,omitempty"`
+	Parts         []UploadPartURL   `json:"parts,omitempty"`
+}
+
+// UploadPartURL is where to PUT one part of a resumable upload. Part n holds
+// the bytes from (n-1)*partSizeBytes; URL is a bearer credential.
+type UploadPartURL struct {
+	PartNumber int    `json:"partNumber"`
+	URL        string `json:"url"`
+}
+
+// UploadPartsResponse is the progress of a resumable upload, with URLs for
+// the next parts it is missing.
+type UploadPartsResponse struct {
+	UploadID      string          `json:"uploadId"`
+	PartSizeBytes int64           `json:"partSizeBytes"`
+	PartCount     int             `json:"partCount"`
+	ReceivedBytes int64           `json:"receivedBytes"`
+	UploadedParts []int           `json:"uploadedParts"`
+	Parts         []UploadPartURL `json:"parts"`
+	ExpiresAt     time.Time       `json:"expiresAt"`
+}
+
+type FinalizeUploadResponse struct {
+	UploadID string `json:"uploadId"`
+	JobID    string `json:"jobId"`
+	Status   string `json:"status"`
+}
+
+// CreateUpload handles POST /api/v1/uploads
+func (h *UploadHandlers) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeUploadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req CreateUploadRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeUploadError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	filename := strings.TrimSpace(path.Base(strings.ReplaceAll(req.Filename, "\\", "/")))
+	if filename == "" || filename == "." || filename == "/" || utf8.RuneCountInString(filename) > maxUploadFilenameLength || strings.IndexFunc(filename, unicode.IsControl) >= 0 {
+		writeUploadError(w, http.StatusBadRequest, "INVALID_FILENAME", "filename must be 1-255 characters without control characters")
+		return
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+	defaultType, ok := uploadExtensions[ext]
+	if !ok {
+		writeUploadError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_FILE_TYPE", "file must be FLAC, MP3, M4A, AAC, Ogg, Opus, WAV, or AIFF audio")
+		return
+	}
+	if req.SizeBytes <= 0 {
+		writeUploadError(w, http.StatusBadRequest, "INVALID_SIZE", "sizeBytes must be positive")
+		return
+	}
+	if req.SizeBytes > h.maxBytes {
+		writeUploadError(w, http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE", "file is larger than the upload limit")
+		return
+	}
+	contentType := strings.ToLower(strings.TrimSpace(req.ContentType))
+	if !strings.HasPrefix(contentType, "audio/") || len(contentType) > 100 {
+		contentType = defaultType
+	}
+
+	upload := &db.Upload{
+		ID:                uuid.New(),
+		UserID:            userCtx.UserID,
+		Filename:          filename,
+		ContentType:       contentType,
+		DeclaredSizeBytes: req.SizeBytes,
+		ExpiresAt:         time.Now().Add(h.urlTTL),
+	}
+	upload.ObjectKey = "uploads/" + upload.UserID.String() + "/" + upload.ID.String() + "." + ext
+	resp := CreateUploadResponse{
+		UploadID:  upload.ID.String(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: upload.ExpiresAt,
+		MaxBytes:  h.maxBytes,
+	}
+	if req.Resumable {
+		multipartID, err := h.objects.NewMultipartUpload(r.Context(), upload.ObjectKey, contentType)
+		if err != nil {
+			writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to start upload")
+			return
+		}
+		upload.MultipartUploadID = sql.NullString{String: multipartID, Valid: true}
+		upload.PartSizeBytes = h.partSize
+		// Each part is its own request, so the type goes on the upload
+		// rather than on each PUT.
+		resp.Headers = map[string]string{}
+		resp.PartSizeBytes = upload.PartSizeBytes
+		resp.PartCount = uploadPartCount(upload)
+		resp.Parts, err = h.presignMissingParts(r.Context(), upload, nil)
+		if err != nil {
+			writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to create upload URLs")
+			return
+		}
+	} else {
+		uploadURL, err := h.objects.PresignPutObject(r.Context(), upload.ObjectKey, h.urlTTL)
+		if err != nil {
+			writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to create upload URL")
+			return
+		}
+		resp.UploadURL = uploadURL
+	}
+	if err := h.uploads.Create(r.Context(), upload); err != nil {
+		writeUploadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to record upload")
+		return
+	}
+
+	writeUploadJSON(w, http.StatusCreated, resp)
+}
+
+// GetUploadParts handles GET /api/v1/uploads/{id}/parts. It reports which
+// parts of a resumable upload storage holds and presigns URLs for the next
+// missing ones, so a client can resume after an interruption and call it
+// again until no parts are missing.
+func (h *UploadHandlers) GetUploadParts(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeUploadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	uploadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeUploadError(w, http.StatusNotFound, "UPLOAD_NOT_FOUND", "upload not found")
+		return
+	}
+
+	upload, err := h.uploads.Get(r.Context(), uploadID, userCtx.UserID)
+	if err != nil {
+		writeUploadLoadError(w, err)
+		return
+	}
+	if !upload.MultipartUploadID.Valid {
+		writeUploadError(w, http.StatusConflict, "UPLOAD_NOT_RESUMABLE", "upload was not created as resumable")
+		return
+	}
+	if upload.Status == db.UploadStatusFinalized {
+		writeUploadError(w, http.StatusConflict, "UPLOAD_ALREADY_FINALIZED", "upload has already been finalized")
+		return
+	}
+
+	received, err := h.receivedParts(r.Context(), upload)
+	if err != nil {
+		writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to list uploaded parts")
+		return
+	}
+	parts, err := h.presignMissingParts(r.Context(), upload, received)
+	if err != nil {
+		writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to create upload URLs")
+		return
+	}
+	resp := UploadPartsResponse{
+		UploadID:      upload.ID.String(),
+		PartSizeBytes: upload.PartSizeBytes,
+		PartCount:     uploadPartCount(upload),
+		UploadedParts: make([]int, 0, len(received)),
+		Parts:         parts,
+		ExpiresAt:     time.Now().Add(h.urlTTL),
+	}
+	for partNumber := 1; partNumber <= resp.PartCount; partNumber++ {
+		if part, ok := received[partNumber]; ok {
+			resp.UploadedParts = append(resp.UploadedParts, partNumber)
+			resp.ReceivedBytes += part.Size
+		}
+	}
+	writeUploadJSON(w, http.StatusOK, resp)
+}
+
+// uploadPartCount is the number of parts a resumable upload is split into.
+func uploadPartCount(upload *db.Upload) int {
+	return int((upload.DeclaredSizeBytes + upload.PartSizeBytes - 1) / upload.PartSizeBytes)
+}
+
+// uploadPartSize is the size part partNumber of a resumable upload must have:
+// the part size for all but the last part, which holds the remainder.
+func uploadPartSize(upload *db.Upload, partNumber int) int64 {
+	if partNumber < uploadPartCount(upload) {
+		return upload.PartSizeBytes
+	}
+	return upload.DeclaredSizeBytes - int64(partNumber-1)*upload.PartSizeBytes
+}
+
+// receivedParts returns the parts of a resumable upload that storage holds
+// at their expected size, by part number. A part of any other size is left
+// out so that the client sends it again.
+func (h *UploadHandlers) receivedParts(ctx context.Context, upload *db.Upload) (map[int]storage.UploadedPart, error) {
+	parts, err := h.objects.ListUploadedParts(ctx, upload.ObjectKey, upload.MultipartUploadID.String)
+	if err != nil {
+		return nil, err
+	}
+	received := make(map[int]storage.UploadedPart, len(parts))
+	count := uploadPartCount(upload)
+	for _, part := range parts {
+		if part.PartNumber >= 1 && part.PartNumber <= count && part.Size == uploadPartSize(upload, part.PartNumber) {
+			received[part.PartNumber] = part
+		}
+	}
+	return received, nil
+}
+
+// presignMissingParts presigns URLs for up to uploadPartURLBatch parts not in
+// received, lowest part number first.
+func (h *UploadHandlers) presignMissingParts(ctx context.Context, upload *db.Upload, received map[int]storage.UploadedPart) ([]UploadPartURL, error) {
+	parts := []UploadPartURL{}
+	for partNumber := 1; partNumber <= uploadPartCount(upload) && len(parts) < uploadPartURLBatch; partNumber++ {
+		if _, ok := received[partNumber]; ok {
+			continue
+		}
+		partURL, err := h.objects.PresignUploadPart(ctx, upload.ObjectKey, upload.MultipartUploadID.String, partNumber, h.urlTTL)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, UploadPartURL{PartNumber: partNumber, URL: partURL})
+	}
+	return parts, nil
+}
+
+// completeParts joins a resumable upload's parts into its object once every
+// part has arrived. It reports the number of parts received when some are
+// still missing.
+func (h *UploadHandlers) completeParts(ctx context.Context, upload *db.Upload) (int, bool, error) {
+	received, err := h.receivedParts(ctx, upload)
+	if err != nil {
+		return 0, false, err
+	}
+	count := uploadPartCount(upload)
+	if len(received) < count {
+		return len(received), false, nil
+	}
+	parts := make([]storage.UploadedPart, 0, count)
+	for partNumber := 1; partNumber <= count; partNumber++ {
+		parts = append(parts, received[partNumber])
+	}
+	if err := h.objects.CompleteMultipartUpload(ctx, upload.ObjectKey, upload.MultipartUploadID.String, parts); err != nil {
+		return len(received), false, err
+	}
+	return count, true, nil
+}
+
+// FinalizeUpload handles POST /api/v1/uploads/{id}/finalize. It is safe to
+// retry: finalizing an upload again returns the job already queued for it.
+func (h *UploadHandlers) FinalizeUpload(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeUploadError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	uploadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeUploadError(w, http.StatusNotFound, "UPLOAD_NOT_FOUND", "upload not found")
+		return
+	}
+
+	upload, err := h.uploads.Get(r.Context(), uploadID, userCtx.UserID)
+	if err != nil {
+		writeUploadLoadError(w, err)
+		return
+	}
+	if upload.Status == db.UploadStatusFinalized {
+		writeUploadJSON(w, http.StatusAccepted, FinalizeUploadResponse{
+			UploadID: upload.ID.String(), JobID: upload.DownloadJobID.String, Status: upload.Status,
+		})
+		return
+	}
+
+	info, err := h.objects.StatObject(r.Context(), upload.ObjectKey)
+	if err != nil && upload.MultipartUploadID.Valid {
+		// The object exists only once its parts are joined, which an
+		// earlier finalize may already have done.
+		received, complete, completeErr := h.completeParts(r.Context(), upload)
+		if completeErr != nil {
+			writeUploadError(w, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE", "failed to assemble the uploaded parts")
+			return
+		}
+		if !complete {
+			writeUploadError(w, http.StatusConflict, "UPLOAD_INCOMPLETE", fmt.Sprintf("%d of %d parts have been uploaded", received, uploadPartCount(upload)))
+			return
+		}
+		info, err = h.objects.StatObject(r.Context(), upload.ObjectKey)
+	}
+	if err != nil {
+		writeUploadError(w, http.StatusConflict, "UPLOAD_INCOMPLETE", "no file has been uploaded yet")
+		return
+	}
+	if info.Size > h.maxBytes {
+		// The presigned URL cannot cap the body, so an oversized file is only
+		// caught here.
+		if err := h.objects.DeleteObject(r.Context(), upload.ObjectKey); err != nil {
+			log.Printf("Warning: failed to delete oversized upload %s: %v", upload.ID, err)
+		}
+		writeUploadError(w, http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE", "file is larger than the upload limit")
+		return
+	}
+
+	jobID := uuid.NewString()
+	if err := h.uploads.Finalize(r.Context(), upload.ID, userCtx.UserID, jobID); err != nil {
+		if errors.Is(err, db.ErrUploadAlreadyFinalized) {
+			// A concurrent request won; report its job.
+			if upload, err = h.uploads.Get(r.Context(), uploadID, userCtx.UserID); err == nil {
+				writeUploadJSON(w, http.StatusAccepted, FinalizeUploadResponse{
+					UploadID: upload.ID.String(), JobID: upload.DownloadJobID.String, Status: upload.Status,
+				})
+				return
+			}
+		}
+		writeUploadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to finalize upload")
+		return
+	}
+
+	job, err := h.jobs.EnqueueSourceCandidateWithID(r.Context(), jobID, userCtx.UserID.String(), uploadCandidate(upload), nil)
+	if err != nil {
+		if reopenErr := h.uploads.Reopen(r.Context(), upload.ID, jobID); reopenErr != nil {
+			log.Printf("Warning: failed to reopen upload %s after enqueue failure: %v", upload.ID, reopenErr)
+		}
+		writeUploadError(w, http.StatusServiceUnavailable, "DOWNLOAD_ENQUEUE_FAILED", "failed to queue the upload for processing")
+		return
+	}
+	writeUploadJSON(w, http.StatusAccepted, FinalizeUploadResponse{
+		UploadID: upload.ID.String(), JobID: job.ID, Status: db.UploadStatusFinalized,
+	})
+}
+
+// uploadCandidate describes an uploaded file as a source the processor can
+// import. The upload:// URL names the staged object.
+func uploadCandidate(upload *db.Upload) download.SourceCandidate {
+	title := strings.TrimSpace(strings.TrimSuffix(upload.Filename, path.Ext(upload.Filename)))
+	if title == "" {
+		title = upload.Filename
+	}
+	return download.SourceCandidate{
+		CandidateID: "upload:" + upload.ID.String(),
+		Provider:    "upload",
+		SourceID:    upload.ID.String(),
+		SourceURL:   download.UploadURLPrefix + upload.ObjectKey,
+		Title:       title,
+		Metadata: map[string]interface{}{
+			"origin":      "upload",
+			"filename":    upload.Filename,
+			"contentType": upload.ContentType,
+		},
+	}
+}
+
+func writeUploadLoadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrUploadNotFound) {
+		writeUploadError(w, http.StatusNotFound, "UPLOAD_NOT_FOUND", "upload not found")
+		return
+	}
+	writeUploadError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load upload")
+}
+
+func writeUploadJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeUploadError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+type fakeUploadStore struct {
+	uploads map[uuid.UUID]*db.Upload
+}
+
+func (f *fakeUploadStore) Create(_ context.Context, upload *db.Upload) error {
+	upload.Status = db.UploadStatusPending
+	copied := *upload
+	f.uploads[upload.ID] = &copied
+	return nil
+}
+
+func (f *fakeUploadStore) Get(_ context.Context, id, userID uuid.UUID) (*db.Upload, error) {
+	u, ok := f.uploads[id]
+	if !ok || u.UserID != userID {
+		return nil, db.ErrUploadNotFound
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (f *fakeUploadStore) Finalize(_ context.Context, id, userID uuid.UUID, jobID string) error {
+	u := f.uploads[id]
+	if u.Status != db.UploadStatusPending {
+		return db.ErrUploadAlreadyFinalized
+	}
+	u.Status = db.UploadStatusFinalized
+	u.DownloadJobID.String, u.DownloadJobID.Valid = jobID, true
+	return nil
+}
+
+func (f *fakeUploadStore) Reopen(_ context.Context, id uuid.UUID, jobID string) error {
+	u := f.uploads[id]
+	u.Status = db.UploadStatusPending
+	u.DownloadJobID.String, u.DownloadJobID.Valid = "", false
+	return nil
+}
+
+type fakeUploadObjects struct {
+	sizes   map[string]int64
+	deleted []string
+	// parts holds the parts PUT to each multipart upload, by part number.
+	parts map[string]map[int]int64
+}
+
+func (f *fakeUploadObjects) PresignPutObject(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://minio.test/bucket/" + key + "?X-Amz-Signature=sig", nil
+}
+
+func (f *fakeUploadObjects) StatObject(_ context.Context, key string) (*storage.ObjectInfo, error) {
+	size, ok := f.sizes[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &storage.ObjectInfo{Size: size}, nil
+}
+
+func (f *fakeUploadObjects) DeleteObject(_ context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	delete(f.sizes, key)
+	return nil
+}
+
+func (f *fakeUploadObjects) NewMultipartUpload(_ context.Context, key, _ string) (string, error) {
+	if f.parts == nil {
+		f.parts = map[string]map[int]int64{}
+	}
+	f.parts["mp-"+key] = map[int]int64{}
+	return "mp-" + key, nil
+}
+
+func (f *fakeUploadObjects) PresignUploadPart(_ context.Context, key, uploadID string, partNumber int, _ time.Duration) (string, error) {
+	return fmt.Sprintf("https://minio.test/bucket/%s?partNumber=%d&uploadId=%s", key, partNumber, uploadID), nil
+}
+
+func (f *fakeUploadObjects) ListUploadedParts(_ context.Context, _, uploadID string) ([]storage.UploadedPart, error) {
+	parts, ok := f.parts[uploadID]
+	if !ok {
+		return nil, errors.New("no such upload")
+	}
+	var listed []storage.UploadedPart
+	for partNumber, size := range parts {
+		listed = append(listed, storage.UploadedPart{PartNumber: partNumber, ETag: fmt.Sprint("etag-", partNumber), Size: size})
+	}
+	return listed, nil
+}
+
+func (f *fakeUploadObjects) CompleteMultipartUpload(_ context.Context, key, uploadID string, parts []storage.UploadedPart) error {
+	var size int64
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return fmt.Errorf("part %d out of order", part.PartNumber)
+		}
+		size += part.Size
+	}
+	delete(f.parts, uploadID)
+	f.sizes[key] = size
+	return nil
+}
+
+type fakeUploadEnqueuer struct {
+	candidates []download.SourceCandidate
+	err        error
+}
+
+func (f *fakeUploadEnqueuer) EnqueueSourceCandidateWithID(_ context.Context, jobID, userID string, candidate download.SourceCandidate, _ *string) (*download.DownloadJob, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.candidates = append(f.candidates, candidate)
+	return &download.DownloadJob{ID: jobID, UserID: userID, URL: candidate.SourceURL, Status: download.StatusQueued}, nil
+}
+
+func newTestUploadHandlers() (*UploadHandlers, *fakeUploadStore, *fakeUploadObjects, *fakeUploadEnqueuer) {
+	store := &fakeUploadStore{uploads: map[uuid.UUID]*db.Upload{}}
+	objects := &fakeUploadObjects{sizes: map[string]int64{}}
+	jobs := &fakeUploadEnqueuer{}
+	return NewUploadHandlers(store, objects, jobs, 1000, 30*time.Minute), store, objects, jobs
+}
+
+func createTestUpload(t *testing.T, h *UploadHandlers, userID uuid.UUID, body string) (*httptest.ResponseRecorder, CreateUploadResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.CreateUpload(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/v1/uploads", strings.NewReader(body)), userID))
+	var resp CreateUploadResponse
+	if rec.Code == http.StatusCreated {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func finalizeTestUpload(h *UploadHandlers, userID uuid.UUID, uploadID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads/"+uploadID+"/finalize", nil)
+	req.SetPathValue("id", uploadID)
+	rec := httptest.NewRecorder()
+	h.FinalizeUpload(rec, withUser(req, userID))
+	return rec
+}
+
+func TestUploadFlowPresignsThenQueuesOnce(t *testing.T) {
+	h, store, objects, jobs := newTestUploadHandlers()
+	userID := uuid.New()
+
+	rec, created := createTestUpload(t, h, userID, `{"filename":"C:\\Music\\Artist - Song.FLAC","contentType":"application/octet-stream","sizeBytes":900}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	upload := store.uploads[uuid.MustParse(created.UploadID)]
+	if created.Method != http.MethodPut || created.Headers["Content-Type"] != "audio/flac" || !strings.Contains(created.UploadURL, upload.ObjectKey) {
+		t.Fatalf("create response = %+v, want a PUT URL for %s typed audio/flac", created, upload.ObjectKey)
+	}
+	if upload.Filename != "Artist - Song.FLAC" || !strings.HasPrefix(upload.ObjectKey, "uploads/"+userID.String()+"/") || !strings.HasSuffix(upload.ObjectKey, ".flac") {
+		t.Fatalf("upload = %+v", upload)
+	}
+
+	if rec := finalizeTestUpload(h, userID, created.UploadID); rec.Code != http.StatusConflict {
+		t.Fatalf("finalize before PUT status = %d, want 409", rec.Code)
+	}
+	if rec := finalizeTestUpload(h, uuid.New(), created.UploadID); rec.Code != http.StatusNotFound {
+		t.Fatalf("finalize by another user status = %d, want 404", rec.Code)
+	}
+
+	objects.sizes[upload.ObjectKey] = 900
+	rec = finalizeTestUpload(h, userID, created.UploadID)
+	var finalized FinalizeUploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &finalized); err != nil || rec.Code != http.StatusAccepted || finalized.JobID == "" {
+		t.Fatalf("finalize status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(jobs.candidates) != 1 || jobs.candidates[0].SourceURL != download.UploadURLPrefix+upload.ObjectKey || jobs.candidates[0].Title != "Artist - Song" || jobs.candidates[0].Provider != "upload" {
+		t.Fatalf("queued = %+v", jobs.candidates)
+	}
+
+	rec = finalizeTestUpload(h, userID, created.UploadID)
+	var again FinalizeUploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &again); err != nil || again.JobID != finalized.JobID || len(jobs.candidates) != 1 {
+		t.Fatalf("retry = %d %s, want the same job without queueing again", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUploadRejectsUnsupportedAndOversizedFiles(t *testing.T) {
+	h, store, objects, jobs := newTestUploadHandlers()
+	userID := uuid.New()
+	for body, want := range map[string]int{
+		`{"filename":"notes.txt","sizeBytes":10}`:  http.StatusUnsupportedMediaType,
+		`{"filename":"song.mp3","sizeBytes":1001}`: http.StatusRequestEntityTooLarge,
+		`{"filename":"song.mp3","sizeBytes":0}`:    http.StatusBadRequest,
+		`{"filename":"","sizeBytes":10}`:           http.StatusBadRequest,
+	} {
+		if rec, _ := createTestUpload(t, h, userID, body); rec.Code != want {
+			t.Fatalf("create %s status = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	// The presigned PUT cannot cap the body, so the stored size is checked.
+	_, created := createTestUpload(t, h, userID, `{"filename":"song.mp3","sizeBytes":10}`)
+	key := store.uploads[uuid.MustParse(created.UploadID)].ObjectKey
+	objects.sizes[key] = 5000
+	if rec := finalizeTestUpload(h, userID, created.UploadID); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized finalize status = %d", rec.Code)
+	}
+	if len(objects.deleted) != 1 || objects.deleted[0] != key || len(jobs.candidates) != 0 {
+		t.Fatalf("deleted = %v queued = %v, want the oversized object removed and nothing queued", objects.deleted, jobs.candidates)
+	}
+}
+
+func TestUploadEnqueueFailureReopensUpload(t *testing.T) {
+	h, store, objects, jobs := newTestUploadHandlers()
+	userID := uuid.New()
+	_, created := createTestUpload(t, h, userID, `{"filename":"song.opus","sizeBytes":10}`)
+	upload := store.uploads[uuid.MustParse(created.UploadID)]
+	objects.sizes[upload.ObjectKey] = 10
+
+	jobs.err = errors.New("redis down")
+	if rec := finalizeTestUpload(h, userID, created.UploadID); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	if upload.Status != db.UploadStatusPending {
+		t.Fatalf("status = %s, want the upload reopened", upload.Status)
+	}
+	jobs.err = nil
+	if rec := finalizeTestUpload(h, userID, created.UploadID); rec.Code != http.StatusAccepted {
+		t.Fatalf("retry status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func getTestUploadParts(t *testing.T, h *UploadHandlers, userID uuid.UUID, uploadID string) (*httptest.ResponseRecorder, UploadPartsResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/uploads/"+uploadID+"/parts", nil)
+	req.SetPathValue("id", uploadID)
+	rec := httptest.NewRecorder()
+	h.GetUploadParts(rec, withUser(req, userID))
+	var resp UploadPartsResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestResumableUploadResumesMissingPartsThenQueues(t *testing.T) {
+	h, store, objects, jobs := newTestUploadHandlers()
+	h.partSize = 400
+	userID := uuid.New()
+
+	rec, created := createTestUpload(t, h, userID, `{"filename":"Live Set.flac","sizeBytes":900,"resumable":true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	upload := store.uploads[uuid.MustParse(created.UploadID)]
+	if created.UploadURL != "" || created.PartSizeBytes != 400 || created.PartCount != 3 || len(created.Parts) != 3 || !upload.MultipartUploadID.Valid {
+		t.Fatalf("create response = %+v, want three part URLs and no single upload URL", created)
+	}
+
+	// The client sent part 1 and a truncated part 3 before losing its
+	// connection.
+	parts := objects.parts[upload.MultipartUploadID.String]
+	parts[1], parts[3] = 400, 50
+	if rec := finalizeTestUpload(h, userID, created.UploadID); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "1 of 3 parts") {
+		t.Fatalf("early finalize = %d %s, want 409 counting one part", rec.Code, rec.Body.String())
+	}
+	rec, progress := getTestUploadParts(t, h, userID, created.UploadID)
+	if rec.Code != http.StatusOK || progress.ReceivedBytes != 400 || len(progress.UploadedParts) != 1 || len(progress.Parts) != 2 || progress.Parts[0].PartNumber != 2 || progress.Parts[1].PartNumber != 3 {
+		t.Fatalf("parts = %d %+v, want parts 2 and 3 still to send", rec.Code, progress)
+	}
+	if rec, _ := getTestUploadParts(t, h, uuid.New(), created.UploadID); rec.Code != http.StatusNotFound {
+		t.Fatalf("parts for another user status = %d, want 404", rec.Code)
+	}
+
+	parts[2], parts[3] = 400, 100
+	rec = finalizeTestUpload(h, userID, created.UploadID)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("finalize status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if objects.sizes[upload.ObjectKey] != 900 || len(jobs.candidates) != 1 || jobs.candidates[0].SourceURL != download.UploadURLPrefix+upload.ObjectKey {
+		t.Fatalf("stored = %d queued = %+v, want the joined object queued", objects.sizes[upload.ObjectKey], jobs.candidates)
+	}
+	if rec, _ := getTestUploadParts(t, h, userID, created.UploadID); rec.Code != http.StatusConflict {
+		t.Fatalf("parts after finalize status = %d, want 409", rec.Code)
+	}
+}
+
+func TestUploadPartsRequiresResumableUpload(t *testing.T) {
+	h, _, _, _ := newTestUploadHandlers()
+	userID := uuid.New()
+	_, created := createTestUpload(t, h, userID, `{"filename":"song.mp3","sizeBytes":10}`)
+	if rec, _ := getTestUploadParts(t, h, userID, created.UploadID); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "UPLOAD_NOT_RESUMABLE") {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
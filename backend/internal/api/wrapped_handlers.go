@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	wrappedTopLimit = 5
+	// wrappedFirstYear bounds the accepted report years; play history cannot
+	// predate it.
+	wrappedFirstYear = 2000
+)
+
+type wrappedStore interface {
+	ComputeStats(ctx context.Context, userID uuid.UUID, year, limit int, loc *time.Location) (*db.WrappedStats, error)
+	SaveReport(ctx context.Context, userID uuid.UUID, year int, document []byte) (*db.WrappedReport, error)
+	GetReport(ctx context.Context, userID uuid.UUID, year int) (*db.WrappedReport, error)
+	GetReportByShareToken(ctx context.Context, token string) (*db.WrappedReport, error)
+	SetShareToken(ctx context.Context, userID uuid.UUID, year int, token string) error
+}
+
+// WrappedHandlers serves year-in-review reports. Reports are generated on first
+// read and stored, so a shared link shows the same document as its owner.
+type WrappedHandlers struct {
+	wrappedRepo wrappedStore
+	timeZones   timeZoneStore
+	now         func() time.Time
+}
+
+func NewWrappedHandlers(wrappedRepo wrappedStore) *WrappedHandlers {
+	return &WrappedHandlers{wrappedRepo: wrappedRepo, now: time.Now}
+}
+
+// SetTimeZones makes generated reports bucket the year and streak days in each
+// user's own time zone instead of UTC.
+func (h *WrappedHandlers) SetTimeZones(zones timeZoneStore) {
+	h.timeZones = zones
+}
+
+type WrappedCountResponse struct {
+	Name      string `json:"name"`
+	PlayCount int    `json:"playCount"`
+}
+
+type WrappedTrackResponse struct {
+	TrackID   int64  `json:"trackId"`
+	Title     string `json:"title"`
+	Artist    string `json:"artist,omitempty"`
+	PlayCount int    `json:"playCount"`
+}
+
+type WrappedStreakResponse struct {
+	Days      int    `json:"days"`
+	StartDate string `json:"startDate,omitempty"`
+	EndDate   string `json:"endDate,omitempty"`
+}
+
+type WrappedDiscoveryResponse struct {
+	NewTracks   int `json:"newTracks"`
+	NewArtists  int `json:"newArtists"`
+	LibraryAdds int `json:"libraryAdds"`
+}
+
+// WrappedDocument is the stored report body. It carries no user identifiers so
+// it can be served verbatim through a share link.
+type WrappedDocument struct {
+	Year          int                      `json:"year"`
+	TimeZone      string                   `json:"timeZone"`
+	TotalPlays    int                      `json:"totalPlays"`
+	TotalMinutes  int                      `json:"totalMinutes"`
+	TopArtists    []WrappedCountResponse   `json:"topArtists"`
+	TopTracks     []WrappedTrackResponse   `json:"topTracks"`
+	TopGenres     []WrappedCountResponse   `json:"topGenres"`
+	LongestStreak WrappedStreakResponse    `json:"longestStreak"`
+	Discovery     WrappedDiscoveryResponse `json:"discovery"`
+}
+
+type WrappedReportResponse struct {
+	Year        int             `json:"year"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	ShareToken  string          `json:"shareToken,omitempty"`
+	Report      json.RawMessage `json:"report"`
+}
+
+type WrappedShareResponse struct {
+	Year       int    `json:"year"`
+	ShareToken string `json:"shareToken"`
+	SharePath  string `json:"sharePath"`
+}
+
+// GetReport handles GET /api/v1/me/wrapped/{year}. The report is generated
+// the first time it is requested.
+func (h *WrappedHandlers) GetReport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeWrappedError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	year, ok := h.parseYear(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.loadOrGenerate(r.Context(), userCtx.UserID, year)
+	if err != nil {
+		writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load report")
+		return
+	}
+	writeWrappedJSON(w, http.StatusOK, newWrappedReportResponse(report, true))
+}
+
+// RegenerateReport handles POST /api/v1/me/wrapped/{year}, recomputing the
+// report from current play history. An existing share link keeps working.
+func (h *WrappedHandlers) RegenerateReport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeWrappedError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	year, ok := h.parseYear(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.generate(r.Context(), userCtx.UserID, year)
+	if err != nil {
+		writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate report")
+		return
+	}
+	writeWrappedJSON(w, http.StatusOK, newWrappedReportResponse(report, true))
+}
+
+// ShareReport handles POST /api/v1/me/wrapped/{year}/share. Sharing is
+// idempotent: an already shared report keeps its token.
+func (h *WrappedHandlers) ShareReport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeWrappedError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	year, ok := h.parseYear(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.loadOrGenerate(r.Context(), userCtx.UserID, year)
+	if err != nil {
+		writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load report")
+		return
+	}
+
+	token := report.ShareToken.String
+	if !report.ShareToken.Valid {
+		token, err = newWrappedShareToken()
+		if err != nil {
+			writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to share report")
+			return
+		}
+		if err := h.wrappedRepo.SetShareToken(r.Context(), userCtx.UserID, year, token); err != nil {
+			writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to share report")
+			return
+		}
+	}
+
+	writeWrappedJSON(w, http.StatusOK, WrappedShareResponse{
+		Year:       year,
+		ShareToken: token,
+		SharePath:  "/api/v1/public/wrapped/" + token,
+	})
+}
+
+// UnshareReport handles DELETE /api/v1/me/wrapped/{year}/share. The old link
+// stops resolving immediately.
+func (h *WrappedHandlers) UnshareReport(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeWrappedError(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+		return
+	}
+	year, ok := h.parseYear(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.wrappedRepo.SetShareToken(r.Context(), userCtx.UserID, year, ""); err != nil {
+		if errors.Is(err, db.ErrWrappedReportNotFound) {
+			writeWrappedError(w, http.StatusNotFound, "NOT_FOUND", "report not found")
+			return
+		}
+		writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to unshare report")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedReport handles GET /api/v1/public/wrapped/{token}. It is served
+// without authentication and returns only the stored document.
+func (h *WrappedHandlers) GetSharedReport(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.PathValue("token"))
+	if token == "" {
+		writeWrappedError(w, http.StatusNotFound, "NOT_FOUND", "report not found")
+		return
+	}
+
+	report, err := h.wrappedRepo.GetReportByShareToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, db.ErrWrappedReportNotFound) {
+			writeWrappedError(w, http.StatusNotFound, "NOT_FOUND", "report not found")
+			return
+		}
+		writeWrappedError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load report")
+		return
+	}
+	writeWrappedJSON(w, http.StatusOK, newWrappedReportResponse(report, false))
+}
+
+func (h *WrappedHandlers) parseYear(w http.ResponseWriter, r *http.Request) (int, bool) {
+	year, err := strconv.Atoi(r.PathValue("year"))
+	if err != nil || year < wrappedFirstYear || year > h.now().UTC().Year() {
+		writeWrappedError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid year")
+		return 0, false
+	}
+	return year, true
+}
+
+func (h *WrappedHandlers) loadOrGenerate(ctx context.Context, userID uuid.UUID, year int) (*db.WrappedReport, error) {
+	report, err := h.wrappedRepo.GetReport(ctx, userID, year)
+	if err == nil {
+		return report, nil
+	}
+	if !errors.Is(err, db.ErrWrappedReportNotFound) {
+		return nil, err
+	}
+	return h.generate(ctx, userID, year)
+}
+
+func (h *WrappedHandlers) generate(ctx context.Context, userID uuid.UUID, year int) (*db.WrappedReport, error) {
+	loc := userLocation(ctx, h.timeZones, userID)
+	stats, err := h.wrappedRepo.ComputeStats(ctx, userID, year, wrappedTopLimit, loc)
+	if err != nil {
+		return nil, err
+	}
+	document, err := json.Marshal(newWrappedDocument(stats))
+	if err != nil {
+		return nil, err
+	}
+	report, err := h.wrappedRepo.SaveReport(ctx, userID, year, document)
+	if err != nil {
+		log.Printf("Warning: failed to save wrapped report for user %s year %d: %v", userID, year, err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func newWrappedDocument(stats *db.WrappedStats) WrappedDocument {
+	doc := WrappedDocument{
+		Year:         stats.Year,
+		TimeZone:     stats.TimeZone,
+		TotalPlays:   stats.TotalPlays,
+		TotalMinutes: stats.TotalMinutes,
+		TopArtists:   make([]WrappedCountResponse, 0, len(stats.TopArtists)),
+		TopTracks:    make([]WrappedTrackResponse, 0, len(stats.TopTracks)),
+		TopGenres:    make([]WrappedCountResponse, 0, len(stats.TopGenres)),
+		LongestStreak: WrappedStreakResponse{
+			Days: stats.LongestStreak.Days,
+		},
+		Discovery: WrappedDiscoveryResponse{
+			NewTracks:   stats.NewTracks,
+			NewArtists:  stats.NewArtists,
+			LibraryAdds: stats.LibraryAdds,
+		},
+	}
+	for _, a := range stats.TopArtists {
+		doc.TopArtists = append(doc.TopArtists, WrappedCountResponse{Name: a.Name, PlayCount: a.PlayCount})
+	}
+	for _, t := range stats.TopTracks {
+		doc.TopTracks = append(doc.TopTracks, WrappedTrackResponse{
+			TrackID:   t.TrackID,
+			Title:     t.Title,
+			Artist:    t.Artist,
+			PlayCount: t.PlayCount,
+		})
+	}
+	for _, g := range stats.TopGenres {
+		doc.TopGenres = append(doc.TopGenres, WrappedCountResponse{Name: g.Name, PlayCount: g.PlayCount})
+	}
+	if stats.LongestStreak.Days > 0 {
+		doc.LongestStreak.StartDate = stats.LongestStreak.Start.Format("2006-01-02")
+		doc.LongestStreak.EndDate = stats.LongestStreak.End.Format("2006-01-02")
+	}
+	return doc
+}
+
+func newWrappedReportResponse(report *db.WrappedReport, includeShareToken bool) WrappedReportResponse {
+	resp := WrappedReportResponse{
+		Year:        report.Year,
+		GeneratedAt: report.GeneratedAt,
+		Report:      json.RawMessage(report.Document),
+	}
+	if includeShareToken && report.ShareToken.Valid {
+		resp.ShareToken = report.ShareToken.String
+	}
+	return resp
+}
+
+func newWrappedShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeWrappedJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeWrappedError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type wrappedKey struct {
+	userID uuid.UUID
+	year   int
+}
+
+type fakeWrappedStore struct {
+	stats    *db.WrappedStats
+	reports  map[wrappedKey]*db.WrappedReport
+	computed int
+}
+
+func newFakeWrappedStore() *fakeWrappedStore {
+	return &fakeWrappedStore{reports: map[wrappedKey]*db.WrappedReport{}}
+}
+
+func (f *fakeWrappedStore) ComputeStats(ctx context.Context, userID uuid.UUID, year, limit int, loc *time.Location) (*db.WrappedStats, error) {
+	f.computed++
+	stats := *f.stats
+	stats.Year = year
+	stats.TimeZone = loc.String()
+	return &stats, nil
+}
+
+func (f *fakeWrappedStore) SaveReport(ctx context.Context, userID uuid.UUID, year int, document []byte) (*db.WrappedReport, error) {
+	key := wrappedKey{userID, year}
+	report, ok := f.reports[key]
+	if !ok {
+		report = &db.WrappedReport{UserID: userID, Year: year}
+		f.reports[key] = report
+	}
+	report.Document = document
+	report.GeneratedAt = time.Now()
+	copied := *report
+	return &copied, nil
+}
+
+func (f *fakeWrappedStore) GetReport(ctx context.Context, userID uuid.UUID, year int) (*db.WrappedReport, error) {
+	report, ok := f.reports[wrappedKey{userID, year}]
+	if !ok {
+		return nil, db.ErrWrappedReportNotFound
+	}
+	copied := *report
+	return &copied, nil
+}
+
+func (f *fakeWrappedStore) GetReportByShareToken(ctx context.Context, token string) (*db.WrappedReport, error) {
+	for _, report := range f.reports {
+		if report.ShareToken.Valid && report.ShareToken.String == token {
+			copied := *report
+			return &copied, nil
+		}
+	}
+	return nil, db.ErrWrappedReportNotFound
+}
+
+func (f *fakeWrappedStore) SetShareToken(ctx context.Context, userID uuid.UUID, year int, token string) error {
+	report, ok := f.reports[wrappedKey{userID, year}]
+	if !ok {
+		return db.ErrWrappedReportNotFound
+	}
+	report.ShareToken = sql.NullString{String: token, Valid: token != ""}
+	return nil
+}
+
+func newTestWrappedHandlers(store *fakeWrappedStore) *WrappedHandlers {
+	h := NewWrappedHandlers(store)
+	h.now = func() time.Time { return time.Date(2026, time.December, 20, 0, 0, 0, 0, time.UTC) }
+	return h
+}
+
+func wrappedRequest(method, path, year string, userID uuid.UUID) *http.Request {
+	req := withUser(httptest.NewRequest(method, path, nil), userID)
+	req.SetPathValue("year", year)
+	return req
+}
+
+func TestWrappedGetReportGeneratesOnceAndStores(t *testing.T) {
+	store := newFakeWrappedStore()
+	store.stats = &db.WrappedStats{
+		TotalPlays:    42,
+		TotalMinutes:  150,
+		TopArtists:    []db.WrappedCount{{Name: "Boards of Canada", PlayCount: 20}},
+		TopTracks:     []db.WrappedTrackCount{{TrackID: 7, Title: "Roygbiv", Artist: "Boards of Canada", PlayCount: 9}},
+		LongestStreak: db.WrappedStreak{Days: 4, Start: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+		NewArtists:    3,
+	}
+	h := newTestWrappedHandlers(store)
+	userID := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.GetReport(rec, wrappedRequest(http.MethodGet, "/api/v1/me/wrapped/2026", "2026", userID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	}
+	if store.computed != 1 {
+		t.Fatalf("computed = %d, want stored report reused", store.computed)
+	}
+
+	var doc WrappedDocument
+	if err := json.Unmarshal(store.reports[wrappedKey{userID, 2026}].Document, &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.Year != 2026 || doc.TotalMinutes != 150 || len(doc.TopTracks) != 1 || doc.Discovery.NewArtists != 3 {
+		t.Fatalf("document = %#v", doc)
+	}
+	if doc.LongestStreak.StartDate != "2026-03-01" || doc.LongestStreak.EndDate != "2026-03-04" {
+		t.Fatalf("streak = %#v", doc.LongestStreak)
+	}
+	if doc.TopGenres == nil {
+		t.Fatal("topGenres should encode as an empty list, not null")
+	}
+	if doc.TimeZone != "UTC" {
+		t.Fatalf("timeZone = %q, want UTC without a stored zone", doc.TimeZone)
+	}
+}
+
+func TestWrappedGeneratesInUserTimeZone(t *testing.T) {
+	store := newFakeWrappedStore()
+	store.stats = &db.WrappedStats{}
+	h := newTestWrappedHandlers(store)
+	userID := uuid.New()
+	h.SetTimeZones(fakeTimeZones{userID: "Asia/Tokyo"})
+
+	rec := httptest.NewRecorder()
+	h.GetReport(rec, wrappedRequest(http.MethodGet, "/api/v1/me/wrapped/2026", "2026", userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var doc WrappedDocument
+	if err := json.Unmarshal(store.reports[wrappedKey{userID, 2026}].Document, &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.TimeZone != "Asia/Tokyo" {
+		t.Fatalf("timeZone = %q, want Asia/Tokyo", doc.TimeZone)
+	}
+}
+
+func TestWrappedRejectsFutureAndInvalidYears(t *testing.T) {
+	h := newTestWrappedHandlers(newFakeWrappedStore())
+	for _, year := range []string{"2027", "1999", "soon"} {
+		rec := httptest.NewRecorder()
+		h.GetReport(rec, wrappedRequest(http.MethodGet, "/api/v1/me/wrapped/"+year, year, uuid.New()))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("year %s: status = %d, want 400", year, rec.Code)
+		}
+	}
+}
+
+func TestWrappedShareTokenServesPublicReportUntilRevoked(t *testing.T) {
+	store := newFakeWrappedStore()
+	store.stats = &db.WrappedStats{TotalPlays: 5}
+	h := newTestWrappedHandlers(store)
+	userID := uuid.New()
+
+	rec := httptest.NewRecorder()
+	h.ShareReport(rec, wrappedRequest(http.MethodPost, "/api/v1/me/wrapped/2025/share", "2025", userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("share status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var share WrappedShareResponse
+	if err := json.NewDecoder(rec.Body).Decode(&share); err != nil {
+		t.Fatalf("decode share: %v", err)
+	}
+	if len(share.ShareToken) != 32 {
+		t.Fatalf("share token = %q", share.ShareToken)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ShareReport(rec, wrappedRequest(http.MethodPost, "/api/v1/me/wrapped/2025/share", "2025", userID))
+	var again WrappedShareResponse
+	json.NewDecoder(rec.Body).Decode(&again)
+	if again.ShareToken != share.ShareToken {
+		t.Fatalf("re-share token = %q, want existing %q", again.ShareToken, share.ShareToken)
+	}
+
+	publicReq := httptest.NewRequest(http.MethodGet, "/api/v1/public/wrapped/"+share.ShareToken, nil)
+	publicReq.SetPathValue("token", share.ShareToken)
+	rec = httptest.NewRecorder()
+	h.GetSharedReport(rec, publicReq)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public status = %d", rec.Code)
+	}
+	var public map[string]json.RawMessage
+	json.NewDecoder(rec.Body).Decode(&public)
+	if _, ok := public["shareToken"]; ok {
+		t.Fatal("public response should not echo the share token")
+	}
+	if _, ok := public["report"]; !ok {
+		t.Fatal("public response missing report")
+	}
+
+	rec = httptest.NewRecorder()
+	h.UnshareReport(rec, wrappedRequest(http.MethodDelete, "/api/v1/me/wrapped/2025/share", "2025", userID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unshare status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetSharedReport(rec, publicReq)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("revoked public status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,438 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSquareCropsCenterAndResizes(t *testing.T) {
+	// A wide image with red side bands and a blue center square.
+	src := solid(300, 100, color.RGBA{R: 255, A: 255})
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			src.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+	out := Square(src, 40)
+	if out.Bounds().Dx() != 40 || out.Bounds().Dy() != 40 {
+		t.Fatalf("size = %v, want 40x40", out.Bounds())
+	}
+	if got := out.RGBAAt(0, 0); got.B != 255 || got.R != 0 {
+		t.Fatalf("corner = %v, want the blue center crop", got)
+	}
+}
+
+func TestSquareFlattensTransparencyOntoWhite(t *testing.T) {
+	out := Square(image.NewRGBA(image.Rect(0, 0, 10, 10)), 4)
+	if got := out.RGBAAt(1, 1); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("pixel = %v, want opaque white", got)
+	}
+}
+
+func TestMosaicTilesFourImagesAndFallsBackToFirst(t *testing.T) {
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {R: 255, G: 255, A: 255}}
+	var images []image.Image
+	for _, c := range colors {
+		images = append(images, solid(20, 20, c))
+	}
+	grid := Mosaic(images, 40)
+	for i, pt := range []image.Point{{5, 5}, {35, 5}, {5, 35}, {35, 35}} {
+		if got := grid.RGBAAt(pt.X, pt.Y); got != colors[i] {
+			t.Fatalf("tile %d = %v, want %v", i, got, colors[i])
+		}
+	}
+
+	single := Mosaic(images[:3], 40)
+	if got := single.RGBAAt(35, 35); got != colors[0] {
+		t.Fatalf("fallback pixel = %v, want the first cover", got)
+	}
+	if Mosaic(nil, 40) != nil {
+		t.Fatal("mosaic of no images should be nil")
+	}
+}
+
+func TestDecodeRejectsOversizedAndInvalidImages(t *testing.T) {
+	if _, err := Decode(strings.NewReader("not an image")); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("Decode(garbage) = %v, want ErrUnsupportedImage", err)
+	}
+	// A PNG header claiming a huge canvas is refused before decoding pixels.
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	binary.BigEndian.PutUint32(huge[16:20], 65536) // IHDR width
+	binary.BigEndian.PutUint32(huge[20:24], 65536) // IHDR height
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := Decode(bytes.NewReader(huge)); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("Decode(huge) = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestFetcherOnlyAllowsListedHTTPSHosts(t *testing.T) {
+	cover := encodePNG(t, solid(8, 8, color.RGBA{R: 255, A: 255}))
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://example.com/cover.png", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(cover)
+	}))
+	defer srv.Close()
+
+	f := NewFetcher([]string{"127.0.0.1"})
+	f.client.Transport = srv.Client().Transport
+
+	if _, err := f.Fetch(context.Background(), srv.URL+"/cover.png"); err != nil {
+		t.Fatalf("Fetch(allowed) = %v", err)
+	}
+	if _, err := f.Fetch(context.Background(), srv.URL+"/redirect"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Fetch(redirect off the allowlist) = %v, want ErrHostNotAllowed", err)
+	}
+	if _, err := f.Fetch(context.Background(), "http://127.0.0.1/cover.png"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Fetch(plain http) = %v, want ErrHostNotAllowed", err)
+	}
+	if _, err := NewFetcher(nil).Fetch(context.Background(), "https://169.254.169.254/latest"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Fetch(metadata address) = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+type fakeStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeStorage) PutObject(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *fakeStorage) GetObject(_ context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, nil, errors.New("NoSuchKey")
+	}
+	return io.NopCloser(bytes.NewReader(data)), &storage.ObjectInfo{Size: int64(len(data)), ContentType: "image/jpeg"}, nil
+}
+
+func (s *fakeStorage) DeleteObject(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *fakeStorage) PresignGetObject(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://cdn.test/" + key, nil
+}
+
+type fakeStore struct {
+	artworkKey sql.NullString
+	mosaicKey  sql.NullString
+	sources    sql.NullString
+	tracks     []string
+}
+
+func (s *fakeStore) SetArtworkKey(_ context.Context, _ int64, key sql.NullString) (sql.NullString, error) {
+	previous := s.artworkKey
+	s.artworkKey = key
+	return previous, nil
+}
+
+func (s *fakeStore) MosaicArtworkSources(_ context.Context, _ int64, limit int) ([]string, error) {
+	if len(s.tracks) > limit {
+		return s.tracks[:limit], nil
+	}
+	return s.tracks, nil
+}
+
+func (s *fakeStore) GetMosaicState(context.Context, int64) (sql.NullString, sql.NullString, error) {
+	return s.mosaicKey, s.sources, nil
+}
+
+func (s *fakeStore) SetMosaic(_ context.Context, _ int64, key, sources sql.NullString) (sql.NullString, error) {
+	previous := s.mosaicKey
+	s.mosaicKey, s.sources = key, sources
+	return previous, nil
+}
+
+type fakeFetcher struct {
+	calls int
+	fail  map[string]bool
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, rawURL string) (image.Image, error) {
+	f.calls++
+	if f.fail[rawURL] {
+		return nil, errors.New("unreachable")
+	}
+	return solid(16, 16, color.RGBA{G: 200, A: 255}), nil
+}
+
+func TestUploadReplacesPreviousCover(t *testing.T) {
+	storage := &fakeStorage{}
+	store := &fakeStore{}
+	svc := NewService(storage, store, &fakeFetcher{})
+	body := encodePNG(t, solid(900, 700, color.RGBA{R: 10, A: 255}))
+
+	if err := svc.Upload(context.Background(), 7, bytes.NewReader(body)); err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+	first := store.artworkKey.String
+	if err := svc.Upload(context.Background(), 7, bytes.NewReader(body)); err != nil {
+		t.Fatalf("second upload: %v", err)
+	}
+	if store.artworkKey.String == first || !strings.HasPrefix(store.artworkKey.String, "playlists/7/cover-") {
+		t.Fatalf("artwork key = %q, want a fresh cover key", store.artworkKey.String)
+	}
+	if _, ok := storage.objects[first]; ok || len(storage.objects) != 1 {
+		t.Fatalf("objects = %v, want only the latest cover", storage.objects)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(storage.objects[store.artworkKey.String]))
+	if err != nil || cfg.Width != CoverSize || cfg.Height != CoverSize {
+		t.Fatalf("stored cover = %+v, %v; want %dx%d JPEG", cfg, err, CoverSize, CoverSize)
+	}
+
+	if err := svc.Remove(context.Background(), 7); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if store.artworkKey.Valid || len(storage.objects) != 0 {
+		t.Fatalf("remove left key %v and objects %v", store.artworkKey, storage.objects)
+	}
+}
+
+func TestRefreshMosaicOnlyRebuildsWhenSourcesChange(t *testing.T) {
+	storage := &fakeStorage{}
+	store := &fakeStore{tracks: []string{"a", "b", "c", "d", "e"}}
+	fetcher := &fakeFetcher{fail: map[string]bool{"c": true}}
+	svc := NewService(storage, store, fetcher)
+
+	if err := svc.RefreshMosaic(context.Background(), 3); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if !store.mosaicKey.Valid || store.sources.String != "a\nb\nc\nd" || fetcher.calls != 4 {
+		t.Fatalf("mosaic = %v sources %q after %d fetches", store.mosaicKey, store.sources.String, fetcher.calls)
+	}
+
+	if err := svc.RefreshMosaic(context.Background(), 3); err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if fetcher.calls != 4 {
+		t.Fatalf("unchanged playlist refetched covers: %d calls", fetcher.calls)
+	}
+
+	store.tracks = nil
+	if err := svc.RefreshMosaic(context.Background(), 3); err != nil {
+		t.Fatalf("refresh after emptying: %v", err)
+	}
+	if store.mosaicKey.Valid || len(storage.objects) != 0 {
+		t.Fatalf("empty playlist kept mosaic %v and objects %v", store.mosaicKey, storage.objects)
+	}
+}
+
+func TestRefreshMosaicRetriesWhenNoCoverDownloads(t *testing.T) {
+	store := &fakeStore{tracks: []string{"a"}}
+	svc := NewService(&fakeStorage{}, store, &fakeFetcher{fail: map[string]bool{"a": true}})
+	if err := svc.RefreshMosaic(context.Background(), 3); err == nil {
+		t.Fatal("expected an error when no cover could be fetched")
+	}
+	if store.sources.Valid {
+		t.Fatalf("sources recorded %q; a later refresh would never retry", store.sources.String)
+	}
+}
+
+type fakeAvatarStore struct{ key sql.NullString }
+
+func (s *fakeAvatarStore) SetAvatarKey(_ context.Context, _ uuid.UUID, key sql.NullString) (sql.NullString, error) {
+	previous := s.key
+	s.key = key
+	return previous, nil
+}
+
+func TestAvatarUploadStoresResizedSquare(t *testing.T) {
+	storage := &fakeStorage{}
+	store := &fakeAvatarStore{}
+	avatars := NewAvatars(storage, store)
+	userID := uuid.New()
+
+	if err := avatars.Upload(context.Background(), userID, bytes.NewReader(encodePNG(t, solid(40, 80, color.White)))); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !strings.HasPrefix(store.key.String, "avatars/"+userID.String()+"/") {
+		t.Fatalf("avatar key = %q", store.key.String)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(storage.objects[store.key.String]))
+	if err != nil || cfg.Width != AvatarSize || cfg.Height != AvatarSize {
+		t.Fatalf("stored avatar = %+v, %v; want %dx%d", cfg, err, AvatarSize, AvatarSize)
+	}
+
+	if err := avatars.Remove(context.Background(), userID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if store.key.Valid || len(storage.objects) != 0 {
+		t.Fatalf("remove left key %v and objects %v", store.key, storage.objects)
+	}
+}
+
+type fakeEntityStore struct {
+	kind db.EntityKind
+	key  sql.NullString
+}
+
+func (s *fakeEntityStore) SetEntityArtworkKey(_ context.Context, kind db.EntityKind, _ uuid.UUID, key sql.NullString) (sql.NullString, error) {
+	previous := s.key
+	s.kind, s.key = kind, key
+	return previous, nil
+}
+
+func TestEntityUploadReplacesPreviousArtwork(t *testing.T) {
+	storage := &fakeStorage{}
+	store := &fakeEntityStore{}
+	entities := NewEntities(storage, store)
+	artistID := uuid.New()
+
+	for range 2 {
+		if err := entities.Upload(context.Background(), db.EntityArtist, artistID, bytes.NewReader(encodePNG(t, solid(90, 30, color.White)))); err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+	}
+	if store.kind != db.EntityArtist || !strings.HasPrefix(store.key.String, "artwork/artists/"+artistID.String()+"/") {
+		t.Fatalf("artwork = %s %q", store.kind, store.key.String)
+	}
+	if len(storage.objects) != 1 {
+		t.Fatalf("stored %d objects, want the replaced one deleted", len(storage.objects))
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(storage.objects[store.key.String]))
+	if err != nil || cfg.Width != CoverSize || cfg.Height != CoverSize {
+		t.Fatalf("stored artwork = %+v, %v; want %dx%d", cfg, err, CoverSize, CoverSize)
+	}
+
+	if err := entities.Remove(context.Background(), db.EntityArtist, artistID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if store.key.Valid || len(storage.objects) != 0 {
+		t.Fatalf("remove left key %v and objects %v", store.key, storage.objects)
+	}
+}
+
+type coverFetcher struct {
+	mu   sync.Mutex
+	urls []string
+	img  image.Image
+	err  error
+}
+
+func (f *coverFetcher) Fetch(_ context.Context, rawURL string) (image.Image, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.urls = append(f.urls, rawURL)
+	return f.img, f.err
+}
+
+func TestReleaseCoverFetchesOnceAndStoresEverySize(t *testing.T) {
+	storage := &fakeStorage{}
+	fetcher := &coverFetcher{img: solid(800, 800, color.RGBA{B: 200, A: 255})}
+	covers := NewReleaseCovers(storage, fetcher)
+	releaseID := uuid.New()
+
+	data, err := covers.Cover(context.Background(), releaseID, 250)
+	if err != nil {
+		t.Fatalf("Cover: %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width != 250 || cfg.Height != 250 {
+		t.Fatalf("cover = %+v, %v; want 250x250 JPEG", cfg, err)
+	}
+	want := "https://coverartarchive.org/release/" + releaseID.String() + "/front-1200"
+	if len(fetcher.urls) != 1 || fetcher.urls[0] != want {
+		t.Fatalf("fetched %v, want only %s", fetcher.urls, want)
+	}
+
+	// The source is smaller than 1200, so that size is stored unscaled.
+	large, ok := storage.objects[ReleaseCoverKey(releaseID, 1200)]
+	if !ok || len(storage.objects) != len(ReleaseCoverSizes) {
+		t.Fatalf("stored objects %d, want one per size", len(storage.objects))
+	}
+	if cfg, _, _ := image.DecodeConfig(bytes.NewReader(large)); cfg.Width != 800 {
+		t.Fatalf("1200 cover width = %d, want the source's 800", cfg.Width)
+	}
+	if _, err := covers.Cover(context.Background(), releaseID, 500); err != nil || len(fetcher.urls) != 1 {
+		t.Fatalf("second size: err %v after %d fetches; want a storage hit", err, len(fetcher.urls))
+	}
+}
+
+func TestReleaseCoverRemembersMissingCovers(t *testing.T) {
+	fetcher := &coverFetcher{err: fmt.Errorf("%w: coverartarchive.org", ErrImageNotFound)}
+	covers := NewReleaseCovers(&fakeStorage{}, fetcher)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	covers.now = func() time.Time { return now }
+	releaseID := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		if _, err := covers.Cover(context.Background(), releaseID, 500); !errors.Is(err, ErrCoverNotFound) {
+			t.Fatalf("Cover = %v, want ErrCoverNotFound", err)
+		}
+	}
+	if len(fetcher.urls) != 1 {
+		t.Fatalf("fetched %d times, want the miss remembered", len(fetcher.urls))
+	}
+	now = now.Add(releaseCoverMissTTL)
+	covers.Cover(context.Background(), releaseID, 500)
+	if len(fetcher.urls) != 2 {
+		t.Fatalf("fetched %d times, want a retry after the miss expired", len(fetcher.urls))
+	}
+}
+
+func TestReleaseCoverRejectsUnsupportedSize(t *testing.T) {
+	covers := NewReleaseCovers(&fakeStorage{}, &coverFetcher{})
+	if _, err := covers.Cover(context.Background(), uuid.New(), 300); err == nil {
+		t.Fatal("expected an error for an unsupported size")
+	}
+}
@@ -0,0 +1,68 @@
+package artwork
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// AvatarSize is the edge length of stored user avatars.
+const AvatarSize = 256
+
+// AvatarStore persists which object holds a user's avatar.
+type AvatarStore interface {
+	SetAvatarKey(ctx context.Context, userID uuid.UUID, key sql.NullString) (sql.NullString, error)
+}
+
+// Avatars stores square, resized user avatars in object storage.
+type Avatars struct {
+	storage Storage
+	store   AvatarStore
+}
+
+// NewAvatars creates an avatar store backed by object storage.
+func NewAvatars(storage Storage, store AvatarStore) *Avatars {
+	return &Avatars{storage: storage, store: store}
+}
+
+// Upload decodes an uploaded image, crops and resizes it, and makes it the
+// user's avatar, deleting the one it replaces.
+func (a *Avatars) Upload(ctx context.Context, userID uuid.UUID, r io.Reader) error {
+	img, err := Decode(r)
+	if err != nil {
+		return err
+	}
+	data, err := EncodeJPEG(Square(img, AvatarSize))
+	if err != nil {
+		return err
+	}
+	key, err := putJPEG(ctx, a.storage, fmt.Sprintf("avatars/%s", userID), "avatar", data)
+	if err != nil {
+		return err
+	}
+	previous, err := a.store.SetAvatarKey(ctx, userID, sql.NullString{String: key, Valid: true})
+	if err != nil {
+		deleteObjects(ctx, a.storage, key)
+		return err
+	}
+	deleteObjects(ctx, a.storage, previous.String)
+	return nil
+}
+
+// Remove clears the user's avatar.
+func (a *Avatars) Remove(ctx context.Context, userID uuid.UUID) error {
+	previous, err := a.store.SetAvatarKey(ctx, userID, sql.NullString{})
+	if err != nil {
+		return err
+	}
+	deleteObjects(ctx, a.storage, previous.String)
+	return nil
+}
+
+// URL returns a presigned URL for an avatar object.
+func (a *Avatars) URL(ctx context.Context, key string) (string, error) {
+	return a.storage.PresignGetObject(ctx, key, URLTTL)
+}
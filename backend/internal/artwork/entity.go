@@ -0,0 +1,68 @@
+package artwork
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// EntityStore persists which object holds an artist's or release's artwork.
+type EntityStore interface {
+	SetEntityArtworkKey(ctx context.Context, kind db.EntityKind, id uuid.UUID, key sql.NullString) (sql.NullString, error)
+}
+
+// Entities stores square artwork admins upload for artists and releases.
+type Entities struct {
+	storage Storage
+	store   EntityStore
+}
+
+// NewEntities creates an artist and release artwork store backed by object
+// storage.
+func NewEntities(storage Storage, store EntityStore) *Entities {
+	return &Entities{storage: storage, store: store}
+}
+
+// Upload decodes an uploaded image, crops and resizes it to a square cover,
+// and makes it the entity's artwork, deleting the one it replaces.
+func (e *Entities) Upload(ctx context.Context, kind db.EntityKind, id uuid.UUID, r io.Reader) error {
+	img, err := Decode(r)
+	if err != nil {
+		return err
+	}
+	data, err := EncodeJPEG(Square(img, CoverSize))
+	if err != nil {
+		return err
+	}
+	key, err := putJPEG(ctx, e.storage, fmt.Sprintf("artwork/%ss/%s", kind, id), "cover", data)
+	if err != nil {
+		return err
+	}
+	previous, err := e.store.SetEntityArtworkKey(ctx, kind, id, sql.NullString{String: key, Valid: true})
+	if err != nil {
+		deleteObjects(ctx, e.storage, key)
+		return err
+	}
+	deleteObjects(ctx, e.storage, previous.String)
+	return nil
+}
+
+// Remove clears the entity's artwork.
+func (e *Entities) Remove(ctx context.Context, kind db.EntityKind, id uuid.UUID) error {
+	previous, err := e.store.SetEntityArtworkKey(ctx, kind, id, sql.NullString{})
+	if err != nil {
+		return err
+	}
+	deleteObjects(ctx, e.storage, previous.String)
+	return nil
+}
+
+// URL returns a presigned URL for an entity artwork object.
+func (e *Entities) URL(ctx context.Context, key string) (string, error) {
+	return e.storage.PresignGetObject(ctx, key, URLTTL)
+}
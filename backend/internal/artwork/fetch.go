@@ -0,0 +1,111 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultMaxFetchBytes bounds a downloaded track cover.
+const DefaultMaxFetchBytes = 5 << 20
+
+var (
+	// ErrHostNotAllowed reports a source URL outside the fetcher's allowlist.
+	ErrHostNotAllowed = errors.New("artwork host not allowed")
+	// ErrImageNotFound reports a source URL the host answered with 404.
+	ErrImageNotFound = errors.New("artwork image not found")
+)
+
+// DefaultAllowedHosts are the hosts track artwork is served from. Cover Art
+// Archive redirects image requests to archive.org mirrors.
+var DefaultAllowedHosts = []string{"coverartarchive.org", "archive.org"}
+
+// SourceThumbnailHosts serve the thumbnails YouTube and SoundCloud report
+// for a downloaded track.
+var SourceThumbnailHosts = []string{"ytimg.com", "sndcdn.com"}
+
+// Fetcher downloads track artwork over HTTPS from an allowlist of hosts. Track
+// cover URLs come from provider metadata, so they are never fetched from
+// arbitrary or internal addresses.
+type Fetcher struct {
+	client       *http.Client
+	allowedHosts []string
+	maxBytes     int64
+}
+
+// NewFetcher creates a fetcher for the given hosts; a host also admits its
+// subdomains. A nil or empty list uses DefaultAllowedHosts.
+func NewFetcher(allowedHosts []string) *Fetcher {
+	if len(allowedHosts) == 0 {
+		allowedHosts = DefaultAllowedHosts
+	}
+	f := &Fetcher{allowedHosts: allowedHosts, maxBytes: DefaultMaxFetchBytes}
+	f.client = &http.Client{
+		Timeout: 15 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// Fetch downloads and decodes the image at rawURL.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (image.Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, u.Host)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", u.Host, resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxBytes {
+		return nil, fmt.Errorf("fetch %s: %d bytes exceeds limit", u.Host, resp.ContentLength)
+	}
+	body := io.LimitReader(resp.Body, f.maxBytes+1)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("fetch %s: body exceeds %d bytes", u.Host, f.maxBytes)
+	}
+	return Decode(bytes.NewReader(data))
+}
+
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrHostNotAllowed, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+}
@@ -0,0 +1,149 @@
+// Package artwork stores uploaded playlist covers and user avatars, builds
+// 2x2 mosaics from the artwork of a playlist's tracks, and caches Cover Art
+// Archive release covers as thumbnails. Images are square-cropped, resized,
+// and re-encoded as JPEG before they reach object storage.
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxSourcePixels bounds the dimensions of an image we are willing to decode,
+// so a small but huge-canvas file cannot exhaust memory.
+const MaxSourcePixels = 4096 * 4096
+
+// jpegQuality is the encoder quality for covers and mosaics.
+const jpegQuality = 85
+
+var (
+	// ErrUnsupportedImage reports a body that is not a JPEG, PNG, or GIF.
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	// ErrImageTooLarge reports an image whose dimensions exceed MaxSourcePixels.
+	ErrImageTooLarge = errors.New("image dimensions too large")
+)
+
+// Decode reads a JPEG, PNG, or GIF image after checking its header dimensions
+// against MaxSourcePixels.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	return img, nil
+}
+
+// Square center-crops src to a square and resizes it to size x size using an
+// area average, flattening any transparency onto white.
+func Square(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	crop := image.Rect(0, 0, side, side)
+	origin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+
+	flat := image.NewRGBA(crop)
+	draw.Draw(flat, crop, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, crop, src, origin, draw.Over)
+
+	return resample(flat, side, size)
+}
+
+// resample scales a side x side RGBA image to size x size by averaging the
+// source pixels each destination pixel covers.
+func resample(src *image.RGBA, side, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, side, size)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, side, size)
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4:]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					n++
+				}
+			}
+			o := dst.Pix[y*dst.Stride+x*4:]
+			o[0] = uint8(r / n)
+			o[1] = uint8(g / n)
+			o[2] = uint8(b / n)
+			o[3] = 0xff
+		}
+	}
+	return dst
+}
+
+// span returns the source range covered by destination index i, always at
+// least one pixel wide so upscaling repeats pixels instead of dividing by zero.
+func span(i, side, size int) (int, int) {
+	start := i * side / size
+	end := (i + 1) * side / size
+	if end <= start {
+		end = start + 1
+	}
+	if end > side {
+		start, end = side-1, side
+	}
+	return start, end
+}
+
+// Mosaic arranges the first four images in a 2x2 grid of the given size.
+// With fewer than four images the first one fills the whole square, since a
+// grid with gaps or repeats looks worse than a single cover.
+func Mosaic(images []image.Image, size int) *image.RGBA {
+	if len(images) == 0 {
+		return nil
+	}
+	if len(images) < 4 {
+		return Square(images[0], size)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	half := size / 2
+	cells := []image.Rectangle{
+		image.Rect(0, 0, half, half),
+		image.Rect(half, 0, size, half),
+		image.Rect(0, half, half, size),
+		image.Rect(half, half, size, size),
+	}
+	for i, cell := range cells {
+		tile := Square(images[i], cell.Dx())
+		draw.Draw(dst, cell, tile, image.Point{}, draw.Src)
+	}
+	return dst
+}
+
+// EncodeJPEG encodes img at the package's cover quality.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
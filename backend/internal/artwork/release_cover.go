@@ -0,0 +1,218 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+const (
+	// DefaultCoverArtArchiveURL is where release covers are fetched from.
+	DefaultCoverArtArchiveURL = "https://coverartarchive.org"
+
+	// releaseCoverPrefix is where release cover thumbnails live in object
+	// storage.
+	releaseCoverPrefix = "coverart"
+	// releaseCoverSource is the Cover Art Archive thumbnail every stored size
+	// is generated from; it is the largest one CAA serves at a bounded size.
+	releaseCoverSource = "front-1200"
+	// releaseCoverFetches bounds concurrent fetches from Cover Art Archive.
+	releaseCoverFetches = 4
+	// releaseCoverTimeout bounds one fetch and the uploads that follow it.
+	releaseCoverTimeout = 30 * time.Second
+	// releaseCoverMissTTL is how long a release without a front cover is
+	// remembered before Cover Art Archive is asked again.
+	releaseCoverMissTTL = time.Hour
+)
+
+// ReleaseCoverSizes are the edge lengths release covers are served at.
+var ReleaseCoverSizes = []int{250, 500, 1200}
+
+// ErrCoverNotFound reports a release Cover Art Archive has no front cover for.
+var ErrCoverNotFound = errors.New("release has no front cover")
+
+// CoverStorage is the object storage release cover thumbnails are cached in.
+type CoverStorage interface {
+	GetObject(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error)
+	PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+}
+
+// ReleaseCovers serves Cover Art Archive front covers from object storage.
+// The first request for a release fetches its cover once, stores a square
+// JPEG in every ReleaseCoverSizes size, and later requests read the stored
+// thumbnails. Concurrent requests for the same release share one fetch.
+type ReleaseCovers struct {
+	storage CoverStorage
+	fetcher ImageFetcher
+	baseURL string
+	slots   chan struct{}
+	now     func() time.Time
+
+	mu       sync.Mutex
+	inflight map[uuid.UUID]*releaseCoverCall
+	missing  map[uuid.UUID]time.Time
+}
+
+type releaseCoverCall struct {
+	done   chan struct{}
+	covers map[int][]byte
+	err    error
+}
+
+// NewReleaseCovers creates a release cover cache. A nil fetcher uses
+// NewFetcher(nil).
+func NewReleaseCovers(storage CoverStorage, fetcher ImageFetcher) *ReleaseCovers {
+	if fetcher == nil {
+		fetcher = NewFetcher(nil)
+	}
+	return &ReleaseCovers{
+		storage:  storage,
+		fetcher:  fetcher,
+		baseURL:  DefaultCoverArtArchiveURL,
+		slots:    make(chan struct{}, releaseCoverFetches),
+		now:      time.Now,
+		inflight: make(map[uuid.UUID]*releaseCoverCall),
+		missing:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+// IsReleaseCoverSize reports whether size is one of ReleaseCoverSizes.
+func IsReleaseCoverSize(size int) bool {
+	for _, s := range ReleaseCoverSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseCoverKey returns the storage key of a release's cover at size.
+func ReleaseCoverKey(releaseID uuid.UUID, size int) string {
+	return fmt.Sprintf("%s/%s/%d.jpg", releaseCoverPrefix, releaseID, size)
+}
+
+// Cover returns the release's front cover as a JPEG of at most size x size.
+// Covers smaller than size are stored at their own size rather than
+// upscaled. The fetch runs detached from ctx so a client giving up does not
+// waste work the next request can use.
+func (c *ReleaseCovers) Cover(ctx context.Context, releaseID uuid.UUID, size int) ([]byte, error) {
+	if !IsReleaseCoverSize(size) {
+		return nil, fmt.Errorf("unsupported cover size %d", size)
+	}
+	if data, err := c.load(ctx, ReleaseCoverKey(releaseID, size)); err == nil {
+		return data, nil
+	}
+
+	c.mu.Lock()
+	if until, ok := c.missing[releaseID]; ok {
+		if c.now().Before(until) {
+			c.mu.Unlock()
+			return nil, ErrCoverNotFound
+		}
+		delete(c.missing, releaseID)
+	}
+	call, running := c.inflight[releaseID]
+	if !running {
+		call = &releaseCoverCall{done: make(chan struct{})}
+		c.inflight[releaseID] = call
+		go c.run(call, releaseID)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return call.covers[size], nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *ReleaseCovers) load(ctx context.Context, key string) ([]byte, error) {
+	obj, _, err := c.storage.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+func (c *ReleaseCovers) run(call *releaseCoverCall, releaseID uuid.UUID) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, releaseID)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseCoverTimeout)
+	defer cancel()
+
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		call.err = fmt.Errorf("waiting for a cover fetch slot: %w", ctx.Err())
+		return
+	}
+	call.covers, call.err = c.generate(ctx, releaseID)
+	if errors.Is(call.err, ErrCoverNotFound) {
+		c.rememberMissing(releaseID)
+	}
+}
+
+// generate fetches the release's cover and stores every size. A failed
+// upload is logged rather than returned: the thumbnails are still served and
+// the next request retries the fetch.
+func (c *ReleaseCovers) generate(ctx context.Context, releaseID uuid.UUID) (map[int][]byte, error) {
+	img, err := c.fetcher.Fetch(ctx, fmt.Sprintf("%s/release/%s/%s", c.baseURL, releaseID, releaseCoverSource))
+	if errors.Is(err, ErrImageNotFound) {
+		return nil, ErrCoverNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch release %s cover: %w", releaseID, err)
+	}
+
+	side := img.Bounds().Dx()
+	if img.Bounds().Dy() < side {
+		side = img.Bounds().Dy()
+	}
+	covers := make(map[int][]byte, len(ReleaseCoverSizes))
+	for _, size := range ReleaseCoverSizes {
+		data, err := EncodeJPEG(Square(img, min(size, side)))
+		if err != nil {
+			return nil, err
+		}
+		covers[size] = data
+		key := ReleaseCoverKey(releaseID, size)
+		if err := c.storage.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+			log.Printf("Warning: failed to cache release cover %s: %v", key, err)
+		}
+	}
+	return covers, nil
+}
+
+// rememberMissing records a release without a cover, dropping expired
+// entries so the map only holds recent misses.
+func (c *ReleaseCovers) rememberMissing(releaseID uuid.UUID) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, until := range c.missing {
+		if !now.Before(until) {
+			delete(c.missing, id)
+		}
+	}
+	c.missing[releaseID] = now.Add(releaseCoverMissTTL)
+}
@@ -0,0 +1,221 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CoverSize is the edge length of stored covers and mosaics.
+	CoverSize = 600
+	// MosaicTiles is how many distinct track covers a mosaic uses.
+	MosaicTiles = 4
+	// URLTTL is how long presigned artwork URLs stay valid.
+	URLTTL = 6 * time.Hour
+
+	refreshTimeout = time.Minute
+)
+
+// Storage is the object storage the service writes covers into.
+type Storage interface {
+	PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	DeleteObject(ctx context.Context, key string) error
+	PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Store persists which objects a playlist's artwork lives in.
+type Store interface {
+	SetArtworkKey(ctx context.Context, playlistID int64, key sql.NullString) (sql.NullString, error)
+	MosaicArtworkSources(ctx context.Context, playlistID int64, limit int) ([]string, error)
+	GetMosaicState(ctx context.Context, playlistID int64) (key, sources sql.NullString, err error)
+	SetMosaic(ctx context.Context, playlistID int64, key, sources sql.NullString) (sql.NullString, error)
+}
+
+// ImageFetcher downloads a track's cover image.
+type ImageFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (image.Image, error)
+}
+
+// Service uploads custom playlist covers and keeps generated mosaics in step
+// with playlist contents.
+type Service struct {
+	storage Storage
+	store   Store
+	fetcher ImageFetcher
+
+	mu         sync.Mutex
+	refreshing map[int64]bool
+}
+
+// NewService creates an artwork service. A nil fetcher uses NewFetcher(nil).
+func NewService(storage Storage, store Store, fetcher ImageFetcher) *Service {
+	if fetcher == nil {
+		fetcher = NewFetcher(nil)
+	}
+	return &Service{
+		storage:    storage,
+		store:      store,
+		fetcher:    fetcher,
+		refreshing: make(map[int64]bool),
+	}
+}
+
+// Upload decodes an uploaded image, crops and resizes it to a square cover,
+// and makes it the playlist's artwork, deleting the cover it replaces.
+func (s *Service) Upload(ctx context.Context, playlistID int64, r io.Reader) error {
+	img, err := Decode(r)
+	if err != nil {
+		return err
+	}
+	data, err := EncodeJPEG(Square(img, CoverSize))
+	if err != nil {
+		return err
+	}
+	key, err := putJPEG(ctx, s.storage, fmt.Sprintf("playlists/%d", playlistID), "cover", data)
+	if err != nil {
+		return err
+	}
+	previous, err := s.store.SetArtworkKey(ctx, playlistID, sql.NullString{String: key, Valid: true})
+	if err != nil {
+		s.DeleteObjects(ctx, key)
+		return err
+	}
+	s.DeleteObjects(ctx, previous.String)
+	return nil
+}
+
+// Remove clears the playlist's uploaded cover so the mosaic shows again.
+func (s *Service) Remove(ctx context.Context, playlistID int64) error {
+	previous, err := s.store.SetArtworkKey(ctx, playlistID, sql.NullString{})
+	if err != nil {
+		return err
+	}
+	s.DeleteObjects(ctx, previous.String)
+	return nil
+}
+
+// RefreshMosaic rebuilds the playlist's mosaic when the covers of its leading
+// tracks have changed since the last build. Covers that fail to download are
+// left out and the source list is still recorded, so one dead URL is not
+// refetched on every call; if none download, nothing is recorded.
+func (s *Service) RefreshMosaic(ctx context.Context, playlistID int64) error {
+	sources, err := s.store.MosaicArtworkSources(ctx, playlistID, MosaicTiles)
+	if err != nil {
+		return err
+	}
+	joined := strings.Join(sources, "\n")
+	_, stored, err := s.store.GetMosaicState(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+	if stored.String == joined {
+		return nil
+	}
+
+	var images []image.Image
+	for _, source := range sources {
+		img, err := s.fetcher.Fetch(ctx, source)
+		if err != nil {
+			log.Printf("Warning: playlist %d mosaic skipped %s: %v", playlistID, source, err)
+			continue
+		}
+		images = append(images, img)
+	}
+	if len(sources) > 0 && len(images) == 0 {
+		return fmt.Errorf("none of %d track covers could be fetched", len(sources))
+	}
+
+	var key sql.NullString
+	if mosaic := Mosaic(images, CoverSize); mosaic != nil {
+		data, err := EncodeJPEG(mosaic)
+		if err != nil {
+			return err
+		}
+		put, err := putJPEG(ctx, s.storage, fmt.Sprintf("playlists/%d", playlistID), "mosaic", data)
+		if err != nil {
+			return err
+		}
+		key = sql.NullString{String: put, Valid: true}
+	}
+	previous, err := s.store.SetMosaic(ctx, playlistID, key, sql.NullString{String: joined, Valid: joined != ""})
+	if err != nil {
+		s.DeleteObjects(ctx, key.String)
+		return err
+	}
+	if previous.String != key.String {
+		s.DeleteObjects(ctx, previous.String)
+	}
+	return nil
+}
+
+// RefreshMosaicAsync runs RefreshMosaic in the background, skipping playlists
+// that already have a refresh in flight.
+func (s *Service) RefreshMosaicAsync(playlistID int64) {
+	s.mu.Lock()
+	if s.refreshing[playlistID] {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing[playlistID] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.refreshing, playlistID)
+			s.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		if err := s.RefreshMosaic(ctx, playlistID); err != nil {
+			log.Printf("Warning: failed to refresh playlist %d mosaic: %v", playlistID, err)
+		}
+	}()
+}
+
+// URL returns a presigned URL for an artwork object.
+func (s *Service) URL(ctx context.Context, key string) (string, error) {
+	return s.storage.PresignGetObject(ctx, key, URLTTL)
+}
+
+// DeleteObjects removes artwork objects, e.g. after their playlist is deleted.
+func (s *Service) DeleteObjects(ctx context.Context, keys ...string) {
+	deleteObjects(ctx, s.storage, keys...)
+}
+
+// putJPEG stores a JPEG under prefix with a fresh random name, so clients never
+// see a cached old image at the same URL.
+func putJPEG(ctx context.Context, storage Storage, prefix, kind string, data []byte) (string, error) {
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s/%s-%s.jpg", prefix, kind, hex.EncodeToString(suffix[:]))
+	if err := storage.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// deleteObjects removes objects, logging failures; an orphaned image is
+// harmless.
+func deleteObjects(ctx context.Context, storage Storage, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := storage.DeleteObject(ctx, key); err != nil {
+			log.Printf("Warning: failed to delete artwork object %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package audioprobe reads the technical facts of an audio file with
+// ffprobe: its real length, codec, bitrate, sample rate, channels, and tags.
+// Sources often report no length or a rounded one, so the length measured
+// here is what a stored track should carry.
+package audioprobe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+// DefaultTimeout bounds one probe when the caller's context has no sooner
+// deadline.
+const DefaultTimeout = 30 * time.Second
+
+// ErrNoAudioStream is returned for a file ffprobe can read but that has no
+// audio in it.
+var ErrNoAudioStream = errors.New("ffprobe found no audio stream")
+
+// Info is what ffprobe reports about a file's first audio stream and its
+// container. Zero values are facts ffprobe did not report.
+type Info struct {
+	DurationMs   int
+	Codec        string
+	BitrateKbps  int
+	SampleRateHz int
+	Channels     int
+	// FormatName is the container, such as "mov,mp4,m4a,3gp,3g2,mj2".
+	FormatName string
+	// Tags are the container's tags with lowercased names, since formats
+	// differ in case, such as TITLE in FLAC and title in MP3.
+	Tags map[string]string
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName  string `json:"codec_name"`
+		BitRate    string `json:"bit_rate"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+		Duration   string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		BitRate    string            `json:"bit_rate"`
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// Probe runs ffprobe on the file at path.
+func Probe(ctx context.Context, runner ffmpeg.Runner, path string) (Info, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	out, err := runner.FFprobe(probeCtx, []string{
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,bit_rate,sample_rate,channels,duration:format=bit_rate,format_name,duration:format_tags",
+		"-of", "json",
+		path,
+	})
+	if err != nil {
+		return Info{}, err
+	}
+	return parse(out.Stdout)
+}
+
+func parse(output string) (Info, error) {
+	var probed ffprobeOutput
+	if err := json.Unmarshal([]byte(output), &probed); err != nil {
+		return Info{}, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	if len(probed.Streams) == 0 {
+		return Info{}, ErrNoAudioStream
+	}
+	stream := probed.Streams[0]
+	sampleRate, _ := strconv.Atoi(stream.SampleRate)
+	bitRate, _ := strconv.ParseInt(stream.BitRate, 10, 64)
+	if bitRate <= 0 {
+		// Containers such as Ogg and Matroska only report an overall rate.
+		bitRate, _ = strconv.ParseInt(probed.Format.BitRate, 10, 64)
+	}
+	// The container's length covers every stream; a stream's own length is
+	// the fallback for containers without one.
+	durationMs := parseSeconds(probed.Format.Duration)
+	if durationMs <= 0 {
+		durationMs = parseSeconds(stream.Duration)
+	}
+	info := Info{
+		DurationMs:   durationMs,
+		Codec:        stream.CodecName,
+		BitrateKbps:  int((bitRate + 500) / 1000),
+		SampleRateHz: sampleRate,
+		Channels:     stream.Channels,
+		FormatName:   probed.Format.FormatName,
+		Tags:         make(map[string]string, len(probed.Format.Tags)),
+	}
+	for name, value := range probed.Format.Tags {
+		info.Tags[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	return info, nil
+}
+
+// parseSeconds converts an ffprobe length in seconds, such as "215.373061",
+// to whole milliseconds. ffprobe writes "N/A" for an unknown length.
+func parseSeconds(value string) int {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0
+	}
+	return int(math.Round(seconds * 1000))
+}
@@ -0,0 +1,51 @@
+package audioprobe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+func TestProbeReadsStreamFormatAndTags(t *testing.T) {
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		return ffmpeg.Output{Stdout: `{"streams":[{"codec_name":"flac","sample_rate":"96000","channels":2,"duration":"200.000000"}],
+			"format":{"bit_rate":"2304417","format_name":"flac","duration":"215.373061","tags":{"TITLE":" Song ","Artist":"Band"}}}`}, nil
+	}
+
+	info, err := Probe(context.Background(), runner, "/music/song.flac")
+	if err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	if info.DurationMs != 215373 || info.Codec != "flac" || info.BitrateKbps != 2304 || info.SampleRateHz != 96000 || info.Channels != 2 || info.FormatName != "flac" {
+		t.Fatalf("info = %+v", info)
+	}
+	if info.Tags["title"] != "Song" || info.Tags["artist"] != "Band" {
+		t.Fatalf("tags = %v, want lowercased names and trimmed values", info.Tags)
+	}
+	calls := runner.Calls()
+	if len(calls) != 1 || calls[0].Binary != "ffprobe" || calls[0].Args[len(calls[0].Args)-1] != "/music/song.flac" {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
+
+func TestProbeFallsBackToStreamDuration(t *testing.T) {
+	info, err := parse(`{"streams":[{"codec_name":"opus","bit_rate":"","sample_rate":"48000","channels":2,"duration":"61.5"}],"format":{"bit_rate":"128000","format_name":"ogg","duration":"N/A"}}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if info.DurationMs != 61500 || info.BitrateKbps != 128 {
+		t.Fatalf("info = %+v, want the stream length and the container bitrate", info)
+	}
+}
+
+func TestProbeRejectsFilesWithoutAudio(t *testing.T) {
+	if _, err := parse(`{"streams":[],"format":{"format_name":"png_pipe"}}`); !errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("err = %v, want ErrNoAudioStream", err)
+	}
+	if _, err := parse(`not json`); err == nil {
+		t.Fatal("parse accepted invalid output")
+	}
+}
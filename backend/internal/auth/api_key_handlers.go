@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateAPIKeyResponse carries the key itself, which is only ever returned
+// here.
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type APIKeysResponse struct {
+	Keys []APIKeyResponse `json:"keys"`
+}
+
+// CreateAPIKey handles POST /api/v1/auth/api-keys
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperrors.WriteError(w, requestID, apperrors.BadRequest("invalid request body"))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 100 {
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("name must be 1-100 characters"))
+		return
+	}
+
+	plain, key, err := h.authService.CreateAPIKey(r.Context(), userCtx.UserID, req.Name, req.Scopes)
+	switch {
+	case errors.Is(err, ErrInvalidScope):
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("scopes must be one or more of read, stream, admin"))
+	case errors.Is(err, ErrTooManyAPIKeys):
+		apperrors.WriteError(w, requestID, apperrors.Conflict("revoke an api key before creating another"))
+	case errors.Is(err, ErrAPIKeysUnavailable):
+		apperrors.WriteError(w, requestID, apiKeysUnavailable())
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to create api key").WithCause(err))
+	default:
+		apperrors.WriteJSON(w, requestID, http.StatusCreated, CreateAPIKeyResponse{APIKeyResponse: newAPIKeyResponse(*key), Key: plain})
+	}
+}
+
+// ListAPIKeys handles GET /api/v1/auth/api-keys
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(r.Context(), userCtx.UserID)
+	if errors.Is(err, ErrAPIKeysUnavailable) {
+		apperrors.WriteError(w, requestID, apiKeysUnavailable())
+		return
+	}
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to list api keys").WithCause(err))
+		return
+	}
+	resp := APIKeysResponse{Keys: make([]APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, newAPIKeyResponse(key))
+	}
+	apperrors.WriteJSON(w, requestID, http.StatusOK, resp)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/auth/api-keys/{id}
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("invalid api key id"))
+		return
+	}
+
+	err = h.authService.RevokeAPIKey(r.Context(), userCtx.UserID, id)
+	switch {
+	case errors.Is(err, db.ErrAPIKeyNotFound):
+		apperrors.WriteError(w, requestID, apperrors.NotFound("api key"))
+	case errors.Is(err, ErrAPIKeysUnavailable):
+		apperrors.WriteError(w, requestID, apiKeysUnavailable())
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to revoke api key").WithCause(err))
+	default:
+		w.Header().Set("X-Request-ID", requestID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newAPIKeyResponse(key db.APIKey) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.LastUsedAt.Valid {
+		resp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.RevokedAt.Valid {
+		resp.RevokedAt = &key.RevokedAt.Time
+	}
+	return resp
+}
+
+func apiKeysUnavailable() *apperrors.AppError {
+	return apperrors.New("SERVICE_UNAVAILABLE", "api keys are unavailable", apperrors.CategoryServer, http.StatusServiceUnavailable)
+}
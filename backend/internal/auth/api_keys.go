@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// API key scopes. A key may hold several.
+const (
+	// ScopeRead allows GET and HEAD requests outside the admin API.
+	ScopeRead = "read"
+	// ScopeStream allows issuing playback URLs and downloading tracks.
+	ScopeStream = "stream"
+	// ScopeAdmin allows the admin API, for users who are admins.
+	ScopeAdmin = "admin"
+)
+
+const (
+	// APIKeyPrefix starts every API key, which tells the middleware a bearer
+	// token is a key rather than a JWT.
+	APIKeyPrefix = "omp_"
+	// MaxActiveAPIKeys bounds how many unrevoked keys one user may hold.
+	MaxActiveAPIKeys = 25
+	// apiKeyDisplayLength is how much of a key is kept in plain text so users
+	// can recognize it.
+	apiKeyDisplayLength = 12
+)
+
+var (
+	ErrAPIKeysUnavailable = errors.New("api keys are not configured")
+	ErrInvalidScope       = errors.New("invalid api key scope")
+	ErrTooManyAPIKeys     = errors.New("too many active api keys")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+)
+
+// APIKeyStore persists API keys; db.APIKeyRepository satisfies it.
+type APIKeyStore interface {
+	Create(ctx context.Context, key *db.APIKey) error
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]db.APIKey, error)
+	CountActiveForUser(ctx context.Context, userID uuid.UUID) (int, error)
+	GetActiveByHash(ctx context.Context, keyHash string) (*db.APIKeyOwner, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+// SetAPIKeys enables API key issuance and authentication.
+func (s *Service) SetAPIKeys(store APIKeyStore) {
+	s.apiKeys = store
+}
+
+// ValidScope reports whether scope is one of the API key scopes.
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeStream || scope == ScopeAdmin
+}
+
+// CreateAPIKey issues a key for the user and returns it in plain text along
+// with its stored record. The plain key cannot be recovered later.
+func (s *Service) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string) (string, *db.APIKey, error) {
+	if s.apiKeys == nil {
+		return "", nil, ErrAPIKeysUnavailable
+	}
+	if len(scopes) == 0 {
+		return "", nil, ErrInvalidScope
+	}
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", nil, ErrInvalidScope
+		}
+		if !slices.Contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	active, err := s.apiKeys.CountActiveForUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if active >= MaxActiveAPIKeys {
+		return "", nil, ErrTooManyAPIKeys
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	plain := APIKeyPrefix + hex.EncodeToString(secret)
+	key := &db.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:apiKeyDisplayLength],
+		KeyHash:   hashToken(plain),
+		Scopes:    unique,
+		CreatedAt: time.Now(),
+	}
+	if err := s.apiKeys.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// ListAPIKeys returns the user's keys, revoked ones included.
+func (s *Service) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]db.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysUnavailable
+	}
+	return s.apiKeys.ListForUser(ctx, userID)
+}
+
+// RevokeAPIKey revokes one of the user's keys.
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	if s.apiKeys == nil {
+		return ErrAPIKeysUnavailable
+	}
+	return s.apiKeys.Revoke(ctx, userID, id)
+}
+
+// AuthenticateAPIKey returns the caller an unrevoked key belongs to.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, plain string) (*UserContext, error) {
+	if s.apiKeys == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.apiKeys.GetActiveByHash(ctx, hashToken(plain))
+	if errors.Is(err, db.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apiKeys.TouchLastUsed(ctx, key.ID); err != nil {
+		log.Printf("Warning: failed to record api key %s use: %v", key.ID, err)
+	}
+	return &UserContext{UserID: key.UserID, Email: key.Email, Role: s.roleFor(key.Email, key.Role), APIKeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// APIKeyScopeFor returns the scope an API key needs for the request, or ""
+// when keys may not make it at all: account and key management, and writes
+// outside the admin API, need a signed-in session. Routes under
+// /api/v1/admin/ and those marked with AdminRoute need the admin scope.
+func APIKeyScopeFor(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/v1/auth/"):
+		return ""
+	case strings.HasPrefix(path, "/api/v1/admin/") || isAdminRoute(r):
+		return ScopeAdmin
+	case r.Method == http.MethodPost && path == "/api/v1/playback/urls",
+		r.Method == http.MethodGet && strings.HasPrefix(path, "/api/v1/tracks/") && strings.HasSuffix(path, "/download"):
+		return ScopeStream
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return ScopeRead
+	default:
+		return ""
+	}
+}
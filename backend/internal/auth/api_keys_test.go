@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeAPIKeyStore struct {
+	keys    map[string]*db.APIKey
+	touched int
+}
+
+func (f *fakeAPIKeyStore) Create(_ context.Context, key *db.APIKey) error {
+	f.keys[key.KeyHash] = key
+	return nil
+}
+
+func (f *fakeAPIKeyStore) ListForUser(context.Context, uuid.UUID) ([]db.APIKey, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIKeyStore) CountActiveForUser(_ context.Context, userID uuid.UUID) (int, error) {
+	count := 0
+	for _, key := range f.keys {
+		if key.UserID == userID && !key.RevokedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeAPIKeyStore) GetActiveByHash(_ context.Context, keyHash string) (*db.APIKeyOwner, error) {
+	key, ok := f.keys[keyHash]
+	if !ok || key.RevokedAt.Valid {
+		return nil, db.ErrAPIKeyNotFound
+	}
+	return &db.APIKeyOwner{APIKey: *key, Email: "ops@example.test"}, nil
+}
+
+func (f *fakeAPIKeyStore) Revoke(_ context.Context, userID, id uuid.UUID) error {
+	for _, key := range f.keys {
+		if key.ID == id && key.UserID == userID {
+			key.RevokedAt.Valid = true
+			return nil
+		}
+	}
+	return db.ErrAPIKeyNotFound
+}
+
+func (f *fakeAPIKeyStore) TouchLastUsed(context.Context, uuid.UUID) error {
+	f.touched++
+	return nil
+}
+
+func TestCreateAPIKeyStoresOnlyTheHash(t *testing.T) {
+	store := &fakeAPIKeyStore{keys: map[string]*db.APIKey{}}
+	s := NewService(nil, nil, "secret")
+	s.SetAPIKeys(store)
+	userID := uuid.New()
+
+	plain, key, err := s.CreateAPIKey(context.Background(), userID, "beets", []string{ScopeRead, ScopeStream, ScopeRead})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(plain, APIKeyPrefix) || !strings.HasPrefix(plain, key.Prefix) || key.KeyHash == plain {
+		t.Fatalf("plain = %q key = %+v", plain, key)
+	}
+	if len(key.Scopes) != 2 || store.keys[hashToken(plain)] == nil {
+		t.Fatalf("stored key = %+v", key)
+	}
+
+	for _, scopes := range [][]string{nil, {"write"}} {
+		if _, _, err := s.CreateAPIKey(context.Background(), userID, "bad", scopes); !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("scopes %v: err = %v, want ErrInvalidScope", scopes, err)
+		}
+	}
+}
+
+func TestMiddlewareEnforcesAPIKeyScopes(t *testing.T) {
+	store := &fakeAPIKeyStore{keys: map[string]*db.APIKey{}}
+	s := NewService(nil, nil, "secret")
+	s.SetAPIKeys(store)
+	userID := uuid.New()
+	readKey, _, _ := s.CreateAPIKey(context.Background(), userID, "reader", []string{ScopeRead})
+	streamKey, _, _ := s.CreateAPIKey(context.Background(), userID, "player", []string{ScopeStream})
+	revokedKey, revoked, _ := s.CreateAPIKey(context.Background(), userID, "old", []string{ScopeRead})
+	if err := s.RevokeAPIKey(context.Background(), userID, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen *UserContext
+	handler := Middleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetUserFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tc := range []struct {
+		key, method, path string
+		want              int
+	}{
+		{readKey, http.MethodGet, "/api/v1/library", http.StatusOK},
+		{readKey, http.MethodPost, "/api/v1/playlists", http.StatusForbidden},
+		{readKey, http.MethodGet, "/api/v1/auth/api-keys", http.StatusForbidden},
+		{readKey, http.MethodGet, "/api/v1/admin/retention", http.StatusForbidden},
+		{readKey, http.MethodGet, "/api/v1/tracks/7/download", http.StatusForbidden},
+		{streamKey, http.MethodGet, "/api/v1/tracks/7/download", http.StatusOK},
+		{streamKey, http.MethodPost, "/api/v1/playback/urls", http.StatusOK},
+		{streamKey, http.MethodGet, "/api/v1/library", http.StatusForbidden},
+		{revokedKey, http.MethodGet, "/api/v1/library", http.StatusUnauthorized},
+		{APIKeyPrefix + "unknown", http.MethodGet, "/api/v1/library", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+	if seen == nil || seen.UserID != userID || seen.HasScope(ScopeAdmin) || !seen.HasScope(ScopeStream) {
+		t.Fatalf("user context = %+v", seen)
+	}
+	if !(&UserContext{}).HasScope(ScopeAdmin) {
+		t.Fatal("signed-in sessions should hold every scope")
+	}
+}
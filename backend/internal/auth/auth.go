@@ -25,11 +25,17 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
+	// ErrTokenReused means a refresh token was presented after it had been
+	// rotated out; its session has been revoked.
+	ErrTokenReused = errors.New("refresh token reused")
 )
 
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	// SessionID names the session the token was issued to.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -44,14 +50,18 @@ type UserInfo struct {
 	ID        string    `json:"id"`
 	Email     string    `json:"email"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt,omitempty"`
 }
 
 type Service struct {
-	userRepo  *db.UserRepository
-	tokenRepo *db.TokenRepository
-	jwtSecret []byte
+	userRepo    *db.UserRepository
+	tokenRepo   TokenStore
+	jwtSecret   []byte
+	apiKeys     APIKeyStore
+	adminEmails map[string]bool
+	oidc        *oidcLogin
 }
 
 func NewService(userRepo *db.UserRepository, tokenRepo *db.TokenRepository, jwtSecret string) *Service {
@@ -113,15 +123,24 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthRespon
 	}
 
 	if storedToken.Revoked {
-		return nil, ErrInvalidToken
+		return nil, s.revokeReusedSession(ctx, storedToken)
 	}
 
 	if time.Now().After(storedToken.ExpiresAt) {
 		return nil, ErrTokenExpired
 	}
 
-	// Revoke the old token (rotation)
-	if err := s.tokenRepo.Revoke(ctx, storedToken.ID); err != nil {
+	// Revoke the old token (rotation). Losing a race with another refresh of
+	// the same token counts as reuse.
+	if err := s.tokenRepo.Consume(ctx, storedToken.ID); err != nil {
+		if errors.Is(err, db.ErrTokenRevoked) {
+			return nil, s.revokeReusedSession(ctx, storedToken)
+		}
+		return nil, err
+	}
+
+	sessionID, err := s.continueSession(ctx, storedToken)
+	if err != nil {
 		return nil, err
 	}
 
@@ -130,7 +149,7 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthRespon
 		return nil, err
 	}
 
-	return s.generateTokens(ctx, user)
+	return s.issueTokens(ctx, user, sessionID)
 }
 
 func (s *Service) Logout(ctx context.Context, userID uuid.UUID) error {
@@ -164,15 +183,24 @@ func (s *Service) GetUserByID(ctx context.Context, id uuid.UUID) (*db.User, erro
 	return s.userRepo.GetByID(ctx, id)
 }
 
+// generateTokens signs the user in on a new session.
 func (s *Service) generateTokens(ctx context.Context, user *db.User) (*AuthResponse, error) {
+	sessionID, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user, sessionID)
+}
+
+func (s *Service) issueTokens(ctx context.Context, user *db.User, sessionID uuid.UUID) (*AuthResponse, error) {
 	// Generate access token
-	accessToken, err := s.generateAccessToken(user)
+	accessToken, err := s.generateAccessToken(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate refresh token
-	refreshToken, err := s.generateRefreshToken(ctx, user.ID)
+	refreshToken, err := s.generateRefreshToken(ctx, user.ID, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -185,16 +213,19 @@ func (s *Service) generateTokens(ctx context.Context, user *db.User) (*AuthRespo
 			ID:        user.ID.String(),
 			Email:     user.Email,
 			Username:  user.Username,
+			Role:      s.roleFor(user.Email, user.Role),
 			CreatedAt: user.CreatedAt,
 			UpdatedAt: user.UpdatedAt,
 		},
 	}, nil
 }
 
-func (s *Service) generateAccessToken(user *db.User) (string, error) {
+func (s *Service) generateAccessToken(user *db.User, sessionID uuid.UUID) (string, error) {
 	claims := &Claims{
-		UserID: user.ID.String(),
-		Email:  user.Email,
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		Role:      s.roleFor(user.Email, user.Role),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -206,7 +237,7 @@ func (s *Service) generateAccessToken(user *db.User) (string, error) {
 	return token.SignedString(s.jwtSecret)
 }
 
-func (s *Service) generateRefreshToken(ctx context.Context, userID uuid.UUID) (string, error) {
+func (s *Service) generateRefreshToken(ctx context.Context, userID, sessionID uuid.UUID) (string, error) {
 	// Generate secure random token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -219,6 +250,7 @@ func (s *Service) generateRefreshToken(ctx context.Context, userID uuid.UUID) (s
 	refreshToken := &db.RefreshToken{
 		ID:        uuid.New(),
 		UserID:    userID,
+		SessionID: uuid.NullUUID{UUID: sessionID, Valid: true},
 		TokenHash: tokenHash,
 		ExpiresAt: time.Now().Add(RefreshTokenExpiry),
 		CreatedAt: time.Now(),
@@ -16,11 +16,14 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Username string `json:"username"`
+	// DeviceName optionally labels the session in the sessions list.
+	DeviceName string `json:"deviceName,omitempty"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"deviceName,omitempty"`
 }
 
 type RefreshRequest struct {
@@ -54,7 +57,8 @@ func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Username)
+	ctx := WithClientInfo(r.Context(), ClientInfoFromRequest(r, req.DeviceName))
+	resp, err := h.authService.Register(ctx, req.Email, req.Password, req.Username)
 	if err != nil {
 		if errors.Is(err, db.ErrEmailExists) {
 			apperrors.WriteError(w, requestID, apperrors.EmailExists())
@@ -81,7 +85,8 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	ctx := WithClientInfo(r.Context(), ClientInfoFromRequest(r, req.DeviceName))
+	resp, err := h.authService.Login(ctx, req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
 			apperrors.WriteError(w, requestID, apperrors.InvalidCredentials())
@@ -108,8 +113,13 @@ func (h *Handlers) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	ctx := WithClientInfo(r.Context(), ClientInfoFromRequest(r, ""))
+	resp, err := h.authService.Refresh(ctx, req.RefreshToken)
 	if err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			apperrors.WriteError(w, requestID, apperrors.InvalidToken("refresh token was already used; the session has been signed out"))
+			return
+		}
 		if errors.Is(err, ErrInvalidToken) {
 			apperrors.WriteError(w, requestID, apperrors.InvalidToken("invalid or expired refresh token"))
 			return
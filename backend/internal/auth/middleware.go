@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -15,6 +16,29 @@ const UserContextKey contextKey = "user"
 type UserContext struct {
 	UserID uuid.UUID
 	Email  string
+	// Role is the caller's role when the request was authenticated; access
+	// tokens carry it, so a changed role applies from the next refresh.
+	Role string
+	// APIKeyID and Scopes are set when the request authenticated with an
+	// API key; signed-in sessions leave Scopes nil and hold every scope.
+	APIKeyID uuid.UUID
+	Scopes   []string
+	// SessionID is the signed-in session behind an access token; API keys
+	// leave it nil.
+	SessionID uuid.UUID
+}
+
+// HasScope reports whether the caller may act with scope.
+func (u *UserContext) HasScope(scope string) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 func Middleware(authService *Service) func(http.Handler) http.Handler {
@@ -22,36 +46,59 @@ func Middleware(authService *Service) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, `{"code":"UNAUTHORIZED","message":"missing authorization header"}`, http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing authorization header")
 				return
 			}
 
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				http.Error(w, `{"code":"UNAUTHORIZED","message":"invalid authorization header format"}`, http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid authorization header format")
 				return
 			}
 
 			tokenString := parts[1]
+			if strings.HasPrefix(tokenString, APIKeyPrefix) {
+				userCtx, err := authService.AuthenticateAPIKey(r.Context(), tokenString)
+				if err != nil {
+					if err == ErrInvalidAPIKey {
+						writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid api key")
+						return
+					}
+					writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify api key")
+					return
+				}
+				if scope := APIKeyScopeFor(r); scope == "" || !userCtx.HasScope(scope) {
+					writeError(w, http.StatusForbidden, "INSUFFICIENT_SCOPE", "api key is not allowed to make this request")
+					return
+				}
+				ctx := context.WithValue(r.Context(), UserContextKey, userCtx)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			claims, err := authService.ValidateAccessToken(tokenString)
 			if err != nil {
 				if err == ErrTokenExpired {
-					http.Error(w, `{"code":"TOKEN_EXPIRED","message":"access token has expired"}`, http.StatusUnauthorized)
+					writeError(w, http.StatusUnauthorized, "TOKEN_EXPIRED", "access token has expired")
 					return
 				}
-				http.Error(w, `{"code":"UNAUTHORIZED","message":"invalid access token"}`, http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid access token")
 				return
 			}
 
 			userID, err := uuid.Parse(claims.UserID)
 			if err != nil {
-				http.Error(w, `{"code":"UNAUTHORIZED","message":"invalid user ID in token"}`, http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid user ID in token")
 				return
 			}
 
 			userCtx := &UserContext{
 				UserID: userID,
 				Email:  claims.Email,
+				Role:   claims.Role,
+			}
+			if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+				userCtx.SessionID = sessionID
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, userCtx)
@@ -67,3 +114,10 @@ func GetUserFromContext(ctx context.Context) *UserContext {
 	}
 	return user
 }
+
+// writeError writes an error in the API's {code, message} JSON shape.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
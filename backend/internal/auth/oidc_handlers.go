@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+)
+
+type OIDCStatusResponse struct {
+	Enabled   bool   `json:"enabled"`
+	LoginPath string `json:"loginPath,omitempty"`
+}
+
+type OIDCExchangeRequest struct {
+	Code string `json:"code"`
+}
+
+// OIDCStatus handles GET /api/v1/auth/oidc, telling clients whether to offer
+// single sign-on.
+func (h *Handlers) OIDCStatus(w http.ResponseWriter, r *http.Request) {
+	resp := OIDCStatusResponse{Enabled: h.authService.OIDCEnabled()}
+	if resp.Enabled {
+		resp.LoginPath = "/api/v1/auth/oidc/login"
+	}
+	apperrors.WriteJSON(w, apperrors.GetRequestID(r.Context()), http.StatusOK, resp)
+}
+
+// OIDCLogin handles GET /api/v1/auth/oidc/login by redirecting the browser to
+// the provider.
+func (h *Handlers) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	authURL, err := h.authService.StartOIDCLogin(r.Context())
+	switch {
+	case errors.Is(err, ErrOIDCDisabled):
+		apperrors.WriteError(w, requestID, oidcUnavailable())
+	case errors.Is(err, ErrOIDCProvider):
+		apperrors.WriteError(w, requestID, oidcProviderError().WithCause(err))
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to start single sign-on").WithCause(err))
+	default:
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// OIDCCallback handles GET /api/v1/auth/oidc/callback, where the provider
+// returns the browser. With an app redirect configured the browser goes on
+// there with a one-time code (or an error); otherwise the tokens are the
+// response.
+func (h *Handlers) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	if !h.authService.OIDCEnabled() {
+		apperrors.WriteError(w, requestID, oidcUnavailable())
+		return
+	}
+	query := r.URL.Query()
+	appURL := h.authService.OIDCAppRedirectURL()
+	fail := func(code string, appErr *apperrors.AppError) {
+		if appURL != "" {
+			redirectToApp(w, r, appURL, "error", code)
+			return
+		}
+		apperrors.WriteError(w, requestID, appErr)
+	}
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		fail("access_denied", apperrors.Unauthorized("single sign-on was cancelled or denied"))
+		return
+	}
+
+	ctx := WithClientInfo(r.Context(), ClientInfoFromRequest(r, ""))
+	user, err := h.authService.FinishOIDCLogin(ctx, query.Get("code"), query.Get("state"))
+	switch {
+	case errors.Is(err, ErrOIDCInvalidState):
+		fail("invalid_login", apperrors.BadRequest("single sign-on login is invalid or expired; start again"))
+		return
+	case errors.Is(err, ErrOIDCEmailUnverified):
+		fail("email_unverified", apperrors.Forbidden("the provider did not share a verified email"))
+		return
+	case errors.Is(err, ErrOIDCNoAccount):
+		fail("no_account", apperrors.Forbidden("no account matches this sign-in; ask an admin to create one"))
+		return
+	case errors.Is(err, ErrOIDCInvalidToken):
+		log.Printf("Warning: rejected oidc id token: %v", err)
+		fail("invalid_login", apperrors.Unauthorized("the provider's identity token was rejected"))
+		return
+	case errors.Is(err, ErrOIDCProvider):
+		log.Printf("Warning: oidc provider request failed: %v", err)
+		fail("provider_error", oidcProviderError())
+		return
+	case err != nil:
+		log.Printf("Warning: oidc login failed: %v", err)
+		fail("server_error", apperrors.InternalError("single sign-on failed"))
+		return
+	}
+
+	if appURL != "" {
+		code, err := h.authService.CreateOIDCHandoff(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Warning: oidc handoff failed: %v", err)
+			redirectToApp(w, r, appURL, "error", "server_error")
+			return
+		}
+		redirectToApp(w, r, appURL, "code", code)
+		return
+	}
+	resp, err := h.authService.OIDCTokens(ctx, user)
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.InternalError("single sign-on failed").WithCause(err))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	apperrors.WriteJSON(w, requestID, http.StatusOK, resp)
+}
+
+// OIDCExchange handles POST /api/v1/auth/oidc/exchange, trading the code from
+// the app redirect for tokens.
+func (h *Handlers) OIDCExchange(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+
+	var req OIDCExchangeRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperrors.WriteError(w, requestID, apperrors.BadRequest("invalid request body"))
+		return
+	}
+	if req.Code == "" {
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("code is required"))
+		return
+	}
+
+	ctx := WithClientInfo(r.Context(), ClientInfoFromRequest(r, ""))
+	resp, err := h.authService.ExchangeOIDCHandoff(ctx, req.Code)
+	switch {
+	case errors.Is(err, ErrOIDCDisabled):
+		apperrors.WriteError(w, requestID, oidcUnavailable())
+	case errors.Is(err, ErrInvalidToken):
+		apperrors.WriteError(w, requestID, apperrors.InvalidToken("invalid or expired code"))
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("single sign-on failed").WithCause(err))
+	default:
+		w.Header().Set("Cache-Control", "no-store")
+		apperrors.WriteJSON(w, requestID, http.StatusOK, resp)
+	}
+}
+
+// redirectToApp sends the browser to the configured app URL with one query
+// parameter added.
+func redirectToApp(w http.ResponseWriter, r *http.Request, appURL, key, value string) {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		apperrors.WriteError(w, apperrors.GetRequestID(r.Context()), apperrors.InternalError("invalid single sign-on app redirect"))
+		return
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func oidcUnavailable() *apperrors.AppError {
+	return apperrors.New("SERVICE_UNAVAILABLE", "single sign-on is not configured", apperrors.CategoryServer, http.StatusServiceUnavailable)
+}
+
+func oidcProviderError() *apperrors.AppError {
+	return apperrors.New("SSO_PROVIDER_ERROR", "the single sign-on provider could not be reached", apperrors.CategoryExternal, http.StatusBadGateway)
+}
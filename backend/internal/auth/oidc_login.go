@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	// oidcLoginTTL bounds how long a user may spend at the provider.
+	oidcLoginTTL = 10 * time.Minute
+	// oidcHandoffTTL bounds how long a client has to trade the callback's
+	// one-time code for tokens.
+	oidcHandoffTTL    = time.Minute
+	maxUsernameLength = 50
+)
+
+var (
+	ErrOIDCDisabled        = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState    = errors.New("invalid or expired oidc login")
+	ErrOIDCEmailUnverified = errors.New("oidc email is missing or unverified")
+	ErrOIDCNoAccount       = errors.New("no account for oidc identity")
+)
+
+// OIDCStore persists single sign-on state; db.OIDCRepository satisfies it.
+type OIDCStore interface {
+	SaveLoginState(ctx context.Context, state db.OIDCLoginState) error
+	TakeLoginState(ctx context.Context, stateHash string) (*db.OIDCLoginState, error)
+	UserIDForIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) error
+	SaveHandoff(ctx context.Context, codeHash string, userID uuid.UUID, expiresAt time.Time) error
+	TakeHandoff(ctx context.Context, codeHash string) (uuid.UUID, error)
+}
+
+// oidcUserStore is the part of db.UserRepository single sign-on uses.
+type oidcUserStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*db.User, error)
+	GetByEmail(ctx context.Context, email string) (*db.User, error)
+	Create(ctx context.Context, user *db.User) error
+}
+
+type oidcLogin struct {
+	provider *oidcProvider
+	store    OIDCStore
+	users    oidcUserStore
+	issue    func(ctx context.Context, user *db.User) (*AuthResponse, error)
+}
+
+// EnableOIDC turns on single sign-on with the given provider.
+func (s *Service) EnableOIDC(cfg OIDCConfig, store OIDCStore) {
+	s.oidc = &oidcLogin{
+		provider: newOIDCProvider(cfg),
+		store:    store,
+		users:    s.userRepo,
+		issue:    s.generateTokens,
+	}
+}
+
+// OIDCEnabled reports whether single sign-on is configured.
+func (s *Service) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// OIDCAppRedirectURL is where the callback hands logins back to the client,
+// or "" to answer the callback with tokens directly.
+func (s *Service) OIDCAppRedirectURL() string {
+	if s.oidc == nil {
+		return ""
+	}
+	return s.oidc.provider.cfg.AppRedirectURL
+}
+
+// StartOIDCLogin records a new login and returns the provider URL to send the
+// browser to.
+func (s *Service) StartOIDCLogin(ctx context.Context) (string, error) {
+	if s.oidc == nil {
+		return "", ErrOIDCDisabled
+	}
+	state, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := s.oidc.provider.authorizationURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", err
+	}
+	err = s.oidc.store.SaveLoginState(ctx, db.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// FinishOIDCLogin completes the provider callback and returns the local user
+// it signs in, linking or creating the account as needed.
+func (s *Service) FinishOIDCLogin(ctx context.Context, code, state string) (*db.User, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+	if code == "" || state == "" {
+		return nil, ErrOIDCInvalidState
+	}
+	login, err := s.oidc.store.TakeLoginState(ctx, hashToken(state))
+	if errors.Is(err, db.ErrOIDCStateNotFound) {
+		return nil, ErrOIDCInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.oidc.provider.exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.oidc.provider.verify(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.oidc.resolveUser(ctx, claims)
+}
+
+// OIDCTokens issues tokens for a user signed in through single sign-on.
+func (s *Service) OIDCTokens(ctx context.Context, user *db.User) (*AuthResponse, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+	return s.oidc.issue(ctx, user)
+}
+
+// CreateOIDCHandoff returns a one-time code the client trades for the user's
+// tokens with ExchangeOIDCHandoff.
+func (s *Service) CreateOIDCHandoff(ctx context.Context, userID uuid.UUID) (string, error) {
+	if s.oidc == nil {
+		return "", ErrOIDCDisabled
+	}
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.oidc.store.SaveHandoff(ctx, hashToken(code), userID, time.Now().Add(oidcHandoffTTL)); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeOIDCHandoff trades a handoff code for tokens. Each code works once.
+func (s *Service) ExchangeOIDCHandoff(ctx context.Context, code string) (*AuthResponse, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+	userID, err := s.oidc.store.TakeHandoff(ctx, hashToken(code))
+	if errors.Is(err, db.ErrOIDCHandoffNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.oidc.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.oidc.issue(ctx, user)
+}
+
+// resolveUser finds the local account for a provider identity. A linked
+// identity wins; otherwise a verified email links to the account with that
+// email, or creates one when auto-registration is on.
+func (l *oidcLogin) resolveUser(ctx context.Context, claims *oidcClaims) (*db.User, error) {
+	issuer := claims.Issuer
+	userID, err := l.store.UserIDForIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		return l.users.GetByID(ctx, userID)
+	}
+	if !errors.Is(err, db.ErrOIDCIdentityNotFound) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !bool(claims.EmailVerified) {
+		return nil, ErrOIDCEmailUnverified
+	}
+	user, err := l.userByEmail(ctx, email)
+	if errors.Is(err, db.ErrUserNotFound) {
+		if !l.provider.cfg.AutoRegister {
+			return nil, ErrOIDCNoAccount
+		}
+		user, err = l.register(ctx, email, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := l.store.LinkIdentity(ctx, user.ID, issuer, claims.Subject, email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// userByEmail matches the email as given, then lowercased, since accounts
+// registered locally keep the case the user typed.
+func (l *oidcLogin) userByEmail(ctx context.Context, email string) (*db.User, error) {
+	user, err := l.users.GetByEmail(ctx, email)
+	if errors.Is(err, db.ErrUserNotFound) && strings.ToLower(email) != email {
+		user, err = l.users.GetByEmail(ctx, strings.ToLower(email))
+	}
+	return user, err
+}
+
+// register creates a single sign-on account. It has no password, so it can
+// only sign in through the provider.
+func (l *oidcLogin) register(ctx context.Context, email string, claims *oidcClaims) (*db.User, error) {
+	now := time.Now()
+	user := &db.User{
+		ID:        uuid.New(),
+		Email:     strings.ToLower(email),
+		Username:  oidcUsername(email, claims),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := l.users.Create(ctx, user)
+	if errors.Is(err, db.ErrEmailExists) {
+		// A concurrent login registered the same email first.
+		return l.userByEmail(ctx, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.Role = RoleMember
+	return user, nil
+}
+
+// oidcUsername picks the provider's preferred username, then display name,
+// then the email's local part, cut to the username column's length.
+func oidcUsername(email string, claims *oidcClaims) string {
+	name := strings.TrimSpace(claims.PreferredUsername)
+	if name == "" {
+		name = strings.TrimSpace(claims.Name)
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	return truncateRunes(name, maxUsernameLength)
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
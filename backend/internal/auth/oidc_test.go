@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// fakeOIDCProvider serves discovery, JWKS, and a token endpoint that issues
+// an ID token for whatever login the test last authorized.
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeOIDCProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if id, secret, _ := r.BasicAuth(); id != "omp" || secret != "shh" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "good-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(p.idClaims())})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) idClaims() jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "omp",
+		"sub":   "user-123",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": p.nonce,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	return claims
+}
+
+func (p *fakeOIDCProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// authorize records what the login URL asked for, as the provider would, and
+// returns the state to call back with.
+func (p *fakeOIDCProvider) authorize(t *testing.T, location string) string {
+	t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if !strings.HasPrefix(location, p.server.URL+"/authorize") || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "openid email profile" {
+		t.Fatalf("authorization url = %s", location)
+	}
+	p.mu.Lock()
+	p.challenge = q.Get("code_challenge")
+	p.nonce = q.Get("nonce")
+	p.mu.Unlock()
+	return q.Get("state")
+}
+
+type fakeOIDCStore struct {
+	states     map[string]db.OIDCLoginState
+	identities map[string]uuid.UUID
+	handoffs   map[string]uuid.UUID
+}
+
+func newFakeOIDCStore() *fakeOIDCStore {
+	return &fakeOIDCStore{states: map[string]db.OIDCLoginState{}, identities: map[string]uuid.UUID{}, handoffs: map[string]uuid.UUID{}}
+}
+
+func (f *fakeOIDCStore) SaveLoginState(ctx context.Context, state db.OIDCLoginState) error {
+	f.states[state.StateHash] = state
+	return nil
+}
+
+func (f *fakeOIDCStore) TakeLoginState(ctx context.Context, stateHash string) (*db.OIDCLoginState, error) {
+	state, ok := f.states[stateHash]
+	if !ok {
+		return nil, db.ErrOIDCStateNotFound
+	}
+	delete(f.states, stateHash)
+	return &state, nil
+}
+
+func (f *fakeOIDCStore) UserIDForIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	id, ok := f.identities[issuer+"|"+subject]
+	if !ok {
+		return uuid.Nil, db.ErrOIDCIdentityNotFound
+	}
+	return id, nil
+}
+
+func (f *fakeOIDCStore) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) error {
+	f.identities[issuer+"|"+subject] = userID
+	return nil
+}
+
+func (f *fakeOIDCStore) SaveHandoff(ctx context.Context, codeHash string, userID uuid.UUID, expiresAt time.Time) error {
+	f.handoffs[codeHash] = userID
+	return nil
+}
+
+func (f *fakeOIDCStore) TakeHandoff(ctx context.Context, codeHash string) (uuid.UUID, error) {
+	id, ok := f.handoffs[codeHash]
+	if !ok {
+		return uuid.Nil, db.ErrOIDCHandoffNotFound
+	}
+	delete(f.handoffs, codeHash)
+	return id, nil
+}
+
+type fakeOIDCUsers struct {
+	users []*db.User
+}
+
+func (f *fakeOIDCUsers) GetByID(ctx context.Context, id uuid.UUID) (*db.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, db.ErrUserNotFound
+}
+
+func (f *fakeOIDCUsers) GetByEmail(ctx context.Context, email string) (*db.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, db.ErrUserNotFound
+}
+
+func (f *fakeOIDCUsers) Create(ctx context.Context, user *db.User) error {
+	f.users = append(f.users, user)
+	return nil
+}
+
+func newOIDCTestService(provider *fakeOIDCProvider, users *fakeOIDCUsers, store *fakeOIDCStore, cfg OIDCConfig) *Service {
+	cfg.IssuerURL = provider.server.URL
+	cfg.ClientID = "omp"
+	cfg.ClientSecret = "shh"
+	cfg.RedirectURL = "https://music.example.test/api/v1/auth/oidc/callback"
+	s := NewService(nil, nil, "test-secret")
+	s.EnableOIDC(cfg, store)
+	s.oidc.users = users
+	s.oidc.issue = func(ctx context.Context, user *db.User) (*AuthResponse, error) {
+		return &AuthResponse{AccessToken: "token-for-" + user.ID.String(), User: &UserInfo{ID: user.ID.String(), Email: user.Email, Username: user.Username}}, nil
+	}
+	return s
+}
+
+// startOIDCLogin runs the login redirect and returns the state the provider
+// would call back with.
+func startOIDCLogin(t *testing.T, h *Handlers, provider *fakeOIDCProvider) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	return provider.authorize(t, rec.Header().Get("Location"))
+}
+
+func oidcCallback(h *Handlers, code, state string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.OIDCCallback(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code="+code+"&state="+url.QueryEscape(state), nil))
+	return rec
+}
+
+func TestOIDCLoginRegistersAndLinksNewUser(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"email": "New.Listener@Example.test", "email_verified": true, "preferred_username": "newlistener"}
+	users, store := &fakeOIDCUsers{}, newFakeOIDCStore()
+	h := NewHandlers(newOIDCTestService(provider, users, store, OIDCConfig{AutoRegister: true}))
+
+	state := startOIDCLogin(t, h, provider)
+	rec := oidcCallback(h, "good-code", state)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp AuthResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(users.users) != 1 || resp.User == nil || resp.User.ID != users.users[0].ID.String() {
+		t.Fatalf("users = %+v, resp = %+v", users.users, resp)
+	}
+	created := users.users[0]
+	if created.Email != "new.listener@example.test" || created.Username != "newlistener" || created.PasswordHash != "" {
+		t.Fatalf("created user = %+v", created)
+	}
+	if store.identities[provider.server.URL+"|user-123"] != created.ID {
+		t.Fatalf("identity not linked: %+v", store.identities)
+	}
+
+	// A state completes one login only.
+	if rec := oidcCallback(h, "good-code", state); rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback status = %d", rec.Code)
+	}
+
+	// The linked identity signs in to the same account even if its email changes.
+	provider.claims["email"] = "renamed@example.test"
+	state = startOIDCLogin(t, h, provider)
+	if rec := oidcCallback(h, "good-code", state); rec.Code != http.StatusOK || len(users.users) != 1 {
+		t.Fatalf("second login status = %d, users = %d", rec.Code, len(users.users))
+	}
+}
+
+func TestOIDCLoginLinksExistingAccountByVerifiedEmail(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	existing := &db.User{ID: uuid.New(), Email: "dj@example.test", Username: "dj"}
+	users, store := &fakeOIDCUsers{users: []*db.User{existing}}, newFakeOIDCStore()
+	h := NewHandlers(newOIDCTestService(provider, users, store, OIDCConfig{}))
+
+	provider.claims = jwt.MapClaims{"email": "DJ@example.test", "email_verified": "false"}
+	if rec := oidcCallback(h, "good-code", startOIDCLogin(t, h, provider)); rec.Code != http.StatusForbidden {
+		t.Fatalf("unverified status = %d, want 403", rec.Code)
+	}
+
+	provider.claims = jwt.MapClaims{"email": "DJ@example.test", "email_verified": "true"}
+	rec := oidcCallback(h, "good-code", startOIDCLogin(t, h, provider))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if store.identities[provider.server.URL+"|user-123"] != existing.ID || len(users.users) != 1 {
+		t.Fatalf("identities = %+v, users = %d", store.identities, len(users.users))
+	}
+
+	provider.claims = jwt.MapClaims{"sub": "someone-else", "email": "stranger@example.test", "email_verified": true}
+	if rec := oidcCallback(h, "good-code", startOIDCLogin(t, h, provider)); rec.Code != http.StatusForbidden {
+		t.Fatalf("unknown email without auto-register status = %d, want 403", rec.Code)
+	}
+}
+
+func TestOIDCLoginRejectsBadIDTokens(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"wrong audience": {"aud": "someone-else"},
+		"wrong issuer":   {"iss": "https://evil.example.test"},
+		"wrong nonce":    {"nonce": "replayed"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider := newFakeOIDCProvider(t)
+			provider.claims = jwt.MapClaims{"email": "a@example.test", "email_verified": true}
+			for k, v := range claims {
+				provider.claims[k] = v
+			}
+			users := &fakeOIDCUsers{}
+			h := NewHandlers(newOIDCTestService(provider, users, newFakeOIDCStore(), OIDCConfig{AutoRegister: true}))
+
+			rec := oidcCallback(h, "good-code", startOIDCLogin(t, h, provider))
+			if rec.Code != http.StatusUnauthorized || len(users.users) != 0 {
+				t.Fatalf("status = %d, users = %d, body = %s", rec.Code, len(users.users), rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackHandsOffToAppRedirect(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"email": "a@example.test", "email_verified": true}
+	users := &fakeOIDCUsers{}
+	s := newOIDCTestService(provider, users, newFakeOIDCStore(), OIDCConfig{AutoRegister: true, AppRedirectURL: "omp://sso?from=web"})
+	h := NewHandlers(s)
+
+	rec := oidcCallback(h, "bad-code", startOIDCLogin(t, h, provider))
+	if rec.Code != http.StatusFound || !strings.Contains(rec.Header().Get("Location"), "error=provider_error") {
+		t.Fatalf("failed exchange: status = %d, location = %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = oidcCallback(h, "good-code", startOIDCLogin(t, h, provider))
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || err != nil || location.Scheme != "omp" || location.Query().Get("from") != "web" {
+		t.Fatalf("status = %d, location = %s", rec.Code, rec.Header().Get("Location"))
+	}
+	code := location.Query().Get("code")
+
+	exchange := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.OIDCExchange(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/oidc/exchange", strings.NewReader(`{"code":"`+code+`"}`)))
+		return rec
+	}
+	rec = exchange()
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "token-for-"+users.users[0].ID.String()) {
+		t.Fatalf("exchange status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := exchange(); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reused code status = %d, want 401", rec.Code)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	h := NewHandlers(NewService(nil, nil, "test-secret"))
+
+	rec := httptest.NewRecorder()
+	h.OIDCStatus(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc", nil))
+	if !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Fatalf("status body = %s", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("login status = %d, want 503", rec.Code)
+	}
+}
+
+func TestOIDCUsername(t *testing.T) {
+	long := strings.Repeat("é", 60)
+	for _, tc := range []struct {
+		claims oidcClaims
+		want   string
+	}{
+		{oidcClaims{PreferredUsername: "dj", Name: "DJ Shadow"}, "dj"},
+		{oidcClaims{Name: "DJ Shadow"}, "DJ Shadow"},
+		{oidcClaims{}, "listener"},
+		{oidcClaims{PreferredUsername: long}, strings.Repeat("é", 50)},
+	} {
+		if got := oidcUsername("listener@example.test", &tc.claims); got != tc.want {
+			t.Errorf("oidcUsername(%+v) = %q, want %q", tc.claims, got, tc.want)
+		}
+	}
+}
+
+func TestOIDCProviderRefusesIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://elsewhere.example.test",
+			"authorization_endpoint": "https://elsewhere.example.test/authorize",
+			"token_endpoint":         "https://elsewhere.example.test/token",
+			"jwks_uri":               "https://elsewhere.example.test/jwks",
+		})
+	}))
+	defer server.Close()
+
+	p := newOIDCProvider(OIDCConfig{IssuerURL: server.URL, ClientID: "omp"})
+	if _, err := p.discover(context.Background()); !errors.Is(err, ErrOIDCProvider) {
+		t.Fatalf("discover err = %v, want provider error", err)
+	}
+}
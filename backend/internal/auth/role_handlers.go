@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+)
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type AdminUserResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AdminUsersResponse struct {
+	Users  []AdminUserResponse `json:"users"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// ListUsers handles GET /api/v1/admin/users. The router only lets admins
+// through.
+func (h *Handlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	limit, offset := 50, 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 200 {
+			apperrors.WriteError(w, requestID, apperrors.ValidationError("limit must be 1-200"))
+			return
+		}
+		limit = n
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			apperrors.WriteError(w, requestID, apperrors.ValidationError("offset must be a non-negative integer"))
+			return
+		}
+		offset = n
+	}
+
+	users, total, err := h.authService.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to list users").WithCause(err))
+		return
+	}
+	resp := AdminUsersResponse{Users: make([]AdminUserResponse, 0, len(users)), Total: total, Limit: limit, Offset: offset}
+	for _, user := range users {
+		resp.Users = append(resp.Users, newAdminUserResponse(&user))
+	}
+	apperrors.WriteJSON(w, requestID, http.StatusOK, resp)
+}
+
+// UpdateUserRole handles PUT /api/v1/admin/users/{id}/role. The new role
+// applies to the user's sessions from their next token refresh.
+func (h *Handlers) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("invalid user id"))
+		return
+	}
+	var req UpdateUserRoleRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperrors.WriteError(w, requestID, apperrors.BadRequest("invalid request body"))
+		return
+	}
+
+	user, err := h.authService.SetUserRole(r.Context(), GetUserFromContext(r.Context()), id, req.Role)
+	switch {
+	case errors.Is(err, ErrInvalidRole):
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("role must be admin or member"))
+	case errors.Is(err, ErrOwnRole):
+		apperrors.WriteError(w, requestID, apperrors.Conflict("admins cannot change their own role"))
+	case errors.Is(err, db.ErrUserNotFound):
+		apperrors.WriteError(w, requestID, apperrors.NotFound("user"))
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to update role").WithCause(err))
+	default:
+		apperrors.WriteJSON(w, requestID, http.StatusOK, newAdminUserResponse(user))
+	}
+}
+
+func newAdminUserResponse(user *db.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// User roles. Admins may use the admin API; members may not.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrOwnRole     = errors.New("cannot change own role")
+)
+
+// ValidRole reports whether role is one of the user roles.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember
+}
+
+// SetAdminEmails makes the accounts with these emails admins whatever their
+// stored role, so a fresh install has an admin to grant the role to others.
+func (s *Service) SetAdminEmails(emails []string) {
+	s.adminEmails = make(map[string]bool, len(emails))
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			s.adminEmails[email] = true
+		}
+	}
+}
+
+// roleFor returns the role a user acts with.
+func (s *Service) roleFor(email, stored string) string {
+	if s.adminEmails[strings.ToLower(email)] || stored == RoleAdmin {
+		return RoleAdmin
+	}
+	return RoleMember
+}
+
+// IsAdmin reports whether the caller holds the admin role and, when using an
+// API key, its admin scope.
+func (u *UserContext) IsAdmin() bool {
+	return u != nil && u.Role == RoleAdmin && u.HasScope(ScopeAdmin)
+}
+
+type adminRouteKey struct{}
+
+// AdminRoute marks r as made to an admin-only route, so an API key needs the
+// admin scope to make it wherever the route lives. Mark the request before
+// Middleware sees it.
+func AdminRoute(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminRouteKey{}, true))
+}
+
+func isAdminRoute(r *http.Request) bool {
+	marked, _ := r.Context().Value(adminRouteKey{}).(bool)
+	return marked
+}
+
+// RequireAdmin wraps a handler behind Middleware so only admins reach it.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !GetUserFromContext(r.Context()).IsAdmin() {
+			writeError(w, http.StatusForbidden, "FORBIDDEN", "admin role required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetUserRole changes a user's stored role. Admins cannot change their own,
+// so the last admin cannot lock everyone out.
+func (s *Service) SetUserRole(ctx context.Context, actor *UserContext, userID uuid.UUID, role string) (*db.User, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if actor != nil && actor.UserID == userID {
+		return nil, ErrOwnRole
+	}
+	user, err := s.userRepo.SetRole(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = s.roleFor(user.Email, user.Role)
+	return user, nil
+}
+
+// ListUsers returns a page of accounts with the role each acts with.
+func (s *Service) ListUsers(ctx context.Context, limit, offset int) ([]db.User, int64, error) {
+	users, total, err := s.userRepo.ListUsers(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range users {
+		users[i].Role = s.roleFor(users[i].Email, users[i].Role)
+	}
+	return users, total, nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func TestAccessTokenCarriesRole(t *testing.T) {
+	s := NewService(nil, nil, "test-secret")
+	s.SetAdminEmails([]string{" Ops@Example.test "})
+
+	for _, tc := range []struct {
+		email, stored, want string
+	}{
+		{"member@example.test", "", RoleMember},
+		{"member@example.test", RoleMember, RoleMember},
+		{"granted@example.test", RoleAdmin, RoleAdmin},
+		{"ops@example.test", RoleMember, RoleAdmin},
+	} {
+		token, err := s.generateAccessToken(&db.User{ID: uuid.New(), Email: tc.email, Role: tc.stored}, uuid.New())
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		claims, err := s.ValidateAccessToken(token)
+		if err != nil {
+			t.Fatalf("validate token: %v", err)
+		}
+		if claims.Role != tc.want {
+			t.Errorf("%s stored %q: role = %q; want %q", tc.email, tc.stored, claims.Role, tc.want)
+		}
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for name, tc := range map[string]struct {
+		user *UserContext
+		want int
+	}{
+		"anonymous":             {nil, http.StatusForbidden},
+		"member":                {&UserContext{UserID: uuid.New(), Role: RoleMember}, http.StatusForbidden},
+		"admin":                 {&UserContext{UserID: uuid.New(), Role: RoleAdmin}, http.StatusNoContent},
+		"admin key":             {&UserContext{UserID: uuid.New(), Role: RoleAdmin, Scopes: []string{ScopeRead, ScopeAdmin}}, http.StatusNoContent},
+		"admin key, read only":  {&UserContext{UserID: uuid.New(), Role: RoleAdmin, Scopes: []string{ScopeRead}}, http.StatusForbidden},
+		"member key with admin": {&UserContext{UserID: uuid.New(), Role: RoleMember, Scopes: []string{ScopeAdmin}}, http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			if tc.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tc.user))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d; want %d", rec.Code, tc.want)
+			}
+			if tc.want == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("Content-Type = %q; want application/json", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestSetUserRoleRejectsInvalidAndOwnRole(t *testing.T) {
+	s := NewService(nil, nil, "test-secret")
+	admin := &UserContext{UserID: uuid.New(), Role: RoleAdmin}
+
+	if _, err := s.SetUserRole(context.Background(), admin, uuid.New(), "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("invalid role err = %v; want ErrInvalidRole", err)
+	}
+	if _, err := s.SetUserRole(context.Background(), admin, admin.UserID, RoleMember); !errors.Is(err, ErrOwnRole) {
+		t.Fatalf("own role err = %v; want ErrOwnRole", err)
+	}
+}
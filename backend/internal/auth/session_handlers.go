@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+)
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"deviceName,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// ListSessions handles GET /api/v1/auth/sessions
+func (h *Handlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), userCtx.UserID)
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to list sessions").WithCause(err))
+		return
+	}
+	resp := SessionsResponse{Sessions: make([]SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, SessionResponse{
+			ID:         session.ID.String(),
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    userCtx.SessionID != uuid.Nil && session.ID == userCtx.SessionID,
+		})
+	}
+	apperrors.WriteJSON(w, requestID, http.StatusOK, resp)
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/{id}
+func (h *Handlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.ValidationError("invalid session id"))
+		return
+	}
+
+	err = h.authService.RevokeSession(r.Context(), userCtx.UserID, id)
+	switch {
+	case errors.Is(err, db.ErrSessionNotFound):
+		apperrors.WriteError(w, requestID, apperrors.NotFound("session"))
+	case err != nil:
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to revoke session").WithCause(err))
+	default:
+		w.Header().Set("X-Request-ID", requestID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeOtherSessions handles DELETE /api/v1/auth/sessions, signing out
+// every session except the caller's.
+func (h *Handlers) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	requestID := apperrors.GetRequestID(r.Context())
+	userCtx := GetUserFromContext(r.Context())
+	if userCtx == nil {
+		apperrors.WriteError(w, requestID, apperrors.Unauthorized("not authenticated"))
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(r.Context(), userCtx.UserID, userCtx.SessionID)
+	if err != nil {
+		apperrors.WriteError(w, requestID, apperrors.InternalError("failed to revoke sessions").WithCause(err))
+		return
+	}
+	apperrors.WriteJSON(w, requestID, http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
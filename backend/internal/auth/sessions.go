@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 512
+	maxIPAddressLength  = 64
+)
+
+// TokenStore persists refresh tokens and the sessions they belong to;
+// db.TokenRepository satisfies it.
+type TokenStore interface {
+	Create(ctx context.Context, token *db.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*db.RefreshToken, error)
+	Consume(ctx context.Context, id uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	CreateSession(ctx context.Context, session *db.Session) error
+	TouchSession(ctx context.Context, sessionID uuid.UUID, userAgent, ipAddress string, expiresAt time.Time) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, keep uuid.UUID) (int, error)
+}
+
+// ClientInfo describes the device signing in, as recorded on its session.
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo attaches the signing-in device to ctx so new and refreshed
+// sessions record it.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromRequest reads the device from the request. deviceName is the
+// client's own label for itself and may be empty. The address is RemoteAddr:
+// X-Forwarded-For is not read here since any client can set it, and
+// relay.ClientAddr already puts the forwarded address of tunnel traffic in
+// RemoteAddr.
+func ClientInfoFromRequest(r *http.Request, deviceName string) ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return ClientInfo{
+		DeviceName: truncateRunes(strings.TrimSpace(deviceName), maxDeviceNameLength),
+		UserAgent:  truncateRunes(r.UserAgent(), maxUserAgentLength),
+		IPAddress:  truncateRunes(ip, maxIPAddressLength),
+	}
+}
+
+func clientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// ListSessions returns the user's signed-in devices, most recently used
+// first.
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error) {
+	return s.tokenRepo.ListSessions(ctx, userID)
+}
+
+// RevokeSession signs one of the user's devices out. Access tokens already
+// issued to it stay valid until they expire.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return s.tokenRepo.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeOtherSessions signs the user out of every device except current.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, current uuid.UUID) (int, error) {
+	return s.tokenRepo.RevokeOtherSessions(ctx, userID, current)
+}
+
+func (s *Service) startSession(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	info := clientInfoFrom(ctx)
+	now := time.Now()
+	session := &db.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		DeviceName: info.DeviceName,
+		UserAgent:  info.UserAgent,
+		IPAddress:  info.IPAddress,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(RefreshTokenExpiry),
+	}
+	if err := s.tokenRepo.CreateSession(ctx, session); err != nil {
+		return uuid.Nil, err
+	}
+	return session.ID, nil
+}
+
+// continueSession keeps a refreshed token's session alive. Tokens issued
+// before sessions existed start one.
+func (s *Service) continueSession(ctx context.Context, token *db.RefreshToken) (uuid.UUID, error) {
+	if !token.SessionID.Valid {
+		return s.startSession(ctx, token.UserID)
+	}
+	info := clientInfoFrom(ctx)
+	err := s.tokenRepo.TouchSession(ctx, token.SessionID.UUID, info.UserAgent, info.IPAddress, time.Now().Add(RefreshTokenExpiry))
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return token.SessionID.UUID, nil
+}
+
+// revokeReusedSession handles a refresh token presented after rotation.
+// Either the client replayed it or someone else holds a copy, so the whole
+// session is signed out.
+func (s *Service) revokeReusedSession(ctx context.Context, token *db.RefreshToken) error {
+	if !token.SessionID.Valid {
+		return ErrInvalidToken
+	}
+	err := s.tokenRepo.RevokeSession(ctx, token.UserID, token.SessionID.UUID)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		// Already signed out; the token is simply stale.
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Warning: refresh token reuse detected; revoked session %s for user %s", token.SessionID.UUID, token.UserID)
+	return ErrTokenReused
+}
+
+func truncateRunes(value string, limit int) string {
+	for utf8.RuneCountInString(value) > limit {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeTokenStore struct {
+	tokens   map[string]*db.RefreshToken
+	sessions map[uuid.UUID]*db.Session
+	revoked  map[uuid.UUID]bool
+	kept     uuid.UUID
+}
+
+func newFakeTokenStore() *fakeTokenStore {
+	return &fakeTokenStore{
+		tokens:   make(map[string]*db.RefreshToken),
+		sessions: make(map[uuid.UUID]*db.Session),
+		revoked:  make(map[uuid.UUID]bool),
+	}
+}
+
+func (f *fakeTokenStore) Create(ctx context.Context, token *db.RefreshToken) error {
+	f.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (f *fakeTokenStore) GetByHash(ctx context.Context, tokenHash string) (*db.RefreshToken, error) {
+	token, ok := f.tokens[tokenHash]
+	if !ok {
+		return nil, db.ErrTokenNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (f *fakeTokenStore) Consume(ctx context.Context, id uuid.UUID) error {
+	for _, token := range f.tokens {
+		if token.ID == id {
+			if token.Revoked {
+				return db.ErrTokenRevoked
+			}
+			token.Revoked = true
+			return nil
+		}
+	}
+	return db.ErrTokenNotFound
+}
+
+func (f *fakeTokenStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeTokenStore) CreateSession(ctx context.Context, session *db.Session) error {
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeTokenStore) TouchSession(ctx context.Context, sessionID uuid.UUID, userAgent, ipAddress string, expiresAt time.Time) error {
+	if _, ok := f.sessions[sessionID]; !ok || f.revoked[sessionID] {
+		return db.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (f *fakeTokenStore) ListSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error) {
+	var sessions []db.Session
+	for id, session := range f.sessions {
+		if session.UserID == userID && !f.revoked[id] {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeTokenStore) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, ok := f.sessions[sessionID]
+	if !ok || session.UserID != userID || f.revoked[sessionID] {
+		return db.ErrSessionNotFound
+	}
+	f.revoked[sessionID] = true
+	return nil
+}
+
+func (f *fakeTokenStore) RevokeOtherSessions(ctx context.Context, userID, keep uuid.UUID) (int, error) {
+	f.kept = keep
+	revoked := 0
+	for id, session := range f.sessions {
+		if session.UserID == userID && id != keep && !f.revoked[id] {
+			f.revoked[id] = true
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func newSessionTestService(store *fakeTokenStore) *Service {
+	s := NewService(nil, nil, "test-secret")
+	s.tokenRepo = store
+	return s
+}
+
+// seedSessionToken stores a session with one refresh token and returns the
+// plain token.
+func seedSessionToken(store *fakeTokenStore, userID uuid.UUID, revoked bool) (string, uuid.UUID) {
+	sessionID := uuid.New()
+	store.sessions[sessionID] = &db.Session{ID: sessionID, UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	plain := uuid.NewString()
+	store.tokens[hashToken(plain)] = &db.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		SessionID: uuid.NullUUID{UUID: sessionID, Valid: true},
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(time.Hour),
+		Revoked:   revoked,
+	}
+	return plain, sessionID
+}
+
+func TestRefreshWithRotatedTokenRevokesSession(t *testing.T) {
+	store := newFakeTokenStore()
+	s := newSessionTestService(store)
+	userID := uuid.New()
+	plain, sessionID := seedSessionToken(store, userID, true)
+
+	if _, err := s.Refresh(context.Background(), plain); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("Refresh err = %v, want ErrTokenReused", err)
+	}
+	if !store.revoked[sessionID] {
+		t.Fatal("reusing a rotated token did not revoke its session")
+	}
+
+	// Presenting it again finds the session already gone.
+	if _, err := s.Refresh(context.Background(), plain); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second Refresh err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshOnRevokedSessionIsInvalid(t *testing.T) {
+	store := newFakeTokenStore()
+	s := newSessionTestService(store)
+	plain, sessionID := seedSessionToken(store, uuid.New(), false)
+	store.revoked[sessionID] = true
+
+	if _, err := s.Refresh(context.Background(), plain); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Refresh err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestStartSessionRecordsClientInfo(t *testing.T) {
+	store := newFakeTokenStore()
+	s := newSessionTestService(store)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	req.RemoteAddr = "192.0.2.10:51234"
+	req.Header.Set("User-Agent", "OpenMusicPlayer-Android/1.4")
+	ctx := WithClientInfo(context.Background(), ClientInfoFromRequest(req, "  Pixel 8  "))
+
+	sessionID, err := s.startSession(ctx, uuid.New())
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	session := store.sessions[sessionID]
+	if session.DeviceName != "Pixel 8" || session.UserAgent != "OpenMusicPlayer-Android/1.4" || session.IPAddress != "192.0.2.10" {
+		t.Fatalf("session = %+v, want the request's device recorded", session)
+	}
+}
+
+func TestClientInfoIgnoresForwardedForOnDirectConnections(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	req.RemoteAddr = "192.0.2.10:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.99")
+
+	if info := ClientInfoFromRequest(req, ""); info.IPAddress != "192.0.2.10" {
+		t.Fatalf("IPAddress = %q, want the connection's 192.0.2.10, not the spoofed header", info.IPAddress)
+	}
+}
+
+func TestAccessTokenCarriesSessionID(t *testing.T) {
+	s := NewService(nil, nil, "test-secret")
+	sessionID := uuid.New()
+	token, err := s.generateAccessToken(&db.User{ID: uuid.New(), Email: "listener@example.test"}, sessionID)
+	if err != nil {
+		t.Fatalf("generateAccessToken: %v", err)
+	}
+
+	var got *UserContext
+	handler := Middleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetUserFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got == nil || got.SessionID != sessionID {
+		t.Fatalf("user context = %+v, want session %s", got, sessionID)
+	}
+}
+
+func TestSessionHandlersMarkAndKeepCurrentSession(t *testing.T) {
+	store := newFakeTokenStore()
+	h := NewHandlers(newSessionTestService(store))
+	userID := uuid.New()
+	_, current := seedSessionToken(store, userID, false)
+	_, other := seedSessionToken(store, userID, false)
+	seedSessionToken(store, uuid.New(), false)
+
+	withSession := func(req *http.Request) *http.Request {
+		ctx := context.WithValue(req.Context(), UserContextKey, &UserContext{UserID: userID, SessionID: current})
+		return req.WithContext(ctx)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ListSessions(rec, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/auth/sessions", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var listed SessionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(listed.Sessions) != 2 {
+		t.Fatalf("listed %d sessions, want the user's 2", len(listed.Sessions))
+	}
+	for _, session := range listed.Sessions {
+		if session.Current != (session.ID == current.String()) {
+			t.Fatalf("session %s current = %v", session.ID, session.Current)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.RevokeOtherSessions(rec, withSession(httptest.NewRequest(http.MethodDelete, "/api/v1/auth/sessions", nil)))
+	if rec.Code != http.StatusOK || store.kept != current || !store.revoked[other] || store.revoked[current] {
+		t.Fatalf("revoke others status = %d, kept %s; want only %s revoked", rec.Code, store.kept, other)
+	}
+
+	rec = httptest.NewRecorder()
+	req := withSession(httptest.NewRequest(http.MethodDelete, "/api/v1/auth/sessions/"+other.String(), nil))
+	req.SetPathValue("id", other.String())
+	h.RevokeSession(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("revoking an already revoked session status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package capability
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignerRoundTripAndTampering(t *testing.T) {
+	signer := NewSigner("server-secret")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	claims := Claims{GrantID: uuid.New(), TrackID: 42, ExpiresAt: now.Add(time.Hour)}
+	token := signer.Sign(claims)
+
+	got, err := signer.Parse(token, now)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got.GrantID != claims.GrantID || got.TrackID != 42 || !got.ExpiresAt.Equal(claims.ExpiresAt) {
+		t.Fatalf("claims = %+v, want %+v", got, claims)
+	}
+	if signer.Sign(claims) != token {
+		t.Fatal("Sign is not deterministic")
+	}
+
+	other := Claims{GrantID: claims.GrantID, TrackID: 43, ExpiresAt: claims.ExpiresAt}
+	otherPayload := strings.Split(strings.TrimPrefix(signer.Sign(other), tokenPrefix), ".")[0]
+	sig := strings.Split(token, ".")[2]
+	cases := map[string]string{
+		"swapped payload": tokenPrefix + otherPayload + "." + sig,
+		"other secret":    NewSigner("another-secret").Sign(claims),
+		"no prefix":       strings.TrimPrefix(token, tokenPrefix),
+		"garbage":         "tc1.not-base64!.x",
+	}
+	for name, tampered := range cases {
+		if _, err := signer.Parse(tampered, now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	if _, err := signer.Parse(token, claims.ExpiresAt); !errors.Is(err, ErrExpired) {
+		t.Fatalf("at expiry err = %v, want ErrExpired", err)
+	}
+}
+
+type fakeGrants struct {
+	active   map[uuid.UUID]int64
+	grantees map[uuid.UUID]uuid.UUID
+}
+
+func (f fakeGrants) GrantCovers(_ context.Context, grantID uuid.UUID, trackID int64, listener uuid.NullUUID) (bool, error) {
+	if f.active[grantID] != trackID {
+		return false, nil
+	}
+	if grantee, ok := f.grantees[grantID]; ok {
+		return listener.Valid && listener.UUID == grantee, nil
+	}
+	return true, nil
+}
+
+func TestCheckerConsultsGrant(t *testing.T) {
+	signer := NewSigner("server-secret")
+	public, personal, revoked := uuid.New(), uuid.New(), uuid.New()
+	friend := uuid.New()
+	checker := NewChecker(signer, fakeGrants{
+		active:   map[uuid.UUID]int64{public: 7, personal: 7},
+		grantees: map[uuid.UUID]uuid.UUID{personal: friend},
+	})
+	expires := time.Now().Add(time.Hour)
+	token := func(grant uuid.UUID) string {
+		return signer.Sign(Claims{GrantID: grant, TrackID: 7, ExpiresAt: expires})
+	}
+
+	if claims, err := checker.Authorize(context.Background(), token(public), uuid.NullUUID{}); err != nil || claims.TrackID != 7 {
+		t.Fatalf("public grant = %d, %v; want track 7", claims.TrackID, err)
+	}
+	if _, err := checker.Authorize(context.Background(), token(personal), uuid.NullUUID{}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("personal grant anonymously err = %v, want ErrInvalid", err)
+	}
+	if _, err := checker.Authorize(context.Background(), token(personal), uuid.NullUUID{UUID: friend, Valid: true}); err != nil {
+		t.Fatalf("personal grant for grantee: %v", err)
+	}
+	if _, err := checker.Authorize(context.Background(), token(revoked), uuid.NullUUID{}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("revoked grant err = %v, want ErrInvalid", err)
+	}
+}
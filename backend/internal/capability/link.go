@@ -0,0 +1,46 @@
+package capability
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	linkPrefix      = "pl1."
+	linkPayloadSize = 16 + 8
+)
+
+// LinkClaims are what a public link token asserts: the grant behind the link
+// and when the link stops working.
+type LinkClaims struct {
+	GrantID   uuid.UUID
+	ExpiresAt time.Time
+}
+
+// SignLink returns the token for a public link. Like track capabilities it is
+// deterministic and only valid while its grant is.
+func (s *Signer) SignLink(c LinkClaims) string {
+	payload := make([]byte, linkPayloadSize)
+	copy(payload, c.GrantID[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(c.ExpiresAt.Unix()))
+	return s.seal(linkPrefix, payload)
+}
+
+// ParseLink verifies the signature and expiry of a public link token. The
+// caller checks the grant.
+func (s *Signer) ParseLink(token string, now time.Time) (LinkClaims, error) {
+	payload, err := s.open(linkPrefix, token, linkPayloadSize)
+	if err != nil {
+		return LinkClaims{}, err
+	}
+
+	var c LinkClaims
+	copy(c.GrantID[:], payload[:16])
+	c.ExpiresAt = time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0).UTC()
+	if !now.Before(c.ExpiresAt) {
+		return LinkClaims{}, ErrExpired
+	}
+	return c, nil
+}
@@ -0,0 +1,35 @@
+package capability
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLinkTokensAreDistinctFromTrackCapabilities(t *testing.T) {
+	signer := NewSigner("server-secret")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	claims := LinkClaims{GrantID: uuid.New(), ExpiresAt: now.Add(time.Hour)}
+	token := signer.SignLink(claims)
+
+	got, err := signer.ParseLink(token, now)
+	if err != nil || got != claims {
+		t.Fatalf("ParseLink = %+v, %v; want %+v", got, err, claims)
+	}
+	if _, err := signer.ParseLink(token, claims.ExpiresAt); !errors.Is(err, ErrExpired) {
+		t.Fatalf("at expiry err = %v, want ErrExpired", err)
+	}
+
+	// A link token relabelled as a capability, or the reverse, must not verify.
+	relabelled := tokenPrefix + strings.TrimPrefix(token, linkPrefix)
+	if _, err := signer.Parse(relabelled, now); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("relabelled link err = %v, want ErrInvalid", err)
+	}
+	capabilityToken := signer.Sign(Claims{GrantID: claims.GrantID, TrackID: 1, ExpiresAt: claims.ExpiresAt})
+	if _, err := signer.ParseLink(linkPrefix+strings.TrimPrefix(capabilityToken, tokenPrefix), now); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("relabelled capability err = %v, want ErrInvalid", err)
+	}
+}
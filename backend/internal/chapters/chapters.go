@@ -0,0 +1,129 @@
+// Package chapters finds the parts of long recordings, such as hour-long
+// YouTube mixes and live sets: chapter markers to seek between and segments
+// players should skip. Markers and segments come from SponsorBlock
+// submissions for the video and from silences in the audio itself.
+package chapters
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	SourceSponsorBlock = "sponsorblock"
+	SourceSilence      = "silence"
+
+	// CategoryChapter marks a SponsorBlock chapter rather than a segment to
+	// skip.
+	CategoryChapter = "chapter"
+	// CategorySilence is a skip segment of silence at the start or end.
+	CategorySilence = "silence"
+
+	// minChapterMs is the shortest chapter a silence may split off, so
+	// pauses inside one song do not cut it into pieces.
+	minChapterMs = 30000
+	// edgeSlackMs is how near the start or end a silence must reach to be
+	// lead-in or run-out rather than a gap between parts.
+	edgeSlackMs = 500
+)
+
+// Segment is a stretch of a track found by a source: a segment to skip, or
+// a SponsorBlock chapter when Category is CategoryChapter.
+type Segment struct {
+	StartMs  int    `json:"startMs"`
+	EndMs    int    `json:"endMs"`
+	Category string `json:"category"`
+	Title    string `json:"title,omitempty"`
+	Source   string `json:"source"`
+}
+
+// Chapter is one titled part of a track.
+type Chapter struct {
+	StartMs int    `json:"startMs"`
+	EndMs   int    `json:"endMs"`
+	Title   string `json:"title"`
+	Source  string `json:"source"`
+}
+
+// Chapters is what a track stores under "chapters" in its metadata_json.
+type Chapters struct {
+	Chapters     []Chapter `json:"chapters"`
+	SkipSegments []Segment `json:"skipSegments"`
+}
+
+// Empty reports whether nothing was found.
+func (c Chapters) Empty() bool {
+	return len(c.Chapters) == 0 && len(c.SkipSegments) == 0
+}
+
+// Build combines a track's SponsorBlock segments and silences. SponsorBlock
+// chapters are used when the video has any; otherwise silences between
+// parts at least minChapterMs long split the track into numbered parts.
+// Silence at the very start or end becomes a skip segment.
+func Build(durationMs int, segments []Segment, silences []Span) Chapters {
+	result := Chapters{Chapters: []Chapter{}, SkipSegments: []Segment{}}
+	var submitted []Segment
+	for _, segment := range segments {
+		segment.StartMs = max(segment.StartMs, 0)
+		if durationMs > 0 {
+			segment.EndMs = min(segment.EndMs, durationMs)
+		}
+		if segment.EndMs <= segment.StartMs {
+			continue
+		}
+		if segment.Category == CategoryChapter {
+			submitted = append(submitted, segment)
+		} else {
+			result.SkipSegments = append(result.SkipSegments, segment)
+		}
+	}
+
+	var gaps []Span
+	for _, silence := range silences {
+		switch {
+		case silence.StartMs <= edgeSlackMs:
+			result.SkipSegments = append(result.SkipSegments, Segment{StartMs: 0, EndMs: silence.EndMs, Category: CategorySilence, Source: SourceSilence})
+		case durationMs > 0 && silence.EndMs >= durationMs-edgeSlackMs:
+			result.SkipSegments = append(result.SkipSegments, Segment{StartMs: silence.StartMs, EndMs: durationMs, Category: CategorySilence, Source: SourceSilence})
+		default:
+			gaps = append(gaps, silence)
+		}
+	}
+	sort.Slice(result.SkipSegments, func(i, j int) bool {
+		return result.SkipSegments[i].StartMs < result.SkipSegments[j].StartMs
+	})
+
+	if len(submitted) > 0 {
+		sort.Slice(submitted, func(i, j int) bool { return submitted[i].StartMs < submitted[j].StartMs })
+		for i, segment := range submitted {
+			title := segment.Title
+			if title == "" {
+				title = fmt.Sprintf("Chapter %d", i+1)
+			}
+			result.Chapters = append(result.Chapters, Chapter{StartMs: segment.StartMs, EndMs: segment.EndMs, Title: title, Source: SourceSponsorBlock})
+		}
+		return result
+	}
+
+	// Each interior gap splits at its middle, so a part starts shortly
+	// before its music and keeps none of the previous part's tail.
+	var boundaries []int
+	previous := 0
+	for _, gap := range gaps {
+		boundary := (gap.StartMs + gap.EndMs) / 2
+		if boundary-previous < minChapterMs || (durationMs > 0 && durationMs-boundary < minChapterMs) {
+			continue
+		}
+		boundaries = append(boundaries, boundary)
+		previous = boundary
+	}
+	if len(boundaries) == 0 || durationMs <= 0 {
+		return result
+	}
+	start := 0
+	for i, boundary := range append(boundaries, durationMs) {
+		result.Chapters = append(result.Chapters, Chapter{StartMs: start, EndMs: boundary, Title: fmt.Sprintf("Part %d", i+1), Source: SourceSilence})
+		start = boundary
+	}
+	return result
+}
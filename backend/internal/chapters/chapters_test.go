@@ -0,0 +1,93 @@
+package chapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildSplitsLongTrackAtSilences(t *testing.T) {
+	silences := []Span{
+		{StartMs: 0, EndMs: 1500},          // lead-in
+		{StartMs: 10000, EndMs: 12500},     // too early to split off a part
+		{StartMs: 300000, EndMs: 304000},   // between parts
+		{StartMs: 620000, EndMs: 624000},   // between parts
+		{StartMs: 1195000, EndMs: 1200000}, // run-out
+	}
+	segments := []Segment{{StartMs: 60000, EndMs: 90000, Category: "sponsor", Source: SourceSponsorBlock}}
+
+	got := Build(1200000, segments, silences)
+	if len(got.Chapters) != 3 {
+		t.Fatalf("chapters = %+v, want three parts", got.Chapters)
+	}
+	if got.Chapters[0] != (Chapter{StartMs: 0, EndMs: 302000, Title: "Part 1", Source: SourceSilence}) ||
+		got.Chapters[1].StartMs != 302000 || got.Chapters[1].EndMs != 622000 ||
+		got.Chapters[2].EndMs != 1200000 {
+		t.Fatalf("chapters = %+v", got.Chapters)
+	}
+	if len(got.SkipSegments) != 3 || got.SkipSegments[0].Category != CategorySilence ||
+		got.SkipSegments[1].Category != "sponsor" || got.SkipSegments[2].StartMs != 1195000 {
+		t.Fatalf("skip segments = %+v, want lead-in, sponsor, and run-out", got.SkipSegments)
+	}
+}
+
+func TestBuildPrefersSponsorBlockChapters(t *testing.T) {
+	segments := []Segment{
+		{StartMs: 240000, EndMs: 600000, Category: CategoryChapter, Source: SourceSponsorBlock},
+		{StartMs: 0, EndMs: 240000, Category: CategoryChapter, Title: "Intro Song", Source: SourceSponsorBlock},
+	}
+	got := Build(600000, segments, []Span{{StartMs: 100000, EndMs: 104000}})
+	if len(got.Chapters) != 2 || got.Chapters[0].Title != "Intro Song" || got.Chapters[1].Title != "Chapter 2" {
+		t.Fatalf("chapters = %+v, want the submitted chapters in order", got.Chapters)
+	}
+	if !Build(100000, nil, nil).Empty() {
+		t.Fatal("a track without silences or segments was not empty")
+	}
+}
+
+func TestParseSilenceDetectClosesTrailingSilence(t *testing.T) {
+	output := strings.Join([]string{
+		"Input #0, mp3, from 'mix.mp3':",
+		"[silencedetect @ 0x55d1] silence_start: 299.5",
+		"[silencedetect @ 0x55d1] silence_end: 303.25 | silence_duration: 3.75",
+		"[silencedetect @ 0x55d1] silence_start: 1196",
+	}, "\n")
+	got := parseSilenceDetect(output, 1200000)
+	if len(got) != 2 || got[0] != (Span{StartMs: 299500, EndMs: 303250}) || got[1] != (Span{StartMs: 1196000, EndMs: 1200000}) {
+		t.Fatalf("silences = %+v", got)
+	}
+}
+
+func TestSponsorBlockSegments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/skipSegments" || r.URL.Query().Get("videoID") != "dQw4w9WgXcQ" {
+			t.Errorf("request = %s", r.URL)
+		}
+		if r.URL.Query().Get("videoID") == "unknown0000" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"segment":[0,12.5],"category":"music_offtopic","actionType":"skip","videoDuration":600.2},
+			{"segment":[12.5,300],"category":"chapter","actionType":"chapter","description":" First Song ","videoDuration":600},
+			{"segment":[30,40],"category":"sponsor","actionType":"skip","videoDuration":900},
+			{"segment":[50,55],"category":"sponsor","actionType":"mute","videoDuration":600}
+		]`))
+	}))
+	defer server.Close()
+
+	client := NewSponsorBlock(server.URL+"/", server.Client())
+	got, err := client.Segments(context.Background(), "dQw4w9WgXcQ", 600000)
+	if err != nil {
+		t.Fatalf("Segments: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("segments = %+v, want the skip and the chapter for this length", got)
+	}
+	if got[0] != (Segment{StartMs: 0, EndMs: 12500, Category: "music_offtopic", Source: SourceSponsorBlock}) ||
+		got[1].Category != CategoryChapter || got[1].Title != "First Song" || got[1].EndMs != 300000 {
+		t.Fatalf("segments = %+v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package chapters
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+const (
+	// silenceNoiseFloor is the level below which audio counts as silent.
+	silenceNoiseFloor = "-50dB"
+	// MinSilence is the shortest gap detected; shorter pauses are part of
+	// the music.
+	MinSilence = 2 * time.Second
+)
+
+// Span is a stretch of a track in milliseconds from its start.
+type Span struct {
+	StartMs int `json:"startMs"`
+	EndMs   int `json:"endMs"`
+}
+
+// DetectSilence runs ffmpeg's silencedetect filter over the first audio
+// stream of the file at path and returns the silent stretches in order.
+// durationMs closes a silence that runs to the end of the file.
+func DetectSilence(ctx context.Context, runner ffmpeg.Runner, path string, durationMs int) ([]Span, error) {
+	args := ffmpeg.NewCommand().
+		LogLevel("info").
+		Input(path).
+		Map("0:a:0").
+		AudioFilter(fmt.Sprintf("silencedetect=noise=%s:d=%g", silenceNoiseFloor, MinSilence.Seconds())).
+		Format("null").
+		Args("-")
+	out, err := runner.FFmpeg(ctx, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg silencedetect: %w", err)
+	}
+	return parseSilenceDetect(out.Stderr, durationMs), nil
+}
+
+// parseSilenceDetect reads the silence_start and silence_end lines
+// silencedetect writes to stderr, such as
+// "[silencedetect @ 0x55d1] silence_end: 15.6 | silence_duration: 3.3".
+func parseSilenceDetect(output string, durationMs int) []Span {
+	var spans []Span
+	open := -1
+	for _, line := range strings.Split(output, "\n") {
+		idx := strings.Index(line, "] ")
+		if idx < 0 || !strings.Contains(line[:idx], "silencedetect") {
+			continue
+		}
+		rest := line[idx+2:]
+		if value, ok := strings.CutPrefix(rest, "silence_start:"); ok {
+			if seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				open = max(secondsToMs(seconds), 0)
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(rest, "silence_end:"); ok && open >= 0 {
+			value, _, _ = strings.Cut(value, "|")
+			if seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				spans = append(spans, Span{StartMs: open, EndMs: secondsToMs(seconds)})
+			}
+			open = -1
+		}
+	}
+	if open >= 0 && durationMs > open {
+		spans = append(spans, Span{StartMs: open, EndMs: durationMs})
+	}
+	return spans
+}
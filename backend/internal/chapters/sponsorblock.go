@@ -0,0 +1,133 @@
+package chapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSponsorBlockURL is the public SponsorBlock server. See
+	// https://wiki.sponsor.ajay.app/w/API_Docs.
+	DefaultSponsorBlockURL = "https://sponsor.ajay.app"
+
+	sponsorBlockUserAgent = "OpenMusicPlayer/1.0.0 (chapters)"
+	// sponsorBlockMaxResponseBytes bounds how much of a response is read.
+	sponsorBlockMaxResponseBytes = 1 << 20
+	// sponsorBlockDurationToleranceMs is how far the length a segment was
+	// submitted against may differ from the download before its times are
+	// no longer trusted, as when the video was re-uploaded or trimmed.
+	sponsorBlockDurationToleranceMs = 2000
+)
+
+// sponsorBlockSkipCategories are the segment categories worth skipping in
+// audio. music_offtopic covers the non-music parts of music videos.
+var sponsorBlockSkipCategories = []string{
+	"sponsor", "selfpromo", "interaction", "intro", "outro", "preview", "music_offtopic",
+}
+
+// SponsorBlock looks up community-submitted segments of YouTube videos.
+type SponsorBlock struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewSponsorBlock targets the server at baseURL, such as
+// DefaultSponsorBlockURL.
+func NewSponsorBlock(baseURL string, httpClient *http.Client) *SponsorBlock {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &SponsorBlock{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+type sponsorBlockSegment struct {
+	Segment       [2]float64 `json:"segment"`
+	Category      string     `json:"category"`
+	ActionType    string     `json:"actionType"`
+	Description   string     `json:"description"`
+	VideoDuration float64    `json:"videoDuration"`
+}
+
+// Segments returns the skip segments and chapters submitted for videoID.
+// durationMs is the downloaded audio's length; segments submitted against a
+// different length are dropped. A video without submissions has none.
+func (s *SponsorBlock) Segments(ctx context.Context, videoID string, durationMs int) ([]Segment, error) {
+	categories, _ := json.Marshal(append(append([]string{}, sponsorBlockSkipCategories...), CategoryChapter))
+	query := url.Values{
+		"videoID":     {videoID},
+		"categories":  {string(categories)},
+		"actionTypes": {`["skip","chapter"]`},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/skipSegments?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", sponsorBlockUserAgent)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sponsorblock lookup: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, sponsorBlockMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read sponsorblock response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sponsorblock lookup: HTTP %d", resp.StatusCode)
+	}
+	var submitted []sponsorBlockSegment
+	if err := json.Unmarshal(body, &submitted); err != nil {
+		return nil, fmt.Errorf("decode sponsorblock response: %w", err)
+	}
+
+	segments := make([]Segment, 0, len(submitted))
+	for _, sub := range submitted {
+		videoMs := secondsToMs(sub.VideoDuration)
+		if videoMs > 0 && durationMs > 0 && absInt(videoMs-durationMs) > sponsorBlockDurationToleranceMs {
+			continue
+		}
+		segment := Segment{
+			StartMs:  secondsToMs(sub.Segment[0]),
+			EndMs:    secondsToMs(sub.Segment[1]),
+			Category: sub.Category,
+			Source:   SourceSponsorBlock,
+		}
+		if sub.ActionType == "chapter" {
+			segment.Category = CategoryChapter
+			segment.Title = strings.TrimSpace(sub.Description)
+		} else if sub.ActionType != "skip" {
+			continue
+		}
+		if segment.EndMs <= segment.StartMs {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func secondsToMs(seconds float64) int {
+	if seconds <= 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0
+	}
+	return int(math.Round(seconds * 1000))
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
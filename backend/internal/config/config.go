@@ -27,6 +27,42 @@ type Config struct {
 	RedisURL           string
 	WorkerCount        int
 
+	// Download scratch space. Each job works in its own subdirectory of
+	// DownloadTempDir (os.TempDir when empty); workers pause while the volume
+	// has less than DownloadMinFreeBytes free, and a periodic sweep removes
+	// scratch entries older than DownloadTempMaxAge left by crashed workers.
+	DownloadTempDir      string
+	DownloadMinFreeBytes uint64
+	DownloadTempMaxAge   time.Duration
+
+	// DownloadProviderLimits caps concurrent download jobs per source
+	// provider, independent of WorkerCount. Admins can adjust the live values
+	// through the API; these are the startup defaults.
+	DownloadProviderLimits map[string]int
+
+	// DownloadWindow ("HH:MM-HH:MM", server local time) limits when download
+	// jobs start; empty allows any time. DownloadMaxBytesPerSecond caps the
+	// combined yt-dlp download rate; zero is uncapped. Admins can change both
+	// through the API; these are the startup defaults.
+	DownloadWindow            string
+	DownloadMaxBytesPerSecond int64
+
+	// AdminEmails are admins whatever their stored role, so a fresh install
+	// has someone to grant the role to others. Compared case-insensitively.
+	AdminEmails []string
+
+	// OIDC single sign-on, enabled when OIDCIssuerURL and OIDCClientID are
+	// set. OIDCRedirectURL is this server's /api/v1/auth/oidc/callback URL as
+	// registered with the provider; OIDCAppRedirectURL, when set, is where the
+	// callback hands the login back to the client. See docs/OIDC.md.
+	OIDCIssuerURL      string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCAppRedirectURL string
+	OIDCScopes         []string
+	OIDCAutoRegister   bool
+
 	// S3/MinIO storage configuration
 	S3Endpoint       string
 	S3Region         string
@@ -44,6 +80,29 @@ type Config struct {
 	MinioBucket         string
 	MinioUseSSL         bool
 
+	// Relay remote access. When RelayURL is set the server also accepts
+	// connections through tunnels it keeps open to that relay (cmd/omp-relay),
+	// so it is reachable without port forwarding. RelayMediaToken, if set,
+	// opens a second set of tunnels to MinioEndpoint so presigned audio URLs
+	// work remotely too. Both tokens are secrets and must never be logged.
+	RelayURL         string
+	RelayToken       string
+	RelayMediaToken  string
+	RelayConnections int
+
+	// Opt-in anonymous telemetry. Nothing is sent unless TelemetryEnabled is
+	// set and TelemetryURL names a collector; admins can preview the payload
+	// at GET /api/v1/admin/telemetry either way.
+	TelemetryEnabled  bool
+	TelemetryURL      string
+	TelemetryInterval time.Duration
+
+	// Spotify app credentials for importing public playlists by URL. Without
+	// them Spotify links are recognized but the import reports the provider as
+	// not configured. The secret must never be logged.
+	SpotifyClientID     string
+	SpotifyClientSecret string
+
 	// AI assist (OpenAI-compatible) configuration for the grounded search assist
 	// endpoint. Disabled unless fully configured; absence must never break normal
 	// discovery search or direct URL resolution. The API key is a secret and must
@@ -84,11 +143,98 @@ type Config struct {
 	AnalyzerTimeout     time.Duration
 	AnalyzerConcurrency int
 
+	// On-demand playback transcoding. Clients that ask for a format other than
+	// the stored one get a variant transcoded by at most TranscodeWorkers
+	// concurrent ffmpeg runs and cached in object storage.
+	TranscodeWorkers int
+	TranscodeTimeout time.Duration
+
+	// Direct uploads. Clients PUT files straight to object storage with a
+	// presigned URL valid for UploadURLTTL; finalized uploads larger than
+	// UploadMaxBytes are deleted instead of processed.
+	UploadMaxBytes int64
+	UploadURLTTL   time.Duration
+
+	// LibraryScanDir is a directory of existing music admins can import
+	// into a user's library; empty disables the import.
+	LibraryScanDir string
+
+	// Scrobbling. Plays of users who connected a ListenBrainz account are
+	// forwarded to ListenBrainzAPIURL in the background.
+	ListenBrainzAPIURL string
+
+	// Library manager hand-off. Completed downloads are announced to
+	// DownloadWebhookURL, signed with DownloadWebhookSecret when set, and
+	// copied with tags into ExportDir for tools such as beets or Plex to
+	// pick up. Each is off when empty.
+	DownloadWebhookURL    string
+	DownloadWebhookSecret string
+	ExportDir             string
+	// TagStoredAudio writes each new track's matched metadata and cover into
+	// its stored audio, so downloaded and exported files arrive tagged.
+	TagStoredAudio bool
+
+	// MusicBrainzRequestsPerSecond caps outgoing MusicBrainz API requests.
+	// musicbrainz.org allows 1; raise it only for a private mirror.
+	MusicBrainzRequestsPerSecond int
+
+	// Track deduplication. Downloads whose artist, title, and the parts
+	// enabled here agree resolve to one track. Changing these requires
+	// re-hashing existing tracks with the rehash-identities command.
+	IdentityIncludeAlbum     bool
+	IdentityDurationBucketMs int
+	IdentityVersionSensitive bool
+
+	// AcoustIDAPIKey enables audio fingerprinting of downloads with
+	// Chromaprint's fpcalc and an AcoustID lookup before title matching.
+	// Register an application key at https://acoustid.org/new-application.
+	AcoustIDAPIKey string
+
+	// SponsorBlockEnabled looks up SponsorBlock segments and chapters for
+	// long YouTube downloads at SponsorBlockURL. Silence detection finds
+	// chapters either way.
+	SponsorBlockEnabled bool
+	SponsorBlockURL     string
+
+	// LyricsLookupEnabled looks lyrics up on the LRCLIB server at LRCLIBURL
+	// the first time a track's lyrics are requested. Lyrics listeners enter
+	// are served either way.
+	LyricsLookupEnabled bool
+	LRCLIBURL           string
+
+	// BeetsPathPrefix is where the object storage bucket is mounted on the
+	// machine running beets (for example with rclone mount). Beets export
+	// items get paths under it; empty leaves paths out.
+	BeetsPathPrefix string
+
 	// Optional "save playlist as mix" seam. Disabled by default; when enabled,
 	// POST /api/v1/playlists/{id}/mix creates a mix_plan from a playlist's
 	// ordered tracks. Backend seam only (no DJ/waveform UI or mixing logic).
 	EnablePlaylistMix bool
 
+	// Daily Mix generation. When enabled, a background loop rebuilds each
+	// user's read-only "Daily Mix" playlists once a day at DailyMixHourUTC.
+	DailyMixEnabled bool
+	DailyMixCount   int
+	DailyMixHourUTC int
+
+	// Listening goal nudges. When enabled, a background loop sends goal_met
+	// and goal_nudge notifications once a day at GoalNudgeHourUTC. Goals and
+	// their progress are available either way.
+	GoalNudgesEnabled bool
+	GoalNudgeHourUTC  int
+
+	// Retention. When enabled, a background loop deletes rows older than each
+	// data type's retention period every RetentionPurgeInterval. The *Days
+	// values are defaults that admins can override at
+	// /api/v1/admin/retention; 0 keeps that data forever.
+	RetentionEnabled           bool
+	RetentionPurgeInterval     time.Duration
+	RetentionPlayHistoryDays   int
+	RetentionAuditLogDays      int
+	RetentionNotificationDays  int
+	RetentionDeadLetterJobDays int
+
 	// Durable research jobs always create a deterministic baseline. This flag
 	// controls only optional model enhancement; a disabled runner records the
 	// model-disabled degradation while retaining that baseline.
@@ -181,11 +327,28 @@ func Load() *Config {
 		DBName:             getEnvOrDefault("DB_NAME", "openmusicplayer"),
 		JWTSecret:          getEnvOrDefault("JWT_SECRET", generateDefaultSecret()),
 		CORSAllowedOrigins: parseCORSAllowedOrigins(),
+		AdminEmails:        parseAdminEmails(),
+		OIDCIssuerURL:      strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")),
+		OIDCClientID:       strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")),
+		OIDCClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:    strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL")),
+		OIDCAppRedirectURL: strings.TrimSpace(os.Getenv("OIDC_APP_REDIRECT_URL")),
+		OIDCScopes:         strings.Fields(getEnvOrDefault("OIDC_SCOPES", "openid email profile")),
+		OIDCAutoRegister:   parseBoolEnv("OIDC_AUTO_REGISTER", true),
 		RedisEnabled:       redisEnabled,
 		RedisAddr:          getEnvOrDefault("REDIS_ADDR", "localhost:6380"),
 		RedisURL:           getEnvOrDefault("REDIS_URL", "redis://localhost:6380"),
 		WorkerCount:        workerCount,
 
+		DownloadTempDir:      strings.TrimSpace(os.Getenv("DOWNLOAD_TEMP_DIR")),
+		DownloadMinFreeBytes: uint64(parseBoundedIntEnv("DOWNLOAD_MIN_FREE_DISK_MB", 1024, 0, 1<<20)) << 20,
+		DownloadTempMaxAge:   parseBoundedDurationSecondsEnv("DOWNLOAD_TEMP_MAX_AGE_S", 6*time.Hour, 30*time.Minute, 7*24*time.Hour),
+
+		DownloadProviderLimits: parseProviderLimits(),
+
+		DownloadWindow:            strings.TrimSpace(os.Getenv("DOWNLOAD_WINDOW")),
+		DownloadMaxBytesPerSecond: int64(parseBoundedIntEnv("DOWNLOAD_MAX_RATE_KB", 0, 0, 1<<30)) << 10,
+
 		// S3/MinIO configuration
 		S3Endpoint:       getEnvOrDefault("MINIO_ENDPOINT", "http://localhost:9000"),
 		S3Region:         getEnvOrDefault("S3_REGION", "us-east-1"),
@@ -203,6 +366,21 @@ func Load() *Config {
 		MinioBucket:         getEnvOrDefault("MINIO_BUCKET", "audio-files"),
 		MinioUseSSL:         minioUseSSL,
 
+		// Relay remote access
+		RelayURL:         strings.TrimSpace(os.Getenv("RELAY_URL")),
+		RelayToken:       strings.TrimSpace(os.Getenv("RELAY_TOKEN")),
+		RelayMediaToken:  strings.TrimSpace(os.Getenv("RELAY_MEDIA_TOKEN")),
+		RelayConnections: parseBoundedIntEnv("RELAY_CONNECTIONS", 4, 1, 32),
+
+		// Telemetry
+		TelemetryEnabled:  parseBoolEnv("TELEMETRY_ENABLED", false),
+		TelemetryURL:      strings.TrimSpace(os.Getenv("TELEMETRY_URL")),
+		TelemetryInterval: parseBoundedDurationSecondsEnv("TELEMETRY_INTERVAL_S", 24*time.Hour, time.Hour, 30*24*time.Hour),
+
+		// Spotify playlist import
+		SpotifyClientID:     strings.TrimSpace(os.Getenv("SPOTIFY_CLIENT_ID")),
+		SpotifyClientSecret: strings.TrimSpace(os.Getenv("SPOTIFY_CLIENT_SECRET")),
+
 		// AI assist configuration
 		AIAssistEnabled: aiEnabled,
 		AIAssistBaseURL: aiBaseURL,
@@ -233,9 +411,58 @@ func Load() *Config {
 		AnalyzerTimeout:     parseDurationMsEnv("ANALYZER_TIMEOUT_MS", 90*time.Second),
 		AnalyzerConcurrency: parseBoundedIntEnv("ANALYZER_CONCURRENCY", 1, 1, 4),
 
+		// On-demand playback transcoding
+		TranscodeWorkers: parseBoundedIntEnv("TRANSCODE_WORKERS", 2, 0, 16),
+		TranscodeTimeout: parseBoundedDurationSecondsEnv("TRANSCODE_TIMEOUT_SECONDS", 5*time.Minute, 10*time.Second, 30*time.Minute),
+
+		// Direct-to-storage uploads
+		UploadMaxBytes: int64(parseBoundedIntEnv("UPLOAD_MAX_MB", 1024, 1, 10240)) << 20,
+		UploadURLTTL:   parseBoundedDurationSecondsEnv("UPLOAD_URL_TTL_SECONDS", 30*time.Minute, time.Minute, 6*time.Hour),
+
+		LibraryScanDir: strings.TrimSpace(os.Getenv("LIBRARY_SCAN_DIR")),
+
+		ListenBrainzAPIURL: strings.TrimRight(getEnvOrDefault("LISTENBRAINZ_API_URL", "https://api.listenbrainz.org"), "/"),
+
+		MusicBrainzRequestsPerSecond: parseBoundedIntEnv("MUSICBRAINZ_REQUESTS_PER_SECOND", 1, 1, 100),
+
+		IdentityIncludeAlbum:     parseBoolEnv("IDENTITY_INCLUDE_ALBUM", true),
+		IdentityDurationBucketMs: parseBoundedIntEnv("IDENTITY_DURATION_BUCKET_MS", 5000, 0, 600000),
+		IdentityVersionSensitive: parseBoolEnv("IDENTITY_VERSION_SENSITIVE", true),
+
+		AcoustIDAPIKey: strings.TrimSpace(os.Getenv("ACOUSTID_API_KEY")),
+
+		SponsorBlockEnabled: parseBoolEnv("SPONSORBLOCK_ENABLED", true),
+		SponsorBlockURL:     strings.TrimRight(getEnvOrDefault("SPONSORBLOCK_API_URL", "https://sponsor.ajay.app"), "/"),
+
+		LyricsLookupEnabled: parseBoolEnv("LYRICS_LOOKUP_ENABLED", true),
+		LRCLIBURL:           strings.TrimRight(getEnvOrDefault("LRCLIB_API_URL", "https://lrclib.net"), "/"),
+
+		DownloadWebhookURL:    strings.TrimSpace(os.Getenv("DOWNLOAD_WEBHOOK_URL")),
+		DownloadWebhookSecret: os.Getenv("DOWNLOAD_WEBHOOK_SECRET"),
+		ExportDir:             strings.TrimSpace(os.Getenv("EXPORT_DIR")),
+		TagStoredAudio:        parseBoolEnv("TAG_STORED_AUDIO", true),
+		BeetsPathPrefix:       strings.TrimSpace(os.Getenv("BEETS_PATH_PREFIX")),
+
 		// Save-playlist-as-mix seam (default OFF)
 		EnablePlaylistMix: parseBoolEnv("ENABLE_PLAYLIST_MIX", false),
 
+		// Daily Mix generator (default ON, nightly at 04:00 UTC)
+		DailyMixEnabled: parseBoolEnv("DAILY_MIX_ENABLED", true),
+		DailyMixCount:   parseBoundedIntEnv("DAILY_MIX_COUNT", 3, 1, 6),
+		DailyMixHourUTC: parseBoundedIntEnv("DAILY_MIX_HOUR_UTC", 4, 0, 23),
+
+		// Listening goal nudges (default ON, daily at 18:00 UTC)
+		GoalNudgesEnabled: parseBoolEnv("GOAL_NUDGES_ENABLED", true),
+		GoalNudgeHourUTC:  parseBoundedIntEnv("GOAL_NUDGE_HOUR_UTC", 18, 0, 23),
+
+		// Retention purges (default ON; play history kept forever)
+		RetentionEnabled:           parseBoolEnv("RETENTION_ENABLED", true),
+		RetentionPurgeInterval:     parseBoundedDurationSecondsEnv("RETENTION_PURGE_INTERVAL_S", 6*time.Hour, 15*time.Minute, 7*24*time.Hour),
+		RetentionPlayHistoryDays:   parseBoundedIntEnv("RETENTION_PLAY_HISTORY_DAYS", 0, 0, 36500),
+		RetentionAuditLogDays:      parseBoundedIntEnv("RETENTION_AUDIT_LOG_DAYS", 365, 0, 36500),
+		RetentionNotificationDays:  parseBoundedIntEnv("RETENTION_NOTIFICATION_DAYS", 90, 0, 36500),
+		RetentionDeadLetterJobDays: parseBoundedIntEnv("RETENTION_DEAD_LETTER_JOB_DAYS", 30, 0, 36500),
+
 		ResearchEnabled:       parseBoolEnv("RESEARCH_ENABLED", false),
 		ResearchWorkerEnabled: parseBoolEnv("RESEARCH_WORKER_ENABLED", true),
 		ResearchCommand:       strings.TrimSpace(os.Getenv("RESEARCH_COMMAND")),
@@ -407,6 +634,37 @@ func parseCORSAllowedOrigins() []string {
 	return origins
 }
 
+func parseAdminEmails() []string {
+	var emails []string
+	for _, part := range strings.Split(os.Getenv("OMP_ADMIN_EMAILS"), ",") {
+		if email := strings.ToLower(strings.TrimSpace(part)); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// parseProviderLimits reads DOWNLOAD_PROVIDER_LIMITS as comma-separated
+// provider=limit pairs. Malformed pairs are skipped; an explicitly empty value
+// removes every cap.
+func parseProviderLimits() map[string]int {
+	value, ok := os.LookupEnv("DOWNLOAD_PROVIDER_LIMITS")
+	if !ok {
+		value = "youtube=2,soundcloud=1"
+	}
+	limits := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		provider, rawLimit, ok := strings.Cut(part, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		limit, err := strconv.Atoi(strings.TrimSpace(rawLimit))
+		if !ok || provider == "" || err != nil || limit <= 0 {
+			continue
+		}
+		limits[provider] = limit
+	}
+	return limits
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
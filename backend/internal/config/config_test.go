@@ -33,6 +33,28 @@ func TestLoadDefaultsMalformedWorkerCount(t *testing.T) {
 	}
 }
 
+func TestLoadDownloadDiskGuardAllowsDisablingThreshold(t *testing.T) {
+	t.Setenv("DOWNLOAD_MIN_FREE_DISK_MB", "0")
+	t.Setenv("DOWNLOAD_TEMP_MAX_AGE_S", "60")
+
+	cfg := Load()
+	if cfg.DownloadMinFreeBytes != 0 {
+		t.Fatalf("DownloadMinFreeBytes = %d, want disabled", cfg.DownloadMinFreeBytes)
+	}
+	if cfg.DownloadTempMaxAge != 6*time.Hour {
+		t.Fatalf("DownloadTempMaxAge = %s, want default for values under the job timeout margin", cfg.DownloadTempMaxAge)
+	}
+}
+
+func TestLoadDownloadProviderLimits(t *testing.T) {
+	t.Setenv("DOWNLOAD_PROVIDER_LIMITS", " YouTube=3, soundcloud=x, bandcamp=0,direct=1")
+
+	cfg := Load()
+	if len(cfg.DownloadProviderLimits) != 2 || cfg.DownloadProviderLimits["youtube"] != 3 || cfg.DownloadProviderLimits["direct"] != 1 {
+		t.Fatalf("DownloadProviderLimits = %v, want youtube=3 direct=1", cfg.DownloadProviderLimits)
+	}
+}
+
 func TestLoadDefaultsMalformedRedisEnabledToTrue(t *testing.T) {
 	t.Setenv("REDIS_ENABLED", "treu")
 
@@ -0,0 +1,247 @@
+// Package dailymix builds each user's rotating "Daily Mix" playlists from
+// clusters of their library and refreshes them once a day.
+package dailymix
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Options bounds the size and number of generated mixes.
+type Options struct {
+	Mixes        int // mixes generated per user
+	TracksPerMix int // upper bound on tracks in one mix
+	MinTracks    int // clusters smaller than this are pooled, never a mix alone
+}
+
+// DefaultOptions returns the generator defaults.
+func DefaultOptions() Options {
+	return Options{Mixes: 3, TracksPerMix: 30, MinTracks: 8}
+}
+
+// Energy bands split large genre/artist clusters. Energy is on a 0..1 scale.
+const (
+	lowEnergyCeiling  = 0.4
+	highEnergyFloor   = 0.7
+	varietyClusterKey = "\x00variety"
+)
+
+type cluster struct {
+	key    string
+	label  string
+	tracks []db.DailyMixCandidate
+}
+
+func (c cluster) score() int {
+	score := 0
+	for _, t := range c.tracks {
+		score += 1 + t.PlayCount
+	}
+	return score
+}
+
+// Build clusters candidates by genre (falling back to artist), splits large
+// clusters by energy band, and turns the strongest clusters into up to
+// opts.Mixes mixes. seed drives the per-day rotation: the same seed yields the
+// same mixes, and more-played tracks are more likely to be picked.
+func Build(candidates []db.DailyMixCandidate, opts Options, seed uint64) []db.DailyMix {
+	if opts.Mixes <= 0 || opts.TracksPerMix <= 0 {
+		return nil
+	}
+	if opts.MinTracks <= 0 {
+		opts.MinTracks = 1
+	}
+
+	clusters := clusterCandidates(candidates, opts.MinTracks)
+	if len(clusters) > opts.Mixes {
+		clusters = clusters[:opts.Mixes]
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	mixes := make([]db.DailyMix, 0, len(clusters))
+	for i, c := range clusters {
+		picked := weightedSample(c.tracks, opts.TracksPerMix, rng)
+		trackIDs := make([]int64, len(picked))
+		for j, t := range picked {
+			trackIDs[j] = t.TrackID
+		}
+		mixes = append(mixes, db.DailyMix{
+			Slot:        i + 1,
+			Name:        fmt.Sprintf("Daily Mix %d", i+1),
+			Description: describe(c.label, picked),
+			TrackIDs:    trackIDs,
+		})
+	}
+	return mixes
+}
+
+// clusterCandidates groups candidates and returns clusters of at least
+// minTracks tracks, strongest first.
+func clusterCandidates(candidates []db.DailyMixCandidate, minTracks int) []cluster {
+	groups := map[string]*cluster{}
+	var order []string
+	for _, c := range candidates {
+		key, label := primaryKey(c)
+		g, ok := groups[key]
+		if !ok {
+			g = &cluster{key: key, label: label}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.tracks = append(g.tracks, c)
+	}
+
+	var result []cluster
+	variety := cluster{key: varietyClusterKey, label: "A bit of everything"}
+	for _, key := range order {
+		g := groups[key]
+		if len(g.tracks) < minTracks {
+			variety.tracks = append(variety.tracks, g.tracks...)
+			continue
+		}
+		result = append(result, splitByEnergy(*g, minTracks)...)
+	}
+	if len(variety.tracks) >= minTracks {
+		result = append(result, variety)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		si, sj := result[i].score(), result[j].score()
+		if si != sj {
+			return si > sj
+		}
+		if len(result[i].tracks) != len(result[j].tracks) {
+			return len(result[i].tracks) > len(result[j].tracks)
+		}
+		return result[i].key < result[j].key
+	})
+	return result
+}
+
+func primaryKey(c db.DailyMixCandidate) (key, label string) {
+	if genre := strings.TrimSpace(c.Genre); genre != "" && !strings.EqualFold(genre, "unknown") {
+		return "genre:" + strings.ToLower(genre), genre
+	}
+	if artist := strings.TrimSpace(c.Artist); artist != "" {
+		return "artist:" + strings.ToLower(artist), artist
+	}
+	return varietyClusterKey, ""
+}
+
+// splitByEnergy divides a cluster big enough for two mixes into energy bands.
+// Bands below minTracks, and tracks without analyzed energy, fold into the
+// largest band so no track is dropped.
+func splitByEnergy(c cluster, minTracks int) []cluster {
+	if len(c.tracks) < 2*minTracks {
+		return []cluster{c}
+	}
+
+	names := []string{"low energy", "mid energy", "high energy"}
+	bands := make([][]db.DailyMixCandidate, len(names))
+	var unknown []db.DailyMixCandidate
+	for _, t := range c.tracks {
+		switch {
+		case t.Energy == nil:
+			unknown = append(unknown, t)
+		case *t.Energy < lowEnergyCeiling:
+			bands[0] = append(bands[0], t)
+		case *t.Energy < highEnergyFloor:
+			bands[1] = append(bands[1], t)
+		default:
+			bands[2] = append(bands[2], t)
+		}
+	}
+
+	var kept []cluster
+	leftover := unknown
+	for i, band := range bands {
+		if len(band) >= minTracks {
+			kept = append(kept, cluster{
+				key:    c.key + "/" + names[i],
+				label:  joinLabel(c.label, names[i]),
+				tracks: band,
+			})
+		} else {
+			leftover = append(leftover, band...)
+		}
+	}
+	if len(kept) < 2 {
+		return []cluster{c}
+	}
+
+	largest := 0
+	for i := range kept {
+		if len(kept[i].tracks) > len(kept[largest].tracks) {
+			largest = i
+		}
+	}
+	kept[largest].tracks = append(kept[largest].tracks, leftover...)
+	return kept
+}
+
+// weightedSample draws up to n tracks without replacement, weighting each by
+// 1+log1p(plays) (Efraimidis-Spirakis keys), and keeps the draw order.
+func weightedSample(tracks []db.DailyMixCandidate, n int, rng *rand.Rand) []db.DailyMixCandidate {
+	type keyed struct {
+		key   float64
+		track db.DailyMixCandidate
+	}
+	keys := make([]keyed, len(tracks))
+	for i, t := range tracks {
+		weight := 1 + math.Log1p(float64(max(t.PlayCount, 0)))
+		u := rng.Float64()
+		for u == 0 {
+			u = rng.Float64()
+		}
+		keys[i] = keyed{key: math.Pow(u, 1/weight), track: t}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	out := make([]db.DailyMixCandidate, len(keys))
+	for i, k := range keys {
+		out[i] = k.track
+	}
+	return out
+}
+
+// describe summarizes a mix as its cluster label plus its most common artists.
+func describe(label string, tracks []db.DailyMixCandidate) string {
+	counts := map[string]int{}
+	var artists []string
+	for _, t := range tracks {
+		if t.Artist == "" {
+			continue
+		}
+		if counts[t.Artist] == 0 {
+			artists = append(artists, t.Artist)
+		}
+		counts[t.Artist]++
+	}
+	sort.SliceStable(artists, func(i, j int) bool { return counts[artists[i]] > counts[artists[j]] })
+	if len(artists) > 3 {
+		artists = artists[:3]
+	}
+
+	parts := []string{}
+	if label != "" {
+		parts = append(parts, label)
+	}
+	if len(artists) > 0 {
+		parts = append(parts, strings.Join(artists, ", ")+" and more")
+	}
+	return strings.Join(parts, " · ")
+}
+
+func joinLabel(label, band string) string {
+	if label == "" {
+		return band
+	}
+	return label + " · " + band
+}
@@ -0,0 +1,88 @@
+package dailymix
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func energy(v float64) *float64 { return &v }
+
+func candidates(start int64, n int, genre, artist string, e *float64, plays int) []db.DailyMixCandidate {
+	out := make([]db.DailyMixCandidate, n)
+	for i := range out {
+		out[i] = db.DailyMixCandidate{TrackID: start + int64(i), Genre: genre, Artist: artist, Energy: e, PlayCount: plays}
+	}
+	return out
+}
+
+func TestBuildRanksClustersAndPoolsSmallOnes(t *testing.T) {
+	var library []db.DailyMixCandidate
+	library = append(library, candidates(1, 10, "House", "A", nil, 5)...)
+	library = append(library, candidates(100, 12, "Jazz", "B", nil, 0)...)
+	// Two tiny artist clusters with no genre pool into a variety mix.
+	library = append(library, candidates(200, 5, "", "C", nil, 0)...)
+	library = append(library, candidates(300, 5, "Unknown", "D", nil, 0)...)
+
+	mixes := Build(library, Options{Mixes: 5, TracksPerMix: 30, MinTracks: 8}, 42)
+	if len(mixes) != 3 {
+		t.Fatalf("mixes = %d, want 3", len(mixes))
+	}
+	if mixes[0].Slot != 1 || mixes[0].Name != "Daily Mix 1" || !strings.HasPrefix(mixes[0].Description, "House") {
+		t.Fatalf("first mix = %+v, want the most-played House cluster", mixes[0])
+	}
+	if !strings.HasPrefix(mixes[1].Description, "Jazz") {
+		t.Fatalf("second mix = %+v, want Jazz", mixes[1])
+	}
+	if len(mixes[2].TrackIDs) != 10 || !strings.HasPrefix(mixes[2].Description, "A bit of everything") {
+		t.Fatalf("third mix = %+v, want pooled variety of 10", mixes[2])
+	}
+}
+
+func TestBuildSplitsLargeClustersByEnergy(t *testing.T) {
+	var library []db.DailyMixCandidate
+	library = append(library, candidates(1, 9, "Techno", "A", energy(0.9), 0)...)
+	library = append(library, candidates(100, 9, "Techno", "B", energy(0.2), 0)...)
+	library = append(library, candidates(200, 2, "Techno", "C", nil, 0)...)
+
+	mixes := Build(library, Options{Mixes: 3, TracksPerMix: 30, MinTracks: 8}, 1)
+	if len(mixes) != 2 {
+		t.Fatalf("mixes = %d, want 2 energy bands", len(mixes))
+	}
+	total := 0
+	for _, m := range mixes {
+		total += len(m.TrackIDs)
+		if !strings.Contains(m.Description, "energy") {
+			t.Fatalf("description %q does not name the energy band", m.Description)
+		}
+	}
+	if total != 20 {
+		t.Fatalf("tracks across bands = %d, want all 20 kept", total)
+	}
+}
+
+func TestBuildIsStableForSeedAndCapsLength(t *testing.T) {
+	library := candidates(1, 50, "Ambient", "A", nil, 1)
+	opts := Options{Mixes: 1, TracksPerMix: 20, MinTracks: 8}
+
+	first := Build(library, opts, 7)
+	again := Build(library, opts, 7)
+	other := Build(library, opts, 8)
+	if len(first) != 1 || len(first[0].TrackIDs) != 20 {
+		t.Fatalf("mix = %+v, want one mix of 20 tracks", first)
+	}
+	if !reflect.DeepEqual(first, again) {
+		t.Fatalf("same seed produced different mixes")
+	}
+	if reflect.DeepEqual(first[0].TrackIDs, other[0].TrackIDs) {
+		t.Fatalf("different seeds produced the same rotation")
+	}
+}
+
+func TestBuildSkipsTinyLibraries(t *testing.T) {
+	if mixes := Build(candidates(1, 3, "Pop", "A", nil, 0), DefaultOptions(), 1); len(mixes) != 0 {
+		t.Fatalf("mixes = %+v, want none for a 3-track library", mixes)
+	}
+}
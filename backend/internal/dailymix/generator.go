@@ -0,0 +1,158 @@
+package dailymix
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Store is the persistence the generator needs; *db.DailyMixRepository
+// implements it.
+type Store interface {
+	UsersWithLibrary(ctx context.Context) ([]uuid.UUID, error)
+	Candidates(ctx context.Context, userID uuid.UUID) ([]db.DailyMixCandidate, error)
+	ReplaceDailyMixes(ctx context.Context, userID uuid.UUID, mixes []db.DailyMix) error
+}
+
+// Report summarizes one refresh pass.
+type Report struct {
+	Users    int
+	Mixes    int
+	Failures int
+}
+
+// Generator rebuilds Daily Mixes for every user with a library. Start runs a
+// refresh immediately and then once a day at the configured UTC hour.
+type Generator struct {
+	store   Store
+	opts    Options
+	hourUTC int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewGenerator(store Store, opts Options, hourUTC int) *Generator {
+	return &Generator{store: store, opts: opts, hourUTC: hourUTC, now: time.Now}
+}
+
+// RefreshUser rebuilds one user's mixes for the given day. Mixes rotate daily
+// but are stable within a day, so re-running a refresh is harmless.
+func (g *Generator) RefreshUser(ctx context.Context, userID uuid.UUID, day time.Time) (int, error) {
+	candidates, err := g.store.Candidates(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	mixes := Build(candidates, g.opts, daySeed(userID, day))
+	if err := g.store.ReplaceDailyMixes(ctx, userID, mixes); err != nil {
+		return 0, err
+	}
+	return len(mixes), nil
+}
+
+// RefreshAll rebuilds mixes for every user. A failure for one user is logged
+// and counted without stopping the pass.
+func (g *Generator) RefreshAll(ctx context.Context) (Report, error) {
+	users, err := g.store.UsersWithLibrary(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	day := g.now().UTC()
+	report := Report{Users: len(users)}
+	for _, userID := range users {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		mixes, err := g.RefreshUser(ctx, userID, day)
+		if err != nil {
+			report.Failures++
+			log.Printf("Warning: daily mix refresh failed for user %s: %v", userID, err)
+			continue
+		}
+		report.Mixes += mixes
+	}
+	return report, nil
+}
+
+// Start launches the refresh loop in the background.
+func (g *Generator) Start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.running = true
+	g.stop = cancel
+	g.wg.Add(1)
+	go g.loop(ctx)
+}
+
+// Stop cancels the loop and waits for an in-flight refresh to return.
+func (g *Generator) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.running {
+		g.mu.Unlock()
+		return nil
+	}
+	g.running = false
+	g.stop()
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { g.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Generator) loop(ctx context.Context) {
+	defer g.wg.Done()
+	for {
+		report, err := g.RefreshAll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: daily mix refresh failed: %v", err)
+		} else if err == nil {
+			log.Printf("Daily mix refresh completed: users=%d mixes=%d failures=%d", report.Users, report.Mixes, report.Failures)
+		}
+
+		timer := time.NewTimer(time.Until(nextRun(g.now(), g.hourUTC)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRun returns the next time strictly after now at hourUTC:00 UTC.
+func nextRun(now time.Time, hourUTC int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hourUTC, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// daySeed derives the rotation seed for a user's mixes on a UTC calendar day.
+func daySeed(userID uuid.UUID, day time.Time) uint64 {
+	h := fnv.New64a()
+	h.Write(userID[:])
+	h.Write([]byte(day.UTC().Format("2006-01-02")))
+	return h.Sum64()
+}
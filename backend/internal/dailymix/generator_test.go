@@ -0,0 +1,76 @@
+package dailymix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeStore struct {
+	users      []uuid.UUID
+	candidates map[uuid.UUID][]db.DailyMixCandidate
+	failFor    uuid.UUID
+	saved      map[uuid.UUID][]db.DailyMix
+}
+
+func (f *fakeStore) UsersWithLibrary(ctx context.Context) ([]uuid.UUID, error) {
+	return f.users, nil
+}
+
+func (f *fakeStore) Candidates(ctx context.Context, userID uuid.UUID) ([]db.DailyMixCandidate, error) {
+	if userID == f.failFor {
+		return nil, errors.New("boom")
+	}
+	return f.candidates[userID], nil
+}
+
+func (f *fakeStore) ReplaceDailyMixes(ctx context.Context, userID uuid.UUID, mixes []db.DailyMix) error {
+	f.saved[userID] = mixes
+	return nil
+}
+
+func TestRefreshAllContinuesPastFailures(t *testing.T) {
+	good, bad, empty := uuid.New(), uuid.New(), uuid.New()
+	store := &fakeStore{
+		users:      []uuid.UUID{bad, good, empty},
+		candidates: map[uuid.UUID][]db.DailyMixCandidate{good: candidates(1, 10, "House", "A", nil, 0)},
+		failFor:    bad,
+		saved:      map[uuid.UUID][]db.DailyMix{},
+	}
+	g := NewGenerator(store, DefaultOptions(), 4)
+
+	report, err := g.RefreshAll(context.Background())
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if report.Users != 3 || report.Mixes != 1 || report.Failures != 1 {
+		t.Fatalf("report = %+v, want 3 users, 1 mix, 1 failure", report)
+	}
+	if len(store.saved[good]) != 1 {
+		t.Fatalf("good user mixes = %+v", store.saved[good])
+	}
+	if mixes, ok := store.saved[empty]; !ok || len(mixes) != 0 {
+		t.Fatalf("empty user should have stale mixes cleared, got %+v (saved=%v)", mixes, ok)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	cases := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 3, 1, 2, 30, 0, 0, time.UTC), time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 4, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		if got := nextRun(tc.now, 4); !got.Equal(tc.want) {
+			t.Fatalf("nextRun(%s) = %s, want %s", tc.now, got, tc.want)
+		}
+	}
+}
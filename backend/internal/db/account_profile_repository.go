@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountProfile is the display metadata every user has, independent of the
+// opt-in public profile. DisplayName, when set, replaces the account username
+// in collaborator lists, comments, activity, and public profiles.
+type AccountProfile struct {
+	UserID      uuid.UUID
+	Username    string
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	AvatarKey   sql.NullString
+	// TimeZone is an IANA zone name used to bucket the user's stats by local
+	// day; unset means UTC.
+	TimeZone  sql.NullString
+	UpdatedAt time.Time
+}
+
+// GetAccountProfile returns the user's display metadata.
+func (r *UserRepository) GetAccountProfile(ctx context.Context, userID uuid.UUID) (*AccountProfile, error) {
+	var p AccountProfile
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, username, display_name, bio, avatar_key, time_zone, updated_at
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&p.UserID, &p.Username, &p.DisplayName, &p.Bio, &p.AvatarKey, &p.TimeZone, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdateAccountProfile saves the display name, bio, and time zone. The avatar
+// is managed separately through SetAvatarKey.
+func (r *UserRepository) UpdateAccountProfile(ctx context.Context, profile *AccountProfile) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE users
+		SET display_name = $2, bio = $3, time_zone = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, profile.UserID, profile.DisplayName, profile.Bio, profile.TimeZone).Scan(&profile.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// SetAvatarKey stores the object key of the user's avatar, or clears it when
+// key is invalid, and returns the key it replaced.
+func (r *UserRepository) SetAvatarKey(ctx context.Context, userID uuid.UUID, key sql.NullString) (sql.NullString, error) {
+	var previous sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE users u
+		SET avatar_key = $2, updated_at = NOW()
+		FROM (SELECT id, avatar_key FROM users WHERE id = $1 FOR UPDATE) old
+		WHERE u.id = old.id
+		RETURNING old.avatar_key
+	`, userID, key).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, ErrUserNotFound
+	}
+	return previous, err
+}
+
+// TimeZone returns the user's IANA time zone name, or "" when none is set.
+func (r *UserRepository) TimeZone(ctx context.Context, userID uuid.UUID) (string, error) {
+	var zone sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT time_zone FROM users WHERE id = $1`, userID).Scan(&zone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return zone.String, err
+}
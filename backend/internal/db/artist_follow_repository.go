@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrArtistFollowNotFound = errors.New("artist follow not found")
+
+// ArtistFollow is a MusicBrainz artist the user follows.
+type ArtistFollow struct {
+	UserID     uuid.UUID
+	MBArtistID uuid.UUID
+	Name       string
+	CreatedAt  time.Time
+}
+
+// ListFollowedArtists returns the artists the user follows by name.
+func (r *UserRepository) ListFollowedArtists(ctx context.Context, userID uuid.UUID) ([]ArtistFollow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, mb_artist_id, name, created_at
+		FROM artist_follows
+		WHERE user_id = $1
+		ORDER BY lower(name), mb_artist_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []ArtistFollow
+	for rows.Next() {
+		var f ArtistFollow
+		if err := rows.Scan(&f.UserID, &f.MBArtistID, &f.Name, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, f)
+	}
+	return follows, rows.Err()
+}
+
+// FollowArtist adds the artist to the user's follows, refreshing its name if
+// it is already followed.
+func (r *UserRepository) FollowArtist(ctx context.Context, follow *ArtistFollow) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO artist_follows (user_id, mb_artist_id, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, mb_artist_id) DO UPDATE SET name = EXCLUDED.name
+		RETURNING created_at
+	`, follow.UserID, follow.MBArtistID, follow.Name).Scan(&follow.CreatedAt)
+}
+
+// UnfollowArtist removes the artist from the user's follows.
+func (r *UserRepository) UnfollowArtist(ctx context.Context, userID, mbArtistID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM artist_follows WHERE user_id = $1 AND mb_artist_id = $2
+	`, userID, mbArtistID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrArtistFollowNotFound
+	}
+	return nil
+}
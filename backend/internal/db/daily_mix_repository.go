@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PlaylistSystemKindDailyMix marks the generated, read-only Daily Mix playlists.
+const PlaylistSystemKindDailyMix = "daily_mix"
+
+// DailyMixCandidate is one library track considered when clustering a user's
+// Daily Mixes. Energy is the analyzed energy (manual override first), if any.
+type DailyMixCandidate struct {
+	TrackID   int64
+	Artist    string
+	Genre     string
+	Energy    *float64
+	PlayCount int
+}
+
+// DailyMix is one generated playlist, identified per user by Slot (1-based).
+type DailyMix struct {
+	Slot        int
+	Name        string
+	Description string
+	TrackIDs    []int64
+}
+
+// DailyMixRepository reads library candidates for the Daily Mix generator and
+// stores its output as system playlists.
+type DailyMixRepository struct {
+	db *DB
+}
+
+func NewDailyMixRepository(db *DB) *DailyMixRepository {
+	return &DailyMixRepository{db: db}
+}
+
+// UsersWithLibrary returns every user that has at least one library track.
+func (r *DailyMixRepository) UsersWithLibrary(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM user_library ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Candidates returns the user's library tracks with the attributes the
+// generator clusters on.
+func (r *DailyMixRepository) Candidates(ctx context.Context, userID uuid.UUID) ([]DailyMixCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, COALESCE(t.artist, ''), COALESCE(t.genre, ''),
+			COALESCE(ta.overrides_json->'energy', ta.summary_json->'energy'),
+			ul.play_count
+		FROM user_library ul
+		JOIN tracks t ON t.id = ul.track_id
+		LEFT JOIN track_analysis ta ON ta.track_id = t.id
+		WHERE ul.user_id = $1
+		ORDER BY t.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []DailyMixCandidate
+	for rows.Next() {
+		var c DailyMixCandidate
+		var energy []byte
+		if err := rows.Scan(&c.TrackID, &c.Artist, &c.Genre, &energy, &c.PlayCount); err != nil {
+			return nil, err
+		}
+		if value := decodeCompactNumberValue(energy); value != nil {
+			c.Energy = value.Value
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// ReplaceDailyMixes makes the user's Daily Mix playlists match mixes: each slot
+// is created or renamed and its tracks rewritten, and slots no longer produced
+// are deleted. Playlist IDs stay stable across refreshes for surviving slots.
+func (r *DailyMixRepository) ReplaceDailyMixes(ctx context.Context, userID uuid.UUID, mixes []DailyMix) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	slots := make([]int64, 0, len(mixes))
+	for _, mix := range mixes {
+		var playlistID int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO playlists (user_id, name, description, is_public, system_kind, system_slot)
+			VALUES ($1, $2, $3, FALSE, $4, $5)
+			ON CONFLICT (user_id, system_kind, system_slot) WHERE system_kind IS NOT NULL
+			DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = NOW()
+			RETURNING id
+		`, userID, mix.Name, mix.Description, PlaylistSystemKindDailyMix, mix.Slot).Scan(&playlistID)
+		if err != nil {
+			return err
+		}
+		if _, err := writePlaylistOrder(ctx, tx, playlistID, mix.TrackIDs); err != nil {
+			return err
+		}
+		slots = append(slots, int64(mix.Slot))
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM playlists
+		WHERE user_id = $1 AND system_kind = $2 AND NOT (system_slot = ANY($3))
+	`, userID, PlaylistSystemKindDailyMix, pq.Array(slots)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
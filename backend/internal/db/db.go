@@ -237,7 +237,7 @@ func (db *DB) Migrate() error {
 			char_length(BTRIM(recommended_candidate_id)) BETWEEN 1 AND 256
 		),
 		CONSTRAINT chk_source_selection_decisions_action CHECK (action IN ('accepted', 'overridden')),
-		CONSTRAINT chk_source_selection_decisions_origin CHECK (origin IN ('discovery', 'direct_url', 'playlist_explicit', 'research')),
+		CONSTRAINT chk_source_selection_decisions_origin CHECK (origin IN ('discovery', 'direct_url', 'playlist_explicit', 'research', 'release')),
 		CONSTRAINT chk_source_selection_decisions_reason CHECK (reason IS NULL OR char_length(BTRIM(reason)) BETWEEN 1 AND 2000),
 		CONSTRAINT chk_source_selection_decisions_candidate CHECK (
 			jsonb_typeof(selected_candidate) = 'object'
@@ -409,6 +409,8 @@ func (db *DB) Migrate() error {
 		ON playlist_import_items(playlist_source_entry_id) WHERE playlist_source_entry_id IS NOT NULL;
 
 	CREATE INDEX IF NOT EXISTS idx_tracks_fulltext ON tracks USING GIN (to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(artist, '') || ' ' || COALESCE(album, '')));
+	CREATE INDEX IF NOT EXISTS idx_tracks_artist_fulltext ON tracks USING GIN (to_tsvector('english', artist)) WHERE artist IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_tracks_album_fulltext ON tracks USING GIN (to_tsvector('english', album)) WHERE album IS NOT NULL;
 
 	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS source_url TEXT;
 	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS source_type VARCHAR(50);
@@ -427,6 +429,22 @@ func (db *DB) Migrate() error {
 	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS cover_art_url TEXT;
 	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS metadata_user_edited BOOLEAN NOT NULL DEFAULT FALSE;
 	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS genre VARCHAR(200);
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS artwork_key TEXT;
+
+	-- Waveforms are found through the track row, like covers, so rehashing
+	-- identities does not strand them. The first start with the column points
+	-- existing tracks at the identity-hash key waveforms were stored under;
+	-- tracks that never got one are told apart by the object being missing.
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'tracks' AND column_name = 'waveform_key'
+		) THEN
+			ALTER TABLE tracks ADD COLUMN waveform_key TEXT;
+			UPDATE tracks SET waveform_key = 'waveforms/tracks/' || identity_hash || '.json';
+		END IF;
+	END $$;
 
 	CREATE INDEX IF NOT EXISTS idx_tracks_genre ON tracks(genre);
 
@@ -751,6 +769,644 @@ func (db *DB) Migrate() error {
 		CONSTRAINT chk_research_user_runtime_slots_active_runs CHECK (active_run_count >= 0)
 	);
 
+	CREATE TABLE IF NOT EXISTS user_profiles (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		handle VARCHAR(32) NOT NULL,
+		is_public BOOLEAN NOT NULL DEFAULT FALSE,
+		show_playlists BOOLEAN NOT NULL DEFAULT TRUE,
+		show_top_artists BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		CONSTRAINT chk_user_profiles_handle CHECK (handle ~ '^[a-z0-9][a-z0-9_-]{2,31}$')
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_user_profiles_handle ON user_profiles(handle);
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS show_on_profile BOOLEAN NOT NULL DEFAULT FALSE;
+	CREATE INDEX IF NOT EXISTS idx_playlists_user_profile
+		ON playlists(user_id, updated_at DESC) WHERE show_on_profile = TRUE;
+
+	CREATE TABLE IF NOT EXISTS playlist_collaborators (
+		playlist_id BIGINT NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (playlist_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_playlist_collaborators_user_id ON playlist_collaborators(user_id);
+
+	CREATE TABLE IF NOT EXISTS playlist_comments (
+		id BIGSERIAL PRIMARY KEY,
+		playlist_id BIGINT NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+		track_id BIGINT REFERENCES tracks(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		CONSTRAINT chk_playlist_comments_body CHECK (char_length(BTRIM(body)) BETWEEN 1 AND 2000)
+	);
+	CREATE INDEX IF NOT EXISTS idx_playlist_comments_playlist_created
+		ON playlist_comments(playlist_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_playlist_comments_track
+		ON playlist_comments(playlist_id, track_id) WHERE track_id IS NOT NULL;
+
+	CREATE TABLE IF NOT EXISTS playlist_activity (
+		id BIGSERIAL PRIMARY KEY,
+		playlist_id BIGINT NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+		actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+		kind VARCHAR(32) NOT NULL,
+		payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		CONSTRAINT chk_playlist_activity_payload CHECK (
+			jsonb_typeof(payload) = 'object' AND octet_length(payload::text) <= 16384
+		)
+	);
+	CREATE INDEX IF NOT EXISTS idx_playlist_activity_playlist_created
+		ON playlist_activity(playlist_id, created_at DESC, id DESC);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind VARCHAR(32) NOT NULL,
+		payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+		read_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		CONSTRAINT chk_notifications_payload CHECK (
+			jsonb_typeof(payload) = 'object' AND octet_length(payload::text) <= 16384
+		)
+	);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user_created
+		ON notifications(user_id, created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user_unread
+		ON notifications(user_id) WHERE read_at IS NULL;
+
+	-- Playlist history: an ordered snapshot of track IDs taken after each track
+	-- mutation so collaborators can undo bulk edits by reverting to a version.
+	CREATE TABLE IF NOT EXISTS playlist_versions (
+		playlist_id BIGINT NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+		version INTEGER NOT NULL,
+		track_ids BIGINT[] NOT NULL DEFAULT '{}',
+		actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+		reason VARCHAR(32) NOT NULL,
+		reverted_from INTEGER,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (playlist_id, version)
+	);
+
+	-- Per-library-entry play aggregates, bumped by RecordPlay so library listings
+	-- can sort by play count without aggregating play_events per request. Counts
+	-- start when the track was added; removing and re-adding resets them. The
+	-- column is added nullable and backfilled once before the default applies.
+	ALTER TABLE user_library ADD COLUMN IF NOT EXISTS play_count INTEGER;
+	ALTER TABLE user_library ADD COLUMN IF NOT EXISTS last_played_at TIMESTAMPTZ;
+	UPDATE user_library ul
+	SET play_count = agg.play_count, last_played_at = agg.last_played_at
+	FROM (
+		SELECT l.user_id, l.track_id, COUNT(pe.id) AS play_count, MAX(pe.played_at) AS last_played_at
+		FROM user_library l
+		LEFT JOIN play_events pe
+			ON pe.user_id = l.user_id AND pe.track_id = l.track_id AND pe.played_at >= l.added_at
+		WHERE l.play_count IS NULL
+		GROUP BY l.user_id, l.track_id
+	) agg
+	WHERE ul.user_id = agg.user_id AND ul.track_id = agg.track_id;
+	ALTER TABLE user_library ALTER COLUMN play_count SET DEFAULT 0;
+	ALTER TABLE user_library ALTER COLUMN play_count SET NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_user_library_play_count ON user_library(user_id, play_count);
+
+	-- System-generated playlists (e.g. Daily Mixes) are owned by the user they
+	-- were built for but are read-only through the API. system_slot orders the
+	-- generated playlists of one kind for a user.
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS system_kind VARCHAR(32);
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS system_slot SMALLINT;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_playlists_user_system_slot
+		ON playlists(user_id, system_kind, system_slot) WHERE system_kind IS NOT NULL;
+
+	-- Year-in-review reports. The document is the rendered JSON snapshot so a
+	-- shared link keeps showing what the owner saw; share_token is NULL until the
+	-- owner shares it.
+	CREATE TABLE IF NOT EXISTS wrapped_reports (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		year INTEGER NOT NULL,
+		document JSONB NOT NULL,
+		share_token VARCHAR(64),
+		generated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, year)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_wrapped_reports_share_token
+		ON wrapped_reports(share_token) WHERE share_token IS NOT NULL;
+
+	-- Per-user star ratings (1-5). source records where the rating came from,
+	-- e.g. an iTunes library import.
+	CREATE TABLE IF NOT EXISTS track_ratings (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+		rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+		source VARCHAR(32),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, track_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_track_ratings_track_id ON track_ratings(track_id);
+
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS storage_key VARCHAR(500);
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS file_size_bytes BIGINT;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS codec TEXT;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS bitrate_kbps INTEGER;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS sample_rate_hz INTEGER;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS channels INTEGER;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS content_type TEXT;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS peak_dbfs DOUBLE PRECISION;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS clipping_ratio DOUBLE PRECISION;
+	ALTER TABLE track_sources ADD COLUMN IF NOT EXISTS quality_measured_at TIMESTAMP WITH TIME ZONE;
+
+	-- Library audits filter by ingest platform; date ranges use idx_user_library_added_at.
+	CREATE INDEX IF NOT EXISTS idx_tracks_source_type ON tracks(source_type) WHERE source_type IS NOT NULL;
+
+	-- Content takedowns block a source URL glob or a track identity hash. Matching
+	-- tracks are quarantined (unstreamable) until the takedown is lifted.
+	CREATE TABLE IF NOT EXISTS content_takedowns (
+		id BIGSERIAL PRIMARY KEY,
+		kind VARCHAR(20) NOT NULL CHECK (kind IN ('source_pattern', 'identity_hash')),
+		pattern TEXT NOT NULL,
+		like_pattern TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		lifted_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_content_takedowns_active ON content_takedowns(kind) WHERE lifted_at IS NULL;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS quarantine_takedown_id BIGINT REFERENCES content_takedowns(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_tracks_quarantine_takedown ON tracks(quarantine_takedown_id) WHERE quarantine_takedown_id IS NOT NULL;
+
+	CREATE TABLE IF NOT EXISTS download_outcomes (
+		id BIGSERIAL PRIMARY KEY,
+		provider VARCHAR(50) NOT NULL,
+		outcome VARCHAR(32) NOT NULL,
+		job_id VARCHAR(64) NOT NULL,
+		recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_download_outcomes_recorded ON download_outcomes(recorded_at DESC);
+
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS artwork_key TEXT;
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS mosaic_key TEXT;
+	ALTER TABLE playlists ADD COLUMN IF NOT EXISTS mosaic_sources TEXT;
+
+	-- Playlist track counts and durations are kept on the playlist row so
+	-- list endpoints need no join. refresh_playlist_totals recomputes them,
+	-- NULL meaning every playlist; it locks the rows first so a concurrent
+	-- increment lands on the recomputed value instead of being lost.
+	CREATE OR REPLACE FUNCTION refresh_playlist_totals(ids BIGINT[])
+	RETURNS INTEGER AS $$
+	DECLARE
+		changed INTEGER;
+	BEGIN
+		PERFORM 1 FROM playlists WHERE ids IS NULL OR id = ANY(ids) ORDER BY id FOR UPDATE;
+		UPDATE playlists p
+		SET track_count = totals.track_count, total_duration_ms = totals.total_duration_ms
+		FROM (
+			SELECT p2.id, COUNT(pt.track_id) AS track_count, COALESCE(SUM(t.duration_ms), 0) AS total_duration_ms
+			FROM playlists p2
+			LEFT JOIN playlist_tracks pt ON pt.playlist_id = p2.id
+			LEFT JOIN tracks t ON t.id = pt.track_id
+			WHERE ids IS NULL OR p2.id = ANY(ids)
+			GROUP BY p2.id
+		) totals
+		WHERE p.id = totals.id
+		  AND (p.track_count <> totals.track_count OR p.total_duration_ms <> totals.total_duration_ms);
+		GET DIAGNOSTICS changed = ROW_COUNT;
+		RETURN changed;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'playlists' AND column_name = 'track_count'
+		) THEN
+			ALTER TABLE playlists ADD COLUMN track_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE playlists ADD COLUMN total_duration_ms BIGINT NOT NULL DEFAULT 0;
+			PERFORM refresh_playlist_totals(NULL);
+		END IF;
+	END $$;
+
+	-- Additions are counted incrementally. Removals recompute the affected
+	-- playlists because a removal cascading from a deleted track can no
+	-- longer read that track's duration.
+	CREATE OR REPLACE FUNCTION playlist_tracks_added()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		UPDATE playlists p
+		SET track_count = p.track_count + added.track_count,
+			total_duration_ms = p.total_duration_ms + added.total_duration_ms
+		FROM (
+			SELECT a.playlist_id, COUNT(*) AS track_count, COALESCE(SUM(t.duration_ms), 0) AS total_duration_ms
+			FROM added_rows a
+			LEFT JOIN tracks t ON t.id = a.track_id
+			GROUP BY a.playlist_id
+		) added
+		WHERE p.id = added.playlist_id;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS trg_playlist_tracks_added ON playlist_tracks;
+	CREATE TRIGGER trg_playlist_tracks_added
+		AFTER INSERT ON playlist_tracks
+		REFERENCING NEW TABLE AS added_rows
+		FOR EACH STATEMENT EXECUTE FUNCTION playlist_tracks_added();
+
+	CREATE OR REPLACE FUNCTION playlist_tracks_removed()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		PERFORM refresh_playlist_totals(ARRAY(SELECT DISTINCT playlist_id FROM removed_rows));
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS trg_playlist_tracks_removed ON playlist_tracks;
+	CREATE TRIGGER trg_playlist_tracks_removed
+		AFTER DELETE ON playlist_tracks
+		REFERENCING OLD TABLE AS removed_rows
+		FOR EACH STATEMENT EXECUTE FUNCTION playlist_tracks_removed();
+
+	-- Reorders only change positions; only rows whose playlist or track
+	-- changed, as when duplicate tracks are merged, need a recompute.
+	CREATE OR REPLACE FUNCTION playlist_tracks_moved()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		PERFORM refresh_playlist_totals(ARRAY(
+			SELECT playlist_id FROM (
+				(SELECT playlist_id, track_id FROM old_rows EXCEPT SELECT playlist_id, track_id FROM new_rows)
+				UNION
+				(SELECT playlist_id, track_id FROM new_rows EXCEPT SELECT playlist_id, track_id FROM old_rows)
+			) moved
+		));
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS trg_playlist_tracks_moved ON playlist_tracks;
+	CREATE TRIGGER trg_playlist_tracks_moved
+		AFTER UPDATE ON playlist_tracks
+		REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+		FOR EACH STATEMENT EXECUTE FUNCTION playlist_tracks_moved();
+
+	CREATE OR REPLACE FUNCTION playlist_track_duration_changed()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		UPDATE playlists p
+		SET total_duration_ms = p.total_duration_ms + COALESCE(NEW.duration_ms, 0) - COALESCE(OLD.duration_ms, 0)
+		FROM playlist_tracks pt
+		WHERE pt.track_id = NEW.id AND p.id = pt.playlist_id;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS trg_playlist_track_duration_changed ON tracks;
+	CREATE TRIGGER trg_playlist_track_duration_changed
+		AFTER UPDATE OF duration_ms ON tracks
+		FOR EACH ROW
+		WHEN (OLD.duration_ms IS DISTINCT FROM NEW.duration_ms)
+		EXECUTE FUNCTION playlist_track_duration_changed();
+
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(64);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS bio VARCHAR(500);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key TEXT;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'member';
+
+	-- Where a finished download goes when the request does not say. No row
+	-- means the defaults: add to the library only.
+	CREATE TABLE IF NOT EXISTS user_download_settings (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		add_to_library BOOLEAN NOT NULL DEFAULT TRUE,
+		playlist_id BIGINT REFERENCES playlists(id) ON DELETE SET NULL,
+		queue_next BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	-- Files clients PUT straight to object storage. An upload is pending until
+	-- the client finalizes it, which queues the download job that imports it.
+	CREATE TABLE IF NOT EXISTS uploads (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		object_key TEXT NOT NULL,
+		filename VARCHAR(255) NOT NULL,
+		content_type VARCHAR(100) NOT NULL,
+		declared_size_bytes BIGINT NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		download_job_id VARCHAR(64),
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		finalized_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_uploads_pending_expiry ON uploads(expires_at) WHERE status = 'pending';
+
+	-- Resumable uploads go to object storage as a multipart upload of parts
+	-- of part_size_bytes; both are NULL for a single presigned PUT.
+	ALTER TABLE uploads ADD COLUMN IF NOT EXISTS multipart_upload_id TEXT;
+	ALTER TABLE uploads ADD COLUMN IF NOT EXISTS part_size_bytes BIGINT;
+
+	-- How much of the track was heard; NULL for plays recorded without it.
+	ALTER TABLE play_events ADD COLUMN IF NOT EXISTS duration_listened_ms INTEGER;
+
+	-- Accounts on external scrobbling services that the user's plays are
+	-- forwarded to, one per service. token is the service's user token.
+	CREATE TABLE IF NOT EXISTS scrobble_connections (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		service VARCHAR(32) NOT NULL,
+		token TEXT NOT NULL,
+		username VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, service)
+	);
+
+	-- Grants let listeners stream tracks they do not own. Each capability token
+	-- names one grant and one listed track; a grant without a grantee is usable
+	-- by anyone holding its tokens. Revoking or expiring the grant invalidates
+	-- every token at once.
+	CREATE TABLE IF NOT EXISTS track_grants (
+		id UUID PRIMARY KEY,
+		owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		context_type VARCHAR(32) NOT NULL,
+		context_id VARCHAR(128) NOT NULL,
+		grantee_id UUID REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_track_grants_owner ON track_grants(owner_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_track_grants_context ON track_grants(context_type, context_id) WHERE revoked_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS track_grant_tracks (
+		grant_id UUID NOT NULL REFERENCES track_grants(id) ON DELETE CASCADE,
+		track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+		PRIMARY KEY (grant_id, track_id)
+	);
+
+	-- MusicBrainz artists whose releases appear in the user's release
+	-- calendar. name is kept so the list renders without a lookup.
+	CREATE TABLE IF NOT EXISTS artist_follows (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		mb_artist_id UUID NOT NULL,
+		name VARCHAR(500) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, mb_artist_id)
+	);
+
+	-- Admin overrides of the configured retention periods, one row per data
+	-- type. max_age_days = 0 keeps the data forever.
+	CREATE TABLE IF NOT EXISTS retention_policies (
+		data_type VARCHAR(32) PRIMARY KEY,
+		max_age_days INTEGER NOT NULL CHECK (max_age_days >= 0),
+		updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+	-- Retention purges scan by age across all users.
+	CREATE INDEX IF NOT EXISTS idx_play_events_played_at ON play_events(played_at);
+	CREATE INDEX IF NOT EXISTS idx_playlist_activity_created ON playlist_activity(created_at);
+	CREATE INDEX IF NOT EXISTS idx_notifications_created ON notifications(created_at);
+	CREATE INDEX IF NOT EXISTS idx_download_jobs_failed_updated
+		ON download_jobs(updated_at) WHERE status = 'failed';
+
+	-- Per-user API keys for third-party clients. Only the SHA-256 of a key
+	-- is stored; prefix is kept so users can tell their keys apart.
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		prefix VARCHAR(16) NOT NULL,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		scopes TEXT[] NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		revoked_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+	-- Free-form labels users put on tracks in their library. Removing a
+	-- track from the library removes its tags.
+	CREATE TABLE IF NOT EXISTS track_tags (
+		user_id UUID NOT NULL,
+		track_id BIGINT NOT NULL,
+		tag VARCHAR(64) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, track_id, tag),
+		FOREIGN KEY (user_id, track_id) REFERENCES user_library(user_id, track_id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_track_tags_user_tag ON track_tags(user_id, tag);
+
+	-- Listening goals: at most one goal of each kind per user. met_period and
+	-- nudged_period hold the local start date of the last period a goal_met or
+	-- goal_nudge notification was sent for, so each is sent once per period.
+	CREATE TABLE IF NOT EXISTS listening_goals (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind VARCHAR(32) NOT NULL,
+		target INTEGER NOT NULL CHECK (target > 0),
+		met_period DATE,
+		nudged_period DATE,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		UNIQUE (user_id, kind)
+	);
+
+	-- OIDC single sign-on. user_identities links a provider account (issuer
+	-- and subject) to a local user. oidc_login_states holds the nonce and PKCE
+	-- verifier of a login in progress, and oidc_handoffs the one-time codes
+	-- clients trade for tokens after the callback; both are keyed by SHA-256
+	-- hashes and expire within minutes.
+	CREATE TABLE IF NOT EXISTS user_identities (
+		issuer VARCHAR(512) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		email VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		last_login_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (issuer, subject)
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+	CREATE TABLE IF NOT EXISTS oidc_login_states (
+		state_hash VARCHAR(64) PRIMARY KEY,
+		nonce VARCHAR(64) NOT NULL,
+		code_verifier VARCHAR(128) NOT NULL,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS oidc_handoffs (
+		code_hash VARCHAR(64) PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	-- The most recent matcher run for each track, kept so users can see why a
+	-- track was tagged the way it was. Each run replaces the previous one.
+	CREATE TABLE IF NOT EXISTS track_match_explanations (
+		track_id BIGINT PRIMARY KEY REFERENCES tracks(id) ON DELETE CASCADE,
+		explanation JSONB NOT NULL,
+		attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	-- A sign-in on one device. Each refresh rotates the session's refresh
+	-- token; presenting a rotated-out token revokes the whole session.
+	CREATE TABLE IF NOT EXISTS auth_sessions (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		device_name VARCHAR(100) NOT NULL DEFAULT '',
+		user_agent VARCHAR(512) NOT NULL DEFAULT '',
+		ip_address VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		revoked_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id, last_used_at DESC);
+	ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES auth_sessions(id) ON DELETE CASCADE;
+	CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+
+	-- EBU R128 loudness of the stored audio and the ReplayGain values derived
+	-- from it. Album values cover every measured track of the same release.
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS loudness_lufs DOUBLE PRECISION;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS true_peak_dbtp DOUBLE PRECISION;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS track_gain_db DOUBLE PRECISION;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS album_gain_db DOUBLE PRECISION;
+	ALTER TABLE tracks ADD COLUMN IF NOT EXISTS album_peak_dbtp DOUBLE PRECISION;
+
+	-- Lyrics fetched from LRCLIB or entered by a listener. A 'none' row
+	-- remembers a failed lookup so it is only retried after a while; manual
+	-- rows are never replaced by a fetch.
+	CREATE TABLE IF NOT EXISTS track_lyrics (
+		track_id BIGINT PRIMARY KEY REFERENCES tracks(id) ON DELETE CASCADE,
+		source VARCHAR(16) NOT NULL CHECK (source IN ('lrclib', 'manual', 'none')),
+		plain_lyrics TEXT NOT NULL DEFAULT '',
+		synced_lyrics TEXT NOT NULL DEFAULT '',
+		instrumental BOOLEAN NOT NULL DEFAULT FALSE,
+		external_id BIGINT,
+		edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	-- Artists and releases as entities keyed by their MusicBrainz IDs, so a
+	-- rename or artwork applies wherever they appear. Tracks keep their own
+	-- artist and album text, which their identity hash is built from. An
+	-- entity renamed by an admin keeps its name when later matches name it.
+	CREATE TABLE IF NOT EXISTS artists (
+		mb_artist_id UUID PRIMARY KEY,
+		name VARCHAR(500) NOT NULL DEFAULT '',
+		name_edited BOOLEAN NOT NULL DEFAULT FALSE,
+		artwork_key TEXT,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS releases (
+		mb_release_id UUID PRIMARY KEY,
+		title VARCHAR(500) NOT NULL DEFAULT '',
+		mb_artist_id UUID REFERENCES artists(mb_artist_id) ON DELETE SET NULL,
+		title_edited BOOLEAN NOT NULL DEFAULT FALSE,
+		artwork_key TEXT,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_releases_mb_artist_id ON releases(mb_artist_id);
+	CREATE INDEX IF NOT EXISTS idx_artists_name_fulltext ON artists USING GIN (to_tsvector('english', name));
+	CREATE INDEX IF NOT EXISTS idx_releases_title_fulltext ON releases USING GIN (to_tsvector('english', title));
+	CREATE INDEX IF NOT EXISTS idx_tracks_mb_artist_id ON tracks(mb_artist_id) WHERE mb_artist_id IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_tracks_mb_release_id ON tracks(mb_release_id) WHERE mb_release_id IS NOT NULL;
+
+	-- The first start with entity tables names them from the newest track
+	-- text for each ID, then ties tracks to them.
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_tracks_mb_artist') THEN
+			INSERT INTO artists (mb_artist_id, name)
+			SELECT DISTINCT ON (mb_artist_id) mb_artist_id, COALESCE(btrim(artist), '')
+			FROM tracks
+			WHERE mb_artist_id IS NOT NULL
+			ORDER BY mb_artist_id, mb_verified DESC, updated_at DESC
+			ON CONFLICT (mb_artist_id) DO NOTHING;
+			ALTER TABLE tracks ADD CONSTRAINT fk_tracks_mb_artist
+				FOREIGN KEY (mb_artist_id) REFERENCES artists(mb_artist_id) ON DELETE SET NULL;
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_tracks_mb_release') THEN
+			INSERT INTO releases (mb_release_id, title, mb_artist_id)
+			SELECT DISTINCT ON (mb_release_id) mb_release_id, COALESCE(btrim(album), ''), mb_artist_id
+			FROM tracks
+			WHERE mb_release_id IS NOT NULL
+			ORDER BY mb_release_id, mb_verified DESC, updated_at DESC
+			ON CONFLICT (mb_release_id) DO NOTHING;
+			ALTER TABLE tracks ADD CONSTRAINT fk_tracks_mb_release
+				FOREIGN KEY (mb_release_id) REFERENCES releases(mb_release_id) ON DELETE SET NULL;
+		END IF;
+	END $$;
+
+	-- MusicBrainz genres of a track's matched recording, as tags every
+	-- listener sees. Tags users add themselves live in track_tags.
+	CREATE TABLE IF NOT EXISTS track_genres (
+		track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+		genre VARCHAR(64) NOT NULL,
+		votes INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (track_id, genre)
+	);
+	CREATE INDEX IF NOT EXISTS idx_track_genres_genre ON track_genres(genre);
+
+	-- Plays the listener skipped away from. A skip counts towards the library
+	-- entry's skip_count instead of its play_count.
+	ALTER TABLE play_events ADD COLUMN IF NOT EXISTS skipped BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE user_library ADD COLUMN IF NOT EXISTS skip_count INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS idx_user_library_last_played ON user_library(user_id, last_played_at DESC NULLS LAST);
+
+	-- Hourly per-track play totals, so listening stats read a few rows per
+	-- hour instead of every play. RecordPlayEvent keeps them current and the
+	-- first start backfills them from existing history. Hours are UTC, and
+	-- listened_ms falls back to the track length for plays sent without it.
+	-- Being aggregates, they outlive play_events pruned by retention.
+	DO $$
+	BEGIN
+		IF to_regclass('play_hourly_rollups') IS NULL THEN
+			CREATE TABLE play_hourly_rollups (
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				track_id BIGINT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+				hour TIMESTAMPTZ NOT NULL,
+				plays INTEGER NOT NULL DEFAULT 0,
+				skips INTEGER NOT NULL DEFAULT 0,
+				listened_ms BIGINT NOT NULL DEFAULT 0,
+				PRIMARY KEY (user_id, hour, track_id)
+			);
+			INSERT INTO play_hourly_rollups (user_id, track_id, hour, plays, skips, listened_ms)
+			SELECT pe.user_id, pe.track_id,
+				   date_trunc('hour', pe.played_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+				   COUNT(*) FILTER (WHERE NOT pe.skipped),
+				   COUNT(*) FILTER (WHERE pe.skipped),
+				   SUM(COALESCE(pe.duration_listened_ms, CASE WHEN pe.skipped THEN 0 ELSE t.duration_ms END, 0))
+			FROM play_events pe
+			JOIN tracks t ON t.id = pe.track_id
+			GROUP BY 1, 2, 3;
+		END IF;
+	END $$;
+
+	-- Recommendations look up every play of a seed track.
+	CREATE INDEX IF NOT EXISTS idx_play_events_track_played_at ON play_events(track_id, played_at);
+
+	-- Durable copies of playback queues, which live in Redis with a TTL.
+	-- The queue service writes through to these and reloads a queue from
+	-- here when Redis has evicted or lost it. name is the named queue and
+	-- version the queue's own version; queue_index_snapshots holds each
+	-- user's list of named queues.
+	CREATE TABLE IF NOT EXISTS queue_snapshots (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(32) NOT NULL,
+		version BIGINT NOT NULL DEFAULT 0,
+		state JSONB NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, name)
+	);
+	CREATE TABLE IF NOT EXISTS queue_index_snapshots (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		data JSONB NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
 	`
 
 	_, err = db.Exec(schema)
@@ -874,7 +1530,7 @@ func (db *DB) refreshResearchSchemaConstraints() error {
 		ALTER TABLE source_selection_decisions ADD COLUMN IF NOT EXISTS research_review_id UUID;
 		ALTER TABLE source_selection_decisions DROP CONSTRAINT IF EXISTS chk_source_selection_decisions_origin;
 		ALTER TABLE source_selection_decisions ADD CONSTRAINT chk_source_selection_decisions_origin CHECK (
-			origin IN ('discovery', 'direct_url', 'playlist_explicit', 'research')
+			origin IN ('discovery', 'direct_url', 'playlist_explicit', 'research', 'release')
 		);
 		ALTER TABLE source_selection_decisions DROP CONSTRAINT IF EXISTS chk_source_selection_decisions_research_review;
 		ALTER TABLE source_selection_decisions ADD CONSTRAINT chk_source_selection_decisions_research_review CHECK (
@@ -950,7 +1606,7 @@ func (db *DB) refreshTrackAnalysisStatusConstraint() error {
 }
 
 // tryEnableTrigram installs the pg_trgm extension and its supporting trigram GIN
-// indexes on tracks(title)/tracks(artist). Every step is best-effort: any failure
+// indexes on tracks(title)/tracks(artist)/tracks(album). Every step is best-effort: any failure
 // is logged and results in a false return so callers know the fuzzy fallback is
 // unavailable. It never returns an error, so it can never abort startup.
 func (db *DB) tryEnableTrigram() bool {
@@ -964,6 +1620,7 @@ func (db *DB) tryEnableTrigram() bool {
 	for _, stmt := range []string{
 		`CREATE INDEX IF NOT EXISTS idx_tracks_title_trgm ON tracks USING GIN (title gin_trgm_ops)`,
 		`CREATE INDEX IF NOT EXISTS idx_tracks_artist_trgm ON tracks USING GIN (artist gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_tracks_album_trgm ON tracks USING GIN (album gin_trgm_ops)`,
 	} {
 		if _, err := db.Exec(stmt); err != nil {
 			log.Printf("db: failed to create trigram index (fuzzy search may be slower): %v", err)
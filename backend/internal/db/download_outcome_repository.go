@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// ProviderDownloadReport summarizes terminal download outcomes for one
+// provider over a reporting window.
+type ProviderDownloadReport struct {
+	Provider      string
+	Total         int
+	Outcomes      map[string]int
+	LastSuccessAt sql.NullTime
+	LastFailureAt sql.NullTime
+}
+
+type DownloadOutcomeRepository struct {
+	db *DB
+}
+
+func NewDownloadOutcomeRepository(db *DB) *DownloadOutcomeRepository {
+	return &DownloadOutcomeRepository{db: db}
+}
+
+// Record stores one job's terminal outcome.
+func (r *DownloadOutcomeRepository) Record(ctx context.Context, provider, outcome, jobID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO download_outcomes (provider, outcome, job_id)
+		VALUES (LEFT($1, 50), LEFT($2, 32), LEFT($3, 64))
+	`, provider, outcome, jobID)
+	return err
+}
+
+// ProviderReport returns per-provider outcome counts recorded since the given
+// time, busiest provider first.
+func (r *DownloadOutcomeRepository) ProviderReport(ctx context.Context, since time.Time) ([]ProviderDownloadReport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT provider, outcome, COUNT(*), MAX(recorded_at)
+		FROM download_outcomes
+		WHERE recorded_at >= $1
+		GROUP BY provider, outcome
+		ORDER BY provider, outcome
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byProvider := map[string]*ProviderDownloadReport{}
+	var order []string
+	for rows.Next() {
+		var provider, outcome string
+		var count int
+		var last time.Time
+		if err := rows.Scan(&provider, &outcome, &count, &last); err != nil {
+			return nil, err
+		}
+		report := byProvider[provider]
+		if report == nil {
+			report = &ProviderDownloadReport{Provider: provider, Outcomes: map[string]int{}}
+			byProvider[provider] = report
+			order = append(order, provider)
+		}
+		report.Outcomes[outcome] = count
+		report.Total += count
+		latest := &report.LastFailureAt
+		if outcome == "success" {
+			latest = &report.LastSuccessAt
+		}
+		if !latest.Valid || last.After(latest.Time) {
+			*latest = sql.NullTime{Time: last, Valid: true}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reports := make([]ProviderDownloadReport, 0, len(order))
+	for _, provider := range order {
+		reports = append(reports, *byProvider[provider])
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Total > reports[j].Total })
+	return reports, nil
+}
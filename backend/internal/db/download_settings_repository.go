@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DownloadSettings decides where the user's finished downloads go when the
+// download request does not say.
+type DownloadSettings struct {
+	UserID       uuid.UUID
+	AddToLibrary bool
+	// PlaylistID, when set, is a playlist the user owns that every download
+	// is appended to. Deleting the playlist clears it.
+	PlaylistID sql.NullInt64
+	QueueNext  bool
+	UpdatedAt  time.Time
+}
+
+// DefaultDownloadSettings returns the settings of a user who never changed
+// them: downloads are added to the library and nowhere else.
+func DefaultDownloadSettings(userID uuid.UUID) *DownloadSettings {
+	return &DownloadSettings{UserID: userID, AddToLibrary: true}
+}
+
+// GetDownloadSettings returns the user's download settings, or the defaults
+// when none are saved.
+func (r *UserRepository) GetDownloadSettings(ctx context.Context, userID uuid.UUID) (*DownloadSettings, error) {
+	s := DownloadSettings{UserID: userID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT add_to_library, playlist_id, queue_next, updated_at
+		FROM user_download_settings
+		WHERE user_id = $1
+	`, userID).Scan(&s.AddToLibrary, &s.PlaylistID, &s.QueueNext, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultDownloadSettings(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateDownloadSettings saves the user's download settings. The caller
+// checks that PlaylistID belongs to the user.
+func (r *UserRepository) UpdateDownloadSettings(ctx context.Context, settings *DownloadSettings) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO user_download_settings (user_id, add_to_library, playlist_id, queue_next)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET add_to_library = EXCLUDED.add_to_library,
+			playlist_id = EXCLUDED.playlist_id,
+			queue_next = EXCLUDED.queue_next,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, settings.UserID, settings.AddToLibrary, settings.PlaylistID, settings.QueueNext).Scan(&settings.UpdatedAt)
+}
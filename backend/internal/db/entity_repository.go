@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrArtistNotFound  = errors.New("artist not found")
+	ErrReleaseNotFound = errors.New("release not found")
+)
+
+// EntityKind names the artist or release table an entity lives in.
+type EntityKind string
+
+const (
+	EntityArtist  EntityKind = "artist"
+	EntityRelease EntityKind = "release"
+)
+
+// ArtistEntity is an artist keyed by MusicBrainz ID. TrackCount counts the
+// tracks credited to it.
+type ArtistEntity struct {
+	MBArtistID uuid.UUID
+	Name       string
+	NameEdited bool
+	ArtworkKey sql.NullString
+	TrackCount int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ReleaseEntity is a release keyed by MusicBrainz ID, with the name of its
+// artist when it has one.
+type ReleaseEntity struct {
+	MBReleaseID uuid.UUID
+	Title       string
+	MBArtistID  *uuid.UUID
+	ArtistName  sql.NullString
+	TitleEdited bool
+	ArtworkKey  sql.NullString
+	TrackCount  int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type EntityRepository struct {
+	db *DB
+}
+
+func NewEntityRepository(db *DB) *EntityRepository {
+	return &EntityRepository{db: db}
+}
+
+// GetArtist returns the artist with the MusicBrainz ID.
+func (r *EntityRepository) GetArtist(ctx context.Context, id uuid.UUID) (*ArtistEntity, error) {
+	var a ArtistEntity
+	err := r.db.QueryRowContext(ctx, `
+		SELECT a.mb_artist_id, a.name, a.name_edited, a.artwork_key,
+			   (SELECT COUNT(*) FROM tracks t WHERE t.mb_artist_id = a.mb_artist_id),
+			   a.created_at, a.updated_at
+		FROM artists a
+		WHERE a.mb_artist_id = $1
+	`, id).Scan(&a.MBArtistID, &a.Name, &a.NameEdited, &a.ArtworkKey, &a.TrackCount, &a.CreatedAt, &a.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetRelease returns the release with the MusicBrainz ID.
+func (r *EntityRepository) GetRelease(ctx context.Context, id uuid.UUID) (*ReleaseEntity, error) {
+	var rel ReleaseEntity
+	err := r.db.QueryRowContext(ctx, `
+		SELECT rl.mb_release_id, rl.title, rl.mb_artist_id, a.name, rl.title_edited, rl.artwork_key,
+			   (SELECT COUNT(*) FROM tracks t WHERE t.mb_release_id = rl.mb_release_id),
+			   rl.created_at, rl.updated_at
+		FROM releases rl
+		LEFT JOIN artists a ON a.mb_artist_id = rl.mb_artist_id
+		WHERE rl.mb_release_id = $1
+	`, id).Scan(&rel.MBReleaseID, &rel.Title, &rel.MBArtistID, &rel.ArtistName, &rel.TitleEdited, &rel.ArtworkKey, &rel.TrackCount, &rel.CreatedAt, &rel.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReleaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// RenameArtist sets the artist's name everywhere it is shown and searched.
+func (r *EntityRepository) RenameArtist(ctx context.Context, id uuid.UUID, name string) error {
+	return r.rename(ctx, `
+		UPDATE artists SET name = $2, name_edited = TRUE, updated_at = NOW()
+		WHERE mb_artist_id = $1
+	`, id, name, ErrArtistNotFound)
+}
+
+// RenameRelease sets the release's title everywhere it is shown and
+// searched.
+func (r *EntityRepository) RenameRelease(ctx context.Context, id uuid.UUID, title string) error {
+	return r.rename(ctx, `
+		UPDATE releases SET title = $2, title_edited = TRUE, updated_at = NOW()
+		WHERE mb_release_id = $1
+	`, id, title, ErrReleaseNotFound)
+}
+
+func (r *EntityRepository) rename(ctx context.Context, query string, id uuid.UUID, name string, notFound error) error {
+	result, err := r.db.ExecContext(ctx, query, id, strings.TrimSpace(name))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// SetEntityArtworkKey points the artist's or release's artwork at key, or
+// clears it, returning the key it replaced.
+func (r *EntityRepository) SetEntityArtworkKey(ctx context.Context, kind EntityKind, id uuid.UUID, key sql.NullString) (sql.NullString, error) {
+	query, notFound := `
+		UPDATE artists a
+		SET artwork_key = $2, updated_at = NOW()
+		FROM (SELECT mb_artist_id, artwork_key FROM artists WHERE mb_artist_id = $1 FOR UPDATE) old
+		WHERE a.mb_artist_id = old.mb_artist_id
+		RETURNING old.artwork_key
+	`, ErrArtistNotFound
+	if kind == EntityRelease {
+		query, notFound = `
+		UPDATE releases rl
+		SET artwork_key = $2, updated_at = NOW()
+		FROM (SELECT mb_release_id, artwork_key FROM releases WHERE mb_release_id = $1 FOR UPDATE) old
+		WHERE rl.mb_release_id = old.mb_release_id
+		RETURNING old.artwork_key
+	`, ErrReleaseNotFound
+	}
+	var previous sql.NullString
+	err := r.db.QueryRowContext(ctx, query, id, key).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, notFound
+	}
+	return previous, err
+}
+
+// ensureMBEntities creates the artist and release rows a track's MusicBrainz
+// IDs point at, naming them from the track. An existing entity only picks up
+// a name it lacked, so neither a later match nor a track's own text undoes
+// an admin's rename.
+func ensureMBEntities(ctx context.Context, db *DB, artistID *uuid.UUID, artist string, releaseID *uuid.UUID, album string) error {
+	if artistID != nil {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO artists (mb_artist_id, name)
+			VALUES ($1, $2)
+			ON CONFLICT (mb_artist_id) DO UPDATE
+			SET name = EXCLUDED.name, updated_at = NOW()
+			WHERE artists.name = '' AND EXCLUDED.name <> ''
+		`, *artistID, strings.TrimSpace(artist)); err != nil {
+			return err
+		}
+	}
+	if releaseID != nil {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO releases (mb_release_id, title, mb_artist_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (mb_release_id) DO UPDATE
+			SET title = CASE WHEN releases.title = '' THEN EXCLUDED.title ELSE releases.title END,
+				mb_artist_id = COALESCE(releases.mb_artist_id, EXCLUDED.mb_artist_id),
+				updated_at = NOW()
+			WHERE (releases.title = '' AND EXCLUDED.title <> '')
+			   OR (releases.mb_artist_id IS NULL AND EXCLUDED.mb_artist_id IS NOT NULL)
+		`, *releaseID, strings.TrimSpace(album), artistID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Collision resolutions for ApplyIdentityRehash.
+const (
+	// CollisionMerge folds the other tracks into the keeper: library entries,
+	// favorites, playlist entries, and other references move to it, and the
+	// other track rows are deleted.
+	CollisionMerge = "merge"
+	// CollisionSeparate gives the keeper the new hash and every other track a
+	// hash suffixed with its ID, so all stay; new downloads dedup to the keeper.
+	CollisionSeparate = "separate"
+	// CollisionSkip leaves every track in the collision on its old hash.
+	CollisionSkip = "skip"
+)
+
+// ErrUnresolvedCollision reports a collision ApplyIdentityRehash was given
+// no resolution for.
+var ErrUnresolvedCollision = errors.New("identity collision has no resolution")
+
+// IdentityRehashTrack is one track as a rehash sees it.
+type IdentityRehashTrack struct {
+	ID           int64
+	IdentityHash string
+	Identity     TrackIdentity
+	SourceURL    string
+	StorageKey   string
+	LibraryCount int
+	CreatedAt    time.Time
+}
+
+// IdentityChange moves one track to a new identity hash.
+type IdentityChange struct {
+	TrackID int64
+	OldHash string
+	NewHash string
+}
+
+// IdentityCollision is a set of tracks a strategy gives the same hash. Tracks
+// are ordered most-referenced first, then oldest, so Tracks[0] is the
+// suggested keeper.
+type IdentityCollision struct {
+	Hash   string
+	Tracks []IdentityRehashTrack
+}
+
+// IdentityRehashPlan is what recomputing every hash under Strategy would do.
+// Changes covers only tracks outside collisions.
+type IdentityRehashPlan struct {
+	Strategy   IdentityStrategy
+	Changes    []IdentityChange
+	Collisions []IdentityCollision
+	Unchanged  int
+}
+
+// CollisionResolution says how to settle one IdentityCollision. KeeperID must
+// be one of the collision's tracks unless Action is CollisionSkip.
+type CollisionResolution struct {
+	Action   string
+	KeeperID int64
+}
+
+// IdentityRehashResult reports what ApplyIdentityRehash changed.
+// OrphanedStorageKeys are objects of merged-away tracks that no remaining
+// track references; the caller may delete them from storage.
+type IdentityRehashResult struct {
+	Rehashed            int
+	Merged              int
+	Separated           int
+	Skipped             int
+	TakedownsUpdated    int
+	OrphanedStorageKeys []string
+}
+
+// ListIdentityRehashTracks loads every track with what a rehash needs to
+// recompute its hash and to describe it to an operator.
+func (r *TrackRepository) ListIdentityRehashTracks(ctx context.Context) ([]IdentityRehashTrack, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.identity_hash, t.title, COALESCE(t.artist, ''), COALESCE(t.album, ''),
+			   COALESCE(t.duration_ms, 0), COALESCE(t.version, ''), COALESCE(t.source_url, ''),
+			   COALESCE(t.storage_key, ''),
+			   (SELECT COUNT(*) FROM user_library ul WHERE ul.track_id = t.id),
+			   t.created_at
+		FROM tracks t
+		ORDER BY t.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tracks []IdentityRehashTrack
+	for rows.Next() {
+		var t IdentityRehashTrack
+		if err := rows.Scan(&t.ID, &t.IdentityHash, &t.Identity.Title, &t.Identity.Artist, &t.Identity.Album,
+			&t.Identity.DurationMs, &t.Identity.Version, &t.SourceURL, &t.StorageKey, &t.LibraryCount, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
+
+// PlanIdentityRehash recomputes every track's hash under strategy and groups
+// the tracks that would then share one.
+func PlanIdentityRehash(tracks []IdentityRehashTrack, strategy IdentityStrategy) *IdentityRehashPlan {
+	plan := &IdentityRehashPlan{Strategy: strategy}
+	byHash := make(map[string][]IdentityRehashTrack)
+	var order []string
+	for _, t := range tracks {
+		hash := strategy.Hash(t.Identity)
+		if _, ok := byHash[hash]; !ok {
+			order = append(order, hash)
+		}
+		byHash[hash] = append(byHash[hash], t)
+	}
+	for _, hash := range order {
+		group := byHash[hash]
+		if len(group) > 1 {
+			sort.SliceStable(group, func(i, j int) bool {
+				if group[i].LibraryCount != group[j].LibraryCount {
+					return group[i].LibraryCount > group[j].LibraryCount
+				}
+				if !group[i].CreatedAt.Equal(group[j].CreatedAt) {
+					return group[i].CreatedAt.Before(group[j].CreatedAt)
+				}
+				return group[i].ID < group[j].ID
+			})
+			plan.Collisions = append(plan.Collisions, IdentityCollision{Hash: hash, Tracks: group})
+			continue
+		}
+		if group[0].IdentityHash == hash {
+			plan.Unchanged++
+			continue
+		}
+		plan.Changes = append(plan.Changes, IdentityChange{TrackID: group[0].ID, OldHash: group[0].IdentityHash, NewHash: hash})
+	}
+	return plan
+}
+
+// ApplyIdentityRehash carries out plan in one transaction, settling each
+// collision with resolutions[collision.Hash]. Identity-hash takedowns follow
+// their tracks to the new hashes. Nothing changes if any step fails.
+func (r *TrackRepository) ApplyIdentityRehash(ctx context.Context, plan *IdentityRehashPlan, resolutions map[string]CollisionResolution) (*IdentityRehashResult, error) {
+	changes := append([]IdentityChange(nil), plan.Changes...)
+	type merge struct{ keeperID, dupID int64 }
+	var merges []merge
+	// hashMoves maps old hashes to the hash now holding that identity, for
+	// takedowns; merged-away tracks map to their keeper's hash.
+	hashMoves := make(map[string]string)
+	for _, c := range plan.Changes {
+		hashMoves[c.OldHash] = c.NewHash
+	}
+
+	result := &IdentityRehashResult{Rehashed: len(plan.Changes)}
+	for _, collision := range plan.Collisions {
+		resolution, ok := resolutions[collision.Hash]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnresolvedCollision, collision.Hash)
+		}
+		if resolution.Action == CollisionSkip {
+			result.Skipped++
+			continue
+		}
+		var keeper *IdentityRehashTrack
+		for i := range collision.Tracks {
+			if collision.Tracks[i].ID == resolution.KeeperID {
+				keeper = &collision.Tracks[i]
+			}
+		}
+		if keeper == nil {
+			return nil, fmt.Errorf("track %d is not part of collision %s", resolution.KeeperID, collision.Hash)
+		}
+		if keeper.IdentityHash != collision.Hash {
+			changes = append(changes, IdentityChange{TrackID: keeper.ID, OldHash: keeper.IdentityHash, NewHash: collision.Hash})
+		}
+		hashMoves[keeper.IdentityHash] = collision.Hash
+		for _, t := range collision.Tracks {
+			if t.ID == keeper.ID {
+				continue
+			}
+			switch resolution.Action {
+			case CollisionMerge:
+				merges = append(merges, merge{keeperID: keeper.ID, dupID: t.ID})
+				hashMoves[t.IdentityHash] = collision.Hash
+				result.Merged++
+			case CollisionSeparate:
+				separate := collision.Hash + "-" + strconv.FormatInt(t.ID, 10)
+				changes = append(changes, IdentityChange{TrackID: t.ID, OldHash: t.IdentityHash, NewHash: separate})
+				hashMoves[t.IdentityHash] = separate
+				result.Separated++
+			default:
+				return nil, fmt.Errorf("unknown collision resolution %q", resolution.Action)
+			}
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, m := range merges {
+		keys, err := mergeTrackInto(ctx, tx, m.keeperID, m.dupID)
+		if err != nil {
+			return nil, fmt.Errorf("merge track %d into %d: %w", m.dupID, m.keeperID, err)
+		}
+		result.OrphanedStorageKeys = append(result.OrphanedStorageKeys, keys...)
+	}
+
+	if len(changes) > 0 {
+		ids := make([]int64, len(changes))
+		hashes := make([]string, len(changes))
+		for i, c := range changes {
+			ids[i], hashes[i] = c.TrackID, c.NewHash
+		}
+		// Park the moving rows on unique placeholders first so swapping two
+		// tracks' hashes never trips the unique index mid-statement.
+		if _, err := tx.ExecContext(ctx, `UPDATE tracks SET identity_hash = 'rehash-' || id WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE tracks t
+			SET identity_hash = u.hash, updated_at = NOW()
+			FROM unnest($1::bigint[], $2::text[]) AS u(id, hash)
+			WHERE t.id = u.id
+		`, pq.Array(ids), pq.Array(hashes)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(hashMoves) > 0 {
+		oldHashes := make([]string, 0, len(hashMoves))
+		newHashes := make([]string, 0, len(hashMoves))
+		for old, moved := range hashMoves {
+			if old != moved {
+				oldHashes = append(oldHashes, old)
+				newHashes = append(newHashes, moved)
+			}
+		}
+		res, err := tx.ExecContext(ctx, `
+			UPDATE content_takedowns c
+			SET pattern = u.new_hash, like_pattern = u.new_hash
+			FROM unnest($1::text[], $2::text[]) AS u(old_hash, new_hash)
+			WHERE c.kind = '`+TakedownKindIdentityHash+`' AND c.pattern = u.old_hash
+		`, pq.Array(oldHashes), pq.Array(newHashes))
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			result.TakedownsUpdated = int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeTrackInto moves everything that references dupID over to keeperID and
+// deletes dupID. It returns dupID's audio and artwork keys that no remaining
+// track uses.
+func mergeTrackInto(ctx context.Context, tx *sql.Tx, keeperID, dupID int64) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_library (user_id, track_id, added_at)
+		SELECT user_id, $1, added_at FROM user_library WHERE track_id = $2
+		ON CONFLICT (user_id, track_id) DO UPDATE SET added_at = LEAST(user_library.added_at, EXCLUDED.added_at)
+	`, keeperID, dupID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO track_favorites (user_id, track_id, created_at)
+		SELECT user_id, $1, created_at FROM track_favorites WHERE track_id = $2
+		ON CONFLICT (user_id, track_id) DO NOTHING
+	`, keeperID, dupID); err != nil {
+		return nil, err
+	}
+
+	// Playlists holding only the duplicate get the keeper in its place.
+	// Playlists already holding both lose the duplicate's entry and are
+	// renumbered.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE playlist_tracks pt SET track_id = $1
+		WHERE pt.track_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM playlist_tracks k WHERE k.playlist_id = pt.playlist_id AND k.track_id = $1)
+	`, keeperID, dupID); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `DELETE FROM playlist_tracks WHERE track_id = $1 RETURNING playlist_id`, dupID)
+	if err != nil {
+		return nil, err
+	}
+	var playlistIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		playlistIDs = append(playlistIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := renumberPlaylists(ctx, tx, playlistIDs); err != nil {
+		return nil, err
+	}
+
+	if err := repointTrackReferences(ctx, tx, keeperID, dupID); err != nil {
+		return nil, err
+	}
+
+	// A quarantined duplicate keeps its identity blocked through the keeper.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tracks k
+		SET quarantined_at = d.quarantined_at, quarantine_takedown_id = d.quarantine_takedown_id
+		FROM tracks d
+		WHERE k.id = $1 AND d.id = $2 AND k.quarantined_at IS NULL AND d.quarantined_at IS NOT NULL
+	`, keeperID, dupID); err != nil {
+		return nil, err
+	}
+
+	// A keeper without embedded artwork takes the duplicate's.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tracks k
+		SET artwork_key = d.artwork_key
+		FROM tracks d
+		WHERE k.id = $1 AND d.id = $2 AND k.artwork_key IS NULL AND d.artwork_key IS NOT NULL
+	`, keeperID, dupID); err != nil {
+		return nil, err
+	}
+
+	var storageKey, artworkKey sql.NullString
+	if err := tx.QueryRowContext(ctx, `DELETE FROM tracks WHERE id = $1 RETURNING storage_key, artwork_key`, dupID).Scan(&storageKey, &artworkKey); err != nil {
+		return nil, err
+	}
+	var orphaned []string
+	for _, key := range []sql.NullString{storageKey, artworkKey} {
+		if !key.Valid || key.String == "" {
+			continue
+		}
+		var stillUsed bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tracks WHERE storage_key = $1 OR artwork_key = $1)`, key.String).Scan(&stillUsed); err != nil {
+			return nil, err
+		}
+		if !stillUsed {
+			orphaned = append(orphaned, key.String)
+		}
+	}
+	return orphaned, nil
+}
+
+// repointTrackReferences moves every remaining foreign key on dupID, such as
+// play history and download jobs, to keeperID. A table whose unique
+// constraints reject the move keeps its rows, which then cascade or go NULL
+// with the duplicate as their foreign key declares.
+func repointTrackReferences(ctx context.Context, tx *sql.Tx, keeperID, dupID int64) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT kcu.table_name, kcu.column_name
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.constraint_column_usage ccu
+		  ON ccu.constraint_schema = rc.unique_constraint_schema AND ccu.constraint_name = rc.unique_constraint_name
+		WHERE ccu.table_schema = current_schema() AND ccu.table_name = 'tracks' AND ccu.column_name = 'id'
+		  AND kcu.table_name NOT IN ('user_library', 'track_favorites', 'playlist_tracks')
+		ORDER BY kcu.table_name, kcu.column_name
+	`)
+	if err != nil {
+		return err
+	}
+	type reference struct{ table, column string }
+	var refs []reference
+	for rows.Next() {
+		var ref reference
+		if err := rows.Scan(&ref.table, &ref.column); err != nil {
+			rows.Close()
+			return err
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT repoint_reference`); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`,
+			pq.QuoteIdentifier(ref.table), pq.QuoteIdentifier(ref.column), pq.QuoteIdentifier(ref.column))
+		if _, err := tx.ExecContext(ctx, query, keeperID, dupID); err != nil {
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) || pqErr.Code.Name() != "unique_violation" {
+				return fmt.Errorf("repoint %s.%s: %w", ref.table, ref.column, err)
+			}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT repoint_reference`); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT repoint_reference`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestApplyIdentityRehashMergesCollisionAgainstPostgres(t *testing.T) {
+	database, ctx := newFavoritesTestDB(t)
+	if _, err := database.Exec("TRUNCATE TABLE content_takedowns, playlists RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	repo := NewTrackRepository(database)
+	user := seedFavUser(t, database, "rehash@example.test")
+
+	keeper, _, err := repo.CreateTrackFromMetadata(ctx, "Artist", "Song", "Album", 200000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup, _, err := repo.CreateTrackFromMetadata(ctx, "Artist", "Song", "Compilation", 200000, WithStorage("tracks/dup.mp3", 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var playlistID int64
+	if err := database.QueryRow(`INSERT INTO playlists (user_id, name) VALUES ($1, 'Mix') RETURNING id`, user).Scan(&playlistID); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO user_library (user_id, track_id) VALUES ($1, $2)`, []any{user, dup.ID}},
+		{`INSERT INTO playlist_tracks (playlist_id, track_id, position) VALUES ($1, $2, 0), ($1, $3, 1)`, []any{playlistID, dup.ID, keeper.ID}},
+		{`INSERT INTO content_takedowns (kind, pattern, like_pattern, reason) VALUES ('identity_hash', $1, $1, 'test')`, []any{dup.IdentityHash}},
+	} {
+		if _, err := database.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("%s: %v", stmt.query, err)
+		}
+	}
+
+	tracks, err := repo.ListIdentityRehashTracks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := PlanIdentityRehash(tracks, IdentityStrategy{DurationBucketMs: 5000, VersionSensitive: true})
+	if len(plan.Collisions) != 1 {
+		t.Fatalf("plan = %+v, want one collision", plan)
+	}
+	newHash := plan.Collisions[0].Hash
+	result, err := repo.ApplyIdentityRehash(ctx, plan, map[string]CollisionResolution{
+		newHash: {Action: CollisionMerge, KeeperID: keeper.ID},
+	})
+	if err != nil {
+		t.Fatalf("ApplyIdentityRehash: %v", err)
+	}
+	if result.Merged != 1 || result.TakedownsUpdated != 1 || len(result.OrphanedStorageKeys) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	if _, err := repo.GetByID(ctx, dup.ID); err != ErrTrackNotFound {
+		t.Fatalf("merged track lookup err = %v, want ErrTrackNotFound", err)
+	}
+	got, err := repo.GetByID(ctx, keeper.ID)
+	if err != nil || got.IdentityHash != newHash {
+		t.Fatalf("keeper = %+v, %v; want hash %s", got, err, newHash)
+	}
+	var inLibrary bool
+	var entries, position int
+	var pattern string
+	if err := database.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_library WHERE user_id = $1 AND track_id = $2)`, user, keeper.ID).Scan(&inLibrary); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow(`SELECT COUNT(*), MAX(position) FROM playlist_tracks WHERE playlist_id = $1`, playlistID).Scan(&entries, &position); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow(`SELECT pattern FROM content_takedowns`).Scan(&pattern); err != nil {
+		t.Fatal(err)
+	}
+	if !inLibrary || entries != 1 || position != 0 || pattern != newHash {
+		t.Fatalf("library=%v playlist entries=%d last position=%d takedown=%q", inLibrary, entries, position, pattern)
+	}
+}
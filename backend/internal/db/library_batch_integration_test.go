@@ -0,0 +1,34 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBatchLibraryAddAndRemove(t *testing.T) {
+	database, ctx := newPlayEventTestDB(t)
+	userID := seedPlayUser(t, database, "library-batch@example.com")
+	tracks := NewTrackRepository(database)
+	library := NewLibraryRepository(database)
+	first := seedPlayTrack(t, tracks, ctx, "Artist", "First")
+	second := seedPlayTrack(t, tracks, ctx, "Artist", "Second")
+
+	if _, err := library.AddTrackToLibrary(ctx, userID, first); err != nil {
+		t.Fatalf("add first: %v", err)
+	}
+	added, err := library.AddTracksToLibrary(ctx, userID, []int64{first, second, second + 1000})
+	if err != nil {
+		t.Fatalf("batch add: %v", err)
+	}
+	if !slices.Equal(added, []int64{second}) {
+		t.Fatalf("added = %v, want only the new, existing track %d", added, second)
+	}
+
+	removed, err := library.RemoveTracksFromLibrary(ctx, userID, []int64{first, second + 1000})
+	if err != nil {
+		t.Fatalf("batch remove: %v", err)
+	}
+	if !slices.Equal(removed, []int64{first}) {
+		t.Fatalf("removed = %v, want [%d]", removed, first)
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// MaxEntityTracks bounds how many library tracks one release or artist
+// lookup returns.
+const MaxEntityTracks = 1000
+
+// EntityTrack is a library track with the MusicBrainz IDs used to put an
+// album or artist in play order.
+type EntityTrack struct {
+	TrackID       int64
+	Title         string
+	Album         sql.NullString
+	MBRecordingID *uuid.UUID
+	MBReleaseID   *uuid.UUID
+}
+
+// ReleaseTracks returns the user's playable library tracks on a release,
+// ordered by title.
+func (r *LibraryRepository) ReleaseTracks(ctx context.Context, userID, releaseID uuid.UUID) ([]EntityTrack, error) {
+	return r.entityTracks(ctx, "t.mb_release_id = $2", userID, releaseID)
+}
+
+// ArtistTracks returns the user's playable library tracks by an artist,
+// ordered by album and title.
+func (r *LibraryRepository) ArtistTracks(ctx context.Context, userID, artistID uuid.UUID) ([]EntityTrack, error) {
+	return r.entityTracks(ctx, "t.mb_artist_id = $2", userID, artistID)
+}
+
+func (r *LibraryRepository) entityTracks(ctx context.Context, match string, userID, entityID uuid.UUID) ([]EntityTrack, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.title, t.album, t.mb_recording_id, t.mb_release_id
+		FROM user_library ul
+		JOIN tracks t ON t.id = ul.track_id
+		WHERE ul.user_id = $1 AND `+match+` AND t.quarantined_at IS NULL
+		ORDER BY t.album NULLS LAST, t.title, t.id
+		LIMIT $3
+	`, userID, entityID, MaxEntityTracks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tracks []EntityTrack
+	for rows.Next() {
+		var t EntityTrack
+		if err := rows.Scan(&t.TrackID, &t.Title, &t.Album, &t.MBRecordingID, &t.MBReleaseID); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
@@ -223,3 +224,53 @@ func TestLibraryQueryExpansionAgainstPostgres(t *testing.T) {
 		t.Fatalf("no-match query returned %d rows (total %d); want empty", len(none), noneTotal)
 	}
 }
+
+// TestLibrarySourceAndAddedDateFiltersAgainstPostgres covers the ingest audit
+// filters: exact source_type and a half-open added_at range.
+func TestLibrarySourceAndAddedDateFiltersAgainstPostgres(t *testing.T) {
+	database, ctx := newLibraryQueryTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	libRepo := NewLibraryRepository(database)
+
+	user := seedQueryUser(t, database, "audit@test.local")
+	yt := seedQueryTrack(t, trackRepo, ctx, "Alpha", "From YouTube", "A", 100000)
+	sc := seedQueryTrack(t, trackRepo, ctx, "Alpha", "From SoundCloud", "A", 100000)
+	ytOld := seedQueryTrack(t, trackRepo, ctx, "Alpha", "Old YouTube", "A", 100000)
+	for id, source := range map[int64]string{yt: "youtube", sc: "soundcloud", ytOld: "youtube"} {
+		if _, err := database.Exec(`UPDATE tracks SET source_type = $1 WHERE id = $2`, source, id); err != nil {
+			t.Fatalf("set source_type on %d: %v", id, err)
+		}
+		if _, err := libRepo.AddTrackToLibrary(ctx, user, id); err != nil {
+			t.Fatalf("add %d to library: %v", id, err)
+		}
+	}
+	if _, err := database.Exec(`UPDATE user_library SET added_at = '2024-01-15T00:00:00Z' WHERE track_id = $1`, ytOld); err != nil {
+		t.Fatalf("backdate %d: %v", ytOld, err)
+	}
+
+	rows, total, err := libRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{SourceType: "youtube"})
+	if err != nil {
+		t.Fatalf("source_type=youtube: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("source_type=youtube returned %v (total %d); want yt and ytOld", idOrder(rows), total)
+	}
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	rows, total, err = libRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{SourceType: "youtube", AddedAfter: &after, AddedBefore: &before})
+	if err != nil {
+		t.Fatalf("dated source_type=youtube: %v", err)
+	}
+	if total != 1 || rows[0].ID != ytOld {
+		t.Fatalf("January youtube rows = %v (total %d); want [ytOld]", idOrder(rows), total)
+	}
+
+	rows, total, err = libRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{AddedBefore: &after})
+	if err != nil {
+		t.Fatalf("added_before: %v", err)
+	}
+	if total != 0 {
+		t.Fatalf("added_before 2024 returned %v; want none", idOrder(rows))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var ErrTrackAlreadyInLibrary = errors.New("track already in library")
@@ -27,6 +28,12 @@ type LibraryTrack struct {
 	AnalysisUpdatedAt sql.NullTime
 	IsLiked           bool
 	Genre             sql.NullString
+	PlayCount         int
+	SkipCount         int
+	LastPlayedAt      sql.NullTime
+	Tags              []string
+	// Genres are the track's MusicBrainz genres in tag form.
+	Genres []string
 }
 
 type LibraryRepository struct {
@@ -97,6 +104,22 @@ func (r *LibraryRepository) GetUserLibrary(ctx context.Context, userID uuid.UUID
 		argIndex++
 	}
 
+	if opts.SourceType != "" {
+		baseCondition += " AND t.source_type = $" + itoa(argIndex)
+		args = append(args, opts.SourceType)
+		argIndex++
+	}
+	if opts.AddedAfter != nil {
+		baseCondition += " AND ul.added_at >= $" + itoa(argIndex)
+		args = append(args, *opts.AddedAfter)
+		argIndex++
+	}
+	if opts.AddedBefore != nil {
+		baseCondition += " AND ul.added_at < $" + itoa(argIndex)
+		args = append(args, *opts.AddedBefore)
+		argIndex++
+	}
+
 	// Liked-only filter. This narrows the library listing to liked tracks; because
 	// GetUserLibrary is scoped to user_library, a liked track that is not in the
 	// library is intentionally not returned here. The standalone "Liked Songs"
@@ -107,6 +130,12 @@ func (r *LibraryRepository) GetUserLibrary(ctx context.Context, userID uuid.UUID
 		baseCondition += " AND EXISTS (SELECT 1 FROM track_favorites tf WHERE tf.user_id = ul.user_id AND tf.track_id = t.id)"
 	}
 
+	for _, tag := range opts.Tags {
+		baseCondition += " AND " + trackTagCondition("t.id", "ul.user_id", "$"+itoa(argIndex))
+		args = append(args, tag)
+		argIndex++
+	}
+
 	// Determine sort order
 	orderBy := "ul.added_at DESC" // default
 	switch opts.SortBy {
@@ -134,6 +163,19 @@ func (r *LibraryRepository) GetUserLibrary(ctx context.Context, userID uuid.UUID
 		} else {
 			orderBy = "t.duration_ms ASC NULLS LAST"
 		}
+	case "play_count":
+		// Most played first by default; asc surfaces never-played tracks, oldest
+		// additions first.
+		if opts.SortOrder == "asc" {
+			orderBy = "ul.play_count ASC, ul.added_at ASC, t.id ASC"
+		} else {
+			orderBy = "ul.play_count DESC, ul.last_played_at DESC NULLS LAST, t.id DESC"
+		}
+	case "most_played":
+		orderBy = "ul.play_count DESC, ul.last_played_at DESC NULLS LAST, t.id DESC"
+	case "recently_played":
+		// Never-played tracks come last, newest additions first.
+		orderBy = "ul.last_played_at DESC NULLS LAST, ul.added_at DESC, t.id DESC"
 	}
 
 	// Single query with window function for total count (eliminates separate COUNT query)
@@ -148,7 +190,10 @@ func (r *LibraryRepository) GetUserLibrary(ctx context.Context, userID uuid.UUID
 			   COALESCE(` + analysisCompactOverridesExpression + `, '{}'::jsonb) AS analysis_overrides,
 			   ta.updated_at AS analysis_updated_at,
 			   EXISTS(SELECT 1 FROM track_favorites tf WHERE tf.user_id = ul.user_id AND tf.track_id = t.id) AS is_liked,
-			   t.genre,
+			   t.genre, ul.play_count, ul.skip_count, ul.last_played_at, t.quarantined_at,
+			   t.loudness_lufs, t.true_peak_dbtp, t.track_gain_db, t.album_gain_db, t.album_peak_dbtp,
+			   ARRAY(SELECT tt.tag FROM track_tags tt WHERE tt.user_id = ul.user_id AND tt.track_id = t.id ORDER BY tt.tag) AS tags,
+			   ARRAY(SELECT tg.genre FROM track_genres tg WHERE tg.track_id = t.id ORDER BY tg.votes DESC, tg.genre) AS genres,
 			   COUNT(*) OVER() as total_count
 		FROM user_library ul
 		JOIN tracks t ON ul.track_id = t.id
@@ -177,7 +222,10 @@ func (r *LibraryRepository) GetUserLibrary(ctx context.Context, userID uuid.UUID
 			&lt.Codec, &lt.BitrateKbps, &lt.SampleRateHz, &lt.Channels, &lt.ContentType,
 			&lt.MetadataJSON, &lt.MetadataStatus, &lt.MetadataConfidence, &lt.MetadataProvenance,
 			&lt.CoverArtURL, &lt.MetadataUserEdited, &lt.CreatedAt, &lt.UpdatedAt, &lt.AddedAt,
-			&lt.AnalysisStatus, &lt.AnalysisSummary, &analysisOverrides, &lt.AnalysisUpdatedAt, &lt.IsLiked, &lt.Genre, &total,
+			&lt.AnalysisStatus, &lt.AnalysisSummary, &analysisOverrides, &lt.AnalysisUpdatedAt, &lt.IsLiked, &lt.Genre,
+			&lt.PlayCount, &lt.SkipCount, &lt.LastPlayedAt, &lt.QuarantinedAt,
+			&lt.ReplayGain.LoudnessLUFS, &lt.ReplayGain.TruePeakDBTP, &lt.ReplayGain.TrackGainDB, &lt.ReplayGain.AlbumGainDB, &lt.ReplayGain.AlbumPeakDBTP,
+			pq.Array(&lt.Tags), pq.Array(&lt.Genres), &total,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -239,6 +287,49 @@ func (r *LibraryRepository) RemoveTrackFromLibrary(ctx context.Context, userID u
 	return nil
 }
 
+// AddTracksToLibrary adds the tracks to a user's library in one statement
+// and returns the IDs it added. IDs of tracks already in the library or that
+// do not exist are left out.
+func (r *LibraryRepository) AddTracksToLibrary(ctx context.Context, userID uuid.UUID, trackIDs []int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		INSERT INTO user_library (user_id, track_id, added_at)
+		SELECT $1, t.id, NOW() FROM tracks t WHERE t.id = ANY($2)
+		ON CONFLICT (user_id, track_id) DO NOTHING
+		RETURNING track_id
+	`, userID, pq.Array(trackIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanTrackIDs(rows)
+}
+
+// RemoveTracksFromLibrary removes the tracks from a user's library in one
+// statement and returns the IDs it removed.
+func (r *LibraryRepository) RemoveTracksFromLibrary(ctx context.Context, userID uuid.UUID, trackIDs []int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		DELETE FROM user_library
+		WHERE user_id = $1 AND track_id = ANY($2)
+		RETURNING track_id
+	`, userID, pq.Array(trackIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanTrackIDs(rows)
+}
+
+func scanTrackIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // IsTrackInLibrary checks if a track is in a user's library.
 func (r *LibraryRepository) IsTrackInLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (bool, error) {
 	query := `
@@ -257,6 +348,30 @@ func (r *LibraryRepository) IsTrackInLibrary(ctx context.Context, userID uuid.UU
 	return exists, nil
 }
 
+// LibraryTrackIDs returns the IDs of every track in a user's library, oldest
+// addition first.
+func (r *LibraryRepository) LibraryTrackIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT track_id FROM user_library
+		WHERE user_id = $1
+		ORDER BY added_at, track_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // AddFavorite marks a track as liked ("Liked Songs") for a user. Idempotent:
 // liking an already-liked track is a no-op success. Favorites are membership +
 // timestamp only and do NOT change user_library membership.
@@ -291,7 +406,7 @@ func (r *LibraryRepository) IsFavorite(ctx context.Context, userID uuid.UUID, tr
 type LibraryQueryOptions struct {
 	Limit      int
 	Offset     int
-	SortBy     string // "added_at", "title", "artist", "duration"
+	SortBy     string // "added_at", "title", "artist", "duration", "play_count", "most_played", "recently_played"
 	SortOrder  string // "asc", "desc"
 	Search     string // Search query for title/artist/album
 	MBVerified *bool  // Filter by MusicBrainz verification status
@@ -299,6 +414,14 @@ type LibraryQueryOptions struct {
 	Genre      string // Exact genre match; "Unknown" matches NULL/empty genre
 	Artist     string // Exact artist match (local artist listing)
 	Album      string // Exact album match (local album listing)
+	SourceType string // Exact tracks.source_type match ("youtube", "soundcloud", "upload")
+	// AddedAfter (inclusive) and AddedBefore (exclusive) bound when the track
+	// entered the user's library.
+	AddedAfter  *time.Time
+	AddedBefore *time.Time
+	// Tags keeps tracks carrying every listed tag, either as the user's own
+	// tag or as a MusicBrainz genre.
+	Tags []string
 }
 
 // itoa converts an integer to a string (simple implementation to avoid importing strconv)
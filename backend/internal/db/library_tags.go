@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// MaxTrackTags bounds how many tags one library entry may carry.
+	MaxTrackTags = 50
+	// MaxTagLength bounds a tag in runes.
+	MaxTagLength = 64
+)
+
+var ErrTooManyTrackTags = errors.New("too many tags on track")
+
+// TagCount is one of a user's tags and how many library tracks carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag lowercases a tag and checks it is 1-64 letters, digits, or
+// ':', '-', '_', '.' characters, so tags like "mood:focus" group the way
+// users expect and stay safe in a URL path.
+func NormalizeTag(raw string) (string, bool) {
+	tag := strings.ToLower(strings.TrimSpace(raw))
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(":-_.", r)
+}
+
+// AddTrackTags adds tags to a track in the user's library and returns all of
+// the track's tags. Tags the track already has are left as they are.
+func (r *LibraryRepository) AddTrackTags(ctx context.Context, userID uuid.UUID, trackID int64, tags []string) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the library row serializes concurrent adds so the limit holds.
+	var locked int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT track_id FROM user_library WHERE user_id = $1 AND track_id = $2 FOR UPDATE
+	`, userID, trackID).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTrackNotInLibrary
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO track_tags (user_id, track_id, tag)
+		SELECT $1, $2, unnest($3::text[])
+		ON CONFLICT DO NOTHING
+	`, userID, trackID, pq.Array(tags)); err != nil {
+		return nil, err
+	}
+	current, err := trackTags(ctx, tx, userID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) > MaxTrackTags {
+		return nil, ErrTooManyTrackTags
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// RemoveTrackTag removes a tag from a track in the user's library and
+// returns the track's remaining tags. Removing a tag the track does not have
+// succeeds.
+func (r *LibraryRepository) RemoveTrackTag(ctx context.Context, userID uuid.UUID, trackID int64, tag string) ([]string, error) {
+	var inLibrary bool
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_library WHERE user_id = $1 AND track_id = $2)
+	`, userID, trackID).Scan(&inLibrary); err != nil {
+		return nil, err
+	}
+	if !inLibrary {
+		return nil, ErrTrackNotInLibrary
+	}
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM track_tags WHERE user_id = $1 AND track_id = $2 AND tag = $3
+	`, userID, trackID, tag); err != nil {
+		return nil, err
+	}
+	return trackTags(ctx, r.db, userID, trackID)
+}
+
+// GetTrackTags returns the user's tags on a track in their library and the
+// track's MusicBrainz genres, most voted first.
+func (r *LibraryRepository) GetTrackTags(ctx context.Context, userID uuid.UUID, trackID int64) (tags, genres []string, err error) {
+	var inLibrary bool
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_library WHERE user_id = $1 AND track_id = $2)
+	`, userID, trackID).Scan(&inLibrary); err != nil {
+		return nil, nil, err
+	}
+	if !inLibrary {
+		return nil, nil, ErrTrackNotInLibrary
+	}
+	if tags, err = trackTags(ctx, r.db, userID, trackID); err != nil {
+		return nil, nil, err
+	}
+	if genres, err = trackGenres(ctx, r.db, trackID); err != nil {
+		return nil, nil, err
+	}
+	return tags, genres, nil
+}
+
+// ListTags returns every tag the user has used with its track count, most
+// used first.
+func (r *LibraryRepository) ListTags(ctx context.Context, userID uuid.UUID) ([]TagCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tag, COUNT(*)
+		FROM track_tags
+		WHERE user_id = $1
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+type tagQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func trackTags(ctx context.Context, q tagQuerier, userID uuid.UUID, trackID int64) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT tag FROM track_tags WHERE user_id = $1 AND track_id = $2 ORDER BY tag
+	`, userID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestTrackTagsAgainstPostgres covers adding and removing tags, the per-user
+// counts, the every-tag library filter, and that tags go with the library
+// entry.
+func TestTrackTagsAgainstPostgres(t *testing.T) {
+	database, ctx := newFavoritesTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	libRepo := NewLibraryRepository(database)
+
+	user := seedFavUser(t, database, "tags1@test.local")
+	other := seedFavUser(t, database, "tags2@test.local")
+	t1 := seedFavTrack(t, trackRepo, ctx, "Artist A", "Focus Song")
+	t2 := seedFavTrack(t, trackRepo, ctx, "Artist B", "Gym Song")
+	for _, id := range []int64{t1, t2} {
+		if _, err := libRepo.AddTrackToLibrary(ctx, user, id); err != nil {
+			t.Fatalf("add %d to library: %v", id, err)
+		}
+	}
+
+	if _, err := libRepo.AddTrackTags(ctx, other, t1, []string{"gym"}); !errors.Is(err, ErrTrackNotInLibrary) {
+		t.Fatalf("tag outside library err = %v; want ErrTrackNotInLibrary", err)
+	}
+
+	tags, err := libRepo.AddTrackTags(ctx, user, t1, []string{"mood:focus", "gym"})
+	if err != nil {
+		t.Fatalf("tag t1: %v", err)
+	}
+	if tags, err = libRepo.AddTrackTags(ctx, user, t1, []string{"gym"}); err != nil {
+		t.Fatalf("retag t1: %v", err)
+	}
+	if !slices.Equal(tags, []string{"gym", "mood:focus"}) {
+		t.Fatalf("t1 tags = %v; want [gym mood:focus]", tags)
+	}
+	if _, err := libRepo.AddTrackTags(ctx, user, t2, []string{"gym"}); err != nil {
+		t.Fatalf("tag t2: %v", err)
+	}
+
+	counts, err := libRepo.ListTags(ctx, user)
+	if err != nil {
+		t.Fatalf("list tags: %v", err)
+	}
+	if want := []TagCount{{"gym", 2}, {"mood:focus", 1}}; !slices.Equal(counts, want) {
+		t.Fatalf("tag counts = %v; want %v", counts, want)
+	}
+
+	tracks, total, err := libRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{Limit: 10, Tags: []string{"gym", "mood:focus"}})
+	if err != nil {
+		t.Fatalf("filter by tags: %v", err)
+	}
+	if total != 1 || len(tracks) != 1 || tracks[0].ID != t1 {
+		t.Fatalf("filtered library = %d tracks (total %d); want only t1", len(tracks), total)
+	}
+	if !slices.Equal(tracks[0].Tags, []string{"gym", "mood:focus"}) {
+		t.Fatalf("listed t1 tags = %v", tracks[0].Tags)
+	}
+
+	if tags, err = libRepo.RemoveTrackTag(ctx, user, t1, "gym"); err != nil {
+		t.Fatalf("untag t1: %v", err)
+	}
+	if !slices.Equal(tags, []string{"mood:focus"}) {
+		t.Fatalf("t1 tags after removal = %v", tags)
+	}
+
+	if err := libRepo.RemoveTrackFromLibrary(ctx, user, t2); err != nil {
+		t.Fatalf("remove t2 from library: %v", err)
+	}
+	if counts, err = libRepo.ListTags(ctx, user); err != nil {
+		t.Fatalf("list tags after removal: %v", err)
+	}
+	if want := []TagCount{{"mood:focus", 1}}; !slices.Equal(counts, want) {
+		t.Fatalf("tag counts after removal = %v; want %v", counts, want)
+	}
+}
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrListeningGoalNotFound = errors.New("listening goal not found")
+	ErrListeningGoalExists   = errors.New("listening goal already exists")
+)
+
+// Notification kinds sent for listening goals.
+const (
+	NotificationKindGoalMet   = "goal_met"
+	NotificationKindGoalNudge = "goal_nudge"
+)
+
+// ListeningGoal is a user's target for one goal kind. MetPeriod and
+// NudgedPeriod are the local start dates of the last periods notified, as UTC
+// midnights.
+type ListeningGoal struct {
+	ID           int64
+	UserID       uuid.UUID
+	Kind         string
+	Target       int
+	MetPeriod    sql.NullTime
+	NudgedPeriod sql.NullTime
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// GoalPeriodTotal is a goal measure summed over one period. Start is the
+// period's local start date as a UTC midnight.
+type GoalPeriodTotal struct {
+	Start time.Time
+	Value int
+}
+
+// ListeningGoalRepository stores listening goals and computes the play-history
+// totals their progress is measured against.
+type ListeningGoalRepository struct {
+	db *DB
+}
+
+func NewListeningGoalRepository(db *DB) *ListeningGoalRepository {
+	return &ListeningGoalRepository{db: db}
+}
+
+const listeningGoalColumns = `id, user_id, kind, target, met_period, nudged_period, created_at, updated_at`
+
+func scanListeningGoal(row interface{ Scan(...any) error }) (*ListeningGoal, error) {
+	g := &ListeningGoal{}
+	err := row.Scan(&g.ID, &g.UserID, &g.Kind, &g.Target, &g.MetPeriod, &g.NudgedPeriod, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// ListGoals returns the user's goals ordered by kind.
+func (r *ListeningGoalRepository) ListGoals(ctx context.Context, userID uuid.UUID) ([]ListeningGoal, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+listeningGoalColumns+`
+		FROM listening_goals
+		WHERE user_id = $1
+		ORDER BY kind
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []ListeningGoal
+	for rows.Next() {
+		g, err := scanListeningGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, *g)
+	}
+	return goals, rows.Err()
+}
+
+// CreateGoal adds a goal. A user has at most one goal of each kind.
+func (r *ListeningGoalRepository) CreateGoal(ctx context.Context, userID uuid.UUID, kind string, target int) (*ListeningGoal, error) {
+	g, err := scanListeningGoal(r.db.QueryRowContext(ctx, `
+		INSERT INTO listening_goals (user_id, kind, target)
+		VALUES ($1, $2, $3)
+		RETURNING `+listeningGoalColumns, userID, kind, target))
+	if isUniqueViolation(err) {
+		return nil, ErrListeningGoalExists
+	}
+	return g, err
+}
+
+// UpdateGoalTarget changes a goal's target. The notification markers are kept,
+// so a goal already reported as met this period is not reported again.
+func (r *ListeningGoalRepository) UpdateGoalTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*ListeningGoal, error) {
+	g, err := scanListeningGoal(r.db.QueryRowContext(ctx, `
+		UPDATE listening_goals
+		SET target = $3, updated_at = NOW()
+		WHERE user_id = $1 AND kind = $2
+		RETURNING `+listeningGoalColumns, userID, kind, target))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListeningGoalNotFound
+	}
+	return g, err
+}
+
+// DeleteGoal removes a goal.
+func (r *ListeningGoalRepository) DeleteGoal(ctx context.Context, userID uuid.UUID, kind string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM listening_goals WHERE user_id = $1 AND kind = $2
+	`, userID, kind)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrListeningGoalNotFound
+	}
+	return nil
+}
+
+// UsersWithGoals returns every user with at least one listening goal.
+func (r *ListeningGoalRepository) UsersWithGoals(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM listening_goals ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+// WeeklyMinutes returns minutes listened per Monday-start week in timeZone,
+// for plays at or after since. Minutes count each play's full track length,
+// as year-in-review reports do.
+func (r *ListeningGoalRepository) WeeklyMinutes(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]GoalPeriodTotal, error) {
+	return r.periodTotals(ctx, `
+		SELECT date_trunc('week', pe.played_at AT TIME ZONE $3)::date AS period,
+			(SUM(COALESCE(t.duration_ms, 0)) / 60000)::int
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2
+		GROUP BY period
+		ORDER BY period
+	`, userID, since, timeZone)
+}
+
+// MonthlyNewArtists returns how many artists the user first played in each
+// calendar month in timeZone, for first plays at or after since.
+func (r *ListeningGoalRepository) MonthlyNewArtists(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]GoalPeriodTotal, error) {
+	return r.periodTotals(ctx, `
+		SELECT date_trunc('month', fa.first_played AT TIME ZONE $3)::date AS period, COUNT(*)::int
+		FROM (
+			SELECT MIN(pe.played_at) AS first_played
+			FROM play_events pe
+			JOIN tracks t ON t.id = pe.track_id
+			WHERE pe.user_id = $1 AND COALESCE(BTRIM(t.artist), '') <> ''
+			GROUP BY LOWER(BTRIM(t.artist))
+		) fa
+		WHERE fa.first_played >= $2
+		GROUP BY period
+		ORDER BY period
+	`, userID, since, timeZone)
+}
+
+func (r *ListeningGoalRepository) periodTotals(ctx context.Context, query string, args ...any) ([]GoalPeriodTotal, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []GoalPeriodTotal
+	for rows.Next() {
+		var total GoalPeriodTotal
+		if err := rows.Scan(&total.Start, &total.Value); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// RecordGoalNotification notifies the goal's owner with kind goal_met or
+// goal_nudge unless that kind was already sent for period (a local start
+// date). It reports whether a notification was inserted.
+func (r *ListeningGoalRepository) RecordGoalNotification(ctx context.Context, goalID int64, kind string, period string, payload []byte) (bool, error) {
+	column := "met_period"
+	if kind == NotificationKindGoalNudge {
+		column = "nudged_period"
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		UPDATE listening_goals
+		SET `+column+` = $2::date
+		WHERE id = $1 AND `+column+` IS DISTINCT FROM $2::date
+		RETURNING user_id
+	`, goalID, period).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, kind, payload) VALUES ($1, $2, $3)
+	`, userID, kind, payload); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListeningCount is an artist's play tally over a stats period.
+type ListeningCount struct {
+	Name       string
+	PlayCount  int
+	ListenedMs int64
+}
+
+// ListeningTrackCount is a track's play tally over a stats period.
+type ListeningTrackCount struct {
+	TrackID    int64
+	Title      string
+	Artist     string
+	PlayCount  int
+	ListenedMs int64
+}
+
+// ListeningOverview aggregates a user's plays over a period. Skips count
+// towards TotalSkips and ListenedMs but not towards any play count.
+type ListeningOverview struct {
+	TotalPlays int
+	TotalSkips int
+	ListenedMs int64
+	TopArtists []ListeningCount
+	TopTracks  []ListeningTrackCount
+	// Heatmap counts plays by local weekday, Monday first, and hour of day.
+	Heatmap [7][24]int
+}
+
+// ListeningStatsRepository reads listening stats from the hourly play
+// rollups RecordPlayEvent maintains.
+type ListeningStatsRepository struct {
+	db *DB
+}
+
+func NewListeningStatsRepository(db *DB) *ListeningStatsRepository {
+	return &ListeningStatsRepository{db: db}
+}
+
+// Overview aggregates the user's plays in [start, end). Rollups are hourly
+// in UTC, so both bounds count to the hour and heatmap hours are whole hours
+// of loc (UTC when nil). limit caps each top list.
+func (r *ListeningStatsRepository) Overview(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int, loc *time.Location) (*ListeningOverview, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+	overview := &ListeningOverview{}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(plays), 0), COALESCE(SUM(skips), 0), COALESCE(SUM(listened_ms), 0)
+		FROM play_hourly_rollups
+		WHERE user_id = $1 AND hour >= $2 AND hour < $3
+	`, userID, start, end).Scan(&overview.TotalPlays, &overview.TotalSkips, &overview.ListenedMs)
+	if err != nil {
+		return nil, err
+	}
+
+	if overview.TopArtists, err = r.topArtists(ctx, userID, start, end, limit); err != nil {
+		return nil, err
+	}
+	if overview.TopTracks, err = r.topTracks(ctx, userID, start, end, limit); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT EXTRACT(ISODOW FROM hour AT TIME ZONE $4)::int, EXTRACT(HOUR FROM hour AT TIME ZONE $4)::int, SUM(plays)
+		FROM play_hourly_rollups
+		WHERE user_id = $1 AND hour >= $2 AND hour < $3
+		GROUP BY 1, 2
+	`, userID, start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var weekday, hour, plays int
+		if err := rows.Scan(&weekday, &hour, &plays); err != nil {
+			return nil, err
+		}
+		if weekday >= 1 && weekday <= 7 && hour >= 0 && hour < 24 {
+			overview.Heatmap[weekday-1][hour] += plays
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overview, nil
+}
+
+func (r *ListeningStatsRepository) topArtists(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int) ([]ListeningCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.artist, SUM(pr.plays) AS play_count, SUM(pr.listened_ms)
+		FROM play_hourly_rollups pr
+		JOIN tracks t ON t.id = pr.track_id
+		WHERE pr.user_id = $1 AND pr.hour >= $2 AND pr.hour < $3
+			AND COALESCE(BTRIM(t.artist), '') <> ''
+		GROUP BY t.artist
+		HAVING SUM(pr.plays) > 0
+		ORDER BY play_count DESC, t.artist ASC
+		LIMIT $4
+	`, userID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := []ListeningCount{}
+	for rows.Next() {
+		var a ListeningCount
+		if err := rows.Scan(&a.Name, &a.PlayCount, &a.ListenedMs); err != nil {
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	return artists, rows.Err()
+}
+
+func (r *ListeningStatsRepository) topTracks(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int) ([]ListeningTrackCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.title, COALESCE(t.artist, ''), SUM(pr.plays) AS play_count, SUM(pr.listened_ms)
+		FROM play_hourly_rollups pr
+		JOIN tracks t ON t.id = pr.track_id
+		WHERE pr.user_id = $1 AND pr.hour >= $2 AND pr.hour < $3
+		GROUP BY t.id, t.title, t.artist
+		HAVING SUM(pr.plays) > 0
+		ORDER BY play_count DESC, t.id ASC
+		LIMIT $4
+	`, userID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracks := []ListeningTrackCount{}
+	for rows.Next() {
+		var t ListeningTrackCount
+		if err := rows.Scan(&t.TrackID, &t.Title, &t.Artist, &t.PlayCount, &t.ListenedMs); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Lyrics sources stored in track_lyrics.source.
+const (
+	LyricsSourceLRCLIB = "lrclib"
+	LyricsSourceManual = "manual"
+	// LyricsSourceNone records a lookup that found nothing.
+	LyricsSourceNone = "none"
+)
+
+var ErrLyricsNotFound = errors.New("lyrics not found")
+
+// TrackLyrics are the cached or edited lyrics of one track. SyncedLyrics is
+// LRC text with [mm:ss.xx] line timings; either text may be empty.
+type TrackLyrics struct {
+	TrackID      int64
+	Source       string
+	PlainLyrics  string
+	SyncedLyrics string
+	Instrumental bool
+	ExternalID   sql.NullInt64
+	EditedBy     *uuid.UUID
+	FetchedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type LyricsRepository struct {
+	db *DB
+}
+
+func NewLyricsRepository(db *DB) *LyricsRepository {
+	return &LyricsRepository{db: db}
+}
+
+// GetTrackLyrics returns the stored lyrics of a track, including a
+// LyricsSourceNone row for a lookup that found nothing.
+func (r *LyricsRepository) GetTrackLyrics(ctx context.Context, trackID int64) (*TrackLyrics, error) {
+	var l TrackLyrics
+	err := r.db.QueryRowContext(ctx, `
+		SELECT track_id, source, plain_lyrics, synced_lyrics, instrumental, external_id, edited_by, fetched_at, updated_at
+		FROM track_lyrics
+		WHERE track_id = $1
+	`, trackID).Scan(&l.TrackID, &l.Source, &l.PlainLyrics, &l.SyncedLyrics, &l.Instrumental, &l.ExternalID, &l.EditedBy, &l.FetchedAt, &l.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLyricsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// SaveFetchedLyrics caches the result of a lookup. It leaves lyrics a
+// listener entered alone and reports whether it saved anything.
+func (r *LyricsRepository) SaveFetchedLyrics(ctx context.Context, l *TrackLyrics) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO track_lyrics (track_id, source, plain_lyrics, synced_lyrics, instrumental, external_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (track_id) DO UPDATE
+		SET source = EXCLUDED.source,
+			plain_lyrics = EXCLUDED.plain_lyrics,
+			synced_lyrics = EXCLUDED.synced_lyrics,
+			instrumental = EXCLUDED.instrumental,
+			external_id = EXCLUDED.external_id,
+			edited_by = NULL,
+			fetched_at = NOW(),
+			updated_at = NOW()
+		WHERE track_lyrics.source <> 'manual'
+		RETURNING fetched_at, updated_at
+	`, l.TrackID, l.Source, l.PlainLyrics, l.SyncedLyrics, l.Instrumental, l.ExternalID).Scan(&l.FetchedAt, &l.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveManualLyrics stores lyrics a listener entered, replacing whatever the
+// track had.
+func (r *LyricsRepository) SaveManualLyrics(ctx context.Context, l *TrackLyrics) error {
+	l.Source = LyricsSourceManual
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO track_lyrics (track_id, source, plain_lyrics, synced_lyrics, instrumental, edited_by)
+		VALUES ($1, 'manual', $2, $3, $4, $5)
+		ON CONFLICT (track_id) DO UPDATE
+		SET source = 'manual',
+			plain_lyrics = EXCLUDED.plain_lyrics,
+			synced_lyrics = EXCLUDED.synced_lyrics,
+			instrumental = EXCLUDED.instrumental,
+			external_id = NULL,
+			edited_by = EXCLUDED.edited_by,
+			updated_at = NOW()
+		RETURNING fetched_at, updated_at
+	`, l.TrackID, l.PlainLyrics, l.SyncedLyrics, l.Instrumental, l.EditedBy).Scan(&l.FetchedAt, &l.UpdatedAt)
+}
+
+// DeleteTrackLyrics forgets a track's lyrics so the next request looks them
+// up again.
+func (r *LyricsRepository) DeleteTrackLyrics(ctx context.Context, trackID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM track_lyrics WHERE track_id = $1`, trackID)
+	return err
+}
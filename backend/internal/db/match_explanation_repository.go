@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrMatchExplanationNotFound = errors.New("match explanation not found")
+
+// MatchExplanation is the stored record of a track's most recent matcher run.
+// The explanation body is owned by the matcher package.
+type MatchExplanation struct {
+	TrackID     int64
+	Explanation json.RawMessage
+	AttemptedAt time.Time
+}
+
+// MatchExplanationRepository keeps the latest matcher explanation per track.
+type MatchExplanationRepository struct {
+	db *DB
+}
+
+func NewMatchExplanationRepository(db *DB) *MatchExplanationRepository {
+	return &MatchExplanationRepository{db: db}
+}
+
+// SaveMatchExplanation replaces the track's stored explanation.
+func (r *MatchExplanationRepository) SaveMatchExplanation(ctx context.Context, trackID int64, explanation json.RawMessage) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO track_match_explanations (track_id, explanation, attempted_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (track_id) DO UPDATE
+		SET explanation = EXCLUDED.explanation, attempted_at = EXCLUDED.attempted_at
+	`, trackID, []byte(explanation))
+	return err
+}
+
+// GetMatchExplanation returns the track's most recent explanation.
+func (r *MatchExplanationRepository) GetMatchExplanation(ctx context.Context, trackID int64) (*MatchExplanation, error) {
+	exp := &MatchExplanation{TrackID: trackID}
+	var raw []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT explanation, attempted_at FROM track_match_explanations WHERE track_id = $1
+	`, trackID).Scan(&raw, &exp.AttemptedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMatchExplanationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	exp.Explanation = raw
+	return exp, nil
+}
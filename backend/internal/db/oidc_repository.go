@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOIDCStateNotFound    = errors.New("oidc login state not found")
+	ErrOIDCIdentityNotFound = errors.New("oidc identity not found")
+	ErrOIDCHandoffNotFound  = errors.New("oidc handoff not found")
+)
+
+// OIDCLoginState is a single sign-on login waiting for the provider's
+// callback.
+type OIDCLoginState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// OIDCRepository stores linked provider identities and the short-lived state
+// of single sign-on logins.
+type OIDCRepository struct {
+	db *DB
+}
+
+func NewOIDCRepository(db *DB) *OIDCRepository {
+	return &OIDCRepository{db: db}
+}
+
+// SaveLoginState records a login in progress, clearing expired ones.
+func (r *OIDCRepository) SaveLoginState(ctx context.Context, state OIDCLoginState) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+// TakeLoginState removes and returns an unexpired login state, so each state
+// completes at most one login.
+func (r *OIDCRepository) TakeLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error) {
+	state := &OIDCLoginState{}
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1
+		RETURNING state_hash, nonce, code_verifier, expires_at
+	`, stateHash).Scan(&state.StateHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOIDCStateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return nil, ErrOIDCStateNotFound
+	}
+	return state, nil
+}
+
+// UserIDForIdentity returns the user linked to a provider account and records
+// the login.
+func (r *OIDCRepository) UserIDForIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE user_identities
+		SET last_login_at = NOW()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`, issuer, subject).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrOIDCIdentityNotFound
+	}
+	return userID, err
+}
+
+// LinkIdentity links a provider account to a user. Relinking an account that
+// is already linked leaves the existing link in place.
+func (r *OIDCRepository) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_identities (issuer, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`, issuer, subject, userID, email)
+	return err
+}
+
+// SaveHandoff records a one-time code a client can trade for tokens, clearing
+// expired ones.
+func (r *OIDCRepository) SaveHandoff(ctx context.Context, codeHash string, userID uuid.UUID, expiresAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_handoffs WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_handoffs (code_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, codeHash, userID, expiresAt)
+	return err
+}
+
+// TakeHandoff removes an unexpired handoff code and returns its user.
+func (r *OIDCRepository) TakeHandoff(ctx context.Context, codeHash string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	var expiresAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_handoffs WHERE code_hash = $1 RETURNING user_id, expires_at
+	`, codeHash).Scan(&userID, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrOIDCHandoffNotFound
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if time.Now().After(expiresAt) {
+		return uuid.Nil, ErrOIDCHandoffNotFound
+	}
+	return userID, nil
+}
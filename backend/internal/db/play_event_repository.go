@@ -28,11 +28,26 @@ type TopTrack struct {
 // RecentlyPlayedTrack, this is not deduped: repeated plays of the same track are
 // returned as separate rows.
 type PlayHistoryEvent struct {
-	ID          int64
-	Track       Track
-	PlayedAt    time.Time
-	ContextType sql.NullString
-	ContextID   sql.NullString
+	ID                 int64
+	Track              Track
+	PlayedAt           time.Time
+	ContextType        sql.NullString
+	ContextID          sql.NullString
+	DurationListenedMs sql.NullInt64
+}
+
+// PlayRecord is one listen to record. A zero PlayedAt means now; clients
+// submitting plays made offline set it to when the play started.
+type PlayRecord struct {
+	UserID             uuid.UUID
+	TrackID            int64
+	ContextType        string
+	ContextID          string
+	PlayedAt           time.Time
+	DurationListenedMs sql.NullInt64
+	// Skipped marks a play the listener skipped away from. It is kept in the
+	// history but bumps the library entry's skip count, not its play count.
+	Skipped bool
 }
 
 // PlayEventRepository records play events and serves recently-played / top-track
@@ -48,15 +63,48 @@ func NewPlayEventRepository(db *DB) *PlayEventRepository {
 // RecordPlay inserts a single play event with a server-set played_at. contextType
 // and contextID are optional; empty strings are stored as SQL NULL.
 func (r *PlayEventRepository) RecordPlay(ctx context.Context, userID uuid.UUID, trackID int64, contextType, contextID string) error {
+	return r.RecordPlayEvent(ctx, PlayRecord{UserID: userID, TrackID: trackID, ContextType: contextType, ContextID: contextID})
+}
+
+// RecordPlayEvent inserts one play event. The same statement adds it to the
+// hourly stats rollup and bumps the library entry's play or skip count and
+// last-played time when the track is in the user's library.
+func (r *PlayEventRepository) RecordPlayEvent(ctx context.Context, play PlayRecord) error {
 	query := `
-		INSERT INTO play_events (user_id, track_id, context_type, context_id)
-		VALUES ($1, $2, $3, $4)
+		WITH played AS (
+			INSERT INTO play_events (user_id, track_id, context_type, context_id, played_at, duration_listened_ms, skipped)
+			VALUES ($1, $2, $3, $4, COALESCE($5, NOW()), $6, $7)
+			RETURNING user_id, track_id, played_at, skipped, duration_listened_ms
+		),
+		rolled_up AS (
+			INSERT INTO play_hourly_rollups (user_id, track_id, hour, plays, skips, listened_ms)
+			SELECT played.user_id, played.track_id,
+				   date_trunc('hour', played.played_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+				   CASE WHEN played.skipped THEN 0 ELSE 1 END,
+				   CASE WHEN played.skipped THEN 1 ELSE 0 END,
+				   COALESCE(played.duration_listened_ms, CASE WHEN played.skipped THEN 0 ELSE t.duration_ms END, 0)
+			FROM played
+			JOIN tracks t ON t.id = played.track_id
+			ON CONFLICT (user_id, hour, track_id) DO UPDATE
+			SET plays = play_hourly_rollups.plays + EXCLUDED.plays,
+				skips = play_hourly_rollups.skips + EXCLUDED.skips,
+				listened_ms = play_hourly_rollups.listened_ms + EXCLUDED.listened_ms
+		)
+		UPDATE user_library ul
+		SET play_count = ul.play_count + CASE WHEN played.skipped THEN 0 ELSE 1 END,
+			skip_count = ul.skip_count + CASE WHEN played.skipped THEN 1 ELSE 0 END,
+			last_played_at = GREATEST(ul.last_played_at, played.played_at)
+		FROM played
+		WHERE ul.user_id = played.user_id AND ul.track_id = played.track_id
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		userID,
-		trackID,
-		sql.NullString{String: contextType, Valid: contextType != ""},
-		sql.NullString{String: contextID, Valid: contextID != ""},
+		play.UserID,
+		play.TrackID,
+		sql.NullString{String: play.ContextType, Valid: play.ContextType != ""},
+		sql.NullString{String: play.ContextID, Valid: play.ContextID != ""},
+		sql.NullTime{Time: play.PlayedAt, Valid: !play.PlayedAt.IsZero()},
+		play.DurationListenedMs,
+		play.Skipped,
 	)
 	return err
 }
@@ -152,7 +200,7 @@ func (r *PlayEventRepository) PlayHistory(ctx context.Context, userID uuid.UUID,
 			   ta.status, COALESCE(` + analysisCompactSummaryExpression + `, '{}'::jsonb),
 			   COALESCE(` + analysisCompactOverridesExpression + `, '{}'::jsonb),
 			   ta.updated_at,
-			   pe.played_at, pe.context_type, pe.context_id
+			   pe.played_at, pe.context_type, pe.context_id, pe.duration_listened_ms
 		FROM play_events pe
 		JOIN tracks t ON t.id = pe.track_id
 		LEFT JOIN track_analysis ta ON ta.track_id = t.id
@@ -180,7 +228,7 @@ func (r *PlayEventRepository) PlayHistory(ctx context.Context, userID uuid.UUID,
 			&event.Track.MetadataJSON, &event.Track.MetadataStatus, &event.Track.MetadataConfidence, &event.Track.MetadataProvenance,
 			&event.Track.CoverArtURL, &event.Track.MetadataUserEdited, &event.Track.CreatedAt, &event.Track.UpdatedAt,
 			&event.Track.AnalysisStatus, &event.Track.AnalysisSummary, &analysisOverrides, &event.Track.AnalysisUpdatedAt,
-			&event.PlayedAt, &event.ContextType, &event.ContextID,
+			&event.PlayedAt, &event.ContextType, &event.ContextID, &event.DurationListenedMs,
 		); err != nil {
 			return nil, err
 		}
@@ -193,13 +241,11 @@ func (r *PlayEventRepository) PlayHistory(ctx context.Context, userID uuid.UUID,
 	return events, nil
 }
 
-// TopTracks returns the user's most-played tracks within the trailing window of
-// days, ordered by play count desc then most-recent play. Tracks with no plays in
-// the window are absent.
-func (r *PlayEventRepository) TopTracks(ctx context.Context, userID uuid.UUID, days, limit int) ([]TopTrack, error) {
-	if days <= 0 {
-		days = 30
-	}
+// TopTracks returns the user's most-played tracks played at or after since,
+// ordered by play count desc then most-recent play. Tracks with no plays in the
+// window are absent. Callers pick since so the window starts on a local day
+// boundary in the listener's time zone.
+func (r *PlayEventRepository) TopTracks(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]TopTrack, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -221,7 +267,7 @@ func (r *PlayEventRepository) TopTracks(ctx context.Context, userID uuid.UUID, d
 		FROM (
 			SELECT track_id, COUNT(*) AS play_count, MAX(played_at) AS last_played_at
 			FROM play_events
-			WHERE user_id = $1 AND played_at >= NOW() - make_interval(days => $2)
+			WHERE user_id = $1 AND played_at >= $2
 			GROUP BY track_id
 		) agg
 		JOIN tracks t ON t.id = agg.track_id
@@ -230,7 +276,7 @@ func (r *PlayEventRepository) TopTracks(ctx context.Context, userID uuid.UUID, d
 		LIMIT $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, days, limit)
+	rows, err := r.db.QueryContext(ctx, query, userID, since, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -260,3 +306,44 @@ func (r *PlayEventRepository) TopTracks(ctx context.Context, userID uuid.UUID, d
 	}
 	return tracks, nil
 }
+
+// ShuffleCandidate is a library track with the user's all-time listening
+// history for it. LastPlayedAt is invalid for tracks never played.
+type ShuffleCandidate struct {
+	TrackID      int64
+	PlayCount    int
+	LastPlayedAt sql.NullTime
+}
+
+// ShuffleCandidates returns up to limit tracks from the user's library with
+// their play counts and most recent plays. Libraries larger than limit are
+// sampled uniformly at random, so every track keeps a chance to be picked.
+func (r *PlayEventRepository) ShuffleCandidates(ctx context.Context, userID uuid.UUID, limit int) ([]ShuffleCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ul.track_id, COALESCE(p.play_count, 0), p.last_played_at
+		FROM user_library ul
+		LEFT JOIN (
+			SELECT track_id, COUNT(*) AS play_count, MAX(played_at) AS last_played_at
+			FROM play_events
+			WHERE user_id = $1
+			GROUP BY track_id
+		) p ON p.track_id = ul.track_id
+		WHERE ul.user_id = $1
+		ORDER BY random()
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []ShuffleCandidate
+	for rows.Next() {
+		var c ShuffleCandidate
+		if err := rows.Scan(&c.TrackID, &c.PlayCount, &c.LastPlayedAt); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
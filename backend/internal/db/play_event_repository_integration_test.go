@@ -197,7 +197,7 @@ func TestPlayEventRecordAndListingsAgainstPostgres(t *testing.T) {
 	// -3h, plus... wait -40d is out) -> in-window trackA count = 2 (now + -3h),
 	// trackC count = 2, trackB count = 1. Order by count desc then recency:
 	// trackA and trackC both 2; trackA most-recent (now) beats trackC (-9h).
-	top, err := repo.TopTracks(ctx, user, 30, 10)
+	top, err := repo.TopTracks(ctx, user, time.Now().AddDate(0, 0, -30), 10)
 	if err != nil {
 		t.Fatalf("TopTracks: %v", err)
 	}
@@ -219,7 +219,7 @@ func TestPlayEventRecordAndListingsAgainstPostgres(t *testing.T) {
 	// Seed a track with only an out-of-window play to prove 0-count absence.
 	trackD := seedPlayTrack(t, trackRepo, ctx, "Artist D", "Delta")
 	insertPlayAt(t, database, user, trackD, now.Add(-100*24*time.Hour))
-	topWindow, err := repo.TopTracks(ctx, user, 30, 10)
+	topWindow, err := repo.TopTracks(ctx, user, time.Now().AddDate(0, 0, -30), 10)
 	if err != nil {
 		t.Fatalf("TopTracks window: %v", err)
 	}
@@ -245,3 +245,46 @@ func TestPlayEventsIndexExists(t *testing.T) {
 		t.Fatal("expected idx_play_events_user_played_at index on play_events(user_id, played_at DESC)")
 	}
 }
+
+// TestRecordPlayMaintainsLibraryPlayCount verifies RecordPlay bumps the library
+// entry's aggregate and that sort=play_count orders by it, with never-played
+// tracks first when ascending.
+func TestRecordPlayMaintainsLibraryPlayCount(t *testing.T) {
+	database, ctx := newPlayEventTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	libraryRepo := NewLibraryRepository(database)
+	repo := NewPlayEventRepository(database)
+
+	user := seedPlayUser(t, database, "counts@example.test")
+	hot := seedPlayTrack(t, trackRepo, ctx, "Artist", "Hot")
+	warm := seedPlayTrack(t, trackRepo, ctx, "Artist", "Warm")
+	cold := seedPlayTrack(t, trackRepo, ctx, "Artist", "Cold")
+	outside := seedPlayTrack(t, trackRepo, ctx, "Artist", "Not In Library")
+	for _, id := range []int64{hot, warm, cold} {
+		if _, err := libraryRepo.AddTrackToLibrary(ctx, user, id); err != nil {
+			t.Fatalf("add to library: %v", err)
+		}
+	}
+
+	for _, id := range []int64{hot, hot, hot, warm, outside} {
+		if err := repo.RecordPlay(ctx, user, id, "library", ""); err != nil {
+			t.Fatalf("record play: %v", err)
+		}
+	}
+
+	tracks, _, err := libraryRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{SortBy: "play_count"})
+	if err != nil {
+		t.Fatalf("library: %v", err)
+	}
+	if len(tracks) != 3 || tracks[0].ID != hot || tracks[0].PlayCount != 3 || !tracks[0].LastPlayedAt.Valid {
+		t.Fatalf("desc order = %+v, want hot first with 3 plays", tracks)
+	}
+
+	tracks, _, err = libraryRepo.GetUserLibrary(ctx, user, LibraryQueryOptions{SortBy: "play_count", SortOrder: "asc"})
+	if err != nil {
+		t.Fatalf("library: %v", err)
+	}
+	if tracks[0].ID != cold || tracks[0].PlayCount != 0 || tracks[0].LastPlayedAt.Valid {
+		t.Fatalf("asc first = %+v, want never-played cold", tracks[0])
+	}
+}
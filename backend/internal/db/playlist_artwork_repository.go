@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// SetArtworkKey stores the object key of an uploaded playlist cover, or clears
+// it when key is invalid, and returns the key it replaced so the caller can
+// delete the old object.
+func (r *PlaylistRepository) SetArtworkKey(ctx context.Context, playlistID int64, key sql.NullString) (sql.NullString, error) {
+	var previous sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE playlists p
+		SET artwork_key = $2, updated_at = NOW()
+		FROM (SELECT id, artwork_key FROM playlists WHERE id = $1 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING old.artwork_key
+	`, playlistID, key).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, ErrPlaylistNotFound
+	}
+	return previous, err
+}
+
+// MosaicArtworkSources returns up to limit distinct artwork URLs of the
+// playlist's tracks in playlist order. Tracks without their own cover fall back
+// to the Cover Art Archive image of their MusicBrainz release.
+func (r *PlaylistRepository) MosaicArtworkSources(ctx context.Context, playlistID int64, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT url
+		FROM (
+			SELECT url, MIN(position) AS first_position
+			FROM (
+				SELECT pt.position,
+					COALESCE(NULLIF(t.cover_art_url, ''),
+						CASE WHEN t.mb_release_id IS NOT NULL
+							THEN 'https://coverartarchive.org/release/' || t.mb_release_id::text || '/front-250'
+						END) AS url
+				FROM playlist_tracks pt
+				JOIN tracks t ON t.id = pt.track_id
+				WHERE pt.playlist_id = $1
+			) artwork
+			WHERE url IS NOT NULL
+			GROUP BY url
+		) sources
+		ORDER BY first_position
+		LIMIT $2
+	`, playlistID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// GetMosaicState returns the generated mosaic's object key and the newline
+// separated source URLs it was built from.
+func (r *PlaylistRepository) GetMosaicState(ctx context.Context, playlistID int64) (key, sources sql.NullString, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT mosaic_key, mosaic_sources FROM playlists WHERE id = $1`, playlistID,
+	).Scan(&key, &sources)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrPlaylistNotFound
+	}
+	return key, sources, err
+}
+
+// SetMosaic records a regenerated mosaic and returns the key it replaced.
+// Mosaics are derived data, so updated_at is left untouched.
+func (r *PlaylistRepository) SetMosaic(ctx context.Context, playlistID int64, key, sources sql.NullString) (sql.NullString, error) {
+	var previous sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE playlists p
+		SET mosaic_key = $2, mosaic_sources = $3
+		FROM (SELECT id, mosaic_key FROM playlists WHERE id = $1 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING old.mosaic_key
+	`, playlistID, key, sources).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, ErrPlaylistNotFound
+	}
+	return previous, err
+}
@@ -0,0 +1,458 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrCollaboratorNotFound = errors.New("collaborator not found")
+var ErrCollaboratorIsOwner = errors.New("playlist owner cannot be added as a collaborator")
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Playlist activity kinds recorded in the collaborative activity feed.
+const (
+	PlaylistActivityCommentAdded        = "comment_added"
+	PlaylistActivityCommentEdited       = "comment_edited"
+	PlaylistActivityCommentDeleted      = "comment_deleted"
+	PlaylistActivityCollaboratorAdded   = "collaborator_added"
+	PlaylistActivityCollaboratorRemoved = "collaborator_removed"
+	PlaylistActivityTracksAdded         = "tracks_added"
+	PlaylistActivityTracksRemoved       = "tracks_removed"
+	PlaylistActivityTrackMoved          = "track_moved"
+	PlaylistActivityReverted            = "reverted"
+)
+
+// NotificationKindMention is stored when a comment @-mentions a playlist member.
+const NotificationKindMention = "mention"
+
+type PlaylistCollaborator struct {
+	PlaylistID int64
+	UserID     uuid.UUID
+	Handle     sql.NullString
+	Username   string // display name, falling back to the account username
+	AvatarKey  sql.NullString
+	AddedAt    time.Time
+}
+
+type PlaylistComment struct {
+	ID         int64
+	PlaylistID int64
+	TrackID    sql.NullInt64
+	UserID     uuid.UUID
+	AuthorName string
+	Body       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type PlaylistActivity struct {
+	ID         int64
+	PlaylistID int64
+	ActorID    uuid.NullUUID
+	ActorName  sql.NullString
+	Kind       string
+	Payload    json.RawMessage
+	CreatedAt  time.Time
+}
+
+// IsCollaborator reports whether userID has been invited to collaborate on the
+// playlist. Owners are not stored as collaborators.
+func (r *PlaylistRepository) IsCollaborator(ctx context.Context, playlistID int64, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM playlist_collaborators WHERE playlist_id = $1 AND user_id = $2)`,
+		playlistID, userID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// AddCollaboratorByHandle invites the user owning a profile handle to the
+// playlist. Adding an existing collaborator is a no-op that still returns them.
+func (r *PlaylistRepository) AddCollaboratorByHandle(ctx context.Context, playlistID int64, handle string, addedBy uuid.UUID) (*PlaylistCollaborator, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var collaborator PlaylistCollaborator
+	err = tx.QueryRowContext(ctx, `
+		SELECT up.user_id, up.handle, COALESCE(u.display_name, u.username), u.avatar_key
+		FROM user_profiles up
+		JOIN users u ON u.id = up.user_id
+		WHERE up.handle = $1
+	`, handle).Scan(&collaborator.UserID, &collaborator.Handle, &collaborator.Username, &collaborator.AvatarKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	collaborator.PlaylistID = playlistID
+
+	var ownerID uuid.UUID
+	if err := tx.QueryRowContext(ctx, `SELECT user_id FROM playlists WHERE id = $1`, playlistID).Scan(&ownerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPlaylistNotFound
+		}
+		return nil, err
+	}
+	if ownerID == collaborator.UserID {
+		return nil, ErrCollaboratorIsOwner
+	}
+
+	inserted := true
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO playlist_collaborators (playlist_id, user_id, added_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (playlist_id, user_id) DO NOTHING
+		RETURNING created_at
+	`, playlistID, collaborator.UserID, addedBy).Scan(&collaborator.AddedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		inserted = false
+		err = tx.QueryRowContext(ctx,
+			`SELECT created_at FROM playlist_collaborators WHERE playlist_id = $1 AND user_id = $2`,
+			playlistID, collaborator.UserID,
+		).Scan(&collaborator.AddedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if inserted {
+		if err := insertPlaylistActivity(ctx, tx, playlistID, addedBy, PlaylistActivityCollaboratorAdded, map[string]interface{}{
+			"handle": collaborator.Handle.String,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+// RemoveCollaborator revokes a collaborator identified by profile handle.
+func (r *PlaylistRepository) RemoveCollaborator(ctx context.Context, playlistID int64, handle string, removedBy uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM playlist_collaborators pc
+		USING user_profiles up
+		WHERE pc.playlist_id = $1 AND pc.user_id = up.user_id AND up.handle = $2
+	`, playlistID, handle)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrCollaboratorNotFound
+	}
+
+	if err := insertPlaylistActivity(ctx, tx, playlistID, removedBy, PlaylistActivityCollaboratorRemoved, map[string]interface{}{
+		"handle": handle,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListCollaborators returns the playlist's collaborators in invitation order.
+func (r *PlaylistRepository) ListCollaborators(ctx context.Context, playlistID int64) ([]PlaylistCollaborator, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pc.playlist_id, pc.user_id, up.handle, COALESCE(u.display_name, u.username), u.avatar_key, pc.created_at
+		FROM playlist_collaborators pc
+		JOIN users u ON u.id = pc.user_id
+		LEFT JOIN user_profiles up ON up.user_id = pc.user_id
+		WHERE pc.playlist_id = $1
+		ORDER BY pc.created_at ASC, pc.user_id ASC
+	`, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collaborators []PlaylistCollaborator
+	for rows.Next() {
+		var c PlaylistCollaborator
+		if err := rows.Scan(&c.PlaylistID, &c.UserID, &c.Handle, &c.Username, &c.AvatarKey, &c.AddedAt); err != nil {
+			return nil, err
+		}
+		collaborators = append(collaborators, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
+
+// RecordActivity appends an entry to the playlist's activity feed.
+func (r *PlaylistRepository) RecordActivity(ctx context.Context, playlistID int64, actorID uuid.UUID, kind string, payload map[string]interface{}) error {
+	return insertPlaylistActivity(ctx, r.db, playlistID, actorID, kind, payload)
+}
+
+// ListActivity returns the playlist's activity feed newest first.
+func (r *PlaylistRepository) ListActivity(ctx context.Context, playlistID int64, limit, offset int) ([]PlaylistActivity, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, a.playlist_id, a.actor_id, COALESCE(u.display_name, u.username), a.kind, a.payload, a.created_at
+		FROM playlist_activity a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE a.playlist_id = $1
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $2 OFFSET $3
+	`, playlistID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activity []PlaylistActivity
+	for rows.Next() {
+		var a PlaylistActivity
+		if err := rows.Scan(&a.ID, &a.PlaylistID, &a.ActorID, &a.ActorName, &a.Kind, &a.Payload, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// ListComments returns a playlist's comments oldest first. A non-zero trackID
+// narrows the listing to that track's notes.
+func (r *PlaylistRepository) ListComments(ctx context.Context, playlistID, trackID int64, limit, offset int) ([]PlaylistComment, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.playlist_id, c.track_id, c.user_id, COALESCE(u.display_name, u.username), c.body, c.created_at, c.updated_at
+		FROM playlist_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.playlist_id = $1 AND ($2 = 0 OR c.track_id = $2)
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $3 OFFSET $4
+	`, playlistID, trackID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []PlaylistComment
+	for rows.Next() {
+		var c PlaylistComment
+		if err := rows.Scan(&c.ID, &c.PlaylistID, &c.TrackID, &c.UserID, &c.AuthorName, &c.Body, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetComment loads one comment scoped to its playlist.
+func (r *PlaylistRepository) GetComment(ctx context.Context, playlistID, commentID int64) (*PlaylistComment, error) {
+	var c PlaylistComment
+	err := r.db.QueryRowContext(ctx, `
+		SELECT c.id, c.playlist_id, c.track_id, c.user_id, COALESCE(u.display_name, u.username), c.body, c.created_at, c.updated_at
+		FROM playlist_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.playlist_id = $1 AND c.id = $2
+	`, playlistID, commentID).Scan(&c.ID, &c.PlaylistID, &c.TrackID, &c.UserID, &c.AuthorName, &c.Body, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateComment stores a comment, records it in the activity feed, and sends a
+// mention notification to every mentioned handle that belongs to a playlist
+// member other than the author. It returns how many users were notified.
+func (r *PlaylistRepository) CreateComment(ctx context.Context, comment *PlaylistComment, mentions []string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if comment.TrackID.Valid {
+		var inPlaylist bool
+		if err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM playlist_tracks WHERE playlist_id = $1 AND track_id = $2)`,
+			comment.PlaylistID, comment.TrackID.Int64,
+		).Scan(&inPlaylist); err != nil {
+			return 0, err
+		}
+		if !inPlaylist {
+			return 0, ErrTrackNotInPlaylist
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO playlist_comments (playlist_id, track_id, user_id, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at, (SELECT COALESCE(display_name, username) FROM users WHERE id = $3)
+	`, comment.PlaylistID, comment.TrackID, comment.UserID, comment.Body,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt, &comment.AuthorName)
+	if err != nil {
+		return 0, err
+	}
+
+	activity := map[string]interface{}{"commentId": comment.ID}
+	if comment.TrackID.Valid {
+		activity["trackId"] = comment.TrackID.Int64
+	}
+	if err := insertPlaylistActivity(ctx, tx, comment.PlaylistID, comment.UserID, PlaylistActivityCommentAdded, activity); err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	if len(mentions) > 0 {
+		payload := map[string]interface{}{
+			"playlistId": comment.PlaylistID,
+			"commentId":  comment.ID,
+			"author":     comment.AuthorName,
+		}
+		if comment.TrackID.Valid {
+			payload["trackId"] = comment.TrackID.Int64
+		}
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return 0, err
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO notifications (user_id, kind, payload)
+			SELECT up.user_id, $1, $2
+			FROM user_profiles up
+			WHERE up.handle = ANY($3)
+			  AND up.user_id <> $4
+			  AND (
+				EXISTS (SELECT 1 FROM playlists p WHERE p.id = $5 AND p.user_id = up.user_id)
+				OR EXISTS (SELECT 1 FROM playlist_collaborators pc WHERE pc.playlist_id = $5 AND pc.user_id = up.user_id)
+			  )
+		`, NotificationKindMention, encoded, pq.Array(mentions), comment.UserID, comment.PlaylistID)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		notified = int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return notified, nil
+}
+
+// UpdateComment replaces a comment body. Only the author may edit.
+func (r *PlaylistRepository) UpdateComment(ctx context.Context, comment *PlaylistComment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE playlist_comments SET body = $1, updated_at = NOW()
+		WHERE playlist_id = $2 AND id = $3 AND user_id = $4
+		RETURNING updated_at
+	`, comment.Body, comment.PlaylistID, comment.ID, comment.UserID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		return err
+	}
+	if err := insertPlaylistActivity(ctx, tx, comment.PlaylistID, comment.UserID, PlaylistActivityCommentEdited, map[string]interface{}{
+		"commentId": comment.ID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteComment removes a comment. Authorization is the caller's concern.
+func (r *PlaylistRepository) DeleteComment(ctx context.Context, playlistID, commentID int64, actorID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM playlist_comments WHERE playlist_id = $1 AND id = $2`, playlistID, commentID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrCommentNotFound
+	}
+	if err := insertPlaylistActivity(ctx, tx, playlistID, actorID, PlaylistActivityCommentDeleted, map[string]interface{}{
+		"commentId": commentID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type playlistActivityExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertPlaylistActivity(ctx context.Context, exec playlistActivityExecer, playlistID int64, actorID uuid.UUID, kind string, payload map[string]interface{}) error {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = exec.ExecContext(ctx,
+		`INSERT INTO playlist_activity (playlist_id, actor_id, kind, payload) VALUES ($1, $2, $3, $4)`,
+		playlistID, uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil}, kind, encoded,
+	)
+	return err
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Position strategies for MergeTracks.
+const (
+	PlaylistMergeAppend     = "append"
+	PlaylistMergePrepend    = "prepend"
+	PlaylistMergeInterleave = "interleave"
+)
+
+var ErrInvalidMergeStrategy = errors.New("invalid merge strategy")
+
+// Duplicate creates dup as a new playlist holding the source playlist's tracks
+// in the same order. dup.ID and timestamps are filled in on success.
+func (r *PlaylistRepository) Duplicate(ctx context.Context, sourceID int64, dup *Playlist) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO playlists (user_id, name, description, cover_url, is_public)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, dup.UserID, dup.Name, dup.Description, dup.CoverURL, dup.IsPublic,
+	).Scan(&dup.ID, &dup.CreatedAt, &dup.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO playlist_tracks (playlist_id, track_id, position)
+		SELECT $1, track_id, (ROW_NUMBER() OVER (ORDER BY position, track_id) - 1)
+		FROM playlist_tracks
+		WHERE playlist_id = $2
+	`, dup.ID, sourceID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MergeTracks combines the source playlist's tracks into the target using the
+// given position strategy. Tracks already in the target are skipped, so the
+// target keeps its own placement for them.
+func (r *PlaylistRepository) MergeTracks(ctx context.Context, targetID, sourceID int64, strategy string) (AddTracksResult, error) {
+	result := AddTracksResult{Added: []int64{}, Skipped: []int64{}}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPlaylistForHistory(ctx, tx, targetID); err != nil {
+		return result, err
+	}
+	target, err := playlistTrackOrder(ctx, tx, targetID)
+	if err != nil {
+		return result, err
+	}
+	source, err := playlistTrackOrder(ctx, tx, sourceID)
+	if err != nil {
+		return result, err
+	}
+
+	order, merged, err := mergePlaylistOrder(target, source, strategy)
+	if err != nil {
+		return result, err
+	}
+	if len(merged.Added) == 0 {
+		return merged, nil
+	}
+
+	if _, err := writePlaylistOrder(ctx, tx, targetID, order); err != nil {
+		return result, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE playlists SET updated_at = NOW() WHERE id = $1`, targetID); err != nil {
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	return merged, nil
+}
+
+// mergePlaylistOrder computes the target's new track order after merging in
+// source. Source tracks already present in the target are reported as skipped.
+func mergePlaylistOrder(target, source []int64, strategy string) ([]int64, AddTracksResult, error) {
+	result := AddTracksResult{Added: []int64{}, Skipped: []int64{}}
+
+	existing := make(map[int64]bool, len(target))
+	for _, id := range target {
+		existing[id] = true
+	}
+	incoming := make([]int64, 0, len(source))
+	for _, id := range source {
+		if existing[id] {
+			result.Skipped = append(result.Skipped, id)
+			continue
+		}
+		existing[id] = true
+		incoming = append(incoming, id)
+	}
+	result.Added = append(result.Added, incoming...)
+
+	order := make([]int64, 0, len(target)+len(incoming))
+	switch strategy {
+	case PlaylistMergeAppend, "":
+		order = append(append(order, target...), incoming...)
+	case PlaylistMergePrepend:
+		order = append(append(order, incoming...), target...)
+	case PlaylistMergeInterleave:
+		for i := 0; i < len(target) || i < len(incoming); i++ {
+			if i < len(target) {
+				order = append(order, target[i])
+			}
+			if i < len(incoming) {
+				order = append(order, incoming[i])
+			}
+		}
+	default:
+		return nil, AddTracksResult{Added: []int64{}, Skipped: []int64{}}, fmt.Errorf("%w: %q", ErrInvalidMergeStrategy, strategy)
+	}
+	return order, result, nil
+}
+
+// playlistTrackOrder returns the playlist's track IDs in position order.
+func playlistTrackOrder(ctx context.Context, tx *sql.Tx, playlistID int64) ([]int64, error) {
+	var trackIDs []int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(track_id ORDER BY position, track_id), '{}')
+		FROM playlist_tracks
+		WHERE playlist_id = $1
+	`, playlistID).Scan(pq.Array(&trackIDs))
+	return trackIDs, err
+}
+
+// writePlaylistOrder sets the playlist's tracks to exactly trackIDs, in order
+// and with contiguous positions. Rows for tracks that stay keep their added_at;
+// IDs whose track no longer exists are skipped. It returns how many rows were
+// written.
+func writePlaylistOrder(ctx context.Context, tx *sql.Tx, playlistID int64, trackIDs []int64) (int64, error) {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM playlist_tracks WHERE playlist_id = $1 AND NOT (track_id = ANY($2))`,
+		playlistID, pq.Array(trackIDs),
+	); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO playlist_tracks (playlist_id, track_id, position)
+		SELECT $1, t.id, (ROW_NUMBER() OVER (ORDER BY v.ord) - 1)
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS v(track_id, ord)
+		JOIN tracks t ON t.id = v.track_id
+		ON CONFLICT (playlist_id, track_id) DO UPDATE SET position = EXCLUDED.position
+	`, playlistID, pq.Array(trackIDs))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergePlaylistOrderStrategies(t *testing.T) {
+	target := []int64{1, 2, 3}
+	source := []int64{3, 10, 11, 12}
+
+	cases := []struct {
+		strategy string
+		want     []int64
+	}{
+		{"", []int64{1, 2, 3, 10, 11, 12}},
+		{PlaylistMergeAppend, []int64{1, 2, 3, 10, 11, 12}},
+		{PlaylistMergePrepend, []int64{10, 11, 12, 1, 2, 3}},
+		{PlaylistMergeInterleave, []int64{1, 10, 2, 11, 3, 12}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			order, result, err := mergePlaylistOrder(target, source, tc.strategy)
+			if err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+			if !reflect.DeepEqual(order, tc.want) {
+				t.Fatalf("order = %v, want %v", order, tc.want)
+			}
+			if !reflect.DeepEqual(result.Added, []int64{10, 11, 12}) || !reflect.DeepEqual(result.Skipped, []int64{3}) {
+				t.Fatalf("result = %#v, want added [10 11 12] skipped [3]", result)
+			}
+		})
+	}
+}
+
+func TestMergePlaylistOrderRejectsUnknownStrategy(t *testing.T) {
+	if _, _, err := mergePlaylistOrder([]int64{1}, []int64{2}, "shuffle"); !errors.Is(err, ErrInvalidMergeStrategy) {
+		t.Fatalf("err = %v, want ErrInvalidMergeStrategy", err)
+	}
+}
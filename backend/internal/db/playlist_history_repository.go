@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrPlaylistVersionNotFound = errors.New("playlist version not found")
+
+// PlaylistVersionBaseline marks the snapshot taken before the first recorded
+// change, so the very first edit to a playlist can also be undone. Other
+// versions use the playlist activity kind that produced them as their reason.
+const PlaylistVersionBaseline = "baseline"
+
+// PlaylistHistoryRetention is the number of versions kept per playlist; older
+// snapshots are pruned as new ones are recorded.
+const PlaylistHistoryRetention = 100
+
+type PlaylistVersion struct {
+	PlaylistID   int64
+	Version      int
+	TrackIDs     []int64
+	TrackCount   int
+	ActorID      uuid.NullUUID
+	ActorName    sql.NullString
+	Reason       string
+	RevertedFrom sql.NullInt32
+	CreatedAt    time.Time
+}
+
+// EnsureHistoryBaseline snapshots the playlist's current tracks as version 1
+// when it has no history yet. Call it before a mutation so the pre-change state
+// of playlists created before history existed is still recoverable.
+func (r *PlaylistRepository) EnsureHistoryBaseline(ctx context.Context, playlistID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO playlist_versions (playlist_id, version, track_ids, reason)
+		SELECT $1, 1,
+			COALESCE((SELECT array_agg(track_id ORDER BY position, track_id) FROM playlist_tracks WHERE playlist_id = $1), '{}'),
+			$2
+		WHERE NOT EXISTS (SELECT 1 FROM playlist_versions WHERE playlist_id = $1)
+		ON CONFLICT (playlist_id, version) DO NOTHING
+	`, playlistID, PlaylistVersionBaseline)
+	return err
+}
+
+// RecordSnapshot stores the playlist's current track order as a new version.
+func (r *PlaylistRepository) RecordSnapshot(ctx context.Context, playlistID int64, actorID uuid.UUID, reason string) (*PlaylistVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPlaylistForHistory(ctx, tx, playlistID); err != nil {
+		return nil, err
+	}
+	version, err := insertPlaylistVersion(ctx, tx, playlistID, actorID, reason, sql.NullInt32{})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// ListVersions returns the playlist's history newest first. TrackIDs is left
+// empty; TrackCount summarizes each snapshot.
+func (r *PlaylistRepository) ListVersions(ctx context.Context, playlistID int64, limit, offset int) ([]PlaylistVersion, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT v.playlist_id, v.version, cardinality(v.track_ids), v.actor_id, COALESCE(u.display_name, u.username),
+			v.reason, v.reverted_from, v.created_at
+		FROM playlist_versions v
+		LEFT JOIN users u ON u.id = v.actor_id
+		WHERE v.playlist_id = $1
+		ORDER BY v.version DESC
+		LIMIT $2 OFFSET $3
+	`, playlistID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []PlaylistVersion
+	for rows.Next() {
+		var v PlaylistVersion
+		if err := rows.Scan(&v.PlaylistID, &v.Version, &v.TrackCount, &v.ActorID, &v.ActorName,
+			&v.Reason, &v.RevertedFrom, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// RevertToVersion restores the playlist's tracks to the order captured in
+// version and records the result as a new version, so a revert can itself be
+// undone. Tracks deleted from the library since the snapshot are skipped and
+// reported in missing. Tracks kept across the revert retain their added_at.
+func (r *PlaylistRepository) RevertToVersion(ctx context.Context, playlistID int64, version int, actorID uuid.UUID) (restored *PlaylistVersion, missing int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPlaylistForHistory(ctx, tx, playlistID); err != nil {
+		return nil, 0, err
+	}
+
+	var trackIDs []int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT track_ids FROM playlist_versions WHERE playlist_id = $1 AND version = $2`,
+		playlistID, version,
+	).Scan(pq.Array(&trackIDs))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrPlaylistVersionNotFound
+		}
+		return nil, 0, err
+	}
+
+	affected, err := writePlaylistOrder(ctx, tx, playlistID, trackIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	missing = len(trackIDs) - int(affected)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE playlists SET updated_at = NOW() WHERE id = $1`, playlistID); err != nil {
+		return nil, 0, err
+	}
+
+	restored, err = insertPlaylistVersion(ctx, tx, playlistID, actorID, PlaylistActivityReverted,
+		sql.NullInt32{Int32: int32(version), Valid: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := insertPlaylistActivity(ctx, tx, playlistID, actorID, PlaylistActivityReverted, map[string]interface{}{
+		"version":      restored.Version,
+		"revertedFrom": version,
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	return restored, missing, nil
+}
+
+// lockPlaylistForHistory serializes version numbering for a playlist.
+func lockPlaylistForHistory(ctx context.Context, tx *sql.Tx, playlistID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM playlists WHERE id = $1 FOR UPDATE`, playlistID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPlaylistNotFound
+	}
+	return err
+}
+
+func insertPlaylistVersion(ctx context.Context, tx *sql.Tx, playlistID int64, actorID uuid.UUID, reason string, revertedFrom sql.NullInt32) (*PlaylistVersion, error) {
+	v := &PlaylistVersion{
+		PlaylistID:   playlistID,
+		ActorID:      uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		Reason:       reason,
+		RevertedFrom: revertedFrom,
+	}
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO playlist_versions (playlist_id, version, track_ids, actor_id, reason, reverted_from)
+		SELECT $1,
+			COALESCE((SELECT MAX(version) FROM playlist_versions WHERE playlist_id = $1), 0) + 1,
+			COALESCE((SELECT array_agg(track_id ORDER BY position, track_id) FROM playlist_tracks WHERE playlist_id = $1), '{}'),
+			$2, $3, $4
+		RETURNING version, track_ids, cardinality(track_ids), created_at
+	`, playlistID, v.ActorID, reason, revertedFrom).Scan(&v.Version, pq.Array(&v.TrackIDs), &v.TrackCount, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM playlist_versions WHERE playlist_id = $1 AND version <= $2`,
+		playlistID, v.Version-PlaylistHistoryRetention,
+	); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
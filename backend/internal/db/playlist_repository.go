@@ -24,6 +24,9 @@ type Playlist struct {
 	Description sql.NullString
 	CoverURL    sql.NullString
 	IsPublic    bool
+	SystemKind  sql.NullString // set for generated, read-only playlists such as Daily Mixes
+	ArtworkKey  sql.NullString // object key of an uploaded cover image
+	MosaicKey   sql.NullString // object key of the generated track-artwork mosaic
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -79,14 +82,14 @@ func (r *PlaylistRepository) Create(ctx context.Context, playlist *Playlist) err
 // GetByID retrieves a playlist by its ID.
 func (r *PlaylistRepository) GetByID(ctx context.Context, id int64) (*Playlist, error) {
 	query := `
-		SELECT id, user_id, name, description, cover_url, is_public, created_at, updated_at
+		SELECT id, user_id, name, description, cover_url, is_public, system_kind, artwork_key, mosaic_key, created_at, updated_at
 		FROM playlists
 		WHERE id = $1
 	`
 
 	var p Playlist
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.SystemKind, &p.ArtworkKey, &p.MosaicKey, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -102,7 +105,7 @@ func (r *PlaylistRepository) GetByID(ctx context.Context, id int64) (*Playlist,
 func (r *PlaylistRepository) GetByIDWithTracks(ctx context.Context, id int64) (*PlaylistWithTracks, error) {
 	// Single query to get playlist info and all tracks
 	query := `
-		SELECT p.id, p.user_id, p.name, p.description, p.cover_url, p.is_public, p.created_at, p.updated_at,
+		SELECT p.id, p.user_id, p.name, p.description, p.cover_url, p.is_public, p.system_kind, p.artwork_key, p.mosaic_key, p.created_at, p.updated_at,
 			   t.id, t.identity_hash, t.title, t.artist, t.album, t.duration_ms, t.version,
 			   t.mb_recording_id, t.mb_release_id, t.mb_artist_id, t.mb_verified,
 			   t.source_url, t.source_type, t.storage_key, t.file_size_bytes,
@@ -137,7 +140,7 @@ func (r *PlaylistRepository) GetByIDWithTracks(ctx context.Context, id int64) (*
 		var analysisOverrides json.RawMessage
 
 		err := rows.Scan(
-			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.SystemKind, &p.ArtworkKey, &p.MosaicKey, &p.CreatedAt, &p.UpdatedAt,
 			&trackID, &t.IdentityHash, &t.Title, &t.Artist, &t.Album, &t.DurationMs, &t.Version,
 			&t.MBRecordingID, &t.MBReleaseID, &t.MBArtistID, &t.MBVerified,
 			&t.SourceURL, &t.SourceType, &t.StorageKey, &t.FileSizeBytes,
@@ -197,7 +200,7 @@ func (r *PlaylistRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 	}
 
 	// Resolve ORDER BY from a whitelist so untrusted query params can never be
-	// concatenated into SQL. track_count aliases the aggregate expression.
+	// concatenated into SQL.
 	orderColumn := "p.updated_at"
 	defaultDesc := true
 	switch strings.ToLower(params.Sort) {
@@ -205,7 +208,7 @@ func (r *PlaylistRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 		orderColumn = "LOWER(p.name)"
 		defaultDesc = false
 	case "track_count":
-		orderColumn = "track_count"
+		orderColumn = "p.track_count"
 		defaultDesc = true
 	}
 
@@ -222,18 +225,15 @@ func (r *PlaylistRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 	}
 
 	// Single query with window function for total count (eliminates separate COUNT query).
+	// Track counts and durations are maintained on the playlist row by triggers.
 	// $2 is the case-insensitive name filter ("" => match all).
 	selectQuery := `
-		SELECT p.id, p.user_id, p.name, p.description, p.cover_url, p.is_public, p.created_at, p.updated_at,
-			   COALESCE(COUNT(pt.track_id), 0) as track_count,
-			   COALESCE(SUM(t.duration_ms), 0) as total_duration,
+		SELECT p.id, p.user_id, p.name, p.description, p.cover_url, p.is_public, p.system_kind, p.artwork_key, p.mosaic_key, p.created_at, p.updated_at,
+			   p.track_count, p.total_duration_ms,
 			   COUNT(*) OVER() as total_playlists
 		FROM playlists p
-		LEFT JOIN playlist_tracks pt ON p.id = pt.playlist_id
-		LEFT JOIN tracks t ON pt.track_id = t.id
 		WHERE p.user_id = $1
 		  AND ($2 = '' OR p.name ILIKE '%' || $2 || '%')
-		GROUP BY p.id
 		ORDER BY ` + orderColumn + ` ` + direction + `, p.id ASC
 		LIMIT $3 OFFSET $4
 	`
@@ -249,7 +249,7 @@ func (r *PlaylistRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 	for rows.Next() {
 		var p PlaylistWithTracks
 		err := rows.Scan(
-			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.SystemKind, &p.ArtworkKey, &p.MosaicKey, &p.CreatedAt, &p.UpdatedAt,
 			&p.TrackCount, &p.DurationMs, &total,
 		)
 		if err != nil {
@@ -265,6 +265,15 @@ func (r *PlaylistRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 	return playlists, total, nil
 }
 
+// RebuildTotals recomputes every playlist's maintained track count and
+// duration and returns how many had drifted. The triggers that keep them
+// current make this a repair tool, not part of normal operation.
+func (r *PlaylistRepository) RebuildTotals(ctx context.Context) (int, error) {
+	var changed int
+	err := r.db.QueryRowContext(ctx, `SELECT refresh_playlist_totals(NULL)`).Scan(&changed)
+	return changed, err
+}
+
 // Update updates a playlist's name and description.
 func (r *PlaylistRepository) Update(ctx context.Context, playlist *Playlist) error {
 	query := `
@@ -417,3 +417,182 @@ func TestPlaylistCoverAndPublicRoundTrip(t *testing.T) {
 		t.Fatalf("cover_url should be NULL after clear, got %#v", cleared.CoverURL)
 	}
 }
+
+// TestPlaylistRevertRestoresSnapshotOrder covers the history flow: a baseline is
+// taken before the first edit, a bulk removal is snapshotted, and reverting to
+// the baseline restores the original order as a new, itself-revertible version.
+func TestPlaylistRevertRestoresSnapshotOrder(t *testing.T) {
+	database, ctx := newPlaylistTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	repo := NewPlaylistRepository(database)
+
+	userID := seedPlaylistUser(t, database, "history@example.test")
+	pl := &Playlist{UserID: userID, Name: "Undo Me"}
+	if err := repo.Create(ctx, pl); err != nil {
+		t.Fatalf("create playlist: %v", err)
+	}
+
+	var trackIDs []int64
+	for _, title := range []string{"h0", "h1", "h2", "h3"} {
+		trackIDs = append(trackIDs, seedPlaylistTrack(t, trackRepo, ctx, "Artist", title))
+	}
+	if _, err := repo.AddTracks(ctx, pl.ID, trackIDs); err != nil {
+		t.Fatalf("add tracks: %v", err)
+	}
+
+	if err := repo.EnsureHistoryBaseline(ctx, pl.ID); err != nil {
+		t.Fatalf("baseline: %v", err)
+	}
+	if err := repo.RemoveTracks(ctx, pl.ID, trackIDs[:3]); err != nil {
+		t.Fatalf("batch remove: %v", err)
+	}
+	removed, err := repo.RecordSnapshot(ctx, pl.ID, userID, PlaylistActivityTracksRemoved)
+	if err != nil {
+		t.Fatalf("record snapshot: %v", err)
+	}
+	if removed.Version != 2 || removed.TrackCount != 1 {
+		t.Fatalf("snapshot = version %d with %d tracks, want version 2 with 1", removed.Version, removed.TrackCount)
+	}
+
+	restored, missing, err := repo.RevertToVersion(ctx, pl.ID, 1, userID)
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if restored.Version != 3 || !restored.RevertedFrom.Valid || restored.RevertedFrom.Int32 != 1 || missing != 0 {
+		t.Fatalf("revert = %#v missing=%d, want version 3 reverted from 1", restored, missing)
+	}
+	positions := playlistPositions(t, database, pl.ID)
+	contiguousPositions(t, positions, 4)
+	for i, id := range trackIDs {
+		if positions[id] != i {
+			t.Fatalf("track %d position = %d, want %d", id, positions[id], i)
+		}
+	}
+
+	versions, err := repo.ListVersions(ctx, pl.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	if len(versions) != 3 || versions[0].Version != 3 || versions[2].Reason != PlaylistVersionBaseline {
+		t.Fatalf("versions = %#v, want 3 newest-first ending with the baseline", versions)
+	}
+
+	if _, _, err := repo.RevertToVersion(ctx, pl.ID, 99, userID); err != ErrPlaylistVersionNotFound {
+		t.Fatalf("revert unknown version err = %v, want ErrPlaylistVersionNotFound", err)
+	}
+}
+
+// TestPlaylistDuplicateAndMerge covers copying a playlist's tracks in order and
+// merging another playlist in with de-duplication.
+func TestPlaylistDuplicateAndMerge(t *testing.T) {
+	database, ctx := newPlaylistTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	repo := NewPlaylistRepository(database)
+
+	userID := seedPlaylistUser(t, database, "curate@example.test")
+	source := &Playlist{UserID: userID, Name: "Source"}
+	if err := repo.Create(ctx, source); err != nil {
+		t.Fatalf("create playlist: %v", err)
+	}
+	var trackIDs []int64
+	for _, title := range []string{"c0", "c1", "c2"} {
+		trackIDs = append(trackIDs, seedPlaylistTrack(t, trackRepo, ctx, "Artist", title))
+	}
+	if _, err := repo.AddTracks(ctx, source.ID, trackIDs[:2]); err != nil {
+		t.Fatalf("add tracks: %v", err)
+	}
+
+	dup := &Playlist{UserID: userID, Name: "Source (copy)"}
+	if err := repo.Duplicate(ctx, source.ID, dup); err != nil {
+		t.Fatalf("duplicate: %v", err)
+	}
+	positions := playlistPositions(t, database, dup.ID)
+	if len(positions) != 2 || positions[trackIDs[0]] != 0 || positions[trackIDs[1]] != 1 {
+		t.Fatalf("duplicate positions = %v, want source order", positions)
+	}
+
+	other := &Playlist{UserID: userID, Name: "Other"}
+	if err := repo.Create(ctx, other); err != nil {
+		t.Fatalf("create playlist: %v", err)
+	}
+	if _, err := repo.AddTracks(ctx, other.ID, []int64{trackIDs[2], trackIDs[1]}); err != nil {
+		t.Fatalf("add tracks: %v", err)
+	}
+
+	result, err := repo.MergeTracks(ctx, dup.ID, other.ID, PlaylistMergePrepend)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != trackIDs[2] || len(result.Skipped) != 1 {
+		t.Fatalf("merge result = %#v", result)
+	}
+	positions = playlistPositions(t, database, dup.ID)
+	contiguousPositions(t, positions, 3)
+	if positions[trackIDs[2]] != 0 || positions[trackIDs[0]] != 1 {
+		t.Fatalf("merged positions = %v, want c2 prepended", positions)
+	}
+}
+
+func playlistTotals(t *testing.T, repo *PlaylistRepository, ctx context.Context, userID uuid.UUID) (int, int64) {
+	t.Helper()
+	playlists, _, err := repo.GetByUserID(ctx, userID, ListPlaylistsParams{})
+	if err != nil || len(playlists) != 1 {
+		t.Fatalf("list playlists: %d, %v", len(playlists), err)
+	}
+	return playlists[0].TrackCount, playlists[0].DurationMs
+}
+
+func TestPlaylistTotalsFollowMutations(t *testing.T) {
+	database, ctx := newPlaylistTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	repo := NewPlaylistRepository(database)
+
+	userID := seedPlaylistUser(t, database, "totals@example.test")
+	pl := &Playlist{UserID: userID, Name: "Totals"}
+	if err := repo.Create(ctx, pl); err != nil {
+		t.Fatalf("create playlist: %v", err)
+	}
+	var trackIDs []int64
+	for _, title := range []string{"t0", "t1", "t2"} {
+		trackIDs = append(trackIDs, seedPlaylistTrack(t, trackRepo, ctx, "Artist", title))
+	}
+
+	steps := []struct {
+		name     string
+		mutate   func() error
+		count    int
+		duration int64
+	}{
+		{"add", func() error { _, err := repo.AddTracks(ctx, pl.ID, trackIDs); return err }, 3, 600000},
+		{"reorder", func() error { return repo.ReorderTrack(ctx, pl.ID, trackIDs[2], 0) }, 3, 600000},
+		{"remove", func() error { return repo.RemoveTrack(ctx, pl.ID, trackIDs[0]) }, 2, 400000},
+		{"duration change", func() error {
+			_, err := database.Exec(`UPDATE tracks SET duration_ms = 250000 WHERE id = $1`, trackIDs[1])
+			return err
+		}, 2, 450000},
+		{"track deleted", func() error {
+			_, err := database.Exec(`DELETE FROM tracks WHERE id = $1`, trackIDs[2])
+			return err
+		}, 1, 250000},
+	}
+	for _, step := range steps {
+		if err := step.mutate(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if count, duration := playlistTotals(t, repo, ctx, userID); count != step.count || duration != step.duration {
+			t.Fatalf("after %s: totals = %d tracks, %d ms; want %d, %d", step.name, count, duration, step.count, step.duration)
+		}
+	}
+
+	// Drift introduced behind the triggers' back is repaired by a rebuild.
+	if _, err := database.Exec(`UPDATE playlists SET track_count = 9, total_duration_ms = 0 WHERE id = $1`, pl.ID); err != nil {
+		t.Fatalf("corrupt totals: %v", err)
+	}
+	changed, err := repo.RebuildTotals(ctx)
+	if err != nil || changed != 1 {
+		t.Fatalf("rebuild = %d, %v; want 1 playlist corrected", changed, err)
+	}
+	if count, duration := playlistTotals(t, repo, ctx, userID); count != 1 || duration != 250000 {
+		t.Fatalf("after rebuild: totals = %d tracks, %d ms", count, duration)
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxPlaylistSearchTrackHits caps how many matching tracks are returned per
+// playlist; MatchedTrackCount still reports the full number.
+const maxPlaylistSearchTrackHits = 5
+
+// PlaylistTrackHit is a playlist track whose title matched a search, with its
+// position so clients can jump straight to it in large playlists.
+type PlaylistTrackHit struct {
+	TrackID  int64
+	Position int
+	Title    string
+	Artist   sql.NullString
+}
+
+// PlaylistSearchResult is a playlist matched by name, description, or the
+// titles of the tracks it contains.
+type PlaylistSearchResult struct {
+	Playlist
+	TrackCount         int
+	NameMatched        bool
+	DescriptionMatched bool
+	MatchedTrackCount  int
+	MatchedTracks      []PlaylistTrackHit
+}
+
+// SearchPlaylists finds playlists the user owns or collaborates on whose name,
+// description, or contained track titles match query case-insensitively. Name
+// matches sort first.
+func (r *PlaylistRepository) SearchPlaylists(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]PlaylistSearchResult, int, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH visible AS (
+			SELECT p.*
+			FROM playlists p
+			WHERE p.user_id = $1
+			   OR EXISTS (SELECT 1 FROM playlist_collaborators pc WHERE pc.playlist_id = p.id AND pc.user_id = $1)
+		), track_hits AS (
+			SELECT pt.playlist_id, COUNT(*) AS hit_count
+			FROM playlist_tracks pt
+			JOIN tracks t ON t.id = pt.track_id
+			WHERE pt.playlist_id IN (SELECT id FROM visible)
+			  AND t.title ILIKE '%' || $2 || '%'
+			GROUP BY pt.playlist_id
+		)
+		SELECT v.id, v.user_id, v.name, v.description, v.cover_url, v.is_public, v.system_kind, v.artwork_key, v.mosaic_key, v.created_at, v.updated_at,
+			   v.track_count,
+			   v.name ILIKE '%' || $2 || '%' AS name_matched,
+			   COALESCE(v.description ILIKE '%' || $2 || '%', FALSE) AS description_matched,
+			   COALESCE(th.hit_count, 0) AS matched_track_count,
+			   COUNT(*) OVER() AS total
+		FROM visible v
+		LEFT JOIN track_hits th ON th.playlist_id = v.id
+		WHERE v.name ILIKE '%' || $2 || '%'
+		   OR v.description ILIKE '%' || $2 || '%'
+		   OR th.hit_count > 0
+		ORDER BY name_matched DESC, LOWER(v.name) ASC, v.id ASC
+		LIMIT $3 OFFSET $4
+	`, userID, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []PlaylistSearchResult
+	var total int
+	index := make(map[int64]int)
+	var withHits []int64
+	for rows.Next() {
+		var p PlaylistSearchResult
+		if err := rows.Scan(
+			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.SystemKind, &p.ArtworkKey, &p.MosaicKey, &p.CreatedAt, &p.UpdatedAt,
+			&p.TrackCount, &p.NameMatched, &p.DescriptionMatched, &p.MatchedTrackCount, &total,
+		); err != nil {
+			return nil, 0, err
+		}
+		index[p.ID] = len(results)
+		if p.MatchedTrackCount > 0 {
+			withHits = append(withHits, p.ID)
+		}
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(withHits) == 0 {
+		return results, total, nil
+	}
+
+	hitRows, err := r.db.QueryContext(ctx, `
+		SELECT playlist_id, track_id, position, title, artist
+		FROM (
+			SELECT pt.playlist_id, pt.track_id, pt.position, t.title, t.artist,
+				   ROW_NUMBER() OVER (PARTITION BY pt.playlist_id ORDER BY pt.position, pt.track_id) AS rn
+			FROM playlist_tracks pt
+			JOIN tracks t ON t.id = pt.track_id
+			WHERE pt.playlist_id = ANY($1)
+			  AND t.title ILIKE '%' || $2 || '%'
+		) hits
+		WHERE rn <= $3
+		ORDER BY playlist_id, position, track_id
+	`, pq.Array(withHits), query, maxPlaylistSearchTrackHits)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer hitRows.Close()
+
+	for hitRows.Next() {
+		var playlistID int64
+		var hit PlaylistTrackHit
+		if err := hitRows.Scan(&playlistID, &hit.TrackID, &hit.Position, &hit.Title, &hit.Artist); err != nil {
+			return nil, 0, err
+		}
+		if i, ok := index[playlistID]; ok {
+			results[i].MatchedTracks = append(results[i].MatchedTracks, hit)
+		}
+	}
+	if err := hitRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
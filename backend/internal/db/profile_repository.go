@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrProfileNotFound = errors.New("profile not found")
+var ErrProfileHandleTaken = errors.New("profile handle already taken")
+
+// UserProfile is the opt-in public face of a user. Nothing is exposed unless
+// IsPublic is set, and each section has its own visibility flag on top of that.
+type UserProfile struct {
+	UserID         uuid.UUID
+	Handle         string
+	Username       string // display name, falling back to the account username
+	Bio            sql.NullString
+	AvatarKey      sql.NullString
+	IsPublic       bool
+	ShowPlaylists  bool
+	ShowTopArtists bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ArtistPlayCount is one row of a user's top-artists listing.
+type ArtistPlayCount struct {
+	Artist    string
+	PlayCount int
+}
+
+// ProfileRepository stores public profile settings and serves the read-only
+// projections shown on a public profile page.
+type ProfileRepository struct {
+	db *DB
+}
+
+func NewProfileRepository(db *DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// GetByUserID returns the caller's own profile settings regardless of visibility.
+func (r *ProfileRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*UserProfile, error) {
+	query := `
+		SELECT p.user_id, p.handle, COALESCE(u.display_name, u.username), u.bio, u.avatar_key,
+			   p.is_public, p.show_playlists, p.show_top_artists, p.created_at, p.updated_at
+		FROM user_profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = $1
+	`
+	return r.scanProfile(r.db.QueryRowContext(ctx, query, userID))
+}
+
+// GetPublicByHandle returns a profile only when its owner has made it public.
+// Private and missing profiles are indistinguishable to callers.
+func (r *ProfileRepository) GetPublicByHandle(ctx context.Context, handle string) (*UserProfile, error) {
+	query := `
+		SELECT p.user_id, p.handle, COALESCE(u.display_name, u.username), u.bio, u.avatar_key,
+			   p.is_public, p.show_playlists, p.show_top_artists, p.created_at, p.updated_at
+		FROM user_profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.handle = $1 AND p.is_public = TRUE
+	`
+	return r.scanProfile(r.db.QueryRowContext(ctx, query, handle))
+}
+
+func (r *ProfileRepository) scanProfile(row *sql.Row) (*UserProfile, error) {
+	var p UserProfile
+	err := row.Scan(
+		&p.UserID, &p.Handle, &p.Username, &p.Bio, &p.AvatarKey,
+		&p.IsPublic, &p.ShowPlaylists, &p.ShowTopArtists, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Upsert creates or replaces the caller's profile settings. A handle already
+// claimed by another user is reported as ErrProfileHandleTaken.
+func (r *ProfileRepository) Upsert(ctx context.Context, profile *UserProfile) error {
+	query := `
+		INSERT INTO user_profiles (user_id, handle, is_public, show_playlists, show_top_artists)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			handle = EXCLUDED.handle,
+			is_public = EXCLUDED.is_public,
+			show_playlists = EXCLUDED.show_playlists,
+			show_top_artists = EXCLUDED.show_top_artists,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		profile.UserID, profile.Handle, profile.IsPublic, profile.ShowPlaylists, profile.ShowTopArtists,
+	).Scan(&profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrProfileHandleTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// SetPlaylistVisibility toggles whether one of the caller's playlists is listed
+// on their public profile.
+func (r *ProfileRepository) SetPlaylistVisibility(ctx context.Context, userID uuid.UUID, playlistID int64, visible bool) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE playlists SET show_on_profile = $1 WHERE id = $2 AND user_id = $3`,
+		visible, playlistID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPlaylistNotFound
+	}
+	return nil
+}
+
+// PublicPlaylists lists the playlists a user has chosen to show on their
+// profile, most recently updated first, with their track counts.
+func (r *ProfileRepository) PublicPlaylists(ctx context.Context, userID uuid.UUID, limit int) ([]PlaylistWithTracks, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := `
+		SELECT p.id, p.user_id, p.name, p.description, p.cover_url, p.is_public, p.system_kind, p.artwork_key, p.mosaic_key, p.created_at, p.updated_at,
+			   p.track_count, p.total_duration_ms
+		FROM playlists p
+		WHERE p.user_id = $1 AND p.show_on_profile = TRUE
+		ORDER BY p.updated_at DESC, p.id ASC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var playlists []PlaylistWithTracks
+	for rows.Next() {
+		var p PlaylistWithTracks
+		if err := rows.Scan(
+			&p.ID, &p.UserID, &p.Name, &p.Description, &p.CoverURL, &p.IsPublic, &p.SystemKind, &p.ArtworkKey, &p.MosaicKey, &p.CreatedAt, &p.UpdatedAt,
+			&p.TrackCount, &p.DurationMs,
+		); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// TopArtists returns the artists the user has played most, derived from play
+// events. Tracks without an artist are ignored.
+func (r *ProfileRepository) TopArtists(ctx context.Context, userID uuid.UUID, limit int) ([]ArtistPlayCount, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	query := `
+		SELECT t.artist, COUNT(*) AS play_count
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND COALESCE(BTRIM(t.artist), '') <> ''
+		GROUP BY t.artist
+		ORDER BY play_count DESC, t.artist ASC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artists []ArtistPlayCount
+	for rows.Next() {
+		var a ArtistPlayCount
+		if err := rows.Scan(&a.Artist, &a.PlayCount); err != nil {
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return artists, nil
+}
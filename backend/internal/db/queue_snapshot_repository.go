@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrQueueSnapshotNotFound = errors.New("queue snapshot not found")
+
+// QueueSnapshotRepository keeps durable copies of users' playback queues,
+// which live in Redis with a TTL. Snapshots are opaque JSON documents owned
+// by the queue service.
+type QueueSnapshotRepository struct {
+	db *DB
+}
+
+func NewQueueSnapshotRepository(db *DB) *QueueSnapshotRepository {
+	return &QueueSnapshotRepository{db: db}
+}
+
+// SaveQueueSnapshot stores the state of one of the user's named queues at
+// version. A write racing ahead of it with a newer version wins, so an
+// older state never replaces a newer one.
+func (r *QueueSnapshotRepository) SaveQueueSnapshot(ctx context.Context, userID uuid.UUID, name string, version int64, state []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO queue_snapshots (user_id, name, version, state, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, name) DO UPDATE
+		SET version = EXCLUDED.version, state = EXCLUDED.state, updated_at = NOW()
+		WHERE queue_snapshots.version <= EXCLUDED.version
+	`, userID, name, version, state)
+	return err
+}
+
+// GetQueueSnapshot returns the stored state of one of the user's queues.
+func (r *QueueSnapshotRepository) GetQueueSnapshot(ctx context.Context, userID uuid.UUID, name string) ([]byte, error) {
+	var state []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT state FROM queue_snapshots WHERE user_id = $1 AND name = $2
+	`, userID, name).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQueueSnapshotNotFound
+	}
+	return state, err
+}
+
+// DeleteQueueSnapshot forgets one of the user's queues. Deleting a queue
+// that has no snapshot is not an error.
+func (r *QueueSnapshotRepository) DeleteQueueSnapshot(ctx context.Context, userID uuid.UUID, name string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM queue_snapshots WHERE user_id = $1 AND name = $2
+	`, userID, name)
+	return err
+}
+
+// SaveQueueIndexSnapshot stores the list of the user's named queues and
+// which is active.
+func (r *QueueSnapshotRepository) SaveQueueIndexSnapshot(ctx context.Context, userID uuid.UUID, index []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO queue_index_snapshots (user_id, data, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET data = EXCLUDED.data, updated_at = NOW()
+	`, userID, index)
+	return err
+}
+
+// GetQueueIndexSnapshot returns the stored list of the user's named queues.
+func (r *QueueSnapshotRepository) GetQueueIndexSnapshot(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	var index []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT data FROM queue_index_snapshots WHERE user_id = $1
+	`, userID).Scan(&index)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQueueSnapshotNotFound
+	}
+	return index, err
+}
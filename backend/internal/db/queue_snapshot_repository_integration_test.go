@@ -0,0 +1,44 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQueueSnapshotKeepsNewestVersion(t *testing.T) {
+	database, ctx := newPlayEventTestDB(t)
+	userID := seedPlayUser(t, database, "queue-snapshot@example.com")
+	repo := NewQueueSnapshotRepository(database)
+
+	if _, err := repo.GetQueueSnapshot(ctx, userID, "main"); !errors.Is(err, ErrQueueSnapshotNotFound) {
+		t.Fatalf("missing snapshot err = %v, want ErrQueueSnapshotNotFound", err)
+	}
+	if err := repo.SaveQueueSnapshot(ctx, userID, "main", 2, []byte(`{"version":2}`)); err != nil {
+		t.Fatalf("save v2: %v", err)
+	}
+	// A slower write of an older version must not replace the newer one.
+	if err := repo.SaveQueueSnapshot(ctx, userID, "main", 1, []byte(`{"version":1}`)); err != nil {
+		t.Fatalf("save v1: %v", err)
+	}
+	state, err := repo.GetQueueSnapshot(ctx, userID, "main")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(state) != `{"version": 2}` {
+		t.Fatalf("snapshot = %s, want version 2", state)
+	}
+
+	if err := repo.DeleteQueueSnapshot(ctx, userID, "main"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetQueueSnapshot(ctx, userID, "main"); !errors.Is(err, ErrQueueSnapshotNotFound) {
+		t.Fatalf("deleted snapshot err = %v, want ErrQueueSnapshotNotFound", err)
+	}
+
+	if err := repo.SaveQueueIndexSnapshot(ctx, userID, []byte(`{"active":"party"}`)); err != nil {
+		t.Fatalf("save index: %v", err)
+	}
+	if index, err := repo.GetQueueIndexSnapshot(ctx, userID); err != nil || string(index) != `{"active": "party"}` {
+		t.Fatalf("index = %s, %v", index, err)
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RecommendationSeed is a track the user has played a lot lately, which
+// recommendations are found around.
+type RecommendationSeed struct {
+	TrackID    int64
+	Title      string
+	Artist     string
+	MBArtistID *uuid.UUID
+	PlayCount  int
+}
+
+// TrackScore is a candidate track and how strongly it is associated with
+// whatever it was looked up from.
+type TrackScore struct {
+	TrackID int64
+	Score   int
+}
+
+// RecommendationRepository reads the listening and playlist history that
+// recommendations are drawn from. Co-occurrence reads span every user's
+// history but only ever return track IDs and counts.
+type RecommendationRepository struct {
+	db *DB
+}
+
+func NewRecommendationRepository(db *DB) *RecommendationRepository {
+	return &RecommendationRepository{db: db}
+}
+
+// RecommendationSeeds returns the user's most played tracks since the
+// cutoff, not counting skips, most played first.
+func (r *RecommendationRepository) RecommendationSeeds(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]RecommendationSeed, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.title, COALESCE(t.artist, ''), t.mb_artist_id, COUNT(*) AS play_count
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2 AND NOT pe.skipped AND t.quarantined_at IS NULL
+		GROUP BY t.id, t.title, t.artist, t.mb_artist_id
+		ORDER BY play_count DESC, MAX(pe.played_at) DESC
+		LIMIT $3
+	`, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seeds []RecommendationSeed
+	for rows.Next() {
+		var s RecommendationSeed
+		if err := rows.Scan(&s.TrackID, &s.Title, &s.Artist, &s.MBArtistID, &s.PlayCount); err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, s)
+	}
+	return seeds, rows.Err()
+}
+
+// PlaylistCoOccurrences returns the tracks sharing the most playlists with
+// trackID. Generated playlists such as Daily Mixes are left out since they
+// are themselves built from listening history.
+func (r *RecommendationRepository) PlaylistCoOccurrences(ctx context.Context, trackID int64, limit int) ([]TrackScore, error) {
+	return r.scores(ctx, `
+		SELECT other.track_id, COUNT(*) AS score
+		FROM playlist_tracks seed
+		JOIN playlists p ON p.id = seed.playlist_id AND p.system_kind IS NULL
+		JOIN playlist_tracks other ON other.playlist_id = seed.playlist_id AND other.track_id <> seed.track_id
+		WHERE seed.track_id = $1
+		GROUP BY other.track_id
+		ORDER BY score DESC, other.track_id
+		LIMIT $2
+	`, trackID, limit)
+}
+
+// SessionCoOccurrences returns the tracks most often played within window
+// of a play of trackID by the same listener, counting plays since the
+// cutoff. Skips count on neither side.
+func (r *RecommendationRepository) SessionCoOccurrences(ctx context.Context, trackID int64, since time.Time, window time.Duration, limit int) ([]TrackScore, error) {
+	return r.scores(ctx, `
+		SELECT other.track_id, COUNT(*) AS score
+		FROM play_events seed
+		JOIN play_events other ON other.user_id = seed.user_id
+			AND other.track_id <> seed.track_id
+			AND other.played_at BETWEEN seed.played_at - make_interval(secs => $3) AND seed.played_at + make_interval(secs => $3)
+			AND NOT other.skipped
+		WHERE seed.track_id = $1 AND seed.played_at >= $2 AND NOT seed.skipped
+		GROUP BY other.track_id
+		ORDER BY score DESC, other.track_id
+		LIMIT $4
+	`, trackID, since, int(window/time.Second), limit)
+}
+
+// TracksByArtists returns tracks credited to any of the MusicBrainz artists,
+// scored by how often anyone has played them.
+func (r *RecommendationRepository) TracksByArtists(ctx context.Context, artistIDs []uuid.UUID, limit int) ([]TrackScore, error) {
+	if len(artistIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(artistIDs))
+	for i, id := range artistIDs {
+		ids[i] = id.String()
+	}
+	return r.scores(ctx, `
+		SELECT t.id, COUNT(pe.id) AS score
+		FROM tracks t
+		LEFT JOIN play_events pe ON pe.track_id = t.id AND NOT pe.skipped
+		WHERE t.mb_artist_id = ANY($1::uuid[]) AND t.quarantined_at IS NULL
+		GROUP BY t.id
+		ORDER BY score DESC, t.id
+		LIMIT $2
+	`, pq.Array(ids), limit)
+}
+
+// LibraryTrackIDs reports which of the tracks are in the user's library.
+func (r *RecommendationRepository) LibraryTrackIDs(ctx context.Context, userID uuid.UUID, trackIDs []int64) (map[int64]bool, error) {
+	inLibrary := make(map[int64]bool, len(trackIDs))
+	if len(trackIDs) == 0 {
+		return inLibrary, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT track_id FROM user_library WHERE user_id = $1 AND track_id = ANY($2)
+	`, userID, pq.Array(trackIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		inLibrary[id] = true
+	}
+	return inLibrary, rows.Err()
+}
+
+func (r *RecommendationRepository) scores(ctx context.Context, query string, args ...interface{}) ([]TrackScore, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []TrackScore
+	for rows.Next() {
+		var s TrackScore
+		if err := rows.Scan(&s.TrackID, &s.Score); err != nil {
+			return nil, err
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ReplayGainReferenceLUFS is the ReplayGain 2.0 target loudness. A track's
+// gain is what brings its measured loudness to this level.
+const ReplayGainReferenceLUFS = -18.0
+
+// ReplayGain is a track's EBU R128 measurement and the gains derived from
+// it. Peaks are true peaks in dBTP. Album values are NULL until a track of
+// a known release or album has been measured.
+type ReplayGain struct {
+	LoudnessLUFS  sql.NullFloat64
+	TruePeakDBTP  sql.NullFloat64
+	TrackGainDB   sql.NullFloat64
+	AlbumGainDB   sql.NullFloat64
+	AlbumPeakDBTP sql.NullFloat64
+}
+
+// UpdateLoudness stores a track's integrated loudness and true peak, sets its
+// track gain, and recomputes the album gain and peak of every measured track
+// on the same release. The release is the MusicBrainz release when the track
+// has one and otherwise its album and artist names; a track with neither
+// gets no album gain. Album loudness is the duration-weighted energy mean of
+// its tracks, which is what measuring the album as one programme gives
+// without the gating.
+func (r *TrackRepository) UpdateLoudness(ctx context.Context, trackID int64, loudnessLUFS, truePeakDBTP float64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE tracks
+		SET loudness_lufs = $2,
+			true_peak_dbtp = $3,
+			track_gain_db = $4::double precision - $2::double precision,
+			updated_at = NOW()
+		WHERE id = $1
+	`, trackID, loudnessLUFS, truePeakDBTP, ReplayGainReferenceLUFS)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTrackNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		WITH target AS (
+			SELECT mb_release_id, lower(btrim(COALESCE(album, ''))) AS album_key,
+				   lower(btrim(COALESCE(artist, ''))) AS artist_key
+			FROM tracks
+			WHERE id = $1
+		),
+		album AS (
+			SELECT t.id, t.loudness_lufs, t.true_peak_dbtp,
+				   GREATEST(COALESCE(t.duration_ms, 0), 1)::double precision AS weight
+			FROM tracks t, target
+			WHERE t.loudness_lufs IS NOT NULL
+			  AND (
+				  (target.mb_release_id IS NOT NULL AND t.mb_release_id = target.mb_release_id)
+				  OR (target.mb_release_id IS NULL AND target.album_key <> '' AND t.mb_release_id IS NULL
+					  AND lower(btrim(COALESCE(t.album, ''))) = target.album_key
+					  AND lower(btrim(COALESCE(t.artist, ''))) = target.artist_key)
+			  )
+		),
+		summary AS (
+			SELECT 10 * log(SUM(weight * power(10, loudness_lufs / 10)) / SUM(weight)) AS loudness,
+				   MAX(true_peak_dbtp) AS peak
+			FROM album
+		)
+		UPDATE tracks t
+		SET album_gain_db = $2::double precision - summary.loudness,
+			album_peak_dbtp = summary.peak
+		FROM album, summary
+		WHERE t.id = album.id
+	`, trackID, ReplayGainReferenceLUFS); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionOverride is an admin's replacement for a data type's configured
+// retention period.
+type RetentionOverride struct {
+	DataType   string
+	MaxAgeDays int
+	UpdatedBy  uuid.NullUUID
+	UpdatedAt  time.Time
+}
+
+// RetentionRepository stores retention overrides and deletes aged rows for
+// the retention purge. Each Delete*Before call removes at most limit rows,
+// oldest first, so a large backlog is purged in short transactions.
+type RetentionRepository struct {
+	db *DB
+}
+
+func NewRetentionRepository(db *DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// ListRetentionOverrides returns every saved override.
+func (r *RetentionRepository) ListRetentionOverrides(ctx context.Context) ([]RetentionOverride, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT data_type, max_age_days, updated_by, updated_at
+		FROM retention_policies
+		ORDER BY data_type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []RetentionOverride
+	for rows.Next() {
+		var o RetentionOverride
+		if err := rows.Scan(&o.DataType, &o.MaxAgeDays, &o.UpdatedBy, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetRetentionOverride saves the data type's retention period.
+func (r *RetentionRepository) SetRetentionOverride(ctx context.Context, dataType string, maxAgeDays int, updatedBy uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO retention_policies (data_type, max_age_days, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (data_type) DO UPDATE
+		SET max_age_days = EXCLUDED.max_age_days,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+	`, dataType, maxAgeDays, updatedBy)
+	return err
+}
+
+// DeleteRetentionOverride returns the data type to its configured period.
+func (r *RetentionRepository) DeleteRetentionOverride(ctx context.Context, dataType string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM retention_policies WHERE data_type = $1`, dataType)
+	return err
+}
+
+// DeletePlayEventsBefore removes play history recorded before the cutoff.
+func (r *RetentionRepository) DeletePlayEventsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.deleteBatch(ctx, `
+		DELETE FROM play_events
+		WHERE id IN (
+			SELECT id FROM play_events
+			WHERE played_at < $1
+			ORDER BY played_at
+			LIMIT $2
+		)
+	`, before, limit)
+}
+
+// DeletePlaylistActivityBefore removes playlist audit entries written before
+// the cutoff.
+func (r *RetentionRepository) DeletePlaylistActivityBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.deleteBatch(ctx, `
+		DELETE FROM playlist_activity
+		WHERE id IN (
+			SELECT id FROM playlist_activity
+			WHERE created_at < $1
+			ORDER BY created_at
+			LIMIT $2
+		)
+	`, before, limit)
+}
+
+// DeleteNotificationsBefore removes notifications raised before the cutoff,
+// read or not.
+func (r *RetentionRepository) DeleteNotificationsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.deleteBatch(ctx, `
+		DELETE FROM notifications
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE created_at < $1
+			ORDER BY created_at
+			LIMIT $2
+		)
+	`, before, limit)
+}
+
+// DeleteFailedDownloadJobsBefore removes download jobs that failed for good
+// and were last touched before the cutoff. A retried job is no longer
+// failed, so only abandoned ones go.
+func (r *RetentionRepository) DeleteFailedDownloadJobsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.deleteBatch(ctx, `
+		DELETE FROM download_jobs
+		WHERE id IN (
+			SELECT id FROM download_jobs
+			WHERE status = 'failed' AND updated_at < $1
+			ORDER BY updated_at
+			LIMIT $2
+		)
+	`, before, limit)
+}
+
+func (r *RetentionRepository) deleteBatch(ctx context.Context, query string, before time.Time, limit int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrScrobbleConnectionNotFound = errors.New("scrobble connection not found")
+
+// ScrobbleConnection links a user to an account on an external scrobbling
+// service. Token authenticates submissions and is never returned by the API.
+type ScrobbleConnection struct {
+	UserID    uuid.UUID
+	Service   string
+	Token     string
+	Username  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ListScrobbleConnections returns the user's connected services by name.
+func (r *UserRepository) ListScrobbleConnections(ctx context.Context, userID uuid.UUID) ([]ScrobbleConnection, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, service, token, username, created_at, updated_at
+		FROM scrobble_connections
+		WHERE user_id = $1
+		ORDER BY service
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var connections []ScrobbleConnection
+	for rows.Next() {
+		var c ScrobbleConnection
+		if err := rows.Scan(&c.UserID, &c.Service, &c.Token, &c.Username, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		connections = append(connections, c)
+	}
+	return connections, rows.Err()
+}
+
+// GetScrobbleConnection returns the user's connection to one service.
+func (r *UserRepository) GetScrobbleConnection(ctx context.Context, userID uuid.UUID, service string) (*ScrobbleConnection, error) {
+	var c ScrobbleConnection
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, service, token, username, created_at, updated_at
+		FROM scrobble_connections
+		WHERE user_id = $1 AND service = $2
+	`, userID, service).Scan(&c.UserID, &c.Service, &c.Token, &c.Username, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScrobbleConnectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SaveScrobbleConnection connects the service, replacing the token of an
+// existing connection.
+func (r *UserRepository) SaveScrobbleConnection(ctx context.Context, c *ScrobbleConnection) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO scrobble_connections (user_id, service, token, username)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, service) DO UPDATE
+		SET token = EXCLUDED.token,
+			username = EXCLUDED.username,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, c.UserID, c.Service, c.Token, c.Username).Scan(&c.CreatedAt, &c.UpdatedAt)
+}
+
+// DeleteScrobbleConnection disconnects the service.
+func (r *UserRepository) DeleteScrobbleConnection(ctx context.Context, userID uuid.UUID, service string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM scrobble_connections WHERE user_id = $1 AND service = $2
+	`, userID, service)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrScrobbleConnectionNotFound
+	}
+	return nil
+}
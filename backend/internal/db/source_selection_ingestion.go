@@ -36,10 +36,23 @@ func NewSourceSelectionIngestion(database *DB, decisions *SourceSelectionReposit
 }
 
 func (s *SourceSelectionIngestion) CreateTrustedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, reason string) (*SourceSelectionDownload, error) {
+	return s.createTrustedDownload(ctx, userID, origin, candidate, trustedCandidate(candidate, origin), reason)
+}
+
+// CreateSearchedDownload is CreateTrustedDownload for a candidate the server
+// picked from search results. The decision keeps the source-quality judgment
+// behind the pick rather than the trusted-ingestion default.
+func (s *SourceSelectionIngestion) CreateSearchedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, quality TrustedSourceSelectionQuality, reason string) (*SourceSelectionDownload, error) {
+	trusted := trustedCandidate(candidate, origin)
+	trusted.SourceQuality = &quality
+	return s.createTrustedDownload(ctx, userID, origin, candidate, trusted, reason)
+}
+
+func (s *SourceSelectionIngestion) createTrustedDownload(ctx context.Context, userID uuid.UUID, origin string, candidate download.SourceCandidate, trusted TrustedSourceSelectionCandidate, reason string) (*SourceSelectionDownload, error) {
 	if s == nil || s.db == nil || s.decisions == nil {
 		return nil, fmt.Errorf("source selection ingestion is unavailable")
 	}
-	decision, err := s.decisions.CreateTrustedSourceSelectionDecision(ctx, userID, origin, trustedCandidate(candidate, origin), reason)
+	decision, err := s.decisions.CreateTrustedSourceSelectionDecision(ctx, userID, origin, trusted, reason)
 	if err != nil {
 		return nil, err
 	}
@@ -101,10 +114,17 @@ func (s *SourceSelectionIngestion) EnqueueTrustedPlaylistDownload(ctx context.Co
 }
 
 func (s *SourceSelectionIngestion) EnqueueTrustedDownload(ctx context.Context, persisted *SourceSelectionDownload, enqueuer SourceSelectionDownloadEnqueuer) (*download.DownloadJob, error) {
+	return s.EnqueueTrustedRecordingDownload(ctx, persisted, enqueuer, nil)
+}
+
+// EnqueueTrustedRecordingDownload is EnqueueTrustedDownload for a source
+// already known to be a MusicBrainz recording, which the processor then uses
+// instead of matching the download itself.
+func (s *SourceSelectionIngestion) EnqueueTrustedRecordingDownload(ctx context.Context, persisted *SourceSelectionDownload, enqueuer SourceSelectionDownloadEnqueuer, mbRecordingID *string) (*download.DownloadJob, error) {
 	if persisted == nil || persisted.Job == nil || persisted.Decision == nil || enqueuer == nil {
 		return nil, fmt.Errorf("persisted source-selection download is required")
 	}
-	job, err := enqueuer.EnqueueSourceCandidateWithID(ctx, persisted.Job.ID, persisted.Job.UserID, persisted.Candidate, nil)
+	job, err := enqueuer.EnqueueSourceCandidateWithID(ctx, persisted.Job.ID, persisted.Job.UserID, persisted.Candidate, mbRecordingID)
 	if err != nil {
 		if persistErr := s.markFailed(ctx, persisted.Decision.UserID, persisted.Job.ID, err); persistErr != nil {
 			return nil, fmt.Errorf("enqueue trusted download: %w; persist failure: %v", err, persistErr)
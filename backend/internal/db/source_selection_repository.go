@@ -21,6 +21,7 @@ const (
 	SourceSelectionOriginDirectURL        = "direct_url"
 	SourceSelectionOriginPlaylistExplicit = "playlist_explicit"
 	SourceSelectionOriginResearch         = "research"
+	SourceSelectionOriginRelease          = "release"
 
 	maxSourceSelectionCandidates   = 50
 	maxSourceSelectionSnapshotSize = 48 * 1024
@@ -582,7 +583,7 @@ func validAction(action string) bool {
 }
 
 func validTrustedOrigin(origin string) bool {
-	return origin == SourceSelectionOriginDirectURL || origin == SourceSelectionOriginPlaylistExplicit
+	return origin == SourceSelectionOriginDirectURL || origin == SourceSelectionOriginPlaylistExplicit || origin == SourceSelectionOriginRelease
 }
 
 func validCandidateID(candidateID string) bool {
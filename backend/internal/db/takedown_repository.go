@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Takedown kinds.
+const (
+	TakedownKindSourcePattern = "source_pattern"
+	TakedownKindIdentityHash  = "identity_hash"
+)
+
+// NotificationKindTakedown notifies listeners that a track in their library
+// was quarantined.
+const NotificationKindTakedown = "takedown"
+
+var ErrTakedownNotFound = errors.New("takedown not found")
+
+// ErrContentTakenDown is returned when a download matches an active takedown.
+var ErrContentTakenDown = errors.New("content taken down")
+
+// ContentTakedown blocks a source URL glob (where * matches anything) or one
+// track identity hash.
+type ContentTakedown struct {
+	ID                int64
+	Kind              string
+	Pattern           string
+	Reason            string
+	CreatedBy         uuid.NullUUID
+	CreatedAt         time.Time
+	LiftedAt          sql.NullTime
+	QuarantinedTracks int
+}
+
+type TakedownRepository struct {
+	db *DB
+}
+
+func NewTakedownRepository(db *DB) *TakedownRepository {
+	return &TakedownRepository{db: db}
+}
+
+// TakedownLikePattern converts a source URL glob into a LIKE pattern, escaping
+// LIKE's own wildcards so only * is special.
+func TakedownLikePattern(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '\\', '%', '_':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '*':
+			b.WriteRune('%')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Create records the takedown, quarantines every track it matches, and
+// notifies each listener holding one of them. It returns how many tracks were
+// quarantined and how many notifications were sent.
+func (r *TakedownRepository) Create(ctx context.Context, t *ContentTakedown) (int, int, error) {
+	likePattern := t.Pattern
+	if t.Kind == TakedownKindSourcePattern {
+		likePattern = TakedownLikePattern(t.Pattern)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO content_takedowns (kind, pattern, like_pattern, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, t.Kind, t.Pattern, likePattern, t.Reason, t.CreatedBy).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE tracks t
+		SET quarantined_at = NOW(), quarantine_takedown_id = $1
+		WHERE t.quarantined_at IS NULL
+		  AND (
+			($2 = '`+TakedownKindIdentityHash+`' AND t.identity_hash = $3)
+			OR ($2 = '`+TakedownKindSourcePattern+`' AND (
+				t.source_url LIKE $3
+				OR EXISTS (SELECT 1 FROM track_sources ts WHERE ts.track_id = t.id AND ts.source_url LIKE $3)
+			))
+		  )
+		RETURNING t.id
+	`, t.ID, t.Kind, likePattern)
+	if err != nil {
+		return 0, 0, err
+	}
+	var trackIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		trackIDs = append(trackIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	t.QuarantinedTracks = len(trackIDs)
+
+	notified := 0
+	if len(trackIDs) > 0 {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO notifications (user_id, kind, payload)
+			SELECT ul.user_id, $1, jsonb_build_object(
+				'takedownId', $2::bigint,
+				'trackId', t.id,
+				'title', LEFT(t.title, 500),
+				'reason', LEFT($3, 1000)
+			)
+			FROM user_library ul
+			JOIN tracks t ON t.id = ul.track_id
+			WHERE ul.track_id = ANY($4)
+		`, NotificationKindTakedown, t.ID, t.Reason, pq.Array(trackIDs))
+		if err != nil {
+			return 0, 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, 0, err
+		}
+		notified = int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return len(trackIDs), notified, nil
+}
+
+// List returns takedowns newest first with how many tracks each currently
+// quarantines. Lifted takedowns are included only when requested.
+func (r *TakedownRepository) List(ctx context.Context, includeLifted bool) ([]ContentTakedown, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ct.id, ct.kind, ct.pattern, ct.reason, ct.created_by, ct.created_at, ct.lifted_at,
+			   (SELECT COUNT(*) FROM tracks t WHERE t.quarantine_takedown_id = ct.id)
+		FROM content_takedowns ct
+		WHERE $1 OR ct.lifted_at IS NULL
+		ORDER BY ct.created_at DESC, ct.id DESC
+	`, includeLifted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	takedowns := []ContentTakedown{}
+	for rows.Next() {
+		var t ContentTakedown
+		if err := rows.Scan(&t.ID, &t.Kind, &t.Pattern, &t.Reason, &t.CreatedBy, &t.CreatedAt, &t.LiftedAt, &t.QuarantinedTracks); err != nil {
+			return nil, err
+		}
+		takedowns = append(takedowns, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return takedowns, nil
+}
+
+// Lift deactivates a takedown and releases the tracks it quarantined. It
+// returns the number of released tracks.
+func (r *TakedownRepository) Lift(ctx context.Context, id int64) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE content_takedowns SET lifted_at = NOW() WHERE id = $1 AND lifted_at IS NULL`, id)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrTakedownNotFound
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE tracks SET quarantined_at = NULL, quarantine_takedown_id = NULL
+		WHERE quarantine_takedown_id = $1
+	`, id)
+	if err != nil {
+		return 0, err
+	}
+	released, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(released), nil
+}
+
+// FindActive returns the oldest active takedown matching the source URL or the
+// identity hash, or nil when neither is blocked. Either argument may be empty.
+func (r *TakedownRepository) FindActive(ctx context.Context, sourceURL, identityHash string) (*ContentTakedown, error) {
+	var t ContentTakedown
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, kind, pattern, reason, created_by, created_at, lifted_at
+		FROM content_takedowns
+		WHERE lifted_at IS NULL
+		  AND (
+			(kind = '`+TakedownKindSourcePattern+`' AND $1 <> '' AND $1 LIKE like_pattern)
+			OR (kind = '`+TakedownKindIdentityHash+`' AND $2 <> '' AND pattern = $2)
+		  )
+		ORDER BY created_at ASC, id ASC
+		LIMIT 1
+	`, sourceURL, identityHash).Scan(&t.ID, &t.Kind, &t.Pattern, &t.Reason, &t.CreatedBy, &t.CreatedAt, &t.LiftedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// QuarantineTrack quarantines one track under an existing takedown, e.g. when
+// a download slipped past the URL check but resolved to a blocked identity.
+func (r *TakedownRepository) QuarantineTrack(ctx context.Context, trackID, takedownID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tracks SET quarantined_at = NOW(), quarantine_takedown_id = $2
+		WHERE id = $1 AND quarantined_at IS NULL
+	`, trackID, takedownID)
+	return err
+}
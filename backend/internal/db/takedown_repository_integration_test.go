@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTakedownQuarantinesNotifiesAndLiftsAgainstPostgres(t *testing.T) {
+	database, ctx := newPlayEventTestDB(t)
+	trackRepo := NewTrackRepository(database)
+	libraryRepo := NewLibraryRepository(database)
+	takedowns := NewTakedownRepository(database)
+
+	admin := seedPlayUser(t, database, "admin@example.test")
+	listener := seedPlayUser(t, database, "listener@example.test")
+	blocked := seedPlayTrack(t, trackRepo, ctx, "Artist", "Blocked")
+	kept := seedPlayTrack(t, trackRepo, ctx, "Artist", "Kept")
+	if _, err := database.Exec(`UPDATE tracks SET source_url = 'https://soundcloud.com/label/blocked' WHERE id = $1`, blocked); err != nil {
+		t.Fatalf("set source url: %v", err)
+	}
+	if _, err := database.Exec(`UPDATE tracks SET source_url = 'https://soundcloud.com/other/kept' WHERE id = $1`, kept); err != nil {
+		t.Fatalf("set source url: %v", err)
+	}
+	for _, id := range []int64{blocked, kept} {
+		if _, err := libraryRepo.AddTrackToLibrary(ctx, listener, id); err != nil {
+			t.Fatalf("add track %d to library: %v", id, err)
+		}
+	}
+
+	takedown := &ContentTakedown{
+		Kind:      TakedownKindSourcePattern,
+		Pattern:   "https://soundcloud.com/label/*",
+		Reason:    "rights holder request",
+		CreatedBy: uuid.NullUUID{UUID: admin, Valid: true},
+	}
+	quarantined, notified, err := takedowns.Create(ctx, takedown)
+	if err != nil {
+		t.Fatalf("create takedown: %v", err)
+	}
+	if quarantined != 1 || notified != 1 {
+		t.Fatalf("quarantined/notified = %d/%d, want 1/1", quarantined, notified)
+	}
+
+	track, err := trackRepo.GetByID(ctx, blocked)
+	if err != nil {
+		t.Fatalf("get blocked track: %v", err)
+	}
+	if !track.QuarantinedAt.Valid {
+		t.Fatal("matching track was not quarantined")
+	}
+	if track, err := trackRepo.GetByID(ctx, kept); err != nil || track.QuarantinedAt.Valid {
+		t.Fatalf("non-matching track quarantined = %v (err %v)", track != nil && track.QuarantinedAt.Valid, err)
+	}
+
+	var kind string
+	if err := database.QueryRow(`SELECT kind FROM notifications WHERE user_id = $1`, listener).Scan(&kind); err != nil || kind != NotificationKindTakedown {
+		t.Fatalf("notification kind = %q, err = %v", kind, err)
+	}
+
+	found, err := takedowns.FindActive(ctx, "https://soundcloud.com/label/another", "")
+	if err != nil || found == nil || found.ID != takedown.ID {
+		t.Fatalf("FindActive = %+v, %v; want takedown %d", found, err, takedown.ID)
+	}
+	if found, err := takedowns.FindActive(ctx, "https://soundcloud.com/other/kept", ""); err != nil || found != nil {
+		t.Fatalf("FindActive for unblocked URL = %+v, %v", found, err)
+	}
+
+	released, err := takedowns.Lift(ctx, takedown.ID)
+	if err != nil || released != 1 {
+		t.Fatalf("lift = %d, %v; want 1 released", released, err)
+	}
+	if track, err := trackRepo.GetByID(ctx, blocked); err != nil || track.QuarantinedAt.Valid {
+		t.Fatalf("lifted track still quarantined (err %v)", err)
+	}
+	if _, err := takedowns.Lift(ctx, takedown.ID); !errors.Is(err, ErrTakedownNotFound) {
+		t.Fatalf("second lift err = %v, want ErrTakedownNotFound", err)
+	}
+	if found, err := takedowns.FindActive(ctx, "https://soundcloud.com/label/another", ""); err != nil || found != nil {
+		t.Fatalf("FindActive after lift = %+v, %v", found, err)
+	}
+}
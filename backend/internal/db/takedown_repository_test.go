@@ -0,0 +1,17 @@
+package db
+
+import "testing"
+
+func TestTakedownLikePattern(t *testing.T) {
+	cases := map[string]string{
+		"https://soundcloud.com/label/*":        "https://soundcloud.com/label/%",
+		"*youtube.com/watch?v=abc*":             "%youtube.com/watch?v=abc%",
+		"https://example.test/100%_real\\mix":   "https://example.test/100\\%\\_real\\\\mix",
+		"https://www.youtube.com/watch?v=exact": "https://www.youtube.com/watch?v=exact",
+	}
+	for glob, want := range cases {
+		if got := TakedownLikePattern(glob); got != want {
+			t.Fatalf("TakedownLikePattern(%q) = %q, want %q", glob, got, want)
+		}
+	}
+}
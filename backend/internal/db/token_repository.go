@@ -12,16 +12,31 @@ import (
 var ErrTokenNotFound = errors.New("token not found")
 var ErrTokenRevoked = errors.New("token has been revoked")
 var ErrTokenExpired = errors.New("token has expired")
+var ErrSessionNotFound = errors.New("session not found")
 
 type RefreshToken struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
+	SessionID uuid.NullUUID
 	TokenHash string
 	ExpiresAt time.Time
 	CreatedAt time.Time
 	Revoked   bool
 }
 
+// Session is one signed-in device. Its refresh token changes on every
+// refresh; the session lives on until it expires or is revoked.
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
 type TokenRepository struct {
 	db *DB
 }
@@ -32,26 +47,26 @@ func NewTokenRepository(db *DB) *TokenRepository {
 
 func (r *TokenRepository) Create(ctx context.Context, token *RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at, revoked)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO refresh_tokens (id, user_id, session_id, token_hash, expires_at, created_at, revoked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
-		token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt, token.Revoked,
+		token.ID, token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt, token.CreatedAt, token.Revoked,
 	)
 	return err
 }
 
 func (r *TokenRepository) GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, expires_at, created_at, revoked
+		SELECT id, user_id, session_id, token_hash, expires_at, created_at, revoked
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
 
 	token := &RefreshToken{}
 	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
-		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &token.Revoked,
+		&token.ID, &token.UserID, &token.SessionID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &token.Revoked,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -87,23 +102,181 @@ func (r *TokenRepository) Revoke(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// Consume revokes a refresh token that is being rotated. It returns
+// ErrTokenRevoked when the token was already used, so two requests racing
+// with the same token cannot both succeed.
+func (r *TokenRepository) Consume(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE WHERE id = $1 AND revoked = FALSE
+	`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
 func (r *TokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
-	query := `
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET revoked = TRUE
 		WHERE user_id = $1 AND revoked = FALSE
-	`
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	_, err := r.db.ExecContext(ctx, query, userID)
+// CreateSession records a new signed-in device.
+func (r *TokenRepository) CreateSession(ctx context.Context, session *Session) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO auth_sessions (id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+	`, session.ID, session.UserID, session.DeviceName, session.UserAgent, session.IPAddress, session.CreatedAt, session.ExpiresAt)
 	return err
 }
 
+// TouchSession records a refresh on a live session, extending it to
+// expiresAt. It returns ErrSessionNotFound once the session is revoked.
+func (r *TokenRepository) TouchSession(ctx context.Context, sessionID uuid.UUID, userAgent, ipAddress string, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE auth_sessions
+		SET last_used_at = NOW(),
+			user_agent = CASE WHEN $2 = '' THEN user_agent ELSE $2 END,
+			ip_address = CASE WHEN $3 = '' THEN ip_address ELSE $3 END,
+			expires_at = $4
+		WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID, userAgent, ipAddress, expiresAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// ListSessions returns the user's live sessions, most recently used first.
+func (r *TokenRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.DeviceName, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession signs one of the user's sessions out, revoking its refresh
+// tokens.
+func (r *TokenRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE WHERE session_id = $1 AND revoked = FALSE
+	`, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeOtherSessions signs the user out everywhere except keep and returns
+// how many sessions it revoked.
+func (r *TokenRepository) RevokeOtherSessions(ctx context.Context, userID, keep uuid.UUID) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, userID, keep)
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE
+		WHERE user_id = $1 AND revoked = FALSE AND session_id IS DISTINCT FROM $2
+	`, userID, keep); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(revoked), nil
+}
+
+// DeleteExpired clears dead tokens and sessions. Rotated-out tokens of a live
+// session are kept so that presenting one again is still caught as reuse.
 func (r *TokenRepository) DeleteExpired(ctx context.Context) error {
 	query := `
 		DELETE FROM refresh_tokens
-		WHERE expires_at < NOW() OR revoked = TRUE
+		WHERE expires_at < NOW() OR (revoked = TRUE AND session_id IS NULL)
 	`
 
-	_, err := r.db.ExecContext(ctx, query)
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM auth_sessions WHERE expires_at < NOW() OR revoked_at IS NOT NULL
+	`)
 	return err
 }
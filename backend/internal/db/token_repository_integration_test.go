@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTokenRepositorySessionsRotateAndRevoke(t *testing.T) {
+	database, ctx := newPlaylistTestDB(t)
+	repo := NewTokenRepository(database)
+	userID := seedPlaylistUser(t, database, "sessions@example.test")
+
+	now := time.Now()
+	newSession := func(name string) uuid.UUID {
+		t.Helper()
+		session := &Session{ID: uuid.New(), UserID: userID, DeviceName: name, CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := repo.CreateSession(ctx, session); err != nil {
+			t.Fatalf("create session %s: %v", name, err)
+		}
+		return session.ID
+	}
+	phone, laptop := newSession("phone"), newSession("laptop")
+
+	token := &RefreshToken{ID: uuid.New(), UserID: userID, SessionID: uuid.NullUUID{UUID: phone, Valid: true}, TokenHash: uuid.NewString(), ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	if err := repo.Create(ctx, token); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if err := repo.Consume(ctx, token.ID); err != nil {
+		t.Fatalf("first consume: %v", err)
+	}
+	if err := repo.Consume(ctx, token.ID); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("second consume err = %v, want ErrTokenRevoked", err)
+	}
+	stored, err := repo.GetByHash(ctx, token.TokenHash)
+	if err != nil || !stored.Revoked || stored.SessionID.UUID != phone {
+		t.Fatalf("stored token = %+v, %v", stored, err)
+	}
+
+	if err := repo.TouchSession(ctx, phone, "agent", "192.0.2.1", now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("touch session: %v", err)
+	}
+	sessions, err := repo.ListSessions(ctx, userID)
+	if err != nil || len(sessions) != 2 || sessions[0].ID != phone || sessions[0].UserAgent != "agent" {
+		t.Fatalf("sessions = %+v, %v; want the touched phone session first", sessions, err)
+	}
+
+	revoked, err := repo.RevokeOtherSessions(ctx, userID, phone)
+	if err != nil || revoked != 1 {
+		t.Fatalf("revoke others = %d, %v", revoked, err)
+	}
+	if err := repo.TouchSession(ctx, laptop, "", "", now.Add(time.Hour)); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("touch revoked session err = %v, want ErrSessionNotFound", err)
+	}
+	if err := repo.RevokeSession(ctx, userID, phone); err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	if err := repo.RevokeSession(ctx, userID, phone); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("revoke twice err = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestTrackAnalysisProjectsIntoSongListingsAgainstPostgres(t *testing.T) {
@@ -107,7 +108,7 @@ func TestTrackAnalysisProjectsIntoSongListingsAgainstPostgres(t *testing.T) {
 	if err != nil {
 		t.Fatalf("play history: %v", err)
 	}
-	top, err := playEventRepo.TopTracks(ctx, userID, 30, 10)
+	top, err := playEventRepo.TopTracks(ctx, userID, time.Now().AddDate(0, 0, -30), 10)
 	if err != nil {
 		t.Fatalf("top tracks: %v", err)
 	}
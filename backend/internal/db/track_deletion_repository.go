@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrTrackStillReferenced = errors.New("track is in another user's library")
+
+// TrackDeletion reports what DeleteTrackReferences removed. StorageKeys lists
+// objects no remaining track or source row references, including the track's
+// cover and waveform; the caller deletes them from storage after the
+// transaction commits. AudioKeys are the StorageKeys holding audio, whose
+// transcoded variants the caller releases with them.
+type TrackDeletion struct {
+	TrackID              int64
+	RemovedFromLibrary   bool
+	RemovedFromPlaylists int
+	OtherLibraryRefs     int
+	TrackDeleted         bool
+	StorageKeys          []string
+	AudioKeys            []string
+}
+
+// DeleteTrackReferences removes the user's library entry and the track from
+// the user's own playlists. When no other library still holds the track, the
+// track row itself is deleted (dependent rows cascade) and its no longer
+// referenced storage keys are returned.
+//
+// Unless allowShared is set, the user must have the track in their library and
+// be its only referencer; otherwise ErrTrackNotInLibrary or
+// ErrTrackStillReferenced is returned and nothing changes.
+func (r *TrackRepository) DeleteTrackReferences(ctx context.Context, trackID int64, userID uuid.UUID, allowShared bool) (*TrackDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var storageKey, artworkKey, waveformKey sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT storage_key, artwork_key, waveform_key FROM tracks WHERE id = $1 FOR UPDATE`, trackID).Scan(&storageKey, &artworkKey, &waveformKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTrackNotFound
+		}
+		return nil, err
+	}
+
+	result := &TrackDeletion{TrackID: trackID}
+	var inLibrary bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(BOOL_OR(user_id = $2), FALSE),
+			   COUNT(*) FILTER (WHERE user_id <> $2)
+		FROM user_library
+		WHERE track_id = $1
+	`, trackID, userID).Scan(&inLibrary, &result.OtherLibraryRefs)
+	if err != nil {
+		return nil, err
+	}
+	if !allowShared {
+		if !inLibrary {
+			return nil, ErrTrackNotInLibrary
+		}
+		if result.OtherLibraryRefs > 0 {
+			return nil, ErrTrackStillReferenced
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_library WHERE user_id = $1 AND track_id = $2`, userID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		result.RemovedFromLibrary = true
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM playlist_tracks pt
+		USING playlists p
+		WHERE pt.playlist_id = p.id
+		  AND p.user_id = $1
+		  AND pt.track_id = $2
+		RETURNING pt.playlist_id
+	`, userID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	var playlistIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		playlistIDs = append(playlistIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.RemovedFromPlaylists = len(playlistIDs)
+	if err := renumberPlaylists(ctx, tx, playlistIDs); err != nil {
+		return nil, err
+	}
+
+	if result.OtherLibraryRefs == 0 {
+		candidates := []string{}
+		audio := map[string]bool{}
+		if storageKey.Valid && storageKey.String != "" {
+			candidates = append(candidates, storageKey.String)
+			audio[storageKey.String] = true
+		}
+		for _, key := range []sql.NullString{artworkKey, waveformKey} {
+			if key.Valid && key.String != "" {
+				candidates = append(candidates, key.String)
+			}
+		}
+		sourceRows, err := tx.QueryContext(ctx, `
+			SELECT DISTINCT storage_key FROM track_sources
+			WHERE track_id = $1 AND storage_key IS NOT NULL AND storage_key <> ''
+		`, trackID)
+		if err != nil {
+			return nil, err
+		}
+		for sourceRows.Next() {
+			var key string
+			if err := sourceRows.Scan(&key); err != nil {
+				sourceRows.Close()
+				return nil, err
+			}
+			candidates = append(candidates, key)
+			audio[key] = true
+		}
+		sourceRows.Close()
+		if err := sourceRows.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tracks WHERE id = $1`, trackID); err != nil {
+			return nil, err
+		}
+		result.TrackDeleted = true
+
+		// Objects can be shared after a canonical-source switch or a merge, so
+		// only keys nothing else points at are released.
+		keyRows, err := tx.QueryContext(ctx, `
+			SELECT DISTINCT c.object_key
+			FROM UNNEST($1::text[]) AS c(object_key)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM tracks t
+				WHERE t.storage_key = c.object_key OR t.artwork_key = c.object_key OR t.waveform_key = c.object_key
+			)
+			  AND NOT EXISTS (SELECT 1 FROM track_sources ts WHERE ts.storage_key = c.object_key)
+		`, pq.Array(candidates))
+		if err != nil {
+			return nil, err
+		}
+		for keyRows.Next() {
+			var key string
+			if err := keyRows.Scan(&key); err != nil {
+				keyRows.Close()
+				return nil, err
+			}
+			result.StorageKeys = append(result.StorageKeys, key)
+			if audio[key] {
+				result.AudioKeys = append(result.AudioKeys, key)
+			}
+		}
+		keyRows.Close()
+		if err := keyRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// renumberPlaylists closes the position gaps left by removing tracks from the
+// given playlists, as RemoveTracks does, and marks them updated.
+func renumberPlaylists(ctx context.Context, tx *sql.Tx, playlistIDs []int64) error {
+	if len(playlistIDs) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		WITH ordered AS (
+			SELECT playlist_id, track_id,
+				   (ROW_NUMBER() OVER (PARTITION BY playlist_id ORDER BY position ASC) - 1) AS new_position
+			FROM playlist_tracks
+			WHERE playlist_id = ANY($1)
+		)
+		UPDATE playlist_tracks pt
+		SET position = ordered.new_position
+		FROM ordered
+		WHERE pt.playlist_id = ordered.playlist_id
+		  AND pt.track_id = ordered.track_id
+		  AND pt.position <> ordered.new_position
+	`, pq.Array(playlistIDs)); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE playlists SET updated_at = NOW() WHERE id = ANY($1)`, pq.Array(playlistIDs))
+	return err
+}
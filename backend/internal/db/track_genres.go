@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MaxTrackGenres bounds how many MusicBrainz genres are kept per track.
+const MaxTrackGenres = 10
+
+// TrackGenre is a MusicBrainz genre of a track's recording, with how many
+// MusicBrainz editors voted for it.
+type TrackGenre struct {
+	Genre string
+	Votes int
+}
+
+// GenreTag turns a MusicBrainz genre name into tag form so it filters like
+// a user tag: "Drum and Bass" becomes "drum-and-bass" and "R&B" "r-b". It
+// returns "" for a name with nothing usable in it.
+func GenreTag(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if isTagRune(r) && r != '-' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	tag, ok := NormalizeTag(b.String())
+	if !ok {
+		return ""
+	}
+	return tag
+}
+
+// ReplaceTrackGenres makes genres the track's MusicBrainz genres, dropping
+// the ones a previous match stored. Genres are stored in tag form (see
+// GenreTag) and only the MaxTrackGenres most voted are kept.
+func (r *TrackRepository) ReplaceTrackGenres(ctx context.Context, trackID int64, genres []TrackGenre) error {
+	names := make([]string, 0, len(genres))
+	votes := make([]int64, 0, len(genres))
+	seen := make(map[string]bool, len(genres))
+	for _, g := range genres {
+		tag := GenreTag(g.Genre)
+		if tag == "" || seen[tag] || len(names) == MaxTrackGenres {
+			continue
+		}
+		seen[tag] = true
+		names = append(names, tag)
+		votes = append(votes, int64(g.Votes))
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM track_genres WHERE track_id = $1`, trackID); err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO track_genres (track_id, genre, votes)
+			SELECT $1, g.genre, g.votes
+			FROM unnest($2::text[], $3::int[]) AS g(genre, votes)
+		`, trackID, pq.Array(names), pq.Array(votes)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TrackGenres returns the track's MusicBrainz genres, most voted first.
+func (r *TrackRepository) TrackGenres(ctx context.Context, trackID int64) ([]string, error) {
+	return trackGenres(ctx, r.db, trackID)
+}
+
+func trackGenres(ctx context.Context, q tagQuerier, trackID int64) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT genre FROM track_genres WHERE track_id = $1 ORDER BY votes DESC, genre
+	`, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []string{}
+	for rows.Next() {
+		var genre string
+		if err := rows.Scan(&genre); err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	return genres, rows.Err()
+}
+
+// trackTagCondition is an SQL condition matching when the track has the tag
+// bound to param, either as one of user's tags or as a MusicBrainz genre.
+func trackTagCondition(trackID, userID, param string) string {
+	return "(EXISTS (SELECT 1 FROM track_tags tt WHERE tt.user_id = " + userID + " AND tt.track_id = " + trackID + " AND tt.tag = " + param + ")" +
+		" OR EXISTS (SELECT 1 FROM track_genres tg WHERE tg.track_id = " + trackID + " AND tg.genre = " + param + "))"
+}
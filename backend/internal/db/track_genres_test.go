@@ -0,0 +1,22 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenreTag(t *testing.T) {
+	for name, want := range map[string]string{
+		"Drum and Bass":         "drum-and-bass",
+		"  hip hop ":            "hip-hop",
+		"R&B":                   "r-b",
+		"post-rock":             "post-rock",
+		"k-pop / j-pop":         "k-pop-j-pop",
+		"&&":                    "",
+		strings.Repeat("a", 65): "",
+	} {
+		if got := GenreTag(name); got != want {
+			t.Errorf("GenreTag(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
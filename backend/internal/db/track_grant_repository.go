@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Track grant context types: what a grant shares tracks for. A playlist
+// link grant lists no tracks; it covers whatever the public playlist holds
+// when a capability is used.
+const (
+	TrackGrantContextPlaylist     = "playlist"
+	TrackGrantContextPlaylistLink = "playlist_link"
+)
+
+var ErrTrackGrantNotFound = errors.New("track grant not found")
+
+// TrackGrant lets listeners stream TrackIDs on the owner's behalf until
+// ExpiresAt. A grant without a GranteeID is usable by anyone holding one of
+// its capability tokens.
+type TrackGrant struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	ContextType string
+	ContextID   string
+	GranteeID   uuid.NullUUID
+	TrackIDs    []int64
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	CreatedAt   time.Time
+}
+
+// Active reports whether the grant can still authorize playback at now.
+func (g *TrackGrant) Active(now time.Time) bool {
+	return !g.RevokedAt.Valid && now.Before(g.ExpiresAt)
+}
+
+type TrackGrantRepository struct {
+	db *DB
+}
+
+func NewTrackGrantRepository(db *DB) *TrackGrantRepository {
+	return &TrackGrantRepository{db: db}
+}
+
+// Create stores the grant and its track list.
+func (r *TrackGrantRepository) Create(ctx context.Context, grant *TrackGrant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO track_grants (id, owner_id, context_type, context_id, grantee_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`, grant.ID, grant.OwnerID, grant.ContextType, grant.ContextID, grant.GranteeID, grant.ExpiresAt).Scan(&grant.CreatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO track_grant_tracks (grant_id, track_id)
+		SELECT $1, unnest($2::BIGINT[])
+		ON CONFLICT DO NOTHING
+	`, grant.ID, pq.Array(grant.TrackIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListByOwner returns the grants the user created, newest first.
+func (r *TrackGrantRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]TrackGrant, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.owner_id, g.context_type, g.context_id, g.grantee_id,
+			COALESCE((SELECT array_agg(gt.track_id ORDER BY gt.track_id) FROM track_grant_tracks gt WHERE gt.grant_id = g.id), '{}'),
+			g.expires_at, g.revoked_at, g.created_at
+		FROM track_grants g
+		WHERE g.owner_id = $1
+		ORDER BY g.created_at DESC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []TrackGrant
+	for rows.Next() {
+		var g TrackGrant
+		var trackIDs pq.Int64Array
+		if err := rows.Scan(&g.ID, &g.OwnerID, &g.ContextType, &g.ContextID, &g.GranteeID,
+			&trackIDs, &g.ExpiresAt, &g.RevokedAt, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		g.TrackIDs = trackIDs
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// Get returns a grant with its track list.
+func (r *TrackGrantRepository) Get(ctx context.Context, id uuid.UUID) (*TrackGrant, error) {
+	var g TrackGrant
+	var trackIDs pq.Int64Array
+	err := r.db.QueryRowContext(ctx, `
+		SELECT g.id, g.owner_id, g.context_type, g.context_id, g.grantee_id,
+			COALESCE((SELECT array_agg(gt.track_id ORDER BY gt.track_id) FROM track_grant_tracks gt WHERE gt.grant_id = g.id), '{}'),
+			g.expires_at, g.revoked_at, g.created_at
+		FROM track_grants g
+		WHERE g.id = $1
+	`, id).Scan(&g.ID, &g.OwnerID, &g.ContextType, &g.ContextID, &g.GranteeID,
+		&trackIDs, &g.ExpiresAt, &g.RevokedAt, &g.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTrackGrantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.TrackIDs = trackIDs
+	return &g, nil
+}
+
+// Revoke ends the owner's grant. Revoking an already revoked grant succeeds.
+func (r *TrackGrantRepository) Revoke(ctx context.Context, id, ownerID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE track_grants
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND owner_id = $2
+	`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTrackGrantNotFound
+	}
+	return nil
+}
+
+// RevokeContext ends every active grant for a context, such as when the
+// playlist is deleted. With bearerOnly, grants naming a grantee are kept.
+func (r *TrackGrantRepository) RevokeContext(ctx context.Context, contextType, contextID string, bearerOnly bool) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE track_grants
+		SET revoked_at = NOW()
+		WHERE context_type = $1 AND context_id = $2 AND revoked_at IS NULL
+			AND (NOT $3 OR grantee_id IS NULL)
+	`, contextType, contextID, bearerOnly)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GrantCovers reports whether the grant is active, covers the track, and is
+// usable by listener: either it names no grantee or listener is the grantee.
+// A playlist link grant covers the tracks of its playlist for as long as the
+// playlist stays public.
+func (r *TrackGrantRepository) GrantCovers(ctx context.Context, grantID uuid.UUID, trackID int64, listener uuid.NullUUID) (bool, error) {
+	var covers bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM track_grants g
+			WHERE g.id = $1
+				AND g.revoked_at IS NULL AND g.expires_at > NOW()
+				AND (g.grantee_id IS NULL OR g.grantee_id = $3)
+				AND (
+					EXISTS (
+						SELECT 1 FROM track_grant_tracks gt
+						WHERE gt.grant_id = g.id AND gt.track_id = $2
+					)
+					OR (g.context_type = $4 AND EXISTS (
+						SELECT 1
+						FROM playlist_tracks pt
+						JOIN playlists p ON p.id = pt.playlist_id
+						WHERE pt.playlist_id::text = g.context_id AND pt.track_id = $2 AND p.is_public
+					))
+				)
+		)
+	`, grantID, trackID, listener, TrackGrantContextPlaylistLink).Scan(&covers)
+	return covers, err
+}
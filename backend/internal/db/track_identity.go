@@ -250,15 +250,47 @@ func DurationBucket(durationMs, bucketSizeMs int) int {
 	return (durationMs / bucketSizeMs) * bucketSizeMs
 }
 
-// CalculateIdentityHash generates a unique identity hash for a track.
-// The hash is based on normalized artist, title, album, duration bucket, and version.
-// Returns a 16-character hex string (first 16 chars of SHA256).
-func CalculateIdentityHash(artist, title, album string, durationMs int, version string) string {
+// IdentityStrategy decides which parts of a track's identity separate it from
+// other tracks, and so which downloads deduplicate to one track row.
+type IdentityStrategy struct {
+	// IncludeAlbum keeps the same recording on different albums apart.
+	IncludeAlbum bool
+	// DurationBucketMs is the width of the duration buckets; tracks in
+	// different buckets stay apart. 0 ignores duration.
+	DurationBucketMs int
+	// VersionSensitive keeps versions such as "live" or "remix" apart from
+	// the original.
+	VersionSensitive bool
+}
+
+// DefaultIdentityStrategy is the strategy identity hashes were computed with
+// before it became configurable.
+var DefaultIdentityStrategy = IdentityStrategy{IncludeAlbum: true, DurationBucketMs: 5000, VersionSensitive: true}
+
+// String describes the strategy for logs and prompts.
+func (s IdentityStrategy) String() string {
+	duration := "ignored"
+	if s.DurationBucketMs > 0 {
+		duration = fmt.Sprintf("%dms buckets", s.DurationBucketMs)
+	}
+	return fmt.Sprintf("album=%t duration=%s version=%t", s.IncludeAlbum, duration, s.VersionSensitive)
+}
+
+// Hash returns the identity hash of t under the strategy. Excluded components
+// hash as empty, so the default strategy reproduces CalculateIdentityHash.
+func (s IdentityStrategy) Hash(t TrackIdentity) string {
+	album, version := t.Album, t.Version
+	if !s.IncludeAlbum {
+		album = ""
+	}
+	if !s.VersionSensitive {
+		version = ""
+	}
 	normalized := fmt.Sprintf("%s|%s|%s|%d|%s",
-		NormalizeString(artist),
-		NormalizeString(title),
+		NormalizeString(t.Artist),
+		NormalizeString(t.Title),
 		NormalizeString(album),
-		DurationBucket(durationMs, 5000), // 5 second buckets
+		DurationBucket(t.DurationMs, s.DurationBucketMs),
 		NormalizeString(version),
 	)
 
@@ -266,9 +298,22 @@ func CalculateIdentityHash(artist, title, album string, durationMs int, version
 	return hex.EncodeToString(hash[:])[:16]
 }
 
+// CalculateIdentityHash generates a unique identity hash for a track.
+// The hash is based on normalized artist, title, album, duration bucket, and version.
+// Returns a 16-character hex string (first 16 chars of SHA256).
+func CalculateIdentityHash(artist, title, album string, durationMs int, version string) string {
+	return DefaultIdentityStrategy.Hash(TrackIdentity{
+		Artist:     artist,
+		Title:      title,
+		Album:      album,
+		DurationMs: durationMs,
+		Version:    version,
+	})
+}
+
 // CalculateIdentityHashFromTrack calculates the identity hash from a TrackIdentity struct.
 func CalculateIdentityHashFromTrack(t TrackIdentity) string {
-	return CalculateIdentityHash(t.Artist, t.Title, t.Album, t.DurationMs, t.Version)
+	return DefaultIdentityStrategy.Hash(t)
 }
 
 // ParseTrackMetadata extracts identity components from raw track metadata.
@@ -1,7 +1,12 @@
 package db
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 )
 
 func TestNormalizeString(t *testing.T) {
@@ -285,3 +290,82 @@ func TestDeduplicationScenarios(t *testing.T) {
 		}
 	})
 }
+
+func TestIdentityStrategyDefaultKeepsExistingHashes(t *testing.T) {
+	// Hashes stored before the strategy was configurable must not move.
+	sum := sha256.Sum256([]byte("beatles|hey jude|past masters|430000|live"))
+	want := hex.EncodeToString(sum[:])[:16]
+	got := DefaultIdentityStrategy.Hash(TrackIdentity{Artist: "The Beatles", Title: "Hey Jude", Album: "Past Masters", DurationMs: 431000, Version: "live"})
+	if got != want {
+		t.Fatalf("default strategy hash = %q, want %q", got, want)
+	}
+}
+
+func TestIdentityStrategyExcludesComponents(t *testing.T) {
+	original := TrackIdentity{Artist: "Artist", Title: "Song", Album: "Album", DurationMs: 210000}
+	tests := []struct {
+		name     string
+		strategy IdentityStrategy
+		other    TrackIdentity
+	}{
+		{"without album", IdentityStrategy{DurationBucketMs: 5000, VersionSensitive: true},
+			TrackIdentity{Artist: "Artist", Title: "Song", Album: "Greatest Hits", DurationMs: 210000}},
+		{"wider duration bucket", IdentityStrategy{IncludeAlbum: true, DurationBucketMs: 30000, VersionSensitive: true},
+			TrackIdentity{Artist: "Artist", Title: "Song", Album: "Album", DurationMs: 229000}},
+		{"without duration", IdentityStrategy{IncludeAlbum: true, VersionSensitive: true},
+			TrackIdentity{Artist: "Artist", Title: "Song", Album: "Album", DurationMs: 400000}},
+		{"without version", IdentityStrategy{IncludeAlbum: true, DurationBucketMs: 5000},
+			TrackIdentity{Artist: "Artist", Title: "Song", Album: "Album", DurationMs: 210000, Version: "remaster"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if DefaultIdentityStrategy.Hash(original) == DefaultIdentityStrategy.Hash(tt.other) {
+				t.Fatal("default strategy already merges these tracks")
+			}
+			if tt.strategy.Hash(original) != tt.strategy.Hash(tt.other) {
+				t.Fatalf("strategy %s keeps the tracks apart", tt.strategy)
+			}
+		})
+	}
+}
+
+func TestPlanIdentityRehashGroupsCollisionsWithSuggestedKeeperFirst(t *testing.T) {
+	strategy := IdentityStrategy{DurationBucketMs: 5000, VersionSensitive: true}
+	track := func(id int64, album string, libraries int, created time.Time) IdentityRehashTrack {
+		identity := TrackIdentity{Artist: "Artist", Title: "Song", Album: album, DurationMs: 210000}
+		return IdentityRehashTrack{ID: id, IdentityHash: CalculateIdentityHashFromTrack(identity), Identity: identity, LibraryCount: libraries, CreatedAt: created}
+	}
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	unchanged := IdentityRehashTrack{ID: 9, Identity: TrackIdentity{Artist: "Other", Title: "Tune"}}
+	unchanged.IdentityHash = strategy.Hash(unchanged.Identity)
+	tracks := []IdentityRehashTrack{
+		track(1, "Album", 1, day),
+		track(2, "Single", 3, day.Add(time.Hour)),
+		track(3, "Greatest Hits", 1, day.Add(-time.Hour)),
+		{ID: 4, IdentityHash: "old", Identity: TrackIdentity{Artist: "Solo", Title: "Song", Album: "Only"}},
+		unchanged,
+	}
+
+	plan := PlanIdentityRehash(tracks, strategy)
+	if plan.Unchanged != 1 || len(plan.Changes) != 1 || plan.Changes[0].TrackID != 4 || plan.Changes[0].OldHash != "old" {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if len(plan.Collisions) != 1 {
+		t.Fatalf("got %d collisions, want 1", len(plan.Collisions))
+	}
+	var order []int64
+	for _, tr := range plan.Collisions[0].Tracks {
+		order = append(order, tr.ID)
+	}
+	if fmt.Sprint(order) != "[2 3 1]" {
+		t.Fatalf("collision order = %v, want most libraries, then oldest", order)
+	}
+}
+
+func TestTrackConflictErrorMatchesErrDuplicateTrack(t *testing.T) {
+	err := fmt.Errorf("import: %w", &TrackConflictError{IdentityHash: "abc", ExistingID: 7})
+	var conflict *TrackConflictError
+	if !errors.Is(err, ErrDuplicateTrack) || !errors.As(err, &conflict) || conflict.ExistingID != 7 {
+		t.Fatalf("err = %v, want a TrackConflictError matching ErrDuplicateTrack", err)
+	}
+}
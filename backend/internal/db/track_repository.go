@@ -5,22 +5,54 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var ErrTrackNotFound = errors.New("track not found")
 var ErrDuplicateTrack = errors.New("track with this identity hash already exists")
+var ErrTrackSourceNotFound = errors.New("track source not found")
+
+// TrackConflictError reports a create that lost to an existing track with the
+// same identity hash. It matches ErrDuplicateTrack with errors.Is.
+type TrackConflictError struct {
+	IdentityHash string
+	// ExistingID is the conflicting track's ID, or 0 if it was deleted
+	// before it could be read.
+	ExistingID int64
+}
+
+func (e *TrackConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDuplicateTrack, e.IdentityHash)
+}
+
+func (e *TrackConflictError) Is(target error) bool {
+	return target == ErrDuplicateTrack
+}
 
 // trigramSearchThreshold is the minimum pg_trgm similarity() score a row must reach
 // to be considered a fuzzy match. It is deliberately loose enough that a single-character
 // typo of a stored title/artist still clears it, while filtering out unrelated rows. Exact
 // matches score ~1.0 and therefore always rank first. Only used on the fuzzy fallback path
 // (FTS returned nothing AND pg_trgm is installed); the FTS path is unaffected.
+//
+// The fallback queries also filter with the % operator so the trigram GIN indexes can
+// be used; % compares against pg_trgm.similarity_threshold, whose default is this same
+// 0.3. Lowering this constant below that default has no effect without also changing
+// the setting.
 const trigramSearchThreshold = 0.3
 
+// trackSearchRankVector weights title matches above artist matches above album
+// matches when ranking SearchRecordings results. Only matched rows are ranked, so
+// it does not need an index of its own; matching uses idx_tracks_fulltext.
+const trackSearchRankVector = `setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+				   setweight(to_tsvector('english', COALESCE(artist, '')), 'B') ||
+				   setweight(to_tsvector('english', COALESCE(album, '')), 'C')`
+
 type Track struct {
 	ID                 int64
 	IdentityHash       string
@@ -53,14 +85,24 @@ type Track struct {
 	AnalysisUpdatedAt  sql.NullTime
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
+	// QuarantinedAt is set while a content takedown blocks the track; only
+	// single-track lookups and library listings load it.
+	QuarantinedAt sql.NullTime
+	// ReplayGain is loaded by the same queries as QuarantinedAt.
+	ReplayGain ReplayGain
 }
 
+// Artist is a search result grouping tracks by artist. ArtworkKey is set
+// when an admin uploaded artwork for the MusicBrainz artist.
 type Artist struct {
 	Name       string
 	MBArtistID *uuid.UUID
 	TrackCount int
+	ArtworkKey sql.NullString
 }
 
+// Release is a search result grouping tracks by album. ArtworkKey is set
+// when an admin uploaded artwork for the MusicBrainz release.
 type Release struct {
 	ID          int64
 	Name        string
@@ -68,18 +110,55 @@ type Release struct {
 	MBReleaseID *uuid.UUID
 	CoverArtURL sql.NullString
 	TrackCount  int
+	ArtworkKey  sql.NullString
 }
 
 type TrackRepository struct {
-	db *DB
+	db       *DB
+	identity IdentityStrategy
 }
 
 func NewTrackRepository(db *DB) *TrackRepository {
-	return &TrackRepository{db: db}
+	return &TrackRepository{db: db, identity: DefaultIdentityStrategy}
+}
+
+// SetIdentityStrategy changes how new tracks are deduplicated. Existing rows
+// keep their hashes until the rehash-identities command recomputes them, so
+// the two should change together.
+func (r *TrackRepository) SetIdentityStrategy(strategy IdentityStrategy) {
+	r.identity = strategy
+}
+
+// RecordingSearchFilter narrows a recording search to tracks carrying every
+// tag in Tags, either as one of UserID's tags or as a MusicBrainz genre.
+type RecordingSearchFilter struct {
+	Tags   []string
+	UserID uuid.UUID
+}
+
+// condition returns the SQL condition for the filter with its parameters
+// numbered from first, or "" when the filter is empty.
+func (f RecordingSearchFilter) condition(first int) (string, []any) {
+	if len(f.Tags) == 0 {
+		return "", nil
+	}
+	args := []any{f.UserID}
+	userParam := "$" + itoa(first)
+	var cond strings.Builder
+	for i, tag := range f.Tags {
+		cond.WriteString(" AND " + trackTagCondition("tracks.id", userParam, "$"+itoa(first+1+i)))
+		args = append(args, tag)
+	}
+	return cond.String(), args
 }
 
 // SearchRecordings searches tracks by title with optional artist filter using full-text search
 func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, limit, offset int) ([]Track, int, error) {
+	return r.SearchRecordingsFiltered(ctx, query, RecordingSearchFilter{}, limit, offset)
+}
+
+// SearchRecordingsFiltered is SearchRecordings narrowed by filter.
+func (r *TrackRepository) SearchRecordingsFiltered(ctx context.Context, query string, filter RecordingSearchFilter, limit, offset int) ([]Track, int, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -93,6 +172,8 @@ func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, li
 		return []Track{}, 0, nil
 	}
 
+	filterCond, filterArgs := filter.condition(4)
+
 	// Single query with window function to get both results and total count
 	selectQuery := `
 		WITH search_results AS (
@@ -102,10 +183,10 @@ func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, li
 				   codec, bitrate_kbps, sample_rate_hz, channels, content_type,
 				   metadata_json, metadata_status, metadata_confidence, metadata_provenance,
 				   cover_art_url, metadata_user_edited, created_at, updated_at,
-				   ts_rank(to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(artist, '') || ' ' || COALESCE(album, '')), to_tsquery('english', $1)) as rank,
+				   ts_rank(` + trackSearchRankVector + `, to_tsquery('english', $1)) as rank,
 				   COUNT(*) OVER() as total_count
 			FROM tracks
-			WHERE to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(artist, '') || ' ' || COALESCE(album, '')) @@ to_tsquery('english', $1)
+			WHERE to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(artist, '') || ' ' || COALESCE(album, '')) @@ to_tsquery('english', $1)` + filterCond + `
 		)
 		SELECT sr.id, sr.identity_hash, sr.title, sr.artist, sr.album, sr.duration_ms, sr.version,
 			   sr.mb_recording_id, sr.mb_release_id, sr.mb_artist_id, sr.mb_verified,
@@ -123,7 +204,7 @@ func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, li
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, selectQuery, tsQuery, limit, offset)
+	rows, err := r.db.QueryContext(ctx, selectQuery, append([]any{tsQuery, limit, offset}, filterArgs...)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -158,7 +239,7 @@ func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, li
 	// retry with a trigram similarity() match so a typo still surfaces the track. When
 	// the extension is absent we return the (empty) FTS result unchanged.
 	if total == 0 && r.db.TrigramEnabled {
-		return r.searchRecordingsTrigram(ctx, query, limit, offset)
+		return r.searchRecordingsTrigram(ctx, query, filter, limit, offset)
 	}
 
 	return tracks, total, nil
@@ -168,11 +249,12 @@ func (r *TrackRepository) SearchRecordings(ctx context.Context, query string, li
 // tracks by the best similarity() across title/artist/album against the raw query and
 // keeps only rows at or above trigramSearchThreshold. Callers must gate this on
 // r.db.TrigramEnabled; it assumes the extension is installed.
-func (r *TrackRepository) searchRecordingsTrigram(ctx context.Context, query string, limit, offset int) ([]Track, int, error) {
+func (r *TrackRepository) searchRecordingsTrigram(ctx context.Context, query string, filter RecordingSearchFilter, limit, offset int) ([]Track, int, error) {
 	q := strings.TrimSpace(query)
 	if q == "" {
 		return []Track{}, 0, nil
 	}
+	filterCond, filterArgs := filter.condition(5)
 
 	selectQuery := `
 		WITH search_results AS (
@@ -189,11 +271,12 @@ func (r *TrackRepository) searchRecordingsTrigram(ctx context.Context, query str
 				   ) as rank,
 				   COUNT(*) OVER() as total_count
 			FROM tracks
-			WHERE GREATEST(
+			WHERE (title % $1 OR artist % $1 OR album % $1)
+				AND GREATEST(
 					  similarity(COALESCE(title, ''), $1),
 					  similarity(COALESCE(artist, ''), $1),
 					  similarity(COALESCE(album, ''), $1)
-				  ) >= $4
+				  ) >= $4` + filterCond + `
 		)
 		SELECT sr.id, sr.identity_hash, sr.title, sr.artist, sr.album, sr.duration_ms, sr.version,
 			   sr.mb_recording_id, sr.mb_release_id, sr.mb_artist_id, sr.mb_verified,
@@ -211,7 +294,7 @@ func (r *TrackRepository) searchRecordingsTrigram(ctx context.Context, query str
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, selectQuery, q, limit, offset, trigramSearchThreshold)
+	rows, err := r.db.QueryContext(ctx, selectQuery, append([]any{q, limit, offset, trigramSearchThreshold}, filterArgs...)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -245,6 +328,76 @@ func (r *TrackRepository) searchRecordingsTrigram(ctx context.Context, query str
 	return tracks, total, nil
 }
 
+// searchArtistsQuery finds artists by their entity name, falling back to the
+// track's artist text. Matching that COALESCE directly would build a tsvector
+// for every track, so candidate tracks come from the two indexed matches
+// (idx_tracks_artist_fulltext and idx_artists_name_fulltext) and only those
+// are rechecked against the displayed name. One query with a window function
+// for the total count.
+const searchArtistsQuery = `
+	WITH matched AS (
+		SELECT t.id
+		FROM tracks t
+		WHERE t.artist IS NOT NULL
+			AND to_tsvector('english', t.artist) @@ to_tsquery('english', $1)
+		UNION
+		SELECT t.id
+		FROM artists a
+		JOIN tracks t ON t.mb_artist_id = a.mb_artist_id
+		WHERE to_tsvector('english', a.name) @@ to_tsquery('english', $1)
+	),
+	artist_results AS (
+		SELECT COALESCE(NULLIF(a.name, ''), t.artist) as artist, t.mb_artist_id, MAX(a.artwork_key) as artwork_key, COUNT(*) as track_count,
+			   ts_rank(to_tsvector('english', COALESCE(NULLIF(a.name, ''), t.artist)), to_tsquery('english', $1)) as rank,
+			   COUNT(*) OVER() as total_groups
+		FROM matched m
+		JOIN tracks t ON t.id = m.id
+		LEFT JOIN artists a ON a.mb_artist_id = t.mb_artist_id
+		WHERE COALESCE(NULLIF(a.name, ''), t.artist) IS NOT NULL
+			AND to_tsvector('english', COALESCE(NULLIF(a.name, ''), t.artist)) @@ to_tsquery('english', $1)
+		GROUP BY COALESCE(NULLIF(a.name, ''), t.artist), t.mb_artist_id
+	)
+	SELECT artist, mb_artist_id, artwork_key, track_count, total_groups
+	FROM artist_results
+	ORDER BY rank DESC, track_count DESC, artist ASC
+	LIMIT $2 OFFSET $3
+`
+
+// searchReleasesQuery is searchArtistsQuery for albums: candidates come from
+// idx_tracks_album_fulltext and idx_releases_title_fulltext, then are
+// rechecked against the displayed title.
+const searchReleasesQuery = `
+	WITH matched AS (
+		SELECT t.id
+		FROM tracks t
+		WHERE t.album IS NOT NULL
+			AND to_tsvector('english', t.album) @@ to_tsquery('english', $1)
+		UNION
+		SELECT t.id
+		FROM releases rl
+		JOIN tracks t ON t.mb_release_id = rl.mb_release_id
+		WHERE to_tsvector('english', rl.title) @@ to_tsquery('english', $1)
+	),
+	release_results AS (
+		SELECT MIN(t.id) as id, COALESCE(NULLIF(rl.title, ''), t.album) as album,
+			   COALESCE(NULLIF(a.name, ''), t.artist) as artist, t.mb_release_id,
+			   MAX(t.cover_art_url) as cover_art_url, MAX(rl.artwork_key) as artwork_key, COUNT(*) as track_count,
+			   ts_rank(to_tsvector('english', COALESCE(NULLIF(rl.title, ''), t.album)), to_tsquery('english', $1)) as rank,
+			   COUNT(*) OVER() as total_groups
+		FROM matched m
+		JOIN tracks t ON t.id = m.id
+		LEFT JOIN releases rl ON rl.mb_release_id = t.mb_release_id
+		LEFT JOIN artists a ON a.mb_artist_id = t.mb_artist_id
+		WHERE COALESCE(NULLIF(rl.title, ''), t.album) IS NOT NULL
+			AND to_tsvector('english', COALESCE(NULLIF(rl.title, ''), t.album)) @@ to_tsquery('english', $1)
+		GROUP BY COALESCE(NULLIF(rl.title, ''), t.album), COALESCE(NULLIF(a.name, ''), t.artist), t.mb_release_id
+	)
+	SELECT id, album, artist, mb_release_id, cover_art_url, artwork_key, track_count, total_groups
+	FROM release_results
+	ORDER BY rank DESC, track_count DESC, album ASC
+	LIMIT $2 OFFSET $3
+`
+
 // SearchArtists searches distinct artists by name using full-text search
 func (r *TrackRepository) SearchArtists(ctx context.Context, query string, limit, offset int) ([]Artist, int, error) {
 	if limit <= 0 {
@@ -260,24 +413,7 @@ func (r *TrackRepository) SearchArtists(ctx context.Context, query string, limit
 		return []Artist{}, 0, nil
 	}
 
-	// Single query with window function for total count
-	selectQuery := `
-		WITH artist_results AS (
-			SELECT artist, mb_artist_id, COUNT(*) as track_count,
-				   ts_rank(to_tsvector('english', artist), to_tsquery('english', $1)) as rank,
-				   COUNT(*) OVER() as total_groups
-			FROM tracks
-			WHERE artist IS NOT NULL
-				AND to_tsvector('english', artist) @@ to_tsquery('english', $1)
-			GROUP BY artist, mb_artist_id
-		)
-		SELECT artist, mb_artist_id, track_count, total_groups
-		FROM artist_results
-		ORDER BY rank DESC, track_count DESC, artist ASC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := r.db.QueryContext(ctx, selectQuery, tsQuery, limit, offset)
+	rows, err := r.db.QueryContext(ctx, searchArtistsQuery, tsQuery, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -287,7 +423,7 @@ func (r *TrackRepository) SearchArtists(ctx context.Context, query string, limit
 	var total int
 	for rows.Next() {
 		var a Artist
-		err := rows.Scan(&a.Name, &a.MBArtistID, &a.TrackCount, &total)
+		err := rows.Scan(&a.Name, &a.MBArtistID, &a.ArtworkKey, &a.TrackCount, &total)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -316,15 +452,17 @@ func (r *TrackRepository) searchArtistsTrigram(ctx context.Context, query string
 
 	selectQuery := `
 		WITH artist_results AS (
-			SELECT artist, mb_artist_id, COUNT(*) as track_count,
-				   MAX(similarity(artist, $1)) as rank,
+			SELECT COALESCE(NULLIF(a.name, ''), t.artist) as artist, t.mb_artist_id, MAX(a.artwork_key) as artwork_key, COUNT(*) as track_count,
+				   MAX(similarity(COALESCE(NULLIF(a.name, ''), t.artist), $1)) as rank,
 				   COUNT(*) OVER() as total_groups
-			FROM tracks
-			WHERE artist IS NOT NULL
-				AND similarity(artist, $1) >= $4
-			GROUP BY artist, mb_artist_id
+			FROM tracks t
+			LEFT JOIN artists a ON a.mb_artist_id = t.mb_artist_id
+			WHERE COALESCE(NULLIF(a.name, ''), t.artist) IS NOT NULL
+				AND COALESCE(NULLIF(a.name, ''), t.artist) % $1
+				AND similarity(COALESCE(NULLIF(a.name, ''), t.artist), $1) >= $4
+			GROUP BY COALESCE(NULLIF(a.name, ''), t.artist), t.mb_artist_id
 		)
-		SELECT artist, mb_artist_id, track_count, total_groups
+		SELECT artist, mb_artist_id, artwork_key, track_count, total_groups
 		FROM artist_results
 		ORDER BY rank DESC, track_count DESC, artist ASC
 		LIMIT $2 OFFSET $3
@@ -340,7 +478,7 @@ func (r *TrackRepository) searchArtistsTrigram(ctx context.Context, query string
 	var total int
 	for rows.Next() {
 		var a Artist
-		if err := rows.Scan(&a.Name, &a.MBArtistID, &a.TrackCount, &total); err != nil {
+		if err := rows.Scan(&a.Name, &a.MBArtistID, &a.ArtworkKey, &a.TrackCount, &total); err != nil {
 			return nil, 0, err
 		}
 		artists = append(artists, a)
@@ -368,24 +506,7 @@ func (r *TrackRepository) SearchReleases(ctx context.Context, query string, limi
 		return []Release{}, 0, nil
 	}
 
-	// Single query with window function for total count
-	selectQuery := `
-		WITH release_results AS (
-			SELECT MIN(id) as id, album, artist, mb_release_id, MAX(cover_art_url) as cover_art_url, COUNT(*) as track_count,
-				   ts_rank(to_tsvector('english', album), to_tsquery('english', $1)) as rank,
-				   COUNT(*) OVER() as total_groups
-			FROM tracks
-			WHERE album IS NOT NULL
-				AND to_tsvector('english', album) @@ to_tsquery('english', $1)
-			GROUP BY album, artist, mb_release_id
-		)
-		SELECT id, album, artist, mb_release_id, cover_art_url, track_count, total_groups
-		FROM release_results
-		ORDER BY rank DESC, track_count DESC, album ASC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := r.db.QueryContext(ctx, selectQuery, tsQuery, limit, offset)
+	rows, err := r.db.QueryContext(ctx, searchReleasesQuery, tsQuery, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -396,7 +517,7 @@ func (r *TrackRepository) SearchReleases(ctx context.Context, query string, limi
 	for rows.Next() {
 		var release Release
 		var artist sql.NullString
-		err := rows.Scan(&release.ID, &release.Name, &artist, &release.MBReleaseID, &release.CoverArtURL, &release.TrackCount, &total)
+		err := rows.Scan(&release.ID, &release.Name, &artist, &release.MBReleaseID, &release.CoverArtURL, &release.ArtworkKey, &release.TrackCount, &total)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -428,15 +549,20 @@ func (r *TrackRepository) searchReleasesTrigram(ctx context.Context, query strin
 
 	selectQuery := `
 		WITH release_results AS (
-			SELECT MIN(id) as id, album, artist, mb_release_id, MAX(cover_art_url) as cover_art_url, COUNT(*) as track_count,
-				   MAX(similarity(album, $1)) as rank,
+			SELECT MIN(t.id) as id, COALESCE(NULLIF(rl.title, ''), t.album) as album,
+				   COALESCE(NULLIF(a.name, ''), t.artist) as artist, t.mb_release_id,
+				   MAX(t.cover_art_url) as cover_art_url, MAX(rl.artwork_key) as artwork_key, COUNT(*) as track_count,
+				   MAX(similarity(COALESCE(NULLIF(rl.title, ''), t.album), $1)) as rank,
 				   COUNT(*) OVER() as total_groups
-			FROM tracks
-			WHERE album IS NOT NULL
-				AND similarity(album, $1) >= $4
-			GROUP BY album, artist, mb_release_id
+			FROM tracks t
+			LEFT JOIN releases rl ON rl.mb_release_id = t.mb_release_id
+			LEFT JOIN artists a ON a.mb_artist_id = t.mb_artist_id
+			WHERE COALESCE(NULLIF(rl.title, ''), t.album) IS NOT NULL
+				AND COALESCE(NULLIF(rl.title, ''), t.album) % $1
+				AND similarity(COALESCE(NULLIF(rl.title, ''), t.album), $1) >= $4
+			GROUP BY COALESCE(NULLIF(rl.title, ''), t.album), COALESCE(NULLIF(a.name, ''), t.artist), t.mb_release_id
 		)
-		SELECT id, album, artist, mb_release_id, cover_art_url, track_count, total_groups
+		SELECT id, album, artist, mb_release_id, cover_art_url, artwork_key, track_count, total_groups
 		FROM release_results
 		ORDER BY rank DESC, track_count DESC, album ASC
 		LIMIT $2 OFFSET $3
@@ -453,7 +579,7 @@ func (r *TrackRepository) searchReleasesTrigram(ctx context.Context, query strin
 	for rows.Next() {
 		var release Release
 		var artist sql.NullString
-		if err := rows.Scan(&release.ID, &release.Name, &artist, &release.MBReleaseID, &release.CoverArtURL, &release.TrackCount, &total); err != nil {
+		if err := rows.Scan(&release.ID, &release.Name, &artist, &release.MBReleaseID, &release.CoverArtURL, &release.ArtworkKey, &release.TrackCount, &total); err != nil {
 			return nil, 0, err
 		}
 		if artist.Valid {
@@ -477,7 +603,8 @@ func (r *TrackRepository) GetByID(ctx context.Context, id int64) (*Track, error)
 			   source_url, source_type, storage_key, file_size_bytes,
 			   codec, bitrate_kbps, sample_rate_hz, channels, content_type,
 			   metadata_json, metadata_status, metadata_confidence, metadata_provenance,
-			   cover_art_url, metadata_user_edited, created_at, updated_at
+			   cover_art_url, metadata_user_edited, created_at, updated_at, quarantined_at,
+			   loudness_lufs, true_peak_dbtp, track_gain_db, album_gain_db, album_peak_dbtp
 		FROM tracks
 		WHERE id = $1
 	`
@@ -489,7 +616,8 @@ func (r *TrackRepository) GetByID(ctx context.Context, id int64) (*Track, error)
 		&t.SourceURL, &t.SourceType, &t.StorageKey, &t.FileSizeBytes,
 		&t.Codec, &t.BitrateKbps, &t.SampleRateHz, &t.Channels, &t.ContentType,
 		&t.MetadataJSON, &t.MetadataStatus, &t.MetadataConfidence, &t.MetadataProvenance,
-		&t.CoverArtURL, &t.MetadataUserEdited, &t.CreatedAt, &t.UpdatedAt,
+		&t.CoverArtURL, &t.MetadataUserEdited, &t.CreatedAt, &t.UpdatedAt, &t.QuarantinedAt,
+		&t.ReplayGain.LoudnessLUFS, &t.ReplayGain.TruePeakDBTP, &t.ReplayGain.TrackGainDB, &t.ReplayGain.AlbumGainDB, &t.ReplayGain.AlbumPeakDBTP,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -501,6 +629,51 @@ func (r *TrackRepository) GetByID(ctx context.Context, id int64) (*Track, error)
 	return &t, nil
 }
 
+// GetByIDs retrieves the tracks with the given IDs keyed by ID. Unknown IDs
+// are simply absent from the result.
+func (r *TrackRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*Track, error) {
+	result := make(map[int64]*Track, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	query := `
+		SELECT id, identity_hash, title, artist, album, duration_ms, version,
+			   mb_recording_id, mb_release_id, mb_artist_id, mb_verified,
+			   source_url, source_type, storage_key, file_size_bytes,
+			   codec, bitrate_kbps, sample_rate_hz, channels, content_type,
+			   metadata_json, metadata_status, metadata_confidence, metadata_provenance,
+			   cover_art_url, metadata_user_edited, created_at, updated_at, quarantined_at,
+			   loudness_lufs, true_peak_dbtp, track_gain_db, album_gain_db, album_peak_dbtp
+		FROM tracks
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Track
+		if err := rows.Scan(
+			&t.ID, &t.IdentityHash, &t.Title, &t.Artist, &t.Album, &t.DurationMs, &t.Version,
+			&t.MBRecordingID, &t.MBReleaseID, &t.MBArtistID, &t.MBVerified,
+			&t.SourceURL, &t.SourceType, &t.StorageKey, &t.FileSizeBytes,
+			&t.Codec, &t.BitrateKbps, &t.SampleRateHz, &t.Channels, &t.ContentType,
+			&t.MetadataJSON, &t.MetadataStatus, &t.MetadataConfidence, &t.MetadataProvenance,
+			&t.CoverArtURL, &t.MetadataUserEdited, &t.CreatedAt, &t.UpdatedAt, &t.QuarantinedAt,
+			&t.ReplayGain.LoudnessLUFS, &t.ReplayGain.TruePeakDBTP, &t.ReplayGain.TrackGainDB, &t.ReplayGain.AlbumGainDB, &t.ReplayGain.AlbumPeakDBTP,
+		); err != nil {
+			return nil, err
+		}
+		result[t.ID] = &t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // MBMatchUpdate contains the MusicBrainz match data to update
 type MBMatchUpdate struct {
 	MBRecordingID      *uuid.UUID
@@ -525,6 +698,12 @@ type MBMatchUpdate struct {
 
 // UpdateMBMatch updates a track's MusicBrainz identifiers and verification status
 func (r *TrackRepository) UpdateMBMatch(ctx context.Context, trackID int64, match *MBMatchUpdate) error {
+	if match.ApplyMBIdentity {
+		if err := ensureMBEntities(ctx, r.db, match.MBArtistID, match.Artist, match.MBReleaseID, match.Album); err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE tracks
 		SET mb_recording_id = CASE WHEN $15 AND (metadata_user_edited = FALSE OR $16 = FALSE) THEN $2 ELSE mb_recording_id END,
@@ -684,7 +863,8 @@ func (r *TrackRepository) GetByIdentityHash(ctx context.Context, identityHash st
 			   source_url, source_type, storage_key, file_size_bytes,
 			   codec, bitrate_kbps, sample_rate_hz, channels, content_type,
 			   metadata_json, metadata_status, metadata_confidence, metadata_provenance,
-			   cover_art_url, metadata_user_edited, created_at, updated_at
+			   cover_art_url, metadata_user_edited, created_at, updated_at, quarantined_at,
+			   loudness_lufs, true_peak_dbtp, track_gain_db, album_gain_db, album_peak_dbtp
 		FROM tracks
 		WHERE identity_hash = $1
 	`
@@ -696,7 +876,8 @@ func (r *TrackRepository) GetByIdentityHash(ctx context.Context, identityHash st
 		&t.SourceURL, &t.SourceType, &t.StorageKey, &t.FileSizeBytes,
 		&t.Codec, &t.BitrateKbps, &t.SampleRateHz, &t.Channels, &t.ContentType,
 		&t.MetadataJSON, &t.MetadataStatus, &t.MetadataConfidence, &t.MetadataProvenance,
-		&t.CoverArtURL, &t.MetadataUserEdited, &t.CreatedAt, &t.UpdatedAt,
+		&t.CoverArtURL, &t.MetadataUserEdited, &t.CreatedAt, &t.UpdatedAt, &t.QuarantinedAt,
+		&t.ReplayGain.LoudnessLUFS, &t.ReplayGain.TruePeakDBTP, &t.ReplayGain.TrackGainDB, &t.ReplayGain.AlbumGainDB, &t.ReplayGain.AlbumPeakDBTP,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -708,9 +889,60 @@ func (r *TrackRepository) GetByIdentityHash(ctx context.Context, identityHash st
 	return &t, nil
 }
 
-// Create inserts a new track into the database.
-// Returns ErrDuplicateTrack if a track with the same identity hash already exists.
+// Create inserts a new track into the database. Returns a *TrackConflictError,
+// which matches ErrDuplicateTrack, if a track with the same identity hash
+// already exists.
 func (r *TrackRepository) Create(ctx context.Context, track *Track) error {
+	inserted, err := r.insertTrack(ctx, track)
+	if err != nil || inserted {
+		return err
+	}
+	conflict := &TrackConflictError{IdentityHash: track.IdentityHash}
+	if existing, err := r.GetByIdentityHash(ctx, track.IdentityHash); err == nil {
+		conflict.ExistingID = existing.ID
+	}
+	return conflict
+}
+
+// CreateOrGet attempts to create a new track, but if a track with the same
+// identity hash already exists, it returns the existing track instead.
+// The second return value indicates whether a new track was created (true)
+// or an existing track was returned (false).
+func (r *TrackRepository) CreateOrGet(ctx context.Context, track *Track) (*Track, bool, error) {
+	for attempt := 0; ; attempt++ {
+		inserted, err := r.insertTrack(ctx, track)
+		if err != nil {
+			return nil, false, err
+		}
+		if inserted {
+			return track, true, nil
+		}
+		existing, err := r.GetByIdentityHash(ctx, track.IdentityHash)
+		// The conflicting track can be deleted between the insert and the
+		// select; insert again rather than report a track that is gone.
+		if errors.Is(err, ErrTrackNotFound) && attempt < createOrGetAttempts-1 {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+}
+
+// createOrGetAttempts bounds how often CreateOrGet retries when the track it
+// conflicted with disappears before it can be read.
+const createOrGetAttempts = 3
+
+// insertTrack inserts track unless its identity hash is taken, filling in
+// the generated columns. It reports whether the row was inserted; the unique
+// index settles concurrent inserts of the same identity, so no caller needs
+// to check for the track first.
+func (r *TrackRepository) insertTrack(ctx context.Context, track *Track) (bool, error) {
+	if err := ensureMBEntities(ctx, r.db, track.MBArtistID, track.Artist.String, track.MBReleaseID, track.Album.String); err != nil {
+		return false, err
+	}
+
 	query := `
 		INSERT INTO tracks (
 			identity_hash, title, artist, album, duration_ms, version,
@@ -719,6 +951,7 @@ func (r *TrackRepository) Create(ctx context.Context, track *Track) error {
 			codec, bitrate_kbps, sample_rate_hz, channels, content_type,
 			metadata_status, metadata_confidence, metadata_provenance, cover_art_url, metadata_user_edited
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, COALESCE($21, 'provider'), $22, $23, $24, $25)
+		ON CONFLICT (identity_hash) DO NOTHING
 		RETURNING id, created_at, updated_at
 	`
 
@@ -729,51 +962,13 @@ func (r *TrackRepository) Create(ctx context.Context, track *Track) error {
 		track.Codec, track.BitrateKbps, track.SampleRateHz, track.Channels, track.ContentType,
 		track.MetadataStatus, track.MetadataConfidence, nullableRawJSON(track.MetadataProvenance), track.CoverArtURL, track.MetadataUserEdited,
 	).Scan(&track.ID, &track.CreatedAt, &track.UpdatedAt)
-
-	if err != nil {
-		// Check for unique constraint violation on identity_hash
-		if strings.Contains(err.Error(), "duplicate key") ||
-			strings.Contains(err.Error(), "unique constraint") ||
-			strings.Contains(err.Error(), "idx_tracks_identity_hash") {
-			return ErrDuplicateTrack
-		}
-		return err
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
 	}
-
-	return nil
-}
-
-// CreateOrGet attempts to create a new track, but if a track with the same
-// identity hash already exists, it returns the existing track instead.
-// The second return value indicates whether a new track was created (true)
-// or an existing track was returned (false).
-func (r *TrackRepository) CreateOrGet(ctx context.Context, track *Track) (*Track, bool, error) {
-	// First, try to get existing track by identity hash
-	existing, err := r.GetByIdentityHash(ctx, track.IdentityHash)
-	if err == nil {
-		// Track already exists, return it
-		return existing, false, nil
-	}
-	if !errors.Is(err, ErrTrackNotFound) {
-		// Unexpected error
-		return nil, false, err
-	}
-
-	// Track doesn't exist, try to create it
-	if err := r.Create(ctx, track); err != nil {
-		if errors.Is(err, ErrDuplicateTrack) {
-			// Race condition: another process created the track
-			// Try to fetch it again
-			existing, err = r.GetByIdentityHash(ctx, track.IdentityHash)
-			if err != nil {
-				return nil, false, err
-			}
-			return existing, false, nil
-		}
-		return nil, false, err
+	if err != nil {
+		return false, err
 	}
-
-	return track, true, nil
+	return true, nil
 }
 
 // CreateTrackFromMetadata creates a track from raw metadata, handling normalization
@@ -783,7 +978,7 @@ func (r *TrackRepository) CreateTrackFromMetadata(ctx context.Context, artist, t
 	identity := ParseTrackMetadata(artist, title, album, durationMs)
 
 	// Calculate identity hash
-	identityHash := CalculateIdentityHashFromTrack(identity)
+	identityHash := r.identity.Hash(identity)
 
 	// Create track with normalized data
 	track := &Track{
@@ -803,6 +998,12 @@ func (r *TrackRepository) CreateTrackFromMetadata(ctx context.Context, artist, t
 	return r.CreateOrGet(ctx, track)
 }
 
+// FindByMetadata returns the track that CreateTrackFromMetadata would dedupe
+// the given metadata to, or ErrTrackNotFound if there is none yet.
+func (r *TrackRepository) FindByMetadata(ctx context.Context, artist, title, album string, durationMs int) (*Track, error) {
+	return r.GetByIdentityHash(ctx, r.identity.Hash(ParseTrackMetadata(artist, title, album, durationMs)))
+}
+
 // TrackOption is a functional option for configuring a track during creation.
 type TrackOption func(*Track)
 
@@ -845,8 +1046,9 @@ func WithAudioQuality(codec string, bitrateKbps, sampleRateHz, channels int, con
 	}
 }
 
-// UpdateAudioQuality persists facts probed from the stored artifact.
-func (r *TrackRepository) UpdateAudioQuality(ctx context.Context, trackID int64, codec string, bitrateKbps, sampleRateHz, channels int, contentType string) error {
+// UpdateAudioQuality persists facts probed from the stored artifact. A
+// measured length replaces the source-reported one; zero keeps it.
+func (r *TrackRepository) UpdateAudioQuality(ctx context.Context, trackID int64, codec string, bitrateKbps, sampleRateHz, channels int, contentType string, durationMs int) error {
 	result, err := r.db.ExecContext(ctx, `
 		UPDATE tracks
 		SET codec = NULLIF($2, ''),
@@ -854,9 +1056,32 @@ func (r *TrackRepository) UpdateAudioQuality(ctx context.Context, trackID int64,
 			sample_rate_hz = NULLIF($4, 0),
 			channels = NULLIF($5, 0),
 			content_type = NULLIF($6, ''),
+			duration_ms = COALESCE(NULLIF($7, 0), duration_ms),
+			updated_at = NOW()
+		WHERE id = $1
+	`, trackID, codec, bitrateKbps, sampleRateHz, channels, contentType, durationMs)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTrackNotFound
+	}
+	return nil
+}
+
+// UpdateChapters stores a track's chapter markers and skip segments under
+// "chapters" in its metadata_json, replacing any found before.
+func (r *TrackRepository) UpdateChapters(ctx context.Context, trackID int64, chapters json.RawMessage) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE tracks
+		SET metadata_json = COALESCE(metadata_json, '{}'::jsonb) || jsonb_build_object('chapters', $2::jsonb),
 			updated_at = NOW()
 		WHERE id = $1
-	`, trackID, codec, bitrateKbps, sampleRateHz, channels, contentType)
+	`, trackID, string(chapters))
 	if err != nil {
 		return err
 	}
@@ -870,6 +1095,20 @@ func (r *TrackRepository) UpdateAudioQuality(ctx context.Context, trackID int64,
 	return nil
 }
 
+// UpdateStoredFileSize records the new size of a track's stored audio after
+// it was rewritten in place, on the track and on the source that holds it.
+func (r *TrackRepository) UpdateStoredFileSize(ctx context.Context, trackID int64, storageKey string, size int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH updated AS (
+			UPDATE tracks SET file_size_bytes = $3, updated_at = NOW()
+			WHERE id = $1 AND storage_key = $2
+		)
+		UPDATE track_sources SET file_size_bytes = $3, updated_at = NOW()
+		WHERE track_id = $1 AND storage_key = $2
+	`, trackID, storageKey, size)
+	return err
+}
+
 // MarkAudioQualityProbeAttempt moves a failed artifact to the end of the
 // maintenance queue so one corrupt object cannot starve later rows.
 func (r *TrackRepository) MarkAudioQualityProbeAttempt(ctx context.Context, trackID int64) error {
@@ -881,6 +1120,94 @@ func (r *TrackRepository) MarkAudioQualityProbeAttempt(ctx context.Context, trac
 	return err
 }
 
+// GetArtworkKey returns the object holding the cover extracted from the
+// track's audio, or "" when it has none.
+func (r *TrackRepository) GetArtworkKey(ctx context.Context, trackID int64) (string, error) {
+	var key sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT artwork_key FROM tracks WHERE id = $1`, trackID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTrackNotFound
+	}
+	return key.String, err
+}
+
+// SetArtworkKey records the object holding the track's extracted cover. A
+// track that already has one keeps it.
+func (r *TrackRepository) SetArtworkKey(ctx context.Context, trackID int64, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tracks
+		SET artwork_key = $2
+		WHERE id = $1 AND artwork_key IS NULL
+	`, trackID, key)
+	return err
+}
+
+// GetWaveformKey returns the object holding the track's waveform peaks, or ""
+// when it has none.
+func (r *TrackRepository) GetWaveformKey(ctx context.Context, trackID int64) (string, error) {
+	var key sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT waveform_key FROM tracks WHERE id = $1`, trackID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTrackNotFound
+	}
+	return key.String, err
+}
+
+// SetWaveformKey records the object holding the track's waveform peaks,
+// replacing any earlier one.
+func (r *TrackRepository) SetWaveformKey(ctx context.Context, trackID int64, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tracks
+		SET waveform_key = $2
+		WHERE id = $1
+	`, trackID, key)
+	return err
+}
+
+// ArtworkBackfillCandidate is a track without a stored cover and the
+// sources one could come from.
+type ArtworkBackfillCandidate struct {
+	TrackID      int64
+	IdentityHash string
+	MBReleaseID  *uuid.UUID
+	// CoverArtURL is the cover matching recorded; ThumbnailURL is the
+	// thumbnail of the most recent download that produced the track.
+	CoverArtURL  string
+	ThumbnailURL string
+}
+
+// ListArtworkBackfillCandidates returns up to limit unquarantined tracks
+// with no stored cover and an ID above afterID, in ID order.
+func (r *TrackRepository) ListArtworkBackfillCandidates(ctx context.Context, afterID int64, limit int) ([]ArtworkBackfillCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.identity_hash, t.mb_release_id, COALESCE(t.cover_art_url, ''),
+			   COALESCE((
+				   SELECT j.thumbnail_url FROM download_jobs j
+				   WHERE j.track_id = t.id AND COALESCE(j.thumbnail_url, '') <> ''
+				   ORDER BY j.completed_at DESC NULLS LAST, j.created_at DESC
+				   LIMIT 1
+			   ), '')
+		FROM tracks t
+		WHERE t.artwork_key IS NULL AND t.quarantined_at IS NULL AND t.id > $1
+		ORDER BY t.id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []ArtworkBackfillCandidate
+	for rows.Next() {
+		var c ArtworkBackfillCandidate
+		if err := rows.Scan(&c.TrackID, &c.IdentityHash, &c.MBReleaseID, &c.CoverArtURL, &c.ThumbnailURL); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
 // WithMetadata sets additional metadata JSON on the track.
 func WithMetadata(metadata json.RawMessage) TrackOption {
 	return func(t *Track) {
@@ -900,6 +1227,20 @@ func WithMetadataEnrichment(status string, confidence *float64, provenance json.
 	}
 }
 
+// CountTracks returns how many tracks the instance stores.
+func (r *TrackRepository) CountTracks(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tracks`).Scan(&total)
+	return total, err
+}
+
+// CountUnverifiedTracks returns how many tracks lack MB verification.
+func (r *TrackRepository) CountUnverifiedTracks(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tracks WHERE mb_verified = FALSE`).Scan(&total)
+	return total, err
+}
+
 // GetUnverifiedTracks returns tracks without MB verification for batch processing
 func (r *TrackRepository) GetUnverifiedTracks(ctx context.Context, limit, offset int) ([]Track, int, error) {
 	if limit <= 0 {
@@ -1052,7 +1393,7 @@ func (r *TrackRepository) GetAudioQualityMaintenanceCandidates(ctx context.Conte
 		  AND (
 			  NULLIF(btrim(codec), '') IS NULL OR COALESCE(bitrate_kbps, 0) <= 0
 			  OR COALESCE(sample_rate_hz, 0) <= 0 OR COALESCE(channels, 0) <= 0
-			  OR NULLIF(btrim(content_type), '') IS NULL
+			  OR NULLIF(btrim(content_type), '') IS NULL OR COALESCE(duration_ms, 0) <= 0
 		  )
 		ORDER BY audio_quality_probe_attempted_at ASC NULLS FIRST, id ASC
 		LIMIT $1
@@ -1082,3 +1423,133 @@ func (r *TrackRepository) GetAudioQualityMaintenanceCandidates(ctx context.Conte
 	}
 	return tracks, nil
 }
+
+// TrackSource is one provider location known to resolve to a track, recorded
+// when a download or import deduplicates onto an existing identity. The
+// storage and quality columns are set only for sources whose audio was
+// downloaded and measured.
+type TrackSource struct {
+	ID                int64
+	TrackID           int64
+	Provider          string
+	SourceID          string
+	SourceURL         string
+	StorageKey        sql.NullString
+	FileSizeBytes     sql.NullInt64
+	Codec             sql.NullString
+	BitrateKbps       sql.NullInt32
+	SampleRateHz      sql.NullInt32
+	Channels          sql.NullInt32
+	ContentType       sql.NullString
+	PeakDBFS          sql.NullFloat64
+	ClippingRatio     sql.NullFloat64
+	QualityMeasuredAt sql.NullTime
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// SourceQuality is the measured audio of one downloaded source. PeakDBFS and
+// ClippingRatio are nil when loudness could not be measured.
+type SourceQuality struct {
+	StorageKey    string
+	FileSizeBytes int64
+	Codec         string
+	BitrateKbps   int
+	SampleRateHz  int
+	Channels      int
+	ContentType   string
+	PeakDBFS      *float64
+	ClippingRatio *float64
+}
+
+// ListSources returns every recorded provider source for a track, oldest first.
+func (r *TrackRepository) ListSources(ctx context.Context, trackID int64) ([]TrackSource, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, track_id, provider, source_id, source_url,
+			   storage_key, file_size_bytes, codec, bitrate_kbps, sample_rate_hz, channels, content_type,
+			   peak_dbfs, clipping_ratio, quality_measured_at, created_at, updated_at
+		FROM track_sources
+		WHERE track_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []TrackSource
+	for rows.Next() {
+		var s TrackSource
+		if err := rows.Scan(
+			&s.ID, &s.TrackID, &s.Provider, &s.SourceID, &s.SourceURL,
+			&s.StorageKey, &s.FileSizeBytes, &s.Codec, &s.BitrateKbps, &s.SampleRateHz, &s.Channels, &s.ContentType,
+			&s.PeakDBFS, &s.ClippingRatio, &s.QualityMeasuredAt, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// RecordSourceQuality stores the measured audio for the track_sources row that
+// matches provider and source ID or URL. A source with no recorded row is left
+// alone; the caller records the source first.
+func (r *TrackRepository) RecordSourceQuality(ctx context.Context, trackID int64, provider, sourceID, sourceURL string, q SourceQuality) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE track_sources
+		SET storage_key = NULLIF($5, ''),
+			file_size_bytes = NULLIF($6, 0),
+			codec = NULLIF($7, ''),
+			bitrate_kbps = NULLIF($8, 0),
+			sample_rate_hz = NULLIF($9, 0),
+			channels = NULLIF($10, 0),
+			content_type = NULLIF($11, ''),
+			peak_dbfs = $12,
+			clipping_ratio = $13,
+			quality_measured_at = NOW(),
+			updated_at = NOW()
+		WHERE track_id = $1
+		  AND provider = $2
+		  AND ((source_id <> '' AND source_id = $3) OR (source_url <> '' AND source_url = $4))
+	`, trackID, strings.TrimSpace(provider), strings.TrimSpace(sourceID), strings.TrimSpace(sourceURL),
+		q.StorageKey, q.FileSizeBytes, q.Codec, q.BitrateKbps, q.SampleRateHz, q.Channels, q.ContentType,
+		q.PeakDBFS, q.ClippingRatio)
+	return err
+}
+
+// SetCanonicalSource points the track's stored audio, quality facts, and
+// primary source at one of its measured sources. Every user of the
+// deduplicated track hears the switched audio.
+func (r *TrackRepository) SetCanonicalSource(ctx context.Context, trackID, sourceID int64) (*Track, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE tracks t
+		SET storage_key = ts.storage_key,
+			file_size_bytes = ts.file_size_bytes,
+			codec = ts.codec,
+			bitrate_kbps = ts.bitrate_kbps,
+			sample_rate_hz = ts.sample_rate_hz,
+			channels = ts.channels,
+			content_type = ts.content_type,
+			source_url = COALESCE(NULLIF(ts.source_url, ''), t.source_url),
+			source_type = ts.provider,
+			updated_at = NOW()
+		FROM track_sources ts
+		WHERE t.id = $1
+		  AND ts.id = $2
+		  AND ts.track_id = t.id
+		  AND NULLIF(BTRIM(ts.storage_key), '') IS NOT NULL
+		RETURNING t.id
+	`, trackID, sourceID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTrackSourceNotFound
+		}
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -80,6 +82,54 @@ func newPostgresTestRepository(t *testing.T) (*TrackRepository, context.Context)
 	return NewTrackRepository(database), context.Background()
 }
 
+func TestConcurrentCreateOrGetReturnsOneTrackAgainstPostgres(t *testing.T) {
+	repo, ctx := newPostgresTestRepository(t)
+
+	const callers = 8
+	type result struct {
+		track   *Track
+		created bool
+		err     error
+	}
+	results := make(chan result, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			track, created, err := repo.CreateTrackFromMetadata(ctx, "Race Artist", "Race Title", "Race Album", 180000)
+			results <- result{track, created, err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var createdCount int
+	var id int64
+	var hash string
+	for r := range results {
+		if r.err != nil {
+			t.Fatalf("CreateTrackFromMetadata: %v", r.err)
+		}
+		if r.created {
+			createdCount++
+		}
+		if id != 0 && r.track.ID != id {
+			t.Fatalf("callers got tracks %d and %d, want one", id, r.track.ID)
+		}
+		id, hash = r.track.ID, r.track.IdentityHash
+	}
+	if createdCount != 1 {
+		t.Fatalf("%d callers created the track, want exactly one", createdCount)
+	}
+
+	err := repo.Create(ctx, &Track{IdentityHash: hash, Title: "Race Title"})
+	var conflict *TrackConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrDuplicateTrack) || conflict.ExistingID != id {
+		t.Fatalf("Create duplicate = %v, want a TrackConflictError naming track %d", err, id)
+	}
+}
+
 func TestUpdateMBMatchPersistsJSONBAndPreservesStickyFieldsAgainstPostgres(t *testing.T) {
 	repo, ctx := newPostgresTestRepository(t)
 
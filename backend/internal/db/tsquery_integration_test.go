@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -122,3 +123,80 @@ func TestLibrarySearchEmptyTSQueryReturnsNoRows(t *testing.T) {
 		t.Fatalf("punctuation library search returned %d rows (total %d); want 0, not the full library", len(rows), total)
 	}
 }
+
+// TestSearchRecordingsRanksTitleMatchesFirstAgainstPostgres checks the weighted
+// rank: a title match outranks a track that only matches on its album, even
+// when the album-only track sorts first by title.
+func TestSearchRecordingsRanksTitleMatchesFirstAgainstPostgres(t *testing.T) {
+	database, ctx := newSearchTestDB(t)
+	repo := NewTrackRepository(database)
+
+	if _, _, err := repo.CreateTrackFromMetadata(ctx, "Miles Davis", "So What", "Kind of Blue", 544000,
+		WithMetadata(json.RawMessage(`{}`)),
+		WithMetadataEnrichment("provider", nil, json.RawMessage(`{}`), "")); err != nil {
+		t.Fatalf("seed album match: %v", err)
+	}
+	if _, _, err := repo.CreateTrackFromMetadata(ctx, "Bob Dylan", "Tangled Up in Blue", "Blood on the Tracks", 341000,
+		WithMetadata(json.RawMessage(`{}`)),
+		WithMetadataEnrichment("provider", nil, json.RawMessage(`{}`), "")); err != nil {
+		t.Fatalf("seed title match: %v", err)
+	}
+
+	tracks, total, err := repo.SearchRecordings(ctx, "blue", 20, 0)
+	if err != nil {
+		t.Fatalf("SearchRecordings: %v", err)
+	}
+	if total != 2 || len(tracks) != 2 {
+		t.Fatalf("SearchRecordings matched %d tracks (total %d); want 2", len(tracks), total)
+	}
+	if tracks[0].Title != "Tangled Up in Blue" {
+		t.Fatalf("first result = %q; want the title match ranked first", tracks[0].Title)
+	}
+}
+
+// TestArtistAndReleaseSearchUseFullTextIndexesAgainstPostgres checks the
+// plans of the artist and release searches reach the GIN indexes on both the
+// track text and the entity names instead of building a tsvector per row.
+func TestArtistAndReleaseSearchUseFullTextIndexesAgainstPostgres(t *testing.T) {
+	database, ctx := newSearchTestDB(t)
+
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer conn.Close()
+	// The test tables are tiny, so a sequential scan would always win; turn
+	// it off to see which indexes the planner can use.
+	if _, err := conn.ExecContext(ctx, "SET enable_seqscan = off"); err != nil {
+		t.Fatalf("disable seqscan: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		query   string
+		indexes []string
+	}{
+		{"artists", searchArtistsQuery, []string{"idx_tracks_artist_fulltext", "idx_artists_name_fulltext"}},
+		{"releases", searchReleasesQuery, []string{"idx_tracks_album_fulltext", "idx_releases_title_fulltext"}},
+	}
+	for _, tc := range cases {
+		rows, err := conn.QueryContext(ctx, "EXPLAIN "+tc.query, buildPrefixTSQuery("blue"), 20, 0)
+		if err != nil {
+			t.Fatalf("explain %s: %v", tc.name, err)
+		}
+		var plan strings.Builder
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				t.Fatalf("scan %s plan: %v", tc.name, err)
+			}
+			plan.WriteString(line + "\n")
+		}
+		rows.Close()
+		for _, index := range tc.indexes {
+			if !strings.Contains(plan.String(), index) {
+				t.Errorf("%s search plan does not use %s:\n%s", tc.name, index, plan.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload statuses.
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusFinalized = "finalized"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+// ErrUploadAlreadyFinalized is returned when another request finalized the
+// upload first.
+var ErrUploadAlreadyFinalized = errors.New("upload already finalized")
+
+// Upload is a file a client PUTs straight to object storage. It stays pending
+// until the client finalizes it, which queues DownloadJobID to import it. A
+// resumable upload is PUT in parts of PartSizeBytes to the storage multipart
+// upload MultipartUploadID.
+type Upload struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	ObjectKey         string
+	Filename          string
+	ContentType       string
+	DeclaredSizeBytes int64
+	MultipartUploadID sql.NullString
+	PartSizeBytes     int64
+	Status            string
+	DownloadJobID     sql.NullString
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	FinalizedAt       sql.NullTime
+}
+
+type UploadRepository struct {
+	db *DB
+}
+
+func NewUploadRepository(db *DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+// Create records a pending upload.
+func (r *UploadRepository) Create(ctx context.Context, upload *Upload) error {
+	upload.Status = UploadStatusPending
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO uploads (id, user_id, object_key, filename, content_type, declared_size_bytes,
+			multipart_upload_id, part_size_bytes, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8::bigint, 0), $9, $10)
+		RETURNING created_at
+	`, upload.ID, upload.UserID, upload.ObjectKey, upload.Filename, upload.ContentType,
+		upload.DeclaredSizeBytes, upload.MultipartUploadID, upload.PartSizeBytes, upload.Status, upload.ExpiresAt).Scan(&upload.CreatedAt)
+}
+
+// Get returns the user's upload. Another user's upload is reported as not
+// found.
+func (r *UploadRepository) Get(ctx context.Context, id, userID uuid.UUID) (*Upload, error) {
+	var u Upload
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, object_key, filename, content_type, declared_size_bytes,
+			multipart_upload_id, COALESCE(part_size_bytes, 0), status, download_job_id, created_at, expires_at, finalized_at
+		FROM uploads
+		WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&u.ID, &u.UserID, &u.ObjectKey, &u.Filename, &u.ContentType, &u.DeclaredSizeBytes,
+		&u.MultipartUploadID, &u.PartSizeBytes, &u.Status, &u.DownloadJobID, &u.CreatedAt, &u.ExpiresAt, &u.FinalizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Finalize claims a pending upload for jobID. Only one of several concurrent
+// finalize requests wins; the others get ErrUploadAlreadyFinalized.
+func (r *UploadRepository) Finalize(ctx context.Context, id, userID uuid.UUID, jobID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE uploads
+		SET status = $3, download_job_id = $4, finalized_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = $5
+	`, id, userID, UploadStatusFinalized, jobID, UploadStatusPending)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUploadAlreadyFinalized
+	}
+	return nil
+}
+
+// Reopen returns an upload finalized for jobID to pending, for when the job
+// could not be queued, so the client can finalize again.
+func (r *UploadRepository) Reopen(ctx context.Context, id uuid.UUID, jobID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE uploads
+		SET status = $3, download_job_id = NULL, finalized_at = NULL
+		WHERE id = $1 AND download_job_id = $2
+	`, id, jobID, UploadStatusPending)
+	return err
+}
@@ -17,8 +17,10 @@ type User struct {
 	Email        string
 	Username     string
 	PasswordHash string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// Role is "admin" or "member"; the column defaults to member.
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type UserRepository struct {
@@ -50,14 +52,14 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, username, password_hash, created_at, updated_at
+		SELECT id, email, username, password_hash, role, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
 
 	user := &User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -71,14 +73,14 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, e
 
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, email, username, password_hash, created_at, updated_at
+		SELECT id, email, username, password_hash, role, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
 
 	user := &User{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -90,6 +92,64 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*User, erro
 	return user, nil
 }
 
+// CountUsers returns how many accounts exist.
+func (r *UserRepository) CountUsers(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&total)
+	return total, err
+}
+
+// ListUsers returns accounts oldest first, with the total count for paging.
+func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]User, int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, email, username, role, created_at, updated_at, COUNT(*) OVER()
+		FROM users
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []User
+	var total int64
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Username, &u.Role, &u.CreatedAt, &u.UpdatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(users) == 0 && offset > 0 {
+		total, err = r.CountUsers(ctx)
+	}
+	return users, total, err
+}
+
+// SetRole changes a user's role and returns the updated user.
+func (r *UserRepository) SetRole(ctx context.Context, id uuid.UUID, role string) (*User, error) {
+	user := &User{}
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE users
+		SET role = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, email, username, password_hash, role, created_at, updated_at
+	`, id, role).Scan(
+		&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func isUniqueViolation(err error) bool {
 	return err != nil && (contains(err.Error(), "unique") || contains(err.Error(), "duplicate"))
 }
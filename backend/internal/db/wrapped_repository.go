@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrWrappedReportNotFound = errors.New("wrapped report not found")
+
+// WrappedReport is a stored year-in-review document. Document is the JSON the
+// API rendered when the report was generated.
+type WrappedReport struct {
+	UserID      uuid.UUID
+	Year        int
+	Document    []byte
+	ShareToken  sql.NullString
+	GeneratedAt time.Time
+}
+
+// WrappedCount is a named play tally (artist or genre).
+type WrappedCount struct {
+	Name      string
+	PlayCount int
+}
+
+// WrappedTrackCount is a track's play tally for the year.
+type WrappedTrackCount struct {
+	TrackID   int64
+	Title     string
+	Artist    string
+	PlayCount int
+}
+
+// WrappedStreak is the longest run of consecutive local days with at least one
+// play. Start and End are the calendar dates, and are zero when Days is zero.
+type WrappedStreak struct {
+	Days  int
+	Start time.Time
+	End   time.Time
+}
+
+// WrappedStats are the aggregates a year-in-review report is built from. All
+// figures cover plays in [Jan 1, Jan 1 next year) in TimeZone.
+type WrappedStats struct {
+	Year          int
+	TimeZone      string
+	TotalPlays    int
+	TotalMinutes  int
+	TopArtists    []WrappedCount
+	TopTracks     []WrappedTrackCount
+	TopGenres     []WrappedCount
+	LongestStreak WrappedStreak
+	// NewTracks and NewArtists were first played by the user during the year.
+	NewTracks   int
+	NewArtists  int
+	LibraryAdds int
+}
+
+// WrappedRepository computes year-in-review aggregates from play history and
+// the library, and stores the generated reports.
+type WrappedRepository struct {
+	db *DB
+}
+
+func NewWrappedRepository(db *DB) *WrappedRepository {
+	return &WrappedRepository{db: db}
+}
+
+// wrappedYearBounds returns the half-open range covering year in loc.
+func wrappedYearBounds(year int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// ComputeStats aggregates the user's plays for year, with the year and its
+// days bounded by local midnight in loc (UTC when nil). limit caps each top
+// list.
+func (r *WrappedRepository) ComputeStats(ctx context.Context, userID uuid.UUID, year, limit int, loc *time.Location) (*WrappedStats, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > 50 {
+		limit = 50
+	}
+	start, end := wrappedYearBounds(year, loc)
+	stats := &WrappedStats{Year: year, TimeZone: loc.String()}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(COALESCE(t.duration_ms, 0)), 0) / 60000
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2 AND pe.played_at < $3
+	`, userID, start, end).Scan(&stats.TotalPlays, &stats.TotalMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.TopArtists, err = r.topCounts(ctx, `
+		SELECT t.artist, COUNT(*) AS play_count
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2 AND pe.played_at < $3
+			AND COALESCE(BTRIM(t.artist), '') <> ''
+		GROUP BY t.artist
+		ORDER BY play_count DESC, t.artist ASC
+		LIMIT $4
+	`, userID, start, end, limit); err != nil {
+		return nil, err
+	}
+
+	if stats.TopGenres, err = r.topCounts(ctx, `
+		SELECT t.genre, COUNT(*) AS play_count
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2 AND pe.played_at < $3
+			AND COALESCE(BTRIM(t.genre), '') <> ''
+		GROUP BY t.genre
+		ORDER BY play_count DESC, t.genre ASC
+		LIMIT $4
+	`, userID, start, end, limit); err != nil {
+		return nil, err
+	}
+
+	if stats.TopTracks, err = r.topTracks(ctx, userID, start, end, limit); err != nil {
+		return nil, err
+	}
+
+	days, err := r.playDays(ctx, userID, start, end, loc)
+	if err != nil {
+		return nil, err
+	}
+	stats.LongestStreak = longestStreak(days)
+
+	// A track or artist is new for the year when the user's first play of it
+	// falls inside the year.
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM (
+				SELECT MIN(played_at) AS first_played
+				FROM play_events
+				WHERE user_id = $1
+				GROUP BY track_id
+			) ft WHERE ft.first_played >= $2 AND ft.first_played < $3),
+			(SELECT COUNT(*) FROM (
+				SELECT MIN(pe.played_at) AS first_played
+				FROM play_events pe
+				JOIN tracks t ON t.id = pe.track_id
+				WHERE pe.user_id = $1 AND COALESCE(BTRIM(t.artist), '') <> ''
+				GROUP BY LOWER(BTRIM(t.artist))
+			) fa WHERE fa.first_played >= $2 AND fa.first_played < $3),
+			(SELECT COUNT(*) FROM user_library
+				WHERE user_id = $1 AND added_at >= $2 AND added_at < $3)
+	`, userID, start, end).Scan(&stats.NewTracks, &stats.NewArtists, &stats.LibraryAdds)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (r *WrappedRepository) topCounts(ctx context.Context, query string, args ...interface{}) ([]WrappedCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []WrappedCount
+	for rows.Next() {
+		var c WrappedCount
+		if err := rows.Scan(&c.Name, &c.PlayCount); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *WrappedRepository) topTracks(ctx context.Context, userID uuid.UUID, start, end time.Time, limit int) ([]WrappedTrackCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.title, COALESCE(t.artist, ''), COUNT(*) AS play_count
+		FROM play_events pe
+		JOIN tracks t ON t.id = pe.track_id
+		WHERE pe.user_id = $1 AND pe.played_at >= $2 AND pe.played_at < $3
+		GROUP BY t.id, t.title, t.artist
+		ORDER BY play_count DESC, t.id ASC
+		LIMIT $4
+	`, userID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tracks []WrappedTrackCount
+	for rows.Next() {
+		var t WrappedTrackCount
+		if err := rows.Scan(&t.TrackID, &t.Title, &t.Artist, &t.PlayCount); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// playDays returns the distinct calendar dates in loc with plays, ascending.
+// The dates come back as UTC midnights, which longestStreak compares.
+func (r *WrappedRepository) playDays(ctx context.Context, userID uuid.UUID, start, end time.Time, loc *time.Location) ([]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT (played_at AT TIME ZONE $4)::date AS play_day
+		FROM play_events
+		WHERE user_id = $1 AND played_at >= $2 AND played_at < $3
+		ORDER BY play_day
+	`, userID, start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// longestStreak finds the longest run of consecutive dates in days, which must
+// be distinct and ascending. Ties keep the earliest run.
+func longestStreak(days []time.Time) WrappedStreak {
+	var best WrappedStreak
+	runStart := 0
+	for i := range days {
+		if i > 0 && !sameUTCDate(days[i-1].AddDate(0, 0, 1), days[i]) {
+			runStart = i
+		}
+		if length := i - runStart + 1; length > best.Days {
+			best = WrappedStreak{Days: length, Start: days[runStart], End: days[i]}
+		}
+	}
+	return best
+}
+
+func sameUTCDate(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// SaveReport stores a freshly generated document for the year, keeping any
+// existing share token so shared links follow the latest version.
+func (r *WrappedRepository) SaveReport(ctx context.Context, userID uuid.UUID, year int, document []byte) (*WrappedReport, error) {
+	report := &WrappedReport{UserID: userID, Year: year}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO wrapped_reports (user_id, year, document)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, year)
+		DO UPDATE SET document = EXCLUDED.document, generated_at = NOW()
+		RETURNING document, share_token, generated_at
+	`, userID, year, document).Scan(&report.Document, &report.ShareToken, &report.GeneratedAt)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetReport returns the user's stored report for year.
+func (r *WrappedRepository) GetReport(ctx context.Context, userID uuid.UUID, year int) (*WrappedReport, error) {
+	report := &WrappedReport{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, year, document, share_token, generated_at
+		FROM wrapped_reports
+		WHERE user_id = $1 AND year = $2
+	`, userID, year).Scan(&report.UserID, &report.Year, &report.Document, &report.ShareToken, &report.GeneratedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrWrappedReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetReportByShareToken returns the report published under token.
+func (r *WrappedRepository) GetReportByShareToken(ctx context.Context, token string) (*WrappedReport, error) {
+	report := &WrappedReport{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, year, document, share_token, generated_at
+		FROM wrapped_reports
+		WHERE share_token = $1
+	`, token).Scan(&report.UserID, &report.Year, &report.Document, &report.ShareToken, &report.GeneratedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrWrappedReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// SetShareToken publishes the report under token. An empty token revokes the
+// share.
+func (r *WrappedRepository) SetShareToken(ctx context.Context, userID uuid.UUID, year int, token string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE wrapped_reports SET share_token = NULLIF($3, '')
+		WHERE user_id = $1 AND year = $2
+	`, userID, year, token)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWrappedReportNotFound
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLongestStreakPicksLongestConsecutiveRun(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	days := []time.Time{
+		day(time.January, 1), day(time.January, 2),
+		day(time.January, 30), day(time.January, 31), day(time.February, 1),
+		day(time.March, 5), day(time.March, 6), day(time.March, 7),
+	}
+
+	got := longestStreak(days)
+	if got.Days != 3 {
+		t.Fatalf("days = %d, want 3", got.Days)
+	}
+	// Ties keep the earliest run, and runs cross month boundaries.
+	if !got.Start.Equal(day(time.January, 30)) || !got.End.Equal(day(time.February, 1)) {
+		t.Fatalf("streak = %s..%s, want 2026-01-30..2026-02-01", got.Start, got.End)
+	}
+}
+
+func TestLongestStreakEmpty(t *testing.T) {
+	if got := longestStreak(nil); got.Days != 0 || !got.Start.IsZero() {
+		t.Fatalf("streak = %#v, want zero", got)
+	}
+}
@@ -192,7 +192,7 @@ func (s *AssistService) Assist(ctx context.Context, prompt string, limit int) As
 
 	// 1. Grounded direct-URL path: the URL comes from the user's own prompt.
 	if raw := findFirstURL(prompt); raw != "" {
-		if candidate, err := s.resolver.Resolve(raw); err == nil {
+		if candidate, err := s.resolver.ResolveContext(ctx, raw); err == nil {
 			return s.directURLResponse(candidate)
 		}
 		// A URL-looking token that the resolver rejects (unsupported host, bad
@@ -19,6 +19,7 @@ import (
 	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/musicbrainz"
+	"github.com/openmusicplayer/backend/internal/validators"
 )
 
 const (
@@ -581,6 +582,15 @@ func NewHandlersWithAssistAndSelectionStore(service *Service, assist *AssistServ
 	}
 }
 
+// SetShortLinks expands SoundCloud short links pasted into resolve-url and
+// assist through resolver. Both share one URLResolver, so setting it here
+// covers both.
+func (h *Handlers) SetShortLinks(resolver *validators.ShortLinkResolver) {
+	if h.resolver != nil {
+		h.resolver.registry = validators.DefaultRegistryWithShortLinks(resolver)
+	}
+}
+
 func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
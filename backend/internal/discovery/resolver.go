@@ -1,6 +1,7 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -25,6 +26,7 @@ const (
 	ErrResolveURLRequired    = "RESOLVE_URL_REQUIRED"
 	ErrResolveInvalidURL     = "RESOLVE_INVALID_URL"
 	ErrResolveUnsupportedURL = "RESOLVE_UNSUPPORTED_URL"
+	ErrResolveShortLink      = "RESOLVE_SHORT_LINK_UNRESOLVED"
 )
 
 // ResolveError is a typed resolver failure carrying a stable machine code so the
@@ -45,9 +47,10 @@ func newResolveError(code, message string) *ResolveError {
 // URLResolver turns a single user-pasted source URL into a grounded discovery
 // candidate. It reuses the existing URL validators for provider detection and
 // download.ValidateUserFacingURL for scheme/safety gating, so it never invents a
-// source the rest of the pipeline could not already accept. It performs no
-// network calls and holds no queue or download dependency: by construction it
-// cannot start a download or mutate the queue.
+// source the rest of the pipeline could not already accept. Its only network
+// calls are short-link expansions, when the registry is configured for them. It
+// holds no queue or download dependency: by construction it cannot start a
+// download or mutate the queue.
 type URLResolver struct {
 	registry *validators.Registry
 }
@@ -75,6 +78,11 @@ func (r *URLResolver) validatorRegistry() *validators.Registry {
 // The candidate is queueable through the existing POST /api/v1/queue/items
 // contract but is never queued here. Failures are typed *ResolveError values.
 func (r *URLResolver) Resolve(rawURL string) (Candidate, error) {
+	return r.ResolveContext(context.Background(), rawURL)
+}
+
+// ResolveContext is Resolve with a context bounding short-link expansion.
+func (r *URLResolver) ResolveContext(ctx context.Context, rawURL string) (Candidate, error) {
 	trimmed := strings.TrimSpace(rawURL)
 	if trimmed == "" {
 		return Candidate{}, newResolveError(ErrResolveURLRequired, "url is required")
@@ -86,7 +94,14 @@ func (r *URLResolver) Resolve(rawURL string) (Candidate, error) {
 		return Candidate{}, newResolveError(ErrResolveInvalidURL, "url must be an absolute http(s) URL")
 	}
 
-	result := r.validatorRegistry().Validate(trimmed)
+	// Expand short links first so the candidate carries the canonical URL and
+	// dedups against the same track pasted in full.
+	expanded, err := r.validatorRegistry().Expand(ctx, trimmed)
+	if err != nil {
+		return Candidate{}, newResolveError(ErrResolveShortLink, "short link could not be resolved to a track or playlist")
+	}
+
+	result := r.validatorRegistry().Validate(expanded)
 	if result.SourceType == validators.SourceUnknown {
 		return Candidate{}, newResolveError(ErrResolveUnsupportedURL, "url is not a supported source")
 	}
@@ -191,7 +206,7 @@ func (h *Handlers) ResolveURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	candidate, err := h.resolver.Resolve(req.URL)
+	candidate, err := h.resolver.ResolveContext(r.Context(), req.URL)
 	if err != nil {
 		var resolveErr *ResolveError
 		if errors.As(err, &resolveErr) {
@@ -9,8 +9,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/validators"
 )
 
 func TestURLResolverNormalizesSupportedURLs(t *testing.T) {
@@ -156,6 +158,41 @@ func TestURLResolverRejectsBadURLsWithTypedErrors(t *testing.T) {
 	}
 }
 
+type shortLinkTransport struct{ location string }
+
+func (rt shortLinkTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+	if req.URL.Host == "on.soundcloud.com" {
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", rt.location)
+	}
+	return resp, nil
+}
+
+func TestURLResolverExpandsShortLinksToTheFullCandidate(t *testing.T) {
+	shortLinks := validators.NewShortLinkResolver(&http.Client{Transport: shortLinkTransport{"https://soundcloud.com/artist-name/track-name?si=share"}}, time.Hour)
+	resolver := NewURLResolver(validators.DefaultRegistryWithShortLinks(shortLinks))
+
+	short, err := resolver.ResolveContext(context.Background(), "https://on.soundcloud.com/AbCdE12345")
+	if err != nil {
+		t.Fatalf("ResolveContext: %v", err)
+	}
+	full, err := resolver.Resolve("https://soundcloud.com/artist-name/track-name")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if short.CandidateID != full.CandidateID || short.SourceURL != full.SourceURL || short.Title != "track name" {
+		t.Fatalf("short = %+v, want it to match the full link %+v", short, full)
+	}
+
+	failing := NewURLResolver(validators.DefaultRegistryWithShortLinks(validators.NewShortLinkResolver(&http.Client{Transport: shortLinkTransport{"https://example.com/elsewhere"}}, time.Hour)))
+	_, err = failing.ResolveContext(context.Background(), "https://on.soundcloud.com/AbCdE12345")
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || resolveErr.Code != ErrResolveShortLink {
+		t.Fatalf("err = %v, want %s", err, ErrResolveShortLink)
+	}
+}
+
 func TestZeroValueURLResolverFallsBackToDefaultRegistry(t *testing.T) {
 	// A var-declared resolver has a nil registry; Resolve must not panic and must
 	// behave like a default resolver.
@@ -0,0 +1,68 @@
+package download
+
+// destinationMetadataKey is the job metadata key holding a Destination.
+const destinationMetadataKey = "destination"
+
+// Destination overrides where one download's track ends up. Unset fields
+// fall back to the user's download settings.
+type Destination struct {
+	AddToLibrary *bool  `json:"add_to_library,omitempty"`
+	PlaylistID   *int64 `json:"playlist_id,omitempty"`
+	QueueNext    *bool  `json:"queue_next,omitempty"`
+}
+
+// IsZero reports whether d overrides nothing.
+func (d Destination) IsZero() bool {
+	return d.AddToLibrary == nil && d.PlaylistID == nil && d.QueueNext == nil
+}
+
+// WithDestination returns metadata with d recorded in it. metadata is not
+// modified; a zero d returns it unchanged.
+func WithDestination(metadata map[string]interface{}, d Destination) map[string]interface{} {
+	if d.IsZero() {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	value := map[string]interface{}{}
+	if d.AddToLibrary != nil {
+		value["add_to_library"] = *d.AddToLibrary
+	}
+	if d.PlaylistID != nil {
+		value["playlist_id"] = *d.PlaylistID
+	}
+	if d.QueueNext != nil {
+		value["queue_next"] = *d.QueueNext
+	}
+	out[destinationMetadataKey] = value
+	return out
+}
+
+// DestinationFromMetadata reads the Destination recorded by WithDestination.
+// Metadata that went through JSON holds numbers as float64, so both forms
+// are accepted.
+func DestinationFromMetadata(metadata map[string]interface{}) Destination {
+	raw, ok := metadata[destinationMetadataKey].(map[string]interface{})
+	if !ok {
+		return Destination{}
+	}
+	var d Destination
+	if v, ok := raw["add_to_library"].(bool); ok {
+		d.AddToLibrary = &v
+	}
+	switch v := raw["playlist_id"].(type) {
+	case int64:
+		d.PlaylistID = &v
+	case float64:
+		if v > 0 && v == float64(int64(v)) {
+			id := int64(v)
+			d.PlaylistID = &id
+		}
+	}
+	if v, ok := raw["queue_next"].(bool); ok {
+		d.QueueNext = &v
+	}
+	return d
+}
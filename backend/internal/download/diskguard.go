@@ -0,0 +1,87 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"syscall"
+)
+
+// ErrLowDiskSpace reports that the download temp volume is below its
+// configured free-space threshold and workers are not taking new jobs.
+var ErrLowDiskSpace = errors.New("download temp volume is low on disk space")
+
+// DiskObserver receives the free space seen by each disk guard check and
+// whether the worker pool is paused as a result.
+type DiskObserver interface {
+	ObserveDownloadDiskSpace(freeBytes uint64, paused bool)
+}
+
+// DiskGuard pauses dequeuing while the volume holding download temp files has
+// less than a minimum of free space. Jobs already running are left alone; the
+// pool resumes on its own once space is freed.
+type DiskGuard struct {
+	path         string
+	minFreeBytes uint64
+	observer     DiskObserver
+	freeBytes    func(path string) (uint64, error)
+
+	mu     sync.Mutex
+	paused bool
+}
+
+// NewDiskGuard creates a guard for the volume containing path. A zero
+// minFreeBytes disables pausing but still reports free space.
+func NewDiskGuard(path string, minFreeBytes uint64, observer DiskObserver) *DiskGuard {
+	return &DiskGuard{
+		path:         path,
+		minFreeBytes: minFreeBytes,
+		observer:     observer,
+		freeBytes:    statfsFreeBytes,
+	}
+}
+
+// Allow checks free space and reports whether a worker may take a new job.
+// A failed check allows work so a stat error never stalls downloads.
+func (g *DiskGuard) Allow() bool {
+	free, err := g.freeBytes(g.path)
+	if err != nil {
+		log.Printf("Warning: disk space check for %s failed: %v", g.path, err)
+		return true
+	}
+	paused := g.minFreeBytes > 0 && free < g.minFreeBytes
+
+	g.mu.Lock()
+	changed := paused != g.paused
+	g.paused = paused
+	g.mu.Unlock()
+
+	if changed && paused {
+		log.Printf("Pausing download workers: %d MB free in %s, need %d MB", free>>20, g.path, g.minFreeBytes>>20)
+	} else if changed {
+		log.Printf("Resuming download workers: %d MB free in %s", free>>20, g.path)
+	}
+	if g.observer != nil {
+		g.observer.ObserveDownloadDiskSpace(free, paused)
+	}
+	return !paused
+}
+
+// Check returns ErrLowDiskSpace while the guard is holding workers paused.
+func (g *DiskGuard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return fmt.Errorf("%w: %s", ErrLowDiskSpace, g.path)
+	}
+	return nil
+}
+
+func statfsFreeBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,65 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingDiskObserver struct {
+	free   uint64
+	paused bool
+	calls  int
+}
+
+func (o *recordingDiskObserver) ObserveDownloadDiskSpace(freeBytes uint64, paused bool) {
+	o.free = freeBytes
+	o.paused = paused
+	o.calls++
+}
+
+func TestDiskGuardPausesBelowThresholdAndResumes(t *testing.T) {
+	observer := &recordingDiskObserver{}
+	guard := NewDiskGuard("/tmp", 100, observer)
+	free := uint64(50)
+	guard.freeBytes = func(string) (uint64, error) { return free, nil }
+
+	if guard.Allow() {
+		t.Fatal("guard allowed work below the threshold")
+	}
+	if err := guard.Check(); !errors.Is(err, ErrLowDiskSpace) {
+		t.Fatalf("Check() = %v, want ErrLowDiskSpace", err)
+	}
+	if !observer.paused || observer.free != 50 {
+		t.Fatalf("observer = %+v, want paused with 50 bytes free", observer)
+	}
+
+	free = 200
+	if !guard.Allow() {
+		t.Fatal("guard stayed paused after space was freed")
+	}
+	if err := guard.Check(); err != nil {
+		t.Fatalf("Check() after resume = %v", err)
+	}
+	if observer.paused || observer.calls != 2 {
+		t.Fatalf("observer = %+v, want resumed after two checks", observer)
+	}
+}
+
+func TestDiskGuardAllowsWorkWhenCheckFails(t *testing.T) {
+	guard := NewDiskGuard("/missing", 100, nil)
+	guard.freeBytes = func(string) (uint64, error) { return 0, errors.New("statfs failed") }
+	if !guard.Allow() {
+		t.Fatal("a failed disk check must not stall downloads")
+	}
+}
+
+func TestWorkerPool_PausedDiskGuardSkipsDequeue(t *testing.T) {
+	guard := NewDiskGuard("/tmp", 100, nil)
+	guard.freeBytes = func(string) (uint64, error) { return 0, nil }
+	// A nil queue panics on Dequeue, so returning proves the worker held off.
+	pool := NewWorkerPool(nil, nil, &WorkerPoolConfig{DiskGuard: guard})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool.processNextJob(ctx, 0)
+}
@@ -1,6 +1,7 @@
 package download
 
 import (
+	"context"
 	"time"
 )
 
@@ -12,9 +13,25 @@ const (
 	StatusUploading   = "uploading"
 	StatusComplete    = "complete"
 	StatusFailed      = "failed"
+	StatusCancelled   = "cancelled"
 )
 
-// DownloadJob represents a download task in the queue
+// Job priorities. Workers drain every interactive job before taking a batch
+// one, so a download a user is waiting on is not stuck behind a playlist
+// import or library re-download.
+const (
+	PriorityInteractive = "interactive"
+	PriorityBatch       = "batch"
+)
+
+// UploadURLPrefix marks a job importing a file the user uploaded to object
+// storage; the rest of the URL is the uploaded object's key.
+const UploadURLPrefix = "upload://"
+
+// DownloadJob represents a download task in the queue. A playlist download
+// is a parent job listing one child job per track in ChildJobIDs; the parent
+// is never queued itself, and its status, progress, and item counts follow
+// its children. Each child names its parent in ParentJobID.
 type DownloadJob struct {
 	ID                   string                 `json:"id"`
 	UserID               string                 `json:"user_id"`
@@ -24,6 +41,7 @@ type DownloadJob struct {
 	Progress             int                    `json:"progress"`
 	Error                string                 `json:"error,omitempty"`
 	RetryCount           int                    `json:"retry_count"`
+	Priority             string                 `json:"priority,omitempty"`
 	MBRecordingID        *string                `json:"mb_recording_id,omitempty"`
 	TrackID              *int64                 `json:"track_id,omitempty"`
 	CandidateID          string                 `json:"candidate_id,omitempty"`
@@ -39,6 +57,11 @@ type DownloadJob struct {
 	PlaylistImportItemID int64                  `json:"playlist_import_item_id,omitempty"`
 	PlaylistID           int64                  `json:"playlist_id,omitempty"`
 	PlaylistPosition     int                    `json:"playlist_position,omitempty"`
+	RequestID            string                 `json:"request_id,omitempty"`
+	ParentJobID          string                 `json:"parent_job_id,omitempty"`
+	ChildJobIDs          []string               `json:"child_job_ids,omitempty"`
+	CompletedItems       int                    `json:"completed_items,omitempty"`
+	FailedItems          int                    `json:"failed_items,omitempty"`
 	CreatedAt            time.Time              `json:"created_at"`
 	UpdatedAt            time.Time              `json:"updated_at"`
 	StartedAt            *time.Time             `json:"started_at,omitempty"`
@@ -47,10 +70,37 @@ type DownloadJob struct {
 
 // IsTerminal returns true if the job is in a terminal state
 func (j *DownloadJob) IsTerminal() bool {
-	return j.Status == StatusComplete || j.Status == StatusFailed
+	return j.Status == StatusComplete || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// IsParent returns true if the job is a playlist download made of child jobs
+func (j *DownloadJob) IsParent() bool {
+	return len(j.ChildJobIDs) > 0
 }
 
 // CanRetry returns true if the job can be retried
 func (j *DownloadJob) CanRetry(maxRetries int) bool {
-	return j.Status == StatusFailed && j.RetryCount < maxRetries
+	return (j.Status == StatusFailed || j.Status == StatusCancelled) && j.RetryCount < maxRetries
+}
+
+// ValidPriority reports whether priority is a known job priority.
+func ValidPriority(priority string) bool {
+	return priority == PriorityInteractive || priority == PriorityBatch
+}
+
+type priorityContextKey struct{}
+
+// WithPriority sets the priority of jobs enqueued with ctx that do not set
+// their own, so callers several layers above the queue can mark a batch.
+func WithPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or
+// PriorityInteractive when none was set.
+func PriorityFromContext(ctx context.Context) string {
+	if priority, ok := ctx.Value(priorityContextKey{}).(string); ok && ValidPriority(priority) {
+		return priority
+	}
+	return PriorityInteractive
 }
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderLimits caps how many jobs from each source provider run at once,
+// independent of the overall worker count, so a large pool cannot exceed a
+// provider's rate tolerance. Providers without a limit are uncapped. Limits
+// can be changed while workers are running.
+type ProviderLimits struct {
+	mu       sync.Mutex
+	limits   map[string]int
+	inFlight map[string]int
+}
+
+// ProviderLimitStatus is one provider's configured cap and current load.
+type ProviderLimitStatus struct {
+	Provider string
+	Limit    int
+	InFlight int
+}
+
+// NewProviderLimits creates limits from provider -> max concurrent jobs.
+// Non-positive values are ignored.
+func NewProviderLimits(limits map[string]int) *ProviderLimits {
+	l := &ProviderLimits{limits: make(map[string]int), inFlight: make(map[string]int)}
+	for provider, limit := range limits {
+		l.SetLimit(provider, limit)
+	}
+	return l
+}
+
+// TryAcquire claims a slot for provider, reporting false when it is at its
+// limit. Every successful call must be paired with Release.
+func (l *ProviderLimits) TryAcquire(provider string) bool {
+	provider = normalizeProvider(provider)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit, ok := l.limits[provider]; ok && l.inFlight[provider] >= limit {
+		return false
+	}
+	l.inFlight[provider]++
+	return true
+}
+
+// Release frees a slot claimed by TryAcquire.
+func (l *ProviderLimits) Release(provider string) {
+	provider = normalizeProvider(provider)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[provider] <= 1 {
+		delete(l.inFlight, provider)
+		return
+	}
+	l.inFlight[provider]--
+}
+
+// SetLimit caps provider at limit concurrent jobs; a non-positive limit
+// removes the cap. Lowering a limit does not interrupt running jobs.
+func (l *ProviderLimits) SetLimit(provider string, limit int) {
+	provider = normalizeProvider(provider)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit <= 0 {
+		delete(l.limits, provider)
+		return
+	}
+	l.limits[provider] = limit
+}
+
+// Snapshot lists every provider that has a limit or running jobs, sorted by
+// name. Limit is zero for uncapped providers.
+func (l *ProviderLimits) Snapshot() []ProviderLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seen := make(map[string]bool, len(l.limits)+len(l.inFlight))
+	statuses := make([]ProviderLimitStatus, 0, len(seen))
+	for _, providers := range []map[string]int{l.limits, l.inFlight} {
+		for provider := range providers {
+			if seen[provider] {
+				continue
+			}
+			seen[provider] = true
+			statuses = append(statuses, ProviderLimitStatus{
+				Provider: provider,
+				Limit:    l.limits[provider],
+				InFlight: l.inFlight[provider],
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Provider < statuses[j].Provider })
+	return statuses
+}
+
+func normalizeProvider(provider string) string {
+	return strings.ToLower(strings.TrimSpace(provider))
+}
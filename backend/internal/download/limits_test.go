@@ -0,0 +1,106 @@
+package download
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProviderLimitsCapConcurrencyPerProvider(t *testing.T) {
+	limits := NewProviderLimits(map[string]int{"youtube": 2, "SoundCloud": 1, "bandcamp": 0})
+
+	if !limits.TryAcquire("youtube") || !limits.TryAcquire("YouTube") {
+		t.Fatal("youtube should allow two concurrent jobs")
+	}
+	if limits.TryAcquire("youtube") {
+		t.Fatal("third youtube job exceeded its limit")
+	}
+	if !limits.TryAcquire("soundcloud") || limits.TryAcquire("soundcloud") {
+		t.Fatal("soundcloud should allow exactly one concurrent job")
+	}
+	for i := 0; i < 5; i++ {
+		if !limits.TryAcquire("bandcamp") {
+			t.Fatal("providers without a limit must stay uncapped")
+		}
+	}
+
+	limits.Release("youtube")
+	if !limits.TryAcquire("youtube") {
+		t.Fatal("released youtube slot was not reusable")
+	}
+}
+
+func TestProviderLimitsAdjustAtRuntime(t *testing.T) {
+	limits := NewProviderLimits(map[string]int{"youtube": 1})
+	if !limits.TryAcquire("youtube") {
+		t.Fatal("first acquire failed")
+	}
+
+	limits.SetLimit("youtube", 3)
+	if !limits.TryAcquire("youtube") {
+		t.Fatal("raised limit did not take effect")
+	}
+	limits.SetLimit("soundcloud", 1)
+	limits.SetLimit("youtube", 0)
+	if !limits.TryAcquire("youtube") {
+		t.Fatal("removed limit still capped youtube")
+	}
+
+	want := []ProviderLimitStatus{
+		{Provider: "soundcloud", Limit: 1, InFlight: 0},
+		{Provider: "youtube", Limit: 0, InFlight: 3},
+	}
+	if got := limits.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWorkerPool_ProviderLimitDefersExcessJobs(t *testing.T) {
+	queue := newTestQueue(t)
+	ctx := context.Background()
+
+	var running, peak, processed int32
+	processor := func(ctx context.Context, job *DownloadJob, progress func(int)) error {
+		if job.SourceType == "youtube" {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				seen := atomic.LoadInt32(&peak)
+				if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+					break
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}
+		atomic.AddInt32(&processed, 1)
+		return nil
+	}
+	pool := NewWorkerPool(queue, processor, &WorkerPoolConfig{
+		WorkerCount: workerCountPtr(3),
+		JobTimeout:  time.Minute,
+		Limits:      NewProviderLimits(map[string]int{"youtube": 1}),
+	})
+
+	for _, sourceType := range []string{"youtube", "youtube", "soundcloud"} {
+		if _, err := queue.Enqueue(ctx, "test-user", "https://example.com/"+sourceType, sourceType, nil); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	pool.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&processed) < 3 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	pool.Stop(stopCtx)
+
+	if got := atomic.LoadInt32(&processed); got != 3 {
+		t.Fatalf("processed = %d, want all 3 jobs", got)
+	}
+	if got := atomic.LoadInt32(&peak); got != 1 {
+		t.Fatalf("peak concurrent youtube jobs = %d, want 1", got)
+	}
+}
@@ -0,0 +1,73 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Terminal download outcomes recorded per provider.
+const (
+	OutcomeSuccess       = "success"
+	OutcomeAgeRestricted = "age_restricted"
+	OutcomeUnavailable   = "unavailable"
+	OutcomeNetwork       = "network"
+	OutcomeOther         = "other"
+)
+
+// OutcomeObserver receives each job's terminal outcome: once on completion, or
+// once when it fails without another retry.
+type OutcomeObserver interface {
+	ObserveDownloadOutcome(ctx context.Context, job *DownloadJob, outcome string)
+}
+
+var (
+	ageRestrictedMarkers = []string{
+		"age-restricted", "age restricted", "confirm your age", "inappropriate for some users",
+	}
+	unavailableMarkers = []string{
+		"video unavailable", "not available", "private video", "has been removed", "been terminated",
+		"does not exist", "http error 404", "http error 410", "unsupported url",
+		"blocked it in your country", "geo restricted", "geo-restricted",
+	}
+	networkMarkers = []string{
+		"timed out", "timeout", "connection reset", "connection refused", "network is unreachable",
+		"temporary failure in name resolution", "no such host", "http error 5", "tls handshake",
+		"ssl:", "unexpected eof", "remote end closed connection",
+	}
+)
+
+// ClassifyFailure maps a processor error, usually carrying yt-dlp output, to a
+// coarse outcome so extractor breakage shows up per provider.
+func ClassifyFailure(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return OutcomeNetwork
+	}
+	text := strings.ToLower(err.Error())
+	switch {
+	case containsAny(text, ageRestrictedMarkers):
+		return OutcomeAgeRestricted
+	case containsAny(text, unavailableMarkers):
+		return OutcomeUnavailable
+	case containsAny(text, networkMarkers):
+		return OutcomeNetwork
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return OutcomeNetwork
+	}
+	return OutcomeOther
+}
+
+func containsAny(text string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeSuccess},
+		{errors.New("yt-dlp failed: exit status 1: ERROR: [youtube] abc: Sign in to confirm your age. This video may be inappropriate for some users."), OutcomeAgeRestricted},
+		{errors.New("yt-dlp failed: exit status 1: ERROR: [youtube] abc: Video unavailable"), OutcomeUnavailable},
+		{errors.New("yt-dlp failed: exit status 1: ERROR: [soundcloud] 123: Unable to download JSON metadata: HTTP Error 404: Not Found"), OutcomeUnavailable},
+		{errors.New("yt-dlp failed: exit status 1: ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>"), OutcomeNetwork},
+		{fmt.Errorf("download: %w", context.DeadlineExceeded), OutcomeNetwork},
+		{errors.New("yt-dlp is not installed"), OutcomeOther},
+	}
+	for _, tc := range cases {
+		if got := ClassifyFailure(tc.err); got != tc.want {
+			t.Errorf("ClassifyFailure(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
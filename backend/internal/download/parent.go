@@ -0,0 +1,239 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+)
+
+// maxParentRefreshAttempts bounds how often a parent refresh retries after
+// another update to the same parent raced it.
+const maxParentRefreshAttempts = 5
+
+type parentJobContextKey struct{}
+
+// WithParentJob makes jobs enqueued with ctx items of the parent job parentID.
+func WithParentJob(ctx context.Context, parentID string) context.Context {
+	return context.WithValue(ctx, parentJobContextKey{}, parentID)
+}
+
+func parentJobFromContext(ctx context.Context) string {
+	parentID, _ := ctx.Value(parentJobContextKey{}).(string)
+	return parentID
+}
+
+// CreateParentJob stores a playlist download's parent job. It is not queued;
+// AttachChildJobs adds its items once they are enqueued.
+func (q *Queue) CreateParentJob(ctx context.Context, userID, url, sourceType, title string) (*DownloadJob, error) {
+	now := time.Now()
+	job := &DownloadJob{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		URL:        url,
+		SourceType: sourceType,
+		Title:      title,
+		Status:     StatusQueued,
+		Priority:   PriorityFromContext(ctx),
+		RequestID:  apperrors.GetRequestID(ctx),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := q.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// AttachChildJobs records a parent job's items and brings its status up to
+// date with them.
+func (q *Queue) AttachChildJobs(ctx context.Context, parentID string, childIDs []string) (*DownloadJob, error) {
+	return q.updateParent(ctx, parentID, func(parent *DownloadJob) {
+		parent.ChildJobIDs = append([]string(nil), childIDs...)
+	})
+}
+
+// refreshParentOf re-aggregates the parent of job after the job changed. A
+// failure only leaves the parent stale until its next item changes, so it is
+// logged rather than failing the child's update.
+func (q *Queue) refreshParentOf(ctx context.Context, job *DownloadJob) {
+	if job.ParentJobID == "" {
+		return
+	}
+	if _, err := q.updateParent(ctx, job.ParentJobID, nil); err != nil {
+		log.Printf("Failed to refresh parent download job %s of %s: %v", job.ParentJobID, job.ID, err)
+	}
+}
+
+// updateParent applies change to the parent job, then recomputes it from its
+// children. The write is retried if another item updated the parent
+// meanwhile, so concurrent workers cannot leave it behind its children.
+func (q *Queue) updateParent(ctx context.Context, parentID string, change func(*DownloadJob)) (*DownloadJob, error) {
+	key := keyJobStatus + parentID
+	var parent *DownloadJob
+	for attempt := 0; attempt < maxParentRefreshAttempts; attempt++ {
+		err := q.client.Watch(ctx, func(tx *redis.Tx) error {
+			loaded, err := q.GetJob(ctx, parentID)
+			if err != nil {
+				return err
+			}
+			if change != nil {
+				change(loaded)
+			}
+			children := make([]*DownloadJob, 0, len(loaded.ChildJobIDs))
+			for _, childID := range loaded.ChildJobIDs {
+				child, err := q.GetJob(ctx, childID)
+				if errors.Is(err, ErrJobNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				children = append(children, child)
+			}
+			aggregateParent(loaded, children, time.Now())
+			data, err := json.Marshal(loaded)
+			if err != nil {
+				return fmt.Errorf("failed to marshal job: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, 0)
+				return nil
+			})
+			parent = loaded
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parent, q.publishProgress(ctx, parent)
+	}
+	return nil, fmt.Errorf("parent download job %s kept changing during refresh", parentID)
+}
+
+// aggregateParent derives a parent job's status, progress, and item counts
+// from its children. An item missing from children is counted as failed. A
+// finished item counts as fully progressed whatever its outcome. Once every
+// item has finished the parent is complete if any item downloaded, cancelled
+// if every item was cancelled, and failed otherwise.
+func aggregateParent(parent *DownloadJob, children []*DownloadJob, now time.Time) {
+	total := len(parent.ChildJobIDs)
+	if total == 0 {
+		return
+	}
+	missing := total - len(children)
+	completed, failed, cancelled := 0, missing, 0
+	progress := missing * 100
+	started := false
+	for _, child := range children {
+		switch child.Status {
+		case StatusComplete:
+			completed++
+			progress += 100
+		case StatusFailed:
+			failed++
+			progress += 100
+		case StatusCancelled:
+			failed++
+			cancelled++
+			progress += 100
+		case StatusQueued:
+			progress += child.Progress
+		default:
+			started = true
+			progress += child.Progress
+		}
+	}
+
+	parent.CompletedItems = completed
+	parent.FailedItems = failed
+	parent.Progress = progress / total
+	parent.Error = ""
+	parent.UpdatedAt = now
+	if completed+failed < total {
+		parent.CompletedAt = nil
+		if started || completed+failed > 0 {
+			parent.Status = StatusDownloading
+			if parent.StartedAt == nil {
+				parent.StartedAt = &now
+			}
+		} else {
+			parent.Status = StatusQueued
+		}
+		return
+	}
+
+	switch {
+	case completed > 0:
+		parent.Status = StatusComplete
+		if failed > 0 {
+			parent.Error = fmt.Sprintf("%d of %d items did not download", failed, total)
+		}
+	case cancelled == total:
+		parent.Status = StatusCancelled
+	default:
+		parent.Status = StatusFailed
+		parent.Error = fmt.Sprintf("all %d items failed", total)
+	}
+	if parent.CompletedAt == nil {
+		parent.CompletedAt = &now
+	}
+}
+
+// CreateParentJob stores the parent job of a playlist download.
+func (s *Service) CreateParentJob(ctx context.Context, userID, url, sourceType, title string) (*DownloadJob, error) {
+	return s.queue.CreateParentJob(ctx, userID, url, sourceType, title)
+}
+
+// AttachChildJobs records the items of a playlist download's parent job.
+func (s *Service) AttachChildJobs(ctx context.Context, parentID string, childIDs []string) (*DownloadJob, error) {
+	return s.queue.AttachChildJobs(ctx, parentID, childIDs)
+}
+
+// cancelChildren cancels every unfinished item of a parent job.
+func (s *Service) cancelChildren(ctx context.Context, parent *DownloadJob) error {
+	cancelled := 0
+	for _, childID := range parent.ChildJobIDs {
+		err := s.CancelJob(ctx, childID)
+		switch {
+		case err == nil:
+			cancelled++
+		case errors.Is(err, ErrJobNotCancellable), errors.Is(err, ErrJobNotFound):
+		default:
+			return err
+		}
+	}
+	if cancelled == 0 {
+		return ErrJobNotCancellable
+	}
+	return nil
+}
+
+// retryChildren requeues every failed or cancelled item of a parent job.
+func (s *Service) retryChildren(ctx context.Context, parent *DownloadJob) error {
+	retried := 0
+	for _, childID := range parent.ChildJobIDs {
+		err := s.RetryJob(ctx, childID)
+		switch {
+		case err == nil:
+			retried++
+		case errors.Is(err, ErrJobNotRetryable), errors.Is(err, ErrJobNotFound):
+		default:
+			return err
+		}
+	}
+	if retried == 0 {
+		return ErrJobNotRetryable
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregateParentFollowsChildren(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	parent := &DownloadJob{ID: "parent", Status: StatusQueued, ChildJobIDs: []string{"a", "b", "c"}}
+	children := []*DownloadJob{
+		{ID: "a", Status: StatusComplete, Progress: 100},
+		{ID: "b", Status: StatusDownloading, Progress: 50},
+		{ID: "c", Status: StatusQueued},
+	}
+
+	aggregateParent(parent, children, now)
+	if parent.Status != StatusDownloading || parent.Progress != 50 || parent.CompletedItems != 1 || parent.StartedAt == nil || parent.CompletedAt != nil {
+		t.Fatalf("in-progress parent = %+v", parent)
+	}
+
+	children[1].Status = StatusFailed
+	children[2].Status = StatusComplete
+	aggregateParent(parent, children, now)
+	if parent.Status != StatusComplete || parent.Progress != 100 || parent.CompletedItems != 2 || parent.FailedItems != 1 || parent.Error == "" || parent.CompletedAt == nil {
+		t.Fatalf("finished parent = %+v, want complete with one failed item noted", parent)
+	}
+
+	// An item retried after the parent finished reopens it.
+	children[1].Status = StatusQueued
+	children[1].Progress = 0
+	aggregateParent(parent, children, now)
+	if parent.Status != StatusDownloading || parent.CompletedAt != nil || parent.Error != "" {
+		t.Fatalf("reopened parent = %+v", parent)
+	}
+}
+
+func TestAggregateParentOutcomeWithoutCompletedItems(t *testing.T) {
+	now := time.Now()
+	parent := &DownloadJob{ChildJobIDs: []string{"a", "b"}}
+	aggregateParent(parent, []*DownloadJob{{ID: "a", Status: StatusCancelled}, {ID: "b", Status: StatusCancelled}}, now)
+	if parent.Status != StatusCancelled {
+		t.Fatalf("all cancelled status = %q, want cancelled", parent.Status)
+	}
+
+	// A missing item counts as failed.
+	aggregateParent(parent, []*DownloadJob{{ID: "a", Status: StatusCancelled}}, now)
+	if parent.Status != StatusFailed || parent.FailedItems != 2 {
+		t.Fatalf("missing item parent = %+v, want failed", parent)
+	}
+}
@@ -0,0 +1,213 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// keyRecentDurations holds the run times of recently completed jobs in
+	// milliseconds, newest first.
+	keyRecentDurations = "download:durations"
+
+	// recentDurationSamples is how many completed jobs the average covers.
+	recentDurationSamples = 50
+)
+
+// QueuePosition is where a queued job stands. Position 1 is the next job a
+// worker will pick up. EstimatedStartAt is nil until enough jobs have
+// completed to estimate a run time.
+type QueuePosition struct {
+	JobID            string
+	Position         int
+	EstimatedStartAt *time.Time
+}
+
+// recordDuration remembers how long a completed job ran so queue estimates
+// follow recent throughput.
+func (q *Queue) recordDuration(ctx context.Context, d time.Duration) error {
+	pipe := q.client.TxPipeline()
+	pipe.LPush(ctx, keyRecentDurations, d.Milliseconds())
+	pipe.LTrim(ctx, keyRecentDurations, 0, recentDurationSamples-1)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// AverageJobDuration returns the mean run time of recently completed jobs, or
+// zero when none have completed yet.
+func (q *Queue) AverageJobDuration(ctx context.Context) (time.Duration, error) {
+	values, err := q.client.LRange(ctx, keyRecentDurations, 0, recentDurationSamples-1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read job durations: %w", err)
+	}
+	var total int64
+	var count int64
+	for _, v := range values {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			continue
+		}
+		total += ms
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return time.Duration(total/count) * time.Millisecond, nil
+}
+
+// QueuedJobIDs returns the IDs of waiting jobs, next to run first: every
+// interactive job, then every batch job.
+func (q *Queue) QueuedJobIDs(ctx context.Context) ([]string, error) {
+	var ordered []string
+	for _, key := range []string{keyJobQueue, keyBatchJobQueue} {
+		ids, err := q.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queue: %w", err)
+		}
+		// Jobs are pushed on the left and popped from the right.
+		for i := len(ids) - 1; i >= 0; i-- {
+			ordered = append(ordered, ids[i])
+		}
+	}
+	return ordered, nil
+}
+
+// queuePositions numbers ids, which are ordered next-to-run first. A job
+// listed twice (deferred and restored) keeps its earlier place.
+func queuePositions(ids []string, now time.Time, workers int, avg time.Duration) map[string]QueuePosition {
+	positions := make(map[string]QueuePosition, len(ids))
+	for i, id := range ids {
+		if _, seen := positions[id]; seen {
+			continue
+		}
+		position := i + 1
+		positions[id] = QueuePosition{
+			JobID:            id,
+			Position:         position,
+			EstimatedStartAt: estimateStart(now, position, workers, avg),
+		}
+	}
+	return positions
+}
+
+// estimateStart predicts when the job at position starts. Jobs wait in the
+// queue only while every worker is busy, so the first workers jobs start as
+// the running ones finish, assumed halfway through on average, and each
+// further batch of workers jobs adds one average run.
+func estimateStart(now time.Time, position, workers int, avg time.Duration) *time.Time {
+	if avg <= 0 || position <= 0 {
+		return nil
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	batches := (position - 1) / workers
+	wait := avg/2 + time.Duration(batches)*avg
+	start := now.Add(wait)
+	return &start
+}
+
+// QueuePositions returns the position and estimated start of every waiting
+// job, keyed by job ID.
+func (s *Service) QueuePositions(ctx context.Context) (map[string]QueuePosition, error) {
+	ids, err := s.queue.QueuedJobIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	avg, err := s.queue.AverageJobDuration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queuePositions(ids, time.Now(), s.workerPool.workerCount, avg), nil
+}
+
+// QueuePositionObserver is told when a waiting job moves in the queue.
+type QueuePositionObserver interface {
+	QueuePositionChanged(userID string, position QueuePosition)
+}
+
+// queuePositionSource is the part of Service a PositionWatcher polls.
+type queuePositionSource interface {
+	QueuePositions(ctx context.Context) (map[string]QueuePosition, error)
+	GetJob(ctx context.Context, jobID string) (*DownloadJob, error)
+}
+
+// PositionWatcher polls the queue and reports each waiting job whose
+// position changed since the last poll, so clients can show "3rd in queue"
+// without polling the API. Polling rather than hooking the workers keeps it
+// correct when workers run in another process.
+type PositionWatcher struct {
+	source   queuePositionSource
+	observer QueuePositionObserver
+	interval time.Duration
+
+	mu    sync.Mutex
+	last  map[string]int
+	users map[string]string
+}
+
+// NewPositionWatcher creates a watcher that polls every interval.
+func NewPositionWatcher(source queuePositionSource, observer QueuePositionObserver, interval time.Duration) *PositionWatcher {
+	return &PositionWatcher{
+		source:   source,
+		observer: observer,
+		interval: interval,
+		last:     make(map[string]int),
+		users:    make(map[string]string),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (w *PositionWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to poll download queue positions: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *PositionWatcher) poll(ctx context.Context) error {
+	positions, err := w.source.QueuePositions(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, pos := range positions {
+		if w.last[id] == pos.Position {
+			continue
+		}
+		userID, ok := w.users[id]
+		if !ok {
+			job, err := w.source.GetJob(ctx, id)
+			if err != nil {
+				continue
+			}
+			userID = job.UserID
+			w.users[id] = userID
+		}
+		w.last[id] = pos.Position
+		w.observer.QueuePositionChanged(userID, pos)
+	}
+	// Forget jobs that left the queue; their status updates take over.
+	for id := range w.last {
+		if _, ok := positions[id]; !ok {
+			delete(w.last, id)
+			delete(w.users, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package download
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueuePositionsNumbersFromTheFrontAndKeepsEarliestDuplicate(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	positions := queuePositions([]string{"a", "b", "a", "c"}, now, 2, time.Minute)
+
+	if len(positions) != 3 || positions["a"].Position != 1 || positions["b"].Position != 2 || positions["c"].Position != 4 {
+		t.Fatalf("positions = %+v, want a=1 b=2 c=4", positions)
+	}
+	// Two workers: the first two start as the running jobs finish (half an
+	// average run), the next pair one full run later.
+	if got := positions["b"].EstimatedStartAt; got == nil || !got.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("b starts at %v, want 12:00:30", got)
+	}
+	if got := positions["c"].EstimatedStartAt; got == nil || !got.Equal(now.Add(90*time.Second)) {
+		t.Fatalf("c starts at %v, want 12:01:30", got)
+	}
+}
+
+func TestEstimateStartUnknownWithoutCompletedJobs(t *testing.T) {
+	if got := estimateStart(time.Now(), 1, 4, 0); got != nil {
+		t.Fatalf("estimate = %v, want nil before any job has completed", got)
+	}
+}
+
+type fakePositionSource struct {
+	positions map[string]QueuePosition
+	lookups   int
+}
+
+func (f *fakePositionSource) QueuePositions(context.Context) (map[string]QueuePosition, error) {
+	return f.positions, nil
+}
+
+func (f *fakePositionSource) GetJob(_ context.Context, jobID string) (*DownloadJob, error) {
+	f.lookups++
+	return &DownloadJob{ID: jobID, UserID: "user-" + jobID}, nil
+}
+
+type recordedPosition struct {
+	userID   string
+	position QueuePosition
+}
+
+type fakePositionObserver struct {
+	changes []recordedPosition
+}
+
+func (f *fakePositionObserver) QueuePositionChanged(userID string, position QueuePosition) {
+	f.changes = append(f.changes, recordedPosition{userID, position})
+}
+
+func TestPositionWatcherReportsOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	source := &fakePositionSource{positions: map[string]QueuePosition{
+		"a": {JobID: "a", Position: 1},
+		"b": {JobID: "b", Position: 2},
+	}}
+	observer := &fakePositionObserver{}
+	watcher := NewPositionWatcher(source, observer, time.Second)
+
+	if err := watcher.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(observer.changes) != 2 {
+		t.Fatalf("changes = %+v, want both jobs reported initially", observer.changes)
+	}
+
+	observer.changes = nil
+	if err := watcher.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(observer.changes) != 0 {
+		t.Fatalf("changes = %+v, want nothing when the queue did not move", observer.changes)
+	}
+
+	// "a" started; "b" moves up.
+	source.positions = map[string]QueuePosition{"b": {JobID: "b", Position: 1}}
+	if err := watcher.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(observer.changes) != 1 || observer.changes[0].userID != "user-b" || observer.changes[0].position.Position != 1 {
+		t.Fatalf("changes = %+v, want b moved to 1", observer.changes)
+	}
+	if source.lookups != 2 {
+		t.Fatalf("job lookups = %d, want owners cached after the first poll", source.lookups)
+	}
+}
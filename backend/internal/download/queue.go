@@ -9,13 +9,17 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
 )
 
 const (
-	// Redis key prefixes
-	keyJobQueue  = "download:queue"
-	keyJobStatus = "download:job:"
-	keyProgress  = "download:progress"
+	// Redis key prefixes. keyJobQueue holds interactive jobs and predates
+	// priorities, so jobs queued before them keep their place.
+	keyJobQueue      = "download:queue"
+	keyBatchJobQueue = "download:queue:batch"
+	keyJobStatus     = "download:job:"
+	keyProgress      = "download:progress"
 
 	// Default timeout for blocking operations
 	defaultBlockTimeout = 5 * time.Second
@@ -25,6 +29,11 @@ var (
 	ErrJobNotFound     = errors.New("job not found")
 	ErrQueueEmpty      = errors.New("queue is empty")
 	ErrJobNotRetryable = errors.New("job is not retryable")
+	// ErrJobNotCancellable is returned when cancelling a finished job.
+	ErrJobNotCancellable = errors.New("job is not cancellable")
+	// ErrJobCancelled is the cause a cancelled job's context carries; status
+	// updates to a cancelled job also return it.
+	ErrJobCancelled = errors.New("download job cancelled")
 )
 
 // Queue manages download jobs using Redis
@@ -123,12 +132,12 @@ func (q *Queue) EnsureCandidateWithID(ctx context.Context, jobID, userID string,
 			return nil, fmt.Errorf("download job %s belongs to another user", jobID)
 		}
 		if !job.IsTerminal() {
-			_, positionErr := q.client.LPos(ctx, keyJobQueue, jobID, redis.LPosArgs{}).Result()
+			_, positionErr := q.client.LPos(ctx, queueKey(job.Priority), jobID, redis.LPosArgs{}).Result()
 			switch {
 			case positionErr == nil:
 				return job, nil
 			case errors.Is(positionErr, redis.Nil):
-				if err := q.client.LPush(ctx, keyJobQueue, jobID).Err(); err != nil {
+				if err := q.client.LPush(ctx, queueKey(job.Priority), jobID).Err(); err != nil {
 					return nil, fmt.Errorf("restore queued job: %w", err)
 				}
 				return job, nil
@@ -156,11 +165,11 @@ func (q *Queue) EnsurePlaylistImportItemWithID(ctx context.Context, jobID, userI
 			if job.PlaylistImportJobID != importJobID || job.PlaylistImportItemID != importItemID || job.PlaylistID != playlistID || job.PlaylistPosition != playlistPosition {
 				return nil, fmt.Errorf("playlist import metadata mismatch for download job %s", jobID)
 			}
-			_, positionErr := q.client.LPos(ctx, keyJobQueue, jobID, redis.LPosArgs{}).Result()
+			_, positionErr := q.client.LPos(ctx, queueKey(job.Priority), jobID, redis.LPosArgs{}).Result()
 			switch {
 			case positionErr == nil:
 			case errors.Is(positionErr, redis.Nil):
-				if err := q.client.LPush(ctx, keyJobQueue, jobID).Err(); err != nil {
+				if err := q.client.LPush(ctx, queueKey(job.Priority), jobID).Err(); err != nil {
 					return nil, fmt.Errorf("restore queued playlist import job: %w", err)
 				}
 			default:
@@ -183,10 +192,12 @@ func (q *Queue) EnqueuePlaylistImportItem(ctx context.Context, userID string, ca
 
 // EnqueuePlaylistImportItemWithID publishes a playlist item using a durable
 // caller-provided job ID. Source-selection ingestion uses it after SQL commit.
+// Playlist items always run at batch priority.
 func (q *Queue) EnqueuePlaylistImportItemWithID(ctx context.Context, jobID, userID string, candidate SourceCandidate, importJobID string, importItemID int64, playlistID int64, playlistPosition int) (*DownloadJob, error) {
 	return q.enqueueJob(ctx, &DownloadJob{
 		ID:                   jobID,
 		UserID:               userID,
+		Priority:             PriorityBatch,
 		URL:                  candidate.SourceURL,
 		SourceType:           candidate.Provider,
 		CandidateID:          candidate.CandidateID,
@@ -210,6 +221,15 @@ func (q *Queue) enqueueJob(ctx context.Context, job *DownloadJob) (*DownloadJob,
 	if job.ID == "" {
 		job.ID = uuid.New().String()
 	}
+	if job.RequestID == "" {
+		job.RequestID = apperrors.GetRequestID(ctx)
+	}
+	if job.Priority == "" {
+		job.Priority = PriorityFromContext(ctx)
+	}
+	if job.ParentJobID == "" {
+		job.ParentJobID = parentJobFromContext(ctx)
+	}
 	job.Status = StatusQueued
 	job.Progress = 0
 	job.RetryCount = 0
@@ -220,7 +240,7 @@ func (q *Queue) enqueueJob(ctx context.Context, job *DownloadJob) (*DownloadJob,
 		return nil, err
 	}
 
-	if err := q.client.LPush(ctx, keyJobQueue, job.ID).Err(); err != nil {
+	if err := q.client.LPush(ctx, queueKey(job.Priority), job.ID).Err(); err != nil {
 		_ = q.client.Del(ctx, keyJobStatus+job.ID).Err()
 		return nil, fmt.Errorf("failed to enqueue job: %w", err)
 	}
@@ -228,13 +248,22 @@ func (q *Queue) enqueueJob(ctx context.Context, job *DownloadJob) (*DownloadJob,
 	return job, nil
 }
 
-// Dequeue retrieves and removes a job from the queue (blocking)
+// queueKey returns the list a job of the given priority waits in.
+func queueKey(priority string) string {
+	if priority == PriorityBatch {
+		return keyBatchJobQueue
+	}
+	return keyJobQueue
+}
+
+// Dequeue retrieves and removes a job from the queue (blocking). Interactive
+// jobs are taken before any batch job.
 func (q *Queue) Dequeue(ctx context.Context, timeout time.Duration) (*DownloadJob, error) {
 	if timeout == 0 {
 		timeout = defaultBlockTimeout
 	}
 
-	result, err := q.client.BRPop(ctx, timeout, keyJobQueue).Result()
+	result, err := q.client.BRPop(ctx, timeout, keyJobQueue, keyBatchJobQueue).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, ErrQueueEmpty
@@ -274,6 +303,9 @@ func (q *Queue) UpdateStatus(ctx context.Context, jobID, status string, progress
 	if err != nil {
 		return err
 	}
+	if job.Status == StatusCancelled {
+		return ErrJobCancelled
+	}
 
 	job.Status = status
 	job.Progress = progress
@@ -294,7 +326,16 @@ func (q *Queue) UpdateStatus(ctx context.Context, jobID, status string, progress
 		return err
 	}
 
-	return q.publishProgress(ctx, job)
+	if status == StatusComplete && job.StartedAt != nil {
+		// Estimates are best effort; a lost sample must not fail the job.
+		_ = q.recordDuration(ctx, job.CompletedAt.Sub(*job.StartedAt))
+	}
+
+	if err := q.publishProgress(ctx, job); err != nil {
+		return err
+	}
+	q.refreshParentOf(ctx, job)
+	return nil
 }
 
 // UpdateTrackID stores the created local track ID for a completed download job.
@@ -319,13 +360,14 @@ func (q *Queue) IncrementRetry(ctx context.Context, jobID string) error {
 	if err != nil {
 		return err
 	}
-	if job.Status != StatusFailed {
+	if job.Status != StatusFailed && job.Status != StatusCancelled {
 		return ErrJobNotRetryable
 	}
 
 	job.RetryCount++
 	job.Status = StatusQueued
 	job.Error = ""
+	job.CompletedAt = nil
 	job.UpdatedAt = time.Now()
 
 	data, err := json.Marshal(job)
@@ -335,9 +377,15 @@ func (q *Queue) IncrementRetry(ctx context.Context, jobID string) error {
 
 	pipe := q.client.TxPipeline()
 	pipe.Set(ctx, keyJobStatus+job.ID, data, 0)
-	pipe.LPush(ctx, keyJobQueue, jobID)
-	_, err = pipe.Exec(ctx)
-	return err
+	pipe.LPush(ctx, queueKey(job.Priority), jobID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if err := q.publishProgress(ctx, job); err != nil {
+		return err
+	}
+	q.refreshParentOf(ctx, job)
+	return nil
 }
 
 // PrepareRetry persists retry metadata before a worker waits for its backoff.
@@ -360,6 +408,7 @@ func (q *Queue) PrepareRetry(ctx context.Context, jobID string) (*DownloadJob, e
 	if err := q.publishProgress(ctx, job); err != nil {
 		return nil, err
 	}
+	q.refreshParentOf(ctx, job)
 	return job, nil
 }
 
@@ -373,14 +422,23 @@ func (q *Queue) PublishQueuedRetry(ctx context.Context, jobID string) error {
 	if job.Status != StatusQueued {
 		return ErrJobNotRetryable
 	}
-	_, err = q.client.LPos(ctx, keyJobQueue, jobID, redis.LPosArgs{}).Result()
+	_, err = q.client.LPos(ctx, queueKey(job.Priority), jobID, redis.LPosArgs{}).Result()
 	if err == nil {
 		return nil
 	}
 	if !errors.Is(err, redis.Nil) {
 		return fmt.Errorf("check queued retry: %w", err)
 	}
-	return q.client.LPush(ctx, keyJobQueue, jobID).Err()
+	return q.client.LPush(ctx, queueKey(job.Priority), jobID).Err()
+}
+
+// Defer puts a dequeued job back at the far end of its priority's queue so
+// workers reach other jobs before seeing it again.
+func (q *Queue) Defer(ctx context.Context, job *DownloadJob) error {
+	if err := q.client.LPush(ctx, queueKey(job.Priority), job.ID).Err(); err != nil {
+		return fmt.Errorf("failed to defer job: %w", err)
+	}
+	return nil
 }
 
 // GetUserJobs retrieves all jobs for a specific user
@@ -412,9 +470,49 @@ func (q *Queue) GetUserJobs(ctx context.Context, userID string) ([]*DownloadJob,
 	return jobs, nil
 }
 
+// Cancel marks a job cancelled and takes it off the queue if it is still
+// waiting. Stopping a job a worker already runs is the worker pool's part.
+func (q *Queue) Cancel(ctx context.Context, jobID string) (*DownloadJob, error) {
+	job, err := q.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsTerminal() {
+		return nil, ErrJobNotCancellable
+	}
+
+	now := time.Now()
+	job.Status = StatusCancelled
+	job.Error = ""
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	pipe := q.client.TxPipeline()
+	pipe.Set(ctx, keyJobStatus+job.ID, data, 0)
+	pipe.LRem(ctx, queueKey(job.Priority), 0, job.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if err := q.publishProgress(ctx, job); err != nil {
+		return nil, err
+	}
+	q.refreshParentOf(ctx, job)
+	return job, nil
+}
+
 // QueueLength returns the number of jobs waiting in the queue
 func (q *Queue) QueueLength(ctx context.Context) (int64, error) {
-	return q.client.LLen(ctx, keyJobQueue).Result()
+	pipe := q.client.Pipeline()
+	interactive := pipe.LLen(ctx, keyJobQueue)
+	batch := pipe.LLen(ctx, keyBatchJobQueue)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return interactive.Val() + batch.Val(), nil
 }
 
 // saveJob saves a job to Redis
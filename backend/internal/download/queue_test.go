@@ -2,9 +2,12 @@ package download
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
 )
 
 func getTestRedisURL() string {
@@ -295,6 +298,7 @@ func TestDownloadJob_IsTerminal(t *testing.T) {
 		{StatusUploading, false},
 		{StatusComplete, true},
 		{StatusFailed, true},
+		{StatusCancelled, true},
 	}
 
 	for _, tt := range tests {
@@ -318,6 +322,8 @@ func TestDownloadJob_CanRetry(t *testing.T) {
 		{StatusFailed, 2, true},
 		{StatusFailed, 3, false},
 		{StatusFailed, 4, false},
+		{StatusCancelled, 0, true},
+		{StatusCancelled, 3, false},
 		{StatusComplete, 0, false},
 		{StatusQueued, 0, false},
 	}
@@ -330,3 +336,85 @@ func TestDownloadJob_CanRetry(t *testing.T) {
 		}
 	}
 }
+
+func TestQueue_EnqueueRecordsOriginatingRequestID(t *testing.T) {
+	queue := newTestQueue(t)
+	ctx := apperrors.WithRequestID(context.Background(), "req-abc")
+
+	job, err := queue.Enqueue(ctx, "user-123", "https://example.com/track.mp3", "youtube", nil)
+	if err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	if err := queue.UpdateStatus(context.Background(), job.ID, StatusDownloading, 10, ""); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	stored, err := queue.GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if stored.RequestID != "req-abc" {
+		t.Errorf("stored request ID = %q, want req-abc", stored.RequestID)
+	}
+}
+
+func TestQueue_InteractiveJobsDequeueBeforeBatch(t *testing.T) {
+	queue := newTestQueue(t)
+	ctx := context.Background()
+
+	batch, err := queue.Enqueue(WithPriority(ctx, PriorityBatch), "user-123", "https://example.com/batch.mp3", "youtube", nil)
+	if err != nil {
+		t.Fatalf("Failed to enqueue batch job: %v", err)
+	}
+	interactive, err := queue.Enqueue(ctx, "user-123", "https://example.com/interactive.mp3", "youtube", nil)
+	if err != nil {
+		t.Fatalf("Failed to enqueue interactive job: %v", err)
+	}
+	if batch.Priority != PriorityBatch || interactive.Priority != PriorityInteractive {
+		t.Fatalf("priorities = %q, %q", batch.Priority, interactive.Priority)
+	}
+
+	ids, err := queue.QueuedJobIDs(ctx)
+	if err != nil || len(ids) != 2 || ids[0] != interactive.ID || ids[1] != batch.ID {
+		t.Fatalf("queued ids = %v, %v; want the interactive job first", ids, err)
+	}
+	if length, err := queue.QueueLength(ctx); err != nil || length != 2 {
+		t.Fatalf("queue length = %d, %v; want both queues counted", length, err)
+	}
+	for _, want := range []string{interactive.ID, batch.ID} {
+		job, err := queue.Dequeue(ctx, time.Second)
+		if err != nil || job.ID != want {
+			t.Fatalf("dequeued %+v, %v; want %s", job, err, want)
+		}
+	}
+}
+
+func TestQueue_CancelRemovesQueuedJobAndBlocksUpdates(t *testing.T) {
+	queue := newTestQueue(t)
+	ctx := context.Background()
+
+	job, err := queue.Enqueue(WithPriority(ctx, PriorityBatch), "user-123", "https://example.com/track.mp3", "youtube", nil)
+	if err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	cancelled, err := queue.Cancel(ctx, job.ID)
+	if err != nil || cancelled.Status != StatusCancelled || cancelled.CompletedAt == nil {
+		t.Fatalf("Cancel = %+v, %v", cancelled, err)
+	}
+	if length, _ := queue.QueueLength(ctx); length != 0 {
+		t.Fatalf("queue length = %d after cancel, want 0", length)
+	}
+	if err := queue.UpdateStatus(ctx, job.ID, StatusDownloading, 0, ""); !errors.Is(err, ErrJobCancelled) {
+		t.Fatalf("UpdateStatus on cancelled job err = %v, want ErrJobCancelled", err)
+	}
+	if _, err := queue.Cancel(ctx, job.ID); !errors.Is(err, ErrJobNotCancellable) {
+		t.Fatalf("second Cancel err = %v, want ErrJobNotCancellable", err)
+	}
+
+	if err := queue.IncrementRetry(ctx, job.ID); err != nil {
+		t.Fatalf("IncrementRetry on cancelled job: %v", err)
+	}
+	retried, err := queue.Dequeue(ctx, time.Second)
+	if err != nil || retried.ID != job.ID || retried.Status != StatusQueued || retried.CompletedAt != nil {
+		t.Fatalf("retried job = %+v, %v", retried, err)
+	}
+}
@@ -0,0 +1,158 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidDownloadWindow reports a download window that is not two
+// distinct HH:MM times joined by '-'.
+var ErrInvalidDownloadWindow = errors.New("download window must look like 01:00-07:00")
+
+// DownloadWindow is the time of day workers may start jobs, as offsets from
+// local midnight. A window whose end is before its start runs past
+// midnight.
+type DownloadWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseDownloadWindow parses a window written as "HH:MM-HH:MM".
+func ParseDownloadWindow(value string) (DownloadWindow, error) {
+	rawStart, rawEnd, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return DownloadWindow{}, ErrInvalidDownloadWindow
+	}
+	start, err := parseTimeOfDay(rawStart)
+	if err != nil {
+		return DownloadWindow{}, err
+	}
+	end, err := parseTimeOfDay(rawEnd)
+	if err != nil {
+		return DownloadWindow{}, err
+	}
+	if start == end {
+		return DownloadWindow{}, ErrInvalidDownloadWindow
+	}
+	return DownloadWindow{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, ErrInvalidDownloadWindow
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t's local time of day falls in the window. The
+// start is inside the window and the end is not.
+func (w DownloadWindow) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+func (w DownloadWindow) String() string {
+	return formatTimeOfDay(w.Start) + "-" + formatTimeOfDay(w.End)
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// ScheduleSettings are the download window and bandwidth cap.
+type ScheduleSettings struct {
+	// Window limits when jobs start; nil lets them start at any time.
+	Window *DownloadWindow
+	// MaxBytesPerSecond caps the combined download rate of all workers;
+	// zero is uncapped.
+	MaxBytesPerSecond int64
+}
+
+// DownloadSchedule holds the hours workers may start jobs and the overall
+// bandwidth cap for yt-dlp. Outside the window workers take no new jobs but
+// let running ones finish. Settings can be changed while workers are running
+// and apply to jobs started afterwards.
+type DownloadSchedule struct {
+	mu       sync.RWMutex
+	settings ScheduleSettings
+}
+
+// NewDownloadSchedule creates a schedule with the given startup settings.
+func NewDownloadSchedule(settings ScheduleSettings) *DownloadSchedule {
+	s := &DownloadSchedule{}
+	s.Update(settings)
+	return s
+}
+
+// Settings returns the current settings.
+func (s *DownloadSchedule) Settings() ScheduleSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	settings := s.settings
+	if settings.Window != nil {
+		window := *settings.Window
+		settings.Window = &window
+	}
+	return settings
+}
+
+// Update replaces the settings. A negative rate is treated as uncapped.
+func (s *DownloadSchedule) Update(settings ScheduleSettings) {
+	if settings.Window != nil {
+		window := *settings.Window
+		settings.Window = &window
+	}
+	if settings.MaxBytesPerSecond < 0 {
+		settings.MaxBytesPerSecond = 0
+	}
+	s.mu.Lock()
+	s.settings = settings
+	s.mu.Unlock()
+}
+
+// Allow reports whether a worker may start a job at now.
+func (s *DownloadSchedule) Allow(now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Window == nil || s.settings.Window.Contains(now)
+}
+
+// JobRateLimit is the download rate one job may use so that workers running
+// side by side stay under the overall cap. It is zero when uncapped.
+func (s *DownloadSchedule) JobRateLimit(workers int) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.settings.MaxBytesPerSecond == 0 {
+		return 0
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return max(s.settings.MaxBytesPerSecond/int64(workers), 1)
+}
+
+type rateLimitContextKey struct{}
+
+// WithRateLimit caps the download rate, in bytes per second, of the job run
+// with ctx.
+func WithRateLimit(ctx context.Context, bytesPerSecond int64) context.Context {
+	if bytesPerSecond <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimitContextKey{}, bytesPerSecond)
+}
+
+// RateLimitFromContext returns the rate set by WithRateLimit, or zero.
+func RateLimitFromContext(ctx context.Context) int64 {
+	rate, _ := ctx.Value(rateLimitContextKey{}).(int64)
+	return rate
+}
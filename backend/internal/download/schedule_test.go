@@ -0,0 +1,71 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDownloadWindowContains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 5, 1, hour, minute, 0, 0, time.Local)
+	}
+	night, err := ParseDownloadWindow("22:30-06:00")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	day, err := ParseDownloadWindow(" 01:00 - 07:00 ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if night.String() != "22:30-06:00" || day.String() != "01:00-07:00" {
+		t.Fatalf("windows = %s, %s", night, day)
+	}
+	for _, tc := range []struct {
+		window DownloadWindow
+		at     time.Time
+		want   bool
+	}{
+		{day, at(1, 0), true},
+		{day, at(6, 59), true},
+		{day, at(7, 0), false},
+		{day, at(0, 59), false},
+		{night, at(23, 0), true},
+		{night, at(3, 0), true},
+		{night, at(6, 0), false},
+		{night, at(22, 29), false},
+	} {
+		if got := tc.window.Contains(tc.at); got != tc.want {
+			t.Errorf("%s contains %s = %v, want %v", tc.window, tc.at.Format("15:04"), got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", "01:00", "01:00-01:00", "25:00-07:00", "1am-7am"} {
+		if _, err := ParseDownloadWindow(bad); !errors.Is(err, ErrInvalidDownloadWindow) {
+			t.Errorf("ParseDownloadWindow(%q) err = %v, want ErrInvalidDownloadWindow", bad, err)
+		}
+	}
+}
+
+func TestDownloadScheduleSplitsBandwidthAcrossWorkers(t *testing.T) {
+	schedule := NewDownloadSchedule(ScheduleSettings{MaxBytesPerSecond: 3 << 20})
+	if got := schedule.JobRateLimit(3); got != 1<<20 {
+		t.Fatalf("JobRateLimit(3) = %d, want a third of the cap", got)
+	}
+	if !schedule.Allow(time.Now()) {
+		t.Fatal("a schedule without a window must always allow jobs")
+	}
+
+	schedule.Update(ScheduleSettings{MaxBytesPerSecond: -1})
+	if got := schedule.JobRateLimit(3); got != 0 {
+		t.Fatalf("JobRateLimit after removing the cap = %d, want 0", got)
+	}
+	ctx := WithRateLimit(context.Background(), schedule.JobRateLimit(3))
+	if RateLimitFromContext(ctx) != 0 {
+		t.Fatal("an uncapped job must carry no rate limit")
+	}
+	if RateLimitFromContext(WithRateLimit(context.Background(), 4096)) != 4096 {
+		t.Fatal("rate limit did not round-trip through the context")
+	}
+}
@@ -11,6 +11,8 @@ type Service struct {
 	queue      *Queue
 	workerPool *WorkerPool
 	lifecycle  JobLifecycle
+	limits     *ProviderLimits
+	schedule   *DownloadSchedule
 	maxRetries int
 }
 
@@ -20,6 +22,16 @@ type ServiceConfig struct {
 	WorkerCount int
 	MaxRetries  int
 	JobTimeout  time.Duration
+	// Outcomes optionally records each job's terminal outcome per provider.
+	Outcomes OutcomeObserver
+	// DiskGuard optionally pauses workers while temp disk space is low.
+	DiskGuard *DiskGuard
+	// ProviderLimits caps concurrent jobs per source provider; adjustable
+	// at runtime through Service.ProviderLimits.
+	ProviderLimits map[string]int
+	// Schedule sets when jobs may start and the overall bandwidth cap;
+	// adjustable at runtime through Service.Schedule.
+	Schedule ScheduleSettings
 }
 
 // NewService creates a new download service
@@ -38,6 +50,10 @@ func NewService(config *ServiceConfig, processor JobProcessor, lifecycle ...JobL
 		WorkerCount: &workerCount,
 		MaxRetries:  maxRetries,
 		JobTimeout:  config.JobTimeout,
+		Outcomes:    config.Outcomes,
+		DiskGuard:   config.DiskGuard,
+		Limits:      NewProviderLimits(config.ProviderLimits),
+		Schedule:    NewDownloadSchedule(config.Schedule),
 	}
 	if len(lifecycle) > 0 {
 		workerConfig.Lifecycle = lifecycle[0]
@@ -48,6 +64,8 @@ func NewService(config *ServiceConfig, processor JobProcessor, lifecycle ...JobL
 		queue:      queue,
 		workerPool: workerPool,
 		lifecycle:  workerConfig.Lifecycle,
+		limits:     workerConfig.Limits,
+		schedule:   workerConfig.Schedule,
 		maxRetries: maxRetries,
 	}, nil
 }
@@ -70,6 +88,16 @@ func (s *Service) Queue() *Queue {
 	return s.queue
 }
 
+// ProviderLimits returns the live per-provider concurrency limits
+func (s *Service) ProviderLimits() *ProviderLimits {
+	return s.limits
+}
+
+// Schedule returns the live download window and bandwidth cap
+func (s *Service) Schedule() *DownloadSchedule {
+	return s.schedule
+}
+
 // EnqueueDownload adds a new download job to the queue
 func (s *Service) EnqueueDownload(ctx context.Context, userID, url, sourceType string, mbRecordingID *string) (*DownloadJob, error) {
 	return s.queue.Enqueue(ctx, userID, url, sourceType, mbRecordingID)
@@ -115,12 +143,16 @@ func (s *Service) GetUserJobs(ctx context.Context, userID string) ([]*DownloadJo
 	return s.queue.GetUserJobs(ctx, userID)
 }
 
-// RetryJob increments retry metadata and places a failed job back on the queue.
+// RetryJob increments retry metadata and places a failed or cancelled job
+// back on the queue. For a playlist download it requeues every such item.
 func (s *Service) RetryJob(ctx context.Context, jobID string) error {
 	job, err := s.queue.GetJob(ctx, jobID)
 	if err != nil {
 		return err
 	}
+	if job.IsParent() {
+		return s.retryChildren(ctx, job)
+	}
 	if !job.CanRetry(s.maxRetries) {
 		return ErrJobNotRetryable
 	}
@@ -137,6 +169,31 @@ func (s *Service) RetryJob(ctx context.Context, jobID string) error {
 	return s.queue.IncrementRetry(ctx, jobID)
 }
 
+// CancelJob cancels a queued or running job, or every unfinished item of a
+// playlist download. A job running in this process is stopped at once; one
+// running in another process finishes its attempt, but its status updates
+// can no longer overwrite the cancellation.
+func (s *Service) CancelJob(ctx context.Context, jobID string) error {
+	job, err := s.queue.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.IsParent() {
+		return s.cancelChildren(ctx, job)
+	}
+	job, err = s.queue.Cancel(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	s.workerPool.cancelJob(jobID)
+	if s.lifecycle != nil {
+		if err := s.lifecycle.Fail(ctx, job, ErrJobCancelled); err != nil {
+			log.Printf("Failed to mirror cancellation of download job %s: %v", jobID, err)
+		}
+	}
+	return nil
+}
+
 // GetQueueLength returns the number of pending jobs
 func (s *Service) GetQueueLength(ctx context.Context) (int64, error) {
 	return s.queue.QueueLength(ctx)
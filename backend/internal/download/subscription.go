@@ -1,6 +1,7 @@
 package download
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/redis/go-redis/v9"
@@ -34,3 +35,32 @@ func (s *ProgressSubscription) Channel() <-chan *DownloadJob {
 func (s *ProgressSubscription) Close() error {
 	return s.pubsub.Close()
 }
+
+// JobProgressObserver is told about every update to any user's jobs.
+type JobProgressObserver interface {
+	DownloadJobChanged(job *DownloadJob)
+}
+
+// RelayProgress passes every published job update to observer until ctx is
+// cancelled. Updates come through Redis, so jobs run by workers in other
+// processes are relayed too.
+func (s *Service) RelayProgress(ctx context.Context, observer JobProgressObserver) {
+	pubsub := s.queue.client.PSubscribe(ctx, keyProgress+":*")
+	defer pubsub.Close()
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var job DownloadJob
+			if err := json.Unmarshal([]byte(msg.Payload), &job); err != nil {
+				continue
+			}
+			observer.DownloadJobChanged(&job)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"math"
 	"sync"
 	"time"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+	"github.com/openmusicplayer/backend/internal/logger"
 )
 
 const (
@@ -20,6 +23,18 @@ const (
 	// Redis blocking pop when the queue is idle.
 	workerDequeueTimeout = 1 * time.Second
 
+	// diskPausePollInterval is how often a worker paused by the disk guard
+	// re-checks free space.
+	diskPausePollInterval = 5 * time.Second
+
+	// schedulePausePollInterval is how often a worker waiting for the
+	// download window re-checks the schedule.
+	schedulePausePollInterval = 30 * time.Second
+
+	// providerDeferInterval is how long a worker waits after handing back a
+	// job whose provider is at its concurrency limit.
+	providerDeferInterval = 500 * time.Millisecond
+
 	// Exponential backoff parameters
 	baseBackoff = 1 * time.Second
 	maxBackoff  = 5 * time.Minute
@@ -46,6 +61,10 @@ type WorkerPool struct {
 	jobTimeout   time.Duration
 	processor    JobProcessor
 	lifecycle    JobLifecycle
+	outcomes     OutcomeObserver
+	diskGuard    *DiskGuard
+	limits       *ProviderLimits
+	schedule     *DownloadSchedule
 	prepareRetry func(context.Context, string) (*DownloadJob, error)
 
 	wg         sync.WaitGroup
@@ -53,6 +72,10 @@ type WorkerPool struct {
 	stopCancel context.CancelFunc
 	mu         sync.RWMutex
 	running    bool
+
+	// active holds the cancel function of each job a worker is running.
+	activeMu sync.Mutex
+	active   map[string]context.CancelCauseFunc
 }
 
 // WorkerPoolConfig holds configuration for the worker pool
@@ -61,6 +84,10 @@ type WorkerPoolConfig struct {
 	MaxRetries  int
 	JobTimeout  time.Duration
 	Lifecycle   JobLifecycle
+	Outcomes    OutcomeObserver
+	DiskGuard   *DiskGuard
+	Limits      *ProviderLimits
+	Schedule    *DownloadSchedule
 }
 
 // NewWorkerPool creates a new worker pool
@@ -94,7 +121,12 @@ func NewWorkerPool(queue *Queue, processor JobProcessor, config *WorkerPoolConfi
 		jobTimeout:  jobTimeout,
 		processor:   processor,
 		lifecycle:   config.Lifecycle,
+		outcomes:    config.Outcomes,
+		diskGuard:   config.DiskGuard,
+		limits:      config.Limits,
+		schedule:    config.Schedule,
 		stopChan:    make(chan struct{}),
+		active:      make(map[string]context.CancelCauseFunc),
 	}
 	if queue != nil {
 		pool.prepareRetry = queue.PrepareRetry
@@ -183,6 +215,20 @@ func (wp *WorkerPool) worker(stopCtx context.Context, id int) {
 
 // processNextJob dequeues and processes the next available job
 func (wp *WorkerPool) processNextJob(dequeueCtx context.Context, workerID int) {
+	if wp.diskGuard != nil && !wp.diskGuard.Allow() {
+		select {
+		case <-dequeueCtx.Done():
+		case <-time.After(diskPausePollInterval):
+		}
+		return
+	}
+	if wp.schedule != nil && !wp.schedule.Allow(time.Now()) {
+		select {
+		case <-dequeueCtx.Done():
+		case <-time.After(schedulePausePollInterval):
+		}
+		return
+	}
 	job, err := wp.queue.Dequeue(dequeueCtx, workerDequeueTimeout)
 	if err != nil {
 		if errors.Is(err, ErrQueueEmpty) || errors.Is(err, context.Canceled) {
@@ -191,17 +237,51 @@ func (wp *WorkerPool) processNextJob(dequeueCtx context.Context, workerID int) {
 		log.Printf("Worker %d: failed to dequeue job: %v", workerID, err)
 		return
 	}
+	if job.Status == StatusCancelled {
+		log.Printf("Worker %d: skipping cancelled job %s", workerID, job.ID)
+		return
+	}
+
+	if wp.limits != nil {
+		if !wp.limits.TryAcquire(job.SourceType) {
+			wp.deferJob(dequeueCtx, workerID, job)
+			return
+		}
+		defer wp.limits.Release(job.SourceType)
+	}
 
-	log.Printf("Worker %d: processing job %s", workerID, job.ID)
+	log.Printf("Worker %d: processing job %s (request_id=%s)", workerID, job.ID, job.RequestID)
 	wp.processJob(context.Background(), workerID, job)
 }
 
+// deferJob hands a job back to the queue because its provider is at its
+// concurrency limit, then pauses briefly so a queue holding only that
+// provider's jobs does not spin.
+func (wp *WorkerPool) deferJob(ctx context.Context, workerID int, job *DownloadJob) {
+	if err := wp.queue.Defer(context.Background(), job); err != nil {
+		log.Printf("Worker %d: failed to defer job %s at provider limit: %v", workerID, job.ID, err)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(providerDeferInterval):
+	}
+}
+
 // processJob handles the full lifecycle of a single job
 func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *DownloadJob) {
-	jobCtx, cancel := context.WithTimeout(ctx, wp.jobTimeout)
+	jobCtx, cancelJob := context.WithCancelCause(wp.withRateLimit(jobContext(ctx, job)))
+	defer cancelJob(nil)
+	jobCtx, cancel := context.WithTimeout(jobCtx, wp.jobTimeout)
 	defer cancel()
+	wp.trackJob(job.ID, cancelJob)
+	defer wp.untrackJob(job.ID)
 
 	if err := wp.queue.UpdateStatus(ctx, job.ID, StatusDownloading, 0, ""); err != nil {
+		if errors.Is(err, ErrJobCancelled) {
+			log.Printf("Worker %d: job %s was cancelled before it started", workerID, job.ID)
+			return
+		}
 		log.Printf("Worker %d: failed to update job status to downloading: %v", workerID, err)
 		return
 	}
@@ -216,6 +296,9 @@ func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *Downloa
 	}
 
 	progressFn := func(progress int) {
+		if jobCtx.Err() != nil {
+			return
+		}
 		job.Progress = progress
 		if err := wp.queue.UpdateStatus(ctx, job.ID, job.Status, progress, ""); err != nil {
 			log.Printf("Worker %d: failed to update progress: %v", workerID, err)
@@ -229,6 +312,11 @@ func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *Downloa
 
 	err := wp.processor(jobCtx, job, progressFn)
 
+	if errors.Is(context.Cause(jobCtx), ErrJobCancelled) {
+		// CancelJob already recorded the cancellation in both stores.
+		log.Printf("Worker %d: job %s cancelled", workerID, job.ID)
+		return
+	}
 	if err != nil {
 		wp.handleJobFailure(ctx, workerID, job, err)
 		return
@@ -254,13 +342,62 @@ func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *Downloa
 		log.Printf("Worker %d: failed to update job status to complete: %v", workerID, err)
 	}
 
+	wp.observeOutcome(ctx, job, OutcomeSuccess)
 	log.Printf("Worker %d: job %s completed successfully", workerID, job.ID)
 }
 
+func (wp *WorkerPool) trackJob(jobID string, cancel context.CancelCauseFunc) {
+	wp.activeMu.Lock()
+	defer wp.activeMu.Unlock()
+	wp.active[jobID] = cancel
+}
+
+func (wp *WorkerPool) untrackJob(jobID string) {
+	wp.activeMu.Lock()
+	defer wp.activeMu.Unlock()
+	delete(wp.active, jobID)
+}
+
+// cancelJob stops a job running in this pool, which kills its yt-dlp
+// process through the job context. It reports whether the job was running.
+func (wp *WorkerPool) cancelJob(jobID string) bool {
+	wp.activeMu.Lock()
+	cancel, ok := wp.active[jobID]
+	wp.activeMu.Unlock()
+	if ok {
+		cancel(ErrJobCancelled)
+	}
+	return ok
+}
+
+func (wp *WorkerPool) observeOutcome(ctx context.Context, job *DownloadJob, outcome string) {
+	if wp.outcomes != nil {
+		wp.outcomes.ObserveDownloadOutcome(ctx, job, outcome)
+	}
+}
+
+// jobContext carries the job's originating request ID so processor, matcher,
+// and MusicBrainz logs can be correlated with the API request that queued it.
+func jobContext(ctx context.Context, job *DownloadJob) context.Context {
+	if job.RequestID == "" {
+		return ctx
+	}
+	ctx = apperrors.WithRequestID(ctx, job.RequestID)
+	return logger.WithRequestID(ctx, job.RequestID)
+}
+
+// withRateLimit gives the job its share of the schedule's bandwidth cap.
+func (wp *WorkerPool) withRateLimit(ctx context.Context) context.Context {
+	if wp.schedule == nil {
+		return ctx
+	}
+	return WithRateLimit(ctx, wp.schedule.JobRateLimit(wp.workerCount))
+}
+
 // handleJobFailure handles a failed job, implementing retry logic with exponential backoff
 func (wp *WorkerPool) handleJobFailure(ctx context.Context, workerID int, job *DownloadJob, jobErr error) {
 	errMsg := jobErr.Error()
-	log.Printf("Worker %d: job %s failed: %v", workerID, job.ID, jobErr)
+	log.Printf("Worker %d: job %s failed (request_id=%s): %v", workerID, job.ID, job.RequestID, jobErr)
 	if isRetryable(jobErr) && job.RetryCount < wp.maxRetries {
 		retrying := *job
 		retrying.Status = StatusQueued
@@ -302,6 +439,7 @@ func (wp *WorkerPool) handleJobFailure(ctx context.Context, workerID int, job *D
 			log.Printf("Worker %d: failed to mirror job failure for %s: %v", workerID, job.ID, err)
 		}
 	}
+	wp.observeOutcome(ctx, job, ClassifyFailure(jobErr))
 }
 
 // failRetryPreparation reconciles retry setup failures to a terminal state. A
@@ -6,6 +6,9 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	apperrors "github.com/openmusicplayer/backend/internal/errors"
+	"github.com/openmusicplayer/backend/internal/logger"
 )
 
 func workerCountPtr(count int) *int {
@@ -204,3 +207,52 @@ func TestCalculateBackoff(t *testing.T) {
 		}
 	}
 }
+
+func TestJobContextCarriesOriginatingRequestID(t *testing.T) {
+	ctx := jobContext(context.Background(), &DownloadJob{ID: "job-1", RequestID: "req-123"})
+	if got := apperrors.GetRequestID(ctx); got != "req-123" {
+		t.Errorf("apperrors request ID = %q, want req-123", got)
+	}
+	if got := logger.GetRequestID(ctx); got != "req-123" {
+		t.Errorf("logger request ID = %q, want req-123", got)
+	}
+
+	ctx = jobContext(context.Background(), &DownloadJob{ID: "job-2"})
+	if got := logger.GetRequestID(ctx); got != "" {
+		t.Errorf("job without request ID got %q", got)
+	}
+}
+
+func TestWorkerPool_CancelJobStopsRunningJob(t *testing.T) {
+	pool := NewWorkerPool(nil, nil, nil)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	pool.trackJob("job-1", cancel)
+
+	if pool.cancelJob("job-2") {
+		t.Fatal("cancelJob reported a job that is not running")
+	}
+	if !pool.cancelJob("job-1") {
+		t.Fatal("cancelJob did not find the running job")
+	}
+	if !errors.Is(context.Cause(ctx), ErrJobCancelled) {
+		t.Fatalf("job context cause = %v, want ErrJobCancelled", context.Cause(ctx))
+	}
+
+	pool.untrackJob("job-1")
+	if pool.cancelJob("job-1") {
+		t.Fatal("cancelJob found a job that finished")
+	}
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	if got := PriorityFromContext(context.Background()); got != PriorityInteractive {
+		t.Errorf("default priority = %q, want interactive", got)
+	}
+	if got := PriorityFromContext(WithPriority(context.Background(), PriorityBatch)); got != PriorityBatch {
+		t.Errorf("batch priority = %q", got)
+	}
+	if got := PriorityFromContext(WithPriority(context.Background(), "urgent")); got != PriorityInteractive {
+		t.Errorf("unknown priority = %q, want interactive", got)
+	}
+}
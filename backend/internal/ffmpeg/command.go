@@ -0,0 +1,140 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Command builds an ffmpeg argument list. ffmpeg is position sensitive:
+// options before an -i apply to that input and options after the last input
+// apply to the output, so the builder keeps the two apart and Args assembles
+// them in order.
+type Command struct {
+	global   []string
+	inputs   []string
+	pending  []string
+	output   []string
+	progress bool
+}
+
+// NewCommand starts a non-interactive command that logs only errors and
+// never writes the interactive stats line, which would crowd filter output
+// out of the retained stderr.
+func NewCommand() *Command {
+	return &Command{global: []string{"-hide_banner", "-nostdin", "-nostats", "-loglevel", "error"}}
+}
+
+// LogLevel replaces the default "error" log level. Filters such as
+// volumedetect report at "info".
+func (c *Command) LogLevel(level string) *Command {
+	for i := 0; i+1 < len(c.global); i++ {
+		if c.global[i] == "-loglevel" {
+			c.global[i+1] = level
+			return c
+		}
+	}
+	c.global = append(c.global, "-loglevel", level)
+	return c
+}
+
+// Overwrite lets ffmpeg replace an existing output file.
+func (c *Command) Overwrite() *Command {
+	c.global = append(c.global, "-y")
+	return c
+}
+
+// Progress asks ffmpeg for machine-readable progress on stdout, which the
+// Runner parses when given a progress callback.
+func (c *Command) Progress() *Command {
+	c.progress = true
+	return c
+}
+
+// Seek positions the next input at start.
+func (c *Command) Seek(start time.Duration) *Command {
+	c.pending = append(c.pending, "-ss", FormatDuration(start))
+	return c
+}
+
+// Input adds an input file, consuming any pending input options.
+func (c *Command) Input(path string) *Command {
+	c.inputs = append(c.inputs, c.pending...)
+	c.inputs = append(c.inputs, "-i", path)
+	c.pending = nil
+	return c
+}
+
+// Duration limits how much of the input is written to the output.
+func (c *Command) Duration(d time.Duration) *Command {
+	return c.Option("-t", FormatDuration(d))
+}
+
+// Map selects input streams for the output, e.g. "0:a:0".
+func (c *Command) Map(spec string) *Command {
+	return c.Option("-map", spec)
+}
+
+// NoVideo drops video streams such as embedded cover art.
+func (c *Command) NoVideo() *Command {
+	return c.Option("-vn")
+}
+
+// AudioFilter sets the audio filter graph.
+func (c *Command) AudioFilter(graph string) *Command {
+	return c.Option("-af", graph)
+}
+
+// AudioCodec selects the output audio encoder.
+func (c *Command) AudioCodec(codec string) *Command {
+	return c.Option("-c:a", codec)
+}
+
+// AudioBitrate sets the output audio bitrate in kbps.
+func (c *Command) AudioBitrate(kbps int) *Command {
+	return c.Option("-b:a", strconv.Itoa(kbps)+"k")
+}
+
+// SampleRate sets the output sample rate in Hz.
+func (c *Command) SampleRate(hz int) *Command {
+	return c.Option("-ar", strconv.Itoa(hz))
+}
+
+// Channels sets the output channel count.
+func (c *Command) Channels(n int) *Command {
+	return c.Option("-ac", strconv.Itoa(n))
+}
+
+// Format forces the output container format.
+func (c *Command) Format(format string) *Command {
+	return c.Option("-f", format)
+}
+
+// Option appends a raw output option and its values.
+func (c *Command) Option(name string, values ...string) *Command {
+	c.output = append(c.output, name)
+	c.output = append(c.output, values...)
+	return c
+}
+
+// Args returns the argument list ending in target, which may be a path,
+// "pipe:1", or "-" with Format("null") for analysis-only runs.
+func (c *Command) Args(target string) []string {
+	args := make([]string, 0, len(c.global)+len(c.inputs)+len(c.output)+4)
+	args = append(args, c.global...)
+	if c.progress {
+		args = append(args, "-progress", "pipe:1")
+	}
+	args = append(args, c.inputs...)
+	args = append(args, c.output...)
+	return append(args, target)
+}
+
+// FormatDuration renders d as seconds with millisecond precision, which every
+// ffmpeg time option accepts.
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%d.%03d", d/time.Second, (d%time.Second)/time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Call is one invocation recorded by Fake.
+type Call struct {
+	Binary string
+	Args   []string
+}
+
+// Fake is a Runner for tests. It answers capability queries from its
+// Version, Encoders, and Filters fields, sends Progress to every ffmpeg
+// callback, and delegates everything else to Handle. Calls are recorded.
+type Fake struct {
+	Version  string
+	Encoders []string
+	Filters  []string
+	Progress []Progress
+	Handle   func(call Call) (Output, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewFake returns a Fake that reports a typical ffmpeg 6.1 build.
+func NewFake() *Fake {
+	return &Fake{
+		Version:  "6.1.1",
+		Encoders: []string{"aac", "libmp3lame", "libopus", "flac", "pcm_s16le"},
+		Filters:  []string{"loudnorm", "volumedetect", "silencedetect", "aresample", "showwavespic"},
+	}
+}
+
+func (f *Fake) FFmpeg(ctx context.Context, args []string, onProgress func(Progress)) (Output, error) {
+	call := f.record("ffmpeg", args)
+	if err := ctx.Err(); err != nil {
+		return Output{}, err
+	}
+	switch {
+	case len(args) == 1 && args[0] == "-version":
+		return Output{Stdout: "ffmpeg version " + f.Version + " Copyright (c) 2000-2024 the FFmpeg developers\n"}, nil
+	case hasArg(args, "-encoders"):
+		var sb strings.Builder
+		sb.WriteString("Encoders:\n ------\n")
+		for _, name := range f.Encoders {
+			sb.WriteString(" A....D " + name + "  fake encoder\n")
+		}
+		return Output{Stdout: sb.String()}, nil
+	case hasArg(args, "-filters"):
+		var sb strings.Builder
+		sb.WriteString("Filters:\n  A = Audio input/output\n")
+		for _, name := range f.Filters {
+			sb.WriteString(" ... " + name + "  A->A  fake filter\n")
+		}
+		return Output{Stdout: sb.String()}, nil
+	}
+	if onProgress != nil {
+		for _, progress := range f.Progress {
+			onProgress(progress)
+		}
+	}
+	return f.handle(call)
+}
+
+func (f *Fake) FFprobe(ctx context.Context, args []string) (Output, error) {
+	call := f.record("ffprobe", args)
+	if err := ctx.Err(); err != nil {
+		return Output{}, err
+	}
+	return f.handle(call)
+}
+
+// Calls returns the invocations recorded so far.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *Fake) record(binary string, args []string) Call {
+	call := Call{Binary: binary, Args: append([]string(nil), args...)}
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+	return call
+}
+
+func (f *Fake) handle(call Call) (Output, error) {
+	if f.Handle == nil {
+		return Output{}, nil
+	}
+	return f.Handle(call)
+}
+
+func hasArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
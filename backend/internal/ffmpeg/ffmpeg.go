@@ -0,0 +1,152 @@
+// Package ffmpeg wraps the ffmpeg and ffprobe binaries behind a small Runner
+// interface so transcoding, loudness, waveform, and chapter-splitting code can
+// build arguments, follow progress, and check capabilities without shelling
+// out directly. Tests substitute Fake for the real binaries.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// DefaultOutputLimit bounds how much stdout and stderr a run retains.
+const DefaultOutputLimit = 64 * 1024
+
+// ErrNotInstalled reports that the ffmpeg or ffprobe binary is missing.
+var ErrNotInstalled = errors.New("ffmpeg is not installed")
+
+// Output is the retained, possibly truncated, output of one run.
+type Output struct {
+	Stdout string
+	Stderr string
+}
+
+// Runner executes ffmpeg and ffprobe.
+type Runner interface {
+	// FFmpeg runs ffmpeg with args. When onProgress is non-nil the args must
+	// come from a Command with Progress enabled; progress updates are then
+	// read from stdout instead of being retained.
+	FFmpeg(ctx context.Context, args []string, onProgress func(Progress)) (Output, error)
+	// FFprobe runs ffprobe with args.
+	FFprobe(ctx context.Context, args []string) (Output, error)
+}
+
+// ExecRunner runs the real binaries, resolved through PATH on every call.
+type ExecRunner struct {
+	FFmpegPath  string
+	FFprobePath string
+	OutputLimit int
+}
+
+// NewExecRunner creates a runner for the ffmpeg and ffprobe on PATH.
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{FFmpegPath: "ffmpeg", FFprobePath: "ffprobe", OutputLimit: DefaultOutputLimit}
+}
+
+func (r *ExecRunner) FFmpeg(ctx context.Context, args []string, onProgress func(Progress)) (Output, error) {
+	return r.run(ctx, r.FFmpegPath, "ffmpeg", args, onProgress)
+}
+
+func (r *ExecRunner) FFprobe(ctx context.Context, args []string) (Output, error) {
+	return r.run(ctx, r.FFprobePath, "ffprobe", args, nil)
+}
+
+func (r *ExecRunner) run(ctx context.Context, executable, fallback string, args []string, onProgress func(Progress)) (Output, error) {
+	if executable == "" {
+		executable = fallback
+	}
+	limit := r.OutputLimit
+	if limit <= 0 {
+		limit = DefaultOutputLimit
+	}
+	if _, err := exec.LookPath(executable); err != nil {
+		return Output{}, fmt.Errorf("%w: %s: %v", ErrNotInstalled, fallback, err)
+	}
+
+	cmd := exec.CommandContext(ctx, executable, args...)
+	stdout := limitedOutput{limit: limit}
+	stderr := limitedOutput{limit: limit}
+	cmd.Stderr = &stderr
+
+	var wg sync.WaitGroup
+	if onProgress != nil {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return Output{}, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			readProgress(pipe, onProgress)
+		}()
+	} else {
+		cmd.Stdout = &stdout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return Output{}, fmt.Errorf("%s failed to start: %w", fallback, err)
+	}
+	wg.Wait()
+	err := cmd.Wait()
+	out := Output{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		if ctx.Err() != nil {
+			return out, fmt.Errorf("%s timed out or canceled: %w", fallback, ctx.Err())
+		}
+		return out, fmt.Errorf("%s failed: %w: %s", fallback, err, lastLine(out.Stderr))
+	}
+	return out, nil
+}
+
+func readProgress(r io.Reader, onProgress func(Progress)) {
+	var parser ProgressParser
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if progress, ok := parser.Feed(scanner.Text()); ok {
+			onProgress(progress)
+		}
+	}
+	// Drain anything left so ffmpeg never blocks on a full pipe.
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// lastLine returns the final non-empty stderr line, which is where ffmpeg puts
+// the error that ended the run.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+type limitedOutput struct {
+	buf       strings.Builder
+	limit     int
+	truncated bool
+}
+
+func (o *limitedOutput) Write(p []byte) (int, error) {
+	if o.buf.Len() >= o.limit {
+		o.truncated = true
+		return len(p), nil
+	}
+	remaining := o.limit - o.buf.Len()
+	if len(p) > remaining {
+		o.buf.Write(p[:remaining])
+		o.truncated = true
+		return len(p), nil
+	}
+	o.buf.Write(p)
+	return len(p), nil
+}
+
+func (o *limitedOutput) String() string {
+	if o.truncated {
+		return o.buf.String() + "... (output truncated)"
+	}
+	return o.buf.String()
+}
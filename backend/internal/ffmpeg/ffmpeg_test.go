@@ -0,0 +1,175 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandOrdersInputAndOutputOptions(t *testing.T) {
+	args := NewCommand().
+		Overwrite().
+		Progress().
+		Seek(90*time.Second + 250*time.Millisecond).
+		Input("in.flac").
+		Duration(3 * time.Minute).
+		Map("0:a:0").
+		NoVideo().
+		AudioCodec("libopus").
+		AudioBitrate(128).
+		Args("out.opus")
+
+	want := []string{
+		"-hide_banner", "-nostdin", "-nostats", "-loglevel", "error", "-y",
+		"-progress", "pipe:1",
+		"-ss", "90.250", "-i", "in.flac",
+		"-t", "180.000", "-map", "0:a:0", "-vn", "-c:a", "libopus", "-b:a", "128k",
+		"out.opus",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args =\n%q\nwant\n%q", args, want)
+	}
+}
+
+func TestCommandLogLevelReplacesDefault(t *testing.T) {
+	args := NewCommand().LogLevel("info").Input("a.mp3").AudioFilter("volumedetect").Format("null").Args("-")
+	if strings.Join(args, " ") != "-hide_banner -nostdin -nostats -loglevel info -i a.mp3 -af volumedetect -f null -" {
+		t.Fatalf("args = %q", args)
+	}
+}
+
+func TestProgressParserEmitsPerBlock(t *testing.T) {
+	var parser ProgressParser
+	var got []Progress
+	for _, line := range []string{
+		"frame=0", "out_time_us=1500000", "speed=12.5x", "progress=continue",
+		"out_time_ms=3000000", "speed=N/A", "progress=end",
+	} {
+		if progress, ok := parser.Feed(line); ok {
+			got = append(got, progress)
+		}
+	}
+	want := []Progress{
+		{OutTime: 1500 * time.Millisecond, Speed: 12.5},
+		{OutTime: 3 * time.Second, Done: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("progress = %+v, want %+v", got, want)
+	}
+	if pct := got[0].Percent(3 * time.Second); pct != 50 {
+		t.Fatalf("Percent = %d, want 50", pct)
+	}
+	if pct := got[1].Percent(0); pct != 100 {
+		t.Fatalf("done Percent = %d, want 100", pct)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		output   string
+		major    int
+		minor    int
+		snapshot bool
+	}{
+		{"ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023", 6, 1, false},
+		{"ffmpeg version n7.0 Copyright (c) 2000-2024", 7, 0, false},
+		{"ffmpeg version 4.4.2-0ubuntu0.22.04.1 Copyright", 4, 4, false},
+		{"ffmpeg version N-113348-g0a5813fc68-20240115 Copyright", 0, 0, true},
+	}
+	for _, tc := range cases {
+		version, err := ParseVersion(tc.output + "\nbuilt with gcc\n")
+		if err != nil {
+			t.Fatalf("ParseVersion(%q): %v", tc.output, err)
+		}
+		if version.Major != tc.major || version.Minor != tc.minor || version.Snapshot != tc.snapshot {
+			t.Fatalf("ParseVersion(%q) = %+v", tc.output, version)
+		}
+	}
+	if _, err := ParseVersion("avconv version 12"); err == nil {
+		t.Fatal("expected non-ffmpeg output to be rejected")
+	}
+	if !(Version{Major: 6, Minor: 1}).AtLeast(5, 1) || (Version{Major: 4, Minor: 4}).AtLeast(5, 0) {
+		t.Fatal("AtLeast compared releases incorrectly")
+	}
+	if !(Version{Snapshot: true}).AtLeast(99, 0) {
+		t.Fatal("snapshot builds should satisfy any minimum")
+	}
+}
+
+func TestDetectReadsEncodersAndFilters(t *testing.T) {
+	fake := NewFake()
+	fake.Encoders = []string{"libmp3lame"}
+	fake.Filters = []string{"loudnorm"}
+
+	caps, err := Detect(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if caps.Version.Major != 6 || caps.Version.Minor != 1 {
+		t.Fatalf("version = %+v", caps.Version)
+	}
+	if !caps.HasEncoder("libmp3lame") || caps.HasEncoder("libopus") {
+		t.Fatalf("encoders = %v", caps.Encoders)
+	}
+	if !caps.HasFilter("loudnorm") || caps.HasFilter("volumedetect") {
+		t.Fatalf("filters = %v", caps.Filters)
+	}
+	if calls := fake.Calls(); len(calls) != 3 {
+		t.Fatalf("calls = %d, want 3", len(calls))
+	}
+}
+
+func TestParseRealEncoderAndFilterListings(t *testing.T) {
+	encoders := parseEncoders("Encoders:\n V..... = Video\n A..... = Audio\n ------\n A....D aac                  AAC (Advanced Audio Coding)\n A....D libmp3lame           libmp3lame MP3 (MPEG audio layer 3)\n")
+	if !encoders["aac"] || !encoders["libmp3lame"] || encoders["="] {
+		t.Fatalf("encoders = %v", encoders)
+	}
+	filters := parseFilters("Filters:\n  T.. = Timeline support\n  A = Audio input/output\n TSC loudnorm          A->A       EBU R128 loudness normalization\n ... showwavespic      A->V       Convert input audio to a video output single picture.\n")
+	if !filters["loudnorm"] || !filters["showwavespic"] || len(filters) != 2 {
+		t.Fatalf("filters = %v", filters)
+	}
+}
+
+func TestExecRunnerStreamsProgressAndReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+for arg in "$@"; do
+  if [ "$arg" = "fail" ]; then
+    echo "fake: first line" >&2
+    echo "fail: Invalid data found when processing input" >&2
+    exit 1
+  fi
+done
+printf 'out_time_us=500000\nprogress=continue\nout_time_us=1000000\nprogress=end\n'
+`
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	runner := NewExecRunner()
+	var updates []Progress
+	if _, err := runner.FFmpeg(context.Background(), NewCommand().Progress().Input("in.wav").Args("out.mp3"), func(p Progress) {
+		updates = append(updates, p)
+	}); err != nil {
+		t.Fatalf("FFmpeg: %v", err)
+	}
+	if len(updates) != 2 || !updates[1].Done || updates[1].OutTime != time.Second {
+		t.Fatalf("updates = %+v", updates)
+	}
+
+	_, err := runner.FFmpeg(context.Background(), []string{"fail"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Fatalf("err = %v, want last stderr line", err)
+	}
+
+	runner.FFprobePath = filepath.Join(dir, "missing-ffprobe")
+	if _, err := runner.FFprobe(context.Background(), nil); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("missing binary err = %v, want ErrNotInstalled", err)
+	}
+}
@@ -0,0 +1,68 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is one block of ffmpeg -progress output.
+type Progress struct {
+	// OutTime is how much output media has been written so far.
+	OutTime time.Duration
+	// Speed is the processing speed relative to real time; zero if unknown.
+	Speed float64
+	// Done is set on the final block.
+	Done bool
+}
+
+// Percent reports completion against the expected output length, clamped to
+// 0-100. It returns 100 once ffmpeg reports the run is done.
+func (p Progress) Percent(total time.Duration) int {
+	if p.Done {
+		return 100
+	}
+	if total <= 0 {
+		return 0
+	}
+	percent := int(p.OutTime * 100 / total)
+	if percent < 0 {
+		return 0
+	}
+	if percent > 99 {
+		return 99
+	}
+	return percent
+}
+
+// ProgressParser accumulates the key=value lines ffmpeg writes for -progress.
+// Each block ends with a progress=continue or progress=end line.
+type ProgressParser struct {
+	current Progress
+}
+
+// Feed consumes one line and returns a Progress when it completes a block.
+func (p *ProgressParser) Feed(line string) (Progress, bool) {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return Progress{}, false
+	}
+	value = strings.TrimSpace(value)
+	switch key {
+	case "out_time_us", "out_time_ms":
+		// Despite its name, out_time_ms is also in microseconds.
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			p.current.OutTime = time.Duration(us) * time.Microsecond
+		}
+	case "speed":
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+			p.current.Speed = speed
+		}
+	case "progress":
+		done := p.current
+		done.Done = value == "end"
+		p.current = Progress{OutTime: done.OutTime}
+		return done, true
+	}
+	return Progress{}, false
+}
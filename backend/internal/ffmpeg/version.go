@@ -0,0 +1,136 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`^ffmpeg version n?(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// Version is the parsed ffmpeg release. Snapshot builds from git ("N-...")
+// carry no release number and are treated as newer than any release.
+type Version struct {
+	Major    int
+	Minor    int
+	Patch    int
+	Snapshot bool
+	Raw      string
+}
+
+// AtLeast reports whether v is the given release or newer.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Snapshot {
+		return true
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+func (v Version) String() string {
+	return v.Raw
+}
+
+// ParseVersion reads the first line of `ffmpeg -version`.
+func ParseVersion(output string) (Version, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	line = strings.TrimSpace(line)
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "ffmpeg" || fields[1] != "version" {
+		return Version{}, fmt.Errorf("unrecognized ffmpeg version output %q", line)
+	}
+	version := Version{Raw: fields[2]}
+	if strings.HasPrefix(version.Raw, "N-") {
+		version.Snapshot = true
+		return version, nil
+	}
+	match := versionPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Version{}, fmt.Errorf("unrecognized ffmpeg version %q", version.Raw)
+	}
+	version.Major, _ = strconv.Atoi(match[1])
+	version.Minor, _ = strconv.Atoi(match[2])
+	version.Patch, _ = strconv.Atoi(match[3])
+	return version, nil
+}
+
+// Capabilities describes what the installed ffmpeg build can do.
+type Capabilities struct {
+	Version  Version
+	Encoders map[string]bool
+	Filters  map[string]bool
+}
+
+// HasEncoder reports whether the build includes the named encoder.
+func (c *Capabilities) HasEncoder(name string) bool {
+	return c != nil && c.Encoders[name]
+}
+
+// HasFilter reports whether the build includes the named filter.
+func (c *Capabilities) HasFilter(name string) bool {
+	return c != nil && c.Filters[name]
+}
+
+// Detect queries the ffmpeg build's version, encoders, and filters so callers
+// can disable features the build cannot serve instead of failing per job.
+func Detect(ctx context.Context, runner Runner) (*Capabilities, error) {
+	out, err := runner.FFmpeg(ctx, []string{"-version"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	version, err := ParseVersion(out.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	encoders, err := runner.FFmpeg(ctx, []string{"-hide_banner", "-encoders"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := runner.FFmpeg(ctx, []string{"-hide_banner", "-filters"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Capabilities{
+		Version:  version,
+		Encoders: parseEncoders(encoders.Stdout),
+		Filters:  parseFilters(filters.Stdout),
+	}, nil
+}
+
+// parseEncoders reads `ffmpeg -encoders`, whose entries follow a "------"
+// separator as "<flags> <name> <description>".
+func parseEncoders(output string) map[string]bool {
+	encoders := make(map[string]bool)
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !listing {
+			listing = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// parseFilters reads `ffmpeg -filters`, whose entries are
+// "<flags> <name> <in>-><out> <description>".
+func parseFilters(output string) map[string]bool {
+	filters := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			filters[fields[1]] = true
+		}
+	}
+	return filters
+}
@@ -0,0 +1,121 @@
+package fingerprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAcoustIDURL is AcoustID's lookup endpoint. See
+	// https://acoustid.org/webservice.
+	DefaultAcoustIDURL = "https://api.acoustid.org/v2/lookup"
+
+	acoustIDUserAgent = "OpenMusicPlayer/1.0.0 (fingerprint)"
+	// acoustIDMaxResponseBytes bounds how much of a response is read.
+	acoustIDMaxResponseBytes = 1 << 20
+)
+
+// Match is a MusicBrainz recording AcoustID links to a fingerprint. Score
+// is AcoustID's fingerprint similarity from 0 to 1.
+type Match struct {
+	RecordingID string  `json:"recording_id"`
+	Score       float64 `json:"score"`
+}
+
+// AcoustID looks fingerprints up with an application API key registered at
+// https://acoustid.org/new-application.
+type AcoustID struct {
+	apiKey     string
+	lookupURL  string
+	httpClient *http.Client
+}
+
+// NewAcoustID creates a client for the public AcoustID service.
+func NewAcoustID(apiKey string, httpClient *http.Client) *AcoustID {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &AcoustID{apiKey: apiKey, lookupURL: DefaultAcoustIDURL, httpClient: httpClient}
+}
+
+type acoustIDResponse struct {
+	Status string `json:"status"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Results []struct {
+		Score      float64 `json:"score"`
+		Recordings []struct {
+			ID string `json:"id"`
+		} `json:"recordings"`
+	} `json:"results"`
+}
+
+// Lookup returns the recordings linked to fp, best score first, each
+// recording once.
+func (a *AcoustID) Lookup(ctx context.Context, fp *Fingerprint) ([]Match, error) {
+	form := url.Values{
+		"client":      {a.apiKey},
+		"meta":        {"recordingids"},
+		"duration":    {strconv.Itoa(fp.Duration)},
+		"fingerprint": {fp.Value},
+	}
+	// Fingerprints run to several kilobytes, too long for a query string.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.lookupURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", acoustIDUserAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("acoustid lookup: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, acoustIDMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read acoustid response: %w", err)
+	}
+
+	var out acoustIDResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("acoustid lookup: HTTP %d", resp.StatusCode)
+	}
+	if out.Status != "ok" {
+		message := "unknown error"
+		if out.Error != nil && out.Error.Message != "" {
+			message = out.Error.Message
+		}
+		return nil, errors.New("acoustid lookup: " + message)
+	}
+
+	best := map[string]float64{}
+	for _, result := range out.Results {
+		for _, recording := range result.Recordings {
+			if score, ok := best[recording.ID]; !ok || result.Score > score {
+				best[recording.ID] = result.Score
+			}
+		}
+	}
+	matches := make([]Match, 0, len(best))
+	for id, score := range best {
+		matches = append(matches, Match{RecordingID: id, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].RecordingID < matches[j].RecordingID
+	})
+	return matches, nil
+}
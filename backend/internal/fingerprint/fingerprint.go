@@ -0,0 +1,110 @@
+// Package fingerprint identifies audio by its acoustic fingerprint. It runs
+// Chromaprint's fpcalc on a local file and looks the fingerprint up on
+// AcoustID, which maps it to MusicBrainz recording IDs independent of how
+// the upload was titled.
+package fingerprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultLength is how many seconds of audio fpcalc fingerprints. AcoustID
+// computes its own fingerprints from the first two minutes.
+const DefaultLength = 120
+
+// ErrNotInstalled reports that the fpcalc binary is missing.
+var ErrNotInstalled = errors.New("fpcalc is not installed")
+
+// Fingerprint is a Chromaprint fingerprint of one audio file.
+type Fingerprint struct {
+	// Duration is the whole file's length in seconds, which AcoustID uses
+	// to narrow the lookup.
+	Duration int
+	Value    string
+}
+
+// Fpcalc runs Chromaprint's fpcalc command-line tool.
+type Fpcalc struct {
+	Path    string
+	Length  int
+	Timeout time.Duration
+}
+
+// NewFpcalc creates a calculator for the fpcalc on PATH.
+func NewFpcalc() *Fpcalc {
+	return &Fpcalc{Path: "fpcalc", Length: DefaultLength, Timeout: 60 * time.Second}
+}
+
+// Compute fingerprints the audio file at path.
+func (f *Fpcalc) Compute(ctx context.Context, path string) (*Fingerprint, error) {
+	executable := f.Path
+	if executable == "" {
+		executable = "fpcalc"
+	}
+	if _, err := exec.LookPath(executable); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotInstalled, err)
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	length := f.Length
+	if length <= 0 {
+		length = DefaultLength
+	}
+
+	cmd := exec.CommandContext(ctx, executable, "-json", "-length", fmt.Sprint(length), path)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("fpcalc timed out or canceled: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("fpcalc failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseFpcalcOutput(stdout)
+}
+
+func parseFpcalcOutput(stdout []byte) (*Fingerprint, error) {
+	var out struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		return nil, fmt.Errorf("parse fpcalc output: %w", err)
+	}
+	if out.Fingerprint == "" || out.Duration <= 0 {
+		return nil, errors.New("fpcalc returned no fingerprint")
+	}
+	return &Fingerprint{Duration: int(out.Duration + 0.5), Value: out.Fingerprint}, nil
+}
+
+// Identifier fingerprints a file and looks it up on AcoustID.
+type Identifier struct {
+	fpcalc   *Fpcalc
+	acoustID *AcoustID
+}
+
+// NewIdentifier creates an Identifier from a calculator and an AcoustID
+// client.
+func NewIdentifier(fpcalc *Fpcalc, acoustID *AcoustID) *Identifier {
+	return &Identifier{fpcalc: fpcalc, acoustID: acoustID}
+}
+
+// Identify returns the MusicBrainz recordings whose AcoustID fingerprints
+// match the audio at path, best first. No matches is not an error.
+func (i *Identifier) Identify(ctx context.Context, path string) ([]Match, error) {
+	fp, err := i.fpcalc.Compute(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return i.acoustID.Lookup(ctx, fp)
+}
@@ -0,0 +1,70 @@
+package fingerprint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFpcalcOutput(t *testing.T) {
+	fp, err := parseFpcalcOutput([]byte(`{"duration": 243.71, "fingerprint": "AQADtMmybfGO8NCNEESLnzHyXNOHeHnG"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp.Duration != 244 || fp.Value != "AQADtMmybfGO8NCNEESLnzHyXNOHeHnG" {
+		t.Fatalf("fingerprint = %+v", fp)
+	}
+	if _, err := parseFpcalcOutput([]byte(`{"duration": 0, "fingerprint": ""}`)); err == nil {
+		t.Fatal("empty fpcalc output parsed without error")
+	}
+}
+
+func TestAcoustIDLookupMergesRecordingsBestFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("client") != "key" || r.PostForm.Get("duration") != "244" ||
+			r.PostForm.Get("fingerprint") != "AQAD" || r.PostForm.Get("meta") != "recordingids" {
+			t.Errorf("form = %v", r.PostForm)
+		}
+		w.Write([]byte(`{"status":"ok","results":[
+			{"id":"a","score":0.62,"recordings":[{"id":"rec-2"},{"id":"rec-1"}]},
+			{"id":"b","score":0.97,"recordings":[{"id":"rec-1"}]},
+			{"id":"c","score":0.4}
+		]}`))
+	}))
+	defer server.Close()
+	client := NewAcoustID("key", server.Client())
+	client.lookupURL = server.URL
+
+	matches, err := client.Lookup(context.Background(), &Fingerprint{Duration: 244, Value: "AQAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{{RecordingID: "rec-1", Score: 0.97}, {RecordingID: "rec-2", Score: 0.62}}
+	if !reflect.DeepEqual(matches, want) {
+		t.Fatalf("matches = %+v, want %+v", matches, want)
+	}
+}
+
+func TestAcoustIDLookupReportsServiceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","error":{"code":4,"message":"invalid API key"}}`))
+	}))
+	defer server.Close()
+	client := NewAcoustID("bad", server.Client())
+	client.lookupURL = server.URL
+
+	_, err := client.Lookup(context.Background(), &Fingerprint{Duration: 10, Value: "AQAD"})
+	if err == nil || !strings.Contains(err.Error(), "invalid API key") {
+		t.Fatalf("err = %v, want the AcoustID message", err)
+	}
+}
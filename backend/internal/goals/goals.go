@@ -0,0 +1,288 @@
+// Package goals tracks optional listening goals: progress for the current
+// period, streaks of consecutive periods met, and nudge notifications.
+package goals
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	_ "time/tzdata" // zone names must resolve even on hosts without zoneinfo
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Goal kinds.
+const (
+	KindMinutesPerWeek     = "minutes_per_week"
+	KindNewArtistsPerMonth = "new_artists_per_month"
+)
+
+// Streaks look back at most this many periods before the current one.
+const (
+	weekStreakLookback  = 52
+	monthStreakLookback = 24
+)
+
+var (
+	ErrInvalidKind   = errors.New("invalid goal kind")
+	ErrInvalidTarget = errors.New("invalid goal target")
+)
+
+// kindSpec describes how a goal kind is measured.
+type kindSpec struct {
+	maxTarget int
+	monthly   bool
+	// nudgeWindow is how close to the end of an unmet period a nudge is sent.
+	nudgeWindow time.Duration
+}
+
+var kinds = map[string]kindSpec{
+	// A week has 10080 minutes.
+	KindMinutesPerWeek:     {maxTarget: 10080, nudgeWindow: 2 * 24 * time.Hour},
+	KindNewArtistsPerMonth: {maxTarget: 1000, monthly: true, nudgeWindow: 7 * 24 * time.Hour},
+}
+
+// ValidKind reports whether kind is a known goal kind.
+func ValidKind(kind string) bool {
+	_, ok := kinds[kind]
+	return ok
+}
+
+// MaxTarget returns the largest target accepted for kind.
+func MaxTarget(kind string) int {
+	return kinds[kind].maxTarget
+}
+
+// Store is the persistence goals need; *db.ListeningGoalRepository
+// implements it.
+type Store interface {
+	ListGoals(ctx context.Context, userID uuid.UUID) ([]db.ListeningGoal, error)
+	CreateGoal(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error)
+	UpdateGoalTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error)
+	DeleteGoal(ctx context.Context, userID uuid.UUID, kind string) error
+	UsersWithGoals(ctx context.Context) ([]uuid.UUID, error)
+	WeeklyMinutes(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]db.GoalPeriodTotal, error)
+	MonthlyNewArtists(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]db.GoalPeriodTotal, error)
+	RecordGoalNotification(ctx context.Context, goalID int64, kind string, period string, payload []byte) (bool, error)
+}
+
+// TimeZoneStore looks up the IANA zone a user's periods are bucketed in;
+// *db.UserRepository implements it.
+type TimeZoneStore interface {
+	TimeZone(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// Status is a goal's progress in the period containing now.
+type Status struct {
+	Goal        db.ListeningGoal
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Progress    int
+	Met         bool
+	// CurrentStreak counts consecutive met periods ending with the current
+	// one, or with the previous one while the current period is unmet.
+	CurrentStreak int
+	BestStreak    int
+}
+
+// Percent is progress as a share of the target, capped at 100.
+func (s Status) Percent() int {
+	if s.Goal.Target <= 0 || s.Progress >= s.Goal.Target {
+		return 100
+	}
+	return s.Progress * 100 / s.Goal.Target
+}
+
+// Service manages goals and measures them against play history.
+type Service struct {
+	store     Store
+	timeZones TimeZoneStore
+	now       func() time.Time
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// SetTimeZones makes weeks and months start at local midnight in each user's
+// own time zone instead of UTC.
+func (s *Service) SetTimeZones(zones TimeZoneStore) {
+	s.timeZones = zones
+}
+
+func validate(kind string, target int) error {
+	spec, ok := kinds[kind]
+	if !ok {
+		return ErrInvalidKind
+	}
+	if target < 1 || target > spec.maxTarget {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Create adds a goal; the store reports db.ErrListeningGoalExists when the
+// user already has one of that kind.
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	if err := validate(kind, target); err != nil {
+		return nil, err
+	}
+	return s.store.CreateGoal(ctx, userID, kind, target)
+}
+
+// UpdateTarget changes a goal's target. Streaks are always measured against
+// the current target, including for past periods.
+func (s *Service) UpdateTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	if err := validate(kind, target); err != nil {
+		return nil, err
+	}
+	return s.store.UpdateGoalTarget(ctx, userID, kind, target)
+}
+
+// Delete removes a goal.
+func (s *Service) Delete(ctx context.Context, userID uuid.UUID, kind string) error {
+	if !ValidKind(kind) {
+		return ErrInvalidKind
+	}
+	return s.store.DeleteGoal(ctx, userID, kind)
+}
+
+// Statuses returns the progress of each of the user's goals and the time zone
+// their periods were computed in.
+func (s *Service) Statuses(ctx context.Context, userID uuid.UUID) ([]Status, *time.Location, error) {
+	loc := s.location(ctx, userID)
+	goals, err := s.store.ListGoals(ctx, userID)
+	if err != nil {
+		return nil, loc, err
+	}
+	now := s.now()
+	statuses := make([]Status, 0, len(goals))
+	for _, goal := range goals {
+		status, err := s.evaluate(ctx, goal, now, loc)
+		if err != nil {
+			return nil, loc, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, loc, nil
+}
+
+func (s *Service) evaluate(ctx context.Context, goal db.ListeningGoal, now time.Time, loc *time.Location) (Status, error) {
+	spec, ok := kinds[goal.Kind]
+	if !ok {
+		return Status{}, ErrInvalidKind
+	}
+	current := periodStart(spec, now, loc)
+	first := periodStart(spec, goal.CreatedAt, loc)
+	if earliest := addPeriods(spec, current, -lookback(spec)); first.Before(earliest) {
+		first = earliest
+	}
+
+	var totals []db.GoalPeriodTotal
+	var err error
+	if goal.Kind == KindMinutesPerWeek {
+		totals, err = s.store.WeeklyMinutes(ctx, goal.UserID, first, loc.String())
+	} else {
+		totals, err = s.store.MonthlyNewArtists(ctx, goal.UserID, first, loc.String())
+	}
+	if err != nil {
+		return Status{}, err
+	}
+	byPeriod := make(map[string]int, len(totals))
+	for _, total := range totals {
+		byPeriod[dateKey(total.Start)] = total.Value
+	}
+
+	status := Status{
+		Goal:        goal,
+		PeriodStart: current,
+		PeriodEnd:   addPeriods(spec, current, 1),
+		Progress:    byPeriod[dateKey(current)],
+	}
+	status.Met = status.Progress >= goal.Target
+
+	var met []bool
+	for p := first; !p.After(current); p = addPeriods(spec, p, 1) {
+		met = append(met, byPeriod[dateKey(p)] >= goal.Target)
+	}
+	status.CurrentStreak, status.BestStreak = streaks(met)
+	return status, nil
+}
+
+// streaks returns the run of met periods ending at the last entry (or the one
+// before it when the last, still open, period is unmet) and the longest run.
+func streaks(met []bool) (current, best int) {
+	run := 0
+	for _, ok := range met {
+		if ok {
+			run++
+		} else {
+			run = 0
+		}
+		best = max(best, run)
+	}
+	end := len(met)
+	if end > 0 && !met[end-1] {
+		end--
+	}
+	for i := end - 1; i >= 0 && met[i]; i-- {
+		current++
+	}
+	return current, best
+}
+
+func lookback(spec kindSpec) int {
+	if spec.monthly {
+		return monthStreakLookback
+	}
+	return weekStreakLookback
+}
+
+// periodStart returns local midnight on the Monday of t's week, or on the
+// first of t's month for monthly goals.
+func periodStart(spec kindSpec, t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	if spec.monthly {
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	}
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func addPeriods(spec kindSpec, start time.Time, n int) time.Time {
+	if spec.monthly {
+		return start.AddDate(0, n, 0)
+	}
+	return start.AddDate(0, 0, 7*n)
+}
+
+// dateKey is the calendar date of t in its own location, matching the DATE
+// values the store returns as UTC midnights.
+func dateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// location resolves the user's stored time zone, falling back to UTC when none
+// is set or it cannot be loaded.
+func (s *Service) location(ctx context.Context, userID uuid.UUID) *time.Location {
+	if s.timeZones == nil {
+		return time.UTC
+	}
+	name, err := s.timeZones.TimeZone(ctx, userID)
+	if err != nil {
+		log.Printf("Warning: failed to load time zone for user %s: %v", userID, err)
+		return time.UTC
+	}
+	if name == "" || name == "Local" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,269 @@
+package goals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type sentNotification struct {
+	goalID  int64
+	kind    string
+	period  string
+	payload []byte
+}
+
+type fakeStore struct {
+	goals      map[uuid.UUID][]db.ListeningGoal
+	weekly     []db.GoalPeriodTotal
+	monthly    []db.GoalPeriodTotal
+	timeZones  []string
+	since      []time.Time
+	sent       []sentNotification
+	notifiedBy map[string]bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{goals: map[uuid.UUID][]db.ListeningGoal{}, notifiedBy: map[string]bool{}}
+}
+
+func (f *fakeStore) ListGoals(ctx context.Context, userID uuid.UUID) ([]db.ListeningGoal, error) {
+	return f.goals[userID], nil
+}
+
+func (f *fakeStore) CreateGoal(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	goal := db.ListeningGoal{ID: int64(len(f.goals[userID]) + 1), UserID: userID, Kind: kind, Target: target}
+	f.goals[userID] = append(f.goals[userID], goal)
+	return &goal, nil
+}
+
+func (f *fakeStore) UpdateGoalTarget(ctx context.Context, userID uuid.UUID, kind string, target int) (*db.ListeningGoal, error) {
+	return nil, db.ErrListeningGoalNotFound
+}
+
+func (f *fakeStore) DeleteGoal(ctx context.Context, userID uuid.UUID, kind string) error {
+	return db.ErrListeningGoalNotFound
+}
+
+func (f *fakeStore) UsersWithGoals(ctx context.Context) ([]uuid.UUID, error) {
+	var users []uuid.UUID
+	for userID := range f.goals {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (f *fakeStore) WeeklyMinutes(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]db.GoalPeriodTotal, error) {
+	f.since = append(f.since, since)
+	f.timeZones = append(f.timeZones, timeZone)
+	return f.weekly, nil
+}
+
+func (f *fakeStore) MonthlyNewArtists(ctx context.Context, userID uuid.UUID, since time.Time, timeZone string) ([]db.GoalPeriodTotal, error) {
+	f.since = append(f.since, since)
+	f.timeZones = append(f.timeZones, timeZone)
+	return f.monthly, nil
+}
+
+func (f *fakeStore) RecordGoalNotification(ctx context.Context, goalID int64, kind string, period string, payload []byte) (bool, error) {
+	key := kind + "/" + period
+	if f.notifiedBy[key] {
+		return false, nil
+	}
+	f.notifiedBy[key] = true
+	f.sent = append(f.sent, sentNotification{goalID: goalID, kind: kind, period: period, payload: payload})
+	return true, nil
+}
+
+type fakeTimeZones map[uuid.UUID]string
+
+func (f fakeTimeZones) TimeZone(ctx context.Context, userID uuid.UUID) (string, error) {
+	return f[userID], nil
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestService(store *fakeStore, now time.Time) *Service {
+	s := NewService(store)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestPeriodStart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekly, monthly := kinds[KindMinutesPerWeek], kinds[KindNewArtistsPerMonth]
+	cases := []struct {
+		spec kindSpec
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{weekly, time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), time.UTC, "2026-10-12"},
+		{weekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.UTC, "2026-10-12"},
+		{weekly, time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), time.UTC, "2026-10-12"},
+		// Sunday 23:30 UTC is already Monday in Berlin.
+		{weekly, time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC), berlin, "2026-10-19"},
+		{monthly, time.Date(2026, 10, 31, 23, 30, 0, 0, time.UTC), time.UTC, "2026-10-01"},
+		{monthly, time.Date(2026, 10, 31, 23, 30, 0, 0, time.UTC), berlin, "2026-11-01"},
+	}
+	for _, tc := range cases {
+		got := periodStart(tc.spec, tc.t, tc.loc)
+		if dateKey(got) != tc.want || got.Hour() != 0 || got.Location() != tc.loc {
+			t.Fatalf("periodStart(%s, %s) = %s, want %s local midnight", tc.t, tc.loc, got, tc.want)
+		}
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	cases := []struct {
+		met           []bool
+		current, best int
+	}{
+		{nil, 0, 0},
+		{[]bool{false}, 0, 0},
+		{[]bool{true}, 1, 1},
+		// An unmet current period does not break the streak yet.
+		{[]bool{true, true, false}, 2, 2},
+		{[]bool{true, true, true, false, true}, 1, 3},
+		{[]bool{true, false, false}, 0, 1},
+		{[]bool{false, true, true, true}, 3, 3},
+	}
+	for _, tc := range cases {
+		current, best := streaks(tc.met)
+		if current != tc.current || best != tc.best {
+			t.Fatalf("streaks(%v) = %d, %d, want %d, %d", tc.met, current, best, tc.current, tc.best)
+		}
+	}
+}
+
+func TestStatusesMeasureProgressAndStreaks(t *testing.T) {
+	store := newFakeStore()
+	userID := uuid.New()
+	store.goals[userID] = []db.ListeningGoal{
+		{ID: 1, UserID: userID, Kind: KindMinutesPerWeek, Target: 300, CreatedAt: time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)},
+		{ID: 2, UserID: userID, Kind: KindNewArtistsPerMonth, Target: 5, CreatedAt: time.Date(2026, 7, 3, 9, 0, 0, 0, time.UTC)},
+	}
+	store.weekly = []db.GoalPeriodTotal{
+		{Start: date(2026, 9, 21), Value: 400},
+		{Start: date(2026, 9, 28), Value: 310},
+		{Start: date(2026, 10, 5), Value: 300},
+		{Start: date(2026, 10, 12), Value: 120},
+	}
+	store.monthly = []db.GoalPeriodTotal{
+		{Start: date(2026, 7, 1), Value: 6},
+		{Start: date(2026, 8, 1), Value: 2},
+		{Start: date(2026, 9, 1), Value: 9},
+		{Start: date(2026, 10, 1), Value: 5},
+	}
+	s := newTestService(store, time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+
+	statuses, loc, err := s.Statuses(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("statuses: %v", err)
+	}
+	if loc != time.UTC || len(statuses) != 2 {
+		t.Fatalf("loc = %s, statuses = %+v", loc, statuses)
+	}
+
+	weekly := statuses[0]
+	if dateKey(weekly.PeriodStart) != "2026-10-12" || dateKey(weekly.PeriodEnd) != "2026-10-19" {
+		t.Fatalf("weekly period = %s..%s", weekly.PeriodStart, weekly.PeriodEnd)
+	}
+	if weekly.Progress != 120 || weekly.Met || weekly.Percent() != 40 {
+		t.Fatalf("weekly = %+v, percent %d", weekly, weekly.Percent())
+	}
+	if weekly.CurrentStreak != 3 || weekly.BestStreak != 3 {
+		t.Fatalf("weekly streaks = %d/%d, want 3/3", weekly.CurrentStreak, weekly.BestStreak)
+	}
+	// Streaks start with the week the goal was created in.
+	if dateKey(store.since[0]) != "2026-08-31" {
+		t.Fatalf("weekly since = %s, want creation week", store.since[0])
+	}
+
+	monthly := statuses[1]
+	if monthly.Progress != 5 || !monthly.Met || monthly.Percent() != 100 {
+		t.Fatalf("monthly = %+v", monthly)
+	}
+	if monthly.CurrentStreak != 2 || monthly.BestStreak != 2 {
+		t.Fatalf("monthly streaks = %d/%d, want 2/2", monthly.CurrentStreak, monthly.BestStreak)
+	}
+}
+
+func TestStatusesLookBackAtMostAYear(t *testing.T) {
+	store := newFakeStore()
+	userID := uuid.New()
+	store.goals[userID] = []db.ListeningGoal{
+		{ID: 1, UserID: userID, Kind: KindMinutesPerWeek, Target: 60, CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	s := newTestService(store, time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+
+	if _, _, err := s.Statuses(context.Background(), userID); err != nil {
+		t.Fatalf("statuses: %v", err)
+	}
+	if want := date(2025, 10, 13); !store.since[0].Equal(want) {
+		t.Fatalf("since = %s, want %s", store.since[0], want)
+	}
+}
+
+func TestStatusesUseUserTimeZone(t *testing.T) {
+	store := newFakeStore()
+	userID := uuid.New()
+	store.goals[userID] = []db.ListeningGoal{
+		{ID: 1, UserID: userID, Kind: KindMinutesPerWeek, Target: 60, CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	// Sunday evening in UTC is Monday morning in Tokyo.
+	s := newTestService(store, time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC))
+	s.SetTimeZones(fakeTimeZones{userID: "Asia/Tokyo"})
+
+	statuses, loc, err := s.Statuses(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("statuses: %v", err)
+	}
+	if loc.String() != "Asia/Tokyo" || store.timeZones[0] != "Asia/Tokyo" {
+		t.Fatalf("loc = %s, store zone = %s", loc, store.timeZones[0])
+	}
+	if dateKey(statuses[0].PeriodStart) != "2026-10-19" {
+		t.Fatalf("period start = %s, want the Tokyo week", statuses[0].PeriodStart)
+	}
+}
+
+func TestCreateValidatesKindAndTarget(t *testing.T) {
+	s := newTestService(newFakeStore(), time.Now())
+	userID := uuid.New()
+	cases := []struct {
+		kind   string
+		target int
+		want   error
+	}{
+		{"hours_per_day", 1, ErrInvalidKind},
+		{KindMinutesPerWeek, 0, ErrInvalidTarget},
+		{KindMinutesPerWeek, 10081, ErrInvalidTarget},
+		{KindNewArtistsPerMonth, 1001, ErrInvalidTarget},
+		{KindMinutesPerWeek, 10080, nil},
+	}
+	for _, tc := range cases {
+		if _, err := s.Create(context.Background(), userID, tc.kind, tc.target); err != tc.want {
+			t.Fatalf("Create(%s, %d) = %v, want %v", tc.kind, tc.target, err, tc.want)
+		}
+	}
+}
+
+func TestPercentCapsAtHundred(t *testing.T) {
+	status := Status{Goal: db.ListeningGoal{Target: 3}, Progress: 7}
+	if status.Percent() != 100 {
+		t.Fatalf("percent = %d", status.Percent())
+	}
+	status = Status{Goal: db.ListeningGoal{Target: 3}, Progress: 1}
+	if status.Percent() != 33 {
+		t.Fatalf("percent = %d", status.Percent())
+	}
+}
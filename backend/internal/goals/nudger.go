@@ -0,0 +1,170 @@
+package goals
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Report summarizes one nudge pass.
+type Report struct {
+	Users    int
+	Met      int
+	Nudged   int
+	Failures int
+}
+
+// Nudger notifies users about their goals: goal_met once per period when a
+// goal is reached, and goal_nudge once per period when a goal is still unmet
+// near the end of it. Start runs a pass immediately and then once a day at
+// the configured UTC hour.
+type Nudger struct {
+	service *Service
+	hourUTC int
+
+	mu      sync.Mutex
+	running bool
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewNudger(service *Service, hourUTC int) *Nudger {
+	return &Nudger{service: service, hourUTC: hourUTC}
+}
+
+// NudgeUser sends any notifications due for the user's goals and returns how
+// many goal_met and goal_nudge notifications were sent.
+func (n *Nudger) NudgeUser(ctx context.Context, userID uuid.UUID) (met, nudged int, err error) {
+	statuses, loc, err := n.service.Statuses(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	now := n.service.now()
+	for _, status := range statuses {
+		kind := ""
+		switch {
+		case status.Met:
+			kind = db.NotificationKindGoalMet
+		case status.PeriodEnd.Sub(now) <= kinds[status.Goal.Kind].nudgeWindow:
+			kind = db.NotificationKindGoalNudge
+		default:
+			continue
+		}
+		payload, err := json.Marshal(map[string]any{
+			"goalKind":    status.Goal.Kind,
+			"target":      status.Goal.Target,
+			"progress":    status.Progress,
+			"periodStart": dateKey(status.PeriodStart),
+			"periodEnd":   dateKey(status.PeriodEnd),
+			"timeZone":    loc.String(),
+		})
+		if err != nil {
+			return met, nudged, err
+		}
+		sent, err := n.service.store.RecordGoalNotification(ctx, status.Goal.ID, kind, dateKey(status.PeriodStart), payload)
+		if err != nil {
+			return met, nudged, err
+		}
+		if sent && kind == db.NotificationKindGoalMet {
+			met++
+		} else if sent {
+			nudged++
+		}
+	}
+	return met, nudged, nil
+}
+
+// NudgeAll checks every user with goals. A failure for one user is logged and
+// counted without stopping the pass.
+func (n *Nudger) NudgeAll(ctx context.Context) (Report, error) {
+	users, err := n.service.store.UsersWithGoals(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Users: len(users)}
+	for _, userID := range users {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		met, nudged, err := n.NudgeUser(ctx, userID)
+		report.Met += met
+		report.Nudged += nudged
+		if err != nil {
+			report.Failures++
+			log.Printf("Warning: goal nudges failed for user %s: %v", userID, err)
+		}
+	}
+	return report, nil
+}
+
+// Start launches the nudge loop in the background.
+func (n *Nudger) Start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n.running = true
+	n.stop = cancel
+	n.wg.Add(1)
+	go n.loop(ctx)
+}
+
+// Stop cancels the loop and waits for an in-flight pass to return.
+func (n *Nudger) Stop(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return nil
+	}
+	n.running = false
+	n.stop()
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { n.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Nudger) loop(ctx context.Context) {
+	defer n.wg.Done()
+	for {
+		report, err := n.NudgeAll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: goal nudge pass failed: %v", err)
+		} else if err == nil {
+			log.Printf("Goal nudge pass completed: users=%d met=%d nudged=%d failures=%d", report.Users, report.Met, report.Nudged, report.Failures)
+		}
+
+		timer := time.NewTimer(time.Until(nextRun(n.service.now(), n.hourUTC)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRun returns the next time strictly after now at hourUTC:00 UTC.
+func nextRun(now time.Time, hourUTC int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hourUTC, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,107 @@
+package goals
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func TestNudgeUserSendsMetOncePerPeriod(t *testing.T) {
+	store := newFakeStore()
+	userID := uuid.New()
+	store.goals[userID] = []db.ListeningGoal{
+		{ID: 7, UserID: userID, Kind: KindMinutesPerWeek, Target: 300, CreatedAt: date(2026, 10, 1)},
+	}
+	store.weekly = []db.GoalPeriodTotal{{Start: date(2026, 10, 12), Value: 320}}
+	n := NewNudger(newTestService(store, time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)), 18)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := n.NudgeUser(context.Background(), userID); err != nil {
+			t.Fatalf("nudge: %v", err)
+		}
+	}
+	if len(store.sent) != 1 {
+		t.Fatalf("sent = %+v, want one goal_met", store.sent)
+	}
+	sent := store.sent[0]
+	if sent.goalID != 7 || sent.kind != db.NotificationKindGoalMet || sent.period != "2026-10-12" {
+		t.Fatalf("sent = %+v", sent)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(sent.payload, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload["goalKind"] != KindMinutesPerWeek || payload["progress"] != float64(320) || payload["periodEnd"] != "2026-10-19" {
+		t.Fatalf("payload = %v", payload)
+	}
+}
+
+func TestNudgeUserNudgesOnlyNearPeriodEnd(t *testing.T) {
+	userID := uuid.New()
+	cases := []struct {
+		name string
+		now  time.Time
+		kind string
+		want int
+	}{
+		{"weekly midweek", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC), KindMinutesPerWeek, 0},
+		{"weekly saturday", time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC), KindMinutesPerWeek, 1},
+		{"monthly mid-month", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC), KindNewArtistsPerMonth, 0},
+		{"monthly last week", time.Date(2026, 10, 25, 18, 0, 0, 0, time.UTC), KindNewArtistsPerMonth, 1},
+	}
+	for _, tc := range cases {
+		store := newFakeStore()
+		store.goals[userID] = []db.ListeningGoal{
+			{ID: 1, UserID: userID, Kind: tc.kind, Target: 100, CreatedAt: date(2026, 10, 1)},
+		}
+		n := NewNudger(newTestService(store, tc.now), 18)
+
+		met, nudged, err := n.NudgeUser(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("%s: nudge: %v", tc.name, err)
+		}
+		if met != 0 || nudged != tc.want {
+			t.Fatalf("%s: met=%d nudged=%d, want %d nudges", tc.name, met, nudged, tc.want)
+		}
+		if tc.want == 1 && store.sent[0].kind != db.NotificationKindGoalNudge {
+			t.Fatalf("%s: sent = %+v", tc.name, store.sent)
+		}
+	}
+}
+
+func TestNudgeAllCountsNotifications(t *testing.T) {
+	store := newFakeStore()
+	a, b := uuid.New(), uuid.New()
+	store.goals[a] = []db.ListeningGoal{{ID: 1, UserID: a, Kind: KindMinutesPerWeek, Target: 10, CreatedAt: date(2026, 10, 1)}}
+	store.goals[b] = []db.ListeningGoal{{ID: 2, UserID: b, Kind: KindNewArtistsPerMonth, Target: 10, CreatedAt: date(2026, 10, 1)}}
+	store.weekly = []db.GoalPeriodTotal{{Start: date(2026, 10, 26), Value: 45}}
+	n := NewNudger(newTestService(store, time.Date(2026, 10, 30, 18, 0, 0, 0, time.UTC)), 18)
+
+	report, err := n.NudgeAll(context.Background())
+	if err != nil {
+		t.Fatalf("nudge all: %v", err)
+	}
+	if report.Users != 2 || report.Met != 1 || report.Nudged != 1 || report.Failures != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	cases := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 3, 1, 2, 30, 0, 0, time.UTC), time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		if got := nextRun(tc.now, 18); !got.Equal(tc.want) {
+			t.Fatalf("nextRun(%s) = %s, want %s", tc.now, got, tc.want)
+		}
+	}
+}
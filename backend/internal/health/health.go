@@ -40,6 +40,7 @@ type Checker struct {
 	db           *sql.DB
 	redis        *redis.Client
 	storageCheck func(ctx context.Context) error
+	diskCheck    func(ctx context.Context) error
 	version      string
 	checkTimeout time.Duration
 }
@@ -51,6 +52,9 @@ type CheckerConfig struct {
 	StorageCheck func(ctx context.Context) error
 	Version      string
 	Timeout      time.Duration
+	// DiskCheck optionally reports download temp disk pressure. Failures
+	// degrade readiness without failing it, since playback still works.
+	DiskCheck func(ctx context.Context) error
 }
 
 // NewChecker creates a new health checker
@@ -63,6 +67,7 @@ func NewChecker(cfg *CheckerConfig) *Checker {
 		db:           cfg.DB,
 		redis:        cfg.Redis,
 		storageCheck: cfg.StorageCheck,
+		diskCheck:    cfg.DiskCheck,
 		version:      cfg.Version,
 		checkTimeout: timeout,
 	}
@@ -162,6 +167,17 @@ func (c *Checker) CheckStorage(ctx context.Context) ComponentHealth {
 	}
 }
 
+// CheckDisk checks download temp disk pressure
+func (c *Checker) CheckDisk(ctx context.Context) ComponentHealth {
+	if err := c.diskCheck(ctx); err != nil {
+		return ComponentHealth{
+			Status:  StatusDegraded,
+			Message: "download workers paused: low disk space",
+		}
+	}
+	return ComponentHealth{Status: StatusHealthy}
+}
+
 // Check performs a basic health check (liveness)
 func (c *Checker) Check(ctx context.Context) *HealthResponse {
 	return &HealthResponse{
@@ -189,6 +205,9 @@ func (c *Checker) DeepCheck(ctx context.Context) *HealthResponse {
 		"redis":    c.CheckRedis,
 		"storage":  c.CheckStorage,
 	}
+	if c.diskCheck != nil {
+		checks["disk"] = c.CheckDisk
+	}
 
 	for name, check := range checks {
 		wg.Add(1)
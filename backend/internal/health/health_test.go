@@ -168,3 +168,22 @@ func TestHandler_HealthHandler_DeepQuery(t *testing.T) {
 		t.Error("deep check should include components")
 	}
 }
+
+func TestChecker_DeepCheck_LowDiskDegrades(t *testing.T) {
+	checker := NewChecker(&CheckerConfig{
+		StorageCheck: func(ctx context.Context) error {
+			return nil
+		},
+		DiskCheck: func(ctx context.Context) error {
+			return errors.New("low disk space")
+		},
+		Version: "1.0.0",
+		Timeout: 5 * time.Second,
+	})
+
+	response := checker.DeepCheck(context.Background())
+
+	if response.Components["disk"].Status != StatusDegraded {
+		t.Errorf("expected disk component degraded, got %s", response.Components["disk"].Status)
+	}
+}
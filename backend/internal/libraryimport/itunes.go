@@ -0,0 +1,276 @@
+package libraryimport
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLibrary = errors.New("invalid library export")
+
+// ParseITunesLibrary reads an iTunes / Music.app "Library.xml" property list.
+// Non-music items (podcasts, movies, TV shows) are skipped, as are the master
+// library playlist, Apple's built-in playlists, and folders. Ratings the app
+// derived from album ratings are ignored.
+func ParseITunesLibrary(r io.Reader) (*SourceLibrary, error) {
+	root, err := decodePlist(r)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: root is not a dict", ErrInvalidLibrary)
+	}
+	tracks, ok := dict["Tracks"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: missing Tracks", ErrInvalidLibrary)
+	}
+
+	lib := &SourceLibrary{}
+	known := make(map[string]bool, len(tracks))
+	for _, raw := range tracks {
+		entry, ok := raw.(map[string]interface{})
+		if !ok || plistBool(entry, "Podcast") || plistBool(entry, "Movie") || plistBool(entry, "TV Show") {
+			continue
+		}
+		id := plistInt(entry, "Track ID")
+		if id == 0 {
+			continue
+		}
+		track := SourceTrack{
+			SourceID:   strconv.FormatInt(id, 10),
+			Title:      plistString(entry, "Name"),
+			Artist:     plistString(entry, "Artist"),
+			Album:      plistString(entry, "Album"),
+			DurationMs: int(plistInt(entry, "Total Time")),
+			Loved:      plistBool(entry, "Loved") || plistBool(entry, "Favorited"),
+			PlayCount:  int(plistInt(entry, "Play Count")),
+		}
+		if !plistBool(entry, "Rating Computed") {
+			track.Rating = itunesStars(plistInt(entry, "Rating"))
+		}
+		if played, ok := entry["Play Date UTC"].(time.Time); ok {
+			track.LastPlayedAt = &played
+		}
+		known[track.SourceID] = true
+		lib.Tracks = append(lib.Tracks, track)
+	}
+	// Tracks is a dict keyed by ID; keep the report order stable.
+	sort.Slice(lib.Tracks, func(i, j int) bool {
+		a, _ := strconv.ParseInt(lib.Tracks[i].SourceID, 10, 64)
+		b, _ := strconv.ParseInt(lib.Tracks[j].SourceID, 10, 64)
+		return a < b
+	})
+
+	playlists, _ := dict["Playlists"].([]interface{})
+	for _, raw := range playlists {
+		entry, ok := raw.(map[string]interface{})
+		if !ok || plistBool(entry, "Master") || plistBool(entry, "Folder") {
+			continue
+		}
+		if _, builtIn := entry["Distinguished Kind"]; builtIn {
+			continue
+		}
+		if visible, ok := entry["Visible"].(bool); ok && !visible {
+			continue
+		}
+		playlist := SourcePlaylist{
+			SourceID: plistString(entry, "Playlist Persistent ID"),
+			Name:     strings.TrimSpace(plistString(entry, "Name")),
+		}
+		if playlist.SourceID == "" {
+			playlist.SourceID = strconv.FormatInt(plistInt(entry, "Playlist ID"), 10)
+		}
+		items, _ := entry["Playlist Items"].([]interface{})
+		for _, rawItem := range items {
+			item, ok := rawItem.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id := strconv.FormatInt(plistInt(item, "Track ID"), 10)
+			if known[id] {
+				playlist.TrackSourceIDs = append(playlist.TrackSourceIDs, id)
+			}
+		}
+		if playlist.Name == "" {
+			continue
+		}
+		lib.Playlists = append(lib.Playlists, playlist)
+	}
+
+	return lib, nil
+}
+
+// itunesStars converts the 0-100 rating scale (20 per star) to 0-5 stars.
+func itunesStars(rating int64) int {
+	stars := int((rating + 10) / 20)
+	if stars < 0 {
+		return 0
+	}
+	if stars > 5 {
+		return 5
+	}
+	return stars
+}
+
+func plistString(dict map[string]interface{}, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+func plistInt(dict map[string]interface{}, key string) int64 {
+	switch v := dict[key].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func plistBool(dict map[string]interface{}, key string) bool {
+	b, _ := dict[key].(bool)
+	return b
+}
+
+// decodePlist decodes an XML property list into Go values: dict becomes
+// map[string]interface{}, array []interface{}, integer int64, real float64,
+// date time.Time, true/false bool, and string/data string.
+func decodePlist(r io.Reader) (interface{}, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no plist element", ErrInvalidLibrary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLibrary, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "plist" {
+			return nil, fmt.Errorf("%w: root element is %q, not plist", ErrInvalidLibrary, start.Name.Local)
+		}
+		value, err := decodePlistFirstChild(dec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLibrary, err)
+		}
+		return value, nil
+	}
+}
+
+func decodePlistFirstChild(dec *xml.Decoder) (interface{}, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return decodePlistValue(dec, t)
+		case xml.EndElement:
+			return nil, errors.New("empty plist")
+		}
+	}
+}
+
+func decodePlistValue(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		var key string
+		haveKey := false
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if key, err = plistText(dec); err != nil {
+						return nil, err
+					}
+					haveKey = true
+					continue
+				}
+				value, err := decodePlistValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				if !haveKey {
+					return nil, errors.New("dict value without key")
+				}
+				dict[key] = value
+				haveKey = false
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := plistText(dec)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "string", "data":
+		return text, nil
+	}
+	return nil, fmt.Errorf("unsupported plist element %q", start.Name.Local)
+}
+
+// plistText reads character data up to the end of the current element.
+func plistText(dec *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected element %q in text", t.Name.Local)
+		}
+	}
+}
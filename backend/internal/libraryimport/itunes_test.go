@@ -0,0 +1,55 @@
+package libraryimport
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseITunesLibraryFixture(t *testing.T) {
+	f, err := os.Open("testdata/itunes_library.xml")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+
+	lib, err := ParseITunesLibrary(f)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(lib.Tracks) != 3 {
+		t.Fatalf("tracks = %d, want 3 (podcast skipped)", len(lib.Tracks))
+	}
+	first := lib.Tracks[0]
+	played := time.Date(2024, 5, 1, 20, 15, 0, 0, time.UTC)
+	if first.SourceID != "101" || first.Title != "Windowlicker" || first.DurationMs != 367000 ||
+		first.Rating != 4 || !first.Loved || first.PlayCount != 12 || first.LastPlayedAt == nil || !first.LastPlayedAt.Equal(played) {
+		t.Fatalf("first track = %#v", first)
+	}
+	if lib.Tracks[1].Rating != 0 {
+		t.Fatalf("computed album rating should be ignored, got %d", lib.Tracks[1].Rating)
+	}
+
+	if len(lib.Playlists) != 2 {
+		t.Fatalf("playlists = %#v, want master and built-in skipped", lib.Playlists)
+	}
+	lateNight := lib.Playlists[0]
+	if lateNight.Name != "Late Night" || lateNight.SourceID != "ABCDEF0123456789" {
+		t.Fatalf("playlist = %#v", lateNight)
+	}
+	if !reflect.DeepEqual(lateNight.TrackSourceIDs, []string{"102", "101", "104"}) {
+		t.Fatalf("playlist items = %v, want skipped podcast dropped and order kept", lateNight.TrackSourceIDs)
+	}
+}
+
+func TestParseITunesLibraryRejectsNonPlist(t *testing.T) {
+	for _, body := range []string{"", "<html></html>", "<plist><array></array></plist>", "not xml"} {
+		if _, err := ParseITunesLibrary(strings.NewReader(body)); !errors.Is(err, ErrInvalidLibrary) {
+			t.Fatalf("body %q: err = %v, want ErrInvalidLibrary", body, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package libraryimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// jellyfinAuthorization identifies this client to Jellyfin; the server requires
+// it on the authenticate call.
+const jellyfinAuthorization = `MediaBrowser Client="OpenMusicPlayer", Device="OpenMusicPlayer", DeviceId="openmusicplayer-migration", Version="1.0.0"`
+
+// jellyfinTicksPerMs converts RunTimeTicks (100ns units) to milliseconds.
+const jellyfinTicksPerMs = 10000
+
+// JellyfinSource reads a library through the Jellyfin (and Emby-compatible)
+// REST API, authenticating by username and password for an access token.
+type JellyfinSource struct {
+	baseURL    *url.URL
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+type jellyfinItem struct {
+	ID           string   `json:"Id"`
+	Name         string   `json:"Name"`
+	Album        string   `json:"Album"`
+	AlbumArtist  string   `json:"AlbumArtist"`
+	Artists      []string `json:"Artists"`
+	RunTimeTicks int64    `json:"RunTimeTicks"`
+	UserData     struct {
+		PlayCount      int    `json:"PlayCount"`
+		IsFavorite     bool   `json:"IsFavorite"`
+		LastPlayedDate string `json:"LastPlayedDate"`
+	} `json:"UserData"`
+}
+
+type jellyfinItems struct {
+	Items            []jellyfinItem `json:"Items"`
+	TotalRecordCount int            `json:"TotalRecordCount"`
+}
+
+// Fetch pages through the user's audio items and playlists. Jellyfin has no
+// star ratings, so Rating is always left unset.
+func (s *JellyfinSource) Fetch(ctx context.Context) (*SourceLibrary, error) {
+	token, userID, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := newRemoteLibraryBuilder()
+
+	err = s.eachPage(ctx, token, "/Users/"+url.PathEscape(userID)+"/Items", url.Values{
+		"IncludeItemTypes": {"Audio"},
+		"Recursive":        {"true"},
+		"Fields":           {"UserData"},
+	}, func(item jellyfinItem) error {
+		return b.addTrack(item.sourceTrack())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var playlists []jellyfinItem
+	err = s.eachPage(ctx, token, "/Users/"+url.PathEscape(userID)+"/Items", url.Values{
+		"IncludeItemTypes": {"Playlist"},
+		"Recursive":        {"true"},
+	}, func(item jellyfinItem) error {
+		playlists = append(playlists, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range playlists {
+		playlist := SourcePlaylist{SourceID: summary.ID, Name: strings.TrimSpace(summary.Name)}
+		err := s.eachPage(ctx, token, "/Playlists/"+url.PathEscape(summary.ID)+"/Items", url.Values{
+			"UserId": {userID},
+			"Fields": {"UserData"},
+		}, func(item jellyfinItem) error {
+			if err := b.addTrack(item.sourceTrack()); err != nil {
+				return err
+			}
+			playlist.TrackSourceIDs = append(playlist.TrackSourceIDs, item.ID)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if playlist.Name != "" {
+			b.lib.Playlists = append(b.lib.Playlists, playlist)
+		}
+	}
+
+	return b.library(), nil
+}
+
+func (item jellyfinItem) sourceTrack() SourceTrack {
+	artist := item.AlbumArtist
+	if len(item.Artists) > 0 {
+		artist = strings.Join(item.Artists, ", ")
+	}
+	return SourceTrack{
+		SourceID:     item.ID,
+		Title:        item.Name,
+		Artist:       artist,
+		Album:        item.Album,
+		DurationMs:   int(item.RunTimeTicks / jellyfinTicksPerMs),
+		Loved:        item.UserData.IsFavorite,
+		PlayCount:    item.UserData.PlayCount,
+		LastPlayedAt: parseRemoteTime(item.UserData.LastPlayedDate),
+	}
+}
+
+func (s *JellyfinSource) authenticate(ctx context.Context) (token, userID string, err error) {
+	body, err := json.Marshal(map[string]string{"Username": s.username, "Pw": s.password})
+	if err != nil {
+		return "", "", err
+	}
+	endpoint := *s.baseURL
+	endpoint.Path += "/Users/AuthenticateByName"
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Emby-Authorization", jellyfinAuthorization)
+
+	var auth struct {
+		AccessToken string `json:"AccessToken"`
+		User        struct {
+			ID string `json:"Id"`
+		} `json:"User"`
+	}
+	if err := getRemoteJSON(ctx, s.httpClient, req, &auth); err != nil {
+		return "", "", err
+	}
+	if auth.AccessToken == "" || auth.User.ID == "" {
+		return "", "", ErrRemoteAuth
+	}
+	return auth.AccessToken, auth.User.ID, nil
+}
+
+func (s *JellyfinSource) eachPage(ctx context.Context, token, path string, params url.Values, fn func(jellyfinItem) error) error {
+	for start := 0; ; start += remotePageSize {
+		if start >= remoteMaxTracks {
+			return fmt.Errorf("%w: more than %d items", ErrRemoteUnavailable, remoteMaxTracks)
+		}
+		query := url.Values{}
+		for key, values := range params {
+			query[key] = values
+		}
+		query.Set("StartIndex", strconv.Itoa(start))
+		query.Set("Limit", strconv.Itoa(remotePageSize))
+
+		endpoint := *s.baseURL
+		endpoint.Path += path
+		endpoint.RawQuery = query.Encode()
+		req, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+		}
+		req.Header.Set("X-Emby-Authorization", jellyfinAuthorization)
+		req.Header.Set("X-Emby-Token", token)
+
+		var page jellyfinItems
+		if err := getRemoteJSON(ctx, s.httpClient, req, &page); err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(page.Items) < remotePageSize || (page.TotalRecordCount > 0 && start+len(page.Items) >= page.TotalRecordCount) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package libraryimport
+
+import (
+	"sort"
+
+	"github.com/openmusicplayer/backend/internal/matcher"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+// LibraryMatcher matches external tracks against one user's library. Exact
+// matches share the identity-hash normalized title and artist with durations
+// within matcher.DurationTolerance; anything else is scored with the
+// MusicBrainz matcher's similarity and accepted at matcher.AutoMatchThreshold.
+//
+// Fuzzy scoring only considers candidates that share a normalized title or
+// artist with the source track, which keeps large imports from degrading into
+// a full cross product.
+type LibraryMatcher struct {
+	byKey    map[string][]Candidate
+	byTitle  map[string][]Candidate
+	byArtist map[string][]Candidate
+}
+
+func NewLibraryMatcher(candidates []Candidate) *LibraryMatcher {
+	m := &LibraryMatcher{
+		byKey:    make(map[string][]Candidate),
+		byTitle:  make(map[string][]Candidate),
+		byArtist: make(map[string][]Candidate),
+	}
+	for _, c := range candidates {
+		title := storage.NormalizeIdentityField(c.Title)
+		artist := storage.NormalizeIdentityField(c.Artist)
+		key := identityKey(title, artist)
+		m.byKey[key] = append(m.byKey[key], c)
+		m.byTitle[title] = append(m.byTitle[title], c)
+		if artist != "" {
+			m.byArtist[artist] = append(m.byArtist[artist], c)
+		}
+	}
+	return m
+}
+
+func identityKey(title, artist string) string {
+	return title + "|" + artist
+}
+
+// Match returns the best library track for t.
+func (m *LibraryMatcher) Match(t SourceTrack) Match {
+	title := storage.NormalizeIdentityField(t.Title)
+	artist := storage.NormalizeIdentityField(t.Artist)
+	if title == "" {
+		return Match{Status: MatchUnmatched}
+	}
+
+	exacts := m.byKey[identityKey(title, artist)]
+	exact := -1
+	for i, c := range exacts {
+		if !durationsAgree(t.DurationMs, c.DurationMs) {
+			continue
+		}
+		if exact < 0 || durationGap(t.DurationMs, c.DurationMs) < durationGap(t.DurationMs, exacts[exact].DurationMs) {
+			exact = i
+		}
+	}
+	if exact >= 0 {
+		return Match{TrackID: exacts[exact].TrackID, Status: MatchExact, Score: 100}
+	}
+
+	best := Match{Status: MatchUnmatched}
+	for _, scored := range m.fuzzy(t, title, artist) {
+		if scored.Score > best.Score {
+			best = scored
+		}
+	}
+	if best.Score < matcher.AutoMatchThreshold {
+		return Match{Status: MatchUnmatched, Score: best.Score}
+	}
+	return best
+}
+
+// Suggest returns up to limit library tracks that t might be, best first,
+// scoring at least minScore. It offers the alternatives a user picks from
+// when Match is fuzzy or finds nothing.
+func (m *LibraryMatcher) Suggest(t SourceTrack, limit int, minScore float64) []Match {
+	title := storage.NormalizeIdentityField(t.Title)
+	artist := storage.NormalizeIdentityField(t.Artist)
+	if title == "" || limit <= 0 {
+		return nil
+	}
+	var suggestions []Match
+	for _, scored := range m.fuzzy(t, title, artist) {
+		if scored.Score >= minScore {
+			suggestions = append(suggestions, scored)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// fuzzy scores every candidate sharing t's normalized title or artist, in
+// bucket order.
+func (m *LibraryMatcher) fuzzy(t SourceTrack, title, artist string) []Match {
+	parsed := &matcher.ParsedTitle{Artist: t.Artist, Track: t.Title}
+	var scored []Match
+	seen := make(map[int64]bool)
+	for _, bucket := range [][]Candidate{m.byTitle[title], m.byArtist[artist]} {
+		for _, c := range bucket {
+			if seen[c.TrackID] {
+				continue
+			}
+			seen[c.TrackID] = true
+			score := matcher.CalculateScore(parsed, c.Artist, c.Title, t.DurationMs, c.DurationMs, 0, matcher.DefaultWeights)
+			scored = append(scored, Match{TrackID: c.TrackID, Status: MatchFuzzy, Score: score.Overall})
+		}
+	}
+	return scored
+}
+
+// durationsAgree treats an unknown duration on either side as agreeing.
+func durationsAgree(a, b int) bool {
+	if a <= 0 || b <= 0 {
+		return true
+	}
+	return durationGap(a, b) <= matcher.DurationTolerance*1000
+}
+
+func durationGap(a, b int) int {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
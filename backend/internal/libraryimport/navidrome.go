@@ -0,0 +1,194 @@
+package libraryimport
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const subsonicAPIVersion = "1.16.1"
+
+// subsonicAuthFailedCode is the Subsonic error code for a wrong username or
+// password.
+const subsonicAuthFailedCode = 40
+
+// NavidromeSource reads a library through Navidrome's Subsonic-compatible API,
+// so it also works against other Subsonic servers. Authentication uses the
+// salted token scheme; the password itself is never sent.
+type NavidromeSource struct {
+	baseURL    *url.URL
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+type subsonicSong struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	Album      string `json:"album"`
+	Duration   int    `json:"duration"`
+	PlayCount  int    `json:"playCount"`
+	Played     string `json:"played"`
+	Starred    string `json:"starred"`
+	UserRating int    `json:"userRating"`
+	IsVideo    bool   `json:"isVideo"`
+}
+
+type subsonicPlaylist struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Entry []subsonicSong `json:"entry"`
+}
+
+type subsonicEnvelope struct {
+	Response struct {
+		Status string `json:"status"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		SearchResult3 struct {
+			Song []subsonicSong `json:"song"`
+		} `json:"searchResult3"`
+		Playlists struct {
+			Playlist []subsonicPlaylist `json:"playlist"`
+		} `json:"playlists"`
+		Playlist subsonicPlaylist `json:"playlist"`
+	} `json:"subsonic-response"`
+}
+
+// Fetch pages through every song with search3 (an empty query lists the whole
+// library on Navidrome), then loads each playlist's entries.
+func (s *NavidromeSource) Fetch(ctx context.Context) (*SourceLibrary, error) {
+	b := newRemoteLibraryBuilder()
+
+	for offset := 0; ; offset += remotePageSize {
+		if offset >= remoteMaxTracks {
+			return nil, fmt.Errorf("%w: library exceeds %d tracks", ErrRemoteUnavailable, remoteMaxTracks)
+		}
+		var env subsonicEnvelope
+		err := s.call(ctx, "search3", url.Values{
+			"query":       {""},
+			"songCount":   {strconv.Itoa(remotePageSize)},
+			"songOffset":  {strconv.Itoa(offset)},
+			"artistCount": {"0"},
+			"albumCount":  {"0"},
+		}, &env)
+		if err != nil {
+			return nil, err
+		}
+		songs := env.Response.SearchResult3.Song
+		for _, song := range songs {
+			if song.IsVideo {
+				continue
+			}
+			if err := b.addTrack(song.sourceTrack()); err != nil {
+				return nil, err
+			}
+		}
+		if len(songs) < remotePageSize {
+			break
+		}
+	}
+
+	var listing subsonicEnvelope
+	if err := s.call(ctx, "getPlaylists", nil, &listing); err != nil {
+		return nil, err
+	}
+	for _, summary := range listing.Response.Playlists.Playlist {
+		var env subsonicEnvelope
+		if err := s.call(ctx, "getPlaylist", url.Values{"id": {summary.ID}}, &env); err != nil {
+			return nil, err
+		}
+		playlist := SourcePlaylist{SourceID: summary.ID, Name: strings.TrimSpace(summary.Name)}
+		for _, song := range env.Response.Playlist.Entry {
+			if song.IsVideo {
+				continue
+			}
+			if err := b.addTrack(song.sourceTrack()); err != nil {
+				return nil, err
+			}
+			playlist.TrackSourceIDs = append(playlist.TrackSourceIDs, song.ID)
+		}
+		if playlist.Name != "" {
+			b.lib.Playlists = append(b.lib.Playlists, playlist)
+		}
+	}
+
+	return b.library(), nil
+}
+
+func (song subsonicSong) sourceTrack() SourceTrack {
+	rating := song.UserRating
+	if rating < 0 || rating > 5 {
+		rating = 0
+	}
+	return SourceTrack{
+		SourceID:     song.ID,
+		Title:        song.Title,
+		Artist:       song.Artist,
+		Album:        song.Album,
+		DurationMs:   song.Duration * 1000,
+		Rating:       rating,
+		Loved:        song.Starred != "",
+		PlayCount:    song.PlayCount,
+		LastPlayedAt: parseRemoteTime(song.Played),
+	}
+}
+
+func (s *NavidromeSource) call(ctx context.Context, method string, params url.Values, out *subsonicEnvelope) error {
+	salt, err := subsonicSalt()
+	if err != nil {
+		return err
+	}
+	token := md5.Sum([]byte(s.password + salt))
+
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("u", s.username)
+	query.Set("t", hex.EncodeToString(token[:]))
+	query.Set("s", salt)
+	query.Set("v", subsonicAPIVersion)
+	query.Set("c", "openmusicplayer")
+	query.Set("f", "json")
+
+	endpoint := *s.baseURL
+	endpoint.Path += "/rest/" + method
+	endpoint.RawQuery = query.Encode()
+	req, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+	}
+	if err := getRemoteJSON(ctx, s.httpClient, req, out); err != nil {
+		return err
+	}
+
+	if out.Response.Status != "ok" {
+		if out.Response.Error != nil && out.Response.Error.Code == subsonicAuthFailedCode {
+			return ErrRemoteAuth
+		}
+		message := "unknown error"
+		if out.Response.Error != nil {
+			message = out.Response.Error.Message
+		}
+		return fmt.Errorf("%w: %s: %s", ErrRemoteUnavailable, method, message)
+	}
+	return nil
+}
+
+func subsonicSalt() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
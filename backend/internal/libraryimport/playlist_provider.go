@@ -0,0 +1,36 @@
+package libraryimport
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnsupportedPlaylistURL = errors.New("not a playlist URL from a supported service")
+	ErrProviderNotConfigured  = errors.New("playlist provider is not configured")
+	ErrRemotePlaylistNotFound = errors.New("playlist not found or not public")
+)
+
+// PlaylistProvider reads a public playlist from a streaming service, as a
+// SourceLibrary holding the playlist and its tracks, so Service.Import can
+// match it and create the local copy. Spotify implements it; other services
+// such as Apple Music plug in the same way.
+type PlaylistProvider interface {
+	// Source is the provider's Source* name, used in import reports.
+	Source() string
+	// PlaylistID extracts the playlist ID from a share URL or URI, reporting
+	// false when rawURL is not one of the provider's playlists.
+	PlaylistID(rawURL string) (string, bool)
+	FetchPlaylist(ctx context.Context, id string) (*SourceLibrary, error)
+}
+
+// PlaylistProviderFor returns the provider that recognizes rawURL and the
+// playlist ID it names.
+func PlaylistProviderFor(providers []PlaylistProvider, rawURL string) (PlaylistProvider, string, error) {
+	for _, provider := range providers {
+		if id, ok := provider.PlaylistID(rawURL); ok {
+			return provider, id, nil
+		}
+	}
+	return nil, "", ErrUnsupportedPlaylistURL
+}
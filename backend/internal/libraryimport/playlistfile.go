@@ -0,0 +1,330 @@
+package libraryimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	PlaylistFormatM3U = "m3u"
+	PlaylistFormatCSV = "csv"
+
+	// MaxPlaylistFileEntries bounds how many tracks one playlist file may list.
+	MaxPlaylistFileEntries = 10000
+)
+
+var (
+	ErrInvalidPlaylistFile  = errors.New("invalid playlist file")
+	ErrPlaylistFileTooLarge = fmt.Errorf("playlist file lists more than %d tracks", MaxPlaylistFileEntries)
+)
+
+// PlaylistFileEntry is one track listed in an M3U/M3U8 or CSV playlist.
+// SourceID is the entry's position in the file, starting at 1; Line is the
+// line (M3U) or record (CSV) it came from, for pointing users at it.
+type PlaylistFileEntry struct {
+	SourceTrack
+	Line     int
+	Location string
+}
+
+// ParsePlaylistFile reads a playlist in the given format.
+func ParsePlaylistFile(r io.Reader, format string) ([]PlaylistFileEntry, error) {
+	switch format {
+	case PlaylistFormatM3U:
+		return ParseM3U(r)
+	case PlaylistFormatCSV:
+		return ParsePlaylistCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidPlaylistFile, format)
+	}
+}
+
+// DetectPlaylistFormat guesses a playlist's format from its first bytes: an
+// #EXTM3U header or a first line that looks like a path or URL means M3U,
+// anything else CSV.
+func DetectPlaylistFormat(head []byte) string {
+	head = bytes.TrimPrefix(head, utf8BOM)
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	line = bytes.TrimSpace(line)
+	switch {
+	case bytes.HasPrefix(line, []byte("#")),
+		bytes.Contains(line, []byte("://")),
+		bytes.HasPrefix(line, []byte("/")),
+		bytes.HasPrefix(line, []byte(".")),
+		bytes.Contains(line, []byte(`\`)):
+		return PlaylistFormatM3U
+	}
+	return PlaylistFormatCSV
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ParseM3U reads a plain or extended M3U/M3U8 playlist. Titles and artists
+// come from #EXTINF ("Artist - Title"), #EXTART, and #EXTALB when present,
+// and otherwise from the file name and its folders, as in
+// "Artist/Album/01 - Title.mp3".
+func ParseM3U(r io.Reader) ([]PlaylistFileEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var entries []PlaylistFileEntry
+	var pending PlaylistFileEntry
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, string(utf8BOM))
+		}
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			pending.Line = lineNo
+			parseEXTINF(strings.TrimPrefix(line, "#EXTINF:"), &pending.SourceTrack)
+			continue
+		case strings.HasPrefix(line, "#EXTART:"):
+			pending.Artist = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+			continue
+		case strings.HasPrefix(line, "#EXTALB:"):
+			pending.Album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		entry := pending
+		pending = PlaylistFileEntry{}
+		if entry.Line == 0 {
+			entry.Line = lineNo
+		}
+		entry.Location = line
+		fillFromLocation(&entry)
+		if entry.Title == "" {
+			continue
+		}
+		if len(entries) == MaxPlaylistFileEntries {
+			return nil, ErrPlaylistFileTooLarge
+		}
+		entry.SourceID = strconv.Itoa(len(entries) + 1)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlaylistFile, err)
+	}
+	return entries, nil
+}
+
+// parseEXTINF reads "<seconds> [attributes],<display name>". An artist set
+// by #EXTART is kept.
+func parseEXTINF(value string, track *SourceTrack) {
+	info, display, _ := strings.Cut(value, ",")
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil && seconds > 0 {
+			track.DurationMs = int(seconds * 1000)
+		}
+	}
+	artist, title := splitArtistTitle(strings.TrimSpace(display))
+	track.Title = title
+	if track.Artist == "" {
+		track.Artist = artist
+	}
+}
+
+// leadingTrackNumber matches "01 " or "1. " before a title.
+var leadingTrackNumber = regexp.MustCompile(`^(0\d\s+|\d{1,3}\.\s*)`)
+
+// fillFromLocation fills the title, artist, and album an entry lacks from
+// its path: the file name as "[NN - ]Artist - Title" or "NN Title", and the
+// two folders above it as artist and album.
+func fillFromLocation(entry *PlaylistFileEntry) {
+	location := entry.Location
+	if u, err := url.Parse(location); err == nil && len(u.Scheme) > 1 {
+		location = u.Path
+	} else if unescaped, err := url.PathUnescape(location); err == nil {
+		location = unescaped
+	}
+	location = strings.ReplaceAll(location, `\`, "/")
+	dir, file := path.Split(location)
+	name := strings.TrimSuffix(file, path.Ext(file))
+
+	if entry.Title == "" {
+		artist, title := splitArtistTitle(name)
+		if isDigits(artist) {
+			artist, title = splitArtistTitle(title)
+		}
+		if artist == "" {
+			title = leadingTrackNumber.ReplaceAllString(title, "")
+		}
+		entry.Title = title
+		if entry.Artist == "" {
+			entry.Artist = artist
+		}
+	}
+	var folders []string
+	for _, folder := range strings.Split(dir, "/") {
+		if folder != "" && folder != "." && folder != ".." {
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) >= 2 {
+		if entry.Album == "" {
+			entry.Album = folders[len(folders)-1]
+		}
+		if entry.Artist == "" {
+			entry.Artist = folders[len(folders)-2]
+		}
+	}
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitArtistTitle splits "Artist - Title"; a name without the separator is
+// all title.
+func splitArtistTitle(name string) (artist, title string) {
+	if a, t, ok := strings.Cut(name, " - "); ok && strings.TrimSpace(a) != "" && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(a), strings.TrimSpace(t)
+	}
+	return "", strings.TrimSpace(name)
+}
+
+// csvColumns maps lowercased header names from common exporters (Exportify,
+// Soundiiz, TuneMyMusic, spreadsheets) to fields.
+var csvColumns = map[string]string{
+	"title":               "title",
+	"track":               "title",
+	"track name":          "title",
+	"track title":         "title",
+	"song":                "title",
+	"name":                "title",
+	"artist":              "artist",
+	"artists":             "artist",
+	"artist name":         "artist",
+	"artist name(s)":      "artist",
+	"album":               "album",
+	"album name":          "album",
+	"album title":         "album",
+	"duration":            "duration",
+	"length":              "duration",
+	"time":                "duration",
+	"duration (ms)":       "duration_ms",
+	"duration_ms":         "duration_ms",
+	"track duration (ms)": "duration_ms",
+}
+
+// ParsePlaylistCSV reads a CSV (or semicolon- or tab-separated) playlist
+// with a header row naming at least a title column. Durations are read as
+// milliseconds from "Duration (ms)"-style columns, and as seconds or m:ss
+// otherwise.
+func ParsePlaylistCSV(r io.Reader) ([]PlaylistFileEntry, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(4096)
+	reader := csv.NewReader(buffered)
+	reader.Comma = csvDelimiter(head)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidPlaylistFile)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, string(utf8BOM))))
+		if field, ok := csvColumns[name]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("%w: no title column in header", ErrInvalidPlaylistFile)
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []PlaylistFileEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPlaylistFile, err)
+		}
+		entry := PlaylistFileEntry{SourceTrack: SourceTrack{
+			Title:  field(record, "title"),
+			Artist: field(record, "artist"),
+			Album:  field(record, "album"),
+		}}
+		if entry.Title == "" {
+			continue
+		}
+		entry.Line, _ = reader.FieldPos(0)
+		if ms, err := strconv.Atoi(field(record, "duration_ms")); err == nil && ms > 0 {
+			entry.DurationMs = ms
+		} else {
+			entry.DurationMs = parseClockDuration(field(record, "duration"))
+		}
+		if len(entries) == MaxPlaylistFileEntries {
+			return nil, ErrPlaylistFileTooLarge
+		}
+		entry.SourceID = strconv.Itoa(len(entries) + 1)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// csvDelimiter picks whichever of comma, semicolon, or tab appears most in
+// the header line.
+func csvDelimiter(head []byte) rune {
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	best, count := ',', bytes.Count(line, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(candidate))); n > count {
+			best, count = candidate, n
+		}
+	}
+	return best
+}
+
+// parseClockDuration reads "h:mm:ss", "m:ss", or whole seconds into
+// milliseconds, returning 0 when value is none of those.
+func parseClockDuration(value string) int {
+	if value == "" {
+		return 0
+	}
+	seconds := 0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds * 1000
+}
@@ -0,0 +1,119 @@
+package libraryimport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseM3UReadsExtendedAndPlainEntries(t *testing.T) {
+	playlist := "\ufeff#EXTM3U\n" +
+		"#EXTINF:365,Aphex Twin - Windowlicker\n" +
+		"/music/Aphex Twin/Windowlicker/01 Windowlicker.flac\n" +
+		"\n" +
+		"# a comment\n" +
+		"../Boards%20of%20Canada/Music%20Has%20the%20Right%20to%20Children/05%20-%20Roygbiv.mp3\n" +
+		"#EXTART:Burial\n" +
+		"#EXTINF:-1,Archangel\n" +
+		"file:///C:/Music/Untrue/Archangel.mp3\n" +
+		`D:\Music\Daft Punk\Discovery\01 - Daft Punk - One More Time.mp3` + "\n"
+
+	entries, err := ParseM3U(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line                 int
+		artist, title, album string
+		durationMs           int
+	}{
+		{2, "Aphex Twin", "Windowlicker", "Windowlicker", 365000},
+		{6, "Boards of Canada", "Roygbiv", "Music Has the Right to Children", 0},
+		{8, "Burial", "Archangel", "Untrue", 0},
+		{10, "Daft Punk", "One More Time", "Discovery", 0},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Line != w.line || e.Artist != w.artist || e.Title != w.title || e.Album != w.album || e.DurationMs != w.durationMs || e.SourceID == "" {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestParsePlaylistCSVReadsExporterColumns(t *testing.T) {
+	exportify := "\"Track Name\",\"Artist Name(s)\",\"Album Name\",\"Duration (ms)\"\n" +
+		"\"Windowlicker\",\"Aphex Twin\",\"Windowlicker\",\"365000\"\n" +
+		"\"\",\"Nobody\",\"\",\"1\"\n" +
+		"\"Roygbiv\",\"Boards of Canada\",\"Music Has the Right to Children\",\"151000\"\n"
+	entries, err := ParsePlaylistCSV(strings.NewReader(exportify))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].DurationMs != 365000 || entries[1].Title != "Roygbiv" || entries[1].Line != 4 || entries[1].SourceID != "2" {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	spreadsheet := "Artist;Title;Length\nBurial;Archangel;3:58\n"
+	entries, err = ParsePlaylistCSV(strings.NewReader(spreadsheet))
+	if err != nil || len(entries) != 1 || entries[0].Artist != "Burial" || entries[0].DurationMs != 238000 {
+		t.Fatalf("entries = %+v, err = %v", entries, err)
+	}
+
+	if _, err := ParsePlaylistCSV(strings.NewReader("Artist,Album\nBurial,Untrue\n")); !errors.Is(err, ErrInvalidPlaylistFile) {
+		t.Fatalf("missing title column: err = %v", err)
+	}
+}
+
+func TestDetectPlaylistFormat(t *testing.T) {
+	for head, want := range map[string]string{
+		"#EXTM3U\n#EXTINF:1,a":      PlaylistFormatM3U,
+		"/music/a.mp3\n":            PlaylistFormatM3U,
+		`C:\Music\a.mp3`:            PlaylistFormatM3U,
+		"Title,Artist\nA,B\n":       PlaylistFormatCSV,
+		"\ufeffTrack Name,Artist\n": PlaylistFormatCSV,
+	} {
+		if got := DetectPlaylistFormat([]byte(head)); got != want {
+			t.Errorf("DetectPlaylistFormat(%q) = %q, want %q", head, got, want)
+		}
+	}
+}
+
+func TestMatchPlaylistFileSuggestsAlternatives(t *testing.T) {
+	store := &fakeImportStore{candidates: []Candidate{
+		{TrackID: 1, Title: "Windowlicker", Artist: "Aphex Twin", DurationMs: 365000},
+		{TrackID: 2, Title: "Windowlicker (Acid Edit)", Artist: "Aphex Twin", DurationMs: 370000},
+		{TrackID: 3, Title: "Xtal", Artist: "Aphex Twin", DurationMs: 291000},
+	}}
+	service := NewService(store, &fakeImportPlaylists{})
+	entries := []PlaylistFileEntry{
+		{SourceTrack: SourceTrack{SourceID: "1", Title: "Windowlicker", Artist: "Aphex Twin", DurationMs: 365000}},
+		{SourceTrack: SourceTrack{SourceID: "2", Title: "Windowlickr", Artist: "Aphex Twin"}},
+		{SourceTrack: SourceTrack{SourceID: "3", Title: "Archangel", Artist: "Burial"}},
+	}
+
+	matches, err := service.MatchPlaylistFile(context.Background(), uuid.New(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches[0].Match.Status != MatchExact || matches[0].Track == nil || matches[0].Track.TrackID != 1 || matches[0].Suggestions != nil {
+		t.Fatalf("exact row = %+v", matches[0])
+	}
+	fuzzy := matches[1]
+	if fuzzy.Match.Status != MatchFuzzy || fuzzy.Track == nil || len(fuzzy.Suggestions) == 0 {
+		t.Fatalf("fuzzy row = %+v", fuzzy)
+	}
+	for _, s := range fuzzy.Suggestions {
+		if s.Track.TrackID == fuzzy.Match.TrackID || s.Track.Title == "" {
+			t.Fatalf("suggestion %+v repeats the match or lacks a title", s)
+		}
+	}
+	if matches[2].Match.Status != MatchUnmatched || matches[2].Track != nil || len(matches[2].Suggestions) != 0 {
+		t.Fatalf("unmatched row = %+v", matches[2])
+	}
+}
@@ -0,0 +1,160 @@
+package libraryimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SourceNavidrome = "navidrome"
+	SourceJellyfin  = "jellyfin"
+
+	remotePageSize  = 500
+	remoteMaxTracks = 50000
+	remoteUserAgent = "OpenMusicPlayer/1.0.0 (library migration)"
+	// remoteMaxResponseBytes caps one API page so a hostile or broken server
+	// cannot exhaust memory.
+	remoteMaxResponseBytes = 32 << 20
+)
+
+var (
+	ErrInvalidRemote     = errors.New("remote server url must be an absolute http(s) URL")
+	ErrUnsupportedRemote = errors.New("unsupported remote server kind")
+	ErrRemoteAuth        = errors.New("remote server rejected the credentials")
+	ErrRemoteUnavailable = errors.New("remote server request failed")
+)
+
+// RemoteCredentials identify one account on a Navidrome or Jellyfin server.
+// They are used for the duration of a single import and never stored.
+type RemoteCredentials struct {
+	Kind     string
+	BaseURL  string
+	Username string
+	Password string
+}
+
+// RemoteSource fetches a user's library from another music server.
+type RemoteSource interface {
+	Fetch(ctx context.Context) (*SourceLibrary, error)
+}
+
+// NewRemoteSource returns the client for creds.Kind after validating the URL.
+func NewRemoteSource(creds RemoteCredentials, httpClient *http.Client) (RemoteSource, error) {
+	base, err := url.Parse(strings.TrimSpace(creds.BaseURL))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, ErrInvalidRemote
+	}
+	base.Path = strings.TrimRight(base.Path, "/")
+	base.RawQuery = ""
+	base.Fragment = ""
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	switch creds.Kind {
+	case SourceNavidrome:
+		return &NavidromeSource{baseURL: base, username: creds.Username, password: creds.Password, httpClient: httpClient}, nil
+	case SourceJellyfin:
+		return &JellyfinSource{baseURL: base, username: creds.Username, password: creds.Password, httpClient: httpClient}, nil
+	}
+	return nil, ErrUnsupportedRemote
+}
+
+// remoteLibraryBuilder collects tracks by remote ID so playlist entries that
+// reference the same song share one SourceTrack.
+type remoteLibraryBuilder struct {
+	lib   SourceLibrary
+	index map[string]int
+}
+
+func newRemoteLibraryBuilder() *remoteLibraryBuilder {
+	return &remoteLibraryBuilder{index: make(map[string]int)}
+}
+
+func (b *remoteLibraryBuilder) addTrack(track SourceTrack) error {
+	if track.SourceID == "" {
+		return nil
+	}
+	if _, ok := b.index[track.SourceID]; ok {
+		return nil
+	}
+	if len(b.lib.Tracks) >= remoteMaxTracks {
+		return fmt.Errorf("%w: library exceeds %d tracks", ErrRemoteUnavailable, remoteMaxTracks)
+	}
+	b.index[track.SourceID] = len(b.lib.Tracks)
+	b.lib.Tracks = append(b.lib.Tracks, track)
+	return nil
+}
+
+func (b *remoteLibraryBuilder) library() *SourceLibrary {
+	return &b.lib
+}
+
+// getRemoteJSON performs a GET and decodes the JSON body into out. 401 and 403
+// map to ErrRemoteAuth; other failures wrap ErrRemoteUnavailable. The error
+// never includes the request URL, which may carry credentials.
+func getRemoteJSON(ctx context.Context, client *http.Client, req *http.Request, out interface{}) error {
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", remoteUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrRemoteAuth
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &remoteStatusError{status: resp.StatusCode}
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, remoteMaxResponseBytes)).Decode(out); err != nil {
+		return fmt.Errorf("%w: decode response: %v", ErrRemoteUnavailable, err)
+	}
+	return nil
+}
+
+// remoteStatusError is an unexpected HTTP status; it is an
+// ErrRemoteUnavailable.
+type remoteStatusError struct {
+	status int
+}
+
+func (e *remoteStatusError) Error() string {
+	return fmt.Sprintf("%v: unexpected status %d", ErrRemoteUnavailable, e.status)
+}
+
+func (e *remoteStatusError) Unwrap() error {
+	return ErrRemoteUnavailable
+}
+
+func isRemoteStatus(err error, status int) bool {
+	var statusErr *remoteStatusError
+	return errors.As(err, &statusErr) && statusErr.status == status
+}
+
+// parseRemoteTime accepts the RFC 3339 timestamps both servers emit; anything
+// else is treated as unknown.
+func parseRemoteTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || parsed.Year() < 1971 {
+		return nil
+	}
+	parsed = parsed.UTC()
+	return &parsed
+}
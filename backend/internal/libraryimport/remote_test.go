@@ -0,0 +1,145 @@
+package libraryimport
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewRemoteSourceValidatesKindAndURL(t *testing.T) {
+	if _, err := NewRemoteSource(RemoteCredentials{Kind: SourceNavidrome, BaseURL: "ftp://music.local"}, nil); !errors.Is(err, ErrInvalidRemote) {
+		t.Fatalf("ftp url err = %v, want ErrInvalidRemote", err)
+	}
+	if _, err := NewRemoteSource(RemoteCredentials{Kind: "plex", BaseURL: "http://music.local"}, nil); !errors.Is(err, ErrUnsupportedRemote) {
+		t.Fatalf("plex err = %v, want ErrUnsupportedRemote", err)
+	}
+}
+
+func TestNavidromeFetchUsesTokenAuthAndMergesPlaylistEntries(t *testing.T) {
+	song := func(id, title string, plays int, starred string, rating int) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "title": title, "artist": "Artist", "duration": 200,
+			"playCount": plays, "played": "2025-02-03T04:05:06Z", "starred": starred, "userRating": rating,
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("p") != "" {
+			t.Errorf("plain password must not be sent")
+		}
+		sum := md5.Sum([]byte("secret" + q.Get("s")))
+		if q.Get("u") != "alice" || q.Get("t") != hex.EncodeToString(sum[:]) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"subsonic-response": map[string]interface{}{
+				"status": "failed", "error": map[string]interface{}{"code": 40, "message": "Wrong username or password"},
+			}})
+			return
+		}
+		body := map[string]interface{}{"status": "ok"}
+		switch r.URL.Path {
+		case "/music/rest/search3":
+			body["searchResult3"] = map[string]interface{}{"song": []interface{}{
+				song("s1", "One", 4, "2024-01-01T00:00:00Z", 5),
+				song("s2", "Two", 0, "", 0),
+			}}
+		case "/music/rest/getPlaylists":
+			body["playlists"] = map[string]interface{}{"playlist": []interface{}{map[string]interface{}{"id": "p1", "name": "Mix"}}}
+		case "/music/rest/getPlaylist":
+			body["playlist"] = map[string]interface{}{"id": "p1", "name": "Mix", "entry": []interface{}{
+				song("s2", "Two", 0, "", 0),
+				song("s3", "Three", 1, "", 0),
+			}}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"subsonic-response": body})
+	}))
+	defer server.Close()
+
+	source, err := NewRemoteSource(RemoteCredentials{Kind: SourceNavidrome, BaseURL: server.URL + "/music/", Username: "alice", Password: "secret"}, server.Client())
+	if err != nil {
+		t.Fatalf("new source: %v", err)
+	}
+	lib, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	if len(lib.Tracks) != 3 {
+		t.Fatalf("tracks = %#v, want playlist-only song added", lib.Tracks)
+	}
+	first := lib.Tracks[0]
+	if !first.Loved || first.Rating != 5 || first.PlayCount != 4 || first.DurationMs != 200000 || first.LastPlayedAt == nil {
+		t.Fatalf("first track = %#v", first)
+	}
+	if len(lib.Playlists) != 1 || !reflect.DeepEqual(lib.Playlists[0].TrackSourceIDs, []string{"s2", "s3"}) {
+		t.Fatalf("playlists = %#v", lib.Playlists)
+	}
+
+	bad, _ := NewRemoteSource(RemoteCredentials{Kind: SourceNavidrome, BaseURL: server.URL + "/music", Username: "alice", Password: "wrong"}, server.Client())
+	if _, err := bad.Fetch(context.Background()); !errors.Is(err, ErrRemoteAuth) {
+		t.Fatalf("wrong password err = %v, want ErrRemoteAuth", err)
+	}
+}
+
+func TestJellyfinFetchAuthenticatesAndReadsUserData(t *testing.T) {
+	item := func(id, name string, favorite bool) map[string]interface{} {
+		return map[string]interface{}{
+			"Id": id, "Name": name, "Artists": []string{"A", "B"}, "RunTimeTicks": 1800000000,
+			"UserData": map[string]interface{}{"PlayCount": 7, "IsFavorite": favorite, "LastPlayedDate": "2025-06-01T10:00:00.0000000Z"},
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Users/AuthenticateByName" {
+			var creds map[string]string
+			json.NewDecoder(r.Body).Decode(&creds)
+			if creds["Username"] != "bob" || creds["Pw"] != "pw" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"AccessToken": "tok", "User": map[string]string{"Id": "u1"}})
+			return
+		}
+		if r.Header.Get("X-Emby-Token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var items []interface{}
+		switch {
+		case r.URL.Path == "/Users/u1/Items" && r.URL.Query().Get("IncludeItemTypes") == "Audio":
+			items = []interface{}{item("a1", "Song", true)}
+		case r.URL.Path == "/Users/u1/Items":
+			items = []interface{}{map[string]interface{}{"Id": "pl1", "Name": "Road Trip"}}
+		case r.URL.Path == "/Playlists/pl1/Items":
+			items = []interface{}{item("a1", "Song", true), item("a2", "Other", false)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Items": items, "TotalRecordCount": len(items)})
+	}))
+	defer server.Close()
+
+	source, _ := NewRemoteSource(RemoteCredentials{Kind: SourceJellyfin, BaseURL: server.URL, Username: "bob", Password: "pw"}, server.Client())
+	lib, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(lib.Tracks) != 2 {
+		t.Fatalf("tracks = %#v", lib.Tracks)
+	}
+	first := lib.Tracks[0]
+	if first.Artist != "A, B" || first.DurationMs != 180000 || !first.Loved || first.PlayCount != 7 || first.LastPlayedAt == nil {
+		t.Fatalf("first track = %#v", first)
+	}
+	if len(lib.Playlists) != 1 || !reflect.DeepEqual(lib.Playlists[0].TrackSourceIDs, []string{"a1", "a2"}) {
+		t.Fatalf("playlists = %#v", lib.Playlists)
+	}
+
+	bad, _ := NewRemoteSource(RemoteCredentials{Kind: SourceJellyfin, BaseURL: server.URL, Username: "bob", Password: "nope"}, server.Client())
+	if _, err := bad.Fetch(context.Background()); !errors.Is(err, ErrRemoteAuth) {
+		t.Fatalf("wrong password err = %v, want ErrRemoteAuth", err)
+	}
+}
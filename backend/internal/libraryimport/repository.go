@@ -0,0 +1,81 @@
+package libraryimport
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type Repository struct {
+	db *db.DB
+}
+
+func NewRepository(database *db.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// LibraryCandidates returns every track in the user's library.
+func (r *Repository) LibraryCandidates(ctx context.Context, userID uuid.UUID) ([]Candidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.title, COALESCE(t.artist, ''), COALESCE(t.duration_ms, 0)
+		FROM user_library ul
+		JOIN tracks t ON t.id = ul.track_id
+		WHERE ul.user_id = $1
+		ORDER BY t.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.TrackID, &c.Title, &c.Artist, &c.DurationMs); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// SetRating stores the user's star rating for a track, replacing any earlier
+// rating.
+func (r *Repository) SetRating(ctx context.Context, userID uuid.UUID, trackID int64, stars int, source string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO track_ratings (user_id, track_id, rating, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, track_id)
+		DO UPDATE SET rating = EXCLUDED.rating, source = EXCLUDED.source, updated_at = NOW()
+	`, userID, trackID, stars, source)
+	return err
+}
+
+// MergePlayStats raises the library play count and last-played time to the
+// imported values. Counts never decrease, so importing the same export twice
+// does not double them.
+func (r *Repository) MergePlayStats(ctx context.Context, userID uuid.UUID, trackID int64, playCount int, lastPlayedAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_library
+		SET play_count = GREATEST(play_count, $3),
+			last_played_at = GREATEST(last_played_at, $4)
+		WHERE user_id = $1 AND track_id = $2
+	`, userID, trackID, playCount, lastPlayedAt)
+	return err
+}
+
+// AddFavorite likes the track; already liked tracks are left alone.
+func (r *Repository) AddFavorite(ctx context.Context, userID uuid.UUID, trackID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO track_favorites (user_id, track_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, track_id) DO NOTHING
+	`, userID, trackID)
+	return err
+}
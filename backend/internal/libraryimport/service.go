@@ -0,0 +1,194 @@
+package libraryimport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Store backfills per-user library state for matched tracks.
+type Store interface {
+	LibraryCandidates(ctx context.Context, userID uuid.UUID) ([]Candidate, error)
+	SetRating(ctx context.Context, userID uuid.UUID, trackID int64, stars int, source string) error
+	MergePlayStats(ctx context.Context, userID uuid.UUID, trackID int64, playCount int, lastPlayedAt *time.Time) error
+	AddFavorite(ctx context.Context, userID uuid.UUID, trackID int64) error
+}
+
+type PlaylistStore interface {
+	Create(ctx context.Context, playlist *db.Playlist) error
+	AddTracks(ctx context.Context, playlistID int64, trackIDs []int64) (db.AddTracksResult, error)
+}
+
+// Service matches an external library export against a user's library and
+// backfills ratings, play counts, favorites, and playlists for the matches.
+// Only tracks already in the user's library are touched; unmatched items are
+// reported, never downloaded.
+type Service struct {
+	store     Store
+	playlists PlaylistStore
+}
+
+func NewService(store Store, playlists PlaylistStore) *Service {
+	return &Service{store: store, playlists: playlists}
+}
+
+// Import applies lib to the user's library and returns a per-item report.
+// Re-importing the same export is safe: ratings are overwritten, play counts
+// only ever grow to the imported value, and favorites are idempotent. Playlists
+// are created fresh on every import.
+func (s *Service) Import(ctx context.Context, userID uuid.UUID, source string, lib *SourceLibrary) (*Report, error) {
+	candidates, err := s.store.LibraryCandidates(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load library: %w", err)
+	}
+	m := NewLibraryMatcher(candidates)
+
+	report := &Report{UserID: userID, Source: source, Tracks: make([]TrackReport, 0, len(lib.Tracks))}
+	matched := make(map[string]int64, len(lib.Tracks))
+	for _, track := range lib.Tracks {
+		match := m.Match(track)
+		item := TrackReport{
+			SourceID: track.SourceID,
+			Title:    track.Title,
+			Artist:   track.Artist,
+			Status:   match.Status,
+			TrackID:  match.TrackID,
+			Score:    match.Score,
+		}
+		switch match.Status {
+		case MatchExact:
+			report.ExactMatches++
+		case MatchFuzzy:
+			report.FuzzyMatches++
+		default:
+			report.Unmatched++
+			report.Tracks = append(report.Tracks, item)
+			continue
+		}
+		matched[track.SourceID] = match.TrackID
+
+		if track.Rating > 0 {
+			if err := s.store.SetRating(ctx, userID, match.TrackID, track.Rating, source); err != nil {
+				return nil, fmt.Errorf("set rating for track %d: %w", match.TrackID, err)
+			}
+			item.RatingApplied = true
+			report.RatingsImported++
+		}
+		if track.PlayCount > 0 || track.LastPlayedAt != nil {
+			if err := s.store.MergePlayStats(ctx, userID, match.TrackID, track.PlayCount, track.LastPlayedAt); err != nil {
+				return nil, fmt.Errorf("merge play stats for track %d: %w", match.TrackID, err)
+			}
+			item.PlayCountApplied = true
+			report.PlayCountsImported++
+		}
+		if track.Loved {
+			if err := s.store.AddFavorite(ctx, userID, match.TrackID); err != nil {
+				return nil, fmt.Errorf("favorite track %d: %w", match.TrackID, err)
+			}
+			item.Favorited = true
+			report.FavoritesImported++
+		}
+		report.Tracks = append(report.Tracks, item)
+	}
+
+	for _, playlist := range lib.Playlists {
+		item := PlaylistReport{SourceID: playlist.SourceID, Name: playlist.Name}
+		trackIDs := make([]int64, 0, len(playlist.TrackSourceIDs))
+		for _, sourceID := range playlist.TrackSourceIDs {
+			if trackID, ok := matched[sourceID]; ok {
+				trackIDs = append(trackIDs, trackID)
+			} else {
+				item.MissingTracks++
+			}
+		}
+		if len(trackIDs) == 0 {
+			item.Status = PlaylistStatusEmpty
+			report.Playlists = append(report.Playlists, item)
+			continue
+		}
+
+		created := &db.Playlist{
+			UserID:      userID,
+			Name:        truncateRunes(playlist.Name, 255),
+			Description: sql.NullString{String: "Imported from " + sourceLabel(source), Valid: true},
+		}
+		if err := s.playlists.Create(ctx, created); err != nil {
+			return nil, fmt.Errorf("create playlist %q: %w", playlist.Name, err)
+		}
+		result, err := s.playlists.AddTracks(ctx, created.ID, trackIDs)
+		if err != nil {
+			return nil, fmt.Errorf("add tracks to playlist %q: %w", playlist.Name, err)
+		}
+		item.Status = PlaylistStatusCreated
+		item.PlaylistID = created.ID
+		item.MatchedTracks = len(result.Added)
+		report.PlaylistsCreated++
+		report.Playlists = append(report.Playlists, item)
+	}
+
+	return report, nil
+}
+
+const (
+	playlistFileSuggestions     = 3
+	playlistFileSuggestionScore = 50.0
+)
+
+// MatchPlaylistFile matches each entry of a playlist file against the user's
+// library without changing anything. Clients show the result, let the user
+// settle fuzzy and unmatched rows, and build a playlist from the chosen IDs.
+func (s *Service) MatchPlaylistFile(ctx context.Context, userID uuid.UUID, entries []PlaylistFileEntry) ([]PlaylistFileMatch, error) {
+	candidates, err := s.store.LibraryCandidates(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load library: %w", err)
+	}
+	m := NewLibraryMatcher(candidates)
+	byID := make(map[int64]Candidate, len(candidates))
+	for _, c := range candidates {
+		byID[c.TrackID] = c
+	}
+
+	matches := make([]PlaylistFileMatch, 0, len(entries))
+	for _, entry := range entries {
+		result := PlaylistFileMatch{Entry: entry, Match: m.Match(entry.SourceTrack)}
+		if track, ok := byID[result.Match.TrackID]; ok && result.Match.Status != MatchUnmatched {
+			result.Track = &track
+		}
+		if result.Match.Status != MatchExact {
+			for _, suggestion := range m.Suggest(entry.SourceTrack, playlistFileSuggestions+1, playlistFileSuggestionScore) {
+				if suggestion.TrackID != result.Match.TrackID && len(result.Suggestions) < playlistFileSuggestions {
+					result.Suggestions = append(result.Suggestions, PlaylistFileSuggestion{Track: byID[suggestion.TrackID], Score: suggestion.Score})
+				}
+			}
+		}
+		matches = append(matches, result)
+	}
+	return matches, nil
+}
+
+func sourceLabel(source string) string {
+	switch source {
+	case SourceITunes:
+		return "iTunes"
+	case SourceNavidrome:
+		return "Navidrome"
+	case SourceJellyfin:
+		return "Jellyfin"
+	case SourceSpotify:
+		return "Spotify"
+	}
+	return source
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
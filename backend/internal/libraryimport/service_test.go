@@ -0,0 +1,135 @@
+package libraryimport
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeImportStore struct {
+	candidates []Candidate
+	ratings    map[int64]int
+	playCounts map[int64]int
+	favorites  map[int64]bool
+}
+
+func (s *fakeImportStore) LibraryCandidates(context.Context, uuid.UUID) ([]Candidate, error) {
+	return s.candidates, nil
+}
+
+func (s *fakeImportStore) SetRating(_ context.Context, _ uuid.UUID, trackID int64, stars int, _ string) error {
+	s.ratings[trackID] = stars
+	return nil
+}
+
+func (s *fakeImportStore) MergePlayStats(_ context.Context, _ uuid.UUID, trackID int64, playCount int, _ *time.Time) error {
+	s.playCounts[trackID] = playCount
+	return nil
+}
+
+func (s *fakeImportStore) AddFavorite(_ context.Context, _ uuid.UUID, trackID int64) error {
+	s.favorites[trackID] = true
+	return nil
+}
+
+type fakeImportPlaylists struct {
+	created []*db.Playlist
+	tracks  map[int64][]int64
+}
+
+func (p *fakeImportPlaylists) Create(_ context.Context, playlist *db.Playlist) error {
+	playlist.ID = int64(len(p.created) + 1)
+	p.created = append(p.created, playlist)
+	return nil
+}
+
+func (p *fakeImportPlaylists) AddTracks(_ context.Context, playlistID int64, trackIDs []int64) (db.AddTracksResult, error) {
+	p.tracks[playlistID] = append(p.tracks[playlistID], trackIDs...)
+	return db.AddTracksResult{Added: trackIDs, Skipped: []int64{}}, nil
+}
+
+func TestLibraryMatcherExactFuzzyAndUnmatched(t *testing.T) {
+	m := NewLibraryMatcher([]Candidate{
+		{TrackID: 1, Title: "Windowlicker", Artist: "Aphex Twin", DurationMs: 365000},
+		{TrackID: 2, Title: "Roygbiv", Artist: "Boards of Canada", DurationMs: 151000},
+		{TrackID: 3, Title: "Windowlicker", Artist: "Aphex Twin", DurationMs: 600000},
+	})
+
+	cases := []struct {
+		name  string
+		track SourceTrack
+		want  Match
+	}{
+		{"identity key with closest duration", SourceTrack{Title: " WINDOWLICKER", Artist: "aphex twin", DurationMs: 367000}, Match{TrackID: 1, Status: MatchExact, Score: 100}},
+		{"unknown duration still exact", SourceTrack{Title: "Roygbiv", Artist: "Boards Of Canada"}, Match{TrackID: 2, Status: MatchExact, Score: 100}},
+		{"artist spelling differs", SourceTrack{Title: "Roygbiv", Artist: "Boards Canada", DurationMs: 151000}, Match{TrackID: 2, Status: MatchFuzzy}},
+		{"no shared title or artist", SourceTrack{Title: "Olson", Artist: "Someone Else", DurationMs: 91000}, Match{Status: MatchUnmatched}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := m.Match(tc.track)
+			if got.Status != tc.want.Status || got.TrackID != tc.want.TrackID {
+				t.Fatalf("match = %#v, want %#v", got, tc.want)
+			}
+			if tc.want.Status == MatchFuzzy && got.Score < 85 {
+				t.Fatalf("fuzzy score = %v, want >= 85", got.Score)
+			}
+		})
+	}
+}
+
+func TestImportBackfillsMatchedTracksAndPlaylists(t *testing.T) {
+	f, err := os.Open("testdata/itunes_library.xml")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+	lib, err := ParseITunesLibrary(f)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	store := &fakeImportStore{
+		candidates: []Candidate{
+			{TrackID: 10, Title: "Windowlicker", Artist: "Aphex Twin", DurationMs: 367000},
+			{TrackID: 20, Title: "Roygbiv", Artist: "Boards of Canada", DurationMs: 151500},
+		},
+		ratings:    map[int64]int{},
+		playCounts: map[int64]int{},
+		favorites:  map[int64]bool{},
+	}
+	playlists := &fakeImportPlaylists{tracks: map[int64][]int64{}}
+
+	report, err := NewService(store, playlists).Import(context.Background(), uuid.New(), SourceITunes, lib)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if report.ExactMatches != 2 || report.Unmatched != 1 || len(report.Tracks) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if store.ratings[10] != 4 || len(store.ratings) != 1 {
+		t.Fatalf("ratings = %v, want only the explicit rating", store.ratings)
+	}
+	if store.playCounts[10] != 12 || !store.favorites[10] {
+		t.Fatalf("play counts = %v favorites = %v", store.playCounts, store.favorites)
+	}
+	if unmatched := report.Tracks[2]; unmatched.Status != MatchUnmatched || unmatched.PlayCountApplied {
+		t.Fatalf("unmatched track report = %+v", unmatched)
+	}
+
+	if report.PlaylistsCreated != 1 || len(playlists.created) != 1 {
+		t.Fatalf("playlists created = %d", report.PlaylistsCreated)
+	}
+	if got := playlists.tracks[1]; len(got) != 2 || got[0] != 20 || got[1] != 10 {
+		t.Fatalf("playlist tracks = %v, want source order [20 10]", got)
+	}
+	if report.Playlists[0].MissingTracks != 1 || report.Playlists[1].Status != PlaylistStatusEmpty {
+		t.Fatalf("playlist reports = %+v", report.Playlists)
+	}
+}
@@ -0,0 +1,235 @@
+package libraryimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SourceSpotify = "spotify"
+
+	spotifyAPIBase      = "https://api.spotify.com/v1"
+	spotifyTokenURL     = "https://accounts.spotify.com/api/token"
+	spotifyTokenLeeway  = time.Minute
+	spotifyTrackFields  = "items(track(id,name,type,duration_ms,artists(name),album(name))),next"
+	spotifyHeaderFields = "name,tracks(" + spotifyTrackFields + ")"
+)
+
+var (
+	spotifyPlaylistURL = regexp.MustCompile(`^https?://open\.spotify\.com/(?:intl-[a-z-]+/)?(?:user/[^/]+/)?playlist/([A-Za-z0-9]{10,40})(?:[/?#].*)?$`)
+	spotifyPlaylistURI = regexp.MustCompile(`^spotify:(?:user:[^:]+:)?playlist:([A-Za-z0-9]{10,40})$`)
+)
+
+// SpotifySource reads public playlists through the Spotify Web API with an
+// app's client credentials; no listener signs in. Playlists Spotify generates
+// for a listener (Discover Weekly, Daily Mix) are not visible to app tokens.
+type SpotifySource struct {
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	apiBase      string
+	tokenURL     string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewSpotifySource returns a provider for Spotify playlist URLs. With an empty
+// client ID or secret it still recognizes URLs, but fetching fails with
+// ErrProviderNotConfigured.
+func NewSpotifySource(clientID, clientSecret string, httpClient *http.Client) *SpotifySource {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &SpotifySource{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   httpClient,
+		apiBase:      spotifyAPIBase,
+		tokenURL:     spotifyTokenURL,
+	}
+}
+
+func (s *SpotifySource) Source() string {
+	return SourceSpotify
+}
+
+// PlaylistID accepts open.spotify.com playlist links, with or without a
+// locale segment and share query, and spotify:playlist: URIs.
+func (s *SpotifySource) PlaylistID(rawURL string) (string, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	for _, pattern := range []*regexp.Regexp{spotifyPlaylistURL, spotifyPlaylistURI} {
+		if m := pattern.FindStringSubmatch(rawURL); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+type spotifyTrackPage struct {
+	Items []struct {
+		Track *struct {
+			ID         string `json:"id"`
+			Name       string `json:"name"`
+			Type       string `json:"type"`
+			DurationMs int    `json:"duration_ms"`
+			Artists    []struct {
+				Name string `json:"name"`
+			} `json:"artists"`
+			Album struct {
+				Name string `json:"name"`
+			} `json:"album"`
+		} `json:"track"`
+	} `json:"items"`
+	Next string `json:"next"`
+}
+
+// FetchPlaylist reads the playlist's name and every track on it. Podcast
+// episodes are skipped; local files, which have no Spotify ID, are kept by
+// position so they can still match by title and artist.
+func (s *SpotifySource) FetchPlaylist(ctx context.Context, id string) (*SourceLibrary, error) {
+	if s.clientID == "" || s.clientSecret == "" {
+		return nil, ErrProviderNotConfigured
+	}
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Name   string           `json:"name"`
+		Tracks spotifyTrackPage `json:"tracks"`
+	}
+	pageURL := s.apiBase + "/playlists/" + url.PathEscape(id) + "?" + url.Values{
+		"fields":           {spotifyHeaderFields},
+		"additional_types": {"track"},
+	}.Encode()
+	if err := s.get(ctx, token, pageURL, &header); err != nil {
+		return nil, err
+	}
+
+	b := newRemoteLibraryBuilder()
+	playlist := SourcePlaylist{SourceID: id, Name: header.Name}
+	page := header.Tracks
+	for position := 0; ; {
+		for _, item := range page.Items {
+			position++
+			track := item.Track
+			if track == nil || track.Type != "track" || track.Name == "" {
+				continue
+			}
+			sourceID := track.ID
+			if sourceID == "" {
+				sourceID = "local:" + strconv.Itoa(position)
+			}
+			artists := make([]string, 0, len(track.Artists))
+			for _, artist := range track.Artists {
+				if artist.Name != "" {
+					artists = append(artists, artist.Name)
+				}
+			}
+			// The first credited artist is the one local copies are filed
+			// under; "A, B" would defeat exact matching.
+			artist := ""
+			if len(artists) > 0 {
+				artist = artists[0]
+			}
+			if err := b.addTrack(SourceTrack{
+				SourceID:   sourceID,
+				Title:      track.Name,
+				Artist:     artist,
+				Album:      track.Album.Name,
+				DurationMs: track.DurationMs,
+			}); err != nil {
+				return nil, err
+			}
+			playlist.TrackSourceIDs = append(playlist.TrackSourceIDs, sourceID)
+		}
+		if page.Next == "" {
+			break
+		}
+		next := page.Next
+		if !strings.HasPrefix(next, s.apiBase+"/") {
+			return nil, fmt.Errorf("%w: unexpected next page URL", ErrRemoteUnavailable)
+		}
+		page = spotifyTrackPage{}
+		if err := s.get(ctx, token, next, &page); err != nil {
+			return nil, err
+		}
+	}
+
+	lib := b.library()
+	lib.Playlists = []SourcePlaylist{playlist}
+	return lib, nil
+}
+
+func (s *SpotifySource) get(ctx context.Context, token, rawURL string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	err = getRemoteJSON(ctx, s.httpClient, req, out)
+	if errors.Is(err, ErrRemoteAuth) {
+		// The app's credentials were revoked or the token expired early;
+		// fetch a new one next time.
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+	}
+	if isRemoteStatus(err, http.StatusNotFound) || isRemoteStatus(err, http.StatusBadRequest) {
+		return ErrRemotePlaylistNotFound
+	}
+	return err
+}
+
+// accessToken returns a cached client-credentials token, requesting a new
+// one shortly before the old one expires.
+func (s *SpotifySource) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.tokenExpiry) {
+		return s.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRemoteUnavailable, err)
+	}
+	req.SetBasicAuth(s.clientID, s.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", remoteUserAgent)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: token request failed", ErrRemoteUnavailable)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return "", ErrRemoteAuth
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token request returned status %d", ErrRemoteUnavailable, resp.StatusCode)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil || body.AccessToken == "" {
+		return "", fmt.Errorf("%w: invalid token response", ErrRemoteUnavailable)
+	}
+	s.token = body.AccessToken
+	s.tokenExpiry = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - spotifyTokenLeeway)
+	return s.token, nil
+}
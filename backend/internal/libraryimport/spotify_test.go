@@ -0,0 +1,99 @@
+package libraryimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpotifyPlaylistID(t *testing.T) {
+	s := NewSpotifySource("", "", nil)
+	for raw, want := range map[string]string{
+		"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M":                 "37i9dQZF1DXcBWIGoYBM5M",
+		"https://open.spotify.com/intl-de/playlist/37i9dQZF1DXcBWIGoYBM5M?si=abc1": "37i9dQZF1DXcBWIGoYBM5M",
+		"https://open.spotify.com/user/alice/playlist/37i9dQZF1DXcBWIGoYBM5M":      "37i9dQZF1DXcBWIGoYBM5M",
+		" spotify:playlist:37i9dQZF1DXcBWIGoYBM5M ":                                "37i9dQZF1DXcBWIGoYBM5M",
+		"https://open.spotify.com/album/37i9dQZF1DXcBWIGoYBM5M":                    "",
+		"https://evil.example/open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M":    "",
+	} {
+		got, ok := s.PlaylistID(raw)
+		if got != want || ok != (want != "") {
+			t.Errorf("PlaylistID(%q) = %q, %v; want %q", raw, got, ok, want)
+		}
+	}
+}
+
+func TestSpotifyFetchPlaylistPagesAndCachesToken(t *testing.T) {
+	tokenRequests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		track := func(id, name, kind string) map[string]interface{} {
+			return map[string]interface{}{"track": map[string]interface{}{
+				"id": id, "name": name, "type": kind, "duration_ms": 200000,
+				"artists": []interface{}{map[string]interface{}{"name": "Burial"}, map[string]interface{}{"name": "Four Tet"}},
+				"album":   map[string]interface{}{"name": "Untrue"},
+			}}
+		}
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if user, pass, _ := r.BasicAuth(); user != "id" || pass != "secret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tok", "expires_in": 3600})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/playlists/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/playlists/p1":
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": "Night Bus", "tracks": map[string]interface{}{
+				"items": []interface{}{track("t1", "Archangel", "track"), track("e1", "Episode", "episode")},
+				"next":  server.URL + "/v1/playlists/p1/tracks?offset=2",
+			}})
+		case "/v1/playlists/p1/tracks":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []interface{}{track("", "Home Demo", "track"), map[string]interface{}{"track": nil}},
+			})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	s := NewSpotifySource("id", "secret", server.Client())
+	s.apiBase = server.URL + "/v1"
+	s.tokenURL = server.URL + "/token"
+
+	lib, err := s.FetchPlaylist(context.Background(), "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Tracks) != 2 || lib.Tracks[0].Artist != "Burial" || lib.Tracks[0].Album != "Untrue" || lib.Tracks[1].SourceID != "local:3" {
+		t.Fatalf("tracks = %+v", lib.Tracks)
+	}
+	if len(lib.Playlists) != 1 || lib.Playlists[0].Name != "Night Bus" || len(lib.Playlists[0].TrackSourceIDs) != 2 {
+		t.Fatalf("playlists = %+v", lib.Playlists)
+	}
+
+	if _, err := s.FetchPlaylist(context.Background(), "missing"); !errors.Is(err, ErrRemotePlaylistNotFound) {
+		t.Fatalf("missing playlist err = %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Fatalf("token requests = %d, want 1", tokenRequests)
+	}
+}
+
+func TestSpotifyFetchPlaylistRequiresCredentials(t *testing.T) {
+	if _, err := NewSpotifySource("id", "", nil).FetchPlaylist(context.Background(), "p1"); !errors.Is(err, ErrProviderNotConfigured) {
+		t.Fatalf("err = %v, want ErrProviderNotConfigured", err)
+	}
+}
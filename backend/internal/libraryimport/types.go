@@ -0,0 +1,116 @@
+package libraryimport
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SourceITunes = "itunes"
+
+	MatchExact     = "exact"
+	MatchFuzzy     = "fuzzy"
+	MatchUnmatched = "unmatched"
+
+	PlaylistStatusCreated = "created"
+	PlaylistStatusEmpty   = "skipped_empty"
+)
+
+// SourceTrack is one track as described by an external library. Rating is in
+// stars (0-5, 0 meaning unrated); LastPlayedAt is nil when unknown.
+type SourceTrack struct {
+	SourceID     string
+	Title        string
+	Artist       string
+	Album        string
+	DurationMs   int
+	Rating       int
+	Loved        bool
+	PlayCount    int
+	LastPlayedAt *time.Time
+}
+
+// SourcePlaylist lists its tracks by SourceTrack.SourceID, in order.
+type SourcePlaylist struct {
+	SourceID       string
+	Name           string
+	TrackSourceIDs []string
+}
+
+// SourceLibrary is a parsed external library export.
+type SourceLibrary struct {
+	Tracks    []SourceTrack
+	Playlists []SourcePlaylist
+}
+
+// Candidate is a track in the user's library that source tracks can match.
+type Candidate struct {
+	TrackID    int64
+	Title      string
+	Artist     string
+	DurationMs int
+}
+
+// Match is the outcome of matching one source track. TrackID is zero and Score
+// is the best rejected score when Status is MatchUnmatched.
+type Match struct {
+	TrackID int64
+	Status  string
+	Score   float64
+}
+
+// TrackReport records what happened to one source track.
+type TrackReport struct {
+	SourceID         string
+	Title            string
+	Artist           string
+	Status           string
+	TrackID          int64
+	Score            float64
+	RatingApplied    bool
+	PlayCountApplied bool
+	Favorited        bool
+}
+
+// PlaylistReport records what happened to one source playlist. PlaylistID is
+// zero when nothing matched and no playlist was created.
+type PlaylistReport struct {
+	SourceID      string
+	Name          string
+	Status        string
+	PlaylistID    int64
+	MatchedTracks int
+	MissingTracks int
+}
+
+// Report is the per-item outcome of an import.
+type Report struct {
+	UserID             uuid.UUID
+	Source             string
+	Tracks             []TrackReport
+	Playlists          []PlaylistReport
+	ExactMatches       int
+	FuzzyMatches       int
+	Unmatched          int
+	RatingsImported    int
+	PlayCountsImported int
+	FavoritesImported  int
+	PlaylistsCreated   int
+}
+
+// PlaylistFileMatch is the outcome of matching one playlist file entry.
+// Track is the matched library track, nil when unmatched. Suggestions are
+// other library tracks the entry might be, best first, offered when the match
+// is not exact so the user can resolve it.
+type PlaylistFileMatch struct {
+	Entry       PlaylistFileEntry
+	Match       Match
+	Track       *Candidate
+	Suggestions []PlaylistFileSuggestion
+}
+
+type PlaylistFileSuggestion struct {
+	Track Candidate
+	Score float64
+}
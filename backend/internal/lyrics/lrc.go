@@ -0,0 +1,99 @@
+// Package lyrics finds song lyrics for tracks. Lyrics are looked up on
+// LRCLIB by artist, title, and duration, cached in Postgres, and may be
+// replaced by a listener's own edit. Synced lyrics are LRC text, which
+// ParseLRC turns into timed lines for karaoke-style display.
+package lyrics
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoTimedLines reports LRC text without a single timed line.
+var ErrNoTimedLines = errors.New("synced lyrics have no timed lines")
+
+// Line is one lyric line and when it starts, in milliseconds from the start
+// of the track.
+type Line struct {
+	TimeMs int    `json:"timeMs"`
+	Text   string `json:"text"`
+}
+
+// ParseLRC reads LRC text such as "[00:12.34]First line" into lines in time
+// order. A line may carry several timestamps, and an [offset:+/-ms] tag
+// shifts every line. Metadata tags like [ar:] and untimed lines are
+// skipped.
+func ParseLRC(text string) []Line {
+	var lines []Line
+	offset := 0
+	for _, raw := range strings.Split(text, "\n") {
+		rest := strings.TrimSpace(raw)
+		var times []int
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				break
+			}
+			tag := rest[1:end]
+			if ms, ok := parseLRCTime(tag); ok {
+				times = append(times, ms)
+			} else if value, ok := strings.CutPrefix(tag, "offset:"); ok {
+				if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+					offset = ms
+				}
+			}
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+		for _, ms := range times {
+			lines = append(lines, Line{TimeMs: ms, Text: rest})
+		}
+	}
+	// A positive offset makes lyrics appear sooner.
+	for i := range lines {
+		lines[i].TimeMs = max(lines[i].TimeMs-offset, 0)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].TimeMs < lines[j].TimeMs })
+	return lines
+}
+
+// ValidateLRC reports whether text has at least one timed line.
+func ValidateLRC(text string) error {
+	if len(ParseLRC(text)) == 0 {
+		return ErrNoTimedLines
+	}
+	return nil
+}
+
+// parseLRCTime reads "mm:ss", "mm:ss.xx", or "mm:ss.xxx".
+func parseLRCTime(tag string) (int, bool) {
+	minutes, seconds, ok := strings.Cut(tag, ":")
+	if !ok {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, false
+	}
+	whole, fraction, _ := strings.Cut(seconds, ".")
+	s, err := strconv.Atoi(whole)
+	if err != nil || s < 0 || s >= 60 || len(whole) != 2 {
+		return 0, false
+	}
+	ms := 0
+	if fraction != "" {
+		if len(fraction) > 3 {
+			return 0, false
+		}
+		f, err := strconv.Atoi(fraction)
+		if err != nil || f < 0 {
+			return 0, false
+		}
+		for i := len(fraction); i < 3; i++ {
+			f *= 10
+		}
+		ms = f
+	}
+	return (m*60+s)*1000 + ms, true
+}
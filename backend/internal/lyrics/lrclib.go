@@ -0,0 +1,137 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLRCLIBURL is the public LRCLIB server. See
+	// https://lrclib.net/docs.
+	DefaultLRCLIBURL = "https://lrclib.net"
+
+	lrclibUserAgent = "OpenMusicPlayer/1.0.0 (lyrics)"
+	// lrclibMaxResponseBytes bounds how much of a response is read.
+	lrclibMaxResponseBytes = 1 << 20
+)
+
+// ErrNotFound reports that a source has no lyrics for a track.
+var ErrNotFound = errors.New("lyrics not found")
+
+// Query names the track to look lyrics up for. DurationMs narrows the match
+// to recordings of the same length; without it the best search hit is used.
+type Query struct {
+	Artist     string
+	Title      string
+	Album      string
+	DurationMs int
+}
+
+// Result is what a source found for a query.
+type Result struct {
+	ExternalID   int64
+	PlainLyrics  string
+	SyncedLyrics string
+	Instrumental bool
+}
+
+// LRCLIB looks up lyrics on an LRCLIB server.
+type LRCLIB struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewLRCLIB targets the server at baseURL, such as DefaultLRCLIBURL.
+func NewLRCLIB(baseURL string, httpClient *http.Client) *LRCLIB {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &LRCLIB{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+type lrclibRecord struct {
+	ID           int64   `json:"id"`
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
+
+// Lookup finds lyrics for q: the record matching its length through
+// /api/get when the duration is known, otherwise the first search hit with
+// lyrics. It returns ErrNotFound when LRCLIB has none.
+func (c *LRCLIB) Lookup(ctx context.Context, q Query) (*Result, error) {
+	if strings.TrimSpace(q.Title) == "" || strings.TrimSpace(q.Artist) == "" {
+		return nil, ErrNotFound
+	}
+	params := url.Values{"artist_name": {q.Artist}, "track_name": {q.Title}}
+	if q.Album != "" {
+		params.Set("album_name", q.Album)
+	}
+	if q.DurationMs > 0 {
+		params.Set("duration", strconv.Itoa(int(math.Round(float64(q.DurationMs)/1000))))
+		var record lrclibRecord
+		if err := c.get(ctx, "/api/get", params, &record); err != nil {
+			return nil, err
+		}
+		return record.result()
+	}
+
+	params.Del("album_name")
+	var records []lrclibRecord
+	if err := c.get(ctx, "/api/search", params, &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if result, err := record.result(); err == nil {
+			return result, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (c *LRCLIB) get(ctx context.Context, path string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", lrclibUserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("lrclib lookup: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, lrclibMaxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("read lrclib response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("lrclib lookup: HTTP %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode lrclib response: %w", err)
+	}
+	return nil
+}
+
+func (r lrclibRecord) result() (*Result, error) {
+	plain, synced := strings.TrimSpace(r.PlainLyrics), strings.TrimSpace(r.SyncedLyrics)
+	if !r.Instrumental && plain == "" && synced == "" {
+		return nil, ErrNotFound
+	}
+	return &Result{ExternalID: r.ID, PlainLyrics: plain, SyncedLyrics: synced, Instrumental: r.Instrumental}, nil
+}
@@ -0,0 +1,169 @@
+package lyrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func TestParseLRC(t *testing.T) {
+	got := ParseLRC("[ar:Artist]\n[offset:+500]\n[00:12.34]First line\n[01:02.5][00:05.00]Chorus\nnot timed\n[00:20]")
+	want := []Line{
+		{TimeMs: 4500, Text: "Chorus"},
+		{TimeMs: 11840, Text: "First line"},
+		{TimeMs: 19500, Text: ""},
+		{TimeMs: 62000, Text: "Chorus"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("lines = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("lines = %+v, want %+v", got, want)
+		}
+	}
+	if err := ValidateLRC("just words\n[ti:Song]"); !errors.Is(err, ErrNoTimedLines) {
+		t.Fatalf("ValidateLRC err = %v, want ErrNoTimedLines", err)
+	}
+}
+
+func TestLRCLIBLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/api/get" && q.Get("track_name") == "Song" && q.Get("duration") == "234":
+			w.Write([]byte(`{"id":7,"duration":234,"instrumental":false,"plainLyrics":"Hello\n","syncedLyrics":"[00:01.00]Hello"}`))
+		case r.URL.Path == "/api/search" && q.Get("duration") == "":
+			w.Write([]byte(`[{"id":8,"plainLyrics":""},{"id":9,"instrumental":true}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"name":"TrackNotFound"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewLRCLIB(server.URL+"/", server.Client())
+
+	got, err := client.Lookup(context.Background(), Query{Artist: "Artist", Title: "Song", DurationMs: 233600})
+	if err != nil || got.ExternalID != 7 || got.PlainLyrics != "Hello" || got.SyncedLyrics != "[00:01.00]Hello" {
+		t.Fatalf("Lookup = %+v, %v", got, err)
+	}
+	if got, err := client.Lookup(context.Background(), Query{Artist: "Artist", Title: "Other"}); err != nil || got.ExternalID != 9 || !got.Instrumental {
+		t.Fatalf("search Lookup = %+v, %v; want the instrumental hit", got, err)
+	}
+	if _, err := client.Lookup(context.Background(), Query{Artist: "Artist", Title: "Missing", DurationMs: 1000}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing Lookup err = %v, want ErrNotFound", err)
+	}
+}
+
+type fakeStore struct {
+	rows map[int64]*db.TrackLyrics
+}
+
+func (f *fakeStore) GetTrackLyrics(_ context.Context, trackID int64) (*db.TrackLyrics, error) {
+	if l, ok := f.rows[trackID]; ok {
+		copied := *l
+		return &copied, nil
+	}
+	return nil, db.ErrLyricsNotFound
+}
+
+func (f *fakeStore) SaveFetchedLyrics(_ context.Context, l *db.TrackLyrics) (bool, error) {
+	if existing, ok := f.rows[l.TrackID]; ok && existing.Source == db.LyricsSourceManual {
+		return false, nil
+	}
+	l.FetchedAt = time.Now()
+	copied := *l
+	f.rows[l.TrackID] = &copied
+	return true, nil
+}
+
+func (f *fakeStore) SaveManualLyrics(_ context.Context, l *db.TrackLyrics) error {
+	l.Source = db.LyricsSourceManual
+	copied := *l
+	f.rows[l.TrackID] = &copied
+	return nil
+}
+
+func (f *fakeStore) DeleteTrackLyrics(_ context.Context, trackID int64) error {
+	delete(f.rows, trackID)
+	return nil
+}
+
+type fakeSource struct {
+	queries []Query
+	result  *Result
+	err     error
+}
+
+func (f *fakeSource) Lookup(_ context.Context, q Query) (*Result, error) {
+	f.queries = append(f.queries, q)
+	return f.result, f.err
+}
+
+func TestServiceCachesLookups(t *testing.T) {
+	store := &fakeStore{rows: map[int64]*db.TrackLyrics{}}
+	source := &fakeSource{result: &Result{ExternalID: 7, SyncedLyrics: "[00:01.00]Hello"}}
+	service := NewService(store, source)
+	track := &db.Track{ID: 1, Title: "Song", Artist: sql.NullString{String: "Artist", Valid: true}, DurationMs: sql.NullInt32{Int32: 200000, Valid: true}}
+
+	for range 2 {
+		got, err := service.ForTrack(context.Background(), track)
+		if err != nil || got.Source != db.LyricsSourceLRCLIB || got.SyncedLyrics != "[00:01.00]Hello" {
+			t.Fatalf("ForTrack = %+v, %v", got, err)
+		}
+	}
+	if len(source.queries) != 1 || source.queries[0] != (Query{Artist: "Artist", Title: "Song", DurationMs: 200000}) {
+		t.Fatalf("queries = %+v, want one lookup", source.queries)
+	}
+
+	// A lookup that found nothing is remembered until RetryAfter passes.
+	source.err = ErrNotFound
+	missing := &db.Track{ID: 2, Title: "Unknown", Artist: sql.NullString{String: "Artist", Valid: true}}
+	for range 2 {
+		if _, err := service.ForTrack(context.Background(), missing); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("ForTrack err = %v, want ErrNotFound", err)
+		}
+	}
+	service.now = func() time.Time { return time.Now().Add(RetryAfter + time.Hour) }
+	service.ForTrack(context.Background(), missing)
+	if len(source.queries) != 3 {
+		t.Fatalf("made %d lookups, want the miss cached and then retried", len(source.queries))
+	}
+
+	source.err = errors.New("lrclib down")
+	if _, err := service.ForTrack(context.Background(), &db.Track{ID: 3, Title: "Song", Artist: track.Artist}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("ForTrack err = %v, want the lookup failure", err)
+	}
+	if _, ok := store.rows[3]; ok {
+		t.Fatal("cached a failed lookup")
+	}
+}
+
+func TestServiceSaveKeepsManualLyrics(t *testing.T) {
+	store := &fakeStore{rows: map[int64]*db.TrackLyrics{}}
+	source := &fakeSource{result: &Result{PlainLyrics: "fetched"}}
+	service := NewService(store, source)
+	userID := uuid.New()
+
+	if _, err := service.Save(context.Background(), 1, userID, Edit{SyncedLyrics: "no timings"}); !errors.Is(err, ErrNoTimedLines) {
+		t.Fatalf("Save err = %v, want ErrNoTimedLines", err)
+	}
+	if _, err := service.Save(context.Background(), 1, userID, Edit{PlainLyrics: "  "}); !errors.Is(err, ErrEmptyEdit) {
+		t.Fatalf("Save err = %v, want ErrEmptyEdit", err)
+	}
+	if _, err := service.Save(context.Background(), 1, userID, Edit{PlainLyrics: " mine \n"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := service.ForTrack(context.Background(), &db.Track{ID: 1, Title: "Song"})
+	if err != nil || got.Source != db.LyricsSourceManual || got.PlainLyrics != "mine" || *got.EditedBy != userID || len(source.queries) != 0 {
+		t.Fatalf("ForTrack = %+v, %v after %d lookups", got, err, len(source.queries))
+	}
+}
@@ -0,0 +1,136 @@
+package lyrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// RetryAfter is how long a lookup that found nothing is remembered before
+// the source is asked again; LRCLIB gains lyrics as listeners submit them.
+const RetryAfter = 7 * 24 * time.Hour
+
+// ErrEmptyEdit reports an edit with no lyrics for a track that is not
+// instrumental; Reset is how lyrics are removed.
+var ErrEmptyEdit = errors.New("lyrics edit is empty")
+
+// Source looks lyrics up for a track. *LRCLIB satisfies it.
+type Source interface {
+	Lookup(ctx context.Context, q Query) (*Result, error)
+}
+
+// Store caches lyrics per track. db.LyricsRepository satisfies it.
+type Store interface {
+	GetTrackLyrics(ctx context.Context, trackID int64) (*db.TrackLyrics, error)
+	SaveFetchedLyrics(ctx context.Context, l *db.TrackLyrics) (bool, error)
+	SaveManualLyrics(ctx context.Context, l *db.TrackLyrics) error
+	DeleteTrackLyrics(ctx context.Context, trackID int64) error
+}
+
+// Edit is a listener's replacement for a track's lyrics.
+type Edit struct {
+	PlainLyrics  string
+	SyncedLyrics string
+	Instrumental bool
+}
+
+// Service serves cached lyrics, looking them up on first request.
+type Service struct {
+	store  Store
+	source Source
+	now    func() time.Time
+}
+
+// NewService caches lookups from source in store. A nil source serves only
+// lyrics already stored or entered by listeners.
+func NewService(store Store, source Source) *Service {
+	return &Service{store: store, source: source, now: time.Now}
+}
+
+// ForTrack returns the track's lyrics, looking them up when none are cached
+// or a lookup that found nothing is older than RetryAfter. It returns
+// ErrNotFound when the track has none.
+func (s *Service) ForTrack(ctx context.Context, track *db.Track) (*db.TrackLyrics, error) {
+	stored, err := s.store.GetTrackLyrics(ctx, track.ID)
+	if err != nil && !errors.Is(err, db.ErrLyricsNotFound) {
+		return nil, err
+	}
+	if stored != nil && (stored.Source != db.LyricsSourceNone || s.source == nil || s.now().Sub(stored.FetchedAt) < RetryAfter) {
+		if stored.Source == db.LyricsSourceNone {
+			return nil, ErrNotFound
+		}
+		return stored, nil
+	}
+	if s.source == nil {
+		return nil, ErrNotFound
+	}
+
+	q := Query{Artist: track.Artist.String, Title: track.Title, Album: track.Album.String}
+	if track.DurationMs.Valid {
+		q.DurationMs = int(track.DurationMs.Int32)
+	}
+	fetched := &db.TrackLyrics{TrackID: track.ID, Source: db.LyricsSourceNone}
+	result, err := s.source.Lookup(ctx, q)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("look up lyrics: %w", err)
+	default:
+		fetched.Source = db.LyricsSourceLRCLIB
+		fetched.PlainLyrics = result.PlainLyrics
+		fetched.SyncedLyrics = result.SyncedLyrics
+		fetched.Instrumental = result.Instrumental
+		fetched.ExternalID = sql.NullInt64{Int64: result.ExternalID, Valid: result.ExternalID > 0}
+	}
+	saved, err := s.store.SaveFetchedLyrics(ctx, fetched)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		// A listener saved lyrics while the lookup ran; theirs win.
+		return s.ForTrack(ctx, track)
+	}
+	if fetched.Source == db.LyricsSourceNone {
+		return nil, ErrNotFound
+	}
+	return fetched, nil
+}
+
+// Save replaces the track's lyrics with a listener's edit. Synced lyrics
+// must be LRC text with at least one timed line.
+func (s *Service) Save(ctx context.Context, trackID int64, userID uuid.UUID, edit Edit) (*db.TrackLyrics, error) {
+	edit.PlainLyrics = strings.TrimSpace(edit.PlainLyrics)
+	edit.SyncedLyrics = strings.TrimSpace(edit.SyncedLyrics)
+	if edit.PlainLyrics == "" && edit.SyncedLyrics == "" && !edit.Instrumental {
+		return nil, ErrEmptyEdit
+	}
+	if edit.SyncedLyrics != "" {
+		if err := ValidateLRC(edit.SyncedLyrics); err != nil {
+			return nil, err
+		}
+	}
+	l := &db.TrackLyrics{
+		TrackID:      trackID,
+		PlainLyrics:  edit.PlainLyrics,
+		SyncedLyrics: edit.SyncedLyrics,
+		Instrumental: edit.Instrumental,
+		EditedBy:     &userID,
+	}
+	if err := s.store.SaveManualLyrics(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reset drops the track's lyrics, including a listener's edit, so the next
+// request looks them up again.
+func (s *Service) Reset(ctx context.Context, trackID int64) error {
+	return s.store.DeleteTrackLyrics(ctx, trackID)
+}
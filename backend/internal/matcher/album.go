@@ -0,0 +1,412 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+const (
+	// AlbumAutoMatchThreshold is the minimum album score for linking every
+	// track in the set without review.
+	AlbumAutoMatchThreshold = 85.0
+
+	// MaxAlbumTracks bounds one album match request.
+	MaxAlbumTracks = 100
+
+	albumSearchLimit       = 10
+	albumMaxReleaseLookups = 5
+	albumMaxCandidates     = 3
+	// albumMinTitleScore is the title similarity a local track needs before it
+	// can be paired with a release track at all.
+	albumMinTitleScore = 60.0
+	// albumDurationTolerance is tighter than the per-track tolerance: the same
+	// rip of an album agrees to within a second or two per track.
+	albumDurationToleranceSeconds = 3.0
+	albumDurationZeroSeconds      = 15.0
+)
+
+var ErrAlbumTrackCount = fmt.Errorf("album match needs between 1 and %d tracks", MaxAlbumTracks)
+
+// AlbumTrack is one local track in a set being matched as an album, in the
+// order it was downloaded.
+type AlbumTrack struct {
+	TrackID    int64  `json:"trackId,omitempty"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist,omitempty"`
+	Album      string `json:"album,omitempty"`
+	DurationMs int    `json:"durationMs,omitempty"`
+}
+
+// AlbumMatchInput is a set of tracks believed to come from one release. Album
+// and Artist are optional hints; without them the most common values on the
+// tracks are used.
+type AlbumMatchInput struct {
+	Album  string
+	Artist string
+	Tracks []AlbumTrack
+}
+
+// AlbumTrackAssignment pairs one input track with a release track. Position is
+// 1-based across every medium of the release.
+type AlbumTrackAssignment struct {
+	Index         int     `json:"index"`
+	TrackID       int64   `json:"trackId,omitempty"`
+	Position      int     `json:"position"`
+	MBRecordingID string  `json:"mbRecordingId"`
+	Title         string  `json:"title"`
+	DurationMs    int     `json:"durationMs,omitempty"`
+	TitleScore    float64 `json:"titleScore"`
+	DurationScore float64 `json:"durationScore"`
+}
+
+// AlbumScore breaks down how well a release fits the whole track set (0-100).
+type AlbumScore struct {
+	Overall         float64 `json:"overall"`
+	TrackCountScore float64 `json:"trackCountScore"`
+	TitleScore      float64 `json:"titleScore"`
+	DurationScore   float64 `json:"durationScore"`
+	OrderScore      float64 `json:"orderScore"`
+}
+
+// AlbumCandidate is one scored release with its per-track pairing.
+type AlbumCandidate struct {
+	ReleaseID    string                 `json:"releaseId"`
+	Title        string                 `json:"title"`
+	Artist       string                 `json:"artist,omitempty"`
+	ArtistMBID   string                 `json:"artistMbid,omitempty"`
+	Date         string                 `json:"date,omitempty"`
+	CoverArtURL  string                 `json:"coverArtUrl,omitempty"`
+	TrackCount   int                    `json:"trackCount"`
+	Score        AlbumScore             `json:"score"`
+	Confidence   float64                `json:"confidence"`
+	MatchReasons []string               `json:"matchReasons,omitempty"`
+	Assignments  []AlbumTrackAssignment `json:"assignments"`
+}
+
+// AlbumMatchOutput is the result of matching a track set against releases.
+// Verified is set only when the best release clears AlbumAutoMatchThreshold
+// and every input track was paired.
+type AlbumMatchOutput struct {
+	Verified   bool             `json:"verified"`
+	BestMatch  *AlbumCandidate  `json:"bestMatch,omitempty"`
+	Candidates []AlbumCandidate `json:"candidates,omitempty"`
+}
+
+// ReleaseCatalog is the MusicBrainz surface album matching needs.
+// *musicbrainz.Client satisfies it.
+type ReleaseCatalog interface {
+	SearchReleases(ctx context.Context, query string, limit, offset int, skipCache bool) (*musicbrainz.SearchResponse[musicbrainz.ReleaseResult], error)
+	GetRelease(ctx context.Context, mbID string) (*musicbrainz.Release, error)
+}
+
+// AlbumMatcher matches a set of tracks collectively against MusicBrainz
+// releases. Track count, per-track durations, and running order together
+// identify a release far more reliably than any single track title.
+type AlbumMatcher struct {
+	catalog ReleaseCatalog
+}
+
+func NewAlbumMatcher(catalog ReleaseCatalog) *AlbumMatcher {
+	return &AlbumMatcher{catalog: catalog}
+}
+
+// AlbumMatcher returns an album matcher backed by the same MusicBrainz client.
+func (m *Matcher) AlbumMatcher() *AlbumMatcher {
+	if m == nil || m.mbClient == nil {
+		return nil
+	}
+	return NewAlbumMatcher(m.mbClient)
+}
+
+type albumInputTrack struct {
+	title      string
+	artist     string
+	durationMs int
+}
+
+// MatchAlbum searches releases for the album hint and scores each against the
+// whole track set.
+func (a *AlbumMatcher) MatchAlbum(ctx context.Context, input AlbumMatchInput) (*AlbumMatchOutput, error) {
+	if len(input.Tracks) == 0 || len(input.Tracks) > MaxAlbumTracks {
+		return nil, ErrAlbumTrackCount
+	}
+	if a == nil || a.catalog == nil {
+		return nil, errors.New("musicbrainz client is not configured")
+	}
+
+	tracks := make([]albumInputTrack, len(input.Tracks))
+	albums := make([]string, 0, len(input.Tracks))
+	artists := make([]string, 0, len(input.Tracks))
+	for i, t := range input.Tracks {
+		parsed := ParseTitle(t.Title)
+		artist := parsed.Artist
+		if artist == "" {
+			artist = cleanArtist(t.Artist)
+		}
+		tracks[i] = albumInputTrack{title: parsed.Track, artist: artist, durationMs: t.DurationMs}
+		albums = append(albums, t.Album)
+		artists = append(artists, artist)
+	}
+	album := strings.TrimSpace(input.Album)
+	if album == "" {
+		album = mostCommon(albums)
+	}
+	artist := strings.TrimSpace(input.Artist)
+	if artist == "" {
+		artist = mostCommon(artists)
+	}
+
+	query := buildAlbumSearchQuery(album, artist, len(tracks))
+	if query == "" {
+		return &AlbumMatchOutput{}, nil
+	}
+	searchResp, err := a.catalog.SearchReleases(ctx, query, albumSearchLimit, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("musicbrainz release search failed: %w", err)
+	}
+
+	releases := searchResp.Results
+	// Releases shorter than the set can never pair every track; an exact track
+	// count is the strongest pre-lookup signal, then the search score.
+	filtered := releases[:0:0]
+	for _, r := range releases {
+		if r.TrackCount == 0 || r.TrackCount >= len(tracks) {
+			filtered = append(filtered, r)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		exactI, exactJ := filtered[i].TrackCount == len(tracks), filtered[j].TrackCount == len(tracks)
+		if exactI != exactJ {
+			return exactI
+		}
+		return filtered[i].Score > filtered[j].Score
+	})
+	if len(filtered) > albumMaxReleaseLookups {
+		filtered = filtered[:albumMaxReleaseLookups]
+	}
+
+	candidates := make([]AlbumCandidate, 0, len(filtered))
+	for _, r := range filtered {
+		release, err := a.catalog.GetRelease(ctx, r.MBID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		candidates = append(candidates, scoreAlbumRelease(input.Tracks, tracks, release))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score.Overall > candidates[j].Score.Overall
+	})
+
+	output := &AlbumMatchOutput{}
+	if len(candidates) == 0 {
+		return output, nil
+	}
+	best := candidates[0]
+	output.BestMatch = &best
+	output.Verified = best.Score.Overall >= AlbumAutoMatchThreshold && len(best.Assignments) == len(tracks)
+	if !output.Verified {
+		limit := albumMaxCandidates
+		if len(candidates) < limit {
+			limit = len(candidates)
+		}
+		output.Candidates = candidates[:limit]
+	}
+	return output, nil
+}
+
+// scoreAlbumRelease pairs input tracks with release tracks greedily by title
+// and duration, then scores the pairing as a whole.
+func scoreAlbumRelease(originals []AlbumTrack, tracks []albumInputTrack, release *musicbrainz.Release) AlbumCandidate {
+	candidate := AlbumCandidate{
+		ReleaseID:   release.ID,
+		Title:       release.Title,
+		Artist:      release.Artist,
+		ArtistMBID:  release.ArtistID,
+		Date:        release.Date,
+		CoverArtURL: release.CoverArtURL,
+		TrackCount:  len(release.Tracks),
+		Assignments: []AlbumTrackAssignment{},
+	}
+	n, m := len(tracks), len(release.Tracks)
+	if n == 0 || m == 0 {
+		return candidate
+	}
+
+	type pair struct {
+		i, j     int
+		title    float64
+		duration float64
+	}
+	pairs := make([]pair, 0, n*m)
+	for i, t := range tracks {
+		for j, rt := range release.Tracks {
+			title := calculateStringSimilarity(t.title, rt.Title)
+			if title < albumMinTitleScore {
+				continue
+			}
+			pairs = append(pairs, pair{i: i, j: j, title: title, duration: albumDurationScore(t.durationMs, rt.Duration)})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		sa := pairs[a].title*0.7 + pairs[a].duration*0.3
+		sb := pairs[b].title*0.7 + pairs[b].duration*0.3
+		if sa != sb {
+			return sa > sb
+		}
+		// Prefer the pairing that keeps running order on ties, e.g. two
+		// identically titled interludes.
+		return absInt(pairs[a].i-pairs[a].j) < absInt(pairs[b].i-pairs[b].j)
+	})
+
+	assignedTrack := make([]int, n)
+	for i := range assignedTrack {
+		assignedTrack[i] = -1
+	}
+	usedRelease := make([]bool, m)
+	var titleSum, durationSum float64
+	for _, p := range pairs {
+		if assignedTrack[p.i] >= 0 || usedRelease[p.j] {
+			continue
+		}
+		assignedTrack[p.i] = p.j
+		usedRelease[p.j] = true
+		titleSum += p.title
+		durationSum += p.duration
+	}
+
+	order := make([]int, 0, n)
+	for i, j := range assignedTrack {
+		if j < 0 {
+			continue
+		}
+		rt := release.Tracks[j]
+		candidate.Assignments = append(candidate.Assignments, AlbumTrackAssignment{
+			Index:         i,
+			TrackID:       originals[i].TrackID,
+			Position:      j + 1,
+			MBRecordingID: rt.ID,
+			Title:         rt.Title,
+			DurationMs:    rt.Duration,
+			TitleScore:    calculateStringSimilarity(tracks[i].title, rt.Title),
+			DurationScore: albumDurationScore(tracks[i].durationMs, rt.Duration),
+		})
+		order = append(order, j)
+	}
+
+	score := AlbumScore{
+		TrackCountScore: 100 * float64(minInt(n, m)) / float64(maxInt(n, m)),
+		TitleScore:      titleSum / float64(n),
+		DurationScore:   durationSum / float64(n),
+		OrderScore:      100 * float64(longestIncreasingRun(order)) / float64(n),
+	}
+	score.Overall = score.TrackCountScore*0.20 + score.TitleScore*0.40 + score.DurationScore*0.25 + score.OrderScore*0.15
+	candidate.Score = score
+	candidate.Confidence = score.Overall / 100.0
+
+	if n == m {
+		candidate.MatchReasons = append(candidate.MatchReasons, "track count matches")
+	}
+	if len(candidate.Assignments) == n && score.TitleScore >= 90 {
+		candidate.MatchReasons = append(candidate.MatchReasons, "all titles match")
+	}
+	if score.DurationScore >= 90 {
+		candidate.MatchReasons = append(candidate.MatchReasons, "durations agree")
+	}
+	if score.OrderScore >= 99.9 {
+		candidate.MatchReasons = append(candidate.MatchReasons, "running order matches")
+	}
+	return candidate
+}
+
+// albumDurationScore is 100 within albumDurationToleranceSeconds, falling
+// linearly to 0 at albumDurationZeroSeconds. Unknown durations are neutral.
+func albumDurationScore(localMs, releaseMs int) float64 {
+	if localMs <= 0 || releaseMs <= 0 {
+		return 50.0
+	}
+	diff := math.Abs(float64(localMs-releaseMs)) / 1000.0
+	if diff <= albumDurationToleranceSeconds {
+		return 100.0
+	}
+	score := 100.0 * (albumDurationZeroSeconds - diff) / (albumDurationZeroSeconds - albumDurationToleranceSeconds)
+	return math.Max(0, score)
+}
+
+// longestIncreasingRun is the length of the longest strictly increasing
+// subsequence, i.e. how many tracks already sit in release order.
+func longestIncreasingRun(values []int) int {
+	tails := make([]int, 0, len(values))
+	for _, v := range values {
+		idx := sort.SearchInts(tails, v)
+		if idx == len(tails) {
+			tails = append(tails, v)
+		} else {
+			tails[idx] = v
+		}
+	}
+	return len(tails)
+}
+
+func buildAlbumSearchQuery(album, artist string, trackCount int) string {
+	var parts []string
+	if album != "" {
+		parts = append(parts, fmt.Sprintf("release:\"%s\"", escapeLuceneQuotes(album)))
+	}
+	if artist != "" {
+		parts = append(parts, fmt.Sprintf("artist:\"%s\"", escapeLuceneQuotes(artist)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	if album == "" {
+		// Without a title, the track count is what narrows an artist's
+		// discography down to this release.
+		parts = append(parts, fmt.Sprintf("tracks:%d", trackCount))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func escapeLuceneQuotes(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), `"`, `\"`)
+}
+
+// mostCommon returns the most frequent non-empty value, preferring the earliest
+// on ties.
+func mostCommon(values []string) string {
+	counts := make(map[string]int, len(values))
+	best, bestCount := "", 0
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		if counts[v] > bestCount {
+			best, bestCount = v, counts[v]
+		}
+	}
+	return best
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,165 @@
+package matcher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const maxAlbumMatchRequestBytes = 256 << 10
+
+// AlbumMatchRequest matches either stored tracks (TrackIDs, in download order)
+// or ad-hoc track metadata (Tracks). Apply links every stored track to its
+// paired recording when the album match is verified.
+type AlbumMatchRequest struct {
+	Album    string       `json:"album,omitempty"`
+	Artist   string       `json:"artist,omitempty"`
+	TrackIDs []int64      `json:"trackIds,omitempty"`
+	Tracks   []AlbumTrack `json:"tracks,omitempty"`
+	Apply    bool         `json:"apply,omitempty"`
+}
+
+// AlbumMatchResponse is the response for an album match request.
+type AlbumMatchResponse struct {
+	AlbumMatchOutput
+	AppliedTracks int `json:"appliedTracks"`
+}
+
+// HandleMatchAlbum handles POST /api/v1/albums/match - matches a set of tracks
+// collectively against a MusicBrainz release
+func (h *Handler) HandleMatchAlbum(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.albums == nil {
+		writeError(w, http.StatusServiceUnavailable, "Album matching is unavailable")
+		return
+	}
+
+	var req AlbumMatchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxAlbumMatchRequestBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if (len(req.TrackIDs) == 0) == (len(req.Tracks) == 0) {
+		writeError(w, http.StatusBadRequest, "Exactly one of trackIds or tracks is required")
+		return
+	}
+	if req.Apply && len(req.TrackIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "apply requires trackIds")
+		return
+	}
+
+	tracks := req.Tracks
+	if len(req.TrackIDs) > 0 {
+		if len(req.TrackIDs) > MaxAlbumTracks {
+			writeError(w, http.StatusBadRequest, ErrAlbumTrackCount.Error())
+			return
+		}
+		if h.trackRepo == nil {
+			writeError(w, http.StatusServiceUnavailable, "Album matching is unavailable")
+			return
+		}
+		tracks = make([]AlbumTrack, 0, len(req.TrackIDs))
+		seen := make(map[int64]bool, len(req.TrackIDs))
+		for _, id := range req.TrackIDs {
+			if seen[id] {
+				writeError(w, http.StatusBadRequest, "trackIds must not repeat")
+				return
+			}
+			seen[id] = true
+			track, err := h.trackRepo.GetByID(r.Context(), id)
+			if err != nil {
+				if errors.Is(err, db.ErrTrackNotFound) {
+					writeError(w, http.StatusNotFound, "Track not found")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "Failed to get track")
+				return
+			}
+			tracks = append(tracks, albumTrackFromDB(track))
+		}
+	}
+	for _, t := range tracks {
+		if strings.TrimSpace(t.Title) == "" {
+			writeError(w, http.StatusBadRequest, "Every track needs a title")
+			return
+		}
+	}
+
+	output, err := h.albums.MatchAlbum(r.Context(), AlbumMatchInput{Album: req.Album, Artist: req.Artist, Tracks: tracks})
+	if err != nil {
+		if errors.Is(err, ErrAlbumTrackCount) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Album matching failed: "+err.Error())
+		return
+	}
+
+	resp := AlbumMatchResponse{AlbumMatchOutput: *output}
+	if req.Apply && output.Verified {
+		for _, assignment := range output.BestMatch.Assignments {
+			update := albumMatchMBUpdate(output.BestMatch, assignment)
+			if update == nil {
+				continue
+			}
+			if err := h.trackRepo.UpdateMBMatch(r.Context(), assignment.TrackID, update); err != nil {
+				writeError(w, http.StatusInternalServerError, "Failed to update track")
+				return
+			}
+			h.matcher.SyncGenres(r.Context(), assignment.TrackID, update)
+			resp.AppliedTracks++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func albumTrackFromDB(track *db.Track) AlbumTrack {
+	t := AlbumTrack{TrackID: track.ID, Title: track.Title}
+	if track.Artist.Valid {
+		t.Artist = track.Artist.String
+	}
+	if track.Album.Valid {
+		t.Album = track.Album.String
+	}
+	if track.DurationMs.Valid {
+		t.DurationMs = int(track.DurationMs.Int32)
+	}
+	return t
+}
+
+// albumMatchMBUpdate links one stored track to its paired recording on the
+// matched release. User-edited tracks keep their identity.
+func albumMatchMBUpdate(release *AlbumCandidate, assignment AlbumTrackAssignment) *db.MBMatchUpdate {
+	if assignment.TrackID <= 0 {
+		return nil
+	}
+	recordingID, err := uuid.Parse(assignment.MBRecordingID)
+	if err != nil {
+		return nil
+	}
+	confidence := release.Confidence
+	update := &db.MBMatchUpdate{
+		MBRecordingID:      &recordingID,
+		MBVerified:         boolPtr(true),
+		ApplyMBIdentity:    true,
+		RespectUserEdits:   true,
+		MetadataConfidence: &confidence,
+	}
+	if releaseID, err := uuid.Parse(release.ReleaseID); err == nil {
+		update.MBReleaseID = &releaseID
+	}
+	if artistID, err := uuid.Parse(release.ArtistMBID); err == nil {
+		update.MBArtistID = &artistID
+	}
+	return update
+}
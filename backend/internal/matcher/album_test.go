@@ -0,0 +1,161 @@
+package matcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+type fakeReleaseCatalog struct {
+	results  []musicbrainz.ReleaseResult
+	releases map[string]*musicbrainz.Release
+	queries  []string
+	lookups  []string
+}
+
+func (f *fakeReleaseCatalog) SearchReleases(ctx context.Context, query string, limit, offset int, skipCache bool) (*musicbrainz.SearchResponse[musicbrainz.ReleaseResult], error) {
+	f.queries = append(f.queries, query)
+	return &musicbrainz.SearchResponse[musicbrainz.ReleaseResult]{Results: f.results}, nil
+}
+
+func (f *fakeReleaseCatalog) GetRelease(ctx context.Context, mbID string) (*musicbrainz.Release, error) {
+	f.lookups = append(f.lookups, mbID)
+	release, ok := f.releases[mbID]
+	if !ok {
+		return nil, musicbrainz.ErrNotFound
+	}
+	return release, nil
+}
+
+func testRelease(id, title string, tracks ...musicbrainz.Track) *musicbrainz.Release {
+	for i := range tracks {
+		tracks[i].Position = i + 1
+	}
+	return &musicbrainz.Release{
+		ID:         id,
+		Title:      title,
+		Artist:     "The Band",
+		ArtistID:   "44444444-4444-4444-4444-444444444444",
+		TrackCount: len(tracks),
+		Tracks:     tracks,
+	}
+}
+
+func albumCatalog() *fakeReleaseCatalog {
+	standard := testRelease("11111111-1111-1111-1111-111111111111", "Record",
+		musicbrainz.Track{ID: "aaaaaaaa-0000-0000-0000-000000000001", Title: "Opening", Duration: 201000},
+		musicbrainz.Track{ID: "aaaaaaaa-0000-0000-0000-000000000002", Title: "Middle Song", Duration: 185000},
+		musicbrainz.Track{ID: "aaaaaaaa-0000-0000-0000-000000000003", Title: "Closing Time", Duration: 240000},
+	)
+	// A deluxe edition shares the titles but adds bonus tracks, so track count
+	// and order must push it below the standard release.
+	deluxe := testRelease("22222222-2222-2222-2222-222222222222", "Record (Deluxe)",
+		musicbrainz.Track{ID: "bbbbbbbb-0000-0000-0000-000000000001", Title: "Intro Demo", Duration: 60000},
+		musicbrainz.Track{ID: "bbbbbbbb-0000-0000-0000-000000000002", Title: "Closing Time", Duration: 240000},
+		musicbrainz.Track{ID: "bbbbbbbb-0000-0000-0000-000000000003", Title: "Middle Song", Duration: 185000},
+		musicbrainz.Track{ID: "bbbbbbbb-0000-0000-0000-000000000004", Title: "Opening", Duration: 201000},
+		musicbrainz.Track{ID: "bbbbbbbb-0000-0000-0000-000000000005", Title: "Bonus", Duration: 200000},
+	)
+	return &fakeReleaseCatalog{
+		results: []musicbrainz.ReleaseResult{
+			{MBID: deluxe.ID, Title: deluxe.Title, TrackCount: 5, Score: 100},
+			{MBID: standard.ID, Title: standard.Title, TrackCount: 3, Score: 95},
+			{MBID: "33333333-3333-3333-3333-333333333333", Title: "Single", TrackCount: 1, Score: 90},
+		},
+		releases: map[string]*musicbrainz.Release{standard.ID: standard, deluxe.ID: deluxe},
+	}
+}
+
+func albumTracks() []AlbumTrack {
+	return []AlbumTrack{
+		{TrackID: 10, Title: "The Band - Opening (Official Audio)", Album: "Record", DurationMs: 202000},
+		{TrackID: 11, Title: "The Band - Middle Song", Album: "Record", DurationMs: 184500},
+		{TrackID: 12, Title: "The Band - Closing Time", Album: "Record", DurationMs: 241000},
+	}
+}
+
+func TestMatchAlbumPrefersReleaseMatchingCountDurationsAndOrder(t *testing.T) {
+	catalog := albumCatalog()
+	output, err := NewAlbumMatcher(catalog).MatchAlbum(context.Background(), AlbumMatchInput{Tracks: albumTracks()})
+	if err != nil {
+		t.Fatalf("MatchAlbum: %v", err)
+	}
+
+	if len(catalog.queries) != 1 || catalog.queries[0] != `release:"Record" AND artist:"The Band"` {
+		t.Fatalf("queries = %q, want album and artist inferred from the tracks", catalog.queries)
+	}
+	if len(catalog.lookups) != 2 || catalog.lookups[0] != "11111111-1111-1111-1111-111111111111" {
+		t.Fatalf("lookups = %q, want exact track count first and the short single skipped", catalog.lookups)
+	}
+	if !output.Verified || output.BestMatch == nil || output.BestMatch.ReleaseID != "11111111-1111-1111-1111-111111111111" {
+		t.Fatalf("output = %+v, want verified standard release", output)
+	}
+	if len(output.BestMatch.Assignments) != 3 {
+		t.Fatalf("assignments = %+v, want all three tracks", output.BestMatch.Assignments)
+	}
+	for i, a := range output.BestMatch.Assignments {
+		if a.Index != i || a.Position != i+1 || a.TrackID != int64(10+i) {
+			t.Fatalf("assignment %d = %+v, want in-order pairing", i, a)
+		}
+	}
+	if output.BestMatch.Score.OrderScore != 100 || output.BestMatch.Score.TrackCountScore != 100 {
+		t.Fatalf("score = %+v", output.BestMatch.Score)
+	}
+}
+
+func TestMatchAlbumDoesNotVerifyWhenTracksAreMissing(t *testing.T) {
+	catalog := albumCatalog()
+	tracks := albumTracks()
+	tracks[1].Title = "Completely Different"
+	output, err := NewAlbumMatcher(catalog).MatchAlbum(context.Background(), AlbumMatchInput{Tracks: tracks})
+	if err != nil {
+		t.Fatalf("MatchAlbum: %v", err)
+	}
+	if output.Verified {
+		t.Fatalf("output verified with an unpaired track: %+v", output.BestMatch)
+	}
+	if len(output.Candidates) == 0 {
+		t.Fatal("unverified match returned no candidates for review")
+	}
+}
+
+func TestBuildAlbumSearchQueryUsesTrackCountWithoutTitle(t *testing.T) {
+	if got := buildAlbumSearchQuery("", `Say "Hi"`, 12); got != `artist:"Say \"Hi\"" AND tracks:12` {
+		t.Fatalf("query = %q", got)
+	}
+	if got := buildAlbumSearchQuery("", "", 12); got != "" {
+		t.Fatalf("query = %q, want empty without album or artist", got)
+	}
+}
+
+func TestHandleMatchAlbumValidatesAndMatchesInlineTracks(t *testing.T) {
+	h := &Handler{albums: NewAlbumMatcher(albumCatalog())}
+
+	body, _ := json.Marshal(AlbumMatchRequest{Tracks: albumTracks()})
+	rec := httptest.NewRecorder()
+	h.HandleMatchAlbum(rec, httptest.NewRequest(http.MethodPost, "/api/v1/albums/match", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp AlbumMatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Verified || resp.AppliedTracks != 0 {
+		t.Fatalf("response = %+v, want verified without applying", resp)
+	}
+
+	for _, bad := range []string{`{}`, `{"trackIds":[1],"tracks":[{"title":"x"}]}`, `{"tracks":[{"title":"x"}],"apply":true}`, `{"tracks":[{"title":" "}]}`} {
+		rec := httptest.NewRecorder()
+		h.HandleMatchAlbum(rec, httptest.NewRequest(http.MethodPost, "/api/v1/albums/match", strings.NewReader(bad)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("body %s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+}
@@ -51,6 +51,7 @@ type TrackMetadata struct {
 	ThumbnailURL  string                 `json:"thumbnailUrl"`
 	RawProvider   map[string]interface{} `json:"rawProvider,omitempty"`
 	Deterministic map[string]interface{} `json:"deterministic,omitempty"`
+	Fingerprints  []FingerprintMatch     `json:"fingerprints,omitempty"` // AcoustID matches for the audio, best first
 }
 
 // DisambiguationInput is the only model input: existing candidates plus bounded
@@ -233,15 +234,18 @@ func applyDisambiguation(output *MatchOutput, decision *DisambiguationDecision)
 	return nil
 }
 
-func tryApplyDisambiguation(ctx context.Context, disambiguator Disambiguator, input DisambiguationInput, output *MatchOutput) {
+// tryApplyDisambiguation consults the disambiguator and applies a valid
+// decision. Errors leave the deterministic output untouched; they are returned
+// only so the caller can explain the run.
+func tryApplyDisambiguation(ctx context.Context, disambiguator Disambiguator, input DisambiguationInput, output *MatchOutput) error {
 	if disambiguator == nil || output == nil || output.Verified || len(output.Suggestions) == 0 {
-		return
+		return nil
 	}
 	decision, err := disambiguator.Disambiguate(ctx, input)
 	if err != nil {
-		return
+		return err
 	}
-	_ = applyDisambiguation(output, decision)
+	return applyDisambiguation(output, decision)
 }
 
 func moveSuggestionToFront(suggestions []MatchResult, idx int, selected MatchResult) []MatchResult {
@@ -0,0 +1,168 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// ExplanationStore persists the latest matcher explanation per track;
+// db.MatchExplanationRepository satisfies it.
+type ExplanationStore interface {
+	SaveMatchExplanation(ctx context.Context, trackID int64, explanation json.RawMessage) error
+	GetMatchExplanation(ctx context.Context, trackID int64) (*db.MatchExplanation, error)
+}
+
+// MatchExplanation records how one Match run reached its decision: what was
+// parsed from the title, every scored candidate, the thresholds in force and
+// each step taken along the way.
+type MatchExplanation struct {
+	TrackID      int64                 `json:"track_id,omitempty"`
+	AttemptedAt  time.Time             `json:"attempted_at"`
+	Input        ExplanationInput      `json:"input"`
+	ParsedTitle  *ParsedTitle          `json:"parsed_title,omitempty"`
+	ArtistSource string                `json:"artist_source"`
+	Query        string                `json:"query,omitempty"`
+	Candidates   []ExplainedCandidate  `json:"candidates"`
+	Thresholds   ExplanationThresholds `json:"thresholds"`
+	Decision     []DecisionStep        `json:"decision"`
+	Outcome      string                `json:"outcome"`
+	SelectedMBID string                `json:"selected_mb_recording_id,omitempty"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// ExplanationInput is the metadata a run matched against.
+type ExplanationInput struct {
+	Title        string `json:"title"`
+	Artist       string `json:"artist,omitempty"`
+	Album        string `json:"album,omitempty"`
+	Uploader     string `json:"uploader,omitempty"`
+	DurationMs   int    `json:"duration_ms,omitempty"`
+	Fingerprints int    `json:"fingerprints"`
+}
+
+// ExplainedCandidate is one MusicBrainz recording the run scored, with the
+// per-component breakdown behind its overall score.
+type ExplainedCandidate struct {
+	MBID     string      `json:"mb_recording_id"`
+	Title    string      `json:"title"`
+	Artist   string      `json:"artist"`
+	Album    string      `json:"album,omitempty"`
+	Duration int         `json:"duration,omitempty"`
+	Source   string      `json:"source"` // "fingerprint" or "search"
+	Score    *MatchScore `json:"score"`
+}
+
+// ExplanationThresholds are the scoring weights and cut-offs a run applied.
+type ExplanationThresholds struct {
+	ArtistWeight           float64 `json:"artist_weight"`
+	TrackWeight            float64 `json:"track_weight"`
+	DurationWeight         float64 `json:"duration_weight"`
+	AutoMatch              float64 `json:"auto_match"`
+	MediumConfidence       float64 `json:"medium_confidence"`
+	DurationToleranceSecs  int     `json:"duration_tolerance_seconds"`
+	FingerprintAutoMatch   float64 `json:"fingerprint_auto_match"`
+	FingerprintMinimum     float64 `json:"fingerprint_minimum"`
+	FingerprintMinDuration float64 `json:"fingerprint_min_duration_score"`
+	DisambiguationAuto     float64 `json:"disambiguation_auto_confidence"`
+	MaxSuggestions         int     `json:"max_suggestions"`
+}
+
+// DecisionStep is one branch the run took, in order.
+type DecisionStep struct {
+	Step   string `json:"step"`
+	Detail string `json:"detail"`
+}
+
+func newExplanation(metadata TrackMetadata, weights ScoreWeights) *MatchExplanation {
+	return &MatchExplanation{
+		AttemptedAt: time.Now().UTC(),
+		Input: ExplanationInput{
+			Title:        metadata.Title,
+			Artist:       metadata.Artist,
+			Album:        metadata.Album,
+			Uploader:     metadata.Uploader,
+			DurationMs:   metadata.DurationMs,
+			Fingerprints: len(metadata.Fingerprints),
+		},
+		ArtistSource: "none",
+		Candidates:   []ExplainedCandidate{},
+		Thresholds: ExplanationThresholds{
+			ArtistWeight:           weights.ArtistWeight,
+			TrackWeight:            weights.TrackWeight,
+			DurationWeight:         weights.DurationWeight,
+			AutoMatch:              AutoMatchThreshold,
+			MediumConfidence:       MediumConfidenceThreshold,
+			DurationToleranceSecs:  DurationTolerance,
+			FingerprintAutoMatch:   FingerprintAutoMatchScore,
+			FingerprintMinimum:     MinFingerprintScore,
+			FingerprintMinDuration: minFingerprintDurationScore,
+			DisambiguationAuto:     DisambiguationAutoConfidence,
+			MaxSuggestions:         maxSuggestions,
+		},
+		Outcome: "error",
+	}
+}
+
+func (e *MatchExplanation) step(name, format string, args ...interface{}) {
+	e.Decision = append(e.Decision, DecisionStep{Step: name, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (e *MatchExplanation) addCandidates(source string, results []MatchResult) {
+	for _, result := range results {
+		e.Candidates = append(e.Candidates, ExplainedCandidate{
+			MBID:     result.MBID,
+			Title:    result.Title,
+			Artist:   result.Artist,
+			Album:    result.Album,
+			Duration: result.Duration,
+			Source:   source,
+			Score:    result.Score,
+		})
+	}
+}
+
+// SetExplanationStore makes MatchTrack persist an explanation of every run.
+func (m *Matcher) SetExplanationStore(store ExplanationStore) {
+	m.explanations = store
+}
+
+// MatchTrack matches a stored track like Match and, when an explanation store
+// is set, records how the run decided. A failed save is logged, not returned,
+// so explanations never block matching.
+func (m *Matcher) MatchTrack(ctx context.Context, trackID int64, metadata TrackMetadata) (*MatchOutput, error) {
+	output, explanation, err := m.match(ctx, metadata)
+	if m.explanations != nil {
+		explanation.TrackID = trackID
+		if raw, marshalErr := json.Marshal(explanation); marshalErr != nil {
+			log.Printf("Warning: failed to encode match explanation for track %d: %v", trackID, marshalErr)
+		} else if saveErr := m.explanations.SaveMatchExplanation(ctx, trackID, raw); saveErr != nil {
+			log.Printf("Warning: failed to save match explanation for track %d: %v", trackID, saveErr)
+		}
+	}
+	return output, err
+}
+
+// Explanation returns the track's most recent match explanation.
+func (m *Matcher) Explanation(ctx context.Context, trackID int64) (*MatchExplanation, error) {
+	stored, err := m.explanations.GetMatchExplanation(ctx, trackID)
+	if err != nil {
+		return nil, err
+	}
+	explanation := &MatchExplanation{}
+	if err := json.Unmarshal(stored.Explanation, explanation); err != nil {
+		return nil, fmt.Errorf("decode match explanation: %w", err)
+	}
+	explanation.TrackID = stored.TrackID
+	explanation.AttemptedAt = stored.AttemptedAt
+	return explanation, nil
+}
+
+// ExplanationsEnabled reports whether match explanations are recorded.
+func (m *Matcher) ExplanationsEnabled() bool {
+	return m.explanations != nil
+}
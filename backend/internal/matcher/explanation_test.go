@@ -0,0 +1,98 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type memoryExplanationStore struct {
+	saved   map[int64]json.RawMessage
+	saveErr error
+}
+
+func (s *memoryExplanationStore) SaveMatchExplanation(ctx context.Context, trackID int64, explanation json.RawMessage) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	if s.saved == nil {
+		s.saved = make(map[int64]json.RawMessage)
+	}
+	s.saved[trackID] = explanation
+	return nil
+}
+
+func (s *memoryExplanationStore) GetMatchExplanation(ctx context.Context, trackID int64) (*db.MatchExplanation, error) {
+	raw, ok := s.saved[trackID]
+	if !ok {
+		return nil, db.ErrMatchExplanationNotFound
+	}
+	return &db.MatchExplanation{TrackID: trackID, Explanation: raw, AttemptedAt: time.Unix(1700000000, 0).UTC()}, nil
+}
+
+func TestMatchTrackRecordsExplanation(t *testing.T) {
+	store := &memoryExplanationStore{}
+	m := NewMatcher(nil)
+	m.SetExplanationStore(store)
+
+	if _, err := m.MatchTrack(context.Background(), 42, TrackMetadata{Uploader: "Some Channel", DurationMs: 180000}); err != nil {
+		t.Fatalf("MatchTrack: %v", err)
+	}
+
+	explanation, err := m.Explanation(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("Explanation: %v", err)
+	}
+	if explanation.TrackID != 42 || explanation.Outcome != "no_query" || explanation.ArtistSource != "uploader" {
+		t.Fatalf("explanation = %+v, want a no_query run for track 42 attributed to the uploader", explanation)
+	}
+	if explanation.ParsedTitle == nil || explanation.ParsedTitle.Artist != "Some Channel" {
+		t.Fatalf("parsed title = %+v, want the uploader as artist", explanation.ParsedTitle)
+	}
+	if explanation.Input.DurationMs != 180000 || explanation.Thresholds.AutoMatch != AutoMatchThreshold {
+		t.Fatalf("input %+v / thresholds %+v not recorded", explanation.Input, explanation.Thresholds)
+	}
+	if len(explanation.Decision) == 0 || explanation.Decision[len(explanation.Decision)-1].Step != "search" {
+		t.Fatalf("decision = %+v, want the run to end at the search step", explanation.Decision)
+	}
+	if !explanation.AttemptedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("attempted_at = %v, want the stored time", explanation.AttemptedAt)
+	}
+}
+
+func TestMatchTrackIgnoresExplanationSaveFailure(t *testing.T) {
+	m := NewMatcher(nil)
+	m.SetExplanationStore(&memoryExplanationStore{saveErr: errors.New("db down")})
+
+	output, err := m.MatchTrack(context.Background(), 7, TrackMetadata{})
+	if err != nil || output == nil {
+		t.Fatalf("MatchTrack = %+v, %v; a failed save must not fail matching", output, err)
+	}
+}
+
+func TestExplanationReportsMissingRun(t *testing.T) {
+	m := NewMatcher(nil)
+	m.SetExplanationStore(&memoryExplanationStore{})
+
+	if _, err := m.Explanation(context.Background(), 1); !errors.Is(err, db.ErrMatchExplanationNotFound) {
+		t.Fatalf("err = %v, want ErrMatchExplanationNotFound", err)
+	}
+}
+
+func TestExplanationAddCandidatesKeepsComponentScores(t *testing.T) {
+	exp := newExplanation(TrackMetadata{Title: "Artist - Song"}, DefaultWeights)
+	score := CalculateScore(&ParsedTitle{Artist: "Artist", Track: "Song"}, "Artist", "Song", 200000, 201000, 100, DefaultWeights)
+	exp.addCandidates("search", []MatchResult{{MBID: "rec-1", Title: "Song", Artist: "Artist", Score: score}})
+
+	if len(exp.Candidates) != 1 {
+		t.Fatalf("candidates = %+v", exp.Candidates)
+	}
+	got := exp.Candidates[0]
+	if got.Source != "search" || got.Score.ArtistScore != 100 || got.Score.TrackScore != 100 || got.Score.DurationScore == 0 {
+		t.Fatalf("candidate = %+v (score %+v), want per-component scores", got, got.Score)
+	}
+}
@@ -0,0 +1,105 @@
+package matcher
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+const (
+	// FingerprintAutoMatchScore is the AcoustID score at which a fingerprint
+	// match is verified without title-based scoring, provided the durations
+	// agree.
+	FingerprintAutoMatchScore = 0.9
+
+	// MinFingerprintScore is the lowest AcoustID score still offered as a
+	// suggestion.
+	MinFingerprintScore = 0.5
+
+	// maxFingerprintCandidates bounds the MusicBrainz lookups one match makes.
+	maxFingerprintCandidates = 3
+
+	// minFingerprintDurationScore rejects fingerprint matches whose length is
+	// more than about 20 seconds off, such as a full mix that opens with the
+	// matched track. An unknown duration scores a neutral 50 and passes.
+	minFingerprintDurationScore = 50.0
+)
+
+// FingerprintMatch is a MusicBrainz recording an acoustic fingerprint
+// lookup linked to the audio. Score runs from 0 to 1.
+type FingerprintMatch struct {
+	RecordingID string  `json:"recording_id"`
+	Score       float64 `json:"score"`
+}
+
+type recordingLookup func(ctx context.Context, mbID string) (*musicbrainz.Track, error)
+
+// fingerprintCandidates turns fingerprint matches into scored results, best
+// first. The title still contributes match reasons, but the fingerprint
+// score sets the floor and alone decides whether the result auto-matches.
+func (m *Matcher) fingerprintCandidates(ctx context.Context, metadata TrackMetadata, parsed *ParsedTitle) []MatchResult {
+	if len(metadata.Fingerprints) == 0 || m.mbClient == nil {
+		return nil
+	}
+	return scoreFingerprintMatches(ctx, m.mbClient.GetRecording, m.mbClient.GetCoverArtURL, metadata, parsed, m.weights)
+}
+
+func scoreFingerprintMatches(ctx context.Context, lookup recordingLookup, coverArtURL func(string) string, metadata TrackMetadata, parsed *ParsedTitle, weights ScoreWeights) []MatchResult {
+	var results []MatchResult
+	for _, fp := range metadata.Fingerprints {
+		if len(results) == maxFingerprintCandidates {
+			break
+		}
+		if fp.Score < MinFingerprintScore || fp.RecordingID == "" {
+			continue
+		}
+		recording, err := lookup(ctx, fp.RecordingID)
+		if err != nil {
+			log.Printf("Warning: fingerprint candidate %s lookup failed: %v", fp.RecordingID, err)
+			continue
+		}
+
+		score := CalculateScore(parsed, recording.Artist, recording.Title, metadata.DurationMs, recording.Duration, 0, weights)
+		score.Overall = math.Max(score.Overall, fp.Score*100)
+		score.MatchReasons = append(score.MatchReasons, "fingerprint_match")
+		score.IsAutoMatchable = fp.Score >= FingerprintAutoMatchScore && score.DurationScore >= minFingerprintDurationScore
+		switch {
+		case score.IsAutoMatchable:
+			score.Confidence = "high"
+		case score.Overall >= MediumConfidenceThreshold:
+			score.Confidence = "medium"
+		default:
+			score.Confidence = "low"
+		}
+
+		result := MatchResult{
+			MBID:         recording.ID,
+			Title:        recording.Title,
+			Artist:       recording.Artist,
+			ArtistMBID:   recording.ArtistID,
+			Album:        recording.Album,
+			ReleaseID:    recording.AlbumID,
+			Duration:     recording.Duration,
+			Score:        score,
+			MatchReasons: score.MatchReasons,
+			Confidence:   score.Overall / 100.0,
+		}
+		if recording.AlbumID != "" {
+			result.CoverArtURL = coverArtURL(recording.AlbumID)
+		}
+		results = append(results, result)
+	}
+	// AcoustID often links one fingerprint to several recordings of the same
+	// audio; among equal fingerprint scores the better title match leads.
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Score, results[j].Score
+		if a.Overall != b.Overall {
+			return a.Overall > b.Overall
+		}
+		return a.ArtistScore+a.TrackScore > b.ArtistScore+b.TrackScore
+	})
+	return results
+}
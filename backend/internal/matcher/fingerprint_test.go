@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+func fakeRecordings(recordings map[string]*musicbrainz.Track) recordingLookup {
+	return func(_ context.Context, mbID string) (*musicbrainz.Track, error) {
+		if recording, ok := recordings[mbID]; ok {
+			return recording, nil
+		}
+		return nil, errors.New("not found")
+	}
+}
+
+func coverArt(releaseID string) string { return "cover/" + releaseID }
+
+func TestFingerprintMatchAutoMatchesDespiteUnhelpfulTitle(t *testing.T) {
+	lookup := fakeRecordings(map[string]*musicbrainz.Track{
+		"rec-1": {ID: "rec-1", Title: "Windowlicker", Artist: "Aphex Twin", AlbumID: "rel-1", Duration: 367000},
+	})
+	metadata := TrackMetadata{
+		Title:        "track 04 (upload)",
+		DurationMs:   368000,
+		Fingerprints: []FingerprintMatch{{RecordingID: "rec-1", Score: 0.96}},
+	}
+
+	results := scoreFingerprintMatches(context.Background(), lookup, coverArt, metadata, ParseTitle(metadata.Title), DefaultWeights)
+	if len(results) != 1 || !results[0].Score.IsAutoMatchable {
+		t.Fatalf("results = %+v, want one auto-matchable result", results)
+	}
+	best := results[0]
+	if best.MBID != "rec-1" || best.Confidence != 0.96 || best.CoverArtURL != "cover/rel-1" {
+		t.Fatalf("best = %+v", best)
+	}
+	if reasons := best.MatchReasons; len(reasons) == 0 || reasons[len(reasons)-1] != "fingerprint_match" {
+		t.Fatalf("match reasons = %v, want fingerprint_match", reasons)
+	}
+}
+
+func TestFingerprintMatchNeedsScoreAndDurationToAutoMatch(t *testing.T) {
+	lookup := fakeRecordings(map[string]*musicbrainz.Track{
+		"short": {ID: "short", Title: "Intro", Artist: "A", Duration: 90000},
+		"weak":  {ID: "weak", Title: "Song", Artist: "B", Duration: 200000},
+		"faint": {ID: "faint", Title: "Other", Artist: "C", Duration: 200000},
+	})
+	metadata := TrackMetadata{
+		Title:      "Song",
+		DurationMs: 200000,
+		Fingerprints: []FingerprintMatch{
+			{RecordingID: "short", Score: 0.98}, // a 90s recording inside a 200s upload
+			{RecordingID: "missing", Score: 0.95},
+			{RecordingID: "weak", Score: 0.7},
+			{RecordingID: "faint", Score: 0.3},
+		},
+	}
+
+	results := scoreFingerprintMatches(context.Background(), lookup, coverArt, metadata, ParseTitle(metadata.Title), DefaultWeights)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want the two looked-up candidates above the minimum score", len(results))
+	}
+	for _, result := range results {
+		if result.Score.IsAutoMatchable {
+			t.Fatalf("%s auto-matched: %+v", result.MBID, result.Score)
+		}
+	}
+}
+
+func TestFingerprintMatchPrefersBetterTitleAmongEqualScores(t *testing.T) {
+	lookup := fakeRecordings(map[string]*musicbrainz.Track{
+		"compilation": {ID: "compilation", Title: "Roygbiv (live)", Artist: "Various Artists", Duration: 150000},
+		"album":       {ID: "album", Title: "Roygbiv", Artist: "Boards of Canada", Duration: 150000},
+	})
+	metadata := TrackMetadata{
+		Title:      "Boards of Canada - Roygbiv",
+		DurationMs: 151000,
+		Fingerprints: []FingerprintMatch{
+			{RecordingID: "compilation", Score: 0.93},
+			{RecordingID: "album", Score: 0.93},
+		},
+	}
+
+	results := scoreFingerprintMatches(context.Background(), lookup, coverArt, metadata, ParseTitle(metadata.Title), DefaultWeights)
+	if len(results) != 2 || results[0].MBID != "album" {
+		t.Fatalf("results = %+v, want the album recording first", results)
+	}
+}
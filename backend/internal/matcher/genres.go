@@ -0,0 +1,45 @@
+package matcher
+
+import (
+	"context"
+	"log"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// GenreStore keeps the MusicBrainz genres of matched tracks.
+type GenreStore interface {
+	ReplaceTrackGenres(ctx context.Context, trackID int64, genres []db.TrackGenre) error
+}
+
+// SetGenreStore makes SyncGenres store the genres of every track matched to
+// a MusicBrainz recording.
+func (m *Matcher) SetGenreStore(store GenreStore) {
+	m.genres = store
+}
+
+// SyncGenres stores the MusicBrainz genres of the recording update matches
+// the track to, falling back to its artist's genres. It does nothing for
+// updates that do not set a recording, and failures are logged, not
+// returned, so genres never block matching.
+func (m *Matcher) SyncGenres(ctx context.Context, trackID int64, update *db.MBMatchUpdate) {
+	if m.genres == nil || m.mbClient == nil || update == nil || !update.ApplyMBIdentity || update.MBRecordingID == nil {
+		return
+	}
+	artistID := ""
+	if update.MBArtistID != nil {
+		artistID = update.MBArtistID.String()
+	}
+	found, err := m.mbClient.GetRecordingGenres(ctx, update.MBRecordingID.String(), artistID)
+	if err != nil {
+		log.Printf("Warning: failed to fetch MusicBrainz genres for track %d: %v", trackID, err)
+		return
+	}
+	genres := make([]db.TrackGenre, len(found))
+	for i, g := range found {
+		genres[i] = db.TrackGenre{Genre: g.Name, Votes: g.Count}
+	}
+	if err := m.genres.ReplaceTrackGenres(ctx, trackID, genres); err != nil {
+		log.Printf("Warning: failed to save genres for track %d: %v", trackID, err)
+	}
+}
@@ -2,6 +2,7 @@ package matcher
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -13,6 +14,7 @@ import (
 // Handler handles HTTP requests for auto-matching
 type Handler struct {
 	matcher   *Matcher
+	albums    *AlbumMatcher
 	trackRepo *db.TrackRepository
 }
 
@@ -20,6 +22,7 @@ type Handler struct {
 func NewHandler(matcher *Matcher, trackRepo *db.TrackRepository) *Handler {
 	return &Handler{
 		matcher:   matcher,
+		albums:    matcher.AlbumMatcher(),
 		trackRepo: trackRepo,
 	}
 }
@@ -136,7 +139,7 @@ func (h *Handler) HandleMatchTrack(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run matching
-	output, err := h.matcher.Match(r.Context(), metadata)
+	output, err := h.matcher.MatchTrack(r.Context(), trackID, metadata)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Matching failed: "+err.Error())
 		return
@@ -149,6 +152,7 @@ func (h *Handler) HandleMatchTrack(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, "Failed to update track")
 			return
 		}
+		h.matcher.SyncGenres(r.Context(), trackID, update)
 	}
 
 	resp := MatchResponse{
@@ -162,6 +166,42 @@ func (h *Handler) HandleMatchTrack(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// HandleMatchExplanation handles GET /api/v1/tracks/{id}/match-explanation -
+// explains the track's most recent match attempt
+func (h *Handler) HandleMatchExplanation(w http.ResponseWriter, r *http.Request) {
+	if !h.matcher.ExplanationsEnabled() {
+		writeError(w, http.StatusServiceUnavailable, "Match explanations are not available")
+		return
+	}
+
+	trackID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid track ID")
+		return
+	}
+
+	if _, err := h.trackRepo.GetByID(r.Context(), trackID); err != nil {
+		if err == db.ErrTrackNotFound {
+			writeError(w, http.StatusNotFound, "Track not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get track")
+		return
+	}
+
+	explanation, err := h.matcher.Explanation(r.Context(), trackID)
+	if err != nil {
+		if errors.Is(err, db.ErrMatchExplanationNotFound) {
+			writeError(w, http.StatusNotFound, "No match attempt recorded for this track")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get match explanation")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, explanation)
+}
+
 func matchTrackMBUpdate(output *MatchOutput) *db.MBMatchUpdate {
 	update := &db.MBMatchUpdate{RespectUserEdits: true}
 	if output == nil || output.BestMatch == nil {
@@ -273,6 +313,7 @@ func (h *Handler) HandleConfirmMatch(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "Failed to update track")
 		return
 	}
+	h.matcher.SyncGenres(r.Context(), trackID, update)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success":  true,
@@ -390,6 +431,7 @@ func (h *Handler) HandleLinkMB(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "Failed to update track")
 		return
 	}
+	h.matcher.SyncGenres(r.Context(), trackID, update)
 
 	// Optionally update metadata from MusicBrainz
 	metadataUpdated := false
@@ -3,6 +3,7 @@ package matcher
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/openmusicplayer/backend/internal/musicbrainz"
 )
@@ -78,6 +79,17 @@ type Matcher struct {
 	mbClient      *musicbrainz.Client
 	weights       ScoreWeights
 	disambiguator Disambiguator
+	observer      Observer
+	explanations  ExplanationStore
+	genres        GenreStore
+}
+
+// maxSuggestions bounds the suggestions kept for an uncertain match.
+const maxSuggestions = 3
+
+// Observer receives aggregate matcher outcomes for operational metrics.
+type Observer interface {
+	ObserveMatcherRun(outcome, artistSource string, suggestions int, confidence float64, hasConfidence bool)
 }
 
 // NewMatcher creates a new Matcher instance
@@ -106,6 +118,11 @@ func NewMatcherWithDisambiguator(mbClient *musicbrainz.Client, disambiguator Dis
 	}
 }
 
+// SetObserver reports every Match run to observer.
+func (m *Matcher) SetObserver(observer Observer) {
+	m.observer = observer
+}
+
 // MBClient returns the MusicBrainz client for direct access
 func (m *Matcher) MBClient() *musicbrainz.Client {
 	return m.mbClient
@@ -113,44 +130,104 @@ func (m *Matcher) MBClient() *musicbrainz.Client {
 
 // Match attempts to find a MusicBrainz match for the given track metadata
 func (m *Matcher) Match(ctx context.Context, metadata TrackMetadata) (*MatchOutput, error) {
+	output, _, err := m.match(ctx, metadata)
+	return output, err
+}
+
+// match runs one matching attempt and explains how it decided.
+func (m *Matcher) match(ctx context.Context, metadata TrackMetadata) (output *MatchOutput, exp *MatchExplanation, err error) {
+	exp = newExplanation(metadata, m.weights)
+	defer func() {
+		if err != nil {
+			exp.Error = err.Error()
+			exp.step("outcome", "matching failed: %v", err)
+		} else if output != nil && output.BestMatch != nil && output.Verified {
+			exp.SelectedMBID = output.BestMatch.MBID
+		}
+		if m.observer != nil {
+			m.observeRun(exp.Outcome, exp.ArtistSource, output)
+		}
+	}()
+
 	// Parse the title to extract artist and track info
 	parsed := ParseTitle(metadata.Title)
+	exp.ParsedTitle = parsed
 
 	// If no artist was parsed from title, prefer the provider/deterministic artist
 	// and then fall back to the channel/uploader name.
-	if parsed.Artist == "" {
-		if metadata.Artist != "" {
-			parsed.Artist = cleanArtist(metadata.Artist)
-		} else if metadata.Uploader != "" {
-			parsed.Artist = cleanArtist(metadata.Uploader)
-		}
+	if parsed.Artist != "" {
+		exp.ArtistSource = "title"
+	} else if metadata.Artist != "" {
+		parsed.Artist = cleanArtist(metadata.Artist)
+		exp.ArtistSource = "metadata"
+	} else if metadata.Uploader != "" {
+		parsed.Artist = cleanArtist(metadata.Uploader)
+		exp.ArtistSource = "uploader"
+	}
+	exp.step("parse_title", "track %q, artist %q from %s", parsed.Track, parsed.Artist, exp.ArtistSource)
+
+	// An acoustic fingerprint identifies the audio regardless of how the
+	// upload was titled, so a confident one decides before any search.
+	fingerprinted := m.fingerprintCandidates(ctx, metadata, parsed)
+	exp.addCandidates("fingerprint", fingerprinted)
+	if len(metadata.Fingerprints) > 0 {
+		exp.step("fingerprint", "%d of %d fingerprint matches resolved to candidates", len(fingerprinted), len(metadata.Fingerprints))
+	}
+	if len(fingerprinted) > 0 && fingerprinted[0].Score.IsAutoMatchable {
+		best := fingerprinted[0]
+		exp.Outcome = "verified"
+		exp.step("auto_match", "fingerprint candidate %s reached %.2f with duration score %.1f", best.MBID, best.Score.Overall/100, best.Score.DurationScore)
+		return &MatchOutput{Verified: true, BestMatch: &best, ParsedTitle: parsed}, exp, nil
 	}
 
 	// Build the search query
 	query := m.buildSearchQuery(parsed)
-	if query == "" {
+	exp.Query = query
+	if query == "" && len(fingerprinted) == 0 {
+		exp.Outcome = "no_query"
+		exp.step("search", "no track title to search for")
 		return &MatchOutput{
 			Verified:    false,
 			ParsedTitle: parsed,
-		}, nil
+		}, exp, nil
 	}
 
 	// Search MusicBrainz for matches
-	searchResp, err := m.mbClient.SearchTracks(ctx, query, 10, 0, false)
-	if err != nil {
-		return nil, fmt.Errorf("musicbrainz search failed: %w", err)
+	var results []musicbrainz.TrackResult
+	if query != "" {
+		searchResp, err := m.mbClient.SearchTracks(ctx, query, 10, 0, false)
+		if err != nil {
+			if len(fingerprinted) == 0 {
+				return nil, exp, fmt.Errorf("musicbrainz search failed: %w", err)
+			}
+			log.Printf("Warning: musicbrainz search failed, keeping fingerprint suggestions: %v", err)
+			exp.step("search", "search failed, keeping fingerprint candidates: %v", err)
+		} else {
+			results = searchResp.Results
+			exp.step("search", "%d results for %s", len(results), query)
+		}
 	}
 
-	if len(searchResp.Results) == 0 {
+	if len(results) == 0 && len(fingerprinted) == 0 {
+		exp.Outcome = "no_results"
 		return &MatchOutput{
 			Verified:    false,
 			ParsedTitle: parsed,
-		}, nil
+		}, exp, nil
 	}
 
-	// Score each result
-	var scoredResults []MatchResult
-	for _, mbTrack := range searchResp.Results {
+	// Score each result. Recordings the fingerprint already found keep their
+	// fingerprint scoring.
+	scoredResults := fingerprinted
+	seen := make(map[string]bool, len(fingerprinted))
+	for _, result := range fingerprinted {
+		seen[result.MBID] = true
+	}
+	searched := len(scoredResults)
+	for _, mbTrack := range results {
+		if seen[mbTrack.MBID] {
+			continue
+		}
 		score := CalculateScore(
 			parsed,
 			mbTrack.Artist,
@@ -177,11 +254,12 @@ func (m *Matcher) Match(ctx context.Context, metadata TrackMetadata) (*MatchOutp
 			ReleaseDate:  mbTrack.ReleaseDate,
 		})
 	}
+	exp.addCandidates("search", scoredResults[searched:])
 
 	// Sort by overall score (descending)
 	sortByScore(scoredResults)
 
-	output := &MatchOutput{
+	output = &MatchOutput{
 		ParsedTitle: parsed,
 	}
 
@@ -193,25 +271,48 @@ func (m *Matcher) Match(ctx context.Context, metadata TrackMetadata) (*MatchOutp
 			// High confidence match - auto-verify
 			output.Verified = true
 			output.BestMatch = &best
+			exp.step("auto_match", "best candidate %s scored %.1f, at or above %.1f", best.MBID, best.Score.Overall, AutoMatchThreshold)
 		} else {
-			// Uncertain - store top 3 as suggestions
+			// Uncertain - store top suggestions
 			output.Verified = false
 			output.BestMatch = &best
 
-			maxSuggestions := 3
-			if len(scoredResults) < maxSuggestions {
-				maxSuggestions = len(scoredResults)
+			limit := maxSuggestions
+			if len(scoredResults) < limit {
+				limit = len(scoredResults)
 			}
-			output.Suggestions = scoredResults[:maxSuggestions]
+			output.Suggestions = scoredResults[:limit]
+			exp.step("suggest", "best candidate %s scored %.1f, below %.1f; keeping %d suggestions", best.MBID, best.Score.Overall, AutoMatchThreshold, limit)
 		}
 	}
 
 	if m.disambiguator != nil && !output.Verified && len(output.Suggestions) > 0 {
 		input := buildDisambiguationInput(metadata, parsed, output.Suggestions)
-		tryApplyDisambiguation(ctx, m.disambiguator, input, output)
+		if err := tryApplyDisambiguation(ctx, m.disambiguator, input, output); err != nil {
+			exp.step("disambiguation", "ignored: %v", err)
+		} else if d := output.Disambiguation; d != nil && d.Match {
+			exp.step("disambiguation", "selected %s with confidence %.2f (auto-match at %.2f)", d.CandidateID, d.Confidence, DisambiguationAutoConfidence)
+		} else if d != nil {
+			exp.step("disambiguation", "no candidate selected")
+		}
+	}
+
+	exp.Outcome = "suggested"
+	if output.Verified {
+		exp.Outcome = "verified"
 	}
+	return output, exp, nil
+}
 
-	return output, nil
+func (m *Matcher) observeRun(outcome, artistSource string, output *MatchOutput) {
+	suggestions, confidence, hasConfidence := 0, 0.0, false
+	if output != nil {
+		suggestions = len(output.Suggestions)
+		if output.BestMatch != nil {
+			confidence, hasConfidence = output.BestMatch.Confidence, true
+		}
+	}
+	m.observer.ObserveMatcherRun(outcome, artistSource, suggestions, confidence, hasConfidence)
 }
 
 // MatchNonMusic checks if the content appears to be non-music
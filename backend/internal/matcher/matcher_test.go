@@ -0,0 +1,31 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+)
+
+type recordingMatchObserver struct {
+	outcome, artistSource string
+	hasConfidence         bool
+	runs                  int
+}
+
+func (o *recordingMatchObserver) ObserveMatcherRun(outcome, artistSource string, suggestions int, confidence float64, hasConfidence bool) {
+	o.runs++
+	o.outcome, o.artistSource, o.hasConfidence = outcome, artistSource, hasConfidence
+}
+
+func TestMatchReportsRunsWithoutSearchableTitle(t *testing.T) {
+	m := NewMatcher(nil)
+	observer := &recordingMatchObserver{}
+	m.SetObserver(observer)
+
+	output, err := m.Match(context.Background(), TrackMetadata{Uploader: "Some Channel"})
+	if err != nil || output.Verified {
+		t.Fatalf("Match = %+v, %v", output, err)
+	}
+	if observer.runs != 1 || observer.outcome != "no_query" || observer.artistSource != "uploader" || observer.hasConfidence {
+		t.Fatalf("observed %+v, want one no_query run attributed to the uploader", observer)
+	}
+}
@@ -41,6 +41,9 @@ const (
 	// AutoMatchThreshold is the minimum score required for automatic MB linking
 	AutoMatchThreshold = 85.0
 
+	// MediumConfidenceThreshold is the minimum score for "medium" confidence
+	MediumConfidenceThreshold = 70.0
+
 	// DurationTolerance is the maximum difference in seconds for a perfect duration match
 	DurationTolerance = 10
 )
@@ -89,7 +92,7 @@ func CalculateScore(parsed *ParsedTitle, mbArtist, mbTrack string, parsedDuratio
 	case score.Overall >= AutoMatchThreshold:
 		score.Confidence = "high"
 		score.IsAutoMatchable = true
-	case score.Overall >= 70:
+	case score.Overall >= MediumConfidenceThreshold:
 		score.Confidence = "medium"
 		score.IsAutoMatchable = false
 	default:
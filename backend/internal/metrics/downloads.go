@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+func downloadProviderLabel(value string) string {
+	switch value = strings.ToLower(value); value {
+	case "youtube", "soundcloud", "bandcamp", "direct":
+		return value
+	default:
+		return "other"
+	}
+}
+
+func downloadOutcomeLabel(value string) string {
+	switch value {
+	case "success", "age_restricted", "unavailable", "network", "other":
+		return value
+	default:
+		return "unknown"
+	}
+}
+
+// ObserveDownloadOutcome records a download job's terminal outcome for its
+// provider.
+func (m *Metrics) ObserveDownloadOutcome(provider, outcome string) {
+	m.incrementLabeledCounter(m.downloadOutcomes, downloadProviderLabel(provider)+":"+downloadOutcomeLabel(outcome))
+}
+
+// ObserveDownloadDiskSpace records the free space on the download temp volume
+// and whether the disk guard has paused the worker pool.
+func (m *Metrics) ObserveDownloadDiskSpace(freeBytes uint64, paused bool) {
+	atomic.StoreUint64(&m.downloadTempFreeBytes, freeBytes)
+	var value int64
+	if paused {
+		value = 1
+	}
+	atomic.StoreInt64(&m.downloadWorkersPaused, value)
+}
+
+func writeDownloadDiskMetrics(sb *strings.Builder, m *Metrics) {
+	sb.WriteString("# HELP omp_download_temp_free_bytes Free bytes on the download temp volume\n")
+	sb.WriteString("# TYPE omp_download_temp_free_bytes gauge\n")
+	sb.WriteString(fmt.Sprintf("omp_download_temp_free_bytes %d\n\n", atomic.LoadUint64(&m.downloadTempFreeBytes)))
+	sb.WriteString("# HELP omp_download_workers_paused Whether download workers are paused for low disk space\n")
+	sb.WriteString("# TYPE omp_download_workers_paused gauge\n")
+	sb.WriteString(fmt.Sprintf("omp_download_workers_paused %d\n\n", atomic.LoadInt64(&m.downloadWorkersPaused)))
+}
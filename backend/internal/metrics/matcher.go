@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Matcher and MusicBrainz metrics use fixed, allowlisted labels only; titles,
+// queries, and recording IDs never become label values.
+
+func matcherOutcomeLabel(value string) string {
+	switch value {
+	case "verified", "suggested", "no_results", "no_query", "error":
+		return value
+	default:
+		return "unknown"
+	}
+}
+
+func matcherArtistSourceLabel(value string) string {
+	switch value {
+	case "title", "metadata", "uploader", "none":
+		return value
+	default:
+		return "unknown"
+	}
+}
+
+func musicBrainzStatusLabel(value string) string {
+	switch value {
+	case "ok", "not_found", "rate_limited", "server_error", "client_error", "transport_error":
+		return value
+	default:
+		return "unknown"
+	}
+}
+
+func newConfidenceHistogram() *Histogram {
+	buckets := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95, 1}
+	return &Histogram{buckets: buckets, bucketVals: make([]uint64, len(buckets))}
+}
+
+func newSuggestionCountHistogram() *Histogram {
+	buckets := []float64{0, 1, 2, 3, 5, 10}
+	return &Histogram{buckets: buckets, bucketVals: make([]uint64, len(buckets))}
+}
+
+// ObserveMatcherRun records one matcher run. artistSource says where the
+// searched artist came from (title, metadata, uploader, or none); confidence
+// is the best candidate's 0-1 score and is skipped when there was none.
+func (m *Metrics) ObserveMatcherRun(outcome, artistSource string, suggestions int, confidence float64, hasConfidence bool) {
+	outcome = matcherOutcomeLabel(outcome)
+	m.incrementLabeledCounter(m.matcherRuns, outcome+":"+matcherArtistSourceLabel(artistSource))
+	if outcome == "error" {
+		return
+	}
+	if suggestions >= 0 {
+		m.matcherSuggestions.Observe(float64(suggestions))
+	}
+	if hasConfidence && confidence >= 0 {
+		m.matcherConfidence.Observe(confidence)
+	}
+}
+
+// ObserveMusicBrainzRequest records one MusicBrainz HTTP attempt, including
+// attempts that are retried.
+func (m *Metrics) ObserveMusicBrainzRequest(status string, duration time.Duration) {
+	if duration < 0 {
+		return
+	}
+	status = musicBrainzStatusLabel(status)
+	m.incrementLabeledCounter(m.mbRequests, status)
+	m.mu.Lock()
+	if m.mbLatency[status] == nil {
+		m.mbLatency[status] = NewHistogram()
+	}
+	histogram := m.mbLatency[status]
+	m.mu.Unlock()
+	histogram.Observe(duration.Seconds())
+}
+
+// SetUnverifiedTracks sets the number of tracks without a verified
+// MusicBrainz match.
+func (m *Metrics) SetUnverifiedTracks(count int64) {
+	atomic.StoreInt64(&m.unverifiedTracks, count)
+}
+
+func writeMatcherMetrics(sb *strings.Builder, m *Metrics) {
+	sb.WriteString("# HELP omp_tracks_unverified Tracks without a verified MusicBrainz match\n")
+	sb.WriteString("# TYPE omp_tracks_unverified gauge\n")
+	sb.WriteString(fmt.Sprintf("omp_tracks_unverified %d\n\n", atomic.LoadInt64(&m.unverifiedTracks)))
+
+	writeLabeledCounter(sb, "omp_matcher_runs_total", "Matcher runs by outcome and artist source", m.matcherRuns, "outcome", "artist_source")
+	writeLabeledHistogram(sb, "omp_matcher_suggestions", "Suggestions kept per matcher run", map[string]*Histogram{"": m.matcherSuggestions})
+	writeLabeledHistogram(sb, "omp_matcher_best_confidence", "Best candidate confidence per matcher run", map[string]*Histogram{"": m.matcherConfidence})
+	writeLabeledCounter(sb, "omp_musicbrainz_requests_total", "MusicBrainz HTTP attempts by result", m.mbRequests, "status")
+	writeLabeledHistogram(sb, "omp_musicbrainz_request_duration_seconds", "MusicBrainz HTTP attempt latency", m.mbLatency, "status")
+}
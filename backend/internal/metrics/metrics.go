@@ -38,6 +38,21 @@ type Metrics struct {
 	researchToolCalls     *Histogram
 	researchModelAttempts map[string]*Histogram
 
+	// Matcher and MusicBrainz metrics
+	matcherRuns        map[string]*uint64
+	matcherSuggestions *Histogram
+	matcherConfidence  *Histogram
+	mbRequests         map[string]*uint64
+	mbLatency          map[string]*Histogram
+	unverifiedTracks   int64
+
+	// Terminal download outcomes by provider
+	downloadOutcomes map[string]*uint64
+
+	// Download temp volume free space and disk-guard pause state
+	downloadTempFreeBytes uint64
+	downloadWorkersPaused int64
+
 	// Custom gauges and counters
 	gauges   map[string]float64
 	counters map[string]*uint64
@@ -99,6 +114,12 @@ func New() *Metrics {
 		researchTimeToLatest:  make(map[string]*Histogram),
 		researchToolCalls:     NewHistogram(),
 		researchModelAttempts: make(map[string]*Histogram),
+		matcherRuns:           make(map[string]*uint64),
+		matcherSuggestions:    newSuggestionCountHistogram(),
+		matcherConfidence:     newConfidenceHistogram(),
+		mbRequests:            make(map[string]*uint64),
+		mbLatency:             make(map[string]*Histogram),
+		downloadOutcomes:      make(map[string]*uint64),
 		gauges:                make(map[string]float64),
 		counters:              make(map[string]*uint64),
 		startTime:             time.Now(),
@@ -292,7 +313,7 @@ func (m *Metrics) IncCounter(name string) {
 // ObserveResearchCreate records a bounded create outcome and baseline latency.
 func (m *Metrics) ObserveResearchCreate(outcome string, baselineLatency time.Duration) {
 	outcome = researchOutcomeLabel(outcome)
-	m.incrementResearchCounter(m.researchCreates, outcome)
+	m.incrementLabeledCounter(m.researchCreates, outcome)
 	if baselineLatency > 0 {
 		m.observeResearchHistogram(m.researchBaseline, outcome, baselineLatency.Seconds())
 	}
@@ -300,15 +321,15 @@ func (m *Metrics) ObserveResearchCreate(outcome string, baselineLatency time.Dur
 
 // ObserveResearchSnapshot records safe state derived from an immutable snapshot.
 func (m *Metrics) ObserveResearchSnapshot(status, terminalStatus, degradation, revisionStage, revisionKind string, timeToLatest time.Duration, hasTimeToLatest bool) {
-	m.incrementResearchCounter(m.researchStatuses, researchStatusLabel(status))
+	m.incrementLabeledCounter(m.researchStatuses, researchStatusLabel(status))
 	if terminalStatus != "" {
-		m.incrementResearchCounter(m.researchTerminals, researchTerminalLabel(terminalStatus))
+		m.incrementLabeledCounter(m.researchTerminals, researchTerminalLabel(terminalStatus))
 	}
 	if degradation != "" {
-		m.incrementResearchCounter(m.researchDegradations, researchDegradationLabel(degradation))
+		m.incrementLabeledCounter(m.researchDegradations, researchDegradationLabel(degradation))
 	}
 	stage, kind := researchStageLabel(revisionStage), researchRevisionKindLabel(revisionKind)
-	m.incrementResearchCounter(m.researchRevisions, stage+":"+kind)
+	m.incrementLabeledCounter(m.researchRevisions, stage+":"+kind)
 	if hasTimeToLatest && timeToLatest >= 0 {
 		m.observeResearchHistogram(m.researchTimeToLatest, stage+":"+kind, timeToLatest.Seconds())
 	}
@@ -316,12 +337,12 @@ func (m *Metrics) ObserveResearchSnapshot(status, terminalStatus, degradation, r
 
 // ObserveResearchMutation records cancel and retry outcomes with fixed labels.
 func (m *Metrics) ObserveResearchMutation(operation, outcome string) {
-	m.incrementResearchCounter(m.researchMutations, researchMutationLabel(operation)+":"+researchOutcomeLabel(outcome))
+	m.incrementLabeledCounter(m.researchMutations, researchMutationLabel(operation)+":"+researchOutcomeLabel(outcome))
 }
 
 // ObserveResearchReview records review actions and outcomes with fixed labels.
 func (m *Metrics) ObserveResearchReview(action, outcome string) {
-	m.incrementResearchCounter(m.researchReviews, researchReviewActionLabel(action)+":"+researchOutcomeLabel(outcome))
+	m.incrementLabeledCounter(m.researchReviews, researchReviewActionLabel(action)+":"+researchOutcomeLabel(outcome))
 }
 
 // ObserveResearchToolCalls records only the aggregate count supplied by safe terminal telemetry.
@@ -341,7 +362,7 @@ func (m *Metrics) ObserveResearchModelAttempt(stage, status string, repair bool,
 	m.observeResearchHistogram(m.researchModelAttempts, key, duration.Seconds())
 }
 
-func (m *Metrics) incrementResearchCounter(values map[string]*uint64, key string) {
+func (m *Metrics) incrementLabeledCounter(values map[string]*uint64, key string) {
 	m.mu.Lock()
 	if values[key] == nil {
 		var zero uint64
@@ -452,6 +473,9 @@ func (m *Metrics) Handler() http.HandlerFunc {
 		}
 
 		writeResearchMetrics(&sb, m)
+		writeMatcherMetrics(&sb, m)
+		writeLabeledCounter(&sb, "omp_download_outcomes_total", "Terminal download outcomes by provider", m.downloadOutcomes, "provider", "outcome")
+		writeDownloadDiskMetrics(&sb, m)
 
 		// Custom gauges
 		if len(m.gauges) > 0 {
@@ -488,55 +512,65 @@ func (m *Metrics) Handler() http.HandlerFunc {
 	}
 }
 
-func writeResearchMetrics(sb *strings.Builder, m *Metrics) {
-	writeResearchCounter := func(name, help string, values map[string]*uint64, labels ...string) {
-		if len(values) == 0 {
-			return
-		}
-		sb.WriteString("# HELP " + name + " " + help + "\n# TYPE " + name + " counter\n")
-		keys := sortedMetricKeys(values)
-		for _, key := range keys {
-			writeMetricLabels(sb, name, labels, strings.Split(key, ":"))
-			sb.WriteString(fmt.Sprintf(" %d\n", atomic.LoadUint64(values[key])))
-		}
-		sb.WriteString("\n")
+func writeLabeledCounter(sb *strings.Builder, name, help string, values map[string]*uint64, labels ...string) {
+	if len(values) == 0 {
+		return
 	}
-	writeResearchHistogram := func(name, help string, values map[string]*Histogram, labels ...string) {
-		if len(values) == 0 {
-			return
-		}
-		sb.WriteString("# HELP " + name + " " + help + "\n# TYPE " + name + " histogram\n")
-		for _, key := range sortedMetricKeys(values) {
-			histogram := values[key]
-			histogram.mu.Lock()
-			for index, bucket := range histogram.buckets {
-				writeMetricLabels(sb, name+"_bucket", append(labels, "le"), append(strings.Split(key, ":"), strconv.FormatFloat(bucket, 'g', -1, 64)))
-				sb.WriteString(fmt.Sprintf(" %d\n", histogram.bucketVals[index]))
-			}
-			writeMetricLabels(sb, name+"_bucket", append(labels, "le"), append(strings.Split(key, ":"), "+Inf"))
-			sb.WriteString(fmt.Sprintf(" %d\n", histogram.count))
-			writeMetricLabels(sb, name+"_sum", labels, strings.Split(key, ":"))
-			sb.WriteString(fmt.Sprintf(" %f\n", histogram.sum))
-			writeMetricLabels(sb, name+"_count", labels, strings.Split(key, ":"))
-			sb.WriteString(fmt.Sprintf(" %d\n", histogram.count))
-			histogram.mu.Unlock()
+	sb.WriteString("# HELP " + name + " " + help + "\n# TYPE " + name + " counter\n")
+	for _, key := range sortedMetricKeys(values) {
+		writeMetricLabels(sb, name, labels, splitMetricKey(key))
+		sb.WriteString(fmt.Sprintf(" %d\n", atomic.LoadUint64(values[key])))
+	}
+	sb.WriteString("\n")
+}
+
+func writeLabeledHistogram(sb *strings.Builder, name, help string, values map[string]*Histogram, labels ...string) {
+	if len(values) == 0 {
+		return
+	}
+	sb.WriteString("# HELP " + name + " " + help + "\n# TYPE " + name + " histogram\n")
+	for _, key := range sortedMetricKeys(values) {
+		histogram := values[key]
+		labelValues := splitMetricKey(key)
+		histogram.mu.Lock()
+		for index, bucket := range histogram.buckets {
+			writeMetricLabels(sb, name+"_bucket", append(labels, "le"), append(labelValues, strconv.FormatFloat(bucket, 'g', -1, 64)))
+			sb.WriteString(fmt.Sprintf(" %d\n", histogram.bucketVals[index]))
 		}
-		sb.WriteString("\n")
-	}
-
-	writeResearchCounter("omp_research_job_creates_total", "Research job create outcomes", m.researchCreates, "outcome")
-	writeResearchHistogram("omp_research_baseline_duration_seconds", "Research baseline build latency", m.researchBaseline, "outcome")
-	writeResearchCounter("omp_research_job_status_observations_total", "Research job status observations", m.researchStatuses, "status")
-	writeResearchCounter("omp_research_terminal_observations_total", "Research terminal status observations", m.researchTerminals, "status")
-	writeResearchCounter("omp_research_degradations_total", "Research degradation observations", m.researchDegradations, "code")
-	writeResearchCounter("omp_research_mutations_total", "Research cancel and retry outcomes", m.researchMutations, "operation", "outcome")
-	writeResearchCounter("omp_research_reviews_total", "Research review outcomes", m.researchReviews, "action", "outcome")
-	writeResearchCounter("omp_research_latest_revision_observations_total", "Latest validated research revision observations", m.researchRevisions, "stage", "kind")
-	writeResearchHistogram("omp_research_time_to_latest_revision_seconds", "Time from job creation to latest validated revision", m.researchTimeToLatest, "stage", "kind")
+		writeMetricLabels(sb, name+"_bucket", append(labels, "le"), append(labelValues, "+Inf"))
+		sb.WriteString(fmt.Sprintf(" %d\n", histogram.count))
+		writeMetricLabels(sb, name+"_sum", labels, labelValues)
+		sb.WriteString(fmt.Sprintf(" %f\n", histogram.sum))
+		writeMetricLabels(sb, name+"_count", labels, labelValues)
+		sb.WriteString(fmt.Sprintf(" %d\n", histogram.count))
+		histogram.mu.Unlock()
+	}
+	sb.WriteString("\n")
+}
+
+// splitMetricKey splits a colon-joined label key; the empty key of an
+// unlabeled series has no label values.
+func splitMetricKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ":")
+}
+
+func writeResearchMetrics(sb *strings.Builder, m *Metrics) {
+	writeLabeledCounter(sb, "omp_research_job_creates_total", "Research job create outcomes", m.researchCreates, "outcome")
+	writeLabeledHistogram(sb, "omp_research_baseline_duration_seconds", "Research baseline build latency", m.researchBaseline, "outcome")
+	writeLabeledCounter(sb, "omp_research_job_status_observations_total", "Research job status observations", m.researchStatuses, "status")
+	writeLabeledCounter(sb, "omp_research_terminal_observations_total", "Research terminal status observations", m.researchTerminals, "status")
+	writeLabeledCounter(sb, "omp_research_degradations_total", "Research degradation observations", m.researchDegradations, "code")
+	writeLabeledCounter(sb, "omp_research_mutations_total", "Research cancel and retry outcomes", m.researchMutations, "operation", "outcome")
+	writeLabeledCounter(sb, "omp_research_reviews_total", "Research review outcomes", m.researchReviews, "action", "outcome")
+	writeLabeledCounter(sb, "omp_research_latest_revision_observations_total", "Latest validated research revision observations", m.researchRevisions, "stage", "kind")
+	writeLabeledHistogram(sb, "omp_research_time_to_latest_revision_seconds", "Time from job creation to latest validated revision", m.researchTimeToLatest, "stage", "kind")
 	if m.researchToolCalls != nil {
-		writeResearchHistogram("omp_research_terminal_tool_calls", "Tool calls reported by safe terminal telemetry", map[string]*Histogram{"": m.researchToolCalls})
+		writeLabeledHistogram(sb, "omp_research_terminal_tool_calls", "Tool calls reported by safe terminal telemetry", map[string]*Histogram{"": m.researchToolCalls})
 	}
-	writeResearchHistogram("omp_research_terminal_model_attempt_duration_seconds", "Model attempt duration reported by safe terminal telemetry", m.researchModelAttempts, "stage", "status", "repair")
+	writeLabeledHistogram(sb, "omp_research_terminal_model_attempt_duration_seconds", "Model attempt duration reported by safe terminal telemetry", m.researchModelAttempts, "stage", "status", "repair")
 }
 
 func sortedMetricKeys[V any](values map[string]V) []string {
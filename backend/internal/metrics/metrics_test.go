@@ -217,3 +217,69 @@ func TestMetrics_CustomGauge(t *testing.T) {
 		t.Errorf("expected active_downloads gauge, got:\n%s", body)
 	}
 }
+
+func TestMetrics_MatcherAndMusicBrainz(t *testing.T) {
+	m := New()
+	m.ObserveMatcherRun("verified", "title", 0, 0.93, true)
+	m.ObserveMatcherRun("suggested", "uploader", 3, 0.62, true)
+	m.ObserveMatcherRun("error", "attacker-controlled", 0, 0, false)
+	m.ObserveMusicBrainzRequest("ok", 120*time.Millisecond)
+	m.ObserveMusicBrainzRequest("rate_limited", 10*time.Millisecond)
+	m.SetUnverifiedTracks(17)
+
+	w := httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`omp_matcher_runs_total{outcome="verified",artist_source="title"} 1`,
+		`omp_matcher_runs_total{outcome="error",artist_source="unknown"} 1`,
+		`omp_matcher_suggestions_bucket{le="3"} 2`,
+		`omp_matcher_best_confidence_count 2`,
+		`omp_musicbrainz_requests_total{status="rate_limited"} 1`,
+		`omp_musicbrainz_request_duration_seconds_count{status="ok"} 1`,
+		`omp_tracks_unverified 17`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_DownloadOutcomesUseFixedLabels(t *testing.T) {
+	m := New()
+	m.ObserveDownloadOutcome("SoundCloud", "network")
+	m.ObserveDownloadOutcome("soundcloud", "network")
+	m.ObserveDownloadOutcome("https://evil.example", "exploded")
+
+	w := httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`omp_download_outcomes_total{provider="soundcloud",outcome="network"} 2`,
+		`omp_download_outcomes_total{provider="other",outcome="unknown"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_DownloadDiskSpaceGauges(t *testing.T) {
+	m := New()
+	m.ObserveDownloadDiskSpace(512<<20, true)
+
+	w := httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		"omp_download_temp_free_bytes 536870912",
+		"omp_download_workers_paused 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+}
@@ -54,9 +54,26 @@ func ETag(next http.Handler) http.Handler {
 		// Handle the request
 		next.ServeHTTP(wrapped, r)
 
-		// Calculate ETag from response body
-		hash := md5.Sum(buf.Bytes())
-		etag := `"` + hex.EncodeToString(hash[:]) + `"`
+		// Only successful responses are validated; errors and redirects
+		// pass through as written.
+		if wrapped.statusCode != http.StatusOK {
+			w.WriteHeader(wrapped.statusCode)
+			w.Write(buf.Bytes())
+			return
+		}
+
+		// Calculate ETag from response body unless the handler set its own
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			hash := md5.Sum(buf.Bytes())
+			etag = `"` + hex.EncodeToString(hash[:]) + `"`
+			w.Header().Set("ETag", etag)
+		}
+		// Responses are per user by default; handlers serving shared content
+		// such as release covers set their own policy.
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "private, max-age=0, must-revalidate")
+		}
 
 		// Check If-None-Match header
 		ifNoneMatch := r.Header.Get("If-None-Match")
@@ -65,9 +82,6 @@ func ETag(next http.Handler) http.Handler {
 			return
 		}
 
-		// Write ETag header and response
-		w.Header().Set("ETag", etag)
-		w.Header().Set("Cache-Control", "private, max-age=0, must-revalidate")
 		w.WriteHeader(wrapped.statusCode)
 		w.Write(buf.Bytes())
 	})
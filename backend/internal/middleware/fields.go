@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldsParam is the query parameter clients use to request a partial
+// response, e.g. ?fields=title,artist.
+const FieldsParam = "fields"
+
+// FieldSelector tracks which fields to include in the response
+type FieldSelector struct {
+	fields map[string]bool
+	all    bool
+}
+
+// NewFieldSelector creates a selector from a comma-separated list of fields
+// If fields is empty, all fields are included
+func NewFieldSelector(fieldsParam string) *FieldSelector {
+	if strings.TrimSpace(fieldsParam) == "" {
+		return &FieldSelector{all: true}
+	}
+	fields := make(map[string]bool)
+	for _, f := range strings.Split(fieldsParam, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return &FieldSelector{fields: fields}
+}
+
+func (s *FieldSelector) Include(field string) bool {
+	if s.all {
+		return true
+	}
+	return s.fields[field]
+}
+
+// All reports whether no selection was requested.
+func (s *FieldSelector) All() bool {
+	return s.all
+}
+
+// FieldSet is the registry entry for one endpoint's selectable fields.
+type FieldSet struct {
+	// Items names the top-level keys holding lists of items; selection
+	// applies to each item. When empty, selection applies to the top-level
+	// object itself.
+	Items []string
+	// Fields are the item fields a client may select.
+	Fields []string
+	// Always are returned whether or not they were selected, so clients can
+	// still key the items.
+	Always []string
+}
+
+// Parse builds a selector for fieldsParam, rejecting fields the set does not
+// declare so a typo is an error rather than a silently empty payload.
+func (fs FieldSet) Parse(fieldsParam string) (*FieldSelector, error) {
+	selector := NewFieldSelector(fieldsParam)
+	if selector.all {
+		return selector, nil
+	}
+	known := make(map[string]bool, len(fs.Fields)+len(fs.Always))
+	for _, f := range fs.Fields {
+		known[f] = true
+	}
+	for _, f := range fs.Always {
+		known[f] = true
+	}
+	var unknown []string
+	for f := range selector.fields {
+		if !known[f] {
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown fields: %s; available fields: %s", strings.Join(unknown, ", "), strings.Join(fs.Fields, ", "))
+	}
+	for _, f := range fs.Always {
+		selector.fields[f] = true
+	}
+	return selector, nil
+}
+
+// fieldsResponseWriter buffers the response so it can be trimmed before it is
+// sent.
+type fieldsResponseWriter struct {
+	http.ResponseWriter
+	buf        bytes.Buffer
+	statusCode int
+}
+
+func (w *fieldsResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *fieldsResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+}
+
+// Fields returns a middleware that applies ?fields= to successful JSON GET
+// responses, dropping every field of the selected items that the client did
+// not ask for. Handlers stay unaware of the selection; requests without the
+// parameter pass straight through.
+func Fields(set FieldSet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			param := r.URL.Query().Get(FieldsParam)
+			if r.Method != http.MethodGet || strings.TrimSpace(param) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			selector, err := set.Parse(param)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"code":    "INVALID_FIELDS",
+					"message": err.Error(),
+				})
+				return
+			}
+
+			wrapped := &fieldsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			body := wrapped.buf.Bytes()
+			if wrapped.statusCode >= 200 && wrapped.statusCode < 300 &&
+				strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				if trimmed, ok := selectFields(body, set, selector); ok {
+					body = trimmed
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				}
+			}
+			w.WriteHeader(wrapped.statusCode)
+			w.Write(body)
+		})
+	}
+}
+
+// selectFields trims body according to set. It reports false, leaving the
+// body untouched, when the body is not a JSON object.
+func selectFields(body []byte, set FieldSet, selector *FieldSelector) ([]byte, bool) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, false
+	}
+
+	if len(set.Items) == 0 {
+		trimObject(doc, selector)
+	} else {
+		for _, key := range set.Items {
+			raw, ok := doc[key]
+			if !ok {
+				continue
+			}
+			var items []map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				continue
+			}
+			for _, item := range items {
+				trimObject(item, selector)
+			}
+			if encoded, err := json.Marshal(items); err == nil {
+				doc[key] = encoded
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return append(encoded, '\n'), true
+}
+
+func trimObject(obj map[string]json.RawMessage, selector *FieldSelector) {
+	for key := range obj {
+		if !selector.Include(key) {
+			delete(obj, key)
+		}
+	}
+}
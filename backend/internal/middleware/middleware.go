@@ -142,8 +142,8 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, X-Request-ID, X-Trace-ID")
-				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, X-Request-ID, X-Trace-ID, X-Queue-Version, X-Device-ID")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-ReplayGain-Track-Gain, X-ReplayGain-Track-Peak, X-ReplayGain-Album-Gain, X-ReplayGain-Album-Peak")
 			}
 
 			if r.Method == http.MethodOptions {
@@ -0,0 +1,131 @@
+package musicbrainz
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxBatchLookup is the most entities one LookupBatch call resolves.
+	MaxBatchLookup = 50
+	// batchLookupWorkers bounds concurrent MusicBrainz requests for cache
+	// misses; the rate limit makes more pointless.
+	batchLookupWorkers = 4
+)
+
+// Entity types accepted by LookupBatch.
+const (
+	EntityArtist    = "artist"
+	EntityRelease   = "release"
+	EntityRecording = "recording"
+)
+
+// Lookup result statuses.
+const (
+	LookupOK       = "ok"
+	LookupNotFound = "not_found"
+	LookupInvalid  = "invalid"
+	LookupFailed   = "error"
+)
+
+// LookupItem names one entity to look up by MBID.
+type LookupItem struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// LookupResult is the outcome for one LookupItem. Exactly one of Artist,
+// Release, and Recording is set when Status is LookupOK. Summaries leave out
+// an artist's discography and a release's track listing.
+type LookupResult struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Artist    *Artist  `json:"artist,omitempty"`
+	Release   *Release `json:"release,omitempty"`
+	Recording *Track   `json:"recording,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// LookupBatch resolves items from the cache or MusicBrainz, returning one
+// result per item in order. A failure only affects its own item; repeated
+// items are looked up once.
+func (c *Client) LookupBatch(ctx context.Context, items []LookupItem) []LookupResult {
+	results := make([]LookupResult, len(items))
+	pending := make(map[LookupItem][]int)
+	for i, item := range items {
+		results[i] = LookupResult{Type: item.Type, ID: item.ID}
+		key := LookupItem{Type: strings.ToLower(strings.TrimSpace(item.Type)), ID: strings.ToLower(strings.TrimSpace(item.ID))}
+		switch key.Type {
+		case EntityArtist, EntityRelease, EntityRecording:
+		default:
+			results[i].Status = LookupInvalid
+			results[i].Error = "type must be artist, release, or recording"
+			continue
+		}
+		if _, err := uuid.Parse(key.ID); err != nil {
+			results[i].Status = LookupInvalid
+			results[i].Error = "id must be a MusicBrainz ID"
+			continue
+		}
+		pending[key] = append(pending[key], i)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchLookupWorkers)
+	for key, indexes := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key LookupItem, indexes []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resolved := c.lookupOne(ctx, key)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, i := range indexes {
+				resolved.Type, resolved.ID = results[i].Type, results[i].ID
+				results[i] = resolved
+			}
+		}(key, indexes)
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Client) lookupOne(ctx context.Context, item LookupItem) LookupResult {
+	result := LookupResult{Status: LookupOK}
+	var err error
+	switch item.Type {
+	case EntityArtist:
+		var artist *Artist
+		if artist, err = c.GetArtist(ctx, item.ID); err == nil {
+			summary := *artist
+			summary.Releases = nil
+			result.Artist = &summary
+		}
+	case EntityRelease:
+		var release *Release
+		if release, err = c.GetRelease(ctx, item.ID); err == nil {
+			summary := *release
+			summary.Tracks = nil
+			result.Release = &summary
+		}
+	case EntityRecording:
+		result.Recording, err = c.GetRecording(ctx, item.ID)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNotFound):
+		result.Status = LookupNotFound
+		result.Error = "not found in MusicBrainz"
+	default:
+		result.Status = LookupFailed
+		result.Error = "MusicBrainz lookup failed"
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package musicbrainz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// calendarArtistsPerQuery keeps the search query well under URL limits.
+	calendarArtistsPerQuery = 20
+	calendarPageSize        = 100
+	calendarMaxPages        = 3
+)
+
+// ReleaseGroupsByArtists returns the release groups credited to any of
+// artistIDs whose first release date falls between from and to (YYYY-MM-DD,
+// inclusive). MusicBrainz is asked through release-group search, a page of
+// results per group of artists, so the usual search caching applies.
+func (c *Client) ReleaseGroupsByArtists(ctx context.Context, artistIDs []string, from, to string) ([]AlbumResult, error) {
+	seen := make(map[string]bool)
+	var out []AlbumResult
+	for start := 0; start < len(artistIDs); start += calendarArtistsPerQuery {
+		end := min(start+calendarArtistsPerQuery, len(artistIDs))
+		terms := make([]string, 0, end-start)
+		for _, id := range artistIDs[start:end] {
+			terms = append(terms, "arid:"+id)
+		}
+		query := fmt.Sprintf("(%s) AND firstreleasedate:[%s TO %s]", strings.Join(terms, " OR "), from, to)
+
+		for page := 0; page < calendarMaxPages; page++ {
+			resp, err := c.SearchAlbums(ctx, query, calendarPageSize, page*calendarPageSize, false)
+			if err != nil {
+				return nil, err
+			}
+			for _, album := range resp.Results {
+				if !seen[album.MBID] {
+					seen[album.MBID] = true
+					out = append(out, album)
+				}
+			}
+			if len(resp.Results) < calendarPageSize || (page+1)*calendarPageSize >= resp.Total {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// GetReleaseGroupCoverArtURL returns the Cover Art Archive URL for a release
+// group, which redirects to the cover of its chosen release.
+func (c *Client) GetReleaseGroupCoverArtURL(releaseGroupID string) string {
+	return fmt.Sprintf("%s/release-group/%s/front-250", coverArtURL, releaseGroupID)
+}
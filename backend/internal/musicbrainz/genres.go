@@ -0,0 +1,73 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// Genre is a MusicBrainz genre with how many editors voted for it.
+type Genre struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type mbGenresResponse struct {
+	Genres []Genre `json:"genres"`
+}
+
+// GetRecordingGenres returns the genres MusicBrainz editors gave a
+// recording, most voted first. Recordings are rarely tagged, so when a
+// recording has none the genres of artistID are returned instead; pass ""
+// to skip that fallback.
+func (c *Client) GetRecordingGenres(ctx context.Context, recordingID, artistID string) ([]Genre, error) {
+	genres, err := c.entityGenres(ctx, "recording", recordingID)
+	if err != nil || len(genres) > 0 || artistID == "" {
+		return genres, err
+	}
+	return c.entityGenres(ctx, "artist", artistID)
+}
+
+func (c *Client) entityGenres(ctx context.Context, entity, mbID string) ([]Genre, error) {
+	cacheKey := fmt.Sprintf("mb:%s-genres:%s", entity, mbID)
+
+	if cached, ok := c.cacheGet(ctx, cacheKey); ok {
+		var genres []Genre
+		if err := json.Unmarshal([]byte(cached), &genres); err == nil {
+			return genres, nil
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/%s?fmt=json&inc=genres", baseURL, entity, url.PathEscape(mbID))
+
+	body, err := c.doRequest(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var mbResp mbGenresResponse
+	if err := json.Unmarshal(body, &mbResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	genres := make([]Genre, 0, len(mbResp.Genres))
+	for _, g := range mbResp.Genres {
+		if g.Name != "" && g.Count > 0 {
+			genres = append(genres, g)
+		}
+	}
+	sort.SliceStable(genres, func(i, j int) bool {
+		if genres[i].Count != genres[j].Count {
+			return genres[i].Count > genres[j].Count
+		}
+		return genres[i].Name < genres[j].Name
+	})
+
+	if genresJSON, err := json.Marshal(genres); err == nil {
+		c.cacheSet(ctx, cacheKey, string(genresJSON), entityLookupTTL)
+	}
+
+	return genres, nil
+}
@@ -71,6 +71,43 @@ func (h *Handlers) SearchAlbums(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, results)
 }
 
+// BatchLookupRequest lists up to MaxBatchLookup entities of any type.
+type BatchLookupRequest struct {
+	Items []LookupItem `json:"items"`
+}
+
+type BatchLookupResponse struct {
+	Results []LookupResult `json:"results"`
+}
+
+// LookupBatch handles POST /api/v1/musicbrainz/lookup:batch. Items that
+// cannot be resolved carry their own status; the request as a whole only
+// fails when it is malformed.
+func (h *Handlers) LookupBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchLookupRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if len(req.Items) == 0 {
+		writeError(w, http.StatusBadRequest, "MISSING_ITEMS", "items must contain at least one entity")
+		return
+	}
+	if len(req.Items) > MaxBatchLookup {
+		writeError(w, http.StatusBadRequest, "TOO_MANY_ITEMS", "items may contain at most 50 entities")
+		return
+	}
+
+	results := h.client.LookupBatch(r.Context(), req.Items)
+	if r.Context().Err() != nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, BatchLookupResponse{Results: results})
+}
+
 func parsePagination(r *http.Request) (limit, offset int) {
 	limit = 20
 	offset = 0
@@ -32,6 +32,15 @@ var ErrNotFound = fmt.Errorf("not found")
 type Client struct {
 	httpClient *http.Client
 	cache      *cache.Cache
+	observer   RequestObserver
+	limiter    *tokenBucket
+	inflight   coalescer
+}
+
+// RequestObserver receives the result and latency of every MusicBrainz HTTP
+// attempt, including retried ones.
+type RequestObserver interface {
+	ObserveMusicBrainzRequest(status string, duration time.Duration)
 }
 
 func NewClient(cache *cache.Cache) *Client {
@@ -39,7 +48,29 @@ func NewClient(cache *cache.Cache) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		cache: cache,
+		cache:   cache,
+		limiter: newTokenBucket(DefaultRequestsPerSecond, defaultRequestBurst),
+	}
+}
+
+// SetRateLimit changes how many requests per second the client sends and
+// how many may go out at once after idle time. Raise it only for a private
+// MusicBrainz mirror; musicbrainz.org blocks clients that exceed 1/s.
+func (c *Client) SetRateLimit(requestsPerSecond float64, burst int) {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = DefaultRequestsPerSecond
+	}
+	c.limiter = newTokenBucket(requestsPerSecond, burst)
+}
+
+// SetObserver reports MusicBrainz request outcomes to observer.
+func (c *Client) SetObserver(observer RequestObserver) {
+	c.observer = observer
+}
+
+func (c *Client) observeRequest(status string, started time.Time) {
+	if c.observer != nil {
+		c.observer.ObserveMusicBrainzRequest(status, time.Since(started))
 	}
 }
 
@@ -96,6 +127,19 @@ type AlbumResult struct {
 	Score          int      `json:"score"`
 }
 
+// ReleaseResult is a concrete release (not a release group) from search, used
+// when a match needs the release's exact track listing.
+type ReleaseResult struct {
+	MBID       string `json:"mbid"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist,omitempty"`
+	ArtistMBID string `json:"artistMbid,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Country    string `json:"country,omitempty"`
+	TrackCount int    `json:"trackCount,omitempty"`
+	Score      int    `json:"score"`
+}
+
 type SearchResponse[T any] struct {
 	Results []T `json:"results"`
 	Total   int `json:"total"`
@@ -215,6 +259,25 @@ type mbReleaseGroupResponse struct {
 	} `json:"release-groups"`
 }
 
+type mbReleaseSearchResponse struct {
+	Count    int `json:"count"`
+	Offset   int `json:"offset"`
+	Releases []struct {
+		ID           string `json:"id"`
+		Score        int    `json:"score"`
+		Title        string `json:"title"`
+		Date         string `json:"date"`
+		Country      string `json:"country"`
+		TrackCount   int    `json:"track-count"`
+		ArtistCredit []struct {
+			Artist struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"artist"`
+		} `json:"artist-credit"`
+	} `json:"releases"`
+}
+
 // mbArtistLookupResponse is for single artist lookup with release-groups
 type mbArtistLookupResponse struct {
 	ID             string `json:"id"`
@@ -466,6 +529,65 @@ func (c *Client) SearchAlbums(ctx context.Context, query string, limit, offset i
 	return resp, nil
 }
 
+// SearchReleases searches concrete releases; unlike SearchAlbums the results
+// can be passed straight to GetRelease.
+func (c *Client) SearchReleases(ctx context.Context, query string, limit, offset int, skipCache bool) (*SearchResponse[ReleaseResult], error) {
+	limit = normalizeLimit(limit)
+	cacheKey := c.buildCacheKey("release", query, limit, offset)
+
+	if !skipCache {
+		if cached, ok := c.cacheGet(ctx, cacheKey); ok {
+			var resp SearchResponse[ReleaseResult]
+			if err := json.Unmarshal([]byte(cached), &resp); err == nil {
+				return &resp, nil
+			}
+		}
+	}
+
+	reqURL := fmt.Sprintf("%s/release?query=%s&limit=%d&offset=%d&fmt=json",
+		baseURL, url.QueryEscape(query), limit, offset)
+
+	body, err := c.doRequest(ctx, reqURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var mbResp mbReleaseSearchResponse
+	if err := json.Unmarshal(body, &mbResp); err != nil {
+		return nil, fmt.Errorf("failed to parse MusicBrainz response: %w", err)
+	}
+
+	results := make([]ReleaseResult, 0, len(mbResp.Releases))
+	for _, rel := range mbResp.Releases {
+		release := ReleaseResult{
+			MBID:       rel.ID,
+			Title:      rel.Title,
+			Date:       rel.Date,
+			Country:    rel.Country,
+			TrackCount: rel.TrackCount,
+			Score:      rel.Score,
+		}
+		if len(rel.ArtistCredit) > 0 {
+			release.Artist = rel.ArtistCredit[0].Artist.Name
+			release.ArtistMBID = rel.ArtistCredit[0].Artist.ID
+		}
+		results = append(results, release)
+	}
+
+	resp := &SearchResponse[ReleaseResult]{
+		Results: results,
+		Total:   mbResp.Count,
+		Limit:   limit,
+		Offset:  mbResp.Offset,
+	}
+
+	if respJSON, err := json.Marshal(resp); err == nil {
+		c.cacheSet(ctx, cacheKey, string(respJSON), searchTTL)
+	}
+
+	return resp, nil
+}
+
 // Browse/lookup methods
 
 // GetArtist fetches artist details with discography from MusicBrainz
@@ -520,6 +642,49 @@ func (c *Client) GetArtist(ctx context.Context, mbID string) (*Artist, error) {
 	return artist, nil
 }
 
+// mbArtistURLRelsResponse is the artist lookup with url-rels included.
+type mbArtistURLRelsResponse struct {
+	Relations []struct {
+		Type string `json:"type"`
+		URL  struct {
+			Resource string `json:"resource"`
+		} `json:"url"`
+	} `json:"relations"`
+}
+
+// GetArtistHomepage returns the artist's "official homepage" URL relation, or
+// an empty string when MusicBrainz lists none.
+func (c *Client) GetArtistHomepage(ctx context.Context, mbID string) (string, error) {
+	cacheKey := fmt.Sprintf("mb:artist-homepage:%s", mbID)
+
+	if cached, ok := c.cacheGet(ctx, cacheKey); ok {
+		return cached, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/artist/%s?fmt=json&inc=url-rels", baseURL, url.PathEscape(mbID))
+
+	body, err := c.doRequest(ctx, endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	var mbResp mbArtistURLRelsResponse
+	if err := json.Unmarshal(body, &mbResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	homepage := ""
+	for _, rel := range mbResp.Relations {
+		if rel.Type == "official homepage" && rel.URL.Resource != "" {
+			homepage = rel.URL.Resource
+			break
+		}
+	}
+
+	c.cacheSet(ctx, cacheKey, homepage, entityLookupTTL)
+	return homepage, nil
+}
+
 // GetRelease fetches release/album details with track listing from MusicBrainz
 func (c *Client) GetRelease(ctx context.Context, mbID string) (*Release, error) {
 	cacheKey := fmt.Sprintf("mb:release:%s", mbID)
@@ -635,12 +800,24 @@ func (c *Client) GetCoverArtURL(releaseID string) string {
 
 // HTTP client helpers
 
+// doRequest fetches reqURL, sharing the response with identical requests
+// already in flight. Every HTTP attempt, retries included, waits its turn
+// under the client's rate limit.
 func (c *Client) doRequest(ctx context.Context, reqURL string) ([]byte, error) {
+	return c.inflight.Do(ctx, reqURL, func(ctx context.Context) ([]byte, error) {
+		return c.fetch(ctx, reqURL)
+	})
+}
+
+func (c *Client) fetch(ctx context.Context, reqURL string) ([]byte, error) {
 	log := logger.Default().WithComponent("musicbrainz")
 	cfg := apperrors.MusicBrainzRetryConfig()
 
 	var result []byte
 	err := apperrors.Retry(ctx, cfg, func(ctx context.Context) error {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
@@ -649,8 +826,10 @@ func (c *Client) doRequest(ctx context.Context, reqURL string) ([]byte, error) {
 		req.Header.Set("User-Agent", userAgent)
 		req.Header.Set("Accept", "application/json")
 
+		started := time.Now()
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
+			c.observeRequest("transport_error", started)
 			log.Warn(ctx, "MusicBrainz request failed, may retry", map[string]interface{}{
 				"url":   reqURL,
 				"error": err.Error(),
@@ -660,11 +839,13 @@ func (c *Client) doRequest(ctx context.Context, reqURL string) ([]byte, error) {
 		defer resp.Body.Close()
 
 		if resp.StatusCode == http.StatusNotFound {
+			c.observeRequest("not_found", started)
 			return ErrNotFound
 		}
 
 		// Check for rate limiting (429)
 		if resp.StatusCode == http.StatusTooManyRequests {
+			c.observeRequest("rate_limited", started)
 			log.Warn(ctx, "MusicBrainz rate limited, will retry", map[string]interface{}{
 				"url": reqURL,
 			})
@@ -673,6 +854,7 @@ func (c *Client) doRequest(ctx context.Context, reqURL string) ([]byte, error) {
 
 		// Check for retryable server errors
 		if apperrors.HTTPRetryableStatus(resp.StatusCode) {
+			c.observeRequest("server_error", started)
 			log.Warn(ctx, "MusicBrainz server error, will retry", map[string]interface{}{
 				"url":    reqURL,
 				"status": resp.StatusCode,
@@ -681,13 +863,16 @@ func (c *Client) doRequest(ctx context.Context, reqURL string) ([]byte, error) {
 		}
 
 		if resp.StatusCode != http.StatusOK {
+			c.observeRequest("client_error", started)
 			return fmt.Errorf("MusicBrainz API returned status %d", resp.StatusCode)
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
+			c.observeRequest("transport_error", started)
 			return fmt.Errorf("failed to read response body: %w", err)
 		}
+		c.observeRequest("ok", started)
 
 		result = body
 		return nil
@@ -1,6 +1,15 @@
 package musicbrainz
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestGetCoverArtURLUsesReleaseID(t *testing.T) {
 	client := NewClient(nil)
@@ -10,3 +19,149 @@ func TestGetCoverArtURLUsesReleaseID(t *testing.T) {
 		t.Fatalf("GetCoverArtURL = %q, want %q", got, want)
 	}
 }
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+type recordingRequestObserver struct {
+	statuses []string
+}
+
+func (o *recordingRequestObserver) ObserveMusicBrainzRequest(status string, duration time.Duration) {
+	o.statuses = append(o.statuses, status)
+}
+
+func TestDoRequestReportsOutcomeToObserver(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, "ok"},
+		{http.StatusNotFound, "not_found"},
+		{http.StatusBadRequest, "client_error"},
+	} {
+		client := NewClient(nil)
+		client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: tc.status, Body: io.NopCloser(strings.NewReader(`{}`)), Header: http.Header{}}, nil
+		})
+		observer := &recordingRequestObserver{}
+		client.SetObserver(observer)
+
+		_, err := client.doRequest(context.Background(), baseURL+"/recording/x?fmt=json")
+		if tc.status == http.StatusNotFound && !errors.Is(err, ErrNotFound) {
+			t.Fatalf("404 err = %v, want ErrNotFound", err)
+		}
+		if len(observer.statuses) != 1 || observer.statuses[0] != tc.want {
+			t.Fatalf("HTTP %d observed %v, want [%s]", tc.status, observer.statuses, tc.want)
+		}
+	}
+}
+
+func TestLookupBatchIsolatesItemFailures(t *testing.T) {
+	const (
+		artistID    = "a74b1b7f-71a5-4011-9441-d0b5e4122711"
+		releaseID   = "b84ee12a-09ef-421b-82de-0441a926375b"
+		recordingID = "c0b8b1f4-7d3b-4a3b-9e6e-2b1f0a9d5e11"
+	)
+	client := NewClient(nil)
+	client.SetRateLimit(1000, 10)
+	var mu sync.Mutex
+	requests := map[string]int{}
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		mu.Unlock()
+		status, body := http.StatusOK, `{}`
+		switch req.URL.Path {
+		case "/ws/2/artist/" + artistID:
+			body = `{"id":"` + artistID + `","name":"Radiohead","release-groups":[{"id":"rg","title":"OK Computer"}]}`
+		case "/ws/2/recording/" + recordingID:
+			body = `{"id":"` + recordingID + `","title":"Airbag","length":284000}`
+		default:
+			status = http.StatusNotFound
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+
+	results := client.LookupBatch(context.Background(), []LookupItem{
+		{Type: "artist", ID: artistID},
+		{Type: "release", ID: releaseID},
+		{Type: "recording", ID: recordingID},
+		{Type: "Recording", ID: strings.ToUpper(recordingID)},
+		{Type: "label", ID: artistID},
+		{Type: "artist", ID: "not-an-mbid"},
+	})
+
+	wantStatuses := []string{LookupOK, LookupNotFound, LookupOK, LookupOK, LookupInvalid, LookupInvalid}
+	for i, want := range wantStatuses {
+		if results[i].Status != want {
+			t.Fatalf("result %d status = %q, want %q (%+v)", i, results[i].Status, want, results[i])
+		}
+	}
+	if results[0].Artist == nil || results[0].Artist.Name != "Radiohead" || results[0].Artist.Releases != nil {
+		t.Fatalf("artist summary = %+v, want name without discography", results[0].Artist)
+	}
+	if results[3].Recording == nil || results[3].Recording.Title != "Airbag" || results[3].ID != strings.ToUpper(recordingID) {
+		t.Fatalf("duplicate recording result = %+v", results[3])
+	}
+	if n := requests["/ws/2/recording/"+recordingID]; n != 1 {
+		t.Fatalf("recording fetched %d times, want once", n)
+	}
+}
+
+func TestGetRecordingGenresFallsBackToArtist(t *testing.T) {
+	const (
+		artistID    = "a74b1b7f-71a5-4011-9441-d0b5e4122711"
+		recordingID = "c0b8b1f4-7d3b-4a3b-9e6e-2b1f0a9d5e11"
+	)
+	client := NewClient(nil)
+	client.SetRateLimit(1000, 10)
+	var paths []string
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		body := `{"genres":[]}`
+		if req.URL.Path == "/ws/2/artist/"+artistID {
+			body = `{"genres":[{"name":"art rock","count":3},{"name":"alternative rock","count":7},{"name":"unvoted","count":0}]}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+
+	genres, err := client.GetRecordingGenres(context.Background(), recordingID, artistID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(genres) != 2 || genres[0].Name != "alternative rock" || genres[1].Name != "art rock" {
+		t.Fatalf("genres = %+v, want artist genres by votes without unvoted ones", genres)
+	}
+	if len(paths) != 2 || paths[0] != "/ws/2/recording/"+recordingID {
+		t.Fatalf("requested %v, want the recording then the artist", paths)
+	}
+}
+
+func TestGetRelatedArtistsKeepsArtistRelationsOnce(t *testing.T) {
+	const artistID = "a74b1b7f-71a5-4011-9441-d0b5e4122711"
+	client := NewClient(nil)
+	client.SetRateLimit(1000, 10)
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("inc") != "artist-rels" {
+			t.Errorf("inc = %q, want artist-rels", req.URL.Query().Get("inc"))
+		}
+		body := `{"relations":[
+			{"type":"member of band","target-type":"artist","artist":{"id":"m1","name":"Thom Yorke"}},
+			{"type":"official homepage","target-type":"url","url":{"resource":"https://example.com"}},
+			{"type":"collaboration","target-type":"artist","artist":{"id":"m1","name":"Thom Yorke"}},
+			{"type":"collaboration","target-type":"artist","artist":{"id":"` + artistID + `","name":"Self"}},
+			{"type":"supporting musician","target-type":"artist","artist":{"id":"m2","name":"Nigel Godrich"}}
+		]}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+
+	related, err := client.GetRelatedArtists(context.Background(), artistID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related) != 2 || related[0].ID != "m1" || related[0].Type != "member of band" || related[1].Name != "Nigel Godrich" {
+		t.Fatalf("related = %+v, want the two distinct related artists", related)
+	}
+}
@@ -0,0 +1,150 @@
+package musicbrainz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketPacesRequestsAfterBurst(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	bucket := newTokenBucket(2, 2)
+	bucket.now = func() time.Time { return now }
+
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond, time.Second} {
+		if got := bucket.reserve(); got != want {
+			t.Fatalf("reservation %d waits %v, want %v", i, got, want)
+		}
+	}
+	now = now.Add(10 * time.Second)
+	if got := bucket.reserve(); got != 0 {
+		t.Fatalf("after idle time waits %v, want 0", got)
+	}
+}
+
+func TestTokenBucketReturnsTokenWhenWaiterCancels(t *testing.T) {
+	bucket := newTokenBucket(1, 1)
+	bucket.reserve()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want context.Canceled", err)
+	}
+	bucket.mu.Lock()
+	tokens := bucket.tokens
+	bucket.mu.Unlock()
+	if tokens < -0.01 {
+		t.Fatalf("canceled waiter kept its token: balance %v", tokens)
+	}
+}
+
+func TestDoRequestCoalescesIdenticalInFlightLookups(t *testing.T) {
+	client := NewClient(nil)
+	client.SetRateLimit(1000, 10)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		fetches.Add(1)
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"x"}`)), Header: http.Header{}}, nil
+	})
+
+	const callers = 5
+	var wg sync.WaitGroup
+	bodies := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := client.doRequest(context.Background(), baseURL+"/recording/x?fmt=json")
+			if err != nil {
+				t.Errorf("caller %d: %v", i, err)
+			}
+			bodies[i] = string(body)
+		}()
+	}
+	// Let every caller join before the shared fetch answers.
+	for client.waiters(baseURL+"/recording/x?fmt=json") < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("%d HTTP requests for identical lookups, want 1", n)
+	}
+	for i, body := range bodies {
+		if body != `{"id":"x"}` {
+			t.Fatalf("caller %d got %q", i, body)
+		}
+	}
+}
+
+func TestCoalescerCancelsFetchOnlyAfterLastWaiterLeaves(t *testing.T) {
+	var c coalescer
+	started := make(chan struct{})
+	fetchErr := make(chan error, 1)
+	fetch := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		fetchErr <- ctx.Err()
+		return nil, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := c.Do(first, "k", fetch)
+		firstDone <- err
+	}()
+	<-started
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := c.Do(second, "k", func(context.Context) ([]byte, error) {
+			t.Error("second caller started its own fetch")
+			return nil, nil
+		})
+		secondDone <- err
+	}()
+	for c.waiters("k") < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelFirst()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller = %v, want context.Canceled", err)
+	}
+	select {
+	case err := <-fetchErr:
+		t.Fatalf("fetch canceled (%v) while a caller still waits", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	<-secondDone
+	select {
+	case <-fetchErr:
+	case <-time.After(time.Second):
+		t.Fatal("fetch kept running after every caller left")
+	}
+}
+
+func (c *Client) waiters(key string) int {
+	return c.inflight.waiters(key)
+}
+
+func (c *coalescer) waiters(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if call, ok := c.calls[key]; ok {
+		return call.waiters
+	}
+	return 0
+}
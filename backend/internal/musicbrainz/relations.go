@@ -0,0 +1,69 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// RelatedArtist is an artist MusicBrainz links to another, such as a band
+// member, a side project, or a frequent collaborator. Type is the
+// MusicBrainz relationship type, e.g. "member of band" or "collaboration".
+type RelatedArtist struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type mbArtistRelsResponse struct {
+	Relations []struct {
+		Type       string `json:"type"`
+		TargetType string `json:"target-type"`
+		Artist     struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"artist"`
+	} `json:"relations"`
+}
+
+// GetRelatedArtists returns the artists MusicBrainz relates to the artist,
+// each once, in the order MusicBrainz lists them.
+func (c *Client) GetRelatedArtists(ctx context.Context, mbID string) ([]RelatedArtist, error) {
+	cacheKey := fmt.Sprintf("mb:artist-related:%s", mbID)
+
+	if cached, ok := c.cacheGet(ctx, cacheKey); ok {
+		var related []RelatedArtist
+		if err := json.Unmarshal([]byte(cached), &related); err == nil {
+			return related, nil
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/artist/%s?fmt=json&inc=artist-rels", baseURL, url.PathEscape(mbID))
+
+	body, err := c.doRequest(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var mbResp mbArtistRelsResponse
+	if err := json.Unmarshal(body, &mbResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	related := make([]RelatedArtist, 0, len(mbResp.Relations))
+	seen := map[string]bool{mbID: true}
+	for _, rel := range mbResp.Relations {
+		if rel.TargetType != "artist" || rel.Artist.ID == "" || seen[rel.Artist.ID] {
+			continue
+		}
+		seen[rel.Artist.ID] = true
+		related = append(related, RelatedArtist{ID: rel.Artist.ID, Name: rel.Artist.Name, Type: rel.Type})
+	}
+
+	if relatedJSON, err := json.Marshal(related); err == nil {
+		c.cacheSet(ctx, cacheKey, string(relatedJSON), entityLookupTTL)
+	}
+
+	return related, nil
+}
@@ -0,0 +1,102 @@
+// Package pagination is the paginated envelope every /api/v2 list endpoint
+// returns. List handlers build it directly; v1 routes keep their own
+// per-endpoint envelopes.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Items    []T  `json:"items"`
+	PageInfo Info `json:"pageInfo"`
+}
+
+// Info describes where a page sits in the full result. Total is omitted when
+// the endpoint cannot count without extra work; HasMore is then a best guess
+// from whether the page came back full. NextCursor, when set, is passed back
+// as ?cursor= to fetch the following page.
+type Info struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      *int   `json:"total,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ErrInvalidCursor is returned for a ?cursor= this server did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPrefix versions the opaque cursor so its encoding can change without
+// breaking clients holding old cursors mid-scroll.
+const cursorPrefix = "o1:"
+
+// EncodeCursor returns the cursor for the page starting at offset.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset a cursor points at.
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return offset, nil
+}
+
+// Offset returns where the requested page starts: the ?cursor= from a
+// previous page when there is one, otherwise offset as the handler parsed it
+// from ?offset=.
+func Offset(r *http.Request, offset int) (int, error) {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return offset, nil
+	}
+	return DecodeCursor(cursor)
+}
+
+// NewInfo fills in HasMore and NextCursor for a page of count items.
+func NewInfo(limit, offset, count int, total *int) Info {
+	info := Info{Limit: limit, Offset: offset, Total: total}
+	if total != nil {
+		info.HasMore = offset+count < *total
+	} else {
+		info.HasMore = limit > 0 && count >= limit
+	}
+	if info.HasMore {
+		info.NextCursor = EncodeCursor(offset + count)
+	}
+	return info
+}
+
+// Write writes items as a page.
+func Write[T any](w http.ResponseWriter, items []T, limit, offset int, total *int) {
+	if items == nil {
+		items = []T{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Page[T]{
+		Items:    items,
+		PageInfo: NewInfo(limit, offset, len(items), total),
+	})
+}
+
+// Slice cuts the page at offset from a list an endpoint can only load whole,
+// returning the page and the offset clamped to the list.
+func Slice[T any](items []T, limit, offset int) ([]T, int) {
+	offset = min(offset, len(items))
+	end := min(offset+limit, len(items))
+	return items[offset:end], offset
+}
@@ -0,0 +1,92 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, offset := range []int{0, 1, 250} {
+		got, err := DecodeCursor(EncodeCursor(offset))
+		if err != nil || got != offset {
+			t.Fatalf("DecodeCursor(EncodeCursor(%d)) = %d, %v", offset, got, err)
+		}
+	}
+	for _, bad := range []string{"not-a-cursor", "bzE6LTE"} {
+		if _, err := DecodeCursor(bad); err != ErrInvalidCursor {
+			t.Fatalf("DecodeCursor(%q) err = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestOffsetPrefersCursor(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?offset=3", nil)
+	if got, err := Offset(r, 3); err != nil || got != 3 {
+		t.Fatalf("Offset without cursor = %d, %v; want 3", got, err)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/?offset=3&cursor="+EncodeCursor(8), nil)
+	if got, err := Offset(r, 3); err != nil || got != 8 {
+		t.Fatalf("Offset with cursor = %d, %v; want 8", got, err)
+	}
+}
+
+func TestWriteFollowsCursorsToTheEnd(t *testing.T) {
+	all := []int{1, 2, 3, 4, 5}
+	total := len(all)
+
+	var seen []int
+	offset := 0
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("cursor never ran out")
+		}
+		items, at := Slice(all, 2, offset)
+		rec := httptest.NewRecorder()
+		Write(rec, items, 2, at, &total)
+
+		var page Page[int]
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		seen = append(seen, page.Items...)
+		if page.PageInfo.Total == nil || *page.PageInfo.Total != 5 || page.PageInfo.Limit != 2 {
+			t.Fatalf("pageInfo = %+v, want total 5 and limit 2", page.PageInfo)
+		}
+		if !page.PageInfo.HasMore {
+			if page.PageInfo.NextCursor != "" {
+				t.Fatalf("last page has cursor %q", page.PageInfo.NextCursor)
+			}
+			break
+		}
+		next, err := DecodeCursor(page.PageInfo.NextCursor)
+		if err != nil {
+			t.Fatalf("next cursor: %v", err)
+		}
+		offset = next
+	}
+	if len(seen) != 5 || seen[0] != 1 || seen[4] != 5 {
+		t.Fatalf("seen = %v, want 1 through 5 once each", seen)
+	}
+}
+
+func TestWriteEmptyPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write[string](rec, nil, 20, 0, nil)
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if string(body["items"]) != "[]" {
+		t.Fatalf("items = %s, want []", body["items"])
+	}
+}
+
+func TestSliceClampsOffset(t *testing.T) {
+	items, offset := Slice([]int{1, 2, 3}, 2, 10)
+	if len(items) != 0 || offset != 3 {
+		t.Fatalf("Slice past the end = %v at %d, want empty at 3", items, offset)
+	}
+}
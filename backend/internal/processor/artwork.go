@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+const (
+	embeddedArtworkTimeout = 30 * time.Second
+	// embeddedArtworkPrefix is where extracted covers live in object storage.
+	embeddedArtworkPrefix = "artwork/tracks"
+)
+
+// TrackArtworkStore records which object holds a track's embedded cover.
+// db.TrackRepository satisfies it.
+type TrackArtworkStore interface {
+	GetArtworkKey(ctx context.Context, trackID int64) (string, error)
+	SetArtworkKey(ctx context.Context, trackID int64, key string) error
+}
+
+// EmbeddedArtworkKey returns the storage key of the cover extracted for a
+// track identity.
+func EmbeddedArtworkKey(identityHash string) string {
+	return fmt.Sprintf("%s/%s.jpg", embeddedArtworkPrefix, identityHash)
+}
+
+// extractArtwork pulls the cover embedded in the downloaded file, such as an
+// ID3 APIC frame or the thumbnail yt-dlp embeds, while the file is still on
+// disk. Failures only cost the track its fallback cover.
+func (p *Processor) extractArtwork(ctx context.Context, job *download.DownloadJob, dir, path string, metadata *TrackMetadata) {
+	if p.trackArtwork == nil {
+		return
+	}
+	extractCtx, cancel := context.WithTimeout(ctx, embeddedArtworkTimeout)
+	defer cancel()
+	cover, err := extractEmbeddedArtwork(extractCtx, p.mediaRunner(), dir, path)
+	if err != nil {
+		log.Printf("Warning: embedded artwork extraction failed for job %s: %v", job.ID, err)
+		return
+	}
+	metadata.Artwork = cover
+}
+
+// extractEmbeddedArtwork returns the file's attached picture as a square
+// JPEG, or nil when it has none.
+func extractEmbeddedArtwork(ctx context.Context, runner ffmpeg.Runner, dir, path string) ([]byte, error) {
+	out, err := runner.FFprobe(ctx, []string{
+		"-v", "error",
+		"-select_streams", "v",
+		"-show_entries", "stream=index:stream_disposition=attached_pic",
+		"-of", "json",
+		path,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var probed struct {
+		Streams []struct {
+			Index       int `json:"index"`
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(out.Stdout), &probed); err != nil {
+		return nil, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	index := -1
+	for _, stream := range probed.Streams {
+		if stream.Disposition.AttachedPic == 1 {
+			index = stream.Index
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil
+	}
+
+	target := filepath.Join(dir, "embedded-cover.png")
+	args := ffmpeg.NewCommand().
+		Overwrite().
+		Input(path).
+		Map("0:"+strconv.Itoa(index)).
+		Option("-frames:v", "1").
+		Option("-c:v", "png").
+		Args(target)
+	if _, err := runner.FFmpeg(ctx, args, nil); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return nil, err
+	}
+	img, err := artwork.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return artwork.EncodeJPEG(artwork.Square(img, artwork.CoverSize))
+}
+
+// storeArtwork saves the extracted cover under the track's identity unless
+// the track already has one, so a re-download never replaces a cover.
+func (p *Processor) storeArtwork(ctx context.Context, track *db.Track, metadata *TrackMetadata) {
+	if p.trackArtwork == nil || len(metadata.Artwork) == 0 {
+		return
+	}
+	existing, err := p.trackArtwork.GetArtworkKey(ctx, track.ID)
+	if err != nil {
+		log.Printf("Warning: failed to load track %d artwork: %v", track.ID, err)
+		return
+	}
+	if existing != "" {
+		return
+	}
+	key := EmbeddedArtworkKey(track.IdentityHash)
+	if err := p.storage.PutObject(ctx, key, bytes.NewReader(metadata.Artwork), int64(len(metadata.Artwork)), "image/jpeg"); err != nil {
+		log.Printf("Warning: failed to store track %d artwork: %v", track.ID, err)
+		return
+	}
+	if err := p.trackArtwork.SetArtworkKey(ctx, track.ID, key); err != nil {
+		log.Printf("Warning: failed to record track %d artwork: %v", track.ID, err)
+	}
+}
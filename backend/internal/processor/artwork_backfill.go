@@ -0,0 +1,316 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	artworkBackfillPageSize     = 200
+	artworkBackfillTrackTimeout = time.Minute
+	// artworkBackfillCoverSize is the release cover size warmed for each
+	// release; fetching one stores every artwork.ReleaseCoverSizes size.
+	artworkBackfillCoverSize = 500
+)
+
+// Artwork backfill run states.
+const (
+	ArtworkBackfillRunning   = "running"
+	ArtworkBackfillCompleted = "completed"
+	ArtworkBackfillCanceled  = "canceled"
+	ArtworkBackfillFailed    = "failed"
+)
+
+// ErrArtworkBackfillRunning is returned when a run is started while another
+// is still going.
+var ErrArtworkBackfillRunning = errors.New("an artwork backfill is already running")
+
+// ArtworkBackfillTracks lists tracks without a stored cover and records the
+// covers found for them; db.TrackRepository satisfies it.
+type ArtworkBackfillTracks interface {
+	ListArtworkBackfillCandidates(ctx context.Context, afterID int64, limit int) ([]db.ArtworkBackfillCandidate, error)
+	SetArtworkKey(ctx context.Context, trackID int64, key string) error
+}
+
+// ReleaseCoverCache fetches and caches a release's Cover Art Archive cover;
+// *artwork.ReleaseCovers satisfies it.
+type ReleaseCoverCache interface {
+	Cover(ctx context.Context, releaseID uuid.UUID, size int) ([]byte, error)
+}
+
+// ArtworkObjectStorage stores backfilled covers.
+type ArtworkObjectStorage interface {
+	PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+}
+
+// ArtworkBackfillStatus is a run's progress. ReleaseCovers counts tracks
+// whose release cover is now cached, Thumbnails tracks given a cover from
+// their matched cover URL or source thumbnail, and Missing tracks none of
+// those had art for.
+type ArtworkBackfillStatus struct {
+	ID             string     `json:"id"`
+	State          string     `json:"state"`
+	Total          int        `json:"total"`
+	Processed      int        `json:"processed"`
+	ReleaseCovers  int        `json:"releaseCovers"`
+	Thumbnails     int        `json:"thumbnails"`
+	Missing        int        `json:"missing"`
+	Failed         int        `json:"failed"`
+	CurrentTrackID int64      `json:"currentTrackId,omitempty"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// ArtworkBackfill gives tracks stored before artwork was cached a cover. For
+// each track without one it warms the release cover from Cover Art Archive,
+// and when the track has no release or the release has no cover, stores the
+// track's matched cover URL or source thumbnail as its cover. Only one run
+// exists at a time; its progress goes to the user who started it through
+// the report callback.
+type ArtworkBackfill struct {
+	tracks  ArtworkBackfillTracks
+	covers  ReleaseCoverCache
+	fetcher artwork.ImageFetcher
+	storage ArtworkObjectStorage
+	report  func(userID uuid.UUID, status ArtworkBackfillStatus)
+
+	mu      sync.Mutex
+	status  *ArtworkBackfillStatus
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewArtworkBackfill creates a backfill job. A nil fetcher downloads from
+// Cover Art Archive and the source thumbnail hosts.
+func NewArtworkBackfill(tracks ArtworkBackfillTracks, covers ReleaseCoverCache, fetcher artwork.ImageFetcher, storage ArtworkObjectStorage) *ArtworkBackfill {
+	if fetcher == nil {
+		hosts := append(append([]string{}, artwork.DefaultAllowedHosts...), artwork.SourceThumbnailHosts...)
+		fetcher = artwork.NewFetcher(hosts)
+	}
+	return &ArtworkBackfill{tracks: tracks, covers: covers, fetcher: fetcher, storage: storage}
+}
+
+// SetReporter receives the run's status after every track and when it ends.
+func (b *ArtworkBackfill) SetReporter(report func(userID uuid.UUID, status ArtworkBackfillStatus)) {
+	b.report = report
+}
+
+// Start begins a run over at most limit tracks without a cover, or all of
+// them when limit is zero.
+func (b *ArtworkBackfill) Start(userID uuid.UUID, limit int) (ArtworkBackfillStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running {
+		return *b.status, ErrArtworkBackfillRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.status = &ArtworkBackfillStatus{ID: uuid.NewString(), State: ArtworkBackfillRunning, StartedAt: time.Now()}
+	b.running = true
+	b.cancel = cancel
+	b.wg.Add(1)
+	go b.run(ctx, userID, limit)
+	return *b.status, nil
+}
+
+// Status returns the current or most recent run.
+func (b *ArtworkBackfill) Status() (ArtworkBackfillStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status == nil {
+		return ArtworkBackfillStatus{}, false
+	}
+	return *b.status, true
+}
+
+// Cancel stops the running run after its current track and reports whether
+// one was running.
+func (b *ArtworkBackfill) Cancel() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.running {
+		return false
+	}
+	b.cancel()
+	return true
+}
+
+// Stop cancels any run and waits for it to return.
+func (b *ArtworkBackfill) Stop(ctx context.Context) error {
+	b.Cancel()
+	done := make(chan struct{})
+	go func() { b.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *ArtworkBackfill) run(ctx context.Context, userID uuid.UUID, limit int) {
+	defer b.wg.Done()
+	candidates, err := b.collect(ctx, limit)
+	if err != nil {
+		b.finish(userID, err)
+		return
+	}
+	b.update(userID, func(s *ArtworkBackfillStatus) { s.Total = len(candidates) })
+
+	// releases remembers each release's outcome so tracks from one album
+	// cost one Cover Art Archive lookup.
+	releases := make(map[uuid.UUID]string)
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		b.update(userID, func(s *ArtworkBackfillStatus) { s.CurrentTrackID = candidate.TrackID })
+		trackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), artworkBackfillTrackTimeout)
+		outcome := b.backfillOne(trackCtx, candidate, releases)
+		cancel()
+		b.update(userID, func(s *ArtworkBackfillStatus) {
+			s.Processed++
+			switch outcome {
+			case "release_cover":
+				s.ReleaseCovers++
+			case "thumbnail":
+				s.Thumbnails++
+			case "missing":
+				s.Missing++
+			default:
+				s.Failed++
+			}
+		})
+	}
+	b.finish(userID, ctx.Err())
+}
+
+// collect snapshots the tracks to backfill before any are handled, so the
+// run's total is known up front.
+func (b *ArtworkBackfill) collect(ctx context.Context, limit int) ([]db.ArtworkBackfillCandidate, error) {
+	var candidates []db.ArtworkBackfillCandidate
+	var afterID int64
+	for {
+		page, err := b.tracks.ListArtworkBackfillCandidates(ctx, afterID, artworkBackfillPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range page {
+			candidates = append(candidates, candidate)
+			if limit > 0 && len(candidates) == limit {
+				return candidates, nil
+			}
+		}
+		if len(page) < artworkBackfillPageSize {
+			return candidates, nil
+		}
+		afterID = page[len(page)-1].TrackID
+	}
+}
+
+// backfillOne resolves one track's cover and returns "release_cover",
+// "thumbnail", "missing", or "failed".
+func (b *ArtworkBackfill) backfillOne(ctx context.Context, candidate db.ArtworkBackfillCandidate, releases map[uuid.UUID]string) string {
+	if candidate.MBReleaseID != nil {
+		releaseID := *candidate.MBReleaseID
+		outcome, ok := releases[releaseID]
+		if !ok {
+			_, err := b.covers.Cover(ctx, releaseID, artworkBackfillCoverSize)
+			switch {
+			case err == nil:
+				outcome = "release_cover"
+			case errors.Is(err, artwork.ErrCoverNotFound):
+				outcome = "missing"
+			default:
+				log.Printf("Warning: artwork backfill failed to fetch release %s cover: %v", releaseID, err)
+				outcome = "failed"
+			}
+			releases[releaseID] = outcome
+		}
+		// Only a release known to have no cover falls back to thumbnails;
+		// a failed fetch is retried by the next run.
+		if outcome != "missing" {
+			return outcome
+		}
+	}
+
+	outcome := "missing"
+	for _, source := range []string{candidate.CoverArtURL, candidate.ThumbnailURL} {
+		if source == "" {
+			continue
+		}
+		err := b.storeThumbnail(ctx, candidate, source)
+		if err == nil {
+			return "thumbnail"
+		}
+		if !errors.Is(err, artwork.ErrImageNotFound) && !errors.Is(err, artwork.ErrHostNotAllowed) {
+			log.Printf("Warning: artwork backfill failed for track %d: %v", candidate.TrackID, err)
+			outcome = "failed"
+		}
+	}
+	return outcome
+}
+
+// storeThumbnail stores the image at source as the track's cover, where
+// extracted embedded art would go.
+func (b *ArtworkBackfill) storeThumbnail(ctx context.Context, candidate db.ArtworkBackfillCandidate, source string) error {
+	img, err := b.fetcher.Fetch(ctx, source)
+	if err != nil {
+		return err
+	}
+	data, err := artwork.EncodeJPEG(artwork.Square(img, artwork.CoverSize))
+	if err != nil {
+		return err
+	}
+	key := EmbeddedArtworkKey(candidate.IdentityHash)
+	if err := b.storage.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		return err
+	}
+	return b.tracks.SetArtworkKey(ctx, candidate.TrackID, key)
+}
+
+func (b *ArtworkBackfill) update(userID uuid.UUID, change func(*ArtworkBackfillStatus)) {
+	b.mu.Lock()
+	change(b.status)
+	status := *b.status
+	b.mu.Unlock()
+	if b.report != nil {
+		b.report(userID, status)
+	}
+}
+
+func (b *ArtworkBackfill) finish(userID uuid.UUID, err error) {
+	b.mu.Lock()
+	b.running = false
+	b.cancel()
+	now := time.Now()
+	b.status.FinishedAt = &now
+	b.status.CurrentTrackID = 0
+	switch {
+	case errors.Is(err, context.Canceled):
+		b.status.State = ArtworkBackfillCanceled
+	case err != nil:
+		b.status.State = ArtworkBackfillFailed
+		b.status.Error = err.Error()
+	default:
+		b.status.State = ArtworkBackfillCompleted
+	}
+	status := *b.status
+	b.mu.Unlock()
+	if b.report != nil {
+		b.report(userID, status)
+	}
+	log.Printf("Artwork backfill %s %s: %d of %d tracks processed (%d release covers, %d thumbnails, %d missing, %d failed)",
+		status.ID, status.State, status.Processed, status.Total, status.ReleaseCovers, status.Thumbnails, status.Missing, status.Failed)
+}
@@ -0,0 +1,169 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeBackfillTracks struct {
+	mu         sync.Mutex
+	candidates []db.ArtworkBackfillCandidate
+	keys       map[int64]string
+}
+
+func (f *fakeBackfillTracks) ListArtworkBackfillCandidates(_ context.Context, afterID int64, limit int) ([]db.ArtworkBackfillCandidate, error) {
+	var page []db.ArtworkBackfillCandidate
+	for _, c := range f.candidates {
+		if c.TrackID > afterID && len(page) < limit {
+			page = append(page, c)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeBackfillTracks) SetArtworkKey(_ context.Context, trackID int64, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[trackID] = key
+	return nil
+}
+
+// fakeBackfillCovers has covers for releases in found and counts lookups.
+type fakeBackfillCovers struct {
+	found   map[uuid.UUID]bool
+	lookups map[uuid.UUID]int
+}
+
+func (f *fakeBackfillCovers) Cover(_ context.Context, releaseID uuid.UUID, _ int) ([]byte, error) {
+	f.lookups[releaseID]++
+	if !f.found[releaseID] {
+		return nil, artwork.ErrCoverNotFound
+	}
+	return []byte("jpeg"), nil
+}
+
+type fakeBackfillFetcher map[string]error
+
+func (f fakeBackfillFetcher) Fetch(_ context.Context, rawURL string) (image.Image, error) {
+	if err, ok := f[rawURL]; ok {
+		return nil, err
+	}
+	return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+}
+
+type fakeBackfillStorage struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *fakeBackfillStorage) PutObject(_ context.Context, key string, _ io.Reader, _ int64, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+func waitForArtworkBackfill(t *testing.T, b *ArtworkBackfill) ArtworkBackfillStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := b.Status(); ok && status.State != ArtworkBackfillRunning {
+			return status
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("artwork backfill did not finish")
+	return ArtworkBackfillStatus{}
+}
+
+func TestArtworkBackfillResolvesReleaseCoversThenThumbnails(t *testing.T) {
+	withCover, withoutCover := uuid.New(), uuid.New()
+	tracks := &fakeBackfillTracks{keys: map[int64]string{}}
+	for id := int64(1); id <= 250; id++ {
+		// The first 250 tracks share one release that has a cover.
+		tracks.candidates = append(tracks.candidates, db.ArtworkBackfillCandidate{TrackID: id, IdentityHash: fmt.Sprint("hash", id), MBReleaseID: &withCover})
+	}
+	tracks.candidates = append(tracks.candidates,
+		db.ArtworkBackfillCandidate{TrackID: 251, IdentityHash: "a", MBReleaseID: &withoutCover, ThumbnailURL: "https://i.ytimg.com/vi/a/hq.jpg"},
+		db.ArtworkBackfillCandidate{TrackID: 252, IdentityHash: "b", CoverArtURL: "https://coverartarchive.org/gone", ThumbnailURL: "https://i1.sndcdn.com/b.jpg"},
+		db.ArtworkBackfillCandidate{TrackID: 253, IdentityHash: "c", ThumbnailURL: "https://evil.test/c.jpg"},
+		db.ArtworkBackfillCandidate{TrackID: 254, IdentityHash: "d", ThumbnailURL: "https://i.ytimg.com/vi/d/hq.jpg"},
+		db.ArtworkBackfillCandidate{TrackID: 255, IdentityHash: "e"},
+	)
+	covers := &fakeBackfillCovers{found: map[uuid.UUID]bool{withCover: true}, lookups: map[uuid.UUID]int{}}
+	fetcher := fakeBackfillFetcher{
+		"https://coverartarchive.org/gone": artwork.ErrImageNotFound,
+		"https://evil.test/c.jpg":          artwork.ErrHostNotAllowed,
+		"https://i.ytimg.com/vi/d/hq.jpg":  errors.New("connection reset"),
+	}
+	storage := &fakeBackfillStorage{}
+	b := NewArtworkBackfill(tracks, covers, fetcher, storage)
+
+	if _, err := b.Start(uuid.New(), 0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := waitForArtworkBackfill(t, b)
+	if status.State != ArtworkBackfillCompleted || status.Total != 255 || status.Processed != 255 {
+		t.Fatalf("status = %+v, want all 255 tracks processed", status)
+	}
+	if status.ReleaseCovers != 250 || status.Thumbnails != 2 || status.Missing != 2 || status.Failed != 1 {
+		t.Fatalf("outcomes = %+v", status)
+	}
+	if covers.lookups[withCover] != 1 {
+		t.Fatalf("release looked up %d times, want once per run", covers.lookups[withCover])
+	}
+	if tracks.keys[251] != EmbeddedArtworkKey("a") || tracks.keys[252] != EmbeddedArtworkKey("b") || len(tracks.keys) != 2 || len(storage.keys) != 2 {
+		t.Fatalf("stored keys = %v objects = %v", tracks.keys, storage.keys)
+	}
+}
+
+// blockingFetcher holds each fetch until released.
+type blockingFetcher struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (f blockingFetcher) Fetch(context.Context, string) (image.Image, error) {
+	f.entered <- struct{}{}
+	<-f.release
+	return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+}
+
+func TestArtworkBackfillRejectsConcurrentRunsAndCancels(t *testing.T) {
+	tracks := &fakeBackfillTracks{keys: map[int64]string{}}
+	for id := int64(1); id <= 5; id++ {
+		tracks.candidates = append(tracks.candidates, db.ArtworkBackfillCandidate{TrackID: id, ThumbnailURL: "https://i.ytimg.com/x.jpg"})
+	}
+	fetcher := blockingFetcher{entered: make(chan struct{}, 5), release: make(chan struct{})}
+	b := NewArtworkBackfill(tracks, &fakeBackfillCovers{}, fetcher, &fakeBackfillStorage{})
+
+	if _, err := b.Start(uuid.New(), 3); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := b.Start(uuid.New(), 0); !errors.Is(err, ErrArtworkBackfillRunning) {
+		t.Fatalf("second Start err = %v, want ErrArtworkBackfillRunning", err)
+	}
+	<-fetcher.entered
+	if !b.Cancel() {
+		t.Fatal("Cancel reported no running backfill")
+	}
+	close(fetcher.release)
+	status := waitForArtworkBackfill(t, b)
+	if status.State != ArtworkBackfillCanceled || status.Total != 3 || status.Processed != 1 || status.Thumbnails != 1 {
+		t.Fatalf("status = %+v, want a canceled run that finished its current track", status)
+	}
+	if err := b.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
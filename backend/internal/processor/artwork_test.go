@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/artwork"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+type fakeTrackArtwork struct {
+	keys map[int64]string
+}
+
+func (f *fakeTrackArtwork) GetArtworkKey(_ context.Context, trackID int64) (string, error) {
+	return f.keys[trackID], nil
+}
+
+func (f *fakeTrackArtwork) SetArtworkKey(_ context.Context, trackID int64, key string) error {
+	if f.keys == nil {
+		f.keys = make(map[int64]string)
+	}
+	f.keys[trackID] = key
+	return nil
+}
+
+// coverRunner reports an attached picture at stream 1 and writes a wide PNG
+// wherever ffmpeg is asked to extract it.
+func coverRunner(t *testing.T, probe string) *ffmpeg.Fake {
+	fake := ffmpeg.NewFake()
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		if call.Binary == "ffprobe" {
+			return ffmpeg.Output{Stdout: probe}, nil
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 320, 180))); err != nil {
+			t.Fatal(err)
+		}
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], buf.Bytes(), 0o600)
+	}
+	return fake
+}
+
+func TestExtractEmbeddedArtworkReturnsSquareCover(t *testing.T) {
+	fake := coverRunner(t, `{"streams":[{"index":1,"disposition":{"attached_pic":1}}]}`)
+
+	cover, err := extractEmbeddedArtwork(context.Background(), fake, t.TempDir(), "song.mp3")
+	if err != nil {
+		t.Fatalf("extractEmbeddedArtwork: %v", err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(cover))
+	if err != nil || format != "jpeg" || cfg.Width != artwork.CoverSize || cfg.Height != artwork.CoverSize {
+		t.Fatalf("cover = %+v %s, %v; want a %d square JPEG", cfg, format, err, artwork.CoverSize)
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 || !strings.Contains(strings.Join(calls[1].Args, " "), "-i song.mp3 -map 0:1 -frames:v 1") {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
+
+func TestExtractEmbeddedArtworkSkipsFilesWithoutAttachedPicture(t *testing.T) {
+	// A video stream that is not an attached picture, as in a music video.
+	fake := coverRunner(t, `{"streams":[{"index":0,"disposition":{"attached_pic":0}}]}`)
+
+	cover, err := extractEmbeddedArtwork(context.Background(), fake, t.TempDir(), "clip.mp4")
+	if err != nil || cover != nil {
+		t.Fatalf("cover = %d bytes, %v; want none", len(cover), err)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Fatalf("ran ffmpeg without a picture to extract: %+v", calls)
+	}
+}
+
+func TestStoreArtworkKeepsExistingCover(t *testing.T) {
+	store := &fakeTrackArtwork{keys: map[int64]string{2: "artwork/tracks/old.jpg"}}
+	objects := &fakeObjectStorage{}
+	p := New(&ProcessorConfig{Storage: objects, TrackArtwork: store})
+	metadata := &TrackMetadata{Artwork: []byte("jpeg")}
+
+	p.storeArtwork(context.Background(), &db.Track{ID: 1, IdentityHash: "abc"}, metadata)
+	if store.keys[1] != EmbeddedArtworkKey("abc") || objects.key != "artwork/tracks/abc.jpg" || objects.contentType != "image/jpeg" {
+		t.Fatalf("keys = %v, stored %q as %q", store.keys, objects.key, objects.contentType)
+	}
+
+	objects.key = ""
+	p.storeArtwork(context.Background(), &db.Track{ID: 2, IdentityHash: "def"}, metadata)
+	if store.keys[2] != "artwork/tracks/old.jpg" || objects.key != "" {
+		t.Fatalf("existing cover replaced: keys = %v, stored %q", store.keys, objects.key)
+	}
+}
+
+func TestCollectYTDLPOutputIgnoresLeftoverThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"audio.jpg": "thumb", "audio.mp3": "ID3", "audio.info.json": "{}"} {
+		if err := os.WriteFile(dir+"/"+name, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, _, err := collectYTDLPOutput(dir, t.TempDir(), &TrackMetadata{}, 1<<20)
+	if err != nil {
+		t.Fatalf("collectYTDLPOutput: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "ID3" {
+		t.Fatalf("collected %q, want the audio file", data)
+	}
+}
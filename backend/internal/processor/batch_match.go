@@ -0,0 +1,277 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+const (
+	// DefaultBatchMatchInterval spaces track matches so a run stays within
+	// MusicBrainz's one-request-per-second policy on average.
+	DefaultBatchMatchInterval = 1100 * time.Millisecond
+	batchMatchPageSize        = 100
+	batchMatchTrackTimeout    = 2 * time.Minute
+)
+
+// Batch match run states.
+const (
+	BatchMatchRunning   = "running"
+	BatchMatchCompleted = "completed"
+	BatchMatchCanceled  = "canceled"
+	BatchMatchFailed    = "failed"
+)
+
+// ErrBatchMatchRunning is returned when a run is started while another is
+// still going.
+var ErrBatchMatchRunning = errors.New("a batch match is already running")
+
+// BatchMatchTracks lists unverified tracks; db.TrackRepository satisfies it.
+type BatchMatchTracks interface {
+	GetUnverifiedTracks(ctx context.Context, limit, offset int) ([]db.Track, int, error)
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+}
+
+// MetadataRepairer re-runs matching for one track and stores the outcome;
+// *Processor satisfies it.
+type MetadataRepairer interface {
+	RepairMetadata(ctx context.Context, track *db.Track, opts MetadataRepairOptions) (MetadataRepairResult, error)
+}
+
+// BatchMatchStatus is a run's progress. Verified, Suggested, and NoMatch
+// count tracks by the metadata status matching left behind.
+type BatchMatchStatus struct {
+	ID             string     `json:"id"`
+	State          string     `json:"state"`
+	Total          int        `json:"total"`
+	Processed      int        `json:"processed"`
+	Verified       int        `json:"verified"`
+	Suggested      int        `json:"suggested"`
+	NoMatch        int        `json:"noMatch"`
+	Skipped        int        `json:"skipped"`
+	Failed         int        `json:"failed"`
+	CurrentTrackID int64      `json:"currentTrackId,omitempty"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// BatchMatcher walks unverified tracks in the background, matching one at a
+// time against MusicBrainz. Only one run exists at a time; its progress goes
+// to the user who started it through the report callback.
+type BatchMatcher struct {
+	tracks   BatchMatchTracks
+	repairer MetadataRepairer
+	interval time.Duration
+	report   func(userID uuid.UUID, status BatchMatchStatus)
+
+	mu      sync.Mutex
+	status  *BatchMatchStatus
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewBatchMatcher matches at most one track per interval; a non-positive
+// interval uses DefaultBatchMatchInterval.
+func NewBatchMatcher(tracks BatchMatchTracks, repairer MetadataRepairer, interval time.Duration) *BatchMatcher {
+	if interval <= 0 {
+		interval = DefaultBatchMatchInterval
+	}
+	return &BatchMatcher{tracks: tracks, repairer: repairer, interval: interval}
+}
+
+// SetReporter receives the run's status after every track and when it ends.
+func (b *BatchMatcher) SetReporter(report func(userID uuid.UUID, status BatchMatchStatus)) {
+	b.report = report
+}
+
+// Start begins a run over at most limit unverified tracks, or all of them
+// when limit is zero.
+func (b *BatchMatcher) Start(userID uuid.UUID, limit int) (BatchMatchStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running {
+		return *b.status, ErrBatchMatchRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.status = &BatchMatchStatus{ID: uuid.NewString(), State: BatchMatchRunning, StartedAt: time.Now()}
+	b.running = true
+	b.cancel = cancel
+	b.wg.Add(1)
+	go b.run(ctx, userID, limit)
+	return *b.status, nil
+}
+
+// Status returns the current or most recent run.
+func (b *BatchMatcher) Status() (BatchMatchStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status == nil {
+		return BatchMatchStatus{}, false
+	}
+	return *b.status, true
+}
+
+// Cancel stops the running run after its current track and reports whether
+// one was running.
+func (b *BatchMatcher) Cancel() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.running {
+		return false
+	}
+	b.cancel()
+	return true
+}
+
+// Stop cancels any run and waits for it to return.
+func (b *BatchMatcher) Stop(ctx context.Context) error {
+	b.Cancel()
+	done := make(chan struct{})
+	go func() { b.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *BatchMatcher) run(ctx context.Context, userID uuid.UUID, limit int) {
+	defer b.wg.Done()
+	ids, err := b.collect(ctx, limit)
+	if err != nil {
+		b.finish(userID, err)
+		return
+	}
+	b.update(userID, func(s *BatchMatchStatus) { s.Total = len(ids) })
+
+	var last time.Time
+	for _, id := range ids {
+		if wait := b.interval - time.Since(last); !last.IsZero() && wait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		last = time.Now()
+		b.update(userID, func(s *BatchMatchStatus) { s.CurrentTrackID = id })
+		// A canceled run still finishes its current track, so cancellation
+		// never records a spurious match failure.
+		trackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), batchMatchTrackTimeout)
+		outcome := b.matchOne(trackCtx, id)
+		cancel()
+		b.update(userID, func(s *BatchMatchStatus) {
+			s.Processed++
+			switch outcome {
+			case "enriched":
+				s.Verified++
+			case "suggested":
+				s.Suggested++
+			case "no_match":
+				s.NoMatch++
+			case "failed":
+				s.Failed++
+			default:
+				s.Skipped++
+			}
+		})
+	}
+	b.finish(userID, ctx.Err())
+}
+
+// collect snapshots the IDs to match before any are matched, since matching
+// removes verified tracks from the unverified listing and shifts its pages.
+func (b *BatchMatcher) collect(ctx context.Context, limit int) ([]int64, error) {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for offset := 0; ; offset += batchMatchPageSize {
+		page, total, err := b.tracks.GetUnverifiedTracks(ctx, batchMatchPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range page {
+			if !seen[track.ID] {
+				seen[track.ID] = true
+				ids = append(ids, track.ID)
+			}
+			if limit > 0 && len(ids) == limit {
+				return ids, nil
+			}
+		}
+		if len(page) < batchMatchPageSize || offset+len(page) >= total {
+			return ids, nil
+		}
+	}
+}
+
+// matchOne matches a track unless it was verified or edited since the run
+// began, and returns the metadata status matching left, or "skipped".
+func (b *BatchMatcher) matchOne(ctx context.Context, id int64) string {
+	track, err := b.tracks.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrTrackNotFound) {
+			return "skipped"
+		}
+		log.Printf("Warning: batch match failed to load track %d: %v", id, err)
+		return "failed"
+	}
+	result, err := b.repairer.RepairMetadata(ctx, track, MetadataRepairOptions{})
+	if err != nil {
+		log.Printf("Warning: batch match failed for track %d: %v", id, err)
+		return "failed"
+	}
+	if result.Status != "processed" {
+		return "skipped"
+	}
+	matched, err := b.tracks.GetByID(ctx, id)
+	if err != nil {
+		return "failed"
+	}
+	return matched.MetadataStatus.String
+}
+
+func (b *BatchMatcher) update(userID uuid.UUID, change func(*BatchMatchStatus)) {
+	b.mu.Lock()
+	change(b.status)
+	status := *b.status
+	b.mu.Unlock()
+	if b.report != nil {
+		b.report(userID, status)
+	}
+}
+
+func (b *BatchMatcher) finish(userID uuid.UUID, err error) {
+	b.mu.Lock()
+	b.running = false
+	b.cancel()
+	now := time.Now()
+	b.status.FinishedAt = &now
+	b.status.CurrentTrackID = 0
+	switch {
+	case errors.Is(err, context.Canceled):
+		b.status.State = BatchMatchCanceled
+	case err != nil:
+		b.status.State = BatchMatchFailed
+		b.status.Error = err.Error()
+	default:
+		b.status.State = BatchMatchCompleted
+	}
+	status := *b.status
+	b.mu.Unlock()
+	if b.report != nil {
+		b.report(userID, status)
+	}
+	log.Printf("Batch match %s %s: %d of %d tracks processed (%d verified, %d suggested, %d failed)",
+		status.ID, status.State, status.Processed, status.Total, status.Verified, status.Suggested, status.Failed)
+}
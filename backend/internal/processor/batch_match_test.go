@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeBatchMatchTracks struct {
+	mu     sync.Mutex
+	tracks []*db.Track
+}
+
+func (f *fakeBatchMatchTracks) GetUnverifiedTracks(_ context.Context, limit, offset int) ([]db.Track, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var unverified []db.Track
+	for _, track := range f.tracks {
+		if !track.MBVerified {
+			unverified = append(unverified, *track)
+		}
+	}
+	if offset >= len(unverified) {
+		return nil, len(unverified), nil
+	}
+	end := min(offset+limit, len(unverified))
+	return unverified[offset:end], len(unverified), nil
+}
+
+func (f *fakeBatchMatchTracks) GetByID(_ context.Context, id int64) (*db.Track, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, track := range f.tracks {
+		if track.ID == id {
+			copied := *track
+			return &copied, nil
+		}
+	}
+	return nil, db.ErrTrackNotFound
+}
+
+// fakeRepairer verifies even track IDs, suggests for multiples of three,
+// and fails track 5.
+type fakeRepairer struct {
+	tracks  *fakeBatchMatchTracks
+	matched []int64
+	entered chan struct{}
+	block   chan struct{}
+}
+
+func (f *fakeRepairer) RepairMetadata(_ context.Context, track *db.Track, _ MetadataRepairOptions) (MetadataRepairResult, error) {
+	if f.block != nil {
+		f.entered <- struct{}{}
+		<-f.block
+	}
+	f.tracks.mu.Lock()
+	defer f.tracks.mu.Unlock()
+	f.matched = append(f.matched, track.ID)
+	if track.ID == 5 {
+		return MetadataRepairResult{TrackID: track.ID, Status: "failed"}, errors.New("musicbrainz unavailable")
+	}
+	for _, stored := range f.tracks.tracks {
+		if stored.ID != track.ID {
+			continue
+		}
+		switch {
+		case track.ID%2 == 0:
+			stored.MBVerified = true
+			stored.MetadataStatus = sql.NullString{String: "enriched", Valid: true}
+		case track.ID%3 == 0:
+			stored.MetadataStatus = sql.NullString{String: "suggested", Valid: true}
+		default:
+			stored.MetadataStatus = sql.NullString{String: "no_match", Valid: true}
+		}
+	}
+	return MetadataRepairResult{TrackID: track.ID, Status: "processed"}, nil
+}
+
+func waitForBatchMatch(t *testing.T, b *BatchMatcher) BatchMatchStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := b.Status(); ok && status.State != BatchMatchRunning {
+			return status
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("batch match did not finish")
+	return BatchMatchStatus{}
+}
+
+func TestBatchMatcherWalksEveryUnverifiedTrackAndReports(t *testing.T) {
+	tracks := &fakeBatchMatchTracks{}
+	for id := int64(1); id <= 250; id++ {
+		tracks.tracks = append(tracks.tracks, &db.Track{ID: id, MBVerified: id == 250})
+	}
+	repairer := &fakeRepairer{tracks: tracks}
+	b := NewBatchMatcher(tracks, repairer, time.Nanosecond)
+	var mu sync.Mutex
+	var reports []BatchMatchStatus
+	userID := uuid.New()
+	b.SetReporter(func(got uuid.UUID, status BatchMatchStatus) {
+		if got != userID {
+			t.Errorf("reported to %s, want %s", got, userID)
+		}
+		mu.Lock()
+		reports = append(reports, status)
+		mu.Unlock()
+	})
+
+	if _, err := b.Start(userID, 0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := waitForBatchMatch(t, b)
+	if status.State != BatchMatchCompleted || status.Total != 249 || status.Processed != 249 {
+		t.Fatalf("status = %+v, want all 249 unverified tracks processed", status)
+	}
+	if status.Verified != 124 || status.Failed != 1 || status.Verified+status.Suggested+status.NoMatch+status.Failed+status.Skipped != 249 {
+		t.Fatalf("outcomes = %+v", status)
+	}
+	if len(repairer.matched) != 249 {
+		t.Fatalf("matched %d tracks, want each once even as verified ones leave the listing", len(repairer.matched))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if last := reports[len(reports)-1]; last.State != BatchMatchCompleted || last.FinishedAt == nil {
+		t.Fatalf("last report = %+v, want the completed run", last)
+	}
+}
+
+func TestBatchMatcherRejectsConcurrentRunsAndCancels(t *testing.T) {
+	tracks := &fakeBatchMatchTracks{tracks: []*db.Track{{ID: 1}, {ID: 3}, {ID: 7}}}
+	repairer := &fakeRepairer{tracks: tracks, entered: make(chan struct{}, 3), block: make(chan struct{})}
+	b := NewBatchMatcher(tracks, repairer, time.Nanosecond)
+
+	if _, err := b.Start(uuid.New(), 2); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := b.Start(uuid.New(), 0); !errors.Is(err, ErrBatchMatchRunning) {
+		t.Fatalf("second Start err = %v, want ErrBatchMatchRunning", err)
+	}
+	<-repairer.entered
+	if !b.Cancel() {
+		t.Fatal("Cancel reported no running batch")
+	}
+	close(repairer.block)
+	status := waitForBatchMatch(t, b)
+	if status.State != BatchMatchCanceled || status.Total != 2 || status.Processed != 1 {
+		t.Fatalf("status = %+v, want a canceled run that finished its current track", status)
+	}
+	if b.Cancel() {
+		t.Fatal("Cancel after the run ended reported a running batch")
+	}
+	if err := b.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
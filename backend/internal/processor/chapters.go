@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/chapters"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/validators"
+)
+
+const (
+	// chapterMinDurationMs is the length from which a YouTube download is
+	// treated as a mix or set worth splitting into chapters.
+	chapterMinDurationMs = 10 * 60 * 1000
+	chapterDetectTimeout = 3 * time.Minute
+)
+
+// ChapterSource looks up segments submitted for a YouTube video.
+// *chapters.SponsorBlock satisfies it.
+type ChapterSource interface {
+	Segments(ctx context.Context, videoID string, durationMs int) ([]chapters.Segment, error)
+}
+
+// detectChapters finds chapter markers and skip segments for long YouTube
+// downloads while the file is still on disk, for storeChapters to save once
+// the track exists. A failed lookup or detection leaves the other's result.
+func (p *Processor) detectChapters(ctx context.Context, job *download.DownloadJob, path string, metadata *TrackMetadata) {
+	if metadata.DurationMs < chapterMinDurationMs {
+		return
+	}
+	video := validators.NewYouTubeValidator().Validate(job.URL)
+	if !video.Valid || video.MediaID == "" || video.MediaType == "playlist" {
+		return
+	}
+	detectCtx, cancel := context.WithTimeout(ctx, chapterDetectTimeout)
+	defer cancel()
+
+	var segments []chapters.Segment
+	if p.chapterSource != nil {
+		found, err := p.chapterSource.Segments(detectCtx, video.MediaID, metadata.DurationMs)
+		if err != nil {
+			log.Printf("Warning: SponsorBlock lookup failed for job %s: %v", job.ID, err)
+		}
+		segments = found
+	}
+	silences, err := chapters.DetectSilence(detectCtx, p.mediaRunner(), path, metadata.DurationMs)
+	if err != nil {
+		log.Printf("Warning: silence detection failed for job %s: %v", job.ID, err)
+	}
+	if found := chapters.Build(metadata.DurationMs, segments, silences); !found.Empty() {
+		metadata.Chapters = &found
+	}
+}
+
+// storeChapters saves what detectChapters found in the new track's
+// metadata_json.
+func (p *Processor) storeChapters(ctx context.Context, job *download.DownloadJob, track *db.Track, metadata *TrackMetadata) {
+	if p.trackRepo == nil || track == nil || metadata == nil || metadata.Chapters == nil {
+		return
+	}
+	encoded, err := json.Marshal(metadata.Chapters)
+	if err == nil {
+		err = p.trackRepo.UpdateChapters(ctx, track.ID, encoded)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to store chapters for job %s (track %d): %v", job.ID, track.ID, err)
+	}
+}
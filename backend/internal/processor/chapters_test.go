@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/chapters"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+type fakeChapterSource struct {
+	videoIDs []string
+	segments []chapters.Segment
+	err      error
+}
+
+func (f *fakeChapterSource) Segments(_ context.Context, videoID string, _ int) ([]chapters.Segment, error) {
+	f.videoIDs = append(f.videoIDs, videoID)
+	return f.segments, f.err
+}
+
+func TestDetectChaptersForLongYouTubeDownloads(t *testing.T) {
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		if !strings.Contains(strings.Join(call.Args, " "), "silencedetect") {
+			return ffmpeg.Output{}, errors.New("unexpected call")
+		}
+		return ffmpeg.Output{Stderr: "[silencedetect @ 0x1] silence_start: 600\n[silencedetect @ 0x1] silence_end: 604 | silence_duration: 4\n"}, nil
+	}
+	source := &fakeChapterSource{err: errors.New("sponsorblock unavailable")}
+	p := &Processor{media: runner, chapterSource: source}
+
+	long := &TrackMetadata{DurationMs: 1200000}
+	p.detectChapters(context.Background(), &download.DownloadJob{ID: "mix", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, "/tmp/mix.opus", long)
+	if strings.Join(source.videoIDs, ",") != "dQw4w9WgXcQ" {
+		t.Fatalf("looked up %v, want the video ID", source.videoIDs)
+	}
+	if long.Chapters == nil || len(long.Chapters.Chapters) != 2 || long.Chapters.Chapters[1].StartMs != 602000 {
+		t.Fatalf("chapters = %+v, want silence to split the mix despite the lookup failure", long.Chapters)
+	}
+
+	short := &TrackMetadata{DurationMs: 240000}
+	p.detectChapters(context.Background(), &download.DownloadJob{ID: "song", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, "/tmp/song.opus", short)
+	other := &TrackMetadata{DurationMs: 1200000}
+	p.detectChapters(context.Background(), &download.DownloadJob{ID: "set", URL: "https://soundcloud.com/dj/set"}, "/tmp/set.mp3", other)
+	if short.Chapters != nil || other.Chapters != nil || len(source.videoIDs) != 1 || len(runner.Calls()) != 1 {
+		t.Fatalf("detected chapters for a short or non-YouTube download: %d lookups, %d ffmpeg calls", len(source.videoIDs), len(runner.Calls()))
+	}
+}
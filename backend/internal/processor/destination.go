@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+// DownloadSettingsStore returns where a user's finished downloads go by
+// default. db.UserRepository satisfies it.
+type DownloadSettingsStore interface {
+	GetDownloadSettings(ctx context.Context, userID uuid.UUID) (*db.DownloadSettings, error)
+}
+
+// PlaybackQueue puts a finished download next in the user's play queue.
+// Implementations skip jobs that already belong to a queue item, since that
+// item starts playing the track on its own.
+type PlaybackQueue interface {
+	QueueNext(ctx context.Context, userID, jobID string, trackID int64) error
+}
+
+// destination is where one job's track goes once it is created.
+type destination struct {
+	addToLibrary bool
+	playlistID   int64
+	queueNext    bool
+}
+
+// SetPlaybackQueue enables the "queue next" download setting. The play queue
+// only exists when Redis is enabled.
+func (p *Processor) SetPlaybackQueue(queue PlaybackQueue) {
+	p.playbackQueue = queue
+}
+
+// resolveDestination applies the job's own overrides on top of the user's
+// download settings. Playlist import items always go to the library and
+// their import playlist only, as before settings existed.
+func (p *Processor) resolveDestination(ctx context.Context, job *download.DownloadJob) destination {
+	dest := destination{addToLibrary: true}
+	if job.PlaylistImportItemID != 0 {
+		return dest
+	}
+	if p.downloadSettings != nil {
+		if userID, err := uuid.Parse(job.UserID); err == nil {
+			settings, err := p.downloadSettings.GetDownloadSettings(ctx, userID)
+			if err != nil {
+				log.Printf("Warning: failed to load download settings for user %s, using defaults: %v", job.UserID, err)
+			} else {
+				dest.addToLibrary = settings.AddToLibrary
+				dest.playlistID = settings.PlaylistID.Int64
+				dest.queueNext = settings.QueueNext
+			}
+		}
+	}
+	override := download.DestinationFromMetadata(job.Metadata)
+	if override.AddToLibrary != nil {
+		dest.addToLibrary = *override.AddToLibrary
+	}
+	if override.PlaylistID != nil {
+		dest.playlistID = *override.PlaylistID
+	}
+	if override.QueueNext != nil {
+		dest.queueNext = *override.QueueNext
+	}
+	return dest
+}
+
+// addToDestinationPlaylist appends the track to a playlist the job's user
+// owns. Ownership is checked again here because the playlist may have
+// changed hands or become read-only since the setting was saved.
+func (p *Processor) addToDestinationPlaylist(ctx context.Context, userID string, playlistID, trackID int64) error {
+	if p.playlistRepo == nil {
+		return nil
+	}
+	playlist, err := p.playlistRepo.GetByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+	if playlist.UserID.String() != userID {
+		return db.ErrPlaylistNotOwned
+	}
+	if playlist.SystemKind.Valid {
+		return fmt.Errorf("playlist %d is generated and read-only", playlistID)
+	}
+	if err := p.playlistRepo.AddTrack(ctx, playlistID, trackID); err != nil && !errors.Is(err, db.ErrTrackAlreadyInPlaylist) {
+		return err
+	}
+	return nil
+}
+
+// deliver sends the track everywhere dest names. Failures are logged rather
+// than failing the job: the track exists and can still be added by hand.
+func (p *Processor) deliver(ctx context.Context, job *download.DownloadJob, trackID int64, dest destination) {
+	if dest.addToLibrary {
+		if err := p.addToLibrary(ctx, job.UserID, trackID); err != nil {
+			log.Printf("Warning: failed to add track %d to library: %v", trackID, err)
+		}
+	}
+	if dest.playlistID != 0 {
+		if err := p.addToDestinationPlaylist(ctx, job.UserID, dest.playlistID, trackID); err != nil {
+			log.Printf("Warning: failed to add track %d to playlist %d: %v", trackID, dest.playlistID, err)
+		}
+	}
+	if dest.queueNext && p.playbackQueue != nil {
+		if err := p.playbackQueue.QueueNext(ctx, job.UserID, job.ID, trackID); err != nil {
+			log.Printf("Warning: failed to queue track %d next for user %s: %v", trackID, job.UserID, err)
+		}
+	}
+}
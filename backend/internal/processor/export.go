@@ -0,0 +1,174 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/tagger"
+)
+
+const (
+	exportTimeout       = 2 * time.Minute
+	webhookTimeout      = 30 * time.Second
+	maxExportNameLength = 120
+)
+
+// postProcess runs the optional completion stages: a tagged copy in the
+// export directory and the download webhook. Both are best effort; the track
+// is already in the library when they run.
+func (p *Processor) postProcess(ctx context.Context, job *download.DownloadJob, track *db.Track, isNew bool) {
+	if p.exportDir == "" && p.webhook == nil {
+		return
+	}
+	// Matching updates the row, not the in-memory track.
+	if p.trackRepo != nil {
+		if reloaded, err := p.trackRepo.GetByID(ctx, track.ID); err == nil {
+			track = reloaded
+		} else {
+			log.Printf("Warning: failed to reload track %d for post-processing: %v", track.ID, err)
+		}
+	}
+
+	exportPath := ""
+	if p.exportDir != "" {
+		exportCtx, cancel := context.WithTimeout(ctx, exportTimeout)
+		path, err := p.exportCopy(exportCtx, track)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: failed to export track %d to %s: %v", track.ID, p.exportDir, err)
+		} else {
+			exportPath = path
+		}
+	}
+
+	if p.webhook != nil {
+		event := newDownloadEvent(job, track, isNew, time.Now())
+		event.ExportPath = exportPath
+		webhookCtx, cancel := context.WithTimeout(ctx, webhookTimeout)
+		err := p.webhook.Send(webhookCtx, event)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: download webhook for job %s failed: %v", job.ID, err)
+		}
+	}
+}
+
+// exportCopy writes the track's audio, tagged with its current metadata, to
+// <exportDir>/<artist>/<album>/<title>.<ext> and returns that path. A file
+// already at the path is left alone so duplicate downloads do not rewrite
+// it. The copy is written under a dot-prefixed name and renamed into place,
+// so directory watchers never see a partial file.
+func (p *Processor) exportCopy(ctx context.Context, track *db.Track) (string, error) {
+	if p.storage == nil || !track.StorageKey.Valid || track.StorageKey.String == "" {
+		return "", errors.New("track has no stored audio")
+	}
+	ext := strings.ToLower(path.Ext(track.StorageKey.String))
+	if ext == "" {
+		return "", fmt.Errorf("stored audio %q has no extension", track.StorageKey.String)
+	}
+	target := filepath.Join(p.exportDir, filepath.FromSlash(ExportPath(track)))
+	dir := filepath.Dir(target)
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	scratch, err := os.MkdirTemp(p.tempDir, "omp-export-*")
+	if err != nil {
+		return "", fmt.Errorf("create export temp dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	source := filepath.Join(scratch, "source"+ext)
+	if err := p.copyObjectToFile(ctx, track.StorageKey.String, source); err != nil {
+		return "", err
+	}
+
+	partial, err := os.CreateTemp(dir, ".omp-export-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	partialPath := partial.Name()
+	partial.Close()
+	defer os.Remove(partialPath)
+
+	if err := tagger.New(p.mediaRunner()).Write(ctx, source, partialPath, tagger.FromTrack(track)); err != nil {
+		return "", err
+	}
+	if err := os.Rename(partialPath, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (p *Processor) copyObjectToFile(ctx context.Context, key, dest string) error {
+	return copyObjectToFile(ctx, p.storage, key, dest)
+}
+
+// objectReader reads stored objects; ObjectStorage satisfies it.
+type objectReader interface {
+	GetObject(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error)
+}
+
+func copyObjectToFile(ctx context.Context, objects objectReader, key, dest string) error {
+	reader, _, err := objects.GetObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read stored audio: %w", err)
+	}
+	defer reader.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(reader, maxUploadedAudioBytes)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ExportPath is where exports place a track, relative to the export root:
+// Artist/Album/Title.ext with the stored audio's extension. EXPORT_DIR copies
+// and library ZIP archives both use it.
+func ExportPath(track *db.Track) string {
+	return exportName(track.Artist.String, "Unknown Artist") + "/" +
+		exportName(track.Album.String, "Unknown Album") + "/" +
+		exportName(track.Title, fmt.Sprintf("Track %d", track.ID)) +
+		strings.ToLower(path.Ext(strings.TrimSpace(track.StorageKey.String)))
+}
+
+// exportName makes value safe as a single path element on common
+// filesystems, falling back when nothing usable is left.
+func exportName(value, fallback string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, value)
+	value = strings.TrimSpace(value)
+	if runes := []rune(value); len(runes) > maxExportNameLength {
+		value = strings.TrimSpace(string(runes[:maxExportNameLength]))
+	}
+	value = strings.Trim(value, ".")
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+func exportTestTrack() *db.Track {
+	recordingID := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	return &db.Track{
+		ID:            42,
+		Title:         "Roygbiv",
+		Artist:        sql.NullString{String: "Boards of Canada", Valid: true},
+		Album:         sql.NullString{String: "Music Has the Right to Children", Valid: true},
+		MBRecordingID: &recordingID,
+		StorageKey:    sql.NullString{String: "tracks/youtube/job-1.opus", Valid: true},
+	}
+}
+
+func TestExportCopyWritesTaggedFileOnce(t *testing.T) {
+	exportDir := t.TempDir()
+	objects := &fakeObjectStorage{objects: map[string][]byte{"tracks/youtube/job-1.opus": []byte("opus bytes")}}
+	media := ffmpeg.NewFake()
+	media.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		out := call.Args[len(call.Args)-1]
+		if !strings.HasPrefix(filepath.Base(out), ".omp-export-") {
+			t.Errorf("ffmpeg wrote %s directly, want a hidden partial file", out)
+		}
+		return ffmpeg.Output{}, os.WriteFile(out, []byte("tagged"), 0o644)
+	}
+	p := New(&ProcessorConfig{Storage: objects, FFmpeg: media, ExportDir: exportDir, TempDir: t.TempDir()})
+
+	path, err := p.exportCopy(context.Background(), exportTestTrack())
+	if err != nil {
+		t.Fatalf("exportCopy: %v", err)
+	}
+	want := filepath.Join(exportDir, "Boards of Canada", "Music Has the Right to Children", "Roygbiv.opus")
+	if data, err := os.ReadFile(path); path != want || err != nil || string(data) != "tagged" {
+		t.Fatalf("exported %s (%q, %v), want %s", path, data, err, want)
+	}
+	args := strings.Join(media.Calls()[0].Args, " ")
+	for _, fragment := range []string{"-c copy", "-map_metadata -1", "-metadata artist=Boards of Canada", "-metadata MUSICBRAINZ_TRACKID=11111111-2222-3333-4444-555555555555"} {
+		if !strings.Contains(args, fragment) {
+			t.Fatalf("ffmpeg args %q missing %q", args, fragment)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(want))
+	if len(entries) != 1 {
+		t.Fatalf("export dir has %d entries, want only the final file", len(entries))
+	}
+
+	if _, err := p.exportCopy(context.Background(), exportTestTrack()); err != nil || len(media.Calls()) != 1 {
+		t.Fatalf("second export err %v after %d ffmpeg calls, want the existing file kept", err, len(media.Calls()))
+	}
+}
+
+func TestExportNameSanitizesPathElements(t *testing.T) {
+	for value, want := range map[string]string{
+		"AC/DC":          "AC_DC",
+		"  What?  ":      "What_",
+		"..":             "Unknown",
+		"Tab\there":      "Tabhere",
+		"Sigur Rós":      "Sigur Rós",
+		"":               "Unknown",
+		`a:b*c"d<e>f|g\`: "a_b_c_d_e_f_g_",
+	} {
+		if got := exportName(value, "Unknown"); got != want {
+			t.Errorf("exportName(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestWebhookSignsAndRetriesServerErrors(t *testing.T) {
+	var attempts int
+	var received DownloadEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if got := r.Header.Get("X-OMP-Signature"); got != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("signature = %q", got)
+		}
+		if got := r.Header.Get("X-OMP-Event"); got != EventDownloadCompleted {
+			t.Errorf("event header = %q", got)
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, "s3cret", nil)
+	webhook.retryDelay = time.Millisecond
+	job := &download.DownloadJob{ID: "job-1", UserID: uuid.NewString(), URL: "https://example.test/a", SourceType: "youtube"}
+	event := newDownloadEvent(job, exportTestTrack(), true, time.Now())
+	event.ExportPath = "/exports/a.opus"
+	if err := webhook.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want a retry after the 502", attempts)
+	}
+	if received.Track.StorageKey != "tracks/youtube/job-1.opus" || received.Track.MBRecordingID == "" || !received.IsNew || received.ExportPath != "/exports/a.opus" {
+		t.Fatalf("received %+v", received)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, "", nil)
+	webhook.retryDelay = time.Millisecond
+	if err := webhook.Send(context.Background(), DownloadEvent{Event: EventDownloadCompleted}); err == nil || attempts != 1 {
+		t.Fatalf("err %v after %d attempts, want one failed attempt", err, attempts)
+	}
+}
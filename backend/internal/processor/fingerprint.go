@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/fingerprint"
+	"github.com/openmusicplayer/backend/internal/matcher"
+)
+
+const audioFingerprintTimeout = 90 * time.Second
+
+// Fingerprinter identifies downloaded audio by its acoustic fingerprint.
+// *fingerprint.Identifier satisfies it.
+type Fingerprinter interface {
+	Identify(ctx context.Context, path string) ([]fingerprint.Match, error)
+}
+
+// identifyAudio fingerprints the downloaded file while it is still on disk,
+// for runMatching to use later. Failures only cost the matcher a signal.
+func (p *Processor) identifyAudio(ctx context.Context, job *download.DownloadJob, path string, metadata *TrackMetadata) {
+	if p.fingerprinter == nil || p.matcher == nil || metadata.PreselectedMBID != "" {
+		return
+	}
+	identifyCtx, cancel := context.WithTimeout(ctx, audioFingerprintTimeout)
+	defer cancel()
+	matches, err := p.fingerprinter.Identify(identifyCtx, path)
+	if err != nil {
+		log.Printf("Warning: fingerprint lookup failed for job %s: %v", job.ID, err)
+		return
+	}
+	metadata.Fingerprints = matches
+}
+
+func matcherFingerprints(matches []fingerprint.Match) []matcher.FingerprintMatch {
+	if len(matches) == 0 {
+		return nil
+	}
+	out := make([]matcher.FingerprintMatch, len(matches))
+	for i, m := range matches {
+		out[i] = matcher.FingerprintMatch{RecordingID: m.RecordingID, Score: m.Score}
+	}
+	return out
+}
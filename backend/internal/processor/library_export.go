@@ -0,0 +1,469 @@
+package processor
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/tagger"
+)
+
+const (
+	// LibraryExportRetention is how long a finished archive stays in object
+	// storage for download.
+	LibraryExportRetention = 24 * time.Hour
+	// libraryExportWorkers bounds how many archives are built at once; each
+	// reads every selected track out of object storage.
+	libraryExportWorkers      = 1
+	libraryExportTrackTimeout = 2 * time.Minute
+)
+
+// Library export states.
+const (
+	LibraryExportQueued    = "queued"
+	LibraryExportRunning   = "running"
+	LibraryExportCompleted = "completed"
+	LibraryExportCanceled  = "canceled"
+	LibraryExportFailed    = "failed"
+)
+
+// ErrLibraryExportRunning is returned when a user starts an export while
+// another of theirs is queued or running.
+var ErrLibraryExportRunning = errors.New("a library export is already running")
+
+// LibraryExportLibrary lists a user's tracks; db.LibraryRepository
+// satisfies it.
+type LibraryExportLibrary interface {
+	LibraryTrackIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+}
+
+// LibraryExportTracks loads tracks; db.TrackRepository satisfies it.
+type LibraryExportTracks interface {
+	GetByID(ctx context.Context, id int64) (*db.Track, error)
+}
+
+// LibraryExportStorage reads audio and stores archives; *storage.Client
+// satisfies it.
+type LibraryExportStorage interface {
+	ObjectStorage
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// LibraryExportStatus is an export's progress. Total counts the tracks
+// selected, and Processed those added to the archive or skipped so far.
+type LibraryExportStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Added      int        `json:"added"`
+	Skipped    int        `json:"skipped"`
+	SizeBytes  int64      `json:"sizeBytes,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// ExpiresAt is when a completed archive is deleted.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	// ArchiveKey is the archive's object key once it is complete.
+	ArchiveKey string `json:"-"`
+}
+
+// LibraryExporter builds ZIP archives of users' libraries in the background.
+// Tracks are laid out as Artist/Album/Title.ext and tagged with their
+// current metadata. Archives stream into object storage as they are
+// written, so an export never holds a library on local disk, and are
+// deleted after LibraryExportRetention. Each user has at most one export;
+// starting another replaces a finished one.
+type LibraryExporter struct {
+	library   LibraryExportLibrary
+	tracks    LibraryExportTracks
+	objects   LibraryExportStorage
+	tagger    *tagger.Tagger
+	tempDir   string
+	retention time.Duration
+	slots     chan struct{}
+	report    func(userID uuid.UUID, status LibraryExportStatus)
+	now       func() time.Time
+
+	mu      sync.Mutex
+	exports map[uuid.UUID]*libraryExport
+	wg      sync.WaitGroup
+}
+
+type libraryExport struct {
+	status   LibraryExportStatus
+	trackIDs []int64
+	running  bool
+	cancel   context.CancelFunc
+	expiry   *time.Timer
+}
+
+// NewLibraryExporter creates an exporter. A nil runner tags with the ffmpeg
+// binary on PATH; an empty tempDir uses the system default.
+func NewLibraryExporter(library LibraryExportLibrary, tracks LibraryExportTracks, objects LibraryExportStorage, runner ffmpeg.Runner, tempDir string) *LibraryExporter {
+	return &LibraryExporter{
+		library:   library,
+		tracks:    tracks,
+		objects:   objects,
+		tagger:    tagger.New(runner),
+		tempDir:   tempDir,
+		retention: LibraryExportRetention,
+		slots:     make(chan struct{}, libraryExportWorkers),
+		now:       time.Now,
+		exports:   make(map[uuid.UUID]*libraryExport),
+	}
+}
+
+// SetReporter receives an export's status after every track and when it
+// ends.
+func (e *LibraryExporter) SetReporter(report func(userID uuid.UUID, status LibraryExportStatus)) {
+	e.report = report
+}
+
+// LibraryExportKey is where a user's export archive is stored.
+func LibraryExportKey(userID uuid.UUID, exportID string) string {
+	return fmt.Sprintf("exports/library/%s/%s.zip", userID, exportID)
+}
+
+// Start queues an export of trackIDs, or of the whole library when trackIDs
+// is empty. Selected tracks that are not in the user's library are skipped.
+func (e *LibraryExporter) Start(userID uuid.UUID, trackIDs []int64) (LibraryExportStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if previous := e.exports[userID]; previous != nil {
+		if previous.running {
+			return previous.status, ErrLibraryExportRunning
+		}
+		e.discardLocked(userID, previous)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exp := &libraryExport{
+		status:   LibraryExportStatus{ID: uuid.NewString(), State: LibraryExportQueued, CreatedAt: e.now()},
+		trackIDs: trackIDs,
+		running:  true,
+		cancel:   cancel,
+	}
+	e.exports[userID] = exp
+	e.wg.Add(1)
+	go e.run(ctx, userID, exp)
+	return exp.status, nil
+}
+
+// Status returns the user's export with id.
+func (e *LibraryExporter) Status(userID uuid.UUID, id string) (LibraryExportStatus, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exp := e.exports[userID]
+	if exp == nil || exp.status.ID != id {
+		return LibraryExportStatus{}, false
+	}
+	return exp.status, true
+}
+
+// Cancel stops the user's export with id, or deletes its archive when it has
+// finished, and reports whether the export existed.
+func (e *LibraryExporter) Cancel(userID uuid.UUID, id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exp := e.exports[userID]
+	if exp == nil || exp.status.ID != id {
+		return false
+	}
+	if exp.running {
+		exp.cancel()
+		return true
+	}
+	e.discardLocked(userID, exp)
+	return true
+}
+
+// Stop cancels running exports and waits for them to return. Finished
+// archives are left for the object store's own expiry.
+func (e *LibraryExporter) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	for _, exp := range e.exports {
+		if exp.running {
+			exp.cancel()
+		}
+	}
+	e.mu.Unlock()
+	done := make(chan struct{})
+	go func() { e.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// discardLocked forgets a finished export and deletes its archive.
+func (e *LibraryExporter) discardLocked(userID uuid.UUID, exp *libraryExport) {
+	if exp.expiry != nil {
+		exp.expiry.Stop()
+	}
+	if e.exports[userID] == exp {
+		delete(e.exports, userID)
+	}
+	if key := exp.status.ArchiveKey; key != "" {
+		go e.deleteArchive(key)
+	}
+}
+
+func (e *LibraryExporter) deleteArchive(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := e.objects.DeleteObject(ctx, key); err != nil {
+		log.Printf("Warning: failed to delete library export %s: %v", key, err)
+	}
+}
+
+func (e *LibraryExporter) run(ctx context.Context, userID uuid.UUID, exp *libraryExport) {
+	defer e.wg.Done()
+	select {
+	case e.slots <- struct{}{}:
+		defer func() { <-e.slots }()
+	case <-ctx.Done():
+		e.finish(userID, exp, 0, ctx.Err())
+		return
+	}
+	e.update(userID, exp, func(s *LibraryExportStatus) { s.State = LibraryExportRunning })
+
+	ids, err := e.selectTracks(ctx, userID, exp.trackIDs)
+	if err != nil {
+		e.finish(userID, exp, 0, err)
+		return
+	}
+	e.update(userID, exp, func(s *LibraryExportStatus) {
+		s.Total = len(exp.trackIDs)
+		if s.Total == 0 {
+			s.Total = len(ids)
+		}
+		s.Skipped = s.Total - len(ids)
+		s.Processed = s.Skipped
+	})
+	size, err := e.build(ctx, userID, exp, ids)
+	e.finish(userID, exp, size, err)
+}
+
+// selectTracks returns the requested tracks that are in the user's library,
+// in request order, or the whole library when none were requested.
+func (e *LibraryExporter) selectTracks(ctx context.Context, userID uuid.UUID, requested []int64) ([]int64, error) {
+	library, err := e.library.LibraryTrackIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list library: %w", err)
+	}
+	if len(requested) == 0 {
+		return library, nil
+	}
+	inLibrary := make(map[int64]bool, len(library))
+	for _, id := range library {
+		inLibrary[id] = true
+	}
+	ids := make([]int64, 0, len(requested))
+	for _, id := range requested {
+		if inLibrary[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// build streams the archive into object storage and returns its size.
+func (e *LibraryExporter) build(ctx context.Context, userID uuid.UUID, exp *libraryExport, ids []int64) (int64, error) {
+	key := LibraryExportKey(userID, exp.status.ID)
+	reader, writer := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := e.writeArchive(ctx, writer, userID, exp, ids)
+		writer.CloseWithError(err)
+		written <- err
+	}()
+	archive := &countingReader{r: reader}
+	putErr := e.objects.PutObject(ctx, key, archive, -1, "application/zip")
+	// Unblocks the writer when the upload stopped reading early.
+	reader.CloseWithError(errors.New("archive upload stopped"))
+	writeErr := <-written
+
+	err := ctx.Err()
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil && putErr == nil {
+		// The upload finished before the export was stopped.
+		e.deleteArchive(key)
+	}
+	switch {
+	case err != nil:
+		return 0, err
+	case putErr != nil:
+		return 0, fmt.Errorf("upload archive: %w", putErr)
+	}
+	return archive.n, nil
+}
+
+func (e *LibraryExporter) writeArchive(ctx context.Context, w io.Writer, userID uuid.UUID, exp *libraryExport, ids []int64) error {
+	scratch, err := os.MkdirTemp(e.tempDir, "omp-library-export-*")
+	if err != nil {
+		return fmt.Errorf("create export temp dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	archive := zip.NewWriter(w)
+	names := make(map[string]bool)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		added, err := e.addTrack(ctx, archive, scratch, id, names)
+		if err != nil {
+			return err
+		}
+		e.update(userID, exp, func(s *LibraryExportStatus) {
+			s.Processed++
+			if added {
+				s.Added++
+			} else {
+				s.Skipped++
+			}
+		})
+	}
+	return archive.Close()
+}
+
+// addTrack writes one track into the archive. Tracks that cannot be read
+// are skipped and reported false; only archive write failures are returned.
+func (e *LibraryExporter) addTrack(ctx context.Context, archive *zip.Writer, scratch string, id int64, names map[string]bool) (bool, error) {
+	trackCtx, cancel := context.WithTimeout(ctx, libraryExportTrackTimeout)
+	defer cancel()
+	track, err := e.tracks.GetByID(trackCtx, id)
+	if err != nil {
+		if !errors.Is(err, db.ErrTrackNotFound) {
+			log.Printf("Warning: library export failed to load track %d: %v", id, err)
+		}
+		return false, nil
+	}
+	key := strings.TrimSpace(track.StorageKey.String)
+	if track.QuarantinedAt.Valid || !track.StorageKey.Valid || key == "" {
+		return false, nil
+	}
+	ext := strings.ToLower(path.Ext(key))
+
+	source := filepath.Join(scratch, fmt.Sprintf("%d-source%s", id, ext))
+	tagged := filepath.Join(scratch, fmt.Sprintf("%d-tagged%s", id, ext))
+	defer os.Remove(source)
+	defer os.Remove(tagged)
+	if err := copyObjectToFile(trackCtx, e.objects, key, source); err != nil {
+		log.Printf("Warning: library export skipped track %d: %v", id, err)
+		return false, nil
+	}
+	file := tagged
+	if ext == "" {
+		file = source
+	} else if err := e.tagger.Write(trackCtx, source, tagged, tagger.FromTrack(track)); err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		log.Printf("Warning: library export could not tag track %d, adding it as stored: %v", id, err)
+		file = source
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	out, err := archive.CreateHeader(&zip.FileHeader{
+		Name: libraryExportEntryName(track, ext, names),
+		// Audio is already compressed.
+		Method:   zip.Store,
+		Modified: track.UpdatedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// libraryExportEntryName places a track at its ExportPath, numbering names
+// already taken in the archive.
+func libraryExportEntryName(track *db.Track, ext string, taken map[string]bool) string {
+	name := ExportPath(track)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; taken[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	taken[strings.ToLower(name)] = true
+	return name
+}
+
+func (e *LibraryExporter) update(userID uuid.UUID, exp *libraryExport, change func(*LibraryExportStatus)) {
+	e.mu.Lock()
+	change(&exp.status)
+	status := exp.status
+	e.mu.Unlock()
+	if e.report != nil {
+		e.report(userID, status)
+	}
+}
+
+func (e *LibraryExporter) finish(userID uuid.UUID, exp *libraryExport, size int64, err error) {
+	e.mu.Lock()
+	exp.running = false
+	exp.cancel()
+	now := e.now()
+	exp.status.FinishedAt = &now
+	switch {
+	case errors.Is(err, context.Canceled):
+		exp.status.State = LibraryExportCanceled
+	case err != nil:
+		exp.status.State = LibraryExportFailed
+		exp.status.Error = "archive could not be built"
+	default:
+		expiresAt := now.Add(e.retention)
+		exp.status.State = LibraryExportCompleted
+		exp.status.SizeBytes = size
+		exp.status.ExpiresAt = &expiresAt
+		exp.status.ArchiveKey = LibraryExportKey(userID, exp.status.ID)
+		exp.expiry = time.AfterFunc(e.retention, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.discardLocked(userID, exp)
+		})
+	}
+	status := exp.status
+	e.mu.Unlock()
+	if e.report != nil {
+		e.report(userID, status)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Warning: library export %s for user %s failed: %v", status.ID, userID, err)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,249 @@
+package processor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+type fakeExportLibrary struct{ ids []int64 }
+
+func (f fakeExportLibrary) LibraryTrackIDs(context.Context, uuid.UUID) ([]int64, error) {
+	return f.ids, nil
+}
+
+type fakeExportTracks map[int64]*db.Track
+
+func (f fakeExportTracks) GetByID(_ context.Context, id int64) (*db.Track, error) {
+	if track, ok := f[id]; ok {
+		return track, nil
+	}
+	return nil, db.ErrTrackNotFound
+}
+
+type fakeExportObjects struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	deleted chan string
+}
+
+func (f *fakeExportObjects) GetObject(_ context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, nil, errors.New("object not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), &storage.ObjectInfo{Size: int64(len(data))}, nil
+}
+
+func (f *fakeExportObjects) PutObject(_ context.Context, key string, reader io.Reader, size int64, _ string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = data
+	return nil
+}
+
+func (f *fakeExportObjects) DeleteObject(_ context.Context, key string) error {
+	f.mu.Lock()
+	delete(f.objects, key)
+	f.mu.Unlock()
+	f.deleted <- key
+	return nil
+}
+
+// taggingRunner "tags" a file by prefixing its bytes with the title it was
+// given.
+func taggingRunner() *ffmpeg.Fake {
+	fake := ffmpeg.NewFake()
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		var source, title string
+		for i, arg := range call.Args {
+			switch {
+			case arg == "-i" && source == "":
+				source = call.Args[i+1]
+			case arg == "-metadata" && len(call.Args[i+1]) > 6 && call.Args[i+1][:6] == "title=":
+				title = call.Args[i+1][6:]
+			}
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return ffmpeg.Output{}, err
+		}
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], append([]byte(title+":"), data...), 0o600)
+	}
+	return fake
+}
+
+func exportTrack(id int64, title, key string) *db.Track {
+	return &db.Track{
+		ID:         id,
+		Title:      title,
+		Artist:     sql.NullString{String: "Björk", Valid: true},
+		Album:      sql.NullString{String: "Homogenic", Valid: true},
+		StorageKey: sql.NullString{String: key, Valid: key != ""},
+	}
+}
+
+func waitForExport(t *testing.T, exporter *LibraryExporter, userID uuid.UUID, id string) LibraryExportStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := exporter.Status(userID, id); ok && status.FinishedAt != nil {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("export %s did not finish", id)
+	return LibraryExportStatus{}
+}
+
+func TestLibraryExporterZipsTaggedTracksByArtistAndAlbum(t *testing.T) {
+	objects := &fakeExportObjects{deleted: make(chan string, 1), objects: map[string][]byte{
+		"audio/a.mp3": []byte("one"), "audio/b.mp3": []byte("two"),
+	}}
+	tracks := fakeExportTracks{
+		1: exportTrack(1, "Jóga", "audio/a.mp3"),
+		2: exportTrack(2, "Jóga", "audio/b.mp3"),
+		3: exportTrack(3, "Bachelorette", ""),
+	}
+	exporter := NewLibraryExporter(fakeExportLibrary{ids: []int64{1, 2, 3}}, tracks, objects, taggingRunner(), t.TempDir())
+	var reports []LibraryExportStatus
+	var reportsMu sync.Mutex
+	exporter.SetReporter(func(_ uuid.UUID, status LibraryExportStatus) {
+		reportsMu.Lock()
+		reports = append(reports, status)
+		reportsMu.Unlock()
+	})
+	userID := uuid.New()
+
+	started, err := exporter.Start(userID, nil)
+	if err != nil || started.State != LibraryExportQueued {
+		t.Fatalf("Start = %+v, %v", started, err)
+	}
+	status := waitForExport(t, exporter, userID, started.ID)
+	if status.State != LibraryExportCompleted || status.Total != 3 || status.Added != 2 || status.Skipped != 1 || status.ExpiresAt == nil {
+		t.Fatalf("status = %+v", status)
+	}
+
+	objects.mu.Lock()
+	archive := objects.objects[status.ArchiveKey]
+	objects.mu.Unlock()
+	if int64(len(archive)) != status.SizeBytes {
+		t.Fatalf("archive is %d bytes, status says %d", len(archive), status.SizeBytes)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	got := map[string]string{}
+	for _, file := range zr.File {
+		rc, _ := file.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[file.Name] = string(data)
+	}
+	want := map[string]string{
+		"Björk/Homogenic/Jóga.mp3":     "Jóga:one",
+		"Björk/Homogenic/Jóga (2).mp3": "Jóga:two",
+	}
+	if len(got) != len(want) || got["Björk/Homogenic/Jóga.mp3"] != want["Björk/Homogenic/Jóga.mp3"] || got["Björk/Homogenic/Jóga (2).mp3"] != want["Björk/Homogenic/Jóga (2).mp3"] {
+		t.Fatalf("archive = %v, want %v", got, want)
+	}
+	reportsMu.Lock()
+	last := reports[len(reports)-1]
+	reportsMu.Unlock()
+	if last.State != LibraryExportCompleted {
+		t.Fatalf("last report = %+v", last)
+	}
+
+	// Discarding a finished export deletes its archive.
+	if !exporter.Cancel(userID, status.ID) {
+		t.Fatal("Cancel did not find the finished export")
+	}
+	if key := <-objects.deleted; key != status.ArchiveKey {
+		t.Fatalf("deleted %q, want %q", key, status.ArchiveKey)
+	}
+	if _, ok := exporter.Status(userID, status.ID); ok {
+		t.Fatal("discarded export is still reported")
+	}
+}
+
+func TestLibraryExporterSkipsSelectedTracksOutsideLibrary(t *testing.T) {
+	objects := &fakeExportObjects{objects: map[string][]byte{"audio/a.mp3": []byte("one"), "audio/b.mp3": []byte("two")}}
+	tracks := fakeExportTracks{1: exportTrack(1, "Hunter", "audio/a.mp3"), 2: exportTrack(2, "Unravel", "audio/b.mp3")}
+	exporter := NewLibraryExporter(fakeExportLibrary{ids: []int64{1}}, tracks, objects, taggingRunner(), t.TempDir())
+	userID := uuid.New()
+
+	started, err := exporter.Start(userID, []int64{2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := waitForExport(t, exporter, userID, started.ID)
+	if status.Total != 2 || status.Added != 1 || status.Skipped != 1 {
+		t.Fatalf("status = %+v", status)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(objects.objects[status.ArchiveKey]), int64(status.SizeBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 1 || names[0] != "Björk/Homogenic/Hunter.mp3" {
+		t.Fatalf("archive holds %v", names)
+	}
+}
+
+func TestLibraryExporterCancelStopsRunningExport(t *testing.T) {
+	objects := &fakeExportObjects{deleted: make(chan string, 1), objects: map[string][]byte{"audio/a.mp3": []byte("one")}}
+	entered, release := make(chan struct{}), make(chan struct{})
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		close(entered)
+		<-release
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], []byte("tagged"), 0o600)
+	}
+	exporter := NewLibraryExporter(fakeExportLibrary{ids: []int64{1}}, fakeExportTracks{1: exportTrack(1, "Hunter", "audio/a.mp3")}, objects, runner, t.TempDir())
+	userID := uuid.New()
+
+	started, err := exporter.Start(userID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-entered
+	if _, err := exporter.Start(userID, nil); !errors.Is(err, ErrLibraryExportRunning) {
+		t.Fatalf("second Start = %v, want ErrLibraryExportRunning", err)
+	}
+	if !exporter.Cancel(userID, started.ID) {
+		t.Fatal("Cancel did not find the running export")
+	}
+	close(release)
+	status := waitForExport(t, exporter, userID, started.ID)
+	if status.State != LibraryExportCanceled || status.ArchiveKey != "" {
+		t.Fatalf("status = %+v, want canceled without an archive", status)
+	}
+	if _, ok := objects.objects[LibraryExportKey(userID, started.ID)]; ok {
+		t.Fatal("canceled export stored an archive")
+	}
+}
@@ -0,0 +1,463 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/audioprobe"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+const (
+	// libraryScanFileTimeout bounds reading one file's tags and copying it
+	// to object storage.
+	libraryScanFileTimeout = 10 * time.Minute
+	// libraryScanAttachEvery is how many queued files the parent job may
+	// fall behind by before it is brought up to date.
+	libraryScanAttachEvery = 25
+)
+
+// Library scan run states.
+const (
+	LibraryScanRunning   = "running"
+	LibraryScanCompleted = "completed"
+	LibraryScanCanceled  = "canceled"
+	LibraryScanFailed    = "failed"
+)
+
+// ErrLibraryScanRunning is returned when a run is started while another is
+// still going.
+var ErrLibraryScanRunning = errors.New("a library scan is already running")
+
+// ErrLibraryScanPath is returned for a path that is not a directory under
+// the import root.
+var ErrLibraryScanPath = errors.New("import path is not a directory under the import root")
+
+// libraryScanContentTypes maps the audio file extensions a scan picks up
+// to the content type they are stored with.
+var libraryScanContentTypes = map[string]string{
+	"flac": "audio/flac",
+	"mp3":  "audio/mpeg",
+	"m4a":  "audio/mp4",
+	"aac":  "audio/aac",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg",
+	"wav":  "audio/wav",
+	"aiff": "audio/aiff",
+	"aif":  "audio/aiff",
+}
+
+// LibraryScanTracks finds tracks already in the catalog;
+// db.TrackRepository satisfies it.
+type LibraryScanTracks interface {
+	FindByMetadata(ctx context.Context, artist, title, album string, durationMs int) (*db.Track, error)
+}
+
+// LibraryScanLibrary adds tracks to a user's library; db.LibraryRepository
+// satisfies it.
+type LibraryScanLibrary interface {
+	AddTrackToLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (*db.LibraryEntry, error)
+}
+
+// LibraryScanStorage stages scanned files for the processor.
+type LibraryScanStorage interface {
+	PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// LibraryScanJobs queues staged files; *download.Service satisfies it.
+type LibraryScanJobs interface {
+	CreateParentJob(ctx context.Context, userID, url, sourceType, title string) (*download.DownloadJob, error)
+	AttachChildJobs(ctx context.Context, parentID string, childIDs []string) (*download.DownloadJob, error)
+	EnqueueSourceCandidateWithID(ctx context.Context, jobID, userID string, candidate download.SourceCandidate, mbRecordingID *string) (*download.DownloadJob, error)
+}
+
+// LibraryScanStatus is a run's progress. Queued counts files staged and
+// queued for processing, Existing files whose tags matched a track already
+// in the catalog, which was added to the library directly, and Failed files
+// that could not be read or staged. The queued files are items of
+// ParentJobID, whose progress follows their processing.
+type LibraryScanStatus struct {
+	ID          string     `json:"id"`
+	State       string     `json:"state"`
+	UserID      string     `json:"userId"`
+	Path        string     `json:"path"`
+	ParentJobID string     `json:"parentJobId,omitempty"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Queued      int        `json:"queued"`
+	Existing    int        `json:"existing"`
+	Failed      int        `json:"failed"`
+	CurrentFile string     `json:"currentFile,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// LibraryScan brings a directory of existing music on the server into a
+// user's library. It reads each audio file's tags and looks the track up by
+// identity hash; a track already in the catalog is added to the library as
+// is, and any other file is copied to object storage and queued as an upload,
+// so it goes through the same processing as a download. Only one run exists
+// at a time; its progress goes to the admin who started it through the
+// report callback.
+type LibraryScan struct {
+	root    string
+	tracks  LibraryScanTracks
+	library LibraryScanLibrary
+	storage LibraryScanStorage
+	jobs    LibraryScanJobs
+	runner  ffmpeg.Runner
+	report  func(adminID uuid.UUID, status LibraryScanStatus)
+
+	mu      sync.Mutex
+	status  *LibraryScanStatus
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewLibraryScan creates an importer for directories under root. A nil
+// runner runs the system ffprobe.
+func NewLibraryScan(root string, tracks LibraryScanTracks, library LibraryScanLibrary, storage LibraryScanStorage, jobs LibraryScanJobs, runner ffmpeg.Runner) *LibraryScan {
+	if runner == nil {
+		runner = ffmpeg.NewExecRunner()
+	}
+	return &LibraryScan{root: root, tracks: tracks, library: library, storage: storage, jobs: jobs, runner: runner}
+}
+
+// SetReporter receives the run's status after every file and when it ends.
+func (l *LibraryScan) SetReporter(report func(adminID uuid.UUID, status LibraryScanStatus)) {
+	l.report = report
+}
+
+// Start begins importing the files under dir, a slash-separated path
+// relative to the import root, into userID's library. An empty dir imports
+// the whole root.
+func (l *LibraryScan) Start(adminID, userID uuid.UUID, dir string) (LibraryScanStatus, error) {
+	rel := strings.TrimPrefix(path.Clean("/"+dir), "/")
+	abs := filepath.Join(l.root, filepath.FromSlash(rel))
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return LibraryScanStatus{}, ErrLibraryScanPath
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running {
+		return *l.status, ErrLibraryScanRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.status = &LibraryScanStatus{ID: uuid.NewString(), State: LibraryScanRunning, UserID: userID.String(), Path: rel, StartedAt: time.Now()}
+	l.running = true
+	l.cancel = cancel
+	l.wg.Add(1)
+	go l.run(ctx, adminID, userID, abs)
+	return *l.status, nil
+}
+
+// Status returns the current or most recent run.
+func (l *LibraryScan) Status() (LibraryScanStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.status == nil {
+		return LibraryScanStatus{}, false
+	}
+	return *l.status, true
+}
+
+// Cancel stops the running run after its current file and reports whether
+// one was running. Files already queued still finish processing.
+func (l *LibraryScan) Cancel() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.running {
+		return false
+	}
+	l.cancel()
+	return true
+}
+
+// Stop cancels any run and waits for it to return.
+func (l *LibraryScan) Stop(ctx context.Context) error {
+	l.Cancel()
+	done := make(chan struct{})
+	go func() { l.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *LibraryScan) run(ctx context.Context, adminID, userID uuid.UUID, dir string) {
+	defer l.wg.Done()
+	files, err := collectLibraryScanFiles(dir)
+	if err != nil {
+		l.finish(adminID, err)
+		return
+	}
+	l.update(adminID, func(s *LibraryScanStatus) { s.Total = len(files) })
+
+	var parent *download.DownloadJob
+	var childIDs []string
+	attached := 0
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		rel, _ := filepath.Rel(dir, file)
+		l.update(adminID, func(s *LibraryScanStatus) { s.CurrentFile = filepath.ToSlash(rel) })
+
+		fileCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), libraryScanFileTimeout)
+		tags, err := readLibraryScanTags(fileCtx, l.runner, file)
+		outcome := "failed"
+		if err != nil {
+			log.Printf("Warning: library scan could not read %s: %v", file, err)
+		} else if l.addExisting(fileCtx, userID, tags) {
+			outcome = "existing"
+		} else {
+			if parent == nil {
+				if parent, err = l.createParent(fileCtx, userID); err == nil {
+					l.update(adminID, func(s *LibraryScanStatus) { s.ParentJobID = parent.ID })
+				}
+			}
+			if err == nil {
+				var jobID string
+				jobID, err = l.queueFile(download.WithParentJob(download.WithPriority(fileCtx, download.PriorityBatch), parent.ID), userID, file, rel, tags)
+				if err == nil {
+					childIDs = append(childIDs, jobID)
+					outcome = "queued"
+				}
+			}
+			if err != nil {
+				log.Printf("Warning: library scan could not queue %s: %v", file, err)
+			}
+		}
+		if len(childIDs)-attached >= libraryScanAttachEvery {
+			l.attach(fileCtx, parent.ID, childIDs)
+			attached = len(childIDs)
+		}
+		cancel()
+
+		l.update(adminID, func(s *LibraryScanStatus) {
+			s.Processed++
+			switch outcome {
+			case "queued":
+				s.Queued++
+			case "existing":
+				s.Existing++
+			default:
+				s.Failed++
+			}
+		})
+	}
+	if len(childIDs) > attached {
+		l.attach(context.WithoutCancel(ctx), parent.ID, childIDs)
+	}
+	l.finish(adminID, ctx.Err())
+}
+
+// collectLibraryScanFiles lists the audio files under dir, skipping hidden
+// files and directories and anything that is not a regular file, so the
+// run's total is known up front. Unreadable subdirectories are skipped.
+func collectLibraryScanFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if file == dir {
+				return err
+			}
+			log.Printf("Warning: library scan skipped %s: %v", file, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if file != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if _, ok := libraryScanContentTypes[libraryScanExt(file)]; ok {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func libraryScanExt(file string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
+}
+
+// libraryScanTags is what a file's tags say it is. Title falls back to the
+// file name.
+type libraryScanTags struct {
+	Title      string
+	Artist     string
+	Album      string
+	DurationMs int
+	// Tagged reports whether the title and artist both came from tags.
+	Tagged bool
+}
+
+// readLibraryScanTags reads a file's title, artist, album, and length with
+// ffprobe. A file ffprobe cannot read, or one with no audio stream, is an
+// error.
+func readLibraryScanTags(ctx context.Context, runner ffmpeg.Runner, file string) (libraryScanTags, error) {
+	info, err := audioprobe.Probe(ctx, runner, file)
+	if err != nil {
+		return libraryScanTags{}, err
+	}
+	result := libraryScanTags{
+		Title:      info.Tags["title"],
+		Artist:     firstNonEmpty(info.Tags["artist"], info.Tags["album_artist"]),
+		Album:      info.Tags["album"],
+		DurationMs: info.DurationMs,
+	}
+	result.Tagged = result.Title != "" && result.Artist != ""
+	if result.Title == "" {
+		result.Title = strings.TrimSpace(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	}
+	return result, nil
+}
+
+// addExisting adds the catalog track a tagged file's identity hash names to
+// the user's library and reports whether there was one. Untagged files are
+// always queued, since a file name is too weak to dedupe on before the
+// processor has matched the audio.
+func (l *LibraryScan) addExisting(ctx context.Context, userID uuid.UUID, tags libraryScanTags) bool {
+	if !tags.Tagged {
+		return false
+	}
+	track, err := l.tracks.FindByMetadata(ctx, tags.Artist, tags.Title, tags.Album, tags.DurationMs)
+	if err != nil {
+		if !errors.Is(err, db.ErrTrackNotFound) {
+			log.Printf("Warning: library scan track lookup failed: %v", err)
+		}
+		return false
+	}
+	if _, err := l.library.AddTrackToLibrary(ctx, userID, track.ID); err != nil && !errors.Is(err, db.ErrTrackAlreadyInLibrary) {
+		log.Printf("Warning: library scan failed to add track %d to library: %v", track.ID, err)
+		return false
+	}
+	return true
+}
+
+func (l *LibraryScan) createParent(ctx context.Context, userID uuid.UUID) (*download.DownloadJob, error) {
+	status, _ := l.Status()
+	title := "Library import"
+	if status.Path != "" {
+		title += ": " + status.Path
+	}
+	return l.jobs.CreateParentJob(download.WithPriority(ctx, download.PriorityBatch), userID.String(), "library-scan://"+status.ID, "library_scan", title)
+}
+
+// queueFile stages a file as an upload and queues the job that imports it.
+func (l *LibraryScan) queueFile(ctx context.Context, userID uuid.UUID, file, rel string, tags libraryScanTags) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxUploadedAudioBytes {
+		return "", fmt.Errorf("file too large: %d bytes", info.Size())
+	}
+	reader, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	ext := libraryScanExt(file)
+	contentType := libraryScanContentTypes[ext]
+	uploadID := uuid.NewString()
+	key := "uploads/" + userID.String() + "/" + uploadID + "." + ext
+	if err := l.storage.PutObject(ctx, key, reader, info.Size(), contentType); err != nil {
+		return "", err
+	}
+	candidate := download.SourceCandidate{
+		CandidateID: "upload:" + uploadID,
+		Provider:    "upload",
+		SourceID:    uploadID,
+		SourceURL:   download.UploadURLPrefix + key,
+		Title:       tags.Title,
+		Artist:      tags.Artist,
+		Album:       tags.Album,
+		DurationMs:  tags.DurationMs,
+		Metadata: map[string]interface{}{
+			"origin":      "library_scan",
+			"filename":    filepath.ToSlash(rel),
+			"contentType": contentType,
+		},
+	}
+	job, err := l.jobs.EnqueueSourceCandidateWithID(ctx, uuid.NewString(), userID.String(), candidate, nil)
+	if err != nil {
+		if deleteErr := l.storage.DeleteObject(context.WithoutCancel(ctx), key); deleteErr != nil {
+			log.Printf("Warning: failed to delete staged import %s: %v", key, deleteErr)
+		}
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// attach brings the parent job's items up to date. A failure only leaves
+// the parent short of items until the next attach.
+func (l *LibraryScan) attach(ctx context.Context, parentID string, childIDs []string) {
+	if _, err := l.jobs.AttachChildJobs(ctx, parentID, childIDs); err != nil {
+		log.Printf("Warning: library scan failed to attach items to job %s: %v", parentID, err)
+	}
+}
+
+func (l *LibraryScan) update(adminID uuid.UUID, change func(*LibraryScanStatus)) {
+	l.mu.Lock()
+	change(l.status)
+	status := *l.status
+	l.mu.Unlock()
+	if l.report != nil {
+		l.report(adminID, status)
+	}
+}
+
+func (l *LibraryScan) finish(adminID uuid.UUID, err error) {
+	l.mu.Lock()
+	l.running = false
+	l.cancel()
+	now := time.Now()
+	l.status.FinishedAt = &now
+	l.status.CurrentFile = ""
+	switch {
+	case errors.Is(err, context.Canceled):
+		l.status.State = LibraryScanCanceled
+	case err != nil:
+		l.status.State = LibraryScanFailed
+		l.status.Error = err.Error()
+	default:
+		l.status.State = LibraryScanCompleted
+	}
+	status := *l.status
+	l.mu.Unlock()
+	if l.report != nil {
+		l.report(adminID, status)
+	}
+	log.Printf("Library scan %s %s: %d of %d files processed (%d queued, %d existing, %d failed)",
+		status.ID, status.State, status.Processed, status.Total, status.Queued, status.Existing, status.Failed)
+}
@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+// fakeScanTracks has one catalog track per "artist|title" key.
+type fakeScanTracks map[string]int64
+
+func (f fakeScanTracks) FindByMetadata(_ context.Context, artist, title, _ string, _ int) (*db.Track, error) {
+	id, ok := f[artist+"|"+title]
+	if !ok {
+		return nil, db.ErrTrackNotFound
+	}
+	return &db.Track{ID: id}, nil
+}
+
+type fakeScanLibrary struct {
+	added []int64
+}
+
+func (f *fakeScanLibrary) AddTrackToLibrary(_ context.Context, _ uuid.UUID, trackID int64) (*db.LibraryEntry, error) {
+	f.added = append(f.added, trackID)
+	return &db.LibraryEntry{}, nil
+}
+
+type fakeScanStorage struct {
+	objects map[string]string
+}
+
+func (f *fakeScanStorage) PutObject(_ context.Context, key string, reader io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	f.objects[key] = string(data)
+	return nil
+}
+
+func (f *fakeScanStorage) DeleteObject(_ context.Context, key string) error {
+	delete(f.objects, key)
+	return nil
+}
+
+type fakeScanJobs struct {
+	parents    []string
+	candidates []download.SourceCandidate
+	priorities []string
+	attached   []string
+}
+
+func (f *fakeScanJobs) CreateParentJob(_ context.Context, _, _, sourceType, title string) (*download.DownloadJob, error) {
+	f.parents = append(f.parents, title)
+	return &download.DownloadJob{ID: "parent-1", SourceType: sourceType, Title: title}, nil
+}
+
+func (f *fakeScanJobs) AttachChildJobs(_ context.Context, parentID string, childIDs []string) (*download.DownloadJob, error) {
+	f.attached = append([]string(nil), childIDs...)
+	return &download.DownloadJob{ID: parentID, ChildJobIDs: childIDs}, nil
+}
+
+func (f *fakeScanJobs) EnqueueSourceCandidateWithID(ctx context.Context, jobID, userID string, candidate download.SourceCandidate, _ *string) (*download.DownloadJob, error) {
+	f.candidates = append(f.candidates, candidate)
+	f.priorities = append(f.priorities, download.PriorityFromContext(ctx))
+	return &download.DownloadJob{ID: jobID, UserID: userID, URL: candidate.SourceURL}, nil
+}
+
+func writeScanFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	file := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForLibraryScan(t *testing.T, l *LibraryScan) LibraryScanStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := l.Status(); ok && status.State != LibraryScanRunning {
+			return status
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("library import did not finish")
+	return LibraryScanStatus{}
+}
+
+func TestLibraryScanQueuesNewFilesAndAddsKnownTracks(t *testing.T) {
+	root := t.TempDir()
+	writeScanFile(t, root, "Band/Album/01 Known.flac", "known")
+	writeScanFile(t, root, "Band/Album/02 New.FLAC", "new audio")
+	writeScanFile(t, root, "Band/Album/03 Untagged.mp3", "untagged")
+	writeScanFile(t, root, "Band/Album/cover.jpg", "image")
+	writeScanFile(t, root, "Band/Album/broken.ogg", "not audio")
+	writeScanFile(t, root, "Band/.trash/old.mp3", "hidden")
+	writeScanFile(t, root, "Other/skip.mp3", "outside the scanned directory")
+
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		file := call.Args[len(call.Args)-1]
+		switch filepath.Base(file) {
+		case "01 Known.flac":
+			return ffmpeg.Output{Stdout: `{"streams":[{"codec_name":"flac","bit_rate":"","sample_rate":"44100","channels":2}],"format":{"duration":"200.5","tags":{"TITLE":"Known","ARTIST":"Band"}}}`}, nil
+		case "02 New.FLAC":
+			return ffmpeg.Output{Stdout: `{"streams":[{"codec_name":"flac","bit_rate":"","sample_rate":"44100","channels":2}],"format":{"duration":"180.25","tags":{"title":"New","album_artist":"Band","album":"Album"}}}`}, nil
+		case "03 Untagged.mp3":
+			return ffmpeg.Output{Stdout: `{"streams":[{"codec_name":"mp3","bit_rate":"192000","sample_rate":"44100","channels":2}],"format":{"duration":"90"}}`}, nil
+		}
+		return ffmpeg.Output{}, fmt.Errorf("invalid data found when processing input")
+	}
+	library := &fakeScanLibrary{}
+	storage := &fakeScanStorage{objects: map[string]string{}}
+	jobs := &fakeScanJobs{}
+	l := NewLibraryScan(root, fakeScanTracks{"Band|Known": 7}, library, storage, jobs, runner)
+	userID := uuid.New()
+
+	if _, err := l.Start(uuid.New(), userID, "../Band"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := waitForLibraryScan(t, l)
+	if status.State != LibraryScanCompleted || status.Path != "Band" || status.Total != 4 || status.Processed != 4 {
+		t.Fatalf("status = %+v, want the four audio files under Band processed", status)
+	}
+	if status.Queued != 2 || status.Existing != 1 || status.Failed != 1 || status.ParentJobID != "parent-1" {
+		t.Fatalf("outcomes = %+v", status)
+	}
+	if len(library.added) != 1 || library.added[0] != 7 {
+		t.Fatalf("added = %v, want the known track added directly", library.added)
+	}
+
+	if len(jobs.candidates) != 2 || len(jobs.attached) != 2 || len(jobs.parents) != 1 || jobs.parents[0] != "Library import: Band" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	tagged, untagged := jobs.candidates[0], jobs.candidates[1]
+	if tagged.Title != "New" || tagged.Artist != "Band" || tagged.Album != "Album" || tagged.DurationMs != 180250 || tagged.Provider != "upload" {
+		t.Fatalf("tagged candidate = %+v", tagged)
+	}
+	if untagged.Title != "03 Untagged" || untagged.Artist != "" || untagged.Metadata["filename"] != "Album/03 Untagged.mp3" {
+		t.Fatalf("untagged candidate = %+v", untagged)
+	}
+	key := strings.TrimPrefix(tagged.SourceURL, download.UploadURLPrefix)
+	if !strings.HasPrefix(key, "uploads/"+userID.String()+"/") || !strings.HasSuffix(key, ".flac") || storage.objects[key] != "new audio" {
+		t.Fatalf("staged %q = %q, want the file under the user's uploads", key, storage.objects[key])
+	}
+	if jobs.priorities[0] != download.PriorityBatch {
+		t.Fatalf("priority = %q, want batch", jobs.priorities[0])
+	}
+}
+
+func TestLibraryScanRejectsMissingDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeScanFile(t, root, "song.mp3", "audio")
+	l := NewLibraryScan(root, fakeScanTracks{}, &fakeScanLibrary{}, &fakeScanStorage{}, &fakeScanJobs{}, ffmpeg.NewFake())
+	for _, dir := range []string{"missing", "song.mp3"} {
+		if _, err := l.Start(uuid.New(), uuid.New(), dir); err != ErrLibraryScanPath {
+			t.Fatalf("Start(%q) error = %v, want ErrLibraryScanPath", dir, err)
+		}
+	}
+}
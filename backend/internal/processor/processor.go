@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
@@ -21,11 +22,17 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/openmusicplayer/backend/internal/analyzer"
+	"github.com/openmusicplayer/backend/internal/audioprobe"
+	"github.com/openmusicplayer/backend/internal/chapters"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/fingerprint"
 	"github.com/openmusicplayer/backend/internal/matcher"
 	"github.com/openmusicplayer/backend/internal/playlistimport"
 	"github.com/openmusicplayer/backend/internal/storage"
+	"github.com/openmusicplayer/backend/internal/tagger"
+	"github.com/openmusicplayer/backend/internal/waveform"
 )
 
 // ObjectStorage is the small MinIO surface the processor needs. storage.Client
@@ -44,6 +51,13 @@ type AnalysisStore interface {
 	MarkUnsupported(ctx context.Context, trackID int64, errText string, provenance json.RawMessage) error
 }
 
+// TakedownChecker rejects downloads of content under an active takedown.
+// db.TakedownRepository satisfies it.
+type TakedownChecker interface {
+	FindActive(ctx context.Context, sourceURL, identityHash string) (*db.ContentTakedown, error)
+	QuarantineTrack(ctx context.Context, trackID, takedownID int64) error
+}
+
 const (
 	maxYTDLPOutputBytes             = 256 * 1024 * 1024
 	maxYTDLPLogBytes                = 64 * 1024
@@ -53,6 +67,20 @@ const (
 	analysisShutdownRecoveryTimeout = 2 * time.Second
 	audioQualityProbeTimeout        = 45 * time.Second
 	audioQualityRepairTimeout       = 45 * time.Second
+	audioLoudnessTimeout            = 45 * time.Second
+	// clippingPeakDBFS is the sample peak at or above which near-full-scale
+	// samples are counted as clipped.
+	clippingPeakDBFS = -0.1
+)
+
+const (
+	// ytdlpProgressPrefix marks the progress lines yt-dlp prints through
+	// --progress-template, so they can be told apart from its log output.
+	ytdlpProgressPrefix = "omp-progress "
+	// downloadProgressStart and downloadProgressEnd bound the share of job
+	// progress the source download covers.
+	downloadProgressStart = 5
+	downloadProgressEnd   = 25
 )
 
 type analysisTask struct {
@@ -85,6 +113,19 @@ type Processor struct {
 	expectedAnalyzer        string
 	expectedAnalyzerVersion string
 	storage                 ObjectStorage
+	takedowns               TakedownChecker
+	downloadSettings        DownloadSettingsStore
+	playbackQueue           PlaybackQueue
+	webhook                 *Webhook
+	exportDir               string
+	fingerprinter           Fingerprinter
+	chapterSource           ChapterSource
+	trackArtwork            TrackArtworkStore
+	trackWaveforms          TrackWaveformStore
+	tagger                  *tagger.Tagger
+	releaseCovers           ReleaseCoverSource
+	tempDir                 string
+	media                   ffmpeg.Runner
 }
 
 // ProcessorConfig holds configuration for the processor
@@ -101,6 +142,37 @@ type ProcessorConfig struct {
 	AnalysisConcurrency     int
 	RequireAnalyzerIdentity bool
 	Storage                 ObjectStorage
+	Takedowns               TakedownChecker
+	// DownloadSettings decides where finished downloads go; nil adds every
+	// download to the library only.
+	DownloadSettings DownloadSettingsStore
+	// Webhook, when set, is told about every completed download.
+	Webhook *Webhook
+	// ExportDir, when set, receives a tagged copy of every completed
+	// download for external library managers to pick up.
+	ExportDir string
+	// Fingerprinter, when set, identifies downloads by their audio so the
+	// matcher does not depend on the title alone.
+	Fingerprinter Fingerprinter
+	// ChapterSource, when set, supplies SponsorBlock segments and chapters
+	// for long YouTube downloads; silence detection runs without it.
+	ChapterSource ChapterSource
+	// TrackArtwork, when set, enables extracting cover art embedded in
+	// downloads as a fallback for tracks without a MusicBrainz release.
+	TrackArtwork TrackArtworkStore
+	// TrackWaveforms, when set, enables generating the seek bar peaks of
+	// each download and records where they are stored.
+	TrackWaveforms TrackWaveformStore
+	// TagAudio rewrites each new track's stored audio with its matched
+	// title, artist, album, MusicBrainz IDs, and cover once matching ends.
+	TagAudio bool
+	// ReleaseCovers supplies the cover TagAudio writes for tracks on a
+	// MusicBrainz release; nil uses the cover embedded in the download.
+	ReleaseCovers ReleaseCoverSource
+	// TempDir holds per-job scratch directories; empty uses os.TempDir.
+	TempDir string
+	// FFmpeg runs ffmpeg and ffprobe; nil uses the binaries on PATH.
+	FFmpeg ffmpeg.Runner
 }
 
 // New creates a new Processor instance
@@ -124,6 +196,20 @@ func New(config *ProcessorConfig) *Processor {
 		analyzerClient:          config.AnalyzerClient,
 		requireAnalyzerIdentity: config.RequireAnalyzerIdentity,
 		storage:                 config.Storage,
+		takedowns:               config.Takedowns,
+		downloadSettings:        config.DownloadSettings,
+		webhook:                 config.Webhook,
+		exportDir:               config.ExportDir,
+		fingerprinter:           config.Fingerprinter,
+		chapterSource:           config.ChapterSource,
+		trackArtwork:            config.TrackArtwork,
+		trackWaveforms:          config.TrackWaveforms,
+		releaseCovers:           config.ReleaseCovers,
+		tempDir:                 config.TempDir,
+		media:                   config.FFmpeg,
+	}
+	if config.TagAudio {
+		processor.tagger = tagger.New(config.FFmpeg)
 	}
 	if processor.analysisRepo != nil && processor.analyzerClient != nil {
 		processor.analysisCtx, processor.analysisCancel = context.WithCancel(context.Background())
@@ -155,10 +241,13 @@ func (p *Processor) Process(ctx context.Context, job *download.DownloadJob, prog
 			p.markPlaylistImportFailed(ctx, job, err)
 		}
 	}()
-	log.Printf("Processing job %s: downloading from %s", job.ID, job.URL)
-	progress(5)
+	if err := p.checkTakedown(ctx, job, nil); err != nil {
+		return err
+	}
+	log.Printf("Processing job %s (request_id=%s): downloading from %s", job.ID, job.RequestID, job.URL)
+	progress(downloadProgressStart)
 
-	metadata, err := p.downloadAndStore(ctx, job)
+	metadata, err := p.downloadAndStore(ctx, job, stageProgress(progress, downloadProgressStart, downloadProgressEnd))
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
@@ -182,8 +271,13 @@ func (p *Processor) Process(ctx context.Context, job *download.DownloadJob, prog
 			}
 		}
 	}
+	if err := p.checkTakedown(ctx, job, track); err != nil {
+		return err
+	}
 	job.TrackID = &track.ID
 	p.recordTrackSource(ctx, job, track.ID)
+	p.recordSourceQuality(ctx, job, track.ID, metadata)
+	p.storeArtwork(ctx, track, metadata)
 	progress(65)
 
 	if p.matcher != nil {
@@ -192,17 +286,23 @@ func (p *Processor) Process(ctx context.Context, job *download.DownloadJob, prog
 			log.Printf("Warning: matching failed for job %s: %v", job.ID, err)
 		}
 	}
+	if isNew {
+		p.tagStoredAudio(ctx, job, track, metadata)
+		p.storeReplayGain(ctx, job, track, metadata)
+		p.storeChapters(ctx, job, track, metadata)
+		p.storeWaveform(ctx, job, track, metadata)
+	}
 	progress(80)
 
 	log.Printf("Processing job %s: adding to library", job.ID)
 	job.Status = download.StatusUploading
-	if err := p.addToLibrary(ctx, job.UserID, track.ID); err != nil {
-		log.Printf("Warning: failed to add track %d to library: %v", track.ID, err)
-	}
+	p.deliver(ctx, job, track.ID, p.resolveDestination(ctx, job))
 	if err := p.attachPlaylistImportTrack(ctx, job, track.ID); err != nil {
 		return fmt.Errorf("playlist import attach failed: %w", err)
 	}
 	p.enqueueAnalysis(ctx, track, metadata)
+	p.removeStagedUpload(ctx, job)
+	p.postProcess(ctx, job, track, isNew)
 	progress(95)
 
 	log.Printf("Processing job %s: complete (track_id=%d, is_new=%v)", job.ID, track.ID, isNew)
@@ -210,6 +310,35 @@ func (p *Processor) Process(ctx context.Context, job *download.DownloadJob, prog
 	return nil
 }
 
+// checkTakedown fails the job when its source URL, or the track it resolved
+// to, is under an active takedown. A blocked track is quarantined so the new
+// copy never becomes streamable.
+func (p *Processor) checkTakedown(ctx context.Context, job *download.DownloadJob, track *db.Track) error {
+	if p.takedowns == nil {
+		return nil
+	}
+	identityHash := ""
+	if track != nil {
+		if track.QuarantinedAt.Valid {
+			return fmt.Errorf("%w: track %d is quarantined", db.ErrContentTakenDown, track.ID)
+		}
+		identityHash = track.IdentityHash
+	}
+	takedown, err := p.takedowns.FindActive(ctx, job.URL, identityHash)
+	if err != nil {
+		return fmt.Errorf("takedown check failed: %w", err)
+	}
+	if takedown == nil {
+		return nil
+	}
+	if track != nil {
+		if err := p.takedowns.QuarantineTrack(ctx, track.ID, takedown.ID); err != nil {
+			log.Printf("Warning: failed to quarantine track %d under takedown %d: %v", track.ID, takedown.ID, err)
+		}
+	}
+	return fmt.Errorf("%w: %s", db.ErrContentTakenDown, takedown.Reason)
+}
+
 // TrackMetadata holds extracted metadata from a download
 type TrackMetadata struct {
 	Title           string
@@ -222,12 +351,39 @@ type TrackMetadata struct {
 	StorageKey      string
 	FileSizeBytes   int64
 	AudioQuality    AudioQuality
+	Loudness        *Loudness
+	R128            *R128Loudness
+	Chapters        *chapters.Chapters
+	Waveform        *waveform.Waveform
 	PreselectedMBID string
+	Fingerprints    []fingerprint.Match
+	Artwork         []byte
 	Raw             map[string]interface{}
 	Cleanup         deterministicCleanup
 }
 
-func (p *Processor) downloadAndStore(ctx context.Context, job *download.DownloadJob) (*TrackMetadata, error) {
+// stageProgress maps a stage's own 0-100 percent onto [from, to] of the job's
+// progress, reporting only whole-point increases.
+func stageProgress(progress func(int), from, to int) func(float64) {
+	last := from
+	return func(percent float64) {
+		if percent < 0 {
+			percent = 0
+		} else if percent > 100 {
+			percent = 100
+		}
+		value := from + int(percent*float64(to-from)/100)
+		if value <= last {
+			return
+		}
+		last = value
+		progress(value)
+	}
+}
+
+// downloadAndStore fetches the job's audio, reporting the download's percent
+// through downloadProgress when the source provides one, and uploads it.
+func (p *Processor) downloadAndStore(ctx context.Context, job *download.DownloadJob, downloadProgress func(float64)) (*TrackMetadata, error) {
 	if p.storage == nil {
 		return nil, fmt.Errorf("object storage is not configured")
 	}
@@ -258,11 +414,21 @@ func (p *Processor) downloadAndStore(ctx context.Context, job *download.Download
 		metadata.PreselectedMBID = *job.MBRecordingID
 	}
 
-	tmpPath, contentType, err := p.obtainAudioFile(ctx, job, metadata)
+	// Every scratch file for this job lives under one directory so success,
+	// failure, and cancellation all clean up with a single RemoveAll.
+	jobDir, err := os.MkdirTemp(p.tempDir, "omp-job-*")
+	if err != nil {
+		return nil, fmt.Errorf("create job temp dir: %w", err)
+	}
+	defer os.RemoveAll(jobDir)
+
+	tmpPath, contentType, err := p.obtainAudioFile(ctx, jobDir, job, metadata, downloadProgress)
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(tmpPath)
+	if downloadProgress != nil {
+		downloadProgress(100)
+	}
 
 	info, err := os.Stat(tmpPath)
 	if err != nil {
@@ -280,10 +446,24 @@ func (p *Processor) downloadAndStore(ctx context.Context, job *download.Download
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
-	quality, err := probeAudioFile(ctx, tmpPath, contentType)
+	quality, err := probeAudioFile(ctx, p.mediaRunner(), tmpPath, contentType)
 	if err != nil {
 		return nil, fmt.Errorf("probe downloaded audio: %w", err)
 	}
+	loudness, err := measureLoudness(ctx, p.mediaRunner(), tmpPath)
+	if err != nil {
+		log.Printf("Warning: loudness measurement failed for job %s: %v", job.ID, err)
+	}
+	metadata.Loudness = loudness
+	r128, err := measureR128Loudness(ctx, p.mediaRunner(), tmpPath)
+	if err != nil {
+		log.Printf("Warning: R128 loudness measurement failed for job %s: %v", job.ID, err)
+	}
+	metadata.R128 = r128
+	p.detectChapters(ctx, job, tmpPath, metadata)
+	p.generateWaveform(ctx, job, jobDir, tmpPath, quality.DurationMs, metadata)
+	p.identifyAudio(ctx, job, tmpPath, metadata)
+	p.extractArtwork(ctx, job, jobDir, tmpPath, metadata)
 	key := storageKey(job, tmpPath)
 	if err := p.storage.PutObject(ctx, key, file, info.Size(), quality.ContentType); err != nil {
 		return nil, fmt.Errorf("upload audio to object storage: %w", err)
@@ -291,6 +471,11 @@ func (p *Processor) downloadAndStore(ctx context.Context, job *download.Download
 	metadata.StorageKey = key
 	metadata.FileSizeBytes = info.Size()
 	metadata.AudioQuality = quality
+	if quality.DurationMs > 0 {
+		// Sources often omit the length or round it, so the measured one
+		// is what the track stores.
+		metadata.DurationMs = quality.DurationMs
+	}
 	return metadata, nil
 }
 
@@ -301,61 +486,32 @@ type AudioQuality struct {
 	SampleRateHz int    `json:"sampleRateHz"`
 	Channels     int    `json:"channels"`
 	ContentType  string `json:"contentType"`
+	// DurationMs is the measured length; zero when ffprobe could not tell.
+	DurationMs int `json:"durationMs,omitempty"`
 }
 
-type ffprobeOutput struct {
-	Streams []struct {
-		CodecName  string `json:"codec_name"`
-		BitRate    string `json:"bit_rate"`
-		SampleRate string `json:"sample_rate"`
-		Channels   int    `json:"channels"`
-	} `json:"streams"`
-	Format struct {
-		BitRate    string `json:"bit_rate"`
-		FormatName string `json:"format_name"`
-	} `json:"format"`
+func (p *Processor) mediaRunner() ffmpeg.Runner {
+	if p.media == nil {
+		return ffmpeg.NewExecRunner()
+	}
+	return p.media
 }
 
-func probeAudioFile(ctx context.Context, path, fallbackContentType string) (AudioQuality, error) {
+func probeAudioFile(ctx context.Context, runner ffmpeg.Runner, path, fallbackContentType string) (AudioQuality, error) {
 	probeCtx, cancel := context.WithTimeout(ctx, audioQualityProbeTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(probeCtx, "ffprobe",
-		"-v", "error",
-		"-select_streams", "a:0",
-		"-show_entries", "stream=codec_name,bit_rate,sample_rate,channels:format=bit_rate,format_name",
-		"-of", "json",
-		path,
-	)
-	stdout := limitedOutput{limit: maxYTDLPLogBytes}
-	stderr := limitedOutput{limit: maxYTDLPLogBytes}
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		if probeCtx.Err() != nil {
-			return AudioQuality{}, fmt.Errorf("ffprobe timed out or canceled: %w", probeCtx.Err())
-		}
-		return AudioQuality{}, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
-	}
-	var probed ffprobeOutput
-	if err := json.Unmarshal([]byte(stdout.String()), &probed); err != nil {
-		return AudioQuality{}, fmt.Errorf("decode ffprobe output: %w", err)
-	}
-	if len(probed.Streams) == 0 {
-		return AudioQuality{}, errors.New("ffprobe found no audio stream")
-	}
-	stream := probed.Streams[0]
-	sampleRate, _ := strconv.Atoi(stream.SampleRate)
-	bitRate, _ := strconv.ParseInt(stream.BitRate, 10, 64)
-	if bitRate <= 0 {
-		bitRate, _ = strconv.ParseInt(probed.Format.BitRate, 10, 64)
+	info, err := audioprobe.Probe(probeCtx, runner, path)
+	if err != nil {
+		return AudioQuality{}, err
 	}
 	quality := AudioQuality{
-		Codec:        stream.CodecName,
-		BitrateKbps:  int((bitRate + 500) / 1000),
-		SampleRateHz: sampleRate,
-		Channels:     stream.Channels,
-		ContentType:  audioContentType(stream.CodecName, probed.Format.FormatName, fallbackContentType),
+		Codec:        info.Codec,
+		BitrateKbps:  info.BitrateKbps,
+		SampleRateHz: info.SampleRateHz,
+		Channels:     info.Channels,
+		ContentType:  audioContentType(info.Codec, info.FormatName, fallbackContentType),
+		DurationMs:   info.DurationMs,
 	}
 	if quality.Codec == "" || quality.BitrateKbps <= 0 || quality.SampleRateHz <= 0 || quality.Channels <= 0 {
 		return AudioQuality{}, fmt.Errorf("ffprobe returned incomplete audio stream facts")
@@ -363,6 +519,67 @@ func probeAudioFile(ctx context.Context, path, fallbackContentType string) (Audi
 	return quality, nil
 }
 
+// Loudness is the sample-peak summary of one stored artifact. ClippingRatio is
+// the share of samples within 1 dB of full scale when the peak reaches it.
+type Loudness struct {
+	PeakDBFS      float64 `json:"peakDbfs"`
+	ClippingRatio float64 `json:"clippingRatio"`
+}
+
+func measureLoudness(ctx context.Context, runner ffmpeg.Runner, path string) (*Loudness, error) {
+	measureCtx, cancel := context.WithTimeout(ctx, audioLoudnessTimeout)
+	defer cancel()
+
+	args := ffmpeg.NewCommand().
+		LogLevel("info").
+		Input(path).
+		Map("0:a:0").
+		AudioFilter("volumedetect").
+		Format("null").
+		Args("-")
+	out, err := runner.FFmpeg(measureCtx, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg volumedetect: %w", err)
+	}
+	return parseVolumeDetect(out.Stderr)
+}
+
+// parseVolumeDetect reads the n_samples, max_volume, and histogram_0db lines
+// ffmpeg's volumedetect filter writes to stderr.
+func parseVolumeDetect(output string) (*Loudness, error) {
+	var samples, fullScale int64
+	peak, havePeak := 0.0, false
+	for _, line := range strings.Split(output, "\n") {
+		idx := strings.Index(line, "] ")
+		if idx < 0 || !strings.Contains(line[:idx], "volumedetect") {
+			continue
+		}
+		name, value, ok := strings.Cut(line[idx+2:], ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "dB"))
+		switch strings.TrimSpace(name) {
+		case "n_samples":
+			samples, _ = strconv.ParseInt(value, 10, 64)
+		case "max_volume":
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				peak, havePeak = parsed, true
+			}
+		case "histogram_0db":
+			fullScale, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	if !havePeak || samples <= 0 {
+		return nil, errors.New("volumedetect reported no samples")
+	}
+	loudness := &Loudness{PeakDBFS: peak}
+	if peak >= clippingPeakDBFS && fullScale > 0 {
+		loudness.ClippingRatio = float64(fullScale) / float64(samples)
+	}
+	return loudness, nil
+}
+
 func audioContentType(codec, formatName, fallback string) string {
 	switch strings.ToLower(codec) {
 	case "mp3":
@@ -391,22 +608,30 @@ func audioContentType(codec, formatName, fallback string) string {
 	return "application/octet-stream"
 }
 
-func (p *Processor) obtainAudioFile(ctx context.Context, job *download.DownloadJob, metadata *TrackMetadata) (string, string, error) {
+func (p *Processor) obtainAudioFile(ctx context.Context, dir string, job *download.DownloadJob, metadata *TrackMetadata, downloadProgress func(float64)) (string, string, error) {
 	if strings.HasPrefix(job.URL, "fixture://") || job.SourceType == "fixture" {
-		return writeFixtureWAV(job.ID)
+		return writeFixtureWAV(dir, job.ID)
+	}
+	if key, ok := uploadKey(job); ok {
+		return p.fetchUploadedObject(ctx, dir, key)
 	}
 	if strings.HasPrefix(job.URL, "file://") {
 		path := strings.TrimPrefix(job.URL, "file://")
 		if path == "" {
 			return "", "", fmt.Errorf("empty file URL")
 		}
-		return copyToBoundedTemp(path, 256*1024*1024)
+		return copyToBoundedTemp(dir, path, 256*1024*1024)
 	}
-	return runYTDLP(ctx, job.URL, metadata)
+	return runYTDLP(ctx, dir, job.URL, metadata, downloadProgress)
 }
 
-func writeFixtureWAV(jobID string) (string, string, error) {
-	path := filepath.Join(os.TempDir(), "omp-fixture-"+jobID+".wav")
+// writeFixtureWAV writes a silent test WAV into dir; an empty dir uses
+// os.TempDir.
+func writeFixtureWAV(dir, jobID string) (string, string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	path := filepath.Join(dir, "omp-fixture-"+jobID+".wav")
 	file, err := os.Create(path)
 	if err != nil {
 		return "", "", err
@@ -460,7 +685,7 @@ func writeFixtureWAV(jobID string) (string, string, error) {
 	return path, "audio/wav", nil
 }
 
-func copyToBoundedTemp(source string, maxBytes int64) (string, string, error) {
+func copyToBoundedTemp(dir, source string, maxBytes int64) (string, string, error) {
 	in, err := os.Open(source)
 	if err != nil {
 		return "", "", err
@@ -473,7 +698,7 @@ func copyToBoundedTemp(source string, maxBytes int64) (string, string, error) {
 	if info.Size() > maxBytes {
 		return "", "", fmt.Errorf("downloaded file too large: %d bytes", info.Size())
 	}
-	out, err := os.CreateTemp("", "omp-download-*"+filepath.Ext(source))
+	out, err := os.CreateTemp(dir, "omp-download-*"+filepath.Ext(source))
 	if err != nil {
 		return "", "", err
 	}
@@ -486,40 +711,51 @@ func copyToBoundedTemp(source string, maxBytes int64) (string, string, error) {
 	return outPath, mime.TypeByExtension(filepath.Ext(source)), nil
 }
 
-func runYTDLP(ctx context.Context, sourceURL string, metadata *TrackMetadata) (string, string, error) {
-	return runYTDLPCommand(ctx, "yt-dlp", sourceURL, metadata, maxYTDLPOutputBytes)
+func runYTDLP(ctx context.Context, tempDir, sourceURL string, metadata *TrackMetadata, downloadProgress func(float64)) (string, string, error) {
+	return runYTDLPCommand(ctx, "yt-dlp", tempDir, sourceURL, metadata, maxYTDLPOutputBytes, downloadProgress)
 }
 
-func runYTDLPCommand(ctx context.Context, executable, sourceURL string, metadata *TrackMetadata, maxBytes int64) (string, string, error) {
+// runYTDLPCommand downloads sourceURL with yt-dlp. The process is started
+// with ctx, so cancelling the job kills it, and is held to the rate limit ctx
+// carries.
+func runYTDLPCommand(ctx context.Context, executable, tempDir, sourceURL string, metadata *TrackMetadata, maxBytes int64, downloadProgress func(float64)) (string, string, error) {
 	if _, err := exec.LookPath(executable); err != nil {
 		return "", "", fmt.Errorf("yt-dlp is not installed")
 	}
-	dir, err := os.MkdirTemp("", "omp-ytdlp-*")
+	dir, err := os.MkdirTemp(tempDir, "omp-ytdlp-*")
 	if err != nil {
 		return "", "", err
 	}
 	defer os.RemoveAll(dir)
 
 	outputTemplate := filepath.Join(dir, "audio.%(ext)s")
-	cmd := exec.CommandContext(ctx, executable, "--no-playlist", "--max-filesize", fmt.Sprintf("%d", maxBytes), "--extract-audio", "--audio-format", "mp3", "--write-info-json", "--no-progress", "-o", outputTemplate, sourceURL)
+	args := []string{"--no-playlist", "--max-filesize", fmt.Sprintf("%d", maxBytes), "--extract-audio", "--audio-format", "mp3", "--embed-thumbnail", "--write-info-json", "--newline", "--progress-template", "download:" + ytdlpProgressPrefix + "%(progress._percent_str)s"}
+	if rate := download.RateLimitFromContext(ctx); rate > 0 {
+		args = append(args, "--limit-rate", fmt.Sprintf("%d", rate))
+	}
+	args = append(args, "-o", outputTemplate, sourceURL)
+	cmd := exec.CommandContext(ctx, executable, args...)
 	var output limitedOutput
 	output.limit = maxYTDLPLogBytes
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-	if err := cmd.Run(); err != nil {
+	lines := &ytdlpOutput{log: &output, progress: downloadProgress}
+	cmd.Stdout = lines
+	cmd.Stderr = lines
+	err = cmd.Run()
+	lines.flush()
+	if err != nil {
 		return "", "", fmt.Errorf("yt-dlp failed: %w: %s", err, strings.TrimSpace(output.String()))
 	}
-	return collectYTDLPOutput(dir, metadata, maxBytes)
+	return collectYTDLPOutput(dir, tempDir, metadata, maxBytes)
 }
 
-func collectYTDLPOutput(dir string, metadata *TrackMetadata, maxBytes int64) (string, string, error) {
+func collectYTDLPOutput(dir, tempDir string, metadata *TrackMetadata, maxBytes int64) (string, string, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return "", "", err
 	}
 	var audioPath string
 	for _, entry := range entries {
-		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".json") {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".json") || isThumbnailFile(entry.Name()) {
 			continue
 		}
 		audioPath = filepath.Join(dir, entry.Name())
@@ -534,7 +770,7 @@ func collectYTDLPOutput(dir string, metadata *TrackMetadata, maxBytes int64) (st
 			break
 		}
 	}
-	path, contentType, err := copyToBoundedTemp(audioPath, maxBytes)
+	path, contentType, err := copyToBoundedTemp(tempDir, audioPath, maxBytes)
 	if err != nil {
 		return "", "", err
 	}
@@ -544,6 +780,16 @@ func collectYTDLPOutput(dir string, metadata *TrackMetadata, maxBytes int64) (st
 	return path, contentType, nil
 }
 
+// isThumbnailFile reports a thumbnail yt-dlp left behind, e.g. when it could
+// not embed it, so it is never mistaken for the audio.
+func isThumbnailFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return true
+	}
+	return false
+}
+
 type limitedOutput struct {
 	buf       strings.Builder
 	limit     int
@@ -572,6 +818,50 @@ func (o *limitedOutput) String() string {
 	return o.buf.String()
 }
 
+// ytdlpOutput splits yt-dlp's output into lines, reporting progress lines
+// and passing everything else to log.
+type ytdlpOutput struct {
+	log      io.Writer
+	progress func(float64)
+	partial  []byte
+}
+
+func (o *ytdlpOutput) Write(p []byte) (int, error) {
+	o.partial = append(o.partial, p...)
+	for {
+		end := bytes.IndexByte(o.partial, '\n')
+		if end < 0 {
+			break
+		}
+		o.line(o.partial[:end+1])
+		o.partial = o.partial[end+1:]
+	}
+	// A line longer than the log limit is not a progress line; pass it on
+	// rather than buffering it without bound.
+	if len(o.partial) > maxYTDLPLogBytes {
+		o.flush()
+	}
+	return len(p), nil
+}
+
+func (o *ytdlpOutput) flush() {
+	if len(o.partial) > 0 {
+		o.line(o.partial)
+		o.partial = nil
+	}
+}
+
+func (o *ytdlpOutput) line(line []byte) {
+	text := strings.TrimSpace(string(line))
+	if rest, ok := strings.CutPrefix(text, ytdlpProgressPrefix); ok {
+		if percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(rest, "%")), 64); err == nil && o.progress != nil {
+			o.progress(percent)
+		}
+		return
+	}
+	_, _ = o.log.Write(line)
+}
+
 func populateMetadataFromInfo(path string, metadata *TrackMetadata) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -770,7 +1060,7 @@ func (p *Processor) RepairAudioQuality(ctx context.Context, track *db.Track) (Au
 	}
 
 	ext := filepath.Ext(storageKey)
-	tmp, err := os.CreateTemp("", "omp-quality-backfill-*"+ext)
+	tmp, err := os.CreateTemp(p.tempDir, "omp-quality-backfill-*"+ext)
 	if err != nil {
 		return AudioQualityRepairResult{}, err
 	}
@@ -792,7 +1082,7 @@ func (p *Processor) RepairAudioQuality(ctx context.Context, track *db.Track) (Au
 	if info != nil {
 		contentType = info.ContentType
 	}
-	quality, err := probeAudioFile(repairCtx, tmpPath, contentType)
+	quality, err := probeAudioFile(repairCtx, p.mediaRunner(), tmpPath, contentType)
 	if err != nil {
 		return AudioQualityRepairResult{}, err
 	}
@@ -804,6 +1094,7 @@ func (p *Processor) RepairAudioQuality(ctx context.Context, track *db.Track) (Au
 		quality.SampleRateHz,
 		quality.Channels,
 		quality.ContentType,
+		quality.DurationMs,
 	); err != nil {
 		return AudioQualityRepairResult{}, err
 	}
@@ -815,7 +1106,8 @@ func hasCompleteAudioQuality(track *db.Track) bool {
 		track.BitrateKbps.Valid && track.BitrateKbps.Int32 > 0 &&
 		track.SampleRateHz.Valid && track.SampleRateHz.Int32 > 0 &&
 		track.Channels.Valid && track.Channels.Int32 > 0 &&
-		track.ContentType.Valid && strings.TrimSpace(track.ContentType.String) != ""
+		track.ContentType.Valid && strings.TrimSpace(track.ContentType.String) != "" &&
+		track.DurationMs.Valid && track.DurationMs.Int32 > 0
 }
 
 // runMatching runs MusicBrainz matching and stores suggestions
@@ -834,6 +1126,7 @@ func (p *Processor) runMatching(ctx context.Context, track *db.Track, metadata *
 		ThumbnailURL:  stringValueFromMap(provider, "thumbnail_url"),
 		RawProvider:   provider,
 		Deterministic: deterministicCleanupMetadata(metadata.Cleanup),
+		Fingerprints:  matcherFingerprints(metadata.Fingerprints),
 	}
 	if metadata.DurationMs > 0 {
 		matchMetadata.DurationMs = metadata.DurationMs
@@ -842,13 +1135,17 @@ func (p *Processor) runMatching(ctx context.Context, track *db.Track, metadata *
 		log.Printf("Track %d appears to be non-music content, skipping matching", track.ID)
 		return nil
 	}
-	output, err := p.matcher.Match(ctx, matchMetadata)
+	output, err := p.matcher.MatchTrack(ctx, track.ID, matchMetadata)
 	if err != nil {
 		_ = p.trackRepo.UpdateMBMatch(ctx, track.ID, failedMBMatchUpdate(err))
 		return fmt.Errorf("matching failed: %w", err)
 	}
 	update := automaticMBMatchUpdate(output)
-	return p.trackRepo.UpdateMBMatch(ctx, track.ID, update)
+	if err := p.trackRepo.UpdateMBMatch(ctx, track.ID, update); err != nil {
+		return err
+	}
+	p.matcher.SyncGenres(ctx, track.ID, update)
+	return nil
 }
 
 func failedMBMatchUpdate(matchErr error) *db.MBMatchUpdate {
@@ -951,6 +1248,31 @@ func (p *Processor) recordTrackSource(ctx context.Context, job *download.Downloa
 	}
 }
 
+// recordSourceQuality keeps the measured audio of this download on its source
+// row, so a duplicate download's object stays available as an alternative to
+// the track's canonical audio.
+func (p *Processor) recordSourceQuality(ctx context.Context, job *download.DownloadJob, trackID int64, metadata *TrackMetadata) {
+	if p.sourceRepo == nil || p.trackRepo == nil || job == nil || metadata == nil || metadata.StorageKey == "" {
+		return
+	}
+	quality := db.SourceQuality{
+		StorageKey:    metadata.StorageKey,
+		FileSizeBytes: metadata.FileSizeBytes,
+		Codec:         metadata.AudioQuality.Codec,
+		BitrateKbps:   metadata.AudioQuality.BitrateKbps,
+		SampleRateHz:  metadata.AudioQuality.SampleRateHz,
+		Channels:      metadata.AudioQuality.Channels,
+		ContentType:   metadata.AudioQuality.ContentType,
+	}
+	if metadata.Loudness != nil {
+		quality.PeakDBFS = &metadata.Loudness.PeakDBFS
+		quality.ClippingRatio = &metadata.Loudness.ClippingRatio
+	}
+	if err := p.trackRepo.RecordSourceQuality(ctx, trackID, job.SourceType, job.SourceID, job.URL, quality); err != nil {
+		log.Printf("Warning: failed to record source quality for track %d: %v", trackID, err)
+	}
+}
+
 func (p *Processor) attachPlaylistImportTrack(ctx context.Context, job *download.DownloadJob, trackID int64) error {
 	if job == nil || job.PlaylistImportItemID == 0 {
 		return nil
@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"github.com/openmusicplayer/backend/internal/analyzer"
 	"github.com/openmusicplayer/backend/internal/db"
 	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
 	"github.com/openmusicplayer/backend/internal/matcher"
 	"github.com/openmusicplayer/backend/internal/playlistimport"
 	"github.com/openmusicplayer/backend/internal/storage"
@@ -1504,7 +1506,7 @@ func TestDownloadAndStoreFixtureCreatesPlayableWAVObject(t *testing.T) {
 		Title:      "Fixture Silence",
 	}
 
-	metadata, err := processor.downloadAndStore(context.Background(), job)
+	metadata, err := processor.downloadAndStore(context.Background(), job, nil)
 	if err != nil {
 		t.Fatalf("downloadAndStore failed: %v", err)
 	}
@@ -1536,6 +1538,7 @@ func TestHasCompleteAudioQualityRejectsEmptyAndNonPositiveFacts(t *testing.T) {
 		SampleRateHz: sql.NullInt32{Int32: 44100, Valid: true},
 		Channels:     sql.NullInt32{Int32: 2, Valid: true},
 		ContentType:  sql.NullString{String: "audio/mpeg", Valid: true},
+		DurationMs:   sql.NullInt32{Int32: 215373, Valid: true},
 	}
 	if !hasCompleteAudioQuality(complete) {
 		t.Fatal("complete artifact facts were rejected")
@@ -1550,10 +1553,15 @@ func TestHasCompleteAudioQualityRejectsEmptyAndNonPositiveFacts(t *testing.T) {
 	if hasCompleteAudioQuality(&incomplete) {
 		t.Fatal("blank codec was treated as complete")
 	}
+	incomplete = *complete
+	incomplete.DurationMs = sql.NullInt32{}
+	if hasCompleteAudioQuality(&incomplete) {
+		t.Fatal("missing duration was treated as complete")
+	}
 }
 
 func TestDownloadAndStoreUsesProbeContentTypeDespiteMisleadingExtension(t *testing.T) {
-	wavPath, _, err := writeFixtureWAV("misleading-extension")
+	wavPath, _, err := writeFixtureWAV("", "misleading-extension")
 	if err != nil {
 		t.Fatalf("write fixture wav: %v", err)
 	}
@@ -1570,7 +1578,7 @@ func TestDownloadAndStoreUsesProbeContentTypeDespiteMisleadingExtension(t *testi
 		ID:         "misleading-extension",
 		URL:        "file://" + misleadingPath,
 		SourceType: "file",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("downloadAndStore: %v", err)
 	}
@@ -1583,20 +1591,20 @@ func TestProbeAudioFileUsesStdoutOnlyForJSON(t *testing.T) {
 	ffprobe := filepath.Join(t.TempDir(), "ffprobe")
 	script := `#!/bin/sh
 echo "diagnostic noise" >&2
-printf '%s\n' '{"streams":[{"codec_name":"mp3","bit_rate":"137000","sample_rate":"44100","channels":2}],"format":{"bit_rate":"137000","format_name":"mp3"}}'
+printf '%s\n' '{"streams":[{"codec_name":"mp3","bit_rate":"137000","sample_rate":"44100","channels":2}],"format":{"bit_rate":"137000","format_name":"mp3","duration":"215.373061"}}'
 `
 	if err := os.WriteFile(ffprobe, []byte(script), 0o755); err != nil {
 		t.Fatalf("write fake ffprobe: %v", err)
 	}
 	t.Setenv("PATH", filepath.Dir(ffprobe)+string(os.PathListSeparator)+os.Getenv("PATH"))
 
-	quality, err := probeAudioFile(context.Background(), "ignored.mp3", "audio/mpeg")
+	quality, err := probeAudioFile(context.Background(), ffmpeg.NewExecRunner(), "ignored.mp3", "audio/mpeg")
 	if err != nil {
 		t.Fatalf("probe with exit-0 stderr noise: %v", err)
 	}
 	if quality.Codec != "mp3" || quality.BitrateKbps != 137 ||
 		quality.SampleRateHz != 44100 || quality.Channels != 2 ||
-		quality.ContentType != "audio/mpeg" {
+		quality.ContentType != "audio/mpeg" || quality.DurationMs != 215373 {
 		t.Fatalf("quality = %+v", quality)
 	}
 }
@@ -1630,7 +1638,7 @@ printf '{"title":"Downloaded Title","duration":2}' > "${out%.*}.info.json"
 `)
 	metadata := &TrackMetadata{}
 
-	path, contentType, err := runYTDLPCommand(context.Background(), fakeYTDLP, "https://example.test/watch?v=1", metadata, maxYTDLPOutputBytes)
+	path, contentType, err := runYTDLPCommand(context.Background(), fakeYTDLP, "", "https://example.test/watch?v=1", metadata, maxYTDLPOutputBytes, nil)
 	if err != nil {
 		t.Fatalf("runYTDLPCommand failed: %v", err)
 	}
@@ -1668,7 +1676,7 @@ audio="${out%.*}.mp3"
 head -c 32 /dev/zero > "$audio"
 `)
 
-	path, _, err := runYTDLPCommand(context.Background(), fakeYTDLP, "https://example.test/watch?v=oversize", &TrackMetadata{}, 8)
+	path, _, err := runYTDLPCommand(context.Background(), fakeYTDLP, "", "https://example.test/watch?v=oversize", &TrackMetadata{}, 8, nil)
 	if err == nil {
 		os.Remove(path)
 		t.Fatalf("runYTDLPCommand oversize succeeded with path %q", path)
@@ -1689,7 +1697,7 @@ printf 'nope' >&2
 exit 7
 `)
 
-	_, _, err := runYTDLPCommand(context.Background(), fakeYTDLP, "https://example.test/watch?v=fail", &TrackMetadata{}, maxYTDLPOutputBytes)
+	_, _, err := runYTDLPCommand(context.Background(), fakeYTDLP, "", "https://example.test/watch?v=fail", &TrackMetadata{}, maxYTDLPOutputBytes, nil)
 	if err == nil {
 		t.Fatalf("runYTDLPCommand failure succeeded")
 	}
@@ -1698,6 +1706,70 @@ exit 7
 	}
 }
 
+func TestRunYTDLPReportsDownloadProgress(t *testing.T) {
+	fakeYTDLP := writeFakeYTDLP(t, `
+set -eu
+out=""
+template=""
+prev=""
+for arg in "$@"; do
+  if [ "$prev" = "-o" ]; then out="$arg"; fi
+  if [ "$prev" = "--progress-template" ]; then template="$arg"; fi
+  prev="$arg"
+done
+[ "$template" = "download:omp-progress %(progress._percent_str)s" ]
+printf '[youtube] Extracting URL\n'
+printf 'omp-progress   12.5%%\nomp-progress  60.0%%\nomp-progress 100%%\n'
+printf 'fake mp3 data' > "${out%.*}.mp3"
+printf 'broken' >&2
+exit 3
+`)
+	var reported []int
+	_, _, err := runYTDLPCommand(context.Background(), fakeYTDLP, "", "https://example.test/watch?v=progress", &TrackMetadata{}, maxYTDLPOutputBytes, stageProgress(func(p int) {
+		reported = append(reported, p)
+	}, downloadProgressStart, downloadProgressEnd))
+	if err == nil {
+		t.Fatal("runYTDLPCommand succeeded, want the exit status reported")
+	}
+	if want := []int{7, 17, 25}; !slices.Equal(reported, want) {
+		t.Fatalf("reported progress %v, want %v", reported, want)
+	}
+	if !strings.Contains(err.Error(), "[youtube] Extracting URL") || !strings.Contains(err.Error(), "broken") || strings.Contains(err.Error(), "omp-progress") {
+		t.Fatalf("error = %v, want yt-dlp's log without progress lines", err)
+	}
+}
+
+func TestRunYTDLPAppliesJobRateLimit(t *testing.T) {
+	fakeYTDLP := writeFakeYTDLP(t, `
+set -eu
+out=""
+rate=""
+prev=""
+for arg in "$@"; do
+  if [ "$prev" = "-o" ]; then out="$arg"; fi
+  if [ "$prev" = "--limit-rate" ]; then rate="$arg"; fi
+  prev="$arg"
+done
+[ "$rate" = "524288" ]
+printf 'fake mp3 data' > "${out%.*}.mp3"
+`)
+	ctx := download.WithRateLimit(context.Background(), 512<<10)
+	if _, _, err := runYTDLPCommand(ctx, fakeYTDLP, "", "https://example.test/watch?v=slow", &TrackMetadata{}, maxYTDLPOutputBytes, nil); err != nil {
+		t.Fatalf("runYTDLPCommand with a rate limit: %v", err)
+	}
+}
+
+func TestStageProgressOnlyMovesForward(t *testing.T) {
+	var reported []int
+	report := stageProgress(func(p int) { reported = append(reported, p) }, 5, 25)
+	for _, percent := range []float64{0, 3, 50, 40, 50.1, 250} {
+		report(percent)
+	}
+	if want := []int{15, 25}; !slices.Equal(reported, want) {
+		t.Fatalf("reported %v, want %v", reported, want)
+	}
+}
+
 func writeFakeYTDLP(t *testing.T, body string) string {
 	t.Helper()
 	path := filepath.Join(t.TempDir(), "yt-dlp-fake")
@@ -1734,3 +1806,191 @@ func newYTDLPTempDirs(t *testing.T, before map[string]struct{}) []string {
 	}
 	return leaked
 }
+
+func TestParseVolumeDetectReportsClippingOnlyAtFullScale(t *testing.T) {
+	clipped := strings.Join([]string{
+		"Input #0, wav, from 'song.wav':",
+		"[Parsed_volumedetect_0 @ 0x5581] n_samples: 200000",
+		"[Parsed_volumedetect_0 @ 0x5581] mean_volume: -12.3 dB",
+		"[Parsed_volumedetect_0 @ 0x5581] max_volume: -0.0 dB",
+		"[Parsed_volumedetect_0 @ 0x5581] histogram_0db: 500",
+	}, "\n")
+	loudness, err := parseVolumeDetect(clipped)
+	if err != nil {
+		t.Fatalf("parseVolumeDetect: %v", err)
+	}
+	if loudness.PeakDBFS != 0 || loudness.ClippingRatio != 0.0025 {
+		t.Fatalf("loudness = %+v, want peak 0 dBFS and ratio 0.0025", loudness)
+	}
+
+	quiet := strings.Join([]string{
+		"[Parsed_volumedetect_0 @ 0x5581] n_samples: 200000",
+		"[Parsed_volumedetect_0 @ 0x5581] max_volume: -3.5 dB",
+		"[Parsed_volumedetect_0 @ 0x5581] histogram_3db: 20",
+	}, "\n")
+	loudness, err = parseVolumeDetect(quiet)
+	if err != nil {
+		t.Fatalf("parseVolumeDetect: %v", err)
+	}
+	if loudness.PeakDBFS != -3.5 || loudness.ClippingRatio != 0 {
+		t.Fatalf("loudness = %+v, want peak -3.5 dBFS and no clipping", loudness)
+	}
+
+	if _, err := parseVolumeDetect("Input #0, wav\n"); err == nil {
+		t.Fatal("parseVolumeDetect accepted output without volumedetect lines")
+	}
+}
+
+type fakeTakedownChecker struct {
+	takedown    *db.ContentTakedown
+	hash        string
+	quarantined int64
+}
+
+func (f *fakeTakedownChecker) FindActive(ctx context.Context, sourceURL, identityHash string) (*db.ContentTakedown, error) {
+	f.hash = identityHash
+	return f.takedown, nil
+}
+
+func (f *fakeTakedownChecker) QuarantineTrack(ctx context.Context, trackID, takedownID int64) error {
+	f.quarantined = trackID
+	return nil
+}
+
+func TestCheckTakedownQuarantinesTracksResolvedToBlockedIdentity(t *testing.T) {
+	checker := &fakeTakedownChecker{takedown: &db.ContentTakedown{ID: 3, Reason: "rights holder request"}}
+	p := New(&ProcessorConfig{Takedowns: checker})
+	job := &download.DownloadJob{ID: "job-1", URL: "https://soundcloud.com/label/track"}
+
+	err := p.checkTakedown(context.Background(), job, &db.Track{ID: 42, IdentityHash: "hash-42"})
+	if !errors.Is(err, db.ErrContentTakenDown) || !strings.Contains(err.Error(), "rights holder request") {
+		t.Fatalf("checkTakedown err = %v, want ErrContentTakenDown with reason", err)
+	}
+	if checker.hash != "hash-42" || checker.quarantined != 42 {
+		t.Fatalf("checked hash %q, quarantined %d; want hash-42 and track 42", checker.hash, checker.quarantined)
+	}
+
+	checker.takedown = nil
+	if err := p.checkTakedown(context.Background(), job, &db.Track{ID: 43, QuarantinedAt: sql.NullTime{Valid: true}}); !errors.Is(err, db.ErrContentTakenDown) {
+		t.Fatalf("already quarantined track err = %v, want ErrContentTakenDown", err)
+	}
+	if err := p.checkTakedown(context.Background(), job, &db.Track{ID: 44}); err != nil {
+		t.Fatalf("unblocked track err = %v", err)
+	}
+}
+
+func TestMeasureLoudnessRunsVolumedetectThroughRunner(t *testing.T) {
+	fake := ffmpeg.NewFake()
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		return ffmpeg.Output{Stderr: "[Parsed_volumedetect_0 @ 0x1] n_samples: 1000\n[Parsed_volumedetect_0 @ 0x1] max_volume: -6.0 dB\n"}, nil
+	}
+
+	loudness, err := measureLoudness(context.Background(), fake, "song.mp3")
+	if err != nil {
+		t.Fatalf("measureLoudness: %v", err)
+	}
+	if loudness.PeakDBFS != -6 {
+		t.Fatalf("loudness = %+v, want -6 dBFS peak", loudness)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || !strings.Contains(strings.Join(calls[0].Args, " "), "-loglevel info -i song.mp3 -map 0:a:0 -af volumedetect -f null -") {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
+
+type fakeDownloadSettings struct {
+	settings *db.DownloadSettings
+}
+
+func (f fakeDownloadSettings) GetDownloadSettings(_ context.Context, userID uuid.UUID) (*db.DownloadSettings, error) {
+	if f.settings == nil {
+		return db.DefaultDownloadSettings(userID), nil
+	}
+	return f.settings, nil
+}
+
+type fakePlaybackQueue struct {
+	queued []int64
+}
+
+func (f *fakePlaybackQueue) QueueNext(_ context.Context, _, _ string, trackID int64) error {
+	f.queued = append(f.queued, trackID)
+	return nil
+}
+
+func TestResolveDestinationAppliesJobOverridesOverUserSettings(t *testing.T) {
+	userID := uuid.New()
+	p := New(&ProcessorConfig{DownloadSettings: fakeDownloadSettings{settings: &db.DownloadSettings{
+		UserID:       userID,
+		AddToLibrary: false,
+		PlaylistID:   sql.NullInt64{Int64: 9, Valid: true},
+		QueueNext:    true,
+	}}})
+
+	job := &download.DownloadJob{ID: "job-1", UserID: userID.String()}
+	if got := p.resolveDestination(context.Background(), job); got != (destination{playlistID: 9, queueNext: true}) {
+		t.Fatalf("settings destination = %+v", got)
+	}
+
+	on, off, playlist := true, false, int64(12)
+	job.Metadata = download.WithDestination(nil, download.Destination{AddToLibrary: &on, PlaylistID: &playlist, QueueNext: &off})
+	if got := p.resolveDestination(context.Background(), job); got != (destination{addToLibrary: true, playlistID: 12}) {
+		t.Fatalf("overridden destination = %+v", got)
+	}
+
+	// Playlist imports keep going to the library and their import playlist.
+	importJob := &download.DownloadJob{ID: "job-2", UserID: userID.String(), PlaylistImportItemID: 4}
+	if got := p.resolveDestination(context.Background(), importJob); got != (destination{addToLibrary: true}) {
+		t.Fatalf("import destination = %+v", got)
+	}
+}
+
+func TestDeliverQueuesNextOnlyWhenAsked(t *testing.T) {
+	queue := &fakePlaybackQueue{}
+	p := New(&ProcessorConfig{})
+	p.SetPlaybackQueue(queue)
+	job := &download.DownloadJob{ID: "job-1", UserID: uuid.NewString()}
+
+	p.deliver(context.Background(), job, 5, destination{addToLibrary: true})
+	p.deliver(context.Background(), job, 6, destination{queueNext: true})
+	if len(queue.queued) != 1 || queue.queued[0] != 6 {
+		t.Fatalf("queued = %v, want only track 6", queue.queued)
+	}
+}
+
+type deletingObjectStorage struct {
+	fakeObjectStorage
+	deleted []string
+}
+
+func (s *deletingObjectStorage) DeleteObject(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func TestUploadJobReadsAndRemovesStagedObject(t *testing.T) {
+	key := "uploads/11111111-1111-1111-1111-111111111111/upload-1.flac"
+	objects := &deletingObjectStorage{fakeObjectStorage: fakeObjectStorage{objects: map[string][]byte{key: []byte("flac bytes")}}}
+	p := New(&ProcessorConfig{Storage: objects})
+	job := &download.DownloadJob{ID: "job-1", URL: download.UploadURLPrefix + key, SourceType: "upload"}
+
+	path, contentType, err := p.obtainAudioFile(context.Background(), t.TempDir(), job, &TrackMetadata{}, nil)
+	if err != nil {
+		t.Fatalf("obtainAudioFile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "flac bytes" || filepath.Ext(path) != ".flac" || contentType != "audio/wav" {
+		t.Fatalf("copied %q to %s as %q (err %v), want the staged bytes with its extension and stored type", data, path, contentType, err)
+	}
+
+	p.removeStagedUpload(context.Background(), job)
+	p.removeStagedUpload(context.Background(), &download.DownloadJob{ID: "job-2", URL: "https://example.test/a"})
+	if len(objects.deleted) != 1 || objects.deleted[0] != key {
+		t.Fatalf("deleted = %v, want only the staged upload", objects.deleted)
+	}
+
+	job.URL = download.UploadURLPrefix + "tracks/upload/other.flac"
+	if _, _, err := p.obtainAudioFile(context.Background(), t.TempDir(), job, &TrackMetadata{}, nil); err == nil {
+		t.Fatal("upload job outside uploads/ was read")
+	}
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+// audioR128Timeout bounds the loudnorm pass, which decodes the whole file
+// and oversamples it to find the true peak.
+const audioR128Timeout = 2 * time.Minute
+
+// R128Loudness is the EBU R128 measurement of one stored artifact.
+type R128Loudness struct {
+	IntegratedLUFS float64 `json:"integratedLufs"`
+	TruePeakDBTP   float64 `json:"truePeakDbtp"`
+	RangeLU        float64 `json:"rangeLu"`
+}
+
+// measureR128Loudness runs ffmpeg's loudnorm filter in analysis mode over the
+// first audio stream.
+func measureR128Loudness(ctx context.Context, runner ffmpeg.Runner, path string) (*R128Loudness, error) {
+	measureCtx, cancel := context.WithTimeout(ctx, audioR128Timeout)
+	defer cancel()
+
+	args := ffmpeg.NewCommand().
+		LogLevel("info").
+		Input(path).
+		Map("0:a:0").
+		AudioFilter("loudnorm=print_format=json").
+		Format("null").
+		Args("-")
+	out, err := runner.FFmpeg(measureCtx, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg loudnorm: %w", err)
+	}
+	return parseLoudnorm(out.Stderr)
+}
+
+// parseLoudnorm reads the JSON block loudnorm writes to stderr after its
+// "[Parsed_loudnorm_0 @ ...]" line. Values are strings, and silence
+// measures "-inf".
+func parseLoudnorm(output string) (*R128Loudness, error) {
+	idx := strings.LastIndex(output, "Parsed_loudnorm")
+	if idx < 0 {
+		return nil, errors.New("loudnorm reported no measurement")
+	}
+	block := output[idx:]
+	start, end := strings.Index(block, "{"), strings.LastIndex(block, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("loudnorm reported no measurement")
+	}
+	var measured struct {
+		InputI   string `json:"input_i"`
+		InputTP  string `json:"input_tp"`
+		InputLRA string `json:"input_lra"`
+	}
+	if err := json.Unmarshal([]byte(block[start:end+1]), &measured); err != nil {
+		return nil, fmt.Errorf("decode loudnorm output: %w", err)
+	}
+	integrated, err := strconv.ParseFloat(strings.TrimSpace(measured.InputI), 64)
+	if err != nil || math.IsInf(integrated, 0) || math.IsNaN(integrated) {
+		return nil, fmt.Errorf("loudnorm measured no integrated loudness (%q)", measured.InputI)
+	}
+	truePeak, err := strconv.ParseFloat(strings.TrimSpace(measured.InputTP), 64)
+	if err != nil || math.IsInf(truePeak, 0) || math.IsNaN(truePeak) {
+		return nil, fmt.Errorf("loudnorm measured no true peak (%q)", measured.InputTP)
+	}
+	loudnessRange, _ := strconv.ParseFloat(strings.TrimSpace(measured.InputLRA), 64)
+	return &R128Loudness{IntegratedLUFS: integrated, TruePeakDBTP: truePeak, RangeLU: loudnessRange}, nil
+}
+
+// storeReplayGain saves the download's R128 measurement on its track, which
+// also updates the album gain of the track's release. It runs after matching
+// so a newly matched release is the one the album gain covers.
+func (p *Processor) storeReplayGain(ctx context.Context, job *download.DownloadJob, track *db.Track, metadata *TrackMetadata) {
+	if p.trackRepo == nil || track == nil || metadata == nil || metadata.R128 == nil {
+		return
+	}
+	if err := p.trackRepo.UpdateLoudness(ctx, track.ID, metadata.R128.IntegratedLUFS, metadata.R128.TruePeakDBTP); err != nil {
+		log.Printf("Warning: failed to store ReplayGain for job %s (track %d): %v", job.ID, track.ID, err)
+	}
+}
@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLoudnormReadsMeasurementBlock(t *testing.T) {
+	output := strings.Join([]string{
+		"Input #0, flac, from 'song.flac':",
+		"[Parsed_loudnorm_0 @ 0x55d1] ",
+		"{",
+		`	"input_i" : "-9.84",`,
+		`	"input_tp" : "0.31",`,
+		`	"input_lra" : "5.20",`,
+		`	"input_thresh" : "-19.98",`,
+		`	"output_i" : "-23.05",`,
+		`	"normalization_type" : "dynamic",`,
+		`	"target_offset" : "0.05"`,
+		"}",
+	}, "\n")
+	loudness, err := parseLoudnorm(output)
+	if err != nil {
+		t.Fatalf("parseLoudnorm: %v", err)
+	}
+	if loudness.IntegratedLUFS != -9.84 || loudness.TruePeakDBTP != 0.31 || loudness.RangeLU != 5.2 {
+		t.Fatalf("loudness = %+v", loudness)
+	}
+}
+
+func TestParseLoudnormRejectsSilenceAndMissingOutput(t *testing.T) {
+	silent := "[Parsed_loudnorm_0 @ 0x55d1] \n{\n\"input_i\" : \"-inf\",\n\"input_tp\" : \"-inf\",\n\"input_lra\" : \"0.00\"\n}"
+	if _, err := parseLoudnorm(silent); err == nil {
+		t.Fatal("parseLoudnorm accepted a silent measurement")
+	}
+	if _, err := parseLoudnorm("Input #0, wav\n"); err == nil {
+		t.Fatal("parseLoudnorm accepted output without a loudnorm block")
+	}
+}
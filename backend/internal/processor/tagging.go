@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/tagger"
+)
+
+const (
+	tagTimeout = 2 * time.Minute
+	// tagCoverSize is the edge length of release covers written into files.
+	tagCoverSize = 500
+)
+
+// ReleaseCoverSource returns a release's front cover as a JPEG.
+// artwork.ReleaseCovers satisfies it.
+type ReleaseCoverSource interface {
+	Cover(ctx context.Context, releaseID uuid.UUID, size int) ([]byte, error)
+}
+
+// tagStoredAudio rewrites a new track's stored audio with the metadata
+// matching settled on, so files downloaded from the library carry it. The
+// cover is the release cover when the track has a release and the cover
+// extracted from the download otherwise. Failures leave the untagged audio
+// in place.
+func (p *Processor) tagStoredAudio(ctx context.Context, job *download.DownloadJob, track *db.Track, metadata *TrackMetadata) {
+	if p.tagger == nil || p.storage == nil {
+		return
+	}
+	// Matching updates the row, not the in-memory track.
+	if p.trackRepo != nil {
+		if reloaded, err := p.trackRepo.GetByID(ctx, track.ID); err == nil {
+			track = reloaded
+		} else {
+			log.Printf("Warning: failed to reload track %d for tagging: %v", track.ID, err)
+		}
+	}
+	tagCtx, cancel := context.WithTimeout(ctx, tagTimeout)
+	defer cancel()
+	if err := p.writeStoredTags(tagCtx, track, metadata); err != nil {
+		log.Printf("Warning: failed to tag stored audio for job %s: %v", job.ID, err)
+	}
+}
+
+func (p *Processor) writeStoredTags(ctx context.Context, track *db.Track, metadata *TrackMetadata) error {
+	if !track.StorageKey.Valid || track.StorageKey.String == "" {
+		return errors.New("track has no stored audio")
+	}
+	key := track.StorageKey.String
+	ext := path.Ext(key)
+	if ext == "" {
+		return fmt.Errorf("stored audio %q has no extension", key)
+	}
+
+	scratch, err := os.MkdirTemp(p.tempDir, "omp-tag-*")
+	if err != nil {
+		return fmt.Errorf("create tag temp dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	source := filepath.Join(scratch, "source"+ext)
+	if err := p.copyObjectToFile(ctx, key, source); err != nil {
+		return err
+	}
+	target := filepath.Join(scratch, "tagged"+ext)
+	tags := tagger.FromTrack(track)
+	tags.Cover = p.tagCover(ctx, track, metadata)
+	if err := p.tagger.Write(ctx, source, target, tags); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	contentType := metadata.AudioQuality.ContentType
+	if track.ContentType.Valid && track.ContentType.String != "" {
+		contentType = track.ContentType.String
+	}
+	if err := p.storage.PutObject(ctx, key, file, info.Size(), contentType); err != nil {
+		return fmt.Errorf("upload tagged audio: %w", err)
+	}
+	if p.trackRepo != nil {
+		if err := p.trackRepo.UpdateStoredFileSize(ctx, track.ID, key, info.Size()); err != nil {
+			log.Printf("Warning: failed to record tagged size of track %d: %v", track.ID, err)
+		}
+	}
+	return nil
+}
+
+// tagCover picks the cover written into the file, or nil to keep the one
+// the download embedded.
+func (p *Processor) tagCover(ctx context.Context, track *db.Track, metadata *TrackMetadata) []byte {
+	if track.MBReleaseID != nil && p.releaseCovers != nil {
+		cover, err := p.releaseCovers.Cover(ctx, *track.MBReleaseID, tagCoverSize)
+		if err == nil {
+			return cover
+		}
+		log.Printf("Warning: release cover unavailable for track %d: %v", track.ID, err)
+	}
+	return metadata.Artwork
+}
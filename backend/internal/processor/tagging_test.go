@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+type fakeReleaseCoverSource struct {
+	requested uuid.UUID
+}
+
+func (f *fakeReleaseCoverSource) Cover(_ context.Context, releaseID uuid.UUID, _ int) ([]byte, error) {
+	f.requested = releaseID
+	return []byte("release jpeg"), nil
+}
+
+func TestTagStoredAudioReplacesObjectWithTaggedCopy(t *testing.T) {
+	releaseID := uuid.New()
+	key := "tracks/youtube/job-1.mp3"
+	objects := &fakeObjectStorage{objects: map[string][]byte{key: []byte("ID3 untagged")}}
+	covers := &fakeReleaseCoverSource{}
+	var cover string
+	media := ffmpeg.NewFake()
+	media.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		for i, arg := range call.Args {
+			if arg == "-i" && strings.HasSuffix(call.Args[i+1], ".jpg") {
+				data, _ := os.ReadFile(call.Args[i+1])
+				cover = string(data)
+			}
+		}
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], []byte("ID3 tagged"), 0o600)
+	}
+	p := New(&ProcessorConfig{Storage: objects, FFmpeg: media, TagAudio: true, ReleaseCovers: covers, TempDir: t.TempDir()})
+	track := &db.Track{
+		ID:          7,
+		Title:       "Song",
+		MBReleaseID: &releaseID,
+		StorageKey:  sql.NullString{String: key, Valid: true},
+		ContentType: sql.NullString{String: "audio/mpeg", Valid: true},
+	}
+
+	p.tagStoredAudio(context.Background(), &download.DownloadJob{ID: "job-1"}, track, &TrackMetadata{Artwork: []byte("embedded jpeg")})
+	if objects.key != key || string(objects.data) != "ID3 tagged" || objects.contentType != "audio/mpeg" {
+		t.Fatalf("stored %q = %q as %q, want the tagged copy under the same key", objects.key, objects.data, objects.contentType)
+	}
+	if covers.requested != releaseID || cover != "release jpeg" {
+		t.Fatalf("embedded cover %q from release %s, want the release cover", cover, covers.requested)
+	}
+	if args := strings.Join(media.Calls()[0].Args, " "); !strings.Contains(args, "-metadata title=Song") {
+		t.Fatalf("ffmpeg args %q missing the title", args)
+	}
+}
+
+func TestTagStoredAudioDisabledByDefault(t *testing.T) {
+	objects := &fakeObjectStorage{}
+	media := ffmpeg.NewFake()
+	p := New(&ProcessorConfig{Storage: objects, FFmpeg: media})
+
+	p.tagStoredAudio(context.Background(), &download.DownloadJob{ID: "job-1"}, exportTestTrack(), &TrackMetadata{})
+	if len(media.Calls()) != 0 || objects.key != "" {
+		t.Fatalf("tagged without TagAudio: %d ffmpeg calls, stored %q", len(media.Calls()), objects.key)
+	}
+}
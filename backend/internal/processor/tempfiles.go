@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempFilePrefixes are the scratch entries the processor creates. The sweep
+// only touches these so a shared temp directory is safe to point it at.
+var tempFilePrefixes = []string{
+	"omp-job-",
+	"omp-ytdlp-",
+	"omp-download-",
+	"omp-fixture-",
+	"omp-quality-backfill-",
+	"omp-export-",
+	// transcode.Service scratch, when it shares the download temp dir.
+	"omp-transcode-",
+}
+
+// SweepStaleTempFiles removes processor scratch files and directories in dir
+// last modified before now-maxAge. It catches leftovers from crashed or killed
+// workers, which never reach their deferred cleanup. An empty dir uses
+// os.TempDir. It returns the number of entries removed.
+func SweepStaleTempFiles(dir string, maxAge time.Duration, now time.Time) (int, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if !hasTempFilePrefix(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func hasTempFilePrefix(name string) bool {
+	for _, prefix := range tempFilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
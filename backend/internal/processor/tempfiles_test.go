@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+func TestSweepStaleTempFilesRemovesOnlyOldProcessorEntries(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+
+	staleJobDir := filepath.Join(dir, "omp-job-stale")
+	if err := os.Mkdir(staleJobDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(staleJobDir, "audio.mp3"), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	staleDownload := filepath.Join(dir, "omp-download-123.mp3")
+	freshDownload := filepath.Join(dir, "omp-download-456.mp3")
+	foreign := filepath.Join(dir, "someone-elses-file")
+	for _, path := range []string{staleDownload, freshDownload, foreign} {
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	for _, path := range []string{staleJobDir, staleDownload, foreign} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	removed, err := SweepStaleTempFiles(dir, time.Hour, now)
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("removed = %d, want 2", removed)
+	}
+	for _, path := range []string{staleJobDir, staleDownload} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s survived the sweep", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{freshDownload, foreign} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("%s was removed: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestDownloadAndStoreRemovesJobTempDirOnFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	p := &Processor{storage: &fakeObjectStorage{}, tempDir: tempDir}
+	_, err := p.downloadAndStore(context.Background(), &download.DownloadJob{
+		ID:         "missing-source",
+		URL:        "file://" + filepath.Join(tempDir, "does-not-exist.mp3"),
+		SourceType: "file",
+	}, nil)
+	if err == nil {
+		t.Fatal("expected missing source to fail")
+	}
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("read temp dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("job temp dir leaked: %v", entries[0].Name())
+	}
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+// maxUploadedAudioBytes bounds the copy of an uploaded object. The upload API
+// enforces its own, usually smaller, limit before a job is queued.
+const maxUploadedAudioBytes = 10 << 30
+
+// objectDeleter is implemented by storage that can remove staged uploads.
+type objectDeleter interface {
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// uploadKey returns the staged object key of an upload job.
+func uploadKey(job *download.DownloadJob) (string, bool) {
+	if !strings.HasPrefix(job.URL, download.UploadURLPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(job.URL, download.UploadURLPrefix), true
+}
+
+// fetchUploadedObject copies the uploaded object at key into dir.
+func (p *Processor) fetchUploadedObject(ctx context.Context, dir, key string) (string, string, error) {
+	if key == "" || !strings.HasPrefix(key, "uploads/") {
+		return "", "", fmt.Errorf("invalid upload key %q", key)
+	}
+	reader, info, err := p.storage.GetObject(ctx, key)
+	if err != nil {
+		return "", "", fmt.Errorf("read uploaded audio: %w", err)
+	}
+	defer reader.Close()
+	if info != nil && info.Size > maxUploadedAudioBytes {
+		return "", "", fmt.Errorf("uploaded file too large: %d bytes", info.Size)
+	}
+
+	out, err := os.CreateTemp(dir, "omp-upload-*"+path.Ext(key))
+	if err != nil {
+		return "", "", err
+	}
+	outPath := out.Name()
+	defer out.Close()
+	written, err := io.Copy(out, io.LimitReader(reader, maxUploadedAudioBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("copy uploaded audio: %w", err)
+	}
+	if written > maxUploadedAudioBytes {
+		return "", "", fmt.Errorf("uploaded file too large: more than %d bytes", int64(maxUploadedAudioBytes))
+	}
+	contentType := ""
+	if info != nil {
+		contentType = info.ContentType
+	}
+	return outPath, contentType, nil
+}
+
+// removeStagedUpload deletes an upload job's staged object once its audio is
+// stored as a track. Failures only leave a stray object behind.
+func (p *Processor) removeStagedUpload(ctx context.Context, job *download.DownloadJob) {
+	key, ok := uploadKey(job)
+	if !ok {
+		return
+	}
+	deleter, ok := p.storage.(objectDeleter)
+	if !ok {
+		return
+	}
+	if err := deleter.DeleteObject(ctx, key); err != nil {
+		log.Printf("Warning: failed to delete staged upload %s: %v", key, err)
+	}
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/waveform"
+)
+
+// waveformTimeout bounds decoding a download for its waveform peaks.
+const waveformTimeout = 2 * time.Minute
+
+// TrackWaveformStore records which object holds a track's waveform.
+// db.TrackRepository satisfies it.
+type TrackWaveformStore interface {
+	SetWaveformKey(ctx context.Context, trackID int64, key string) error
+}
+
+// generateWaveform computes the seek bar peaks of the downloaded file while
+// it is still on disk, for storeWaveform to save once the track exists. A
+// failure only costs the track its waveform.
+func (p *Processor) generateWaveform(ctx context.Context, job *download.DownloadJob, dir, path string, durationMs int, metadata *TrackMetadata) {
+	if p.trackWaveforms == nil {
+		return
+	}
+	generateCtx, cancel := context.WithTimeout(ctx, waveformTimeout)
+	defer cancel()
+
+	peaks, err := waveform.Generate(generateCtx, p.mediaRunner(), path, dir, durationMs, waveform.DefaultPeaks)
+	if err != nil {
+		log.Printf("Warning: waveform generation failed for job %s: %v", job.ID, err)
+		return
+	}
+	metadata.Waveform = peaks
+}
+
+// storeWaveform saves what generateWaveform computed and records the key on
+// the track, which is where the API looks it up.
+func (p *Processor) storeWaveform(ctx context.Context, job *download.DownloadJob, track *db.Track, metadata *TrackMetadata) {
+	if p.trackWaveforms == nil || track == nil || metadata == nil || metadata.Waveform == nil {
+		return
+	}
+	key := waveform.Key(track.IdentityHash)
+	encoded, err := json.Marshal(metadata.Waveform)
+	if err == nil {
+		err = p.storage.PutObject(ctx, key, bytes.NewReader(encoded), int64(len(encoded)), waveform.ContentType)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to store waveform for job %s (track %d): %v", job.ID, track.ID, err)
+		return
+	}
+	if err := p.trackWaveforms.SetWaveformKey(ctx, track.ID, key); err != nil {
+		log.Printf("Warning: failed to record waveform for job %s (track %d): %v", job.ID, track.ID, err)
+	}
+}
@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/waveform"
+)
+
+type fakeWaveformKeys map[int64]string
+
+func (f fakeWaveformKeys) SetWaveformKey(_ context.Context, trackID int64, key string) error {
+	f[trackID] = key
+	return nil
+}
+
+func TestGenerateAndStoreWaveform(t *testing.T) {
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		samples := make([]byte, 2*2000)
+		binary.LittleEndian.PutUint16(samples[2*1500:], uint16(16384))
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], samples, 0o644)
+	}
+	objects := &fakeObjectStorage{}
+	keys := fakeWaveformKeys{}
+	p := &Processor{media: runner, storage: objects, trackWaveforms: keys}
+	job := &download.DownloadJob{ID: "wave"}
+
+	metadata := &TrackMetadata{}
+	p.generateWaveform(context.Background(), job, t.TempDir(), "/tmp/song.mp3", 250, metadata)
+	if metadata.Waveform == nil || len(metadata.Waveform.Peaks) != waveform.DefaultPeaks || metadata.Waveform.Peaks[750] != 0.5 {
+		t.Fatalf("waveform = %+v, want %d peaks with the loud sample three quarters in", metadata.Waveform, waveform.DefaultPeaks)
+	}
+
+	p.storeWaveform(context.Background(), job, &db.Track{ID: 7, IdentityHash: "hash"}, metadata)
+	if objects.key != "waveforms/tracks/hash.json" || objects.contentType != "application/json" {
+		t.Fatalf("stored %q as %q", objects.key, objects.contentType)
+	}
+	if keys[7] != objects.key {
+		t.Fatalf("recorded key = %q, want %q", keys[7], objects.key)
+	}
+	var stored waveform.Waveform
+	if err := json.Unmarshal(objects.data, &stored); err != nil || stored.DurationMs != 250 || len(stored.Peaks) != waveform.DefaultPeaks {
+		t.Fatalf("stored waveform = %s (%v)", objects.data, err)
+	}
+}
@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/download"
+)
+
+const (
+	// EventDownloadCompleted is sent once a download job's track is stored,
+	// matched, and delivered.
+	EventDownloadCompleted = "download.completed"
+
+	webhookUserAgent   = "OpenMusicPlayer/1.0.0 (webhook)"
+	webhookAttempts    = 3
+	webhookRetryDelay  = time.Second
+	webhookMaxResponse = 64 * 1024
+)
+
+// Webhook posts download events to an external library manager. When a
+// secret is set, each body is signed with HMAC-SHA256 in the
+// X-OMP-Signature header as "sha256=<hex>".
+type Webhook struct {
+	url        string
+	secret     []byte
+	httpClient *http.Client
+	retryDelay time.Duration
+}
+
+// NewWebhook posts to url. A nil httpClient uses one with a 10s timeout.
+func NewWebhook(url, secret string, httpClient *http.Client) *Webhook {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Webhook{url: url, secret: []byte(secret), httpClient: httpClient, retryDelay: webhookRetryDelay}
+}
+
+// DownloadEvent is the webhook body for EventDownloadCompleted.
+type DownloadEvent struct {
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurredAt"`
+	JobID      string       `json:"jobId"`
+	RequestID  string       `json:"requestId,omitempty"`
+	UserID     string       `json:"userId"`
+	SourceURL  string       `json:"sourceUrl"`
+	SourceType string       `json:"sourceType,omitempty"`
+	IsNew      bool         `json:"isNew"`
+	Track      WebhookTrack `json:"track"`
+	// ExportPath is where the export stage wrote the tagged copy, if it ran.
+	ExportPath string `json:"exportPath,omitempty"`
+}
+
+// WebhookTrack is the track's stored metadata and object key.
+type WebhookTrack struct {
+	ID            int64   `json:"id"`
+	IdentityHash  string  `json:"identityHash"`
+	Title         string  `json:"title"`
+	Artist        string  `json:"artist,omitempty"`
+	Album         string  `json:"album,omitempty"`
+	Version       string  `json:"version,omitempty"`
+	DurationMs    int32   `json:"durationMs,omitempty"`
+	MBRecordingID string  `json:"mbRecordingId,omitempty"`
+	MBReleaseID   string  `json:"mbReleaseId,omitempty"`
+	MBArtistID    string  `json:"mbArtistId,omitempty"`
+	MBVerified    bool    `json:"mbVerified"`
+	CoverArtURL   string  `json:"coverArtUrl,omitempty"`
+	StorageKey    string  `json:"storageKey"`
+	ContentType   string  `json:"contentType,omitempty"`
+	Codec         string  `json:"codec,omitempty"`
+	BitrateKbps   int32   `json:"bitrateKbps,omitempty"`
+	SampleRateHz  int32   `json:"sampleRateHz,omitempty"`
+	Channels      int32   `json:"channels,omitempty"`
+	FileSizeBytes int64   `json:"fileSizeBytes,omitempty"`
+	Confidence    float64 `json:"metadataConfidence,omitempty"`
+}
+
+func newDownloadEvent(job *download.DownloadJob, track *db.Track, isNew bool, now time.Time) DownloadEvent {
+	event := DownloadEvent{
+		Event:      EventDownloadCompleted,
+		OccurredAt: now.UTC(),
+		JobID:      job.ID,
+		RequestID:  job.RequestID,
+		UserID:     job.UserID,
+		SourceURL:  job.URL,
+		SourceType: job.SourceType,
+		IsNew:      isNew,
+		Track: WebhookTrack{
+			ID:            track.ID,
+			IdentityHash:  track.IdentityHash,
+			Title:         track.Title,
+			Artist:        track.Artist.String,
+			Album:         track.Album.String,
+			Version:       track.Version.String,
+			DurationMs:    track.DurationMs.Int32,
+			MBVerified:    track.MBVerified,
+			CoverArtURL:   track.CoverArtURL.String,
+			StorageKey:    track.StorageKey.String,
+			ContentType:   track.ContentType.String,
+			Codec:         track.Codec.String,
+			BitrateKbps:   track.BitrateKbps.Int32,
+			SampleRateHz:  track.SampleRateHz.Int32,
+			Channels:      track.Channels.Int32,
+			FileSizeBytes: track.FileSizeBytes.Int64,
+			Confidence:    track.MetadataConfidence.Float64,
+		},
+	}
+	if track.MBRecordingID != nil {
+		event.Track.MBRecordingID = track.MBRecordingID.String()
+	}
+	if track.MBReleaseID != nil {
+		event.Track.MBReleaseID = track.MBReleaseID.String()
+	}
+	if track.MBArtistID != nil {
+		event.Track.MBArtistID = track.MBArtistID.String()
+	}
+	return event
+}
+
+// Send posts event, retrying network errors, 429s, and 5xx responses.
+func (w *Webhook) Send(ctx context.Context, event DownloadEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.retryDelay * time.Duration(attempt)):
+			}
+		}
+		retry, err := w.post(ctx, event.Event, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+func (w *Webhook) post(ctx context.Context, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set("X-OMP-Event", eventType)
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set("X-OMP-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookMaxResponse))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// expandTracks is the ?expand= value that embeds each item's track.
+const expandTracks = "tracks"
+
+// TrackLookup loads tracks in one query. db.TrackRepository satisfies it.
+type TrackLookup interface {
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error)
+}
+
+// SetTrackLookup lets GET /api/v1/queue?expand=tracks embed track metadata.
+func (h *Handlers) SetTrackLookup(tracks TrackLookup) {
+	h.tracks = tracks
+}
+
+// QueueTrackResponse is the track a queue item plays, embedded with
+// ?expand=tracks so clients need not fetch each one.
+type QueueTrackResponse struct {
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	Artist        string     `json:"artist,omitempty"`
+	Album         string     `json:"album,omitempty"`
+	DurationMs    int        `json:"durationMs,omitempty"`
+	CoverArtURL   string     `json:"coverArtUrl,omitempty"`
+	MBRecordingID *uuid.UUID `json:"mbRecordingId,omitempty"`
+	MBReleaseID   *uuid.UUID `json:"mbReleaseId,omitempty"`
+	MBArtistID    *uuid.UUID `json:"mbArtistId,omitempty"`
+}
+
+// parseExpand reads ?expand=, a comma-separated list, and reports whether
+// tracks were asked for. ok is false for anything it does not know.
+func parseExpand(r *http.Request) (tracks bool, ok bool) {
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return false, true
+	}
+	for _, field := range strings.Split(raw, ",") {
+		if strings.TrimSpace(field) != expandTracks {
+			return false, false
+		}
+	}
+	return true, true
+}
+
+// expandQueueTracks embeds the track of every item that has one. Failing to
+// load them leaves the response as it would be without ?expand.
+func (h *Handlers) expandQueueTracks(ctx context.Context, resp *QueueResponse) {
+	if h.tracks == nil {
+		return
+	}
+	var ids []int64
+	for _, item := range resp.Items {
+		if item.TrackID != nil {
+			ids = append(ids, *item.TrackID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	tracks, err := h.tracks.GetByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("Warning: failed to load queue tracks: %v", err)
+		return
+	}
+	for i := range resp.Items {
+		item := &resp.Items[i]
+		if item.TrackID == nil {
+			continue
+		}
+		if track := tracks[*item.TrackID]; track != nil {
+			item.Track = newQueueTrackResponse(track)
+		}
+	}
+}
+
+func newQueueTrackResponse(t *db.Track) *QueueTrackResponse {
+	return &QueueTrackResponse{
+		ID:            t.ID,
+		Title:         t.Title,
+		Artist:        t.Artist.String,
+		Album:         t.Album.String,
+		DurationMs:    int(t.DurationMs.Int32),
+		CoverArtURL:   t.CoverArtURL.String,
+		MBRecordingID: t.MBRecordingID,
+		MBReleaseID:   t.MBReleaseID,
+		MBArtistID:    t.MBArtistID,
+	}
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeTrackLookup struct {
+	tracks map[int64]*db.Track
+	calls  int
+}
+
+func (f *fakeTrackLookup) GetByIDs(_ context.Context, ids []int64) (map[int64]*db.Track, error) {
+	f.calls++
+	found := map[int64]*db.Track{}
+	for _, id := range ids {
+		if track := f.tracks[id]; track != nil {
+			found[id] = track
+		}
+	}
+	return found, nil
+}
+
+func TestGetQueueExpandsTracks(t *testing.T) {
+	lookup := &fakeTrackLookup{tracks: map[int64]*db.Track{
+		7: {ID: 7, Title: "First", Artist: sql.NullString{String: "Artist", Valid: true}, DurationMs: sql.NullInt32{Int32: 180000, Valid: true}},
+	}}
+	h := NewHandlers(&fakeQueueHandlerService{state: twoTrackQueue()})
+	h.SetTrackLookup(lookup)
+
+	rec := httptest.NewRecorder()
+	h.GetQueue(rec, radioRequest(http.MethodGet, "/api/v1/queue?expand=tracks", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	first := resp.Items[0].Track
+	if first == nil || first.Title != "First" || first.Artist != "Artist" || first.DurationMs != 180000 {
+		t.Fatalf("first track = %+v, want track 7's metadata", first)
+	}
+	if resp.Items[1].Track != nil {
+		t.Fatalf("unknown track 8 = %+v, want none", resp.Items[1].Track)
+	}
+	if lookup.calls != 1 {
+		t.Fatalf("lookups = %d, want one batch", lookup.calls)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetQueue(rec, radioRequest(http.MethodGet, "/api/v1/queue", ""))
+	resp = QueueResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Items[0].Track != nil || lookup.calls != 1 {
+		t.Fatal("tracks were embedded without ?expand=tracks")
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetQueue(rec, radioRequest(http.MethodGet, "/api/v1/queue?expand=albums", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown expand = %d, want 400", rec.Code)
+	}
+}
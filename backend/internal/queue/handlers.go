@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -25,6 +27,16 @@ type Handlers struct {
 	analysisRepo    *db.AnalysisRepository
 	selectionRepo   sourceDecisionRepository
 	database        durableDownloadJobStore
+	plays           PlayRecorder
+	shuffle         ShuffleSource
+	entities        EntitySource
+	tracklists      Tracklists
+	states          PlaybackStates
+	radio           RadioSource
+	radioLibrary    RadioLibrary
+	notifier        QueueNotifier
+	queues          NamedQueues
+	tracks          TrackLookup
 }
 
 // These seams keep the HTTP boundary testable without Redis or PostgreSQL.
@@ -32,6 +44,8 @@ type Handlers struct {
 type queueHandlerService interface {
 	GetQueue(context.Context, string) (*QueueState, error)
 	AddToQueue(context.Context, string, int64, string) (*QueueState, error)
+	AddMultipleToQueue(context.Context, string, []int64, string) (*QueueState, error)
+	ReplaceQueue(context.Context, string, []int64) (*QueueState, error)
 	ValidateInsertPosition(context.Context, string, string) error
 	AddSourceCandidate(context.Context, string, SourceCandidate, string, string) (*QueueState, error)
 	EnsureSourceCandidateWithID(context.Context, string, string, SourceCandidate, string, string) (*QueueState, error)
@@ -39,6 +53,10 @@ type queueHandlerService interface {
 	QueueItemDownloadJobID(context.Context, string, string) (string, error)
 	RetryQueueItem(context.Context, string, string) (*QueueState, string, error)
 	ReorderQueueItem(context.Context, string, string, int) (*QueueState, error)
+	SetCurrentPosition(context.Context, string, int) (*QueueState, *QueueItem, error)
+	SetPlaybackPosition(context.Context, string, string, int64, bool) (*QueueState, error)
+	SetShuffle(context.Context, string, bool) (*QueueState, error)
+	SetRepeat(context.Context, string, string) (*QueueState, error)
 	ClearQueue(context.Context, string) error
 	saveQueue(context.Context, string, *QueueState) error
 }
@@ -60,6 +78,18 @@ type durableDownloadJobStore interface {
 	ExecContext(context.Context, string, ...any) (sql.Result, error)
 }
 
+// PlayRecorder records listens to the user's play history.
+// db.PlayEventRepository satisfies it.
+type PlayRecorder interface {
+	RecordPlayEvent(ctx context.Context, play db.PlayRecord) error
+}
+
+// ShuffleSource lists the library tracks a shuffle draws from along with
+// their play history. db.PlayEventRepository satisfies it.
+type ShuffleSource interface {
+	ShuffleCandidates(ctx context.Context, userID uuid.UUID, limit int) ([]db.ShuffleCandidate, error)
+}
+
 // NewHandlers creates a new Handlers instance
 func NewHandlers(service queueHandlerService, downloadServices ...queueDownloadService) *Handlers {
 	var downloadService queueDownloadService
@@ -79,6 +109,16 @@ func NewHandlersWithSourceSelections(service queueHandlerService, downloadServic
 	return &Handlers{service: service, downloadService: downloadService, analysisRepo: analysisRepo, selectionRepo: selectionRepo, database: database}
 }
 
+// SetPlays makes advancing the queue record a play for the item being left.
+func (h *Handlers) SetPlays(plays PlayRecorder) {
+	h.plays = plays
+}
+
+// SetShuffleSource enables POST /api/v1/queue/shuffle.
+func (h *Handlers) SetShuffleSource(shuffle ShuffleSource) {
+	h.shuffle = shuffle
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Code    string `json:"code"`
@@ -90,6 +130,18 @@ type QueueResponse struct {
 	Items           []QueueItemResponse `json:"items"`
 	CurrentPosition int                 `json:"currentPosition"`
 	UpdatedAt       time.Time           `json:"updatedAt"`
+	Shuffle         bool                `json:"shuffle"`
+	Repeat          string              `json:"repeat"`
+	// PositionMs is how far into the current item playback was at
+	// PositionUpdatedAt; a client resuming adds the time since unless
+	// Paused.
+	PositionMs        int64         `json:"positionMs"`
+	Paused            bool          `json:"paused"`
+	PositionUpdatedAt *time.Time    `json:"positionUpdatedAt,omitempty"`
+	Radio             *RadioStation `json:"radio,omitempty"`
+	Version           int64         `json:"version"`
+	// Name is the named queue this is, main unless the user switched.
+	Name string `json:"name"`
 }
 
 // QueueItemResponse is the canonical camelCase API projection of a queue item.
@@ -127,6 +179,8 @@ type QueueItemResponse struct {
 	CanRemove         bool             `json:"canRemove"`
 	AddedAt           time.Time        `json:"addedAt"`
 	UpdatedAt         time.Time        `json:"updatedAt"`
+	// Track is the item's track, with ?expand=tracks.
+	Track *QueueTrackResponse `json:"track,omitempty"`
 }
 
 // AddQueueItemRequest is the mobile-facing queue insertion contract. It accepts
@@ -145,7 +199,61 @@ type ReorderQueueRequest struct {
 	ToPosition  int    `json:"toPosition"`
 }
 
-// GetQueue handles GET /api/v1/queue
+// SetCurrentPositionRequest moves playback within the queue. ListenedMs is
+// how long the client played the item it is leaving; when positive, that
+// listen is recorded in the user's play history. PositionMs is where in the
+// new item playback starts, for a client resuming mid-track.
+type SetCurrentPositionRequest struct {
+	Position   int    `json:"position"`
+	ListenedMs *int   `json:"listenedMs,omitempty"`
+	PositionMs *int64 `json:"positionMs,omitempty"`
+}
+
+// PlaybackPositionRequest is a playback heartbeat: how far into the current
+// item playback is, and whether it is paused. QueueItemID names the item the
+// client is playing so a heartbeat racing a skip is rejected.
+type PlaybackPositionRequest struct {
+	QueueItemID string `json:"queueItemId"`
+	PositionMs  *int64 `json:"positionMs"`
+	Paused      bool   `json:"paused"`
+}
+
+const maxListenedMs = 24 * 60 * 60 * 1000
+
+// ShuffleQueueRequest fills the queue from the caller's library. Mode is
+// "smart" (the default), which favours tracks not played recently or often,
+// or "random". HalfLifeHours tunes how fast a played track recovers in smart
+// mode. Position is "next", "last" (the default), or "replace" to swap out
+// the whole queue.
+type ShuffleQueueRequest struct {
+	Mode          string   `json:"mode"`
+	Count         *int     `json:"count,omitempty"`
+	HalfLifeHours *float64 `json:"halfLifeHours,omitempty"`
+	Position      string   `json:"position"`
+}
+
+// ShuffleModeRequest turns shuffle on or off for the queue.
+type ShuffleModeRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// RepeatModeRequest sets the queue's repeat mode: "off", "all" or "one".
+type RepeatModeRequest struct {
+	Mode string `json:"mode"`
+}
+
+const (
+	defaultShuffleCount    = 50
+	maxShuffleCount        = 500
+	defaultShuffleHalfLife = 7 * 24 * time.Hour
+	maxShuffleHalfLife     = 365 * 24 * time.Hour
+	// maxShuffleCandidates bounds how much of a large library one shuffle
+	// scores; bigger libraries are sampled down to it first.
+	maxShuffleCandidates = 20000
+)
+
+// GetQueue handles GET /api/v1/queue. With ?expand=tracks each item embeds
+// its track, loaded in one query.
 func (h *Handlers) GetQueue(w http.ResponseWriter, r *http.Request) {
 	userCtx := auth.GetUserFromContext(r.Context())
 	if userCtx == nil {
@@ -153,6 +261,12 @@ func (h *Handlers) GetQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expand, ok := parseExpand(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_EXPAND", "expand must be tracks")
+		return
+	}
+
 	state, err := h.service.GetQueue(r.Context(), userCtx.UserID.String())
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get queue")
@@ -160,7 +274,11 @@ func (h *Handlers) GetQueue(w http.ResponseWriter, r *http.Request) {
 	}
 	jobs := h.resolveDownloadBackedItems(r, userCtx.UserID.String(), state)
 
-	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, jobs))
+	resp := h.buildQueueResponse(r.Context(), state, jobs)
+	if expand {
+		h.expandQueueTracks(r.Context(), &resp)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // AddQueueItem handles POST /api/v1/queue/items.
@@ -509,6 +627,287 @@ func (h *Handlers) ReorderQueue(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
 }
 
+// SetCurrentPosition handles PUT /api/v1/queue/current
+func (h *Handlers) SetCurrentPosition(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req SetCurrentPositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.ListenedMs != nil && (*req.ListenedMs < 0 || *req.ListenedMs > maxListenedMs) {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "listenedMs must be between 0 and 86400000")
+		return
+	}
+	if req.PositionMs != nil && (*req.PositionMs < 0 || *req.PositionMs > maxListenedMs) {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "positionMs must be between 0 and 86400000")
+		return
+	}
+
+	userID := userCtx.UserID.String()
+	state, previous, err := h.service.SetCurrentPosition(r.Context(), userID, req.Position)
+	if err != nil {
+		if err == ErrInvalidPosition {
+			writeError(w, http.StatusBadRequest, "INVALID_POSITION", "invalid position")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update current position")
+		return
+	}
+	if req.PositionMs != nil && *req.PositionMs > 0 {
+		current := state.Items[state.CurrentPosition]
+		if resumed, err := h.service.SetPlaybackPosition(r.Context(), userID, current.ID, *req.PositionMs, state.Paused); err == nil {
+			state = resumed
+		} else {
+			log.Printf("Warning: failed to save playback position for user %s: %v", userID, err)
+		}
+	}
+
+	if req.ListenedMs != nil && *req.ListenedMs > 0 {
+		h.recordQueuePlay(r.Context(), userCtx.UserID, previous, state, *req.ListenedMs)
+	}
+	state = h.extendRadio(r.Context(), userCtx.UserID, state)
+
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// UpdatePlaybackPosition handles PUT /api/v1/queue/position. Clients send it
+// as a heartbeat while playing and on pause, seek and resume, so any device
+// can pick up where playback left off. A heartbeat for an item that is no
+// longer current gets 409 and the client should refetch the queue.
+func (h *Handlers) UpdatePlaybackPosition(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req PlaybackPositionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.QueueItemID == "" || req.PositionMs == nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "queueItemId and positionMs are required")
+		return
+	}
+	if *req.PositionMs < 0 || *req.PositionMs > maxListenedMs {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "positionMs must be between 0 and 86400000")
+		return
+	}
+
+	if _, err := h.service.SetPlaybackPosition(r.Context(), userCtx.UserID.String(), req.QueueItemID, *req.PositionMs, req.Paused); err != nil {
+		if err == ErrNotCurrentItem {
+			writeError(w, http.StatusConflict, "NOT_CURRENT_ITEM", "queue item is not the current item")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update playback position")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordQueuePlay records the listen of the item playback just left. Staying
+// on the same item records nothing, and a failure is only logged since the
+// queue has already moved.
+func (h *Handlers) recordQueuePlay(ctx context.Context, userID uuid.UUID, previous *QueueItem, state *QueueState, listenedMs int) {
+	if h.plays == nil || previous == nil || previous.TrackID == nil {
+		return
+	}
+	if current := state.Items[state.CurrentPosition]; current.ID == previous.ID {
+		return
+	}
+	listened := time.Duration(listenedMs) * time.Millisecond
+	play := db.PlayRecord{
+		UserID:             userID,
+		TrackID:            *previous.TrackID,
+		ContextType:        "queue",
+		ContextID:          previous.ID,
+		PlayedAt:           time.Now().Add(-listened),
+		DurationListenedMs: sql.NullInt64{Int64: int64(listenedMs), Valid: true},
+	}
+	if err := h.plays.RecordPlayEvent(ctx, play); err != nil {
+		log.Printf("Warning: failed to record queue play of track %d for user %s: %v", play.TrackID, userID, err)
+	}
+}
+
+// ShuffleQueue handles POST /api/v1/queue/shuffle. Tracks already in the
+// queue are skipped unless the queue is being replaced.
+func (h *Handlers) ShuffleQueue(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.shuffle == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "shuffle is disabled")
+		return
+	}
+
+	var req ShuffleQueueRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = ShuffleModeSmart
+	}
+	if req.Mode != ShuffleModeSmart && req.Mode != ShuffleModeRandom {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "mode must be smart or random")
+		return
+	}
+	count := defaultShuffleCount
+	if req.Count != nil {
+		if *req.Count < 1 || *req.Count > maxShuffleCount {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("count must be between 1 and %d", maxShuffleCount))
+			return
+		}
+		count = *req.Count
+	}
+	halfLife := defaultShuffleHalfLife
+	if req.HalfLifeHours != nil {
+		halfLife = time.Duration(*req.HalfLifeHours * float64(time.Hour))
+		if halfLife < time.Hour || halfLife > maxShuffleHalfLife {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "halfLifeHours must be between 1 and 8760")
+			return
+		}
+	}
+	replace := req.Position == "replace"
+	if replace {
+		req.Position = "last"
+	}
+
+	userID := userCtx.UserID.String()
+	state, err := h.service.GetQueue(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get queue")
+		return
+	}
+	if !replace {
+		if _, _, err := resolveInsertPosition(state, req.Position); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_POSITION", "invalid position")
+			return
+		}
+	}
+
+	candidates, err := h.shuffle.ShuffleCandidates(r.Context(), userCtx.UserID, maxShuffleCandidates)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load library")
+		return
+	}
+	if !replace {
+		queued := map[int64]bool{}
+		for _, item := range state.Items {
+			if item.TrackID != nil {
+				queued[*item.TrackID] = true
+			}
+		}
+		fresh := candidates[:0]
+		for _, c := range candidates {
+			if !queued[c.TrackID] {
+				fresh = append(fresh, c)
+			}
+		}
+		candidates = fresh
+	}
+	if len(candidates) == 0 {
+		writeError(w, http.StatusConflict, "NOTHING_TO_SHUFFLE", "no library tracks left to shuffle")
+		return
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var trackIDs []int64
+	if req.Mode == ShuffleModeRandom {
+		trackIDs = RandomShuffle(candidates, count, rng)
+	} else {
+		trackIDs = SmartShuffle(candidates, count, halfLife, time.Now(), rng)
+	}
+
+	if replace {
+		if err := h.service.ClearQueue(r.Context(), userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to clear queue")
+			return
+		}
+	}
+	state, err = h.service.AddMultipleToQueue(r.Context(), userID, trackIDs, req.Position)
+	if err != nil {
+		if err == ErrInvalidPosition {
+			writeError(w, http.StatusBadRequest, "INVALID_POSITION", "invalid position")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add tracks to queue")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// UpdateShuffle handles PUT /api/v1/queue/shuffle. Turning shuffle on
+// shuffles the items after the current one; turning it off restores their
+// order, with items added meanwhile after the rest.
+func (h *Handlers) UpdateShuffle(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req ShuffleModeRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil || req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "enabled is required")
+		return
+	}
+
+	state, err := h.service.SetShuffle(r.Context(), userCtx.UserID.String(), *req.Enabled)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update shuffle")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// UpdateRepeat handles PUT /api/v1/queue/repeat
+func (h *Handlers) UpdateRepeat(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+
+	var req RepeatModeRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Mode != RepeatOff && req.Mode != RepeatAll && req.Mode != RepeatOne {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "mode must be off, all or one")
+		return
+	}
+
+	state, err := h.service.SetRepeat(r.Context(), userCtx.UserID.String(), req.Mode)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update repeat")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
 // ClearQueue handles DELETE /api/v1/queue
 func (h *Handlers) ClearQueue(w http.ResponseWriter, r *http.Request) {
 	userCtx := auth.GetUserFromContext(r.Context())
@@ -517,12 +916,17 @@ func (h *Handlers) ClearQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.ClearQueue(r.Context(), userCtx.UserID.String()); err != nil {
+	userID := userCtx.UserID.String()
+	if err := h.service.ClearQueue(r.Context(), userID); err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to clear queue")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), &QueueState{Items: []QueueItem{}, CurrentPosition: 0, UpdatedAt: time.Now()}, nil))
+	state, err := h.service.GetQueue(r.Context(), userID)
+	if err != nil {
+		state = &QueueState{Items: []QueueItem{}, CurrentPosition: 0, UpdatedAt: time.Now()}
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
 }
 
 func (h *Handlers) resolveDownloadBackedItems(r *http.Request, userID string, state *QueueState) map[string]*download.DownloadJob {
@@ -550,7 +954,7 @@ func (h *Handlers) resolveDownloadBackedItems(r *http.Request, userID string, st
 					changed = true
 				}
 			}
-		case download.StatusFailed:
+		case download.StatusFailed, download.StatusCancelled:
 			if item.PlaybackState != "failed" {
 				item.PlaybackState = "failed"
 				changed = true
@@ -614,11 +1018,30 @@ func buildQueueResponseWithAnalysis(state *QueueState, jobs map[string]*download
 	for i, item := range state.Items {
 		items[i] = buildQueueItemResponse(item, state.UpdatedAt, jobs[item.DownloadJobID], analysis)
 	}
-	return QueueResponse{
+	repeat := state.Repeat
+	if repeat == "" {
+		repeat = RepeatOff
+	}
+	name := state.Name
+	if name == "" {
+		name = DefaultQueueName
+	}
+	resp := QueueResponse{
 		Items:           items,
 		CurrentPosition: state.CurrentPosition,
 		UpdatedAt:       state.UpdatedAt,
-	}
+		Shuffle:         state.Shuffle,
+		Repeat:          repeat,
+		PositionMs:      state.PositionMs,
+		Paused:          state.Paused,
+		Radio:           state.Radio,
+		Version:         state.Version,
+		Name:            name,
+	}
+	if !state.PositionUpdatedAt.IsZero() {
+		resp.PositionUpdatedAt = &state.PositionUpdatedAt
+	}
+	return resp
 }
 
 func buildQueueItemResponse(item QueueItem, updatedAt time.Time, job *download.DownloadJob, analysis map[int64]db.AnalysisCompact) QueueItemResponse {
@@ -639,7 +1062,7 @@ func buildQueueItemResponse(item QueueItem, updatedAt time.Time, job *download.D
 				progress = 100
 				trackID = job.TrackID
 			}
-		case download.StatusFailed:
+		case download.StatusFailed, download.StatusCancelled:
 			state = "failed"
 			if job.Error != "" {
 				err := job.Error
@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakePlayRecorder struct {
+	plays []db.PlayRecord
+}
+
+func (f *fakePlayRecorder) RecordPlayEvent(_ context.Context, play db.PlayRecord) error {
+	f.plays = append(f.plays, play)
+	return nil
+}
+
+func currentPositionRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/queue/current", strings.NewReader(body))
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.MustParse("11111111-1111-1111-1111-111111111111")}))
+}
+
+func twoTrackQueue() *QueueState {
+	first, second := int64(7), int64(8)
+	return &QueueState{Items: []QueueItem{
+		{ID: "item-a", Position: 0, Kind: "track", TrackID: &first, PlaybackState: "playable"},
+		{ID: "item-b", Position: 1, Kind: "track", TrackID: &second, PlaybackState: "playable"},
+	}}
+}
+
+func TestSetCurrentPositionRecordsPlayOfItemLeft(t *testing.T) {
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	plays := &fakePlayRecorder{}
+	h := NewHandlers(service)
+	h.SetPlays(plays)
+
+	rec := httptest.NewRecorder()
+	before := time.Now()
+	h.SetCurrentPosition(rec, currentPositionRequest(`{"position":1,"listenedMs":120000}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if service.state.CurrentPosition != 1 {
+		t.Fatalf("current position = %d, want 1", service.state.CurrentPosition)
+	}
+	if len(plays.plays) != 1 {
+		t.Fatalf("recorded plays = %d, want 1", len(plays.plays))
+	}
+	play := plays.plays[0]
+	if play.TrackID != 7 || play.ContextType != "queue" || play.ContextID != "item-a" || play.DurationListenedMs.Int64 != 120000 {
+		t.Fatalf("recorded play = %#v, want track 7 from queue item-a with 120000ms", play)
+	}
+	if started := before.Add(-2 * time.Minute); play.PlayedAt.Before(started.Add(-time.Second)) || play.PlayedAt.After(time.Now().Add(-2*time.Minute)) {
+		t.Fatalf("playedAt = %v, want about two minutes ago", play.PlayedAt)
+	}
+}
+
+func TestSetCurrentPositionRecordsNothingWithoutListen(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"no listenedMs", `{"position":1}`},
+		{"zero listenedMs", `{"position":1,"listenedMs":0}`},
+		{"same item", `{"position":0,"listenedMs":5000}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plays := &fakePlayRecorder{}
+			h := NewHandlers(&fakeQueueHandlerService{state: twoTrackQueue()})
+			h.SetPlays(plays)
+			rec := httptest.NewRecorder()
+			h.SetCurrentPosition(rec, currentPositionRequest(tc.body))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+			}
+			if len(plays.plays) != 0 {
+				t.Fatalf("recorded plays = %#v, want none", plays.plays)
+			}
+		})
+	}
+}
+
+func TestSetCurrentPositionRejectsInvalidRequests(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"out of range", `{"position":2}`},
+		{"negative position", `{"position":-1}`},
+		{"negative listen", `{"position":1,"listenedMs":-5}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &fakeQueueHandlerService{state: twoTrackQueue()}
+			h := NewHandlers(service)
+			rec := httptest.NewRecorder()
+			h.SetCurrentPosition(rec, currentPositionRequest(tc.body))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body=%s", rec.Code, rec.Body.String())
+			}
+			if service.state.CurrentPosition != 0 {
+				t.Fatalf("current position moved to %d on a rejected request", service.state.CurrentPosition)
+			}
+		})
+	}
+}
+
+func TestPlaybackPositionHeartbeatAndResume(t *testing.T) {
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	h := NewHandlers(service)
+
+	rec := httptest.NewRecorder()
+	h.UpdatePlaybackPosition(rec, currentPositionRequest(`{"queueItemId":"item-a","positionMs":42000,"paused":true}`))
+	if rec.Code != http.StatusNoContent || service.state.PositionMs != 42000 || !service.state.Paused {
+		t.Fatalf("status = %d, state = %+v; want 204 with the position saved", rec.Code, service.state)
+	}
+
+	rec = httptest.NewRecorder()
+	h.UpdatePlaybackPosition(rec, currentPositionRequest(`{"queueItemId":"item-b","positionMs":1000}`))
+	if rec.Code != http.StatusConflict || service.state.PositionMs != 42000 {
+		t.Fatalf("stale heartbeat status = %d at %dms, want 409 and the position kept", rec.Code, service.state.PositionMs)
+	}
+
+	rec = httptest.NewRecorder()
+	h.SetCurrentPosition(rec, currentPositionRequest(`{"position":1,"positionMs":5000}`))
+	if rec.Code != http.StatusOK || service.state.PositionMs != 5000 {
+		t.Fatalf("resume status = %d at %dms, want item-b from 5000ms", rec.Code, service.state.PositionMs)
+	}
+
+	for _, body := range []string{`{"positionMs":1}`, `{"queueItemId":"item-b"}`, `{"queueItemId":"item-b","positionMs":-1}`} {
+		rec := httptest.NewRecorder()
+		h.UpdatePlaybackPosition(rec, currentPositionRequest(body))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want 400", body, rec.Code)
+		}
+	}
+}
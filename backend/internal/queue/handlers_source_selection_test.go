@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -42,6 +44,17 @@ func (s *fakeQueueHandlerService) AddToQueue(_ context.Context, _ string, trackI
 	s.state.Items = append(s.state.Items, QueueItem{ID: "track-item", Kind: "track", TrackID: &trackID, PlaybackState: "playable", AddedAt: time.Now(), UpdatedAt: time.Now()})
 	return s.state, nil
 }
+func (s *fakeQueueHandlerService) AddMultipleToQueue(_ context.Context, _ string, trackIDs []int64, _ string) (*QueueState, error) {
+	for _, trackID := range trackIDs {
+		trackID := trackID
+		s.state.Items = append(s.state.Items, QueueItem{ID: fmt.Sprintf("track-%d", trackID), Kind: "track", TrackID: &trackID, PlaybackState: "playable", AddedAt: time.Now(), UpdatedAt: time.Now()})
+	}
+	return s.state, nil
+}
+func (s *fakeQueueHandlerService) ReplaceQueue(ctx context.Context, userID string, trackIDs []int64) (*QueueState, error) {
+	s.state.Items, s.state.CurrentPosition = nil, 0
+	return s.AddMultipleToQueue(ctx, userID, trackIDs, "last")
+}
 func (s *fakeQueueHandlerService) ValidateInsertPosition(context.Context, string, string) error {
 	return nil
 }
@@ -77,7 +90,46 @@ func (s *fakeQueueHandlerService) RetryQueueItem(context.Context, string, string
 func (s *fakeQueueHandlerService) ReorderQueueItem(context.Context, string, string, int) (*QueueState, error) {
 	return nil, ErrTrackNotFound
 }
-func (s *fakeQueueHandlerService) ClearQueue(context.Context, string) error             { return nil }
+func (s *fakeQueueHandlerService) SetCurrentPosition(_ context.Context, _ string, position int) (*QueueState, *QueueItem, error) {
+	if position < 0 || position >= len(s.state.Items) {
+		return nil, nil, ErrInvalidPosition
+	}
+	var previous *QueueItem
+	if s.state.CurrentPosition < len(s.state.Items) {
+		item := s.state.Items[s.state.CurrentPosition]
+		previous = &item
+	}
+	if previous == nil || previous.ID != s.state.Items[position].ID {
+		s.state.PositionMs = 0
+	}
+	s.state.CurrentPosition = position
+	return s.state, previous, nil
+}
+func (s *fakeQueueHandlerService) SetPlaybackPosition(_ context.Context, _ string, itemID string, positionMs int64, paused bool) (*QueueState, error) {
+	if s.state.CurrentPosition >= len(s.state.Items) || s.state.Items[s.state.CurrentPosition].ID != itemID {
+		return nil, ErrNotCurrentItem
+	}
+	s.state.PositionMs, s.state.Paused, s.state.PositionUpdatedAt = positionMs, paused, time.Now()
+	return s.state, nil
+}
+func (s *fakeQueueHandlerService) SetShuffle(_ context.Context, _ string, enabled bool) (*QueueState, error) {
+	if enabled && !s.state.Shuffle {
+		shuffleUpcoming(s.state, rand.New(rand.NewSource(1)))
+	} else if !enabled && s.state.Shuffle {
+		unshuffle(s.state)
+	}
+	return s.state, nil
+}
+func (s *fakeQueueHandlerService) SetRepeat(_ context.Context, _ string, mode string) (*QueueState, error) {
+	s.state.Repeat = mode
+	return s.state, nil
+}
+func (s *fakeQueueHandlerService) ClearQueue(context.Context, string) error {
+	if s.state != nil {
+		s.state.Items, s.state.CurrentPosition = nil, 0
+	}
+	return nil
+}
 func (s *fakeQueueHandlerService) saveQueue(context.Context, string, *QueueState) error { return nil }
 
 type fakeQueueDownloadService struct {
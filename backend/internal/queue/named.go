@@ -0,0 +1,487 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+const (
+	// DefaultQueueName is the queue every user has. It is stored under the
+	// original single-queue key, so queues saved before named queues existed
+	// become the main queue.
+	DefaultQueueName = "main"
+
+	// Redis key prefix for the index of a user's named queues.
+	keyQueueIndexPrefix = "playqueues:"
+
+	// maxQueues caps how many named queues a user may keep, main included.
+	maxQueues = 10
+)
+
+var (
+	ErrQueueNotFound    = errors.New("queue not found")
+	ErrQueueExists      = errors.New("queue already exists")
+	ErrTooManyQueues    = errors.New("too many queues")
+	ErrInvalidQueueName = errors.New("invalid queue name")
+	ErrActiveQueue      = errors.New("queue is active")
+)
+
+var queueNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// queueIndex lists a user's named queues and which one is active. Every
+// queue operation that does not name a queue acts on the active one.
+type queueIndex struct {
+	Active string   `json:"active"`
+	Names  []string `json:"names"`
+}
+
+// QueueSummary describes one of a user's named queues.
+type QueueSummary struct {
+	Name      string    `json:"name"`
+	Items     int       `json:"items"`
+	Active    bool      `json:"active"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// queueKey returns the Redis key for one of a user's queues.
+func (s *Service) queueKey(userID, name string) string {
+	if name == "" || name == DefaultQueueName {
+		return keyQueuePrefix + userID
+	}
+	return keyQueuePrefix + userID + ":" + name
+}
+
+func (s *Service) queueIndexKey(userID string) string {
+	return keyQueueIndexPrefix + userID
+}
+
+// loadIndex returns the user's queue index; a user who has never created a
+// queue has only main.
+func (s *Service) loadIndex(ctx context.Context, userID string) (*queueIndex, error) {
+	data, err := s.client.Get(ctx, s.queueIndexKey(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		restored, ok, restoreErr := s.restoreIndex(ctx, userID)
+		if restoreErr != nil {
+			return nil, restoreErr
+		}
+		if !ok {
+			return &queueIndex{Active: DefaultQueueName, Names: []string{DefaultQueueName}}, nil
+		}
+		data, err = string(restored), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue index: %w", err)
+	}
+	var index queueIndex
+	if err := json.Unmarshal([]byte(data), &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue index: %w", err)
+	}
+	return &index, nil
+}
+
+func (s *Service) setIndex(ctx context.Context, pipe redis.Pipeliner, userID string, index *queueIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue index: %w", err)
+	}
+	pipe.Set(ctx, s.queueIndexKey(userID), data, queueTTL)
+	return nil
+}
+
+// loadQueue reads one of the user's queues by name.
+func (s *Service) loadQueue(ctx context.Context, userID, name string) (*QueueState, error) {
+	data, err := s.client.Get(ctx, s.queueKey(userID, name)).Result()
+	if errors.Is(err, redis.Nil) {
+		restored, ok, restoreErr := s.restoreQueue(ctx, userID, name)
+		if restoreErr != nil {
+			return nil, restoreErr
+		}
+		if !ok {
+			// Return empty queue if none exists
+			return &QueueState{
+				Items:           []QueueItem{},
+				CurrentPosition: 0,
+				UpdatedAt:       time.Now(),
+				Name:            name,
+			}, nil
+		}
+		data, err = string(restored), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+
+	var state QueueState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue: %w", err)
+	}
+	state.Name = name
+	return &state, nil
+}
+
+// ListQueues lists the user's queues in the order they were created.
+func (s *Service) ListQueues(ctx context.Context, userID string) ([]QueueSummary, error) {
+	index, err := s.loadIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]QueueSummary, 0, len(index.Names))
+	for _, name := range index.Names {
+		state, err := s.loadQueue(ctx, userID, name)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, QueueSummary{
+			Name:      name,
+			Items:     len(state.Items),
+			Active:    name == index.Active,
+			UpdatedAt: state.UpdatedAt,
+		})
+	}
+	return summaries, nil
+}
+
+// CreateQueue adds an empty queue without switching to it.
+func (s *Service) CreateQueue(ctx context.Context, userID, name string) (*QueueSummary, error) {
+	if !queueNamePattern.MatchString(name) {
+		return nil, ErrInvalidQueueName
+	}
+	index, err := s.loadIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(index.Names, name) {
+		return nil, ErrQueueExists
+	}
+	if len(index.Names) >= maxQueues {
+		return nil, ErrTooManyQueues
+	}
+	index.Names = append(index.Names, name)
+
+	state := &QueueState{Items: []QueueItem{}, UpdatedAt: time.Now(), Name: name}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queue: %w", err)
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.queueKey(userID, name), data, queueTTL)
+		return s.setIndex(ctx, pipe, userID, index)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue: %w", err)
+	}
+	s.snapshotQueue(ctx, userID, name, state.Version, data)
+	s.snapshotIndex(ctx, userID, index)
+	return &QueueSummary{Name: name, Items: 0, UpdatedAt: state.UpdatedAt}, nil
+}
+
+// SwitchQueue makes the named queue the active one on all of the user's
+// devices and returns it.
+func (s *Service) SwitchQueue(ctx context.Context, userID, name string) (*QueueState, error) {
+	index, err := s.loadIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(index.Names, name) {
+		return nil, ErrQueueNotFound
+	}
+	state, err := s.loadQueue(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	if index.Active == name {
+		return state, nil
+	}
+
+	index.Active = name
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return s.setIndex(ctx, pipe, userID, index)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch queue: %w", err)
+	}
+	s.snapshotIndex(ctx, userID, index)
+	return state, nil
+}
+
+// MergeQueue moves the named queue's items into the active queue at
+// position ("next" or "last") in one write. The named queue is deleted, or
+// emptied when it is main.
+func (s *Service) MergeQueue(ctx context.Context, userID, name, position string) (*QueueState, error) {
+	index, err := s.loadIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(index.Names, name) {
+		return nil, ErrQueueNotFound
+	}
+	if name == index.Active {
+		return nil, ErrActiveQueue
+	}
+	from, err := s.loadQueue(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.loadQueue(ctx, userID, index.Active)
+	if err != nil {
+		return nil, err
+	}
+	if err := mergeQueue(state, from, position); err != nil {
+		return nil, err
+	}
+	s.recalculatePositions(state)
+	state.UpdatedAt = time.Now()
+	state.Version++
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queue: %w", err)
+	}
+	var emptied []byte
+	emptiedVersion := from.Version + 1
+	if name == DefaultQueueName {
+		emptied, err = json.Marshal(&QueueState{Items: []QueueItem{}, UpdatedAt: state.UpdatedAt, Repeat: from.Repeat, Version: emptiedVersion})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal queue: %w", err)
+		}
+	} else {
+		index.Names = slices.DeleteFunc(index.Names, func(n string) bool { return n == name })
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.queueKey(userID, index.Active), data, queueTTL)
+		if emptied != nil {
+			pipe.Set(ctx, s.queueKey(userID, name), emptied, queueTTL)
+			return nil
+		}
+		pipe.Del(ctx, s.queueKey(userID, name))
+		return s.setIndex(ctx, pipe, userID, index)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge queue: %w", err)
+	}
+	s.snapshotQueue(ctx, userID, index.Active, state.Version, data)
+	if emptied != nil {
+		s.snapshotQueue(ctx, userID, name, emptiedVersion, emptied)
+	} else {
+		s.deleteQueueSnapshot(ctx, userID, name)
+		s.snapshotIndex(ctx, userID, index)
+	}
+	return state, nil
+}
+
+// mergeQueue inserts from's items into state at position. Items keep their
+// IDs, so source items stay tied to their download intents.
+func mergeQueue(state, from *QueueState, position string) error {
+	if position != "next" && position != "last" && position != "" {
+		return ErrInvalidPosition
+	}
+	insertIdx, _, err := resolveInsertPosition(state, position)
+	if err != nil {
+		return err
+	}
+	state.Items = insertMultipleAt(state.Items, insertIdx, from.Items)
+	return nil
+}
+
+// DeleteQueue deletes one of the user's queues. Main and the active queue
+// cannot be deleted.
+func (s *Service) DeleteQueue(ctx context.Context, userID, name string) error {
+	index, err := s.loadIndex(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(index.Names, name) {
+		return ErrQueueNotFound
+	}
+	if name == index.Active || name == DefaultQueueName {
+		return ErrActiveQueue
+	}
+	index.Names = slices.DeleteFunc(index.Names, func(n string) bool { return n == name })
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.queueKey(userID, name))
+		return s.setIndex(ctx, pipe, userID, index)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete queue: %w", err)
+	}
+	s.deleteQueueSnapshot(ctx, userID, name)
+	s.snapshotIndex(ctx, userID, index)
+	return nil
+}
+
+// NamedQueues manages a user's named queues. Service satisfies it.
+type NamedQueues interface {
+	ListQueues(ctx context.Context, userID string) ([]QueueSummary, error)
+	CreateQueue(ctx context.Context, userID, name string) (*QueueSummary, error)
+	SwitchQueue(ctx context.Context, userID, name string) (*QueueState, error)
+	MergeQueue(ctx context.Context, userID, name, position string) (*QueueState, error)
+	DeleteQueue(ctx context.Context, userID, name string) error
+}
+
+// SetNamedQueues enables the /api/v1/queues endpoints.
+func (h *Handlers) SetNamedQueues(queues NamedQueues) {
+	h.queues = queues
+}
+
+// QueueListResponse lists a user's named queues.
+type QueueListResponse struct {
+	Queues []QueueSummary `json:"queues"`
+}
+
+// QueueNameRequest names a queue to create or switch to.
+type QueueNameRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeQueueRequest says where in the active queue merged items go: "next"
+// or "last" (the default).
+type MergeQueueRequest struct {
+	Position string `json:"position"`
+}
+
+// ListQueues handles GET /api/v1/queues.
+func (h *Handlers) ListQueues(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.namedQueuesUser(w, r)
+	if !ok {
+		return
+	}
+	queues, err := h.queues.ListQueues(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list queues")
+		return
+	}
+	writeJSON(w, http.StatusOK, QueueListResponse{Queues: queues})
+}
+
+// CreateQueue handles POST /api/v1/queues, adding an empty queue such as
+// "party" or "later" alongside main. Names are 1-32 lowercase letters,
+// digits and hyphens.
+func (h *Handlers) CreateQueue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.namedQueuesUser(w, r)
+	if !ok {
+		return
+	}
+	var req QueueNameRequest
+	if !decodeNamedQueueRequest(w, r, &req) {
+		return
+	}
+
+	summary, err := h.queues.CreateQueue(r.Context(), userID, req.Name)
+	switch {
+	case errors.Is(err, ErrInvalidQueueName):
+		writeError(w, http.StatusBadRequest, "INVALID_QUEUE_NAME", "name must be 1-32 lowercase letters, digits or '-'")
+	case errors.Is(err, ErrQueueExists):
+		writeError(w, http.StatusConflict, "QUEUE_EXISTS", "a queue with this name already exists")
+	case errors.Is(err, ErrTooManyQueues):
+		writeError(w, http.StatusConflict, "QUEUE_LIMIT", fmt.Sprintf("at most %d queues are allowed", maxQueues))
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create queue")
+	default:
+		writeJSON(w, http.StatusCreated, summary)
+	}
+}
+
+// SwitchQueue handles PUT /api/v1/queues/active, making the named queue the
+// one the /api/v1/queue endpoints act on, and returns it.
+func (h *Handlers) SwitchQueue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.namedQueuesUser(w, r)
+	if !ok {
+		return
+	}
+	var req QueueNameRequest
+	if !decodeNamedQueueRequest(w, r, &req) {
+		return
+	}
+
+	state, err := h.queues.SwitchQueue(r.Context(), userID, req.Name)
+	if errors.Is(err, ErrQueueNotFound) {
+		writeError(w, http.StatusNotFound, "QUEUE_NOT_FOUND", "queue not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to switch queue")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// MergeQueue handles POST /api/v1/queues/{name}/merge, moving that queue's
+// items into the active queue, and returns the active queue.
+func (h *Handlers) MergeQueue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.namedQueuesUser(w, r)
+	if !ok {
+		return
+	}
+	var req MergeQueueRequest
+	if r.ContentLength != 0 && !decodeNamedQueueRequest(w, r, &req) {
+		return
+	}
+
+	state, err := h.queues.MergeQueue(r.Context(), userID, r.PathValue("name"), req.Position)
+	switch {
+	case errors.Is(err, ErrQueueNotFound):
+		writeError(w, http.StatusNotFound, "QUEUE_NOT_FOUND", "queue not found")
+	case errors.Is(err, ErrActiveQueue):
+		writeError(w, http.StatusConflict, "ACTIVE_QUEUE", "cannot merge the active queue into itself")
+	case errors.Is(err, ErrInvalidPosition):
+		writeError(w, http.StatusBadRequest, "INVALID_POSITION", "position must be next or last")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to merge queue")
+	default:
+		writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+	}
+}
+
+// DeleteQueue handles DELETE /api/v1/queues/{name}.
+func (h *Handlers) DeleteQueue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.namedQueuesUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.queues.DeleteQueue(r.Context(), userID, r.PathValue("name"))
+	switch {
+	case errors.Is(err, ErrQueueNotFound):
+		writeError(w, http.StatusNotFound, "QUEUE_NOT_FOUND", "queue not found")
+	case errors.Is(err, ErrActiveQueue):
+		writeError(w, http.StatusConflict, "ACTIVE_QUEUE", "main and the active queue cannot be deleted")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete queue")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *Handlers) namedQueuesUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return "", false
+	}
+	if h.queues == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "named queues are disabled")
+		return "", false
+	}
+	return userCtx.UserID.String(), true
+}
+
+func decodeNamedQueueRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeNamedQueues keeps queues in memory, mirroring Service's rules.
+type fakeNamedQueues struct {
+	active string
+	names  []string
+	states map[string]*QueueState
+}
+
+func newFakeNamedQueues() *fakeNamedQueues {
+	return &fakeNamedQueues{active: DefaultQueueName, names: []string{DefaultQueueName}, states: map[string]*QueueState{DefaultQueueName: twoTrackQueue()}}
+}
+
+func (f *fakeNamedQueues) ListQueues(context.Context, string) ([]QueueSummary, error) {
+	var summaries []QueueSummary
+	for _, name := range f.names {
+		summaries = append(summaries, QueueSummary{Name: name, Items: len(f.states[name].Items), Active: name == f.active})
+	}
+	return summaries, nil
+}
+
+func (f *fakeNamedQueues) CreateQueue(_ context.Context, _ string, name string) (*QueueSummary, error) {
+	if !queueNamePattern.MatchString(name) {
+		return nil, ErrInvalidQueueName
+	}
+	if slices.Contains(f.names, name) {
+		return nil, ErrQueueExists
+	}
+	f.names = append(f.names, name)
+	f.states[name] = &QueueState{Items: []QueueItem{}, UpdatedAt: time.Now(), Name: name}
+	return &QueueSummary{Name: name}, nil
+}
+
+func (f *fakeNamedQueues) SwitchQueue(_ context.Context, _ string, name string) (*QueueState, error) {
+	if !slices.Contains(f.names, name) {
+		return nil, ErrQueueNotFound
+	}
+	f.active = name
+	return f.states[name], nil
+}
+
+func (f *fakeNamedQueues) MergeQueue(_ context.Context, _ string, name, position string) (*QueueState, error) {
+	if !slices.Contains(f.names, name) {
+		return nil, ErrQueueNotFound
+	}
+	if name == f.active {
+		return nil, ErrActiveQueue
+	}
+	state := f.states[f.active]
+	if err := mergeQueue(state, f.states[name], position); err != nil {
+		return nil, err
+	}
+	f.names = slices.DeleteFunc(f.names, func(n string) bool { return n == name })
+	return state, nil
+}
+
+func (f *fakeNamedQueues) DeleteQueue(context.Context, string, string) error { return nil }
+
+func TestNamedQueuesCreateSwitchAndMerge(t *testing.T) {
+	queues := newFakeNamedQueues()
+	h := NewHandlers(&fakeQueueHandlerService{state: &QueueState{}})
+	h.SetNamedQueues(queues)
+
+	rec := httptest.NewRecorder()
+	h.CreateQueue(rec, radioRequest(http.MethodPost, "/api/v1/queues", `{"name":"party"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.CreateQueue(rec, radioRequest(http.MethodPost, "/api/v1/queues", `{"name":"party"}`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate create status = %d, want 409", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.SwitchQueue(rec, radioRequest(http.MethodPut, "/api/v1/queues/active", `{"name":"party"}`))
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || resp.Name != "party" || len(resp.Items) != 0 {
+		t.Fatalf("switch = %d %+v, want the empty party queue", rec.Code, resp)
+	}
+
+	req := radioRequest(http.MethodPost, "/api/v1/queues/main/merge", `{"position":"next"}`)
+	req.SetPathValue("name", DefaultQueueName)
+	rec = httptest.NewRecorder()
+	h.MergeQueue(rec, req)
+	resp = QueueResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(resp.Items) != 2 {
+		t.Fatalf("merge = %d %+v, want main's two items in party", rec.Code, resp)
+	}
+
+	req = radioRequest(http.MethodPost, "/api/v1/queues/party/merge", ``)
+	req.SetPathValue("name", "party")
+	rec = httptest.NewRecorder()
+	h.MergeQueue(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("merging the active queue = %d, want 409", rec.Code)
+	}
+}
+
+func TestNamedQueuesValidateRequests(t *testing.T) {
+	h := NewHandlers(&fakeQueueHandlerService{state: &QueueState{}})
+	rec := httptest.NewRecorder()
+	h.ListQueues(rec, radioRequest(http.MethodGet, "/api/v1/queues", ``))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without named queues = %d, want 503", rec.Code)
+	}
+
+	h.SetNamedQueues(newFakeNamedQueues())
+	for _, body := range []string{`{"name":"Party Mix"}`, `{"name":""}`, `{"name":"party","x":1}`} {
+		rec := httptest.NewRecorder()
+		h.CreateQueue(rec, radioRequest(http.MethodPost, "/api/v1/queues", body))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("create %s = %d, want 400", body, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.SwitchQueue(rec, radioRequest(http.MethodPut, "/api/v1/queues/active", `{"name":"later"}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("switch to a missing queue = %d, want 404", rec.Code)
+	}
+}
+
+func TestMergeQueueInsertsAfterCurrent(t *testing.T) {
+	state := twoTrackQueue()
+	from := &QueueState{Items: []QueueItem{{ID: "item-c"}}}
+	if err := mergeQueue(state, from, "next"); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, item := range state.Items {
+		ids = append(ids, item.ID)
+	}
+	if want := []string{"item-a", "item-c", "item-b"}; !slices.Equal(ids, want) {
+		t.Fatalf("items = %v, want %v", ids, want)
+	}
+	if err := mergeQueue(state, from, "2"); err != ErrInvalidPosition {
+		t.Fatalf("index position err = %v, want ErrInvalidPosition", err)
+	}
+}
@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+// maxArtistTracklists bounds how many of an artist's releases one play-artist
+// request looks up; releases past it keep album and title order.
+const maxArtistTracklists = 20
+
+// EntitySource lists the library tracks on a release or by an artist.
+// db.LibraryRepository satisfies it.
+type EntitySource interface {
+	ReleaseTracks(ctx context.Context, userID, releaseID uuid.UUID) ([]db.EntityTrack, error)
+	ArtistTracks(ctx context.Context, userID, artistID uuid.UUID) ([]db.EntityTrack, error)
+}
+
+// Tracklists looks up a release's track listing and date.
+// musicbrainz.Client satisfies it.
+type Tracklists interface {
+	GetRelease(ctx context.Context, mbID string) (*musicbrainz.Release, error)
+}
+
+// SetEntitySources enables POST /api/v1/queue/play-album and play-artist.
+// Without tracklists, or when a lookup fails, tracks play in album and title
+// order.
+func (h *Handlers) SetEntitySources(entities EntitySource, tracklists Tracklists) {
+	h.entities = entities
+	h.tracklists = tracklists
+}
+
+// PlayEntityRequest says where an album or artist goes: "replace" (the
+// default) swaps out the whole queue, "next" or "last" insert.
+type PlayEntityRequest struct {
+	Position string `json:"position"`
+}
+
+// PlayAlbum handles POST /api/v1/queue/play-album/{mb_release_id}, queueing
+// the caller's library tracks on the release in track listing order.
+func (h *Handlers) PlayAlbum(w http.ResponseWriter, r *http.Request) {
+	h.playEntity(w, r, "mb_release_id", func(ctx context.Context, userID, releaseID uuid.UUID) ([]int64, error) {
+		tracks, err := h.entities.ReleaseTracks(ctx, userID, releaseID)
+		if err != nil || len(tracks) == 0 {
+			return nil, err
+		}
+		return orderByTracklist(tracks, h.tracklist(ctx, releaseID)), nil
+	})
+}
+
+// PlayArtist handles POST /api/v1/queue/play-artist/{mb_artist_id}, queueing
+// the caller's library tracks by the artist album by album, oldest release
+// first, each in track listing order.
+func (h *Handlers) PlayArtist(w http.ResponseWriter, r *http.Request) {
+	h.playEntity(w, r, "mb_artist_id", func(ctx context.Context, userID, artistID uuid.UUID) ([]int64, error) {
+		tracks, err := h.entities.ArtistTracks(ctx, userID, artistID)
+		if err != nil || len(tracks) == 0 {
+			return nil, err
+		}
+		tracklists := map[uuid.UUID]*musicbrainz.Release{}
+		for _, t := range tracks {
+			if t.MBReleaseID == nil || len(tracklists) >= maxArtistTracklists {
+				continue
+			}
+			if _, seen := tracklists[*t.MBReleaseID]; !seen {
+				tracklists[*t.MBReleaseID] = h.tracklist(ctx, *t.MBReleaseID)
+			}
+		}
+		return orderByRelease(tracks, tracklists), nil
+	})
+}
+
+func (h *Handlers) playEntity(w http.ResponseWriter, r *http.Request, param string, resolve func(context.Context, uuid.UUID, uuid.UUID) ([]int64, error)) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.entities == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "playing albums and artists is disabled")
+		return
+	}
+	entityID, err := uuid.Parse(r.PathValue(param))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", param+" must be a MusicBrainz ID")
+		return
+	}
+
+	var req PlayEntityRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if req.Position == "" {
+		req.Position = "replace"
+	}
+	userID := userCtx.UserID.String()
+	if req.Position != "replace" {
+		if err := h.service.ValidateInsertPosition(r.Context(), userID, req.Position); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_POSITION", "invalid position")
+			return
+		}
+	}
+
+	trackIDs, err := resolve(r.Context(), userCtx.UserID, entityID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load library tracks")
+		return
+	}
+	if len(trackIDs) == 0 {
+		writeError(w, http.StatusNotFound, "NO_LIBRARY_TRACKS", "no library tracks to play")
+		return
+	}
+
+	var state *QueueState
+	if req.Position == "replace" {
+		state, err = h.service.ReplaceQueue(r.Context(), userID, trackIDs)
+	} else {
+		state, err = h.service.AddMultipleToQueue(r.Context(), userID, trackIDs, req.Position)
+	}
+	if err != nil {
+		if err == ErrInvalidPosition {
+			writeError(w, http.StatusBadRequest, "INVALID_POSITION", "invalid position")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add tracks to queue")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// tracklist returns the release's track listing, or nil when it cannot be
+// looked up; the queue then falls back to title order rather than failing.
+func (h *Handlers) tracklist(ctx context.Context, releaseID uuid.UUID) *musicbrainz.Release {
+	if h.tracklists == nil {
+		return nil
+	}
+	release, err := h.tracklists.GetRelease(ctx, releaseID.String())
+	if err != nil {
+		log.Printf("Warning: track listing lookup failed for release %s: %v", releaseID, err)
+		return nil
+	}
+	return release
+}
+
+// orderByTracklist puts tracks in the release's track listing order. Tracks
+// the listing does not name follow in their given order.
+func orderByTracklist(tracks []db.EntityTrack, release *musicbrainz.Release) []int64 {
+	index := map[string]int{}
+	if release != nil {
+		for i, t := range release.Tracks {
+			if _, dup := index[t.ID]; !dup {
+				index[t.ID] = i
+			}
+		}
+	}
+	rank := func(t db.EntityTrack) int {
+		if t.MBRecordingID != nil {
+			if i, ok := index[t.MBRecordingID.String()]; ok {
+				return i
+			}
+		}
+		return len(index)
+	}
+	ordered := append([]db.EntityTrack(nil), tracks...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+
+	trackIDs := make([]int64, len(ordered))
+	for i, t := range ordered {
+		trackIDs[i] = t.TrackID
+	}
+	return trackIDs
+}
+
+// orderByRelease groups tracks by release, oldest release date first, and
+// orders each group by its track listing. Releases without a known date keep
+// their given order after dated ones; tracks on no release come last.
+func orderByRelease(tracks []db.EntityTrack, tracklists map[uuid.UUID]*musicbrainz.Release) []int64 {
+	var releases []uuid.UUID
+	groups := map[uuid.UUID][]db.EntityTrack{}
+	var loose []db.EntityTrack
+	for _, t := range tracks {
+		if t.MBReleaseID == nil {
+			loose = append(loose, t)
+			continue
+		}
+		if _, seen := groups[*t.MBReleaseID]; !seen {
+			releases = append(releases, *t.MBReleaseID)
+		}
+		groups[*t.MBReleaseID] = append(groups[*t.MBReleaseID], t)
+	}
+	date := func(id uuid.UUID) string {
+		if release := tracklists[id]; release != nil {
+			return release.Date
+		}
+		return ""
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := date(releases[i]), date(releases[j])
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		return a < b
+	})
+
+	trackIDs := make([]int64, 0, len(tracks))
+	for _, id := range releases {
+		trackIDs = append(trackIDs, orderByTracklist(groups[id], tracklists[id])...)
+	}
+	for _, t := range loose {
+		trackIDs = append(trackIDs, t.TrackID)
+	}
+	return trackIDs
+}
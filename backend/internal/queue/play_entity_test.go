@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+type fakeEntitySource struct {
+	release []db.EntityTrack
+	artist  []db.EntityTrack
+}
+
+func (f *fakeEntitySource) ReleaseTracks(context.Context, uuid.UUID, uuid.UUID) ([]db.EntityTrack, error) {
+	return f.release, nil
+}
+
+func (f *fakeEntitySource) ArtistTracks(context.Context, uuid.UUID, uuid.UUID) ([]db.EntityTrack, error) {
+	return f.artist, nil
+}
+
+type fakeTracklists map[string]*musicbrainz.Release
+
+func (f fakeTracklists) GetRelease(_ context.Context, mbID string) (*musicbrainz.Release, error) {
+	if release, ok := f[mbID]; ok {
+		return release, nil
+	}
+	return nil, errors.New("musicbrainz unavailable")
+}
+
+func entityTrack(id int64, recording, release *uuid.UUID) db.EntityTrack {
+	return db.EntityTrack{TrackID: id, MBRecordingID: recording, MBReleaseID: release}
+}
+
+func ptr(id uuid.UUID) *uuid.UUID { return &id }
+
+func TestOrderByTracklistFollowsListingThenGivenOrder(t *testing.T) {
+	r1, r2, r3 := uuid.New(), uuid.New(), uuid.New()
+	release := &musicbrainz.Release{Tracks: []musicbrainz.Track{{ID: r1.String()}, {ID: r2.String()}, {ID: r3.String()}}}
+	tracks := []db.EntityTrack{
+		entityTrack(10, nil, nil),
+		entityTrack(3, ptr(r3), nil),
+		entityTrack(11, ptr(uuid.New()), nil),
+		entityTrack(1, ptr(r1), nil),
+		entityTrack(2, ptr(r2), nil),
+	}
+
+	if got := orderByTracklist(tracks, release); !slices.Equal(got, []int64{1, 2, 3, 10, 11}) {
+		t.Fatalf("order = %v; want listing order then unlisted", got)
+	}
+	if got := orderByTracklist(tracks, nil); !slices.Equal(got, []int64{10, 3, 11, 1, 2}) {
+		t.Fatalf("order without listing = %v; want given order", got)
+	}
+}
+
+func TestOrderByReleaseOldestFirst(t *testing.T) {
+	early, late, unknown := uuid.New(), uuid.New(), uuid.New()
+	a, b := uuid.New(), uuid.New()
+	tracklists := map[uuid.UUID]*musicbrainz.Release{
+		early: {Date: "1999", Tracks: []musicbrainz.Track{{ID: b.String()}, {ID: a.String()}}},
+		late:  {Date: "2004-03-01"},
+	}
+	tracks := []db.EntityTrack{
+		entityTrack(1, nil, ptr(unknown)),
+		entityTrack(2, nil, ptr(late)),
+		entityTrack(3, nil, nil),
+		entityTrack(4, ptr(a), ptr(early)),
+		entityTrack(5, ptr(b), ptr(early)),
+	}
+
+	if got := orderByRelease(tracks, tracklists); !slices.Equal(got, []int64{5, 4, 2, 1, 3}) {
+		t.Fatalf("order = %v; want [5 4 2 1 3]", got)
+	}
+}
+
+func playEntityRequest(path, param, id, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path+id, strings.NewReader(body))
+	req.SetPathValue(param, id)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+}
+
+func TestPlayAlbumReplacesQueueInTracklistOrder(t *testing.T) {
+	releaseID := uuid.New()
+	r1, r2 := uuid.New(), uuid.New()
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	h := NewHandlers(service)
+	h.SetEntitySources(
+		&fakeEntitySource{release: []db.EntityTrack{entityTrack(21, ptr(r2), ptr(releaseID)), entityTrack(20, ptr(r1), ptr(releaseID))}},
+		fakeTracklists{releaseID.String(): {Tracks: []musicbrainz.Track{{ID: r1.String()}, {ID: r2.String()}}}},
+	)
+
+	rec := httptest.NewRecorder()
+	h.PlayAlbum(rec, playEntityRequest("/api/v1/queue/play-album/", "mb_release_id", releaseID.String(), ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 2 || *resp.Items[0].TrackID != 20 || *resp.Items[1].TrackID != 21 {
+		t.Fatalf("queue = %+v; want only tracks 20, 21", resp.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PlayAlbum(rec, playEntityRequest("/api/v1/queue/play-album/", "mb_release_id", releaseID.String(), `{"position":"last"}`))
+	if rec.Code != http.StatusOK || len(service.state.Items) != 4 {
+		t.Fatalf("append status = %d, queue length %d; want 200 and 4", rec.Code, len(service.state.Items))
+	}
+}
+
+func TestPlayArtistValidatesRequest(t *testing.T) {
+	service := &fakeQueueHandlerService{state: &QueueState{}}
+	h := NewHandlers(service)
+	artistID := uuid.NewString()
+
+	rec := httptest.NewRecorder()
+	h.PlayArtist(rec, playEntityRequest("/api/v1/queue/play-artist/", "mb_artist_id", artistID, ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without an entity source = %d, want 503", rec.Code)
+	}
+
+	h.SetEntitySources(&fakeEntitySource{}, nil)
+	for _, tc := range []struct {
+		id, body string
+		want     int
+	}{
+		{"not-an-mbid", "", http.StatusBadRequest},
+		{artistID, `{"position":"replace","shuffle":true}`, http.StatusBadRequest},
+		{artistID, "", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		h.PlayArtist(rec, playEntityRequest("/api/v1/queue/play-artist/", "mb_artist_id", tc.id, tc.body))
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s status = %d, want %d", tc.id, tc.body, rec.Code, tc.want)
+		}
+	}
+}
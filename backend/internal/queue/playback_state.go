@@ -0,0 +1,363 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/websocket"
+)
+
+const (
+	// PlaybackStateVersion is the version of the playback state document
+	// this server exports. Imports of other versions are rejected.
+	PlaybackStateVersion = 1
+
+	// Redis key prefix for the playback settings that travel with the queue.
+	keyPlaybackSettingsPrefix = "playsettings:"
+
+	maxPlaybackStateBytes   = 2 << 20
+	maxPlaybackStateItems   = 1000
+	maxPlaybackStateDevices = 10
+)
+
+// Repeat modes.
+const (
+	RepeatOff = "off"
+	RepeatAll = "all"
+	RepeatOne = "one"
+)
+
+var (
+	ErrUnsupportedStateVersion = errors.New("unsupported playback state version")
+	ErrInvalidPlaybackState    = errors.New("invalid playback state")
+)
+
+// PlaybackState is the portable document a client exports before a reinstall
+// or migration and imports afterwards. It carries the shared queue, where
+// playback is within it, the shuffle and repeat modes, and any queues devices
+// keep for themselves. Queue item flags such as canPlay are derived on
+// import, so only the fields below are part of the contract.
+type PlaybackState struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exportedAt"`
+	Queue      PlaybackQueue `json:"queue"`
+	PositionMs int64         `json:"positionMs"`
+	Paused     bool          `json:"paused"`
+	Shuffle    bool          `json:"shuffle"`
+	Repeat     string        `json:"repeat"`
+	Devices    []DeviceQueue `json:"devices"`
+}
+
+// PlaybackQueue is a queue's items and the index of the current one.
+type PlaybackQueue struct {
+	Items           []PlaybackStateItem `json:"items"`
+	CurrentPosition int                 `json:"currentPosition"`
+}
+
+// PlaybackStateItem is one queue entry: a library track, or a source still
+// being downloaded.
+type PlaybackStateItem struct {
+	ID            string           `json:"id,omitempty"`
+	Kind          string           `json:"kind"`
+	TrackID       *int64           `json:"trackId,omitempty"`
+	DownloadJobID string           `json:"downloadJobId,omitempty"`
+	Source        *SourceCandidate `json:"sourceCandidate,omitempty"`
+	AddedAt       time.Time        `json:"addedAt"`
+}
+
+// DeviceQueue is a queue one device plays on its own, apart from the shared
+// queue.
+type DeviceQueue struct {
+	DeviceID   string        `json:"deviceId"`
+	Queue      PlaybackQueue `json:"queue"`
+	PositionMs int64         `json:"positionMs"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+// playbackSettings is what the document holds beyond the shared queue and
+// the modes and position stored with it, kept beside it in Redis.
+type playbackSettings struct {
+	Devices []DeviceQueue `json:"devices"`
+}
+
+// PlaybackStates exports and imports whole playback state documents;
+// *Service satisfies it.
+type PlaybackStates interface {
+	ExportPlaybackState(ctx context.Context, userID string) (*PlaybackState, error)
+	ImportPlaybackState(ctx context.Context, userID string, doc *PlaybackState) (*PlaybackState, error)
+}
+
+func (s *Service) playbackSettingsKey(userID string) string {
+	return keyPlaybackSettingsPrefix + userID
+}
+
+// ExportPlaybackState returns the user's playback state as a document.
+func (s *Service) ExportPlaybackState(ctx context.Context, userID string) (*PlaybackState, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var settings playbackSettings
+	data, err := s.client.Get(ctx, s.playbackSettingsKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get playback settings: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(data), &settings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal playback settings: %w", err)
+		}
+	}
+	return exportPlaybackState(state, settings, time.Now()), nil
+}
+
+// ImportPlaybackState replaces the user's queue and playback settings with a
+// validated document in one write, and returns the state as now stored.
+func (s *Service) ImportPlaybackState(ctx context.Context, userID string, doc *PlaybackState) (*PlaybackState, error) {
+	previous, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	state := s.queueStateFromDocument(doc.Queue, now)
+	state.Version = previous.Version + 1
+	state.Name = previous.Name
+	// The document carries the order items play in, not the order they had
+	// before shuffling, so turning shuffle off later keeps that order.
+	state.Shuffle = doc.Shuffle
+	state.Repeat = doc.Repeat
+	state.PositionMs = doc.PositionMs
+	state.Paused = doc.Paused
+	state.PositionUpdatedAt = now
+	settings := playbackSettings{Devices: doc.Devices}
+
+	queueData, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queue: %w", err)
+	}
+	settingsData, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal playback settings: %w", err)
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.queueKey(userID, state.Name), queueData, queueTTL)
+		pipe.Set(ctx, s.playbackSettingsKey(userID), settingsData, queueTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save playback state: %w", err)
+	}
+	s.snapshotQueue(ctx, userID, state.Name, state.Version, queueData)
+	return exportPlaybackState(state, settings, now), nil
+}
+
+// queueStateFromDocument builds queue items from an imported queue. Items
+// keep their IDs, so source items stay tied to their download intents.
+func (s *Service) queueStateFromDocument(queue PlaybackQueue, now time.Time) *QueueState {
+	state := &QueueState{Items: make([]QueueItem, 0, len(queue.Items)), CurrentPosition: queue.CurrentPosition, UpdatedAt: now}
+	for _, item := range queue.Items {
+		id := item.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+		addedAt := item.AddedAt
+		if addedAt.IsZero() {
+			addedAt = now
+		}
+		queued := QueueItem{
+			ID:            id,
+			Kind:          item.Kind,
+			TrackID:       item.TrackID,
+			DownloadJobID: item.DownloadJobID,
+			Source:        item.Source,
+			PlaybackState: "queued",
+			AddedAt:       addedAt,
+			UpdatedAt:     now,
+		}
+		if item.TrackID != nil {
+			queued.PlaybackState = "playable"
+		}
+		state.Items = append(state.Items, queued)
+	}
+	s.recalculatePositions(state)
+	return state
+}
+
+func exportPlaybackState(state *QueueState, settings playbackSettings, now time.Time) *PlaybackState {
+	doc := &PlaybackState{
+		Version:    PlaybackStateVersion,
+		ExportedAt: now.UTC(),
+		Queue:      PlaybackQueue{Items: make([]PlaybackStateItem, 0, len(state.Items)), CurrentPosition: state.CurrentPosition},
+		PositionMs: state.PositionMs,
+		Paused:     state.Paused,
+		Shuffle:    state.Shuffle,
+		Repeat:     state.Repeat,
+		Devices:    settings.Devices,
+	}
+	if doc.Repeat == "" {
+		doc.Repeat = RepeatOff
+	}
+	if doc.Devices == nil {
+		doc.Devices = []DeviceQueue{}
+	}
+	for _, item := range state.Items {
+		doc.Queue.Items = append(doc.Queue.Items, PlaybackStateItem{
+			ID:            item.ID,
+			Kind:          item.Kind,
+			TrackID:       item.TrackID,
+			DownloadJobID: item.DownloadJobID,
+			Source:        item.Source,
+			AddedAt:       item.AddedAt,
+		})
+	}
+	return doc
+}
+
+// validatePlaybackState checks an imported document and fills defaults. The
+// error names the first problem found.
+func validatePlaybackState(doc *PlaybackState) error {
+	if doc.Version != PlaybackStateVersion {
+		return fmt.Errorf("%w: got %d, this server reads version %d", ErrUnsupportedStateVersion, doc.Version, PlaybackStateVersion)
+	}
+	if doc.Repeat == "" {
+		doc.Repeat = RepeatOff
+	}
+	if doc.Repeat != RepeatOff && doc.Repeat != RepeatAll && doc.Repeat != RepeatOne {
+		return fmt.Errorf("%w: repeat must be off, all or one", ErrInvalidPlaybackState)
+	}
+	if doc.PositionMs < 0 {
+		return fmt.Errorf("%w: positionMs must not be negative", ErrInvalidPlaybackState)
+	}
+	if err := validatePlaybackQueue("queue", doc.Queue); err != nil {
+		return err
+	}
+	if len(doc.Devices) > maxPlaybackStateDevices {
+		return fmt.Errorf("%w: at most %d device queues", ErrInvalidPlaybackState, maxPlaybackStateDevices)
+	}
+	seen := make(map[string]bool, len(doc.Devices))
+	for _, device := range doc.Devices {
+		if !websocket.ValidDeviceID(device.DeviceID) {
+			return fmt.Errorf("%w: deviceId must be 1-64 letters, digits, '-', '_' or '.'", ErrInvalidPlaybackState)
+		}
+		if seen[device.DeviceID] {
+			return fmt.Errorf("%w: device %s appears twice", ErrInvalidPlaybackState, device.DeviceID)
+		}
+		seen[device.DeviceID] = true
+		if device.PositionMs < 0 {
+			return fmt.Errorf("%w: device %s positionMs must not be negative", ErrInvalidPlaybackState, device.DeviceID)
+		}
+		if err := validatePlaybackQueue("device "+device.DeviceID+" queue", device.Queue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePlaybackQueue(name string, queue PlaybackQueue) error {
+	if len(queue.Items) > maxPlaybackStateItems {
+		return fmt.Errorf("%w: %s holds more than %d items", ErrInvalidPlaybackState, name, maxPlaybackStateItems)
+	}
+	if queue.CurrentPosition < 0 || (queue.CurrentPosition > 0 && queue.CurrentPosition >= len(queue.Items)) {
+		return fmt.Errorf("%w: %s currentPosition is out of range", ErrInvalidPlaybackState, name)
+	}
+	ids := make(map[string]bool, len(queue.Items))
+	for i, item := range queue.Items {
+		if item.ID != "" {
+			if ids[item.ID] {
+				return fmt.Errorf("%w: %s item %d repeats id %s", ErrInvalidPlaybackState, name, i, item.ID)
+			}
+			ids[item.ID] = true
+		}
+		switch item.Kind {
+		case "track":
+			if item.TrackID == nil || *item.TrackID <= 0 {
+				return fmt.Errorf("%w: %s item %d needs a positive trackId", ErrInvalidPlaybackState, name, i)
+			}
+		case "source":
+			if item.TrackID != nil && *item.TrackID <= 0 {
+				return fmt.Errorf("%w: %s item %d trackId must be positive", ErrInvalidPlaybackState, name, i)
+			}
+			if item.TrackID == nil && (item.DownloadJobID == "" || item.Source == nil) {
+				return fmt.Errorf("%w: %s item %d needs a trackId or a downloadJobId and sourceCandidate", ErrInvalidPlaybackState, name, i)
+			}
+		default:
+			return fmt.Errorf("%w: %s item %d kind must be track or source", ErrInvalidPlaybackState, name, i)
+		}
+	}
+	return nil
+}
+
+// SetPlaybackStates enables playback state export and import.
+func (h *Handlers) SetPlaybackStates(states PlaybackStates) {
+	h.states = states
+}
+
+// ExportPlaybackState handles GET /api/v1/playback/state/export
+func (h *Handlers) ExportPlaybackState(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.states == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "playback state export is disabled")
+		return
+	}
+
+	doc, err := h.states.ExportPlaybackState(r.Context(), userCtx.UserID.String())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export playback state")
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// ImportPlaybackState handles POST /api/v1/playback/state/import, replacing
+// the caller's queue and playback settings with the posted document.
+func (h *Handlers) ImportPlaybackState(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.states == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "playback state import is disabled")
+		return
+	}
+
+	var doc PlaybackState
+	r.Body = http.MaxBytesReader(w, r.Body, maxPlaybackStateBytes)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeError(w, http.StatusRequestEntityTooLarge, "PLAYBACK_STATE_TOO_LARGE", "playback state document is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if err := validatePlaybackState(&doc); err != nil {
+		code := "INVALID_PLAYBACK_STATE"
+		if errors.Is(err, ErrUnsupportedStateVersion) {
+			code = "UNSUPPORTED_STATE_VERSION"
+		}
+		writeError(w, http.StatusBadRequest, code, err.Error())
+		return
+	}
+
+	imported, err := h.states.ImportPlaybackState(r.Context(), userCtx.UserID.String(), &doc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to import playback state")
+		return
+	}
+	writeJSON(w, http.StatusOK, imported)
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+)
+
+type fakePlaybackStates struct {
+	imported *PlaybackState
+}
+
+func (f *fakePlaybackStates) ExportPlaybackState(context.Context, string) (*PlaybackState, error) {
+	return &PlaybackState{Version: PlaybackStateVersion, Repeat: RepeatOff}, nil
+}
+
+func (f *fakePlaybackStates) ImportPlaybackState(_ context.Context, _ string, doc *PlaybackState) (*PlaybackState, error) {
+	f.imported = doc
+	return doc, nil
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestPlaybackStateRoundTripsThroughQueueState(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	doc := PlaybackQueue{
+		CurrentPosition: 1,
+		Items: []PlaybackStateItem{
+			{ID: "a", Kind: "track", TrackID: int64Ptr(7)},
+			{ID: "b", Kind: "source", DownloadJobID: "job-1", Source: &SourceCandidate{SourceURL: "https://example.test/v", Title: "Demo"}},
+			{Kind: "track", TrackID: int64Ptr(9)},
+		},
+	}
+
+	state := (&Service{}).queueStateFromDocument(doc, now)
+	if state.CurrentPosition != 1 || len(state.Items) != 3 {
+		t.Fatalf("state = %+v", state)
+	}
+	if !state.Items[0].CanPlay || state.Items[0].PlaybackState != "playable" {
+		t.Fatalf("track item = %+v, want playable", state.Items[0])
+	}
+	if state.Items[1].CanPlay || state.Items[1].PlaybackState != "queued" || state.Items[1].ID != "b" {
+		t.Fatalf("source item = %+v, want queued with its id kept", state.Items[1])
+	}
+	if state.Items[2].ID == "" || state.Items[2].Position != 2 || !state.Items[2].AddedAt.Equal(now) {
+		t.Fatalf("item without id = %+v, want a new id, position and addedAt", state.Items[2])
+	}
+
+	state.Shuffle, state.PositionMs = true, 42000
+	exported := exportPlaybackState(state, playbackSettings{}, now)
+	if exported.Version != PlaybackStateVersion || exported.Repeat != RepeatOff || !exported.Shuffle || exported.PositionMs != 42000 {
+		t.Fatalf("exported = %+v", exported)
+	}
+	if len(exported.Queue.Items) != 3 || exported.Queue.Items[1].DownloadJobID != "job-1" || exported.Devices == nil {
+		t.Fatalf("exported queue = %+v, devices = %v", exported.Queue, exported.Devices)
+	}
+}
+
+func TestValidatePlaybackState(t *testing.T) {
+	valid := func() PlaybackState {
+		return PlaybackState{
+			Version: PlaybackStateVersion,
+			Queue:   PlaybackQueue{Items: []PlaybackStateItem{{Kind: "track", TrackID: int64Ptr(1)}}},
+			Devices: []DeviceQueue{{DeviceID: "phone-1", Queue: PlaybackQueue{Items: []PlaybackStateItem{}}}},
+		}
+	}
+	tests := map[string]struct {
+		mutate func(*PlaybackState)
+		want   error
+	}{
+		"valid":                {func(*PlaybackState) {}, nil},
+		"missing version":      {func(d *PlaybackState) { d.Version = 0 }, ErrUnsupportedStateVersion},
+		"future version":       {func(d *PlaybackState) { d.Version = 2 }, ErrUnsupportedStateVersion},
+		"unknown repeat":       {func(d *PlaybackState) { d.Repeat = "shuffle" }, ErrInvalidPlaybackState},
+		"negative position":    {func(d *PlaybackState) { d.PositionMs = -1 }, ErrInvalidPlaybackState},
+		"current out of range": {func(d *PlaybackState) { d.Queue.CurrentPosition = 1 }, ErrInvalidPlaybackState},
+		"track without id":     {func(d *PlaybackState) { d.Queue.Items[0].TrackID = nil }, ErrInvalidPlaybackState},
+		"unknown kind":         {func(d *PlaybackState) { d.Queue.Items[0].Kind = "podcast" }, ErrInvalidPlaybackState},
+		"source without job": {func(d *PlaybackState) {
+			d.Queue.Items = append(d.Queue.Items, PlaybackStateItem{Kind: "source", Source: &SourceCandidate{}})
+		}, ErrInvalidPlaybackState},
+		"duplicate item ids": {func(d *PlaybackState) {
+			d.Queue.Items = []PlaybackStateItem{{ID: "x", Kind: "track", TrackID: int64Ptr(1)}, {ID: "x", Kind: "track", TrackID: int64Ptr(2)}}
+		}, ErrInvalidPlaybackState},
+		"bad device id":    {func(d *PlaybackState) { d.Devices[0].DeviceID = "has space" }, ErrInvalidPlaybackState},
+		"duplicate device": {func(d *PlaybackState) { d.Devices = append(d.Devices, d.Devices[0]) }, ErrInvalidPlaybackState},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc := valid()
+			tc.mutate(&doc)
+			err := validatePlaybackState(&doc)
+			if tc.want == nil {
+				if err != nil || doc.Repeat != RepeatOff {
+					t.Fatalf("err = %v, repeat = %q; want valid with repeat defaulted to off", err, doc.Repeat)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestImportPlaybackStateHandler(t *testing.T) {
+	states := &fakePlaybackStates{}
+	h := NewHandlers(&fakeQueueHandlerService{state: &QueueState{}})
+	h.SetPlaybackStates(states)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/playback/state/import", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+		rec := httptest.NewRecorder()
+		h.ImportPlaybackState(rec, req)
+		return rec
+	}
+
+	rec := post(`{"version":9,"queue":{"items":[]}}`)
+	var errResp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil || rec.Code != http.StatusBadRequest || errResp.Code != "UNSUPPORTED_STATE_VERSION" {
+		t.Fatalf("future version: status %d, %+v", rec.Code, errResp)
+	}
+	if states.imported != nil {
+		t.Fatal("a rejected document was imported")
+	}
+
+	rec = post(`{"version":1,"queue":{"items":[{"kind":"track","trackId":3}],"currentPosition":0},"shuffle":true,"repeat":"one","positionMs":1500}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if states.imported == nil || !states.imported.Shuffle || states.imported.Repeat != RepeatOne || states.imported.PositionMs != 1500 {
+		t.Fatalf("imported = %+v", states.imported)
+	}
+}
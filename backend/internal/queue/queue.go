@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +28,7 @@ var (
 	ErrQueueEmpty      = errors.New("queue is empty")
 	ErrInvalidPosition = errors.New("invalid position")
 	ErrTrackNotFound   = errors.New("track not found in queue")
+	ErrNotCurrentItem  = errors.New("queue item is not the current item")
 )
 
 // QueueItem represents an entry in the playback queue. Source-backed entries
@@ -68,6 +70,29 @@ type QueueState struct {
 	Items           []QueueItem `json:"items"`
 	CurrentPosition int         `json:"currentPosition"`
 	UpdatedAt       time.Time   `json:"updatedAt"`
+	// Radio is set while the queue is an endless radio station. Replacing
+	// or clearing the queue ends the station.
+	Radio *RadioStation `json:"radio,omitempty"`
+	// Shuffle is whether the upcoming items are in shuffled order, and
+	// OriginalOrder the item IDs in the order they had before, so turning
+	// shuffle off can restore it. Items added while shuffled go where they
+	// were inserted and are not in OriginalOrder.
+	Shuffle       bool     `json:"shuffle,omitempty"`
+	OriginalOrder []string `json:"originalOrder,omitempty"`
+	// Repeat is RepeatOff, RepeatAll or RepeatOne; empty means off.
+	Repeat string `json:"repeat,omitempty"`
+	// PositionMs is how far into the current item playback was at
+	// PositionUpdatedAt, and Paused whether it was paused. Moving to another
+	// item starts it from the beginning.
+	PositionMs        int64     `json:"positionMs,omitempty"`
+	Paused            bool      `json:"paused,omitempty"`
+	PositionUpdatedAt time.Time `json:"positionUpdatedAt"`
+	// Version counts saves, so devices editing the queue at once can tell
+	// whether they edited what they last saw; see Handlers.Synced.
+	Version int64 `json:"version"`
+	// Name is the named queue the state was loaded from; see named.go. It
+	// is not stored, since the key already says it.
+	Name string `json:"-"`
 }
 
 // AddRequest represents a request to add tracks to the queue
@@ -79,7 +104,10 @@ type AddRequest struct {
 
 // Service manages playback queues using Redis
 type Service struct {
-	client *redis.Client
+	client    *redis.Client
+	snapshots QueueSnapshots
+	// positionSnapshots throttles snapshots of position-only writes.
+	positionSnapshots positionThrottle
 }
 
 // NewService creates a new queue service with the given Redis URL
@@ -106,32 +134,13 @@ func (s *Service) Close() error {
 	return s.client.Close()
 }
 
-// queueKey returns the Redis key for a user's queue
-func (s *Service) queueKey(userID string) string {
-	return keyQueuePrefix + userID
-}
-
-// GetQueue retrieves the current queue for a user
+// GetQueue retrieves the user's active queue
 func (s *Service) GetQueue(ctx context.Context, userID string) (*QueueState, error) {
-	data, err := s.client.Get(ctx, s.queueKey(userID)).Result()
+	index, err := s.loadIndex(ctx, userID)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			// Return empty queue if none exists
-			return &QueueState{
-				Items:           []QueueItem{},
-				CurrentPosition: 0,
-				UpdatedAt:       time.Now(),
-			}, nil
-		}
-		return nil, fmt.Errorf("failed to get queue: %w", err)
-	}
-
-	var state QueueState
-	if err := json.Unmarshal([]byte(data), &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal queue: %w", err)
+		return nil, err
 	}
-
-	return &state, nil
+	return s.loadQueue(ctx, userID, index.Active)
 }
 
 // AddToQueue adds a track to the queue
@@ -266,23 +275,7 @@ func (s *Service) AddMultipleToQueue(ctx context.Context, userID string, trackID
 		return nil, err
 	}
 
-	now := time.Now()
-	newItems := make([]QueueItem, len(trackIDs))
-	for i, trackID := range trackIDs {
-		trackIDCopy := trackID
-		newItems[i] = QueueItem{
-			ID:            uuid.NewString(),
-			Kind:          "track",
-			TrackID:       &trackIDCopy,
-			PlaybackState: "playable",
-			Progress:      100,
-			CanPlay:       true,
-			CanRetry:      false,
-			CanRemove:     true,
-			AddedAt:       now,
-			UpdatedAt:     now,
-		}
-	}
+	newItems := newTrackItems(trackIDs, time.Now())
 
 	insertIdx, adjustCurrent, err := resolveInsertPosition(state, position)
 	if err != nil {
@@ -303,6 +296,51 @@ func (s *Service) AddMultipleToQueue(ctx context.Context, userID string, trackID
 	return state, nil
 }
 
+// ReplaceQueue swaps the whole queue for trackIDs in a single write, with
+// the first track current, so no client sees a half-built queue. The shuffle
+// and repeat modes carry over; with shuffle on, the tracks after the first
+// are shuffled.
+func (s *Service) ReplaceQueue(ctx context.Context, userID string, trackIDs []int64) (*QueueState, error) {
+	previous, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &QueueState{Items: newTrackItems(trackIDs, now), CurrentPosition: 0, UpdatedAt: now, Repeat: previous.Repeat, Version: previous.Version, Name: previous.Name}
+	if previous.Shuffle {
+		shuffleUpcoming(state, rand.New(rand.NewSource(now.UnixNano())))
+	}
+	s.recalculatePositions(state)
+
+	if err := s.saveQueue(ctx, userID, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// newTrackItems builds playable queue items for library tracks.
+func newTrackItems(trackIDs []int64, now time.Time) []QueueItem {
+	items := make([]QueueItem, len(trackIDs))
+	for i, trackID := range trackIDs {
+		trackIDCopy := trackID
+		items[i] = QueueItem{
+			ID:            uuid.NewString(),
+			Kind:          "track",
+			TrackID:       &trackIDCopy,
+			PlaybackState: "playable",
+			Progress:      100,
+			CanPlay:       true,
+			CanRetry:      false,
+			CanRemove:     true,
+			AddedAt:       now,
+			UpdatedAt:     now,
+		}
+	}
+	return items
+}
+
 // RemoveFromQueue removes a track at the specified position
 func (s *Service) RemoveFromQueue(ctx context.Context, userID string, position int) (*QueueState, error) {
 	state, err := s.GetQueue(ctx, userID)
@@ -383,6 +421,61 @@ func (s *Service) ReorderQueue(ctx context.Context, userID string, fromPos, toPo
 	return state, nil
 }
 
+// SetCurrentPosition moves playback to the item at position. It also returns
+// a copy of the item that was current before the move, or nil when the queue
+// had none, so callers can record how long it was played.
+func (s *Service) SetCurrentPosition(ctx context.Context, userID string, position int) (*QueueState, *QueueItem, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if position < 0 || position >= len(state.Items) {
+		return nil, nil, ErrInvalidPosition
+	}
+
+	var previous *QueueItem
+	if state.CurrentPosition >= 0 && state.CurrentPosition < len(state.Items) {
+		item := state.Items[state.CurrentPosition]
+		previous = &item
+	}
+
+	now := time.Now()
+	if previous == nil || previous.ID != state.Items[position].ID {
+		state.PositionMs = 0
+		state.PositionUpdatedAt = now
+	}
+	state.CurrentPosition = position
+	state.UpdatedAt = now
+	if err := s.saveQueue(ctx, userID, state); err != nil {
+		return nil, nil, err
+	}
+	return state, previous, nil
+}
+
+// SetPlaybackPosition records how far into the current item playback is and
+// whether it is paused, so another device or a restarted app can resume
+// there. It returns ErrNotCurrentItem when queueItemID is no longer current,
+// so a device that missed a skip cannot overwrite the new item's position.
+// The queue's UpdatedAt and version are left alone since its contents have
+// not changed.
+func (s *Service) SetPlaybackPosition(ctx context.Context, userID, queueItemID string, positionMs int64, paused bool) (*QueueState, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.CurrentPosition < 0 || state.CurrentPosition >= len(state.Items) || state.Items[state.CurrentPosition].ID != queueItemID {
+		return nil, ErrNotCurrentItem
+	}
+
+	state.PositionMs = positionMs
+	state.Paused = paused
+	state.PositionUpdatedAt = time.Now()
+	if err := s.writePosition(ctx, userID, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
 // RemoveQueueItem removes the queue item with the specified server ID.
 func (s *Service) RemoveQueueItem(ctx context.Context, userID, queueItemID string) (*QueueState, error) {
 	state, err := s.GetQueue(ctx, userID)
@@ -397,6 +490,49 @@ func (s *Service) RemoveQueueItem(ctx context.Context, userID, queueItemID strin
 	return nil, ErrTrackNotFound
 }
 
+// RemoveTrack drops every queue item that plays the given library track and
+// returns how many were removed. The current item stays current when it
+// survives; otherwise playback moves to the item that took its place.
+func (s *Service) RemoveTrack(ctx context.Context, userID string, trackID int64) (int, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := state.Items[:0]
+	current := state.CurrentPosition
+	removed := 0
+	for i, item := range state.Items {
+		if item.TrackID != nil && *item.TrackID == trackID {
+			removed++
+			if i < state.CurrentPosition {
+				current--
+			}
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	state.Items = kept
+	if current >= len(state.Items) {
+		current = len(state.Items) - 1
+	}
+	if current < 0 {
+		current = 0
+	}
+	state.CurrentPosition = current
+	s.recalculatePositions(state)
+	state.UpdatedAt = time.Now()
+
+	if err := s.saveQueue(ctx, userID, state); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
 // ReorderQueueItem moves a queue item by server ID.
 func (s *Service) ReorderQueueItem(ctx context.Context, userID, queueItemID string, toPos int) (*QueueState, error) {
 	state, err := s.GetQueue(ctx, userID)
@@ -463,19 +599,105 @@ func (s *Service) RetryQueueItem(ctx context.Context, userID, queueItemID string
 	return nil, "", ErrTrackNotFound
 }
 
-// ClearQueue clears all items from the queue
+// SetShuffle turns shuffle on or off. Turning it on shuffles the items after
+// the current one; turning it off restores their order. Setting the mode the
+// queue is already in changes nothing.
+func (s *Service) SetShuffle(ctx context.Context, userID string, enabled bool) (*QueueState, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Shuffle == enabled {
+		return state, nil
+	}
+
+	if enabled {
+		shuffleUpcoming(state, rand.New(rand.NewSource(time.Now().UnixNano())))
+	} else {
+		unshuffle(state)
+	}
+	s.recalculatePositions(state)
+	state.UpdatedAt = time.Now()
+	if err := s.saveQueue(ctx, userID, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SetRepeat sets the repeat mode, one of RepeatOff, RepeatAll or RepeatOne.
+func (s *Service) SetRepeat(ctx context.Context, userID string, mode string) (*QueueState, error) {
+	state, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	state.Repeat = mode
+	state.UpdatedAt = time.Now()
+	if err := s.saveQueue(ctx, userID, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ClearQueue clears all items from the queue. The repeat mode and version
+// carry over, so a device holding the version from before the clear cannot
+// mistake the emptied queue for the one it saw.
 func (s *Service) ClearQueue(ctx context.Context, userID string) error {
-	return s.client.Del(ctx, s.queueKey(userID)).Err()
+	previous, err := s.GetQueue(ctx, userID)
+	if err != nil {
+		return err
+	}
+	state := &QueueState{Items: []QueueItem{}, UpdatedAt: time.Now(), Repeat: previous.Repeat, Version: previous.Version, Name: previous.Name}
+	return s.saveQueue(ctx, userID, state)
 }
 
-// saveQueue saves the queue state to Redis with TTL
+// saveQueue saves the queue state to Redis with TTL, advancing its version
 func (s *Service) saveQueue(ctx context.Context, userID string, state *QueueState) error {
+	state.Version++
+	return s.writeQueue(ctx, userID, state)
+}
+
+// writeQueue stores the queue state as is, for saves that are not edits,
+// under the named queue it was loaded from, and writes it through to its
+// snapshot. The queue index is kept alive as long as any of the user's
+// queues is.
+func (s *Service) writeQueue(ctx context.Context, userID string, state *QueueState) error {
+	data, err := s.cacheQueue(ctx, userID, state)
+	if err != nil {
+		return err
+	}
+	s.snapshotQueue(ctx, userID, state.Name, state.Version, data)
+	return nil
+}
+
+// writePosition saves a queue whose playback position is all that changed.
+// Players report it every few seconds, so its snapshot is throttled.
+func (s *Service) writePosition(ctx context.Context, userID string, state *QueueState) error {
+	data, err := s.cacheQueue(ctx, userID, state)
+	if err != nil {
+		return err
+	}
+	if s.snapshots != nil && s.positionSnapshots.due(userID, state.Name, time.Now()) {
+		s.snapshotQueue(ctx, userID, state.Name, state.Version, data)
+	}
+	return nil
+}
+
+// cacheQueue writes the queue to Redis and returns what it wrote.
+func (s *Service) cacheQueue(ctx context.Context, userID string, state *QueueState) ([]byte, error) {
 	data, err := json.Marshal(state)
 	if err != nil {
-		return fmt.Errorf("failed to marshal queue: %w", err)
+		return nil, fmt.Errorf("failed to marshal queue: %w", err)
 	}
 
-	return s.client.Set(ctx, s.queueKey(userID), data, queueTTL).Err()
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.queueKey(userID, state.Name), data, queueTTL)
+		pipe.Expire(ctx, s.queueIndexKey(userID), queueTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // recalculatePositions updates the position field for all items
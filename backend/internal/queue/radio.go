@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/recommender"
+)
+
+const (
+	// radioBatch is how many tracks a radio station queues at a time.
+	radioBatch = 10
+	// radioRefillAt is how few tracks may be left after the current one
+	// before the station queues another batch.
+	radioRefillAt = 3
+)
+
+// RadioSource finds tracks like a seed. recommender.Service satisfies it.
+type RadioSource interface {
+	Similar(ctx context.Context, userID uuid.UUID, seed recommender.Seed, limit int) ([]recommender.Recommendation, error)
+}
+
+// RadioLibrary checks and grows the caller's library, since only library
+// tracks can be played. db.LibraryRepository satisfies it.
+type RadioLibrary interface {
+	IsTrackInLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (bool, error)
+	AddTrackToLibrary(ctx context.Context, userID uuid.UUID, trackID int64) (*db.LibraryEntry, error)
+}
+
+// SetRadio enables POST /api/v1/queue/radio and keeps radio queues topped
+// up as playback advances.
+func (h *Handlers) SetRadio(source RadioSource, library RadioLibrary) {
+	h.radio = source
+	h.radioLibrary = library
+}
+
+// RadioStation is what a radio queue was started from.
+type RadioStation struct {
+	TrackID    *int64     `json:"trackId,omitempty"`
+	MBArtistID *uuid.UUID `json:"mbArtistId,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+}
+
+// StartRadioRequest seeds a station from exactly one of a track or a
+// MusicBrainz artist.
+type StartRadioRequest struct {
+	TrackID    *int64     `json:"trackId"`
+	MBArtistID *uuid.UUID `json:"mbArtistId"`
+}
+
+func (s *RadioStation) seed() recommender.Seed {
+	seed := recommender.Seed{MBArtistID: s.MBArtistID}
+	if s.TrackID != nil {
+		seed.TrackID = *s.TrackID
+	}
+	return seed
+}
+
+// StartRadio handles POST /api/v1/queue/radio, replacing the queue with a
+// station of tracks like the seed: the seed track itself when it is in the
+// caller's library, then similar library tracks, then similar tracks from
+// the catalog, which are added to the library as they are queued.
+func (h *Handlers) StartRadio(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.radio == nil || h.radioLibrary == nil {
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_DISABLED", "radio is disabled")
+		return
+	}
+
+	var req StartRadioRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+		return
+	}
+	if (req.TrackID == nil) == (req.MBArtistID == nil) || (req.TrackID != nil && *req.TrackID <= 0) {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "exactly one of trackId or mbArtistId is required")
+		return
+	}
+
+	station := &RadioStation{TrackID: req.TrackID, MBArtistID: req.MBArtistID, StartedAt: time.Now()}
+	var trackIDs []int64
+	if req.TrackID != nil {
+		inLibrary, err := h.radioLibrary.IsTrackInLibrary(r.Context(), userCtx.UserID, *req.TrackID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check library")
+			return
+		}
+		if inLibrary {
+			trackIDs = append(trackIDs, *req.TrackID)
+		}
+	}
+	similar, err := h.radioTracks(r.Context(), userCtx.UserID, station.seed(), queuedTrackIDs(trackIDs))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to find radio tracks")
+		return
+	}
+	trackIDs = append(trackIDs, similar...)
+	if len(trackIDs) == 0 {
+		writeError(w, http.StatusNotFound, "NO_RADIO_TRACKS", "no tracks to play for this seed")
+		return
+	}
+
+	userID := userCtx.UserID.String()
+	state, err := h.service.ReplaceQueue(r.Context(), userID, trackIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start radio")
+		return
+	}
+	state.Radio = station
+	if err := h.service.saveQueue(r.Context(), userID, state); err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start radio")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.buildQueueResponse(r.Context(), state, nil))
+}
+
+// extendRadio queues another batch when a radio queue is nearly played
+// out, seeded from the last queued track so the station drifts the way a
+// radio does, and from the station seed when that finds nothing new. A
+// failure is only logged; the queue is still valid, just shorter.
+func (h *Handlers) extendRadio(ctx context.Context, userID uuid.UUID, state *QueueState) *QueueState {
+	if h.radio == nil || h.radioLibrary == nil || state.Radio == nil {
+		return state
+	}
+	if len(state.Items)-state.CurrentPosition-1 >= radioRefillAt {
+		return state
+	}
+
+	queued := map[int64]bool{}
+	var last *int64
+	for _, item := range state.Items {
+		if item.TrackID != nil {
+			queued[*item.TrackID] = true
+			last = item.TrackID
+		}
+	}
+	seeds := []recommender.Seed{state.Radio.seed()}
+	if last != nil && (state.Radio.TrackID == nil || *last != *state.Radio.TrackID) {
+		seeds = append([]recommender.Seed{{TrackID: *last}}, seeds...)
+	}
+
+	for _, seed := range seeds {
+		trackIDs, err := h.radioTracks(ctx, userID, seed, queued)
+		if err != nil {
+			log.Printf("Warning: failed to find radio tracks for user %s: %v", userID, err)
+			return state
+		}
+		if len(trackIDs) == 0 {
+			continue
+		}
+		extended, err := h.service.AddMultipleToQueue(ctx, userID.String(), trackIDs, "last")
+		if err != nil {
+			log.Printf("Warning: failed to extend radio queue for user %s: %v", userID, err)
+			return state
+		}
+		return extended
+	}
+	return state
+}
+
+// radioTracks returns up to a batch of tracks like the seed that are not
+// already queued, library tracks first. Catalog tracks are added to the
+// library so they can be played; one that cannot be added is left out.
+func (h *Handlers) radioTracks(ctx context.Context, userID uuid.UUID, seed recommender.Seed, queued map[int64]bool) ([]int64, error) {
+	recs, err := h.radio.Similar(ctx, userID, seed, radioBatch*3)
+	if err != nil {
+		return nil, err
+	}
+
+	var library, catalog []int64
+	for _, rec := range recs {
+		if queued[rec.Track.ID] {
+			continue
+		}
+		if rec.InLibrary {
+			library = append(library, rec.Track.ID)
+		} else {
+			catalog = append(catalog, rec.Track.ID)
+		}
+	}
+
+	trackIDs := library[:min(len(library), radioBatch)]
+	for _, trackID := range catalog {
+		if len(trackIDs) == radioBatch {
+			break
+		}
+		if _, err := h.radioLibrary.AddTrackToLibrary(ctx, userID, trackID); err != nil && !errors.Is(err, db.ErrTrackAlreadyInLibrary) {
+			log.Printf("Warning: failed to add radio track %d to library for user %s: %v", trackID, userID, err)
+			continue
+		}
+		trackIDs = append(trackIDs, trackID)
+	}
+	return trackIDs, nil
+}
+
+func queuedTrackIDs(trackIDs []int64) map[int64]bool {
+	queued := make(map[int64]bool, len(trackIDs))
+	for _, id := range trackIDs {
+		queued[id] = true
+	}
+	return queued
+}
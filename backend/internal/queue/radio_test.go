@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/recommender"
+)
+
+// fakeRadio recommends the tracks listed for each seed track, or for the
+// artist seed under 0. Tracks in the library are marked as such.
+type fakeRadio struct {
+	similar map[int64][]int64
+	library map[int64]bool
+	seeds   []recommender.Seed
+}
+
+func (f *fakeRadio) Similar(_ context.Context, _ uuid.UUID, seed recommender.Seed, _ int) ([]recommender.Recommendation, error) {
+	f.seeds = append(f.seeds, seed)
+	var recs []recommender.Recommendation
+	for _, id := range f.similar[seed.TrackID] {
+		recs = append(recs, recommender.Recommendation{Track: &db.Track{ID: id}, InLibrary: f.library[id]})
+	}
+	return recs, nil
+}
+
+func (f *fakeRadio) IsTrackInLibrary(_ context.Context, _ uuid.UUID, trackID int64) (bool, error) {
+	return f.library[trackID], nil
+}
+
+func (f *fakeRadio) AddTrackToLibrary(_ context.Context, userID uuid.UUID, trackID int64) (*db.LibraryEntry, error) {
+	f.library[trackID] = true
+	return &db.LibraryEntry{UserID: userID, TrackID: trackID}, nil
+}
+
+func radioRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+}
+
+func queueTrackIDs(items []QueueItemResponse) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = *item.TrackID
+	}
+	return ids
+}
+
+func TestStartRadioQueuesSeedThenLibraryThenCatalog(t *testing.T) {
+	radio := &fakeRadio{
+		similar: map[int64][]int64{1: {30, 20, 21}},
+		library: map[int64]bool{1: true, 20: true, 21: true},
+	}
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	h := NewHandlers(service)
+	h.SetRadio(radio, radio)
+
+	rec := httptest.NewRecorder()
+	h.StartRadio(rec, radioRequest(http.MethodPost, "/api/v1/queue/radio", `{"trackId":1}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queueTrackIDs(resp.Items), []int64{1, 20, 21, 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	if resp.Radio == nil || resp.Radio.TrackID == nil || *resp.Radio.TrackID != 1 {
+		t.Fatalf("radio = %+v, want seeded from track 1", resp.Radio)
+	}
+	if !radio.library[30] {
+		t.Fatal("catalog track 30 was queued without being added to the library")
+	}
+}
+
+func TestRadioRefillsNearTheEndOfTheQueue(t *testing.T) {
+	radio := &fakeRadio{
+		similar: map[int64][]int64{1: {20, 21}, 21: {20, 22}},
+		library: map[int64]bool{1: true, 20: true, 21: true, 22: true},
+	}
+	service := &fakeQueueHandlerService{state: &QueueState{}}
+	h := NewHandlers(service)
+	h.SetRadio(radio, radio)
+
+	rec := httptest.NewRecorder()
+	h.StartRadio(rec, radioRequest(http.MethodPost, "/api/v1/queue/radio", `{"trackId":1}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.SetCurrentPosition(rec, radioRequest(http.MethodPut, "/api/v1/queue/current", `{"position":1}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("advance status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	// Seeded from the last queued track, skipping what is already queued.
+	if got, want := queueTrackIDs(resp.Items), []int64{1, 20, 21, 22}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	if last := radio.seeds[len(radio.seeds)-1]; last.TrackID != 21 {
+		t.Fatalf("refill seed = %+v, want the last queued track", last)
+	}
+}
+
+func TestStartRadioValidatesRequest(t *testing.T) {
+	h := NewHandlers(&fakeQueueHandlerService{state: &QueueState{}})
+	rec := httptest.NewRecorder()
+	h.StartRadio(rec, radioRequest(http.MethodPost, "/api/v1/queue/radio", `{"trackId":1}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without a radio source = %d, want 503", rec.Code)
+	}
+
+	radio := &fakeRadio{library: map[int64]bool{}}
+	h.SetRadio(radio, radio)
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"trackId":1,"mbArtistId":"` + uuid.NewString() + `"}`, http.StatusBadRequest},
+		{`{"trackId":1,"shuffle":true}`, http.StatusBadRequest},
+		{`{"mbArtistId":"` + uuid.NewString() + `"}`, http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		h.StartRadio(rec, radioRequest(http.MethodPost, "/api/v1/queue/radio", tc.body))
+		if rec.Code != tc.want {
+			t.Fatalf("%s status = %d, want %d", tc.body, rec.Code, tc.want)
+		}
+	}
+}
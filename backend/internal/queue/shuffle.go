@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Shuffle modes.
+const (
+	ShuffleModeSmart  = "smart"
+	ShuffleModeRandom = "random"
+)
+
+// minShuffleWeight keeps a just-played track pickable, so a small library can
+// still fill a long shuffle.
+const minShuffleWeight = 0.001
+
+// shuffleWeight is how likely a track is to be picked relative to one never
+// played. The recency factor recovers from near zero right after a play to 1,
+// halving the remaining gap every halfLife; the neglect factor favours tracks
+// with few plays overall.
+func shuffleWeight(c db.ShuffleCandidate, halfLife time.Duration, now time.Time) float64 {
+	if c.PlayCount == 0 || !c.LastPlayedAt.Valid {
+		return 1
+	}
+	recency := 0.0
+	if age := now.Sub(c.LastPlayedAt.Time); age > 0 {
+		recency = 1 - math.Pow(0.5, float64(age)/float64(halfLife))
+	}
+	neglect := 1 / (1 + math.Log1p(float64(c.PlayCount)))
+	return math.Max(recency*neglect, minShuffleWeight)
+}
+
+// SmartShuffle picks up to count distinct tracks, weighting each by
+// shuffleWeight so recently and often played tracks come up less. Picks are
+// drawn without replacement and returned in play order.
+func SmartShuffle(candidates []db.ShuffleCandidate, count int, halfLife time.Duration, now time.Time, rng *rand.Rand) []int64 {
+	type keyed struct {
+		trackID int64
+		key     float64
+	}
+	// Weighted sampling without replacement (Efraimidis-Spirakis): the count
+	// largest keys ln(u)/w are a weighted sample, and sorting by key orders it.
+	keys := make([]keyed, 0, len(candidates))
+	for _, c := range candidates {
+		u := rng.Float64()
+		for u == 0 {
+			u = rng.Float64()
+		}
+		keys = append(keys, keyed{trackID: c.TrackID, key: math.Log(u) / shuffleWeight(c, halfLife, now)})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+	if count > len(keys) {
+		count = len(keys)
+	}
+	trackIDs := make([]int64, count)
+	for i := range trackIDs {
+		trackIDs[i] = keys[i].trackID
+	}
+	return trackIDs
+}
+
+// RandomShuffle picks up to count distinct tracks uniformly at random.
+func RandomShuffle(candidates []db.ShuffleCandidate, count int, rng *rand.Rand) []int64 {
+	order := rng.Perm(len(candidates))
+	if count > len(order) {
+		count = len(order)
+	}
+	trackIDs := make([]int64, count)
+	for i := range trackIDs {
+		trackIDs[i] = candidates[order[i]].TrackID
+	}
+	return trackIDs
+}
+
+// shuffleUpcoming turns on shuffle for a queue: it records the current item
+// order and shuffles the items after the current one, leaving what has
+// already played and the current item where they are.
+func shuffleUpcoming(state *QueueState, rng *rand.Rand) {
+	state.OriginalOrder = make([]string, len(state.Items))
+	for i, item := range state.Items {
+		state.OriginalOrder[i] = item.ID
+	}
+	if next := state.CurrentPosition + 1; next < len(state.Items) {
+		upcoming := state.Items[next:]
+		rng.Shuffle(len(upcoming), func(i, j int) { upcoming[i], upcoming[j] = upcoming[j], upcoming[i] })
+	}
+	state.Shuffle = true
+}
+
+// unshuffle turns off shuffle for a queue, putting items back in their
+// recorded order with playback staying on the current item. Items added
+// while shuffled follow, in the order they sit in the shuffled queue.
+func unshuffle(state *QueueState) {
+	var currentID string
+	if state.CurrentPosition >= 0 && state.CurrentPosition < len(state.Items) {
+		currentID = state.Items[state.CurrentPosition].ID
+	}
+	rank := make(map[string]int, len(state.OriginalOrder))
+	for i, id := range state.OriginalOrder {
+		rank[id] = i
+	}
+	key := func(item QueueItem) int {
+		if i, ok := rank[item.ID]; ok {
+			return i
+		}
+		return len(rank)
+	}
+	sort.SliceStable(state.Items, func(i, j int) bool { return key(state.Items[i]) < key(state.Items[j]) })
+	for i, item := range state.Items {
+		if item.ID == currentID {
+			state.CurrentPosition = i
+		}
+	}
+	state.Shuffle = false
+	state.OriginalOrder = nil
+}
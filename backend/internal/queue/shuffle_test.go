@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+func TestShuffleWeightFavoursNeglectedTracks(t *testing.T) {
+	now := time.Now()
+	halfLife := 7 * 24 * time.Hour
+	played := func(ago time.Duration, plays int) db.ShuffleCandidate {
+		return db.ShuffleCandidate{PlayCount: plays, LastPlayedAt: sql.NullTime{Time: now.Add(-ago), Valid: true}}
+	}
+
+	never := shuffleWeight(db.ShuffleCandidate{}, halfLife, now)
+	if never != 1 {
+		t.Fatalf("never played weight = %v, want 1", never)
+	}
+	justNow := shuffleWeight(played(time.Minute, 1), halfLife, now)
+	lastWeek := shuffleWeight(played(halfLife, 1), halfLife, now)
+	lastYear := shuffleWeight(played(365*24*time.Hour, 1), halfLife, now)
+	if !(justNow < lastWeek && lastWeek < lastYear && lastYear < never) {
+		t.Fatalf("weights by recency = %v, %v, %v, %v; want increasing", justNow, lastWeek, lastYear, never)
+	}
+	if justNow < minShuffleWeight {
+		t.Fatalf("just played weight = %v, want at least %v", justNow, minShuffleWeight)
+	}
+	if often := shuffleWeight(played(halfLife, 50), halfLife, now); often >= lastWeek {
+		t.Fatalf("often played weight = %v, want below once played %v", often, lastWeek)
+	}
+}
+
+func TestSmartShuffleAvoidsRecentlyPlayedTracks(t *testing.T) {
+	now := time.Now()
+	var candidates []db.ShuffleCandidate
+	for id := int64(1); id <= 200; id++ {
+		c := db.ShuffleCandidate{TrackID: id}
+		if id <= 100 {
+			// The first half was played within the last day.
+			c.PlayCount = 3
+			c.LastPlayedAt = sql.NullTime{Time: now.Add(-time.Duration(id) * 10 * time.Minute), Valid: true}
+		}
+		candidates = append(candidates, c)
+	}
+
+	picked := SmartShuffle(candidates, 50, 7*24*time.Hour, now, rand.New(rand.NewSource(1)))
+	if len(picked) != 50 {
+		t.Fatalf("picked %d tracks, want 50", len(picked))
+	}
+	seen := map[int64]bool{}
+	recent := 0
+	for _, id := range picked {
+		if seen[id] {
+			t.Fatalf("track %d picked twice", id)
+		}
+		seen[id] = true
+		if id <= 100 {
+			recent++
+		}
+	}
+	if recent > 5 {
+		t.Fatalf("picked %d recently played tracks out of 50, want almost none", recent)
+	}
+
+	if all := SmartShuffle(candidates[:3], 10, time.Hour, now, rand.New(rand.NewSource(1))); len(all) != 3 {
+		t.Fatalf("shuffle of a small library picked %d tracks, want all 3", len(all))
+	}
+}
+
+type fakeShuffleSource struct {
+	candidates []db.ShuffleCandidate
+}
+
+func (f *fakeShuffleSource) ShuffleCandidates(context.Context, uuid.UUID, int) ([]db.ShuffleCandidate, error) {
+	return append([]db.ShuffleCandidate(nil), f.candidates...), nil
+}
+
+func shuffleRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/queue/shuffle", strings.NewReader(body))
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: uuid.New()}))
+}
+
+func TestShuffleQueueSkipsQueuedTracksUnlessReplacing(t *testing.T) {
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	h := NewHandlers(service)
+	h.SetShuffleSource(&fakeShuffleSource{candidates: []db.ShuffleCandidate{{TrackID: 7}, {TrackID: 8}, {TrackID: 9}}})
+
+	rec := httptest.NewRecorder()
+	h.ShuffleQueue(rec, shuffleRequest(`{"count":10}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 3 || *resp.Items[2].TrackID != 9 {
+		t.Fatalf("queue after shuffle = %+v, want only track 9 appended", resp.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ShuffleQueue(rec, shuffleRequest(`{"mode":"random","position":"replace"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("replace status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if len(service.state.Items) != 3 {
+		t.Fatalf("replaced queue has %d items, want the 3 library tracks", len(service.state.Items))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ShuffleQueue(rec, shuffleRequest(`{}`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("shuffle with everything queued = %d, want 409", rec.Code)
+	}
+}
+
+func TestShuffleQueueValidatesRequest(t *testing.T) {
+	service := &fakeQueueHandlerService{state: &QueueState{}}
+	h := NewHandlers(service)
+
+	rec := httptest.NewRecorder()
+	h.ShuffleQueue(rec, shuffleRequest(`{}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without a shuffle source = %d, want 503", rec.Code)
+	}
+
+	h.SetShuffleSource(&fakeShuffleSource{candidates: []db.ShuffleCandidate{{TrackID: 1}}})
+	for _, body := range []string{
+		`{"mode":"loud"}`,
+		`{"count":0}`,
+		`{"count":501}`,
+		`{"halfLifeHours":0.5}`,
+		`{"position":"middle"}`,
+	} {
+		rec := httptest.NewRecorder()
+		h.ShuffleQueue(rec, shuffleRequest(body))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func itemIDs(items []QueueItem) string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestUnshuffleRestoresOrderWithAddedItemsLast(t *testing.T) {
+	state := &QueueState{CurrentPosition: 1}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		state.Items = append(state.Items, QueueItem{ID: id})
+	}
+
+	shuffleUpcoming(state, rand.New(rand.NewSource(3)))
+	if !state.Shuffle || itemIDs(state.Items[:2]) != "a,b" || state.CurrentPosition != 1 {
+		t.Fatalf("shuffled = %s at %d, want a,b kept and b current", itemIDs(state.Items), state.CurrentPosition)
+	}
+
+	// Items added mid-shuffle play where inserted: "x" next, "y" last.
+	state.Items = insertAt(state.Items, 2, QueueItem{ID: "x"})
+	state.Items = append(state.Items, QueueItem{ID: "y"})
+	state.CurrentPosition = 3
+	current := state.Items[3].ID
+
+	unshuffle(state)
+	if got := itemIDs(state.Items); got != "a,b,c,d,e,x,y" {
+		t.Fatalf("unshuffled = %s, want original order then added items", got)
+	}
+	if state.Shuffle || state.OriginalOrder != nil || state.Items[state.CurrentPosition].ID != current {
+		t.Fatalf("state = %+v, want shuffle off and %s still current", state, current)
+	}
+}
+
+func TestUpdateShuffleAndRepeat(t *testing.T) {
+	service := &fakeQueueHandlerService{state: twoTrackQueue()}
+	h := NewHandlers(service)
+
+	rec := httptest.NewRecorder()
+	h.UpdateShuffle(rec, shuffleRequest(`{"enabled":true}`))
+	var resp QueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || !resp.Shuffle || resp.Repeat != RepeatOff {
+		t.Fatalf("shuffle status = %d, resp = %+v, err = %v", rec.Code, resp, err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.UpdateRepeat(rec, shuffleRequest(`{"mode":"one"}`))
+	resp = QueueResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || resp.Repeat != RepeatOne || !resp.Shuffle {
+		t.Fatalf("repeat status = %d, resp = %+v, err = %v", rec.Code, resp, err)
+	}
+
+	for _, tc := range []struct {
+		handler func(http.ResponseWriter, *http.Request)
+		body    string
+	}{
+		{h.UpdateShuffle, `{}`},
+		{h.UpdateShuffle, `{"enabled":"yes"}`},
+		{h.UpdateRepeat, `{"mode":"shuffle"}`},
+		{h.UpdateRepeat, `{"mode":"all","extra":1}`},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, shuffleRequest(tc.body))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want 400", tc.body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// QueueSnapshots keeps durable copies of queues, so a queue outlives its
+// Redis TTL, eviction and Redis restarts. db.QueueSnapshotRepository
+// satisfies it.
+type QueueSnapshots interface {
+	SaveQueueSnapshot(ctx context.Context, userID uuid.UUID, name string, version int64, state []byte) error
+	GetQueueSnapshot(ctx context.Context, userID uuid.UUID, name string) ([]byte, error)
+	DeleteQueueSnapshot(ctx context.Context, userID uuid.UUID, name string) error
+	SaveQueueIndexSnapshot(ctx context.Context, userID uuid.UUID, index []byte) error
+	GetQueueIndexSnapshot(ctx context.Context, userID uuid.UUID) ([]byte, error)
+}
+
+// SetSnapshots makes every queue write also go to snapshots, and a queue
+// missing from Redis be reloaded from them. Redis stays the fast path: a
+// failed snapshot write is logged, not returned, since the queue itself
+// was saved.
+func (s *Service) SetSnapshots(snapshots QueueSnapshots) {
+	s.snapshots = snapshots
+}
+
+// snapshotQueue writes a queue through to its snapshot.
+func (s *Service) snapshotQueue(ctx context.Context, userID, name string, version int64, data []byte) {
+	id, ok := s.snapshotUser(userID)
+	if !ok {
+		return
+	}
+	if name == "" {
+		name = DefaultQueueName
+	}
+	if err := s.snapshots.SaveQueueSnapshot(ctx, id, name, version, data); err != nil {
+		log.Printf("Warning: failed to snapshot queue %q for user %s: %v", name, userID, err)
+	}
+}
+
+// positionSnapshotInterval is how often a queue's snapshot takes up playback
+// position changes alone. A queue restored from its snapshot resumes at most
+// this far behind.
+const positionSnapshotInterval = time.Minute
+
+// positionThrottle remembers when each queue's position was last
+// snapshotted.
+type positionThrottle struct {
+	mu        sync.Mutex
+	last      map[string]time.Time
+	lastSweep time.Time
+}
+
+// due reports whether a position change to the queue should be snapshotted
+// at now, and if so counts it as snapshotted.
+func (t *positionThrottle) due(userID, name string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		t.last = make(map[string]time.Time)
+	}
+	// Forget queues that have gone quiet once per interval so the map
+	// stays small.
+	if now.Sub(t.lastSweep) >= positionSnapshotInterval {
+		for k, last := range t.last {
+			if now.Sub(last) >= positionSnapshotInterval {
+				delete(t.last, k)
+			}
+		}
+		t.lastSweep = now
+	}
+	key := userID + ":" + name
+	if last, ok := t.last[key]; ok && now.Sub(last) < positionSnapshotInterval {
+		return false
+	}
+	t.last[key] = now
+	return true
+}
+
+// snapshotIndex writes the user's queue index through to its snapshot.
+func (s *Service) snapshotIndex(ctx context.Context, userID string, index *queueIndex) {
+	id, ok := s.snapshotUser(userID)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(index)
+	if err == nil {
+		err = s.snapshots.SaveQueueIndexSnapshot(ctx, id, data)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to snapshot queue index for user %s: %v", userID, err)
+	}
+}
+
+// deleteQueueSnapshot forgets a deleted queue.
+func (s *Service) deleteQueueSnapshot(ctx context.Context, userID, name string) {
+	id, ok := s.snapshotUser(userID)
+	if !ok {
+		return
+	}
+	if err := s.snapshots.DeleteQueueSnapshot(ctx, id, name); err != nil {
+		log.Printf("Warning: failed to delete queue snapshot %q for user %s: %v", name, userID, err)
+	}
+}
+
+// restoreQueue returns a queue's snapshot when Redis has none, putting it
+// back in Redis. ok is false when there is no snapshot either. A snapshot
+// that cannot be read is an error rather than an empty queue, which the
+// next edit would save over it.
+func (s *Service) restoreQueue(ctx context.Context, userID, name string) (data []byte, ok bool, err error) {
+	id, ok := s.snapshotUser(userID)
+	if !ok {
+		return nil, false, nil
+	}
+	if name == "" {
+		name = DefaultQueueName
+	}
+	data, err = s.snapshots.GetQueueSnapshot(ctx, id, name)
+	if errors.Is(err, db.ErrQueueSnapshotNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get queue snapshot: %w", err)
+	}
+	if err := s.client.Set(ctx, s.queueKey(userID, name), data, queueTTL).Err(); err != nil {
+		log.Printf("Warning: failed to cache restored queue %q for user %s: %v", name, userID, err)
+	}
+	return data, true, nil
+}
+
+// restoreIndex is restoreQueue for the user's queue index.
+func (s *Service) restoreIndex(ctx context.Context, userID string) (data []byte, ok bool, err error) {
+	id, ok := s.snapshotUser(userID)
+	if !ok {
+		return nil, false, nil
+	}
+	data, err = s.snapshots.GetQueueIndexSnapshot(ctx, id)
+	if errors.Is(err, db.ErrQueueSnapshotNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get queue index snapshot: %w", err)
+	}
+	if err := s.client.Set(ctx, s.queueIndexKey(userID), data, queueTTL).Err(); err != nil {
+		log.Printf("Warning: failed to cache restored queue index for user %s: %v", userID, err)
+	}
+	return data, true, nil
+}
+
+// snapshotUser parses userID for the snapshot store, reporting false when
+// snapshots are disabled.
+func (s *Service) snapshotUser(userID string) (uuid.UUID, bool) {
+	if s.snapshots == nil {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,24 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPositionThrottleSnapshotsOncePerInterval(t *testing.T) {
+	var throttle positionThrottle
+	start := time.Now()
+
+	if !throttle.due("user-1", DefaultQueueName, start) {
+		t.Fatal("first position change should be snapshotted")
+	}
+	if throttle.due("user-1", DefaultQueueName, start.Add(5*time.Second)) {
+		t.Fatal("heartbeat within the interval should not be snapshotted")
+	}
+	if !throttle.due("user-1", "party", start.Add(5*time.Second)) {
+		t.Fatal("another queue has its own interval")
+	}
+	if !throttle.due("user-1", DefaultQueueName, start.Add(positionSnapshotInterval)) {
+		t.Fatal("position change after the interval should be snapshotted")
+	}
+}
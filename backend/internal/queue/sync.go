@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/websocket"
+)
+
+const (
+	// queueVersionHeader carries the queue version an edit was made against.
+	queueVersionHeader = "X-Queue-Version"
+	// deviceIDHeader names the device making a request, so the change it
+	// causes is not pushed back to it.
+	deviceIDHeader = "X-Device-ID"
+)
+
+// QueueChange summarizes an edit to the shared queue for the user's other
+// devices, which refetch the queue when its version is newer than theirs.
+type QueueChange struct {
+	Version         int64     `json:"version"`
+	CurrentPosition int       `json:"currentPosition"`
+	Items           int       `json:"items"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// QueueNotifier tells a user's devices the queue changed.
+type QueueNotifier interface {
+	QueueChanged(userID uuid.UUID, fromDeviceID string, change QueueChange)
+}
+
+// SetQueueNotifier makes edits made through Synced handlers notify the
+// user's other devices.
+func (h *Handlers) SetQueueNotifier(notifier QueueNotifier) {
+	h.notifier = notifier
+}
+
+// Synced wraps a handler that edits the queue. A request may send the queue
+// version it last saw in X-Queue-Version; if another device has saved the
+// queue since, the edit is refused with 409 QUEUE_CONFLICT and the client
+// should refetch and retry, so simultaneous edits never silently overwrite
+// each other. Requests without the header edit whatever is current. After a
+// successful edit the user's other devices are told the new version.
+func (h *Handlers) Synced(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userCtx := auth.GetUserFromContext(r.Context())
+		if userCtx == nil {
+			next(w, r)
+			return
+		}
+		userID := userCtx.UserID.String()
+
+		if raw := r.Header.Get(queueVersionHeader); raw != "" {
+			version, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || version < 0 {
+				writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "X-Queue-Version must be a queue version")
+				return
+			}
+			state, err := h.service.GetQueue(r.Context(), userID)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get queue")
+				return
+			}
+			if state.Version != version {
+				writeError(w, http.StatusConflict, "QUEUE_CONFLICT", "queue changed since version "+raw+"; refetch it and retry")
+				return
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		if h.notifier == nil || recorder.status >= http.StatusMultipleChoices {
+			return
+		}
+
+		state, err := h.service.GetQueue(r.Context(), userID)
+		if err != nil {
+			log.Printf("Warning: failed to load queue to notify devices of user %s: %v", userID, err)
+			return
+		}
+		deviceID := r.Header.Get(deviceIDHeader)
+		if !websocket.ValidDeviceID(deviceID) {
+			deviceID = ""
+		}
+		h.notifier.QueueChanged(userCtx.UserID, deviceID, QueueChange{
+			Version:         state.Version,
+			CurrentPosition: state.CurrentPosition,
+			Items:           len(state.Items),
+			UpdatedAt:       state.UpdatedAt,
+		})
+	}
+}
+
+// statusRecorder remembers the status a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeQueueNotifier struct {
+	devices []string
+	changes []QueueChange
+}
+
+func (f *fakeQueueNotifier) QueueChanged(_ uuid.UUID, fromDeviceID string, change QueueChange) {
+	f.devices = append(f.devices, fromDeviceID)
+	f.changes = append(f.changes, change)
+}
+
+func TestSyncedRefusesStaleEditsAndNotifiesDevices(t *testing.T) {
+	state := twoTrackQueue()
+	state.Version = 4
+	service := &fakeQueueHandlerService{state: state}
+	notifier := &fakeQueueNotifier{}
+	h := NewHandlers(service)
+	h.SetQueueNotifier(notifier)
+	repeat := h.Synced(h.UpdateRepeat)
+
+	req := shuffleRequest(`{"mode":"all"}`)
+	req.Header.Set(queueVersionHeader, "3")
+	rec := httptest.NewRecorder()
+	repeat(rec, req)
+	if rec.Code != http.StatusConflict || service.state.Repeat != "" || len(notifier.changes) != 0 {
+		t.Fatalf("stale edit status = %d, repeat = %q, notified %d; want 409 and nothing changed", rec.Code, service.state.Repeat, len(notifier.changes))
+	}
+
+	req = shuffleRequest(`{"mode":"all"}`)
+	req.Header.Set(queueVersionHeader, "4")
+	req.Header.Set(deviceIDHeader, "phone")
+	rec = httptest.NewRecorder()
+	repeat(rec, req)
+	if rec.Code != http.StatusOK || service.state.Repeat != RepeatAll {
+		t.Fatalf("current edit status = %d, repeat = %q; want 200 and all", rec.Code, service.state.Repeat)
+	}
+	if len(notifier.changes) != 1 || notifier.devices[0] != "phone" || notifier.changes[0].Items != 2 {
+		t.Fatalf("notified %v %+v, want one change from phone", notifier.devices, notifier.changes)
+	}
+
+	// Failed edits notify no one.
+	rec = httptest.NewRecorder()
+	repeat(rec, shuffleRequest(`{"mode":"sometimes"}`))
+	if rec.Code != http.StatusBadRequest || len(notifier.changes) != 1 {
+		t.Fatalf("invalid edit status = %d, notified %d; want 400 and no new notice", rec.Code, len(notifier.changes))
+	}
+}
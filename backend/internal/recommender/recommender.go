@@ -0,0 +1,372 @@
+// Package recommender suggests tracks from what a user has been listening
+// to: tracks that share playlists or listening sessions with their recent
+// favorites, and tracks by the same or MusicBrainz-related artists.
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+// List kinds.
+const (
+	// KindBecauseYouListened lists tracks around one recent favorite.
+	KindBecauseYouListened = "because_you_listened"
+	// KindMix blends recommendations for every recent favorite and rotates
+	// daily.
+	KindMix = "mix"
+)
+
+// Signal weights. A shared playlist is a deliberate pairing, so it counts
+// for more than two tracks happening to be played in one sitting.
+const (
+	playlistWeight      = 2.0
+	sessionWeight       = 1.0
+	sameArtistWeight    = 0.5
+	relatedArtistWeight = 0.75
+)
+
+// Options bounds how much history is read and how long lists are.
+type Options struct {
+	Lookback      time.Duration // how far back seed plays are read
+	Session       time.Duration // plays this close together share a session
+	Seeds         int           // "because you listened to" lists
+	MixSeeds      int           // recent favorites blended into the mix
+	TracksPerList int
+}
+
+// DefaultOptions returns the service defaults.
+func DefaultOptions() Options {
+	return Options{
+		Lookback:      90 * 24 * time.Hour,
+		Session:       30 * time.Minute,
+		Seeds:         3,
+		MixSeeds:      10,
+		TracksPerList: 20,
+	}
+}
+
+// Store is the history the service reads; *db.RecommendationRepository
+// implements it.
+type Store interface {
+	RecommendationSeeds(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]db.RecommendationSeed, error)
+	PlaylistCoOccurrences(ctx context.Context, trackID int64, limit int) ([]db.TrackScore, error)
+	SessionCoOccurrences(ctx context.Context, trackID int64, since time.Time, window time.Duration, limit int) ([]db.TrackScore, error)
+	TracksByArtists(ctx context.Context, artistIDs []uuid.UUID, limit int) ([]db.TrackScore, error)
+	LibraryTrackIDs(ctx context.Context, userID uuid.UUID, trackIDs []int64) (map[int64]bool, error)
+}
+
+// TrackLoader loads recommended tracks; *db.TrackRepository implements it.
+type TrackLoader interface {
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error)
+}
+
+// ArtistRelations finds related artists; *musicbrainz.Client implements it.
+type ArtistRelations interface {
+	GetRelatedArtists(ctx context.Context, mbID string) ([]musicbrainz.RelatedArtist, error)
+}
+
+// Recommendation is one recommended track. InLibrary tells whether the user
+// already has it.
+type Recommendation struct {
+	Track     *db.Track
+	Score     float64
+	InLibrary bool
+}
+
+// List is one titled list of recommendations. Seed is the track a
+// KindBecauseYouListened list was built around.
+type List struct {
+	Kind   string
+	Title  string
+	Seed   *db.RecommendationSeed
+	Tracks []Recommendation
+}
+
+// Service builds recommendation lists on request.
+type Service struct {
+	store   Store
+	tracks  TrackLoader
+	artists ArtistRelations
+	opts    Options
+	now     func() time.Time
+}
+
+func NewService(store Store, tracks TrackLoader, opts Options) *Service {
+	return &Service{store: store, tracks: tracks, opts: opts, now: time.Now}
+}
+
+// SetArtistRelations adds tracks by MusicBrainz-related artists to each
+// seed's candidates. Without it only the seed's own artist is used.
+func (s *Service) SetArtistRelations(artists ArtistRelations) {
+	s.artists = artists
+}
+
+// Recommend returns a "because you listened to" list for each of the user's
+// top recent favorites followed by a mix across all of them. limit caps each
+// list; 0 uses the configured length. A user with no recent plays gets no
+// lists.
+func (s *Service) Recommend(ctx context.Context, userID uuid.UUID, limit int) ([]List, error) {
+	if limit <= 0 {
+		limit = s.opts.TracksPerList
+	}
+	now := s.now()
+	since := now.Add(-s.opts.Lookback)
+
+	seeds, err := s.store.RecommendationSeeds(ctx, userID, since, max(s.opts.Seeds, s.opts.MixSeeds))
+	if err != nil {
+		return nil, err
+	}
+	if len(seeds) == 0 {
+		return []List{}, nil
+	}
+
+	seedIDs := make(map[int64]bool, len(seeds))
+	for _, seed := range seeds {
+		seedIDs[seed.TrackID] = true
+	}
+
+	var lists []List
+	mix := map[int64]float64{}
+	for i, seed := range seeds {
+		scores, err := s.candidates(ctx, seed, since, limit)
+		if err != nil {
+			return nil, err
+		}
+		if i < s.opts.Seeds {
+			lists = append(lists, List{
+				Kind:   KindBecauseYouListened,
+				Title:  fmt.Sprintf("Because you listened to %s", seed.Title),
+				Seed:   &seeds[i],
+				Tracks: ranked(scores, limit*2),
+			})
+		}
+		// Favorites count towards the mix in proportion to how much they
+		// were played, and are left out of it since they are already known.
+		weight := float64(seed.PlayCount) / float64(seeds[0].PlayCount)
+		for id, score := range scores {
+			if !seedIDs[id] {
+				mix[id] += score * weight
+			}
+		}
+	}
+	if len(mix) > 0 {
+		lists = append(lists, List{
+			Kind:   KindMix,
+			Title:  "Your Discovery Mix",
+			Tracks: rotate(ranked(mix, limit*3), limit*2, daySeed(userID, now)),
+		})
+	}
+
+	if err := s.load(ctx, userID, lists, limit); err != nil {
+		return nil, err
+	}
+	kept := lists[:0]
+	for _, list := range lists {
+		if len(list.Tracks) > 0 {
+			kept = append(kept, list)
+		}
+	}
+	return kept, nil
+}
+
+// Seed names what Similar finds tracks like: a track, an artist, or a
+// track with its artist known.
+type Seed struct {
+	TrackID    int64
+	MBArtistID *uuid.UUID
+}
+
+// Similar returns up to limit playable tracks like the seed, best first,
+// never including the seed track. A track seed's artist is looked up when
+// the seed does not name one.
+func (s *Service) Similar(ctx context.Context, userID uuid.UUID, seed Seed, limit int) ([]Recommendation, error) {
+	if limit <= 0 {
+		limit = s.opts.TracksPerList
+	}
+	if seed.TrackID != 0 && seed.MBArtistID == nil {
+		tracks, err := s.tracks.GetByIDs(ctx, []int64{seed.TrackID})
+		if err != nil {
+			return nil, err
+		}
+		if track, ok := tracks[seed.TrackID]; ok {
+			seed.MBArtistID = track.MBArtistID
+		}
+	}
+
+	scores, err := s.candidates(ctx, db.RecommendationSeed{TrackID: seed.TrackID, MBArtistID: seed.MBArtistID}, s.now().Add(-s.opts.Lookback), limit)
+	if err != nil {
+		return nil, err
+	}
+	lists := []List{{Tracks: ranked(scores, limit*2)}}
+	if err := s.load(ctx, userID, lists, limit); err != nil {
+		return nil, err
+	}
+	return lists[0].Tracks, nil
+}
+
+// candidates scores every track associated with the seed, excluding the
+// seed itself.
+func (s *Service) candidates(ctx context.Context, seed db.RecommendationSeed, since time.Time, limit int) (map[int64]float64, error) {
+	scores := map[int64]float64{}
+	fetch := limit * 3
+
+	playlists, err := s.store.PlaylistCoOccurrences(ctx, seed.TrackID, fetch)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range playlists {
+		scores[c.TrackID] += playlistWeight * float64(c.Score)
+	}
+
+	sessions, err := s.store.SessionCoOccurrences(ctx, seed.TrackID, since, s.opts.Session, fetch)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range sessions {
+		scores[c.TrackID] += sessionWeight * float64(c.Score)
+	}
+
+	if seed.MBArtistID != nil {
+		byArtist, err := s.store.TracksByArtists(ctx, []uuid.UUID{*seed.MBArtistID}, fetch)
+		if err != nil {
+			return nil, err
+		}
+		addArtistScores(scores, byArtist, sameArtistWeight)
+
+		if related := s.relatedArtists(ctx, *seed.MBArtistID); len(related) > 0 {
+			byRelated, err := s.store.TracksByArtists(ctx, related, fetch)
+			if err != nil {
+				return nil, err
+			}
+			addArtistScores(scores, byRelated, relatedArtistWeight)
+		}
+	}
+
+	delete(scores, seed.TrackID)
+	return scores, nil
+}
+
+// relatedArtists returns the MusicBrainz IDs of artists related to the
+// artist. A lookup failure only costs those candidates, so it is logged.
+func (s *Service) relatedArtists(ctx context.Context, artistID uuid.UUID) []uuid.UUID {
+	if s.artists == nil {
+		return nil
+	}
+	related, err := s.artists.GetRelatedArtists(ctx, artistID.String())
+	if err != nil {
+		log.Printf("Warning: related artist lookup failed for %s: %v", artistID, err)
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(related))
+	for _, a := range related {
+		if id, err := uuid.Parse(a.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// addArtistScores gives every track by the artists weight, plus up to as
+// much again the more it has been played.
+func addArtistScores(scores map[int64]float64, tracks []db.TrackScore, weight float64) {
+	for _, t := range tracks {
+		plays := float64(t.Score)
+		scores[t.TrackID] += weight * (1 + plays/(plays+10))
+	}
+}
+
+// ranked returns up to n of the scored tracks, best first.
+func ranked(scores map[int64]float64, n int) []Recommendation {
+	recs := make([]Recommendation, 0, len(scores))
+	for id, score := range scores {
+		recs = append(recs, Recommendation{Track: &db.Track{ID: id}, Score: score})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].Track.ID < recs[j].Track.ID
+	})
+	if len(recs) > n {
+		recs = recs[:n]
+	}
+	return recs
+}
+
+// rotate picks n of the recommendations, favoring higher scores, in an order
+// that holds for seed. The same seed yields the same mix.
+func rotate(recs []Recommendation, n int, seed uint64) []Recommendation {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	pool := append([]Recommendation(nil), recs...)
+	picked := make([]Recommendation, 0, min(n, len(pool)))
+	for len(picked) < n && len(pool) > 0 {
+		total := 0.0
+		for _, r := range pool {
+			total += r.Score
+		}
+		target := rng.Float64() * total
+		i := 0
+		for ; i < len(pool)-1; i++ {
+			target -= pool[i].Score
+			if target < 0 {
+				break
+			}
+		}
+		picked = append(picked, pool[i])
+		pool = append(pool[:i], pool[i+1:]...)
+	}
+	return picked
+}
+
+// load replaces each recommendation's placeholder track with the stored
+// track, drops tracks that cannot be played, trims lists to limit, and marks
+// library membership.
+func (s *Service) load(ctx context.Context, userID uuid.UUID, lists []List, limit int) error {
+	var ids []int64
+	for _, list := range lists {
+		for _, r := range list.Tracks {
+			ids = append(ids, r.Track.ID)
+		}
+	}
+	tracks, err := s.tracks.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	inLibrary, err := s.store.LibraryTrackIDs(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range lists {
+		kept := make([]Recommendation, 0, limit)
+		for _, r := range lists[i].Tracks {
+			track, ok := tracks[r.Track.ID]
+			if !ok || track.QuarantinedAt.Valid || !track.StorageKey.Valid || len(kept) == limit {
+				continue
+			}
+			r.Track = track
+			r.InLibrary = inLibrary[track.ID]
+			kept = append(kept, r)
+		}
+		lists[i].Tracks = kept
+	}
+	return nil
+}
+
+// daySeed derives the mix rotation for a user on a UTC calendar day.
+func daySeed(userID uuid.UUID, day time.Time) uint64 {
+	h := fnv.New64a()
+	h.Write(userID[:])
+	h.Write([]byte(day.UTC().Format("2006-01-02")))
+	return h.Sum64()
+}
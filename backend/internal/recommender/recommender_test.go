@@ -0,0 +1,194 @@
+package recommender
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/musicbrainz"
+)
+
+type fakeStore struct {
+	seeds     []db.RecommendationSeed
+	playlists map[int64][]db.TrackScore
+	sessions  map[int64][]db.TrackScore
+	byArtist  map[uuid.UUID][]db.TrackScore
+	library   map[int64]bool
+}
+
+func (f *fakeStore) RecommendationSeeds(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]db.RecommendationSeed, error) {
+	return f.seeds, nil
+}
+
+func (f *fakeStore) PlaylistCoOccurrences(ctx context.Context, trackID int64, limit int) ([]db.TrackScore, error) {
+	return f.playlists[trackID], nil
+}
+
+func (f *fakeStore) SessionCoOccurrences(ctx context.Context, trackID int64, since time.Time, window time.Duration, limit int) ([]db.TrackScore, error) {
+	return f.sessions[trackID], nil
+}
+
+func (f *fakeStore) TracksByArtists(ctx context.Context, artistIDs []uuid.UUID, limit int) ([]db.TrackScore, error) {
+	var out []db.TrackScore
+	for _, id := range artistIDs {
+		out = append(out, f.byArtist[id]...)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) LibraryTrackIDs(ctx context.Context, userID uuid.UUID, trackIDs []int64) (map[int64]bool, error) {
+	return f.library, nil
+}
+
+type fakeTracks struct{ quarantined map[int64]bool }
+
+func (f fakeTracks) GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error) {
+	out := map[int64]*db.Track{}
+	for _, id := range ids {
+		out[id] = &db.Track{
+			ID:            id,
+			StorageKey:    sql.NullString{String: "audio", Valid: true},
+			QuarantinedAt: sql.NullTime{Valid: f.quarantined[id]},
+		}
+	}
+	return out, nil
+}
+
+type fakeRelations map[string][]musicbrainz.RelatedArtist
+
+func (f fakeRelations) GetRelatedArtists(ctx context.Context, mbID string) ([]musicbrainz.RelatedArtist, error) {
+	related, ok := f[mbID]
+	if !ok {
+		return nil, errors.New("lookup failed")
+	}
+	return related, nil
+}
+
+func trackIDs(recs []Recommendation) []int64 {
+	ids := make([]int64, len(recs))
+	for i, r := range recs {
+		ids[i] = r.Track.ID
+	}
+	return ids
+}
+
+func TestRecommendRanksSignalsAroundEachSeed(t *testing.T) {
+	artist, related := uuid.New(), uuid.New()
+	store := &fakeStore{
+		seeds: []db.RecommendationSeed{
+			{TrackID: 1, Title: "Seed", MBArtistID: &artist, PlayCount: 10},
+		},
+		playlists: map[int64][]db.TrackScore{1: {{TrackID: 10, Score: 2}}},
+		sessions:  map[int64][]db.TrackScore{1: {{TrackID: 11, Score: 3}, {TrackID: 10, Score: 1}}},
+		byArtist: map[uuid.UUID][]db.TrackScore{
+			artist:  {{TrackID: 1, Score: 50}, {TrackID: 12, Score: 0}},
+			related: {{TrackID: 13, Score: 0}},
+		},
+		library: map[int64]bool{11: true},
+	}
+	svc := NewService(store, fakeTracks{}, DefaultOptions())
+	svc.SetArtistRelations(fakeRelations{artist.String(): {{ID: related.String(), Name: "Side Project"}}})
+
+	lists, err := svc.Recommend(context.Background(), uuid.New(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 || lists[0].Kind != KindBecauseYouListened || lists[1].Kind != KindMix {
+		t.Fatalf("lists = %+v, want one seed list and the mix", lists)
+	}
+	if lists[0].Title != "Because you listened to Seed" {
+		t.Fatalf("title = %q", lists[0].Title)
+	}
+	// Playlist 2*2+session 1 beats session 3, then related beats same artist;
+	// the seed itself is never recommended.
+	if got, want := trackIDs(lists[0].Tracks), []int64{10, 11, 13, 12}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("because list = %v, want %v", got, want)
+	}
+	if !lists[0].Tracks[1].InLibrary || lists[0].Tracks[0].InLibrary {
+		t.Fatalf("library flags = %+v", lists[0].Tracks)
+	}
+}
+
+func TestRecommendMixSkipsFavoritesAndUnplayableTracks(t *testing.T) {
+	store := &fakeStore{
+		seeds: []db.RecommendationSeed{
+			{TrackID: 1, Title: "A", PlayCount: 4},
+			{TrackID: 2, Title: "B", PlayCount: 2},
+		},
+		sessions: map[int64][]db.TrackScore{
+			1: {{TrackID: 2, Score: 5}, {TrackID: 20, Score: 1}, {TrackID: 21, Score: 1}},
+			2: {{TrackID: 1, Score: 5}, {TrackID: 22, Score: 1}},
+		},
+	}
+	opts := DefaultOptions()
+	opts.Seeds = 1
+	svc := NewService(store, fakeTracks{quarantined: map[int64]bool{21: true}}, opts)
+
+	lists, err := svc.Recommend(context.Background(), uuid.New(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("lists = %d, want one seed list and the mix", len(lists))
+	}
+	mix := map[int64]bool{}
+	for _, r := range lists[1].Tracks {
+		mix[r.Track.ID] = true
+	}
+	if len(mix) != 2 || !mix[20] || !mix[22] {
+		t.Fatalf("mix = %v, want 20 and 22 without favorites or the quarantined track", trackIDs(lists[1].Tracks))
+	}
+}
+
+func TestRecommendWithoutHistoryReturnsNothing(t *testing.T) {
+	svc := NewService(&fakeStore{}, fakeTracks{}, DefaultOptions())
+	lists, err := svc.Recommend(context.Background(), uuid.New(), 0)
+	if err != nil || len(lists) != 0 {
+		t.Fatalf("lists = %+v, err = %v; want none", lists, err)
+	}
+}
+
+func TestRotateIsStableForSeed(t *testing.T) {
+	recs := ranked(map[int64]float64{1: 5, 2: 4, 3: 3, 4: 2, 5: 1}, 5)
+	first := rotate(recs, 3, 7)
+	if len(first) != 3 || !reflect.DeepEqual(trackIDs(first), trackIDs(rotate(recs, 3, 7))) {
+		t.Fatalf("rotation = %v, want 3 tracks stable for a seed", trackIDs(first))
+	}
+}
+
+func TestSimilarLooksUpTheSeedArtist(t *testing.T) {
+	artist := uuid.New()
+	store := &fakeStore{
+		sessions: map[int64][]db.TrackScore{5: {{TrackID: 6, Score: 1}}},
+		byArtist: map[uuid.UUID][]db.TrackScore{artist: {{TrackID: 5, Score: 9}, {TrackID: 7, Score: 0}}},
+	}
+	svc := NewService(store, artistTracks{fakeTracks{}, artist}, DefaultOptions())
+
+	recs, err := svc.Similar(context.Background(), uuid.New(), Seed{TrackID: 5}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := trackIDs(recs), []int64{6, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("similar = %v, want %v", got, want)
+	}
+}
+
+// artistTracks credits every loaded track to one artist.
+type artistTracks struct {
+	fakeTracks
+	artist uuid.UUID
+}
+
+func (a artistTracks) GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error) {
+	tracks, _ := a.fakeTracks.GetByIDs(ctx, ids)
+	for _, t := range tracks {
+		t.MBArtistID = &a.artist
+	}
+	return tracks, nil
+}
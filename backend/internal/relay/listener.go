@@ -0,0 +1,301 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	handshakeTimeout = 30 * time.Second
+	maxRedialDelay   = 30 * time.Second
+)
+
+// errUnauthorized means the relay rejected the tunnel token.
+var errUnauthorized = errors.New("relay rejected the tunnel token")
+
+// Listener is a net.Listener whose connections arrive through a relay.
+// Pass it to http.Server.Serve alongside the server's own listener.
+type Listener struct {
+	relay  *url.URL
+	token  string
+	dialer *net.Dialer
+	tls    *tls.Config
+
+	conns     chan net.Conn
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Listen keeps the given number of idle tunnels open to the relay at
+// relayURL, an http or https URL, authenticating with token. It returns
+// immediately; tunnels that cannot be opened are retried with backoff and
+// logged.
+func Listen(relayURL, token string, connections int) (*Listener, error) {
+	u, err := url.Parse(relayURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("relay URL must be an http or https URL, got %q", relayURL)
+	}
+	if token == "" {
+		return nil, errors.New("relay token is required")
+	}
+	if connections < 1 {
+		connections = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		relay:  u,
+		token:  token,
+		dialer: &net.Dialer{Timeout: handshakeTimeout, KeepAlive: PingInterval},
+		conns:  make(chan net.Conn),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if u.Scheme == "https" {
+		l.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	for i := 0; i < connections; i++ {
+		l.wg.Add(1)
+		go l.keep()
+	}
+	return l, nil
+}
+
+// Accept waits for a client to arrive through a tunnel.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.ctx.Done():
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops opening tunnels and closes idle ones. Connections already
+// accepted are unaffected.
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		l.cancel()
+		l.wg.Wait()
+	})
+	return nil
+}
+
+// Addr returns the relay's address.
+func (l *Listener) Addr() net.Addr {
+	return relayAddr(l.relay.Host)
+}
+
+type relayAddr string
+
+func (a relayAddr) Network() string { return "relay" }
+func (a relayAddr) String() string  { return string(a) }
+
+// keep holds one idle tunnel open at a time, opening the next as soon as
+// the last is handed to Accept.
+func (l *Listener) keep() {
+	defer l.wg.Done()
+	delay := time.Second
+	for l.ctx.Err() == nil {
+		conn, err := l.open()
+		if err != nil {
+			if l.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Warning: relay tunnel to %s failed: %v", l.relay.Host, err)
+			select {
+			case <-l.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRedialDelay)
+			continue
+		}
+		delay = time.Second
+		select {
+		case l.conns <- conn:
+		case <-l.ctx.Done():
+			conn.Close()
+			return
+		}
+	}
+}
+
+// open dials the relay, upgrades to a tunnel, and waits until a client is
+// attached to it.
+func (l *Listener) open() (net.Conn, error) {
+	addr := l.relay.Host
+	if l.relay.Port() == "" {
+		port := "80"
+		if l.tls != nil {
+			port = "443"
+		}
+		addr = net.JoinHostPort(l.relay.Hostname(), port)
+	}
+	conn, err := l.dialer.DialContext(l.ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if l.tls != nil {
+		conn = tls.Client(conn, l.tls)
+	}
+	// Closing the connection is the only way to interrupt a blocked read.
+	stop := context.AfterFunc(l.ctx, func() { conn.Close() })
+	defer stop()
+
+	reader, err := l.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for {
+		conn.SetReadDeadline(time.Now().Add(2 * PingInterval))
+		signal, err := reader.ReadByte()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("idle tunnel: %w", err)
+		}
+		switch signal {
+		case pingByte:
+		case attachByte:
+			conn.SetReadDeadline(time.Time{})
+			return &tunnelConn{Conn: conn, reader: reader}, nil
+		default:
+			conn.Close()
+			return nil, fmt.Errorf("unexpected tunnel signal %#x", signal)
+		}
+	}
+}
+
+func (l *Listener) handshake(conn net.Conn) (*bufio.Reader, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Scheme: l.relay.Scheme, Host: l.relay.Host, Path: TunnelPath},
+		Host:       l.relay.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Connection":    {"Upgrade"},
+			"Upgrade":       {upgradeProtocol},
+			"Authorization": {"Bearer " + l.token},
+		},
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusSwitchingProtocols:
+		return reader, nil
+	case http.StatusUnauthorized:
+		return nil, errUnauthorized
+	default:
+		return nil, fmt.Errorf("relay answered %s", resp.Status)
+	}
+}
+
+// tunnelConn reads through the buffer the handshake filled.
+type tunnelConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+type relayedKey struct{}
+
+// ConnContext marks connections that arrived through a tunnel. Set it as the
+// http.Server's ConnContext and wrap the handler in ClientAddr.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	if _, ok := c.(*tunnelConn); ok {
+		return context.WithValue(ctx, relayedKey{}, true)
+	}
+	return ctx
+}
+
+// ClientAddr gives requests that arrived through a tunnel the client's
+// address as RemoteAddr instead of the relay's, so rate limits and sessions
+// see the real client. The relay sends it as the last X-Forwarded-For entry.
+// Other requests keep their RemoteAddr, since anyone can set the header.
+func ClientAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if relayed, _ := r.Context().Value(relayedKey{}).(bool); relayed {
+			if ip := forwardedClient(r.Header.Values("X-Forwarded-For")); ip != "" {
+				r = r.WithContext(r.Context())
+				r.RemoteAddr = net.JoinHostPort(ip, "0")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClient returns the last address in X-Forwarded-For, the one the
+// relay added, or "" when it is not an IP.
+func forwardedClient(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	ip := strings.TrimSpace(entries[len(entries)-1])
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// Forward copies every connection accepted from ln to and from target, so
+// a service that is not an http.Handler, such as object storage, can be
+// reached through a relay. It returns when ln is closed.
+func Forward(ln net.Listener, target string) error {
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer client.Close()
+			upstream, err := net.DialTimeout("tcp", target, handshakeTimeout)
+			if err != nil {
+				log.Printf("Warning: relay forward to %s failed: %v", target, err)
+				return
+			}
+			defer upstream.Close()
+			done := make(chan struct{})
+			go func() {
+				io.Copy(upstream, client)
+				if tcp, ok := upstream.(*net.TCPConn); ok {
+					tcp.CloseWrite()
+				}
+				close(done)
+			}()
+			io.Copy(client, upstream)
+			client.Close()
+			<-done
+		}()
+	}
+}
@@ -0,0 +1,32 @@
+// Package relay lets an instance behind NAT be reached through a public
+// relay without port forwarding or certificates of its own. The instance
+// keeps a few idle outbound connections ("tunnels") open to the relay; the
+// relay hands each incoming client connection to one of them and the
+// instance serves it as if it had accepted it locally.
+//
+// A tunnel starts as an HTTP/1.1 upgrade:
+//
+//	GET /_omp/tunnel HTTP/1.1
+//	Upgrade: omp-tunnel
+//	Authorization: Bearer <token>
+//
+// The relay answers 101 Switching Protocols and the tunnel goes idle. While
+// idle the relay writes a ping byte (0x00) every PingInterval; when a client
+// arrives it writes an attach byte (0x01), and from then on the tunnel
+// carries that client's HTTP traffic unchanged. Tunnels are used once.
+package relay
+
+import "time"
+
+const (
+	// TunnelPath is the relay path instances open tunnels on.
+	TunnelPath = "/_omp/tunnel"
+	// upgradeProtocol is the Upgrade header value of a tunnel request.
+	upgradeProtocol = "omp-tunnel"
+	// PingInterval is how often the relay pings idle tunnels. Instances
+	// reopen a tunnel that has been silent for twice as long.
+	PingInterval = 30 * time.Second
+
+	pingByte   byte = 0x00
+	attachByte byte = 0x01
+)
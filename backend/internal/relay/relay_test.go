@@ -0,0 +1,130 @@
+package relay
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func relayGet(t *testing.T, relayURL, host, path string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, relayURL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = host
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s via relay: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestRelayServesInstanceThroughTunnel(t *testing.T) {
+	relay := NewServer(map[string]string{"Music.Example.Test": "secret"})
+	defer relay.Close()
+	public := httptest.NewServer(relay)
+	defer public.Close()
+
+	ln, err := Listen(public.URL, "secret", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host+" "+r.URL.Path+" from "+r.Header.Get("X-Forwarded-For"))
+	})}
+	go instance.Serve(ln)
+	defer instance.Close()
+
+	for i := 0; i < 5; i++ {
+		resp, body := relayGet(t, public.URL, "music.example.test", "/health")
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(body, "music.example.test /health from 127.0.0.1") {
+			t.Fatalf("request %d: status = %d body = %q", i, resp.StatusCode, body)
+		}
+	}
+
+	if resp, _ := relayGet(t, public.URL, "other.example.test", "/health"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown host: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestRelayRejectsWrongTunnelToken(t *testing.T) {
+	relay := NewServer(map[string]string{"music.example.test": "secret"})
+	defer relay.Close()
+	public := httptest.NewServer(relay)
+	defer public.Close()
+
+	u, _ := url.Parse(public.URL)
+	ln := &Listener{relay: u, token: "wrong"}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := ln.handshake(conn); err != errUnauthorized {
+		t.Fatalf("handshake = %v, want errUnauthorized", err)
+	}
+	if len(relay.idle["music.example.test"]) != 0 {
+		t.Fatal("relay kept a tunnel opened with the wrong token")
+	}
+}
+
+func TestForwardCopiesRelayedConnections(t *testing.T) {
+	relay := NewServer(map[string]string{"media.example.test": "media-secret"})
+	defer relay.Close()
+	public := httptest.NewServer(relay)
+	defer public.Close()
+	objects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "object "+r.URL.Path+" for "+r.Host)
+	}))
+	defer objects.Close()
+
+	ln, err := Listen(public.URL, "media-secret", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go Forward(ln, strings.TrimPrefix(objects.URL, "http://"))
+
+	resp, body := relayGet(t, public.URL, "media.example.test", "/audio-files/track.mp3")
+	if resp.StatusCode != http.StatusOK || body != "object /audio-files/track.mp3 for media.example.test" {
+		t.Fatalf("status = %d body = %q", resp.StatusCode, body)
+	}
+}
+
+func TestClientAddrTrustsForwardedForOnlyFromTunnels(t *testing.T) {
+	var got string
+	handler := ClientAddr(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+	tunnel, other := net.Pipe()
+	defer tunnel.Close()
+	defer other.Close()
+
+	for _, tc := range []struct {
+		conn net.Conn
+		xff  []string
+		want string
+	}{
+		{&tunnelConn{Conn: tunnel}, []string{"198.51.100.7"}, "198.51.100.7:0"},
+		{&tunnelConn{Conn: tunnel}, []string{"203.0.113.1, 198.51.100.7"}, "198.51.100.7:0"},
+		{&tunnelConn{Conn: tunnel}, []string{"not-an-ip"}, "192.0.2.10:443"},
+		{other, []string{"198.51.100.7"}, "192.0.2.10:443"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = "192.0.2.10:443"
+		for _, v := range tc.xff {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ConnContext(req.Context(), tc.conn)))
+		if got != tc.want {
+			t.Errorf("%T with X-Forwarded-For %v: RemoteAddr = %q, want %q", tc.conn, tc.xff, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package relay
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tunnelWait bounds how long a request waits for an idle tunnel.
+const tunnelWait = 10 * time.Second
+
+// errNoTunnel means no instance has an idle tunnel open for a host.
+var errNoTunnel = errors.New("no instance is connected for this host")
+
+// Server is the public side of a relay. Requests for a configured host are
+// proxied through a tunnel the host's instance opened; tunnel requests are
+// authenticated by the host's token. Hosts are served over whatever the
+// Server is listening on, so TLS for them is configured once, on the relay.
+type Server struct {
+	// tokens maps a lowercase host name to the token its instance presents.
+	tokens       map[string]string
+	pingInterval time.Duration
+	proxy        *httputil.ReverseProxy
+
+	mu   sync.Mutex
+	idle map[string][]net.Conn
+	// arrived is closed and replaced whenever a tunnel is opened.
+	arrived chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewServer relays requests for each host in tokens to the instance that
+// opens tunnels with that host's token.
+func NewServer(tokens map[string]string) *Server {
+	s := &Server{
+		tokens:       make(map[string]string, len(tokens)),
+		pingInterval: PingInterval,
+		idle:         make(map[string][]net.Conn),
+		arrived:      make(chan struct{}),
+		stop:         make(chan struct{}),
+	}
+	for host, token := range tokens {
+		s.tokens[strings.ToLower(host)] = token
+	}
+	s.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL.Scheme = "http"
+			r.Out.URL.Host = requestHost(r.In)
+			// The instance sees the public host, which presigned object
+			// URLs are signed for.
+			r.Out.Host = r.In.Host
+			r.SetXForwarded()
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(addr)
+				if err != nil {
+					host = addr
+				}
+				return s.take(ctx, host)
+			},
+			IdleConnTimeout: 90 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if !errors.Is(err, errNoTunnel) && !errors.Is(err, context.Canceled) {
+				log.Printf("Warning: relay request for %s failed: %v", requestHost(r), err)
+			}
+			http.Error(w, "This music server is offline.", http.StatusBadGateway)
+		},
+	}
+	go s.pingIdle()
+	return s
+}
+
+// ServeHTTP accepts tunnels on TunnelPath and proxies everything else.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == TunnelPath && strings.EqualFold(r.Header.Get("Upgrade"), upgradeProtocol) {
+		s.acceptTunnel(w, r)
+		return
+	}
+	if _, ok := s.tokens[requestHost(r)]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.proxy.ServeHTTP(w, r)
+}
+
+// Close stops pinging and closes idle tunnels.
+func (s *Server) Close() {
+	s.once.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for host, conns := range s.idle {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		delete(s.idle, host)
+	}
+}
+
+func (s *Server) acceptTunnel(w http.ResponseWriter, r *http.Request) {
+	host, ok := s.hostForToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if !ok {
+		http.Error(w, "invalid tunnel token", http.StatusUnauthorized)
+		return
+	}
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "tunnels need HTTP/1.1", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + upgradeProtocol + "\r\n\r\n")
+	if err := buffered.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.idle[host] = append(s.idle[host], conn)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// hostForToken compares token against every host's so a failed lookup
+// takes the same time whichever host it nearly matched.
+func (s *Server) hostForToken(token string) (string, bool) {
+	match := ""
+	for host, want := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			match = host
+		}
+	}
+	return match, match != "" && token != ""
+}
+
+// take attaches the newest idle tunnel for host and returns it. Tunnels
+// are used once, so a burst of requests may find none idle; take waits up
+// to tunnelWait for the instance to open more.
+func (s *Server) take(ctx context.Context, host string) (net.Conn, error) {
+	host = strings.ToLower(host)
+	timeout := time.NewTimer(tunnelWait)
+	defer timeout.Stop()
+	for {
+		s.mu.Lock()
+		for conns := s.idle[host]; len(conns) > 0; conns = s.idle[host] {
+			conn := conns[len(conns)-1]
+			s.idle[host] = conns[:len(conns)-1]
+			if err := signal(conn, attachByte); err == nil {
+				s.mu.Unlock()
+				return conn, nil
+			}
+			conn.Close()
+		}
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-timeout.C:
+			return nil, errNoTunnel
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pingIdle keeps idle tunnels alive and drops the ones that fail.
+func (s *Server) pingIdle() {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		for host, conns := range s.idle {
+			alive := conns[:0]
+			for _, conn := range conns {
+				if err := signal(conn, pingByte); err != nil {
+					conn.Close()
+					continue
+				}
+				alive = append(alive, conn)
+			}
+			s.idle[host] = alive
+		}
+		s.mu.Unlock()
+	}
+}
+
+func signal(conn net.Conn, b byte) error {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetWriteDeadline(time.Time{})
+	_, err := conn.Write([]byte{b})
+	return err
+}
+
+// requestHost is r's host name without a port, lowercased.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,325 @@
+// Package retention deletes data that has outlived its retention period, so
+// a long-running instance does not grow without bound. Each data type has a
+// configured period that admins can override; a period of 0 keeps that data
+// forever.
+package retention
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// Data types with a retention policy.
+const (
+	// PlayHistory is play_events, which feed stats, Wrapped, and
+	// recommendations.
+	PlayHistory = "play_history"
+	// AuditLogs is the playlist activity log collaborators read.
+	AuditLogs = "audit_logs"
+	// Notifications is every user's notification inbox, read or not.
+	Notifications = "notifications"
+	// DeadLetterJobs is download jobs that failed and were never retried.
+	DeadLetterJobs = "dead_letter_jobs"
+)
+
+// DataTypes lists every data type in the order admins see them.
+var DataTypes = []string{PlayHistory, AuditLogs, Notifications, DeadLetterJobs}
+
+// MaxAgeDaysLimit bounds a retention period; anything longer is the same as
+// keeping the data forever.
+const MaxAgeDaysLimit = 36500
+
+// DefaultBatchSize is how many rows one delete statement removes.
+const DefaultBatchSize = 5000
+
+var (
+	ErrUnknownDataType   = errors.New("unknown retention data type")
+	ErrInvalidMaxAgeDays = errors.New("retention period out of range")
+)
+
+// Store is the persistence the service needs; *db.RetentionRepository
+// implements it.
+type Store interface {
+	ListRetentionOverrides(ctx context.Context) ([]db.RetentionOverride, error)
+	SetRetentionOverride(ctx context.Context, dataType string, maxAgeDays int, updatedBy uuid.UUID) error
+	DeleteRetentionOverride(ctx context.Context, dataType string) error
+	DeletePlayEventsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	DeletePlaylistActivityBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	DeleteNotificationsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	DeleteFailedDownloadJobsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Policy is a data type's effective retention period and how its last purge
+// went.
+type Policy struct {
+	DataType          string
+	MaxAgeDays        int
+	DefaultMaxAgeDays int
+	// Overridden reports whether an admin replaced the default.
+	Overridden   bool
+	UpdatedAt    time.Time
+	LastPurgedAt time.Time
+	LastDeleted  int64
+	LastError    string
+}
+
+// Report summarizes one purge pass. Deleted counts rows per data type;
+// types kept forever are absent.
+type Report struct {
+	Deleted  map[string]int64
+	Failures int
+}
+
+type purgeResult struct {
+	at      time.Time
+	deleted int64
+	err     string
+}
+
+// Service applies retention policies. Start runs a purge immediately and then
+// every interval.
+type Service struct {
+	store     Store
+	defaults  map[string]int
+	interval  time.Duration
+	batchSize int
+	now       func() time.Time
+
+	// purgeMu serializes purges so a manual run never overlaps the loop.
+	purgeMu sync.Mutex
+
+	mu      sync.Mutex
+	last    map[string]purgeResult
+	running bool
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewService returns a service using defaults as each data type's retention
+// period in days. Types missing from defaults are kept forever.
+func NewService(store Store, defaults map[string]int, interval time.Duration) *Service {
+	d := make(map[string]int, len(DataTypes))
+	for _, dataType := range DataTypes {
+		d[dataType] = defaults[dataType]
+	}
+	return &Service{
+		store:     store,
+		defaults:  d,
+		interval:  interval,
+		batchSize: DefaultBatchSize,
+		now:       time.Now,
+		last:      make(map[string]purgeResult),
+	}
+}
+
+// Interval is the time between scheduled purges.
+func (s *Service) Interval() time.Duration {
+	return s.interval
+}
+
+// Policies returns the effective policy for every data type.
+func (s *Service) Policies(ctx context.Context) ([]Policy, error) {
+	overrides, err := s.store.ListRetentionOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[string]db.RetentionOverride, len(overrides))
+	for _, o := range overrides {
+		byType[o.DataType] = o
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policies := make([]Policy, 0, len(DataTypes))
+	for _, dataType := range DataTypes {
+		p := Policy{DataType: dataType, MaxAgeDays: s.defaults[dataType], DefaultMaxAgeDays: s.defaults[dataType]}
+		if o, ok := byType[dataType]; ok {
+			p.MaxAgeDays = o.MaxAgeDays
+			p.Overridden = true
+			p.UpdatedAt = o.UpdatedAt
+		}
+		if last, ok := s.last[dataType]; ok {
+			p.LastPurgedAt = last.at
+			p.LastDeleted = last.deleted
+			p.LastError = last.err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// SetPolicy overrides the data type's retention period. A nil maxAgeDays
+// removes the override so the configured default applies again.
+func (s *Service) SetPolicy(ctx context.Context, dataType string, maxAgeDays *int, updatedBy uuid.UUID) (Policy, error) {
+	if !isDataType(dataType) {
+		return Policy{}, ErrUnknownDataType
+	}
+	var err error
+	if maxAgeDays == nil {
+		err = s.store.DeleteRetentionOverride(ctx, dataType)
+	} else if *maxAgeDays < 0 || *maxAgeDays > MaxAgeDaysLimit {
+		return Policy{}, ErrInvalidMaxAgeDays
+	} else {
+		err = s.store.SetRetentionOverride(ctx, dataType, *maxAgeDays, updatedBy)
+	}
+	if err != nil {
+		return Policy{}, err
+	}
+
+	policies, err := s.Policies(ctx)
+	if err != nil {
+		return Policy{}, err
+	}
+	for _, p := range policies {
+		if p.DataType == dataType {
+			return p, nil
+		}
+	}
+	return Policy{}, ErrUnknownDataType
+}
+
+// Purge deletes everything older than its data type's retention period. A
+// failure for one data type is logged and counted without stopping the pass.
+func (s *Service) Purge(ctx context.Context) (Report, error) {
+	s.purgeMu.Lock()
+	defer s.purgeMu.Unlock()
+
+	policies, err := s.Policies(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Deleted: make(map[string]int64)}
+	for _, p := range policies {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if p.MaxAgeDays == 0 {
+			continue
+		}
+		cutoff := s.now().Add(-time.Duration(p.MaxAgeDays) * 24 * time.Hour)
+		deleted, err := s.purgeType(ctx, p.DataType, cutoff)
+		result := purgeResult{at: s.now(), deleted: deleted}
+		report.Deleted[p.DataType] = deleted
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			report.Failures++
+			result.err = err.Error()
+			log.Printf("Warning: retention purge of %s failed after %d rows: %v", p.DataType, deleted, err)
+		}
+		s.mu.Lock()
+		s.last[p.DataType] = result
+		s.mu.Unlock()
+	}
+	return report, nil
+}
+
+// purgeType deletes in batches until a batch comes back short.
+func (s *Service) purgeType(ctx context.Context, dataType string, cutoff time.Time) (int64, error) {
+	var deleteBefore func(context.Context, time.Time, int) (int64, error)
+	switch dataType {
+	case PlayHistory:
+		deleteBefore = s.store.DeletePlayEventsBefore
+	case AuditLogs:
+		deleteBefore = s.store.DeletePlaylistActivityBefore
+	case Notifications:
+		deleteBefore = s.store.DeleteNotificationsBefore
+	case DeadLetterJobs:
+		deleteBefore = s.store.DeleteFailedDownloadJobsBefore
+	default:
+		return 0, ErrUnknownDataType
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := deleteBefore(ctx, cutoff, s.batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// Start launches the purge loop in the background.
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.running = true
+	s.stop = cancel
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Stop cancels the loop and waits for an in-flight purge to return.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	s.stop()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { s.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) loop(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		report, err := s.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: retention purge failed: %v", err)
+		} else if err == nil {
+			var deleted int64
+			for _, n := range report.Deleted {
+				deleted += n
+			}
+			if deleted > 0 || report.Failures > 0 {
+				log.Printf("Retention purge completed: deleted=%d failures=%d", deleted, report.Failures)
+			}
+		}
+
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func isDataType(dataType string) bool {
+	for _, t := range DataTypes {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
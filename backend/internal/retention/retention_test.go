@@ -0,0 +1,144 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+// fakeStore holds rows as ages in days; deletes remove the oldest first.
+type fakeStore struct {
+	overrides map[string]int
+	rows      map[string][]int
+	now       time.Time
+	failFor   string
+	calls     map[string]int
+}
+
+func (f *fakeStore) ListRetentionOverrides(context.Context) ([]db.RetentionOverride, error) {
+	var out []db.RetentionOverride
+	for dataType, days := range f.overrides {
+		out = append(out, db.RetentionOverride{DataType: dataType, MaxAgeDays: days})
+	}
+	return out, nil
+}
+
+func (f *fakeStore) SetRetentionOverride(_ context.Context, dataType string, days int, _ uuid.UUID) error {
+	f.overrides[dataType] = days
+	return nil
+}
+
+func (f *fakeStore) DeleteRetentionOverride(_ context.Context, dataType string) error {
+	delete(f.overrides, dataType)
+	return nil
+}
+
+func (f *fakeStore) deleteBefore(dataType string, before time.Time, limit int) (int64, error) {
+	f.calls[dataType]++
+	if dataType == f.failFor {
+		return 0, errors.New("boom")
+	}
+	var kept []int
+	var deleted int64
+	for _, age := range f.rows[dataType] {
+		if int(deleted) < limit && f.now.Add(-time.Duration(age)*24*time.Hour).Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, age)
+	}
+	f.rows[dataType] = kept
+	return deleted, nil
+}
+
+func (f *fakeStore) DeletePlayEventsBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	return f.deleteBefore(PlayHistory, before, limit)
+}
+
+func (f *fakeStore) DeletePlaylistActivityBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	return f.deleteBefore(AuditLogs, before, limit)
+}
+
+func (f *fakeStore) DeleteNotificationsBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	return f.deleteBefore(Notifications, before, limit)
+}
+
+func (f *fakeStore) DeleteFailedDownloadJobsBefore(_ context.Context, before time.Time, limit int) (int64, error) {
+	return f.deleteBefore(DeadLetterJobs, before, limit)
+}
+
+func newFakeStore(now time.Time) *fakeStore {
+	return &fakeStore{
+		overrides: map[string]int{},
+		rows: map[string][]int{
+			PlayHistory:    {400, 10},
+			AuditLogs:      {400, 366, 30},
+			Notifications:  {100, 95, 91, 5},
+			DeadLetterJobs: {31},
+		},
+		now:   now,
+		calls: map[string]int{},
+	}
+}
+
+func TestPurgeAppliesPoliciesInBatches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := newFakeStore(now)
+	store.failFor = DeadLetterJobs
+	s := NewService(store, map[string]int{AuditLogs: 365, Notifications: 90, DeadLetterJobs: 30}, time.Hour)
+	s.now = func() time.Time { return now }
+	s.batchSize = 2
+
+	report, err := s.Purge(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Deleted[PlayHistory]; ok || len(store.rows[PlayHistory]) != 2 {
+		t.Fatalf("play history is kept forever by default: report = %+v rows = %v", report, store.rows[PlayHistory])
+	}
+	if report.Deleted[AuditLogs] != 2 || report.Deleted[Notifications] != 3 || report.Failures != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	// Three old notifications with a batch of two take a full batch and a short one.
+	if store.calls[Notifications] != 2 || len(store.rows[Notifications]) != 1 {
+		t.Fatalf("notification calls = %d rows = %v", store.calls[Notifications], store.rows[Notifications])
+	}
+
+	policies, err := s.Policies(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range policies {
+		if p.DataType == DeadLetterJobs && (p.LastError == "" || p.LastPurgedAt.IsZero()) {
+			t.Fatalf("failed purge not recorded: %+v", p)
+		}
+	}
+}
+
+func TestSetPolicyOverridesAndResets(t *testing.T) {
+	store := newFakeStore(time.Now())
+	s := NewService(store, map[string]int{Notifications: 90}, time.Hour)
+
+	days := 7
+	p, err := s.SetPolicy(context.Background(), Notifications, &days, uuid.New())
+	if err != nil || p.MaxAgeDays != 7 || !p.Overridden || p.DefaultMaxAgeDays != 90 {
+		t.Fatalf("override = %+v, err = %v", p, err)
+	}
+	p, err = s.SetPolicy(context.Background(), Notifications, nil, uuid.New())
+	if err != nil || p.MaxAgeDays != 90 || p.Overridden {
+		t.Fatalf("reset = %+v, err = %v", p, err)
+	}
+
+	if _, err := s.SetPolicy(context.Background(), "crash_reports", &days, uuid.New()); !errors.Is(err, ErrUnknownDataType) {
+		t.Fatalf("unknown type err = %v", err)
+	}
+	negative := -1
+	if _, err := s.SetPolicy(context.Background(), PlayHistory, &negative, uuid.New()); !errors.Is(err, ErrInvalidMaxAgeDays) {
+		t.Fatalf("negative days err = %v", err)
+	}
+}
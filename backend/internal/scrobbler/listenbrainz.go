@@ -0,0 +1,145 @@
+package scrobbler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ServiceListenBrainz = "listenbrainz"
+
+	listenBrainzUserAgent = "OpenMusicPlayer/1.0.0 (scrobbler)"
+	listenBrainzClient    = "Open Music Player"
+	// listenBrainzMaxResponseBytes bounds how much of a response is read.
+	listenBrainzMaxResponseBytes = 1 << 20
+)
+
+// ListenBrainz submits listens with a user token from the account's settings
+// page. See https://listenbrainz.readthedocs.io/en/latest/users/api/.
+type ListenBrainz struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewListenBrainz targets the API at baseURL, such as
+// https://api.listenbrainz.org.
+func NewListenBrainz(baseURL string, httpClient *http.Client) *ListenBrainz {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &ListenBrainz{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+func (lb *ListenBrainz) Name() string {
+	return ServiceListenBrainz
+}
+
+func (lb *ListenBrainz) ValidateToken(ctx context.Context, token string) (string, error) {
+	var result struct {
+		Valid    bool   `json:"valid"`
+		UserName string `json:"user_name"`
+	}
+	if err := lb.do(ctx, http.MethodGet, "/1/validate-token", token, nil, &result); err != nil {
+		return "", err
+	}
+	if !result.Valid || result.UserName == "" {
+		return "", ErrInvalidToken
+	}
+	return result.UserName, nil
+}
+
+type listenBrainzSubmission struct {
+	ListenType string               `json:"listen_type"`
+	Payload    []listenBrainzListen `json:"payload"`
+}
+
+type listenBrainzListen struct {
+	ListenedAt    int64                     `json:"listened_at"`
+	TrackMetadata listenBrainzTrackMetadata `json:"track_metadata"`
+}
+
+type listenBrainzTrackMetadata struct {
+	ArtistName     string                 `json:"artist_name"`
+	TrackName      string                 `json:"track_name"`
+	ReleaseName    string                 `json:"release_name,omitempty"`
+	AdditionalInfo map[string]interface{} `json:"additional_info"`
+}
+
+func (lb *ListenBrainz) Submit(ctx context.Context, token string, listen Listen) error {
+	info := map[string]interface{}{
+		"media_player":      listenBrainzClient,
+		"submission_client": listenBrainzClient,
+	}
+	if listen.DurationMs > 0 {
+		info["duration_ms"] = listen.DurationMs
+	}
+	if listen.RecordingMBID != "" {
+		info["recording_mbid"] = listen.RecordingMBID
+	}
+	body, err := json.Marshal(listenBrainzSubmission{
+		ListenType: "single",
+		Payload: []listenBrainzListen{{
+			ListenedAt: listen.ListenedAt.Unix(),
+			TrackMetadata: listenBrainzTrackMetadata{
+				ArtistName:     listen.Artist,
+				TrackName:      listen.Title,
+				ReleaseName:    listen.Album,
+				AdditionalInfo: info,
+			},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return lb.do(ctx, http.MethodPost, "/1/submit-listens", token, body, nil)
+}
+
+// do sends an authenticated request. 401 is ErrInvalidToken and other 4xx
+// responses except 429 are ErrRejected; everything else is left retryable.
+// Errors never include the token.
+func (lb *ListenBrainz) do(ctx context.Context, method, path, token string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, lb.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", listenBrainzUserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := lb.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("listenbrainz request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%w: %w", ErrRejected, ErrInvalidToken)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("listenbrainz returned status %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%w: listenbrainz returned status %d", ErrRejected, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, listenBrainzMaxResponseBytes)).Decode(out); err != nil {
+		return fmt.Errorf("listenbrainz response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package scrobbler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListenBrainzSubmitSendsSingleListen(t *testing.T) {
+	var got listenBrainzSubmission
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/1/submit-listens" {
+			t.Errorf("request = %s %s, want POST /1/submit-listens", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Token secret" {
+			t.Errorf("Authorization = %q, want token header", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	listenedAt := time.Unix(1767225600, 0)
+	err := NewListenBrainz(server.URL+"/", nil).Submit(context.Background(), "secret", Listen{
+		Title: "Alpha", Artist: "Band", Album: "Record", DurationMs: 201000,
+		RecordingMBID: "b1a9c0e9-d987-4042-ae91-78d6a3267d69", ListenedAt: listenedAt,
+	})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if got.ListenType != "single" || len(got.Payload) != 1 {
+		t.Fatalf("submission = %#v, want one single listen", got)
+	}
+	listen := got.Payload[0]
+	if listen.ListenedAt != listenedAt.Unix() || listen.TrackMetadata.TrackName != "Alpha" || listen.TrackMetadata.ArtistName != "Band" || listen.TrackMetadata.ReleaseName != "Record" {
+		t.Fatalf("listen = %#v, want Alpha by Band on Record at %d", listen, listenedAt.Unix())
+	}
+	if listen.TrackMetadata.AdditionalInfo["recording_mbid"] != "b1a9c0e9-d987-4042-ae91-78d6a3267d69" || listen.TrackMetadata.AdditionalInfo["duration_ms"] != float64(201000) {
+		t.Fatalf("additional_info = %#v, want recording MBID and duration", listen.TrackMetadata.AdditionalInfo)
+	}
+}
+
+func TestListenBrainzClassifiesFailures(t *testing.T) {
+	cases := []struct {
+		status       int
+		wantRejected bool
+		wantInvalid  bool
+	}{
+		{http.StatusUnauthorized, true, true},
+		{http.StatusBadRequest, true, false},
+		{http.StatusTooManyRequests, false, false},
+		{http.StatusBadGateway, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			err := NewListenBrainz(server.URL, nil).Submit(context.Background(), "secret", completeListen())
+			if err == nil {
+				t.Fatal("Submit succeeded, want an error")
+			}
+			if errors.Is(err, ErrRejected) != tc.wantRejected || errors.Is(err, ErrInvalidToken) != tc.wantInvalid {
+				t.Fatalf("err = %v, want rejected=%v invalidToken=%v", err, tc.wantRejected, tc.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestListenBrainzValidateToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Token good" {
+			w.Write([]byte(`{"code":200,"valid":true,"user_name":"listener"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"valid":false}`))
+	}))
+	defer server.Close()
+	lb := NewListenBrainz(server.URL, nil)
+
+	name, err := lb.ValidateToken(context.Background(), "good")
+	if err != nil || name != "listener" {
+		t.Fatalf("ValidateToken(good) = %q, %v; want listener", name, err)
+	}
+	if _, err := lb.ValidateToken(context.Background(), "bad"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ValidateToken(bad) err = %v, want ErrInvalidToken", err)
+	}
+}
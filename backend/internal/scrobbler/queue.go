@@ -0,0 +1,90 @@
+package scrobbler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPending = "scrobble:pending"
+	// keyRetry is a sorted set of parked jobs scored by when they are due.
+	keyRetry = "scrobble:retry"
+
+	promoteBatch = 100
+)
+
+// RedisQueue keeps pending submissions in a Redis list and parked retries in
+// a sorted set, so queued listens survive a restart.
+type RedisQueue struct {
+	client *redis.Client
+}
+
+func NewRedisQueue(client *redis.Client) *RedisQueue {
+	return &RedisQueue{client: client}
+}
+
+func (q *RedisQueue) Push(ctx context.Context, job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scrobble job: %w", err)
+	}
+	return q.client.LPush(ctx, keyPending, data).Err()
+}
+
+func (q *RedisQueue) Pop(ctx context.Context, timeout time.Duration) (*Job, error) {
+	result, err := q.client.BRPop(ctx, timeout, keyPending).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scrobble job: %w", err)
+	}
+	return &job, nil
+}
+
+func (q *RedisQueue) RetryAt(ctx context.Context, job Job, at time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scrobble job: %w", err)
+	}
+	return q.client.ZAdd(ctx, keyRetry, redis.Z{Score: float64(at.Unix()), Member: data}).Err()
+}
+
+// PromoteDue moves due retries back to the pending list. A job is pushed only
+// by the caller whose ZREM removed it, so several servers sharing Redis do not
+// submit it twice.
+func (q *RedisQueue) PromoteDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := q.client.ZRangeByScore(ctx, keyRetry, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: promoteBatch,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	promoted := 0
+	for _, member := range due {
+		removed, err := q.client.ZRem(ctx, keyRetry, member).Result()
+		if err != nil {
+			return promoted, err
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := q.client.LPush(ctx, keyPending, member).Err(); err != nil {
+			return promoted, err
+		}
+		promoted++
+	}
+	return promoted, nil
+}
@@ -0,0 +1,307 @@
+// Package scrobbler forwards completed plays to external listening-history
+// services such as ListenBrainz. Plays are queued in Redis and submitted in
+// the background so recording a play never waits on a third-party API;
+// submissions that fail transiently are retried with backoff.
+package scrobbler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+var (
+	// ErrRejected marks a submission the service refused outright. It is not
+	// retried.
+	ErrRejected = errors.New("scrobble rejected")
+	// ErrInvalidToken is returned when the service does not accept the
+	// user's token.
+	ErrInvalidToken = errors.New("invalid scrobbling token")
+)
+
+const (
+	// maxAttempts bounds how often one listen is submitted before it is
+	// dropped; with the backoff below the last try is about four hours
+	// after the play.
+	maxAttempts  = 10
+	retryBackoff = 30 * time.Second
+	maxBackoff   = 2 * time.Hour
+
+	popTimeout      = 5 * time.Second
+	promoteInterval = 15 * time.Second
+
+	// A play counts once the listener heard half the track or four minutes,
+	// whichever comes first. Shorter tracks than minTrackDuration never count.
+	scrobbleAfter    = 4 * time.Minute
+	minTrackDuration = 30 * time.Second
+)
+
+// Listen is one play as submitted to a scrobbling service.
+type Listen struct {
+	TrackID       int64     `json:"trackId"`
+	Title         string    `json:"title"`
+	Artist        string    `json:"artist"`
+	Album         string    `json:"album,omitempty"`
+	DurationMs    int       `json:"durationMs,omitempty"`
+	RecordingMBID string    `json:"recordingMbid,omitempty"`
+	ListenedAt    time.Time `json:"listenedAt"`
+	// ListenedMs is how long the track was heard, or zero when the client
+	// did not say.
+	ListenedMs int `json:"listenedMs,omitempty"`
+}
+
+// ListenFromPlay describes a recorded play of track. A play without a
+// timestamp happened at now.
+func ListenFromPlay(track *db.Track, play db.PlayRecord, now time.Time) Listen {
+	listen := Listen{
+		TrackID:    track.ID,
+		Title:      track.Title,
+		Artist:     track.Artist.String,
+		Album:      track.Album.String,
+		DurationMs: int(track.DurationMs.Int32),
+		ListenedAt: play.PlayedAt,
+		ListenedMs: int(play.DurationListenedMs.Int64),
+	}
+	if track.MBRecordingID != nil {
+		listen.RecordingMBID = track.MBRecordingID.String()
+	}
+	if listen.ListenedAt.IsZero() {
+		listen.ListenedAt = now
+	}
+	return listen
+}
+
+// Completed reports whether the listen is long enough to scrobble. A play
+// recorded without a listened duration is taken as complete.
+func (l Listen) Completed() bool {
+	if l.Title == "" || l.Artist == "" {
+		return false
+	}
+	duration := time.Duration(l.DurationMs) * time.Millisecond
+	if l.DurationMs > 0 && duration < minTrackDuration {
+		return false
+	}
+	if l.ListenedMs == 0 {
+		return true
+	}
+	threshold := scrobbleAfter
+	if l.DurationMs > 0 && duration/2 < threshold {
+		threshold = duration / 2
+	}
+	return time.Duration(l.ListenedMs)*time.Millisecond >= threshold
+}
+
+// Target is a scrobbling service. Implementations wrap ErrRejected for
+// failures that retrying cannot fix; any other error is retried.
+type Target interface {
+	// Name identifies the service in connections and routes.
+	Name() string
+	// ValidateToken checks a user token and returns the account name it
+	// belongs to, or ErrInvalidToken.
+	ValidateToken(ctx context.Context, token string) (string, error)
+	Submit(ctx context.Context, token string, listen Listen) error
+}
+
+// ConnectionStore returns users' connected services. db.UserRepository
+// satisfies it.
+type ConnectionStore interface {
+	ListScrobbleConnections(ctx context.Context, userID uuid.UUID) ([]db.ScrobbleConnection, error)
+	GetScrobbleConnection(ctx context.Context, userID uuid.UUID, service string) (*db.ScrobbleConnection, error)
+}
+
+// Job is one listen waiting to be submitted to one service.
+type Job struct {
+	UserID   uuid.UUID `json:"userId"`
+	Service  string    `json:"service"`
+	Listen   Listen    `json:"listen"`
+	Attempts int       `json:"attempts"`
+}
+
+// Queue holds pending submissions. RedisQueue is the production
+// implementation.
+type Queue interface {
+	Push(ctx context.Context, job Job) error
+	// Pop waits up to timeout for a job and returns nil when none arrived.
+	Pop(ctx context.Context, timeout time.Duration) (*Job, error)
+	// RetryAt parks a job until at.
+	RetryAt(ctx context.Context, job Job, at time.Time) error
+	// PromoteDue moves parked jobs whose time has come back onto the queue.
+	PromoteDue(ctx context.Context, now time.Time) (int, error)
+}
+
+// Service queues completed plays for every service the user connected and
+// submits them in the background once started.
+type Service struct {
+	store   ConnectionStore
+	queue   Queue
+	targets map[string]Target
+	now     func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewService(store ConnectionStore, queue Queue, targets ...Target) *Service {
+	s := &Service{store: store, queue: queue, targets: make(map[string]Target, len(targets)), now: time.Now}
+	for _, target := range targets {
+		s.targets[target.Name()] = target
+	}
+	return s
+}
+
+// Target returns the named service, if it is configured.
+func (s *Service) Target(name string) (Target, bool) {
+	target, ok := s.targets[name]
+	return target, ok
+}
+
+// Scrobble queues the listen for each service the user connected. Listens
+// that are not Completed are ignored.
+func (s *Service) Scrobble(ctx context.Context, userID uuid.UUID, listen Listen) error {
+	if !listen.Completed() {
+		return nil
+	}
+	connections, err := s.store.ListScrobbleConnections(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, connection := range connections {
+		if _, ok := s.targets[connection.Service]; !ok {
+			continue
+		}
+		if err := s.queue.Push(ctx, Job{UserID: userID, Service: connection.Service, Listen: listen}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle submits one job. The token is read at submission time so a
+// disconnected service stops receiving retries.
+func (s *Service) handle(ctx context.Context, job Job) {
+	target, ok := s.targets[job.Service]
+	if !ok {
+		return
+	}
+	connection, err := s.store.GetScrobbleConnection(ctx, job.UserID, job.Service)
+	if errors.Is(err, db.ErrScrobbleConnectionNotFound) {
+		return
+	}
+	if err == nil {
+		err = target.Submit(ctx, connection.Token, job.Listen)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrRejected) {
+			log.Printf("Warning: %s rejected listen of track %d for user %s: %v", job.Service, job.Listen.TrackID, job.UserID, err)
+			return
+		}
+	}
+
+	job.Attempts++
+	if job.Attempts >= maxAttempts {
+		log.Printf("Warning: giving up on %s listen of track %d for user %s after %d attempts: %v", job.Service, job.Listen.TrackID, job.UserID, job.Attempts, err)
+		return
+	}
+	if retryErr := s.queue.RetryAt(ctx, job, s.now().Add(backoff(job.Attempts))); retryErr != nil {
+		log.Printf("Warning: failed to schedule %s retry for user %s: %v", job.Service, job.UserID, retryErr)
+	}
+}
+
+// backoff doubles from retryBackoff after each failed attempt.
+func backoff(attempts int) time.Duration {
+	delay := retryBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// Start launches the submission worker and the retry promoter.
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.running = true
+	s.stop = cancel
+	s.wg.Add(2)
+	go s.work(ctx)
+	go s.promote(ctx)
+}
+
+// Stop cancels the background loops and waits for an in-flight submission.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	s.stop()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { s.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) work(ctx context.Context) {
+	defer s.wg.Done()
+	for ctx.Err() == nil {
+		job, err := s.queue.Pop(ctx, popTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: failed to read scrobble queue: %v", err)
+				sleep(ctx, popTimeout)
+			}
+			continue
+		}
+		if job != nil {
+			s.handle(ctx, *job)
+		}
+	}
+}
+
+func (s *Service) promote(ctx context.Context) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(promoteInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.queue.PromoteDue(ctx, s.now()); err != nil && ctx.Err() == nil {
+				log.Printf("Warning: failed to promote scrobble retries: %v", err)
+			}
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
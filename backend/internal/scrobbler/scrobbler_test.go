@@ -0,0 +1,225 @@
+package scrobbler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+)
+
+type fakeConnections struct {
+	connections []db.ScrobbleConnection
+}
+
+func (f *fakeConnections) ListScrobbleConnections(_ context.Context, userID uuid.UUID) ([]db.ScrobbleConnection, error) {
+	var out []db.ScrobbleConnection
+	for _, c := range f.connections {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeConnections) GetScrobbleConnection(_ context.Context, userID uuid.UUID, service string) (*db.ScrobbleConnection, error) {
+	for _, c := range f.connections {
+		if c.UserID == userID && c.Service == service {
+			return &c, nil
+		}
+	}
+	return nil, db.ErrScrobbleConnectionNotFound
+}
+
+type parkedJob struct {
+	job Job
+	at  time.Time
+}
+
+type fakeQueue struct {
+	pending []Job
+	parked  []parkedJob
+}
+
+func (q *fakeQueue) Push(_ context.Context, job Job) error {
+	q.pending = append(q.pending, job)
+	return nil
+}
+
+func (q *fakeQueue) Pop(context.Context, time.Duration) (*Job, error) {
+	if len(q.pending) == 0 {
+		return nil, nil
+	}
+	job := q.pending[0]
+	q.pending = q.pending[1:]
+	return &job, nil
+}
+
+func (q *fakeQueue) RetryAt(_ context.Context, job Job, at time.Time) error {
+	q.parked = append(q.parked, parkedJob{job, at})
+	return nil
+}
+
+func (q *fakeQueue) PromoteDue(context.Context, time.Time) (int, error) { return 0, nil }
+
+type fakeTarget struct {
+	name      string
+	err       error
+	submitted []string
+}
+
+func (t *fakeTarget) Name() string { return t.name }
+
+func (t *fakeTarget) ValidateToken(context.Context, string) (string, error) { return "listener", nil }
+
+func (t *fakeTarget) Submit(_ context.Context, token string, listen Listen) error {
+	t.submitted = append(t.submitted, token)
+	return t.err
+}
+
+func completeListen() Listen {
+	return Listen{TrackID: 1, Title: "Alpha", Artist: "Band", DurationMs: 200000, ListenedAt: time.Now()}
+}
+
+func TestListenCompleted(t *testing.T) {
+	cases := []struct {
+		name   string
+		listen Listen
+		want   bool
+	}{
+		{"unknown listened duration", Listen{Title: "a", Artist: "b", DurationMs: 200000}, true},
+		{"half of a short track", Listen{Title: "a", Artist: "b", DurationMs: 200000, ListenedMs: 100000}, true},
+		{"under half", Listen{Title: "a", Artist: "b", DurationMs: 200000, ListenedMs: 99000}, false},
+		{"four minutes of a long track", Listen{Title: "a", Artist: "b", DurationMs: 1200000, ListenedMs: 240000}, true},
+		{"unknown track duration needs four minutes", Listen{Title: "a", Artist: "b", ListenedMs: 200000}, false},
+		{"track under thirty seconds", Listen{Title: "a", Artist: "b", DurationMs: 20000, ListenedMs: 20000}, false},
+		{"no artist", Listen{Title: "a", DurationMs: 200000}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.listen.Completed(); got != tc.want {
+				t.Fatalf("Completed() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScrobbleQueuesOneJobPerConfiguredConnection(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeConnections{connections: []db.ScrobbleConnection{
+		{UserID: userID, Service: "listenbrainz", Token: "tok"},
+		{UserID: userID, Service: "retired-service", Token: "old"},
+		{UserID: uuid.New(), Service: "listenbrainz", Token: "other"},
+	}}
+	queue := &fakeQueue{}
+	s := NewService(store, queue, &fakeTarget{name: "listenbrainz"})
+
+	if err := s.Scrobble(context.Background(), userID, completeListen()); err != nil {
+		t.Fatalf("Scrobble: %v", err)
+	}
+	if len(queue.pending) != 1 || queue.pending[0].Service != "listenbrainz" || queue.pending[0].UserID != userID {
+		t.Fatalf("queued = %#v, want one listenbrainz job for the user", queue.pending)
+	}
+
+	skipped := completeListen()
+	skipped.ListenedMs = 1000
+	if err := s.Scrobble(context.Background(), userID, skipped); err != nil {
+		t.Fatalf("Scrobble: %v", err)
+	}
+	if len(queue.pending) != 1 {
+		t.Fatalf("queued %d jobs, want a skipped track not to be queued", len(queue.pending))
+	}
+}
+
+func TestHandleRetriesTransientFailuresWithBackoff(t *testing.T) {
+	userID := uuid.New()
+	store := &fakeConnections{connections: []db.ScrobbleConnection{{UserID: userID, Service: "listenbrainz", Token: "tok"}}}
+	queue := &fakeQueue{}
+	target := &fakeTarget{name: "listenbrainz", err: errors.New("status 503")}
+	s := NewService(store, queue, target)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.handle(context.Background(), Job{UserID: userID, Service: "listenbrainz", Listen: completeListen(), Attempts: 2})
+
+	if len(target.submitted) != 1 || target.submitted[0] != "tok" {
+		t.Fatalf("submitted with tokens %v, want [tok]", target.submitted)
+	}
+	if len(queue.parked) != 1 {
+		t.Fatalf("parked %d jobs, want 1", len(queue.parked))
+	}
+	if got := queue.parked[0]; got.job.Attempts != 3 || !got.at.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("parked job attempts=%d at %v, want 3 at %v", got.job.Attempts, got.at, now.Add(2*time.Minute))
+	}
+}
+
+func TestHandleDropsRejectedExhaustedAndDisconnectedJobs(t *testing.T) {
+	userID := uuid.New()
+	cases := []struct {
+		name     string
+		err      error
+		attempts int
+		connect  bool
+	}{
+		{"rejected", fmt.Errorf("%w: status 400", ErrRejected), 0, true},
+		{"invalid token", fmt.Errorf("%w: %w", ErrRejected, ErrInvalidToken), 0, true},
+		{"out of attempts", errors.New("timeout"), maxAttempts - 1, true},
+		{"disconnected", nil, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeConnections{}
+			if tc.connect {
+				store.connections = []db.ScrobbleConnection{{UserID: userID, Service: "listenbrainz", Token: "tok"}}
+			}
+			queue := &fakeQueue{}
+			target := &fakeTarget{name: "listenbrainz", err: tc.err}
+			s := NewService(store, queue, target)
+
+			s.handle(context.Background(), Job{UserID: userID, Service: "listenbrainz", Listen: completeListen(), Attempts: tc.attempts})
+
+			if len(queue.parked) != 0 {
+				t.Fatalf("parked %#v, want the job dropped", queue.parked)
+			}
+			if !tc.connect && len(target.submitted) != 0 {
+				t.Fatalf("submitted for a disconnected service")
+			}
+		})
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	if got := backoff(1); got != retryBackoff {
+		t.Fatalf("backoff(1) = %v, want %v", got, retryBackoff)
+	}
+	if got := backoff(maxAttempts); got != maxBackoff {
+		t.Fatalf("backoff(%d) = %v, want cap %v", maxAttempts, got, maxBackoff)
+	}
+}
+
+func TestListenFromPlay(t *testing.T) {
+	mbid := uuid.New()
+	track := &db.Track{
+		ID: 4, Title: "Alpha", Artist: sql.NullString{String: "Band", Valid: true},
+		DurationMs: sql.NullInt32{Int32: 200000, Valid: true}, MBRecordingID: &mbid,
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	listen := ListenFromPlay(track, db.PlayRecord{TrackID: 4, DurationListenedMs: sql.NullInt64{Int64: 150000, Valid: true}}, now)
+	if listen.Title != "Alpha" || listen.Artist != "Band" || listen.Album != "" || listen.DurationMs != 200000 || listen.ListenedMs != 150000 {
+		t.Fatalf("listen = %#v, want Alpha by Band, 200000ms long, 150000ms heard", listen)
+	}
+	if listen.RecordingMBID != mbid.String() || !listen.ListenedAt.Equal(now) {
+		t.Fatalf("listen = %#v, want recording %s at %v", listen, mbid, now)
+	}
+
+	playedAt := now.Add(-time.Hour)
+	if listen := ListenFromPlay(track, db.PlayRecord{PlayedAt: playedAt}, now); !listen.ListenedAt.Equal(playedAt) {
+		t.Fatalf("ListenedAt = %v, want the client timestamp %v", listen.ListenedAt, playedAt)
+	}
+}
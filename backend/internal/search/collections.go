@@ -0,0 +1,241 @@
+package search
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/queue"
+)
+
+// Match reasons reported by playlist and queue searches.
+const (
+	MatchedOnName        = "name"
+	MatchedOnDescription = "description"
+	MatchedOnTracks      = "tracks"
+	MatchedOnTitle       = "title"
+	MatchedOnArtist      = "artist"
+	MatchedOnAlbum       = "album"
+)
+
+type playlistSearcher interface {
+	SearchPlaylists(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]db.PlaylistSearchResult, int, error)
+}
+
+type queueReader interface {
+	GetQueue(ctx context.Context, userID string) (*queue.QueueState, error)
+}
+
+type trackLookup interface {
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error)
+}
+
+// PlaylistTrackMatchResponse is a matching track inside a playlist.
+type PlaylistTrackMatchResponse struct {
+	TrackID  int64  `json:"trackId"`
+	Position int    `json:"position"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist,omitempty"`
+}
+
+// PlaylistSearchResponse is one playlist returned by type=playlists. At most a
+// few matching tracks are listed; MatchedTrackCount is the full count.
+type PlaylistSearchResponse struct {
+	ID                int64                        `json:"id"`
+	Name              string                       `json:"name"`
+	Description       string                       `json:"description,omitempty"`
+	CoverURL          string                       `json:"coverUrl,omitempty"`
+	IsPublic          bool                         `json:"isPublic"`
+	TrackCount        int                          `json:"trackCount"`
+	MatchedOn         []string                     `json:"matchedOn"`
+	MatchedTrackCount int                          `json:"matchedTrackCount"`
+	MatchedTracks     []PlaylistTrackMatchResponse `json:"matchedTracks"`
+}
+
+// QueueSearchMatchResponse is one queue item returned by type=queue, with the
+// queue position needed to jump to it.
+type QueueSearchMatchResponse struct {
+	QueueItemID string   `json:"queueItemId"`
+	Position    int      `json:"position"`
+	TrackID     *int64   `json:"trackId"`
+	Title       string   `json:"title,omitempty"`
+	Artist      string   `json:"artist,omitempty"`
+	Album       string   `json:"album,omitempty"`
+	Current     bool     `json:"current"`
+	MatchedOn   []string `json:"matchedOn"`
+}
+
+func (h *Handlers) searchPlaylists(w http.ResponseWriter, r *http.Request, query string, limit, offset int) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.playlists == nil {
+		writeError(w, http.StatusServiceUnavailable, "PLAYLIST_SEARCH_UNAVAILABLE", "playlist search is unavailable")
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	results, total, err := h.playlists.SearchPlaylists(r.Context(), userCtx.UserID, query, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to search playlists")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PaginatedResponse{
+		Data:   toPlaylistSearchResponses(results),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func toPlaylistSearchResponses(results []db.PlaylistSearchResult) []PlaylistSearchResponse {
+	responses := make([]PlaylistSearchResponse, len(results))
+	for i, p := range results {
+		resp := PlaylistSearchResponse{
+			ID:                p.ID,
+			Name:              p.Name,
+			IsPublic:          p.IsPublic,
+			TrackCount:        p.TrackCount,
+			MatchedOn:         []string{},
+			MatchedTrackCount: p.MatchedTrackCount,
+			MatchedTracks:     make([]PlaylistTrackMatchResponse, len(p.MatchedTracks)),
+		}
+		if p.Description.Valid {
+			resp.Description = p.Description.String
+		}
+		if p.CoverURL.Valid {
+			resp.CoverURL = p.CoverURL.String
+		}
+		if p.NameMatched {
+			resp.MatchedOn = append(resp.MatchedOn, MatchedOnName)
+		}
+		if p.DescriptionMatched {
+			resp.MatchedOn = append(resp.MatchedOn, MatchedOnDescription)
+		}
+		if p.MatchedTrackCount > 0 {
+			resp.MatchedOn = append(resp.MatchedOn, MatchedOnTracks)
+		}
+		for j, hit := range p.MatchedTracks {
+			resp.MatchedTracks[j] = PlaylistTrackMatchResponse{
+				TrackID:  hit.TrackID,
+				Position: hit.Position,
+				Title:    hit.Title,
+			}
+			if hit.Artist.Valid {
+				resp.MatchedTracks[j].Artist = hit.Artist.String
+			}
+		}
+		responses[i] = resp
+	}
+	return responses
+}
+
+// searchQueue matches the caller's current queue in memory. Library items are
+// matched on their stored track metadata; pending downloads on their source
+// candidate.
+func (h *Handlers) searchQueue(w http.ResponseWriter, r *http.Request, query string, limit, offset int) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "user not authenticated")
+		return
+	}
+	if h.queue == nil {
+		writeError(w, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE", "queue search is unavailable")
+		return
+	}
+
+	state, err := h.queue.GetQueue(r.Context(), userCtx.UserID.String())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get queue")
+		return
+	}
+
+	var tracks map[int64]*db.Track
+	if h.tracks != nil {
+		ids := make([]int64, 0, len(state.Items))
+		for _, item := range state.Items {
+			if item.TrackID != nil {
+				ids = append(ids, *item.TrackID)
+			}
+		}
+		tracks, err = h.tracks.GetByIDs(r.Context(), ids)
+		if err != nil {
+			log.Printf("Warning: failed to load queue tracks for search: %v", err)
+			tracks = nil
+		}
+	}
+
+	matches := matchQueueItems(state, tracks, query)
+	page := []QueueSearchMatchResponse{}
+	if offset < len(matches) {
+		end := offset + limit
+		if end > len(matches) {
+			end = len(matches)
+		}
+		page = matches[offset:end]
+	}
+
+	writeJSON(w, http.StatusOK, PaginatedResponse{
+		Data:   page,
+		Total:  len(matches),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func matchQueueItems(state *queue.QueueState, tracks map[int64]*db.Track, query string) []QueueSearchMatchResponse {
+	needle := strings.ToLower(strings.TrimSpace(query))
+	matches := []QueueSearchMatchResponse{}
+	if needle == "" {
+		return matches
+	}
+	for _, item := range state.Items {
+		m := QueueSearchMatchResponse{
+			QueueItemID: item.ID,
+			Position:    item.Position,
+			TrackID:     item.TrackID,
+			Current:     item.Position == state.CurrentPosition,
+		}
+		if item.TrackID != nil && tracks[*item.TrackID] != nil {
+			t := tracks[*item.TrackID]
+			m.Title = t.Title
+			if t.Artist.Valid {
+				m.Artist = t.Artist.String
+			}
+			if t.Album.Valid {
+				m.Album = t.Album.String
+			}
+		} else if item.Source != nil {
+			m.Title = item.Source.Title
+			m.Artist = item.Source.Artist
+			if m.Artist == "" {
+				m.Artist = item.Source.Uploader
+			}
+			m.Album = item.Source.Album
+		}
+
+		for _, field := range []struct{ name, value string }{
+			{MatchedOnTitle, m.Title},
+			{MatchedOnArtist, m.Artist},
+			{MatchedOnAlbum, m.Album},
+		} {
+			if strings.Contains(strings.ToLower(field.value), needle) {
+				m.MatchedOn = append(m.MatchedOn, field.name)
+			}
+		}
+		if len(m.MatchedOn) > 0 {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/auth"
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/queue"
+)
+
+type fakePlaylistSearcher struct {
+	userID  uuid.UUID
+	query   string
+	results []db.PlaylistSearchResult
+}
+
+func (f *fakePlaylistSearcher) SearchPlaylists(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]db.PlaylistSearchResult, int, error) {
+	f.userID = userID
+	f.query = query
+	return f.results, len(f.results), nil
+}
+
+type fakeQueueReader struct {
+	state *queue.QueueState
+}
+
+func (f *fakeQueueReader) GetQueue(ctx context.Context, userID string) (*queue.QueueState, error) {
+	return f.state, nil
+}
+
+type fakeTrackLookup map[int64]*db.Track
+
+func (f fakeTrackLookup) GetByIDs(ctx context.Context, ids []int64) (map[int64]*db.Track, error) {
+	return f, nil
+}
+
+func searchRequest(target string, userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.UserContext{UserID: userID}))
+}
+
+func TestSearchPlaylistsReportsMatchReasonsAndTrackPositions(t *testing.T) {
+	userID := uuid.New()
+	playlists := &fakePlaylistSearcher{results: []db.PlaylistSearchResult{{
+		Playlist:           db.Playlist{ID: 7, Name: "Road Trip", Description: sql.NullString{String: "long drives", Valid: true}},
+		TrackCount:         250,
+		MatchedTrackCount:  1,
+		MatchedTracks:      []db.PlaylistTrackHit{{TrackID: 42, Position: 180, Title: "Drive", Artist: sql.NullString{String: "Band", Valid: true}}},
+		DescriptionMatched: true,
+	}}}
+	h := &Handlers{playlists: playlists}
+
+	w := httptest.NewRecorder()
+	h.Search(w, searchRequest("/api/v1/search?q=drive&type=playlists", userID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if playlists.userID != userID || playlists.query != "drive" {
+		t.Fatalf("searcher called with %s/%q", playlists.userID, playlists.query)
+	}
+
+	var resp struct {
+		Data  []PlaylistSearchResponse `json:"data"`
+		Total int                      `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Data) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	got := resp.Data[0]
+	if len(got.MatchedOn) != 2 || got.MatchedOn[0] != MatchedOnDescription || got.MatchedOn[1] != MatchedOnTracks {
+		t.Fatalf("matchedOn = %v", got.MatchedOn)
+	}
+	if len(got.MatchedTracks) != 1 || got.MatchedTracks[0].Position != 180 || got.MatchedTracks[0].Artist != "Band" {
+		t.Fatalf("matchedTracks = %+v", got.MatchedTracks)
+	}
+}
+
+func TestSearchQueueReturnsPositionsForLibraryAndPendingItems(t *testing.T) {
+	libraryID := int64(5)
+	state := &queue.QueueState{
+		CurrentPosition: 1,
+		Items: []queue.QueueItem{
+			{ID: "a", Position: 0, Kind: "track", TrackID: &libraryID},
+			{ID: "b", Position: 1, Kind: "source", Source: &queue.SourceCandidate{Title: "Unrelated", Uploader: "Night Channel"}},
+			{ID: "c", Position: 2, Kind: "source", Source: &queue.SourceCandidate{Title: "Something Else"}},
+		},
+	}
+	h := &Handlers{
+		queue:  &fakeQueueReader{state: state},
+		tracks: fakeTrackLookup{5: {ID: 5, Title: "Nightcall", Artist: sql.NullString{String: "Kavinsky", Valid: true}}},
+	}
+
+	w := httptest.NewRecorder()
+	h.Search(w, searchRequest("/api/v1/search?q=NIGHT&type=queue", uuid.New()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data  []QueueSearchMatchResponse `json:"data"`
+		Total int                        `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Data) != 2 {
+		t.Fatalf("response = %+v, want two matches", resp)
+	}
+	if resp.Data[0].QueueItemID != "a" || resp.Data[0].Position != 0 || resp.Data[0].MatchedOn[0] != MatchedOnTitle {
+		t.Fatalf("first match = %+v", resp.Data[0])
+	}
+	if resp.Data[1].QueueItemID != "b" || !resp.Data[1].Current || resp.Data[1].MatchedOn[0] != MatchedOnArtist {
+		t.Fatalf("second match = %+v", resp.Data[1])
+	}
+}
+
+func TestSearchRejectsUnknownTypeAndUnavailableCollections(t *testing.T) {
+	h := NewHandlers(nil)
+	for target, want := range map[string]int{
+		"/api/v1/search?q=x&type=podcasts":  http.StatusBadRequest,
+		"/api/v1/search?q=x&type=playlists": http.StatusServiceUnavailable,
+		"/api/v1/search?q=x&type=queue":     http.StatusServiceUnavailable,
+	} {
+		w := httptest.NewRecorder()
+		h.Search(w, searchRequest(target, uuid.New()))
+		if w.Code != want {
+			t.Fatalf("%s: status = %d, want %d", target, w.Code, want)
+		}
+	}
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/openmusicplayer/backend/internal/auth"
 	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/pagination"
 )
 
 const coverArtArchiveURL = "https://coverartarchive.org"
@@ -40,6 +42,7 @@ type RecordingResponse struct {
 type ArtistResponse struct {
 	Name       string     `json:"name"`
 	MBArtistID *uuid.UUID `json:"mbArtistId,omitempty"`
+	ArtworkUrl string     `json:"artworkUrl,omitempty"`
 	TrackCount int        `json:"trackCount"`
 }
 
@@ -76,93 +79,181 @@ type ErrorResponse struct {
 
 type Handlers struct {
 	trackRepo *db.TrackRepository
+	playlists playlistSearcher
+	queue     queueReader
+	tracks    trackLookup
 }
 
 func NewHandlers(trackRepo *db.TrackRepository) *Handlers {
-	return &Handlers{trackRepo: trackRepo}
+	h := &Handlers{trackRepo: trackRepo}
+	if trackRepo != nil {
+		h.tracks = trackRepo
+	}
+	return h
+}
+
+// NewHandlersWithPlaylists creates search handlers that can also search the
+// caller's playlists with type=playlists.
+func NewHandlersWithPlaylists(trackRepo *db.TrackRepository, playlists playlistSearcher) *Handlers {
+	h := NewHandlers(trackRepo)
+	h.playlists = playlists
+	return h
+}
+
+// SetQueue enables type=queue searches against the caller's playback queue.
+// The queue service only exists when Redis is enabled, so it is attached after
+// construction.
+func (h *Handlers) SetQueue(queue queueReader) {
+	h.queue = queue
 }
 
 // SearchRecordings handles GET /api/v1/search/recordings
 func (h *Handlers) SearchRecordings(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	recordings, total, ok := h.searchRecordings(w, r, limit, offset)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PaginatedResponse{
+		Data:   recordings,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// SearchRecordingsPage handles GET /api/v2/search/recordings
+func (h *Handlers) SearchRecordingsPage(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	recordings, total, ok := h.searchRecordings(w, r, limit, offset)
+	if !ok {
+		return
+	}
+
+	pagination.Write(w, recordings, limit, offset, &total)
+}
+
+func (h *Handlers) searchRecordings(w http.ResponseWriter, r *http.Request, limit, offset int) ([]RecordingResponse, int, bool) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "query parameter 'q' is required")
-		return
+		return nil, 0, false
 	}
 
-	limit, offset := parsePagination(r)
+	filter, ok := parseTagFilter(w, r)
+	if !ok {
+		return nil, 0, false
+	}
 
-	tracks, total, err := h.trackRepo.SearchRecordings(r.Context(), query, limit, offset)
+	tracks, total, err := h.trackRepo.SearchRecordingsFiltered(r.Context(), query, filter, limit, offset)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to search recordings")
-		return
+		return nil, 0, false
 	}
 
-	recordings := toRecordingResponses(tracks)
+	return toRecordingResponses(tracks), total, true
+}
+
+// SearchArtists handles GET /api/v1/search/artists
+func (h *Handlers) SearchArtists(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	artists, total, ok := h.searchArtists(w, r, limit, offset)
+	if !ok {
+		return
+	}
 
 	writeJSON(w, http.StatusOK, PaginatedResponse{
-		Data:   recordings,
+		Data:   artists,
 		Total:  total,
 		Limit:  limit,
 		Offset: offset,
 	})
 }
 
-// SearchArtists handles GET /api/v1/search/artists
-func (h *Handlers) SearchArtists(w http.ResponseWriter, r *http.Request) {
+// SearchArtistsPage handles GET /api/v2/search/artists
+func (h *Handlers) SearchArtistsPage(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	artists, total, ok := h.searchArtists(w, r, limit, offset)
+	if !ok {
+		return
+	}
+
+	pagination.Write(w, artists, limit, offset, &total)
+}
+
+func (h *Handlers) searchArtists(w http.ResponseWriter, r *http.Request, limit, offset int) ([]ArtistResponse, int, bool) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "query parameter 'q' is required")
-		return
+		return nil, 0, false
 	}
 
-	limit, offset := parsePagination(r)
-
 	artists, total, err := h.trackRepo.SearchArtists(r.Context(), query, limit, offset)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to search artists")
-		return
+		return nil, 0, false
 	}
 
-	responses := toArtistResponses(artists)
+	return toArtistResponses(artists), total, true
+}
+
+// SearchReleases handles GET /api/v1/search/releases
+func (h *Handlers) SearchReleases(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	releases, total, ok := h.searchReleases(w, r, limit, offset)
+	if !ok {
+		return
+	}
 
 	writeJSON(w, http.StatusOK, PaginatedResponse{
-		Data:   responses,
+		Data:   releases,
 		Total:  total,
 		Limit:  limit,
 		Offset: offset,
 	})
 }
 
-// SearchReleases handles GET /api/v1/search/releases
-func (h *Handlers) SearchReleases(w http.ResponseWriter, r *http.Request) {
+// SearchReleasesPage handles GET /api/v2/search/releases
+func (h *Handlers) SearchReleasesPage(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	releases, total, ok := h.searchReleases(w, r, limit, offset)
+	if !ok {
+		return
+	}
+
+	pagination.Write(w, releases, limit, offset, &total)
+}
+
+func (h *Handlers) searchReleases(w http.ResponseWriter, r *http.Request, limit, offset int) ([]ReleaseResponse, int, bool) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "query parameter 'q' is required")
-		return
+		return nil, 0, false
 	}
 
-	limit, offset := parsePagination(r)
-
 	releases, total, err := h.trackRepo.SearchReleases(r.Context(), query, limit, offset)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to search releases")
-		return
+		return nil, 0, false
 	}
 
-	responses := toReleaseResponses(releases)
-
-	writeJSON(w, http.StatusOK, PaginatedResponse{
-		Data:   responses,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	})
+	return toReleaseResponses(releases), total, true
 }
 
 // Search handles GET /api/v1/search and returns tracks, artists, and albums for
 // a single query in one sectioned body. It runs the same local searches as the
-// split /search/recordings|artists|releases endpoints.
+// split /search/recordings|artists|releases endpoints. type=playlists and
+// type=queue search the caller's playlists or current queue instead.
 func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -172,7 +263,24 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 
 	limit, offset := parsePagination(r)
 
-	tracks, _, err := h.trackRepo.SearchRecordings(r.Context(), query, limit, offset)
+	switch r.URL.Query().Get("type") {
+	case "", "all":
+	case "playlists":
+		h.searchPlaylists(w, r, query, limit, offset)
+		return
+	case "queue":
+		h.searchQueue(w, r, query, limit, offset)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "type must be one of all, playlists, queue")
+		return
+	}
+
+	filter, ok := parseTagFilter(w, r)
+	if !ok {
+		return
+	}
+	tracks, _, err := h.trackRepo.SearchRecordingsFiltered(r.Context(), query, filter, limit, offset)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to search recordings")
 		return
@@ -241,11 +349,15 @@ func toRecordingResponses(tracks []db.Track) []RecordingResponse {
 func toArtistResponses(artists []db.Artist) []ArtistResponse {
 	responses := make([]ArtistResponse, 0, len(artists))
 	for _, a := range artists {
-		responses = append(responses, ArtistResponse{
+		resp := ArtistResponse{
 			Name:       a.Name,
 			MBArtistID: a.MBArtistID,
 			TrackCount: a.TrackCount,
-		})
+		}
+		if a.ArtworkKey.Valid && a.MBArtistID != nil {
+			resp.ArtworkUrl = fmt.Sprintf("/api/v1/artists/%s/artwork", a.MBArtistID)
+		}
+		responses = append(responses, resp)
 	}
 	return responses
 }
@@ -254,9 +366,14 @@ func toReleaseResponses(releases []db.Release) []ReleaseResponse {
 	responses := make([]ReleaseResponse, 0, len(releases))
 	for _, rel := range releases {
 		coverArtURL := ""
-		if rel.CoverArtURL.Valid {
+		switch {
+		case rel.ArtworkKey.Valid && rel.MBReleaseID != nil:
+			// Artwork an admin uploaded for the release wins over any
+			// track's cover.
+			coverArtURL = fmt.Sprintf("/api/v1/albums/%s/artwork", rel.MBReleaseID)
+		case rel.CoverArtURL.Valid:
 			coverArtURL = rel.CoverArtURL.String
-		} else {
+		default:
 			coverArtURL = getCoverArtURL(rel.MBReleaseID)
 		}
 		responses = append(responses, ReleaseResponse{
@@ -271,6 +388,36 @@ func toReleaseResponses(releases []db.Release) []ReleaseResponse {
 	return responses
 }
 
+// maxTagFilters bounds how many ?tag= filters one search may combine.
+const maxTagFilters = 10
+
+// parseTagFilter reads repeated ?tag= parameters, which keep only tracks
+// carrying every tag as one of the caller's tags or a MusicBrainz genre. It
+// writes a 400 for too many or malformed tags.
+func parseTagFilter(w http.ResponseWriter, r *http.Request) (db.RecordingSearchFilter, bool) {
+	var filter db.RecordingSearchFilter
+	rawTags := r.URL.Query()["tag"]
+	if len(rawTags) == 0 {
+		return filter, true
+	}
+	if len(rawTags) > maxTagFilters {
+		writeError(w, http.StatusBadRequest, "INVALID_TAG", "at most 10 tag filters are allowed")
+		return filter, false
+	}
+	for _, raw := range rawTags {
+		tag, ok := db.NormalizeTag(raw)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "INVALID_TAG", "invalid tag: "+raw)
+			return filter, false
+		}
+		filter.Tags = append(filter.Tags, tag)
+	}
+	if userCtx := auth.GetUserFromContext(r.Context()); userCtx != nil {
+		filter.UserID = userCtx.UserID
+	}
+	return filter, true
+}
+
 func parsePagination(r *http.Request) (limit, offset int) {
 	limit = 20
 	offset = 0
@@ -290,6 +437,18 @@ func parsePagination(r *http.Request) (limit, offset int) {
 	return limit, offset
 }
 
+// parsePage is parsePagination for /api/v2, where ?cursor= from a previous
+// page replaces offset.
+func parsePage(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, offset = parsePagination(r)
+	offset, err := pagination.Offset(r, offset)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid or expired")
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+
 	"github.com/openmusicplayer/backend/internal/db"
 )
 
@@ -202,3 +205,42 @@ func TestToReleaseResponsesIncludesNumericID(t *testing.T) {
 		t.Fatalf("json id = %#v, want 42", payload["id"])
 	}
 }
+
+func TestEntityArtworkOverridesCovers(t *testing.T) {
+	artistID, releaseID := uuid.New(), uuid.New()
+	artists := toArtistResponses([]db.Artist{
+		{Name: "Uploaded", MBArtistID: &artistID, ArtworkKey: sql.NullString{String: "artwork/artists/a.jpg", Valid: true}},
+		{Name: "Plain", MBArtistID: &artistID},
+	})
+	if artists[0].ArtworkUrl != "/api/v1/artists/"+artistID.String()+"/artwork" || artists[1].ArtworkUrl != "" {
+		t.Fatalf("artist artwork URLs = %q, %q", artists[0].ArtworkUrl, artists[1].ArtworkUrl)
+	}
+
+	releases := toReleaseResponses([]db.Release{{
+		Name:        "Uploaded",
+		MBReleaseID: &releaseID,
+		CoverArtURL: sql.NullString{String: "https://example.test/cover.jpg", Valid: true},
+		ArtworkKey:  sql.NullString{String: "artwork/releases/r.jpg", Valid: true},
+	}})
+	if want := "/api/v1/albums/" + releaseID.String() + "/artwork"; releases[0].CoverArtUrl != want {
+		t.Fatalf("release cover = %q, want %q", releases[0].CoverArtUrl, want)
+	}
+}
+
+func TestSearchRecordingsRejectsInvalidTagFilter(t *testing.T) {
+	h := NewHandlers(nil)
+
+	for _, query := range []string{"?q=blue&tag=two%20words", "?q=blue" + strings.Repeat("&tag=a", maxTagFilters+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search/recordings"+query, nil)
+		w := httptest.NewRecorder()
+		h.SearchRecordings(w, req)
+
+		var resp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if w.Code != http.StatusBadRequest || resp.Code != "INVALID_TAG" {
+			t.Errorf("%s = %d %s, want 400 INVALID_TAG", query, w.Code, resp.Code)
+		}
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -192,17 +195,126 @@ func (c *Client) ObjectExists(ctx context.Context, key string) (bool, error) {
 // PresignGetObject returns a short-lived bearer URL for directly reading an object.
 // Callers must not log the returned URL.
 func (c *Client) PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return c.presignGet(ctx, key, expires, url.Values{})
+}
+
+// PresignPrivateGetObject is PresignGetObject for content only its requester
+// may read. The object is served with "Cache-Control: private" and a max-age
+// matching the URL, so the client may reuse the response while the URL is
+// valid but shared proxies and CDNs must not store it.
+func (c *Client) PresignPrivateGetObject(ctx context.Context, key string, expires time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-cache-control", fmt.Sprintf("private, max-age=%d", int(expires.Seconds())))
+	return c.presignGet(ctx, key, expires, params)
+}
+
+// PresignDownloadObject is PresignPrivateGetObject for a file download. The
+// object is served as an attachment named filename, so browsers save it
+// rather than play it.
+func (c *Client) PresignDownloadObject(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	params := url.Values{}
+	params.Set("response-cache-control", fmt.Sprintf("private, max-age=%d", int(expires.Seconds())))
+	params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.presignGet(ctx, key, expires, params)
+}
+
+func (c *Client) presignGet(ctx context.Context, key string, expires time.Duration, params url.Values) (string, error) {
 	if expires <= 0 {
 		return "", fmt.Errorf("presign expiry must be positive")
 	}
 
-	u, err := c.presignClient.PresignedGetObject(ctx, c.bucket, key, expires, url.Values{})
+	u, err := c.presignClient.PresignedGetObject(ctx, c.bucket, key, expires, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to presign object %s: %w", key, err)
 	}
 	return u.String(), nil
 }
 
+// PresignPutObject returns a short-lived bearer URL a client can PUT an
+// object to directly. The URL does not bound the body size, so callers check
+// the stored object before trusting it. Callers must not log the returned URL.
+func (c *Client) PresignPutObject(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", fmt.Errorf("presign expiry must be positive")
+	}
+
+	u, err := c.presignClient.PresignedPutObject(ctx, c.bucket, key, expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign upload %s: %w", key, err)
+	}
+	return u.String(), nil
+}
+
+// UploadedPart is one part of a multipart upload that storage has received.
+type UploadedPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// NewMultipartUpload starts a multipart upload of key, whose parts clients
+// PUT to URLs from PresignUploadPart. It returns the upload ID.
+func (c *Client) NewMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	core := minio.Core{Client: c.client}
+	uploadID, err := core.NewMultipartUpload(ctx, c.bucket, key, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload %s: %w", key, err)
+	}
+	return uploadID, nil
+}
+
+// PresignUploadPart returns a short-lived bearer URL a client can PUT one
+// part of a multipart upload to. Like PresignPutObject, the URL does not
+// bound the body size. Callers must not log the returned URL.
+func (c *Client) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", fmt.Errorf("presign expiry must be positive")
+	}
+
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+	u, err := c.presignClient.Presign(ctx, http.MethodPut, c.bucket, key, expires, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign upload part %d of %s: %w", partNumber, key, err)
+	}
+	return u.String(), nil
+}
+
+// ListUploadedParts returns the parts storage has received for a multipart
+// upload, in part number order.
+func (c *Client) ListUploadedParts(ctx context.Context, key, uploadID string) ([]UploadedPart, error) {
+	core := minio.Core{Client: c.client}
+	var parts []UploadedPart
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, c.bucket, key, uploadID, marker, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parts of %s: %w", key, err)
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, UploadedPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// CompleteMultipartUpload joins the given parts into the object key.
+func (c *Client) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []UploadedPart) error {
+	core := minio.Core{Client: c.client}
+	complete := make([]minio.CompletePart, len(parts))
+	for i, part := range parts {
+		complete[i] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+	if _, err := core.CompleteMultipartUpload(ctx, c.bucket, key, uploadID, complete, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload %s: %w", key, err)
+	}
+	return nil
+}
+
 // PutObject uploads an object to storage.
 func (c *Client) PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
 	opts := minio.PutObjectOptions{
@@ -313,12 +425,18 @@ func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
 	}, nil
 }
 
+// NormalizeIdentityField applies the identity-hash normalization (lowercase,
+// trimmed) to a title or artist so other matchers agree with deduplication.
+func NormalizeIdentityField(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
 // GenerateIdentityHash creates a unique hash for track deduplication
 // Based on normalized title, artist, and duration
 func GenerateIdentityHash(metadata TrackMetadata) string {
 	// Normalize strings: lowercase, trim whitespace
-	normalizedTitle := strings.ToLower(strings.TrimSpace(metadata.Title))
-	normalizedArtist := strings.ToLower(strings.TrimSpace(metadata.Artist))
+	normalizedTitle := NormalizeIdentityField(metadata.Title)
+	normalizedArtist := NormalizeIdentityField(metadata.Artist)
 
 	// Create deterministic string for hashing
 	// Format: title|artist|duration_ms
@@ -59,3 +59,56 @@ func TestPresignGetObjectDefaultsToInternalEndpoint(t *testing.T) {
 		t.Fatalf("presigned URL endpoint = %s://%s, want http://minio:9000; url=%s", parsed.Scheme, parsed.Host, rawURL)
 	}
 }
+
+func TestPresignPrivateGetObjectSetsPrivateCacheControl(t *testing.T) {
+	client, err := New(&Config{
+		Endpoint:  "minio:9000",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Bucket:    "audio-files",
+		UseSSL:    false,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	rawURL, err := client.PresignPrivateGetObject(context.Background(), "audio/track.mp3", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("PresignPrivateGetObject() error = %v", err)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("presigned URL did not parse: %v", err)
+	}
+	if got := parsed.Query().Get("response-cache-control"); got != "private, max-age=600" {
+		t.Fatalf("response-cache-control = %q, want private, max-age=600", got)
+	}
+}
+
+func TestPresignDownloadObjectNamesAttachment(t *testing.T) {
+	client, err := New(&Config{
+		Endpoint:  "minio:9000",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Bucket:    "audio-files",
+		UseSSL:    false,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	rawURL, err := client.PresignDownloadObject(context.Background(), "audio/track.flac", 10*time.Minute, "Sigur Rós - Hoppípolla.flac")
+	if err != nil {
+		t.Fatalf("PresignDownloadObject() error = %v", err)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("presigned URL did not parse: %v", err)
+	}
+	want := "attachment; filename*=utf-8''Sigur%20R%C3%B3s%20-%20Hopp%C3%ADpolla.flac"
+	if got := parsed.Query().Get("response-content-disposition"); got != want {
+		t.Fatalf("response-content-disposition = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Package tagger writes a track's metadata into its audio file: title,
+// artist, album, MusicBrainz IDs, and a front cover. ffmpeg remuxes the file
+// with every audio stream copied unchanged, so tagging never re-encodes.
+// mp3 files get ID3v2.3 tags, Ogg, Opus, and FLAC files get Vorbis comments,
+// and MP4 files get iTunes-style atoms.
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/db"
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+// Tags is what Write stores in a file. Empty fields are left out.
+type Tags struct {
+	Title       string
+	Artist      string
+	Album       string
+	RecordingID *uuid.UUID
+	ReleaseID   *uuid.UUID
+	ArtistID    *uuid.UUID
+	// Cover is a JPEG front cover. Nil keeps any picture the file already
+	// embeds; containers without picture support ignore it.
+	Cover []byte
+}
+
+// FromTrack returns the tags for a track's current metadata, without a cover.
+func FromTrack(track *db.Track) Tags {
+	return Tags{
+		Title:       track.Title,
+		Artist:      track.Artist.String,
+		Album:       track.Album.String,
+		RecordingID: track.MBRecordingID,
+		ReleaseID:   track.MBReleaseID,
+		ArtistID:    track.MBArtistID,
+	}
+}
+
+// musicBrainzTagNames returns the names MusicBrainz Picard gives the
+// recording, release, and artist IDs. ffmpeg writes unknown names to mp3
+// files as ID3 TXXX frames, which Picard describes differently from the
+// Vorbis comments every other container gets.
+func musicBrainzTagNames(ext string) [3]string {
+	if ext == ".mp3" {
+		return [3]string{"MusicBrainz Track Id", "MusicBrainz Album Id", "MusicBrainz Artist Id"}
+	}
+	return [3]string{"MUSICBRAINZ_TRACKID", "MUSICBRAINZ_ALBUMID", "MUSICBRAINZ_ARTISTID"}
+}
+
+// SupportsCover reports whether ffmpeg can embed a picture in files with the
+// extension ext. Its Ogg muxer cannot, so Ogg and Opus files keep whatever
+// cover they were downloaded with.
+func SupportsCover(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".mp3", ".m4a", ".mp4", ".flac":
+		return true
+	}
+	return false
+}
+
+// Command returns an ffmpeg command that copies source, whose extension is
+// ext, and replaces its tags with tags. When coverPath is not empty the
+// picture there replaces any embedded one.
+func Command(source, ext string, tags Tags, coverPath string) *ffmpeg.Command {
+	ext = strings.ToLower(ext)
+	cmd := ffmpeg.NewCommand().Overwrite().Input(source)
+	if coverPath != "" {
+		cmd.Input(coverPath).Map("0:a").Map("1:0")
+	} else {
+		cmd.Map("0")
+	}
+	cmd.Option("-c", "copy").Option("-map_metadata", "-1")
+
+	tag := func(name, value string) {
+		if value != "" {
+			cmd.Option("-metadata", name+"="+value)
+		}
+	}
+	tag("title", tags.Title)
+	tag("artist", tags.Artist)
+	tag("album", tags.Album)
+	names := musicBrainzTagNames(ext)
+	for i, id := range []*uuid.UUID{tags.RecordingID, tags.ReleaseID, tags.ArtistID} {
+		if id != nil {
+			tag(names[i], id.String())
+		}
+	}
+	if coverPath != "" {
+		cmd.Option("-disposition:v:0", "attached_pic").
+			Option("-metadata:s:v:0", "comment=Cover (front)")
+	}
+
+	switch ext {
+	case ".mp3":
+		cmd.Option("-id3v2_version", "3")
+	case ".m4a", ".mp4":
+		cmd.Option("-movflags", "use_metadata_tags")
+	}
+	return cmd
+}
+
+// Tagger writes tags with an ffmpeg Runner.
+type Tagger struct {
+	runner ffmpeg.Runner
+}
+
+// New creates a Tagger. A nil runner uses the ffmpeg binary on PATH.
+func New(runner ffmpeg.Runner) *Tagger {
+	if runner == nil {
+		runner = ffmpeg.NewExecRunner()
+	}
+	return &Tagger{runner: runner}
+}
+
+// Write copies source to target with tags applied. Both paths keep the
+// audio's extension so ffmpeg picks the same container; the cover is
+// staged beside target while ffmpeg runs.
+func (t *Tagger) Write(ctx context.Context, source, target string, tags Tags) error {
+	ext := filepath.Ext(source)
+	coverPath := ""
+	if len(tags.Cover) > 0 && SupportsCover(ext) {
+		cover, err := os.CreateTemp(filepath.Dir(target), ".omp-cover-*.jpg")
+		if err != nil {
+			return fmt.Errorf("stage cover: %w", err)
+		}
+		coverPath = cover.Name()
+		defer os.Remove(coverPath)
+		_, err = cover.Write(tags.Cover)
+		if closeErr := cover.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("stage cover: %w", err)
+		}
+	}
+	if _, err := t.runner.FFmpeg(ctx, Command(source, ext, tags, coverPath).Args(target), nil); err != nil {
+		return fmt.Errorf("write tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package tagger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+var recordingID = uuid.MustParse("11111111-2222-3333-4444-555555555555")
+
+func TestCommandUsesContainerTagNames(t *testing.T) {
+	tags := Tags{Title: "Roygbiv", Artist: "Boards of Canada", RecordingID: &recordingID}
+	for _, tc := range []struct {
+		ext, want, unwanted string
+	}{
+		{".mp3", "-metadata MusicBrainz Track Id=" + recordingID.String(), "MUSICBRAINZ_TRACKID"},
+		{".opus", "-metadata MUSICBRAINZ_TRACKID=" + recordingID.String(), "-id3v2_version"},
+		{".m4a", "-movflags use_metadata_tags", "-id3v2_version"},
+	} {
+		args := strings.Join(Command("in"+tc.ext, tc.ext, tags, "").Args("out"+tc.ext), " ")
+		for _, fragment := range []string{"-map 0 -c copy -map_metadata -1", "-metadata title=Roygbiv", tc.want} {
+			if !strings.Contains(args, fragment) {
+				t.Errorf("%s args %q missing %q", tc.ext, args, fragment)
+			}
+		}
+		if strings.Contains(args, tc.unwanted) || strings.Contains(args, "album=") {
+			t.Errorf("%s args %q include %q or an empty album", tc.ext, args, tc.unwanted)
+		}
+	}
+}
+
+func TestWriteEmbedsCoverOnlyWhereSupported(t *testing.T) {
+	fake := ffmpeg.NewFake()
+	var covers []string
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		for i, arg := range call.Args {
+			if arg == "-i" && strings.HasSuffix(call.Args[i+1], ".jpg") {
+				data, err := os.ReadFile(call.Args[i+1])
+				if err != nil {
+					t.Errorf("cover not staged: %v", err)
+				}
+				covers = append(covers, string(data))
+			}
+		}
+		return ffmpeg.Output{}, nil
+	}
+	dir := t.TempDir()
+	tagger := New(fake)
+	tags := Tags{Title: "Song", Cover: []byte("jpeg")}
+
+	for _, ext := range []string{".mp3", ".opus"} {
+		if err := tagger.Write(context.Background(), filepath.Join(dir, "in"+ext), filepath.Join(dir, "out"+ext), tags); err != nil {
+			t.Fatalf("Write %s: %v", ext, err)
+		}
+	}
+	if len(covers) != 1 || covers[0] != "jpeg" {
+		t.Fatalf("embedded covers = %q, want one for the mp3", covers)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("staged cover left behind: %v", entries)
+	}
+	if args := strings.Join(fake.Calls()[0].Args, " "); !strings.Contains(args, "-map 0:a -map 1:0") {
+		t.Fatalf("mp3 args %q do not replace the embedded picture", args)
+	}
+}
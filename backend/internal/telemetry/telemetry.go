@@ -0,0 +1,269 @@
+// Package telemetry sends an opt-in, anonymous summary of an instance to the
+// maintainers: the version, coarse user and track counts, and which optional
+// features are switched on. Nothing identifies the instance, its users, or
+// their music, and the exact payload can be previewed before enabling it.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is bumped whenever a field is added to or removed from Report.
+const SchemaVersion = 1
+
+const (
+	// firstReportDelay keeps restart loops from sending a report each time.
+	firstReportDelay = time.Hour
+	sendTimeout      = 10 * time.Second
+)
+
+// Report is the complete payload. Counts are bucketed so an instance cannot
+// be told apart by its exact size.
+type Report struct {
+	SchemaVersion int      `json:"schemaVersion"`
+	Version       string   `json:"version"`
+	Users         string   `json:"users"`
+	Tracks        string   `json:"tracks"`
+	Features      []string `json:"features"`
+}
+
+// Status describes the reporter for the preview endpoint.
+type Status struct {
+	Enabled    bool          `json:"enabled"`
+	Endpoint   string        `json:"endpoint,omitempty"`
+	Interval   time.Duration `json:"-"`
+	LastSentAt *time.Time    `json:"lastSentAt,omitempty"`
+	LastError  string        `json:"lastError,omitempty"`
+}
+
+// UserCounter and TrackCounter are implemented by *db.UserRepository and
+// *db.TrackRepository.
+type UserCounter interface {
+	CountUsers(ctx context.Context) (int64, error)
+}
+
+type TrackCounter interface {
+	CountTracks(ctx context.Context) (int64, error)
+}
+
+// Config configures a Reporter. Reports are sent only when Enabled is true
+// and Endpoint is set; Preview works either way.
+type Config struct {
+	Enabled  bool
+	Endpoint string
+	Interval time.Duration
+	Version  string
+	Features []string
+	Users    UserCounter
+	Tracks   TrackCounter
+	Client   *http.Client
+}
+
+// Reporter builds reports and, when enabled, sends one per Interval.
+type Reporter struct {
+	cfg        Config
+	firstDelay time.Duration
+	now        func() time.Time
+
+	mu       sync.Mutex
+	running  bool
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+	lastSent *time.Time
+	lastErr  string
+}
+
+func NewReporter(cfg Config) *Reporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: sendTimeout}
+	}
+	features := append([]string(nil), cfg.Features...)
+	sort.Strings(features)
+	cfg.Features = features
+	return &Reporter{cfg: cfg, firstDelay: firstReportDelay, now: time.Now}
+}
+
+// Enabled reports whether Start will send reports.
+func (r *Reporter) Enabled() bool {
+	return r.cfg.Enabled && r.cfg.Endpoint != ""
+}
+
+// Preview builds the report that would be sent now, without sending it.
+func (r *Reporter) Preview(ctx context.Context) (Report, error) {
+	users, err := r.cfg.Users.CountUsers(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("count users: %w", err)
+	}
+	tracks, err := r.cfg.Tracks.CountTracks(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("count tracks: %w", err)
+	}
+	features := r.cfg.Features
+	if features == nil {
+		features = []string{}
+	}
+	return Report{
+		SchemaVersion: SchemaVersion,
+		Version:       r.cfg.Version,
+		Users:         UserBucket(users),
+		Tracks:        TrackBucket(tracks),
+		Features:      features,
+	}, nil
+}
+
+// Status returns the configuration and the outcome of the last send.
+func (r *Reporter) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := Status{Enabled: r.Enabled(), Interval: r.cfg.Interval, LastError: r.lastErr}
+	if status.Enabled {
+		status.Endpoint = r.cfg.Endpoint
+	}
+	if r.lastSent != nil {
+		sent := *r.lastSent
+		status.LastSentAt = &sent
+	}
+	return status
+}
+
+// Send builds a report and posts it to the endpoint as JSON.
+func (r *Reporter) Send(ctx context.Context) error {
+	err := r.send(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.lastErr = err.Error()
+		return err
+	}
+	now := r.now()
+	r.lastSent, r.lastErr = &now, ""
+	return nil
+}
+
+func (r *Reporter) send(ctx context.Context) error {
+	if r.cfg.Endpoint == "" {
+		return errors.New("telemetry endpoint is not configured")
+	}
+	report, err := r.Preview(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "openmusicplayer-telemetry/"+r.cfg.Version)
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telemetry endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Start launches the send loop in the background. It does nothing unless
+// the reporter is enabled.
+func (r *Reporter) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running || !r.Enabled() {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.running = true
+	r.stop = cancel
+	r.wg.Add(1)
+	go r.loop(ctx)
+}
+
+// Stop cancels the loop and waits for an in-flight send to return.
+func (r *Reporter) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = false
+	r.stop()
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { r.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Reporter) loop(ctx context.Context) {
+	defer r.wg.Done()
+	timer := time.NewTimer(r.firstDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := r.Send(sendCtx); err != nil && ctx.Err() == nil {
+			log.Printf("Warning: telemetry report failed: %v", err)
+		}
+		cancel()
+		timer.Reset(r.cfg.Interval)
+	}
+}
+
+var (
+	userBuckets  = []int64{0, 1, 5, 20, 100}
+	trackBuckets = []int64{0, 999, 9999, 99999}
+)
+
+// UserBucket labels a user count as one of 0, 1, 2-5, 6-20, 21-100, 101+.
+func UserBucket(n int64) string {
+	return bucket(n, userBuckets)
+}
+
+// TrackBucket labels a track count as one of 0, 1-999, 1000-9999,
+// 10000-99999, 100000+.
+func TrackBucket(n int64) string {
+	return bucket(n, trackBuckets)
+}
+
+// bucket returns the range in which n falls; uppers are the inclusive upper
+// bounds of each range but the last.
+func bucket(n int64, uppers []int64) string {
+	lower := int64(0)
+	for _, upper := range uppers {
+		if n <= upper {
+			if lower == upper {
+				return fmt.Sprint(upper)
+			}
+			return fmt.Sprintf("%d-%d", lower, upper)
+		}
+		lower = upper + 1
+	}
+	return fmt.Sprintf("%d+", lower)
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeCounts struct {
+	users, tracks int64
+	err           error
+}
+
+func (f fakeCounts) CountUsers(context.Context) (int64, error)  { return f.users, f.err }
+func (f fakeCounts) CountTracks(context.Context) (int64, error) { return f.tracks, f.err }
+
+func TestBuckets(t *testing.T) {
+	for _, tc := range []struct {
+		n           int64
+		users, trks string
+	}{
+		{0, "0", "0"},
+		{1, "1", "1-999"},
+		{5, "2-5", "1-999"},
+		{21, "21-100", "1-999"},
+		{101, "101+", "1-999"},
+		{1000, "101+", "1000-9999"},
+		{250000, "101+", "100000+"},
+	} {
+		if got := UserBucket(tc.n); got != tc.users {
+			t.Errorf("UserBucket(%d) = %q, want %q", tc.n, got, tc.users)
+		}
+		if got := TrackBucket(tc.n); got != tc.trks {
+			t.Errorf("TrackBucket(%d) = %q, want %q", tc.n, got, tc.trks)
+		}
+	}
+}
+
+func TestSendPostsPreviewedReport(t *testing.T) {
+	var got Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Cookie") != "" {
+			t.Errorf("method = %s cookie = %q", r.Method, r.Header.Get("Cookie"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	counts := fakeCounts{users: 3, tracks: 1500}
+	reporter := NewReporter(Config{
+		Enabled:  true,
+		Endpoint: server.URL,
+		Version:  "1.2.3",
+		Features: []string{"transcode", "analysis"},
+		Users:    counts,
+		Tracks:   counts,
+	})
+	preview, err := reporter.Preview(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Users != "2-5" || got.Tracks != "1000-9999" || got.Version != "1.2.3" || len(got.Features) != 2 || got.Features[0] != "analysis" {
+		t.Fatalf("sent %+v", got)
+	}
+	if got.Users != preview.Users || got.Tracks != preview.Tracks || got.SchemaVersion != SchemaVersion {
+		t.Fatalf("sent %+v, previewed %+v", got, preview)
+	}
+	if status := reporter.Status(); status.LastSentAt == nil || status.LastError != "" {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestReporterIsOffByDefault(t *testing.T) {
+	counts := fakeCounts{err: errors.New("should not be counted")}
+	reporter := NewReporter(Config{Endpoint: "http://127.0.0.1:1", Users: counts, Tracks: counts})
+	if reporter.Enabled() {
+		t.Fatal("reporter enabled without opting in")
+	}
+	reporter.Start()
+	if reporter.running {
+		t.Fatal("Start launched a disabled reporter")
+	}
+	if status := reporter.Status(); status.Enabled || status.Endpoint != "" {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestSendRecordsEndpointFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	reporter := NewReporter(Config{Enabled: true, Endpoint: server.URL, Users: fakeCounts{}, Tracks: fakeCounts{}})
+	if err := reporter.Send(context.Background()); err == nil {
+		t.Fatal("Send succeeded against a failing endpoint")
+	}
+	if status := reporter.Status(); status.LastSentAt != nil || status.LastError == "" {
+		t.Fatalf("status = %+v", status)
+	}
+}
@@ -0,0 +1,121 @@
+// Package transcode produces alternate encodings of stored audio on demand.
+// Tracks keep one stored original, often lossless FLAC; a client that asks
+// for another format gets a variant transcoded once with ffmpeg and cached in
+// object storage next to the original.
+package transcode
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned for a requested format with no encoder.
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// Format is an encoding playback can be served in.
+type Format struct {
+	// Name is the value clients pass as ?format=.
+	Name        string
+	Extension   string
+	ContentType string
+	// Codec matches the codec name ffprobe reports, which is what tracks
+	// store, so an original already in this format is served untouched.
+	Codec       string
+	BitrateKbps int
+
+	encoder   string
+	container string
+}
+
+var formats = []Format{
+	{Name: "opus", Extension: "opus", ContentType: "audio/ogg", Codec: "opus", BitrateKbps: 128, encoder: "libopus", container: "ogg"},
+	{Name: "mp3", Extension: "mp3", ContentType: "audio/mpeg", Codec: "mp3", BitrateKbps: 256, encoder: "libmp3lame", container: "mp3"},
+	{Name: "flac", Extension: "flac", ContentType: "audio/flac", Codec: "flac", encoder: "flac", container: "flac"},
+}
+
+// mediaTypeFormats maps Accept media types to format names.
+var mediaTypeFormats = map[string]string{
+	"audio/opus":   "opus",
+	"audio/ogg":    "opus",
+	"audio/mpeg":   "mp3",
+	"audio/mp3":    "mp3",
+	"audio/flac":   "flac",
+	"audio/x-flac": "flac",
+}
+
+// Formats returns the supported formats.
+func Formats() []Format {
+	return append([]Format(nil), formats...)
+}
+
+// Lookup returns the format called name.
+func Lookup(name string) (Format, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, f := range formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Negotiate picks the format a client asked for. An explicit format name
+// wins; otherwise the audio media types in accept are tried by preference.
+// ok is false when the client expressed no usable preference, in which case
+// the stored original should be served.
+func Negotiate(name, accept string) (format Format, ok bool, err error) {
+	if strings.TrimSpace(name) != "" {
+		f, found := Lookup(name)
+		if !found {
+			return Format{}, false, ErrUnsupportedFormat
+		}
+		return f, true, nil
+	}
+
+	type candidate struct {
+		name  string
+		q     float64
+		order int
+	}
+	var candidates []candidate
+	for i, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		codecs := ""
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "q":
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			case "codecs":
+				codecs = strings.ToLower(value)
+			}
+		}
+		formatName, known := mediaTypeFormats[mediaType]
+		if mediaType == "audio/webm" && codecs == "opus" {
+			// Browsers advertise Opus support as WebM; Ogg Opus plays there too.
+			formatName, known = "opus", true
+		}
+		if !known || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{name: formatName, q: q, order: i})
+	}
+	if len(candidates) == 0 {
+		return Format{}, false, nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	f, _ := Lookup(candidates[0].name)
+	return f, true, nil
+}
+
+// Matches reports whether audio stored with codec already is in f.
+func (f Format) Matches(codec string) bool {
+	return strings.EqualFold(strings.TrimSpace(codec), f.Codec)
+}
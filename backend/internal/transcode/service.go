@@ -0,0 +1,251 @@
+package transcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+// DefaultTimeout bounds one transcode, including the download of the
+// original and the upload of the variant.
+const DefaultTimeout = 5 * time.Minute
+
+// variantPrefix is where variants live in object storage.
+const variantPrefix = "transcodes"
+
+// ErrDisabled is returned when the service has no ffmpeg workers.
+var ErrDisabled = errors.New("transcoding is disabled")
+
+// ErrLowDiskSpace is returned when the scratch volume is too full to start a
+// transcode.
+var ErrLowDiskSpace = errors.New("transcode scratch volume is low on disk space")
+
+// DiskGuard reports whether the scratch volume has room for more work.
+// download.DiskGuard satisfies it.
+type DiskGuard interface {
+	Allow() bool
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithTempDir puts transcode scratch directories in dir instead of
+// os.TempDir, so they share the download temp dir's volume and sweep.
+func WithTempDir(dir string) Option {
+	return func(s *Service) { s.tempDir = dir }
+}
+
+// WithDiskGuard refuses transcodes while guard reports the scratch volume
+// low on space.
+func WithDiskGuard(guard DiskGuard) Option {
+	return func(s *Service) { s.diskGuard = guard }
+}
+
+// ObjectStore is the object storage a Service reads originals from and
+// writes variants to.
+type ObjectStore interface {
+	StatObject(ctx context.Context, key string) (*storage.ObjectInfo, error)
+	GetObject(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error)
+	PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+}
+
+// Service transcodes stored audio into other formats with at most a fixed
+// number of concurrent ffmpeg runs. Variants are keyed by the original's key
+// and ETag, so replacing a track's audio produces fresh variants, and
+// concurrent requests for the same variant share one run.
+type Service struct {
+	store   ObjectStore
+	runner  ffmpeg.Runner
+	slots   chan struct{}
+	timeout time.Duration
+
+	tempDir   string
+	diskGuard DiskGuard
+
+	mu       sync.Mutex
+	inflight map[string]*variantCall
+}
+
+type variantCall struct {
+	done chan struct{}
+	info *storage.ObjectInfo
+	err  error
+}
+
+// NewService creates a service running up to workers transcodes at once. A
+// nil runner uses the ffmpeg on PATH; zero workers disables transcoding.
+func NewService(store ObjectStore, runner ffmpeg.Runner, workers int, timeout time.Duration, opts ...Option) *Service {
+	if runner == nil {
+		runner = ffmpeg.NewExecRunner()
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	var slots chan struct{}
+	if workers > 0 {
+		slots = make(chan struct{}, workers)
+	}
+	s := &Service{
+		store:    store,
+		runner:   runner,
+		slots:    slots,
+		timeout:  timeout,
+		inflight: make(map[string]*variantCall),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// VariantKey returns the storage key of sourceKey's variant in format.
+func VariantKey(sourceKey, sourceETag string, format Format) string {
+	sum := sha256.Sum256([]byte(sourceKey + "\x00" + sourceETag))
+	return fmt.Sprintf("%s/%s/%s.%s", variantPrefix, format.Name, hex.EncodeToString(sum[:16]), format.Extension)
+}
+
+// VariantKeys returns the storage keys sourceKey's variants would have in
+// every supported format, for releasing them along with the original.
+func VariantKeys(sourceKey, sourceETag string) []string {
+	keys := make([]string, 0, len(formats))
+	for _, format := range formats {
+		keys = append(keys, VariantKey(sourceKey, sourceETag, format))
+	}
+	return keys
+}
+
+// Variant returns the storage key and object info of the original at
+// sourceKey encoded as format, transcoding it first when no variant is
+// stored yet. The transcode runs detached from ctx so a caller giving up
+// does not waste work the next request can use.
+func (s *Service) Variant(ctx context.Context, sourceKey string, source *storage.ObjectInfo, format Format) (string, *storage.ObjectInfo, error) {
+	if s == nil || s.slots == nil {
+		return "", nil, ErrDisabled
+	}
+	key := VariantKey(sourceKey, source.ETag, format)
+	if info, err := s.store.StatObject(ctx, key); err == nil {
+		return key, info, nil
+	}
+
+	s.mu.Lock()
+	call, running := s.inflight[key]
+	if !running {
+		call = &variantCall{done: make(chan struct{})}
+		s.inflight[key] = call
+		go s.run(call, key, sourceKey, format)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return "", nil, call.err
+		}
+		return key, call.info, nil
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	}
+}
+
+func (s *Service) run(call *variantCall, key, sourceKey string, format Format) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		close(call.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		call.err = fmt.Errorf("waiting for a transcode worker: %w", ctx.Err())
+		return
+	}
+	call.info, call.err = s.transcode(ctx, key, sourceKey, format)
+}
+
+func (s *Service) transcode(ctx context.Context, key, sourceKey string, format Format) (*storage.ObjectInfo, error) {
+	if s.diskGuard != nil && !s.diskGuard.Allow() {
+		return nil, ErrLowDiskSpace
+	}
+	workDir, err := os.MkdirTemp(s.tempDir, "omp-transcode-*")
+	if err != nil {
+		return nil, fmt.Errorf("create transcode directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	inputPath := filepath.Join(workDir, "source")
+	if err := s.download(ctx, sourceKey, inputPath); err != nil {
+		return nil, err
+	}
+
+	outputPath := filepath.Join(workDir, "variant."+format.Extension)
+	if _, err := s.runner.FFmpeg(ctx, Args(inputPath, outputPath, format), nil); err != nil {
+		return nil, fmt.Errorf("transcode %s to %s: %w", sourceKey, format.Name, err)
+	}
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("open transcoded audio: %w", err)
+	}
+	defer output.Close()
+	stat, err := output.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat transcoded audio: %w", err)
+	}
+	if err := s.store.PutObject(ctx, key, output, stat.Size(), format.ContentType); err != nil {
+		return nil, err
+	}
+	return s.store.StatObject(ctx, key)
+}
+
+func (s *Service) download(ctx context.Context, sourceKey, path string) error {
+	reader, _, err := s.store.GetObject(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create transcode input: %w", err)
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return fmt.Errorf("download %s for transcoding: %w", sourceKey, err)
+	}
+	return file.Close()
+}
+
+// Args returns the ffmpeg arguments that encode the audio at input into
+// format at output. Cover art and other video streams are dropped.
+func Args(input, output string, format Format) []string {
+	cmd := ffmpeg.NewCommand().
+		Overwrite().
+		Input(input).
+		Map("0:a:0").
+		NoVideo().
+		AudioCodec(format.encoder)
+	if format.BitrateKbps > 0 {
+		cmd.AudioBitrate(format.BitrateKbps)
+	}
+	if format.encoder == "libopus" {
+		// Opus only runs at 48 kHz; say so rather than rely on ffmpeg's guess.
+		cmd.SampleRate(48000)
+	}
+	return cmd.Format(format.container).Args(output)
+}
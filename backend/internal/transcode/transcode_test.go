@@ -0,0 +1,218 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+	"github.com/openmusicplayer/backend/internal/storage"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		name, format, accept string
+		want                 string
+		wantErr              bool
+	}{
+		{name: "explicit format wins", format: "MP3", accept: "audio/flac", want: "mp3"},
+		{name: "unknown format", format: "wma", wantErr: true},
+		{name: "no preference", accept: "application/json, */*"},
+		{name: "highest q", accept: "audio/mpeg;q=0.4, audio/ogg;q=0.8", want: "opus"},
+		{name: "webm opus", accept: "audio/webm; codecs=\"opus\"", want: "opus"},
+		{name: "refused type skipped", accept: "audio/flac;q=0, audio/mpeg;q=0.1", want: "mp3"},
+		{name: "ties keep header order", accept: "audio/flac, audio/mpeg", want: "flac"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := Negotiate(tc.format, tc.accept)
+			if tc.wantErr {
+				if !errors.Is(err, ErrUnsupportedFormat) {
+					t.Fatalf("err = %v, want ErrUnsupportedFormat", err)
+				}
+				return
+			}
+			if err != nil || ok != (tc.want != "") || got.Name != tc.want {
+				t.Fatalf("Negotiate = %q, %v, %v; want %q", got.Name, ok, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestArgsEncodesOpusAt48kWithoutCoverArt(t *testing.T) {
+	opus, _ := Lookup("opus")
+	args := strings.Join(Args("in.flac", "out.opus", opus), " ")
+	for _, want := range []string{"-i in.flac", "-map 0:a:0", "-vn", "-c:a libopus", "-b:a 128k", "-ar 48000", "-f ogg out.opus"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("args = %q, missing %q", args, want)
+		}
+	}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+}
+
+func (m *memoryStore) StatObject(_ context.Context, key string) (*storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &storage.ObjectInfo{Size: int64(len(data)), ETag: "etag-" + key}, nil
+}
+
+func (m *memoryStore) GetObject(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	info, err := m.StatObject(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(m.objects[key])), info, nil
+}
+
+func (m *memoryStore) PutObject(_ context.Context, key string, reader io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	m.puts++
+	return nil
+}
+
+// encodingFake writes "encoded:<input>" to the output path after release is
+// closed.
+func encodingFake(release <-chan struct{}) *ffmpeg.Fake {
+	fake := ffmpeg.NewFake()
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		<-release
+		input := call.Args[indexOf(call.Args, "-i")+1]
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return ffmpeg.Output{}, err
+		}
+		return ffmpeg.Output{}, os.WriteFile(call.Args[len(call.Args)-1], append([]byte("encoded:"), data...), 0o644)
+	}
+	return fake
+}
+
+func indexOf(args []string, want string) int {
+	for i, arg := range args {
+		if arg == want {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestVariantTranscodesOnceAndReusesTheStoredVariant(t *testing.T) {
+	store := &memoryStore{objects: map[string][]byte{"audio/1.flac": []byte("lossless")}}
+	release := make(chan struct{})
+	fake := encodingFake(release)
+	service := NewService(store, fake, 1, time.Minute)
+	opus, _ := Lookup("opus")
+	source := &storage.ObjectInfo{ETag: "v1"}
+
+	var wg sync.WaitGroup
+	keys := make([]string, 3)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, _, err := service.Variant(context.Background(), "audio/1.flac", source, opus)
+			if err != nil {
+				t.Errorf("Variant: %v", err)
+			}
+			keys[i] = key
+		}(i)
+	}
+	// Let all three callers join the in-flight transcode before it finishes.
+	for deadline := time.Now().Add(time.Second); len(fake.Calls()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	want := VariantKey("audio/1.flac", "v1", opus)
+	for _, key := range keys {
+		if key != want {
+			t.Fatalf("keys = %v, want %s", keys, want)
+		}
+	}
+	if got := string(store.objects[want]); got != "encoded:lossless" {
+		t.Fatalf("variant = %q, want the encoded original", got)
+	}
+
+	if _, _, err := service.Variant(context.Background(), "audio/1.flac", source, opus); err != nil {
+		t.Fatalf("Variant: %v", err)
+	}
+	if calls := len(fake.Calls()); calls != 1 || store.puts != 1 {
+		t.Fatalf("ffmpeg calls = %d, puts = %d; want one transcode shared by every request", calls, store.puts)
+	}
+
+	// New audio under the same key gets a fresh variant.
+	if key := VariantKey("audio/1.flac", "v2", opus); key == want {
+		t.Fatalf("variant key ignores the source ETag")
+	}
+}
+
+func TestVariantDisabledWithoutWorkers(t *testing.T) {
+	service := NewService(&memoryStore{objects: map[string][]byte{}}, ffmpeg.NewFake(), 0, time.Minute)
+	opus, _ := Lookup("opus")
+	if _, _, err := service.Variant(context.Background(), "audio/1.flac", &storage.ObjectInfo{}, opus); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("err = %v, want ErrDisabled", err)
+	}
+}
+
+type fullDisk struct{}
+
+func (fullDisk) Allow() bool { return false }
+
+func TestVariantUsesTempDirAndRespectsDiskGuard(t *testing.T) {
+	dir := t.TempDir()
+	store := &memoryStore{objects: map[string][]byte{"audio/1.flac": []byte("lossless")}}
+	fake := ffmpeg.NewFake()
+	var scratch string
+	fake.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		output := call.Args[len(call.Args)-1]
+		scratch = filepath.Dir(filepath.Dir(output))
+		return ffmpeg.Output{}, os.WriteFile(output, []byte("encoded"), 0o644)
+	}
+	opus, _ := Lookup("opus")
+	source := &storage.ObjectInfo{ETag: "v1"}
+
+	service := NewService(store, fake, 1, time.Minute, WithTempDir(dir))
+	if _, _, err := service.Variant(context.Background(), "audio/1.flac", source, opus); err != nil {
+		t.Fatalf("Variant: %v", err)
+	}
+	if scratch != dir {
+		t.Fatalf("scratch dir parent = %q, want the configured %q", scratch, dir)
+	}
+
+	// A stored variant is still served; only a new transcode is refused.
+	full := NewService(store, fake, 1, time.Minute, WithTempDir(dir), WithDiskGuard(fullDisk{}))
+	if _, _, err := full.Variant(context.Background(), "audio/1.flac", source, opus); err != nil {
+		t.Fatalf("stored Variant: %v", err)
+	}
+	mp3, _ := Lookup("mp3")
+	if _, _, err := full.Variant(context.Background(), "audio/1.flac", source, mp3); !errors.Is(err, ErrLowDiskSpace) {
+		t.Fatalf("err = %v, want ErrLowDiskSpace on a full volume", err)
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Fatalf("ffmpeg calls = %d, want only the first transcode to run ffmpeg", calls)
+	}
+}
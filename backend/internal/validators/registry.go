@@ -1,6 +1,9 @@
 package validators
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // Registry manages URL validators
 type Registry struct {
@@ -41,6 +44,24 @@ func (r *Registry) Validate(url string) ValidationResult {
 	}
 }
 
+// Expand resolves url to the canonical URL it stands for when the validator
+// handling it can expand short links, and returns it unchanged otherwise.
+// Callers expand before Validate so short links dedup against the full URL.
+func (r *Registry) Expand(ctx context.Context, url string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.validators {
+		if v.CanHandle(url) {
+			if expander, ok := v.(Expander); ok {
+				return expander.Expand(ctx, url)
+			}
+			return url, nil
+		}
+	}
+	return url, nil
+}
+
 // GetSupportedSources returns all source types registered in the registry
 func (r *Registry) GetSupportedSources() []SourceType {
 	r.mu.RLock()
@@ -60,3 +81,12 @@ func DefaultRegistry() *Registry {
 	r.Register(NewSoundCloudValidator())
 	return r
 }
+
+// DefaultRegistryWithShortLinks creates the default registry with SoundCloud
+// short links expanded through resolver.
+func DefaultRegistryWithShortLinks(resolver *ShortLinkResolver) *Registry {
+	r := NewRegistry()
+	r.Register(NewYouTubeValidator())
+	r.Register(NewSoundCloudValidator().WithShortLinks(resolver))
+	return r
+}
@@ -0,0 +1,179 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultShortLinkTTL is how long an expanded short link is reused. Short
+	// links never change target, so the limit only bounds staleness after a
+	// track is renamed.
+	DefaultShortLinkTTL = 24 * time.Hour
+
+	// shortLinkCacheSize caps the number of cached expansions.
+	shortLinkCacheSize = 4096
+
+	// shortLinkMaxRedirects bounds the redirect chain of one expansion.
+	shortLinkMaxRedirects = 5
+)
+
+// ErrShortLinkUnresolved is returned when a short link does not lead to a
+// track or playlist.
+var ErrShortLinkUnresolved = errors.New("short link could not be resolved")
+
+// Expander is implemented by validators whose URLs can be short links that
+// only the provider can expand.
+type Expander interface {
+	// Expand returns the canonical URL a short link points to, or url
+	// unchanged when it is not a short link.
+	Expand(ctx context.Context, url string) (string, error)
+}
+
+type shortLinkEntry struct {
+	url     string
+	expires time.Time
+}
+
+// ShortLinkResolver expands on.soundcloud.com short links by following their
+// redirects with HEAD requests. Expansions are cached, so pasting the same
+// link twice costs one round trip.
+type ShortLinkResolver struct {
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]shortLinkEntry
+}
+
+// NewShortLinkResolver creates a resolver using client, or a client with a
+// five second timeout when nil. A ttl of zero uses DefaultShortLinkTTL.
+func NewShortLinkResolver(client *http.Client, ttl time.Duration) *ShortLinkResolver {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if ttl <= 0 {
+		ttl = DefaultShortLinkTTL
+	}
+	// Copy so the redirect policy does not leak into the caller's client.
+	resolving := *client
+	resolving.CheckRedirect = checkShortLinkRedirect
+	return &ShortLinkResolver{
+		client: &resolving,
+		ttl:    ttl,
+		now:    time.Now,
+		cache:  make(map[string]shortLinkEntry),
+	}
+}
+
+// checkShortLinkRedirect keeps the redirect chain on SoundCloud so a short
+// link cannot send the server to an arbitrary host.
+func checkShortLinkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= shortLinkMaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", shortLinkMaxRedirects)
+	}
+	if req.URL.Scheme != "https" || !isSoundCloudHost(req.URL.Hostname()) {
+		return fmt.Errorf("redirect left SoundCloud: %s", req.URL.Host)
+	}
+	return nil
+}
+
+func isSoundCloudHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "soundcloud.com" || strings.HasSuffix(host, ".soundcloud.com")
+}
+
+// Resolve returns the canonical soundcloud.com URL of the track or playlist
+// the short link points to.
+func (r *ShortLinkResolver) Resolve(ctx context.Context, shortURL string) (string, error) {
+	key := strings.TrimSpace(shortURL)
+	if !isShortLink(key) {
+		return "", fmt.Errorf("%w: %s is not a SoundCloud short link", ErrShortLinkUnresolved, key)
+	}
+	if cached, ok := r.cached(key); ok {
+		return cached, nil
+	}
+
+	final, err := r.follow(ctx, key, http.MethodHead)
+	if err != nil {
+		return "", err
+	}
+	if final == "" {
+		// Some edges refuse HEAD; the redirect is the same on GET.
+		if final, err = r.follow(ctx, key, http.MethodGet); err != nil {
+			return "", err
+		}
+	}
+
+	result := NewSoundCloudValidator().Validate(final)
+	if !result.Valid || (result.MediaType != "track" && result.MediaType != "playlist") {
+		return "", fmt.Errorf("%w: %s leads to %s", ErrShortLinkUnresolved, key, final)
+	}
+	r.store(key, result.Canonical)
+	return result.Canonical, nil
+}
+
+// follow requests rawURL and returns the URL the redirects ended at, or ""
+// when the server rejected the method.
+func (r *ShortLinkResolver) follow(ctx context.Context, rawURL, method string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrShortLinkUnresolved, err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrShortLinkUnresolved, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed && method == http.MethodHead {
+		return "", nil
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%w: %s returned %d", ErrShortLinkUnresolved, rawURL, resp.StatusCode)
+	}
+	return resp.Request.URL.String(), nil
+}
+
+func (r *ShortLinkResolver) cached(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.cache[key]
+	if !ok || !r.now().Before(entry.expires) {
+		return "", false
+	}
+	return entry.url, true
+}
+
+func (r *ShortLinkResolver) store(key, canonical string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if len(r.cache) >= shortLinkCacheSize {
+		for k, entry := range r.cache {
+			if !now.Before(entry.expires) {
+				delete(r.cache, k)
+			}
+		}
+		if len(r.cache) >= shortLinkCacheSize {
+			r.cache = make(map[string]shortLinkEntry)
+		}
+	}
+	r.cache[key] = shortLinkEntry{url: canonical, expires: now.Add(r.ttl)}
+}
+
+// isShortLink reports whether rawURL is an https on.soundcloud.com link.
+func isShortLink(rawURL string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "https" && strings.ToLower(parsed.Hostname()) == "on.soundcloud.com"
+}
@@ -0,0 +1,97 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectTransport answers on.soundcloud.com requests with a redirect to
+// target and everything else with 200.
+type redirectTransport struct {
+	target string
+	calls  int
+	method string
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+	if req.URL.Host == "on.soundcloud.com" {
+		rt.calls++
+		rt.method = req.Method
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", rt.target)
+	}
+	return resp, nil
+}
+
+func TestShortLinkResolverExpandsAndCaches(t *testing.T) {
+	transport := &redirectTransport{target: "https://m.soundcloud.com/artist-name/track-name?si=abc&utm_source=clipboard"}
+	resolver := NewShortLinkResolver(&http.Client{Transport: transport}, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		got, err := resolver.Resolve(context.Background(), "https://on.soundcloud.com/AbCdE12345")
+		if err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		if got != "https://soundcloud.com/artist-name/track-name" {
+			t.Fatalf("Resolve = %q, want the canonical track URL", got)
+		}
+	}
+	if transport.calls != 1 || transport.method != http.MethodHead {
+		t.Fatalf("calls = %d with %s, want one HEAD request", transport.calls, transport.method)
+	}
+
+	resolver.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := resolver.Resolve(context.Background(), "https://on.soundcloud.com/AbCdE12345"); err != nil {
+		t.Fatalf("Resolve after expiry: %v", err)
+	}
+	if transport.calls != 2 {
+		t.Fatalf("calls = %d, want the expired entry fetched again", transport.calls)
+	}
+}
+
+func TestShortLinkResolverRejectsRedirectsOffSoundCloud(t *testing.T) {
+	transport := &redirectTransport{target: "https://example.com/artist-name/track-name"}
+	resolver := NewShortLinkResolver(&http.Client{Transport: transport}, time.Hour)
+
+	_, err := resolver.Resolve(context.Background(), "https://on.soundcloud.com/AbCdE12345")
+	if !errors.Is(err, ErrShortLinkUnresolved) || !strings.Contains(err.Error(), "left SoundCloud") {
+		t.Fatalf("err = %v, want the redirect refused", err)
+	}
+}
+
+func TestShortLinkResolverRejectsNonTrackTargets(t *testing.T) {
+	transport := &redirectTransport{target: "https://soundcloud.com/discover"}
+	resolver := NewShortLinkResolver(&http.Client{Transport: transport}, time.Hour)
+
+	if _, err := resolver.Resolve(context.Background(), "https://on.soundcloud.com/AbCdE12345"); !errors.Is(err, ErrShortLinkUnresolved) {
+		t.Fatalf("err = %v, want ErrShortLinkUnresolved", err)
+	}
+}
+
+func TestRegistryExpandOnlyTouchesShortLinks(t *testing.T) {
+	transport := &redirectTransport{target: "https://soundcloud.com/artist-name/sets/playlist-name"}
+	registry := DefaultRegistryWithShortLinks(NewShortLinkResolver(&http.Client{Transport: transport}, time.Hour))
+
+	got, err := registry.Expand(context.Background(), "https://on.soundcloud.com/AbCdE12345")
+	if err != nil || got != "https://soundcloud.com/artist-name/sets/playlist-name" {
+		t.Fatalf("Expand = %q, %v; want the canonical playlist URL", got, err)
+	}
+	for _, raw := range []string{"https://soundcloud.com/artist-name/track-name", "https://youtu.be/dQw4w9WgXcQ"} {
+		if got, err := registry.Expand(context.Background(), raw); err != nil || got != raw {
+			t.Fatalf("Expand(%q) = %q, %v; want it unchanged", raw, got, err)
+		}
+	}
+	if transport.calls != 1 {
+		t.Fatalf("calls = %d, want only the short link fetched", transport.calls)
+	}
+
+	// The default registry stays offline.
+	if got, err := DefaultRegistry().Expand(context.Background(), "https://on.soundcloud.com/AbCdE12345"); err != nil || got != "https://on.soundcloud.com/AbCdE12345" {
+		t.Fatalf("default Expand = %q, %v; want the short link unchanged", got, err)
+	}
+}
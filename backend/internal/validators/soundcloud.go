@@ -1,6 +1,7 @@
 package validators
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -13,6 +14,8 @@ type SoundCloudValidator struct {
 	usernamePattern *regexp.Regexp
 	// trackSlugPattern matches valid track/set slugs
 	trackSlugPattern *regexp.Regexp
+	// shortLinks expands on.soundcloud.com links; nil leaves them as is
+	shortLinks *ShortLinkResolver
 }
 
 // NewSoundCloudValidator creates a new SoundCloud URL validator
@@ -25,6 +28,23 @@ func NewSoundCloudValidator() *SoundCloudValidator {
 	}
 }
 
+// WithShortLinks makes Expand resolve on.soundcloud.com links through
+// resolver. Validate itself never makes network calls.
+func (v *SoundCloudValidator) WithShortLinks(resolver *ShortLinkResolver) *SoundCloudValidator {
+	v.shortLinks = resolver
+	return v
+}
+
+// Expand resolves an on.soundcloud.com link to its canonical track or
+// playlist URL. Other URLs, and every URL when no resolver is configured,
+// are returned unchanged.
+func (v *SoundCloudValidator) Expand(ctx context.Context, rawURL string) (string, error) {
+	if v.shortLinks == nil || !isShortLink(rawURL) {
+		return rawURL, nil
+	}
+	return v.shortLinks.Resolve(ctx, rawURL)
+}
+
 // SourceType returns the source type for this validator
 func (v *SoundCloudValidator) SourceType() SourceType {
 	return SourceSoundCloud
@@ -255,8 +275,8 @@ func (v *SoundCloudValidator) validateShortURL(rawURL string, parsed *url.URL) V
 		}
 	}
 
-	// Short URLs are valid but we can't resolve them without an API call
-	// Mark as valid with the short code as the ID
+	// Short URLs are valid but resolving them takes a network call, which
+	// Expand makes. Mark as valid with the short code as the ID
 	return ValidationResult{
 		Valid:      true,
 		SourceType: SourceSoundCloud,
@@ -11,12 +11,17 @@ import (
 type YouTubeValidator struct {
 	// videoIDPattern matches YouTube video IDs (11 characters, alphanumeric with - and _)
 	videoIDPattern *regexp.Regexp
+	// playlistIDPattern matches shareable playlist IDs (PL..., OLAK5uy_..., RD...)
+	playlistIDPattern *regexp.Regexp
 }
 
 // NewYouTubeValidator creates a new YouTube URL validator
 func NewYouTubeValidator() *YouTubeValidator {
 	return &YouTubeValidator{
 		videoIDPattern: regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`),
+		// Private lists such as WL (watch later) and LL (liked) are too short
+		// to match and cannot be fetched anyway.
+		playlistIDPattern: regexp.MustCompile(`^[a-zA-Z0-9_-]{10,64}$`),
 	}
 }
 
@@ -38,7 +43,8 @@ func (v *YouTubeValidator) CanHandle(rawURL string) bool {
 
 	return host == "youtube.com" ||
 		host == "youtu.be" ||
-		host == "music.youtube.com"
+		host == "music.youtube.com" ||
+		host == "youtube-nocookie.com"
 }
 
 // Validate validates a YouTube URL and extracts the video ID
@@ -81,10 +87,13 @@ func (v *YouTubeValidator) Validate(rawURL string) ValidationResult {
 	switch host {
 	case "youtu.be":
 		// Short URL format: youtu.be/VIDEO_ID
-		videoID = strings.TrimPrefix(parsed.Path, "/")
+		videoID, _, _ = strings.Cut(strings.TrimPrefix(parsed.Path, "/"), "/")
 		mediaType = "video"
 
-	case "youtube.com", "music.youtube.com":
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if strings.TrimSuffix(parsed.Path, "/") == "/playlist" {
+			return v.validatePlaylist(rawURL, parsed)
+		}
 		videoID, mediaType = v.extractFromYouTubeCom(parsed)
 
 	default:
@@ -126,6 +135,38 @@ func (v *YouTubeValidator) Validate(rawURL string) ValidationResult {
 	}
 }
 
+// validatePlaylist validates /playlist?list=ID URLs. YouTube Music and
+// YouTube share playlist IDs, so both canonicalize to the youtube.com form.
+// A list= on a watch URL only gives the video's context and is dropped there.
+func (v *YouTubeValidator) validatePlaylist(rawURL string, parsed *url.URL) ValidationResult {
+	listID := parsed.Query().Get("list")
+	if listID == "" {
+		return ValidationResult{
+			Valid:      false,
+			SourceType: SourceYouTube,
+			URL:        rawURL,
+			Error:      "playlist URL missing list ID",
+		}
+	}
+	if !v.playlistIDPattern.MatchString(listID) {
+		return ValidationResult{
+			Valid:      false,
+			SourceType: SourceYouTube,
+			URL:        rawURL,
+			MediaID:    listID,
+			Error:      "invalid or private playlist ID",
+		}
+	}
+	return ValidationResult{
+		Valid:      true,
+		SourceType: SourceYouTube,
+		MediaID:    listID,
+		MediaType:  "playlist",
+		URL:        rawURL,
+		Canonical:  fmt.Sprintf("https://www.youtube.com/playlist?list=%s", listID),
+	}
+}
+
 // extractFromYouTubeCom extracts video ID from youtube.com URLs
 func (v *YouTubeValidator) extractFromYouTubeCom(parsed *url.URL) (videoID, mediaType string) {
 	path := parsed.Path
@@ -137,6 +137,59 @@ func TestYouTubeValidator_Validate(t *testing.T) {
 			wantCanonical: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
 		},
 
+		{
+			name:          "YouTube Music share link",
+			url:           "https://music.youtube.com/watch?v=dQw4w9WgXcQ&si=trackingToken&feature=share",
+			wantValid:     true,
+			wantMediaID:   "dQw4w9WgXcQ",
+			wantMediaType: "video",
+			wantCanonical: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+		{
+			name:          "youtu.be share link with trailing slash",
+			url:           "https://youtu.be/dQw4w9WgXcQ/?si=trackingToken",
+			wantValid:     true,
+			wantMediaID:   "dQw4w9WgXcQ",
+			wantMediaType: "video",
+			wantCanonical: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+		{
+			name:          "privacy-enhanced embed",
+			url:           "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0",
+			wantValid:     true,
+			wantMediaID:   "dQw4w9WgXcQ",
+			wantMediaType: "video",
+			wantCanonical: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+
+		// Playlists
+		{
+			name:          "playlist",
+			url:           "https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI&si=abc",
+			wantValid:     true,
+			wantMediaID:   "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI",
+			wantMediaType: "playlist",
+			wantCanonical: "https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI",
+		},
+		{
+			name:          "YouTube Music album playlist",
+			url:           "https://music.youtube.com/playlist?list=OLAK5uy_k3E9ZSgxW4OWz_rO3IJZtL3VwHvGtG6CU",
+			wantValid:     true,
+			wantMediaID:   "OLAK5uy_k3E9ZSgxW4OWz_rO3IJZtL3VwHvGtG6CU",
+			wantMediaType: "playlist",
+			wantCanonical: "https://www.youtube.com/playlist?list=OLAK5uy_k3E9ZSgxW4OWz_rO3IJZtL3VwHvGtG6CU",
+		},
+		{
+			name:      "private watch later list",
+			url:       "https://www.youtube.com/playlist?list=WL",
+			wantValid: false,
+		},
+		{
+			name:      "playlist without list",
+			url:       "https://www.youtube.com/playlist",
+			wantValid: false,
+		},
+
 		// Video IDs with special characters
 		{
 			name:          "video ID with hyphen",
@@ -0,0 +1,123 @@
+// Package waveform computes the peak data players draw seek bars from: a
+// fixed number of amplitude peaks across a whole track, stored as JSON next
+// to the track's audio in object storage.
+package waveform
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+const (
+	// Version is bumped whenever the stored layout or peak scale changes.
+	Version = 1
+	// DefaultPeaks is how many peaks a track's waveform holds.
+	DefaultPeaks = 1000
+	// SampleRate is the rate audio is decoded at before peaks are taken.
+	// Seek bars need the envelope, not the detail, and a low rate keeps the
+	// scratch file of an hour-long mix small.
+	SampleRate = 8000
+	// ContentType is what stored waveforms are served as.
+	ContentType = "application/json"
+
+	// keyPrefix is where waveforms live in object storage.
+	keyPrefix = "waveforms/tracks"
+)
+
+// ErrNoAudio reports that decoding produced no samples.
+var ErrNoAudio = errors.New("no audio samples decoded")
+
+// Waveform is the stored peak data of one track. Peaks run from 0 (silence)
+// to 1 (full scale), each covering an equal share of the track.
+type Waveform struct {
+	Version    int       `json:"version"`
+	DurationMs int       `json:"durationMs,omitempty"`
+	SampleRate int       `json:"sampleRate"`
+	Peaks      []float64 `json:"peaks"`
+}
+
+// Key returns the storage key a new waveform is written under for a track
+// identity. Readers take the key recorded on the track instead, since the
+// identity can be rehashed after the waveform is stored.
+func Key(identityHash string) string {
+	return fmt.Sprintf("%s/%s.json", keyPrefix, identityHash)
+}
+
+// Generate decodes the first audio stream of the file at path to mono
+// 16-bit PCM in a scratch file under dir and reduces it to count peaks.
+func Generate(ctx context.Context, runner ffmpeg.Runner, path, dir string, durationMs, count int) (*Waveform, error) {
+	pcmPath := filepath.Join(dir, "waveform.pcm")
+	defer os.Remove(pcmPath)
+
+	args := ffmpeg.NewCommand().
+		Overwrite().
+		Input(path).
+		Map("0:a:0").
+		Channels(1).
+		SampleRate(SampleRate).
+		AudioCodec("pcm_s16le").
+		Format("s16le").
+		Args(pcmPath)
+	if _, err := runner.FFmpeg(ctx, args, nil); err != nil {
+		return nil, fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	file, err := os.Open(pcmPath)
+	if err != nil {
+		return nil, fmt.Errorf("open decoded audio: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat decoded audio: %w", err)
+	}
+	peaks, err := Peaks(bufio.NewReader(file), info.Size()/2, count)
+	if err != nil {
+		return nil, err
+	}
+	return &Waveform{Version: Version, DurationMs: durationMs, SampleRate: SampleRate, Peaks: peaks}, nil
+}
+
+// Peaks reads the given number of little-endian signed 16-bit samples from
+// r and returns the largest absolute amplitude in each of count equal
+// buckets, rounded to four places. Audio shorter than count samples has one
+// peak per sample.
+func Peaks(r io.Reader, samples int64, count int) ([]float64, error) {
+	if samples <= 0 {
+		return nil, ErrNoAudio
+	}
+	if count <= 0 {
+		count = DefaultPeaks
+	}
+	if samples < int64(count) {
+		count = int(samples)
+	}
+
+	peaks := make([]float64, count)
+	var buf [2]byte
+	bucket := 0
+	end := samples / int64(count)
+	for i := int64(0); i < samples; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, fmt.Errorf("read decoded audio: %w", err)
+		}
+		for i >= end && bucket < count-1 {
+			bucket++
+			end = samples * int64(bucket+1) / int64(count)
+		}
+		amplitude := math.Abs(float64(int16(binary.LittleEndian.Uint16(buf[:])))) / 32768
+		peaks[bucket] = max(peaks[bucket], amplitude)
+	}
+	for i, peak := range peaks {
+		peaks[i] = math.Round(peak*10000) / 10000
+	}
+	return peaks, nil
+}
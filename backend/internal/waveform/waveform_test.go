@@ -0,0 +1,68 @@
+package waveform
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/openmusicplayer/backend/internal/ffmpeg"
+)
+
+func pcm(samples ...int16) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+func TestPeaksTakesLargestAmplitudePerBucket(t *testing.T) {
+	// Ten samples split into buckets of two, three, two, and three.
+	data := pcm(100, -16384, 0, 8192, -32768, 3277, 0, 0, -1638, 0)
+	got, err := Peaks(bytes.NewReader(data), 10, 4)
+	if err != nil {
+		t.Fatalf("Peaks: %v", err)
+	}
+	want := []float64{0.5, 1, 0.1, 0.05}
+	if len(got) != len(want) {
+		t.Fatalf("peaks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("peaks = %v, want %v", got, want)
+		}
+	}
+
+	short, err := Peaks(bytes.NewReader(pcm(16384, -8192)), 2, DefaultPeaks)
+	if err != nil || len(short) != 2 || short[0] != 0.5 || short[1] != 0.25 {
+		t.Fatalf("short peaks = %v, %v; want one peak per sample", short, err)
+	}
+	if _, err := Peaks(bytes.NewReader(nil), 0, DefaultPeaks); !errors.Is(err, ErrNoAudio) {
+		t.Fatalf("empty audio err = %v, want ErrNoAudio", err)
+	}
+}
+
+func TestGenerateDecodesToScratchFile(t *testing.T) {
+	dir := t.TempDir()
+	runner := ffmpeg.NewFake()
+	runner.Handle = func(call ffmpeg.Call) (ffmpeg.Output, error) {
+		target := call.Args[len(call.Args)-1]
+		return ffmpeg.Output{}, os.WriteFile(target, pcm(0, 16384, 0, -32768), 0o644)
+	}
+
+	got, err := Generate(context.Background(), runner, "/tmp/track.mp3", dir, 4000, 2)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got.Version != Version || got.DurationMs != 4000 || got.SampleRate != SampleRate ||
+		len(got.Peaks) != 2 || got.Peaks[0] != 0.5 || got.Peaks[1] != 1 {
+		t.Fatalf("waveform = %+v", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("scratch dir still holds %d entries", len(entries))
+	}
+	if Key("abc") != "waveforms/tracks/abc.json" {
+		t.Fatalf("key = %q", Key("abc"))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -28,15 +29,35 @@ type Client struct {
 	conn   *websocket.Conn
 	send   chan *ProgressMessage
 	userID int64
+	user   uuid.UUID
+	// deviceID names the device this connection belongs to, when the client
+	// gave one, so messages can be addressed to a single device.
+	deviceID string
+	// session, deviceName and deviceType are set once the device joins the
+	// playback session; see session.go. They are guarded by the hub's mu.
+	session    bool
+	deviceName string
+	deviceType string
+	// schema is the event schema version the connection asked for, and
+	// topics the topics it is subscribed to, nil meaning all of them; see
+	// topics.go. topics is guarded by the hub's mu.
+	schema int
+	topics map[string]bool
 }
 
 // NewClient creates a new client instance.
-func NewClient(hub *Hub, conn *websocket.Conn, userID int64) *Client {
+// schema is an event schema version and topics the topics to receive, nil
+// for all of them.
+func NewClient(hub *Hub, conn *websocket.Conn, user uuid.UUID, deviceID string, schema int, topics map[string]bool) *Client {
 	return &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan *ProgressMessage, 256),
-		userID: userID,
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan *ProgressMessage, 256),
+		userID:   uuidToInt64(user),
+		user:     user,
+		deviceID: deviceID,
+		schema:   schema,
+		topics:   topics,
 	}
 }
 
@@ -56,15 +77,30 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("websocket error: %v", err)
 			}
 			break
 		}
-		// We don't process incoming messages for progress tracking
-		// This is a one-way channel (server -> client)
+		// Progress is server -> client only; clients send a device's answer
+		// to a playback transfer, playback session messages and topic
+		// subscriptions.
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		msg.UserID = c.user
+		msg.DeviceID = c.deviceID
+		switch msg.Type {
+		case MessagePlaybackTransferAck:
+			c.hub.dispatch(msg)
+		case MessageSessionRegister, MessagePlaybackEvent, MessagePlaybackCommand:
+			c.hub.handleSession(c, msg)
+		case MessageSubscribe, MessageUnsubscribe:
+			c.hub.handleSubscription(c, msg)
+		}
 	}
 }
 
@@ -92,7 +128,11 @@ func (c *Client) WritePump() {
 				return
 			}
 
-			data, err := json.Marshal(message)
+			var payload any = message
+			if c.schema == SchemaEnvelope {
+				payload = envelope(message)
+			}
+			data, err := json.Marshal(payload)
 			if err != nil {
 				log.Printf("error marshaling message: %v", err)
 				continue
@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Playback handoff message types. The server sends playback_transfer to the
+// target device, which answers with playback_transfer_ack; the source device
+// then gets playback_transfer_accepted or playback_transfer_declined.
+const (
+	MessagePlaybackTransfer         = "playback_transfer"
+	MessagePlaybackTransferAck      = "playback_transfer_ack"
+	MessagePlaybackTransferAccepted = "playback_transfer_accepted"
+	MessagePlaybackTransferDeclined = "playback_transfer_declined"
+)
+
+var deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidDeviceID reports whether id is usable as a device ID.
+func ValidDeviceID(id string) bool {
+	return deviceIDPattern.MatchString(id)
+}
+
+// ClientMessage is a message a client sent over its connection. UserID and
+// DeviceID come from the connection, never from the message body.
+type ClientMessage struct {
+	Type       string `json:"type"`
+	TransferID string `json:"transfer_id"`
+	Accepted   bool   `json:"accepted"`
+	// DeviceName and DeviceType describe the device in session_register.
+	DeviceName string `json:"device_name"`
+	DeviceType string `json:"device_type"`
+	// TargetDeviceID is the device a playback_command is for.
+	TargetDeviceID string           `json:"target_device_id"`
+	Playback       *PlaybackMessage `json:"playback"`
+	// Topics are the topics to add or drop in subscribe and unsubscribe.
+	Topics   []string  `json:"topics"`
+	UserID   uuid.UUID `json:"-"`
+	DeviceID string    `json:"-"`
+}
+
+// SetMessageHandler registers the function that receives client messages.
+// It runs on the sending connection's read goroutine.
+func (h *Hub) SetMessageHandler(handler func(ClientMessage)) {
+	h.mu.Lock()
+	h.onMessage = handler
+	h.mu.Unlock()
+}
+
+func (h *Hub) dispatch(msg ClientMessage) {
+	h.mu.RLock()
+	handler := h.onMessage
+	h.mu.RUnlock()
+	if handler != nil {
+		handler(msg)
+	}
+}
+
+// DeviceConnected reports whether the user has a connection from deviceID.
+func (h *Hub) DeviceConnected(userID uuid.UUID, deviceID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients[uuidToInt64(userID)] {
+		if client.deviceID == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// SendToDevice sends a message to the user's connections from deviceID and
+// reports whether any were connected. A device that is not connected picks
+// the message up by polling instead.
+func (h *Hub) SendToDevice(userID uuid.UUID, deviceID, msgType string, transfer any) bool {
+	if !h.DeviceConnected(userID, deviceID) {
+		return false
+	}
+	h.BroadcastProgress(&ProgressMessage{
+		Type:     msgType,
+		UserID:   uuidToInt64(userID),
+		DeviceID: deviceID,
+		Transfer: transfer,
+	})
+	return true
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -36,6 +37,9 @@ func NewHandler(hub *Hub, authService *auth.Service) *Handler {
 // ServeWS handles WebSocket requests from clients.
 // Authentication is done via query parameter: ?token=<jwt_token>
 // This is necessary because browser WebSocket API doesn't support custom headers.
+// Clients that take part in playback handoff also pass ?device_id=<id>.
+// ?schema= picks the event schema version and ?topics= the topics to
+// receive; see topics.go.
 func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	// Get token from query parameter
 	token := r.URL.Query().Get("token")
@@ -61,6 +65,23 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	deviceID := r.URL.Query().Get("device_id")
+	if deviceID != "" && !ValidDeviceID(deviceID) {
+		http.Error(w, `{"code":"INVALID_DEVICE_ID","message":"device_id must be 1-64 letters, digits, '-', '_' or '.'"}`, http.StatusBadRequest)
+		return
+	}
+
+	schema, ok := parseSchema(r.URL.Query().Get("schema"))
+	if !ok {
+		http.Error(w, `{"code":"UNSUPPORTED_SCHEMA","message":"schema must be 1 or 2"}`, http.StatusBadRequest)
+		return
+	}
+	topics, ok := parseTopics(r.URL.Query().Get("topics"))
+	if !ok {
+		http.Error(w, `{"code":"INVALID_TOPIC","message":"topics must be a comma-separated list of `+strings.Join(Topics, ", ")+`"}`, http.StatusBadRequest)
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -68,11 +89,7 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert UUID to int64 for client tracking
-	// Using the first 8 bytes of the UUID as a unique identifier
-	userIDInt := uuidToInt64(userID)
-
-	client := NewClient(h.hub, conn, userIDInt)
+	client := NewClient(h.hub, conn, userID, deviceID, schema, topics)
 	h.hub.register <- client
 
 	// Start the client's read and write pumps
@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to them.
@@ -18,6 +20,14 @@ type Hub struct {
 	// Broadcast channel for progress updates
 	broadcast chan *ProgressMessage
 
+	// onMessage receives messages clients send; see SetMessageHandler.
+	onMessage func(ClientMessage)
+
+	// seq numbers session messages, and active is each user's active
+	// session device; see session.go.
+	seq    atomic.Int64
+	active map[int64]string
+
 	mu sync.RWMutex
 }
 
@@ -31,6 +41,52 @@ type ProgressMessage struct {
 	Error      string `json:"error,omitempty"`
 	TrackTitle string `json:"track_title,omitempty"`
 	ArtistName string `json:"artist_name,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	// DownloadJobID, QueuePosition, and EstimatedStartAt describe a waiting
+	// job in download_queue_position messages. Download jobs have string IDs,
+	// so these messages leave JobID zero.
+	DownloadJobID    string     `json:"download_job_id,omitempty"`
+	QueuePosition    int        `json:"queue_position,omitempty"`
+	EstimatedStartAt *time.Time `json:"estimated_start_at,omitempty"`
+	// ParentJobID names the playlist download a download_progress message's
+	// job is an item of.
+	ParentJobID string `json:"parent_job_id,omitempty"`
+	// DeviceID, when set, limits delivery to the user's connections from that
+	// device.
+	DeviceID string `json:"-"`
+	// Transfer describes the handoff in playback_transfer* messages.
+	Transfer any `json:"transfer,omitempty"`
+	// BatchMatch is the run's status in batch_match_progress messages.
+	BatchMatch any `json:"batch_match,omitempty"`
+	// LibraryExport is the export's status in library_export_progress
+	// messages.
+	LibraryExport any `json:"library_export,omitempty"`
+	// ArtworkBackfill is the run's status in artwork_backfill_progress
+	// messages.
+	ArtworkBackfill any `json:"artwork_backfill,omitempty"`
+	// LibraryScan is the run's status in library_scan_progress messages.
+	LibraryScan any `json:"library_scan,omitempty"`
+	// ExceptDeviceID, when set, skips the user's connections from that
+	// device, so a device's own session events are not echoed back to it.
+	ExceptDeviceID string `json:"-"`
+	// Seq orders session messages; see session.go.
+	Seq int64 `json:"seq,omitempty"`
+	// FromDeviceID names the device a session message came from.
+	FromDeviceID string `json:"from_device_id,omitempty"`
+	// Playback is the event or command in playback_event and
+	// playback_command messages.
+	Playback *PlaybackMessage `json:"playback,omitempty"`
+	// Devices lists the user's session devices in session_devices messages.
+	Devices any `json:"devices,omitempty"`
+	// Queue summarizes the change in queue_changed messages.
+	Queue any `json:"queue,omitempty"`
+	// Playlist summarizes the change in playlist_changed messages.
+	Playlist any `json:"playlist,omitempty"`
+	// Topics lists the topics a connection receives in subscriptions
+	// messages.
+	Topics []string `json:"topics,omitempty"`
+	// client, when set, limits delivery to that one connection.
+	client *Client
 }
 
 // NewHub creates a new Hub instance.
@@ -40,6 +96,7 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *ProgressMessage),
+		active:     make(map[int64]string),
 	}
 }
 
@@ -57,31 +114,56 @@ func (h *Hub) Run() {
 
 		case client := <-h.unregister:
 			h.mu.Lock()
+			registered := false
 			if clients, ok := h.clients[client.userID]; ok {
 				if _, ok := clients[client]; ok {
+					registered = true
 					delete(clients, client)
 					close(client.send)
+					h.dropActiveLocked(client)
 					if len(clients) == 0 {
 						delete(h.clients, client.userID)
 					}
 				}
 			}
 			h.mu.Unlock()
+			if registered && client.session {
+				h.deliver(h.devicesMessage(client.user))
+			}
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			if clients, ok := h.clients[message.UserID]; ok {
-				for client := range clients {
-					select {
-					case client.send <- message:
-					default:
-						// Client's buffer is full, close the connection
-						close(client.send)
-						delete(clients, client)
-					}
-				}
+			h.deliver(message)
+		}
+	}
+}
+
+// deliver queues a message on each of the user's connections it is
+// addressed to.
+func (h *Hub) deliver(message *ProgressMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if clients, ok := h.clients[message.UserID]; ok {
+		for client := range clients {
+			if message.client != nil && client != message.client {
+				continue
+			}
+			if !client.wants(message.Type) {
+				continue
+			}
+			if message.DeviceID != "" && client.deviceID != message.DeviceID {
+				continue
+			}
+			if message.ExceptDeviceID != "" && client.deviceID == message.ExceptDeviceID {
+				continue
+			}
+			select {
+			case client.send <- message:
+			default:
+				// Client's buffer is full, close the connection
+				close(client.send)
+				delete(clients, client)
+				h.dropActiveLocked(client)
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
@@ -1,6 +1,10 @@
 package websocket
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ProgressTracker provides an interface for broadcasting download progress updates.
 type ProgressTracker struct {
@@ -12,8 +16,9 @@ func NewProgressTracker(hub *Hub) *ProgressTracker {
 	return &ProgressTracker{hub: hub}
 }
 
-// UpdateProgress sends a progress update for a download job.
-func (pt *ProgressTracker) UpdateProgress(userID uuid.UUID, jobID int64, status string, progress int, trackTitle, artistName string) {
+// UpdateProgress sends a progress update for a download job. requestID is the
+// ID of the API request that queued the job, echoed so clients can report it.
+func (pt *ProgressTracker) UpdateProgress(userID uuid.UUID, jobID int64, status string, progress int, trackTitle, artistName, requestID string) {
 	userIDInt := uuidToInt64(userID)
 	pt.hub.BroadcastProgress(&ProgressMessage{
 		Type:       "download_progress",
@@ -23,23 +28,25 @@ func (pt *ProgressTracker) UpdateProgress(userID uuid.UUID, jobID int64, status
 		Progress:   progress,
 		TrackTitle: trackTitle,
 		ArtistName: artistName,
+		RequestID:  requestID,
 	})
 }
 
 // SendError sends an error notification for a download job.
-func (pt *ProgressTracker) SendError(userID uuid.UUID, jobID int64, errorMsg string) {
+func (pt *ProgressTracker) SendError(userID uuid.UUID, jobID int64, errorMsg, requestID string) {
 	userIDInt := uuidToInt64(userID)
 	pt.hub.BroadcastProgress(&ProgressMessage{
-		Type:   "download_progress",
-		JobID:  jobID,
-		UserID: userIDInt,
-		Status: "failed",
-		Error:  errorMsg,
+		Type:      "download_progress",
+		JobID:     jobID,
+		UserID:    userIDInt,
+		Status:    "failed",
+		Error:     errorMsg,
+		RequestID: requestID,
 	})
 }
 
 // SendCompletion sends a completion notification for a download job.
-func (pt *ProgressTracker) SendCompletion(userID uuid.UUID, jobID int64, trackTitle, artistName string) {
+func (pt *ProgressTracker) SendCompletion(userID uuid.UUID, jobID int64, trackTitle, artistName, requestID string) {
 	userIDInt := uuidToInt64(userID)
 	pt.hub.BroadcastProgress(&ProgressMessage{
 		Type:       "download_progress",
@@ -49,6 +56,84 @@ func (pt *ProgressTracker) SendCompletion(userID uuid.UUID, jobID int64, trackTi
 		Progress:   100,
 		TrackTitle: trackTitle,
 		ArtistName: artistName,
+		RequestID:  requestID,
+	})
+}
+
+// UpdateDownloadJob sends a download job's current state. parentJobID is set
+// when the job is one item of a playlist download.
+func (pt *ProgressTracker) UpdateDownloadJob(userID uuid.UUID, jobID, parentJobID, status string, progress int, errorMsg, trackTitle, requestID string) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:          "download_progress",
+		UserID:        uuidToInt64(userID),
+		Status:        status,
+		Progress:      progress,
+		Error:         errorMsg,
+		TrackTitle:    trackTitle,
+		RequestID:     requestID,
+		DownloadJobID: jobID,
+		ParentJobID:   parentJobID,
+	})
+}
+
+// UpdateQueuePosition tells the user where a waiting download job stands and
+// when it is expected to start; estimatedStartAt is nil when unknown.
+func (pt *ProgressTracker) UpdateQueuePosition(userID uuid.UUID, jobID string, position int, estimatedStartAt *time.Time) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:             "download_queue_position",
+		UserID:           uuidToInt64(userID),
+		Status:           "queued",
+		DownloadJobID:    jobID,
+		QueuePosition:    position,
+		EstimatedStartAt: estimatedStartAt,
+	})
+}
+
+// UpdateBatchMatch reports a batch track-matching run to the user who
+// started it. progress is the percentage of tracks processed.
+func (pt *ProgressTracker) UpdateBatchMatch(userID uuid.UUID, state string, progress int, batch any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:       "batch_match_progress",
+		UserID:     uuidToInt64(userID),
+		Status:     state,
+		Progress:   progress,
+		BatchMatch: batch,
+	})
+}
+
+// UpdateLibraryExport reports a library export archive being built to the
+// user who requested it. progress is the percentage of tracks processed.
+func (pt *ProgressTracker) UpdateLibraryExport(userID uuid.UUID, state string, progress int, export any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:          "library_export_progress",
+		UserID:        uuidToInt64(userID),
+		Status:        state,
+		Progress:      progress,
+		LibraryExport: export,
+	})
+}
+
+// UpdateArtworkBackfill reports an artwork backfill run to the user who
+// started it. progress is the percentage of tracks processed.
+func (pt *ProgressTracker) UpdateArtworkBackfill(userID uuid.UUID, state string, progress int, backfill any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:            "artwork_backfill_progress",
+		UserID:          uuidToInt64(userID),
+		Status:          state,
+		Progress:        progress,
+		ArtworkBackfill: backfill,
+	})
+}
+
+// UpdateLibraryScan reports a library scan run to the admin who started
+// it. progress is the percentage of files processed.
+func (pt *ProgressTracker) UpdateLibraryScan(userID uuid.UUID, state string, progress int, scan any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:        "library_scan_progress",
+		UserID:      uuidToInt64(userID),
+		Status:      state,
+		Progress:    progress,
+		LibraryScan: scan,
 	})
 }
 
@@ -57,3 +142,25 @@ func (pt *ProgressTracker) HasConnectedClients(userID uuid.UUID) bool {
 	userIDInt := uuidToInt64(userID)
 	return pt.hub.ClientCount(userIDInt) > 0
 }
+
+// QueueChanged tells the user's devices the shared queue was edited. The
+// device that made the edit already has the result, so it is skipped.
+func (pt *ProgressTracker) QueueChanged(userID uuid.UUID, fromDeviceID string, queue any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:           MessageQueueChanged,
+		UserID:         uuidToInt64(userID),
+		ExceptDeviceID: fromDeviceID,
+		Seq:            pt.hub.seq.Add(1),
+		FromDeviceID:   fromDeviceID,
+		Queue:          queue,
+	})
+}
+
+// PlaylistChanged tells the user's devices one of their playlists changed.
+func (pt *ProgressTracker) PlaylistChanged(userID uuid.UUID, playlist any) {
+	pt.hub.BroadcastProgress(&ProgressMessage{
+		Type:     MessagePlaylistChanged,
+		UserID:   uuidToInt64(userID),
+		Playlist: playlist,
+	})
+}
@@ -0,0 +1,174 @@
+package websocket
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Playback session message types. A device joins its user's session with
+// session_register; every connection then gets session_devices whenever the
+// set of devices or the active one changes. A device reports what it is
+// doing with playback_event, which the server relays to the user's other
+// devices, and remote-controls another device with playback_command, which
+// is delivered to that device only. queue_changed tells devices the shared
+// queue was edited.
+//
+// Sessions need the connection to carry a device_id. Every session message
+// the server sends has a seq that increases in the order the server handled
+// them, so when two devices act at once each client applies messages in seq
+// order and ignores any with a lower seq than one it has already applied.
+// Edits to the queue itself are versioned; see queue.Handlers.Synced.
+const (
+	MessageSessionRegister = "session_register"
+	MessageSessionDevices  = "session_devices"
+	MessagePlaybackEvent   = "playback_event"
+	MessagePlaybackCommand = "playback_command"
+	MessageQueueChanged    = "queue_changed"
+)
+
+// Playback actions. Events report play, pause, seek and track_change;
+// commands ask for play, pause, seek, next or previous.
+const (
+	PlaybackPlay        = "play"
+	PlaybackPause       = "pause"
+	PlaybackSeek        = "seek"
+	PlaybackNext        = "next"
+	PlaybackPrevious    = "previous"
+	PlaybackTrackChange = "track_change"
+)
+
+const maxDeviceNameLength = 64
+
+var (
+	playbackEventActions   = map[string]bool{PlaybackPlay: true, PlaybackPause: true, PlaybackSeek: true, PlaybackTrackChange: true}
+	playbackCommandActions = map[string]bool{PlaybackPlay: true, PlaybackPause: true, PlaybackSeek: true, PlaybackNext: true, PlaybackPrevious: true}
+)
+
+// PlaybackMessage is a playback event or command. PositionMs is the offset
+// into the item for seek, and where playback stands for other actions;
+// QueueItemID names the item an event is about.
+type PlaybackMessage struct {
+	Action      string `json:"action"`
+	PositionMs  int64  `json:"position_ms,omitempty"`
+	QueueItemID string `json:"queue_item_id,omitempty"`
+}
+
+// SessionDevice is one device in a user's playback session. Active marks the
+// device that last started playing.
+type SessionDevice struct {
+	DeviceID string `json:"device_id"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Active   bool   `json:"active"`
+}
+
+// handleSession handles a session message from c. Messages from connections
+// without a device ID, from devices that have not registered, and malformed
+// ones are dropped.
+func (h *Hub) handleSession(c *Client, msg ClientMessage) {
+	if c.deviceID == "" {
+		return
+	}
+
+	switch msg.Type {
+	case MessageSessionRegister:
+		if len(msg.DeviceName) > maxDeviceNameLength || len(msg.DeviceType) > maxDeviceNameLength {
+			return
+		}
+		h.mu.Lock()
+		c.session = true
+		c.deviceName = msg.DeviceName
+		c.deviceType = msg.DeviceType
+		h.mu.Unlock()
+		h.BroadcastProgress(h.devicesMessage(c.user))
+
+	case MessagePlaybackEvent:
+		if !h.inSession(c) || msg.Playback == nil || !playbackEventActions[msg.Playback.Action] || msg.Playback.PositionMs < 0 {
+			return
+		}
+		// Starting playback makes the device the active one; the others
+		// stop when they see the event.
+		activated := false
+		if msg.Playback.Action == PlaybackPlay || msg.Playback.Action == PlaybackTrackChange {
+			h.mu.Lock()
+			if h.active[c.userID] != c.deviceID {
+				h.active[c.userID] = c.deviceID
+				activated = true
+			}
+			h.mu.Unlock()
+		}
+		h.BroadcastProgress(&ProgressMessage{
+			Type:           MessagePlaybackEvent,
+			UserID:         c.userID,
+			ExceptDeviceID: c.deviceID,
+			Seq:            h.seq.Add(1),
+			FromDeviceID:   c.deviceID,
+			Playback:       msg.Playback,
+		})
+		if activated {
+			h.BroadcastProgress(h.devicesMessage(c.user))
+		}
+
+	case MessagePlaybackCommand:
+		if !h.inSession(c) || msg.Playback == nil || !playbackCommandActions[msg.Playback.Action] || msg.Playback.PositionMs < 0 {
+			return
+		}
+		if msg.TargetDeviceID == c.deviceID || !h.DeviceConnected(c.user, msg.TargetDeviceID) {
+			return
+		}
+		h.BroadcastProgress(&ProgressMessage{
+			Type:         MessagePlaybackCommand,
+			UserID:       c.userID,
+			DeviceID:     msg.TargetDeviceID,
+			Seq:          h.seq.Add(1),
+			FromDeviceID: c.deviceID,
+			Playback:     msg.Playback,
+		})
+	}
+}
+
+func (h *Hub) inSession(c *Client) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return c.session
+}
+
+// devicesMessage lists the user's session devices, one entry per device
+// however many connections it has, in device ID order.
+func (h *Hub) devicesMessage(userID uuid.UUID) *ProgressMessage {
+	key := uuidToInt64(userID)
+	h.mu.RLock()
+	seen := map[string]bool{}
+	devices := []SessionDevice{}
+	for client := range h.clients[key] {
+		if !client.session || seen[client.deviceID] {
+			continue
+		}
+		seen[client.deviceID] = true
+		devices = append(devices, SessionDevice{
+			DeviceID: client.deviceID,
+			Name:     client.deviceName,
+			Type:     client.deviceType,
+			Active:   h.active[key] == client.deviceID,
+		})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].DeviceID < devices[j].DeviceID })
+	return &ProgressMessage{Type: MessageSessionDevices, UserID: key, Seq: h.seq.Add(1), Devices: devices}
+}
+
+// dropActiveLocked forgets the active device when its last connection has
+// gone. h.mu must be held.
+func (h *Hub) dropActiveLocked(c *Client) {
+	if h.active[c.userID] != c.deviceID {
+		return
+	}
+	for other := range h.clients[c.userID] {
+		if other.deviceID == c.deviceID {
+			return
+		}
+	}
+	delete(h.active, c.userID)
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func sessionClient(hub *Hub, user uuid.UUID, deviceID string) *Client {
+	c := &Client{hub: hub, send: make(chan *ProgressMessage, 16), userID: uuidToInt64(user), user: user, deviceID: deviceID}
+	hub.register <- c
+	return c
+}
+
+// next returns the next message of msgType sent to c.
+func next(t *testing.T, c *Client, msgType string) *ProgressMessage {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case msg := <-c.send:
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("%s got no %s message", c.deviceID, msgType)
+			return nil
+		}
+	}
+}
+
+func TestSessionRelaysEventsAndCommands(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	user := uuid.New()
+	phone := sessionClient(hub, user, "phone")
+	desktop := sessionClient(hub, user, "desktop")
+
+	hub.handleSession(phone, ClientMessage{Type: MessageSessionRegister, DeviceName: "Phone"})
+	hub.handleSession(desktop, ClientMessage{Type: MessageSessionRegister, DeviceName: "Desktop"})
+	devices := next(t, phone, MessageSessionDevices)
+	devices = next(t, phone, MessageSessionDevices)
+	if list := devices.Devices.([]SessionDevice); len(list) != 2 || list[0].DeviceID != "desktop" {
+		t.Fatalf("devices = %+v, want desktop and phone", list)
+	}
+
+	hub.handleSession(phone, ClientMessage{Type: MessagePlaybackEvent, Playback: &PlaybackMessage{Action: PlaybackPlay, PositionMs: 1000}})
+	event := next(t, desktop, MessagePlaybackEvent)
+	if event.FromDeviceID != "phone" || event.Playback.PositionMs != 1000 {
+		t.Fatalf("event = %+v, want phone's play relayed", event)
+	}
+	active := next(t, desktop, MessageSessionDevices).Devices.([]SessionDevice)
+	if active[0].Active || !active[1].Active {
+		t.Fatalf("devices = %+v, want phone active", active)
+	}
+
+	hub.handleSession(desktop, ClientMessage{Type: MessagePlaybackCommand, TargetDeviceID: "phone", Playback: &PlaybackMessage{Action: PlaybackPause}})
+	command := next(t, phone, MessagePlaybackCommand)
+	if command.FromDeviceID != "desktop" || command.Seq <= event.Seq {
+		t.Fatalf("command = %+v, want desktop's pause ordered after the play", command)
+	}
+
+	// The sender never hears its own event back.
+	select {
+	case msg := <-phone.send:
+		if msg.Type == MessagePlaybackEvent {
+			t.Fatalf("phone got its own event back: %+v", msg)
+		}
+	default:
+	}
+}
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event schema versions. Version 1, the default, sends each message as a
+// flat JSON object. Version 2 wraps it in an Envelope that names the event's
+// topic and schema version, so clients can route events without knowing
+// every type and the server can add types and fields without breaking them.
+// A client picks one with ?schema= when it connects.
+const (
+	SchemaFlat     = 1
+	SchemaEnvelope = 2
+)
+
+// Topics group event types so a client can subscribe to only the events it
+// uses. A connection gets every topic unless it asks for some with
+// ?topics=downloads,queue when it connects, or later with subscribe and
+// unsubscribe messages.
+const (
+	TopicDownloads = "downloads"
+	TopicQueue     = "queue"
+	TopicLibrary   = "library"
+	TopicPlaylists = "playlists"
+	TopicPlayback  = "playback"
+)
+
+// Subscription message types. A client sends subscribe or unsubscribe with
+// the topics to add or drop, and gets subscriptions back listing the topics
+// it now receives. subscriptions belongs to no topic, so it always arrives.
+const (
+	MessageSubscribe     = "subscribe"
+	MessageUnsubscribe   = "unsubscribe"
+	MessageSubscriptions = "subscriptions"
+)
+
+// MessagePlaylistChanged tells a user's devices one of their playlists was
+// created, edited or deleted.
+const MessagePlaylistChanged = "playlist_changed"
+
+// Topics lists every topic in the order subscriptions reports them.
+var Topics = []string{TopicDownloads, TopicQueue, TopicLibrary, TopicPlaylists, TopicPlayback}
+
+var messageTopics = map[string]string{
+	"download_progress":             TopicDownloads,
+	"download_queue_position":       TopicDownloads,
+	"batch_match_progress":          TopicLibrary,
+	"library_export_progress":       TopicLibrary,
+	"artwork_backfill_progress":     TopicLibrary,
+	"library_scan_progress":         TopicLibrary,
+	MessageQueueChanged:             TopicQueue,
+	MessagePlaylistChanged:          TopicPlaylists,
+	MessageSessionDevices:           TopicPlayback,
+	MessagePlaybackEvent:            TopicPlayback,
+	MessagePlaybackCommand:          TopicPlayback,
+	MessagePlaybackTransfer:         TopicPlayback,
+	MessagePlaybackTransferAccepted: TopicPlayback,
+	MessagePlaybackTransferDeclined: TopicPlayback,
+}
+
+// TopicOf returns the topic a message type belongs to, or "" for control
+// messages every connection gets.
+func TopicOf(msgType string) string {
+	return messageTopics[msgType]
+}
+
+// Envelope is how schema 2 connections receive every message. Data holds
+// the message itself, as schema 1 connections would receive it.
+type Envelope struct {
+	Version int       `json:"v"`
+	Type    string    `json:"type"`
+	Topic   string    `json:"topic,omitempty"`
+	Seq     int64     `json:"seq,omitempty"`
+	Time    time.Time `json:"ts"`
+	Data    any       `json:"data"`
+}
+
+func envelope(msg *ProgressMessage) *Envelope {
+	return &Envelope{
+		Version: SchemaEnvelope,
+		Type:    msg.Type,
+		Topic:   TopicOf(msg.Type),
+		Seq:     msg.Seq,
+		Time:    time.Now().UTC(),
+		Data:    msg,
+	}
+}
+
+// parseSchema reads ?schema=, defaulting to SchemaFlat.
+func parseSchema(raw string) (int, bool) {
+	if raw == "" {
+		return SchemaFlat, true
+	}
+	schema, err := strconv.Atoi(raw)
+	if err != nil || schema < SchemaFlat || schema > SchemaEnvelope {
+		return 0, false
+	}
+	return schema, true
+}
+
+// parseTopics reads a comma-separated topic list. An empty list means every
+// topic, which is a nil set.
+func parseTopics(raw string) (map[string]bool, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	topics := map[string]bool{}
+	for _, topic := range strings.Split(raw, ",") {
+		if !slices.Contains(Topics, topic) {
+			return nil, false
+		}
+		topics[topic] = true
+	}
+	return topics, true
+}
+
+// wants reports whether c is subscribed to msgType's topic. h.mu must be
+// held.
+func (c *Client) wants(msgType string) bool {
+	topic := TopicOf(msgType)
+	return topic == "" || c.topics == nil || c.topics[topic]
+}
+
+// handleSubscription changes c's topics and tells it which it now gets.
+// Unknown topics are ignored.
+func (h *Hub) handleSubscription(c *Client, msg ClientMessage) {
+	h.mu.Lock()
+	if c.topics == nil {
+		c.topics = map[string]bool{}
+		for _, topic := range Topics {
+			c.topics[topic] = true
+		}
+	}
+	for _, topic := range msg.Topics {
+		if !slices.Contains(Topics, topic) {
+			continue
+		}
+		if msg.Type == MessageSubscribe {
+			c.topics[topic] = true
+		} else {
+			delete(c.topics, topic)
+		}
+	}
+	subscribed := []string{}
+	for _, topic := range Topics {
+		if c.topics[topic] {
+			subscribed = append(subscribed, topic)
+		}
+	}
+	h.mu.Unlock()
+
+	h.BroadcastProgress(&ProgressMessage{
+		Type:   MessageSubscriptions,
+		UserID: c.userID,
+		client: c,
+		Topics: subscribed,
+	})
+}
@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTopicSubscriptionsFilterDelivery(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	user := uuid.New()
+	c := &Client{hub: hub, send: make(chan *ProgressMessage, 16), userID: uuidToInt64(user), user: user, topics: map[string]bool{TopicQueue: true}}
+	hub.register <- c
+
+	tracker := NewProgressTracker(hub)
+	tracker.UpdateQueuePosition(user, "job-1", 2, nil)
+	tracker.QueueChanged(user, "", map[string]int{"version": 3})
+	if msg := next(t, c, MessageQueueChanged); msg.Queue == nil {
+		t.Fatalf("queue_changed = %+v, want the change", msg)
+	}
+	if len(c.send) != 0 {
+		t.Fatalf("got %s, want nothing outside the queue topic", (<-c.send).Type)
+	}
+
+	hub.handleSubscription(c, ClientMessage{Type: MessageSubscribe, Topics: []string{TopicDownloads, "bogus"}})
+	if msg := next(t, c, MessageSubscriptions); len(msg.Topics) != 2 || msg.Topics[0] != TopicDownloads || msg.Topics[1] != TopicQueue {
+		t.Fatalf("subscriptions = %v, want downloads and queue", msg.Topics)
+	}
+	hub.handleSubscription(c, ClientMessage{Type: MessageUnsubscribe, Topics: []string{TopicQueue}})
+	next(t, c, MessageSubscriptions)
+
+	tracker.QueueChanged(user, "", nil)
+	tracker.UpdateQueuePosition(user, "job-1", 1, nil)
+	if msg := <-c.send; msg.Type != "download_queue_position" {
+		t.Fatalf("got %s, want download_queue_position once unsubscribed from queue", msg.Type)
+	}
+}
+
+func TestEnvelopeWrapsMessage(t *testing.T) {
+	data, err := json.Marshal(envelope(&ProgressMessage{Type: MessageQueueChanged, Seq: 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version int    `json:"v"`
+		Type    string `json:"type"`
+		Topic   string `json:"topic"`
+		Seq     int64  `json:"seq"`
+		Data    struct {
+			Type string `json:"type"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != SchemaEnvelope || got.Topic != TopicQueue || got.Seq != 7 || got.Data.Type != MessageQueueChanged {
+		t.Fatalf("envelope = %s", data)
+	}
+}
+
+func TestParseSchemaAndTopics(t *testing.T) {
+	if schema, ok := parseSchema(""); !ok || schema != SchemaFlat {
+		t.Fatalf("default schema = %d, %v", schema, ok)
+	}
+	if _, ok := parseSchema("3"); ok {
+		t.Fatal("schema 3 accepted")
+	}
+	if topics, ok := parseTopics(""); !ok || topics != nil {
+		t.Fatalf("no topics = %v, %v; want every topic", topics, ok)
+	}
+	if _, ok := parseTopics("queue,albums"); ok {
+		t.Fatal("unknown topic accepted")
+	}
+}